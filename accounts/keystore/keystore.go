@@ -46,9 +46,10 @@ import (
 )
 
 var (
-	ErrLocked  = accounts.NewAuthNeededError("password or unlock")
-	ErrNoMatch = errors.New("no key for given address or file")
-	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
+	ErrLocked      = accounts.NewAuthNeededError("password or unlock")
+	ErrNoMatch     = errors.New("no key for given address or file")
+	ErrDecrypt     = errors.New("could not decrypt key with given passphrase")
+	ErrOTANotOwned = errors.New("one-time address does not belong to the account")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -72,6 +73,8 @@ type KeyStore struct {
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
 
+	otaScanner *OTAScanner // Scanner of the OTAs received by the accounts, nil if not running
+
 	mu sync.RWMutex
 }
 
@@ -308,6 +311,127 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	return []string{pub1X, pub1Y, priv1D, priv2D}, err
 }
 
+// otaPrivateKey derives the private key of a one-time address received by an
+// unlocked account. The caller must hold ks.mu.
+func (ks *KeyStore) otaPrivateKey(a accounts.Account, ota []byte) (*ecdsa.PrivateKey, error) {
+	unlockedKey, found := ks.unlocked[a.Address]
+	if !found {
+		return nil, ErrLocked
+	}
+	A, B, err := GeneratePKPairFromWAddress(ota)
+	if err != nil {
+		return nil, err
+	}
+	priv, _, err := crypto.GenerateOneTimePrivateKey2528(unlockedKey.PrivateKey, unlockedKey.PrivateKey2, A, B)
+	if err != nil {
+		return nil, err
+	}
+	priv.Curve = crypto.S256()
+	priv.X, priv.Y = priv.Curve.ScalarBaseMult(priv.D.Bytes())
+	if priv.X.Cmp(A.X) != 0 || priv.Y.Cmp(A.Y) != 0 {
+		zeroKey(priv)
+		return nil, ErrOTANotOwned
+	}
+	return priv, nil
+}
+
+// OTAAddress returns the address of the one-time account of an OTA received by
+// an unlocked account, i.e. the sender of privacy transactions spending it.
+func (ks *KeyStore) OTAAddress(a accounts.Account, ota []byte) (common.Address, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	priv, err := ks.otaPrivateKey(a, ota)
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer zeroKey(priv)
+	return crypto.PubkeyToAddress(priv.PublicKey), nil
+}
+
+// SignOTATx signs the given transaction with the one-time private key of an OTA
+// received by an unlocked account.
+func (ks *KeyStore) SignOTATx(a accounts.Account, ota []byte, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	priv, err := ks.otaPrivateKey(a, ota)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(priv)
+
+	// Depending on the presence of the chain ID, sign with EIP155 or homestead
+	if chainID != nil {
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), priv)
+	}
+	return types.SignTx(tx, types.HomesteadSigner{}, priv)
+}
+
+// RingSignOTA ring signs a message with the one-time private key of an OTA
+// received by an unlocked account. The OTA is hidden among the public keys of
+// the mix set, which are returned with the OTA first, along with the key image
+// and the random numbers of the signature.
+func (ks *KeyStore) RingSignOTA(a accounts.Account, ota []byte, msg []byte, mixSet [][]byte) ([]*ecdsa.PublicKey, *ecdsa.PublicKey, []*big.Int, []*big.Int, error) {
+	publicKeys := make([]*ecdsa.PublicKey, 0, len(mixSet)+1)
+	for _, mixOta := range append([][]byte{ota}, mixSet...) {
+		publicKey, _, err := GeneratePKPairFromWAddress(mixOta)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		publicKeys = append(publicKeys, publicKey)
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	priv, err := ks.otaPrivateKey(a, ota)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	defer zeroKey(priv)
+	return crypto.RingSign(msg, priv.D, publicKeys)
+}
+
+// OTAKeyImage returns the key image of an OTA received by an unlocked account,
+// disclosed by the ring signatures refunding it.
+func (ks *KeyStore) OTAKeyImage(a accounts.Account, ota []byte) ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	priv, err := ks.otaPrivateKey(a, ota)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(priv)
+	return crypto.FromECDSAPub(crypto.KeyImage(priv.D, &priv.PublicKey)), nil
+}
+
+// OTAScanner returns the scanner of the OTAs received by the accounts, nil if
+// the node does not run one.
+func (ks *KeyStore) OTAScanner() *OTAScanner {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.otaScanner
+}
+
+// otaViewKeys returns the public spend key and a copy of the private view key
+// of the unlocked accounts.
+func (ks *KeyStore) otaViewKeys() map[common.Address]*otaViewKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	views := make(map[common.Address]*otaViewKey, len(ks.unlocked))
+	for addr, unlockedKey := range ks.unlocked {
+		if unlockedKey.PrivateKey2 == nil {
+			continue
+		}
+		A := unlockedKey.PrivateKey.PublicKey
+		views[addr] = &otaViewKey{A: &A, b: common.CopyBytes(unlockedKey.PrivateKey2.D.Bytes())}
+	}
+	return views
+}
+
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
@@ -433,11 +557,20 @@ func (ks *KeyStore) expire(addr common.Address, u *unlocked, timeout time.Durati
 		// was launched with. we can check that using pointer equality
 		// because the map stores a new pointer every time the key is
 		// unlocked.
-		if ks.unlocked[addr] == u {
+		dropped := ks.unlocked[addr] == u
+		if dropped {
 			zeroKey(u.PrivateKey)
+			zeroKey(u.PrivateKey2)
 			delete(ks.unlocked, addr)
 		}
+		scanner := ks.otaScanner
 		ks.mu.Unlock()
+
+		// the scanner takes the keystore lock while holding its own, so
+		// it is told after releasing it
+		if dropped && scanner != nil {
+			scanner.dropView(addr)
+		}
 	}
 }
 
@@ -19,6 +19,7 @@ package keystore
 
 import (
 	"io/ioutil"
+	"math/big"
 	"math/rand"
 	"os"
 	"runtime"
@@ -30,6 +31,7 @@ import (
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/event"
 )
@@ -534,3 +536,80 @@ func TestComputeOTAPPKeys(t *testing.T) {
 		t.Errorf("invalid ota pk. pk lenght:%d", len(pk))
 	}
 }
+
+func TestOTASigning(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, err := ks.GetWanAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaStr, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota := common.FromHex(otaStr)
+
+	tx := types.NewOTATransaction(0, common.Address{}, new(big.Int), big.NewInt(300000), big.NewInt(180000000000), []byte{1})
+	if _, err := ks.SignOTATx(a, ota, tx, big.NewInt(1)); err != ErrLocked {
+		t.Fatalf("signing with a locked account: have %v, want %v", err, ErrLocked)
+	}
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Unlock(other, auth); err != nil {
+		t.Fatal(err)
+	}
+
+	// The one-time account signs the transaction
+	otaAddr, err := ks.OTAAddress(a, ota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	signed, err := ks.SignOTATx(a, ota, tx, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := types.Sender(signer, signed); err != nil || from != otaAddr {
+		t.Fatalf("sender mismatch: have %x, want %x, %v", from, otaAddr, err)
+	}
+	if _, err := ks.SignOTATx(other, ota, tx, big.NewInt(1)); err != ErrOTANotOwned {
+		t.Fatalf("signing with another account: have %v, want %v", err, ErrOTANotOwned)
+	}
+
+	// The ring signature hides the OTA in the mix set
+	mixSet := make([][]byte, 0, 2)
+	for i := 0; i < 2; i++ {
+		wAddr, err := ks.GetWanAddress(other)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mixOta, err := genOTA(hexutil.Encode(wAddr[:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		mixSet = append(mixSet, common.FromHex(mixOta))
+	}
+	msg := otaAddr[:]
+	publicKeys, keyImage, w, q, err := ks.RingSignOTA(a, ota, msg, mixSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publicKeys) != 3 || !crypto.VerifyRingSign(msg, publicKeys, keyImage, w, q) {
+		t.Fatal("invalid ring signature")
+	}
+	if _, _, _, _, err := ks.RingSignOTA(other, ota, msg, mixSet); err != ErrOTANotOwned {
+		t.Fatalf("ring signing with another account: have %v, want %v", err, ErrOTANotOwned)
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	// otaScanPrefix + account address -> JSON list of the OTAs received by the account
+	otaScanPrefix = []byte("ota-scan-")
+	// otaScannedPrefix + account address -> number of the last block scanned for the account
+	otaScannedPrefix = []byte("ota-scanned-")
+)
+
+// otaSpentRetention is the number of blocks a spent OTA is kept for, so that a
+// reorg dropping its spend makes it unspent again.
+const otaSpentRetention = 1024
+
+// ReceivedOTA is a one-time address paid to a local account by a buyCoinNote
+// call of the wancoin contract.
+type ReceivedOTA struct {
+	Account     common.Address `json:"account"`
+	OTA         hexutil.Bytes  `json:"ota"`
+	Value       *hexutil.Big   `json:"value"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+
+	// KeyImage is the image refunds of the OTA disclose, known once the
+	// account has been unlocked since the OTA was received.
+	KeyImage   hexutil.Bytes  `json:"keyImage,omitempty"`
+	SpentBlock hexutil.Uint64 `json:"spentBlock,omitempty"`
+
+	// Removed is set on the notifications of OTAs dropped by a reorg.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// OTAPayment is a buyCoinNote call of a block paying an OTA.
+type OTAPayment struct {
+	OTA    []byte
+	Value  *big.Int
+	TxHash common.Hash
+}
+
+// OTABlock holds the successful wancoin calls of a block: the OTAs it pays and
+// the key images of the OTAs it refunds.
+type OTABlock struct {
+	Number   uint64
+	Hash     common.Hash
+	Payments []OTAPayment
+	Images   [][]byte
+}
+
+// otaViewKey is what an account needs to recognise the OTAs paid to it: its
+// public spend key A and its private view key b.
+type otaViewKey struct {
+	A *ecdsa.PublicKey
+	b []byte
+}
+
+// OTAScanner checks the OTAs paid by every block against the view keys of the
+// local accounts and keeps, per account, the OTAs received and not yet spent.
+//
+// The view key of an account is only known while it is unlocked: the scanner
+// drops it when the keystore locks the account again. The last block scanned
+// is kept per account, so that an account unlocked again after missing blocks,
+// or for the first time, catches up from there (see Behind).
+type OTAScanner struct {
+	ks    *KeyStore
+	db    ethdb.Database          // Database persisting the received OTAs, nil to keep them in memory
+	spent func(image []byte) bool // Reports whether a key image is in the chain, may be nil
+
+	views    map[common.Address]*otaViewKey
+	received map[common.Address][]*ReceivedOTA
+	scanned  map[common.Address]uint64 // Number of the last block scanned per account
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	mu sync.Mutex
+}
+
+// NewOTAScanner creates the OTA scanner of the keystore, persisting the OTAs
+// received in db. The key images of the OTAs received while their account was
+// locked are only computed after it is unlocked again; spent then checks
+// whether the chain already holds them.
+func NewOTAScanner(ks *KeyStore, db ethdb.Database, spent func(image []byte) bool) *OTAScanner {
+	s := &OTAScanner{
+		ks:       ks,
+		db:       db,
+		spent:    spent,
+		views:    make(map[common.Address]*otaViewKey),
+		received: make(map[common.Address][]*ReceivedOTA),
+		scanned:  make(map[common.Address]uint64),
+	}
+	ks.mu.Lock()
+	ks.otaScanner = s
+	ks.mu.Unlock()
+	return s
+}
+
+// OTAs returns the unspent OTAs received by the account, oldest first.
+func (s *OTAScanner) OTAs(account common.Address) []ReceivedOTA {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	otas := make([]ReceivedOTA, 0)
+	for _, r := range s.load(account) {
+		if r.SpentBlock == 0 {
+			otas = append(otas, *r)
+		}
+	}
+	return otas
+}
+
+// Subscribe notifies the OTAs received or spent by the local accounts, and
+// those of them dropped by a reorg.
+func (s *OTAScanner) Subscribe(ch chan<- ReceivedOTA) event.Subscription {
+	return s.scope.Track(s.feed.Subscribe(ch))
+}
+
+// Close ends the subscriptions.
+func (s *OTAScanner) Close() {
+	s.scope.Close()
+}
+
+// Behind returns the first block to scan for the account lagging the most
+// behind block head, among the accounts whose view key is known, and false if
+// they are all scanned up to head. Accounts never scanned start after the
+// genesis block.
+func (s *OTAScanner) Behind(head uint64) (uint64, bool) {
+	views := s.ks.otaViewKeys()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.syncViews(views)
+	next, behind := head+1, false
+	for addr := range s.views {
+		if scanned := s.scanned[addr]; scanned < head && scanned+1 < next {
+			next, behind = scanned+1, true
+		}
+	}
+	return next, behind
+}
+
+// ScanBlock adds the OTAs paid by block to the accounts they belong to, and
+// marks the ones it refunds as spent. Only the accounts last scanned up to the
+// parent of block scan it: blocks must be scanned in chain order, and lagging
+// accounts catch up from the block given by Behind.
+func (s *OTAScanner) ScanBlock(block *OTABlock) {
+	views := s.ks.otaViewKeys()
+
+	s.mu.Lock()
+	s.syncViews(views)
+
+	scanning := make(map[common.Address]*otaViewKey)
+	for addr, view := range s.views {
+		if s.scanned[addr]+1 == block.Number {
+			scanning[addr] = view
+		}
+	}
+
+	var events []ReceivedOTA
+	changed := make(map[common.Address]bool)
+	for _, pay := range block.Payments {
+		A1, S1, err := GeneratePKPairFromWAddress(pay.OTA)
+		if err != nil {
+			continue
+		}
+		for addr, view := range scanning {
+			if !crypto.CompareA1(view.b, view.A, S1, A1) {
+				continue
+			}
+			if s.has(addr, pay.OTA) {
+				break
+			}
+			r := &ReceivedOTA{
+				Account:     addr,
+				OTA:         common.CopyBytes(pay.OTA),
+				Value:       (*hexutil.Big)(new(big.Int).Set(pay.Value)),
+				BlockNumber: hexutil.Uint64(block.Number),
+				BlockHash:   block.Hash,
+				TxHash:      pay.TxHash,
+			}
+			s.received[addr] = append(s.received[addr], r)
+			changed[addr] = true
+			events = append(events, *r)
+			break
+		}
+	}
+
+	for addr := range scanning {
+		received := s.received[addr]
+		kept := received[:0]
+		for _, r := range received {
+			if r.SpentBlock != 0 && uint64(r.SpentBlock)+otaSpentRetention < block.Number {
+				changed[addr] = true
+				continue
+			}
+			kept = append(kept, r)
+			if r.SpentBlock != 0 {
+				continue
+			}
+			if r.KeyImage == nil {
+				image, err := s.ks.OTAKeyImage(accounts.Account{Address: addr}, r.OTA)
+				if err != nil {
+					continue
+				}
+				r.KeyImage = image
+				changed[addr] = true
+				if s.spent != nil && s.spent(image) {
+					r.SpentBlock = hexutil.Uint64(block.Number)
+					events = append(events, *r)
+					continue
+				}
+			}
+			for _, image := range block.Images {
+				if bytes.Equal(image, r.KeyImage) {
+					r.SpentBlock = hexutil.Uint64(block.Number)
+					changed[addr] = true
+					events = append(events, *r)
+					break
+				}
+			}
+		}
+		s.received[addr] = kept
+		s.setScanned(addr, block.Number)
+	}
+	for addr := range changed {
+		s.store(addr)
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		s.feed.Send(ev)
+	}
+}
+
+// Rewind drops the OTAs received after block number, and makes the ones spent
+// after it unspent again. The accounts scanned past it are rewound to it.
+func (s *OTAScanner) Rewind(number uint64) {
+	s.mu.Lock()
+	for _, account := range s.ks.Accounts() {
+		s.load(account.Address)
+	}
+	var events []ReceivedOTA
+	for addr, scanned := range s.scanned {
+		if scanned > number {
+			s.setScanned(addr, number)
+		}
+	}
+	for addr, received := range s.received {
+		kept := received[:0]
+		changed := false
+		for _, r := range received {
+			if uint64(r.BlockNumber) > number {
+				ev := *r
+				ev.Removed = true
+				events = append(events, ev)
+				changed = true
+				continue
+			}
+			if uint64(r.SpentBlock) > number {
+				r.SpentBlock = 0
+				events = append(events, *r)
+				changed = true
+			}
+			kept = append(kept, r)
+		}
+		s.received[addr] = kept
+		if changed {
+			s.store(addr)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		s.feed.Send(ev)
+	}
+}
+
+// syncViews adds the view keys of the accounts unlocked since the last call,
+// and drops those of the accounts locked since. The caller must hold s.mu.
+func (s *OTAScanner) syncViews(views map[common.Address]*otaViewKey) {
+	for addr := range s.views {
+		if _, ok := views[addr]; !ok {
+			s.dropViewLocked(addr)
+		}
+	}
+	for addr, view := range views {
+		if _, ok := s.views[addr]; ok {
+			zeroBytes(view.b)
+			continue
+		}
+		s.views[addr] = view
+		s.load(addr)
+		log.Debug("Scanning OTAs of account", "account", addr, "from", s.scanned[addr]+1)
+	}
+}
+
+// dropView forgets the view key of an account the keystore locked.
+func (s *OTAScanner) dropView(account common.Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dropViewLocked(account)
+}
+
+// dropViewLocked forgets the view key of the account. The caller must hold
+// s.mu.
+func (s *OTAScanner) dropViewLocked(account common.Address) {
+	view, ok := s.views[account]
+	if !ok {
+		return
+	}
+	zeroBytes(view.b)
+	delete(s.views, account)
+	log.Debug("Stopped scanning OTAs of locked account", "account", account, "scanned", s.scanned[account])
+}
+
+// zeroBytes clears the copy of a private key.
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+// has reports whether the account already received ota. The caller must hold
+// s.mu.
+func (s *OTAScanner) has(account common.Address, ota []byte) bool {
+	for _, r := range s.received[account] {
+		if bytes.Equal(r.OTA, ota) {
+			return true
+		}
+	}
+	return false
+}
+
+// load returns the OTAs received by the account, reading them and the last
+// block scanned from the database the first time. The caller must hold s.mu.
+func (s *OTAScanner) load(account common.Address) []*ReceivedOTA {
+	if received, ok := s.received[account]; ok {
+		return received
+	}
+	received := make([]*ReceivedOTA, 0)
+	if s.db != nil {
+		if data, _ := s.db.Get(append(append([]byte{}, otaScanPrefix...), account[:]...)); len(data) > 0 {
+			if err := json.Unmarshal(data, &received); err != nil {
+				log.Error("Invalid received OTAs", "account", account, "err", err)
+			}
+		}
+		if data, _ := s.db.Get(append(append([]byte{}, otaScannedPrefix...), account[:]...)); len(data) == 8 {
+			s.scanned[account] = binary.BigEndian.Uint64(data)
+		}
+	}
+	s.received[account] = received
+	return received
+}
+
+// setScanned records number as the last block scanned for the account. The
+// caller must hold s.mu.
+func (s *OTAScanner) setScanned(account common.Address, number uint64) {
+	s.scanned[account] = number
+	if s.db == nil {
+		return
+	}
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], number)
+	if err := s.db.Put(append(append([]byte{}, otaScannedPrefix...), account[:]...), data[:]); err != nil {
+		log.Error("Failed to store OTA scan progress", "account", account, "err", err)
+	}
+}
+
+// store persists the OTAs received by the account. The caller must hold s.mu.
+func (s *OTAScanner) store(account common.Address) {
+	if s.db == nil {
+		return
+	}
+	data, err := json.Marshal(s.received[account])
+	if err != nil {
+		log.Error("Failed to encode received OTAs", "account", account, "err", err)
+		return
+	}
+	if err := s.db.Put(append(append([]byte{}, otaScanPrefix...), account[:]...), data); err != nil {
+		log.Error("Failed to store received OTAs", "account", account, "err", err)
+	}
+}
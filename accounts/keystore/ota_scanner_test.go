@@ -0,0 +1,237 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package keystore
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+func TestOTAScanner(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otas := make([][]byte, 0, 2)
+	for _, acc := range []accounts.Account{a, other} {
+		wAddr, err := ks.GetWanAddress(acc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otaStr, err := genOTA(hexutil.Encode(wAddr[:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		otas = append(otas, common.FromHex(otaStr))
+	}
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := ethdb.NewMemDatabase()
+	scanner := NewOTAScanner(ks, db, nil)
+	if ks.OTAScanner() != scanner {
+		t.Fatal("scanner not attached to the keystore")
+	}
+	events := make(chan ReceivedOTA, 10)
+	sub := scanner.Subscribe(events)
+	defer sub.Unsubscribe()
+
+	// only the OTA paid to the unlocked account is found
+	scanner.ScanBlock(&OTABlock{Number: 1, Hash: common.HexToHash("0x01"), Payments: []OTAPayment{
+		{OTA: otas[0], Value: big.NewInt(10), TxHash: common.HexToHash("0xa0")},
+		{OTA: otas[1], Value: big.NewInt(20), TxHash: common.HexToHash("0xa1")},
+	}})
+	received := scanner.OTAs(a.Address)
+	if len(received) != 1 || !bytes.Equal(received[0].OTA, otas[0]) || received[0].BlockNumber != 1 ||
+		received[0].TxHash != common.HexToHash("0xa0") || received[0].Value.ToInt().Int64() != 10 {
+		t.Fatalf("got received OTAs %+v", received)
+	}
+	if n := len(scanner.OTAs(other.Address)); n != 0 {
+		t.Fatalf("got %d OTAs of a locked account, want 0", n)
+	}
+	if ev := <-events; !bytes.Equal(ev.OTA, otas[0]) || ev.Removed {
+		t.Fatalf("got event %+v", ev)
+	}
+
+	// the refund of the OTA discloses its key image
+	_, keyImage, _, _, err := ks.RingSignOTA(a, otas[0], []byte("refund"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image := crypto.FromECDSAPub(keyImage)
+	if !bytes.Equal(received[0].KeyImage, image) {
+		t.Fatalf("got key image %x, want %x", received[0].KeyImage, image)
+	}
+	scanner.ScanBlock(&OTABlock{Number: 2, Hash: common.HexToHash("0x02"), Images: [][]byte{image}})
+	if n := len(scanner.OTAs(a.Address)); n != 0 {
+		t.Fatalf("got %d OTAs after the refund, want 0", n)
+	}
+	if ev := <-events; ev.SpentBlock != 2 {
+		t.Fatalf("got event %+v, want spent at 2", ev)
+	}
+
+	// the list survives a restart
+	if n := len(NewOTAScanner(ks, db, nil).OTAs(a.Address)); n != 0 {
+		t.Fatalf("got %d OTAs after a restart, want 0", n)
+	}
+	restarted := NewOTAScanner(ks, db, nil)
+	restarted.ScanBlock(&OTABlock{Number: 3, Hash: common.HexToHash("0x03")})
+	restarted.Rewind(1)
+	if n := len(restarted.OTAs(a.Address)); n != 1 {
+		t.Fatalf("got %d OTAs after rewinding the refund, want 1", n)
+	}
+	restarted.Rewind(0)
+	if n := len(restarted.OTAs(a.Address)); n != 0 {
+		t.Fatalf("got %d OTAs after rewinding the payment, want 0", n)
+	}
+}
+
+func TestOTAScannerCatchUp(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	late, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otas := make([][]byte, 0, 2)
+	for _, acc := range []accounts.Account{a, late} {
+		wAddr, err := ks.GetWanAddress(acc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		otaStr, err := genOTA(hexutil.Encode(wAddr[:]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		otas = append(otas, common.FromHex(otaStr))
+	}
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	scanner := NewOTAScanner(ks, db, nil)
+
+	blocks := []*OTABlock{
+		{Number: 1, Hash: common.HexToHash("0x01"), Payments: []OTAPayment{
+			{OTA: otas[0], Value: big.NewInt(10), TxHash: common.HexToHash("0xa0")},
+			{OTA: otas[1], Value: big.NewInt(20), TxHash: common.HexToHash("0xa1")},
+		}},
+		{Number: 2, Hash: common.HexToHash("0x02")},
+	}
+	for _, block := range blocks {
+		scanner.ScanBlock(block)
+	}
+	if _, behind := scanner.Behind(2); behind {
+		t.Fatal("unlocked account behind the head")
+	}
+
+	// the account unlocked later catches up from the first block, without
+	// the other one scanning the blocks again
+	if err := ks.Unlock(late, auth); err != nil {
+		t.Fatal(err)
+	}
+	from, behind := scanner.Behind(2)
+	if !behind || from != 1 {
+		t.Fatalf("got catch-up from %d (%v), want 1", from, behind)
+	}
+	for _, block := range blocks[from-1:] {
+		scanner.ScanBlock(block)
+	}
+	if _, behind := scanner.Behind(2); behind {
+		t.Fatal("account still behind after catching up")
+	}
+	if received := scanner.OTAs(late.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, otas[1]) {
+		t.Fatalf("got OTAs %+v of the late account", received)
+	}
+	if n := len(scanner.OTAs(a.Address)); n != 1 {
+		t.Fatalf("got %d OTAs of the other account, want 1", n)
+	}
+
+	// the progress survives a restart, and rewinds with the chain
+	restarted := NewOTAScanner(ks, db, nil)
+	if _, behind := restarted.Behind(2); behind {
+		t.Fatal("accounts behind after a restart")
+	}
+	restarted.Rewind(1)
+	if from, behind := restarted.Behind(2); !behind || from != 2 {
+		t.Fatalf("got catch-up from %d (%v) after a rewind, want 2", from, behind)
+	}
+}
+
+func TestOTAScannerLock(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	auth := "wanchain_test"
+	a, err := ks.NewAccount(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, err := ks.GetWanAddress(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaStr, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota := common.FromHex(otaStr)
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	scanner := NewOTAScanner(ks, nil, nil)
+	scanner.ScanBlock(&OTABlock{Number: 1, Hash: common.HexToHash("0x01")})
+
+	// locking the account drops its view key, and the OTAs it is paid are
+	// only found once it is unlocked again
+	if err := ks.Lock(a.Address); err != nil {
+		t.Fatal(err)
+	}
+	scanner.mu.Lock()
+	views := len(scanner.views)
+	scanner.mu.Unlock()
+	if views != 0 {
+		t.Fatalf("got %d view keys after locking, want 0", views)
+	}
+	block := &OTABlock{Number: 2, Hash: common.HexToHash("0x02"), Payments: []OTAPayment{
+		{OTA: ota, Value: big.NewInt(10), TxHash: common.HexToHash("0xa0")},
+	}}
+	scanner.ScanBlock(block)
+	if n := len(scanner.OTAs(a.Address)); n != 0 {
+		t.Fatalf("got %d OTAs of the locked account, want 0", n)
+	}
+
+	if err := ks.Unlock(a, auth); err != nil {
+		t.Fatal(err)
+	}
+	if from, behind := scanner.Behind(2); !behind || from != 2 {
+		t.Fatalf("got catch-up from %d (%v), want 2", from, behind)
+	}
+	scanner.ScanBlock(block)
+	if received := scanner.OTAs(a.Address); len(received) != 1 || !bytes.Equal(received[0].OTA, ota) {
+		t.Fatalf("got OTAs %+v after unlocking again", received)
+	}
+}
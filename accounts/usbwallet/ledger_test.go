@@ -0,0 +1,133 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// ledgerMock emulates the Wanchain app of a Ledger wallet behind the HID
+// transport, signing transactions with a fixed key.
+type ledgerMock struct {
+	hidMock
+	key   *ecdsa.PrivateKey
+	apdu  []byte // APDU being reassembled from the written chunks
+	txrlp []byte // Transaction RLP streamed by the signing APDUs
+}
+
+func newLedgerMock(key *ecdsa.PrivateKey) *ledgerMock {
+	m := &ledgerMock{key: key}
+	m.write = m.receive
+	return m
+}
+
+// receive reassembles an APDU from the transport chunks written by the driver.
+func (m *ledgerMock) receive(chunk []byte) error {
+	if len(chunk) < 5 || chunk[0] != 0x01 || chunk[1] != 0x01 || chunk[2] != 0x05 {
+		return errors.New("invalid chunk header")
+	}
+	payload := chunk[5:]
+	if binary.BigEndian.Uint16(chunk[3:5]) == 0 {
+		m.apdu = make([]byte, 0, binary.BigEndian.Uint16(chunk[5:7]))
+		payload = chunk[7:]
+	}
+	if left := cap(m.apdu) - len(m.apdu); left > len(payload) {
+		m.apdu = append(m.apdu, payload...)
+		return nil
+	}
+	m.apdu = append(m.apdu, payload[:cap(m.apdu)-len(m.apdu)]...)
+
+	reply, err := m.exchange(m.apdu)
+	if err != nil {
+		return err
+	}
+	m.send(append(reply, 0x90, 0x00))
+	return nil
+}
+
+// exchange executes an APDU, only signing is supported.
+func (m *ledgerMock) exchange(apdu []byte) ([]byte, error) {
+	if len(apdu) < 5 || apdu[0] != 0xe0 || ledgerOpcode(apdu[1]) != ledgerOpSignTransaction || int(apdu[4]) != len(apdu)-5 {
+		return nil, fmt.Errorf("unexpected APDU %x", apdu)
+	}
+	data := apdu[5:]
+	switch ledgerParam1(apdu[2]) {
+	case ledgerP1InitTransactionData:
+		m.txrlp = append([]byte{}, data[1+4*int(data[0]):]...)
+	case ledgerP1ContTransactionData:
+		m.txrlp = append(m.txrlp, data...)
+	default:
+		return nil, fmt.Errorf("unexpected P1 %x", apdu[2])
+	}
+	// Wait for more data until the whole transaction is streamed
+	var fields []rlp.RawValue
+	if err := rlp.DecodeBytes(m.txrlp, &fields); err != nil {
+		return nil, nil
+	}
+	var chainID *big.Int
+	switch len(fields) {
+	case 7:
+	case 10:
+		chainID = new(big.Int)
+		if err := rlp.DecodeBytes(fields[7], chainID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected transaction with %d fields", len(fields))
+	}
+	v, r, s, err := deviceSignature(m.key, crypto.Keccak256(m.txrlp), chainID)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{v}, r...), s...), nil
+}
+
+// send streams a reply to the driver in 64 byte transport chunks.
+func (m *ledgerMock) send(reply []byte) {
+	payload := make([]byte, 2, 2+len(reply))
+	binary.BigEndian.PutUint16(payload, uint16(len(reply)))
+	payload = append(payload, reply...)
+
+	for seq := uint16(0); len(payload) > 0; seq++ {
+		chunk := make([]byte, 64)
+		copy(chunk, []byte{0x01, 0x01, 0x05})
+		binary.BigEndian.PutUint16(chunk[3:], seq)
+		payload = payload[copy(chunk[5:], payload):]
+		m.reply.Write(chunk)
+	}
+}
+
+// Tests that the Ledger driver streams the Wanchain transaction fields, type
+// included, so that the device signs the hash of the Wanchain signers.
+func TestLedgerSignTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	driver := &ledgerDriver{
+		device:  newLedgerMock(key),
+		version: [3]byte{1, 0, 3},
+		log:     log.New(),
+	}
+	testDriverSignTx(t, driver, key)
+}
@@ -172,11 +172,14 @@ func (w *trezorDriver) trezorDerive(derivationPath []uint32) (common.Address, er
 // trezorSign sends the transaction to the Trezor wallet, and waits for the user
 // to confirm or deny the transaction.
 func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction, chainID *big.Int) (common.Address, *types.Transaction, error) {
+	// Make sure the Wanchain transaction type can be signed by the device
+	if err := checkTxType(tx); err != nil {
+		return common.Address{}, nil, err
+	}
 	// Create the transaction initiation message
 	data := tx.Data()
 	length := uint32(len(data))
-
-    txType := uint32(tx.Txtype())
+	txType := uint32(tx.Txtype())
 
 	request := &trezor.EthereumSignTx{
 		AddressN:   derivationPath,
@@ -222,13 +225,13 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	var signer types.Signer
 	if chainID == nil {
 		signer = new(types.HomesteadSigner)
+		signature[64] = signature[64] - 27
 	} else {
 		signer = types.NewEIP155Signer(chainID)
 		signature[64] = signature[64] - byte(chainID.Uint64()*2+35)
 	}
 	// Inject the final signature into the transaction and sanity check the sender
 	signed, err := tx.WithSignature(signer, signature)
-
 	if err != nil {
 		return common.Address{}, nil, err
 	}
@@ -0,0 +1,164 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/wanchain/go-wanchain/accounts/usbwallet/internal/trezor"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// trezorMock emulates the Wanchain firmware of a Trezor wallet behind the HID
+// transport, signing transactions with a fixed key.
+type trezorMock struct {
+	hidMock
+	key     *ecdsa.PrivateKey
+	kind    uint16                 // Type of the message being reassembled
+	message []byte                 // Message being reassembled from the written chunks
+	request *trezor.EthereumSignTx // Signing request being served
+	data    []byte                 // Transaction data streamed so far
+}
+
+func newTrezorMock(key *ecdsa.PrivateKey) *trezorMock {
+	m := &trezorMock{key: key}
+	m.write = m.receive
+	return m
+}
+
+// receive reassembles a message from the transport chunks written by the driver.
+func (m *trezorMock) receive(chunk []byte) error {
+	if len(chunk) != 64 || chunk[0] != 0x3f {
+		return errors.New("invalid chunk header")
+	}
+	payload := chunk[1:]
+	if m.message == nil {
+		if chunk[1] != 0x23 || chunk[2] != 0x23 {
+			return errors.New("invalid message header")
+		}
+		m.kind = binary.BigEndian.Uint16(chunk[3:5])
+		m.message = make([]byte, 0, binary.BigEndian.Uint32(chunk[5:9]))
+		payload = chunk[9:]
+	}
+	if left := cap(m.message) - len(m.message); left > len(payload) {
+		m.message = append(m.message, payload...)
+		return nil
+	}
+	m.message = append(m.message, payload[:cap(m.message)-len(m.message)]...)
+
+	reply, err := m.exchange(m.kind, m.message)
+	m.message = nil
+	if err != nil {
+		return err
+	}
+	return m.send(reply)
+}
+
+// exchange handles a message of the Ethereum signing flow, requesting the data
+// chunks and a button confirmation before returning the signature.
+func (m *trezorMock) exchange(kind uint16, message []byte) (proto.Message, error) {
+	switch trezor.MessageType(kind) {
+	case trezor.MessageType_MessageType_EthereumSignTx:
+		m.request = new(trezor.EthereumSignTx)
+		if err := proto.Unmarshal(message, m.request); err != nil {
+			return nil, err
+		}
+		m.data = m.request.DataInitialChunk
+
+	case trezor.MessageType_MessageType_EthereumTxAck:
+		ack := new(trezor.EthereumTxAck)
+		if err := proto.Unmarshal(message, ack); err != nil {
+			return nil, err
+		}
+		m.data = append(m.data, ack.DataChunk...)
+
+	case trezor.MessageType_MessageType_ButtonAck:
+		return m.sign()
+
+	default:
+		return nil, fmt.Errorf("unexpected message %s", trezor.Name(kind))
+	}
+	if left := m.request.GetDataLength() - uint32(len(m.data)); left > 0 {
+		if left > 1024 {
+			left = 1024
+		}
+		return &trezor.EthereumTxRequest{DataLength: &left}, nil
+	}
+	return &trezor.ButtonRequest{}, nil
+}
+
+// sign signs the fields of the streamed transaction like the firmware does.
+func (m *trezorMock) sign() (proto.Message, error) {
+	req := m.request
+	fields := []interface{}{req.GetTxType(), req.Nonce, req.GasPrice, req.GasLimit, req.To, req.Value, m.data}
+
+	var chainID *big.Int
+	if req.ChainId != nil {
+		chainID = new(big.Int).SetUint64(uint64(req.GetChainId()))
+		fields = append(fields, chainID, uint(0), uint(0))
+	}
+	txrlp, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		return nil, err
+	}
+	v, r, s, err := deviceSignature(m.key, crypto.Keccak256(txrlp), chainID)
+	if err != nil {
+		return nil, err
+	}
+	sigV := uint32(v)
+	return &trezor.EthereumTxRequest{SignatureV: &sigV, SignatureR: r, SignatureS: s}, nil
+}
+
+// send streams a reply message to the driver in 64 byte transport chunks.
+func (m *trezorMock) send(msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	payload := make([]byte, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], trezor.Type(msg))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	for len(payload) > 0 {
+		chunk := make([]byte, 64)
+		chunk[0] = 0x3f
+		payload = payload[copy(chunk[1:], payload):]
+		m.reply.Write(chunk)
+	}
+	return nil
+}
+
+// Tests that the Trezor driver sends the Wanchain transaction type along with
+// the fields, so that the device signs the hash of the Wanchain signers.
+func TestTrezorSignTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	driver := &trezorDriver{
+		device: newTrezorMock(key),
+		log:    log.New(),
+	}
+	testDriverSignTx(t, driver, key)
+}
@@ -20,6 +20,7 @@ package usbwallet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -41,6 +42,10 @@ const heartbeatCycle = time.Second
 // requesting accounts like crazy.
 const selfDeriveThrottling = time.Second
 
+// errTxTypeUnsupported is returned when a transaction is to be signed whose type
+// the hardware wallets can't handle, e.g. a privacy transaction spending an OTA.
+var errTxTypeUnsupported = errors.New("usbwallet: only normal and PoS transactions can be signed")
+
 // driver defines the vendor specific functionality hardware wallets instances
 // must implement to allow using them with the wallet lifecycle management.
 type driver interface {
@@ -571,3 +576,26 @@ func (w *wallet) GetWanAddress(account accounts.Account) (common.WAddress, error
 func (w *wallet) ComputeOTAPPKeys(account accounts.Account, AX, AY, BX, BY string) ([]string, error) {
 	return nil, nil
 }
+
+// checkTxType ensures the hardware wallets are able to sign the given Wanchain
+// transaction type.
+func checkTxType(tx *types.Transaction) error {
+	if !types.IsNormalTransaction(tx.Txtype()) && !types.IsPosTransaction(tx.Txtype()) {
+		return errTxTypeUnsupported
+	}
+	return nil
+}
+
+// signingFields returns the fields of a Wanchain transaction hashed for signing,
+// Txtype included, in the order used by the Homestead signer or, if a chain ID
+// is given, by the EIP155 signer.
+func signingFields(tx *types.Transaction, chainID *big.Int) ([]interface{}, error) {
+	if err := checkTxType(tx); err != nil {
+		return nil, err
+	}
+	fields := []interface{}{tx.Txtype(), tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+	if chainID != nil {
+		fields = append(fields, chainID, uint(0), uint(0))
+	}
+	return fields, nil
+}
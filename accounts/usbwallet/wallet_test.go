@@ -0,0 +1,117 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// hidMock is the HID transport of a mocked device. Every chunk written by a
+// driver is handed to the device emulation, whose replies are read back.
+type hidMock struct {
+	write func(chunk []byte) error
+	reply bytes.Buffer
+}
+
+func (m *hidMock) Write(chunk []byte) (int, error) {
+	if err := m.write(chunk); err != nil {
+		return 0, err
+	}
+	return len(chunk), nil
+}
+
+func (m *hidMock) Read(chunk []byte) (int, error) {
+	return m.reply.Read(chunk)
+}
+
+// signingTest is a transaction signed by a mocked device with a chain ID, or
+// in Homestead mode if it's nil.
+type signingTest struct {
+	tx      *types.Transaction
+	chainID *big.Int
+}
+
+func signingTests() []signingTest {
+	to := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	newTx := func(txtype uint64, data []byte) *types.Transaction {
+		tx := types.NewTransaction(7, to, big.NewInt(1e18), big.NewInt(200000), big.NewInt(180000000000), data)
+		tx.SetTxtype(txtype)
+		return tx
+	}
+	long := bytes.Repeat([]byte{0xa5}, 2500)
+
+	return []signingTest{
+		{newTx(types.NORMAL_TX, nil), nil},
+		{newTx(types.NORMAL_TX, nil), big.NewInt(1)},
+		{newTx(types.NORMAL_TX, long), big.NewInt(3)},
+		{newTx(types.POS_TX, []byte{1, 2, 3}), nil},
+		{newTx(types.POS_TX, []byte{1, 2, 3}), big.NewInt(3)},
+		{newTx(types.POS_TX, long), big.NewInt(99)},
+		{types.NewContractCreation(0, new(big.Int), big.NewInt(1000000), big.NewInt(180000000000), long), big.NewInt(1)},
+	}
+}
+
+// deviceSignature signs a hash the way the Wanchain apps of the hardware
+// wallets do, returning V as an EIP155 value if a chain ID is given.
+func deviceSignature(key *ecdsa.PrivateKey, hash []byte, chainID *big.Int) (v byte, r, s []byte, err error) {
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	v = sig[64] + 27
+	if chainID != nil {
+		v = sig[64] + byte(chainID.Uint64()*2+35)
+	}
+	return v, sig[:32], sig[32:64], nil
+}
+
+// testDriverSignTx signs all the signing tests with a driver talking to a
+// mocked device and checks the signatures against the Wanchain signers.
+func testDriverSignTx(t *testing.T, d driver, key *ecdsa.PrivateKey) {
+	want := crypto.PubkeyToAddress(key.PublicKey)
+	for i, tt := range signingTests() {
+		sender, signed, err := d.SignTx(nil, tt.tx, tt.chainID)
+		if err != nil {
+			t.Errorf("test %d: signing failed: %v", i, err)
+			continue
+		}
+		if sender != want {
+			t.Errorf("test %d: sender mismatch: have %x, want %x", i, sender, want)
+		}
+		var signer types.Signer = types.HomesteadSigner{}
+		if tt.chainID != nil {
+			signer = types.NewEIP155Signer(tt.chainID)
+		}
+		if from, err := types.Sender(signer, signed); err != nil || from != want {
+			t.Errorf("test %d: recovered sender mismatch: have %x, %v", i, from, err)
+		}
+		if signed.Txtype() != tt.tx.Txtype() {
+			t.Errorf("test %d: transaction type changed to %d", i, signed.Txtype())
+		}
+	}
+	privacy := types.NewOTATransaction(0, common.Address{}, new(big.Int), big.NewInt(300000), big.NewInt(180000000000), nil)
+	if _, _, err := d.SignTx(nil, privacy, big.NewInt(1)); err != errTxTypeUnsupported {
+		t.Errorf("privacy transaction signing error mismatch: have %v, want %v", err, errTxTypeUnsupported)
+	}
+}
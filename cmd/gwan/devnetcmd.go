@@ -0,0 +1,345 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// Copyright 2016 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	kbn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/params"
+	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	devnetValidatorsFlag = cli.IntFlag{
+		Name:  "validators",
+		Value: 4,
+		Usage: "Number of validator nodes",
+	}
+	devnetSeedFlag = cli.StringFlag{
+		Name:  "seed",
+		Value: "wanchain-devnet",
+		Usage: "Seed the validator and node keys are derived from",
+	}
+	devnetOutFlag = cli.StringFlag{
+		Name:  "out",
+		Value: "devnet",
+		Usage: "Directory the genesis and the node data directories are written to",
+	}
+	devnetPasswordFlag = cli.StringFlag{
+		Name:  "password",
+		Value: "wanchain",
+		Usage: "Password of the validator keystores",
+	}
+	devnetChainIdFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Value: 1000,
+		Usage: "Chain id and network id of the devnet",
+	}
+	devnetPortFlag = cli.IntFlag{
+		Name:  "port",
+		Value: 17717,
+		Usage: "P2P port of the first node, the next nodes use the following ports",
+	}
+	devnetRPCPortFlag = cli.IntFlag{
+		Name:  "rpcport",
+		Value: 8545,
+		Usage: "HTTP-RPC port of the first node, the next nodes use the following ports",
+	}
+	devnetStakeFlag = cli.StringFlag{
+		Name:  "stake",
+		Value: "100000",
+		Usage: "Amount of wan pre-staked by each validator",
+	}
+	devnetBalanceFlag = cli.StringFlag{
+		Name:  "balance",
+		Value: "1000000",
+		Usage: "Amount of wan allocated to each validator account",
+	}
+	devnetSlotTimeFlag = cli.Uint64Flag{
+		Name:  "slot-time",
+		Value: params.DefaultPosConfig.SlotTime,
+		Usage: "Time span of a slot in seconds",
+	}
+	devnetKFlag = cli.Uint64Flag{
+		Name:  "k",
+		Value: params.DefaultPosConfig.K,
+		Usage: "Slot count of an epoch stage",
+	}
+	devnetTimestampFlag = cli.Uint64Flag{
+		Name:  "timestamp",
+		Value: 0x59f83144,
+		Usage: "Timestamp of the genesis block",
+	}
+
+	devnetCommand = cli.Command{
+		Action:    utils.MigrateFlags(makeDevnet),
+		Name:      "devnet",
+		Usage:     "Generate a local PoS network",
+		ArgsUsage: "",
+		Category:  "POS COMMANDS",
+		Flags: []cli.Flag{
+			devnetValidatorsFlag,
+			devnetSeedFlag,
+			devnetOutFlag,
+			devnetPasswordFlag,
+			devnetChainIdFlag,
+			devnetPortFlag,
+			devnetRPCPortFlag,
+			devnetStakeFlag,
+			devnetBalanceFlag,
+			devnetSlotTimeFlag,
+			devnetKFlag,
+			devnetTimestampFlag,
+		},
+		Description: `
+    gwan devnet --validators 4 --out devnet
+
+generates the keys of 4 validators, a Pluto genesis pre-staking them and a data
+directory for each node, then prints how to start the nodes.
+
+The keys are derived from --seed, so the same flags always give the same
+network. The genesis block is sealed by the first validator, which is also the
+genesis PK, and all the validators make up the white list of epoch leaders.
+Each node directory has the keystore of its validator, a node key, the
+static peers of the other nodes, the initialized chain and a config.toml:
+
+    gwan --config devnet/node0/config.toml --unlock <address> --password devnet/password.txt --mine`,
+	}
+)
+
+// devnetValidator is a validator of a generated devnet.
+type devnetValidator struct {
+	key     *ecdsa.PrivateKey
+	otaKey  *ecdsa.PrivateKey
+	bn256   *kbn256.PrivateKeyBn256
+	nodeKey *ecdsa.PrivateKey
+	address common.Address
+	enode   string
+}
+
+// devnetSecret derives the secret of kind for validator i from seed.
+func devnetSecret(seed, kind string, i int) []byte {
+	return crypto.Keccak256([]byte(fmt.Sprintf("%s/%s/%d", seed, kind, i)))
+}
+
+func newDevnetValidator(seed string, i int, port int) (*devnetValidator, error) {
+	key, err := crypto.ToECDSA(devnetSecret(seed, "secp256k1", i))
+	if err != nil {
+		return nil, err
+	}
+	otaKey, err := crypto.ToECDSA(devnetSecret(seed, "ota", i))
+	if err != nil {
+		return nil, err
+	}
+	nodeKey, err := crypto.ToECDSA(devnetSecret(seed, "p2p", i))
+	if err != nil {
+		return nil, err
+	}
+	d := new(big.Int).SetBytes(devnetSecret(seed, "bn256", i))
+	d.Mod(d, bn256.Order)
+	if d.Sign() == 0 {
+		return nil, errors.New("invalid bn256 key")
+	}
+
+	node := discover.NewNode(discover.PubkeyID(&nodeKey.PublicKey), net.ParseIP("127.0.0.1"), uint16(port), uint16(port))
+	return &devnetValidator{
+		key:     key,
+		otaKey:  otaKey,
+		bn256:   &kbn256.PrivateKeyBn256{PublicKeyBn256: kbn256.PublicKeyBn256{G1: new(bn256.G1).ScalarBaseMult(d)}, D: d},
+		nodeKey: nodeKey,
+		address: crypto.PubkeyToAddress(key.PublicKey),
+		enode:   node.String(),
+	}, nil
+}
+
+// makeDevnetGenesis makes the Pluto genesis of the validators, the first of
+// which seals the genesis block.
+func makeDevnetGenesis(ctx *cli.Context, validators []*devnetValidator) (*core.Genesis, error) {
+	stake, err := parseWan(ctx.String(devnetStakeFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+	balance, err := parseWan(ctx.String(devnetBalanceFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	stakers := make([]core.PosValidator, len(validators))
+	for i, v := range validators {
+		stakers[i] = core.PosValidator{
+			PublicKey: &v.key.PublicKey,
+			Bn256PK:   v.bn256.G1.Marshal(),
+			Balance:   balance,
+			Stake:     stake,
+		}
+	}
+	genesis := core.PosGenesisBlock(new(big.Int).SetUint64(ctx.Uint64(devnetChainIdFlag.Name)),
+		ctx.Uint64(devnetSlotTimeFlag.Name), ctx.Uint64(devnetKFlag.Name), ctx.Uint64(devnetTimestampFlag.Name), stakers)
+	if err := genesis.CheckPosConfig(); err != nil {
+		return nil, err
+	}
+	return genesis, nil
+}
+
+func makeDevnet(ctx *cli.Context) error {
+	n := ctx.Int(devnetValidatorsFlag.Name)
+	if n < 1 {
+		return errors.New("at least one validator is required")
+	}
+	out, err := filepath.Abs(ctx.String(devnetOutFlag.Name))
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("%s already exists", out)
+	}
+
+	port, rpcPort := ctx.Int(devnetPortFlag.Name), ctx.Int(devnetRPCPortFlag.Name)
+	validators := make([]*devnetValidator, n)
+	for i := range validators {
+		if validators[i], err = newDevnetValidator(ctx.String(devnetSeedFlag.Name), i, port+i); err != nil {
+			return err
+		}
+	}
+	genesis, err := makeDevnetGenesis(ctx, validators)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(out, 0700); err != nil {
+		return err
+	}
+	if err := writeJSONFile(filepath.Join(out, "genesis.json"), genesis); err != nil {
+		return err
+	}
+	password := ctx.String(devnetPasswordFlag.Name)
+	passwordFile := filepath.Join(out, "password.txt")
+	if err := ioutil.WriteFile(passwordFile, []byte(password+"\n"), 0600); err != nil {
+		return err
+	}
+
+	for i, v := range validators {
+		dir := filepath.Join(out, fmt.Sprintf("node%d", i))
+		if err := makeDevnetNode(dir, genesis, validators, i, password, port+i, rpcPort+i); err != nil {
+			return err
+		}
+		log.Info("Generated devnet node", "dir", dir, "validator", v.address.Hex(), "enode", v.enode)
+	}
+
+	fmt.Printf("Generated a PoS devnet of %d validators in %s, start the nodes with:\n\n", n, out)
+	for i, v := range validators {
+		fmt.Printf("gwan --config %s --unlock %s --password %s --mine\n",
+			filepath.Join(out, fmt.Sprintf("node%d", i), "config.toml"), v.address.Hex(), passwordFile)
+	}
+	return nil
+}
+
+// makeDevnetNode writes the data directory of validator i: its keystore, node
+// key and static peers, the chain initialized with genesis and config.toml.
+func makeDevnetNode(dir string, genesis *core.Genesis, validators []*devnetValidator, i int, password string, port, rpcPort int) error {
+	v := validators[i]
+	instance := filepath.Join(dir, clientIdentifier)
+	if err := os.MkdirAll(instance, 0700); err != nil {
+		return err
+	}
+
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.LightScryptN, keystore.LightScryptP)
+	if _, err := ks.ImportECDSA(v.key, v.otaKey, v.bn256, password); err != nil {
+		return err
+	}
+	if err := crypto.SaveECDSA(filepath.Join(instance, "nodekey"), v.nodeKey); err != nil {
+		return err
+	}
+	peers := make([]string, 0, len(validators)-1)
+	peerNodes := make([]*discover.Node, 0, len(validators)-1)
+	for j, peer := range validators {
+		if j != i {
+			node, err := discover.ParseNode(peer.enode)
+			if err != nil {
+				return err
+			}
+			peers = append(peers, peer.enode)
+			peerNodes = append(peerNodes, node)
+		}
+	}
+	if err := writeJSONFile(filepath.Join(instance, "static-nodes.json"), peers); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"chaindata", "lightchaindata"} {
+		db, err := ethdb.NewLDBDatabase(filepath.Join(instance, name), 0, 0)
+		if err != nil {
+			return err
+		}
+		_, _, err = core.SetupGenesisBlock(db, genesis)
+		db.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	cfg := gethConfig{
+		Eth:  eth.DefaultConfig,
+		Shh:  whisper.DefaultConfig,
+		Node: defaultNodeConfig(),
+	}
+	cfg.Eth.NetworkId = genesis.Config.ChainId.Uint64()
+	cfg.Eth.Etherbase = v.address
+	cfg.Node.DataDir = dir
+	cfg.Node.KeyStoreDir = filepath.Join(dir, "keystore")
+	cfg.Node.UseLightweightKDF = true
+	cfg.Node.P2P.ListenAddr = fmt.Sprintf(":%d", port)
+	cfg.Node.P2P.NoDiscovery = true
+	// the static nodes of the config file take the place of static-nodes.json
+	cfg.Node.P2P.StaticNodes = peerNodes
+	cfg.Node.HTTPHost = "127.0.0.1"
+	cfg.Node.HTTPPort = rpcPort
+	enc, err := tomlSettings.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "config.toml"), enc, 0600)
+}
+
+func writeJSONFile(file string, v interface{}) error {
+	enc, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(enc, '\n'), 0600)
+}
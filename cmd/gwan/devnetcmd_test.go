@@ -0,0 +1,84 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// Tests that gwan devnet writes a genesis the nodes start from and a
+// config.toml per node that loads back.
+func TestDevnet(t *testing.T) {
+	dir := tmpdir(t)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "devnet")
+
+	gwan := runGeth(t, "devnet", "--validators", "2", "--out", out)
+	gwan.WaitExit()
+
+	data, err := ioutil.ReadFile(filepath.Join(out, "genesis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := new(core.Genesis)
+	if err := json.Unmarshal(data, genesis); err != nil {
+		t.Fatalf("invalid genesis.json: %v", err)
+	}
+	if err := genesis.CheckPosConfig(); err != nil {
+		t.Fatalf("invalid PoS config: %v", err)
+	}
+	whiteList := genesis.Config.Pos.WhiteList
+	if len(whiteList) != 2 {
+		t.Fatalf("white list length mismatch: have %d, want 2", len(whiteList))
+	}
+	// the node sets the PoS parameters of the genesis at start
+	posconfig.SetChainParams(genesis.Config.Pos)
+	defer posconfig.SetChainParams(nil)
+
+	block, _ := genesis.ToBlock()
+	for i, pk := range whiteList {
+		address := crypto.PubkeyToAddress(*crypto.ToECDSAPub(hexutil.MustDecode(pk)))
+		if _, ok := genesis.Alloc[address]; !ok {
+			t.Errorf("validator %d %x not in the genesis alloc", i, address)
+		}
+
+		node := filepath.Join(out, fmt.Sprintf("node%d", i))
+		var cfg gethConfig
+		if err := loadConfig(filepath.Join(node, "config.toml"), &cfg); err != nil {
+			t.Fatalf("node %d: invalid config.toml: %v", i, err)
+		}
+		if cfg.Eth.NetworkId != genesis.Config.ChainId.Uint64() {
+			t.Errorf("node %d: network id mismatch: have %d, want %d", i, cfg.Eth.NetworkId, genesis.Config.ChainId)
+		}
+		if cfg.Eth.Etherbase != address {
+			t.Errorf("node %d: etherbase mismatch: have %x, want %x", i, cfg.Eth.Etherbase, address)
+		}
+		if cfg.Node.DataDir != node {
+			t.Errorf("node %d: datadir mismatch: have %s, want %s", i, cfg.Node.DataDir, node)
+		}
+		if len(cfg.Node.P2P.StaticNodes) != 1 {
+			t.Errorf("node %d: static nodes mismatch: have %d, want 1", i, len(cfg.Node.P2P.StaticNodes))
+		}
+
+		db, err := ethdb.NewLDBDatabase(filepath.Join(node, clientIdentifier, "chaindata"), 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := core.GetCanonicalHash(db, 0)
+		db.Close()
+		if hash != block.Hash() {
+			t.Errorf("node %d: genesis hash mismatch: have %x, want %x", i, hash, block.Hash())
+		}
+	}
+}
@@ -116,6 +116,7 @@ var (
 		utils.RPCCORSDomainFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.ChainQualityWebhookFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -173,6 +174,12 @@ func init() {
 		accountCommand,
 		walletCommand,
 		transactionCommand,
+		// See poscmd.go:
+		posCommand,
+		// See stakingcmd.go:
+		stakingCommand,
+		// See devnetcmd.go:
+		devnetCommand,
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
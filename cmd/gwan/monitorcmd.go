@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"reflect"
@@ -27,7 +28,10 @@ import (
 
 	"github.com/gizak/termui"
 	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/ethclient/posclient"
 	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/pos/posapi"
 	"github.com/wanchain/go-wanchain/rpc"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -48,6 +52,15 @@ var (
 		Value: 3,
 		Usage: "Refresh interval in seconds",
 	}
+	monitorCommandValidatorFlag = cli.StringFlag{
+		Name:  "validator",
+		Usage: "Show the PoS duties of a validator instead of metrics",
+	}
+	monitorCommandEpochsFlag = cli.Uint64Flag{
+		Name:  "epochs",
+		Value: 2,
+		Usage: "Number of recent epochs to check the validator duties of",
+	}
 	monitorCommand = cli.Command{
 		Action:    utils.MigrateFlags(monitor), // keep track of migration progress
 		Name:      "monitor",
@@ -58,11 +71,17 @@ var (
 The Geth monitor is a tool to collect and visualize various internal metrics
 gathered by the node, supporting different chart types as well as the capacity
 to display multiple metrics simultaneously.
+
+With --validator the monitor shows the slot leader, epoch leader (stage1,
+stage2) and random proposer (dkg1, dkg2, sigshare) duties of a validator in the
+recent epochs instead, and lists every missed duty with its reason.
 `,
 		Flags: []cli.Flag{
 			monitorCommandAttachFlag,
 			monitorCommandRowsFlag,
 			monitorCommandRefreshFlag,
+			monitorCommandValidatorFlag,
+			monitorCommandEpochsFlag,
 		},
 	}
 )
@@ -80,6 +99,9 @@ func monitor(ctx *cli.Context) error {
 	}
 	defer client.Close()
 
+	if ctx.IsSet(monitorCommandValidatorFlag.Name) {
+		return monitorValidator(ctx, client)
+	}
 	// Retrieve all the available metrics and resolve the user pattens
 	metrics, err := retrieveMetrics(client)
 	if err != nil {
@@ -349,3 +371,112 @@ func updateFooter(ctx *cli.Context, err error, footer *termui.Par) {
 		footer.TextFgColor = termui.ColorRed | termui.AttrBold
 	}
 }
+
+// monitorValidator starts a terminal UI showing the PoS duties of a validator
+// in the recent epochs and the ones it missed.
+func monitorValidator(ctx *cli.Context, client *rpc.Client) error {
+	hex := ctx.String(monitorCommandValidatorFlag.Name)
+	if !common.IsHexAddress(hex) {
+		utils.Fatalf("Invalid validator address %q", hex)
+	}
+	addr := common.HexToAddress(hex)
+	epochs := ctx.Uint64(monitorCommandEpochsFlag.Name)
+	if epochs == 0 {
+		utils.Fatalf("At least one epoch must be monitored")
+	}
+	pc := posclient.NewClient(client)
+
+	if err := termui.Init(); err != nil {
+		utils.Fatalf("Unable to initialize terminal UI: %v", err)
+	}
+	defer termui.Close()
+
+	summary := termui.NewPar("")
+	summary.Height = 8
+	summary.BorderLabel = "Validator " + addr.Hex()
+
+	missed := termui.NewList()
+	missed.BorderLabel = "Missed duties"
+
+	footer := termui.NewPar("")
+	footer.Block.Border = true
+	footer.Height = 3
+
+	resize := func() {
+		missed.Height = termui.TermHeight() - summary.Height - footer.Height
+		termui.Body.Width = termui.TermWidth()
+		termui.Body.Align()
+		termui.Render(termui.Body)
+	}
+	termui.Body.AddRows(
+		termui.NewRow(termui.NewCol(12, 0, summary)),
+		termui.NewRow(termui.NewCol(12, 0, missed)),
+		termui.NewRow(termui.NewCol(12, 0, footer)),
+	)
+	refreshValidator(ctx, pc, addr, epochs, summary, missed, footer)
+	resize()
+
+	termui.Handle("/sys/kbd/C-c", func(termui.Event) {
+		termui.StopLoop()
+	})
+	termui.Handle("/sys/wnd/resize", func(termui.Event) {
+		resize()
+	})
+	go func() {
+		tick := time.NewTicker(time.Duration(ctx.Int(monitorCommandRefreshFlag.Name)) * time.Second)
+		for range tick.C {
+			refreshValidator(ctx, pc, addr, epochs, summary, missed, footer)
+			termui.Render(termui.Body)
+		}
+	}()
+	termui.Loop()
+	return nil
+}
+
+// refreshValidator retrieves the validator report of the recent epochs and
+// updates the summary and the list of missed duties, most recent first.
+func refreshValidator(ctx *cli.Context, pc *posclient.Client, addr common.Address, epochs uint64, summary *termui.Par, missed *termui.List, footer *termui.Par) {
+	report, err := retrieveValidatorReport(pc, addr, epochs)
+	updateFooter(ctx, err, footer)
+	if err != nil {
+		return
+	}
+	summary.Text = fmt.Sprintf("Epochs %d-%d\n"+
+		"Slot leader:     %d/%d blocks produced\n"+
+		"Epoch leader:    %d seats, %d stage1 and %d stage2 sent\n"+
+		"Random proposer: %d seats, %d dkg1, %d dkg2 and %d sigshare sent\n",
+		report.FromEpoch, report.ToEpoch,
+		report.SlotsProduced, report.SlotsAssigned,
+		report.EpochLeaderSeats, report.Stage1Sent, report.Stage2Sent,
+		report.RandomProposerSeats, report.Dkg1Sent, report.Dkg2Sent, report.SigShareSent)
+	if len(report.Unknown) > 0 {
+		summary.Text += fmt.Sprintf("Leaders unknown in epochs %v\n", report.Unknown)
+	}
+	summary.TextFgColor = termui.ColorGreen
+	if len(report.Missed) > 0 {
+		summary.TextFgColor = termui.ColorRed
+	}
+
+	items := make([]string, len(report.Missed))
+	for i, duty := range report.Missed {
+		items[len(items)-1-i] = fmt.Sprintf("epoch %d %-8s %-5d %s", duty.EpochID, duty.Duty, duty.Index, duty.Reason)
+	}
+	missed.Items = items
+}
+
+// retrieveValidatorReport retrieves the report of the validator duties in the
+// last epochs up to the current one.
+func retrieveValidatorReport(pc *posclient.Client, addr common.Address, epochs uint64) (*posapi.ValidatorReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	current, err := pc.EpochID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from := uint64(0)
+	if current >= epochs {
+		from = current - epochs + 1
+	}
+	return pc.ValidatorReport(ctx, addr, from, current)
+}
@@ -0,0 +1,573 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// Copyright 2016 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	posFromEpochFlag = cli.Uint64Flag{
+		Name:  "from-epoch",
+		Usage: "First epoch to export",
+	}
+	posToEpochFlag = cli.Uint64Flag{
+		Name:  "to-epoch",
+		Usage: "Last epoch to export (default = epoch of the current block)",
+	}
+	posFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Value: "json",
+		Usage: "Output format (csv|json)",
+	}
+	posOutputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write to (default = stdout)",
+	}
+	posAmountFlag = cli.Uint64Flag{
+		Name:  "amount",
+		Usage: "Amount of wan to stake",
+	}
+	posLockEpochsFlag = cli.Uint64Flag{
+		Name:  "lock-epochs",
+		Value: 7,
+		Usage: "Epochs the stake of a new validator is locked for",
+	}
+	posFeeRateFlag = cli.Uint64Flag{
+		Name:  "fee-rate",
+		Usage: "Fee rate in percent a new validator charges its delegators",
+	}
+	posValidatorFlag = cli.StringFlag{
+		Name:  "validator",
+		Usage: "Validator to delegate to (default = simulate a new validator)",
+	}
+
+	posBlockFlag = cli.Uint64Flag{
+		Name:  "block",
+		Usage: "Last valid block the blocks after the halt are concatenated to (default = current block)",
+	}
+	posStartFlag = cli.Uint64Flag{
+		Name:  "start",
+		Usage: "Unix time the chain restarts at, rounded up to an epoch start (default = now)",
+	}
+
+	posEpochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "Epoch whose incentive is verified",
+	}
+	posRebuildFlag = cli.BoolFlag{
+		Name:  "rebuild",
+		Usage: "Replace the local incentive history of the epoch if it differs from the chain",
+	}
+
+	posCommand = cli.Command{
+		Name:      "pos",
+		Usage:     "Inspect the PoS state of the local chain",
+		ArgsUsage: "",
+		Category:  "POS COMMANDS",
+		Description: `
+    gwan --datadir ./data pos export-stakers --from-epoch 18000 --to-epoch 18010 --format csv --output stakers.csv
+
+will export the staker set of every epoch in the range from the local chain.
+
+    gwan --datadir ./data pos rebuild-db
+
+will derive the PoS local data of the node from the local chain again.
+
+    gwan --datadir ./data pos simulate --amount 10000 --validator 0x...
+
+will estimate the incentive per epoch of delegating 10000 wan to a validator.
+
+    gwan --datadir ./data pos recover --block 120000 --start 1546300800
+
+will restart a halted chain from block 120000 at the first epoch start after
+the given time.
+
+    gwan --datadir ./data pos verify-incentive --epoch 18000 --rebuild
+
+will check the incentive paid for epoch 18000 against the chain and repair the
+local incentive history of the epoch.`,
+		Subcommands: []cli.Command{
+			{
+				Name:     "export-stakers",
+				Usage:    "Export the staker, delegator and partner snapshot of every epoch",
+				Action:   utils.MigrateFlags(exportStakers),
+				Category: "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.PlutoFlag,
+					utils.PlutoDevFlag,
+					posFromEpochFlag,
+					posToEpochFlag,
+					posFormatFlag,
+					posOutputFlag,
+				},
+				Description: `
+The snapshot of an epoch is the staker set found in the state of the last block
+of that epoch. Every staker is written together with its delegators (clients)
+and partners. Epochs without blocks in the local chain are skipped.`,
+			},
+			{
+				Name:     "rebuild-db",
+				Usage:    "Rebuild the PoS local data from the chain",
+				Action:   utils.MigrateFlags(rebuildPosDb),
+				Category: "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.PlutoFlag,
+					utils.PlutoDevFlag,
+				},
+				Description: `
+The epoch leaders, random proposers, incentive history and epoch genesis kept
+by the node are derived from the chain. This command deletes them and derives
+them again from the local chain, e.g. after a deep reorg or when upgrading from
+a version without the reward index of pos_getDelegatorIncentive and
+pos_getValidatorIncentive. The dbs of versions storing them outside of the
+chain database are moved into it when the node starts.`,
+			},
+			{
+				Name:     "simulate",
+				Usage:    "Estimate the incentive per epoch of a stake",
+				Action:   utils.MigrateFlags(simulateReward),
+				Category: "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.PlutoFlag,
+					utils.PlutoDevFlag,
+					posAmountFlag,
+					posLockEpochsFlag,
+					posFeeRateFlag,
+					posValidatorFlag,
+				},
+				Description: `
+The stake is added to the stakers of the current block and is expected to be
+selected as epoch leader, random proposer and slot leader in proportion to its
+weight. The incentive is divided as the one of the last complete epoch would
+be. The result is the same as the one of the pos_simulateReward RPC method.`,
+			},
+			{
+				Name:     "recover",
+				Usage:    "Restart a halted chain by concatenating blocks across epochs",
+				Action:   utils.MigrateFlags(recoverChain),
+				Category: "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.PlutoFlag,
+					utils.PlutoDevFlag,
+					posBlockFlag,
+					posStartFlag,
+				},
+				Description: `
+A chain halted for longer than the security window cannot go on at the epoch
+of the current time, the leaders of that epoch were never selected. The chain
+is rewound to the last valid block agreed by the operators, and the epoch
+after the one of that block starts at the first epoch start after --start.
+The epochs in between are skipped (jumpEpochs): the next blocks are
+concatenated to the last valid block as if no time had passed.
+
+Every validator node is stopped, runs this command with the same --block and
+--start, and is started again. Other nodes follow the restarted chain without
+it.`,
+			},
+			{
+				Name:     "verify-incentive",
+				Usage:    "Verify the incentive paid for an epoch against the chain",
+				Action:   utils.MigrateFlags(verifyIncentive),
+				Category: "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.TestnetFlag,
+					utils.PlutoFlag,
+					utils.PlutoDevFlag,
+					posEpochFlag,
+					posRebuildFlag,
+				},
+				Description: `
+The incentive of the epoch is allocated again from the state of the block that
+paid it: the activity of the epoch, the incentive pool and the delegation
+splits. The block is replayed with it and the balances of the receivers are
+compared with the ones the block left, as well as with the payments recorded
+in the local incentive history, the data of pos_getEpochIncentivePayDetail.
+The command fails if the chain or the local history differ. The leader
+selections of the epochs involved are read from the local PoS data and never
+written, the command fails if they are missing: run 'pos rebuild-db' first.
+
+With --rebuild, a local history that differs from an incentive matching the
+chain is replaced by the recomputed one.`,
+			},
+		},
+	}
+)
+
+// stakerSnapshot is the staker set of an epoch as exported by export-stakers.
+type stakerSnapshot struct {
+	EpochID     uint64
+	BlockNumber uint64
+	Stakers     []*posapi.StakerJson
+}
+
+// stakerExporter writes staker snapshots in one of the supported formats.
+type stakerExporter interface {
+	Write(snap *stakerSnapshot) error
+	Close() error
+}
+
+func rebuildPosDb(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	head := chain.CurrentBlock().Header()
+	headEpoch, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty)
+	start := time.Now()
+
+	epocher := epochLeader.NewEpocher(chain)
+	if err := epocher.RebuildLocalDb(headEpoch + 1); err != nil {
+		return fmt.Errorf("leader selection failed: %v", err)
+	}
+	inc := incentive.New(epocher.GetEpochProbability, epocher.SetEpochIncentive, epocher.GetRBProposerGroup,
+		epocher.GetEpochLeaders, chain.PosDbs().Get(posconfig.IncentiveLocalDB))
+	if err := inc.Rebuild(chain, headEpoch); err != nil {
+		return fmt.Errorf("incentive rebuild failed: %v", err)
+	}
+	if err := chain.ClearEpochGenesis(); err != nil {
+		return err
+	}
+	log.Info("Rebuilt PoS local data", "epochs", headEpoch+1, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// setEpochBaseTime sets the start time of the PoS epochs from the first PoS
+// block of chain, as a running node does.
+func setEpochBaseTime(chain *core.BlockChain) {
+	if posconfig.EpochBaseTime == 0 {
+		if h := chain.GetHeaderByNumber(1); h != nil {
+			posconfig.EpochBaseTime = h.Time.Uint64()
+		}
+	}
+}
+
+func recoverChain(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	if ctx.IsSet(posBlockFlag.Name) {
+		number := ctx.Uint64(posBlockFlag.Name)
+		if head := chain.CurrentBlock().NumberU64(); number > head {
+			return fmt.Errorf("block %d is after the current block %d", number, head)
+		}
+		if err := chain.SetHead(number); err != nil {
+			return err
+		}
+	}
+	setEpochBaseTime(chain)
+	chain.SetRbSelector(epochLeader.NewEpocher(chain))
+
+	start := uint64(time.Now().Unix())
+	if ctx.IsSet(posStartFlag.Name) {
+		start = ctx.Uint64(posStartFlag.Name)
+	}
+	jump, err := chain.StartRecovery(start)
+	if err != nil {
+		return err
+	}
+	log.Info("Started chain halt recovery", "block", chain.CurrentBlock().NumberU64(), "epochID", jump.Epoch,
+		"jumpEpochs", jump.Jump, "start", time.Unix(int64(jump.StartTime()), 0))
+	return nil
+}
+
+func verifyIncentive(ctx *cli.Context) error {
+	if !ctx.IsSet(posEpochFlag.Name) {
+		return errors.New("--epoch is required")
+	}
+	epochID := ctx.Uint64(posEpochFlag.Name)
+
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	// the protocol transactions of the block are replayed at their slot
+	setEpochBaseTime(chain)
+	epocher := epochLeader.NewEpocher(chain)
+	chain.SetRbSelector(epocher)
+	inc := incentive.New(epocher.GetEpochProbability, epocher.SetEpochIncentive, epocher.GetRBProposerGroup,
+		epocher.GetEpochLeaders, chain.PosDbs().Get(posconfig.IncentiveLocalDB))
+
+	audit, err := posapi.VerifyIncentive(inc, epocher, epochID, ctx.Bool(posRebuildFlag.Name))
+	if audit != nil {
+		enc, err := json.MarshalIndent(audit, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(enc))
+	}
+	switch {
+	case err != nil:
+		return err
+	case !audit.Matches():
+		return fmt.Errorf("the incentive of epoch %d differs from the chain", epochID)
+	case !audit.LocalHistoryMatches && !audit.Rebuilt:
+		return fmt.Errorf("the local incentive history of epoch %d differs from the chain, run with --rebuild to replace it", epochID)
+	}
+	return nil
+}
+
+func simulateReward(ctx *cli.Context) error {
+	var validator *common.Address
+	if hex := ctx.String(posValidatorFlag.Name); hex != "" {
+		if !common.IsHexAddress(hex) {
+			return fmt.Errorf("invalid validator address %q", hex)
+		}
+		addr := common.HexToAddress(hex)
+		validator = &addr
+	}
+	amount := new(big.Int).SetUint64(ctx.Uint64(posAmountFlag.Name))
+	amount.Mul(amount, big.NewInt(params.Wan))
+
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	res, err := posapi.SimulateReward(chain, amount, ctx.Uint64(posLockEpochsFlag.Name), ctx.Uint64(posFeeRateFlag.Name), validator)
+	if err != nil {
+		return err
+	}
+	enc, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(enc))
+	return nil
+}
+
+func exportStakers(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	head := chain.CurrentBlock().Header()
+	headEpoch, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty)
+
+	from := ctx.Uint64(posFromEpochFlag.Name)
+	to := headEpoch
+	if ctx.IsSet(posToEpochFlag.Name) {
+		to = ctx.Uint64(posToEpochFlag.Name)
+	}
+	if from > to {
+		return fmt.Errorf("from-epoch %d is after to-epoch %d", from, to)
+	}
+
+	out := io.Writer(os.Stdout)
+	if path := ctx.String(posOutputFlag.Name); path != "" {
+		fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		out = fh
+	}
+	exporter, err := newStakerExporter(ctx.String(posFormatFlag.Name), out)
+	if err != nil {
+		return err
+	}
+
+	if err := writeStakerSnapshots(chain, from, to, exporter); err != nil {
+		return err
+	}
+	return exporter.Close()
+}
+
+// writeStakerSnapshots writes the staker set of every epoch from from to to
+// held by the local chain. The epochs whose state the node pruned are skipped
+// with a warning, they are listed once all the others are written.
+func writeStakerSnapshots(chain *core.BlockChain, from, to uint64, exporter stakerExporter) error {
+	var pruned []uint64
+	for epochID := from; epochID <= to; epochID++ {
+		header := posapi.EpochLastHeader(chain, epochID)
+		if header == nil {
+			continue
+		}
+		stateDb, err := chain.StateAt(header.Root)
+		if err != nil {
+			log.Warn("Skipping epoch with unavailable state", "epoch", epochID, "block", header.Number, "err", err)
+			pruned = append(pruned, epochID)
+			continue
+		}
+		snap := &stakerSnapshot{
+			EpochID:     epochID,
+			BlockNumber: header.Number.Uint64(),
+			Stakers:     make([]*posapi.StakerJson, 0),
+		}
+		for _, staker := range sortStakers(vm.GetStakersSnap(stateDb)) {
+			snap.Stakers = append(snap.Stakers, posapi.ToStakerJson(staker))
+		}
+		if err := exporter.Write(snap); err != nil {
+			return err
+		}
+	}
+	if len(pruned) > 0 {
+		log.Warn("Epochs not exported, their state is pruned", "count", len(pruned), "epochs", pruned)
+	}
+	return nil
+}
+
+func sortStakers(stakers []vm.StakerInfo) []*vm.StakerInfo {
+	sorted := make([]*vm.StakerInfo, len(stakers))
+	for i := range stakers {
+		sorted[i] = &stakers[i]
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Address[:], sorted[j].Address[:]) < 0
+	})
+	return sorted
+}
+
+func newStakerExporter(format string, w io.Writer) (stakerExporter, error) {
+	switch format {
+	case "json":
+		return &jsonStakerExporter{w: w}, nil
+	case "csv":
+		return newCsvStakerExporter(w)
+	default:
+		return nil, errors.New("unsupported format " + format)
+	}
+}
+
+// jsonStakerExporter streams the snapshots as a single JSON array.
+type jsonStakerExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonStakerExporter) Write(snap *stakerSnapshot) error {
+	enc, err := json.MarshalIndent(snap, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if e.count == 0 {
+		sep = "[\n  "
+	}
+	e.count++
+	_, err = fmt.Fprint(e.w, sep, string(enc))
+	return err
+}
+
+func (e *jsonStakerExporter) Close() error {
+	if e.count == 0 {
+		_, err := fmt.Fprintln(e.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(e.w, "\n]")
+	return err
+}
+
+// csvStakerExporter writes one row per staker, delegator and partner. The
+// staker column links delegators and partners to the staker they belong to.
+type csvStakerExporter struct {
+	w *csv.Writer
+}
+
+var csvStakerHeader = []string{
+	"epoch", "block", "role", "staker", "address", "amount", "stakeAmount",
+	"lockEpochs", "nextLockEpochs", "stakingEpoch", "feeRate", "quitEpoch", "renewal", "from",
+}
+
+func newCsvStakerExporter(w io.Writer) (*csvStakerExporter, error) {
+	e := &csvStakerExporter{w: csv.NewWriter(w)}
+	if err := e.w.Write(csvStakerHeader); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *csvStakerExporter) Write(snap *stakerSnapshot) error {
+	epoch := strconv.FormatUint(snap.EpochID, 10)
+	block := strconv.FormatUint(snap.BlockNumber, 10)
+	for _, s := range snap.Stakers {
+		staker := s.Address.Hex()
+		rows := [][]string{{
+			epoch, block, "staker", staker, staker, decimal(s.Amount), decimal(s.StakeAmount),
+			strconv.FormatUint(s.LockEpochs, 10), strconv.FormatUint(s.NextLockEpochs, 10),
+			strconv.FormatUint(s.StakingEpoch, 10), strconv.FormatUint(s.FeeRate, 10), "", "", s.From.Hex(),
+		}}
+		for _, c := range s.Clients {
+			rows = append(rows, []string{
+				epoch, block, "delegator", staker, c.Address.Hex(), decimal(c.Amount), decimal(c.StakeAmount),
+				"", "", "", "", strconv.FormatUint(c.QuitEpoch, 10), "", "",
+			})
+		}
+		for _, p := range s.Partners {
+			rows = append(rows, []string{
+				epoch, block, "partner", staker, p.Address.Hex(), decimal(p.Amount), decimal(p.StakeAmount),
+				strconv.FormatUint(p.LockEpochs, 10), "", strconv.FormatUint(p.StakingEpoch, 10), "", "",
+				strconv.FormatBool(p.Renewal), "",
+			})
+		}
+		if err := e.w.WriteAll(rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvStakerExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func decimal(v *math.HexOrDecimal256) string {
+	if v == nil {
+		return "0"
+	}
+	return (*big.Int)(v).String()
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+)
+
+// Tests that export-stakers writes the staker set of every epoch of the range
+// held by the chain, and skips the epochs whose state is pruned.
+func TestExportStakers(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	pk := crypto.FromECDSAPub(&key.PublicKey)
+	staker := crypto.PubkeyToAddress(key.PublicKey)
+	var (
+		db, _  = ethdb.NewMemDatabase()
+		engine = ethash.NewFullFaker(db)
+		gspec  = &core.Genesis{
+			Config:     params.PlutoChainConfig,
+			GasLimit:   0x47b760,
+			Difficulty: big.NewInt(1),
+			Alloc: core.GenesisAlloc{staker: {
+				Balance: big.NewInt(1000000000000000000),
+				Staking: core.GenesisAccountStaking{Amount: big.NewInt(100), S256pk: pk, Bn256pk: []byte{1}},
+			}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	chain, err := core.NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two blocks in each of epochs 1, 2 and 4, each with a transfer so that
+	// every block has a state of its own
+	epochs := []uint64{1, 1, 2, 2, 4, 4}
+	env := core.NewChainEnv(gspec.Config, gspec, engine, chain, db)
+	blocks, _ := env.GenerateChain(genesis, len(epochs), func(i int, block *core.BlockGen) {
+		block.SetDifficulty(new(big.Int).SetUint64(epochs[i]<<32 | uint64(i)<<8))
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(staker), common.Address{1}, big.NewInt(1), big.NewInt(21000), nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	chain.Stop()
+
+	// the state of the last block of epoch 2 is pruned
+	if err := db.Delete(blocks[3].Root().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if chain, err = core.NewBlockChain(db, gspec.Config, engine, vm.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	var out bytes.Buffer
+	exporter, _ := newStakerExporter("json", &out)
+	if err := writeStakerSnapshots(chain, 0, 5, exporter); err != nil {
+		t.Fatal(err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var snaps []stakerSnapshot
+	if err := json.Unmarshal(out.Bytes(), &snaps); err != nil {
+		t.Fatalf("invalid export %s: %v", out.Bytes(), err)
+	}
+	want := []stakerSnapshot{{EpochID: 1, BlockNumber: 2}, {EpochID: 4, BlockNumber: 6}}
+	if len(snaps) != len(want) {
+		t.Fatalf("got %d snapshots, want %d: %s", len(snaps), len(want), out.Bytes())
+	}
+	for i, snap := range snaps {
+		if snap.EpochID != want[i].EpochID || snap.BlockNumber != want[i].BlockNumber {
+			t.Errorf("snapshot %d: got epoch %d block %d, want epoch %d block %d",
+				i, snap.EpochID, snap.BlockNumber, want[i].EpochID, want[i].BlockNumber)
+		}
+		if len(snap.Stakers) != 1 || snap.Stakers[0].Address != staker || (*big.Int)(snap.Stakers[0].Amount).Int64() != 100 {
+			t.Errorf("snapshot %d: got stakers %+v", i, snap.Stakers)
+		}
+	}
+
+	// the genesis block belongs to no epoch
+	if header := posapi.EpochLastHeader(chain, 0); header != nil {
+		t.Errorf("got block %d as the last of epoch 0", header.Number)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Wanchain Foundation Ltd
+// Copyright 2016 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/cmd/utils"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/staking"
+	"github.com/wanchain/go-wanchain/rlp"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	stakingSecPkFlag = cli.StringFlag{
+		Name:  "secpk",
+		Usage: "Public key of the validator (stakeIn)",
+	}
+	stakingBn256PkFlag = cli.StringFlag{
+		Name:  "bn256pk",
+		Usage: "Bn256 public key of the validator (stakeIn)",
+	}
+	stakingLockEpochsFlag = cli.Uint64Flag{
+		Name:  "lock-epochs",
+		Usage: "Epochs the stake is locked for (stakeIn, stakeUpdate)",
+	}
+	stakingFeeRateFlag = cli.Uint64Flag{
+		Name:  "fee-rate",
+		Usage: "Percentage of the delegators' incentive kept by the validator (stakeIn)",
+	}
+	stakingValidatorFlag = cli.StringFlag{
+		Name:  "validator",
+		Usage: "Address of the validator (stakeAppend, stakeUpdate, delegateIn, delegateOut)",
+	}
+	stakingValueFlag = cli.StringFlag{
+		Name:  "value",
+		Value: "0",
+		Usage: "Amount of wan sent with the operation",
+	}
+	stakingNonceFlag = cli.Uint64Flag{
+		Name:  "nonce",
+		Usage: "Nonce of the sending account",
+	}
+	stakingGasFlag = cli.Uint64Flag{
+		Name:  "gas",
+		Value: 200000,
+		Usage: "Gas limit of the transaction",
+	}
+	stakingGasPriceFlag = cli.Uint64Flag{
+		Name:  "gasprice",
+		Value: 180000000000,
+		Usage: "Gas price of the transaction in wei",
+	}
+	stakingChainIdFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Value: params.MainnetChainConfig.ChainId.Uint64(),
+		Usage: "Chain id the transaction is signed for",
+	}
+	stakingFromFlag = cli.StringFlag{
+		Name:  "from",
+		Usage: "Keystore account to sign the transaction with (default = unsigned)",
+	}
+
+	stakingCommand = cli.Command{
+		Name:      "staking",
+		Usage:     "Build PoS staking transactions offline",
+		ArgsUsage: "",
+		Category:  "POS COMMANDS",
+		Description: `
+    gwan staking build stakeIn --secpk 0x04... --bn256pk 0x... --lock-epochs 30 --fee-rate 10 --value 50000 --nonce 0 --from 0x...
+
+will build a transaction making a new validator and sign it with a keystore account.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "build",
+				Usage:     "Build a raw staking transaction",
+				ArgsUsage: "<stakeIn|stakeAppend|stakeUpdate|delegateIn|delegateOut>",
+				Action:    utils.MigrateFlags(buildStakingTx),
+				Category:  "POS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					stakingSecPkFlag,
+					stakingBn256PkFlag,
+					stakingLockEpochsFlag,
+					stakingFeeRateFlag,
+					stakingValidatorFlag,
+					stakingValueFlag,
+					stakingNonceFlag,
+					stakingGasFlag,
+					stakingGasPriceFlag,
+					stakingChainIdFlag,
+					stakingFromFlag,
+				},
+				Description: `
+The call of the staking operation is encoded and checked with the rules of the
+staking contract without connecting to a node. The raw transaction is printed
+in hex and can be sent from a networked machine with eth.sendRawTransaction.
+Without --from the transaction is left unsigned, otherwise it is signed with
+the given account of the local keystore.`,
+			},
+		},
+	}
+)
+
+func buildStakingTx(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		return errors.New("exactly one staking operation is required")
+	}
+	value, err := parseWan(ctx.String(stakingValueFlag.Name))
+	if err != nil {
+		return err
+	}
+	args := &staking.Args{
+		Op:         ctx.Args().First(),
+		LockEpochs: ctx.Uint64(stakingLockEpochsFlag.Name),
+		FeeRate:    ctx.Uint64(stakingFeeRateFlag.Name),
+		Value:      value,
+	}
+	if args.Op == staking.OpStakeIn {
+		if args.SecPk, err = hexutil.Decode(ctx.String(stakingSecPkFlag.Name)); err != nil {
+			return fmt.Errorf("invalid secpk: %v", err)
+		}
+		if args.Bn256Pk, err = hexutil.Decode(ctx.String(stakingBn256PkFlag.Name)); err != nil {
+			return fmt.Errorf("invalid bn256pk: %v", err)
+		}
+	} else {
+		hex := ctx.String(stakingValidatorFlag.Name)
+		if !common.IsHexAddress(hex) {
+			return fmt.Errorf("invalid validator address %q", hex)
+		}
+		args.Validator = common.HexToAddress(hex)
+	}
+
+	gas := new(big.Int).SetUint64(ctx.Uint64(stakingGasFlag.Name))
+	gasPrice := new(big.Int).SetUint64(ctx.Uint64(stakingGasPriceFlag.Name))
+	tx, err := staking.NewTransaction(args, ctx.Uint64(stakingNonceFlag.Name), gas, gasPrice)
+	if err != nil {
+		return err
+	}
+
+	if from := ctx.String(stakingFromFlag.Name); from != "" {
+		stack, _ := makeConfigNode(ctx)
+		ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+		account, passphrase := unlockAccount(ctx, ks, from, 0, utils.MakePasswordList(ctx))
+		chainID := new(big.Int).SetUint64(ctx.Uint64(stakingChainIdFlag.Name))
+		if tx, err = ks.SignTxWithPassphrase(account, passphrase, tx, chainID); err != nil {
+			return err
+		}
+	}
+	raw, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(hexutil.Encode(raw))
+	return nil
+}
+
+// parseWan converts a decimal amount of wan to wei.
+func parseWan(s string) (*big.Int, error) {
+	amount, ok := new(big.Rat).SetString(s)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	amount.Mul(amount, new(big.Rat).SetInt64(params.Wan))
+	if !amount.IsInt() {
+		return nil, fmt.Errorf("amount %q is not a whole number of wei", s)
+	}
+	return amount.Num(), nil
+}
@@ -540,6 +540,10 @@ var (
 		Usage: "syslog tag",
 		Value: "gwan_pos",
 	}
+	ChainQualityWebhookFlag = cli.StringFlag{
+		Name:  "chainquality.webhook",
+		Usage: "Comma separated URLs the PoS chain quality alerts are posted to",
+	}
 
 
 )
@@ -1042,6 +1046,10 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(ChainQualityWebhookFlag.Name) {
+		cfg.ChainQualityWebhooks = strings.Split(ctx.GlobalString(ChainQualityWebhookFlag.Name), ",")
+	}
+
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
@@ -1184,6 +1192,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if err != nil {
 		Fatalf("%v", err)
 	}
+	if config.Pluto != nil {
+		posconfig.SetChainParams(config.Pos)
+	}
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
@@ -49,7 +49,6 @@ import (
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
-	posUtil "github.com/wanchain/go-wanchain/pos/util"
 )
 
 const (
@@ -73,9 +72,8 @@ var (
 
 	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
 
-	diffInTurn      = big.NewInt(2) // Block difficulty for in-turn signatures
-	diffNoTurn      = big.NewInt(1) // Block difficulty for out-of-turn signatures
-	lastEpochSlotId = uint64(0)
+	diffInTurn = big.NewInt(2) // Block difficulty for in-turn signatures
+	diffNoTurn = big.NewInt(1) // Block difficulty for out-of-turn signatures
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -141,6 +139,10 @@ var (
 	// on an instant chain (0 second period). It's important to refuse these as the
 	// block reward is zero, so an empty block just bloats the chain... fast.
 	errWaitTransactions = errors.New("waiting for transactions")
+
+	// errNoSlotLeaderSelection is returned if the slot leaders are needed before
+	// the slot leader selection of the node is set.
+	errNoSlotLeaderSelection = errors.New("slot leader selection not set")
 )
 
 // SignerFn is a signer callback function to request a hash to be signed by a
@@ -228,6 +230,11 @@ type Pluto struct {
 	lock   sync.RWMutex   // Protects the signer fields
 
 	key *keystore.Key // Unlocked key
+
+	sls       *slotleader.SLS      // Slot leader selection of the node
+	incentive *incentive.Incentive // Incentive of the node
+
+	lastEpochSlotId uint64 // Epoch and slot of the last sealed block
 }
 
 // New creates a Pluto proof-of-authority consensus engine with the initial
@@ -500,7 +507,10 @@ func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []
 
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
 
-	s := slotleader.GetSlotLeaderSelection()
+	s, err := c.slotLeaderSelection()
+	if err != nil {
+		return err
+	}
 
 	proof, proofMeg, err := s.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
 
@@ -533,17 +543,17 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 		return errUnknownBlock
 	}
 
-	epidTime, slIdTime := posUtil.CalEpochSlotID(header.Time.Uint64())
+	if err := verifyEpochJump(chain, header, parents); err != nil {
+		return err
+	}
 
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
 
-	if epidTime != epochID || slIdTime != slotID {
-		log.Error("epochid or slotid do not match", "epidTime=",epidTime,"slIdTime=",slIdTime,"epidFromDiffulty=",epochID,"slotIDFromDifficulty=",slotID)
-		return errors.New("epochid or slotid do not match")
+	s, err := c.slotLeaderSelection()
+	if err != nil {
+		return err
 	}
 
-	s := slotleader.GetSlotLeaderSelection()
-
 	if len(header.Extra) == extraSeal {
 		log.Warn("Header extra info length is too short")
 		return errUnauthorized
@@ -680,15 +690,16 @@ func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, minin
 	//if header.Time.Int64() < time.Now().Unix() {
 	//	header.Time = big.NewInt(time.Now().Unix())
 	//}
-	curEpochId, curSlotId := util.GetEpochSlotID()
+	curEpochId, curSlotId := chainEpochJumps(chain).CurEpochSlotID()
 
 	if posconfig.EpochBaseTime == 0 {
 		cur := time.Now().Unix()
-		hcur := cur - (cur % posconfig.SlotTime) + posconfig.SlotTime
+		slotTime := int64(posconfig.SlotTime)
+		hcur := cur - (cur % slotTime) + slotTime
 		header.Time = big.NewInt(hcur)
 	} else {
 		if curEpochId != 0 || curSlotId != 0 {
-			header.Time = big.NewInt(int64(posconfig.EpochBaseTime + (curEpochId*posconfig.SlotCount+curSlotId)*posconfig.SlotTime))
+			header.Time = big.NewInt(int64(chainEpochJumps(chain).EpochStartTime(curEpochId) + curSlotId*posconfig.SlotTime))
 		}
 	}
 
@@ -707,8 +718,12 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
 	if epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
 		log.Debug("--------Incentive Start--------", "number", header.Number.String(), "epochID", epochID)
+		c.lock.RLock()
+		inc := c.incentive
+		c.lock.RUnlock()
+
 		snap := state.Snapshot()
-		if !incentive.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs) {
+		if inc == nil || !inc.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs) {
 			log.SyslogErr("********Incentive Failed********", "number", header.Number.String(), "epochID", epochID)
 			state.RevertToSnapshot(snap)
 		} else {
@@ -731,6 +746,34 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 	return types.NewBlock(header, txs, nil, receipts), nil
 }
 
+// SetSlotLeaderSelection sets the slot leader selection the slot leaders of
+// the blocks are verified and sealed with.
+func (c *Pluto) SetSlotLeaderSelection(sls *slotleader.SLS) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sls = sls
+}
+
+// slotLeaderSelection returns the slot leader selection of the node.
+func (c *Pluto) slotLeaderSelection() (*slotleader.SLS, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.sls == nil {
+		return nil, errNoSlotLeaderSelection
+	}
+	return c.sls, nil
+}
+
+// SetIncentive sets the incentive the epochs are paid with in Finalize.
+func (c *Pluto) SetIncentive(inc *incentive.Incentive) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.incentive = inc
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (c *Pluto) Authorize(signer common.Address, signFn SignerFn, key *keystore.Key) {
@@ -770,15 +813,20 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	// 	return nil, errUnauthorized
 	// }
 	// check if our trun
-	epochSlotId := uint64(1)
-	epochId, slotId := util.GetEpochSlotID()
-	epochSlotId += slotId << 8
-	epochSlotId += epochId << 32
-	if epochSlotId <= lastEpochSlotId {
+	epochSlotId := header.Difficulty.Uint64()
+	epochId, slotId := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	c.lock.RLock()
+	sealed := epochSlotId <= c.lastEpochSlotId
+	c.lock.RUnlock()
+	if sealed {
 		return nil, nil
 	}
 	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&c.key.PrivateKey.PublicKey))
-	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
+	s, err := c.slotLeaderSelection()
+	if err != nil {
+		return nil, err
+	}
+	leaderPub, err := s.GetSlotLeader(epochId, slotId)
 	if err != nil {
 		return nil, err
 	}
@@ -806,7 +854,6 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	header.Difficulty.SetUint64(epochSlotId)
 	header.Coinbase = signer
 
-	s := slotleader.GetSlotLeaderSelection()
 	buf, err := s.PackSlotProof(epochId, slotId, key.PrivateKey)
 	if err != nil {
 		log.Warn("PackSlotProof failed in Seal", "epochID", epochId, "slotID", slotId, "error", err.Error())
@@ -829,7 +876,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	log.Debug("sigHash(header)", "Bytes", hex.EncodeToString(sigHash(header).Bytes()))
 	log.Debug("Packed slotleader proof info success", "epochID", epochId, "slotID", slotId, "len", len(header.Extra), "pk", hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey)))
 
-	err = c.verifySeal(nil, header, nil, false)
+	err = c.verifySeal(chain, header, nil, false)
 	if err != nil {
 		log.Warn("Seal error", "error", err.Error())
 		return nil, err
@@ -840,7 +887,9 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 		log.Warn("Seal error", "error", err.Error())
 		return nil, err
 	}
-	lastEpochSlotId = epochSlotId
+	c.lock.Lock()
+	c.lastEpochSlotId = epochSlotId
+	c.lock.Unlock()
 	return block.WithSeal(header), nil
 }
 
@@ -0,0 +1,85 @@
+package pluto
+
+import (
+	"errors"
+
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+var (
+	// errInvalidEpochJump is returned if a block is concatenated to a parent of
+	// an epoch other than the one before it, or jumps back.
+	errInvalidEpochJump = errors.New("invalid epoch jump")
+)
+
+// blockJump returns the epochs the time grid is ahead of the epoch of header,
+// they are the epochs skipped by the chain halt recoveries before it.
+func blockJump(header *types.Header) (util.EpochJump, error) {
+	j, err := util.HeaderEpochJump(header)
+	if err != nil {
+		epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		gridEpochID, gridSlotID := util.CalEpochSlotID(header.Time.Uint64())
+		log.Error("epochid or slotid do not match", "gridEpochID", gridEpochID, "gridSlotID", gridSlotID,
+			"epidFromDiffulty", epochID, "slotIDFromDifficulty", slotID)
+	}
+	return j, err
+}
+
+// chainEpochJumps returns the epoch jumps of the chain, nil if it does not keep
+// them.
+func chainEpochJumps(chain consensus.ChainReader) *util.EpochJumps {
+	if c, ok := chain.(interface{ EpochJumps() *util.EpochJumps }); ok {
+		return c.EpochJumps()
+	}
+	return nil
+}
+
+// verifyEpochJump checks the epoch and slot of header against its time. A block
+// skips the same epochs as its parent, or it is the first block after a chain
+// halt: its epoch follows the one of the parent, and at least one whole epoch
+// of the time grid, longer than the security window, passed without blocks.
+// The blocks after the halt are then concatenated across the skipped epochs.
+// The jump is only checked here, the chain records it once the block is
+// accepted as canonical.
+func verifyEpochJump(chain consensus.ChainReader, header *types.Header, parents []*types.Header) error {
+	j, err := blockJump(header)
+	if err != nil {
+		return err
+	}
+
+	number := header.Number.Uint64()
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	// the first block sets the epoch base time, nothing is skipped before it
+	if parent.Number.Uint64() == 0 {
+		if j.Jump != 0 {
+			return errInvalidEpochJump
+		}
+		return nil
+	}
+	parentJump, err := blockJump(parent)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case j.Jump == parentJump.Jump:
+		return nil
+	case j.Jump < parentJump.Jump:
+		return errInvalidEpochJump
+	}
+	if j.Epoch != parentJump.Epoch+1 {
+		return errInvalidEpochJump
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package pluto
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// setTestTimeGrid sets a grid of epochs of 10 slots of 2 seconds from 1000,
+// the returned function restores the previous one.
+func setTestTimeGrid() func() {
+	base, slotTime, slotCount := posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount
+	posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = 1000, 2, 10
+	return func() {
+		posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = base, slotTime, slotCount
+	}
+}
+
+// newJumpHeader returns the header of the slot of epochID after parent, timed
+// the way Prepare does with the jumps of the chain.
+func newJumpHeader(jumps *util.EpochJumps, parent *types.Header, epochID, slotID uint64) *types.Header {
+	return &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+		Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1),
+		Time:       new(big.Int).SetUint64(jumps.EpochStartTime(epochID) + slotID*posconfig.SlotTime),
+	}
+}
+
+// produce extends chain by one block per slot up to the given slot.
+func produce(jumps *util.EpochJumps, chain []*types.Header, toEpochID, toSlotID uint64) []*types.Header {
+	for {
+		parent := chain[len(chain)-1]
+		epochID, slotID := util.GetEpochSlotIDFromDifficulty(parent.Difficulty)
+		if parent.Number.Uint64() > 0 {
+			if epochID == toEpochID && slotID == toSlotID {
+				return chain
+			}
+			if slotID++; slotID == posconfig.SlotCount {
+				epochID, slotID = epochID+1, 0
+			}
+		}
+		chain = append(chain, newJumpHeader(jumps, parent, epochID, slotID))
+	}
+}
+
+func verifyJumps(t *testing.T, chain []*types.Header) {
+	for i := 1; i < len(chain); i++ {
+		if err := verifyEpochJump(nil, chain[i], chain[:i]); err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+	}
+}
+
+// TestHaltRecovery simulates a chain halted in epoch 2 for longer than an
+// epoch and restarted by the operators, the blocks after the halt are valid
+// for a node not knowing about the recovery.
+func TestHaltRecovery(t *testing.T) {
+	defer setTestTimeGrid()()
+
+	operator := util.NewEpochJumps(nil)
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Time: big.NewInt(900)}
+	chain := produce(operator, []*types.Header{genesis}, 2, 5)
+	verifyJumps(t, chain)
+
+	// the chain halted at slot 5 of epoch 2 and restarts at grid epoch 7
+	if _, err := operator.Add(util.EpochJump{Epoch: 3, Jump: 4}); err != nil {
+		t.Fatal(err)
+	}
+	chain = append(chain, newJumpHeader(operator, chain[len(chain)-1], 3, 0))
+	chain = produce(operator, chain, 4, 3)
+	verifyJumps(t, chain)
+
+	last := chain[len(chain)-1]
+	if epochID, slotID := operator.CalEpochSlotID(last.Time.Uint64()); epochID != 4 || slotID != 3 {
+		t.Errorf("last block at epoch %d slot %d, want 4 3", epochID, slotID)
+	}
+	if gridEpochID, _ := util.CalEpochSlotID(last.Time.Uint64()); gridEpochID != 8 {
+		t.Errorf("last block at grid epoch %d, want 8", gridEpochID)
+	}
+
+	// the first block after the halt carries the jump
+	first := chain[len(chain)-14]
+	if j, err := blockJump(first); err != nil || j != (util.EpochJump{Epoch: 3, Jump: 4, Block: first.Number.Uint64()}) {
+		t.Errorf("first block after the halt: got jump %v %v", j, err)
+	}
+}
+
+func TestInvalidEpochJump(t *testing.T) {
+	defer setTestTimeGrid()()
+
+	jumps := util.NewEpochJumps(nil)
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Time: big.NewInt(900)}
+	chain := produce(jumps, []*types.Header{genesis}, 2, 5)
+	parent := chain[len(chain)-1]
+
+	tests := []struct {
+		name            string
+		epochID, slotID uint64
+		time            uint64
+		err             error
+	}{
+		{"slot not of the time", 2, 7, 1052, util.ErrEpochSlotMismatch},
+		{"epoch after the time", 3, 6, 1052, util.ErrEpochSlotMismatch},
+		{"halt skips the epoch after", 4, 0, 1140, errInvalidEpochJump},
+		{"next epoch", 3, 0, 1060, nil},
+		{"halt of a whole epoch", 3, 0, 1080, nil},
+	}
+	for _, tt := range tests {
+		header := newJumpHeader(jumps, parent, tt.epochID, tt.slotID)
+		header.Time.SetUint64(tt.time)
+		if err := verifyEpochJump(nil, header, chain); err != tt.err {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+	}
+
+	// no jump back once recovered
+	if _, err := jumps.Add(util.EpochJump{Epoch: 3, Jump: 2}); err != nil {
+		t.Fatal(err)
+	}
+	chain = append(chain, newJumpHeader(jumps, parent, 3, 0))
+	chain = produce(jumps, chain, 3, 2)
+	verifyJumps(t, chain)
+	header := newJumpHeader(jumps, chain[len(chain)-1], 3, 3)
+	header.Time.SetUint64(1066)
+	if err := verifyEpochJump(nil, header, chain); err != errInvalidEpochJump {
+		t.Errorf("jump back: got %v, want %v", err, errInvalidEpochJump)
+	}
+}
@@ -28,9 +28,11 @@ import (
 
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/common/mclock"
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core/otaindex"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
@@ -41,6 +43,8 @@ import (
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
 	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/trie"
@@ -119,9 +123,21 @@ type BlockChain struct {
 
 	badBlocks *lru.Cache // Bad block cache
 
-	epochGene *EpochGenesisBlock
+	epochGene   *EpochGenesisBlock
+	posDbs      *posdb.Dbs           // PoS local dbs kept in the chain database
+	epochBlocks *posUtil.EpochBlocks // Last blocks of the epochs of the chain
+	otaIndex    *otaindex.Index      // OTAs of the canonical chain by denomination
+	otaIndexer  *otaindex.Indexer    // Background builder of the OTA index
+	epochJumps  *posUtil.EpochJumps  // Epoch jumps of the halt recoveries of the canonical chain
+	posEvents   *posevent.Feed       // PoS events of the node
 
 	slotValidator Validator
+	incentive     IncentiveNotifier // nil if the chain doesn't pay incentives
+
+	posHeadMu     sync.Mutex // protects the PoS head fields below
+	posHeadPosted bool       // whether a head has been posted as PoS events yet
+	posHeadEpoch  uint64     // epoch of the last head posted as PoS events
+	posHeadSlot   uint64     // slot of the last head posted as PoS events
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -146,6 +162,11 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 		engine:       engine,
 		vmConfig:     vmConfig,
 		badBlocks:    badBlocks,
+		posDbs:       posdb.NewDbs(chainDb),
+		epochBlocks:  posUtil.NewEpochBlocks(),
+		otaIndex:     otaindex.New(chainDb),
+		epochJumps:   posUtil.NewEpochJumps(GetEpochJumps(chainDb)),
+		posEvents:    posevent.NewFeed(),
 	}
 
 	bc.epochGene = NewEpochGenesisBlock(bc)
@@ -162,9 +183,13 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
+	// loading the last state may rewind the chain, and the OTA index with it
+	bc.otaIndexer = otaindex.NewIndexer(bc.otaIndex, bc)
 	if err := bc.loadLastState(); err != nil {
+		bc.otaIndexer.Close()
 		return nil, err
 	}
+	bc.rewindEpochJumps(bc.currentBlock.NumberU64())
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -178,6 +203,9 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 			}
 		}
 	}
+	// Build or catch up the OTA index in the background, mix sets are drawn
+	// from the ota storage until it covers the current block
+	bc.otaIndexer.Update()
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -292,6 +320,10 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	if err := WriteHeadFastBlockHash(bc.chainDb, bc.currentFastBlock.Hash()); err != nil {
 		log.Crit("Failed to reset head fast block", "err", err)
 	}
+	if err := bc.otaIndexer.Rewind(bc.currentBlock.Hash(), bc.currentBlock.NumberU64()); err != nil {
+		log.Warn("Failed to rewind the OTA index", "number", bc.currentBlock.Number(), "err", err)
+	}
+	bc.rewindEpochJumps(bc.currentBlock.NumberU64())
 	return bc.loadLastState()
 }
 
@@ -397,6 +429,28 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache)
 }
 
+// StateBeforeFinalize returns the state of block after its transactions, before
+// the consensus engine finalizes it and pays the incentive.
+func (bc *BlockChain) StateBeforeFinalize(block *types.Block) (*state.StateDB, error) {
+	parent := bc.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block %d unavailable", block.NumberU64())
+	}
+	statedb, err := bc.StateAt(parent.Root())
+	if err != nil {
+		return nil, fmt.Errorf("state of block %d unavailable: %v", parent.NumberU64(), err)
+	}
+	gp := new(GasPool).AddGas(block.GasLimit())
+	usedGas := big.NewInt(0)
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if _, _, err := ApplyTransaction(bc.config, bc, nil, gp, statedb, block.Header(), tx, usedGas, vm.Config{}); err != nil {
+			return nil, fmt.Errorf("transaction %d of block %d failed: %v", i, block.NumberU64(), err)
+		}
+	}
+	return statedb, nil
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -564,6 +618,12 @@ func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	return block
 }
 
+// GetReceipts retrieves the receipts of the transactions of a block from the
+// database by hash and number.
+func (bc *BlockChain) GetReceipts(hash common.Hash, number uint64) types.Receipts {
+	return GetBlockReceipts(bc.chainDb, hash, number)
+}
+
 //func (bc *BlockChain) GetBlockByHashWithBuffer(hash common.Hash) *types.Block {
 //	//this function is used by fether
 //	blk := bc.forkMem.kBufferedBlks[hash]
@@ -628,6 +688,7 @@ func (bc *BlockChain) Stop() {
 	atomic.StoreInt32(&bc.procInterrupt, 1)
 
 	bc.wg.Wait()
+	bc.otaIndexer.Close()
 	log.Info("Blockchain manager stopped")
 }
 
@@ -764,6 +825,9 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		if err := WriteTxLookupEntries(batch, block); err != nil {
 			return i, fmt.Errorf("failed to write lookup metadata: %v", err)
 		}
+		if err := otaindex.WriteImages(batch, block, receipts); err != nil {
+			return i, fmt.Errorf("failed to write key image lookups: %v", err)
+		}
 		stats.processed++
 
 		if batch.ValueSize() >= ethdb.IdealBatchSize {
@@ -806,16 +870,17 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 
 // Count blocks in front of specified block within 2k slots(exclude the specified block!!!).
 // pos block number begin with 1, epoc and slot index begin from 0
-func (bc *BlockChain) getBlocksCountIn2KSlots(block *types.Block) int {
-	epochId, slotId := posUtil.CalEpochSlotID(block.Time().Uint64())
+func (bc *BlockChain) getBlocksCountIn2KSlots(block *types.Block, jumps *posUtil.EpochJumps) int {
+	epochId, slotId := jumps.CalEpochSlotID(block.Time().Uint64())
 	endFlatSlotId := epochId*posconfig.SlotCount + slotId
 
 	if endFlatSlotId == 0 {
 		return 0
 	}
 
-	startFlatSlotId := uint64(0)
-	if endFlatSlotId >= posconfig.SlotSecurityParam {
+	// the slots before the last restart of a halted chain are not counted
+	startFlatSlotId := jumps.RestartEpoch(epochId) * posconfig.SlotCount
+	if endFlatSlotId >= startFlatSlotId+posconfig.SlotSecurityParam {
 		startFlatSlotId = endFlatSlotId - posconfig.SlotSecurityParam
 	}
 
@@ -827,7 +892,7 @@ func (bc *BlockChain) getBlocksCountIn2KSlots(block *types.Block) int {
 			break
 		}
 
-		epochId, slotId = posUtil.CalEpochSlotID(block.Time().Uint64())
+		epochId, slotId = jumps.CalEpochSlotID(block.Time().Uint64())
 		flatSlotId := epochId*posconfig.SlotCount + slotId
 		if flatSlotId < startFlatSlotId || flatSlotId >= endFlatSlotId {
 			break
@@ -843,12 +908,13 @@ func (bc *BlockChain) getBlocksCountIn2KSlots(block *types.Block) int {
 }
 
 func (bc *BlockChain) isWriteBlockSecure(block *types.Block) bool {
-	blocksIn2K := bc.getBlocksCountIn2KSlots(block)
-	epochId, slotId := posUtil.CalEpochSlotID(block.Time().Uint64())
-	//because slot index starts from 0
-	totalSlots := epochId*posconfig.SlotCount + slotId + 1
+	jumps := bc.EpochJumpsAt(block.Header())
+	blocksIn2K := bc.getBlocksCountIn2KSlots(block, jumps)
+	epochId, slotId := jumps.CalEpochSlotID(block.Time().Uint64())
+	//because slot index starts from 0, counted from the last chain restart
+	totalSlots := (epochId-jumps.RestartEpoch(epochId))*posconfig.SlotCount + slotId + 1
 	if totalSlots >= posconfig.SlotSecurityParam {
-		return blocksIn2K > posconfig.K
+		return blocksIn2K > int(posconfig.K)
 	} else if totalSlots >= posconfig.K {
 		return blocksIn2K > (int)(totalSlots-posconfig.K)
 	}
@@ -922,6 +988,9 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 		if err := WriteTxLookupEntries(batch, block); err != nil {
 			return NonStatTy, err
 		}
+		if err := otaindex.WriteImages(batch, block, receipts); err != nil {
+			return NonStatTy, err
+		}
 		// Write hash preimages
 		if err := WritePreimages(bc.chainDb, block.NumberU64(), state.Preimages()); err != nil {
 			return NonStatTy, err
@@ -941,17 +1010,19 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		bc.otaIndexer.Update()
 		// TODO: update epoch ->blockNumber
 		if bc.config.Pluto != nil {
 			if block.NumberU64() == 1 {
 				posconfig.EpochBaseTime = block.Time().Uint64()
 			}
+			bc.recordEpochJump(block.Header())
 
 			//if bc.slotValidator != bc.epochGene {
 			//	bc.epochGene.SelfGenerateEpochGenesis(block)
 			//}
 
-			posUtil.UpdateEpochBlock(block)
+			bc.epochBlocks.UpdateEpochBlock(block)
 		}
 	}
 
@@ -1306,6 +1377,20 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	}
 
 
+	// drop the OTAs of the old chain from the index, the indexer adds the ones
+	// of the new chain in the background
+	if err := bc.otaIndexer.Rewind(commonBlock.Hash(), commonBlock.NumberU64()); err != nil {
+		log.Warn("Failed to rewind the OTA index", "number", commonBlock.Number(), "err", err)
+	}
+	// the epoch jumps of the old chain are dropped, the ones of the new chain
+	// recorded from its first block
+	bc.rewindEpochJumps(commonBlock.NumberU64())
+	if bc.config.Pluto != nil {
+		for i := len(newChain) - 1; i >= 0; i-- {
+			bc.recordEpochJump(newChain[i].Header())
+		}
+	}
+
 	for _, block := range newChain {
 
 		// insert the block in the canonical way, re-writing history
@@ -1316,10 +1401,27 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		if err := WriteTxLookupEntries(bc.chainDb, block); err != nil {
 			return err
 		}
+		// the receipts of the new head are not written yet, its images are
+		// indexed by the caller
+		if receipts := GetBlockReceipts(bc.chainDb, block.Hash(), block.NumberU64()); receipts != nil {
+			if err := otaindex.WriteImages(bc.chainDb, block, receipts); err != nil {
+				return err
+			}
+		}
 
 		addedTxs = append(addedTxs, block.Transactions()...)
 	}
 
+	// the incentives and stake outs of the new chain below its head, which is
+	// posted by the caller
+	if bc.config.Pluto != nil && len(newChain) > 1 {
+		go func(blocks types.Blocks) {
+			for i := len(blocks) - 1; i >= 0; i-- {
+				bc.postPosBlockEvents(blocks[i])
+			}
+		}(newChain[1:])
+	}
+
 	// calculate the difference between deleted and added transactions
 	diff := types.TxDifference(deletedTxs, addedTxs)
 	// When transactions get deleted from the database that means the
@@ -1354,9 +1456,15 @@ func (bc *BlockChain) PostChainEvents(events []interface{}, logs []*types.Log) {
 		switch ev := event.(type) {
 		case ChainEvent:
 			bc.chainFeed.Send(ev)
+			if bc.config.Pluto != nil {
+				bc.postPosBlockEvents(ev.Block)
+			}
 
 		case ChainHeadEvent:
 			bc.chainHeadFeed.Send(ev)
+			if bc.config.Pluto != nil {
+				bc.postPosHeadEvents(ev.Block)
+			}
 
 		case ChainSideEvent:
 			bc.chainSideFeed.Send(ev)
@@ -1560,6 +1668,37 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 //////////////////////////////////////////////////////////////////
 func (bc *BlockChain) SetRbSelector(rbs RbLeadersSelInt) {
 	bc.epochGene.rbLeaderSelector = rbs
+	bc.epochBlocks.SetSelecter(rbs)
+}
+
+// Epocher returns the epoch leader selection of the chain, nil if it is not
+// set yet.
+func (bc *BlockChain) Epocher() posUtil.SelectLead {
+	if bc == nil || bc.epochGene.rbLeaderSelector == nil {
+		return nil
+	}
+	return bc.epochGene.rbLeaderSelector
+}
+
+// PosDbs returns the PoS local dbs of the chain.
+func (bc *BlockChain) PosDbs() *posdb.Dbs {
+	if bc == nil {
+		return nil
+	}
+	return bc.posDbs
+}
+
+// PosEvents returns the feed of the PoS events of the node.
+func (bc *BlockChain) PosEvents() *posevent.Feed {
+	if bc == nil {
+		return nil
+	}
+	return bc.posEvents
+}
+
+// EpochBlocks returns the last blocks of the epochs of the chain.
+func (bc *BlockChain) EpochBlocks() *posUtil.EpochBlocks {
+	return bc.epochBlocks
 }
 
 func (bc *BlockChain) SetSlSelector(sls SlLeadersSelInt) {
@@ -1570,6 +1709,10 @@ func (bc *BlockChain) GenerateEpochGenesis(epochid uint64) (*types.EpochGenesis,
 	return bc.epochGene.GenerateEpochGenesis(epochid)
 }
 
+func (bc *BlockChain) GenerateEpochGenesisWithin(epochid uint64, budget int) (*types.EpochGenesis, int, error) {
+	return bc.epochGene.GenerateEpochGenesisWithin(epochid, budget)
+}
+
 func (bc *BlockChain) GetBlockEpochIdAndSlotId(blk *types.Block) (uint64, uint64) {
 	blkEpochId, blkSlotId, err := bc.epochGene.GetBlockEpochIdAndSlotId(blk.Header())
 	if err != nil {
@@ -1586,6 +1729,181 @@ func (bc *BlockChain) SetEpochGenesis(epochgen *types.EpochGenesis) error {
 	return bc.epochGene.SetEpochGenesis(epochgen)
 }
 
+func (bc *BlockChain) ClearEpochGenesis() error {
+	return bc.epochGene.ClearEpochGenesis()
+}
+
+// StartRecovery starts the recovery of the halted chain, concatenating the
+// blocks of the next epoch, started at startTime, to the current block.
+func (bc *BlockChain) StartRecovery(startTime uint64) (posUtil.EpochJump, error) {
+	return bc.epochGene.StartRecovery(startTime)
+}
+
+// EpochJumps returns the epoch jumps of the halt recoveries of the canonical
+// chain, and the scheduled one.
+func (bc *BlockChain) EpochJumps() *posUtil.EpochJumps {
+	if bc == nil {
+		return nil
+	}
+	return bc.epochJumps
+}
+
+// EpochJumpsAt returns the epoch jumps of the chain with the one of header if it
+// is the first block after a chain halt, header is not in the chain yet.
+func (bc *BlockChain) EpochJumpsAt(header *types.Header) *posUtil.EpochJumps {
+	if bc == nil {
+		return nil
+	}
+	if bc.config.Pluto == nil || header.Number.Uint64() < 2 {
+		return bc.epochJumps
+	}
+	j, err := posUtil.HeaderEpochJump(header)
+	if err != nil || j.Jump == bc.epochJumps.JumpOfEpoch(j.Epoch) {
+		return bc.epochJumps
+	}
+	return bc.epochJumps.With(j)
+}
+
+// recordEpochJump records the epoch jump of a new canonical block if it is the
+// first block after a chain halt, the jumps of a block are recorded only once
+// it is part of the chain.
+func (bc *BlockChain) recordEpochJump(header *types.Header) {
+	number := header.Number.Uint64()
+	if number < 2 {
+		return
+	}
+	parent := bc.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return
+	}
+	j, err := posUtil.HeaderEpochJump(header)
+	if err != nil {
+		return
+	}
+	if parentJump, err := posUtil.HeaderEpochJump(parent); err != nil || parentJump.Jump == j.Jump {
+		return
+	}
+	if err := bc.addEpochJump(j); err != nil {
+		log.Warn("Failed to record the epoch jump", "number", number, "epochID", j.Epoch, "jumpEpochs", j.Jump, "err", err)
+	}
+}
+
+// addEpochJump records the epoch jump j of a chain halt recovery, and writes
+// the jumps to the chain database if j is a new one.
+func (bc *BlockChain) addEpochJump(j posUtil.EpochJump) error {
+	added, err := bc.epochJumps.Add(j)
+	if err != nil || !added {
+		return err
+	}
+	log.Info("Chain halt recovery, blocks concatenated across epochs", "epochID", j.Epoch,
+		"jumpEpochs", j.Jump, "startTime", j.StartTime(), "number", j.Block)
+	return WriteEpochJumps(bc.chainDb, bc.epochJumps.List())
+}
+
+// rewindEpochJumps drops the epoch jumps of the blocks above number, which are
+// no longer in the chain.
+func (bc *BlockChain) rewindEpochJumps(number uint64) {
+	if !bc.epochJumps.Rewind(number) {
+		return
+	}
+	log.Warn("Dropped the epoch jumps of rewound blocks", "number", number)
+	if err := WriteEpochJumps(bc.chainDb, bc.epochJumps.List()); err != nil {
+		log.Error("Failed to write the epoch jumps", "err", err)
+	}
+}
+
+// OTAIndex returns the OTA index of the canonical chain.
+func (bc *BlockChain) OTAIndex() *otaindex.Index {
+	return bc.otaIndex
+}
+
+// postPosHeadEvents posts the epoch and slot notifications of a new chain head.
+// Heads going back to an earlier slot, as after a reorg, are not posted.
+func (bc *BlockChain) postPosHeadEvents(block *types.Block) {
+	bc.posHeadMu.Lock()
+	defer bc.posHeadMu.Unlock()
+
+	epochID, slotID := posUtil.GetEpochSlotIDFromDifficulty(block.Difficulty())
+	if bc.posHeadPosted {
+		if epochID < bc.posHeadEpoch || (epochID == bc.posHeadEpoch && slotID <= bc.posHeadSlot) {
+			return
+		}
+	}
+	if !bc.posHeadPosted || epochID != bc.posHeadEpoch {
+		bc.posEvents.Post(posevent.NewEpochEvent{EpochID: epochID, BlockNumber: block.NumberU64(), BlockHash: block.Hash()})
+	}
+	bc.posEvents.Post(posevent.NewSlotEvent{EpochID: epochID, SlotID: slotID, BlockNumber: block.NumberU64(), BlockHash: block.Hash()})
+	bc.posHeadPosted, bc.posHeadEpoch, bc.posHeadSlot = true, epochID, slotID
+}
+
+// postPosBlockEvents posts the random number, the incentive paid and the stakes
+// returned by a new canonical block: the block the random number of the next
+// epoch first shows up in, and the first block of its epoch running the
+// incentive and the stake out. They are only read from the states of that
+// block and its parent.
+func (bc *BlockChain) postPosBlockEvents(block *types.Block) {
+	if block.NumberU64() == 0 {
+		return
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return
+	}
+	pre, err := bc.StateAt(parent.Root)
+	if err != nil {
+		return
+	}
+	post, err := bc.StateAt(block.Root())
+	if err != nil {
+		return
+	}
+
+	epochID, slotID := posUtil.GetEpochSlotIDFromDifficulty(block.Difficulty())
+	if vm.GetStateR(pre, epochID+1) == nil {
+		if r := vm.GetStateR(post, epochID+1); r != nil {
+			bc.posEvents.Post(posevent.RandomBeaconFinalizedEvent{EpochID: epochID + 1, Random: (*hexutil.Big)(r)})
+		}
+	}
+
+	// the incentive and the stake out run from the same slots as in Finalize
+	if epochID < posconfig.IncentiveDelayEpochs || slotID <= posconfig.IncentiveStartStage {
+		return
+	}
+	if bc.incentive != nil {
+		if ev, ok := bc.incentive.PaidEvent(pre, post, epochID-posconfig.IncentiveDelayEpochs); ok {
+			bc.posEvents.Post(ev)
+		}
+	}
+	if vm.StakeoutIsFinished(pre, epochID) || !vm.StakeoutIsFinished(post, epochID) {
+		return
+	}
+	for _, u := range vm.ReleasedUnbonds(vm.GetStakersSnap(pre), vm.GetStakersSnap(post), epochID) {
+		bc.posEvents.Post(posevent.UnbondReleasedEvent{
+			EpochID:     epochID,
+			BlockNumber: block.NumberU64(),
+			BlockHash:   block.Hash(),
+			Kind:        u.Kind,
+			Validator:   u.Validator,
+			Address:     u.Address,
+			Amount:      (*hexutil.Big)(u.Amount),
+		})
+	}
+}
+
+// IncentiveNotifier gives the notifications of the epoch incentives paid by
+// the blocks of the chain.
+type IncentiveNotifier interface {
+	// PaidEvent returns the notification of the incentive of epochID paid
+	// by a block, from the states before and after it, false if it pays none.
+	PaidEvent(pre, post *state.StateDB, epochID uint64) (posevent.IncentivePaidEvent, bool)
+}
+
+// SetIncentive sets the incentive the payments of the canonical blocks are
+// notified from.
+func (bc *BlockChain) SetIncentive(inc IncentiveNotifier) {
+	bc.incentive = inc
+}
+
 func (bc *BlockChain) GetEpochStartCh() chan uint64 {
 	return bc.epochGene.epochGenesisCh
 }
@@ -32,6 +32,8 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
 )
 
 // newTestBlockChain creates a blockchain without validation.
@@ -192,6 +194,91 @@ func TestLastBlock(t *testing.T) {
 	}
 }
 
+// Tests that every chain owns its PoS local data, so that several nodes can run
+// in one process.
+func TestPosDataPerChain(t *testing.T) {
+	bc1, _ := newTestBlockChain(false)
+	defer bc1.Stop()
+	bc2, _ := newTestBlockChain(false)
+	defer bc2.Stop()
+
+	bc1.PosDbs().Local().Put(1, "key", []byte{1})
+	if val, err := bc2.PosDbs().Local().Get(1, "key"); err == nil {
+		t.Errorf("PoS db shared between chains: %x", val)
+	}
+	bc1.EpochBlocks().SetEpochBlock(1, 10, common.Hash{1})
+	if num := bc2.EpochBlocks().GetEpochBlock(1); num != 0 {
+		t.Errorf("epoch blocks shared between chains: %d", num)
+	}
+	if bc1.Epocher() != nil {
+		t.Errorf("epoch leader selection set without a selector")
+	}
+}
+
+// TestEpochJumpsOfCanonicalChain checks that the epoch jump of the first block
+// after a chain halt is recorded once the block is in the chain, and dropped
+// when the chain is rewound below it.
+func TestEpochJumpsOfCanonicalChain(t *testing.T) {
+	base, slotTime, slotCount := posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount
+	posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = 1000, 2, 10
+	defer func() {
+		posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = base, slotTime, slotCount
+	}()
+
+	bc, _ := newTestBlockChain(false)
+	defer bc.Stop()
+	config := *bc.config
+	config.Pluto = &params.PlutoConfig{}
+	bc.config = &config
+
+	// blocks in slots 0 and 1 of epoch 0, halted, and restarted with epoch 1
+	// at grid epoch 4
+	headers := []*types.Header{bc.Genesis().Header()}
+	for _, s := range []struct{ epochID, slotID, gridEpochID uint64 }{{0, 0, 0}, {0, 1, 0}, {1, 0, 4}, {1, 1, 4}} {
+		parent := headers[len(headers)-1]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Difficulty: new(big.Int).SetUint64(s.epochID<<32 | s.slotID<<8 | 1),
+			Time:       new(big.Int).SetUint64(1000 + s.gridEpochID*20 + s.slotID*2),
+		}
+		if err := WriteHeader(bc.chainDb, header); err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header)
+	}
+
+	// the operators scheduled the recovery at another time
+	if err := bc.addEpochJump(posUtil.EpochJump{Epoch: 1, Jump: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if epochID, _ := bc.EpochJumpsAt(headers[3]).CalEpochSlotID(headers[3].Time.Uint64()); epochID != 1 {
+		t.Errorf("block 3 in epoch %d before it is recorded, want 1", epochID)
+	}
+	for _, header := range headers[1:] {
+		bc.recordEpochJump(header)
+	}
+	want := posUtil.EpochJump{Epoch: 1, Jump: 3, Block: 3}
+	if jumps := bc.EpochJumps().List(); len(jumps) != 1 || jumps[0] != want {
+		t.Errorf("got jumps %v, want %v", jumps, want)
+	}
+	if jumps := GetEpochJumps(bc.chainDb); len(jumps) != 1 || jumps[0] != want {
+		t.Errorf("stored jumps %v, want %v", jumps, want)
+	}
+
+	bc.rewindEpochJumps(3)
+	if jumps := bc.EpochJumps().List(); len(jumps) != 1 {
+		t.Errorf("jump dropped with a rewind to its block: %v", jumps)
+	}
+	bc.rewindEpochJumps(2)
+	if jumps := bc.EpochJumps().List(); len(jumps) != 0 {
+		t.Errorf("jumps %v kept after a rewind below their block", jumps)
+	}
+	if jumps := GetEpochJumps(bc.chainDb); len(jumps) != 0 {
+		t.Errorf("jumps %v still stored after a rewind below their block", jumps)
+	}
+}
+
 // Tests that given a starting canonical chain of a given size, it can be extended
 // with various length chains.
 func TestExtendCanonicalHeaders(t *testing.T) { testExtendCanonical(t, false) }
@@ -1072,6 +1159,46 @@ func TestCanonicalBlockRetrieval(t *testing.T) {
 }
 
 
+// Tests that a chain whose head state is missing rewinds on load, the OTA
+// index with it.
+func TestMissingHeadState(t *testing.T) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = DefaultPPOWTestingGenesisBlock()
+		engine  = ethash.NewFaker(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	gspec.Alloc[address] = GenesisAccount{Balance: big.NewInt(1000000000000000000)}
+	genesis := gspec.MustCommit(db)
+	blockchain, err := NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainEnv := NewChainEnv(gspec.Config, gspec, engine, blockchain, db)
+	chain, _ := chainEnv.GenerateChain(genesis, 4, func(i int, gen *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(address), common.Address{1}, big.NewInt(1), big.NewInt(21000), nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatal(err)
+	}
+	blockchain.Stop()
+
+	if err := db.Delete(chain[3].Root().Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	blockchain, err = NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blockchain.Stop()
+	if head := blockchain.CurrentBlock(); head.Hash() == chain[3].Hash() {
+		t.Fatalf("head block %d kept without its state", head.NumberU64())
+	}
+}
+
 //func TestEIP155Transition(t *testing.T) {
 //	// Configure and generate a sample block chain
 //	var (
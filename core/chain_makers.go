@@ -127,6 +127,12 @@ func (b *BlockGen) SetCoinbase(addr common.Address) {
 	b.gasPool = new(GasPool).AddGas(b.header.GasLimit)
 }
 
+// SetDifficulty sets the difficulty field of the generated block, which PoS
+// blocks encode their epoch and slot in.
+func (b *BlockGen) SetDifficulty(difficulty *big.Int) {
+	b.header.Difficulty = new(big.Int).Set(difficulty)
+}
+
 // SetExtra sets the extra data field of the generated block.
 func (b *BlockGen) SetExtra(data []byte) {
 	// ensure the extra data has all its components
@@ -30,6 +30,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
@@ -48,6 +49,8 @@ var (
 	headBlockKey  = []byte("LastBlock")
 	headFastKey   = []byte("LastFast")
 
+	epochJumpsKey = []byte("PosEpochJumps") // epoch jumps of the chain halt recoveries
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`).
 	headerPrefix        = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	tdSuffix            = []byte("t") // headerPrefix + num (uint64 big endian) + hash + tdSuffix -> td
@@ -574,6 +577,32 @@ func WriteBlockChainVersion(db ethdb.Putter, vsn int) {
 	db.Put([]byte("BlockchainVersion"), enc)
 }
 
+// GetEpochJumps reads the epoch jumps of the chain halt recoveries from db.
+func GetEpochJumps(db DatabaseReader) []util.EpochJump {
+	var jumps []util.EpochJump
+	enc, _ := db.Get(epochJumpsKey)
+	if len(enc) == 0 {
+		return nil
+	}
+	if err := rlp.DecodeBytes(enc, &jumps); err != nil {
+		log.Error("Invalid epoch jumps RLP", "err", err)
+		return nil
+	}
+	return jumps
+}
+
+// WriteEpochJumps writes the epoch jumps of the chain halt recoveries to db.
+func WriteEpochJumps(db ethdb.Putter, jumps []util.EpochJump) error {
+	enc, err := rlp.EncodeToBytes(jumps)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(epochJumpsKey, enc); err != nil {
+		log.Crit("Failed to store epoch jumps", "err", err)
+	}
+	return nil
+}
+
 // WriteChainConfig writes the chain config settings to the database.
 func WriteChainConfig(db ethdb.Putter, hash common.Hash, cfg *params.ChainConfig) error {
 	// short circuit and ignore if nil config. GetChainConfig
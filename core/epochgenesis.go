@@ -27,10 +27,7 @@ import (
 
 func (bc *BlockChain) updateReOrg(epochid uint64, slotid uint64, length uint64) {
 
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
-	if reOrgDb == nil {
-		reOrgDb = posdb.NewDb(posconfig.ReorgLocalDB)
-	}
+	reOrgDb := bc.posDbs.Get(posconfig.ReorgLocalDB)
 
 	numberBytes, _ := reOrgDb.Get(epochid, "reorgNumber")
 
@@ -49,10 +46,14 @@ func (bc *BlockChain) updateReOrg(epochid uint64, slotid uint64, length uint64)
 	reOrgDb.Put(epochid, "reorgLength", b)
 }
 
+// ErrEpochGenesisBudget is returned when generating an epoch genesis takes
+// generating more missing ones before it than allowed.
+var ErrEpochGenesisBudget = errors.New("epoch genesis build budget exhausted")
+
 type RbLeadersSelInt interface {
+	posUtil.SelectLead
 	GetEpochLastBlkNumber(epochId uint64) uint64
 	GetRBProposerGroup(epochID uint64) []vm.Leader
-	GetEpochLeaders(epochID uint64) [][]byte
 }
 
 type SlLeadersSelInt interface {
@@ -82,11 +83,47 @@ func NewEpochGenesisBlock(bc *BlockChain) *EpochGenesisBlock {
 	f.epochGenesisCh = make(chan uint64, 1)
 	f.lastEpochId = 0
 
-	f.epochGenDb = posdb.NewDb("epochGendb")
+	f.epochGenDb = bc.posDbs.Get(posconfig.EpochGenLocalDB)
 
 	return f
 }
 
+// StartRecovery starts the recovery of a halted chain. The epoch after the one
+// of the current block starts at the first epoch of the time grid at or after
+// startTime, and its blocks are concatenated to the current block. The chain
+// must have been halted for at least one whole epoch, longer than the security
+// window, so blocks of the current epoch can no longer be produced.
+func (f *EpochGenesisBlock) StartRecovery(startTime uint64) (util.EpochJump, error) {
+	head := f.bc.CurrentBlock()
+	if head.NumberU64() == 0 || posconfig.EpochBaseTime == 0 {
+		return util.EpochJump{}, errors.New("pos chain is not started")
+	}
+	headEpochId, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty())
+	headGridEpochId, _ := util.CalEpochSlotID(head.Time().Uint64())
+
+	epochTimespan := posconfig.SlotCount * posconfig.SlotTime
+	startGridEpochId := uint64(0)
+	if startTime > posconfig.EpochBaseTime {
+		startGridEpochId = (startTime - posconfig.EpochBaseTime + epochTimespan - 1) / epochTimespan
+	}
+	if startGridEpochId < headGridEpochId+2 {
+		return util.EpochJump{}, fmt.Errorf("chain is not halted for a whole epoch, last block in epoch %d", headEpochId)
+	}
+
+	j := util.EpochJump{Epoch: headEpochId + 1, Jump: startGridEpochId - headEpochId - 1}
+	if err := f.bc.addEpochJump(j); err != nil {
+		return util.EpochJump{}, err
+	}
+
+	// no block of the halted epoch got past 2K slots to select the leaders
+	if f.rbLeaderSelector != nil && len(f.rbLeaderSelector.GetEpochLeaders(j.Epoch)) == 0 {
+		if err := f.rbLeaderSelector.SelectLeadersLoop(j.Epoch); err != nil {
+			return j, err
+		}
+	}
+	return j, nil
+}
+
 func (f *EpochGenesisBlock) GetBlockEpochIdAndSlotId(header *types.Header) (blkEpochId uint64, blkSlotId uint64, err error) {
 
 	blkTd := header.Difficulty.Uint64()
@@ -108,84 +145,80 @@ func (f *EpochGenesisBlock) SelfGenerateEpochGenesis(blk *types.Block) {
 }
 
 func (f *EpochGenesisBlock) GenerateEpochGenesis(epochid uint64) (*types.EpochGenesis, error) {
+	// epochs 1 to epochid are all the epoch genesis there are to generate
+	epg, _, err := f.GenerateEpochGenesisWithin(epochid, int(epochid))
+	return epg, err
+}
+
+// GenerateEpochGenesisWithin is GenerateEpochGenesis generating and storing at
+// most budget epoch genesis not stored yet. It returns the number generated,
+// and ErrEpochGenesisBudget when the missing ones before epochid use up the
+// budget. Those generated are kept, so a later call continues from them.
+func (f *EpochGenesisBlock) GenerateEpochGenesisWithin(epochid uint64, budget int) (*types.EpochGenesis, int, error) {
 
 	log.Debug("generate epg", "", epochid)
 	epg := f.GetEpochGenesis(epochid)
 	if epg != nil {
-		return epg, nil
+		return epg, 0, nil
 	} else {
-		return f.generateChainedEpochGenesis(epochid)
+		return f.generateChainedEpochGenesis(epochid, budget)
 	}
 }
 
-func (f *EpochGenesisBlock) generateChainedEpochGenesis(epochid uint64) (*types.EpochGenesis, error) {
-	//it is the first block of this epoch
-	var rb *big.Int
-	var blk *types.Block
-	var epgPre *types.EpochGenesis
-	var epg *types.EpochGenesis
-
+func (f *EpochGenesisBlock) generateChainedEpochGenesis(epochid uint64, budget int) (*types.EpochGenesis, int, error) {
 	curEpid, _, err := f.GetBlockEpochIdAndSlotId(f.bc.currentBlock.Header())
 
 	if curEpid <= epochid || err != nil || epochid == 0 {
-		return nil, errors.New("error epochid")
+		return nil, 0, errors.New("error epochid")
 	}
 
-	epgPre = f.GetEpochGenesis(epochid - 1)
-	if epgPre == nil {
-		//start from ep1
-		for i := uint64(1); i <= epochid; i++ {
-
-			epg = f.GetEpochGenesis(i)
-			if epg != nil {
-				continue
-			}
-
-			if i == 1 {
-				rb = big.NewInt(1)
-				epgPre, err = f.generateEpochGenesis(0, nil, rb.Bytes(), common.Hash{})
-				if err != nil {
-					return nil, err
-				}
-
-			} else {
-				epgPre = f.GetEpochGenesis(i - 1)
-
-			}
-
-			rb, blk = f.getEpochRandomAndPreEpLastBlk(i)
-
-			if epgPre == nil {
-				return nil, errors.New("pre epg is nil")
-			}
-
-			epg, err = f.generateEpochGenesis(i, blk, rb.Bytes(), epgPre.GenesisBlkHash)
-			if err != nil {
-				return nil, err
-			}
+	// the missing epoch genesis are chained to the latest stored one before
+	// them, the epoch 0 one is never stored and is generated again
+	start := epochid
+	for start > 1 && !f.IsExistEpochGenesis(start-1) {
+		start--
+	}
 
-			err = f.SetEpochGenesis(epg)
-			if err != nil {
-				return nil, err
-			}
+	var epgPre *types.EpochGenesis
+	if start == 1 {
+		rb := big.NewInt(1)
+		epgPre, err = f.generateEpochGenesis(0, nil, rb.Bytes(), common.Hash{})
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		epgPre = f.GetEpochGenesis(start - 1)
+		if epgPre == nil {
+			return nil, 0, errors.New("pre epg is nil")
+		}
+	}
 
+	built := 0
+	for i := start; i <= epochid; i++ {
+		if built >= budget {
+			return nil, built, ErrEpochGenesisBudget
 		}
 
-	} else {
+		rb, blk := f.getEpochRandomAndPreEpLastBlk(i)
+		if rb == nil {
+			return nil, built, errors.New("no random of the epoch")
+		}
 
-		rb, blk = f.getEpochRandomAndPreEpLastBlk(epochid)
-		epg, err = f.generateEpochGenesis(epochid, blk, rb.Bytes(), epgPre.GenesisBlkHash)
+		epg, err := f.generateEpochGenesis(i, blk, rb.Bytes(), epgPre.GenesisBlkHash)
 		if err != nil {
-			return nil, err
+			return nil, built, err
 		}
 
 		err = f.SetEpochGenesis(epg)
 		if err != nil {
-			return nil, err
+			return nil, built, err
 		}
+
+		built++
+		epgPre = epg
 	}
 
-	return epg, nil
+	return epgPre, built, nil
 }
 
 func (f *EpochGenesisBlock) getEpochRandomAndPreEpLastBlk(epochid uint64) (*big.Int, *types.Block) {
@@ -245,19 +278,38 @@ func (f *EpochGenesisBlock) generateEpochGenesis(epochid uint64, lastblk *types.
 
 	epGen.PreEpochGenHash = preHash
 
-	epGen.GenesisBlkHash = common.Hash{}
+	hash, err := CalEpochGenesisHash(epGen)
+	if err != nil {
+		return nil, err
+	}
 
-	byteVal, err := json.Marshal(epGen)
+	epGen.GenesisBlkHash = hash
+	return epGen, nil
+}
 
-	//log.Info("generated epochGenesis data","",common.ToHex(byteVal))
+// CalEpochGenesisHash calculates the hash an epoch genesis is identified by.
+// GenesisBlkHash itself is not part of the hashed content.
+func CalEpochGenesisHash(epGen *types.EpochGenesis) (common.Hash, error) {
+	epGenNew := &types.EpochGenesis{}
 
+	epGenNew.ProtocolMagic = epGen.ProtocolMagic
+	epGenNew.PreEpochLastBlkHash = epGen.PreEpochLastBlkHash
+	epGenNew.EpochId = epGen.EpochId
+	epGenNew.RBLeadersSec256 = epGen.RBLeadersSec256
+	epGenNew.RBLeadersBn256 = epGen.RBLeadersBn256
+	epGenNew.EpochLeaders = epGen.EpochLeaders
+	epGenNew.StakerInfos = epGen.StakerInfos
+	epGenNew.SlotLeaders = epGen.SlotLeaders
+	epGenNew.PreEpochGenHash = epGen.PreEpochGenHash
+	epGenNew.GenesisBlkHash = common.Hash{}
+
+	byteVal, err := json.Marshal(epGenNew)
 	if err != nil {
 		log.Debug("Failed to marshal epoch genesis data", "err", err)
-		return nil, err
+		return common.Hash{}, err
 	}
 
-	epGen.GenesisBlkHash = crypto.Keccak256Hash(byteVal)
-	return epGen, nil
+	return crypto.Keccak256Hash(byteVal), nil
 }
 
 func (f *EpochGenesisBlock) preVerifyEpochGenesis(epGen *types.EpochGenesis) bool {
@@ -297,32 +349,12 @@ func (f *EpochGenesisBlock) preVerifyEpochGenesis(epGen *types.EpochGenesis) boo
 		return false
 	}
 
-	epGenNew := &types.EpochGenesis{}
-
-	epGenNew.ProtocolMagic = []byte("wanchainpos")
-	epGenNew.PreEpochLastBlkHash = epGen.PreEpochLastBlkHash
-	epGenNew.EpochId = epGen.EpochId
-	epGenNew.RBLeadersSec256 = epGen.RBLeadersSec256
-	epGenNew.RBLeadersBn256 = epGen.RBLeadersBn256
-	epGenNew.EpochLeaders = epGen.EpochLeaders
-	epGenNew.StakerInfos = epGen.StakerInfos
-	epGenNew.SlotLeaders = epGen.SlotLeaders
-	epGenNew.PreEpochGenHash = epGen.PreEpochGenHash
-	epGenNew.GenesisBlkHash = common.Hash{}
-
-	byteVal, err := json.Marshal(epGenNew)
-	//log.Info("verify genesis data","",common.ToHex(byteVal))
-
+	hash, err := CalEpochGenesisHash(epGen)
 	if err != nil {
-		log.Debug("Failed to marshal epoch genesis data", "err", err)
 		return false
 	}
 
-	epGenNew.GenesisBlkHash = crypto.Keccak256Hash(byteVal)
-
-	res = (epGenNew.GenesisBlkHash == epGen.GenesisBlkHash)
-
-	return res
+	return hash == epGen.GenesisBlkHash
 }
 
 //updated specified epoch genesis
@@ -339,7 +371,7 @@ func (f *EpochGenesisBlock) UpdateEpochGenesis(epochID uint64) {
 
 func (f *EpochGenesisBlock) GetLastBlkInPreEpoch(blk *types.Block) *types.Block {
 	epochID, _ := util.GetEpochSlotIDFromDifficulty(blk.Header().Difficulty)
-	blkNUm := posUtil.GetEpochBlock(epochID - 1)
+	blkNUm := f.bc.epochBlocks.GetEpochBlock(epochID - 1)
 	return f.bc.GetBlockByNumber(blkNUm)
 }
 
@@ -356,10 +388,10 @@ func (f *EpochGenesisBlock) IsExistEpochGenesis(epochid uint64) bool {
 func (f *EpochGenesisBlock) getAllSlotLeaders(epochID uint64) [][]byte {
 	startBlkNum := uint64(0)
 	if epochID > 0 {
-		startBlkNum = posUtil.GetEpochBlock(epochID-1) + 1
+		startBlkNum = f.epochLastBlkNumber(epochID-1) + 1
 	}
 
-	endBlkNum := posUtil.GetEpochBlock(epochID)
+	endBlkNum := f.epochLastBlkNumber(epochID)
 	slotLeaders := make([][]byte, 0)
 	for i := startBlkNum; i <= endBlkNum; i++ {
 		header := f.bc.GetHeaderByNumber(i)
@@ -376,6 +408,16 @@ func (f *EpochGenesisBlock) getAllSlotLeaders(epochID uint64) [][]byte {
 
 }
 
+// epochLastBlkNumber returns the number of the last block of epochID. The epoch
+// blocks of the chain only know the epochs seen since the node started, as after
+// a restart to recover a halted chain, the others are looked up in the chain.
+func (f *EpochGenesisBlock) epochLastBlkNumber(epochID uint64) uint64 {
+	if num := f.bc.epochBlocks.GetEpochBlock(epochID); num != 0 || f.rbLeaderSelector == nil {
+		return num
+	}
+	return f.rbLeaderSelector.GetEpochLastBlkNumber(epochID)
+}
+
 func (f *EpochGenesisBlock) SetEpochGenesis(epochgen *types.EpochGenesis) error {
 	f.epgSetmu.Lock()
 	defer f.epgSetmu.Unlock()
@@ -409,6 +451,18 @@ func (f *EpochGenesisBlock) SetEpochGenesis(epochgen *types.EpochGenesis) error
 	return nil
 }
 
+// ClearEpochGenesis deletes all stored epoch genesis together with the staker
+// infos saved from them. They are generated again from the chain on request.
+func (f *EpochGenesisBlock) ClearEpochGenesis() error {
+	f.epgSetmu.Lock()
+	defer f.epgSetmu.Unlock()
+
+	if err := f.epochGenDb.Clear(); err != nil {
+		return err
+	}
+	return f.bc.posDbs.Get(posconfig.StakerLocalDB).Clear()
+}
+
 func (f *EpochGenesisBlock) GetEpochGenesis(epochid uint64) *types.EpochGenesis {
 
 	val, err := f.epochGenDb.Get(epochid, "epochgenesis")
@@ -437,7 +491,7 @@ func (f *EpochGenesisBlock) ValidateBody(block *types.Block) error {
 		return nil
 	}
 
-	blKBegin := posUtil.GetEpochBlock(epochID-1) + 1
+	blKBegin := f.epochLastBlkNumber(epochID-1) + 1
 
 	idx := int(block.NumberU64() - blKBegin)
 
@@ -490,6 +544,15 @@ func (f *EpochGenesisBlock) ValidateState(block, parent *types.Block, state *sta
 }
 
 func (f *EpochGenesisBlock) recoverSigner(header *types.Header) ([]byte, error) {
+	return RecoverBlockSigner(header)
+}
+
+// RecoverBlockSigner returns the uncompressed public key of the slot leader
+// that sealed a PoS header.
+func RecoverBlockSigner(header *types.Header) ([]byte, error) {
+	if len(header.Extra) < extraSeal {
+		return nil, errors.New("header extra is too short to contain a seal")
+	}
 	signature := header.Extra[len(header.Extra)-extraSeal:]
 
 	log.Debug("signature", "hex", hex.EncodeToString(signature))
@@ -535,10 +598,7 @@ func sigHash(header *types.Header) (hash common.Hash) {
 }
 
 func (f *EpochGenesisBlock) saveToPosDb(epochgen *types.EpochGenesis) error {
-	elDb := posdb.NewDb(posconfig.EpLocalDB)
-	if elDb == nil {
-		return errors.New("create epoch local db error")
-	}
+	elDb := f.bc.posDbs.Get(posconfig.EpLocalDB)
 	count := len(epochgen.EpochLeaders)
 	// TODO: 1. check valid public key, 2. check exist? 3. put failed? 4. how to rollback
 	for i := 0; i < count; i++ {
@@ -551,10 +611,7 @@ func (f *EpochGenesisBlock) saveToPosDb(epochgen *types.EpochGenesis) error {
 		v, _ := rlp.EncodeToBytes(&tmp)
 		elDb.PutWithIndex(epochgen.EpochId, uint64(i), "", v)
 	}
-	rbDb := posdb.NewDb(posconfig.RbLocalDB)
-	if rbDb == nil {
-		return errors.New("create rb local db error")
-	}
+	rbDb := f.bc.posDbs.Get(posconfig.RbLocalDB)
 	count = len(epochgen.RBLeadersSec256)
 	for i := 0; i < count; i++ {
 		tmp := posdb.Proposer{}
@@ -564,10 +621,7 @@ func (f *EpochGenesisBlock) saveToPosDb(epochgen *types.EpochGenesis) error {
 		rbDb.PutWithIndex(epochgen.EpochId, uint64(i), "", v)
 	}
 
-	stDb := posdb.NewDb(posconfig.StakerLocalDB)
-	if stDb == nil {
-		return errors.New("create stakeholder local db error")
-	}
+	stDb := f.bc.posDbs.Get(posconfig.StakerLocalDB)
 	count = len(epochgen.StakerInfos)
 	for _, pkBytes := range epochgen.StakerInfos {
 		staker := vm.StakerInfo{}
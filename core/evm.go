@@ -23,6 +23,8 @@ import (
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
 )
 
 // ChainContext supports retrieving headers and consensus parameters from the
@@ -35,6 +37,14 @@ type ChainContext interface {
 	GetHeader(common.Hash, uint64) *types.Header
 }
 
+// posChainContext is a ChainContext providing the PoS subsystems of the node
+// the chain belongs to, which the PoS precompiled contracts use.
+type posChainContext interface {
+	Epocher() posUtil.SelectLead
+	PosDbs() *posdb.Dbs
+	EpochJumpsAt(header *types.Header) *posUtil.EpochJumps
+}
+
 // NewEVMContext creates a new context for use in the EVM.
 func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author *common.Address) vm.Context {
 	// If we don't have an explicit author (i.e. not mining), extract from the header
@@ -44,7 +54,7 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 	} else {
 		beneficiary = *author
 	}
-	return vm.Context{
+	context := vm.Context{
 		CanTransfer: CanTransfer,
 		Transfer:    Transfer,
 		GetHash:     GetHashFn(header, chain),
@@ -56,6 +66,14 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		GasLimit:    new(big.Int).Set(header.GasLimit),
 		GasPrice:    new(big.Int).Set(msg.GasPrice()),
 	}
+	if pos, ok := chain.(posChainContext); ok {
+		context.Epocher = pos.Epocher()
+		context.EpochJumps = pos.EpochJumpsAt(header)
+		if dbs := pos.PosDbs(); dbs != nil {
+			context.PosDb = dbs.Local()
+		}
+	}
+	return context
 }
 
 // GetHashFn returns a GetHashFunc which retrieves header hashes by number
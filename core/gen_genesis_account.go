@@ -15,10 +15,16 @@ import (
 var _ = (*genesisAccountMarshaling)(nil)
 
 func (g GenesisAccount) MarshalJSON() ([]byte, error) {
+	type GenesisAccountStaking struct {
+		Amount  *math.HexOrDecimal256 `json:"amount"`
+		S256pk  hexutil.Bytes         `json:"s256pk"`
+		Bn256pk hexutil.Bytes         `json:"bn256pk"`
+	}
 	type GenesisAccount struct {
 		Code       hexutil.Bytes               `json:"code,omitempty"`
 		Storage    map[storageJSON]storageJSON `json:"storage,omitempty"`
 		Balance    *math.HexOrDecimal256       `json:"balance" gencodec:"required"`
+		Staking    *GenesisAccountStaking      `json:"staking,omitempty"`
 		Nonce      math.HexOrDecimal64         `json:"nonce,omitempty"`
 		PrivateKey hexutil.Bytes               `json:"secretKey,omitempty"`
 	}
@@ -31,6 +37,13 @@ func (g GenesisAccount) MarshalJSON() ([]byte, error) {
 		}
 	}
 	enc.Balance = (*math.HexOrDecimal256)(g.Balance)
+	if g.Staking.S256pk != nil {
+		enc.Staking = &GenesisAccountStaking{
+			Amount:  (*math.HexOrDecimal256)(g.Staking.Amount),
+			S256pk:  g.Staking.S256pk,
+			Bn256pk: g.Staking.Bn256pk,
+		}
+	}
 	enc.Nonce = math.HexOrDecimal64(g.Nonce)
 	enc.PrivateKey = g.PrivateKey
 	return json.Marshal(&enc)
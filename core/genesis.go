@@ -19,6 +19,7 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -238,6 +239,32 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	}
 }
 
+// CheckPosConfig checks that the PoS parameters of a Pluto genesis are
+// consistent, and that the genesis PK, configured or in the extra data, is a
+// public key.
+func (g *Genesis) CheckPosConfig() error {
+	if g.Config == nil || g.Config.Pluto == nil {
+		return nil
+	}
+	genesisPK := g.ExtraData
+	if pos := g.Config.Pos; pos != nil {
+		if err := pos.Validate(); err != nil {
+			return err
+		}
+		if pos.GenesisPK != "" {
+			pk, _ := hex.DecodeString(pos.GenesisPK)
+			if len(g.ExtraData) != 0 && !bytes.Equal(pk, g.ExtraData) {
+				return errors.New("pos: genesisPK does not match the genesis extra data")
+			}
+			genesisPK = pk
+		}
+	}
+	if len(genesisPK) != 65 || genesisPK[0] != 4 {
+		return errors.New("pos: genesis extra data is not the genesis public key")
+	}
+	return nil
+}
+
 // ToBlock creates the block and state of a genesis specification.
 func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 	db, _ := ethdb.NewMemDatabase()
@@ -432,6 +459,53 @@ func PlutoDevGenesisBlock() *Genesis {
 	}
 }
 
+// PosValidator is a validator staking from the genesis of a PoS chain.
+type PosValidator struct {
+	PublicKey *ecdsa.PublicKey
+	Bn256PK   []byte // marshalled bn256 G1 public key
+	Balance   *big.Int
+	Stake     *big.Int
+}
+
+// PosGenesisBlock returns the genesis block of a Pluto chain starting at
+// timestamp, with slots of slotTime seconds and epochs of 10*k slots. The
+// validators are its white list epoch leaders, the first of which seals the
+// genesis block.
+func PosGenesisBlock(chainID *big.Int, slotTime, k, timestamp uint64, validators []PosValidator) *Genesis {
+	pos := *params.DefaultPosConfig
+	pos.SlotTime = slotTime
+	pos.K = k
+	pos.GenesisPK = hex.EncodeToString(crypto.FromECDSAPub(validators[0].PublicKey))
+	pos.WhiteList = nil
+
+	genesis := &Genesis{
+		Config: &params.ChainConfig{
+			ChainId:        chainID,
+			ByzantiumBlock: big.NewInt(0),
+			Pluto:          &params.PlutoConfig{Period: pos.SlotTime, Epoch: 100},
+			Pos:            &pos,
+		},
+		Timestamp:  timestamp,
+		ExtraData:  crypto.FromECDSAPub(validators[0].PublicKey),
+		GasLimit:   0x47b760,
+		Difficulty: big.NewInt(1),
+		Alloc:      make(GenesisAlloc),
+	}
+	for _, v := range validators {
+		pk := crypto.FromECDSAPub(v.PublicKey)
+		pos.WhiteList = append(pos.WhiteList, common.ToHex(pk))
+		genesis.Alloc[crypto.PubkeyToAddress(*v.PublicKey)] = GenesisAccount{
+			Balance: v.Balance,
+			Staking: GenesisAccountStaking{
+				Amount:  v.Stake,
+				S256pk:  pk,
+				Bn256pk: v.Bn256PK,
+			},
+		}
+	}
+	return genesis
+}
+
 // DevGenesisBlock returns the 'geth --dev' genesis block.
 func DevGenesisBlock() *Genesis {
 	return &Genesis{
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -184,3 +185,44 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestGenesisCheckPosConfig(t *testing.T) {
+	genesisPK := common.FromHex(params.DefaultPosConfig.GenesisPK)
+	pos := &params.PosConfig{SlotTime: 2, K: 10, KCount: 12, EpochLeaderCount: 7, RandomProperCount: 5}
+	posPK := *pos
+	posPK.GenesisPK = params.DefaultPosConfig.GenesisPK
+
+	tests := []struct {
+		genesis *Genesis
+		valid   bool
+	}{
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}}, ExtraData: genesisPK}, true},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: pos}, ExtraData: genesisPK}, true},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: &posPK}, ExtraData: genesisPK}, true},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: &posPK}}, true},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: &posPK}, ExtraData: []byte{1}}, false},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: pos}}, false},
+		{&Genesis{Config: &params.ChainConfig{Pluto: &params.PlutoConfig{}, Pos: &params.PosConfig{SlotTime: 2}}, ExtraData: genesisPK}, false},
+	}
+	for i, test := range tests {
+		if err := test.genesis.CheckPosConfig(); (err == nil) != test.valid {
+			t.Errorf("test %d: check mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
+
+func TestGenesisAccountStakingJSON(t *testing.T) {
+	alloc := jsonPrealloc(PlutoDevAllocJson)
+
+	enc, err := json.Marshal(alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec GenesisAlloc
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(alloc, dec) {
+		t.Errorf("alloc mismatch after json round trip:\n%s", enc)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otaindex
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	// imagePrefix + key image -> hash of the refund transaction that disclosed it
+	imagePrefix = []byte("iOi")
+	// imageTailKey -> number of the oldest block whose key images are indexed
+	imageTailKey = []byte("OTAImageTail")
+)
+
+// imageBatchBlocks is the number of past blocks whose key images are written
+// at once.
+const imageBatchBlocks = 1024
+
+// WriteImages indexes the key images disclosed by the successful refunds of
+// block, as told by its receipts.
+func WriteImages(db ethdb.Putter, block *types.Block, receipts types.Receipts) error {
+	for i, tx := range block.Transactions() {
+		if tx.To() == nil || !vm.IsWanCoinContract(*tx.To()) {
+			continue
+		}
+		if i >= len(receipts) || receipts[i].Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		image, err := vm.DecodeRefundCoin(tx.Data())
+		if err != nil {
+			continue
+		}
+		if err := db.Put(imageKey(image), tx.Hash().Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImageTx returns the hash of the refund transaction that disclosed the key
+// image, the zero hash if the image is not indexed, as for the blocks the
+// indexer has not reached yet. The transaction may have been dropped by a
+// reorg since: callers look it up in the chain.
+func ImageTx(db ethdb.Database, image []byte) common.Hash {
+	data, _ := db.Get(imageKey(image))
+	return common.BytesToHash(data)
+}
+
+func imageKey(image []byte) []byte {
+	return append(append([]byte{}, imagePrefix...), image...)
+}
+
+// indexImages indexes the key images of the blocks imported before the image
+// index existed, from the newest to the oldest. The blocks imported since are
+// indexed with WriteImages. The progress is kept in the database, so that a
+// restart resumes where it stopped.
+func (i *Indexer) indexImages() {
+	defer i.images.Done()
+
+	db := i.idx.db
+	tail := i.chain.CurrentBlock().NumberU64() + 1
+	if data, _ := db.Get(imageTailKey); len(data) == 8 {
+		tail = binary.BigEndian.Uint64(data)
+	}
+	from, start := tail, time.Now()
+	for tail > 1 && !i.closing() {
+		batch := db.NewBatch()
+		for n := 0; n < imageBatchBlocks && tail > 1; n++ {
+			header := i.chain.GetHeaderByNumber(tail - 1)
+			if header == nil {
+				// the chain was rewound below the tail, the blocks after
+				// it are imported with their images again
+				return
+			}
+			block := i.chain.GetBlock(header.Hash(), tail-1)
+			if block == nil {
+				return
+			}
+			if err := WriteImages(batch, block, i.chain.GetReceipts(block.Hash(), tail-1)); err != nil {
+				log.Warn("Failed to index key images", "number", tail-1, "err", err)
+				return
+			}
+			tail--
+		}
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], tail)
+		if err := batch.Put(imageTailKey, data[:]); err != nil {
+			log.Warn("Failed to index key images", "number", tail, "err", err)
+			return
+		}
+		if err := batch.Write(); err != nil {
+			log.Warn("Failed to index key images", "number", tail, "err", err)
+			return
+		}
+	}
+	if from > 1 && tail <= 1 {
+		log.Info("Indexed the key images of past blocks", "blocks", from-1, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
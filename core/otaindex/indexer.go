@@ -0,0 +1,222 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otaindex
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+// Chain is the chain an Indexer keeps the index of.
+type Chain interface {
+	CurrentBlock() *types.Block
+	GetHeader(hash common.Hash, number uint64) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetReceipts(hash common.Hash, number uint64) types.Receipts
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// Indexer brings the index to the current block of a chain in the background,
+// since the first build walks the whole ota storage and a catch up may replay
+// many blocks. Until the index covers a block, the OTAs are read from the ota
+// storage instead. It also indexes the key images of the blocks imported
+// before the image index existed, see indexImages.
+type Indexer struct {
+	idx   *Index
+	chain Chain
+
+	update chan struct{}   // Notification channel that the chain has a new head
+	quit   chan chan error // Quit channel to tear down the indexing goroutine
+	closed int32           // Set by Close to abort a running build or catch up
+	images sync.WaitGroup  // Tracks the indexing of the key images of past blocks
+
+	lock sync.Mutex // Serializes the writes to the index
+}
+
+// NewIndexer starts the indexer of idx, the index of chain. It indexes once
+// notified with Update.
+func NewIndexer(idx *Index, chain Chain) *Indexer {
+	i := &Indexer{
+		idx:    idx,
+		chain:  chain,
+		update: make(chan struct{}, 1),
+		quit:   make(chan chan error),
+	}
+	go i.updateLoop()
+	return i
+}
+
+// Update notifies the indexer that the current block of the chain changed.
+func (i *Indexer) Update() {
+	select {
+	case i.update <- struct{}{}:
+	default:
+	}
+}
+
+// Rewind rewinds the index at once to block number hash, see Index.Rewind, so
+// it covers no block the chain dropped. An index not past number yet is left
+// for the background catch up.
+func (i *Indexer) Rewind(hash common.Hash, number uint64) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if head := i.idx.Head(); head == nil || head.Number < number {
+		return nil
+	}
+	return i.idx.Rewind(hash, number)
+}
+
+// Close stops the indexer, a running build, catch up or indexing of key
+// images is aborted.
+func (i *Indexer) Close() error {
+	atomic.StoreInt32(&i.closed, 1)
+	errc := make(chan error)
+	i.quit <- errc
+	err := <-errc
+	i.images.Wait()
+	return err
+}
+
+func (i *Indexer) updateLoop() {
+	imaging := false
+	for {
+		select {
+		case errc := <-i.quit:
+			errc <- nil
+			return
+
+		case <-i.update:
+			// the chain has a current block once it notifies
+			if !imaging {
+				imaging = true
+				i.images.Add(1)
+				go i.indexImages()
+			}
+			if err := i.sync(); err != nil {
+				log.Warn("Failed to update the OTA index", "err", err)
+			}
+		}
+	}
+}
+
+// sync brings the index to the current block of the chain, one block at a
+// time.
+func (i *Indexer) sync() error {
+	for !i.closing() {
+		done, err := i.step()
+		if done || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// step moves the index one block towards the current block of the chain and
+// reports whether it got there. An index not built yet, or on a block without
+// canonical ancestor it covers, is rebuilt from the state of the current block.
+func (i *Indexer) step() (bool, error) {
+	current := i.chain.CurrentBlock().Header()
+	head := i.idx.Head()
+	switch {
+	case head == nil:
+		return true, i.rebuild(current)
+	case head.Hash == current.Hash():
+		return true, nil
+	case head.Number >= current.Number.Uint64() || !i.canonical(head.Hash, head.Number):
+		return false, i.rewind(head, current)
+	}
+	return i.next(head)
+}
+
+// next indexes the canonical block after head.
+func (i *Indexer) next(head *Head) (bool, error) {
+	next := i.chain.GetHeaderByNumber(head.Number + 1)
+	if next == nil || next.ParentHash != head.Hash {
+		// the chain is being reorganized, it notifies again once done
+		return true, nil
+	}
+	statedb, err := i.chain.StateAt(next.Root)
+	if err != nil {
+		return true, err
+	}
+	var parent *state.StateDB
+	if header := i.chain.GetHeader(head.Hash, head.Number); header != nil {
+		if parent, err = i.chain.StateAt(header.Root); err != nil {
+			parent = nil
+		}
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if now := i.idx.Head(); now == nil || now.Hash != head.Hash {
+		return false, nil
+	}
+	return false, i.idx.Update(parent, statedb, head.Hash, next.Hash(), next.Number.Uint64())
+}
+
+// rewind rewinds the index to the last canonical block it covers, not after
+// current.
+func (i *Indexer) rewind(head *Head, current *types.Header) error {
+	hash, number := head.Hash, head.Number
+	for number >= head.Base {
+		if number <= current.Number.Uint64() && i.canonical(hash, number) {
+			i.lock.Lock()
+			defer i.lock.Unlock()
+
+			if now := i.idx.Head(); now == nil || now.Hash != head.Hash {
+				return nil
+			}
+			return i.idx.Rewind(hash, number)
+		}
+		header := i.chain.GetHeader(hash, number)
+		if header == nil || number == 0 {
+			break
+		}
+		hash, number = header.ParentHash, number-1
+	}
+	return i.rebuild(current)
+}
+
+// rebuild indexes the OTAs of the state of current at once, the index is
+// only locked to write them.
+func (i *Indexer) rebuild(current *types.Header) error {
+	statedb, err := i.chain.StateAt(current.Root)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	batch, err := i.idx.rebuildBatch(statedb, current.Hash(), current.Number.Uint64(), i.closing)
+	if err == errAborted {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Built the OTA index", "number", current.Number, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func (i *Indexer) closing() bool {
+	return atomic.LoadInt32(&i.closed) == 1
+}
+
+func (i *Indexer) canonical(hash common.Hash, number uint64) bool {
+	header := i.chain.GetHeaderByNumber(number)
+	return header != nil && header.Hash() == hash
+}
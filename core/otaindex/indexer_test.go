@@ -0,0 +1,231 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otaindex
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+// testChain is a Chain of headers over the states of a database.
+type testChain struct {
+	sdb       state.Database
+	lock      sync.Mutex
+	headers   map[common.Hash]*types.Header
+	canonical []*types.Header
+	txs       map[common.Hash]types.Transactions
+	receipts  map[common.Hash]types.Receipts
+}
+
+func newTestChain(sdb state.Database) *testChain {
+	return &testChain{
+		sdb:      sdb,
+		headers:  make(map[common.Hash]*types.Header),
+		txs:      make(map[common.Hash]types.Transactions),
+		receipts: make(map[common.Hash]types.Receipts),
+	}
+}
+
+// setBody sets the transactions of the canonical block number and their
+// receipts.
+func (c *testChain) setBody(number int, txs types.Transactions, receipts types.Receipts) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	hash := c.canonical[number].Hash()
+	c.txs[hash], c.receipts[hash] = txs, receipts
+}
+
+// setHead makes the chain of root the canonical one from block number on,
+// every block of it the child of the previous one.
+func (c *testChain) setHead(number int, roots ...common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.canonical = c.canonical[:number]
+	for _, root := range roots {
+		header := &types.Header{
+			Number:     big.NewInt(int64(len(c.canonical))),
+			Root:       root,
+			Difficulty: big.NewInt(1),
+			GasLimit:   big.NewInt(0),
+			GasUsed:    big.NewInt(0),
+			Time:       big.NewInt(0),
+		}
+		if len(c.canonical) > 0 {
+			header.ParentHash = c.canonical[len(c.canonical)-1].Hash()
+		}
+		c.headers[header.Hash()] = header
+		c.canonical = append(c.canonical, header)
+	}
+}
+
+func (c *testChain) CurrentBlock() *types.Block {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return types.NewBlockWithHeader(c.canonical[len(c.canonical)-1])
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.headers[hash]
+}
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if number >= uint64(len(c.canonical)) {
+		return nil
+	}
+	return c.canonical[number]
+}
+
+func (c *testChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	header := c.headers[hash]
+	if header == nil {
+		return nil
+	}
+	return types.NewBlockWithHeader(header).WithBody(c.txs[hash], nil)
+}
+
+func (c *testChain) GetReceipts(hash common.Hash, number uint64) types.Receipts {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.receipts[hash]
+}
+
+func (c *testChain) StateAt(root common.Hash) (*state.StateDB, error) {
+	return state.New(root, c.sdb)
+}
+
+// waitHead waits for the indexer to bring the index to the current block of
+// chain.
+func waitHead(t *testing.T, idx *Index, chain *testChain) *Head {
+	want := chain.CurrentBlock().Hash()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if head := idx.Head(); head != nil && head.Hash == want {
+			return head
+		}
+	}
+	t.Fatalf("index head %+v not at the current block %x", idx.Head(), want)
+	return nil
+}
+
+func TestIndexer(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	idx := New(db)
+	addr10 := vm.OTABalance2ContractAddr(wancoin10)
+
+	st0 := addOTAs(t, db, sdb, common.Hash{}, wancoin10, 0, 5)
+	st1 := addOTAs(t, db, sdb, st0.IntermediateRoot(true), wancoin10, 5, 8)
+	st2 := addOTAs(t, db, sdb, st1.IntermediateRoot(true), wancoin10, 8, 10)
+	chain := newTestChain(sdb)
+	chain.setHead(0, st0.IntermediateRoot(true), st1.IntermediateRoot(true), st2.IntermediateRoot(true))
+
+	indexer := NewIndexer(idx, chain)
+	defer indexer.Close()
+
+	// an index not built yet is built from the state of the current block
+	indexer.Update()
+	if head := waitHead(t, idx, chain); head.Base != 2 {
+		t.Errorf("got head %+v after the build, want base 2", head)
+	}
+	if n, err := idx.OTACount(addr10, 2); err != nil || n != 10 {
+		t.Errorf("count after the build: got %d, %v, want 10", n, err)
+	}
+
+	// new blocks are added one by one
+	st3 := addOTAs(t, db, sdb, st2.IntermediateRoot(true), wancoin10, 10, 12)
+	st4 := addOTAs(t, db, sdb, st3.IntermediateRoot(true), wancoin10, 12, 13)
+	chain.setHead(3, st3.IntermediateRoot(true), st4.IntermediateRoot(true))
+	indexer.Update()
+	if head := waitHead(t, idx, chain); head.Base != 2 {
+		t.Errorf("got head %+v after the catch up, want base 2", head)
+	}
+	counts := []struct{ number, want uint64 }{{2, 10}, {3, 12}, {4, 13}}
+	for _, c := range counts {
+		if n, err := idx.OTACount(addr10, c.number); err != nil || n != c.want {
+			t.Errorf("count at %d: got %d, %v, want %d", c.number, n, err, c.want)
+		}
+	}
+
+	// a fork from block 2 drops the OTAs of the old blocks 3 and 4
+	st3b := addOTAs(t, db, sdb, st2.IntermediateRoot(true), wancoin10, 300, 301)
+	chain.setHead(3, st3b.IntermediateRoot(true))
+	indexer.Update()
+	waitHead(t, idx, chain)
+	if n, err := idx.OTACount(addr10, 3); err != nil || n != 11 {
+		t.Errorf("count of the new block: got %d, %v, want 11", n, err)
+	}
+	if ota, _ := idx.OTAAt(addr10, 10); !bytes.Equal(ota, testOTA(300)) {
+		t.Errorf("got OTA %x of the new block, want %x", ota, testOTA(300))
+	}
+
+	// a fork from before the base rebuilds the index
+	st1b := addOTAs(t, db, sdb, st0.IntermediateRoot(true), wancoin10, 100, 101)
+	chain.setHead(1, st1b.IntermediateRoot(true))
+	indexer.Update()
+	if head := waitHead(t, idx, chain); head.Base != 1 {
+		t.Errorf("got head %+v after the fork, want base 1", head)
+	}
+	if n, err := idx.OTACount(addr10, 1); err != nil || n != 6 {
+		t.Errorf("count after the fork: got %d, %v, want 6", n, err)
+	}
+}
+
+// Tests that the indexer indexes the key images of the blocks imported before
+// the image index, and resumes from where it stopped.
+func TestIndexerImages(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	chain := newTestChain(sdb)
+	chain.setHead(0, common.Hash{}, common.Hash{}, common.Hash{}, common.Hash{})
+
+	refunds := make([]*types.Transaction, 3)
+	images := make([][]byte, 3)
+	for n := range refunds {
+		refunds[n], images[n] = refundTx(t, uint64(n))
+		chain.setBody(n+1, types.Transactions{refunds[n]}, types.Receipts{{Status: types.ReceiptStatusSuccessful}})
+	}
+	// a previous run indexed the images of block 3 and stopped
+	var tail [8]byte
+	binary.BigEndian.PutUint64(tail[:], 3)
+	db.Put(imageTailKey, tail[:])
+
+	indexer := NewIndexer(New(db), chain)
+	indexer.Update()
+	for deadline := time.Now().Add(5 * time.Second); ImageTx(db, images[0]) == (common.Hash{}); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			indexer.Close()
+			t.Fatal("key images of past blocks not indexed")
+		}
+	}
+	indexer.Close()
+	for n := 0; n < 2; n++ {
+		if hash := ImageTx(db, images[n]); hash != refunds[n].Hash() {
+			t.Errorf("block %d: got refund %x of the image, want %x", n+1, hash, refunds[n].Hash())
+		}
+	}
+	if hash := ImageTx(db, images[2]); hash != (common.Hash{}) {
+		t.Errorf("block 3 indexed again, got refund %x", hash)
+	}
+}
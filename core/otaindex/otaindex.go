@@ -0,0 +1,279 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+// Package otaindex keeps, in the chain database, the OTAs of every wancoin and
+// stamp denomination in the order they were added to the canonical chain, so
+// mix sets are drawn from a few entries instead of the whole ota storage. It
+// also maps the key images disclosed by refunds to their transactions.
+package otaindex
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+var (
+	headKey     = []byte("iOh") // headKey -> index head
+	countPrefix = []byte("iOc") // countPrefix + storage address -> number of OTAs (uint64 big endian)
+	entryPrefix = []byte("iOe") // entryPrefix + storage address + position (uint64 big endian) -> entry
+
+	errNoIndex       = errors.New("ota index not built")
+	errBlockNotIndex = errors.New("block not covered by the ota index")
+	errNoEntry       = errors.New("ota index entry not found")
+	errAborted       = errors.New("ota index build aborted")
+)
+
+// Head is the block the index is at. The OTAs added before Base were indexed
+// at once from its state, so the index covers the blocks from Base only.
+type Head struct {
+	Hash   common.Hash
+	Number uint64
+	Base   uint64
+}
+
+// entry is an OTA and the block it was added in.
+type entry struct {
+	Number  uint64
+	WanAddr []byte
+}
+
+// Index is the OTA index of a chain database.
+type Index struct {
+	db ethdb.Database
+}
+
+// New returns the OTA index of db.
+func New(db ethdb.Database) *Index {
+	return &Index{db: db}
+}
+
+// Head returns the block the index is at, nil if it has not been built.
+func (idx *Index) Head() *Head {
+	data, _ := idx.db.Get(headKey)
+	if len(data) == 0 {
+		return nil
+	}
+	head := new(Head)
+	if err := rlp.DecodeBytes(data, head); err != nil {
+		return nil
+	}
+	return head
+}
+
+// OTACount returns the number of OTAs of the storage address at block number.
+func (idx *Index) OTACount(mptAddr common.Address, number uint64) (uint64, error) {
+	head := idx.Head()
+	if head == nil {
+		return 0, errNoIndex
+	}
+	if number < head.Base || number > head.Number {
+		return 0, errBlockNotIndex
+	}
+	count := idx.count(mptAddr)
+	if number == head.Number {
+		return count, nil
+	}
+	return idx.search(mptAddr, count, number)
+}
+
+// OTAAt returns the WanAddr of the OTA of the storage address at position pos.
+func (idx *Index) OTAAt(mptAddr common.Address, pos uint64) ([]byte, error) {
+	e, err := idx.entry(mptAddr, pos)
+	if err != nil {
+		return nil, err
+	}
+	return e.WanAddr, nil
+}
+
+// Rebuild indexes all the OTAs of statedb, the state of block number hash.
+func (idx *Index) Rebuild(statedb *state.StateDB, hash common.Hash, number uint64) error {
+	batch, err := idx.rebuildBatch(statedb, hash, number, nil)
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// rebuildBatch gathers the writes of Rebuild without applying them. It gives
+// up with errAborted once abort, if any, returns true.
+func (idx *Index) rebuildBatch(statedb *state.StateDB, hash common.Hash, number uint64, abort func() bool) (ethdb.Batch, error) {
+	batch := idx.db.NewBatch()
+	for _, addr := range vm.OTAStorageAddrs() {
+		values, err := addedOTAs(nil, statedb.StorageTrie(addr), abort)
+		if err != nil {
+			return nil, err
+		}
+		for pos, value := range values {
+			if err := putEntry(batch, addr, uint64(pos), entry{number, value}); err != nil {
+				return nil, err
+			}
+		}
+		if err := putCount(batch, addr, uint64(len(values))); err != nil {
+			return nil, err
+		}
+	}
+	if err := putHead(batch, &Head{hash, number, number}); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// Update indexes the OTAs added by block number hash, from the state of its
+// parent to its state. An index not at the parent, or without its state, is
+// rebuilt instead.
+func (idx *Index) Update(parent, statedb *state.StateDB, parentHash, hash common.Hash, number uint64) error {
+	head := idx.Head()
+	if parent == nil || head == nil || head.Hash != parentHash || head.Number+1 != number {
+		return idx.Rebuild(statedb, hash, number)
+	}
+	batch := idx.db.NewBatch()
+	for _, addr := range vm.OTAStorageAddrs() {
+		added, err := addedOTAs(parent.StorageTrie(addr), statedb.StorageTrie(addr), nil)
+		if err != nil {
+			return err
+		}
+		if len(added) == 0 {
+			continue
+		}
+		count := idx.count(addr)
+		for _, value := range added {
+			if err := putEntry(batch, addr, count, entry{number, value}); err != nil {
+				return err
+			}
+			count++
+		}
+		if err := putCount(batch, addr, count); err != nil {
+			return err
+		}
+	}
+	if err := putHead(batch, &Head{hash, number, head.Base}); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Rewind removes the OTAs added after block number, which becomes the head of
+// the index as block hash. An index not covering the block is dropped.
+func (idx *Index) Rewind(hash common.Hash, number uint64) error {
+	head := idx.Head()
+	if head == nil {
+		return nil
+	}
+	if number < head.Base || number > head.Number {
+		return idx.db.Delete(headKey)
+	}
+	batch := idx.db.NewBatch()
+	for _, addr := range vm.OTAStorageAddrs() {
+		count := idx.count(addr)
+		kept, err := idx.search(addr, count, number)
+		if err != nil {
+			return err
+		}
+		if kept != count {
+			if err := putCount(batch, addr, kept); err != nil {
+				return err
+			}
+		}
+	}
+	if err := putHead(batch, &Head{hash, number, head.Base}); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// search returns the number of the first count OTAs of the storage address
+// added up to block number.
+func (idx *Index) search(mptAddr common.Address, count, number uint64) (uint64, error) {
+	lo, hi := uint64(0), count
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		e, err := idx.entry(mptAddr, mid)
+		if err != nil {
+			return 0, err
+		}
+		if e.Number <= number {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+func (idx *Index) count(mptAddr common.Address) uint64 {
+	data, _ := idx.db.Get(append(append([]byte{}, countPrefix...), mptAddr[:]...))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+func (idx *Index) entry(mptAddr common.Address, pos uint64) (*entry, error) {
+	data, _ := idx.db.Get(entryKey(mptAddr, pos))
+	if len(data) == 0 {
+		return nil, errNoEntry
+	}
+	e := new(entry)
+	if err := rlp.DecodeBytes(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// addedOTAs returns the values of the storage trie current that are not in
+// parent, all of them if parent is nil. A nil current has no values.
+func addedOTAs(parent, current state.Trie, abort func() bool) ([][]byte, error) {
+	if current == nil || (parent != nil && parent.Hash() == current.Hash()) {
+		return nil, nil
+	}
+	it := current.NodeIterator(nil)
+	if parent != nil {
+		it, _ = trie.NewDifferenceIterator(parent.NodeIterator(nil), it)
+	}
+	var added [][]byte
+	for it.Next(true) {
+		if it.Leaf() {
+			if abort != nil && abort() {
+				return nil, errAborted
+			}
+			added = append(added, common.CopyBytes(it.LeafBlob()))
+		}
+	}
+	return added, it.Error()
+}
+
+func entryKey(mptAddr common.Address, pos uint64) []byte {
+	key := make([]byte, len(entryPrefix)+common.AddressLength+8)
+	copy(key, entryPrefix)
+	copy(key[len(entryPrefix):], mptAddr[:])
+	binary.BigEndian.PutUint64(key[len(entryPrefix)+common.AddressLength:], pos)
+	return key
+}
+
+func putEntry(db ethdb.Putter, mptAddr common.Address, pos uint64, e entry) error {
+	data, err := rlp.EncodeToBytes(e)
+	if err != nil {
+		return err
+	}
+	return db.Put(entryKey(mptAddr, pos), data)
+}
+
+func putCount(db ethdb.Putter, mptAddr common.Address, count uint64) error {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, count)
+	return db.Put(append(append([]byte{}, countPrefix...), mptAddr[:]...), data)
+}
+
+func putHead(db ethdb.Putter, head *Head) error {
+	data, err := rlp.EncodeToBytes(head)
+	if err != nil {
+		return err
+	}
+	return db.Put(headKey, data)
+}
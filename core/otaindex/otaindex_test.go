@@ -0,0 +1,243 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package otaindex
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+var (
+	wancoin10, _ = new(big.Int).SetString(vm.Wancoin10, 10)
+	wancoin20, _ = new(big.Int).SetString(vm.Wancoin20, 10)
+)
+
+// testOTA returns a WanAddr unique to n.
+func testOTA(n int) []byte {
+	seed := make([]byte, 8)
+	binary.BigEndian.PutUint64(seed, uint64(n))
+	wanAddr := make([]byte, common.WAddressLength)
+	wanAddr[0] = 0x02
+	copy(wanAddr[1:], crypto.Keccak256(seed))
+	wanAddr[33] = 0x03
+	copy(wanAddr[34:], crypto.Keccak256(seed, seed))
+	return wanAddr
+}
+
+// addOTAs adds OTAs from..to-1 of balance to the state of root and returns the
+// new state.
+func addOTAs(t testing.TB, db ethdb.Database, sdb state.Database, root common.Hash, balance *big.Int, from, to int) *state.StateDB {
+	statedb, err := state.New(root, sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n := from; n < to; n++ {
+		if _, err := vm.AddOTAIfNotExist(statedb, balance, testOTA(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root, err = statedb.CommitTo(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, err = state.New(root, sdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statedb
+}
+
+func TestIndex(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	idx := New(db)
+	addr10, addr20 := vm.OTABalance2ContractAddr(wancoin10), vm.OTABalance2ContractAddr(wancoin20)
+	h0, h1, h2 := common.HexToHash("0x10"), common.HexToHash("0x11"), common.HexToHash("0x12")
+
+	st0 := addOTAs(t, db, sdb, common.Hash{}, wancoin10, 0, 5)
+	if err := idx.Rebuild(st0, h0, 10); err != nil {
+		t.Fatal(err)
+	}
+	st1 := addOTAs(t, db, sdb, st0.IntermediateRoot(true), wancoin10, 5, 8)
+	st1 = addOTAs(t, db, sdb, st1.IntermediateRoot(true), wancoin20, 100, 102)
+	if err := idx.Update(st0, st1, h0, h1, 11); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := []struct {
+		addr   common.Address
+		number uint64
+		want   uint64
+	}{
+		{addr10, 10, 5}, {addr10, 11, 8}, {addr20, 10, 0}, {addr20, 11, 2},
+	}
+	for _, c := range counts {
+		if n, err := idx.OTACount(c.addr, c.number); err != nil || n != c.want {
+			t.Errorf("count of %x at %d: got %d, %v, want %d", c.addr, c.number, n, err, c.want)
+		}
+	}
+	if _, err := idx.OTACount(addr10, 9); err != errBlockNotIndex {
+		t.Errorf("count before the base: got %v, want %v", err, errBlockNotIndex)
+	}
+
+	// the set is drawn from the OTAs of the block, without self or duplicates
+	self := testOTA(3)
+	set, balance, err := vm.SampleOTASet(st1, idx, 11, self[1:33], 7)
+	if err != nil || balance.Cmp(wancoin10) != 0 {
+		t.Fatalf("got %v, %v", balance, err)
+	}
+	seen := make(map[string]bool)
+	for _, ota := range set {
+		if bytes.Equal(ota, self) || seen[string(ota)] {
+			t.Fatalf("set %x holds self or a duplicate", set)
+		}
+		seen[string(ota)] = true
+	}
+	if len(seen) != 7 {
+		t.Fatalf("got %d OTAs, want 7", len(seen))
+	}
+	if _, _, err := vm.SampleOTASet(st1, idx, 11, self[1:33], 8); err == nil || err == vm.ErrOTAIndexUnavailable {
+		t.Errorf("set larger than the OTAs: got %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		set, _, err = vm.SampleOTASet(st0, idx, 10, self[1:33], 4)
+		if err != nil || len(set) != 4 {
+			t.Fatalf("set at block 10: got %d, %v", len(set), err)
+		}
+		for _, ota := range set {
+			if ax := ota[1:33]; !bytes.Equal(st0.GetStateByteArray(addr10, common.BytesToHash(ax)), ota) {
+				t.Fatalf("OTA %x not in the state of block 10", ota)
+			}
+		}
+	}
+
+	// a reorg to block 10 drops the OTAs of block 11
+	if err := idx.Rewind(h0, 10); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := idx.OTACount(addr10, 10); err != nil || n != 5 {
+		t.Errorf("count after rewind: got %d, %v, want 5", n, err)
+	}
+	if _, err := idx.OTACount(addr10, 11); err != errBlockNotIndex {
+		t.Errorf("count of a dropped block: got %v, want %v", err, errBlockNotIndex)
+	}
+	st2 := addOTAs(t, db, sdb, st0.IntermediateRoot(true), wancoin10, 200, 201)
+	if err := idx.Update(st0, st2, h0, h2, 11); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := idx.OTACount(addr10, 11); n != 6 {
+		t.Errorf("count of the new block: got %d, want 6", n)
+	}
+	if ota, _ := idx.OTAAt(addr10, 5); !bytes.Equal(ota, testOTA(200)) {
+		t.Errorf("got OTA %x of the new block, want %x", ota, testOTA(200))
+	}
+
+	// an update not following the head rebuilds the index
+	if err := idx.Update(st1, st1, h1, h1, 12); err != nil {
+		t.Fatal(err)
+	}
+	if head := idx.Head(); head.Base != 12 || head.Hash != h1 {
+		t.Errorf("got head %+v after a rebuild", head)
+	}
+	if n, _ := idx.OTACount(addr10, 12); n != 8 {
+		t.Errorf("count after a rebuild: got %d, want 8", n)
+	}
+}
+
+// refundTx returns a refundCoin transaction ring signed by a new key, along
+// with the key image it discloses.
+func refundTx(t *testing.T, nonce uint64) (*types.Transaction, []byte) {
+	coinABI, err := abi.JSON(strings.NewReader(`[{"constant": false,"type": "function","inputs": [{"name":"RingSignedData","type": "string"},{"name": "Value","type": "uint256"}],"name": "refundCoin","outputs": [{"name": "RingSignedData","type": "string"},{"name": "Value","type": "uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	publicKeys, keyImage, w, q, err := crypto.RingSign([]byte("refund"), key.D, []*ecdsa.PublicKey{&key.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ringSigned := strings.Join([]string{
+		common.ToHex(crypto.FromECDSAPub(publicKeys[0])),
+		common.ToHex(crypto.FromECDSAPub(keyImage)),
+		hexutil.EncodeBig(w[0]),
+		hexutil.EncodeBig(q[0]),
+	}, "+")
+	data, err := coinABI.Pack("refundCoin", ringSigned, wancoin10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.BytesToAddress([]byte{100})
+	return types.NewTransaction(nonce, to, new(big.Int), big.NewInt(300000), big.NewInt(1), data), crypto.FromECDSAPub(keyImage)
+}
+
+func TestImages(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	refunded, image := refundTx(t, 0)
+	failed, failedImage := refundTx(t, 1)
+	other := types.NewTransaction(2, common.HexToAddress("0x01"), new(big.Int), big.NewInt(21000), big.NewInt(1), nil)
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}).WithBody(types.Transactions{refunded, failed, other}, nil)
+	receipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful},
+		{Status: types.ReceiptStatusFailed},
+		{Status: types.ReceiptStatusSuccessful},
+	}
+
+	if err := WriteImages(db, block, receipts); err != nil {
+		t.Fatal(err)
+	}
+	if hash := ImageTx(db, image); hash != refunded.Hash() {
+		t.Errorf("got refund %x of the image, want %x", hash, refunded.Hash())
+	}
+	if hash := ImageTx(db, failedImage); hash != (common.Hash{}) {
+		t.Errorf("got refund %x of the image of a failed refund", hash)
+	}
+}
+
+func benchmarkState(b *testing.B, count int) (*Index, *state.StateDB, []byte) {
+	db, _ := ethdb.NewMemDatabase()
+	sdb := state.NewDatabase(db)
+	statedb := addOTAs(b, db, sdb, common.Hash{}, wancoin10, 0, count)
+	idx := New(db)
+	if err := idx.Rebuild(statedb, common.Hash{}, 1); err != nil {
+		b.Fatal(err)
+	}
+	return idx, statedb, testOTA(0)[1:33]
+}
+
+func benchmarkGetOTASet(b *testing.B, count int) {
+	_, statedb, ax := benchmarkState(b, count)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := vm.GetOTASet(statedb, ax, 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkSampleOTASet(b *testing.B, count int) {
+	idx, statedb, ax := benchmarkState(b, count)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := vm.SampleOTASet(statedb, idx, 1, ax, 10); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetOTASet1K(b *testing.B)     { benchmarkGetOTASet(b, 1000) }
+func BenchmarkGetOTASet10K(b *testing.B)    { benchmarkGetOTASet(b, 10000) }
+func BenchmarkSampleOTASet1K(b *testing.B)  { benchmarkSampleOTASet(b, 1000) }
+func BenchmarkSampleOTASet10K(b *testing.B) { benchmarkSampleOTASet(b, 10000) }
@@ -27,6 +27,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
 )
 
 // nonceHeap is a heap.Interface implementation over 64bit unsigned integers for
@@ -352,7 +353,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 }
 
 // InvalidPosTx remove invalidate pos transactions
-func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.Transactions {
+func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, epocher posUtil.SelectLead, signer types.Signer) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPosTransaction(tx.Txtype()) || (*tx.To()) != vm.GetRBAddress() {
 			return false
@@ -363,7 +364,7 @@ func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.T
 			return true
 		}
 
-		err = vm.ValidPosRBTx(stateDB, from, tx.Data())
+		err = vm.ValidPosRBTx(stateDB, epocher, from, tx.Data())
 		return err != nil
 	})
 
@@ -34,6 +34,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
 	"github.com/wanchain/go-wanchain/params"
+	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"gopkg.in/karalabe/cookiejar.v2/collections/prque"
 )
 
@@ -613,7 +614,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	// Check precompile contracts transactions validation
 	if tx.To() != nil {
 		if p := vm.PrecompiledContractsByzantium[*tx.To()]; p != nil {
-			if err = p.ValidTx(pool.currentState, pool.signer, tx); err != nil {
+			if err = p.ValidTx(pool.currentState, pool.epocher(), pool.signer, tx); err != nil {
 				return err
 			}
 		}
@@ -622,6 +623,15 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// epocher returns the epoch leader selection of the chain the pool works on, or
+// nil if the chain has none.
+func (pool *TxPool) epocher() posUtil.SelectLead {
+	if pos, ok := pool.chain.(interface{ Epocher() posUtil.SelectLead }); ok {
+		return pos.Epocher()
+	}
+	return nil
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -954,7 +964,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.epocher(), pool.signer)
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
@@ -1140,7 +1150,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.epocher(), pool.signer)
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
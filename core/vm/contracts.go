@@ -35,6 +35,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"golang.org/x/crypto/ripemd160"
 	"fmt"
 )
@@ -81,7 +82,7 @@ func (c *ecrecover) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), nil
 }
 
-func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ecrecover) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -100,7 +101,7 @@ func (c *sha256hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return h[:], nil
 }
 
-func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *sha256hash) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -120,7 +121,7 @@ func (c *ripemd160hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return common.LeftPadBytes(ripemd.Sum(nil), 32), nil
 }
 
-func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ripemd160hash) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -138,7 +139,7 @@ func (c *dataCopy) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return in, nil
 }
 
-func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *dataCopy) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -247,7 +248,7 @@ func (c *bigModExp) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), nil
 }
 
-func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bigModExp) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -306,7 +307,7 @@ func (c *bn256Add) Run(input []byte, contract *Contract, evm *EVM) ([]byte, erro
 	return res.Marshal(), nil
 }
 
-func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Add) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -328,7 +329,7 @@ func (c *bn256ScalarMul) Run(input []byte, contract *Contract, evm *EVM) ([]byte
 	return res.Marshal(), nil
 }
 
-func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256ScalarMul) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -380,7 +381,7 @@ func (c *bn256Pairing) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return false32Byte, nil
 }
 
-func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Pairing) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	return nil
 }
 
@@ -549,7 +550,7 @@ func (c *wanchainStampSC) Run(in []byte, contract *Contract, env *EVM) ([]byte,
 	return nil, errMethodId
 }
 
-func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanchainStampSC) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -692,7 +693,7 @@ func (c *wanCoinSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return nil, errMethodId
 }
 
-func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanCoinSC) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
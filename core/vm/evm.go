@@ -25,6 +25,8 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // emptyCodeHash is used by create to ensure deployment is disallowed to already
@@ -76,6 +78,11 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+
+	// PoS information
+	Epocher    util.SelectLead  // Provides the epoch leaders and random proposers of the node
+	PosDb      *posdb.Db        // Provides the PoS local db of the node, may be nil
+	EpochJumps *util.EpochJumps // Provides the epoch jumps of the chain halt recoveries up to the block, may be nil
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -148,6 +155,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
 	}
+	if tracer := evm.frameTracer(); tracer != nil {
+		tracer.CaptureEnter(CALL, caller.Address(), addr, input, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 
 	var (
 		to       = AccountRef(addr)
@@ -215,6 +226,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if !evm.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
 	}
+	if tracer := evm.frameTracer(); tracer != nil {
+		tracer.CaptureEnter(CALLCODE, caller.Address(), addr, input, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 
 	var (
 		snapshot = evm.StateDB.Snapshot()
@@ -249,6 +264,10 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if tracer := evm.frameTracer(); tracer != nil {
+		tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 
 	var (
 		snapshot = evm.StateDB.Snapshot()
@@ -281,6 +300,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	if tracer := evm.frameTracer(); tracer != nil {
+		tracer.CaptureEnter(STATICCALL, caller.Address(), addr, input, gas, nil)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	// Make sure the readonly is only set if we aren't in readonly yet
 	// this makes also sure that the readonly flag isn't removed for
 	// child calls.
@@ -331,6 +354,10 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	if !evm.StateDB.Empty(contractAddr) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
+	if tracer := evm.frameTracer(); tracer != nil {
+		tracer.CaptureEnter(CREATE, caller.Address(), contractAddr, code, gas, value)
+		defer func() { tracer.CaptureExit(ret, gas-leftOverGas, err) }()
+	}
 	// Create a new account on the state
 	snapshot := evm.StateDB.Snapshot()
 	evm.StateDB.CreateAccount(contractAddr)
@@ -382,6 +409,16 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// frameTracer returns the tracer to notify of the call frames, nil if the
+// tracer of the EVM does not follow them.
+func (evm *EVM) frameTracer() FrameTracer {
+	if !evm.vmConfig.Debug {
+		return nil
+	}
+	tracer, _ := evm.vmConfig.Tracer.(FrameTracer)
+	return tracer
+}
+
 // ChainConfig returns the evmironment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
@@ -89,6 +89,15 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error
 }
 
+// FrameTracer is a Tracer also notified of every call frame entered and left,
+// the first one being the frame of the message itself. Unlike steps, frames
+// include the calls of precompiled contracts, which run no opcodes.
+type FrameTracer interface {
+	Tracer
+	CaptureEnter(typ OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int)
+	CaptureExit(output []byte, gasUsed uint64, err error)
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
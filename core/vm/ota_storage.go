@@ -8,7 +8,7 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
-
+	"sort"
 	"strconv"
 
 	"github.com/wanchain/go-wanchain/common"
@@ -23,6 +23,10 @@ var (
 	ErrInvalidOTAAX     = errors.New("invalid OTA AX")
 	ErrOTAExistAlready  = errors.New("OTA exist already")
 	ErrOTABalanceIsZero = errors.New("OTA balance is 0")
+
+	// ErrOTAIndexUnavailable is returned by SampleOTASet when the index cannot
+	// give the OTA set at the block, GetOTASet gives it from the storage instead.
+	ErrOTAIndexUnavailable = errors.New("OTA index unavailable")
 )
 
 // OTABalance2ContractAddr convert ota balance to ota storage address
@@ -38,6 +42,20 @@ func OTABalance2ContractAddr(balance *big.Int) common.Address {
 	//	return common.BigToAddress(balance)
 }
 
+// OTAStorageAddrs returns the ota storage addresses of all the wancoin and stamp
+// denominations, in ascending order.
+func OTAStorageAddrs() []common.Address {
+	addrs := make([]common.Address, 0, len(WanCoinValueSet)+len(StampValueSet))
+	for _, set := range []map[string]string{WanCoinValueSet, StampValueSet} {
+		for _, value := range set {
+			balance, _ := new(big.Int).SetString(value, 10)
+			addrs = append(addrs, OTABalance2ContractAddr(balance))
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
 // GetAXFromWanAddr retrieve ota AX from ota WanAddr
 func GetAXFromWanAddr(otaWanAddr []byte) ([]byte, error) {
 	if len(otaWanAddr) != common.WAddressLength {
@@ -434,6 +452,83 @@ func GetOTASet(statedb StateDB, otaAX []byte, setNum int) (otaWanAddrs [][]byte,
 	}
 }
 
+// OTAIndex gives the OTAs of every ota storage address in the order they were
+// added to the chain.
+type OTAIndex interface {
+	// OTACount returns the number of OTAs of mptAddr at block number.
+	OTACount(mptAddr common.Address, number uint64) (uint64, error)
+	// OTAAt returns the WanAddr of the OTA of mptAddr at position pos.
+	OTAAt(mptAddr common.Address, pos uint64) ([]byte, error)
+}
+
+// SampleOTASet retrieves the same set as GetOTASet, following the same rules,
+// from index at block number, the block of statedb. The set is drawn uniformly
+// from the OTAs of the balance, reading setNum entries of the index or one more
+// instead of the whole ota storage. ErrOTAIndexUnavailable is returned if index
+// does not cover the block or does not match statedb.
+func SampleOTASet(statedb StateDB, index OTAIndex, number uint64, otaAX []byte, setNum int) (otaWanAddrs [][]byte, balance *big.Int, err error) {
+	if statedb == nil || index == nil {
+		return nil, nil, ErrUnknown
+	}
+	if len(otaAX) != common.HashLength {
+		return nil, nil, ErrInvalidOTAAX
+	}
+
+	balance, err = GetOtaBalanceFromAX(statedb, otaAX)
+	if err != nil {
+		return nil, nil, err
+	} else if balance == nil || balance.Cmp(common.Big0) == 0 {
+		return nil, nil, errors.New("can't find ota address balance!")
+	}
+
+	mptAddr := OTABalance2ContractAddr(balance)
+	count, err := index.OTACount(mptAddr, number)
+	if err != nil {
+		log.Debug("SampleOTASet", "mptAddr", common.ToHex(mptAddr[:]), "err", err)
+		return nil, nil, ErrOTAIndexUnavailable
+	}
+	if count == 0 {
+		return nil, balance, errors.New("no ota exist! balance:" + balance.String())
+	} else if uint64(setNum) >= count {
+		return nil, balance, errors.New("too more required ota number! balance:" + balance.String() +
+			", exist count:" + strconv.FormatUint(count, 10))
+	}
+
+	// partial Fisher-Yates shuffle of the positions, the swapped ones only
+	// are kept
+	swapped := make(map[uint64]uint64, setNum+1)
+	position := func(i uint64) uint64 {
+		if pos, ok := swapped[i]; ok {
+			return pos
+		}
+		return i
+	}
+	otaWanAddrs = make([][]byte, 0, setNum)
+	for i := uint64(0); len(otaWanAddrs) < setNum; i++ {
+		if i == count {
+			// the index holds self more than once
+			return nil, nil, ErrOTAIndexUnavailable
+		}
+		j := i + uint64(rand.Int63n(int64(count-i)))
+		pos := position(j)
+		swapped[j] = position(i)
+
+		value, err := index.OTAAt(mptAddr, pos)
+		if err != nil || len(value) != common.WAddressLength {
+			return nil, nil, ErrOTAIndexUnavailable
+		}
+		ax, _ := GetAXFromWanAddr(value)
+		if !bytes.Equal(statedb.GetStateByteArray(mptAddr, common.BytesToHash(ax)), value) {
+			return nil, nil, ErrOTAIndexUnavailable
+		}
+		if bytes.Equal(ax, otaAX) {
+			continue
+		}
+		otaWanAddrs = append(otaWanAddrs, value)
+	}
+	return otaWanAddrs, balance, nil
+}
+
 // CheckOTAImageExist checks ota image key exist already or not
 func CheckOTAImageExist(statedb StateDB, otaImage []byte) (bool, []byte, error) {
 	if statedb == nil || len(otaImage) == 0 {
@@ -107,7 +107,7 @@ func (p *PosControl) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	copy(methodId[:], input[:4])
 
 	if methodId == upgradeWhiteEpochLeaderId {
-		info, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], evm.Time.Uint64())
+		info, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], evm.EpochJumps, evm.Time.Uint64())
 		if err != nil {
 			return nil, err
 		}
@@ -117,7 +117,7 @@ func (p *PosControl) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, errMethodId
 }
 
-func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosControl) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -127,7 +127,7 @@ func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	copy(methodId[:], input[:4])
 
 	if methodId == upgradeWhiteEpochLeaderId {
-		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], uint64(time.Now().Unix()))
+		_, err := p.upgradeWhiteEpochLeaderParseAndValid(input[4:], epochJumps(epocher), uint64(time.Now().Unix()))
 		if err != nil {
 			return errors.New("upgradeWhiteEpochLeaderParseAndValid verify failed")
 		}
@@ -136,8 +136,8 @@ func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	return errParameters
 }
 
-func posControlCheckEpoch(epochId uint64, time uint64) bool {
-	eid, _ := util.CalEpochSlotID(time)
+func posControlCheckEpoch(jumps *util.EpochJumps, epochId uint64, time uint64) bool {
+	eid, _ := jumps.CalEpochSlotID(time)
 	if  eid+posconfig.PosUpgradeEpochID >  epochId { // must send tx some epochs in advance.
 		return false
 	}
@@ -158,7 +158,7 @@ func (p *PosControl) upgradeWhiteEpochLeader(info *UpgradeWhiteEpochLeaderParam,
 	return nil, nil
 }
 
-func (p *PosControl) upgradeWhiteEpochLeaderParseAndValid(payload []byte, time uint64) (*UpgradeWhiteEpochLeaderParam, error) {
+func (p *PosControl) upgradeWhiteEpochLeaderParseAndValid(payload []byte, jumps *util.EpochJumps, time uint64) (*UpgradeWhiteEpochLeaderParam, error) {
 	var info UpgradeWhiteEpochLeaderParam
 	err := posControlAbi.UnpackInput(&info, "upgradeWhiteEpochLeader", payload)
 	if err != nil {
@@ -174,7 +174,7 @@ func (p *PosControl) upgradeWhiteEpochLeaderParseAndValid(payload []byte, time u
 	if wlCount < posconfig.MinEpHold || wlCount > posconfig.MaxEpHold {
 		return nil, errors.New("wlCount out of range")
 	}
-	if !posControlCheckEpoch(info.EpochId.Uint64(), time) {
+	if !posControlCheckEpoch(jumps, info.EpochId.Uint64(), time) {
 		return nil, errors.New("wrong epoch for upgradeWhiteEpochLeader")
 	}
 	return &info, nil
@@ -319,8 +319,11 @@ func (p *PosStaking) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, errMethodId
 }
 
-func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
-	input := tx.Data()
+func (p *PosStaking) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
+	return p.validInput(tx.Data())
+}
+
+func (p *PosStaking) validInput(input []byte) error {
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
 	}
@@ -331,7 +334,7 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	if methodId == stakeInId {
 		_, err := p.stakeInParseAndValid(input[4:])
 		if err != nil {
-			return errors.New("stakein verify failed")
+			return errors.New("stakein verify failed " + err.Error())
 		}
 		return nil
 	} else if methodId == stakeAppendId {
@@ -355,13 +358,13 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 	} else if methodId == delegateInId {
 		_, err := p.delegateInParseAndValid(input[4:])
 		if err != nil {
-			return errors.New("delegateIn verify failed")
+			return errors.New("delegateIn verify failed " + err.Error())
 		}
 		return nil
 	} else if methodId == delegateOutId {
 		_, err := p.delegateOutParseAndValid(input[4:])
 		if err != nil {
-			return errors.New("delegateOut verify failed")
+			return errors.New("delegateOut verify failed " + err.Error())
 		}
 		return nil
 	}
@@ -370,6 +373,17 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 }
 
 
+// GetPosStakingAbi returns the ABI of the PoS staking contract.
+func GetPosStakingAbi() abi.ABI {
+	return cscAbi
+}
+
+// ValidPosStakingInput checks the input of a call to the PoS staking contract
+// with the rules applied to its transactions.
+func ValidPosStakingInput(input []byte) error {
+	return (&PosStaking{}).validInput(input)
+}
+
 func (p *PosStaking) saveStakeInfo(evm *EVM, stakerInfo *StakerInfo) error {
 	infoBytes, err := rlp.EncodeToBytes(stakerInfo)
 	if err != nil {
@@ -409,7 +423,7 @@ func (p *PosStaking) StakeUpdate(payload []byte, contract *Contract, evm *EVM) (
 		return nil, errors.New("Cannot update from another account")
 	}
 
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 	if eidNow > stakerInfo.StakingEpoch+stakerInfo.LockEpochs-UpdateDelay {
 		return nil, errors.New("cannot change at the last 3 epoch.")
 	}
@@ -432,7 +446,7 @@ func (p *PosStaking) PartnerIn(payload []byte, contract *Contract, evm *EVM) ([]
 		return nil, err
 	}
 
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 	realLockEpoch := stakerInfo.LockEpochs - (eidNow + JoinDelay - stakerInfo.StakingEpoch)
 	if realLockEpoch < 0 || realLockEpoch > PSMaxEpochNum {
 		return nil, errors.New("Wrong lock Epochs")
@@ -488,7 +502,7 @@ func (p *PosStaking) StakeAppend(payload []byte, contract *Contract, evm *EVM) (
 
 	// add origen Amount
 	stakerInfo.Amount.Add(stakerInfo.Amount, contract.Value())
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 	realLockEpoch := stakerInfo.LockEpochs - (eidNow + JoinDelay - stakerInfo.StakingEpoch)
 	if realLockEpoch < 0 || realLockEpoch > PSMaxEpochNum {
 		return nil, errors.New("Wrong lock Epochs")
@@ -529,7 +543,7 @@ func (p *PosStaking) StakeIn(payload []byte, contract *Contract, evm *EVM) ([]by
 	}
 
 	// create stakeholder's information
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 	weight := CalLocktimeWeight(info.LockEpochs.Uint64())
 	stakerInfo := &StakerInfo{
 		Address:        secAddr,
@@ -562,44 +576,21 @@ func (p *PosStaking) DelegateIn(payload []byte, contract *Contract, evm *EVM) ([
 	if err != nil {
 		return nil, err
 	}
-	// check if the validator's feeRate is 100, can't delegatein
-	if stakerInfo.FeeRate == noDelegateFeeRate.Uint64() {
-		return nil, errors.New("Validator don't accept delegation.")
-	}
-	// check if the validator's amount(include partner) is not enough, can't delegatein
-	total := big.NewInt(0).Set(stakerInfo.Amount)
-	for i:=0; i<len(stakerInfo.Partners); i++ {
-		total.Add(total, stakerInfo.Partners[i].Amount)
-	}
-	if total.Cmp(MinValidatorStake) < 0 {
-		return nil, errors.New("Validator don't have enough amount.")
+	if err := CheckDelegateIn(stakerInfo, contract.CallerAddress, contract.Value()); err != nil {
+		return nil, err
 	}
-	//  sender has not delegated by this
-	var info *ClientInfo
-	length := len(stakerInfo.Clients)
 
-	totalDelegated := big.NewInt(0)
-	totalDelegated.Add(totalDelegated, contract.Value())
-	for i := 0; i < length; i++ {
-		totalDelegated.Add(totalDelegated, stakerInfo.Clients[i].Amount)
+	weight := CalLocktimeWeight(PSMinEpochNum)
+	var info *ClientInfo
+	for i := 0; i < len(stakerInfo.Clients); i++ {
 		if stakerInfo.Clients[i].Address == contract.CallerAddress {
-			weight := CalLocktimeWeight(PSMinEpochNum)
 			info = &stakerInfo.Clients[i]
 			info.Amount.Add(info.Amount, contract.Value())
 			info.StakeAmount.Add(info.StakeAmount, big.NewInt(0).Mul(contract.Value(), big.NewInt(int64(weight))))
 		}
 	}
-	// check the totalDelegated <= 5*stakerInfo.Amount
-	if totalDelegated.Cmp(big.NewInt(0).Mul(stakerInfo.Amount, big.NewInt(maxTimeDelegate))) > 0 {
-		return nil, errors.New("over delegate limitation")
-	}
 	if info == nil {
-		// only first delegatein check amount is valid.
-		if contract.value.Cmp(minDelegatorStake) < 0 {
-			return nil, errors.New("low amount")
-		}
 		// save
-		weight := CalLocktimeWeight(PSMinEpochNum)
 		info := &ClientInfo{
 			Address:     contract.CallerAddress,
 			Amount:      contract.value,
@@ -616,6 +607,41 @@ func (p *PosStaking) DelegateIn(payload []byte, contract *Contract, evm *EVM) ([
 	return nil, nil
 }
 
+// CheckDelegateIn checks that from may delegate value to the validator of
+// stakerInfo, as DelegateIn does before recording the delegation.
+func CheckDelegateIn(stakerInfo *StakerInfo, from common.Address, value *big.Int) error {
+	// check if the validator's feeRate is 100, can't delegatein
+	if stakerInfo.FeeRate == noDelegateFeeRate.Uint64() {
+		return errors.New("Validator don't accept delegation.")
+	}
+	// check if the validator's amount(include partner) is not enough, can't delegatein
+	total := big.NewInt(0).Set(stakerInfo.Amount)
+	for i := 0; i < len(stakerInfo.Partners); i++ {
+		total.Add(total, stakerInfo.Partners[i].Amount)
+	}
+	if total.Cmp(MinValidatorStake) < 0 {
+		return errors.New("Validator don't have enough amount.")
+	}
+
+	first := true
+	totalDelegated := big.NewInt(0).Set(value)
+	for i := 0; i < len(stakerInfo.Clients); i++ {
+		totalDelegated.Add(totalDelegated, stakerInfo.Clients[i].Amount)
+		if stakerInfo.Clients[i].Address == from {
+			first = false
+		}
+	}
+	// check the totalDelegated <= 5*stakerInfo.Amount
+	if totalDelegated.Cmp(big.NewInt(0).Mul(stakerInfo.Amount, big.NewInt(maxTimeDelegate))) > 0 {
+		return errors.New("over delegate limitation")
+	}
+	// only first delegatein check amount is valid.
+	if first && value.Cmp(minDelegatorStake) < 0 {
+		return errors.New("low amount")
+	}
+	return nil
+}
+
 func (p *PosStaking) DelegateOut(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	addr, err := p.delegateOutParseAndValid(payload)
 	if err != nil {
@@ -627,7 +653,7 @@ func (p *PosStaking) DelegateOut(payload []byte, contract *Contract, evm *EVM) (
 	}
 
 	length := len(stakerInfo.Clients)
-	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
+	eidNow, _ := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 
 	found := false
 	for i := 0; i < length; i++ {
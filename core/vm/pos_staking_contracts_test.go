@@ -167,6 +167,50 @@ func TestDelegateIn(t *testing.T) {
 	clearDb()
 }
 
+// Tests the checks of a delegation, shared by DelegateIn and the reward
+// simulation.
+func TestCheckDelegateIn(t *testing.T) {
+	validator := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	delegator := common.HexToAddress("0x8b179c2b542f47bb2fb2dc40a3cf648aaae1df16")
+	newStaker := func() *StakerInfo {
+		return &StakerInfo{
+			Address: validator,
+			Amount:  new(big.Int).Set(MinValidatorStake),
+			FeeRate: 10,
+			Clients: []ClientInfo{{Address: delegator, Amount: new(big.Int).Set(minDelegatorStake)}},
+		}
+	}
+	limit := new(big.Int).Mul(MinValidatorStake, big.NewInt(maxTimeDelegate))
+	tests := []struct {
+		name   string
+		modify func(s *StakerInfo)
+		from   common.Address
+		value  *big.Int
+		ok     bool
+	}{
+		{"first delegation", nil, common.Address{}, minDelegatorStake, true},
+		{"first delegation below the minimum", nil, common.Address{}, new(big.Int).Sub(minDelegatorStake, common.Big1), false},
+		{"appended delegation below the minimum", nil, delegator, common.Big1, true},
+		{"up to the limit", nil, common.Address{}, new(big.Int).Sub(limit, minDelegatorStake), true},
+		{"over the limit", nil, common.Address{}, new(big.Int).Sub(limit, new(big.Int).Sub(minDelegatorStake, common.Big1)), false},
+		{"validator not accepting delegations", func(s *StakerInfo) { s.FeeRate = PSNodeleFeeRate }, common.Address{}, minDelegatorStake, false},
+		{"validator below the minimum stake", func(s *StakerInfo) { s.Amount.Sub(s.Amount, common.Big1) }, common.Address{}, minDelegatorStake, false},
+		{"validator with partners", func(s *StakerInfo) {
+			s.Amount.Sub(s.Amount, common.Big1)
+			s.Partners = []PartnerInfo{{Amount: common.Big1}}
+		}, common.Address{}, minDelegatorStake, true},
+	}
+	for _, tt := range tests {
+		staker := newStaker()
+		if tt.modify != nil {
+			tt.modify(staker)
+		}
+		if err := CheckDelegateIn(staker, tt.from, tt.value); (err == nil) != tt.ok {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
 // go test -test.bench=“.×”
 func TestMultiDelegateIn(b *testing.T) {
 	if !reset() {
@@ -0,0 +1,93 @@
+package vm
+
+import (
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// Kinds of pending unbonds.
+const (
+	UnbondStakeOut    = "stakeOut"    // a validator leaving at the end of its lock
+	UnbondPartnerOut  = "partnerOut"  // a partner leaving with its validator
+	UnbondDelegateOut = "delegateOut" // a delegator quitting, or leaving with its validator
+)
+
+// Unbond is a stake the staking contract returns at the stake out of an epoch.
+type Unbond struct {
+	Kind         string
+	Validator    common.Address // address of the staker
+	Address      common.Address // receiver of the funds
+	Amount       *big.Int
+	ReleaseEpoch uint64 // first epoch whose stake out returns the funds
+}
+
+// PendingUnbonds lists the stakes stakers will return, following the rules of
+// the stake out run at every epoch: a validator with a lock not renewed
+// (NextLockEpochs 0) leaves with its partners and delegators at the end of its
+// lock, a delegator quits at its QuitEpoch. Stakers without lock never leave,
+// nor do their delegators.
+func PendingUnbonds(stakers []StakerInfo) []Unbond {
+	unbonds := make([]Unbond, 0)
+	for _, staker := range stakers {
+		if staker.LockEpochs == 0 {
+			continue
+		}
+		end := staker.StakingEpoch + staker.LockEpochs
+		leaving := staker.NextLockEpochs == 0
+		for _, client := range staker.Clients {
+			if client.QuitEpoch == 0 && !leaving {
+				continue
+			}
+			release := client.QuitEpoch
+			if leaving && (release == 0 || release > end) {
+				release = end
+			}
+			unbonds = append(unbonds, Unbond{UnbondDelegateOut, staker.Address, client.Address, client.Amount, release})
+		}
+		if !leaving {
+			continue
+		}
+		for _, partner := range staker.Partners {
+			unbonds = append(unbonds, Unbond{UnbondPartnerOut, staker.Address, partner.Address, partner.Amount, end})
+		}
+		unbonds = append(unbonds, Unbond{UnbondStakeOut, staker.Address, staker.From, staker.Amount, end})
+	}
+	return unbonds
+}
+
+// ReleasedUnbonds lists the stakes returned between two snapshots of the
+// stakers, before and after a stake out run in epochID: the validators gone
+// with their partners and delegators, and the delegators gone from the others.
+func ReleasedUnbonds(before, after []StakerInfo, epochID uint64) []Unbond {
+	remaining := make(map[common.Address]*StakerInfo, len(after))
+	for i := range after {
+		remaining[after[i].Address] = &after[i]
+	}
+	unbonds := make([]Unbond, 0)
+	for _, staker := range before {
+		left := remaining[staker.Address]
+		for _, client := range staker.Clients {
+			if left == nil || !hasClient(left, client.Address) {
+				unbonds = append(unbonds, Unbond{UnbondDelegateOut, staker.Address, client.Address, client.Amount, epochID})
+			}
+		}
+		if left != nil {
+			continue
+		}
+		for _, partner := range staker.Partners {
+			unbonds = append(unbonds, Unbond{UnbondPartnerOut, staker.Address, partner.Address, partner.Amount, epochID})
+		}
+		unbonds = append(unbonds, Unbond{UnbondStakeOut, staker.Address, staker.From, staker.Amount, epochID})
+	}
+	return unbonds
+}
+
+func hasClient(staker *StakerInfo, addr common.Address) bool {
+	for _, client := range staker.Clients {
+		if client.Address == addr {
+			return true
+		}
+	}
+	return false
+}
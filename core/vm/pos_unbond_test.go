@@ -0,0 +1,94 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+)
+
+func TestPendingUnbonds(t *testing.T) {
+	validator, from := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	quitter, staying := common.HexToAddress("0x03"), common.HexToAddress("0x04")
+	partner := common.HexToAddress("0x05")
+	clients := []ClientInfo{
+		{Address: quitter, Amount: big.NewInt(10), QuitEpoch: 12},
+		{Address: staying, Amount: big.NewInt(20)},
+	}
+
+	renewing := StakerInfo{Address: validator, From: from, Amount: big.NewInt(100),
+		LockEpochs: 7, NextLockEpochs: 7, StakingEpoch: 3, Clients: clients}
+	unbonds := PendingUnbonds([]StakerInfo{renewing})
+	if len(unbonds) != 1 {
+		t.Fatalf("got %d unbonds of a renewing validator, want 1", len(unbonds))
+	}
+	if u := unbonds[0]; u.Kind != UnbondDelegateOut || u.Address != quitter || u.Validator != validator ||
+		u.Amount.Int64() != 10 || u.ReleaseEpoch != 12 {
+		t.Errorf("got unbond %+v", u)
+	}
+
+	// a validator leaving at epoch 10 takes its delegators and partners along
+	leaving := renewing
+	leaving.NextLockEpochs = 0
+	leaving.Partners = []PartnerInfo{{Address: partner, Amount: big.NewInt(50)}}
+	want := []Unbond{
+		{UnbondDelegateOut, validator, quitter, big.NewInt(10), 10},
+		{UnbondDelegateOut, validator, staying, big.NewInt(20), 10},
+		{UnbondPartnerOut, validator, partner, big.NewInt(50), 10},
+		{UnbondStakeOut, validator, from, big.NewInt(100), 10},
+	}
+	unbonds = PendingUnbonds([]StakerInfo{leaving})
+	if len(unbonds) != len(want) {
+		t.Fatalf("got %d unbonds of a leaving validator, want %d", len(unbonds), len(want))
+	}
+	for i, u := range unbonds {
+		w := want[i]
+		if u.Kind != w.Kind || u.Validator != w.Validator || u.Address != w.Address ||
+			u.Amount.Cmp(w.Amount) != 0 || u.ReleaseEpoch != w.ReleaseEpoch {
+			t.Errorf("unbond %d: got %+v, want %+v", i, u, w)
+		}
+	}
+
+	// stakers without lock never leave
+	unlocked := leaving
+	unlocked.LockEpochs = 0
+	if unbonds = PendingUnbonds([]StakerInfo{unlocked}); len(unbonds) != 0 {
+		t.Errorf("got %d unbonds of a validator without lock, want 0", len(unbonds))
+	}
+}
+
+func TestReleasedUnbonds(t *testing.T) {
+	validator, from := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	quitter, staying := common.HexToAddress("0x03"), common.HexToAddress("0x04")
+	other := common.HexToAddress("0x05")
+	before := []StakerInfo{
+		{Address: validator, From: from, Amount: big.NewInt(100), Clients: []ClientInfo{
+			{Address: quitter, Amount: big.NewInt(10)},
+			{Address: staying, Amount: big.NewInt(20)},
+		}},
+		{Address: other, From: other, Amount: big.NewInt(200), Clients: []ClientInfo{
+			{Address: quitter, Amount: big.NewInt(30)},
+		}},
+	}
+	after := []StakerInfo{
+		{Address: validator, From: from, Amount: big.NewInt(100), Clients: []ClientInfo{
+			{Address: staying, Amount: big.NewInt(20)},
+		}},
+	}
+	want := []Unbond{
+		{UnbondDelegateOut, validator, quitter, big.NewInt(10), 9},
+		{UnbondDelegateOut, other, quitter, big.NewInt(30), 9},
+		{UnbondStakeOut, other, other, big.NewInt(200), 9},
+	}
+	unbonds := ReleasedUnbonds(before, after, 9)
+	if len(unbonds) != len(want) {
+		t.Fatalf("got %d released unbonds, want %d", len(unbonds), len(want))
+	}
+	for i, u := range unbonds {
+		w := want[i]
+		if u.Kind != w.Kind || u.Validator != w.Validator || u.Address != w.Address ||
+			u.Amount.Cmp(w.Amount) != 0 || u.ReleaseEpoch != w.ReleaseEpoch {
+			t.Errorf("unbond %d: got %+v, want %+v", i, u, w)
+		}
+	}
+}
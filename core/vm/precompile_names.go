@@ -0,0 +1,59 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+)
+
+// precompileNames names the precompiled contracts for traces.
+var precompileNames = map[common.Address]string{
+	ecrecoverPrecompileAddr:      "ecrecover",
+	sha256hashPrecompileAddr:     "sha256",
+	ripemd160hashPrecompileAddr:  "ripemd160",
+	dataCopyPrecompileAddr:       "identity",
+	bigModExpPrecompileAddr:      "modexp",
+	bn256AddPrecompileAddr:       "bn256Add",
+	bn256ScalarMulPrecompileAddr: "bn256ScalarMul",
+	bn256PairingPrecompileAddr:   "bn256Pairing",
+
+	wanCoinPrecompileAddr:  "wanCoin",
+	wanStampPrecompileAddr: "wanStamp",
+
+	WanCscPrecompileAddr:       "staking",
+	PosControlPrecompileAddr:   "posControl",
+	slotLeaderPrecompileAddr:   "slotLeader",
+	randomBeaconPrecompileAddr: "randomBeacon",
+}
+
+// PrecompileCall returns the name of the precompiled contract at addr, empty
+// if there is none, and for the Wanchain ones the name of the method input
+// calls, empty if unknown.
+func PrecompileCall(addr common.Address, input []byte) (contract, method string) {
+	contract = precompileNames[addr]
+	if contract == "" || len(input) < 4 {
+		return contract, ""
+	}
+	var contractAbi *abi.ABI
+	switch addr {
+	case wanCoinPrecompileAddr:
+		contractAbi = &coinAbi
+	case wanStampPrecompileAddr:
+		contractAbi = &stampAbi
+	case WanCscPrecompileAddr:
+		contractAbi = &cscAbi
+	case PosControlPrecompileAddr:
+		contractAbi = &posControlAbi
+	case slotLeaderPrecompileAddr:
+		contractAbi = &slotLeaderAbi
+	case randomBeaconPrecompileAddr:
+		contractAbi = &rbSCAbi
+	default:
+		return contract, ""
+	}
+	if m, err := contractAbi.MethodById(input[:4]); err == nil {
+		method = m.Name
+	}
+	return contract, method
+}
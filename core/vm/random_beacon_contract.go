@@ -109,6 +109,7 @@ var (
 	errUnRlpEncryptShare   = errors.New("rlp decode encrypt share failed")
 	errInvalidCommitBytes  = errors.New("invalid dkg commit bytes")
 	errInvalidEncryptShareBytes = errors.New("invalid dkg encrypt share bytes")
+	errNoEpocher           = errors.New("no epoch leader selection to read the random proposers from")
 )
 
 // return:
@@ -178,7 +179,7 @@ func (c *RandomBeaconContract) Run(input []byte, contract *Contract, evm *EVM) (
 	}
 }
 
-func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *RandomBeaconContract) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	// in order to improve the transmission speed, return nil directly.
 	return nil
 }
@@ -186,19 +187,19 @@ func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx
 //
 // params or gas check functions
 //
-func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
+func ValidPosRBTx(stateDB StateDB, epocher util.SelectLead, from common.Address, payload []byte) error {
 	log.Debug("ValidPosRBTx")
 	var methodId [4]byte
 	copy(methodId[:], payload[:4])
 
 	if methodId == dkg1Id {
-		_, err := validDkg1(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg1(stateDB, epocher, epochJumps(epocher), uint64(time.Now().Unix()), from, payload[4:])
 		return err
 	} else if methodId == dkg2Id {
-		_, err := validDkg2(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg2(stateDB, epocher, epochJumps(epocher), uint64(time.Now().Unix()), from, payload[4:])
 		return err
 	} else if methodId == sigShareId {
-		_, _, _, err := validSigShare(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, _, _, err := validSigShare(stateDB, epocher, epochJumps(epocher), uint64(time.Now().Unix()), from, payload[4:])
 		return err
 	} else {
 		return errParameters
@@ -216,7 +217,7 @@ func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
 // 'caller' is the caller of DKG1. It should be set as Contract.CallerAddress
 // when called by precompiled contract. And should be set as tx's sender when
 // called by tx pool.
-func validDkg1(stateDB StateDB, time uint64, caller common.Address,
+func validDkg1(stateDB StateDB, epocher util.SelectLead, jumps *util.EpochJumps, time uint64, caller common.Address,
 	payload []byte) (*RbDKG1FlatTxPayload, error) {
 
 	var dkg1FlatParam RbDKG1FlatTxPayload
@@ -234,15 +235,18 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	pid := dkg1Param.ProposerId
 
 	// todo : check pks element validity
-	pks := util.GetEpocherInst().GetRBProposerG1(eid)
+	if epocher == nil {
+		return nil, logError(errNoEpocher)
+	}
+	pks := epocher.GetRBProposerG1(eid)
 
 	// 1. EpochId: weather in a wrong time
-	if !isValidEpochStageVar(eid, RbDkg1Stage, time) {
+	if !isValidEpochStageVar(jumps, eid, RbDkg1Stage, time) {
 		return nil, logError(errors.New("invalid rb stage, expect RbDkg1Stage. epochId " + strconv.FormatUint(eid, 10)))
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(epocher, pks, eid, pid, caller) {
 		return nil, logError(errors.New("invalid proposer, proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -278,7 +282,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg1FlatParam, nil
 }
 
-func validDkg2(stateDB StateDB, time uint64, caller common.Address,
+func validDkg2(stateDB StateDB, epocher util.SelectLead, jumps *util.EpochJumps, time uint64, caller common.Address,
 	payload []byte) (*RbDKG2FlatTxPayload, error) {
 
 	var dkg2FlatParam RbDKG2FlatTxPayload
@@ -296,14 +300,17 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	pid := dkg2Param.ProposerId
 
 	// todo : check pks element validity
-	pks := util.GetEpocherInst().GetRBProposerG1(eid)
+	if epocher == nil {
+		return nil, logError(errNoEpocher)
+	}
+	pks := epocher.GetRBProposerG1(eid)
 	// 1. EpochId: weather in a wrong time
-	if !isValidEpochStageVar(eid, RbDkg2Stage, time) {
+	if !isValidEpochStageVar(jumps, eid, RbDkg2Stage, time) {
 		return nil, logError(errors.New("invalid rb stage, expect RbDkg2Stage. error epochId " + strconv.FormatUint(eid, 10)))
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(epocher, pks, eid, pid, caller) {
 		return nil, logError(errors.New("error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -336,7 +343,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg2FlatParam, nil
 }
 
-func validSigShare(stateDB StateDB, time uint64, caller common.Address,
+func validSigShare(stateDB StateDB, epocher util.SelectLead, jumps *util.EpochJumps, time uint64, caller common.Address,
 	payload []byte) (*RbSIGTxPayload, []bn256.G1, []RbCijDataCollector, error) {
 
 	var sigShareParam RbSIGTxPayload
@@ -349,14 +356,17 @@ func validSigShare(stateDB StateDB, time uint64, caller common.Address,
 	pid := sigShareParam.ProposerId
 
 	// todo : check pks element validity
-	pks := util.GetEpocherInst().GetRBProposerG1(eid)
+	if epocher == nil {
+		return nil, nil, nil, logError(errNoEpocher)
+	}
+	pks := epocher.GetRBProposerG1(eid)
 	// 1. EpochId: weather in a wrong time
-	if !isValidEpochStageVar(eid, RbSignStage, time) {
+	if !isValidEpochStageVar(jumps, eid, RbSignStage, time) {
 		return nil, nil, nil, logError(errors.New("invalid rb stage, expect RbSignStage. error epochId " + strconv.FormatUint(eid, 10)))
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(epocher, pks, eid, pid, caller) {
 		return nil, nil, nil, logError(errors.New(" error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -770,8 +780,8 @@ func Dkg2ToDkg2Flat(d *RbDKG2TxPayload) *RbDKG2FlatTxPayload {
 // in file help functions
 //
 // check time in the right stage, dkg1 --- 1k,2k slot, dkg2 --- 5k,6k slot, sig --- 8k,9k slot
-func isValidEpochStage(epochId uint64, stage int, time uint64) bool {
-	eid, sid := util.CalEpochSlotID(time)
+func isValidEpochStage(jumps *util.EpochJumps, epochId uint64, stage int, time uint64) bool {
+	eid, sid := jumps.CalEpochSlotID(time)
 	if epochId != eid {
 		return false
 	}
@@ -784,11 +794,20 @@ func isValidEpochStage(epochId uint64, stage int, time uint64) bool {
 	return true
 }
 
-func isInRandomGroup(pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
+// epochJumps returns the epoch jumps of the chain of epocher, used to check the
+// transactions against the current time out of a block.
+func epochJumps(epocher util.SelectLead) *util.EpochJumps {
+	if e, ok := epocher.(interface{ EpochJumps() *util.EpochJumps }); ok {
+		return e.EpochJumps()
+	}
+	return nil
+}
+
+func isInRandomGroup(epocher util.SelectLead, pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
 	if len(pks) <= int(proposerId) {
 		return false
 	}
-	pk1 := util.GetEpocherInst().GetProposerBn256PK(epochId, uint64(proposerId), address)
+	pk1 := epocher.GetProposerBn256PK(epochId, uint64(proposerId), address)
 	if pk1 != nil {
 		return bytes.Equal(pk1, pks[proposerId].Marshal())
 	}
@@ -838,7 +857,7 @@ var isInRandomGroupVar = isInRandomGroup
 // dkg1: happens in 0~2k-1 slots, send the commits to chain
 func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg1")
-	dkg1FlatParam, err := validDkg1(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg1FlatParam, err := validDkg1(evm.StateDB, evm.Epocher, evm.EpochJumps, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -861,7 +880,7 @@ func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM
 // dkg2: happens in 5k~7k-1 slots, send the proof, enShare to chain
 func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg2")
-	dkg2FlatParam, err := validDkg2(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg2FlatParam, err := validDkg2(evm.StateDB, evm.Epocher, evm.EpochJumps, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -884,7 +903,7 @@ func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM
 // sigShare: sign, happens in 8k~10k-1 slots, generate R if enough signers
 func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("sigShare")
-	sigShareParam, pks, dkgData, err := validSigShare(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	sigShareParam, pks, dkgData, err := validSigShare(evm.StateDB, evm.Epocher, evm.EpochJumps, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
 		return nil, err
 	}
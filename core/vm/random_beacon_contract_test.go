@@ -14,6 +14,7 @@ import (
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 	"math/big"
 	mrand "math/rand"
@@ -245,10 +246,10 @@ func getRBMMock(_ StateDB, epochId uint64) ([]byte, error) {
 }
 
 
-func isValidEpochStageMock(_ uint64, _ int, _ uint64) bool {
+func isValidEpochStageMock(_ *util.EpochJumps, _ uint64, _ int, _ uint64) bool {
 	return true
 }
-func isInRandomGroupMock(_ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
+func isInRandomGroupMock(_ util.SelectLead, _ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
 	return true
 }
 
@@ -422,7 +423,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg1(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, evm.Epocher, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -444,7 +445,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg2(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, evm.Epocher, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -466,7 +467,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(sigShareParam)
 		payload := buildSig(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, evm.Epocher, contract.CallerAddress, payload)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -100,6 +100,7 @@ var (
 	ErrInvalidTx1Range                 = errors.New("slot leader tx1 is not in invalid range")
 	ErrInvalidTx2Range                 = errors.New("slot leader tx2 is not in invalid range")
 	ErrInvalidProof                    = errors.New("proof is bigZero")
+	ErrNoEpocher                       = errors.New("epoch leader selection is not available")
 )
 
 func init() {
@@ -132,13 +133,13 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	from = contract.CallerAddress
 
 	if methodId == stgOneIdArr {
-		err := c.validTxStg1ByData(evm.StateDB, from, in[:])
+		err := c.validTxStg1ByData(evm.StateDB, evm.Epocher, from, in[:])
 		if err != nil {
 			return nil, err
 		}
 		return c.handleStgOne(in[:], contract, evm) //Do not use [4:] because it has do it in function
 	} else if methodId == stgTwoIdArr {
-		err := c.validTxStg2ByData(evm.StateDB, from, in[:])
+		err := c.validTxStg2ByData(evm.StateDB, evm.Epocher, from, in[:])
 		if err != nil {
 			return nil, err
 		}
@@ -150,14 +151,14 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	return nil, errMethodId
 }
 
-func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) ValidTx(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	var methodId [4]byte
 	copy(methodId[:], tx.Data()[:4])
 
 	if methodId == stgOneIdArr {
-		return c.validTxStg1(stateDB, signer, tx)
+		return c.validTxStg1(stateDB, epocher, signer, tx)
 	} else if methodId == stgTwoIdArr {
-		return c.validTxStg2(stateDB, signer, tx)
+		return c.validTxStg2(stateDB, epocher, signer, tx)
 	} else {
 		log.SyslogErr("slotLeaderSC:ValidTx", "", errMethodId.Error())
 		return errMethodId
@@ -190,7 +191,7 @@ func (c *slotLeaderSC) handleStgOne(in []byte, contract *Contract, evm *EVM) ([]
 		return nil, err
 	}
 
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.PosDb, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgOne save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -221,7 +222,7 @@ func (c *slotLeaderSC) handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]
 	if err != nil {
 		return nil, err
 	}
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.PosDb, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgTwo save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -232,17 +233,17 @@ func (c *slotLeaderSC) handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]
 	return nil, nil
 }
 
-func (c *slotLeaderSC) validTxStg1(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) validTxStg1(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	sender, err := signer.Sender(tx)
 	if err != nil {
 		log.SyslogErr("slotLeaderSC:validTxStg1", "", err.Error())
 		return err
 	}
 
-	return c.validTxStg1ByData(stateDB, sender, tx.Data())
+	return c.validTxStg1ByData(stateDB, epocher, sender, tx.Data())
 }
 
-func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, payload []byte) error {
+func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, epocher util.SelectLead, from common.Address, payload []byte) error {
 
 	epochIDBuf, selfIndexBuf, err := RlpGetStage1IDFromTx(payload[:])
 	if err != nil {
@@ -250,7 +251,7 @@ func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, p
 		return err
 	}
 
-	if !InEpochLeadersOrNotByAddress(convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
+	if !InEpochLeadersOrNotByAddress(epocher, convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
 		log.SyslogErr("validTxStg1 failed")
 		return ErrIllegalSender
 	}
@@ -264,14 +265,14 @@ func (c *slotLeaderSC) validTxStg1ByData(stateDB StateDB, from common.Address, p
 	return nil
 }
 
-func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, payload []byte) error {
+func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, epocher util.SelectLead, from common.Address, payload []byte) error {
 	epochID, selfIndex, _, alphaPkis, proofs, err := RlpUnpackStage2DataForTx(payload[:])
 	if err != nil {
 		log.Error("validTxStg2:RlpUnpackStage2DataForTx failed")
 		return err
 	}
 
-	if !InEpochLeadersOrNotByAddress(epochID, selfIndex, from) {
+	if !InEpochLeadersOrNotByAddress(epocher, epochID, selfIndex, from) {
 		log.SyslogErr("validTxStg2:InEpochLeadersOrNotByAddress failed")
 		return ErrIllegalSender
 	}
@@ -302,7 +303,7 @@ func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, p
 	}
 	//Dleq
 
-	buff := util.GetEpocherInst().GetEpochLeaders(epochID)
+	buff := epocher.GetEpochLeaders(epochID)
 	epochLeaders := make([]*ecdsa.PublicKey, len(buff))
 	for i := 0; i < len(buff); i++ {
 		epochLeaders[i] = crypto.ToECDSAPub(buff[i])
@@ -315,13 +316,13 @@ func (c *slotLeaderSC) validTxStg2ByData(stateDB StateDB, from common.Address, p
 	return nil
 }
 
-func (c *slotLeaderSC) validTxStg2(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) validTxStg2(stateDB StateDB, epocher util.SelectLead, signer types.Signer, tx *types.Transaction) error {
 	sender, err := signer.Sender(tx)
 	if err != nil {
 		log.SyslogErr("slotLeaderSC:validTxStg1", "", err.Error())
 		return err
 	}
-	return c.validTxStg2ByData(stateDB, sender, tx.Data())
+	return c.validTxStg2ByData(stateDB, epocher, sender, tx.Data())
 }
 
 // GetSlotLeaderStage2KeyHash use to get SlotLeader Stage 1 KeyHash by epochid and selfindex
@@ -343,9 +344,10 @@ func GetSlotLeaderScAbiString() string {
 	return slotLeaderSCDef
 }
 
-// GetSlotScCallTimes can get this precompile contract called times
-func GetSlotScCallTimes(epochID uint64) uint64 {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+// GetSlotScCallTimes can get this precompile contract called times, as counted
+// in the PoS local db of a node
+func GetSlotScCallTimes(db *posdb.Db, epochID uint64) uint64 {
+	buf, err := db.Get(epochID, scCallTimes)
 	if err != nil {
 		return 0
 	} else {
@@ -396,14 +398,18 @@ func PackStage1Data(input []byte, abiString string) ([]byte, error) {
 	return outBuf, err
 }
 
-func InEpochLeadersOrNotByAddress(epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+func InEpochLeadersOrNotByAddress(epocher util.SelectLead, epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
+	if epocher == nil {
+		log.SyslogErr("InEpochLeadersOrNotByAddress", "error", ErrNoEpocher.Error())
+		return false
+	}
+	epochLeaders := epocher.GetEpochLeaders(epochID)
 	if len(epochLeaders) != posconfig.EpochLeaderCount {
 		log.SyslogWarning("epoch leader is not ready use epoch 0 at InEpochLeadersOrNotByAddress", "epochID", epochID)
-		epochLeaders = util.GetEpocherInst().GetEpochLeaders(0)
+		epochLeaders = epocher.GetEpochLeaders(0)
 	}
 
-	if int64(selfIndex) < 0 || int64(selfIndex) >= posconfig.EpochLeaderCount {
+	if int64(selfIndex) < 0 || int64(selfIndex) >= int64(posconfig.EpochLeaderCount) {
 		log.SyslogErr("InEpochLeadersOrNotByAddress", "selfIndex out of range", int64(selfIndex))
 		return false
 	}
@@ -647,8 +653,11 @@ func getSlotLeaderStageKeyHash(epochID, selfIndex []byte, slotLeaderStage string
 	return crypto.Keccak256Hash(keyBuf.Bytes())
 }
 
-func addSlotScCallTimes(epochID uint64) error {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+func addSlotScCallTimes(db *posdb.Db, epochID uint64) error {
+	if db == nil {
+		return nil
+	}
+	buf, err := db.Get(epochID, scCallTimes)
 	times := uint64(0)
 	if err != nil {
 		if err.Error() != "leveldb: not found" {
@@ -661,12 +670,12 @@ func addSlotScCallTimes(epochID uint64) error {
 
 	times++
 
-	posdb.GetDb().Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
+	db.Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
 	return nil
 }
 
 func isInValidStage(epochID uint64, evm *EVM, kStart uint64, kEnd uint64) bool {
-	eid, sid := util.CalEpochSlotID(evm.Time.Uint64())
+	eid, sid := evm.EpochJumps.CalEpochSlotID(evm.Time.Uint64())
 	if epochID != eid {
 		log.SyslogWarning("Tx epochID is not current epoch", "epochID", eid, "slotID", sid, "currentEpochID", epochID)
 
@@ -684,8 +693,8 @@ func isInValidStage(epochID uint64, evm *EVM, kStart uint64, kEnd uint64) bool {
 }
 
 func updateSlotLeaderStageIndex(evm *EVM, epochID []byte, slotLeaderStageIndexes string, index uint64) error {
-	var sendtrans [posconfig.EpochLeaderCount]bool
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
+	var sendtransGet []bool
 
 	key := getSlotLeaderStageIndexesKeyHash(epochID, slotLeaderStageIndexes)
 	bytes := evm.StateDB.GetStateByteArray(slotLeaderPrecompileAddr, key)
@@ -706,6 +715,10 @@ func updateSlotLeaderStageIndex(evm *EVM, epochID []byte, slotLeaderStageIndexes
 			log.SyslogErr("updateSlotLeaderStageIndex", "rlp.DecodeBytes", err.Error())
 			return err
 		}
+		if len(sendtransGet) != posconfig.EpochLeaderCount {
+			log.SyslogErr("updateSlotLeaderStageIndex", "invalid stage indexes length", len(sendtransGet))
+			return errors.New("invalid slot leader stage indexes")
+		}
 
 		sendtransGet[index] = true
 		value, err := rlp.EncodeToBytes(sendtransGet)
@@ -13,9 +13,6 @@ import (
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/pos/uleaderselection"
 	"math/big"
-	"os"
-	"path"
-	"path/filepath"
 	"testing"
 	"time"
 
@@ -222,22 +219,18 @@ func TestIsInValidStage(t *testing.T) {
 	evm = nil
 }
 func TestAddSlotScCallTimes(t *testing.T) {
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	t.Log("Current dir path ", dir)
-	os.RemoveAll(path.Join(dir, "sl_contract_test"))
-	posdb.NewDb(path.Join(dir, "sl_contract_test"))
+	db := posdb.NewMemoryDbs().Local()
 
 	epochID := uint64(0)
 	loopCount := 10
 	for i := 0; i < loopCount; i++ {
-		addSlotScCallTimes(epochID)
+		addSlotScCallTimes(db, epochID)
 	}
 
-	if intByte, _ := posdb.GetDb().Get(epochID, scCallTimes); convert.BytesToUint64(intByte[:]) != uint64(loopCount) {
+	if intByte, _ := db.Get(epochID, scCallTimes); convert.BytesToUint64(intByte[:]) != uint64(loopCount) {
 		t.Fail()
 	}
 
-	os.RemoveAll(path.Join(dir, "sl_contract_test"))
 }
 
 func TestUpdateSlotLeaderStageIndex(t *testing.T) {
@@ -246,7 +239,7 @@ func TestUpdateSlotLeaderStageIndex(t *testing.T) {
 		stateDb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 	)
 
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	sendtransGet := make([]bool, posconfig.EpochLeaderCount)
 
 	evm := NewEVM(Context{}, stateDb, &params.ChainConfig{ChainId: big1}, Config{Debug: true})
 	epochIDBuf := convert.Uint64ToBytes(uint64(0))
@@ -372,7 +365,7 @@ func TestRlpPackStage2DataForTx(t *testing.T) {
 	pubKey := prvKey.PublicKey
 
 	// pack
-	var alphaPkis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	alphaPkis := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 
 		key, _ := crypto.GenerateKey()
@@ -383,7 +376,7 @@ func TestRlpPackStage2DataForTx(t *testing.T) {
 	proof[0] = big1
 	proof[1] = big4
 
-	stag2Bytes, err := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis[:], proof[:], GetSlotLeaderScAbiString())
+	stag2Bytes, err := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis, proof[:], GetSlotLeaderScAbiString())
 	if err != nil {
 		t.Fail()
 	}
@@ -545,7 +538,7 @@ func TestGetStg2TxAlphaPki(t *testing.T) {
 	}
 
 	// build stage2 data
-	var alphaPkis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	alphaPkis := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
 	alphaPkis[0] = mi0
 	for i := 1; i < posconfig.EpochLeaderCount; i++ {
 		prvKey, _ := crypto.GenerateKey()
@@ -560,7 +553,7 @@ func TestGetStg2TxAlphaPki(t *testing.T) {
 	var proof [2]*big.Int
 	proof[0] = big1
 	proof[1] = big4
-	stg2Bytes, _ := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis[:], proof[:], GetSlotLeaderScAbiString())
+	stg2Bytes, _ := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis, proof[:], GetSlotLeaderScAbiString())
 
 	c.handleStgTwo(stg2Bytes[:], nil, evm)
 
@@ -615,7 +608,7 @@ func TestHandleStgTwo(t *testing.T) {
 	}
 
 	// build stage2 data
-	var alphaPkis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	alphaPkis := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
 	alphaPkis[0] = mi0
 	for i := 1; i < posconfig.EpochLeaderCount; i++ {
 		prvKey, _ := crypto.GenerateKey()
@@ -630,7 +623,7 @@ func TestHandleStgTwo(t *testing.T) {
 	var proof [2]*big.Int
 	proof[0] = big1
 	proof[1] = big4
-	stg2Bytes, _ := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis[:], proof[:], GetSlotLeaderScAbiString())
+	stg2Bytes, _ := RlpPackStage2DataForTx(0, 0, &pubKey, alphaPkis, proof[:], GetSlotLeaderScAbiString())
 
 	testTime = nowTime + (posconfig.Sma2Start+1)*posconfig.SlotTime
 	evm.Time = big.NewInt(0).SetUint64(testTime)
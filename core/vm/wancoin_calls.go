@@ -0,0 +1,72 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// IsWanCoinContract reports whether addr is the wancoin privacy contract.
+func IsWanCoinContract(addr common.Address) bool {
+	return addr == wanCoinPrecompileAddr
+}
+
+// DecodeBuyCoin returns the OTA and the value of a buyCoinNote call to the
+// wancoin contract. The input is not checked against the state.
+func DecodeBuyCoin(in []byte) (otaAddr []byte, value *big.Int, err error) {
+	if len(in) < 4 {
+		return nil, nil, errParameters
+	}
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], in[:4])
+	if methodIdArr != buyIdArr {
+		return nil, nil, errMethodId
+	}
+
+	var outStruct struct {
+		OtaAddr string
+		Value   *big.Int
+	}
+	if err := coinAbi.Unpack(&outStruct, "buyCoinNote", in[4:]); err != nil || outStruct.Value == nil {
+		return nil, nil, errBuyCoin
+	}
+	wanAddr, err := hexutil.Decode(outStruct.OtaAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(wanAddr) != common.WAddressLength {
+		return nil, nil, errBuyCoin
+	}
+	return wanAddr, outStruct.Value, nil
+}
+
+// DecodeRefundCoin returns the key image of the OTA spent by a refundCoin call
+// to the wancoin contract, as stored in the ota image storage. The ring
+// signature is not verified.
+func DecodeRefundCoin(in []byte) (image []byte, err error) {
+	if len(in) < 4 {
+		return nil, errParameters
+	}
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], in[:4])
+	if methodIdArr != refundIdArr {
+		return nil, errMethodId
+	}
+
+	var RefundStruct struct {
+		RingSignedData string
+		Value          *big.Int
+	}
+	if err := coinAbi.Unpack(&RefundStruct, "refundCoin", in[4:]); err != nil {
+		return nil, errRefundCoin
+	}
+	err, _, keyImage, _, _ := DecodeRingSignOut(RefundStruct.RingSignedData)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.FromECDSAPub(keyImage), nil
+}
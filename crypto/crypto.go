@@ -398,6 +398,12 @@ func xScalarHashP(x []byte, pub *ecdsa.PublicKey) (I *ecdsa.PublicKey) {
 	return
 }
 
+// KeyImage returns the key image [x]Hash(P) of the public key P of private key
+// x, which ring signatures by x disclose so that x can not sign twice.
+func KeyImage(x *big.Int, pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	return xScalarHashP(x.Bytes(), pub)
+}
+
 var (
 	ErrInvalidRingSignParams = errors.New("invalid ring sign params")
 	ErrRingSignFail          = errors.New("ring sign fail")
@@ -31,6 +31,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/common/math"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
@@ -360,7 +361,7 @@ type BlockTraceResult struct {
 // TraceArgs holds extra parameters to trace functions
 type TraceArgs struct {
 	*vm.LogConfig
-	Tracer  *string
+	Tracer  *string // JavaScript tracer code, or name of a native tracer (callTracer, prestateTracer, 4byteTracer)
 	Timeout *string
 }
 
@@ -481,52 +482,98 @@ func (t *timeoutError) Error() string {
 	return "Execution time exceeded"
 }
 
+// applyError is returned by traceMessage for a message that could not be
+// applied to the state, unlike one that ran and failed.
+type applyError struct {
+	err error
+}
+
+func (e *applyError) Error() string {
+	return fmt.Sprintf("tracing failed: %v", e.err)
+}
+
 // TraceTransaction returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.Hash, config *TraceArgs) (interface{}, error) {
-	var tracer vm.Tracer
-	if config != nil && config.Tracer != nil {
-		timeout := defaultTraceTimeout
-		if config.Timeout != nil {
-			var err error
-			if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
-				return nil, err
-			}
+	// Retrieve the tx from the chain and the containing block
+	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", txHash)
+	}
+	msg, vmctx, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
+	if err != nil {
+		return nil, err
+	}
+	return api.traceMessage(ctx, msg, vmctx, statedb, new(core.GasPool).AddGas(tx.Gas()), config)
+}
+
+// TraceCall returns the trace of a call executed on the state of the given
+// block, as eth_call would run it, without changing the chain.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args ethapi.CallArgs, blockNr rpc.BlockNumber, config *TraceArgs) (interface{}, error) {
+	statedb, header, err := api.eth.ApiBackend.StateAndHeaderByNumber(ctx, blockNr)
+	if statedb == nil || err != nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", blockNr)
 		}
+		return nil, err
+	}
+	msg := args.ToMessage(api.eth.AccountManager())
+	vmctx := core.NewEVMContext(msg, header, api.eth.BlockChain(), nil)
+
+	return api.traceMessage(ctx, msg, vmctx, statedb, new(core.GasPool).AddGas(math.MaxBig256), config)
+}
 
-		var err error
-		if tracer, err = ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
+// traceMessage runs msg on statedb with the tracer asked for by config, the
+// struct logger by default, and returns the trace. The run is aborted when
+// ctx is done or the timeout of config, 5 seconds by default, expires.
+func (api *PrivateDebugAPI) traceMessage(ctx context.Context, msg core.Message, vmctx vm.Context, statedb *state.StateDB, gp *core.GasPool, config *TraceArgs) (interface{}, error) {
+	var (
+		tracer  vm.Tracer
+		timeout = defaultTraceTimeout
+		err     error
+	)
+	if config != nil && config.Timeout != nil {
+		if timeout, err = time.ParseDuration(*config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if config != nil && config.Tracer != nil {
+		if native, ok := ethapi.NewNativeTracer(*config.Tracer, statedb); ok {
+			tracer = native
+		} else if tracer, err = ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
 			return nil, err
 		}
-
-		// Handle timeouts and RPC cancellations
-		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
-		go func() {
-			<-deadlineCtx.Done()
-			tracer.(*ethapi.JavascriptTracer).Stop(&timeoutError{})
-		}()
-		defer cancel()
 	} else if config == nil {
 		tracer = vm.NewStructLogger(nil)
 	} else {
 		tracer = vm.NewStructLogger(config.LogConfig)
 	}
 
-	// Retrieve the tx from the chain and the containing block
-	tx, blockHash, _, txIndex := core.GetTransaction(api.eth.ChainDb(), txHash)
-	if tx == nil {
-		return nil, fmt.Errorf("transaction %x not found", txHash)
-	}
-	msg, context, statedb, err := api.computeTxEnv(blockHash, int(txIndex))
-	if err != nil {
-		return nil, err
-	}
+	// Run the message with tracing enabled.
+	vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 
-	// Run the transaction with tracing enabled.
-	vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
-	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+	// Handle timeouts and RPC cancellations
+	deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
+	go func() {
+		<-deadlineCtx.Done()
+		vmenv.Cancel()
+		if jst, ok := tracer.(*ethapi.JavascriptTracer); ok {
+			jst.Stop(&timeoutError{})
+		}
+	}()
+	defer cancel()
+
+	ret, gas, failed, err := core.ApplyMessage(vmenv, msg, gp)
 	if err != nil {
-		return nil, fmt.Errorf("tracing failed: %v", err)
+		return nil, &applyError{err}
+	}
+	// An aborted run leaves a partial trace
+	switch deadlineCtx.Err() {
+	case nil:
+	case context.DeadlineExceeded:
+		return nil, &timeoutError{}
+	default:
+		return nil, deadlineCtx.Err()
 	}
 	switch tracer := tracer.(type) {
 	case *vm.StructLogger:
@@ -538,6 +585,8 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, txHash common.
 		}, nil
 	case *ethapi.JavascriptTracer:
 		return tracer.GetResult()
+	case ethapi.NativeTracer:
+		return tracer.GetResult()
 	default:
 		panic(fmt.Sprintf("bad tracer type %T", tracer))
 	}
@@ -18,6 +18,7 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/accounts"
@@ -36,6 +37,10 @@ import (
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
+// errNoBlockConfirmation is returned when the stable block is requested from a
+// node not running the PoS block confirmation.
+var errNoBlockConfirmation = errors.New("stable block requires PoS block confirmation")
+
 // EthApiBackend implements ethapi.Backend for full nodes
 type EthApiBackend struct {
 	eth *Ethereum
@@ -65,6 +70,13 @@ func (b *EthApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock().Header(), nil
 	}
+	if blockNr == rpc.StableBlockNumber {
+		number, err := b.stableBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		return b.eth.blockchain.GetHeaderByNumber(number), nil
+	}
 	return b.eth.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
 
@@ -78,9 +90,25 @@ func (b *EthApiBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	if blockNr == rpc.LatestBlockNumber {
 		return b.eth.blockchain.CurrentBlock(), nil
 	}
+	if blockNr == rpc.StableBlockNumber {
+		number, err := b.stableBlockNumber()
+		if err != nil {
+			return nil, err
+		}
+		return b.eth.blockchain.GetBlockByNumber(number), nil
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
 
+// stableBlockNumber returns the number of the highest block that is final
+// under the block confirmation rules.
+func (b *EthApiBackend) stableBlockNumber() (uint64, error) {
+	if b.eth.pos == nil {
+		return 0, errNoBlockConfirmation
+	}
+	return b.eth.pos.Cfm.GetMaxStableBlkNumber(), nil
+}
+
 func (b *EthApiBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
 	// Pending state is only known by the miner
 	if blockNr == rpc.PendingBlockNumber {
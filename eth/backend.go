@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	"math/big"
 	"runtime"
 	"sync"
@@ -83,10 +85,12 @@ type Ethereum struct {
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
+	otaScan       *otaScan                       // Feeds the keystore OTA scanner with the imported blocks
 
 	ApiBackend *EthApiBackend
 
 	miner     *miner.Miner
+	pos       *miner.Pos // PoS subsystems, nil unless the chain runs Pluto
 	gasPrice  *big.Int
 	etherbase common.Address
 
@@ -113,12 +117,19 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// PoS local dbs of older versions are moved to the chain database
+	if err := posdb.NewDbs(chainDb).MigrateLegacyDbs(ctx.ResolvePath); err != nil {
+		return nil, err
+	}
 	stopDbUpgrade := upgradeDeduplicateData(chainDb)
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if chainConfig.Pluto != nil {
+		posconfig.SetChainParams(chainConfig.Pos)
+	}
 
 	eth := &Ethereum{
 		config:         config,
@@ -159,9 +170,16 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain.CurrentHeader(), eth.blockchain.SubscribeChainEvent)
+	if backends := ctx.AccountManager.Backends(keystore.KeyStoreType); len(backends) > 0 {
+		eth.otaScan = newOTAScan(eth.blockchain, chainDb, backends[0].(*keystore.KeyStore))
+	}
 
 	if chainConfig.Pluto != nil {
-		miner.PosInit(eth)
+		eth.pos = miner.PosInit(eth, config.ChainQualityWebhooks)
+		if pluto, ok := eth.engine.(*pluto.Pluto); ok {
+			pluto.SetSlotLeaderSelection(eth.pos.Sls)
+			pluto.SetIncentive(eth.pos.Incentive)
+		}
 	}
 
 	if config.TxPool.Journal != "" {
@@ -172,7 +190,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, config.SyncMode, config.NetworkId, eth.eventMux, eth.txPool, eth.engine, eth.blockchain, chainDb); err != nil {
 		return nil, err
 	}
+	if eth.pos != nil {
+		eth.pos.Cq.SetSyncer(eth.protocolManager.downloader)
+	}
 	eth.miner = miner.New(eth, eth.chainConfig, eth.EventMux(), eth.engine)
+	eth.miner.SetPos(eth.pos)
 	eth.miner.SetExtra(makeExtraData(config.ExtraData))
 
 	eth.ApiBackend = &EthApiBackend{eth, nil}
@@ -249,7 +271,11 @@ func (s *Ethereum) APIs() []rpc.API {
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
-	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend)...)
+	if s.pos != nil {
+		apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend, s.BlockChain().PosEvents(), s.pos.Epocher, s.pos.Sls, s.pos.Cfm, s.pos.Cq, s.pos.Incentive)...)
+	} else {
+		apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend, s.BlockChain().PosEvents(), nil, nil, nil, nil, nil)...)
+	}
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -409,6 +435,9 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 func (s *Ethereum) Start(srvr *p2p.Server) error {
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers()
+	if s.otaScan != nil {
+		s.otaScan.start()
+	}
 
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
@@ -426,6 +455,9 @@ func (s *Ethereum) Start(srvr *p2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.pos != nil {
+		s.pos.Cq.Start()
+	}
 	return nil
 }
 
@@ -436,6 +468,12 @@ func (s *Ethereum) Stop() error {
 		s.stopDbUpgrade()
 	}
 	s.bloomIndexer.Close()
+	if s.otaScan != nil {
+		s.otaScan.stop()
+	}
+	if s.pos != nil {
+		s.pos.Cq.Stop()
+	}
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
 	if s.lesServer != nil {
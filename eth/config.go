@@ -105,6 +105,9 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// PoS chain quality alerting options
+	ChainQualityWebhooks []string `toml:",omitempty"` // URLs the chain quality alerts are posted to
+
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
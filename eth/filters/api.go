@@ -281,6 +281,8 @@ type FilterCriteria struct {
 // Default criteria for the from and to block are "latest".
 // Using "latest" as block number will return logs for mined blocks.
 // Using "pending" as block number returns logs for not yet mined (pending) blocks.
+// Using "stable" as "toBlock" returns logs once their blocks are stable, these
+// are never removed by a chain reorg.
 // In case logs are removed (chain reorg) previously returned logs are returned
 // again but with the removed property set to true.
 //
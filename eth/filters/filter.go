@@ -109,6 +109,19 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	// Resolve the stable block through the block confirmation
+	if f.begin == rpc.StableBlockNumber.Int64() || f.end == rpc.StableBlockNumber.Int64() {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.StableBlockNumber)
+		if header == nil || err != nil {
+			return nil, err
+		}
+		if f.begin == rpc.StableBlockNumber.Int64() {
+			f.begin = header.Number.Int64()
+		}
+		if f.end == rpc.StableBlockNumber.Int64() {
+			end = header.Number.Uint64()
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
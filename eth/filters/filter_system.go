@@ -50,6 +50,8 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// StableLogsSubscription queries for logs in blocks that become stable
+	StableLogsSubscription
 	// LastSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -161,7 +163,8 @@ func (es *EventSystem) subscribe(sub *subscription) *Subscription {
 
 // SubscribeLogs creates a subscription that will write all logs matching the
 // given criteria to the given logs channel. Default value for the from and to
-// block is "latest". If the fromBlock > toBlock an error is returned.
+// block is "latest". If the toBlock is "stable" logs are only written once their
+// block is stable. If the fromBlock > toBlock an error is returned.
 func (es *EventSystem) SubscribeLogs(crit FilterCriteria, logs chan []*types.Log) (*Subscription, error) {
 	var from, to rpc.BlockNumber
 	if crit.FromBlock == nil {
@@ -175,6 +178,11 @@ func (es *EventSystem) SubscribeLogs(crit FilterCriteria, logs chan []*types.Log
 		to = rpc.BlockNumber(crit.ToBlock.Int64())
 	}
 
+	// only interested in logs of stable blocks, from a specific block number or
+	// the current stable block
+	if to == rpc.StableBlockNumber && (from >= 0 || from == rpc.LatestBlockNumber || from == rpc.StableBlockNumber) {
+		return es.subscribeStableLogs(crit, logs), nil
+	}
 	// only interested in pending logs
 	if from == rpc.PendingBlockNumber && to == rpc.PendingBlockNumber {
 		return es.subscribePendingLogs(crit, logs), nil
@@ -249,6 +257,69 @@ func (es *EventSystem) subscribePendingLogs(crit FilterCriteria, logs chan []*ty
 	return es.subscribe(sub)
 }
 
+// subscribeStableLogs creates a subscription that writes the logs matching the
+// given criteria once their blocks become stable.
+func (es *EventSystem) subscribeStableLogs(crit FilterCriteria, logs chan []*types.Log) *Subscription {
+	sub := &subscription{
+		id:        rpc.NewID(),
+		typ:       StableLogsSubscription,
+		logsCrit:  crit,
+		created:   time.Now(),
+		logs:      logs,
+		hashes:    make(chan common.Hash),
+		headers:   make(chan *types.Header, 1),
+		installed: make(chan struct{}),
+		err:       make(chan error),
+	}
+	s := es.subscribe(sub)
+	go es.stableLogsLoop(sub)
+	return s
+}
+
+// stableLogsLoop checks the stable block at every new chain head and writes the
+// logs of the blocks that became stable since the last check to a stable logs
+// subscription.
+func (es *EventSystem) stableLogsLoop(sub *subscription) {
+	ctx := context.Background()
+
+	next := int64(-1)
+	if from := sub.logsCrit.FromBlock; from != nil && from.Sign() >= 0 {
+		next = from.Int64()
+	}
+	for {
+		select {
+		case <-sub.headers:
+			header, err := es.backend.HeaderByNumber(ctx, rpc.StableBlockNumber)
+			if header == nil || err != nil {
+				continue
+			}
+			stable := header.Number.Int64()
+			if next < 0 {
+				// Only the blocks becoming stable after the subscription
+				next = stable + 1
+			}
+			if stable < next {
+				continue
+			}
+			logs, err := New(es.backend, next, stable, sub.logsCrit.Addresses, sub.logsCrit.Topics).Logs(ctx)
+			if err != nil {
+				continue
+			}
+			next = stable + 1
+
+			if len(logs) > 0 {
+				select {
+				case sub.logs <- logs:
+				case <-sub.err:
+					return
+				}
+			}
+		case <-sub.err:
+			return
+		}
+	}
+}
+
 // SubscribeNewHeads creates a subscription that writes the header of a block that is
 // imported in the chain.
 func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscription {
@@ -323,6 +394,13 @@ func (es *EventSystem) broadcast(filters filterIndex, ev interface{}) {
 		for _, f := range filters[BlocksSubscription] {
 			f.headers <- e.Block.Header()
 		}
+		for _, f := range filters[StableLogsSubscription] {
+			// Only the latest head matters to find the stable block
+			select {
+			case f.headers <- e.Block.Header():
+			default:
+			}
+		}
 		if es.lightMode && len(filters[LogsSubscription]) > 0 {
 			es.lightFilterNewHead(e.Block.Header(), func(header *types.Header, remove bool) {
 				for _, f := range filters[LogsSubscription] {
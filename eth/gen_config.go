@@ -35,6 +35,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		EthashDatasetsOnDisk    int
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
+		ChainQualityWebhooks    []string `toml:",omitempty"`
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
 		PowFake                 bool   `toml:"-"`
@@ -62,6 +63,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.EthashDatasetsOnDisk = c.EthashDatasetsOnDisk
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
+	enc.ChainQualityWebhooks = c.ChainQualityWebhooks
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
 	enc.PowFake = c.PowFake
@@ -93,6 +95,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		EthashDatasetsOnDisk    *int
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
+		ChainQualityWebhooks    []string `toml:",omitempty"`
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
 		PowFake                 *bool   `toml:"-"`
@@ -163,6 +166,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
+	if dec.ChainQualityWebhooks != nil {
+		c.ChainQualityWebhooks = dec.ChainQualityWebhooks
+	}
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
@@ -0,0 +1,154 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+// otaCatchUpBlocks is the number of blocks scanned for the lagging accounts
+// between two checks for new heads.
+const otaCatchUpBlocks = 256
+
+// otaScan feeds the OTA scanner of the keystore with the wancoin calls of the
+// blocks becoming canonical, rewinding it on reorgs.
+type otaScan struct {
+	bc      *core.BlockChain
+	db      ethdb.Database
+	scanner *keystore.OTAScanner
+	quit    chan struct{}
+}
+
+func newOTAScan(bc *core.BlockChain, db ethdb.Database, ks *keystore.KeyStore) *otaScan {
+	spent := func(image []byte) bool {
+		statedb, err := bc.State()
+		if err != nil {
+			return false
+		}
+		exist, _, err := vm.CheckOTAImageExist(statedb, image)
+		return err == nil && exist
+	}
+	return &otaScan{
+		bc:      bc,
+		db:      db,
+		scanner: keystore.NewOTAScanner(ks, db, spent),
+		quit:    make(chan struct{}),
+	}
+}
+
+func (s *otaScan) start() {
+	go s.loop()
+}
+
+func (s *otaScan) stop() {
+	close(s.quit)
+	s.scanner.Close()
+}
+
+func (s *otaScan) loop() {
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := s.bc.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	last := s.bc.CurrentBlock()
+	for {
+		if s.catchUp(last) {
+			select {
+			case ev := <-heads:
+				s.scan(last, ev.Block)
+				last = ev.Block
+			case <-sub.Err():
+				return
+			case <-s.quit:
+				return
+			default:
+			}
+			continue
+		}
+		select {
+		case ev := <-heads:
+			s.scan(last, ev.Block)
+			last = ev.Block
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// scan scans the blocks after last up to head. If head is not a descendant of
+// last, the scanner is first rewound to their common ancestor.
+func (s *otaScan) scan(last, head *types.Block) {
+	var blocks []*types.Block
+	for head != nil && head.NumberU64() > last.NumberU64() {
+		blocks = append(blocks, head)
+		head = s.bc.GetBlock(head.ParentHash(), head.NumberU64()-1)
+	}
+	ancestor := last
+	for ancestor != nil && head != nil && ancestor.NumberU64() > head.NumberU64() {
+		ancestor = s.bc.GetBlock(ancestor.ParentHash(), ancestor.NumberU64()-1)
+	}
+	for ancestor != nil && head != nil && ancestor.Hash() != head.Hash() {
+		blocks = append(blocks, head)
+		head = s.bc.GetBlock(head.ParentHash(), head.NumberU64()-1)
+		ancestor = s.bc.GetBlock(ancestor.ParentHash(), ancestor.NumberU64()-1)
+	}
+	if ancestor == nil || head == nil {
+		log.Warn("OTA scan lost the chain ancestry", "last", last.Number())
+		return
+	}
+	if head.NumberU64() < last.NumberU64() {
+		s.scanner.Rewind(head.NumberU64())
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		s.scanner.ScanBlock(s.otaBlock(blocks[i]))
+	}
+}
+
+// catchUp scans a chunk of the canonical blocks up to head for the accounts
+// unlocked after missing blocks, and reports whether it scanned any.
+func (s *otaScan) catchUp(head *types.Block) bool {
+	from, behind := s.scanner.Behind(head.NumberU64())
+	if !behind {
+		return false
+	}
+	to := from + otaCatchUpBlocks - 1
+	if to > head.NumberU64() {
+		to = head.NumberU64()
+	}
+	for number := from; number <= to; number++ {
+		block := s.bc.GetBlockByNumber(number)
+		if block == nil {
+			return false
+		}
+		s.scanner.ScanBlock(s.otaBlock(block))
+	}
+	return true
+}
+
+// otaBlock collects the OTAs paid and refunded by the successful wancoin calls
+// of block.
+func (s *otaScan) otaBlock(block *types.Block) *keystore.OTABlock {
+	ob := &keystore.OTABlock{Number: block.NumberU64(), Hash: block.Hash()}
+	receipts := core.GetBlockReceipts(s.db, block.Hash(), block.NumberU64())
+	for i, tx := range block.Transactions() {
+		if tx.To() == nil || !vm.IsWanCoinContract(*tx.To()) {
+			continue
+		}
+		if i < len(receipts) && receipts[i].Status == types.ReceiptStatusFailed {
+			continue
+		}
+		if ota, value, err := vm.DecodeBuyCoin(tx.Data()); err == nil {
+			ob.Payments = append(ob.Payments, keystore.OTAPayment{OTA: ota, Value: value, TxHash: tx.Hash()})
+		} else if image, err := vm.DecodeRefundCoin(tx.Data()); err == nil {
+			ob.Images = append(ob.Images, image)
+		}
+	}
+	return ob
+}
@@ -0,0 +1,211 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// ChainTraceResult is a block traced by the traceChain subscription, with the
+// traces of its transactions in order.
+type ChainTraceResult struct {
+	Number hexutil.Uint64   `json:"number"`
+	Hash   common.Hash      `json:"hash"`
+	Traces []*TxTraceResult `json:"traces"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// TxTraceResult is the trace of a transaction, or the error tracing it.
+type TxTraceResult struct {
+	TxHash common.Hash `json:"txHash"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// TraceChain creates a subscription notifying the traces of the canonical
+// blocks from start to end included, in order, and nothing more after end.
+// The blocks are traced in parallel, each on the state of its parent, which
+// must still be available. The genesis block, which has no transactions, is
+// skipped.
+func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *TraceArgs) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	from, err := api.chainBlockNumber(start)
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+	to, err := api.chainBlockNumber(end)
+	if err != nil {
+		return &rpc.Subscription{}, err
+	}
+	if from > to {
+		return &rpc.Subscription{}, fmt.Errorf("start block #%d after end block #%d", from, to)
+	}
+	if from == 0 {
+		from = 1
+	}
+	// Check the tracer once here rather than failing every transaction
+	if config != nil && config.Timeout != nil {
+		if _, err := time.ParseDuration(*config.Timeout); err != nil {
+			return &rpc.Subscription{}, err
+		}
+	}
+	if config != nil && config.Tracer != nil && !ethapi.IsNativeTracer(*config.Tracer) {
+		if _, err := ethapi.NewJavascriptTracer(*config.Tracer); err != nil {
+			return &rpc.Subscription{}, err
+		}
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go api.traceChain(from, to, config, notifier, rpcSub)
+
+	return rpcSub, nil
+}
+
+// chainBlockNumber resolves the number of a canonical block.
+func (api *PrivateDebugAPI) chainBlockNumber(number rpc.BlockNumber) (uint64, error) {
+	head := api.eth.BlockChain().CurrentBlock().NumberU64()
+	switch {
+	case number == rpc.LatestBlockNumber:
+		return head, nil
+	case number == rpc.PendingBlockNumber:
+		return 0, errors.New("pending block can't be traced")
+	case uint64(number) > head:
+		return 0, fmt.Errorf("block #%d not found", number)
+	}
+	return uint64(number), nil
+}
+
+// traceChain traces the blocks from to to, with one worker per CPU, and
+// notifies their traces in order until done or unsubscribed. Workers trace at
+// most a few blocks ahead of the next one to notify.
+func (api *PrivateDebugAPI) traceChain(from, to uint64, config *TraceArgs, notifier *rpc.Notifier, rpcSub *rpc.Subscription) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	threads := runtime.NumCPU()
+	if blocks := to - from + 1; blocks < uint64(threads) {
+		threads = int(blocks)
+	}
+	var (
+		numbers = make(chan uint64)
+		results = make(chan *ChainTraceResult, threads)
+		tokens  = make(chan struct{}, 2*threads) // blocks handed out and not notified yet
+	)
+	for i := 0; i < threads; i++ {
+		go func() {
+			for number := range numbers {
+				select {
+				case results <- api.traceChainBlock(ctx, number, config):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(numbers)
+		for number := from; number <= to; number++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case numbers <- number:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	traced := make(map[uint64]*ChainTraceResult)
+	for next := from; next <= to; {
+		select {
+		case result := <-results:
+			traced[uint64(result.Number)] = result
+			for {
+				result, ok := traced[next]
+				if !ok {
+					break
+				}
+				delete(traced, next)
+				notifier.Notify(rpcSub.ID, result)
+				<-tokens
+				next++
+			}
+		case <-rpcSub.Err():
+			return
+		case <-notifier.Closed():
+			return
+		}
+	}
+}
+
+// traceChainBlock traces the transactions of the canonical block number on
+// the state of its parent, as computeTxEnv recomputes it.
+func (api *PrivateDebugAPI) traceChainBlock(ctx context.Context, number uint64, config *TraceArgs) *ChainTraceResult {
+	result := &ChainTraceResult{Number: hexutil.Uint64(number), Traces: []*TxTraceResult{}}
+
+	blockchain := api.eth.BlockChain()
+	block := blockchain.GetBlockByNumber(number)
+	if block == nil {
+		result.Error = fmt.Sprintf("block #%d not found", number)
+		return result
+	}
+	result.Hash = block.Hash()
+
+	parent := blockchain.GetBlock(block.ParentHash(), number-1)
+	if parent == nil {
+		result.Error = fmt.Sprintf("block parent %x not found", block.ParentHash())
+		return result
+	}
+	statedb, err := blockchain.StateAt(parent.Root())
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	signer := types.MakeSigner(api.config, block.Number())
+	for i, tx := range block.Transactions() {
+		if ctx.Err() != nil {
+			break
+		}
+		trace := &TxTraceResult{TxHash: tx.Hash()}
+		result.Traces = append(result.Traces, trace)
+
+		// The following transactions would run on diverged state if this one
+		// can't be applied, so the block stops there.
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			trace.Error = err.Error()
+			result.Error = fmt.Sprintf("tx %x not applied, following ones not traced", tx.Hash())
+			break
+		}
+		vmctx := core.NewEVMContext(msg, block.Header(), blockchain, nil)
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if trace.Result, err = api.traceMessage(ctx, msg, vmctx, statedb, new(core.GasPool).AddGas(tx.Gas()), config); err != nil {
+			trace.Error = err.Error()
+			if _, ok := err.(*applyError); ok {
+				result.Error = fmt.Sprintf("tx %x not applied, following ones not traced", tx.Hash())
+				break
+			}
+		}
+		statedb.DeleteSuicides()
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+var traceTestAddr = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+
+// newTestDebugAPI creates the debug API of a chain of the given number of
+// blocks, each with a transfer of the test bank.
+func newTestDebugAPI(t *testing.T, blocks int) (*PrivateDebugAPI, *core.BlockChain) {
+	var (
+		db, _   = ethdb.NewMemDatabase()
+		engine  = ethash.NewFaker(db)
+		gspec   = core.DefaultPPOWTestingGenesisBlock()
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewEIP155Signer(gspec.Config.ChainId)
+	)
+	blockchain, err := core.NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := core.NewChainEnv(gspec.Config, gspec, engine, blockchain, db)
+	chain, _ := env.GenerateChain(genesis, blocks, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testBank), traceTestAddr, big.NewInt(1000), bigTxGas, nil, nil), signer, testBankKey)
+		block.AddTx(tx)
+	})
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatal(err)
+	}
+
+	eth := &Ethereum{chainConfig: gspec.Config, blockchain: blockchain, chainDb: db, engine: engine}
+	eth.ApiBackend = &EthApiBackend{eth: eth}
+	return NewPrivateDebugAPI(gspec.Config, eth), blockchain
+}
+
+// dialDebugAPI serves api in process.
+func dialDebugAPI(t *testing.T, api *PrivateDebugAPI) *rpc.Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("debug", api); err != nil {
+		t.Fatal(err)
+	}
+	return rpc.DialInProc(server)
+}
+
+// Tests that the blocks of the range are notified in order, each once, and
+// nothing after the end of the range.
+func TestTraceChain(t *testing.T) {
+	const blocks = 24
+	api, blockchain := newTestDebugAPI(t, blocks)
+	client := dialDebugAPI(t, api)
+	defer client.Close()
+
+	results := make(chan *ChainTraceResult)
+	tracer := ethapi.CallTracerName
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(0), "latest", &TraceArgs{Tracer: &tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// the genesis block is skipped
+	for number := uint64(1); number <= blocks; number++ {
+		select {
+		case result := <-results:
+			block := blockchain.GetBlockByNumber(number)
+			if uint64(result.Number) != number || result.Hash != block.Hash() || result.Error != "" {
+				t.Fatalf("block %d: got block %d %x, error %q", number, result.Number, result.Hash, result.Error)
+			}
+			if len(result.Traces) != 1 || result.Traces[0].TxHash != block.Transactions()[0].Hash() || result.Traces[0].Error != "" {
+				t.Fatalf("block %d: got traces %+v", number, result.Traces)
+			}
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("block %d not notified", number)
+		}
+	}
+	select {
+	case result := <-results:
+		t.Fatalf("block %d notified after the end of the range", result.Number)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+// traceChainGoroutines counts the goroutines of the traceChain subscriptions.
+func traceChainGoroutines() int {
+	buf := make([]byte, 1<<20)
+	return strings.Count(string(buf[:runtime.Stack(buf, true)]), "eth.(*PrivateDebugAPI).traceChain")
+}
+
+// Tests that unsubscribing in the middle of the range stops the tracing.
+func TestTraceChainUnsubscribe(t *testing.T) {
+	api, _ := newTestDebugAPI(t, 32)
+	client := dialDebugAPI(t, api)
+	defer client.Close()
+
+	results := make(chan *ChainTraceResult)
+	sub, err := client.Subscribe(context.Background(), "debug", results, "traceChain", hexutil.Uint64(1), "latest", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for number := uint64(1); number <= 2; number++ {
+		if result := <-results; uint64(result.Number) != number {
+			t.Fatalf("got block %d, want %d", result.Number, number)
+		}
+	}
+	if traceChainGoroutines() == 0 {
+		t.Fatal("the range was traced before the unsubscription")
+	}
+	sub.Unsubscribe()
+
+	// the workers and the notifier of the subscription return
+	deadline := time.Now().Add(10 * time.Second)
+	for traceChainGoroutines() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines of the subscription left running", traceChainGoroutines())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests that a call is traced with a native tracer on the state of a block,
+// and that the trace of a call running past its timeout is aborted.
+func TestTraceCall(t *testing.T) {
+	api, _ := newTestDebugAPI(t, 1)
+
+	tracer := ethapi.CallTracerName
+	args := ethapi.CallArgs{From: testBank, To: &traceTestAddr, Value: hexutil.Big(*big.NewInt(7))}
+	result, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, &TraceArgs{Tracer: &tracer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var frame struct {
+		Type  string         `json:"type"`
+		From  common.Address `json:"from"`
+		To    common.Address `json:"to"`
+		Value *hexutil.Big   `json:"value"`
+	}
+	if err := json.Unmarshal(enc, &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "CALL" || frame.From != testBank || frame.To != traceTestAddr || frame.Value.ToInt().Int64() != 7 {
+		t.Fatalf("got call %s", enc)
+	}
+
+	// the struct logger honours the timeout too
+	timeout := "1ns"
+	if _, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, &TraceArgs{Timeout: &timeout}); err == nil {
+		t.Fatal("no error tracing past the timeout")
+	} else if _, ok := err.(*timeoutError); !ok {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+}
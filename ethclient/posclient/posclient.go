@@ -0,0 +1,452 @@
+// Package posclient provides a client for the Wanchain PoS (pos_*) RPC API.
+package posclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/chainquality"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// Client defines typed wrappers for the Wanchain PoS RPC API.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	c, err := rpc.Dial(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(c), nil
+}
+
+// NewClient creates a client that uses the given RPC client.
+func NewClient(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (pc *Client) Close() {
+	pc.c.Close()
+}
+
+// Version returns the version of the pos API served by the node.
+func (pc *Client) Version(ctx context.Context) (string, error) {
+	var result string
+	err := pc.c.CallContext(ctx, &result, "pos_version")
+	return result, err
+}
+
+// Epoch and slot timing
+
+// EpochID returns the epoch ID at the node's current time.
+func (pc *Client) EpochID(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getEpochID")
+	return result, err
+}
+
+// SlotID returns the slot ID within the epoch at the node's current time.
+func (pc *Client) SlotID(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotID")
+	return result, err
+}
+
+// SlotCount returns the number of slots in one epoch.
+func (pc *Client) SlotCount(ctx context.Context) (int, error) {
+	var result int
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotCount")
+	return result, err
+}
+
+// SlotTime returns the duration of one slot in seconds.
+func (pc *Client) SlotTime(ctx context.Context) (int, error) {
+	var result int
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotTime")
+	return result, err
+}
+
+// EpochIDByTime returns the epoch ID of the given unix time.
+func (pc *Client) EpochIDByTime(ctx context.Context, timeUnix uint64) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getEpochIDByTime", timeUnix)
+	return result, err
+}
+
+// SlotIDByTime returns the slot ID of the given unix time.
+func (pc *Client) SlotIDByTime(ctx context.Context, timeUnix uint64) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotIDByTime", timeUnix)
+	return result, err
+}
+
+// TimeByEpochID returns the unix time at which the given epoch starts.
+func (pc *Client) TimeByEpochID(ctx context.Context, epochID uint64) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getTimeByEpochID", epochID)
+	return result, err
+}
+
+// MaxStableBlkNumber returns the highest block number considered stable by
+// the block confirmation module.
+func (pc *Client) MaxStableBlkNumber(ctx context.Context) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getMaxStableBlkNumber")
+	return result, err
+}
+
+// Leader selection
+
+// SlotLeadersByEpochID returns the hex encoded slot leader public keys of
+// an epoch, keyed by zero padded slot index.
+func (pc *Client) SlotLeadersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	var result map[string]string
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotLeadersByEpochID", epochID)
+	return result, err
+}
+
+// EpochLeadersByEpochID returns the hex encoded epoch leader public keys of
+// an epoch, keyed by zero padded index.
+func (pc *Client) EpochLeadersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	var result map[string]string
+	err := pc.c.CallContext(ctx, &result, "pos_getEpochLeadersByEpochID", epochID)
+	return result, err
+}
+
+// RandomProposersByEpochID returns the hex encoded random proposer public
+// keys of an epoch, keyed by zero padded index.
+func (pc *Client) RandomProposersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	var result map[string]string
+	err := pc.c.CallContext(ctx, &result, "pos_getRandomProposersByEpochID", epochID)
+	return result, err
+}
+
+// SmaByEpochID returns the hex encoded secret message array public keys of
+// an epoch, keyed by zero padded index.
+func (pc *Client) SmaByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	var result map[string]string
+	err := pc.c.CallContext(ctx, &result, "pos_getSmaByEpochID", epochID)
+	return result, err
+}
+
+// SlotScCallTimesByEpochID returns how many times the slot leader contract
+// was called in the given epoch.
+func (pc *Client) SlotScCallTimesByEpochID(ctx context.Context, epochID uint64) (uint64, error) {
+	var result uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotScCallTimesByEpochID", epochID)
+	return result, err
+}
+
+// SlotCreateStatusByEpochID reports whether the slot leaders of the given
+// epoch have been generated.
+func (pc *Client) SlotCreateStatusByEpochID(ctx context.Context, epochID uint64) (bool, error) {
+	var result bool
+	err := pc.c.CallContext(ctx, &result, "pos_getSlotCreateStatusByEpochID", epochID)
+	return result, err
+}
+
+// LocalPK returns the hex encoded public key the node uses for slot leader
+// selection.
+func (pc *Client) LocalPK(ctx context.Context) (string, error) {
+	var result string
+	err := pc.c.CallContext(ctx, &result, "pos_getLocalPK")
+	return result, err
+}
+
+// BootNodePK returns the hex encoded genesis boot node public key.
+func (pc *Client) BootNodePK(ctx context.Context) (string, error) {
+	var result string
+	err := pc.c.CallContext(ctx, &result, "pos_getBootNodePK")
+	return result, err
+}
+
+// WhiteListConfig returns the whitelist upgrade history, sorted by epoch.
+func (pc *Client) WhiteListConfig(ctx context.Context) ([]vm.UpgradeWhiteEpochLeaderParam, error) {
+	var result []vm.UpgradeWhiteEpochLeaderParam
+	err := pc.c.CallContext(ctx, &result, "pos_getWhiteListConfig")
+	return result, err
+}
+
+// WhiteListByEpochID returns the whitelisted epoch leaders of an epoch.
+func (pc *Client) WhiteListByEpochID(ctx context.Context, epochID uint64) ([]string, error) {
+	var result []string
+	err := pc.c.CallContext(ctx, &result, "pos_getWhiteListbyEpochID", epochID)
+	return result, err
+}
+
+// Random beacon
+
+// Random returns the random beacon value of an epoch as seen at the given
+// block number. Negative block numbers select the rpc.BlockNumber tags.
+func (pc *Client) Random(ctx context.Context, epochID uint64, blockNr int64) (*big.Int, error) {
+	var result hexutil.Big
+	if err := pc.c.CallContext(ctx, &result, "pos_getRandom", epochID, blockNr); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// RbSignatureCount returns the number of random beacon signature shares of
+// an epoch stored at the given block number.
+func (pc *Client) RbSignatureCount(ctx context.Context, epochID uint64, blockNr int64) (int, error) {
+	var result int
+	err := pc.c.CallContext(ctx, &result, "pos_getRbSignatureCount", epochID, blockNr)
+	return result, err
+}
+
+// RBAddress returns the addresses of the random beacon proposers of an epoch.
+func (pc *Client) RBAddress(ctx context.Context, epochID uint64) ([]common.Address, error) {
+	var result []common.Address
+	err := pc.c.CallContext(ctx, &result, "pos_getRBAddress", epochID)
+	return result, err
+}
+
+// ReorgState returns the number of reorgs and the total reorg length
+// recorded for an epoch.
+func (pc *Client) ReorgState(ctx context.Context, epochID uint64) ([]uint64, error) {
+	var result []uint64
+	err := pc.c.CallContext(ctx, &result, "pos_getReorgState", epochID)
+	return result, err
+}
+
+// Stakers
+
+// StakerInfo returns a snapshot of every staker at the given block number.
+func (pc *Client) StakerInfo(ctx context.Context, targetBlkNum uint64) ([]*posapi.StakerJson, error) {
+	var result []*posapi.StakerJson
+	err := pc.c.CallContext(ctx, &result, "pos_getStakerInfo", targetBlkNum)
+	return result, err
+}
+
+// EpochStakerInfo returns the selection probabilities of a staker and its
+// delegators in the given epoch.
+func (pc *Client) EpochStakerInfo(ctx context.Context, epochID uint64, addr common.Address) (*posapi.StakerInfo, error) {
+	var result posapi.StakerInfo
+	if err := pc.c.CallContext(ctx, &result, "pos_getEpochStakerInfo", epochID, addr); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EpochStakerInfoAll returns the selection probabilities of every staker in
+// the given epoch.
+func (pc *Client) EpochStakerInfoAll(ctx context.Context, epochID uint64) ([]posapi.StakerInfo, error) {
+	var result []posapi.StakerInfo
+	err := pc.c.CallContext(ctx, &result, "pos_getEpochStakerInfoAll", epochID)
+	return result, err
+}
+
+// CalProbability returns the selection probability of staking amountCoin
+// wan for lockTime epochs.
+func (pc *Client) CalProbability(ctx context.Context, amountCoin uint64, lockTime uint64) (*big.Int, error) {
+	var result string
+	if err := pc.c.CallContext(ctx, &result, "pos_calProbability", amountCoin, lockTime); err != nil {
+		return nil, err
+	}
+	return parseBig(result)
+}
+
+// SimulateReward estimates the incentive per epoch of staking amountCoin wan
+// as a new validator or, if validator is not nil, by delegating to it.
+func (pc *Client) SimulateReward(ctx context.Context, amountCoin uint64, lockEpochs uint64, feeRate uint64, validator *common.Address) (*posapi.RewardSimulation, error) {
+	var result posapi.RewardSimulation
+	if err := pc.c.CallContext(ctx, &result, "pos_simulateReward", amountCoin, lockEpochs, feeRate, validator); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ValidatorReport returns the PoS duties of a validator from fromEpoch to
+// toEpoch and the ones it missed.
+func (pc *Client) ValidatorReport(ctx context.Context, addr common.Address, fromEpoch, toEpoch uint64) (*posapi.ValidatorReport, error) {
+	var result posapi.ValidatorReport
+	if err := pc.c.CallContext(ctx, &result, "pos_getValidatorReport", addr, fromEpoch, toEpoch); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ChainQuality returns the chain quality of an epoch measured by the node, of
+// the current epoch up to the last slot that passed if epochID is nil.
+func (pc *Client) ChainQuality(ctx context.Context, epochID *uint64) (*chainquality.Quality, error) {
+	var result chainquality.Quality
+	if err := pc.c.CallContext(ctx, &result, "pos_getChainQuality", epochID); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Incentive
+
+// EpochIncentivePayDetail returns the incentive paid to every epoch leader,
+// random proposer and slot leader, and their delegators, in an epoch.
+func (pc *Client) EpochIncentivePayDetail(ctx context.Context, epochID uint64) ([][]posapi.PayInfo, error) {
+	var result [][]posapi.PayInfo
+	err := pc.c.CallContext(ctx, &result, "pos_getEpochIncentivePayDetail", epochID)
+	return result, err
+}
+
+// DelegatorIncentive returns a page of the incentive an address received from
+// fromEpoch to toEpoch, the next page starts at its Next epoch.
+func (pc *Client) DelegatorIncentive(ctx context.Context, addr common.Address, fromEpoch, toEpoch uint64) (*posapi.DelegatorIncentivePage, error) {
+	var result posapi.DelegatorIncentivePage
+	if err := pc.c.CallContext(ctx, &result, "pos_getDelegatorIncentive", addr, fromEpoch, toEpoch); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ValidatorIncentive returns a page of the incentive paid through a validator
+// from fromEpoch to toEpoch, the next page starts at its Next epoch.
+func (pc *Client) ValidatorIncentive(ctx context.Context, addr common.Address, fromEpoch, toEpoch uint64) (*posapi.ValidatorIncentivePage, error) {
+	var result posapi.ValidatorIncentivePage
+	if err := pc.c.CallContext(ctx, &result, "pos_getValidatorIncentive", addr, fromEpoch, toEpoch); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PendingUnbonds returns the stake outs and delegate outs addr will get back,
+// or will return as their validator, with the epoch they are released in.
+func (pc *Client) PendingUnbonds(ctx context.Context, addr common.Address) (*posapi.PendingUnbonds, error) {
+	var result posapi.PendingUnbonds
+	if err := pc.c.CallContext(ctx, &result, "pos_getPendingUnbonds", addr); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Activity returns the epoch leader, random proposer and slot leader
+// activity of an epoch.
+func (pc *Client) Activity(ctx context.Context, epochID uint64) (*posapi.Activity, error) {
+	var result *posapi.Activity
+	err := pc.c.CallContext(ctx, &result, "pos_getActivity", epochID)
+	return result, err
+}
+
+// TotalIncentive returns the total incentive paid since PoS started.
+func (pc *Client) TotalIncentive(ctx context.Context) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getTotalIncentive")
+}
+
+// EpochIncentive returns the total incentive paid in an epoch.
+func (pc *Client) EpochIncentive(ctx context.Context, epochID uint64) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getEpochIncentive", epochID)
+}
+
+// EpochRemain returns the incentive left over in an epoch.
+func (pc *Client) EpochRemain(ctx context.Context, epochID uint64) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getEpochRemain", epochID)
+}
+
+// TotalRemain returns the incentive left over since PoS started.
+func (pc *Client) TotalRemain(ctx context.Context) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getTotalRemain")
+}
+
+// IncentiveRunTimes returns how many times the incentive has been paid.
+func (pc *Client) IncentiveRunTimes(ctx context.Context) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getIncentiveRunTimes")
+}
+
+// EpochGasPool returns the gas fees collected in an epoch.
+func (pc *Client) EpochGasPool(ctx context.Context, epochID uint64) (*big.Int, error) {
+	return pc.callBig(ctx, "pos_getEpochGasPool", epochID)
+}
+
+// IncentivePool returns the total incentive pool of an epoch, together with
+// the foundation subsidy and the gas fee parts it is made of.
+func (pc *Client) IncentivePool(ctx context.Context, epochID uint64) (total, foundation, gasPool *big.Int, err error) {
+	var result []string
+	if err = pc.c.CallContext(ctx, &result, "pos_getIncentivePool", epochID); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(result) != 3 {
+		return nil, nil, nil, fmt.Errorf("invalid pos_getIncentivePool result length %d", len(result))
+	}
+	pool := make([]*big.Int, len(result))
+	for i := range result {
+		if pool[i], err = parseBig(result[i]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return pool[0], pool[1], pool[2], nil
+}
+
+// SubscribeNewEpoch subscribes to notifications about the chain head entering
+// a new epoch.
+func (pc *Client) SubscribeNewEpoch(ctx context.Context, ch chan<- posevent.NewEpochEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "newEpoch")
+}
+
+// SubscribeNewSlot subscribes to notifications about the chain head moving to
+// a new slot.
+func (pc *Client) SubscribeNewSlot(ctx context.Context, ch chan<- posevent.NewSlotEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "newSlot")
+}
+
+// SubscribeEpochLeadersSelected subscribes to notifications about the
+// selection of epoch leaders and random proposers.
+func (pc *Client) SubscribeEpochLeadersSelected(ctx context.Context, ch chan<- posevent.EpochLeadersSelectedEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "epochLeadersSelected")
+}
+
+// SubscribeRandomBeaconFinalized subscribes to notifications about the random
+// number of an epoch being generated.
+func (pc *Client) SubscribeRandomBeaconFinalized(ctx context.Context, ch chan<- posevent.RandomBeaconFinalizedEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "randomBeaconFinalized")
+}
+
+// SubscribeIncentivePaid subscribes to notifications about the payment of the
+// incentive of an epoch.
+func (pc *Client) SubscribeIncentivePaid(ctx context.Context, ch chan<- posevent.IncentivePaidEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "incentivePaid")
+}
+
+// SubscribeChainQualityAlert subscribes to notifications about the chain
+// quality monitor of the node changing level.
+func (pc *Client) SubscribeChainQualityAlert(ctx context.Context, ch chan<- posevent.ChainQualityAlertEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "pos", ch, "chainQualityAlert")
+}
+
+// SubscribeUnbondReleased subscribes to notifications about the stakes
+// returned to addr, or by it as their validator, at the stake out of an epoch.
+// A nil addr subscribes to all of them.
+func (pc *Client) SubscribeUnbondReleased(ctx context.Context, addr *common.Address, ch chan<- posevent.UnbondReleasedEvent) (*rpc.ClientSubscription, error) {
+	if addr == nil {
+		return pc.c.Subscribe(ctx, "pos", ch, "unbondReleased")
+	}
+	return pc.c.Subscribe(ctx, "pos", ch, "unbondReleased", *addr)
+}
+
+func (pc *Client) callBig(ctx context.Context, method string, args ...interface{}) (*big.Int, error) {
+	var result string
+	if err := pc.c.CallContext(ctx, &result, method, args...); err != nil {
+		return nil, err
+	}
+	return parseBig(result)
+}
+
+// parseBig decodes the decimal strings the pos API uses for big values.
+// The server reports some internal errors as an empty string, which is
+// returned as nil.
+func parseBig(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid big integer %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,557 @@
+package posclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/chainquality"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+var (
+	testAddr    = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	testClient  = common.HexToAddress("0x0a7e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	testLeaders = map[string]string{"000000": "04aa", "000001": "04bb"}
+)
+
+// PosTestService mirrors the method set of posapi.PosApi with canned results.
+type PosTestService struct{}
+
+func (s *PosTestService) Version() string { return "1.0" }
+
+func (s *PosTestService) GetEpochID() uint64 { return 18000 }
+func (s *PosTestService) GetSlotID() uint64  { return 42 }
+func (s *PosTestService) GetSlotCount() int  { return 17280 }
+func (s *PosTestService) GetSlotTime() int   { return 5 }
+
+func (s *PosTestService) GetEpochIDByTime(timeUnix uint64) uint64 { return timeUnix / 100 }
+func (s *PosTestService) GetSlotIDByTime(timeUnix uint64) uint64  { return timeUnix % 100 }
+func (s *PosTestService) GetTimeByEpochID(epochID uint64) uint64  { return epochID * 100 }
+func (s *PosTestService) GetMaxStableBlkNumber() uint64           { return 1234 }
+
+func (s *PosTestService) GetSlotLeadersByEpochID(epochID uint64) map[string]string {
+	return testLeaders
+}
+
+func (s *PosTestService) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, error) {
+	return testLeaders, nil
+}
+
+func (s *PosTestService) GetRandomProposersByEpochID(epochID uint64) map[string]string {
+	return testLeaders
+}
+
+func (s *PosTestService) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
+	return nil, errors.New("sma not ready")
+}
+
+func (s *PosTestService) GetSlotScCallTimesByEpochID(epochID uint64) uint64 { return 7 }
+func (s *PosTestService) GetSlotCreateStatusByEpochID(epochID uint64) bool  { return true }
+func (s *PosTestService) GetLocalPK() (string, error)                       { return "04cc", nil }
+func (s *PosTestService) GetBootNodePK() string                             { return "04dd" }
+
+func (s *PosTestService) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error) {
+	return []vm.UpgradeWhiteEpochLeaderParam{vm.UpgradeWhiteEpochLeaderDefault}, nil
+}
+
+func (s *PosTestService) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
+	return []string{"04ee"}, nil
+}
+
+func (s *PosTestService) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
+	return new(big.Int).SetUint64(epochId + uint64(blockNr)), nil
+}
+
+func (s *PosTestService) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error) {
+	return 21, nil
+}
+
+func (s *PosTestService) GetRBAddress(epochID uint64) []common.Address {
+	return []common.Address{testAddr}
+}
+
+func (s *PosTestService) GetReorgState(epochid uint64) ([]uint64, error) {
+	return []uint64{2, 3}, nil
+}
+
+func (s *PosTestService) GetStakerInfo(targetBlkNum uint64) ([]*posapi.StakerJson, error) {
+	return []*posapi.StakerJson{testStaker()}, nil
+}
+
+func (s *PosTestService) GetEpochStakerInfo(epochID uint64, addr common.Address) (posapi.StakerInfo, error) {
+	return testEpochStaker(addr), nil
+}
+
+func (s *PosTestService) GetEpochStakerInfoAll(epochID uint64) ([]posapi.StakerInfo, error) {
+	return []posapi.StakerInfo{testEpochStaker(testAddr)}, nil
+}
+
+func (s *PosTestService) CalProbability(amountCoin uint64, lockTime uint64) (string, error) {
+	return new(big.Int).SetUint64(amountCoin * lockTime).String(), nil
+}
+
+func (s *PosTestService) SimulateReward(amountCoin uint64, lockEpochs uint64, feeRate uint64, validator *common.Address) (*posapi.RewardSimulation, error) {
+	res := &posapi.RewardSimulation{
+		FeeRate:   feeRate,
+		Incentive: (*math.HexOrDecimal256)(new(big.Int).SetUint64(amountCoin * lockEpochs)),
+	}
+	if validator != nil {
+		res.Validator = *validator
+	}
+	return res, nil
+}
+
+func (s *PosTestService) GetValidatorReport(addr common.Address, fromEpoch uint64, toEpoch uint64) (*posapi.ValidatorReport, error) {
+	return &posapi.ValidatorReport{
+		Address:       addr,
+		FromEpoch:     fromEpoch,
+		ToEpoch:       toEpoch,
+		SlotsAssigned: 2,
+		SlotsProduced: 1,
+		Missed:        []posapi.MissedDuty{{EpochID: fromEpoch, Index: 7, Duty: posapi.DutySlot, Reason: "no block in slot"}},
+		Unknown:       []uint64{},
+	}, nil
+}
+
+func (s *PosTestService) GetChainQuality(epochID *uint64) (*chainquality.Quality, error) {
+	q := &chainquality.Quality{EpochID: 9, SlotID: 3, EpochBlocks: 3, EpochSlots: 4, EpochQuality: 0.75, Level: chainquality.LevelWarning}
+	if epochID != nil {
+		q.EpochID = *epochID
+	}
+	return q, nil
+}
+
+func (s *PosTestService) GetEpochIncentivePayDetail(epochID uint64) ([][]posapi.PayInfo, error) {
+	return [][]posapi.PayInfo{{{Addr: testAddr, Incentive: (*math.HexOrDecimal256)(big.NewInt(100))}}}, nil
+}
+
+func (s *PosTestService) GetDelegatorIncentive(addr common.Address, fromEpoch uint64, toEpoch uint64) (*posapi.DelegatorIncentivePage, error) {
+	next := toEpoch
+	return &posapi.DelegatorIncentivePage{
+		Address:    addr,
+		Total:      (*math.HexOrDecimal256)(big.NewInt(7)),
+		Incentives: []posapi.DelegatorIncentive{{EpochID: fromEpoch, Validator: testAddr, Incentive: (*math.HexOrDecimal256)(big.NewInt(7))}},
+		Next:       &next,
+	}, nil
+}
+
+func (s *PosTestService) GetValidatorIncentive(addr common.Address, fromEpoch uint64, toEpoch uint64) (*posapi.ValidatorIncentivePage, error) {
+	return &posapi.ValidatorIncentivePage{
+		Address: addr,
+		Total:   (*math.HexOrDecimal256)(big.NewInt(9)),
+		Incentives: []posapi.ValidatorIncentive{{
+			EpochID:    fromEpoch,
+			Total:      (*math.HexOrDecimal256)(big.NewInt(9)),
+			Self:       (*math.HexOrDecimal256)(big.NewInt(4)),
+			Delegators: 2,
+		}},
+	}, nil
+}
+
+func (s *PosTestService) GetPendingUnbonds(addr common.Address) (*posapi.PendingUnbonds, error) {
+	return &posapi.PendingUnbonds{
+		Address:     addr,
+		BlockNumber: 100,
+		EpochID:     4,
+		Unbonds: []posapi.PendingUnbond{{
+			Kind:         vm.UnbondDelegateOut,
+			Validator:    testAddr,
+			Address:      addr,
+			Amount:       (*math.HexOrDecimal256)(big.NewInt(50)),
+			ReleaseEpoch: 7,
+		}},
+	}, nil
+}
+
+func (s *PosTestService) GetActivity(epochID uint64) (*posapi.Activity, error) {
+	return testActivity(), nil
+}
+
+func (s *PosTestService) GetTotalIncentive() (string, error)               { return "1000000000000000000000", nil }
+func (s *PosTestService) GetEpochIncentive(epochID uint64) (string, error) { return "100", nil }
+func (s *PosTestService) GetEpochRemain(epochID uint64) (string, error)    { return "", nil }
+func (s *PosTestService) GetTotalRemain() (string, error)                  { return "5", nil }
+func (s *PosTestService) GetIncentiveRunTimes() (string, error)            { return "9", nil }
+func (s *PosTestService) GetEpochGasPool(epochID uint64) (string, error)   { return "6", nil }
+
+func (s *PosTestService) GetIncentivePool(epochID uint64) ([]string, error) {
+	return []string{"3", "2", "1"}, nil
+}
+
+func testStaker() *posapi.StakerJson {
+	return &posapi.StakerJson{
+		Address:      testAddr,
+		PubSec256:    "0x04aa",
+		PubBn256:     "0x04bb",
+		Amount:       (*math.HexOrDecimal256)(big.NewInt(50000)),
+		StakeAmount:  (*math.HexOrDecimal256)(big.NewInt(60000)),
+		LockEpochs:   10,
+		From:         testAddr,
+		StakingEpoch: 17990,
+		FeeRate:      100,
+		Clients: []posapi.ClientInfo{{
+			Address:     testClient,
+			Amount:      (*math.HexOrDecimal256)(big.NewInt(1000)),
+			StakeAmount: (*math.HexOrDecimal256)(big.NewInt(1200)),
+		}},
+		Partners: []posapi.PartnerInfo{},
+	}
+}
+
+func testEpochStaker(addr common.Address) posapi.StakerInfo {
+	return posapi.StakerInfo{
+		Addr:             addr,
+		Infors:           []vm.ClientProbability{{Addr: testClient, Probability: big.NewInt(11)}},
+		FeeRate:          100,
+		TotalProbability: big.NewInt(22),
+	}
+}
+
+func testActivity() *posapi.Activity {
+	return &posapi.Activity{
+		EpLeader:    []common.Address{testAddr},
+		EpActivity:  []int{1},
+		RpLeader:    []common.Address{testAddr},
+		RpActivity:  []int{0},
+		SltLeader:   []common.Address{testAddr},
+		SlBlocks:    []int{5},
+		SlActivity:  0.5,
+		SlCtrlCount: 3,
+	}
+}
+
+func newTestClient(t *testing.T) *Client {
+	server := rpc.NewServer()
+	if err := server.RegisterName("pos", new(PosTestService)); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(rpc.DialInProc(server))
+}
+
+func TestTiming(t *testing.T) {
+	pc := newTestClient(t)
+	defer pc.Close()
+	ctx := context.Background()
+
+	if v, err := pc.Version(ctx); err != nil || v != "1.0" {
+		t.Fatalf("Version: got %q, %v", v, err)
+	}
+	if v, err := pc.EpochID(ctx); err != nil || v != 18000 {
+		t.Fatalf("EpochID: got %d, %v", v, err)
+	}
+	if v, err := pc.SlotID(ctx); err != nil || v != 42 {
+		t.Fatalf("SlotID: got %d, %v", v, err)
+	}
+	if v, err := pc.SlotCount(ctx); err != nil || v != 17280 {
+		t.Fatalf("SlotCount: got %d, %v", v, err)
+	}
+	if v, err := pc.SlotTime(ctx); err != nil || v != 5 {
+		t.Fatalf("SlotTime: got %d, %v", v, err)
+	}
+	if v, err := pc.EpochIDByTime(ctx, 1234); err != nil || v != 12 {
+		t.Fatalf("EpochIDByTime: got %d, %v", v, err)
+	}
+	if v, err := pc.SlotIDByTime(ctx, 1234); err != nil || v != 34 {
+		t.Fatalf("SlotIDByTime: got %d, %v", v, err)
+	}
+	if v, err := pc.TimeByEpochID(ctx, 12); err != nil || v != 1200 {
+		t.Fatalf("TimeByEpochID: got %d, %v", v, err)
+	}
+	if v, err := pc.MaxStableBlkNumber(ctx); err != nil || v != 1234 {
+		t.Fatalf("MaxStableBlkNumber: got %d, %v", v, err)
+	}
+}
+
+func TestLeaders(t *testing.T) {
+	pc := newTestClient(t)
+	defer pc.Close()
+	ctx := context.Background()
+
+	for name, call := range map[string]func(context.Context, uint64) (map[string]string, error){
+		"SlotLeadersByEpochID":     pc.SlotLeadersByEpochID,
+		"EpochLeadersByEpochID":    pc.EpochLeadersByEpochID,
+		"RandomProposersByEpochID": pc.RandomProposersByEpochID,
+	} {
+		leaders, err := call(ctx, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(leaders, testLeaders) {
+			t.Fatalf("%s: got %v, want %v", name, leaders, testLeaders)
+		}
+	}
+	if _, err := pc.SmaByEpochID(ctx, 1); err == nil || err.Error() != "sma not ready" {
+		t.Fatalf("SmaByEpochID: expected server error, got %v", err)
+	}
+	if v, err := pc.SlotScCallTimesByEpochID(ctx, 1); err != nil || v != 7 {
+		t.Fatalf("SlotScCallTimesByEpochID: got %d, %v", v, err)
+	}
+	if v, err := pc.SlotCreateStatusByEpochID(ctx, 1); err != nil || !v {
+		t.Fatalf("SlotCreateStatusByEpochID: got %v, %v", v, err)
+	}
+	if v, err := pc.LocalPK(ctx); err != nil || v != "04cc" {
+		t.Fatalf("LocalPK: got %q, %v", v, err)
+	}
+	if v, err := pc.BootNodePK(ctx); err != nil || v != "04dd" {
+		t.Fatalf("BootNodePK: got %q, %v", v, err)
+	}
+	wl, err := pc.WhiteListConfig(ctx)
+	if err != nil || len(wl) != 1 || wl[0].WlCount.Cmp(vm.UpgradeWhiteEpochLeaderDefault.WlCount) != 0 {
+		t.Fatalf("WhiteListConfig: got %v, %v", wl, err)
+	}
+	if v, err := pc.WhiteListByEpochID(ctx, 1); err != nil || !reflect.DeepEqual(v, []string{"04ee"}) {
+		t.Fatalf("WhiteListByEpochID: got %v, %v", v, err)
+	}
+}
+
+func TestRandomBeacon(t *testing.T) {
+	pc := newTestClient(t)
+	defer pc.Close()
+	ctx := context.Background()
+
+	if v, err := pc.Random(ctx, 10, 5); err != nil || v.Uint64() != 15 {
+		t.Fatalf("Random: got %v, %v", v, err)
+	}
+	if v, err := pc.RbSignatureCount(ctx, 10, -1); err != nil || v != 21 {
+		t.Fatalf("RbSignatureCount: got %d, %v", v, err)
+	}
+	if v, err := pc.RBAddress(ctx, 10); err != nil || !reflect.DeepEqual(v, []common.Address{testAddr}) {
+		t.Fatalf("RBAddress: got %v, %v", v, err)
+	}
+	if v, err := pc.ReorgState(ctx, 10); err != nil || !reflect.DeepEqual(v, []uint64{2, 3}) {
+		t.Fatalf("ReorgState: got %v, %v", v, err)
+	}
+}
+
+func TestStakers(t *testing.T) {
+	pc := newTestClient(t)
+	defer pc.Close()
+	ctx := context.Background()
+
+	stakers, err := pc.StakerInfo(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stakers) != 1 || !reflect.DeepEqual(stakers[0], testStaker()) {
+		t.Fatalf("StakerInfo: got %+v", stakers)
+	}
+	es, err := pc.EpochStakerInfo(ctx, 1, testClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*es, testEpochStaker(testClient)) {
+		t.Fatalf("EpochStakerInfo: got %+v", es)
+	}
+	all, err := pc.EpochStakerInfoAll(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(all, []posapi.StakerInfo{testEpochStaker(testAddr)}) {
+		t.Fatalf("EpochStakerInfoAll: got %+v", all)
+	}
+	if v, err := pc.CalProbability(ctx, 10000, 30); err != nil || v.Uint64() != 300000 {
+		t.Fatalf("CalProbability: got %v, %v", v, err)
+	}
+	if v, err := pc.SimulateReward(ctx, 10000, 30, 5, nil); err != nil || v.Validator != (common.Address{}) || (*big.Int)(v.Incentive).Uint64() != 300000 {
+		t.Fatalf("SimulateReward: got %+v, %v", v, err)
+	}
+	if v, err := pc.SimulateReward(ctx, 10000, 30, 5, &testAddr); err != nil || v.Validator != testAddr || v.FeeRate != 5 {
+		t.Fatalf("SimulateReward with validator: got %+v, %v", v, err)
+	}
+	report, err := pc.ValidatorReport(ctx, testAddr, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []posapi.MissedDuty{{EpochID: 3, Index: 7, Duty: posapi.DutySlot, Reason: "no block in slot"}}
+	if report.Address != testAddr || report.ToEpoch != 4 || report.SlotsProduced != 1 || !reflect.DeepEqual(report.Missed, want) {
+		t.Fatalf("ValidatorReport: got %+v", report)
+	}
+	unbonds, err := pc.PendingUnbonds(ctx, testClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unbonds.Address != testClient || len(unbonds.Unbonds) != 1 || unbonds.Unbonds[0].Kind != vm.UnbondDelegateOut ||
+		unbonds.Unbonds[0].ReleaseEpoch != 7 || (*big.Int)(unbonds.Unbonds[0].Amount).Uint64() != 50 {
+		t.Fatalf("PendingUnbonds: got %+v", unbonds)
+	}
+	if q, err := pc.ChainQuality(ctx, nil); err != nil || q.EpochID != 9 || q.EpochQuality != 0.75 || q.Level != chainquality.LevelWarning {
+		t.Fatalf("ChainQuality: got %+v, %v", q, err)
+	}
+	epochID := uint64(5)
+	if q, err := pc.ChainQuality(ctx, &epochID); err != nil || q.EpochID != 5 {
+		t.Fatalf("ChainQuality of epoch: got %+v, %v", q, err)
+	}
+}
+
+func TestIncentive(t *testing.T) {
+	pc := newTestClient(t)
+	defer pc.Close()
+	ctx := context.Background()
+
+	detail, err := pc.EpochIncentivePayDetail(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail) != 1 || len(detail[0]) != 1 || detail[0][0].Addr != testAddr ||
+		(*big.Int)(detail[0][0].Incentive).Uint64() != 100 {
+		t.Fatalf("EpochIncentivePayDetail: got %+v", detail)
+	}
+	delegator, err := pc.DelegatorIncentive(ctx, testClient, 3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delegator.Address != testClient || len(delegator.Incentives) != 1 || delegator.Incentives[0].EpochID != 3 ||
+		delegator.Incentives[0].Validator != testAddr || delegator.Next == nil || *delegator.Next != 8 {
+		t.Fatalf("DelegatorIncentive: got %+v", delegator)
+	}
+	validator, err := pc.ValidatorIncentive(ctx, testAddr, 3, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(validator.Incentives) != 1 || (*big.Int)(validator.Incentives[0].Self).Uint64() != 4 ||
+		validator.Incentives[0].Delegators != 2 || validator.Next != nil {
+		t.Fatalf("ValidatorIncentive: got %+v", validator)
+	}
+	activity, err := pc.Activity(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(activity, testActivity()) {
+		t.Fatalf("Activity: got %+v", activity)
+	}
+
+	total, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	if v, err := pc.TotalIncentive(ctx); err != nil || v.Cmp(total) != 0 {
+		t.Fatalf("TotalIncentive: got %v, %v", v, err)
+	}
+	if v, err := pc.EpochIncentive(ctx, 1); err != nil || v.Uint64() != 100 {
+		t.Fatalf("EpochIncentive: got %v, %v", v, err)
+	}
+	if v, err := pc.EpochRemain(ctx, 1); err != nil || v != nil {
+		t.Fatalf("EpochRemain: expected nil for empty result, got %v, %v", v, err)
+	}
+	if v, err := pc.TotalRemain(ctx); err != nil || v.Uint64() != 5 {
+		t.Fatalf("TotalRemain: got %v, %v", v, err)
+	}
+	if v, err := pc.IncentiveRunTimes(ctx); err != nil || v.Uint64() != 9 {
+		t.Fatalf("IncentiveRunTimes: got %v, %v", v, err)
+	}
+	if v, err := pc.EpochGasPool(ctx, 1); err != nil || v.Uint64() != 6 {
+		t.Fatalf("EpochGasPool: got %v, %v", v, err)
+	}
+	pool, foundation, gas, err := pc.IncentivePool(ctx, 1)
+	if err != nil || pool.Uint64() != 3 || foundation.Uint64() != 2 || gas.Uint64() != 1 {
+		t.Fatalf("IncentivePool: got %v %v %v, %v", pool, foundation, gas, err)
+	}
+}
+
+func TestSubscribeIncentivePaid(t *testing.T) {
+	feed := posevent.NewFeed()
+	server := rpc.NewServer()
+	for _, api := range posapi.APIs(nil, nil, feed, nil, nil, nil, nil, nil) {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pc := NewClient(rpc.DialInProc(server))
+	defer pc.Close()
+
+	ch := make(chan posevent.IncentivePaidEvent)
+	sub, err := pc.SubscribeIncentivePaid(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// The server side subscribes to the feed asynchronously, keep posting
+	// until the first notification arrives.
+	var got posevent.IncentivePaidEvent
+	timeout := time.After(2 * time.Second)
+	for received := false; !received; {
+		feed.Post(posevent.IncentivePaidEvent{EpochID: 7, Receivers: 3})
+		select {
+		case got = <-ch:
+			received = true
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatal("no notification received")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if got.EpochID != 7 || got.Receivers != 3 {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+
+	// Repeated payments of the same epoch are not notified again.
+	feed.Post(posevent.IncentivePaidEvent{EpochID: 7})
+	feed.Post(posevent.IncentivePaidEvent{EpochID: 8, Receivers: 5})
+	select {
+	case got = <-ch:
+		if got.EpochID != 8 || got.Receivers != 5 {
+			t.Fatalf("unexpected notification %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestSubscribeUnbondReleased(t *testing.T) {
+	feed := posevent.NewFeed()
+	server := rpc.NewServer()
+	for _, api := range posapi.APIs(nil, nil, feed, nil, nil, nil, nil, nil) {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pc := NewClient(rpc.DialInProc(server))
+	defer pc.Close()
+
+	ch := make(chan posevent.UnbondReleasedEvent)
+	sub, err := pc.SubscribeUnbondReleased(context.Background(), &testClient, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// The server side subscribes to the feed asynchronously, keep posting
+	// until the first notification arrives.
+	var got posevent.UnbondReleasedEvent
+	timeout := time.After(2 * time.Second)
+	for received := false; !received; {
+		feed.Post(posevent.UnbondReleasedEvent{EpochID: 7, Kind: vm.UnbondDelegateOut, Validator: testAddr, Address: testClient})
+		select {
+		case got = <-ch:
+			received = true
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatal("no notification received")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if got.EpochID != 7 || got.Address != testClient {
+		t.Fatalf("unexpected notification %+v", got)
+	}
+
+	// Stakes returned to other addresses are filtered out.
+	feed.Post(posevent.UnbondReleasedEvent{EpochID: 8, Kind: vm.UnbondStakeOut, Validator: testAddr, Address: testAddr})
+	feed.Post(posevent.UnbondReleasedEvent{EpochID: 9, Kind: vm.UnbondDelegateOut, Validator: testAddr, Address: testClient})
+	select {
+	case got = <-ch:
+		if got.EpochID != 9 {
+			t.Fatalf("unexpected notification %+v", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
@@ -40,7 +40,7 @@ eth.sendTransaction({from:eth.accounts[1], to:coinContractAddr, value:web3.toWei
 
 /*
   1.mixPubkeys = get OTAMixSet for [Coins]                         stamps
-  2.ringSignData = genRingSignData(receiver_address, coinNotePrivateKey. mixPubkeys)
+  2.ringSignData = genRingSignData(receiver_account, receiver_address, coinNoteOTA, mixPubkeys)
   3.cxtTxData = coinContract.refundCoin.getData(value)
   4.otaTxData = combiningOTAData(ringSignData, cxtTxData)
   5.eth.sendOTATransaction({from:receiver_address, to:coinContractAddr,data:otaTxData, gas:1000000})
@@ -52,10 +52,7 @@ for (i = 0; i < mixWanAddresses.length; i++){
 	mixSetWith0x.push(mixWanAddresses[i])
 }
 
-keyPairs = wan.computeOTAPPKeys(eth.accounts[2], otaAddr).split('+');
-privateKey = keyPairs[0];
-
-var ringSignData = wan.genRingSignData(eth.accounts[2], privateKey, mixSetWith0x.join("+"))
+var ringSignData = personal.genRingSignData(eth.accounts[2], eth.accounts[2], otaAddr, mixSetWith0x.join("+"))
 var txRefundData = coinContract.refundCoin.getData(ringSignData, web3.toWei(1))
 eth.sendTransaction({from:eth.accounts[2], to:coinContractAddr, value:0, data:txRefundData, gas: 2000000});
 
@@ -69,7 +66,7 @@ oldValue2 = web3.fromWei(eth.getBalance(eth.accounts[2]));
 **********************************************
 
 /*************************
- 为 accounts[1] 买了邮票otaAddrStamp，私钥为privateKeyStamp
+ 为 accounts[1] 买了邮票otaAddrStamp
  **************************/
 abiDefStamp = [{"constant":false,"type":"function","stateMutability":"nonpayable","inputs":[{"name":"OtaAddr","type":"string"},{"name":"Value","type":"uint256"}],"name":"buyStamp","outputs":[{"name":"OtaAddr","type":"string"},{"name":"Value","type":"uint256"}]},{"constant":false,"type":"function","inputs":[{"name":"RingSignedData","type":"string"},{"name":"Value","type":"uint256"}],"name":"refundCoin","outputs":[{"name":"RingSignedData","type":"string"},{"name":"Value","type":"uint256"}]},{"constant":false,"type":"function","stateMutability":"nonpayable","inputs":[],"name":"getCoins","outputs":[{"name":"Value","type":"uint256"}]}];
 
@@ -85,9 +82,6 @@ txBuyData = stampContract.buyStamp.getData(otaAddrStamp, web3.toWei(0.001));
 
 eth.sendTransaction({from:eth.accounts[1], to:stampContractAddr, value:web3.toWei(0.001), data:txBuyData, gas: 1000000});
 
-keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrStamp).split('+');
-privateKeyStamp = keyPairs[0];
-
 //get mixStamp
 var mixStampAddresses = wan.getOTAMixSet(otaAddrStamp,2);
 var mixSetWith0x = []
@@ -118,26 +112,23 @@ var erc20simple = erc20simple_contract.at(contractAddr)
 
 
 //为account1生成一个OTA地址otaAddrTokenHolder持有指定数量的Token,addrTokenHolder为一次性地址的Address
-//privateKeyTokenHolder 为私钥
 var wanAddr = wan.getWanAddress(eth.accounts[1]);
 var otaAddrTokenHolder = wan.generateOneTimeAddress(wanAddr);
 keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrTokenHolder).split('+');
-privateKeyTokenHolder = keyPairs[0];
-addrTokenHolder = keyPairs[2];
+addrTokenHolder = keyPairs[1];
 erc20simple.initPrivacyAsset.sendTransaction(addrTokenHolder, otaAddrTokenHolder, '0x1000000000',{from:eth.accounts[1], gas:10000000});
 //erc20simple.privacyBalance(addrTokenHolder).toString(16)
 
 
-//使用代币发送方的一次性地址的address作为哈希msg，使用邮票私钥做ring sign
+//使用代币发送方的一次性地址的address作为哈希msg，使用邮票OTA在钱包内做ring sign
 var hashMsg = addrTokenHolder
-var ringSignData = wan.genRingSignData(hashMsg, privateKeyStamp, mixSetWith0x.join("+"))
+var ringSignData = personal.genRingSignData(eth.accounts[1], hashMsg, otaAddrStamp, mixSetWith0x.join("+"))
 
 //为接收方生成隐私地址
 var wanAddr = wan.getWanAddress(eth.accounts[2]);
 var otaAddr4Account2 = wan.generateOneTimeAddress(wanAddr);
 keyPairs = wan.computeOTAPPKeys(eth.accounts[2], otaAddr4Account2).split('+');
-privateKeyOtaAcc2 = keyPairs[0];
-addrOTAAcc2 = keyPairs[2];
+addrOTAAcc2 = keyPairs[1];
 //contract interface call data
 
 //使用合约接口生成经典的合约调用数据
@@ -149,7 +140,7 @@ glueContract = glueContractDef.at("0x0000000000000000000000000000000000000000")
 combinedData = glueContract.combine.getData(ringSignData, cxtInterfaceCallData)
 
 //发送隐私保护交易
-wan.sendPrivacyCxtTransaction({from:addrTokenHolder, to:contractAddr, value:0, data: combinedData}, privateKeyTokenHolder)
+personal.sendPrivacyCxtTransaction({from:eth.accounts[1], to:contractAddr, value:0, data: combinedData}, otaAddrTokenHolder)
 //查看接收者账户信息  
 erc20simple.privacyBalance(addrOTAAcc2)
 erc20simple.privacyBalance(addrTokenHolder)
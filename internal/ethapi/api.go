@@ -18,7 +18,6 @@
 package ethapi
 
 import (
-	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
@@ -38,6 +37,7 @@ import (
 	"github.com/wanchain/go-wanchain/common/math"
 	"github.com/wanchain/go-wanchain/consensus/ethash"
 	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/otaindex"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/crypto"
@@ -66,6 +66,8 @@ var (
 	ErrReqTooManyOTAMix                 = errors.New("Require too many OTA mix address")
 	ErrInvalidOTAMixNum                 = errors.New("Invalid required OTA mix address number")
 	ErrInvalidInput                     = errors.New("Invalid input")
+	ErrNoOTAScanner                     = errors.New("OTA scanner not running")
+	ErrOTAOwnerLocked                   = errors.New("no unlocked account owns the OTA")
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
@@ -407,6 +409,69 @@ func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 	return fetchKeystore(s.am).Lock(addr) == nil
 }
 
+// ListOTAs returns the unspent one-time addresses received by the account. An
+// account is only scanned while unlocked: the blocks it missed since its last
+// scan, all of them the first time, are scanned in the background once it is
+// unlocked again, and the OTAs they pay are missing from the list until this
+// catches up.
+func (s *PrivateAccountAPI) ListOTAs(addr common.Address) ([]keystore.ReceivedOTA, error) {
+	scanner := fetchKeystore(s.am).OTAScanner()
+	if scanner == nil {
+		return nil, ErrNoOTAScanner
+	}
+	return scanner.OTAs(addr), nil
+}
+
+// IsOTASpent reports whether the OTA has been refunded in the latest block.
+// The key image of the OTA is computed by the unlocked account that owns it.
+func (s *PrivateAccountAPI) IsOTASpent(ctx context.Context, otaAddr string) (bool, error) {
+	ota, err := hexutil.Decode(otaAddr)
+	if err != nil || len(ota) != common.WAddressLength {
+		return false, ErrInvalidOTAAddr
+	}
+
+	ks := fetchKeystore(s.am)
+	var image []byte
+	for _, account := range ks.Accounts() {
+		if image, err = ks.OTAKeyImage(account, ota); err == nil {
+			break
+		}
+	}
+	if image == nil {
+		return false, ErrOTAOwnerLocked
+	}
+
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return false, err
+	}
+	exist, _, err := vm.CheckOTAImageExist(state, image)
+	return exist, err
+}
+
+// ReceivedOTAs creates a subscription that fires for every one-time address
+// received or spent by the scanned accounts, and for those of them dropped by
+// a reorg. With an address, only the OTAs of that account are notified. A
+// client falling too far behind is dropped, the scanner doesn't wait for it.
+func (s *PrivateAccountAPI) ReceivedOTAs(ctx context.Context, addr *common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	scanner := fetchKeystore(s.am).OTAScanner()
+	if scanner == nil {
+		return &rpc.Subscription{}, ErrNoOTAScanner
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	events := make(chan keystore.ReceivedOTA)
+	go NotifyEvents(notifier, rpcSub, scanner.Subscribe(events), events, func(ev interface{}) bool {
+		return addr == nil || ev.(keystore.ReceivedOTA).Account == *addr
+	})
+
+	return rpcSub, nil
+}
+
 // SendTransaction will create a transaction from the given arguments and
 // tries to sign it with the key associated with args.To. If the given passwd isn't
 // able to decrypt the key it fails.
@@ -447,12 +512,26 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	return submitTransaction(ctx, s.b, signed)
 }
 
-// SendPrivacyCxtTransaction will create a transaction from the given arguments and
-// tries to sign it with the OTA key associated with args.To.
-func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args SendTxArgs, sPrivateKey string) (common.Hash, error) {
+// SendPrivacyCxtTransaction will create a privacy transaction from the given
+// arguments and sign it with the one-time key of an OTA received by args.From.
+// The transaction is sent from the one-time account of the OTA, whose key is
+// derived in the keystore of the unlocked account and never leaves it.
+func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args SendTxArgs, ota string) (common.Hash, error) {
+	otaBytes, err := hexutil.Decode(ota)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	ks := fetchKeystore(s.am)
+	account := accounts.Account{Address: args.From}
+	if args.From, err = ks.OTAAddress(account, otaBytes); err != nil {
+		return common.Hash{}, err
+	}
 
-	if !hexutil.Has0xPrefix(sPrivateKey) {
-		return common.Hash{}, ErrInvalidPrivateKey
+	if args.Nonce == nil {
+		// Hold the one-time address' mutex around signing to prevent concurrent
+		// assignment of the same nonce.
+		s.nonceLock.LockAddr(args.From)
+		defer s.nonceLock.UnlockAddr(args.From)
 	}
 
 	// Set some sanity defaults and terminate on failure
@@ -464,7 +543,7 @@ func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args
 		return common.Hash{}, ErrInvalidInput
 	}
 
-	// Assemble the transaction and sign with the wallet
+	// Assemble the transaction and sign with the OTA key
 	tx := args.toOTATransaction()
 
 	var chainID *big.Int
@@ -473,19 +552,7 @@ func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args
 		chainID = config.ChainId
 	}
 
-	privateKey, err := crypto.HexToECDSA(sPrivateKey[2:])
-	if err != nil {
-		return common.Hash{}, err
-	}
-
-	//for fixing bug send privacy tx with a different private key
-	fromAddr := crypto.PubkeyToAddress(privateKey.PublicKey)
-	if !bytes.Equal(fromAddr[:], args.From[:]) {
-		return common.Hash{}, errors.New("from address mismatch private key")
-	}
-
-	var signed *types.Transaction
-	signed, err = types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signed, err := ks.SignOTATx(account, otaBytes, tx, chainID)
 	if err != nil {
 		return common.Hash{}, err
 	}
@@ -493,46 +560,27 @@ func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args
 	return submitTransaction(ctx, s.b, signed)
 }
 
-// GenRingSignData generate ring sign data
-func (s *PrivateAccountAPI) GenRingSignData(ctx context.Context, hashMsg string, privateKey string, mixWanAdresses string) (string, error) {
-	if !hexutil.Has0xPrefix(privateKey) {
-		return "", ErrInvalidPrivateKey
-	}
-
+// GenRingSignData generates ring sign data of hashMsg with the one-time key of
+// an OTA received by the unlocked account, mixed with the OTAs of mixWanAdresses
+// separated by '+'.
+func (s *PrivateAccountAPI) GenRingSignData(ctx context.Context, address common.Address, hashMsg string, ota string, mixWanAdresses string) (string, error) {
 	hmsg, err := hexutil.Decode(hashMsg)
 	if err != nil {
 		return "", err
 	}
 
-	ecdsaPrivateKey, err := crypto.HexToECDSA(privateKey[2:])
-	if err != nil {
-		return "", err
-	}
-
-	privKey, err := hexutil.Decode(privateKey)
+	otaBytes, err := hexutil.Decode(ota)
 	if err != nil {
 		return "", err
 	}
 
-	if privKey == nil {
-		return "", ErrInvalidPrivateKey
-	}
-
 	wanAddresses := strings.Split(mixWanAdresses, "+")
 	if len(wanAddresses) == 0 {
 		return "", ErrInvalidOTAMixSet
 	}
 
-	return genRingSignData(hmsg, privKey, &ecdsaPrivateKey.PublicKey, wanAddresses)
-}
-
-func genRingSignData(hashMsg []byte, privateKey []byte, actualPub *ecdsa.PublicKey, mixWanAdress []string) (string, error) {
-	otaPrivD := new(big.Int).SetBytes(privateKey)
-
-	publicKeys := make([]*ecdsa.PublicKey, 0)
-	publicKeys = append(publicKeys, actualPub)
-
-	for _, strWanAddr := range mixWanAdress {
+	mixSet := make([][]byte, 0, len(wanAddresses))
+	for _, strWanAddr := range wanAddresses {
 		pubBytes, err := hexutil.Decode(strWanAddr)
 		if err != nil {
 			return "", errors.New("fail to decode wan address!")
@@ -542,17 +590,10 @@ func genRingSignData(hashMsg []byte, privateKey []byte, actualPub *ecdsa.PublicK
 			return "", ErrInvalidWAddress
 		}
 
-		publicKeyA, _, err := keystore.GeneratePKPairFromWAddress(pubBytes)
-		if err != nil {
-
-			return "", errors.New("Fail to generate public key from wan address!")
-
-		}
-
-		publicKeys = append(publicKeys, publicKeyA)
+		mixSet = append(mixSet, pubBytes)
 	}
 
-	retPublicKeys, keyImage, w_random, q_random, err := crypto.RingSign(hashMsg, otaPrivD, publicKeys)
+	retPublicKeys, keyImage, w_random, q_random, err := fetchKeystore(s.am).RingSignOTA(accounts.Account{Address: address}, otaBytes, hmsg, mixSet)
 	if err != nil {
 		return "", err
 	}
@@ -827,17 +868,13 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
-
-	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
-		return nil, common.Big0, false, err
-	}
+// ToMessage returns the call message of args. The sender defaults to the first
+// account of am, the gas and gas price to those of eth_call.
+func (args *CallArgs) ToMessage(am *accounts.Manager) types.Message {
 	// Set sender address or use a default if none specified
 	addr := args.From
 	if addr == (common.Address{}) {
-		if wallets := s.b.AccountManager().Wallets(); len(wallets) > 0 {
+		if wallets := am.Wallets(); len(wallets) > 0 {
 			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
 				addr = accounts[0].Address
 			}
@@ -852,9 +889,18 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gasPrice.Sign() == 0 {
 		gasPrice = defaultGasPrice
 	}
+	return types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config) ([]byte, *big.Int, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, common.Big0, false, err
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
+	msg := args.ToMessage(s.b.AccountManager())
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -1450,7 +1496,7 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		return []string{}, ErrInvalidOTAAddr
 	}
 
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1460,7 +1506,12 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		otaAX, _ = vm.GetAXFromWanAddr(orgOtaAddr)
 	}
 
-	otaByteSet, _, err := vm.GetOTASet(state, otaAX, setLen)
+	// draw the set from the OTA index, walk the ota storage if the node has
+	// no index of the block
+	otaByteSet, _, err := vm.SampleOTASet(state, otaindex.New(s.b.ChainDb()), header.Number.Uint64(), otaAX, setLen)
+	if err == vm.ErrOTAIndexUnavailable {
+		otaByteSet, _, err = vm.GetOTASet(state, otaAX, setLen)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -1474,12 +1525,48 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 	return ret, nil
 }
 
-// ComputeOTAPPKeys compute ota private key, public key and short address
-// from account address and ota full address.
+// OTAImageInfo is the refund that disclosed the key image of an OTA. The block
+// and transaction are nil for the refunds of blocks imported before the node
+// indexed key images, until its background indexer reaches them.
+type OTAImageInfo struct {
+	Image            hexutil.Bytes   `json:"image"`
+	Value            *hexutil.Big    `json:"value"`
+	BlockHash        *common.Hash    `json:"blockHash"`
+	BlockNumber      *hexutil.Big    `json:"blockNumber"`
+	TransactionHash  *common.Hash    `json:"transactionHash"`
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+}
+
+// GetOTAImageInfo returns the refund of the latest block that used the key
+// image, nil if the image is unused.
+func (s *PublicTransactionPoolAPI) GetOTAImageInfo(ctx context.Context, image hexutil.Bytes) (*OTAImageInfo, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	exist, value, err := vm.CheckOTAImageExist(state, image)
+	if err != nil || !exist {
+		return nil, err
+	}
+
+	info := &OTAImageInfo{Image: image, Value: (*hexutil.Big)(new(big.Int).SetBytes(value))}
+	if txHash := otaindex.ImageTx(s.b.ChainDb(), image); txHash != (common.Hash{}) {
+		if tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), txHash); tx != nil {
+			info.BlockHash = &blockHash
+			info.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
+			info.TransactionHash = &txHash
+			info.TransactionIndex = (*hexutil.Uint64)(&index)
+		}
+	}
+	return info, nil
+}
+
+// ComputeOTAPPKeys computes the ota public key and the short address of the
+// one-time account from account address and ota full address. The one-time
+// private key stays in the keystore of the account.
 func (s *PublicTransactionPoolAPI) ComputeOTAPPKeys(ctx context.Context, address common.Address, inOtaAddr string) (string, error) {
 	account := accounts.Account{Address: address}
-	wallet, err := s.b.AccountManager().Find(account)
-	if err != nil {
+	if _, err := s.b.AccountManager().Find(account); err != nil {
 		return "", err
 	}
 
@@ -1493,36 +1580,13 @@ func (s *PublicTransactionPoolAPI) ComputeOTAPPKeys(ctx context.Context, address
 		return "", err
 	}
 
-	otaAddr := hexutil.Encode(otaBytes)
-
-	//AX string, AY string, BX string, BY string
-	otaAddr = strings.Replace(otaAddr, "0x", "", -1)
-	AX := "0x" + otaAddr[0:64]
-	AY := "0x" + otaAddr[64:128]
-
-	BX := "0x" + otaAddr[128:192]
-	BY := "0x" + otaAddr[192:256]
-
-	sS, err := wallet.ComputeOTAPPKeys(account, AX, AY, BX, BY)
-	if err != nil {
-		return "", err
-	}
-
-	otaPub := sS[0] + sS[1][2:]
-	otaPriv := sS[2]
-
-	privateKey, err := crypto.HexToECDSA(otaPriv[2:])
+	addr, err := fetchKeystore(s.b.AccountManager()).OTAAddress(account, wanBytes)
 	if err != nil {
 		return "", err
 	}
 
-	var addr common.Address
-	pubkey := crypto.FromECDSAPub(&privateKey.PublicKey)
-	//caculate the address for replaced pub
-	copy(addr[:], crypto.Keccak256(pubkey[1:])[12:])
-
-	return otaPriv + "+" + otaPub + "+" + hexutil.Encode(addr[:]), nil
-
+	otaPub := hexutil.Encode(otaBytes[:64])
+	return otaPub + "+" + hexutil.Encode(addr[:]), nil
 }
 
 // SendRawTransaction will add the signed transaction to the transaction pool.
@@ -0,0 +1,83 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"reflect"
+
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// notifyQueueSize is the number of notifications a subscriber may fall behind
+// before it is dropped.
+const notifyQueueSize = 128
+
+// notifyQueue delivers the events of a subscription to its client in order.
+// The events are queued by the goroutine reading them off their feed, so a
+// slow client never holds up the feed.
+type notifyQueue struct {
+	queue chan interface{}
+}
+
+// newNotifyQueue starts sending the queued events with notify.
+func newNotifyQueue(notify func(ev interface{})) *notifyQueue {
+	q := &notifyQueue{queue: make(chan interface{}, notifyQueueSize)}
+	go func() {
+		for ev := range q.queue {
+			notify(ev)
+		}
+	}()
+	return q
+}
+
+// push queues an event. It returns false when the client is notifyQueueSize
+// notifications behind, the subscription is then to be dropped.
+func (q *notifyQueue) push(ev interface{}) bool {
+	select {
+	case q.queue <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops the queue once the queued events are sent.
+func (q *notifyQueue) close() {
+	close(q.queue)
+}
+
+// NotifyEvents sends the events sub delivers on the channel events to the RPC
+// subscription rpcSub, until the client unsubscribes or its connection is
+// closed. With filter, only the events it accepts are sent. The events are
+// queued, a client notifyQueueSize notifications behind is dropped instead of
+// holding up the feed.
+func NotifyEvents(notifier *rpc.Notifier, rpcSub *rpc.Subscription, sub event.Subscription, events interface{}, filter func(ev interface{}) bool) {
+	defer sub.Unsubscribe()
+	queue := newNotifyQueue(func(ev interface{}) {
+		notifier.Notify(rpcSub.ID, ev)
+	})
+	defer queue.close()
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(events)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(rpcSub.Err())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(notifier.Closed())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(sub.Err())},
+	}
+	for {
+		chosen, recv, _ := reflect.Select(cases)
+		if chosen != 0 {
+			return
+		}
+		ev := recv.Interface()
+		if filter != nil && !filter(ev) {
+			continue
+		}
+		if !queue.push(ev) {
+			log.Warn("Dropping slow subscriber", "id", rpcSub.ID)
+			return
+		}
+	}
+}
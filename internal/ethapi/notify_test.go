@@ -0,0 +1,55 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests that the events of a subscription are queued in order without waiting
+// for the client, and that a client too far behind is dropped.
+func TestNotifyQueue(t *testing.T) {
+	var (
+		held  = make(chan struct{})
+		stall = make(chan struct{})
+		sent  = make(chan interface{}, notifyQueueSize+1)
+	)
+	queue := newNotifyQueue(func(ev interface{}) {
+		if ev == 0 {
+			close(held)
+		}
+		<-stall
+		sent <- ev
+	})
+
+	// the first event is held by the stalled client, the next ones queued
+	if !queue.push(0) {
+		t.Fatal("first event not queued")
+	}
+	<-held
+	done := make(chan bool)
+	go func() {
+		ok := true
+		for i := 1; i <= notifyQueueSize; i++ {
+			ok = ok && queue.push(i)
+		}
+		done <- ok && !queue.push(notifyQueueSize+1)
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Fatal("client dropped before falling behind, or not dropped after")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pushing to a stalled client blocked")
+	}
+
+	close(stall)
+	queue.close()
+	for i := 0; i <= notifyQueueSize; i++ {
+		if ev := <-sent; ev != i {
+			t.Fatalf("event %d: got %v", i, ev)
+		}
+	}
+}
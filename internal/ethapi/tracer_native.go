@@ -0,0 +1,229 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// Names of the native tracers, given as TraceArgs.Tracer in place of the code
+// of a JavaScript tracer.
+const (
+	CallTracerName     = "callTracer"
+	PrestateTracerName = "prestateTracer"
+	FourByteTracerName = "4byteTracer"
+)
+
+// NativeTracer is a tracer written in Go, following the call frames of the
+// traced message.
+type NativeTracer interface {
+	vm.FrameTracer
+	GetResult() (interface{}, error)
+}
+
+// IsNativeTracer reports whether name is the name of a native tracer.
+func IsNativeTracer(name string) bool {
+	switch name {
+	case CallTracerName, PrestateTracerName, FourByteTracerName:
+		return true
+	}
+	return false
+}
+
+// NewNativeTracer returns the native tracer called name, false if there is
+// none. pre is the state the traced message runs on, which the prestate
+// tracer reads before the message changes it.
+func NewNativeTracer(name string, pre *state.StateDB) (NativeTracer, bool) {
+	switch name {
+	case CallTracerName:
+		return &callTracer{}, true
+	case PrestateTracerName:
+		return &prestateTracer{pre: pre.Copy(), accounts: make(map[common.Address]*prestateAccount)}, true
+	case FourByteTracerName:
+		return &fourByteTracer{ids: make(map[string]int)}, true
+	}
+	return nil, false
+}
+
+// callFrame is a call of the call tree. Calls of precompiled contracts name
+// the contract, and the method called for the Wanchain ones.
+type callFrame struct {
+	Type       string         `json:"type"`
+	From       common.Address `json:"from"`
+	To         common.Address `json:"to"`
+	Value      *hexutil.Big   `json:"value,omitempty"`
+	Gas        hexutil.Uint64 `json:"gas"`
+	GasUsed    hexutil.Uint64 `json:"gasUsed"`
+	Input      hexutil.Bytes  `json:"input"`
+	Output     hexutil.Bytes  `json:"output,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Precompile string         `json:"precompile,omitempty"`
+	Method     string         `json:"method,omitempty"`
+	Calls      []*callFrame   `json:"calls,omitempty"`
+}
+
+// callTracer builds the call tree of the message. The gas of the top frame is
+// the gas left after the intrinsic gas of the transaction.
+type callTracer struct {
+	root  *callFrame
+	stack []*callFrame // frames entered and not left yet
+}
+
+func (t *callTracer) CaptureEnter(typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	frame := &callFrame{
+		Type:  typ.String(),
+		From:  from,
+		To:    to,
+		Gas:   hexutil.Uint64(gas),
+		Input: common.CopyBytes(input),
+	}
+	if value != nil {
+		frame.Value = (*hexutil.Big)(new(big.Int).Set(value))
+	}
+	frame.Precompile, frame.Method = vm.PrecompileCall(to, input)
+
+	if len(t.stack) == 0 {
+		t.root = frame
+	} else {
+		parent := t.stack[len(t.stack)-1]
+		parent.Calls = append(parent.Calls, frame)
+	}
+	t.stack = append(t.stack, frame)
+}
+
+func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if len(t.stack) == 0 {
+		return
+	}
+	frame := t.stack[len(t.stack)-1]
+	t.stack = t.stack[:len(t.stack)-1]
+
+	frame.GasUsed = hexutil.Uint64(gasUsed)
+	frame.Output = common.CopyBytes(output)
+	if err != nil {
+		frame.Error = err.Error()
+	}
+}
+
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+func (t *callTracer) GetResult() (interface{}, error) {
+	if t.root == nil {
+		return nil, errors.New("no call traced")
+	}
+	return t.root, nil
+}
+
+// prestateAccount is an account touched by the message, as it was before.
+type prestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   uint64                      `json:"nonce"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// prestateTracer collects the accounts and storage slots the message reads or
+// writes, with their values before the message. The storage written by the
+// precompiled contracts is not collected, as they run no opcodes.
+type prestateTracer struct {
+	pre      *state.StateDB
+	accounts map[common.Address]*prestateAccount
+}
+
+func (t *prestateTracer) lookupAccount(addr common.Address) *prestateAccount {
+	if account, ok := t.accounts[addr]; ok {
+		return account
+	}
+	account := &prestateAccount{
+		Balance: (*hexutil.Big)(new(big.Int).Set(t.pre.GetBalance(addr))),
+		Nonce:   t.pre.GetNonce(addr),
+		Code:    common.CopyBytes(t.pre.GetCode(addr)),
+	}
+	t.accounts[addr] = account
+	return account
+}
+
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	account := t.lookupAccount(addr)
+	if account.Storage == nil {
+		account.Storage = make(map[common.Hash]common.Hash)
+	}
+	if _, ok := account.Storage[key]; !ok {
+		account.Storage[key] = t.pre.GetState(addr, key)
+	}
+}
+
+func (t *prestateTracer) CaptureEnter(typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.lookupAccount(from)
+	if typ != vm.CREATE {
+		t.lookupAccount(to)
+	}
+}
+
+func (t *prestateTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil || len(stack.Data()) == 0 {
+		return nil
+	}
+	switch op {
+	case vm.SLOAD, vm.SSTORE:
+		t.lookupStorage(contract.Address(), common.BigToHash(stack.Back(0)))
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.SELFDESTRUCT:
+		t.lookupAccount(common.BigToAddress(stack.Back(0)))
+	}
+	return nil
+}
+
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+func (t *prestateTracer) GetResult() (interface{}, error) {
+	return t.accounts, nil
+}
+
+// fourByteTracer counts the calls by method selector and size of the
+// arguments, as "selector-size". Calls of precompiled contracts are counted
+// only for the known methods of the Wanchain ones.
+type fourByteTracer struct {
+	ids map[string]int
+}
+
+func (t *fourByteTracer) CaptureEnter(typ vm.OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if typ == vm.CREATE || len(input) < 4 {
+		return
+	}
+	if contract, method := vm.PrecompileCall(to, input); contract != "" && method == "" {
+		return
+	}
+	t.ids[fmt.Sprintf("%s-%d", hexutil.Encode(input[:4]), len(input)-4)]++
+}
+
+func (t *fourByteTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+func (t *fourByteTracer) GetResult() (interface{}, error) {
+	return t.ids, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package ethapi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// runNativeTrace calls a contract that reads its storage slot 1, calls the
+// sha256 precompile with 4 bytes and the wancoin contract with a bare
+// buyCoinNote selector, which fails.
+func runNativeTrace(t *testing.T, name string) interface{} {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	var (
+		from     = common.HexToAddress("0x1000")
+		contract = common.HexToAddress("0x2000")
+		selector = crypto.Keccak256([]byte("buyCoinNote(string,uint256)"))[:4]
+	)
+	code := []byte{
+		byte(vm.PUSH1), 0x01, byte(vm.SLOAD), byte(vm.POP),
+		// sha256 of memory[0:4] into memory[32:64]
+		byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x20, byte(vm.PUSH1), 0x04, byte(vm.PUSH1), 0x00,
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x02, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		// wancoin with the selector stored at memory[28:32]
+		byte(vm.PUSH4), selector[0], selector[1], selector[2], selector[3], byte(vm.PUSH1), 0x00, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x04, byte(vm.PUSH1), 0x1c,
+		byte(vm.PUSH1), 0x00, byte(vm.PUSH1), 0x64, byte(vm.GAS), byte(vm.CALL), byte(vm.POP),
+		byte(vm.STOP),
+	}
+	statedb.SetBalance(from, big.NewInt(1000))
+	statedb.SetCode(contract, code)
+	statedb.SetState(contract, common.BigToHash(big.NewInt(1)), common.BigToHash(big.NewInt(42)))
+
+	tracer, ok := NewNativeTracer(name, statedb)
+	if !ok {
+		t.Fatalf("no native tracer %q", name)
+	}
+	ctx := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(0),
+		Difficulty:  big.NewInt(0),
+		GasLimit:    big.NewInt(1000000),
+		GasPrice:    big.NewInt(1),
+	}
+	env := vm.NewEVM(ctx, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := env.Call(vm.AccountRef(from), contract, []byte{0x01, 0x02, 0x03, 0x04, 0x05}, 1000000, big.NewInt(0)); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("no result: %v", err)
+	}
+	return res
+}
+
+func TestCallTracer(t *testing.T) {
+	root := runNativeTrace(t, CallTracerName).(*callFrame)
+	if root.Type != "CALL" || root.To != common.HexToAddress("0x2000") || root.Error != "" {
+		t.Fatalf("bad root frame: %+v", root)
+	}
+	if root.GasUsed == 0 || root.GasUsed > root.Gas {
+		t.Errorf("bad root gas used: %d of %d", root.GasUsed, root.Gas)
+	}
+	if len(root.Calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(root.Calls))
+	}
+	sha := root.Calls[0]
+	if sha.Precompile != "sha256" || sha.Method != "" || sha.Error != "" || len(sha.Output) != 32 {
+		t.Errorf("bad sha256 frame: %+v", sha)
+	}
+	coin := root.Calls[1]
+	if coin.Precompile != "wanCoin" || coin.Method != "buyCoinNote" || coin.Error == "" {
+		t.Errorf("bad wancoin frame: %+v", coin)
+	}
+	if len(coin.Input) != 4 {
+		t.Errorf("got wancoin input %x, want the selector", coin.Input)
+	}
+}
+
+func TestPrestateTracer(t *testing.T) {
+	accounts := runNativeTrace(t, PrestateTracerName).(map[common.Address]*prestateAccount)
+
+	sender, ok := accounts[common.HexToAddress("0x1000")]
+	if !ok || sender.Balance.ToInt().Int64() != 1000 {
+		t.Errorf("bad sender: %+v", sender)
+	}
+	contract, ok := accounts[common.HexToAddress("0x2000")]
+	if !ok || len(contract.Code) == 0 {
+		t.Fatalf("bad contract: %+v", contract)
+	}
+	if v := contract.Storage[common.BigToHash(big.NewInt(1))]; v != common.BigToHash(big.NewInt(42)) {
+		t.Errorf("got slot 1 %x, want 42", v)
+	}
+	for _, addr := range []common.Address{common.BytesToAddress([]byte{2}), common.BytesToAddress([]byte{100})} {
+		if _, ok := accounts[addr]; !ok {
+			t.Errorf("precompile %x missing", addr)
+		}
+	}
+}
+
+func TestFourByteTracer(t *testing.T) {
+	ids := runNativeTrace(t, FourByteTracerName).(map[string]int)
+
+	want := map[string]int{
+		"0x01020304-1": 1,
+	}
+	selector := crypto.Keccak256([]byte("buyCoinNote(string,uint256)"))[:4]
+	want[common.ToHex(selector)+"-0"] = 1
+	if len(ids) != len(want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	for id, n := range want {
+		if ids[id] != n {
+			t.Errorf("got %d calls of %s, want %d", ids[id], id, n)
+		}
+	}
+}
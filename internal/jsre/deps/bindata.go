@@ -89,7 +89,7 @@ func bignumberJs() (*asset, error) {
 	return a, nil
 }
 
-var _web3Js = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\xfd\x6b\x7b\x13\x39\xd2\x38\x0e\xbf\xcf\xa7\x50\xfc\xdc\x0f\xb6\x49\x63\xe7\x34\x0c\xd3\x99\x0c\x1b\x02\x33\x64\x6f\x20\x5c\x40\x76\x76\xef\x6c\x96\xab\xed\x96\x6d\x0d\xed\x6e\xff\xba\xdb\x09\x19\x92\xef\xfe\xbf\x54\x3a\x1f\xfa\xe0\x24\xcc\x69\x93\x17\xe0\x96\x4a\xa7\x52\xa9\x54\x2a\x95\xaa\x72\xfc\xff\x96\x24\xc7\xfb\xbd\xc9\x32\x1d\x97\x24\x4b\x11\xee\x95\x41\x1a\xe4\xfd\x2f\x32\xa5\xe8\x65\xc1\xb2\xff\x85\x4c\x7a\xeb\xe9\x69\x76\xc6\x7e\x95\xf0\xeb\x3c\xca\x51\xb4\x5f\x5e\x2e\x70\x36\x41\xa2\xae\xfd\x8e\x28\xda\x79\xf0\x80\x27\xee\xd1\x32\xcb\x07\x0f\xa2\x7e\x8e\xcb\x65\x9e\xa2\xa8\x97\x05\xeb\x9b\x7d\x9a\x4e\x44\x1a\xe1\x69\xb4\xd6\xc9\x7e\x8a\x2f\xd0\x8b\x3c\xcf\xf2\x5e\xe7\x30\x4a\xd3\xac\x44\x13\x92\xc6\x68\x9e\xc5\xcb\x04\xa3\x6e\x67\x23\xdb\xe8\x74\x3b\xfd\xbd\x72\x96\x67\x17\x68\x32\x18\x67\x31\xde\xef\xbc\x3e\x7e\x7e\xf2\xea\xc5\xc7\x37\xc7\x1f\x3e\xfe\x78\x7c\xf2\xe6\x79\x27\x98\x5c\xd3\xfa\x92\x7d\xda\xf7\xfd\x2f\xf8\xf3\x22\xcb\xcb\x22\xfc\x72\x7d\xbd\x47\xc7\x70\xba\x79\x36\x18\x47\x49\xd2\x4b\x06\x3c\x2b\x10\xbd\xef\x61\x36\xc0\x74\x1f\x00\xb7\xce\x4e\xf1\xd9\x1e\xef\x6a\xd1\x4b\x9f\xa6\x21\xee\x5f\x07\x49\xa0\x4a\xe2\x80\xe1\xee\x9a\x43\xd1\x26\x45\x26\xf4\x82\xb4\xc2\xd5\x24\xcb\x7b\x14\x3a\xdb\xdf\xdc\xcb\xbe\xcf\x07\x09\x4e\xa7\xe5\x6c\x2f\xdb\xd8\xe8\x17\xbd\x9c\x22\x5e\x76\xe3\xba\xdf\xfb\xb2\x15\x9e\xca\x2e\xf3\x2a\x02\x86\xa5\x80\xb7\xdd\xff\xb2\xc6\x12\x44\x67\xf6\x4f\xd7\x10\xfa\xb2\x86\x10\x42\x9d\x71\x96\x16\x65\x94\x96\x9d\x10\x95\xf9\x12\x07\x2c\x95\xa4\x8b\x65\x59\x74\x42\x74\x0a\xdf\x02\x1a\xf2\xd2\x68\x8e\x3b\x21\xea\x7c\xcc\x2e\x52\x9c\x77\x02\x95\x43\x47\x47\x73\xa2\x38\xce\x71\x51\x74\x78\xce\x35\xfc\x7f\xc6\xab\x16\xc5\xe1\x7f\x9e\x96\x2d\xcb\xe6\xf6\xb2\x8f\x5a\x11\xa3\xbd\xd1\x65\x89\x8b\x9d\x6d\x7f\x7b\x02\x48\x62\x7a\x0d\xa1\xeb\xe0\x4e\x10\x70\xa3\xfe\xc8\xe1\x68\xd8\x6b\x87\x80\x95\x51\xfd\x47\x1d\xfa\x38\x4b\x4b\x9c\x96\xb7\x1e\xfc\x9f\x72\xde\xe9\x8c\xfd\x61\xa6\x7d\x12\x25\xc5\x6f\x37\xf4\x1c\x17\x38\x3f\xf7\xad\xfa\x3f\xfa\xa4\x15\xcb\xd1\x3b\x3c\x25\x45\x99\x47\xff\x05\x93\x17\xd4\xd5\x81\x2f\x8e\x6f\xc5\xf7\xcb\x3c\x4a\x8b\x89\x97\xf5\xfd\x59\x70\x90\x5b\xa4\xb0\x3a\x12\x0a\x5c\xbe\xaf\x27\xa9\x3b\xc3\x85\xdd\xf4\x6f\xd2\xe8\x57\x9e\x80\xa8\x0d\xe2\xeb\x2a\x58\xe4\x64\x1e\xe5\x97\xde\x7e\x64\x59\xd2\x38\x79\x07\xbc\xad\x3f\x2f\x0a\xcd\x3d\xb8\xb6\x9a\x2a\x24\x1c\x56\x6e\xe3\x7f\x24\x24\x78\x7b\x1f\x93\x22\xbb\x48\x6f\xd1\xf3\x28\xcd\xd2\xcb\x79\xb6\x2c\x56\xe8\x3a\x49\x63\xfc\x19\xc7\xc6\xde\x75\x67\x13\xab\x2a\xd7\xba\x63\xd6\x7e\x41\xd2\xdb\x30\xee\x83\x25\x60\xe2\x45\x1a\xe3\xb8\x63\xa1\x09\x9f\x53\x42\xf8\x0b\xe0\x68\x44\xe2\xb8\x1d\x8e\x6e\x56\xff\x79\x94\x2c\xbd\xdd\x5f\x92\xb4\xdc\xfe\xe6\x71\xfd\x14\xbc\xc1\x17\xcf\xc8\xef\x88\xfc\x5b\xad\xb9\xc3\x59\x94\x4e\x7f\x4f\xd2\xb9\x13\xca\xa9\xa8\x5b\x93\xea\x6b\xa9\xc6\x8b\x99\xb7\x6c\x37\x6a\x44\xd0\xda\xd9\xda\xda\x75\xf0\xe5\xfa\x2c\xd8\xfe\xdd\x0e\xfd\x7f\xa1\x33\xef\xef\x24\x3b\x4e\x96\x69\x7c\x63\x52\xb9\xf5\xc6\x75\x7f\xec\xfd\x73\x1f\x7b\xef\x0f\x7d\x7f\xe4\x33\x87\x77\xf0\xfc\xbc\xf0\x47\x93\x36\xbf\xee\x66\xae\xf6\xaa\x9d\x3b\xdb\xab\x56\x9d\xf7\x49\x9e\xcd\x6f\x39\xed\x65\x76\xcb\xa3\xe6\xed\x04\xbe\xdf\x77\xdd\xfc\x11\xf0\x47\xd2\x98\xe4\x78\x5c\x1e\x79\xf7\xcc\x15\x7a\x72\xbb\x89\x20\xe3\x68\xf1\xe1\x77\x9d\x0c\x3f\x26\xdb\x9d\x76\xf1\x22\x2b\x48\xdd\x41\x7d\x11\x5d\x46\xa3\x04\x9b\x42\xc1\xef\xc2\x95\xaa\x68\xee\x4e\x8e\x5f\xb7\xa3\x81\x03\x31\xde\xe7\x26\x3e\x7f\xfb\x93\xcc\x9d\x20\xa9\xa2\xee\x76\x74\xf6\x3b\xa0\xff\x0f\x8b\xf5\xbb\x38\x3f\xde\x98\x4f\x7e\x6d\xac\xdb\x4c\xef\x1e\xed\x2d\xd1\x7e\xeb\x8d\xeb\x6b\xcf\xec\x91\x67\x4b\xab\x93\xe3\x76\xdb\xc8\x71\x60\xbc\x81\xf6\x85\x85\x43\xaf\x3b\x18\x4e\xb2\x7c\x1e\x95\x25\xce\x8b\x6e\x7f\x0f\x00\xde\x67\x09\x89\x49\x79\xf9\xe1\x72\x81\x4d\x58\xda\x3e\x85\x5a\x1b\x3e\x7c\xb8\x86\x1e\x1a\x90\x5c\xe7\x8e\x48\x81\x22\xb4\xc8\xb3\x8c\x02\xa3\x72\x16\x95\x28\xc7\x0b\x7a\xc8\x4a\xcb\x02\xf1\xb9\x43\x34\x93\xd6\x70\x54\xa2\x79\x54\x8e\x67\xb8\x08\xe9\x27\xcf\xd6\x7e\x9e\x9e\xe9\x1f\xbb\xc6\xd7\x99\x99\xb9\x63\x7d\x9f\x9d\x3e\x3e\x3b\x3d\x0b\xd0\x60\x30\x58\x43\x0f\x87\xce\xd8\x44\x8f\xf7\x91\xb4\xa6\xe9\xf5\xf9\x14\x97\x33\x52\x0c\x3e\xc2\xc2\xf8\x51\x20\x88\x02\x0e\x18\xba\x8e\x68\xc6\x51\x5a\xee\x69\xc0\x6c\xdf\xf6\x41\x1f\x43\x0e\x6f\x6e\x6f\xed\x7a\x6f\x6d\xcd\xd3\x8f\xc1\x22\xcf\x4a\x86\xb5\x7d\x94\xe2\x0b\xa3\xaf\xbd\x2f\xd7\xfd\xbd\xfa\x52\x03\x90\x5e\xf2\xe5\xb8\xcc\x68\xe3\x1e\xd8\xa6\x76\x07\xa4\xe0\x73\xae\x10\x42\xc9\x51\x20\x85\xdb\xb5\xac\xaf\xd3\xc4\x01\xcc\x5b\x6f\xc8\xb1\xdd\xfb\xf7\x69\xef\x74\xf3\xd1\x77\x67\x0f\xfb\xff\x3e\xeb\x3f\x1d\xf6\xd9\x38\xcd\x83\x43\x65\xb7\xae\x83\x2f\x1d\x9d\x14\x3b\xe1\x77\x41\x87\xd1\x5b\x27\xdc\xda\xbd\x3e\x0b\xbe\xf9\x9d\xc9\xfb\x59\x96\x25\x0d\xb4\x3d\xa2\x20\x15\x84\x4d\xf3\xc4\xff\x8c\x4a\xe1\xd7\xae\xfa\x79\xa6\x25\xef\xe8\x1f\x4d\x64\x0c\x3d\xbb\x29\x0d\xd3\xc2\xab\x10\x31\x83\xb7\x29\x98\xa6\xae\x48\xbe\x66\x91\x1a\xda\x65\x2d\xd6\x95\xbd\x09\xd5\xfe\x87\xa2\xd6\xa4\xd9\x87\xff\xd3\x8a\x68\x79\x7f\x9a\x29\xf6\xf1\xef\x4d\xb1\x74\x0f\x93\x24\x5b\xfa\x69\xb6\x9c\x61\x04\x9b\x1d\x10\xee\xc0\x47\xb9\x34\x57\xfe\xe0\x74\x09\x3f\x77\xb5\xdf\x67\x7a\xc6\x8e\xf1\x65\xd2\x2f\xe2\x5b\xab\xfc\xf9\xc4\xa8\x87\x17\xf5\x50\x39\x74\xf2\xc6\x64\x4e\x4b\xaf\x44\xe7\xac\x80\x43\xe8\x34\x79\x55\x4a\x37\xcb\xd4\x91\x3a\x6b\xb4\xb6\xf4\xcd\x88\x9d\x56\xc2\x48\xfd\xcb\x56\x70\xdd\xbf\x19\xe1\xf3\xde\x35\x53\xfe\xb7\x6d\x28\x7f\xf8\x10\x3a\xfc\x61\x46\x0a\x34\x21\x09\xa6\x94\xba\x88\xf2\x12\x65\x13\x74\x81\x47\x3b\x83\x5f\x8a\xc1\x1a\x80\xf0\x2f\x0a\x30\xc9\x31\x46\x45\x36\x29\x2f\xa2\x1c\x87\xe8\x32\x5b\xa2\x71\x94\xa2\x1c\xc7\xa4\x28\x73\x32\x5a\x96\x18\x91\x12\x45\x69\x3c\xcc\x72\x34\xcf\x62\x32\xb9\x84\x3a\x48\x89\x96\x69\x8c\x73\x20\xf8\x12\xe7\xf3\x82\xb6\x43\x3f\x7e\x7a\x73\x82\x5e\xe1\xa2\xc0\x39\xfa\x09\xa7\x38\x8f\x12\xf4\x76\x39\x4a\xc8\x18\xbd\x22\x63\x9c\x16\x18\x45\x05\x5a\xd0\x94\x62\x86\x63\x34\xba\xe4\x54\x84\xd1\x8f\xb4\x33\xef\x79\x67\xd0\x8f\xd9\x32\x8d\x23\x3a\xe6\x00\x61\x52\xce\x70\x8e\xce\x71\x5e\xd0\x19\xda\x11\x6d\xf1\x1a\x03\x94\xe5\x50\x4b\x2f\x2a\xe9\x18\x72\x94\x2d\x68\xc1\x3e\x8a\xd2\x4b\x94\x44\xa5\x2a\xeb\xa2\x40\x8d\x34\x46\x24\x85\x6a\x67\x99\x58\xd9\xa4\x44\x17\x24\x49\xd0\x08\xa3\x65\x81\x27\xcb\x84\x09\x8e\xa3\x65\x89\x7e\x3e\xfa\xf0\xf2\xf8\xe4\x03\x3a\x78\xf3\x2f\xf4\xf3\xc1\xbb\x77\x07\x6f\x3e\xfc\x6b\x0f\x5d\x90\x72\x96\x2d\x4b\x44\x25\x4a\xa8\x8b\xcc\x17\x09\xc1\x31\xba\x88\xf2\x3c\x4a\xcb\x4b\x94\x4d\xa0\x8a\xd7\x2f\xde\x1d\xbe\x3c\x78\xf3\xe1\xe0\xd9\xd1\xab\xa3\x0f\xff\x42\x59\x8e\x7e\x3c\xfa\xf0\xe6\xc5\xfb\xf7\xe8\xc7\xe3\x77\xe8\x00\xbd\x3d\x78\xf7\xe1\xe8\xf0\xe4\xd5\xc1\x3b\xf4\xf6\xe4\xdd\xdb\xe3\xf7\x2f\x06\x08\xbd\xc7\xb4\x63\x18\x6a\x68\x46\xf4\x04\xe6\x2c\xc7\x28\xc6\x65\x44\x12\x31\xff\xff\xca\x96\xa8\x98\x65\xcb\x24\x46\xb3\xe8\x1c\xa3\x1c\x8f\x31\x39\xc7\x31\x8a\xd0\x38\x5b\x5c\xb6\x9e\x48\xa8\x2c\x4a\xb2\x74\x0a\xc3\x96\x54\x86\xd0\xd1\x04\xa5\x59\x19\xa0\x02\x63\xf4\xfd\xac\x2c\x17\xe1\x70\x78\x71\x71\x31\x98\xa6\xcb\x41\x96\x4f\x87\x09\xab\xa0\x18\xfe\x30\x58\x7b\x38\x14\xcc\xf6\x6f\x40\xb6\xe3\x2c\xc6\xf9\xe0\x17\x60\x91\x7f\x8b\x96\xe5\x2c\xcb\xd1\xeb\x28\xc7\x9f\xd0\xff\x66\x25\xbe\x20\xe3\x5f\xd1\xf7\x73\xfa\xfd\x37\x5c\xce\x62\x7c\x3e\x18\x67\xf3\x1f\x00\x38\x8e\x4a\x8c\xb6\x37\xb7\xbe\x01\x86\xd7\xbc\x15\xd4\x08\xb0\x5a\x19\x2e\x8f\xf9\xf6\x0e\x2e\x29\x68\xc0\x74\x17\xf4\x41\x1e\xa5\xa5\x09\x48\xd2\xd2\x07\x77\xe2\x00\x2e\x2b\x20\x9f\x5f\xa6\xd1\x9c\x8c\x05\x1b\xd7\x4a\xc4\x2c\x07\x78\x94\xaf\xe4\xfb\x32\x27\xe9\xd4\x2c\x53\x40\x9a\x0f\xfa\x1d\x8e\xac\x31\xe6\x38\xf2\x8e\xf1\xc4\x05\x5d\x56\xc1\x7a\xba\x2d\xfb\x0b\xc0\xa4\xe0\x03\x34\x38\x73\xa1\x55\x11\xc0\x0e\xcb\xf9\xb4\xb0\x10\xd7\xf2\x07\xb2\x0a\xd8\x46\x18\xf0\xd5\x95\x3c\x3d\xa2\x0a\xe8\x83\x3c\x8f\x2e\x19\x38\x63\xe2\x96\x28\x70\x48\xe9\x53\x93\x00\xf8\x4a\x62\x1c\x22\x46\x65\x86\x70\x4a\x69\x78\x18\x63\xfa\x9f\x6c\x85\x32\xe3\x88\xb1\x49\xca\x95\xb8\x5c\x6b\x6e\xcc\xac\x6e\x7d\xc4\x14\xac\x30\x77\x66\x48\x42\xfb\x50\x43\x61\x74\x11\x78\xff\x1c\x97\xb3\x2c\xf6\x74\x8b\x29\xd7\xb3\x7c\x8e\x98\xe4\x92\x19\x33\xb2\x86\xd8\x1a\xe4\xc5\x3f\xf2\x99\xe1\x59\xe8\x6f\xd0\x7b\xf4\x85\x11\xcf\xb5\x14\xcb\xff\xc6\x30\x5f\xa0\x2f\x7a\x65\xd7\x90\x05\x6f\x15\x0a\xf4\x05\xde\x35\x5c\x23\xfe\x49\x28\x6f\x60\x12\x11\x25\x43\xe8\x0b\xdd\x89\x28\xbb\x07\x84\x18\xc8\xd0\x76\x6a\xbd\x4b\x0e\x8e\x04\x8a\x28\x36\x0b\x53\xbc\xd3\xb0\x36\x98\x90\xa4\xc4\x79\x4f\x2b\xdb\xd7\x74\x10\x9c\x8a\x4a\x2e\x14\x08\x22\x00\x9d\x42\xff\x74\xf3\x6c\x8f\xf1\x4f\x32\x41\xbd\x75\xbd\x11\xbd\x0e\xf6\x40\x83\x3d\xe5\xe8\x92\xf4\x3c\x4a\x48\xac\x68\x80\xd6\xb8\x1e\xa2\x2e\xda\x40\x7a\xe5\x6b\xba\xac\xa1\xd7\x6c\x52\x60\x05\xa5\xa1\x45\x12\x91\x94\xd1\x97\x35\x8d\x0c\xe0\x2d\xcf\xa9\x9e\x45\x9e\x7e\x3c\xfa\x05\x8f\xcb\x6b\xab\x42\x31\xc9\xaa\x1c\xab\x36\xb6\xe0\xaa\xa7\x4e\xeb\x86\x33\x73\x01\x2b\x6f\x09\x5c\x30\x69\x5a\xb1\xa2\x77\x4a\x81\xcf\x02\x74\x0a\xe0\x67\xfd\x76\xa8\x49\x48\x01\x12\x10\x5b\x7c\xd5\xd8\x29\x74\x34\x00\x0b\x60\xd8\xf1\xa5\x2f\x54\x81\x2a\xc4\x38\xcd\xb6\xc2\x4d\xe1\x2e\x7d\x8e\x9d\xa2\x8a\xbe\x0b\x41\xe0\x53\x5c\xea\x2b\xb0\xe0\x9c\x83\x93\x2c\x2d\xc6\xfb\x46\x4b\x18\x35\x0c\xe6\xd1\xa2\x57\xc5\x63\x41\x2b\xe7\x59\x23\x06\xef\x64\x35\xf7\x58\x4f\x4f\xa1\xc8\x19\x63\xcf\xe2\x4b\xae\x22\xad\x3f\x7c\x9f\x3a\x9e\x4c\x0a\x5c\x3a\x9d\xca\x71\xbc\x1c\x63\xad\x5f\xd1\x78\x1c\xa0\x86\xce\x01\x76\xca\xa8\x24\xe3\xb7\x51\x5e\xbe\x82\x97\x44\x56\xcd\x03\x3b\xbf\xe7\xe9\xa7\xa8\x2b\xa7\x4c\x09\xc7\xef\xdd\x2a\x5f\x47\xe5\x6c\x30\x49\xb2\x2c\xef\xf5\x9c\x16\x37\xd0\xce\x56\x1f\x0d\xd1\xce\x76\x1f\x3d\x44\x3b\xdb\x7c\xd0\x1a\xfa\xa2\xf1\x18\x6d\xa0\x9e\xdc\x74\x0c\xac\x57\xa0\x10\x3d\xd5\xf6\x2e\x84\x76\xb6\x51\x68\x24\x54\x74\x56\xa0\x3e\x40\x9b\x3a\xf6\x73\x5c\x2c\x93\x52\x50\x0f\x9b\xc1\xd7\xcb\xa4\x24\x3f\x93\x72\xc6\xe6\x44\x50\xa0\xd1\xb7\x40\xd2\x51\x60\xce\xa0\xa8\x9c\x8f\x90\xd5\x6f\x9e\xf8\xfc\xa4\x6f\xb5\xea\x5b\x03\x2d\x7b\xa0\xad\x11\x39\xbc\x4e\x67\x4f\x2d\x1c\x9c\x4c\xf8\x88\x79\x67\xf9\xae\x90\xe5\x2f\xa2\xf1\xac\x67\x33\x26\xa2\xd3\x16\xe5\xfa\x95\xf3\xa5\xe6\xea\xac\xaf\x17\x62\x08\x81\xae\x6c\xb8\xda\xce\x9e\xd9\x7d\xb1\x8e\x34\x22\x94\x6b\x97\x52\x31\x4e\x26\x1c\xc4\x9e\x23\xe8\x80\xdb\x25\x81\x27\xf8\xb0\x27\x4b\x6f\xc2\x5c\x8a\x1b\xfb\x08\xf3\x67\x78\x68\x88\xb6\x15\xe8\x35\xc2\x49\x81\xad\xe1\x0d\x87\x28\xce\xd2\x6e\x89\xa2\x38\x46\xbc\x54\x99\x99\x55\x0e\x10\x29\xbb\x05\x8a\x92\x1c\x47\xf1\x25\x1a\x67\xcb\xb4\xc4\x71\x05\x96\xbe\xd2\x38\xaf\xd5\x22\x1c\x0e\xd1\x87\xe3\xe7\xc7\x21\x9a\x90\xe9\x32\xc7\x88\x1e\xd8\x52\x5c\xd0\x13\x20\x3d\xa5\x5d\x16\x26\xb3\xfa\x2d\x88\xe4\x8f\x33\xc9\xe6\x64\x60\x1d\x81\x02\x2b\x15\xcb\x5c\xa2\x35\xc7\x93\x08\xd4\x31\x17\xb3\x2c\xc1\xac\x87\x24\x9d\xae\x37\x30\x82\x1a\x1e\x60\x73\x7e\x3e\xe8\x00\x65\xce\xca\x37\x16\xb9\x98\x93\x46\x51\xdf\xb3\xc5\xf5\x5c\xd5\x98\x46\x40\xac\x61\x74\x11\x29\xb2\x2e\x70\xe9\xcc\x29\x23\xab\x37\xd1\x1c\xdb\xfb\x90\xca\xd1\xe5\x4c\xb7\xac\x67\xf3\xa9\xdf\xcf\x54\xc5\x9e\x3a\x25\x5f\xe4\x18\x54\x52\xad\xf8\xab\x19\xb6\xa8\x64\x91\xe3\x73\x92\x2d\x0b\xd9\xa1\xed\x3d\x8a\x12\x92\x22\x92\x96\x4e\x89\x26\xfc\x6b\xfd\xf5\x35\x48\xff\x26\x59\x8e\xe0\x91\x30\x41\xfb\x68\x6b\x0f\x11\xf4\xbd\x18\x80\x78\x2f\x8c\xc8\xc6\x46\x55\x71\xfa\x67\xf5\x79\x63\x1f\x6d\xf4\x04\x0e\x08\x7a\x84\xb6\xce\xa8\x84\x8f\xae\xae\xd0\xe6\x5e\x65\x25\x35\xac\x9c\xd3\xc3\x06\x22\xe8\x61\xd5\xcc\x6d\xd8\xbd\xa0\xc2\x41\x15\xdb\x17\x7f\xd7\x4e\xaa\x99\x72\xdd\xef\xf5\xad\x29\x1c\x0e\xd1\x84\xe4\x45\x89\x70\x82\xe7\x38\x2d\xe9\xf9\x8a\xa1\x29\x40\xc5\x27\xb2\x40\xa4\x5c\x65\xca\x0d\xec\x6f\xfa\xb0\x4f\xf1\x57\x3b\x03\xf0\x74\x3e\x8e\x09\x6d\x24\x4a\xe4\x22\xe7\xf8\x74\xf8\x8f\x8b\x6f\x3f\x5f\x54\xa4\x53\xc1\x20\x4e\x09\xda\x40\x5b\x67\x82\x4f\xa0\x0d\xe4\x74\xc3\x83\xf6\x46\x04\x5b\xcc\xcf\x03\xc9\xb7\x4a\x0f\xed\x33\xaa\xb8\x31\xeb\xf9\x43\x33\x15\x2a\x6c\x99\x98\xba\xe5\xe2\x6f\xa0\x4c\x54\xc5\x90\x36\xeb\x18\x12\x6a\x45\xd3\x8d\x1c\x65\x38\x44\xe3\x28\x19\x2f\x93\xa8\xc4\x42\xf0\xa1\x47\x3e\xde\x17\x44\x4a\x3c\xbf\x05\x3b\xa2\xac\xe8\xf4\x4f\xc4\x94\xfa\x36\xec\xf5\x4a\xfb\xca\x2d\x27\xe4\xf7\x63\x30\x3a\x73\xf9\xea\xbc\x05\x39\xda\x22\xde\x8f\x06\x6d\x08\xd7\x45\xf2\x9b\xc9\xac\x46\x63\xc4\x20\x5b\x6b\x8c\x44\xba\xbc\xd5\x94\x2a\x11\xbf\x2e\xa9\x5a\x0f\xa2\x35\xec\x11\xff\xa0\x7e\x9f\x8e\x48\x2b\xa6\x74\x44\x0c\x1a\x64\x9b\x36\x68\xa9\x55\x12\x55\x20\xa4\x4a\x47\x54\x8d\x10\x5e\x02\x4e\x18\xd0\x9a\x42\x4c\xbd\x86\x48\x1f\xa2\xef\x74\x6c\xe0\x66\x75\x05\x91\x28\xc5\xa8\x58\x87\x67\x44\x5c\x78\x4f\xe1\xd6\x71\xff\x8e\x35\x4a\x6c\xc8\x3d\x18\x99\x58\x5f\x4a\x2d\x62\xe8\x45\x44\x8d\x4a\xc3\x54\xa7\x72\x50\xa3\x6a\xd4\x33\xe8\x18\x65\x1c\x88\x96\xb9\xeb\x91\xb6\x51\x47\xc9\x93\xa8\x4f\x0e\xe6\x5d\xab\x64\x92\xc3\x21\x2a\x96\x73\x76\x43\xe7\xd9\xa5\xb8\x88\x28\xe1\x79\x75\xa7\xe4\x8c\x72\x45\xf9\x05\x5b\x92\x8f\xff\x88\xe6\x4d\x44\x08\x69\xd3\x41\xc1\x70\x88\x72\x3c\xcf\xce\xe1\x1a\x13\x8d\x97\x79\x4e\xe5\x53\x29\x9c\x66\x90\xcc\xbb\x49\x0a\xe8\xb9\xa7\xb7\xc5\x2a\x1a\x3f\x81\xcc\xd6\x9a\x3f\x63\x64\xe8\x91\x53\x7f\x6b\x4a\x7b\x6f\xad\xc3\x8a\x6b\x1d\xef\xa9\x55\xf0\x38\x0f\x95\x95\xd6\x95\x83\x20\x2b\xba\x83\xe9\x97\x24\xe6\xfd\x05\xeb\x2d\x6d\x6b\xcc\x6f\x99\x74\x53\x0b\xe8\x7d\x8f\xd9\xab\xda\x26\x18\xfc\x5a\xb4\xd7\x0f\xbc\xd9\xcf\xb2\x2c\xa9\xca\xa3\x42\x48\x45\xd6\x49\x4d\x9e\x7e\xb9\x59\xd9\x6c\x5d\x26\xe3\xc2\x55\xb9\xef\x70\x54\xd9\xe3\x13\x96\xb9\x46\x09\xc2\xb5\xdf\x00\xd4\x49\x9b\x0d\x61\x38\x1b\xee\x06\x1d\x76\xf7\xdb\x09\xbf\x81\x9f\xb4\x6f\x9d\xf0\x31\xfd\xad\x5f\xc7\x76\xc2\x27\x81\xcf\xd6\x83\xa4\x65\x27\xdc\xda\xa4\x3f\x73\x1c\x25\x9d\x70\x6b\x9b\xfe\x66\xb7\xb2\x9d\x70\x6b\x87\x7e\x2d\x19\x14\x34\xb0\xe4\x60\x8f\xaf\xcf\x82\x27\xbf\xa5\x5d\x54\xc3\x35\xf4\xcd\xac\x89\xf4\x4a\x56\x31\x2a\x32\xcb\xd9\xb6\x45\x7a\xee\x8a\x26\x46\xfe\xa2\x35\x96\x46\x66\x4f\xda\xd4\x75\x0b\xbb\xa3\x0a\x63\xa3\x56\x8d\x6a\x57\xe2\xde\xe9\x12\x6c\x27\x5f\xe2\x16\x26\x4c\xd6\xb0\x9b\x2d\x99\xbe\xbb\xb7\x64\xba\xb7\x64\xfa\x6f\xb1\x64\x52\x0b\xe1\xae\xcc\x99\x9e\x91\xe9\x9b\xe5\x7c\x04\xac\x50\x72\xe7\x11\x99\xa6\x90\x38\xf8\x45\x72\xf2\x65\x49\x12\xd3\xbe\x66\x30\x84\x34\xf6\xaf\x00\x1b\x7b\x41\xc6\x59\x3a\x21\x8e\x31\x90\x38\x99\x69\xbb\x02\x9c\x5d\x60\x5b\x10\x03\x67\xbc\xba\x40\xc0\xef\x11\x3c\xd8\xa0\xe7\x2c\xca\xb7\x94\x95\x2c\x2c\x05\x3a\x37\xa0\x9c\x79\x48\x71\xcc\x20\x49\x81\x52\x3c\x8d\x4a\x72\x8e\x03\xc1\x89\xe0\xe2\xa8\xbc\xc8\xba\x05\x1a\x67\xf3\x85\x90\x56\xa1\x14\x9d\x5b\x59\x72\x92\x64\x51\x49\xd2\x29\x5a\x64\x24\x2d\x03\x76\x1d\x4a\xc9\x3e\xce\x2e\x52\xeb\x4c\x67\xaa\x49\xdc\xe3\xdb\x15\xc3\xf2\x95\xc4\xf7\xb5\x18\x0b\x5d\x4a\x29\xc6\x31\x9c\xa2\x47\x6a\x8e\x63\xbf\x31\x0c\x20\xed\x5a\xda\xf9\x98\xed\x1a\x0c\x18\xea\x17\x5c\x58\xb6\x3b\x60\x73\xd1\x1b\x0f\x5e\x7c\x78\xf9\xf1\xd9\xd1\x4f\x6f\x4e\x5e\x3f\x7b\xf1\xee\xe3\xbb\xe3\x93\x37\xcf\x8f\xde\xfc\xf4\xf1\xf5\xf1\xf3\x17\xda\x19\x4e\x6a\xe2\x60\x26\x07\x8b\x28\x7e\x85\x27\x65\x8f\x7d\x95\xd9\x87\x8b\xac\x38\x94\x58\xe4\x6d\x0e\xca\x8c\x8b\x4b\x5b\x8f\xfb\x01\x7a\xbc\x6b\xde\xf0\xe8\xbb\x25\x0c\xa7\xc7\x1a\x31\x0d\x30\xcc\x89\x17\x87\xdf\x0a\x9c\x3f\x93\x67\x63\xf3\xd0\xbc\x2a\x0e\x5d\xa9\xc3\xc0\xa2\x07\x21\x65\xf6\x12\x7f\x16\xe3\x2e\x96\xa3\xa2\xcc\x7b\xdb\x1a\xfe\x12\xeb\x6a\x9f\x15\x17\x5a\xee\x0d\xf4\x78\xa7\x8f\x86\x3a\x8a\x6c\x74\xbf\x23\xd3\x59\xc9\x8b\x05\x28\x41\x0f\xbf\x32\x3e\xf9\x0e\x7c\xa7\x68\xad\x94\xe9\x6e\x8d\x5d\x71\x3c\x33\xd1\x2a\xb5\x73\xbf\xdb\x0c\x58\x6a\x53\xd6\x58\x7f\xc0\xd6\xfc\x06\x6a\x9e\xa0\x26\x4e\xc7\x24\xf9\xea\x15\xf1\x5e\xe4\xdf\x76\xee\xa4\x71\x67\xfb\x59\x9b\xe4\xd9\xfc\xa4\x9c\x3c\xb9\x9f\x38\xcf\xc4\xf1\x77\x46\x55\x8c\x8c\xbf\x42\x12\x93\x46\xbf\x71\x94\xae\xce\xc8\xec\x27\x47\xd5\x73\xd6\xdd\xbc\xdd\x5f\x17\x6d\xf0\xea\xd1\x53\x84\xba\x5b\x5d\x14\xa2\xee\x66\xf7\xf6\x3c\xaa\x09\x93\xf4\xc4\x4a\x4b\xfd\x83\xc2\x15\x88\x0a\xc6\xf3\x65\x52\x12\x26\x54\x8e\x2e\xd1\xf6\x7f\xe6\x54\x3c\x97\x36\x74\x11\xad\xb9\xc4\x53\x9c\xd7\x6c\x25\xef\x78\xad\x4d\xfb\xf7\xaa\x33\xc2\x6d\x99\x2b\x66\x84\xa3\xc9\xa2\x3e\x8a\x35\xd9\xa2\xdc\x5c\xc9\x1c\x17\x56\xd6\x76\x7f\xb0\xc8\x2e\x7a\x5b\xdb\x4f\xfa\x7d\x13\xa5\x87\x33\x3c\xfe\x84\xc8\xc4\xc0\xa9\x26\x16\x59\x88\x28\xc8\x34\xc5\xf1\x51\xf1\x46\x65\x3b\x8a\x68\x59\xc7\x0c\x7f\xe6\x3d\x36\x91\x21\x88\x16\x0e\x7d\xd0\x76\x69\x4a\x62\x19\x3d\xb2\x5c\x10\x2a\x86\x47\x49\xa1\xac\x96\xed\xd6\x1b\xf1\xe5\xc3\x90\x60\x37\x9b\x01\xda\xea\x07\x68\xeb\xb1\x26\x8f\x6c\xf7\x8d\xdc\x3e\xda\xdf\xdf\xa7\x24\xeb\xa5\xc2\x9c\xb2\x8f\x47\x51\x02\x9d\x42\x4c\x75\xa0\x2e\x3c\x98\xa8\xe9\x12\x11\x53\x24\xd8\x42\xa0\x41\x1e\x8e\x1d\x2c\xc5\x99\x12\x0c\x6b\xda\x95\xc2\x21\x2c\x0b\x32\x45\x4c\x4e\xb7\xe8\x4d\x76\xc1\xc0\x9f\x61\x14\x4b\x81\xd9\x3c\xee\xb3\xde\x68\xba\xcc\x5e\x1f\x5d\x5d\xa1\xce\x66\x87\xeb\x88\x87\x43\x34\x96\x54\x44\x85\x67\x31\x91\xb2\x75\x06\x44\x4a\x36\xd1\x52\xd2\x76\x85\x6c\x71\x7f\x6b\xcd\x33\x9f\x5b\x8f\x0a\xd2\x33\xbf\x6c\x4a\xe7\x24\x5d\xda\xab\xa0\x3b\xb9\xe5\x5f\x17\xea\x16\x95\x6f\xc9\xeb\xb1\x16\x1d\xba\x01\x05\x2d\xeb\x49\xe8\xa4\x96\x86\x7c\xd4\x83\x57\x22\x1f\xde\xbc\x4b\x38\x27\x77\x41\x39\x5f\x07\x65\x9c\xe5\x57\xa1\xcc\xe1\xdd\x8d\x28\x03\x8c\x69\x22\xb1\x89\x22\xde\x9c\x8b\x22\x87\x99\xfb\x2c\xce\xad\xc5\xc8\x61\x06\x31\x39\x27\x31\x8e\x9f\x5d\xd6\xf0\xf0\x9b\x50\x53\x03\x6e\x4e\xee\x1a\x39\xcb\x4a\xec\x9c\xac\x8c\x9e\x93\xdb\xe0\xc7\xbd\x85\x65\x55\x4b\x14\x55\x49\x5c\xea\xc1\x74\x6b\xbc\x88\x9d\xcd\x9c\x8b\x4a\x1c\xf1\xa6\x5d\x14\x39\xf2\x99\x0f\x43\x9e\xe5\x05\xfb\xd5\x2d\x05\xb6\xad\x2e\x7a\xca\xb6\x66\xee\x19\x63\x35\x6c\x56\x9e\x1c\xb5\x77\xb9\x35\x7b\x5f\x82\x27\x0a\x71\x54\x82\xa8\x39\xdb\x38\xa2\x47\x1a\xcd\x31\x7b\xe0\x43\x7f\x59\x22\x18\x87\xa1\x75\xca\x1a\x3c\x98\x77\x0e\xa1\xd0\x46\x80\x74\x65\x39\x2d\xc4\x9f\x58\xa3\x7d\x54\xf5\x52\xf7\x61\x7f\xa8\x1d\x69\x0a\xf2\x2b\xe7\x89\x05\xdc\x52\xf1\xf2\xa7\x5b\x67\xa6\x28\xdc\xdd\xfc\x4c\x45\x66\x77\x72\x07\x45\x42\xc6\x98\x4a\x26\xdb\xe8\x21\x54\xb7\x22\x9d\x37\xcc\x8c\x7e\x0a\xbf\xb3\x09\x5a\x15\xfd\x95\xaa\x00\x67\x93\x91\x47\x44\x8b\x0f\x30\xc4\xf1\x4b\x30\x1b\x73\x8f\x77\xfb\x7c\x0f\x2f\x33\x0e\xdf\x47\x0f\xc5\xa9\xd2\x37\x03\x56\x45\x4c\x3a\x7c\xbc\x1b\xf0\xf6\x57\x9b\x82\x9a\x53\x39\x1b\xbe\xe7\x58\x7e\xa7\xd8\x8f\x8a\x31\x21\x75\xf8\xf7\x1c\xe7\x7f\x43\xcc\x0b\xad\x0e\x68\x07\xda\xe1\x7f\xb5\x09\x50\xee\x69\xaa\x66\xe0\x40\x39\xb0\xa9\x98\x82\x4a\xde\x5e\x81\x72\x59\xa1\x8b\x6d\x9f\x03\x9b\x15\xa4\x29\x03\x77\x9d\xcd\xcf\x1d\xb4\x81\xf8\x19\x07\xd0\xce\x7e\x4b\xb3\x82\xdd\xcd\x00\xe9\x49\x55\x3e\x03\xbe\x08\xd3\x0f\xed\xac\x19\x5a\xdf\x81\x0d\x03\x2b\x36\x74\x52\x1c\x38\x7d\x81\x87\x55\x19\x4e\x29\x86\xcc\xd0\x4d\x72\xfb\x91\x65\x49\x68\x27\x38\x50\x54\x02\x09\xed\x04\x1d\x4a\x8a\x65\xa1\x9d\xe0\x42\x9d\x38\x60\x27\x5e\x38\xbd\x51\x95\xe2\xa9\xcf\x05\x3c\xf1\x43\xea\x83\x55\x29\x1e\x38\x1d\xdb\x5a\x92\x0b\xe9\x9b\x1e\x37\xc7\x2d\x67\x4e\x90\x9e\xe6\xc2\x72\xaa\x0f\xbd\xeb\xee\x5a\x5c\xeb\x9a\x97\x43\x9d\x70\xeb\x49\xd0\x31\x2f\x95\x3a\xe1\x36\x58\x30\xc0\xc2\xe8\x84\x5b\x5b\x41\x47\xbf\x9a\xea\x84\xe6\xe7\xf5\x59\xb0\xb5\xf9\x3b\xbb\x74\x39\x62\xb6\xf1\x35\x3e\x88\x48\x5a\x56\xb9\x20\xe2\xb7\x57\x24\x2d\x99\x77\x16\xfa\x63\x57\xfe\x3a\x53\x89\x3b\xda\x6f\xcb\x79\x0b\x49\x4b\xe6\xba\x85\xa4\xe5\xe3\x5d\x09\xf6\x44\x55\xb4\xfd\xcd\xe3\x8a\xba\x28\x7c\x83\x2b\x23\xfb\x68\xf8\x15\xbd\x71\x01\xb8\x6d\x86\x70\x94\x96\x2b\x5a\x5e\x18\x25\x6a\x0c\x2e\xa0\xb9\x9a\x92\x37\x32\xaf\x20\x69\x29\x44\xc5\xa7\x37\x72\xe9\xc2\x7a\xd5\x6c\x06\xb1\xd5\x2a\x8a\xdd\xbd\x1d\xc4\xbd\x1d\xc4\x9f\xd7\x0e\x02\x29\x43\x08\x26\x2a\xdd\x91\x0d\x44\x0b\xd3\x06\x9b\xd5\x33\xd3\x85\x0c\x0c\xd2\x95\xe7\x8e\x81\x47\x42\xbd\x98\xe1\x54\xbe\x57\x0c\x98\xed\x37\x15\xc0\xa5\x03\x07\x21\x59\x0e\xbd\xb6\x11\x96\xfa\xdb\x7e\x9e\x08\x9c\x54\xc8\x8f\xec\xff\xab\x2b\xd4\xed\x6a\x7c\x36\x13\x2f\x17\xd8\x8f\x3d\xed\xa9\x21\x49\x79\xeb\xad\x3d\x7e\x4c\x71\xa9\x9b\xfc\x82\x01\x79\xb7\x10\x0f\x41\x81\x97\xd0\x4a\x0c\x6b\x77\x25\xdf\x33\x63\x57\x53\x8a\x16\x6a\x26\x55\xab\x5e\x19\xea\x89\x3e\xf6\x0d\x83\x76\x40\x8f\x6e\xd0\x6e\x37\x52\x6b\x8a\x06\x56\xfe\xc6\xb1\x43\xbf\x7e\x6c\x8d\x8c\x71\x8e\x29\x31\x89\xf5\x60\xba\x65\x61\xe4\x1e\x93\xc9\x04\x83\x41\x32\x43\xb9\x75\x2e\xb9\x90\xef\x42\xf4\xe3\x88\x40\x09\x9f\x25\x61\xbb\x9c\x7a\x0f\x21\xe6\xd1\x85\x6e\x87\xbe\x7e\x44\x0b\xc6\x61\x64\x2f\xaa\x51\x79\xe1\x7f\x33\x6b\xd2\x5d\xe5\xad\x9e\x22\x48\x49\xaa\xab\x60\x34\x9b\x8f\x48\xea\x7a\xb8\x29\xb3\x29\xa6\xdc\x9d\xd6\x80\xa7\x03\xb6\xa8\xa2\xc5\x02\xa7\xb0\x96\xa2\x94\xbd\x81\xb0\xb0\xcb\x6b\x6b\xba\x87\xe1\x8c\x69\x46\xc6\x94\x3d\x89\x5e\x35\x17\xe6\x17\xa8\xd9\x84\xc3\xc2\x3e\x54\x8b\x5a\x31\xbc\x26\xbd\x5f\x1d\x5a\xa5\xde\x82\x5d\x99\xec\xa1\x66\xec\x8e\xa3\x24\xe1\xf8\x15\xd7\x38\x6c\x44\xb3\x48\x2d\xdd\x82\xfc\xca\x9d\x0b\xc2\x75\xdd\x2c\x2a\x02\xfa\xbf\x20\x34\x70\xff\xeb\xb9\xb7\xd3\xf1\x2d\x6d\x41\xfd\x3a\xd3\x5a\xd4\xf8\xbd\x33\xf9\x16\x2e\x5f\x15\xeb\xfb\xfb\x20\x5d\x4c\x48\x6a\xbd\x55\x6a\x42\x82\xf2\x5a\xc4\xab\xe2\x37\xcc\xb6\xd2\x80\xe5\x1e\x14\xcf\xaa\x8f\xfe\x4c\xe3\xeb\x6a\x68\x5a\x2c\x33\xa3\xf6\xba\x41\xaf\xc3\xa8\x95\x0b\x80\x3e\x7a\x8a\xba\x5d\x14\xb6\x33\xc8\xd2\x50\xe6\x35\xcb\x5a\x01\x6f\x94\xf7\x33\xe5\x84\x94\x19\x7d\xcf\xbd\x94\xfe\xc2\x8f\x33\xb1\xf7\x88\x5b\xe1\x48\x67\xf8\xd1\x5c\x27\x32\x20\xf1\x5a\x2c\xaa\xc6\xbc\x28\x04\xbf\x4a\x36\xfe\x7c\xfe\x99\xe4\xf2\xda\x43\xec\xca\x0f\x55\xd0\x1d\x9f\xb0\xde\xea\xa8\x33\xb6\xb5\x0a\xdc\x69\x9b\x92\x1f\x79\x22\x21\x12\x97\xf0\x2d\xb0\x88\xe7\x8b\xf2\x52\x57\x09\xb6\xd8\x44\x1b\x57\xa1\x49\x8f\x1a\x7b\x0a\x41\xfa\x58\x01\x37\xc2\xe3\x54\xa5\xaf\x29\x2f\x26\x6a\x07\xc2\xab\x6c\x1a\x83\x71\xb1\xb2\xe1\x11\x0b\x6e\x32\x0e\xf5\x18\xaf\xda\x3f\xd4\x2b\x52\x94\xce\xcb\xbf\x53\x63\x34\x67\x1e\xa7\x50\xb5\xa3\x57\x35\xbb\xdb\x8b\x7c\x17\x24\x6e\xea\x97\x8b\x98\x59\xb6\xf2\x77\x70\x52\x15\x59\x66\xa5\xf6\xd6\x95\x15\x16\xc2\x11\xf3\x3b\x84\x8c\xb7\x7d\xf2\x09\x21\x07\x35\x9f\x15\x19\x7b\x9b\x5c\x8f\x6c\xfb\xaa\x58\x90\xf6\xed\x97\xed\x2c\xc4\x6c\x1e\xed\xeb\x3d\x56\xb0\xfa\x30\x36\xf6\x5d\x45\x3f\x7f\xad\xe5\xbe\xd0\x62\x90\x4a\x04\xea\x65\xfa\xab\x5b\xf9\x6a\x6e\x38\x14\xd3\x8d\xcf\x71\x7e\x59\xce\xc0\x17\x89\x56\x8f\x8e\x1d\xd7\xf1\x94\xb0\x48\x73\xf0\x63\xbc\xd4\xf5\xdf\x50\x48\xdf\x4b\x77\xda\x84\xab\x74\xbe\x0e\x50\xb7\x2b\x94\xef\x35\x4a\x8a\xb7\x6c\x96\x2c\x9d\x9e\x54\xdf\x5d\x9f\x05\x5b\xad\x62\xed\x7d\x45\x9d\x1c\xdc\x46\xd7\x2b\xe5\x72\x0a\x52\xa1\x95\x13\x66\x66\xf4\x7f\xa6\x2a\x83\x5f\xbb\xea\xe7\x99\x96\xbc\xa3\x7f\x58\xba\x39\x9a\xc6\x94\x73\xf4\x97\xd0\xce\xd1\xdf\x4f\xb4\xea\x34\xfd\x9c\x53\x63\x0b\x0d\x9d\x73\xf7\xbe\x8a\x8a\x8e\x16\x5e\x45\x47\xc7\xe0\x6d\x25\x1d\x4d\x5d\x51\x4b\x67\x16\xa9\x51\xd3\xb1\x16\xeb\xca\xde\x44\x51\x47\x71\x5b\xa1\xa8\x6b\xe7\x28\x9f\x77\xab\x85\xa2\xae\x55\x34\xaf\xaf\xf5\xb8\xce\x73\xfb\xb7\x0a\x79\xb0\xe2\xab\x10\x88\x28\x61\x93\x08\x4b\x5f\x91\x48\xec\x42\x35\x64\x22\xda\xad\x2f\x7f\x23\x9d\x2e\x93\xa4\xda\xbc\x99\xf3\xb4\x77\xb7\xaf\xe5\xe4\x28\x5b\xd0\xdd\xdd\x47\x1f\xa9\x7d\xbf\xe3\xe1\xc3\x9a\x8b\x5b\x52\xb4\xf7\x6d\x3b\xc6\x79\x19\x91\xd4\xef\xdf\xd6\x41\x24\xbb\x4d\x6a\x20\x6a\x06\x34\x30\xd3\xeb\xc9\x9a\x17\xb1\x32\x1a\xbd\x41\x94\x38\x9f\xd3\x23\x3f\x99\x40\xcd\x66\xbf\x63\xee\xb5\x16\x4d\xc9\x39\x4e\x85\x49\x8b\x79\xa4\xae\x72\x97\x6b\xd9\xbf\xb0\x63\xb6\xb2\xb8\x05\x2c\xb3\xca\x9d\x76\xfd\xf6\xb7\x3a\x44\xfb\x25\xc2\x9c\xd3\x76\x4a\xaf\x70\x9c\x9d\xe3\x3c\xbf\xc8\x49\x59\x62\x30\xf7\x62\xbd\xea\xa0\x0d\xe8\x7d\x6b\xdc\x5d\x80\x96\xbd\xd0\x1f\xf2\x83\x15\x84\x3a\x8a\x92\x94\xa3\xb0\x74\xfd\x0e\xdb\x6f\xed\x5b\x21\xd3\xd5\x4a\x5a\xcd\x29\xad\x6d\x05\xde\x3c\x2e\x04\xfc\x18\x1c\x0e\x41\x15\x1e\xcd\xe9\xaa\x00\xaf\x87\x5c\x9b\x45\xc7\x4b\x39\x01\x66\x77\x0c\x09\xf9\x84\x51\x84\x0a\x92\x4e\x13\x2c\xfd\x70\x01\xe4\xc0\x30\x89\x06\x0a\x66\x6e\x66\x98\x5b\x0e\xd6\xda\xd5\x15\x3a\xed\x9e\x6e\x9d\x75\xcf\xfa\x52\x18\x6c\x70\x03\xc0\xbb\x67\xe2\x9d\x7e\xe9\xae\x0d\x2b\x44\x77\x66\x03\xc5\x50\x01\xb6\x0a\x5b\x01\x7a\x04\xf6\xd8\x9b\xd0\x97\x2d\xdd\x11\x8d\xea\x90\x23\xc8\x0a\x47\x0d\x81\x70\xed\x50\x75\x5a\x10\x0e\x1d\x1e\x0a\x40\xd5\xc0\x70\x88\xa2\x24\x41\xa3\xa8\x20\x63\xe6\xff\x00\x1e\x0b\xec\x6c\x73\x05\x4e\x92\xd1\x93\xb1\xe8\x4d\x80\x76\xb6\x9b\x8c\x4e\xcc\x85\xcd\x39\x9a\x38\x81\x0b\x5d\x24\xc2\x53\x10\x20\x21\x28\xd4\xe9\x59\x07\xed\xff\x00\xeb\x53\xa5\xed\xb2\xc4\x5a\x65\xda\x81\xa8\x6d\x55\x0e\x30\xc3\x95\x3d\xab\x59\xed\x7a\xab\x95\x34\xab\xdc\x7e\x19\x0e\x61\x1c\xa2\xdb\xb3\xb6\x51\xad\xc8\x83\x07\x48\xff\x3e\xd5\x7e\x6b\x2e\xe0\xce\xc4\xae\x2b\x23\x63\x0c\xa7\x37\x9a\x1b\xbe\x7c\xeb\xa6\x46\xcc\x82\x39\x37\x7c\xc2\xcc\xa9\xd1\x3c\xae\xdd\x72\x66\xac\x7e\xd5\x4c\x8c\xd6\xe6\xd7\x9e\x97\xbb\x9c\x18\xd3\xf5\x89\x62\xa4\xda\x4c\xc0\xd9\xa8\x03\xb6\x08\xdb\x0c\xe9\xec\x90\xd4\xe1\xc6\x0a\x5b\x7c\x2a\xb6\x76\x25\xe0\xf6\xd9\xe9\x0e\x07\x15\x69\x0c\x44\x42\x6c\x9d\x59\x09\xea\xdb\xdd\x1d\x00\xab\x37\xd8\x1e\xf4\xb1\xf0\x21\x36\xef\x09\x5a\x63\x77\x34\x91\x64\x82\x7a\x5a\x96\xc6\x21\x6d\x7e\x7c\xc3\x89\x05\x86\xed\x7b\x0d\xb1\x55\x33\xe5\x7c\x93\x10\xa7\x6a\xdf\x3c\xc3\xbc\xf9\xa6\xba\x23\xe3\xef\x39\x13\xce\x3f\x3b\xc6\xbc\x1b\x15\x9d\x9a\x95\xeb\xd3\xad\xbc\xaf\xb5\x9a\x67\x99\xc1\x86\xc2\xf3\x2b\xe7\xd7\xf0\xa2\x58\xb9\xdb\x73\x6f\x45\x49\x54\x94\xe8\xf4\x8c\x0a\x13\xac\xde\x1b\x4d\xfb\xba\x7f\xde\xe5\x1c\x80\x9c\x85\x1c\x1f\x4b\x70\xa0\x51\x2f\xa1\xe0\x53\xd2\x40\x1b\x22\xa9\x31\x8e\xd5\x8e\x30\x92\x03\xdb\x37\x4d\x68\x74\x89\x62\x3c\x89\x96\x09\x28\x42\x8b\x25\x95\x53\xe5\xc6\xdc\xe1\x6e\x6a\x02\x1e\xe6\xd1\x9e\x45\xe3\x18\x75\x03\x06\xac\x76\xc4\x15\x45\xe1\x96\xa7\xb7\x4a\xa3\x7a\xe1\xab\x5d\xe8\x88\xb5\x25\x52\xd8\x6b\x04\x28\x9e\x93\xf2\x69\x87\x52\x7c\x80\x3a\x74\x11\xd0\xff\xce\x3a\x67\x8a\xda\x39\x84\x96\x06\x85\xd2\x65\x62\x3f\x7b\xd0\x66\xb3\x15\xda\x6c\x07\x73\x56\x7f\x1b\x16\x82\xeb\xa4\xca\x59\x09\x6c\x6f\xe0\xce\xf2\xd8\xac\x17\x70\xc3\x4b\x87\x63\x8c\x97\xfe\x0b\xab\xde\x22\x62\xce\xad\x7a\xff\x3e\x65\xa7\xf1\x7f\x9f\xf5\x9b\x45\x04\xae\xbc\x95\xde\x1e\xaa\xef\x1d\xac\x30\x16\x02\xba\x3d\xeb\x10\x6f\x4f\xdd\xbb\x2c\x0b\x67\x9e\x4b\x0b\x7e\x8f\x6e\x6f\x0c\x5e\x7f\xd4\xe6\xad\x0c\x77\x85\x2a\x9c\xa0\xda\x6c\xa1\xc1\x1b\xac\xb4\xff\xd6\x8d\x89\xf7\x50\xe5\x9f\xdf\x31\xaa\xeb\x57\x16\x27\x13\xdd\x9f\x2c\x67\x65\x4e\x21\xf9\x32\xf9\xf4\xcc\xe7\x44\x7c\xb0\x58\x16\xb3\x9e\xe3\x99\x54\xbc\xd4\x16\x6e\x46\xdd\x9a\xe9\x58\x5c\x9f\xeb\xe7\x3e\x07\xa0\x7a\x4b\x9a\x1f\xcf\xde\x79\x80\x74\xff\xb2\x96\x7b\xd2\x5b\x39\xf5\xe5\x13\xa8\x3b\xf3\xbd\xf5\xfc\x41\xd7\x1d\xa9\x83\x23\xfe\xb7\x9f\x3f\x9f\x47\xd6\x06\x4f\xac\x95\x13\x41\x67\x13\x5c\xa5\xd6\xcc\xc7\xca\xb3\xb1\xe6\xdc\x11\x5a\xba\x23\x63\x49\x6a\x1e\x6d\xdb\xf8\x04\x65\xf7\xa3\x93\x3c\x9b\x7b\xcd\x0d\x18\x94\x8f\xb7\x8c\xec\x07\x3b\x96\x81\x90\x61\x19\xb4\xc2\x83\x29\xc1\xd4\x58\xcb\x2d\x58\x14\x1f\x88\xce\xa2\x0c\x7f\x9a\x0d\xac\xea\xab\xf0\x2a\xd8\x9b\xf4\x1b\x4b\x26\xe8\xf2\x27\x3e\xd0\x3d\x21\xe8\x70\x74\x3d\x44\xdb\x60\xfc\xd0\x17\x1e\x9d\x39\xf2\xaa\x16\x51\x6d\x9d\x7a\xf3\x4e\xc5\xbe\x15\x05\x05\xde\x97\xec\x8e\x5d\x2f\xbd\x81\x76\x98\xd3\x7b\xb6\xdb\x16\x14\xa4\x40\xd1\xa4\xc4\xb9\x5c\x24\x7a\x7f\x6f\xb4\x56\xfd\x65\x7c\xbe\xbb\x15\xe7\xa8\xf0\xd9\x8d\x6a\xb1\xc7\x43\xc7\xbc\xa9\xaa\x5f\xf7\xeb\x51\xe9\x46\xda\x8e\x79\x53\xcb\x68\x5a\x72\x1a\xf4\xb0\xbe\x6f\x14\x76\x63\xbf\x1e\xa6\x15\xa3\x32\x1d\xce\x6a\xd3\xbe\x81\xc8\xdd\x72\xad\x3f\xc4\x1e\xa2\xff\xb5\xa4\x7e\x61\x90\xda\xf2\xef\x0f\x45\xfc\xf7\xb4\xaf\xfd\xfd\x2e\xb4\x8f\xbc\xa4\xaf\x07\x68\xbc\x29\xe9\xdb\x61\xc4\x56\xdc\x54\x1c\x62\xb5\xeb\x6f\xb7\xb3\x98\xbd\x58\xa5\x7e\x3e\x7f\x5e\x7a\x4b\x1c\xfa\xf2\xaf\xbf\xea\x25\xbc\xe0\xb7\x7e\xae\x91\x6a\x53\xf7\x7b\x68\x0b\x6d\x98\xbd\xeb\x33\x9f\x4c\x2c\x92\x98\x67\xea\x99\x07\x62\xeb\xd2\xcd\x78\xb0\x5d\xe3\xcf\xde\xc0\xb5\x65\xf1\x65\x70\xb1\xb5\x15\xc7\xa6\xcf\xb9\x5c\x59\xdb\x7d\x53\xad\xea\xbd\x48\xb4\xba\xde\x78\xc1\x5b\x7d\xb5\x2b\xdf\xc4\x5d\x9f\x05\x5b\xbf\x77\xe8\xfd\x93\xe6\x67\x6f\xcb\x9a\x77\x6f\xdc\x13\x09\xfc\xcf\x6c\x5d\x96\xea\xe9\xdb\x52\x7b\xfb\xb6\xd4\x1f\xac\x2d\x3d\xaf\xdf\x96\xf2\xf9\xdb\x52\x7b\xff\xb6\xd4\x1e\xc0\x2d\xcd\x17\x70\x4e\x8d\x2d\x2c\x6c\x1c\xff\x28\x5f\xf1\x11\xdc\x89\xf7\x15\xdc\xc9\xea\xcf\xe0\x4e\xda\xbe\x83\x3b\x71\x1f\xc2\x9d\xdc\xc1\x4b\xb8\xe5\xad\x9f\xc2\x9d\xb4\x7e\x0b\xf7\x7b\xc7\xf5\x3f\x69\x61\x71\xb6\xac\x33\x39\x13\xae\x55\xd8\x0f\x4e\x9c\x9a\xd5\xd9\x52\x37\x3b\x5b\x1a\x56\x62\x4b\x9f\xe1\xd9\x52\x59\x9e\x2d\x75\xd3\xb3\xa5\x6e\x7b\xb6\xb4\x8c\xcf\x3c\xf5\xb6\x59\x1c\xbf\xa9\xfd\xd9\x89\xdf\x00\xed\xe4\x06\x16\x68\x27\xad\x4d\xd0\x4e\x3c\x36\x68\x76\xe9\x9b\xad\x91\x1a\x33\xb4\xb6\x8b\xa4\xbd\x21\xda\xb7\x6d\x56\x49\x77\x59\x60\x50\xcc\x8e\xcb\x2e\x0b\xc8\x37\xcd\x10\x4e\xcf\x51\x9c\x61\xb0\x56\x80\xd7\x81\x51\x1a\x83\x0f\x5b\xf4\xcf\xd7\xaf\x5e\x96\xe5\xe2\x1d\xfe\x7f\x4b\x5c\x94\x6b\x20\x98\x5d\x2e\x70\x36\xb1\x72\x98\x1f\x1b\xf9\x7e\xa3\x2b\xf0\xc2\x1b\x1e\xd8\xd0\xe8\xcb\xf5\xde\x9a\x11\x2c\xb2\x12\xd2\x4c\x00\x49\xfd\x97\x62\x46\x77\x1f\x32\x4d\xb3\x1c\x87\x09\x49\xf1\xda\x35\xb3\x58\xa5\x78\x68\xe5\xed\xfe\xfe\xe5\xec\xfd\xcb\xd9\x3f\xf1\xcb\x59\xf6\x6a\x96\xdb\xb0\x19\xcf\x66\xd9\x86\x83\x6e\xf6\x7a\x96\xef\x7d\x27\x25\x49\xa0\x4e\xa6\xcf\x84\xb5\xc3\x9e\x27\x39\x60\xa4\xbc\x94\x2c\x51\x15\x19\x27\x51\x51\xa0\x53\x28\x72\xc6\xbb\xc9\x32\x14\x13\x66\x55\xad\x0d\xe1\xde\x08\x56\x29\x57\xae\x52\x0e\x82\x6a\x9c\x59\xb7\xf7\x73\x0e\x90\x3f\x1f\xbc\xf9\x78\xf2\xe6\xe8\xc3\x7b\x7a\xb6\x86\x49\xe8\x5e\x90\xb4\xcb\x48\xb3\xfb\x49\xfb\xfd\x5a\xfb\xfd\x93\xf6\xbb\xf8\x35\x1a\x65\xe2\x63\x42\xd2\x14\x5f\xca\x2f\x3c\x2f\xb3\x8b\x48\x82\x2e\xc8\x58\xff\x4c\xa3\x54\xff\x9c\x93\x71\x6e\x7e\x27\x09\xb1\xc0\x0d\x58\x03\x50\x7c\x68\x05\xa6\x79\x94\xc6\xaa\xfb\x91\xd6\x7d\xf5\xfb\x83\xf6\xfb\xad\xf6\xfb\x85\xf6\xfb\xff\xb4\xdf\xff\xd2\x7e\xbf\xd1\x7e\x3f\xd7\x7e\xff\x43\xfb\x7d\x42\x7f\xaf\x9d\x55\x3b\xa6\xa1\x73\xf9\xf6\xe0\x39\x9d\xc0\x10\xed\x6c\x07\x32\xf1\xfd\xd1\x4f\x6f\x0e\x3e\x9c\xbc\x7b\xf1\xf1\xd5\x8b\x37\x3f\x7d\x78\x19\xa2\x5d\x96\x29\xe7\x2c\x54\x3f\x55\xb1\x0a\xba\x08\xd1\x17\x64\x25\x28\x2f\xe9\x90\xf1\xf1\xf9\xf1\xcf\x6f\xd0\xb5\xaa\xe9\xed\xf1\xab\x57\x14\xfa\xc3\xd1\xeb\x17\xc7\x27\x1f\x42\xb4\xb5\xb9\xb9\x39\xe4\x3d\xe4\xf7\xd9\xcf\x92\x6c\xfc\x29\x44\x5d\xca\x18\x8b\xb2\x6b\xe4\x1d\x8c\x21\x50\x71\xa8\x5e\x2e\xb2\xe7\x15\x74\xb7\x6e\xf2\x6c\x72\x1f\xe8\xe2\x7e\x9b\xfa\xab\x6f\x53\x6b\xd2\xc1\x43\x31\x8b\x76\xee\xca\xbf\xc3\x61\x7e\xb9\x28\xb3\xbf\xbf\xd7\x59\xff\x18\xd2\x1e\xa9\xf8\x16\xb4\x41\x2f\xc0\x90\xe6\x74\xbd\xb1\x9b\x5c\xe7\x0c\x40\x71\x85\xfe\xfc\x94\x27\xa1\x07\x0f\x44\xee\x40\x78\x83\x60\x42\xf0\x0c\x7f\xee\xda\x6f\xe4\x0c\xbf\x5e\x3f\xa0\x6d\x5a\xda\xf6\x6d\xbc\x2d\x9c\x41\x9a\xc5\x91\xb8\xea\x96\xd7\xf7\x96\xf7\x75\x64\xbd\xa5\x63\xa0\x02\x47\xb4\x73\x83\x97\xf8\xf3\x00\x74\x93\xdc\x2f\xaf\xcf\x82\x88\x62\x45\x0c\x5b\xb5\xce\xce\x6b\x4c\xb9\x16\xa2\xed\x6f\x1e\xb3\x92\xda\xd3\x63\xf1\xa2\x8c\xb2\x3c\x89\xe3\x4e\xf8\xcd\x77\x41\xc7\x44\x79\x27\x7c\xb2\x79\x7d\x16\x6c\xb7\xf2\xe8\x74\xcf\xf7\xee\xf9\xde\x9f\x97\xef\x29\xb6\xc7\x5e\xf1\xdf\x01\xdf\xb3\x24\xf3\xd5\x05\x73\x8f\x5c\x2e\x0a\xfa\xc4\xf2\x95\x62\x09\xd9\xbc\x76\x30\xe4\xec\x5e\x05\x1b\x9a\x3c\xd1\x01\xe8\xb7\x14\xd0\x97\x29\x29\x5f\x47\x0b\x29\x2e\x76\x99\xcc\x1c\x32\x0e\xd4\xdd\x94\x72\x2f\x91\x89\xf0\xbd\x65\xc8\xf0\xa1\x96\xb1\xb9\x29\x0b\xfd\x6f\x4d\xde\x28\x1a\x8d\xa2\x29\x96\x2d\xe9\x79\x52\xb0\x0f\xed\x9c\xb9\xa7\x46\x2d\xfb\x75\x7d\x76\x92\x9d\xe3\x24\x1a\x8b\x46\xed\x6c\x71\x7e\x08\x7d\x99\x53\x7f\xd5\x1a\xc4\x4f\x8d\x10\xc5\x2c\x4a\xd3\x2c\x35\xc6\x6c\x42\x88\x33\x4b\x58\x93\xdf\xd0\x06\x9c\x97\x42\x0f\x84\x8e\x46\x71\x16\x0a\xeb\x41\x9a\xea\xe1\xe7\xb1\xd0\x0b\x64\x54\xc6\xcf\x5a\xf6\xb8\x3c\x60\xfe\xe1\x99\x80\x1a\x89\x7a\xa0\x34\xc0\x4f\x3a\xa4\x0f\x50\x9f\x61\x38\xcf\xd5\x56\x6b\x50\x62\x73\xcd\x06\xf9\xb4\x02\xd7\x4a\x94\xad\x4b\xd0\x42\x7e\xa3\x29\xe5\x50\x61\x11\xc5\xdc\x24\x14\x4c\x36\x3f\x2f\xf0\x98\x6e\x53\xd2\xcc\x5e\x37\x9e\xe2\x5e\x50\x7c\x16\x50\xaa\x8a\x11\xa6\x70\x31\x8f\xac\x65\xd9\x52\x8d\x67\x51\x1e\x8d\x4b\x9c\x17\x42\x55\x0f\xf7\xeb\xbc\xb4\xb6\x63\x78\xdb\x20\xd3\x34\xd0\x6c\x9a\xd1\xe6\x9a\xdf\x7d\x07\x99\xce\x4a\x24\x3c\xcb\x5a\x5e\x7a\xf9\x18\x0c\xb9\x92\x81\x04\xd0\xbb\x22\x80\x76\x3c\xbe\x82\x98\xa5\x07\xc0\x40\x70\x59\x78\x75\x2a\x6f\x7a\xb7\xfa\x83\x5f\x32\x92\x42\xd0\x05\xf4\x14\xea\x40\x21\xea\x6c\x76\xfa\x68\x83\x03\x57\x18\xb0\xdd\x78\x2e\x20\xf0\xce\x9f\x7d\x32\x60\x10\x2b\xce\x06\xef\xe1\x06\xf3\x9c\x7c\xd3\x79\xa9\x32\x28\x34\x1d\xca\xc0\x26\x09\xe6\x84\x10\x94\xc3\xf5\x15\x6d\xcd\x0b\xf3\xba\x5c\x33\x2b\x24\xa5\x95\xf8\x91\xa5\xfb\x95\xf6\x38\x3c\xa2\x8d\x2b\xf3\x41\xe6\x49\xe4\x84\x6d\xe2\x52\x78\x9f\xb1\xb8\xcd\xc3\x21\xfa\x91\xa4\x31\x62\x8f\xb4\x78\x47\x65\xdc\x65\x2a\x3b\x74\x3a\xea\x46\x1e\x6c\x58\x02\x08\x05\x35\xc3\x9f\x85\x29\xb2\x3c\x5d\xd1\x34\x76\xbe\xa1\x67\x8b\xea\x53\x11\xad\x66\x5b\x7f\xbf\x02\x46\x32\xdc\x36\x66\x0f\x91\x8d\xfd\x6d\x1d\x5c\xc4\x34\xd6\x6d\x34\x54\x53\x3d\x42\xdb\xe1\x21\x2c\x64\x0b\x13\xd4\x63\x45\xf6\xf7\xd1\x66\xdf\x38\x8f\x8d\x72\x1c\x7d\x52\xa0\x74\x94\x1b\xfb\x88\xbf\x0e\xa7\x33\x78\x38\x8b\xf2\xc3\x2c\xc6\x50\x83\xf7\xb8\x45\x27\x5b\x98\xd5\x14\x65\xde\x8e\x42\xd8\xa4\xad\x44\x22\x07\xb4\xc8\x6f\x47\x23\xd0\xdc\x7f\x0f\x91\xdc\x64\xe6\x8b\xb2\xea\x95\xb9\x39\xd9\x1e\x5f\xf1\xbd\x45\x8e\x27\xe4\x33\x0b\x86\xb5\xf9\xb9\x4f\x67\x01\xb8\x86\xdf\x4d\x3d\x8f\xda\x56\x3d\xfb\x5e\x1b\x64\x38\x6c\x46\x09\x70\xf3\xda\xa0\x00\xbe\x68\x9d\x86\xcf\x7c\xee\x3e\x9d\x77\x43\xa7\x0a\x4a\xf1\x1c\xf3\x6c\xf6\x61\x39\x70\xf3\x6b\xb6\x1c\xc4\x8c\xd0\x96\x14\x75\x4c\xb2\xdc\x36\x85\x2b\xca\xbc\x2a\xb2\xbd\x36\xa3\x14\x6a\xcc\xe7\xe6\xa0\xec\x91\x9b\xad\x74\xb0\x32\xe4\x41\xbe\x0d\xef\x6b\x0a\x84\xf6\x77\x63\x1f\xa5\x62\x5f\xf8\x1e\x6d\xa3\xa7\xf4\x14\x83\x36\x10\xdd\x0f\x52\x1f\x4d\x70\x57\xf0\x33\xfc\xf9\x2e\x49\xc3\x8a\x1d\x60\xd3\x46\x03\x6b\xf8\xcd\x88\xc3\xe1\x19\x1a\x75\xfc\x36\x14\xf0\xbb\x4d\xab\xe5\x79\x74\xb2\x4c\x12\x89\x86\x21\x3e\xc7\x69\xc9\x0c\xfe\x81\xe5\xff\x52\x64\x29\x8a\x46\xc4\xe6\xf1\xc2\xfd\xe1\x87\xec\xc7\x65\x92\xd8\x6f\x21\xc5\xa3\x00\x5a\xfa\x11\x2b\xed\x3e\x6a\x62\x0d\x3b\xed\x2a\xc6\xee\xb6\x61\x08\x52\xb4\x72\x5d\x49\x4a\xbf\x07\x60\x0a\x41\xd2\x18\x7f\x3e\x9e\xf4\xba\xbd\x6e\x1f\x7c\x3c\x3e\xda\xf2\x3c\x6b\x94\xf0\x8e\xbd\x5f\x79\xb9\xc0\xbc\x39\x00\x02\x2a\x32\xfd\x92\xf5\x48\xff\x8b\x08\x05\x3c\xa0\xf0\x7b\xe8\x9a\x8b\x62\xa6\x05\x9f\x6c\x05\x6d\xa0\x6e\x8f\xce\x9c\xac\x7d\x03\x75\xfb\xdd\x56\x6b\x2f\x26\xc5\x22\x89\x2e\xd9\xbc\x80\xaf\xd0\xb4\xa4\xb2\xad\xc4\x86\xfd\xf6\xec\x33\x64\x3f\x67\xc5\xea\x5e\xaa\xd2\xda\xcc\xc9\xf7\x2f\x2f\xa3\x07\x74\x4b\xb3\x28\x06\x4f\x07\x22\x6e\xe2\x65\x8f\x9b\xc7\xf5\xd1\xa3\x1f\x64\xa2\x9c\x56\xb7\x6f\xb5\x0f\x98\xa5\xed\xa5\x33\xb3\x06\x9a\x39\x18\x9b\x6c\xf4\xd4\x7e\x9b\xca\xdf\x75\xd1\x35\xa3\x9c\x86\x0c\x87\x6a\xa0\xd9\x39\xce\x93\x2c\x8a\x71\x2c\x55\xbe\x9e\x35\xa1\x0f\xe0\x83\x22\x92\xaa\xb7\x89\x43\xf4\xe1\xf8\xf9\x71\x88\xe6\xd1\x27\x50\x02\x93\xf4\x7c\x99\xa4\x38\x8f\x46\x09\xbe\xcb\x01\xaa\xd3\x80\xfd\x0a\x77\x0b\x3d\x42\x5a\x76\xbf\x3f\xc8\xf1\x22\x89\xc6\xb8\xd7\x45\x5d\x70\xce\x46\x4f\x0b\x1d\x33\xd8\x63\x96\x9e\xe3\xbc\x2c\x54\xe8\x4c\x90\xfb\x62\x3c\x26\xf3\x28\xb1\x99\x2c\x49\xfd\xcc\xbe\xcc\x9e\xb3\x02\x2e\xe5\xd5\x86\xc1\x34\xdd\x13\x32\x01\x8f\xd7\xd4\x18\xc8\xb1\xcc\xdc\x38\x97\x32\x8c\x4c\x9b\x31\x36\xca\xb6\x94\x27\xde\xd5\xb8\xb4\xba\xea\x83\xac\xa6\x42\x1f\xea\x8e\xcf\x13\x62\x9b\x2b\x4a\xcd\x1d\xc5\x38\xec\x33\x80\x04\x17\xc5\x87\x59\x94\xf6\x36\xc1\x19\xec\x23\x66\x3d\xce\xad\xf0\x39\x61\x6d\xf5\x21\x0c\xab\x96\x63\x60\xf1\x60\x09\xee\x96\x39\x2a\xa3\xf4\x92\x3b\xd0\xe1\x6e\x45\xd3\x6a\xb4\x0e\x38\x5e\x0f\xd2\x98\x29\xfa\x19\x0d\x91\xc9\x65\xc1\x1d\xa2\x17\x68\x84\x27\x59\x8e\x07\x0e\x5d\xbd\xe4\x47\x87\x7a\xdc\x5f\xf1\x3d\xa8\x81\xb4\x5e\xc2\x3e\x6f\x20\x5f\xae\xdf\x87\xdc\xe4\x6b\x1e\x7d\x66\xe1\x27\x3f\x93\xf2\x32\x44\x4f\x40\x59\x2d\x76\x1d\x52\x70\xd7\xc4\x50\xb4\x6f\x6f\x32\xda\x24\xf7\x36\x28\xc4\x9e\x51\x54\x9f\xce\xfa\xc2\x56\x59\x6e\x10\x77\x4e\x69\x87\x9e\x42\x18\xd2\xba\xde\x2a\x20\x4e\xd2\xdf\xdf\x1f\xbf\x19\x48\x2c\xb3\xf6\x94\x23\x4a\x70\x01\x5b\xa0\xc8\x8e\xca\x19\xa0\x45\x54\x14\x94\x77\x95\xb3\x3c\x5b\x4e\x67\xe6\x0a\x90\x03\xe1\xb4\x06\xb5\xba\xd7\x90\x8a\xab\x3d\x82\xd3\x92\x47\xe6\xad\x1c\xb1\x00\xe0\x6f\x34\xcc\xea\x1a\x6a\x3b\x17\x76\xa0\x5a\x05\xa8\xb7\x4e\x8a\x1f\x49\x4a\x4a\x6c\x21\xdd\xea\x06\x48\x88\x5a\x27\x4c\x29\xcb\xed\xa8\xb6\x2e\xde\xf1\x4d\x85\xad\x03\x7a\x5e\x4a\x81\xfb\xa3\x9f\x89\xcd\x50\xa6\xb8\x84\xc8\xc3\xc7\x93\x93\x94\x78\xb5\x5d\x50\xb6\x9c\x61\xfe\x43\x2e\x38\x54\x66\x81\xd4\x4e\x49\xc7\xe6\xde\xe8\x8b\xb2\x1f\xb2\x9a\x1e\xeb\x4c\x1f\x8a\x80\xfb\xad\x02\xe1\x3c\xcf\x72\xe1\x5a\x86\xf5\xb8\x40\x69\x56\xa2\x71\x96\xe7\x78\x5c\x86\x17\x72\xdd\x98\xbd\x36\x16\x10\x2d\x28\x48\x60\xc9\x32\xe1\xbf\xa7\xf0\xdf\xa0\xcc\x5e\x65\x17\x38\x3f\x8c\x0a\xdc\x03\xe6\x72\x11\xa5\x5d\xc5\xc5\x28\xcc\x3f\xf8\x6d\x32\xbf\xa2\x39\xa5\xff\x9f\xa9\x83\xb8\x06\xa2\x7b\xef\xd6\xc9\x8e\xf9\x13\x4b\xf1\x05\x7a\x41\xc7\xd4\xeb\xc2\x95\x2e\x74\x03\x2c\x4e\xff\xdd\x2d\x11\xfe\x4c\x8a\xb2\x08\xd0\x22\xc1\x51\x01\x42\x31\x8c\x3b\x4b\x25\xa2\x26\x59\x92\x64\x17\x24\x9d\x42\xc9\x82\xf2\x40\x6b\x11\xf1\x1e\x06\xe0\x25\x21\x50\x8f\x37\x6a\x62\xbd\xca\xde\x83\x0f\x2b\xd3\x37\x70\xf4\x09\xc3\x12\x64\xec\x3e\x9b\xa0\x0b\x92\x82\x55\xac\x64\xb1\x8c\x00\x28\xa3\x05\x8f\x13\xe8\x22\x4a\x19\x5a\x39\x55\xbd\xcd\x8a\x82\x8c\x12\x36\x7d\xe0\x06\x83\x1b\xe6\xbd\x3f\xa2\xb2\x65\x5e\xb2\x9f\x54\x9c\x16\xb8\x7a\x31\x99\x90\xe9\x25\xff\x38\x16\x64\xf4\x08\x7d\xa2\x8d\xb3\x3f\x71\x2d\x05\x1f\xfc\xfe\x8a\x01\xcd\x15\x10\xbf\x44\x62\x1f\xe2\xc2\x89\x41\x4d\x15\x14\xbf\xe7\x61\x1f\xfc\x5a\x48\xe5\x30\xf0\x47\x8f\xe4\x72\x14\xf7\x35\x0c\xfc\xd7\x68\x94\x19\x39\x1e\x78\x76\xdf\xc2\xba\x0d\x97\x34\x7a\x0e\x83\x97\xed\x6b\x05\xd9\xa7\x18\xb9\x00\x40\x56\xbe\xf6\x01\x97\x26\x02\x09\x51\x2a\x06\x2a\x7e\x95\xec\x97\x2d\x3a\xb0\xf5\x6f\x9d\x70\xaf\xa4\x3c\xce\x26\x7e\x1c\xa5\xf4\x04\x10\x49\x16\xcc\xd3\xb9\x4e\x2c\xcb\x51\x84\x5e\xbe\xf8\x27\x1c\xb6\x85\x54\x76\x63\xc6\xc1\x7b\x6c\xed\x90\x62\xf3\x44\x3f\xcf\xb0\xf0\x8a\x17\x69\x57\xb3\x3c\x66\x89\xe6\xda\x9f\xae\x9b\xa8\x40\x17\x98\x2e\x04\xe5\x0e\x45\x0c\x62\x4d\x93\x79\x7e\x26\xa9\x7e\xf0\x16\xa3\xd4\x19\x07\x13\x68\x68\xcd\x82\x19\xe8\xa2\x0f\x2b\xa1\xc7\x78\x35\xf9\x11\xe3\x41\x96\xd4\xa3\x6f\xb6\xbc\x02\xf4\xd4\x68\x24\xd4\xbf\x34\xf9\xa9\x71\x99\x46\x0c\xc3\x9e\x95\x7a\x41\xd2\xfb\xa5\xf9\xd7\x5b\xca\x65\x56\xb7\x90\x35\xf9\xff\x37\x5b\xd2\x74\x5d\xfd\x6e\x8b\x1a\xd0\x71\x37\x4b\xba\x24\x73\x5c\xfc\x1e\xcb\x39\xe5\xaa\x42\xba\x88\xa5\xe2\xa9\x60\x87\x76\xd8\x72\x91\xb4\x41\x71\x88\x41\xf6\xa4\x15\x49\x28\x22\x10\x37\x81\x74\xe6\x15\x25\x98\xb5\x49\xdf\x53\xaa\x02\x05\x20\xf1\xaf\x1b\xcd\x58\xb3\xd0\x70\x9a\xf9\x86\x8a\x74\xb0\xc8\x45\x79\xfe\xe3\xea\x0a\x6d\xee\x79\x8f\x2a\xbc\x5e\xe7\xa8\xc1\xd2\x8d\xf3\x0d\xc7\xb9\xe8\xc9\x83\x07\x88\xff\xf6\x89\xf0\xb4\x49\x3b\x57\x3f\x2f\x34\x06\xf6\xe7\x85\xa5\x86\x63\xf3\x73\x37\xe8\x76\xf5\xeb\x13\xcb\x7f\x99\xaf\xb4\x4e\x28\x95\x32\x5a\x2a\x22\xba\x7a\x48\x45\xd2\x09\x03\x13\xb1\x35\xe4\xc1\x8a\x9b\x3b\x02\x6c\x79\x91\x75\x0b\x34\x96\x91\x56\x1c\xd2\x32\x03\xb1\xb4\xa1\xaf\x0a\xaa\xd1\x8e\xbc\x66\x9d\xa6\xfa\xca\x20\x19\x0a\x3e\xd2\x28\xcb\xb7\x60\xe1\x21\x76\x4f\xf3\x25\x27\x0b\xe8\x0a\x86\xc6\xa9\xeb\x4c\x6e\xf9\xd7\x81\x59\x1e\x2c\x92\x65\xa1\xba\xc0\xbf\xbd\x4e\x07\x25\x90\xa9\x17\x9a\xe1\xf1\xa7\x42\x1c\x82\x18\x8f\x14\x97\x96\x05\x7f\xc2\x96\x5c\x82\x77\x5d\x6f\x8c\x60\x46\xf2\x63\x6f\x7c\x60\x33\xd2\xaf\xd6\x00\x5d\xff\x91\x82\xd7\xdd\xcd\x41\xc8\x23\x3e\x73\x56\xdd\xc6\xc4\xf1\x4a\x2d\x7d\xd8\xf0\x3f\x9b\x9f\x4f\x37\x1f\x7d\x17\x3d\x9a\x9c\x7d\xd9\xdd\xbc\xfe\x9f\x21\x19\x94\xb8\x28\x25\xf8\x0a\x63\xaf\x19\xf2\xd7\x19\x6c\x8b\x61\xc2\x69\x7e\xf8\x9f\xde\xe6\xe7\xfe\xd3\xda\x71\xea\xf4\x37\x1c\xaa\x08\x56\x2c\x46\x15\xf4\x8e\xf9\xf5\xe5\xc6\x82\x73\x78\x5d\x4b\x77\x63\x6d\xd4\x26\xfd\x72\xf7\x7c\xc8\x74\x20\xe1\xed\x8c\xd9\x17\xca\xe6\x34\xb0\x83\x47\x3f\x7a\xc1\xac\x2e\x43\x40\xad\x6e\x01\x2e\x88\x8b\x39\xfd\x77\x1c\x2d\x0a\x90\x1c\x92\x04\x89\xef\x40\xf7\x9b\x68\xf7\x98\xb9\x83\xd7\x3a\x6c\x34\x70\x2c\xb7\x77\x86\x1d\x1c\x8d\x67\x68\x1c\x15\x4e\x35\xa4\x60\x84\xb2\x9c\xf3\x19\xd2\xa8\x89\xad\xb2\xf6\x34\xc5\xda\x2a\x96\xf3\x39\x8e\x2b\xc9\xcb\x6a\xee\x8e\xc9\xcc\xaa\xbd\x8e\xdc\x86\x43\x36\x1e\x0b\x37\x91\x2c\xc9\x7f\x39\x3b\x90\xd2\x6f\x70\x88\x97\x51\x01\x2e\x62\x66\xd1\x8e\x68\xc4\xd4\x8c\x08\x69\xc7\xe7\x86\x65\x77\x13\x6e\x1c\x91\x45\x21\xe0\x52\xbb\x9c\xa1\x04\xc3\x23\x67\x2d\x2e\xde\x62\x81\x73\xda\x5b\x31\x0d\x29\xc4\x14\x9c\x12\x16\x76\x2e\x2a\xf0\x3c\x5a\xd0\xe9\xd8\x32\xf4\x76\x3d\x69\x8f\xa0\x75\x1a\x9c\xa5\x6d\x3d\xee\xa3\x1f\xd0\xb7\x74\x3b\xe7\x59\xa7\xe4\xcc\xd2\xec\xac\xef\xef\x6b\x99\x40\xf4\xf5\x15\x7e\xbf\xef\xa9\xf1\x84\x76\xdd\x5f\x63\x85\x07\x6f\x6d\x59\x2a\x96\x6f\x30\x7e\x15\x15\x41\x52\xe9\x6b\x7e\xa4\x13\xd4\x58\x41\x8a\x65\x76\x97\xa4\x28\x74\xd1\x72\x5f\x5e\x81\x12\x35\x0d\xb0\xa4\x3e\xfb\x2d\x34\x6f\xa7\xdb\xe5\xa4\xe4\x92\xa9\x31\x63\x37\x22\x5a\x0d\x68\xec\xf4\x9e\x56\x54\x43\xc6\xa2\x17\xda\x25\xba\x4d\xd2\xc0\xf4\x66\xca\x90\x8f\x12\xe9\x77\x10\xae\x9f\xb9\xb5\x17\xf7\x6a\x92\xc2\x0d\x02\x6e\x24\x37\x93\x84\xec\x01\x6c\xec\x57\xd2\xa2\x46\x5f\x36\x27\x6d\xac\xa9\x96\x36\xad\x92\xa6\xe8\xa9\x44\x4d\x83\x8f\x96\x19\x15\x29\x49\x8c\xb6\x37\x99\x2b\x9f\x47\xfc\xbe\x8f\xb5\xc9\x1e\x16\x6c\x7e\x46\xcc\x56\xc3\xb5\xd5\x6a\x24\x65\xff\xe5\x3d\x77\x0a\xa0\xb3\x6d\x69\x8f\xd5\x8e\x80\x2d\x79\xc6\xbb\xf3\x09\x5f\xb7\x02\x1f\x9a\x10\xcf\xb6\xdb\xba\x9d\xd6\x53\x11\xbf\xcb\xf2\xd5\x67\x42\x88\x88\x88\x70\x43\x24\x6b\x54\xcf\xa0\x02\xb4\xbb\xe9\x57\xfa\x0b\xff\xc0\xe2\x38\x5d\x28\x31\xb7\x39\xf6\xa5\xf7\x28\xe9\xbb\xc9\x32\xe2\x61\xb2\x9d\xcc\x77\x08\x44\x3d\xd6\x0d\x4b\x30\x14\x7d\x4b\x8b\x32\x4a\xc7\x94\x8d\xa8\xc2\x57\x57\x12\x69\xbc\x30\x3c\x3b\x83\x5f\x86\x3f\x0b\x6f\x2a\x33\x75\x00\x66\x24\xab\xec\xb6\x45\x94\x38\x10\x37\x61\xe9\xbd\x63\x2b\xd4\x12\x45\x9e\xc8\x45\x5e\xfc\x70\xde\xca\x7b\x06\xa3\x61\x7d\xeb\xde\x1d\x7a\x58\x5f\x5a\xe3\x46\xf4\xb8\x19\x3b\x3f\x2a\x9b\x90\x55\xf1\x23\x8a\xde\x08\x43\xa2\x44\xb7\xe5\x88\x68\x9f\xca\xe6\xe1\xb0\xae\xdf\x60\x30\xc7\xbc\x6f\x2d\x17\x03\x95\x2f\xd2\x65\x92\x40\xf0\x82\x9e\xbb\x42\xc0\x0e\x1b\x74\x18\x9e\xb1\xf3\xeb\xd7\x96\x23\x1f\xb1\xce\xb6\x60\x07\x0c\xf0\x26\xcc\x80\x25\xdd\x68\x22\x79\xf7\xda\x8e\x06\x3c\xf3\x35\x8f\xc5\x09\x74\xd1\x72\x24\x6e\xb0\x8a\x96\x2c\x0d\x0a\xd6\x8e\xc1\x3e\x8f\xb0\x7d\x14\x4c\xde\x32\x41\x75\xe6\x20\xfe\x5e\x98\xeb\x4a\x19\x14\xd4\x8e\x81\x16\xb3\x1f\x01\x48\x8f\x46\xf9\xa5\xbb\x4f\xad\xaf\xc3\x55\x23\x7b\x3f\xab\x6c\x53\xaf\xd1\x18\xa2\x31\xf5\xb0\x67\x6f\xd3\x4f\xa4\xab\x8c\x3a\xe2\xe3\x7e\x41\x27\x10\x2f\xe7\x68\x94\x64\xe3\x4f\x68\x86\xa3\x18\xe7\xf4\x23\x9b\xdb\x46\x18\xa4\x78\x46\x93\x7d\x42\xc3\x0c\x7f\x96\xee\xca\xa1\x2c\x9a\x90\xa4\xb4\xb5\x99\x1e\x82\x05\x58\xc3\x2b\x30\x4d\xa9\x3d\xea\x7f\xb3\xb5\xad\xce\xfa\x0c\xbc\x01\x2f\xd5\x27\x75\x56\x97\x5e\x95\xef\xa0\xee\x42\xf9\x02\x01\xab\x83\xf6\x9a\xdb\x8f\x1b\xcc\x4c\x92\x51\x31\x6f\x41\xc6\xee\x3c\x7c\xa0\xc9\x4d\xf3\x50\x4a\xa0\x9a\x09\x80\x9a\x8c\x09\x80\x62\xb5\x13\xf0\x78\x57\xe1\x9f\x41\xdf\x18\xff\x50\x95\x5e\x93\x0f\xfd\x0e\xd0\x8d\xb0\x5f\xe1\x31\x84\x8b\x7c\xa1\xf8\x11\x88\x54\x78\xc3\x13\xca\x5f\x2c\x1d\x04\xc3\x90\xfd\x27\x52\xb8\x41\x48\xa8\x7e\xb2\x1c\xcd\x58\x24\xd4\x3f\x44\xb9\x93\x72\xf2\x24\xe4\xff\x8b\x34\x30\x3f\x09\xc5\x0f\x55\x0f\x83\x15\xbf\x54\x3a\x87\x97\x3f\x79\x3d\xae\x0d\x6d\xe8\x4b\x64\xd0\xae\x69\x66\xe8\x49\x33\x60\x85\x15\x64\x68\x27\x88\x71\xfc\x4c\xd2\x90\xfd\xa7\xfa\x0a\x69\xfc\x87\x80\x93\xd2\x62\xa8\x7f\x88\x5c\x53\x67\x1d\x3a\x29\x12\x6b\x4c\x50\x0f\xd5\x4f\x96\xa3\x49\xc7\xa1\xfe\x21\x72\x8d\x93\x48\x68\x27\x08\x28\x2d\xdf\xca\xb1\xce\xee\xa1\x9b\x24\x7a\xe8\x40\x3a\x49\xa2\x4e\x21\x8c\x85\xda\x6f\xbd\xbf\xe9\x34\x94\xbf\x44\x3a\xdb\x53\x43\xf9\x4b\x8e\x9e\xad\xf7\x50\xfd\x94\x63\xa2\xbb\x64\x28\x7e\x88\x54\xba\x61\x85\xfc\x7f\x59\x07\xe5\x77\xa1\xf8\x21\x52\x81\x6d\x84\xe2\x47\x00\x0b\x8c\xf9\x8d\xe3\xcf\xb1\x3b\xe1\xd6\x77\x41\xad\x63\x9a\xa0\xb3\x2c\x27\x4f\x3a\xe1\x93\x6f\xae\xcf\x82\xed\xad\x36\xae\x1a\xcc\x25\xbc\xcf\x16\x70\x87\x7b\x28\xe8\x84\xa8\xb3\x39\xd8\xde\x1c\x6c\x75\xd6\xae\x85\x87\xb6\xed\x56\x01\x84\xef\x5d\x40\xdc\xbb\x80\xf8\x2b\xb8\x80\xe0\xb5\xac\xb9\x2e\xda\xfe\x8e\x27\x93\x1c\x5f\xa2\x9f\x49\x32\xfe\x84\xd1\xf7\xbf\xe0\xc9\xc4\xf6\x03\xd1\xd2\x91\x1b\x80\x91\x28\x45\xc7\x54\xe2\x8e\x00\x8a\x44\xa9\x0b\xf6\x63\x34\xa2\x60\xff\xc8\xa6\x38\x29\x4a\x9c\x24\x38\x47\xdf\x4f\x20\xd1\x05\xfe\x29\x3a\x47\x3f\x67\x59\x8c\xbe\x9f\x56\xfa\xa7\xd8\x55\x7e\x79\xb8\x8b\xc6\xd7\x51\x1a\x4d\x4d\xa7\x11\x83\x21\xc5\xc2\x30\x67\x00\x73\x06\x20\x9c\x43\x1c\x8d\xe0\x70\x64\x03\x93\x51\x94\x0a\x90\x17\x60\x95\x6f\x43\x30\xc9\xab\x18\xe2\x72\x26\x00\x7f\xf6\x56\x25\x00\x2f\x54\x8d\xcf\x9f\xd5\xc0\xc5\x23\xe9\x2f\x76\x56\xd7\x70\x31\x93\x0d\xbf\x01\xcf\xe2\x55\x80\x29\x2e\x05\xe0\x5b\x9c\x17\xf0\x84\xaa\x1a\x7a\xc1\x41\x64\x27\x2e\xa2\x7c\x5e\xd7\x0d\x9a\x2f\x81\x71\x59\x42\xd4\x25\x17\xbe\xe0\x59\x02\x54\xb0\x1f\x03\x52\xf0\x15\x7a\xf4\x52\xee\x3b\x48\x62\x55\xa8\x85\x85\xaf\xf6\x02\xc2\x80\x84\xbf\x25\xfe\x60\x03\xa7\xb1\xa7\x6f\x2c\x43\x80\x3d\x83\xa3\x95\x0b\x35\xa2\xe9\x12\x93\x79\xb6\xc0\x79\x79\xe9\x81\x5b\xf0\x2c\x01\xfa\xb2\x2c\x17\x6f\xf3\xec\x9c\xc4\x5e\xba\xa4\x2b\x7a\xc1\xb3\x25\x55\x2e\xc6\x35\x25\xc8\x62\x6c\x17\x68\xe7\x91\x70\x6d\x4d\x0a\xf5\x3f\xe3\xd1\x0e\xea\x89\x6a\x4c\xaf\xba\xb9\xbd\x94\x52\x7c\x61\xad\x2f\x55\x52\x73\xb0\xcb\x43\xa5\x6a\x3d\x17\x50\x1a\x10\x66\x6f\x5c\xf0\x05\x5d\x57\xe0\x68\x5f\xaf\xe2\x02\x96\x10\xcd\xfd\x39\x4a\x9d\xdc\x78\xc4\x33\x9f\x3f\x73\xf2\x8a\x99\xa8\xf7\xfd\xcc\xad\x37\x85\x15\x42\x73\xdf\xe0\xd2\xc9\x5d\xa8\x65\x41\x41\xc4\x2a\x71\xe0\x46\xbf\xfe\x2a\xda\xa0\x54\xef\xf6\x41\x91\x3f\x00\xf1\xcf\x9e\x0e\xa3\xe8\x5e\x9d\x58\xa2\x05\x09\xe5\x9e\xca\xff\x67\x27\x17\xbd\x93\x1c\x97\x85\x51\x54\x27\xae\xd0\xf8\x0a\x24\x8c\x46\x4d\xa1\xfe\xe1\x34\xf1\x51\xae\x10\xf6\xc3\x19\x20\x07\xe8\xa9\xf6\x39\xb1\x13\x5c\x84\xda\xef\x1e\x33\x0a\xba\xee\xef\x51\xc1\x6b\x38\x04\x07\xbb\x05\x46\x6a\x0c\x19\xdb\xd0\xc1\x27\xd0\x1a\x25\x46\xcf\xf8\x9a\xc6\x56\x39\x2e\x2a\x7b\x8a\x3a\x45\xb4\x4c\xac\xd3\xa5\x1e\x11\xb8\x79\x05\xe8\x85\x57\x26\x7c\x4f\x5f\x72\xcc\x42\x41\xa8\x5e\x7c\xc2\x78\x71\x54\xbc\xbf\x4c\xc7\x24\x9d\xd6\x76\x05\xca\x5a\xf0\xed\x28\xd0\xd3\x11\x9d\x6b\x3c\x53\x76\x30\x16\x94\x78\x1c\xc3\xdc\x49\xc1\x97\x07\x46\x3c\xa4\x15\x50\xf0\xed\x81\xe3\xaf\xb1\x05\x18\xfd\x74\xa0\xf4\x87\xb9\x0c\x50\xa6\x78\x61\x8d\x3a\x45\x82\xa7\x6d\xf5\xca\x4a\x34\xcf\x53\xbc\xb5\xda\xd0\x5a\x9a\xa7\x6e\x1d\x97\xa2\xf6\x3a\x9c\x32\x53\x49\x01\xf9\x33\x49\xbd\x7d\xd0\xa1\xf8\xb7\x03\xa7\x9b\xc8\x30\x48\x99\xe2\x81\x75\x6f\x97\x45\x99\x43\xfb\xd2\xd1\xe9\x73\x55\x59\x27\xc7\xd3\xee\xd1\xb3\x83\x37\x5a\x63\xf4\xd3\x81\x52\x4e\x6b\x18\x18\xff\xf6\xc1\x09\x7f\x2a\x12\x10\x12\xe8\x1e\x67\x2f\x7c\x26\x18\xe8\xef\xbb\xd9\xdd\x92\xe9\x5d\xd1\xbc\xf5\x83\xbb\x6d\x1b\xb2\xea\x16\x36\x26\x39\x68\x9c\xc7\xd1\x02\x5e\x65\x68\xf7\xa0\x9e\x19\x3d\x3a\x3c\x78\x6b\xac\x7d\x5a\x4e\xb7\xb4\x23\x23\xb9\x07\x52\xe1\x94\xe5\x1b\x4f\x40\x29\xc4\x80\x37\x23\x56\x36\xb8\x99\x91\xfc\xb7\x2e\x9e\xe8\xa9\xe4\xdd\xb0\xd7\x71\x61\xa5\x67\x5e\x5d\xa7\xa0\xca\xe9\x8a\x3d\x29\xcd\x62\xdc\x0d\x0c\x88\x29\x98\x97\x84\xa8\x4b\x45\x92\x8f\xe3\x84\xe0\xb4\xfc\x07\x03\xef\xaa\xab\xed\x7e\x70\x93\xd6\x70\x79\x91\xe5\x9f\xaa\x1a\x4c\x71\xf9\x91\x83\x5a\x20\x66\x38\x80\xd0\x5e\xe5\xb7\xec\x16\xe6\x7a\xf1\xaa\x7e\xe1\x72\xf6\x11\xe6\x7a\x9c\x25\xff\xf8\x1d\xfa\x77\x31\x23\xc5\x02\xe7\x55\xdd\x2b\x66\xb3\x5b\xa3\x0d\x7e\x9e\x79\xf7\x12\x52\x1c\x66\x69\xca\x3c\x39\x69\xcb\xad\x6f\xd0\x5e\xcf\xbb\x5d\x3e\x78\xe0\xdd\x46\xf5\x2a\x7b\x7d\xff\x0e\xc6\x7c\x17\x08\x89\xbd\x92\xe6\xc1\xc8\x14\xe2\x22\x70\x89\xc6\xab\xfe\xa5\x75\x0b\x6f\xa2\xfa\xf9\x81\x69\xae\x8c\x63\x47\x27\xdc\xde\xa4\x49\xfa\x81\xa5\x13\x6e\x6f\xd1\x34\x75\x58\xe8\x84\xdb\xbb\x32\x85\x89\x4e\x9d\x70\xfb\x89\x4c\xd2\x45\xff\x4e\xb8\xb3\x2d\x33\xe8\x0a\xef\x84\x3b\x3b\x2a\x41\x89\xfc\x9d\x70\x47\x55\xaa\x0e\x8d\x9d\x70\xe7\x5b\x27\x19\x97\xb3\x4e\xb8\xf3\xc4\x49\xbf\xa0\xd5\x3f\x71\xe1\x53\x5c\x76\xc2\x9d\xef\x9c\x74\x21\x20\x77\xc2\xdd\x4d\x27\xb3\x98\xcd\x3a\xe1\xee\x96\x9b\x4e\x65\xe4\x4e\xb8\xab\x86\x25\x4e\x46\x9d\x70\xf7\x1b\x99\x68\x9e\xcb\x3b\xe1\xee\x63\x99\x25\xa4\x99\x4e\xb8\xfb\x6d\xbd\xea\xf0\xfa\x2c\xd8\xde\xb9\x57\xec\xdd\x2b\xf6\xfe\x5b\x14\x7b\x51\x92\x80\x3b\x8a\xdb\xf9\x77\xd5\xf4\x67\x8e\x02\xc5\xa7\x41\x11\xc1\x61\x5e\x9c\xb3\x17\x03\x9a\x66\x02\x7a\x23\xe0\x54\xa8\x98\xa6\x98\x48\xae\x76\xc7\xab\xde\xf9\x11\xee\x8e\xad\xca\x20\x4d\x40\x5c\xb0\x88\x47\x26\x88\xe2\x77\x90\x29\xbd\xbe\x1e\x24\x89\x31\x14\x53\x60\x32\x8f\x5c\x01\x18\x02\x04\xc8\xb2\x5c\xab\x50\x95\x98\x09\xfa\xd1\xf9\x17\x76\xf7\x4f\xff\xd3\x93\x1d\x73\x4c\xb6\x39\x39\x3d\xac\x0f\xee\x6d\x49\xb3\xc2\x4f\xf8\xbe\xfc\x75\x75\x05\x51\x6f\x90\xed\x25\x82\x26\x42\xea\x69\x97\x4a\xa7\x10\x6f\xa0\x1b\xa0\x6e\x99\xb1\x9f\x67\x03\x86\x66\x2d\xca\xe1\xc4\x73\xd9\xca\x9b\x39\x9d\x9c\x81\xfd\x8c\xb4\x3f\xe5\x17\xb0\x7d\x4f\xa8\x6c\xab\x1a\xda\x1f\x5a\x7c\x5f\x23\x1e\xe6\x70\x07\x3a\xc2\xce\x51\x2a\xf4\x9d\x6a\x50\x98\x44\xa8\x87\x61\xe0\xb9\x42\xe1\xd5\xc0\xb3\xf9\x7a\x4c\xf7\x65\x51\x87\x71\x4f\xac\xe2\x38\x2a\x23\x31\x02\xfa\x7b\x40\xff\x41\xfb\xda\xef\xab\x2b\x30\xba\x95\x00\x70\x53\x5d\x08\x10\xfe\x75\x75\xa5\x62\x6e\x82\x8e\x92\x36\x2d\xae\xe0\x35\xc0\xd3\xcd\xb3\x41\x41\x19\x82\x74\xbd\x4e\xa1\xe7\x5c\xf0\x51\x14\xe6\x4e\xd7\x2f\x9e\xe9\xd2\x5b\xd9\xe7\xa6\xbc\x5c\xea\x73\xef\x84\x7b\xbf\xc8\xf7\xec\xfd\xd3\xcd\x33\xed\x61\xd7\x3a\xb4\xdf\x47\x5f\xe0\x31\x45\x94\xa6\x59\x89\x26\x24\x8d\x59\xbf\x48\x3a\x65\x0d\x3d\x95\xcd\x8f\xb3\xb4\xc8\x12\x3c\xb8\x88\xf2\xb4\xd7\xd5\x4b\x30\xdf\x3a\x94\x15\x27\xd9\xb4\xab\x59\xd6\xf2\x1e\x53\x54\x38\xfe\x99\x30\x67\x43\x7a\x3c\x2e\x98\xbb\x9e\x6f\x75\x06\xac\x5b\x81\x49\x10\xe6\xd1\x0a\x6a\x14\xae\x11\x61\x8a\x5b\x2c\xc7\xcf\x78\x4c\x25\x00\xcf\x7a\x0c\xc0\x7f\xd3\x28\x1a\x7f\x92\x91\x43\xc1\x75\x01\x3f\x04\x8b\xdb\xdc\x5e\x94\x4f\x97\xf0\xd6\xe4\x54\xfe\xd2\x7c\xf7\x98\x46\xee\xa2\x46\x88\xf8\x5c\x5b\x4c\xf7\x32\xd7\x73\x20\xe8\xc4\x6f\x99\x5e\x2f\x14\xdb\x48\x97\x49\xe2\xa0\x3b\x13\x94\xc6\x7d\xe5\xa9\x83\xb1\x80\x98\x68\xb1\xd5\x98\xc6\x16\x30\x39\x18\x11\x53\x99\xa8\x89\xe5\x8c\xb3\xf7\xb2\x00\x0e\x50\xdd\xc0\xcb\x58\x03\xc1\xde\xe9\x21\xac\xd7\x0f\x54\x43\x1a\x86\x9b\x67\x2a\x2a\xcb\x68\x3c\xfb\x90\x1d\x0a\xb7\x59\xfa\x94\x09\x5f\x5a\xfa\x59\x5c\x4d\x2d\x1b\x37\xfb\x74\x86\x23\x8a\x0e\xa2\x24\x91\xfb\x09\x07\xae\x38\x6b\x38\xdd\x94\x07\x0f\xcf\xc9\xc3\x7b\xf4\x00\x52\xed\x84\xdb\x20\xc5\xb3\x55\xdf\x09\xb7\x41\x76\xd7\x23\xb5\xed\x00\xb0\xb5\x11\x76\xc2\xdd\x1d\x2a\x32\xef\xde\x8b\xcc\xf7\x22\xf3\x5f\x5b\x64\xd6\xc2\xc0\xc0\x99\xfc\xae\xe2\xc0\xfc\xbd\xc8\xd2\x7c\x31\x36\xe5\xcd\x5f\x58\xa2\xbc\x70\xcc\xf3\xcc\x16\x81\x59\x9a\x94\x44\x5d\xd5\x05\x1d\xac\x21\x64\x3a\x32\x26\xa0\xe3\x63\xa5\xa4\xc9\x33\x0a\x1e\xce\xbb\xc1\xad\x60\x14\xc7\xc2\x03\x24\x65\xc7\xbc\x30\x38\xd5\x86\xae\xf1\x04\xcb\xe0\x30\x8a\x63\x8f\x09\x2f\xe2\xe3\x67\x85\x4a\x65\x3c\x3b\x5c\x83\x71\xea\xac\x38\x8e\x7d\x32\xb7\x6f\xe0\x05\x8b\x05\x2e\x20\x1a\x47\x24\x98\x76\x5d\xff\x39\x8c\xb7\x6b\xbe\xfd\xdc\xf4\x5e\x20\xf1\x6b\x74\xd3\x9d\x02\x75\x7f\x94\xc6\x4c\xfd\x24\x60\x03\xad\x6e\x9c\xe7\x01\x97\x44\x0b\x57\x26\x66\xe4\xc3\x7e\x71\x61\x51\x01\x70\xfc\xe8\x0e\x29\x25\x2a\x03\x04\xaf\xe7\x2b\xde\xc7\xf1\x2a\x4f\x01\xe6\x4c\x3f\x1e\x54\x0a\xec\xac\x48\x45\xb5\x54\xac\x11\xfd\xe1\x95\x0e\x1c\xd9\xc7\x2e\xb0\xce\x17\xd1\x80\x14\xff\x88\x12\x12\xbf\xc3\xc5\x22\x4b\x0b\xcc\x9b\x72\xde\xf6\x39\x63\xf0\xb7\xd7\x63\x6b\x6c\x70\x94\x9e\x7b\x6b\xdd\x73\x2a\xbd\x76\xfb\x57\x59\x39\xf3\xf1\xe4\x0c\x96\xed\xb9\xe0\x7b\xc2\x97\xc1\x1b\x1f\xf0\x3e\x80\x57\x0a\x3d\xc1\x89\x5d\xaf\xa6\x42\x9e\x6f\x90\x5f\x94\x00\xca\x52\x7a\x4b\x36\xf8\x4e\xb8\x0d\x7a\x34\xbe\x22\x3b\xe1\x0e\x18\xd5\xb5\x8a\xed\x7d\xbf\xe1\xdf\x6f\xf8\x7f\xde\x0d\x5f\xed\xf7\x52\x2c\xbf\x23\x15\x59\x4b\x5d\x15\x3d\xf1\xe4\x16\x58\xc1\x65\xfd\x21\x64\xae\xaa\x4e\x13\x70\xda\xb3\x2c\x5d\x0f\x26\xde\x5b\x71\xe8\x03\xed\x10\xa2\x81\x49\x8d\xa1\x11\x68\xdc\xb7\x7f\x32\xf5\x92\xfe\x86\x0d\xb6\x79\xfb\x19\x34\x83\x3b\x54\x60\x6f\x05\x94\x94\x0b\xc0\x96\xf8\x1a\x09\xcf\xcf\x66\xaa\xb7\x01\xee\x1b\xd8\x5f\xb5\xf9\x92\x74\x44\x02\x5e\xce\xba\xed\x89\x46\xc4\xa3\x06\xd1\xbc\x44\x23\xcb\x2d\x33\x0b\xeb\xbd\xbf\x8f\xba\x5a\x9f\xba\xe8\xc1\x03\xc3\xed\xb3\x76\x6e\x66\xcd\x1a\xb1\x01\xae\xfb\xd6\x36\x5c\xd7\xa0\xc7\x91\x34\xea\x41\x62\xc5\x76\x0d\x79\xcc\xbf\xb4\x67\x67\xb0\x2a\xa2\x60\x85\xa7\x69\xa0\x3d\x7e\x78\x67\x08\x65\xa0\x12\x8d\x9a\x96\x47\x68\xaf\x5a\x48\x8f\x32\xd0\x17\x77\x44\xc5\x8e\xd6\xde\x87\x53\x51\x1c\x0b\x1a\x2e\xd4\x31\x5c\xa7\x0d\x91\x76\x2d\x6b\xaa\xa4\x27\x46\x2a\xfe\x2a\x6b\x4f\xf6\xea\xb8\x7e\x73\x42\xd1\x1e\x39\xae\x32\xfb\xba\xa6\x4a\x6a\x7f\x64\x7f\x3e\xe2\x72\x26\xd4\xcd\xaa\x93\xa6\x57\x8d\x46\x55\xea\xc4\x51\x73\x28\x04\x28\x55\x69\x8b\x79\x65\xdc\xa2\xd5\xa4\x32\x7e\x73\x77\x33\x6a\xd7\xd7\xac\xa8\x11\x0c\xef\x2e\xe6\x96\xf1\x5e\x4b\xad\xcc\x39\x2b\xd7\x36\x4a\x1e\x6b\x4e\x9e\xab\xb7\x62\x1d\xab\x9c\xce\x83\x24\xa9\x9d\x2e\x00\xe2\x17\x3d\x2b\x13\x18\x53\x85\x36\x74\x70\x75\x6a\x33\xde\x1c\xba\x4a\xb5\x2a\x6a\xab\x23\x37\xe9\x88\x07\x6c\x02\xc5\xa4\x4f\x71\x59\x70\xa3\x96\xe4\x12\xc5\x78\x91\x64\x97\x38\x16\xa6\x83\xf0\x3a\x71\x3c\x8b\x94\x6b\x45\x41\x90\x50\xdb\x8f\x59\x2e\x7a\xe4\x71\x6d\x20\x0e\xac\x3e\x92\x14\xeb\xf2\x5a\xaa\x16\xd7\x0c\x87\xba\x27\xe2\x72\x43\xbd\x9a\xab\x68\x51\x37\x84\x10\x2d\x69\x0a\x4b\x45\xbe\x10\xc8\x19\xe2\xce\x38\x6a\xde\x1f\x21\x36\xf9\xbe\x78\xb7\x06\xf9\xc3\x21\xba\x88\x08\x53\x97\x83\xc8\xb5\x28\x95\x0a\x56\xdc\x94\x99\xf3\xce\x97\x82\x0c\x24\xad\x3a\x86\xfb\xa6\x9f\xe6\x75\x0c\x2f\x9f\x8d\xf6\xed\x5d\x09\xfa\xbb\xb1\xb1\x67\x1e\x9b\x86\x43\x54\x94\xd9\x82\xe9\x6a\x49\x3a\x45\xd1\x84\x76\xe5\x9b\x4d\x36\x57\x05\xea\x95\x64\x8e\xb3\x65\xd9\x77\x8e\x8e\x0c\x01\x3f\xa0\x6f\x36\xbd\x87\x45\xd6\xfb\x01\xad\xfd\x67\x5e\xb9\x8a\xc0\xd0\x47\x5f\xae\x3d\x67\x3a\x1b\x81\xec\x3d\xa0\xf7\x1c\x2a\x67\xc4\x7b\xda\x54\x27\x3f\xe5\x48\x58\x32\x26\xb8\x2f\x89\xd8\xca\x18\x53\xc2\x06\xa7\xc2\x23\x2a\x31\x2f\xd3\xd8\xc6\x40\xd7\x77\xf8\xc4\x89\xe6\xb0\x44\xff\x73\xdc\x17\xdf\xb8\x55\xba\xfc\xf4\x9a\xa5\xbb\x12\x17\x6b\x06\xd5\x4c\x71\xf9\x41\x35\xf5\x8e\x91\x9a\xe2\x28\x5a\x37\x5e\x46\xc5\x4c\x27\xaa\x40\x10\x66\xdf\x7f\x84\x27\x93\x1e\x07\xf0\x53\x9b\xb7\x90\xb7\x83\x10\xf0\x88\xd7\x35\x18\x9b\x0b\xd0\xec\x11\x44\x45\xf2\x77\x47\xfc\x55\xf9\x7a\x7f\x2c\x7d\xbd\x57\xfd\x91\x49\xcf\xa4\xb8\xab\x2b\xb4\x0e\x2d\xd6\x16\x43\x92\x75\x7b\x68\x53\xff\xbb\xc9\x12\xd0\xff\x5a\x2e\x07\x7b\x48\x59\xac\x85\x8e\xde\xa9\x9d\x19\xf1\x37\x1c\xca\x7b\xbe\x24\x9b\x6a\x54\x0b\xc7\x0a\xc1\xc6\xd7\xbb\xfd\x86\xe6\x91\x21\xaa\x49\x8e\x5a\x31\xd5\x2d\x2a\x1b\x0e\x11\xdb\xac\x84\xb8\x10\xa5\x31\xe2\x37\x23\x28\x9a\x46\x24\xe5\x2b\xe7\x02\xf3\x78\x80\x0d\x7f\x7e\xd9\xd3\xde\x00\x1b\x6a\xb0\x65\x1d\x67\xfb\x6f\x18\xd2\x98\x39\x7d\xe3\x97\x82\x74\x4b\xa0\xbb\x63\x81\xc7\x59\x1a\x23\xca\x70\x1b\x2b\xd1\x48\xb7\x99\x58\x91\xc1\x11\x41\x17\xd6\xb6\xc3\x5e\x27\x49\x77\xdc\x21\xdd\xd7\xbb\x26\x4a\xf0\x13\xad\xc6\x29\x8b\x32\xcb\x71\x2c\xfd\xbe\x33\x09\x04\x34\x3e\xd3\xa8\x40\xd1\x9c\x6e\x48\x03\x2f\xbf\xb6\xff\x2a\xf9\xb7\xfd\xe7\x71\x47\x7f\x17\x5d\xac\xef\xe1\x75\x65\x6e\x15\xc7\x70\x4b\xd8\x90\x9a\x76\xb2\xed\x81\x42\xbb\x62\x10\x84\xfe\x63\x44\x8f\xd9\x97\xd2\x55\x87\x25\xc5\x59\x60\x0d\x87\x06\xbb\x52\xfd\xc0\x00\xa7\xaa\x68\x44\x8c\xcb\x05\xf6\x0e\x09\x8b\xe3\x3b\xa4\x45\x23\x82\xf6\x29\xa4\x90\xb3\x1e\x32\x4d\x68\xf3\x98\xd4\x09\x29\x45\x91\x26\x9a\xf2\xe2\xa2\x16\x31\xb6\x14\x5f\xc8\x24\x31\xa6\xf4\xf2\x5a\x27\x06\x4b\x37\xb2\x25\x6c\x0a\xa2\xa4\xbf\x62\xd1\xed\x9a\xa2\xb6\x1c\x6c\x48\x16\xdc\x95\x8a\x50\x14\xc7\x4e\x69\x9f\xa4\xcc\x21\xa4\xb4\xac\x8e\x7f\x22\x49\xb6\xa5\x26\x1e\x0a\x0d\xd5\x44\x50\x94\xfa\xae\x5f\x50\xcd\x16\xfd\xad\xa8\x81\xfe\xc9\x1f\x4c\xba\x96\xa7\x48\x9d\xfe\x7a\x82\x0e\x02\x39\xfb\x3a\x07\x1b\x0e\x59\x24\x46\x65\x65\x61\x54\xaa\x6c\x25\xbe\x5c\xef\x51\x60\x81\xa5\x75\xb3\x6d\x8e\x18\xad\x62\x38\xe3\xe6\xf0\x46\x07\x08\x59\x7f\xac\x90\x90\x31\x86\xab\x06\x65\xaf\x61\x05\x0b\xf4\xd9\x8e\x80\xfd\x47\xb5\xc5\x08\x72\xac\x46\x8a\xc1\x22\x5b\x18\xfe\xeb\xcc\xee\x25\x51\x51\x72\x48\xa7\x6a\x7f\x77\x18\x21\xf5\x68\x41\xf0\x8d\xb4\x2e\x5f\xa2\x40\xd8\x5a\x48\xb7\xfb\xa4\x50\xd8\xd0\x25\xda\x10\x07\x1e\xb0\x18\x26\x3f\xa0\x4d\xbb\x36\x3e\xd3\x82\xf6\x0f\xc4\x5a\x6e\xd6\x02\x88\xbf\x5b\xa9\x04\x35\x34\x59\xcc\x52\xa8\xd3\xa4\xa9\x9d\x3e\xac\x75\xb3\xcb\x83\x45\x74\x19\x8d\x12\xec\xeb\x9e\x7b\x1c\x60\xf6\x53\x05\x4e\x63\x15\xbf\x2a\xcd\xd2\x47\xbc\x12\x1d\x1d\xf6\x36\x71\x5d\x35\xf5\xe0\x3a\x90\x72\x46\xbf\x0a\xb6\x27\x96\x4a\x00\x23\xd6\x6a\x15\x13\x04\xb6\x6f\x1b\xfb\xac\xa2\x3d\x73\x12\x2b\x6f\x04\xf5\x13\xad\xa1\x03\x10\x72\x5f\x14\xa7\xb6\x96\x20\xc6\xe8\x22\x2a\xa4\x40\xb9\x66\xe2\x8a\x2d\x6d\xb8\x7a\xd5\x8e\x30\xca\x30\xcb\xba\x7f\x9d\x45\xc5\xcc\x87\x74\xda\x6b\x9c\xe7\x55\x37\x91\xfa\x95\xa3\xef\x5e\xb1\x4e\xe2\xa1\xe2\x68\x1c\xb3\x6b\x2f\x8d\xeb\xd2\x9e\xf8\xdb\xaa\x38\x76\xa1\x7d\x28\x53\x21\x7c\x55\x4a\x88\x13\x92\x17\x65\xb5\x80\xb8\xa2\x8c\x57\xa1\x01\xf1\xa9\x3d\x7c\xd7\xaf\xc6\x57\x93\x5f\x4d\x88\xcb\xc9\x06\xde\x34\xcf\x56\x63\x6d\x51\xde\x88\xea\x55\x86\xee\xe7\x69\x52\x27\xcf\x80\xb8\xae\x8c\x63\x57\x6c\x82\xf4\x7c\xfb\x9c\xd9\x95\x42\x12\xff\x34\x0c\xd0\x6e\x2c\x78\xd9\x5a\xb3\x26\xed\xac\x67\x53\xe7\x35\x5d\x9b\x32\xd0\x44\xd6\x3f\x5c\x1b\x0e\xad\x1d\xd8\xb8\xc0\x51\x1e\x95\x35\xf5\xa5\x55\x79\x8f\xed\xcb\xc3\xa1\xe1\xaa\xb7\x32\x4a\xf5\x78\x0c\x5e\x77\x33\x16\xd8\x89\xa4\xd3\x1a\xd9\xcc\x54\x63\x9b\x23\x67\x93\x78\xed\x72\x22\x5d\x1c\xaa\x13\x85\xd0\x17\x4d\xea\x6a\x2b\x11\x4d\x50\x9a\xa9\x1a\x28\x7b\x5b\x44\x45\x81\xe3\x80\x56\xa1\x3c\xeb\x51\x88\x42\x5b\xd2\x26\x2f\x93\x84\x07\x33\x60\xa1\xd3\x30\x87\xf4\xb9\x66\x35\x6d\x56\xd1\xca\x32\x94\xb2\x94\x57\xda\xca\x72\xa6\x79\xae\x84\xa0\x37\x10\x50\x4c\x18\x15\xc8\x2e\x05\xa2\xc0\x08\x8f\xa3\x65\x81\xe9\x49\x3c\xce\x52\x88\x64\x02\x36\x49\xc5\x22\x23\x09\xbb\x0d\x4f\x4b\x9c\x4f\xa2\xb1\xf4\xbd\xdd\xe2\x24\xde\xe6\xb4\x6d\x6f\x53\xcd\xfc\x10\x39\xde\x7b\xe5\x9a\xd6\xd6\xe6\x4f\xb8\x64\xce\xa0\xe9\xfe\x18\xa0\x8b\x19\x19\xcf\xc0\x68\x80\x2e\xef\x32\xe3\xdb\x18\x5a\x24\xcb\xa2\xf9\xea\x95\xf3\x81\x86\xf9\x55\xcc\xc3\x6f\xc8\xd4\x20\xc2\xae\x2e\xa7\xca\x62\xcd\xf2\xe3\x6d\x64\xc7\x6a\xb9\x51\x33\x56\xbe\x91\x1c\x53\x27\xc3\x98\x2f\x1d\x06\xcc\x20\xbd\x3d\xf3\xf5\x9c\x7a\xbc\xc7\xdd\x16\xd7\xe7\x55\xac\xc9\x39\x0c\x7b\x4f\xc1\x15\x2f\x59\x7c\xe7\x61\x77\xf7\x53\x76\xe1\x0c\x7f\xee\xe3\x15\xe4\x39\x4c\x7b\xcd\x96\x2c\xba\xdd\x93\xe6\xcf\xa6\xad\x44\x27\xfc\xb6\xca\x02\x5a\x5a\x34\x74\xc2\xed\x1d\xd7\x24\x9a\x8f\xbc\x13\xee\x6c\x5d\x9f\x05\xdb\x8f\xef\x4d\x9f\xee\x4d\x9f\xfe\xda\xa6\x4f\x9a\xad\x33\x37\x81\xbc\x03\x63\xe7\x0a\x2f\x99\xdc\xb8\x92\xbd\xcb\x3a\x9e\x88\x3b\xe7\x83\x7c\x5a\x84\x15\x9a\x1b\x4d\xc6\xe3\x27\x58\x5e\x89\x1e\x43\x4f\x6c\x27\x94\x7d\x8a\x4a\x91\x6e\xe2\x04\x7c\x3e\xf0\xf5\xe1\xdd\xdb\x43\xc6\xdc\x6f\xd3\x01\x16\xcf\x09\x58\x2d\x86\x07\x8c\x65\x86\xde\xbd\x3d\xe4\xf7\x04\xfe\x0e\xf0\x67\xea\xe0\xa3\x51\xb5\x3c\xcb\x0a\xfd\xf6\xcb\x6d\xfc\xf0\xf8\xcd\x9b\x17\x87\x1f\x8e\x8e\xdf\xa0\x17\xef\xde\x1d\xbf\x0b\xd1\xa1\x54\xff\x8e\x59\x95\xec\x44\x1f\x63\xd4\xdd\x40\xb4\x3e\xb4\xd1\x1d\xf8\xfb\xa0\x3c\xe1\xb4\x1d\xbb\x7c\x7f\xcf\x94\x08\x25\x5d\x3d\x84\x55\xe6\x6f\x42\x18\xd2\x86\xc8\x36\x0a\xd6\x86\x09\xcf\xd2\x70\x51\x44\x53\x8c\xf6\xd1\xfa\x3a\x7f\x69\x48\xb7\x75\xfe\x7b\xc0\x02\xcc\x3a\x29\x03\x51\xec\x29\xf2\x26\x87\x48\x4e\xd7\xdf\xdf\x1f\xbf\x81\x59\xc9\x65\x97\x3c\x61\x59\x79\xdf\x9c\xb7\x64\x0a\x07\xbc\x6a\x73\xb4\x6a\x36\x3f\xb0\xeb\x6a\x7d\xbc\xf3\xa2\xed\x94\x7e\x38\x7a\xfd\xe2\xf8\xe4\x43\x88\xf8\xa5\x37\x25\x2e\xda\xc9\x79\x81\x36\x50\x97\xfe\x17\x8d\x67\x94\x63\x74\x8d\x80\x39\xdc\x4b\xe5\xb7\xf7\xbb\xd5\xfd\x6e\xf5\xd7\xde\xad\xb4\xcd\x0a\x5e\x5d\xfe\x51\xad\x74\xdb\x3f\x66\x6f\xf5\x86\xfe\x0e\x9f\xb2\x0b\x5f\x44\x74\xfd\xcb\xc3\x99\x1e\xf1\xa9\x30\x8e\x21\xfc\x8d\x2d\xb4\xa5\x0e\x0b\xb6\x11\xf2\xd7\x7e\x07\xbf\x10\xa6\xbc\x9a\x22\x5d\xcf\x67\x91\x31\x50\xcd\x73\xe4\x22\x4b\xfb\x0d\x4f\xe8\xb5\xcc\x34\x4b\x2f\xe7\xd9\x52\xb6\x28\x13\x2a\x4e\x6f\x02\x69\x53\x2c\x70\x85\x63\x26\x0f\x40\x8c\x04\x27\x18\x14\x4f\x93\xc7\xb3\x67\x59\x96\x5c\x43\xf0\xd6\x18\x3c\x9c\xb3\x4d\x02\x33\xc8\x58\x9b\x1d\x78\x1f\x82\x63\xc3\x1f\xbb\x38\xf1\x41\xac\x03\xba\x28\x79\xed\xc3\x35\x63\x9a\x74\xaf\x5c\x14\xc2\x74\x28\xc5\xea\xb5\xe3\x3b\x68\xc8\x77\xaf\x44\x88\x47\x54\x20\x03\x5e\x13\xdc\x6f\xf0\xdf\x15\xe6\xae\xfe\xf2\xca\x5e\xb9\xf2\x86\x58\xc7\x36\xa3\xcf\x98\x79\x18\x07\xef\x46\x16\xae\x63\xe5\x7c\xdc\x1b\x8c\xc8\x5b\x41\x35\xea\xb4\xea\xea\x9c\x07\x31\x4a\x74\xfd\x9f\xf7\x14\xbd\xb6\x1f\x1d\xac\x50\xcf\xd0\x4a\xee\x2e\xbd\x66\x5c\x7a\xd1\x7a\x7a\x58\x69\x44\xc2\x51\xfb\x8d\x86\x53\x90\x69\x1a\x95\xcb\xdc\x1e\x8e\x9e\x5e\x35\x1e\x1d\xa6\x7a\x3c\x12\xaa\x6e\x40\xe0\xc0\xa0\x7d\xff\xf9\x0b\x07\x41\xde\x9c\x23\x45\x69\x2c\x55\x4b\x65\x06\x41\x8f\x27\x24\x8d\x12\xbf\xd5\x33\xab\xc3\x67\x53\xaa\xaf\x6b\x2b\x8b\x57\x6f\x20\x45\xe4\xe1\x73\x9c\x5f\x96\x33\xa6\xb1\x9e\x8f\x08\xb0\x8c\x6c\x0a\x5e\xc7\xa0\x6f\x3c\x8a\x43\x2d\xb6\x3c\xae\x41\x78\x77\x1c\x8f\x77\x72\x71\xcb\x5f\xda\x23\xc0\x7b\x07\x22\xca\x5d\x87\xf4\xcf\xd1\xe4\x59\x44\xe8\x35\xd7\xad\x9d\xc7\xed\x27\xaf\x9c\x3d\x6c\xe5\x3e\x07\x99\xff\x4e\x41\xed\xbd\xbe\xab\xd2\xe4\xf3\xf4\x41\x74\xec\xb6\x2c\x5d\x8b\x90\x51\xcb\xcf\xc1\x5d\x33\x0f\xd6\xcd\xcb\x9f\x12\x88\x60\x65\x19\x03\x70\x20\x7d\xfe\x28\xdd\xa8\xe4\x74\x99\x24\x15\x2f\x5c\x94\x66\x11\xb9\x97\xff\xa6\x42\x18\xea\xab\x0a\xfc\x08\x99\xd6\x68\xce\x6b\x6e\xfb\x39\xf6\x9d\xb7\x31\x3d\xb6\x7d\x05\xe8\xdc\xbe\x39\xef\xdb\xa1\xfb\x56\xaa\x0d\xfa\xde\x40\x71\x26\x91\x8c\xb3\x74\x1c\x95\x3d\x63\xf6\xfb\xd5\x7e\x6c\x2a\xb9\x1e\x77\x62\x53\xcd\xf5\xec\xdd\x16\x57\x71\xb8\x98\xdd\x3d\xb8\x3c\x8c\x73\x05\x6e\x38\x04\x27\x04\x56\x4b\x2c\x9b\x7d\xf0\x00\xf4\x0d\x66\x2f\xea\xb7\xe9\x6a\xe7\x3b\x80\x83\x3b\xf4\xbe\x13\xe5\x53\x6b\x75\x29\xf1\xf1\xa9\x51\x32\xd4\xbf\xb8\x67\x9e\x2d\xcd\x13\x0a\x1f\x1f\xbf\x7f\x91\xf5\xda\x2f\xb5\xd8\x64\xa2\x2f\x52\x4a\xd3\xeb\xdb\xee\xef\xd1\x95\xf9\x4b\x46\xd2\x5e\xa7\xe3\x56\x2e\x1f\xc5\x31\x72\x63\x78\xd2\xaf\x37\x40\x36\xec\xd1\x65\xde\x0f\xf4\x1e\xe9\x57\x35\x69\x56\x1e\x19\x7d\x95\x28\xf4\xf8\x1b\x52\xc0\x2d\xdb\x86\x57\x0b\xfd\xc0\x6a\x45\x6f\x5f\x6e\x24\x1a\xd7\xce\x96\xe5\x62\x59\xbe\xca\xa6\x8a\x5d\x73\x5f\x3c\xda\x6a\x11\xce\x7f\x98\x9f\x19\x4d\x2c\x33\xc1\x14\xb7\x86\x31\xd9\x6e\xa0\x18\x0c\xbb\xe5\x32\xf8\x69\x8e\xe3\xe5\x18\x6b\x73\x15\x8d\xc7\x01\xe2\x2e\x2a\x75\x7e\x12\x8d\xc7\xa7\x3c\x99\xf1\x44\x8a\x14\xfe\x2d\xa8\xfc\xa9\x39\x65\x83\x62\x46\x26\x65\xaf\x8f\x42\x07\xa3\x22\xcb\x51\x5a\x45\xe3\xb1\xd0\x52\x31\x63\x6f\x46\xda\x38\xc1\x25\x16\xe3\x50\x4e\x92\xcc\x74\x46\x55\x37\x60\x19\xda\xfd\x15\x7f\x57\xc2\x97\x36\xdd\xea\x99\x18\x57\xe9\x58\xe1\xae\xe4\x22\xa3\xe1\x7a\xe1\xc7\xe3\xb8\xc1\x96\x7e\xfe\xe8\x1e\x99\xb6\x9b\x3d\x32\xd5\x15\xdf\xaa\xb6\xb1\x33\x2b\x40\x86\x04\x68\xf8\x7e\xb0\xc5\x0e\xdb\xed\x93\x23\x50\xfe\xa1\xfc\x3f\x55\xd2\xb2\x6e\xfa\xdf\xe2\x51\xa3\xf5\xaa\xcd\xfb\xa2\xb1\x96\x1a\xbf\x96\xb3\x29\x0a\x6a\x9e\x5c\xab\x38\xa0\xb0\x2f\x84\x96\x4e\x35\x80\x33\x83\x7a\x7d\x00\xba\xff\x2a\x45\x14\x5e\xd0\x53\xc9\xee\x59\xdb\x67\x95\x03\x30\xac\x26\xbc\x77\xc2\x06\x2e\x35\x8f\x58\x75\x57\xc2\x4d\x7e\xb2\x6e\xe8\x1a\xeb\x49\x1b\x05\xfc\x6d\x7d\x5d\x0e\xfd\xba\xc9\xd7\x8c\x06\x3d\xfa\xbf\xfa\xf0\x23\x7a\xe4\x91\xb5\xe1\x10\x7d\x38\x7e\x7e\x1c\xa2\x1c\x33\x83\xac\x00\x15\x19\x37\x9d\x91\x57\x5c\xca\x16\x27\x62\x9a\xae\x01\x2d\x47\xca\x6e\x81\x52\x3c\xc6\x45\x11\xe5\x97\x74\xb1\x40\x84\xed\x82\x92\x5b\x17\xfc\x18\x83\x17\x69\x74\x91\xe5\x9f\x98\x94\x37\x5f\x26\x25\x59\x24\x5a\x84\x07\x33\xe2\x8a\xdf\xbd\xd1\xf0\x21\xf2\xda\x72\x7f\x23\x4c\xb9\x59\x1d\xa6\x19\x83\x68\xde\xb0\x21\x55\x8d\xe1\x98\x6d\x1c\xe6\xc9\x44\x96\x1a\x08\x1c\xf9\x1c\xd8\xac\xd3\xce\x9d\xba\xb0\x67\xbe\xf3\x43\x15\xac\xc5\x4e\x91\x63\xdf\x68\xf6\x93\xfb\x73\xf2\xd5\xd4\x60\x06\xe9\xad\xa7\xf2\x08\xa5\xea\xe7\x04\x6f\x8f\xc9\x01\xf0\x1c\xb9\x59\x8e\x0f\x1b\x2c\x47\x32\x3d\x6e\xd2\x63\x76\xd1\x63\xf9\xc9\x8b\x15\xb8\xb4\xe2\xcb\xf8\x7c\x5c\xd5\x1e\xc4\xea\xa7\x9b\xe0\x9a\xf1\x2a\x18\xcf\x90\xab\xe8\x05\xa9\xe0\x27\x97\x2b\x0f\x5b\x16\xbc\x83\x81\x23\xcd\x6e\x14\x7f\x1e\x18\xec\x48\x7d\xec\x21\x01\x20\xb8\x10\xfc\xbf\x27\x52\x25\xcb\x61\x3f\x64\xba\xc6\x68\xc4\x4f\x53\x8e\xc4\x9f\xf9\xf3\x6e\x97\x9b\x33\x34\x28\xcf\x44\x15\xfc\xb9\x82\x23\x77\xc2\x1d\xf0\x60\xa4\x3b\x0d\xa7\x8c\xf9\xbb\xfb\x9b\xd1\xfb\x9b\xd1\xbf\xf6\xcd\x28\xbf\x16\xe5\x4f\x7e\xff\x2b\xc2\xf7\xdd\xa9\xc7\x70\x38\x04\x3c\x44\x87\x59\x7a\x8e\x29\x2b\x8a\x78\x44\x65\x38\x07\xc3\x59\x00\xc2\x22\x8b\x00\x2f\x94\x80\xa3\xa4\xc8\x50\x94\x24\xd9\x45\xc1\xa2\xbf\x83\xa2\xae\x18\xac\xd1\x8a\x84\xe0\xff\x9a\x7c\xc6\xf1\x35\xcb\x5a\x73\xef\x35\xd6\xf8\x8d\x6a\x99\xd9\x31\x94\xb9\xca\x52\x1e\x38\x7b\xa6\x4a\x14\x5d\x5d\x89\xf8\xeb\x2a\xa3\x2b\x75\xa8\xdd\xbe\xad\x0c\x60\x67\x39\x2e\x22\x31\xc5\x2c\xeb\x43\x4f\xe8\x17\x8d\x86\x98\x1e\xe2\x78\x02\xaa\xe6\x3e\xd4\xbe\xe9\xd4\x09\x90\x9c\xef\xeb\x2f\x5a\x8d\x3b\x23\x11\x5c\x49\xb6\x03\x47\x2e\x2a\x6a\x52\x4e\x2b\x2e\x7f\x6c\x4b\xd8\x4c\xea\xf7\xf9\x61\x2b\xa0\x93\x70\x8e\x73\x32\x01\xbf\x1e\x39\x1e\x47\x94\xe3\x68\x01\x6c\x1e\x3c\x40\x49\xf4\xeb\x25\x4a\xb2\x28\x46\xf1\x65\x1a\xcd\xc9\x18\x65\x29\x2e\xa0\x35\x3e\x21\xaa\x21\x1e\x2b\x3b\x93\x7a\x02\x80\x12\xf6\xf5\xa2\x71\x07\x8a\xce\xd6\x14\x97\xc7\xf2\x90\xec\xf1\xe0\x4c\x27\x46\x09\xac\x4d\xee\x01\x74\x65\x02\x9f\x12\x71\x4c\xae\xbe\xf5\x30\x34\xfd\x95\x57\x2f\x2c\xbb\xb8\x20\x10\xc7\x44\xeb\x15\x02\x1d\x44\x81\xd9\x09\x3a\x74\x5e\x56\x31\xe1\x7d\x99\x63\xae\x5e\x0c\xe0\x02\x6f\xcc\x56\x07\x3b\x1c\xcf\xf0\x67\x9f\xda\x40\x69\x4d\xad\x04\xcb\x13\x65\x8b\x22\x86\xe6\x93\x47\xed\xae\x54\xca\x5b\x0a\x7f\x11\xe3\xfb\x29\x8f\x7e\x4e\xab\xe2\x8b\xac\x8f\x42\xb1\xde\x38\x98\x2b\x2b\xf9\xae\x09\x3c\xcf\xeb\xa0\x9b\xa1\xd5\xed\xc0\x81\xa3\x4b\x40\x41\xd1\x2f\x17\xa6\xcc\xf4\x7a\xdc\xfc\x48\x04\x7d\x16\x40\x91\x1e\xf2\xd9\x6e\x0d\xee\xaf\xc2\x95\xae\xb5\x06\xb2\x5c\xdf\xd7\xbb\x9b\xd4\xc8\x4b\x99\xfa\x29\xed\xa0\xc3\x28\xb0\x98\x51\x0a\xf4\x20\xdc\x22\x75\xa1\xaa\x39\x88\x63\xf6\x2c\x42\x2a\xd1\xa2\x34\x46\x05\x2e\x0b\xb4\x5c\x40\x06\x3f\x8d\x00\xcb\x20\x25\xce\xe9\xde\x91\x9d\x73\x61\x8b\xbb\x31\x1d\xac\xad\x69\x4f\x23\x5e\x65\xd3\xe2\xa0\x7c\x5f\x46\x79\xb9\x66\x6b\x1a\x0b\x9c\x4c\x64\xe2\xc4\x7d\xbf\xcc\x59\xb8\x59\x8b\x11\x3f\x0c\x27\x13\xc7\x87\x8f\x78\x64\x37\xc5\x25\xd3\x67\xd1\xc2\xd6\x4b\x3b\xd0\x2f\xa8\x61\x16\xd0\x3d\x24\x4e\x9e\x16\xcf\xa0\xad\x0c\x7c\x8c\x03\x32\xa6\xb8\xec\x59\x6f\x7e\xb8\x25\xa3\x73\xca\x19\x0e\x51\x9c\xa5\x5d\xfe\x4a\x94\xf6\x91\xa3\x0d\xcc\x26\xe1\xa2\x5b\x24\x0a\xb3\x23\xf0\x84\x31\x18\x0c\xd0\x2f\x4b\xe6\x08\x98\xb6\x49\x79\xaf\x73\x5e\xae\x78\x18\x59\xf3\x28\xf2\xda\x7e\x01\x6b\xad\x74\x39\x0c\xff\x19\x93\x65\x7a\x0f\xa6\xcc\x90\xb3\xe9\x99\x26\x7b\xbc\x63\x9a\x7d\x1a\xfd\x6b\xf6\xc3\xfa\xf5\x48\x77\x91\x25\x09\x23\x1f\x3f\xd9\x72\xda\x54\x60\x36\x5d\x4a\x95\x08\xa8\x6d\xd3\xd7\xd2\x0c\xd7\x20\x96\xac\x82\x5c\xf8\x8c\x66\xce\x9c\x0a\x23\x0b\x4a\x7a\x62\xac\xbe\x49\xf0\x3d\x9b\xf2\xd1\x44\xd6\xfa\x24\xdf\x96\x3a\x6e\x46\x19\xca\x4c\x19\x86\xa6\x94\xd7\x4f\xad\x04\x55\x49\x28\x0a\xb9\xa4\x73\x2b\xf4\xdc\x8e\x48\x2b\xf5\x01\xd0\x27\xdb\x19\x35\x65\x3c\x6f\xb3\x24\xa1\x7c\x46\xf5\x84\xd1\x60\xc8\x8a\xd0\x73\x1a\x9e\xe3\xb4\x84\x23\xe7\x80\x52\x1c\x0c\x4d\xed\x25\x0b\x6e\x68\x7f\xca\x30\x05\xe4\x78\x14\x9f\x05\xe2\x8a\xca\x48\x0e\x14\x31\xf2\x1c\xdd\x8d\x11\x53\x10\x03\xfd\xd2\xcd\x5a\x44\x33\x74\x48\xdc\x92\xc9\x02\xe4\x84\xf7\x10\xcb\xcd\x73\x3b\x10\xf0\xd3\xd4\x61\x8e\x61\x4c\x60\xaf\x7d\xe8\x79\xe8\x08\xcc\x8e\x69\xb0\xb5\x0b\x57\x03\x1f\x9a\x86\x6f\x15\x55\x59\xa5\xae\xab\x52\xd9\xe3\x57\xaa\x99\x9d\xd1\x6c\x09\x50\xa5\xc7\xf8\x4a\x6b\x4c\x25\x6c\x2a\x31\xd8\x12\x7d\x35\x68\x07\x0d\x66\x82\x06\x29\x66\xde\x7d\x32\x26\x57\x08\xb7\xac\x91\x19\x7c\xcb\x3d\xac\xca\x57\x6c\xcf\xc9\xd2\xaf\x9d\xe4\xef\xca\x7e\x0f\x52\x7c\xc1\x6f\x9d\x74\x1c\xe8\xbe\xc2\x18\x93\x24\xb1\xe1\x1a\xcf\xcf\x1c\x1b\x96\x7d\x6f\x3c\xf2\x88\xb9\xe3\x51\x23\x1f\x44\x9c\x23\xf3\x73\x61\x0d\xf5\x5a\x0e\x49\x5d\xf6\x52\x53\xd6\xdf\x8d\x7a\xbd\xb3\xb1\xb4\x29\x11\xc4\xae\x1f\x40\xdd\x55\x43\x8e\xe1\x92\x81\x9e\x39\x16\x38\x8d\xc1\xc0\x4d\x4e\x72\x54\x80\xa2\x25\x2d\x28\x85\x4a\x5f\x30\xaa\xa2\x6c\x02\xc0\xb4\x10\x95\x7a\xfa\x4c\xb9\x22\x5b\x5f\xa6\x51\x51\x90\x69\x8a\xe3\x81\xdb\x47\x9b\xa2\x7c\x3c\xd9\x37\x3b\x52\xc6\x1a\x8f\x1a\x26\xc8\xdb\x8c\x6e\x32\xa6\x8d\x44\xd9\x13\xa3\x44\x97\x0e\xa3\x24\xc7\x51\x7c\xa9\xde\xab\x2b\x41\xb1\xb8\x3d\xa5\x99\x82\xac\x90\x5e\x9b\xc6\x45\x26\x3d\xab\x35\xe9\x03\x6e\xd3\xf5\xc8\xa5\x56\x26\xe3\xe2\x3e\xb7\x90\x54\x8a\x2e\x33\x3e\x36\x32\x9f\xe3\x98\x44\x25\x4e\x2e\xed\x66\x39\xb9\x8f\xdb\xd2\xb6\x29\x9d\x40\xf5\xbd\x0a\x4f\x13\x3e\xaf\x55\xba\x26\x9b\xb1\x7c\xba\xfd\xb0\xc1\x68\x77\xb9\x17\x4e\x94\x0e\x7b\x33\x37\x79\x1b\x33\xec\xd3\xfa\x21\xd4\x31\x06\x73\xd4\x47\x63\xcd\x13\xbf\x2e\x75\x07\xa2\xe1\x5a\xbb\x13\xbe\x6e\x3b\x10\x7d\xdf\x6d\x1e\x8f\x23\x39\x64\x0b\x21\x38\x98\x03\x69\xe0\xef\xf0\xb4\x7c\x9e\x9d\x0b\x95\x26\x8a\x8a\xcb\x74\x2c\x0f\x3f\x3e\xc1\xc8\xc7\xb7\x97\x29\xbc\x9d\x36\x10\xa0\xc9\x18\x16\xb6\x1c\xde\xa5\x1b\xc2\xaf\x52\xb3\x21\xf8\x3b\x18\x9d\x5a\xa1\xdc\x7d\xce\x13\x1c\x99\xc2\x6b\x72\x22\x4b\xda\x42\xb9\xb5\xa3\x56\xd8\x51\x0e\x87\xe8\x68\xa2\x38\x23\x29\xe4\xbb\xbe\x4b\xcc\xdd\xaf\x20\x52\x22\xe5\xa5\x4b\x95\xbb\x98\x61\x30\xc6\xe0\xa3\xef\x23\xc6\x54\x0b\x44\x4a\x93\xad\x7a\x37\x6a\x87\xd8\xe5\x32\xf3\xed\x1e\x3e\xf4\xf3\x1a\xed\x09\xd5\xf7\x63\x88\x90\xe2\xe1\x6f\x5f\xd1\x3f\x8f\x25\x8f\xe7\xd8\xb6\xde\x4b\xb2\x69\x55\xbb\xc8\x62\x4c\x35\x67\x0b\xa8\x25\xa4\x7b\x42\x85\x3b\x3e\x7f\xc0\x12\x13\xc4\x39\x00\xd8\x03\x6b\x4f\x47\x8e\x9b\x29\x2e\x88\x1f\x3d\x67\x09\x21\xa7\xb1\x5e\x7f\xc0\x76\xe4\x71\x24\x1c\x16\x82\x5b\x15\x1c\x23\xba\xba\x67\x79\x96\x66\xcb\x42\x7a\x2f\xe4\x86\x01\x74\xb7\xb7\x3d\x11\xb1\x6a\xb8\xb0\xdb\xf5\x9a\xd7\x82\x53\x89\x4c\x59\xe9\xb5\x21\x20\xd7\x86\x8e\xd6\xd0\x3c\x87\xb7\x98\xb7\xeb\x06\x7e\xec\x5c\x91\x32\xdc\x3a\xb1\xdf\x6a\x2e\x48\xaf\xcf\x82\x9d\xcd\x36\x57\xa0\xdd\x65\xc1\xf4\xe2\xe3\xb2\xbb\x76\x7f\x21\x7a\x7f\x21\xfa\x27\xbe\x10\x55\x4f\x45\x35\x95\xf5\x4d\xde\x8b\x72\xe0\x15\x6e\x32\x7d\xb1\xdf\x5a\x3f\x31\x4d\x27\x64\xea\x85\x63\x59\x02\xf0\x68\x14\x59\x31\x5d\xc8\x28\x4a\x3d\x71\x5a\x40\x9b\xcc\x02\x4d\x31\x1b\x69\x76\x99\x39\x22\x53\xee\xc1\xc0\xb2\x62\x64\x40\xcf\xc8\xd4\x52\xea\xeb\xd6\x8c\x4c\xe3\x7c\xc5\x20\xae\x24\xec\xb5\xe9\xb4\x4a\xa5\xeb\x96\xb8\xa0\x67\xac\x68\xc3\x90\x8a\x58\xef\xbc\xcf\xd0\xca\x4c\x96\x15\x60\x7b\x52\x09\x44\x8a\xb7\x39\xe6\xd7\xa0\xda\xed\x84\x51\xf7\x48\xa5\x5b\x0d\x8c\xf4\x12\xf4\x20\xc1\xbd\xdf\xa3\xab\x2b\x37\x8f\x9f\x4d\xfd\x99\x38\xca\x13\x42\x8b\x6a\x5d\x4b\x17\xcb\xf2\x39\x9e\x44\xcb\xc4\x7b\x71\xd2\xd4\x47\xba\x23\xdb\xed\xc8\x2b\x5f\x6f\xf4\x16\x4a\x32\x83\x58\x6b\xd1\xe3\x7b\xaa\xfa\x1e\x47\xef\x82\x35\x8a\xdf\xa2\xfb\xf6\x8b\x2e\x26\xa0\xd0\x5a\x2a\xe6\xd8\x68\xd4\x53\xa1\x96\xed\xc1\x83\xa0\xad\x97\xf8\xb3\x67\xe4\x7c\x55\xb1\xc1\x16\x9a\x51\x64\x36\x41\x91\xe1\x1a\x30\x4a\x63\x71\x53\x5a\xc0\x4d\x09\xb3\x0b\xa0\xeb\xee\xe5\x8b\x7f\x5a\xcb\x0d\xea\xa0\x72\xb1\x77\xa1\x09\x5d\xbe\xe1\x62\xd7\x31\x7c\x17\x57\xe4\x42\xdb\xef\xd6\xe9\x0d\xfc\xfd\xc5\xb8\x3c\x86\xeb\x3f\xed\x0a\x16\x3e\xaf\xae\x2c\x1a\x3a\x18\x43\xd8\x05\xcd\xf1\x99\x0e\xef\xf1\xb8\x25\xaa\x85\x3e\x71\x37\x54\xfe\xab\x47\x53\x0e\xd2\xab\x2e\x33\x1e\x2f\x9a\x94\x68\x4e\xa6\x33\x26\x38\x4a\xe7\xc5\x5c\x49\xe5\xb4\x5c\x66\x8d\xed\x96\x99\xd9\xea\x69\x77\x1a\x15\x6f\x73\x32\xc6\xdd\x00\xd1\xdf\xf4\x3f\x98\x3e\xfa\x23\xcd\xd2\x31\xf6\xbd\x4e\xfc\x84\x2f\x6b\xde\x27\x7e\xc2\x97\x6d\x5f\x28\x42\x4d\x0e\x0e\x59\x0d\xfb\x9a\x3d\xc5\x73\x3c\x26\xf3\x28\xe9\xe9\x00\xee\x0b\x31\xf3\x12\xf5\x6b\x13\xb1\xe6\x4a\xf3\xae\x69\xd9\x57\xf5\xdd\x93\xf4\x4d\xa9\xf6\x9e\x5e\x7f\x4b\x7a\xe5\x42\x8c\x43\xb0\x70\x5f\x2a\x62\xff\x70\x6a\xf5\x8a\x36\xad\xe9\xf4\xb3\x29\xce\xf0\xf4\x35\x43\x86\x69\xa4\xcc\xf2\x73\xff\x8b\xd4\xe4\x7d\x1e\xe8\xdb\xdf\xba\x38\x4d\x2b\x0d\x96\x09\x20\x3d\x5a\x64\x02\x7f\x26\x80\x7c\xb3\x41\xd3\x35\x5c\xc0\xeb\x30\x7f\xf5\x0e\x94\xb7\x0d\x1b\x4a\x28\xeb\x3e\x0f\x80\xa4\xfc\x85\x20\x4b\x41\x4e\xa3\xc2\x0f\x37\x8d\x0a\x03\x0a\xc8\x57\x03\x55\xa2\x9d\x96\xaf\x4a\x08\xe3\x35\x2f\xb8\xfe\x54\x56\x9c\xa4\x3f\xaf\x4c\x4a\x22\xca\xcc\x4d\x48\x8a\x07\xbc\xa9\xa5\x2c\x19\x7f\x69\x15\xf2\xb2\x2b\xb6\xdc\xae\xe8\x81\x73\x54\xa0\x9c\x06\x7a\xf3\x41\xb9\x73\xe6\x81\x52\x94\x27\x32\x5b\x90\x5f\x25\x68\x75\x93\x15\x84\x28\xe3\xb9\x2c\xe7\xcb\x24\x2a\xc9\x39\xfe\x29\x2a\x4e\x0a\x78\x97\x56\x55\x95\x03\x6b\xd5\x35\x6d\xac\x61\x2a\xcb\x89\xc1\x9b\xa6\x06\x02\x2e\xc9\xa6\xb6\xe5\x9e\xca\xd0\xa2\xd1\x38\xaa\x25\xd0\x0f\x79\x15\x4b\x9e\xd7\xad\x14\xb6\x49\x8b\xc4\x5b\x6a\xb5\x00\x60\x76\xdb\x93\x3c\x48\xdd\xb5\x54\x0e\x15\xb6\xa0\x71\xb3\x26\xdd\x3e\x05\x6a\x90\x06\x2a\xc3\x21\x92\xae\x7d\xc0\xc7\x1d\x3f\xbd\x22\xc4\x9a\xa2\xf3\xf3\x8a\xcc\x49\xe9\x99\x42\x13\x80\xe3\x4a\x26\x56\xcc\xbb\x91\x6f\x94\x29\xc8\xaf\x3e\x26\xa8\x32\x0d\xe8\x92\xcc\x71\x51\x46\xf3\x45\x65\x11\x09\xa1\xd6\x15\xcb\x48\xab\x56\xae\x91\x5d\x55\xad\x3c\x1a\x6b\x9d\x89\xc9\x64\x42\xc6\xcb\x04\x9e\x67\xb8\x3c\xd4\x06\x32\x07\x92\x95\x51\xf2\xbc\x4d\x05\x16\xa4\x2e\x24\x99\x6b\x86\x83\xab\x65\x6e\xae\x1c\x37\xdb\x15\x41\x48\x89\xe7\x7d\xfb\x61\x96\x63\x1d\x07\x50\xee\x3d\xa4\xb1\xbe\x7c\x9b\x39\x2b\xd8\xb4\xd0\x46\xec\x68\xdd\x62\x99\x25\xd9\xd4\xbb\x9e\xf4\xb5\xed\x5b\x4d\x49\x36\x55\x5a\x14\x77\x49\x41\xbd\xc6\xb2\xd2\x2b\xd4\x17\x95\xa6\xbd\x26\x13\xfa\x65\xf0\x75\x39\x4e\x2b\xc3\x43\x63\x76\xd1\x3d\xbd\x4e\x87\x71\x1b\x15\xb7\xd8\x01\xbc\x95\x18\x4d\x24\xd9\xd4\x53\xb5\x48\xad\xa8\x52\x16\x32\x85\x4e\xd0\xc8\x37\x1f\x90\x2e\x66\xa4\xa0\x7c\x71\x91\x15\xe5\x0d\x4e\x48\x6f\xb3\xa2\x5e\x30\x70\x23\xe9\xd4\xf2\x4f\xb7\x52\x7d\xa2\x69\x27\x75\xe6\x49\xbf\x07\x8b\xe8\x12\xcc\xe3\xf7\x0d\x6d\x87\x9e\xc5\x91\x0c\x49\x65\x99\x78\xc5\x78\x91\xa9\xc3\x5e\x64\xf9\xa7\x0f\xd9\xdb\x3c\x3b\xc7\xd5\x65\x34\x20\xbd\xec\x22\x27\x59\x4e\x34\x8e\xe2\x14\x14\x10\x9a\x83\xf8\x89\x1e\x3f\xc8\x30\x88\x65\xac\x85\x75\x92\x79\xcb\xd0\x79\x8a\x96\x8e\xf6\x8d\xaf\xa7\xe8\x54\xfb\x3c\x43\xa1\xbc\x8d\xbe\x56\xad\x32\x55\x2a\xd3\xaa\x26\x49\x76\x01\xaf\x03\xc4\x31\xb6\xae\xfa\x7a\x6b\x76\x16\x01\x8f\x12\x13\xca\xd2\xe4\x92\xb9\xf5\x2f\x0d\x23\x7b\x61\xe8\xce\x0c\xda\x7d\xef\x33\x84\xb5\x3b\x0a\xed\xb7\x17\xba\x9d\xbb\x7d\xe2\xa2\x7d\x6c\xc5\xbb\xa4\x5a\x1f\xe8\x9f\x1b\x1b\x7a\xb9\x59\x13\xa5\xfb\xc9\xda\x54\x0f\xd7\x13\x36\xa7\x6b\xc0\x2f\xfe\xbc\x20\xf9\xa5\x67\xc5\x6b\xb9\x3a\xb9\x15\xcc\x0b\x88\x17\x9a\xe6\x55\x2d\x01\x0b\xd4\xb3\x00\x80\xb2\x7d\xb2\x84\x05\xd1\xdf\xf3\xad\xca\x77\xd1\x85\x20\x19\x9e\xe2\x05\xd3\xaa\x3f\x28\xc6\x84\xd8\xcb\x57\x94\xd1\x77\xdb\xbf\x17\x1c\x71\x12\x4e\xc5\xd1\x50\xab\x42\x35\x00\x6e\x71\x21\x96\x95\x8f\x39\x0c\x87\xab\xac\x08\x58\x9b\xfa\x6a\xac\x5c\x8c\x6a\xb9\xdd\x62\x25\x59\xda\x5c\x86\xa2\x76\xf4\x2f\x99\xaa\xad\x80\xf1\x05\xbd\xd0\xad\x35\x08\xbb\x8d\x49\xf1\x05\x5c\xcc\xf4\xcc\x90\xc8\xa0\xb1\x1e\x45\xe9\x80\x14\xff\x88\x12\x12\xf7\x20\x64\x01\x4f\x79\x4e\x72\x3c\x2e\x7b\x3e\x75\x35\xf7\x0c\x05\x80\xbc\xc6\x5e\xdf\xd1\x85\xeb\x82\x8e\x8a\x24\x23\x7a\xe0\xa9\xd6\x70\x3e\xe6\xa9\xa8\x45\x15\xbc\x67\x66\x4d\xfc\xda\xd5\x32\x05\xe0\xee\xa1\x05\x6c\x57\x84\x76\x56\x0b\xfd\xfd\x65\x3a\x26\xa9\x5f\x5a\xe1\xfe\x98\xb5\x0b\x84\x75\x33\x09\xb9\xee\x69\x0c\xf1\x0c\x9c\xd9\x80\x2d\x19\x49\xa7\x70\xd4\xf0\x9e\xf0\x5c\x30\xd3\x25\x10\xf7\xce\xd3\x50\x81\x0e\x65\x96\x9f\x91\xe9\x0c\x17\x4d\xe5\x75\x28\x8d\x76\x78\xee\xa7\x34\xbb\x48\xdf\x97\x51\x69\x47\xea\xb7\x73\xab\x1b\xd0\xab\xd8\xb3\x6b\x58\x2c\x93\x04\xc7\x4d\x55\xe8\x50\x15\x87\x4e\xe5\x25\xa8\xc2\x11\x7c\xd3\x3d\x58\xd8\x08\x11\xa8\x7a\x6a\x2a\x68\x28\x69\x5c\x91\x84\x9e\x34\x0d\xd6\x77\x36\x08\xab\xb3\xb4\x92\x36\x2f\x09\xfd\xc9\x5a\x09\x63\xe7\x0b\x3d\x69\x0c\xb6\xea\x16\x35\xac\xcc\xd1\xcb\xf9\x07\x54\x9d\x57\x51\xd6\x56\x4b\x79\xaa\xb0\x41\x8c\xde\x1b\x87\xff\xd0\x9b\xaa\xc3\xeb\xa7\x9a\xd0\x93\xa6\xc3\x5a\x68\xf4\x24\xea\xd0\x36\x17\x0a\x2b\xd2\x19\xf7\x32\x4c\x6f\xd8\x7d\x42\x27\xdc\x7a\x52\xe5\x50\x86\xb2\xf0\x4e\xb8\xb3\x73\x7d\x16\xec\x6c\xdd\x3b\x23\xb8\xb7\xbd\xf9\xaf\xb1\xbd\xe1\x94\x7e\x17\x51\x45\x56\x73\xc1\xde\xd2\xe0\x86\x05\x3d\x31\x2d\x69\x58\xda\x57\xf0\xe5\xde\xde\xfb\x7a\x94\x24\x43\x2b\x3e\x21\x3c\xab\xb4\xa3\x9b\xb8\x3e\xd9\x85\x6d\xb0\x1b\xc8\xa9\xc6\x17\xbb\x2f\x92\xd3\x47\xb6\xb9\x71\x5f\xe1\x7a\x08\xd3\xd5\xfd\x78\xab\x4a\xf9\xee\xa0\xd7\xca\x92\x6e\x57\x2d\x84\x3f\x8b\xe0\x58\x07\x75\x8a\x6f\x1d\x46\x84\x38\xe5\x20\xfc\x53\x87\xb8\x13\x3f\xf2\x74\x7f\xb0\x27\xc3\xf0\x38\x08\xd7\xc0\xea\x25\x8e\x7e\xb2\xc8\xa7\x85\x2e\xeb\xde\x20\x70\xaf\x90\xf1\x55\x94\x33\x78\xce\x0f\xbc\x9e\xbd\x1d\xc9\xa7\x05\x73\x16\xbf\xce\x85\xb7\x76\x1d\xd6\x85\xb1\xda\x4e\xeb\xdd\xfb\xc1\x21\x25\x91\xa3\xc7\x6c\xe3\x8f\xd4\xdc\xc1\xf9\xc7\x66\x3f\x41\xaf\x11\x13\x7b\x0a\x0f\x2d\x11\x51\x17\x99\x4d\x8f\x25\xeb\x0b\x3f\x44\x0a\x34\xce\xf2\xdc\xf5\x0c\x08\x27\xa0\xa8\xc4\x07\xf9\xb4\xf0\x05\x6b\x53\xd1\xa2\x1f\xa2\xbf\xc1\x09\xaa\x40\x5f\xe0\xfc\x74\x4d\xdb\x23\x25\x37\xcd\x37\x9c\x07\x7a\xa6\x4a\x6f\xa7\x72\x8e\xd4\x21\x97\x41\x01\x8a\x1c\x13\x81\x48\x21\x9e\x9e\xf1\x99\x3d\x37\x28\x84\x0c\xaf\x1c\xb0\x79\x82\x2f\x38\x15\x84\x89\x6e\xb5\x11\xbc\xe0\xca\xa3\x4b\xf1\x1e\x89\xcf\xdd\x7a\xcf\x89\xe1\x17\xf5\xa5\x67\x69\x7a\x2e\x76\x54\xf4\xd6\x8d\x01\x07\x67\x5e\x62\x6b\xe1\xd5\xa5\x84\x51\xc6\x2a\x60\x3d\x86\x84\xb3\xba\xc0\x8e\x20\xc4\xf5\xfd\xfd\x2a\x42\x36\x1f\xa0\xd0\xb3\x2f\x0f\xa3\x55\x13\xec\xaa\xe7\xbc\x0b\xaf\xf3\xa4\x2a\x94\x32\x60\x92\xa2\x93\x8a\x11\x0b\x20\x7b\xcb\xc0\x3c\xe4\xe5\x6c\x1a\xca\x83\x72\x85\x57\xd5\x28\x91\xad\x2a\x0b\x9d\x5a\xca\x93\xed\xd7\x92\x9d\x11\x3d\x72\x75\x86\xb1\x2a\xbf\x30\x83\x3e\x56\x44\x95\xbc\x56\xdc\x5c\x5f\x3e\x3d\x4f\x88\xc7\x32\xf3\x3b\x70\x37\x42\x40\xee\xa3\x0a\xe7\xec\x3e\x1f\xdf\xfc\xf9\x81\x36\x5c\x23\xb6\x64\x8d\x49\x4c\x45\xf0\x11\x81\xfa\x9b\x05\x59\xf0\x16\xaf\x9d\xf7\x1b\x85\x5a\xe0\x5e\xa2\x37\x03\xf4\x44\x68\x83\x6a\x9a\x58\xa6\x8b\x68\xfc\xe9\x98\xe9\xa1\x0d\xa3\x2f\x48\x32\x74\x43\x66\x92\xea\x82\xe9\x2d\x47\x54\xc5\x7e\x48\xd2\xdb\x47\xdb\xe8\xa9\x48\x14\x8e\xac\x91\x38\x07\xaa\x97\xdd\xd2\xfd\x74\x95\x1f\x6b\x5d\xc8\x09\x78\x71\x73\x46\xb9\x2e\x45\xf7\xc2\x2b\x43\x98\x9d\x6e\x9e\xa1\xd0\xe7\x6b\xf9\x10\x22\xf8\x46\x5a\xd0\x64\x81\x2c\x3b\x2c\x73\x94\x24\xfa\xe2\x1e\x0c\x06\x62\x7d\x1f\xda\x65\xad\xcd\xc7\xf1\x72\x72\xc4\xb6\x3b\x88\xce\x2a\x40\xe9\x6e\x14\xc9\x1a\x02\x33\xfa\xba\x48\x66\x2e\xbd\xe0\x09\x99\x38\x74\x45\xc6\x23\x9f\x28\x8d\x4d\x57\x18\x02\x8c\x85\x2f\x66\x27\x23\x5a\x07\x8b\xfd\x46\xc1\x39\xda\xbc\xb4\xcb\x67\x15\xc2\xaf\x36\x51\x2d\xf4\xaa\x2a\xc4\xed\x2a\xf1\x6b\xfd\xfb\xa6\x90\xc1\x2c\x23\x3a\xb9\xc7\xc0\x41\x46\xc9\x7f\xdc\xf3\xad\x21\x16\xea\xec\x07\xbc\xf7\x9a\xd2\x97\x5e\x44\x17\x7f\xec\x62\x4a\xa1\xcf\x3c\xee\x0a\x2e\x2d\xe0\xd4\xb5\xfc\xba\xef\x45\xa5\xd2\xd1\x4a\xc6\x87\xcb\x19\x43\x02\xaf\x3a\x30\xba\xe6\xbe\x9f\x82\x52\xfa\x12\xee\x19\xeb\x41\xf3\x19\xed\xbc\x78\x6c\xd3\x60\xe0\xfa\x29\x71\x79\x80\xe6\xa5\x44\x18\xc6\x1b\x6f\xd9\x03\x66\x5c\xb1\x67\xfa\x86\x65\x9d\xc6\xb1\xe3\x07\xbb\xcc\x2f\xad\xe7\x5f\x1a\x28\xbc\xf8\xaa\x1e\x2f\x32\x9e\xa8\x8d\xe1\x8d\x70\xcf\xf1\x34\xc2\x28\x7e\x1f\x61\xaf\x8f\x13\xbb\xf3\xa2\x75\x4d\x92\xa9\xdd\x28\xda\x9c\x2b\xed\x6d\xc3\x2c\xd2\xb8\x2b\x58\x2d\xfc\xa9\x96\x5a\xe3\x9a\x11\x24\xc5\x01\xb9\x45\xe2\x0f\x68\x13\x0e\x35\xc6\x59\xd3\x95\x0e\xf5\xc0\x8f\x51\xca\x9e\xdb\xa6\x31\x77\xc9\x07\x91\x3b\xd3\x47\xe2\xa0\xea\x84\x30\x6d\x58\xae\x46\xd8\x2e\xba\x6e\xac\x79\xe8\x99\xb7\x78\xbc\xba\x46\xf0\xf6\xfe\xd2\x71\x51\x92\x79\x54\xe2\x9f\x22\x50\x20\x36\x51\x95\x06\xde\x44\x51\x7a\xcd\x77\x41\x4d\x5f\x9f\x3a\xda\xcd\x90\x36\xae\xa6\xd9\xf1\x80\x56\xcd\xcc\x3b\xd1\x0c\x16\x11\xa0\x58\xcc\x78\xae\x0b\xe4\xf2\x81\x3f\x3a\xbc\xbb\xb3\xda\xd5\x34\xcd\x55\x53\x0c\xf9\x9b\xcd\x53\x2b\xc4\x8b\x1b\x5d\xbe\x32\x6b\x02\xae\x7b\xa5\xe6\x5b\xc4\x91\xd3\x8b\x0a\x3c\x6b\x64\x5f\x8b\xb0\xdf\x36\xa8\x9c\xac\xff\x46\x71\xe5\x64\xa1\x55\x07\xf9\x35\x83\xcc\x29\x1d\x0d\x1d\x60\xbe\x18\x0b\xef\x45\x05\x3b\x35\x37\x31\x22\x0e\x5d\x6d\x95\x52\x33\x2e\x5e\xf6\x8f\xcd\x95\x90\x11\x6c\x43\x80\xe9\x62\x8a\x11\x33\x42\x9f\x13\xd7\xdd\x97\xa5\x71\x7d\x0a\x3e\x44\x3f\xd2\x1e\x77\x51\xc8\x3e\xac\x9d\xa4\x1b\x38\xc2\x4b\xa8\xdc\x6c\xc9\x3c\xe9\x20\x8c\x0f\xe7\x4c\x65\xb1\x8e\x0b\xf7\x93\x8c\x41\x36\x12\x83\x88\x8e\x51\xb5\xfd\xc8\xb8\x33\xf5\x5b\x8f\x27\x44\x8c\x3e\xc1\xa5\x21\xe8\xac\x9b\xd8\x51\xd6\x38\xb0\xcd\x97\xba\x0c\x25\x5c\x2c\xa8\xb4\xaa\xad\xc2\x42\xe7\x20\x5a\x2c\x92\x4b\xee\xf0\xa5\x15\x61\xf5\x6d\x33\x36\xb6\x05\x58\xcd\xd0\xc4\x1b\xd5\xdd\x30\x0f\x3c\x8c\x8a\x62\x3c\x2a\x92\xca\xad\x43\xa8\x78\x26\xec\x6b\x45\x51\x11\xe9\x6a\xc5\xeb\x1e\x6f\x2a\xc1\xf9\x61\x53\x61\xb8\x0a\xd0\x95\x9a\xbd\x93\x5f\x55\xdc\x14\x91\xd8\x48\x54\x52\x65\x31\xb5\x5b\x0b\x4f\x2d\xf4\xf3\x4f\x19\x42\x46\x94\x05\x02\x27\xf9\x78\x99\x44\xf9\xfa\xfa\xfa\x7a\x7d\xe0\x18\x41\x41\x7b\x77\x12\x3a\x86\x69\x7f\x3b\xe1\xf6\x63\xbf\x1f\x8e\xed\xfb\xdb\xff\xfb\xdb\xff\xbf\xf6\xed\x3f\xbf\xfa\xa7\xb0\x22\xb4\x8f\x3f\x20\xc1\xef\x16\x6a\xc0\x67\x59\x50\x6f\x08\xb0\x36\x1c\x42\xe8\xa2\x28\xa7\xa4\x4c\x77\xb0\x65\x61\x0e\x91\x12\x5c\x4c\x26\x13\x9c\xe3\xb4\x44\x38\x3d\x2f\xa0\xd0\x28\xcf\x2e\x0a\x9c\xaf\x69\x7e\x19\x2f\x48\x1a\x67\x17\xa0\xb1\xd0\x1c\xf6\xa3\x07\x0f\x78\xce\xe0\x9f\xaf\x5f\xbd\x2c\xcb\x05\x77\xf9\xc9\xb8\xa6\x99\x86\xf6\xfd\xb0\xc0\xfa\xb8\xbf\x79\x32\x4d\x33\xca\x08\x12\x92\x62\xda\x93\x34\x8b\xf1\x9a\xe6\xe4\xc9\xa9\x51\x0e\xfc\xf3\x3c\xa1\x23\xe3\x1b\x5b\xb7\xdf\xb6\x91\x6b\x86\xc9\x7f\xbe\x7c\xb7\x6d\x54\x37\xcb\xb7\xbb\xfd\xca\x52\x42\x72\xa0\x2d\xbc\x15\xc8\x74\x4d\x22\x40\x7e\xa2\xa2\x3d\x78\x39\x64\x3e\x91\x69\x2f\xa5\x01\x84\x51\x5e\xdf\xf2\x67\x59\x51\x06\xa8\x24\x73\x9c\x2d\xcb\x80\x56\x98\x07\xa0\x64\xbe\xc8\x72\xee\x76\x02\x36\x13\x0a\x87\xf6\x11\xfc\x77\x75\x85\xba\x9c\xd8\x93\x6c\x1c\x25\x34\x31\x7c\xf2\xcd\xee\x37\x10\x1f\x94\xed\x3d\xac\x42\xba\x13\xf2\x5f\x57\x57\x68\x53\x66\xd3\x66\xd0\x3e\xb4\x26\xd3\x44\xa3\x68\x5f\xb6\xdf\x28\x3c\x2d\x72\xbc\x80\x80\x5b\xf8\xc2\x9a\x32\x4b\x76\xe2\x80\xef\xb4\xb3\x8c\x16\x5e\x1e\x47\xe9\x35\xdc\xb1\xb2\xf8\xf2\x6c\xce\x15\x96\xb9\x77\x3d\xed\xc0\x67\xb6\x65\xb8\x70\xd1\x31\xad\xc9\x5d\x66\x07\xcc\x8b\x40\x5a\x3d\x43\x35\xbb\x41\x61\x84\x44\x79\x18\xdc\x00\xaa\x6c\x84\x94\x78\x05\x43\x7e\xf9\x6e\x5b\x85\xe7\x64\x92\x96\x86\x79\x6d\x22\x28\x8c\xe1\xc3\xcc\xaa\xc8\x18\x0f\xab\x12\xe4\x61\x51\x6b\xb6\xc0\x69\xaf\xfb\xf6\xf8\xfd\x07\x11\x51\x90\x11\x0e\xeb\xdc\xde\x9a\xe6\x10\x0d\xe6\xf6\xc1\x03\x73\x52\x8d\x43\xdf\x12\x0c\x6a\xba\xcf\xa2\x82\x8c\x51\x17\x6d\x40\x17\x9e\x2d\x29\x7b\xd0\xaa\xd8\x40\xdd\x50\x5e\x15\xca\x7a\x06\x65\xc6\x1f\xad\x75\x47\x51\x81\x1f\xef\x76\xad\xf1\x2b\x77\xc0\x2f\x71\x14\xe3\xbc\xd7\x3d\x00\xbe\x4a\x7e\x8d\xd8\x69\x0b\xda\x67\x23\xac\x29\x44\xe5\x63\x9c\x96\x8f\xe8\x41\xbb\x1b\xa0\x2e\x95\xfc\xc9\x18\xaa\x18\xfe\x52\x08\xb5\xa3\xbc\xb1\xe2\x53\xd6\x40\xae\x2c\x70\xc4\x65\x3a\xd6\x0e\xd5\xb6\x26\xd9\x77\xf1\xbc\xd0\xae\xaf\xfd\x21\x82\xeb\x48\xaf\xb0\x43\xc6\x09\x5d\x9a\x4d\x72\x82\x66\x4c\x5a\xe5\x31\x67\x81\x1e\x95\x76\x5f\x61\x2e\x8d\x7b\xf0\x20\x82\x05\x03\x21\x93\x4b\x59\x3f\x47\xb2\x50\xe4\xeb\xde\x7e\x7d\xd6\x00\x87\x59\x9a\x62\xfe\x6c\x41\x50\x98\xa2\x44\xe3\x72\x51\xb4\x2e\xfc\xee\x7f\xc0\x9f\x4b\xa7\x83\x1c\x56\x7b\xad\xc1\xad\xe3\xcd\x6e\xd5\x75\xe9\x1d\xaf\xbf\xe7\x6b\x50\x5f\x25\xed\x43\xc0\x02\x0d\x44\x0d\x44\x70\x20\x39\x4e\x0d\x25\xf0\xac\x1f\x9d\xa0\x0b\xa8\xcc\xc9\x74\x8a\x73\x16\x29\x86\xce\x3e\x88\x2d\xd2\xed\x23\xc5\x41\x13\xc1\x40\x0f\x7c\x54\x63\x06\x7c\x6d\x43\x3f\x60\xbc\xb2\x67\x70\x93\x14\x5c\xf4\x16\x65\x54\xe2\xf1\x2c\x4a\xa7\x7e\x05\x02\x33\xef\x17\x88\x8f\xe2\x4b\x30\xac\x87\x1b\xe1\x5d\xca\x61\x6c\x96\xb7\x6e\x06\x6c\x6d\x41\x31\x0a\x50\xdc\x2a\x69\x91\x80\xec\xcb\xac\x06\x8a\x82\x33\x99\xf7\xd6\x4a\xde\x58\xad\x48\x5b\x48\xbf\xda\xb2\x2f\xb6\x8c\x96\xe9\x59\xf0\xda\x42\xb1\xda\x08\x5c\xcc\x9a\x95\x15\x03\xb5\xf4\x3e\xb0\x52\x3d\x7d\xf3\xe0\x0b\xf9\x6e\x39\x80\xdd\x85\x3a\x26\xc0\x57\x9a\xbe\xae\xd4\x65\x79\x72\x49\xe9\x9d\xbd\x2d\x85\xc5\x45\xa9\xba\x62\x6d\x91\x82\xd7\x8f\x4d\x66\x2a\x85\x00\xa1\xe0\xb4\x85\x01\x7a\x7e\x48\xbb\x25\x9a\x44\x24\xc1\xf1\x00\x1d\xd3\x73\xda\x05\xa1\x67\x8f\x08\x82\x3b\x55\xaf\x26\xad\x4d\xcf\x5c\x28\x7c\x4a\x7d\x86\x0c\x22\x40\xe2\x10\x7d\x27\xff\x84\xbe\x8f\xee\x3e\xf9\x62\x1c\xa2\xee\xf6\x60\x53\x2a\x0f\x85\xfe\xb1\x9b\xe2\xf2\x63\x42\x8a\x12\xa7\x24\x9d\xca\x6c\xa9\x3d\x3c\x33\x0c\xba\x84\x82\x2b\x67\x91\xaa\x5c\xf2\x15\x56\x85\x74\x90\x6a\x12\x1c\x75\x81\x3e\x74\xa1\x2a\x30\x4e\xfb\x54\xcc\xed\x84\x4f\xe8\x2f\x43\x7e\xee\x84\x5b\xdf\xd2\x93\xff\xce\xfd\xc9\xff\xfe\xe4\xff\x17\x3f\xf9\x2b\xc3\x7f\x78\xb4\x78\x47\x46\xff\xd2\x90\x53\x3f\x55\x8e\xc8\x94\xd9\xe0\x0e\x7e\x61\x27\x74\x76\x0f\x12\xbf\xc2\x13\x73\x43\x90\x21\xfb\x2e\xb5\x87\x73\xc6\xc6\xc9\x20\x18\xbb\xb8\x98\xd1\xde\xf7\x4c\x03\xad\xef\x59\x61\xf4\x10\x6d\xbb\x2f\xef\xc0\xe2\x8f\x4a\xf1\xe6\xfb\x43\x54\x1b\x37\xff\x2d\x3f\xd5\x45\x29\x3a\x7a\x76\xf0\x86\x4f\x72\x8c\xbe\xfb\x16\x8d\xb3\xf9\x62\xc9\xc3\x65\x8c\x2e\xd1\x3c\x3b\x27\xe9\x54\x0b\x0a\xb5\x8b\xc6\xb3\x28\x87\xbd\x80\xdd\xcc\xc6\xcc\x94\x4a\x98\xab\x0b\xe8\x04\xb3\x47\x0b\x65\x46\x1b\x64\xb8\x2a\x50\xef\x00\xed\xa3\xad\xcd\x00\x3d\xa3\xff\x6f\x05\x68\x30\x18\x04\xe8\xff\xd0\x3e\xda\xf9\xa6\x4f\x0f\x3b\xa8\x58\xe0\x31\x99\x10\xb6\x90\x8e\xde\x1f\x6f\xed\x3c\xde\x7a\x6c\x9b\x98\x91\x22\x83\x74\x3e\x0e\xd7\x39\xe8\x35\x7b\x13\x4b\x3b\x42\x07\x68\x5e\xad\xe9\x37\xcb\x5c\x92\x8a\x05\x18\x77\x6d\x60\xd6\x6f\xc6\xe7\x1f\x45\xa9\x3e\x8f\x74\x44\xdd\x83\xee\x80\xa2\xe5\x30\x8b\xf1\x41\xd9\xdb\xd4\xb4\xd6\x74\x6c\xdd\xff\x73\xb2\x19\x03\x64\xef\x76\x81\x58\xcb\xec\x64\xb1\xc0\xf9\x61\x54\x28\x55\xb6\x96\x5d\x2c\x47\x45\x99\xf7\x76\xfb\xe2\x45\x2e\x4f\xd8\x0c\x76\xad\x1b\x33\x96\xbb\x48\x48\xd9\xeb\x76\xfb\xe6\x63\xe5\xb4\x6f\x5a\x57\x8d\xb3\x98\x0e\x2e\xf5\x75\x5e\xc8\x87\x00\xf3\xc3\x3e\x3a\xa0\x02\x21\x7c\x7c\xbf\x8f\xfe\xaf\xef\xb8\x72\xf7\xcc\x2c\x9f\x58\x03\x52\xfa\x06\x8d\x31\x7a\x84\x0e\xd0\x06\xda\xda\xd4\xec\x8c\x7c\xee\xcd\x45\x08\x49\xdb\x86\xe9\xba\x3f\xf8\x25\x23\x29\x1d\xa6\x6d\xa9\x38\x5e\x82\xe3\x54\x98\xe2\xd7\xc7\xcf\x29\x61\x6f\x6d\x0a\xa6\xc4\x2d\xfc\x80\xf2\x3d\x14\xf7\xed\xe6\xe3\x5d\x9b\xe0\xe6\x59\xfc\xdd\xb7\x5b\x9b\x55\x84\x66\xd2\x97\x72\x47\xcb\xa8\x89\x17\xae\xa5\xa2\x1c\xcf\x23\x92\x32\xdd\x11\xcd\x53\x77\x8f\xdc\xb9\x8b\xc9\x1e\x38\xb0\xb2\x5b\xde\xee\x5b\x6e\x6b\x80\x59\x09\x30\x69\xf1\xfa\x9d\x61\x22\xa7\x9a\x04\x59\xfb\x28\x2d\x99\x47\x9c\x00\x6d\x6d\xf6\xd1\xff\x9f\x62\x6d\xc3\xa9\x85\x39\xc5\xe1\xe6\xe7\xbe\x17\xb8\xb2\x2e\x59\x52\xd5\x67\xcc\x53\xf3\x3b\x24\x66\x82\x0e\xeb\x80\x1b\xfc\xc3\x85\x3a\x24\xf0\xb7\x0e\x9c\x7d\x8a\xf9\xf2\x4f\xce\x50\x77\x5a\xec\x9f\x04\x6e\x09\xad\x96\x9c\xdb\x55\x27\x58\x68\x53\x3f\x31\xc4\x92\x59\xce\xc5\xeb\x1c\x8b\xa8\x28\xcc\x81\xcc\x71\xfa\x1e\x69\x59\x42\x8c\xa6\x43\xb8\x96\x6c\x4d\xd7\x8a\xd1\x9c\x81\x56\x63\x93\xcf\x80\xa8\x78\x26\x7c\x06\x68\x2f\x15\x78\x50\x47\xb4\xf5\x58\x63\x61\xa3\xa8\xc0\x3b\x8f\xd1\x3e\x94\x51\xea\xa1\x9d\xc7\x86\x09\x40\x1c\x63\xa6\x59\x84\x3d\xb0\xc7\x0a\x05\x68\xeb\x1b\x53\x12\x96\xfd\x7c\x36\x8a\xd2\x1e\x2b\x66\x32\x3f\x6b\x31\x73\xb7\x24\xda\xc2\x7d\x46\x87\x5e\x66\xc6\xee\x45\xa7\x0f\x81\x27\xcd\xfc\x52\xac\x68\xa6\x4c\x02\x7b\xdd\xb7\xcc\xa5\x7f\x9a\x95\x5c\x28\xfb\x9e\xfc\xd0\x99\x82\x44\xc2\xdc\xdd\x4c\x14\x52\x8b\x59\xc4\xa4\x35\xd8\xdf\x3e\x8f\x93\x65\x41\xce\x65\x04\x42\x32\x22\x09\x29\xa5\x80\x33\x8a\xd2\x4f\xc3\x51\x1e\xa5\xe3\x19\x2a\x70\x7e\x4e\xc6\x62\x03\x8c\x98\x63\xcf\xce\xf7\x43\xf2\xc3\xc0\xa6\x21\x19\x0d\xa0\x10\xbb\xd0\x04\xe7\x74\x1b\x8a\x92\x69\x96\x93\x72\x36\x47\x31\x2e\xc6\x39\x19\x31\xb6\xc4\xe5\x1f\x9c\x0e\x2e\xc8\x27\xb2\xc0\x31\x89\x40\x08\xa2\x5f\xc3\xa3\xb4\xc4\x79\x1a\xb1\xa7\x13\x1f\x9f\x45\xe9\xa7\x8f\xdc\xab\xe8\x47\x36\xaf\xff\xbf\x9f\xf8\x48\xd3\xe9\x47\x3a\xc4\x8f\xf0\x96\xe8\x63\x4c\xa6\xc4\x79\xca\x21\xa6\xc6\x47\x91\x23\xb1\xa7\x8a\x19\x10\x3e\x63\xca\xcc\xb3\xcd\xb6\xa0\xd5\x67\xf6\x8a\x1c\x59\x6c\x91\xcf\xe8\x21\xdb\xa7\xba\xff\x7c\xd1\xdd\x5b\xf3\xf2\x4c\xce\x63\x7b\xd6\xce\xdd\xd3\x2b\xd8\x40\xdd\x4d\x10\x95\xa0\x15\xdd\xdc\x85\xa2\xe3\x39\xc5\x06\xda\x47\x3d\x26\x4e\xf5\xbe\x7b\x82\x1e\xa9\x26\xfa\xe2\xd9\xc0\xa3\x6d\x6b\xbf\x95\x5e\x37\xcc\xa6\xb4\x3a\x79\x83\x0d\x6a\x2b\xce\x44\x34\x5c\x01\x61\xb3\x38\xd0\x24\x2d\x4a\x52\x2e\x4b\xe1\x1b\x97\xc4\x38\x2d\xe9\xa6\x65\xfb\x4f\x67\xb5\x1c\xa5\x31\xc9\xb1\x69\xc0\x60\xbe\xb1\x29\x02\x21\xcb\xca\x47\x36\xf0\x6a\xaa\xa3\xb5\xd4\x81\xa6\x3a\xaa\xad\xce\x2a\xbc\xc8\xec\x89\xd7\x5f\xae\x79\x04\x36\x39\x43\xf7\xc5\x87\x97\x74\x1e\xc4\xeb\x16\x1d\x03\x5a\xaa\xec\x5b\xdf\xe2\xd7\x59\x1d\xbf\x16\x4f\xe9\x18\x72\x79\x90\x65\x52\xb0\x97\x72\x3a\x1f\x77\xe4\x4e\xf0\xa0\x52\x29\x6f\xca\xbd\xc8\xa3\xf8\x10\x0a\x0f\xf6\x9c\x8e\xb5\x24\x85\xce\x23\xcd\x3f\x4b\xad\x9c\x40\x74\x3f\x2f\x84\x91\x95\x2e\xfc\x29\x27\x2f\x8d\xba\x72\x89\x05\xe8\x7a\xe5\xeb\x41\x7d\xcc\xca\xb9\xbe\x77\x54\x01\xca\x3c\x5a\x1b\x18\x9b\xae\xad\x71\x47\x69\x51\xc2\xf0\x3f\xff\x7c\x71\xba\xf9\xe8\xbb\xb3\x2f\xdb\xd7\xbd\x17\x1f\x5e\xd2\xdf\x07\x8f\xfe\xef\xec\xcb\xd6\xce\xf5\x95\xfc\xd8\xd9\x0c\x76\xb6\xae\xfb\xff\x33\x1c\x94\xa0\x04\x95\x1b\xb8\xf1\x2e\xaf\x8a\x31\x68\xe0\xec\x79\xde\xd6\x8a\x08\xe3\x4f\x30\xe1\xf4\xef\x45\xdb\x73\xb5\x04\xef\x06\x6f\xcf\xdd\x95\x64\x21\x4e\x0d\x4a\x7f\xdc\xb3\xb3\x0b\xe1\xda\xfd\x79\xdf\xdc\x70\xd8\x13\x44\xd2\x8a\x81\x1b\xdc\xe7\x6e\x86\xee\x65\x23\xad\x06\xbf\xbd\xd9\xca\x6a\x93\x89\x94\x74\xa4\xc5\x72\x4e\x01\x4f\x0a\x7e\x7c\x98\x67\xf1\xa3\xef\xbe\x7d\xb4\xb5\x29\xb3\xe1\x8c\x0b\xbd\x1b\x67\x09\xea\x1d\xbd\x3f\x1e\x1e\xbd\x38\x44\xf4\xdc\x10\x6e\x6f\x6e\xee\xf4\x6d\x9e\xac\x55\xeb\x9e\x42\xb5\x5c\x67\xe0\x22\xaf\xe5\xb0\xf9\x99\x70\x3b\x40\xdb\xed\x6c\x55\x75\xa6\x6a\x6c\x29\x08\x4f\x07\xe8\x9f\xef\x5e\xfc\xe4\x38\x12\x94\x05\xfc\xa3\xa9\xac\xd1\x9d\x54\x0d\xb2\x69\x78\x8a\x00\x7a\xe0\x65\xce\x19\xf2\xb7\x01\xda\xed\xa3\x10\x75\xbb\xad\xc6\x3d\x4e\x08\x3c\x24\x93\x1d\x04\xe5\x13\x49\xed\xf1\x51\x2c\xfc\x74\xf0\x8f\xe3\x1f\xff\x75\xfc\xee\x7f\xed\x59\x85\x3a\x2a\xe6\xd4\xae\xdf\x3b\xb9\x0c\xe8\xd6\x63\xdf\xda\x5a\x7d\xe4\x7c\x35\xf9\xcf\x25\xee\xc1\xc3\x1d\x9a\x53\x81\x33\xbc\xc8\x73\x0e\xd1\xef\x9d\xf8\xe0\x7c\x0e\xc8\x8c\x43\x87\x3b\xe0\x5d\xed\x10\x5b\x79\x94\x11\xe7\x0f\x79\x4a\x31\x4e\xa8\xec\x8c\x62\x9e\x67\xb6\x1e\xf7\x03\xb4\xbd\x29\x5d\x9c\x19\x52\x9e\x40\xaf\x35\x48\x51\xb8\xdd\x02\xad\xf0\x4a\x75\x04\x59\x4c\xa9\xaf\xeb\x15\x3b\xa1\xf9\x79\x7d\x16\xec\xec\xde\xab\xf1\xef\xd5\xf8\x7f\x71\x35\x3e\x57\xe1\x2f\xc6\xf5\xf6\x7b\xb7\xb0\xb8\xeb\xa8\x48\x73\x9d\xbd\x95\x22\x64\x35\xd8\xe9\x31\x3d\xd3\x62\xec\xb5\x04\x5b\x44\xe5\x2c\x40\x29\x36\xac\xbf\x3f\x82\xe6\xc2\x79\x78\x2a\xae\xaa\xf5\x18\xbd\xc2\x6b\x01\xb7\xd7\x01\x1b\x1f\xfa\x1f\x4b\x55\x59\x63\x79\xc3\x0b\x5c\xb1\x14\x09\xbd\x2f\x14\x3a\x54\xe5\xa5\x2b\x4a\xab\xd8\x20\x4b\x7b\x5d\x18\x55\x57\x8f\x69\xd9\x37\xec\xa7\x8b\x8c\x32\x31\xf6\x96\xf0\xe8\xed\x21\x52\xf7\xca\xec\x85\x61\x37\x40\x7a\x28\xf8\x8f\x8c\x0d\xf2\x0b\xef\x9e\xed\x0c\xd3\xdb\x83\x34\xd6\xdb\xd7\x9a\xaf\xac\x0c\xad\xc9\x37\x06\xaf\x8e\xde\x7f\x78\xf1\x06\x56\xd0\xe1\xf1\x9b\x37\x2f\x0e\x3f\x1c\x1d\xbf\x41\xef\x5e\xbc\x7f\x7b\xfc\xe6\xfd\x8b\xf7\x95\xad\xc6\x51\x19\xe9\xcd\xd2\x6f\x7d\x73\x1a\x3e\xe4\x66\x84\xf3\xe8\xf3\x38\x9b\x2f\x12\xfc\x99\x94\x97\x21\x7a\x0c\x94\x65\xf5\x10\x74\xa1\xd2\x0e\x81\x56\xa5\xf6\x9b\xbe\x27\xa0\x09\xb7\x39\xf8\x62\xc6\x23\x86\x83\x5f\x6c\xdb\x4e\xf0\xee\xb0\x38\xcd\xc0\x5f\x62\x74\x31\x23\xe3\x19\x9a\x47\xe5\x78\xc6\xc5\x57\xb6\x09\x51\x86\x16\x1b\xe5\x3c\xf1\x03\xa0\x69\x7f\xc0\x62\xb8\x8e\x72\x7a\x0b\x16\x08\xfe\x70\xa2\x64\xd2\xfb\xe8\x27\xe4\x53\x78\x1b\x47\xe2\x33\xd7\x1d\xba\x2c\x4c\xc7\xca\xc1\xf6\x1c\x28\x3b\xf4\x73\x65\x48\x54\xa8\x86\xf7\xdd\xae\xe8\xda\xc1\xe2\x84\xe4\xd8\xf0\x08\x60\xa3\xab\x6a\x3c\x74\x28\x9e\xd6\x6b\xc0\x55\x7c\x56\xdd\xb4\x45\xfd\xc5\x38\xc1\x25\xae\xab\xc1\x1e\x8c\x8d\x1b\xfd\x15\xf6\xcf\x74\xd7\x02\x42\xe4\x04\xc1\xea\x03\xe5\x0e\xb3\x95\x4a\x99\xb3\x1c\x94\x31\x57\xcb\xa4\x1c\xac\xad\x09\x61\xd0\x24\xe1\x35\x5b\xed\x01\x2f\x32\xa9\xf0\xa7\x78\x9e\x26\x1e\x99\x85\x75\xcf\xaa\xfa\xaa\xb2\xd9\x60\x60\xc9\x6b\xff\x60\x9e\xcd\x95\x83\x5e\xb1\xc4\x9f\xbf\x78\x74\xf8\xf2\xe4\xcd\xff\xbe\x78\x27\xeb\x89\xf1\x78\xb6\x4c\x3f\xe1\x98\xbf\x2a\x61\x2f\x46\xf9\xdf\x20\xc7\x8b\x24\x1a\xe3\xde\xf0\xdf\xd7\xa7\xff\x4e\xff\x9d\x9f\x3d\xfd\xf7\x97\xe1\x34\xe8\x5e\x5f\x3d\x7a\x74\xf5\xa5\xdb\x07\xd7\xc2\x5f\xbc\xf0\xff\x3e\x13\x25\x4e\x79\x99\x33\x5a\xe8\x54\x94\x3a\x3b\xf5\x97\xb3\x4b\x19\x85\x2a\xca\xa8\xb6\xb4\x96\x64\x43\x5a\x19\x7e\xcd\x47\xb3\xbb\x82\x93\x1a\x18\x70\xd7\x2c\x20\x5e\xe3\x2f\xc3\x21\xdc\x81\x62\xee\x0e\x03\x3c\x6d\x40\x05\x6b\x0e\xe9\xd3\xbc\x43\x9a\x65\xae\x5c\xee\x77\xc6\x82\x41\x1b\x88\xbd\x7f\x35\x44\x75\x79\x67\x6d\x71\x32\xd7\xa9\x81\xcf\x16\x0c\xfa\xae\x95\xe2\xd6\x34\xcc\x98\x66\xcd\x5d\x7c\xaa\x33\xfb\x76\x67\x90\x11\xe6\x9d\x1b\x72\x81\xeb\xbb\x74\x8c\x13\x70\xa8\x2f\x1e\x71\x1a\x65\xc6\x09\x8e\x72\x61\xc2\x65\xb5\xc2\x93\xad\x05\xed\x07\x02\x5f\x0d\xa5\xa8\xc8\xb7\xc7\x99\xe5\xed\xbd\x4e\xff\xab\xb5\xab\xe4\x38\xd3\xe1\xaf\x03\xb4\xb5\xb9\xb9\x89\x1e\xb2\xcb\x19\xcf\x5d\xab\xd7\xf1\x03\xbc\xdb\x03\xec\x08\x7c\x51\x0e\x52\x60\x4e\x2f\x2c\x28\x0a\x7f\xd7\xb7\x3a\xaa\xdc\x19\xb3\x48\x04\xa2\xfd\x70\xcb\x4a\xa7\xc3\x8c\x45\xb0\xb8\xde\xa6\xdd\x9e\xa5\xad\xd7\xc1\xb9\xf3\x1f\xca\x23\x7f\xe2\x5b\x68\x14\xc7\x85\x1e\xf0\x99\x5b\x39\xb8\xd2\x18\x53\x0f\x07\x6b\x6c\xc3\x15\x07\x03\x7e\xd6\x26\xcc\x5d\x3d\xe7\x7a\x73\x11\xbd\xde\xe0\xbe\x47\x31\x2b\x15\xe5\x39\x39\xc7\x3a\xc3\x8d\x62\x39\x7b\xa2\xbd\x1a\x0e\xeb\x81\x36\xbc\xd5\xfb\x6d\x4a\x35\x99\x42\xbc\x56\x27\x31\xba\xba\x12\x5f\xa7\x9b\x67\x72\xcb\x84\x2b\x6c\xd6\x37\x05\xcd\x13\xcc\x12\x2c\x51\x97\xe8\xbc\x9b\x17\xda\x97\xbd\xa9\x93\x78\x29\xe8\x40\x36\x2c\xea\x16\xbb\x9a\x58\x47\xfa\x4a\x65\x61\xe1\xb9\x59\x0a\x13\xcb\xe1\xf4\x05\x1a\x77\xba\xbf\xc7\x1a\x9a\x39\x11\xd7\xa0\xd6\xb5\xef\x94\x47\xfb\x49\x96\xf7\x28\x5e\x3e\xe1\x4b\x76\x52\xf4\x0d\xc0\x74\x02\xd3\xf3\x03\x0d\x66\x51\x71\x7c\x91\xbe\x85\x90\x47\xe5\x25\x84\xb4\xb3\xb8\x40\x05\x7a\x3e\xe1\xcb\xb3\x6a\xdb\xce\x6e\x96\xa2\xa3\xb7\x87\x5d\x3b\x16\x3b\x97\x2d\x6a\xea\x74\xcc\x2c\xd4\x32\x39\xd4\x7d\x10\x72\x77\xe3\x48\x3b\x6e\x90\x02\x15\x25\x61\xc1\x48\x48\xac\x11\xb5\x6e\x16\x5a\x89\x70\xbf\x1d\x67\xaf\xfa\xb4\x24\xe4\x00\xba\x7b\xe4\x98\xf7\x23\x60\x54\x60\xf6\x6a\x9a\xa5\x98\x6b\x9e\x7a\xeb\x1f\x6d\xb1\xff\x22\x27\x25\xf8\x4b\xb1\xb8\x91\x06\x62\x1d\xa1\x3e\xba\x67\x28\xe1\x62\x70\xbd\xaa\x76\xae\x40\xf2\x0e\xbd\xee\x05\xc1\x9a\x4e\x3f\x56\xbd\xf8\xbd\xf6\x74\x05\x19\x9b\xec\x9e\xc1\xb9\x57\x40\x91\x40\x53\x33\x96\x90\xe7\x08\xd5\x78\xd6\x14\xbd\x8c\xb5\x67\xbf\xbe\x51\xd5\x58\x3c\xdf\x4c\x6c\x90\x54\xad\x4b\x0d\xe6\x50\x1a\xf7\x51\x64\xfd\xf9\xf6\x49\xcb\xec\x8e\x6b\x13\xad\x33\x8a\xe3\x8e\xe7\x5f\xd9\x12\xac\xac\xd5\x6b\xb3\x4e\x77\xc3\x66\xb7\x1b\xdd\x0e\x2a\x74\xc3\xec\x81\x6e\xa7\xad\xf8\x20\xbc\xd8\xca\x4a\x54\x2c\x17\x8b\x2c\x2f\x41\xb7\xc6\x6e\x6a\xdf\x1e\x22\xa9\x55\xe9\x1a\x8e\x20\xab\x09\xb3\xf5\x4b\x85\x9b\x2c\xc6\x66\x2a\x5b\x89\xc2\xbc\xc7\x7a\xa0\xa9\x5a\x0b\x7a\xcd\xa1\xae\xbd\x9b\x56\x7a\xbb\x71\xf5\xb8\x0a\x83\x8e\x93\xf6\x8a\x57\xda\xd7\x67\xc1\xce\x37\xf7\x2a\xdd\x7b\x95\xee\x7f\x85\x4a\x97\x3f\xac\xb8\xd5\x73\xec\x83\x28\xcf\x52\xf4\xbf\xcb\x79\x74\x4e\x0a\xf4\x7d\x44\x3f\xff\xf6\x89\x7d\x0e\xe6\xd8\xab\xee\x1d\x0e\xd1\x51\x4a\x4a\x12\x25\xe4\x57\x8c\xfe\xce\x7a\x41\x09\x35\x42\x05\x58\x62\x09\x83\x1b\x18\x28\x5d\xaa\x86\x93\xf3\x01\x68\x75\x45\x31\x11\x45\x84\x07\xa0\x3a\x8a\x43\xb4\xd9\x74\xf3\xc6\xac\x3d\xe8\xf0\x6d\xb7\xba\x5e\x33\x13\xaf\x3b\x5d\xf5\x0a\x4d\xc4\xba\x9a\x08\x84\x42\x4b\xca\xa0\xc7\xe3\x84\x97\xbd\x4e\x09\x34\x55\xcf\x44\x54\x23\xb2\x84\xef\x5d\xaf\x1b\x22\x6d\x04\xb4\x3d\xa7\xf7\xc3\x35\x8e\x9e\x0a\x57\xbc\xac\xad\x80\x37\x66\x38\x4d\x65\x59\xfd\x2a\xd5\xb2\x68\xd2\x31\xe6\x91\x66\xbb\xeb\x5d\x2d\xea\x54\x14\x9f\xd3\x33\xaa\x98\x1d\x74\xf4\x1c\x72\x44\xef\xe4\xa4\x6d\x6c\x54\xf9\x19\xf2\xbf\xfe\x61\x6f\x85\x9c\x6a\x54\xb6\x78\x1e\xc4\x47\x2a\xd3\xc5\xbb\x20\xf6\x3f\x3d\x30\x89\x17\x42\xed\x0d\x2f\x04\x0e\xe4\xe1\x51\x18\x10\xf9\x4d\x75\xa4\xb2\xae\x29\xf2\x99\xe7\x65\xb6\xd5\x80\xdf\x3c\x43\xa0\xc1\x6a\xcf\x8a\x09\xcc\x12\xad\xcb\x50\xe6\xd3\x47\xd3\x39\x73\xa0\xa7\xb2\xed\x01\x3e\xc7\xf9\x65\x4f\x78\x43\x7e\x4f\xd2\x69\x82\x5f\x33\x84\xf7\x51\x88\xbc\x19\xaa\x26\x3e\xad\xb2\x23\x7e\x70\x3e\x81\xf2\xa0\x25\x85\x77\x41\x37\x9a\x05\x91\x48\x63\x14\x69\xd8\x16\xf1\x0c\x31\x3f\xfb\xfb\xfb\x8c\x6a\x74\x20\xee\x76\x41\xc0\xd2\x33\x37\x05\x63\xd7\xba\x5d\x5f\x75\x5c\x86\xb5\x7c\x4a\x0e\x87\x2c\x34\x9f\x4c\xe2\x5e\xd9\x75\xe6\x22\xd6\x63\x2b\x7f\xf2\x8c\x11\x8d\xe0\x3d\x5a\x03\x3b\x7a\x46\x81\xaa\x5d\x7c\xf3\x8e\x5b\xfc\x85\xd5\x55\x30\xa6\xca\xab\x12\x02\x4e\xdc\x07\xe5\x11\x5f\x14\x3d\xc1\x7b\xfa\x68\x42\x70\x12\x5b\xa6\x07\xbc\x15\xa3\xa7\x16\xcf\xd1\x3b\x68\x31\x1e\xd6\x35\x8b\x0c\x45\xb2\x15\xf5\x41\x90\x85\xeb\x08\xcb\x61\x6f\x02\x76\x20\x58\x9b\xf8\xe6\x2c\xce\xd4\xc3\x3b\xb2\x22\xaf\x8f\xcb\x89\x54\x0c\x7c\x7c\x2f\x06\xde\x8b\x81\x7f\x6d\x31\x50\xbd\xcf\x63\x8b\xe6\xae\x5e\xe8\xdd\xcd\xdd\x3d\x05\x79\x2d\xd4\x8d\x95\xc6\xca\x70\x4e\xe4\x91\x5a\x84\x15\x32\xfd\xd4\x4e\x91\xdc\xe5\x9a\xc8\xa5\x9f\xc6\xc5\x3d\xf0\x3c\x95\xaf\x24\x83\x4d\x0d\x0c\x7c\xf2\xeb\x41\x09\x95\x21\xb4\x9e\xa1\x95\x60\x9e\x9e\x7d\x45\xac\x1c\x43\xe9\x0a\x1a\x83\xd7\x51\x1a\x4d\xb1\x7a\x9d\x4f\x59\x16\x43\x85\xa1\x0a\x10\x2e\x3c\x14\xb8\xb6\xdf\xcf\x0d\x0c\x39\x15\xe7\xf3\x06\xfb\xf7\x18\x53\x0e\x43\x52\xd3\xbf\xa7\x25\xfe\x8d\xa2\x82\xf9\x5c\xa8\x8a\x44\x31\xc5\xe0\xa5\xd2\xb3\x49\x99\x9e\xe6\x6d\xc7\xa2\xa2\x4d\xb3\x3d\x20\x31\x07\x11\xbc\x8d\xca\x58\x13\x86\x3b\x51\x2d\x7c\x8e\x24\x0e\x69\xc7\x27\x7c\x19\x16\x54\xb0\x91\x29\x4d\x6e\x8e\x99\x33\x4e\x75\x49\xc1\x43\x73\xf0\x6d\x57\x8e\x73\x80\x5e\x53\x56\x4e\x70\xc1\x83\x48\x03\x3e\x1c\x2f\x94\x86\x67\xcf\xd6\x78\x13\x83\xba\x7a\xb3\x4c\x12\xe5\x18\x23\xa0\x52\x24\xfe\x4c\xe0\xda\xcc\x87\xbb\x3f\x66\xfc\xa1\x3b\x0b\xbb\x83\xd6\xbe\x56\xdc\x1d\x07\x93\xad\xa2\xed\xd8\x01\x4e\x64\x28\x19\xf3\x20\x86\x1a\xc2\xc7\xbc\x7b\x7b\xc8\x23\x4c\xd4\xc7\x8e\x51\x68\xe3\xae\x5e\x19\xe1\x01\xd2\xe5\x89\xd3\x46\x13\x03\x3d\xa2\x90\x2e\x96\x0c\xa2\x93\x49\x1e\x74\x68\xb5\xd4\x62\x63\xdd\xc3\x5d\x2b\x28\xc8\xf7\xb8\xd1\x53\xda\x92\x21\xa5\xd3\xc5\x00\x41\x90\xec\xba\x10\x52\xe8\xa9\xfa\xcd\xa8\x1b\x8a\x9c\x51\x76\xa0\x7d\x36\x78\xd6\x77\xb0\xce\xf8\xbd\x8c\x5e\xab\x63\xde\x45\x3c\x73\xc0\x5b\x7f\x56\x34\xdd\x11\x57\xe0\xde\x13\x23\xc5\x0c\x96\xab\xa3\xd0\xde\xac\xc0\xd9\x0c\x1c\x7b\x9e\x7a\x01\x64\x55\xde\xd8\x24\x1c\x17\xbe\x90\x45\xe2\xfd\x94\xa0\xc3\x15\x22\x17\x45\x62\xdd\xb6\x42\x42\xbb\x18\x44\xba\x3b\x56\xbe\x8f\xd8\x5e\x92\x57\x76\xbe\xcc\xe5\x09\x00\xd6\x96\x81\x0e\x08\x79\x46\x80\x21\x79\x4c\xf1\x6b\x41\x84\x3a\x03\x34\x4b\x15\xca\x8c\x3a\xb7\xca\xba\x8a\xc3\x41\x95\x70\x91\xcb\xf0\x69\x4a\x5b\xa3\x5f\x74\x74\xd1\x0c\x31\xb4\xd1\x92\x24\x31\x20\x8c\x0f\x8a\x66\x3a\xfe\x6d\x81\xe1\x7f\x38\x7e\x7e\xbc\xbe\xbe\x0e\xe2\x7d\xb7\x40\xcb\x69\x72\x39\xe0\x51\xc4\xe8\x81\x60\x59\xd0\x3d\xb1\x94\xad\xa4\x9a\x4b\x59\xfa\x5b\xd8\xd5\xc8\x1b\x12\xca\x38\x20\x43\xbe\xb7\xde\x32\x22\x3d\x8d\x7e\x39\xa5\xd9\xa7\x9b\x67\x67\x54\xec\xd2\x3f\xaf\xae\xa4\xdd\xa6\x0d\xca\x7e\x6c\x41\x19\x3a\x96\x3d\xff\x55\x91\x55\x3b\x40\x22\x8d\x0b\x3b\xe8\x95\x88\xaa\xba\x45\x95\x97\xea\xca\xe8\x94\x85\x40\x49\xfd\xcf\xb2\x90\xe3\xe7\x9b\xcb\xef\xea\x34\xbc\x8a\x1f\x68\x64\x45\xb0\xf0\x85\x2a\x30\xce\xea\xd0\x96\x29\x51\xaa\x8b\x29\x75\x3f\x63\xc4\x62\x91\xe6\x75\x1e\xd3\x3c\xbb\x61\x06\xcf\xdb\xd1\x99\x99\x34\x8a\xb4\x0c\x68\xbc\xe1\x54\xcc\xee\x1a\xd5\x54\x0f\xc1\xbe\x86\xe2\xa4\x42\xb3\xda\x7a\x7a\x16\x61\xae\x70\xda\xec\xce\x51\x70\xc8\x65\x8e\xe1\x86\xf4\xdd\xdb\x43\xe9\x81\x89\x99\xb2\x8c\xa3\x54\x0a\x9b\x24\xe5\x4a\x17\xbf\xaf\xa7\xdc\xf5\xf5\x38\x18\x0c\xae\xf5\xf8\x6e\xb6\x2f\x3d\xa5\xc9\x14\x45\x3d\x9c\xb4\xc9\xa7\x7d\xa5\xbb\xf9\x55\x88\x50\xd0\x80\xe9\x93\x5e\x9f\xb5\x2a\x44\xf3\x92\x15\x8a\x9d\xd7\xc2\x06\xa6\xed\xf5\xdf\xb7\xf7\x7a\x9f\x7b\xbd\xcf\x5f\x5b\xef\xc3\x95\x3e\xf1\xe8\x16\x37\x7f\x3e\xbd\x8f\xd4\xd6\xe8\x8a\x1f\xc6\x9c\xa4\x46\xe7\xf9\x33\x83\x8f\xd0\x61\x98\x2e\x3f\x1c\x4d\x05\x8c\xd4\x4a\xde\xab\x09\x14\xb6\xa6\xe4\xa5\xa2\xe7\xb1\xe9\xe7\x17\x5c\xe8\x0b\xb2\xa4\x2b\x4b\x0e\xea\xd1\x9a\xb5\x9d\x85\x03\x39\x6a\x97\x9e\xaf\x83\x96\xbe\x59\xed\xf2\xd5\x01\x8b\x16\xcb\x52\x3e\x5e\x4b\xf1\x05\xc7\x66\x4f\x6d\x97\x54\xe8\x08\x51\x57\xc2\x59\x71\x34\x42\xd4\x8d\x47\x1f\x7d\xb9\x42\x4c\xdc\x91\x7d\x92\x8d\x4e\x71\xbb\x46\x25\x9c\xb7\x51\x5f\xae\x68\x74\xdb\x6d\x74\xb1\x2c\x5f\xe2\xcf\xcd\xc3\x7c\x89\x3f\x57\x8d\xd1\xcc\xaa\x1f\x60\x73\x5b\x0c\xa8\x6a\x68\xfe\xb6\xac\x71\xf1\xcd\xe8\x54\xc1\x89\x89\x08\x14\x92\x03\x3e\xf4\x80\x77\x0b\x80\xcf\x2a\x76\xae\xe7\xcf\xd4\xae\xc5\x68\xa7\x13\xee\xc0\x16\xf5\xe4\x7e\x8b\xba\xdf\xa2\xfe\xda\x5b\x94\xba\x9a\xc0\xe5\xec\x46\xf7\x12\x1c\xf8\x6e\x5f\x25\x56\x44\xff\xf7\x85\xff\xf7\x5d\x82\xf8\xef\x41\x1a\xb6\x4d\x0a\x22\xcc\x91\x2d\xa0\x05\x4f\x16\x60\xe3\xaa\xf6\xc6\x59\x3a\x21\x53\x01\xa6\x85\xc2\xd1\xa1\x45\x64\x15\x01\x76\xc1\x9f\xad\x19\x17\x34\x3c\x51\xc0\xfc\xc8\x42\x81\x5b\xc8\x80\x44\x01\x72\x54\xbc\xbf\x4c\xc7\x6c\x8b\xd1\xa1\x0a\x96\x2a\xc0\x28\x2b\xce\xb1\x0d\xc4\x53\x65\x5d\xcc\x41\x91\x0e\x41\x46\x51\x2a\xb2\x99\xd7\x43\xa7\x3f\x22\x59\x0a\x21\xe0\x33\xad\xcd\x9d\x81\xd4\x79\xf3\x37\x82\xa0\x04\xdc\x3c\xeb\xa3\x07\x0f\x10\xff\x3d\x00\x9d\xe0\xf1\xa4\xd7\xdd\xfc\xdc\x65\xae\x4b\x36\xfb\xe8\x29\xea\xe0\x72\x46\x77\x0f\x08\x4c\xfa\xec\xf2\x65\x54\xcc\x3a\x28\xb4\x93\x99\x46\xb7\xa3\xa4\x04\x2d\xfe\xd3\x8f\x79\x36\x7f\xf6\x1b\xf4\xb4\xcb\xbb\xa4\x85\x15\x7a\x76\x09\x0d\xd3\x4e\x1f\xa4\xf1\x11\x2d\x27\xa3\x79\x79\x21\xd9\x38\x24\xac\x1a\xcf\x32\x1d\x27\xf8\x37\x1a\xc0\x09\x6d\xab\xa1\xeb\x3a\x4c\x65\xa7\xc5\xfc\x68\xe3\x3c\xcc\x96\x69\xab\x6b\xa6\x3b\x18\x87\xb7\x6d\x46\x42\xfa\x50\x2a\xc0\xd8\xa8\x9c\x29\xf8\x0d\xfb\x7f\x22\x1b\xd4\x26\xc3\x99\x04\x1d\xc0\xe8\xb3\xec\xde\x8b\x72\x76\xd7\x07\x84\xd6\x87\x83\x3b\x3a\x1b\x40\x00\xe0\xea\xb3\x01\x53\x7d\x30\x2e\x4e\xb0\xb7\x47\x0b\xbd\x33\x8b\x86\x7e\x2c\x6e\xd0\x05\xed\x8e\x9b\x71\x57\xe6\xff\x02\xe9\x1e\xba\x8f\x9e\x1d\xbc\xb1\x82\x91\x71\x9e\xca\xb4\x32\xec\x01\x2d\xd7\xcd\x5c\xaf\xad\xb1\xde\x0d\x98\x65\x94\x7c\x4b\xf3\xa2\x9c\x29\x6d\x50\x80\xba\x7a\xe8\xe6\x6e\xc0\x87\x39\xc5\x65\x58\xa1\xf3\x14\xbe\x4a\x07\x7a\x41\x3e\x92\x80\xeb\xe9\x8c\xc2\xe7\x51\x62\x44\x19\x1b\x58\x81\xb3\xcf\xa3\xc4\x71\x46\x22\xd3\xae\xd7\x00\x3d\x2b\x0d\x85\xfb\xf9\xbb\xc9\x60\x78\xd1\x9b\x0c\x87\x17\x6d\x39\xa0\x36\x67\x51\xca\x5d\xa2\x04\x2c\x37\x1b\x4f\x4e\x1c\xd0\x3d\x3d\x09\x36\xe5\xe4\xcb\x23\x94\x66\xcd\x69\x5c\xe1\x85\xe8\x54\x09\x54\xec\x7a\x8f\xbb\xd1\xfc\x51\xdd\xe6\xd9\x10\x7a\xe4\x73\xc6\x4f\x24\xb0\x16\x3a\xd2\xba\xc7\x0a\xf5\x6a\x58\x9e\xf4\x59\x23\x81\x2a\x8e\xcd\x59\x1e\x4d\xf1\x41\xd9\xe6\xe4\xcc\x41\x2b\x71\xe4\x83\x90\x87\xda\x1a\x2c\xb1\x75\xc7\x38\x76\x99\xc1\xc9\x72\x15\xb4\x78\x07\xc6\x9d\x3b\x36\x8c\x89\x42\x55\x0e\xc7\xca\xfc\xed\xe7\xdb\x3b\x30\xb1\xea\x9b\xe8\x99\xb1\x23\x6b\x68\x52\x64\xbc\xdd\xb0\x7c\xbd\x0d\x9c\x25\xae\xec\x5f\xe9\xe2\x45\xd7\xab\xd1\x2f\x6d\xa2\x9e\x76\x61\xff\x6d\xc6\x04\x80\x39\x98\x90\x12\xdd\xd7\xc0\x84\x46\xca\xb7\x18\x74\xb0\x56\x41\xd9\xf3\x05\x49\xd8\xe1\xad\x91\xbc\x39\x68\x0d\x8d\xbb\x10\x02\x0f\x9b\xd5\xf4\x67\xcb\x6b\x2d\xe9\xd1\x2e\xe6\x74\xab\x4e\x64\x75\x3b\xb8\x75\xcb\x89\xaa\x9b\x1b\x31\x85\xcf\xf1\x98\xcc\xa3\xa4\x1a\x15\x4a\x0a\x6c\x89\x04\x55\xa0\x82\x28\xff\xb8\x03\x36\x85\xa7\x86\xc1\x56\xc7\x4a\xae\x38\x82\x81\x7c\x5d\x3b\xe8\xe6\x15\xa4\x55\x58\xcf\x3c\x3e\x78\x4e\xa8\x2b\x8d\x49\x96\x72\x06\x57\x75\xf8\xfd\x23\x71\x9a\x9b\xe0\xe9\x1d\x1e\x63\xb2\x68\x41\xe6\x6e\x99\x36\x04\xe0\x82\xde\x96\x02\x78\x8d\xad\x07\xd8\x72\x15\x37\x72\x31\xcf\xe0\x6c\xc0\x36\x14\xc0\xc4\xa2\x3b\x12\x10\x1b\x97\x37\x3d\x20\xbd\x8b\x2e\xda\x2f\x71\xb7\x80\x1f\x11\xb5\x70\x6d\x38\x1b\xc5\x83\x47\x16\x72\x43\x4b\x37\xf5\xb6\x55\x57\x6f\xde\x4f\x7b\xa6\x7c\x6b\xcc\x37\x0e\x32\x4d\x57\x18\x87\x09\x5d\x31\x8e\x4a\xa0\xaf\x3c\x8e\x16\x9d\xaf\xee\xf1\x9d\xcb\xda\x15\x84\xc3\x8d\xbb\xea\x3a\x0a\x81\xff\xbd\x1d\xb5\x72\x6e\xd2\x51\xba\x17\xdc\xd9\x89\xc0\x8c\x90\x5e\x37\x26\x0d\xd2\x3f\x34\x3f\xc0\x4d\x28\xc6\x18\xe1\xad\xb8\xd2\x98\xc9\xa7\x22\xae\x79\xd3\xb4\x31\xe8\x81\x08\x76\x5e\x31\x85\x66\x9d\xbe\xb1\x56\x76\xe4\xd5\xab\x57\x2d\xfb\x90\x54\x52\x90\xac\x69\xa5\x96\xdf\xe3\x7c\x81\x1b\xb7\x27\x89\x01\x06\x5d\x8f\x00\x07\xa6\xa6\x17\xc5\x72\x34\x27\xe5\xcf\x59\xde\x24\x25\x29\xc0\x8a\x95\xee\xcb\xaf\xbf\xfa\x6e\xd1\x2a\x87\xaa\xdc\x8a\x2b\xda\xb3\x8e\x38\xce\xf5\xb7\x52\xfc\x04\x7a\x9a\x54\x74\x18\xa9\x87\x59\x6c\x82\xc1\x12\x36\x52\x40\xf6\xb7\x0a\xf1\x03\x98\x5b\xd2\x16\x1f\x5c\x08\x75\x94\x30\xf2\xb4\x82\x55\xe9\x52\x30\xad\x02\xe0\xb2\x53\x55\xb6\xd5\xa8\x69\xd0\xab\x31\x12\x95\xe8\x8a\x01\x5a\x9e\xb9\x5f\x99\x85\x2a\x4b\x68\xcd\x9b\x2b\xda\xc9\x78\xf5\xea\x95\x0b\xcc\xa8\x5f\xab\x52\x12\xa6\x31\x68\x9a\x00\xdf\xdc\xc2\x81\xc5\x64\x93\xca\xee\x3a\x1f\xcd\x8a\x8e\x28\xe9\x4a\xad\xab\x69\x3a\x2a\x17\x2e\x49\x47\x51\xa1\x2b\x2a\x78\x07\x80\x51\xf2\x75\xcb\x61\x24\xc8\x75\x3f\x58\xa1\x8d\x39\x49\x4d\xeb\x16\xa7\x05\x0e\x71\xc3\xfa\x67\x51\x31\xcb\xa3\xb2\x76\x0c\x15\x30\xad\x76\x86\xd5\x7b\x24\x6e\x67\x6b\x3a\xe4\x07\x69\x3e\x67\xf0\xeb\x60\xf3\x70\xb1\x7a\x0f\xa7\x51\xf1\x36\x27\xe3\x5a\x9c\x55\xc0\xdc\x58\x09\xbc\x7a\x2f\x79\xd8\xa1\xa2\xae\x97\x12\xe6\x86\x6d\x8c\xb4\x2b\xb4\x9a\x66\xaa\xc1\xbe\x12\x0d\x89\x98\x0c\xff\x60\xb6\x36\x75\x7d\xb3\x41\xb5\x16\x75\x16\x62\x5c\xbb\x0c\xc6\xca\x8e\x41\xbb\xd2\x1c\x11\xe3\xc5\x42\x34\x2e\xb3\x5c\x88\x3f\xc2\xf2\x01\xcc\x88\x03\x44\x61\x0d\x5b\x62\x0e\xed\x6b\x6c\x22\x2c\x1d\x9c\xa7\xa8\x81\xf6\xdc\x8e\x41\x1d\xe6\x18\x2c\x95\xe0\xf1\xd8\xa1\x65\x4e\xaf\xc5\x29\x62\xd6\x13\xaa\x1e\x8a\x0a\x11\x6e\xde\xba\xb1\x0b\x84\x39\xc6\x00\x97\xb3\x5e\x3f\x70\x49\xf6\x55\x36\xd5\x64\xe3\x76\x5d\xf2\x0d\x54\x19\x62\xd4\xbb\xd0\x17\xd8\xef\xf1\x02\x83\x69\x92\x8d\xa2\x64\x40\x91\x3a\x88\xdc\x64\x1e\xf3\xcc\xd7\x24\x19\x47\x8b\x37\x37\x6d\x96\x16\x76\x1a\x65\x89\x75\x4d\x6a\x56\x29\xaa\x41\xfb\xe9\x83\x1e\x53\x4a\x14\xe8\xf9\xa7\xa7\xc9\x1b\xd5\x8b\x72\xa6\xec\xf9\x2c\xc3\x9c\x4e\xb8\xf5\x24\xe8\x38\x06\x42\xdc\x40\x5d\x59\xe6\x74\xc2\xed\x6f\x20\x81\xcd\x69\x27\xdc\xfe\x8e\x7d\x4a\x5a\xe8\x84\x3b\xac\x08\x19\x45\x69\x27\xdc\xd9\x09\x4c\xf3\x41\xf8\xe4\x48\xea\x84\xbb\xbb\xf0\x2d\xcc\x88\x3a\xe1\x2e\xab\x9e\x73\xf6\x4e\xb8\xcb\xba\x25\x2e\x7b\x3b\xe1\x2e\x6d\x50\x18\x01\x75\xc2\xdd\x9d\xeb\xb3\x60\xe7\xbb\x7b\x7b\xc4\x7b\x7b\xc4\xff\x8f\xbd\x77\xdf\x6f\xdb\x66\x16\x45\xff\x4e\x9e\x02\xed\x3e\x5f\x23\xd5\xb4\xac\xbb\x1d\x25\xee\x5a\x8e\x6c\xc7\x5e\x89\x63\x6f\xdb\x69\xfb\x6d\xff\xdc\xfc\x28\x11\xb2\xd8\x48\xa4\x16\x49\xf9\xd2\xc6\xfb\x7d\xce\x73\x9c\x17\x3b\x3f\x0c\x2e\xc4\x95\xa2\x7c\x49\xd3\x2e\xfb\x5b\xab\x11\x49\x60\x30\x00\x06\x83\xc1\x60\x2e\xff\x6c\x7b\x44\x97\x31\xe2\xbd\x6d\xe6\xcb\x9b\x09\x96\xb0\x01\x84\x72\x1f\x70\xf6\x98\x26\xf6\xf0\x76\xb1\xc9\x4a\x6e\x5c\x7f\x17\x9b\x95\x12\x26\xf5\x6b\x6b\x6b\x79\x4c\x1a\x5b\x9c\x1b\x96\xb0\x91\xb0\x78\x00\x87\xb3\x31\xf2\x67\xa1\x84\xfb\x23\x1d\x48\xcc\x64\xf4\x9a\xc0\xa3\x66\xac\xbf\xab\x70\x85\x71\xa2\xeb\xc6\x8d\x56\x5c\x85\x96\x10\xf8\x64\xf1\xcb\xd8\xd4\x3e\xe0\xcc\xb2\xa9\xa9\x9b\x97\xbc\xbb\xdc\x9e\x7b\xed\xfa\xd3\x6e\xf1\xb4\x5b\xfc\xb3\x77\x8b\x6f\xd4\x7a\xfd\xe1\x0c\xcd\x4b\xda\xc1\xe7\xa6\x9c\x47\x38\x49\xe3\xc8\x9f\x3c\xd9\x73\x3e\xb6\x3d\xe7\x6d\x39\x0b\xbf\x08\x5f\xe5\x66\x83\x45\xfa\xe1\xbc\xa0\xa9\x22\x9e\xb1\x59\xfd\x64\x2d\x74\x8f\x1b\xca\x70\x4a\x36\x82\x63\xff\xea\x1d\x5e\x74\x73\x21\x17\x7d\xe1\x3d\x7f\xf6\xcc\x86\x9f\xb5\x50\x81\xea\xbc\xfc\x8d\x9c\xbd\x3d\xe5\xa3\x64\xc8\x47\x5f\x94\xbc\xaf\x2e\x7d\x2d\x87\x87\xc7\x78\x18\x5f\xd2\x80\x51\x45\xf7\x58\xbc\x9c\x13\x6f\xb3\x44\x81\x1b\xdf\x3c\x9a\xc4\xc3\xcf\xe5\xa8\x48\x29\x5b\x40\x48\xae\x72\x65\x8c\x21\xcb\x8d\x9f\x73\x14\xd3\x71\x7c\x45\xb7\x84\xc5\x44\xa7\x94\x2d\xe8\x8e\xab\xdc\x83\xdc\xd2\x7e\x9d\xdb\xfd\xbc\x2f\x0b\xaf\xf8\x97\xe9\x8d\xed\x6a\xdc\x3d\x35\x38\x0a\x8e\x92\xf0\xd2\x1f\xde\xf4\xaf\x97\xb0\xa5\x72\xd6\x5b\xd0\xcd\x45\x75\x1e\xbd\xc3\xe5\x17\x56\xb9\x65\x55\xbc\xa8\x96\xb9\xa0\xd6\xa9\xd0\x82\xfc\x7c\x06\x51\x6d\xca\xf1\x05\xb9\x6c\x11\x5f\x70\x94\xfb\x0a\x7c\xe1\x02\x47\xc7\x61\x74\x71\x12\x5e\x44\x2c\x89\x55\xf1\x75\xaa\x52\xba\xa0\x4b\xee\x92\xe5\x2d\xbf\x9d\x88\xdb\x8e\xad\x6c\xf4\x24\xa0\xd2\xae\x98\xbf\x55\x58\xb0\x74\x35\xc9\xb7\x06\xd7\x55\xa2\xfb\xc6\xd1\xb5\xa2\xe4\xdb\x42\x65\x2c\xf2\x0f\x56\x54\x14\x62\x90\xda\x91\x99\xad\x7c\x6c\x7d\xbc\x63\xfe\xd6\x82\xdb\x9d\x7c\x01\xca\x85\x1d\xa7\x6b\xf3\x78\xcd\xa5\xe6\xfc\x8c\x6d\xd1\xf4\x6a\xca\x5d\xfd\xb8\xdd\x78\x3a\x6e\x3f\x1d\xb7\xff\xd9\xc7\x6d\x76\xd6\x4e\xc7\x0e\xe5\xec\x82\x53\x34\x4e\xf0\x7c\x4a\x40\xff\xb4\x40\x97\x3b\x8c\x13\x5c\x0b\x63\xf5\xb8\xbd\x5e\x3a\xfe\x49\x49\x7f\xe9\x45\x6e\xd7\x50\xe8\x64\x3c\x7e\x74\x25\xef\xb7\x73\xac\x26\xdc\xf1\x64\xac\x5c\x52\xe2\x2b\x16\x3b\x7e\xf7\x6b\xdc\xcb\xa6\xe3\xc5\xf7\xb2\xe9\x18\xee\x65\xe9\x96\xbc\xcc\xf5\x6b\xd1\x71\xdd\xbd\x39\x19\x02\x88\xb4\x35\x5d\x5a\x2f\xdc\x99\x00\x92\x8e\xc7\x9f\xec\x05\x54\xdb\x2e\x64\x51\x49\x17\x35\x1a\x46\xa3\xd8\xdd\xa2\xe5\xeb\xfd\x9a\x4b\x71\x76\xe0\x5f\x33\x22\x38\x09\xff\xd0\x6d\x3c\xa4\xb6\x17\x15\x55\x8d\xf7\xee\x82\x48\x18\x1d\xc5\xbf\x14\x23\x60\x2b\x72\xbf\x86\xa7\x7e\xf2\xf9\x34\x99\xa7\x19\x0e\x8e\xb0\x61\xd3\x21\x35\x5f\x5c\xf0\x7e\x48\x44\x98\x48\x8f\x47\x7e\x58\xd0\xbe\xb3\xcc\xfd\x28\xc0\x0f\xa8\x58\x99\x61\xf5\x70\xad\xb5\x5e\x54\xec\x7e\x7d\xa7\xb9\xfb\x16\x76\xbf\xa8\xd8\xfd\x10\x18\xfb\xe9\xc2\xd6\x9d\x65\xee\xd7\xf4\x05\xce\x6c\x8a\x0d\xad\xf1\x82\x52\xf7\x6f\xbe\xc4\xdc\x17\x15\xbb\x37\xdd\x9f\xdc\x4c\x0b\x1b\x77\x15\xb9\x37\xd5\x2f\x6a\xd8\x55\xe4\xbe\x43\x4e\xe4\xb8\x0c\x53\xd0\xbb\x49\x3c\x3d\xf2\xd3\xf4\x2a\x4e\x82\xa2\xf1\x2f\x59\xe7\xde\xeb\x60\xd1\x98\xb8\x8a\xdc\x9b\x0c\x17\x35\xec\x2a\xf2\x10\xac\x67\x51\xdb\x05\xa5\xec\xcd\x8b\x87\xb5\x35\x94\xce\x07\x70\x81\x4e\x33\x93\xcf\xa3\xfc\x79\x1a\xa6\x69\x18\x5d\x3c\x2f\x8d\xed\x2c\x4e\xf5\x1b\x68\x09\x4b\xcb\x57\x8b\x66\x0a\x15\x5f\x1f\xa0\xc5\x97\xd5\x27\xe3\xb1\x94\x07\x50\x33\xa1\x52\x4e\xd1\x9a\x81\x53\xbb\xf9\x74\x86\x7e\x3a\x43\xff\xb3\xcf\xd0\xf9\x95\xf5\xe0\x8f\x3f\xb4\x2b\xeb\xad\x09\xbe\x46\x6f\x70\x82\x2f\xd2\x3f\xfc\xf4\x8f\x10\xbd\xf6\x27\xf8\xfa\x3f\x93\x6c\x94\xd6\xc6\x73\xf5\x38\xdc\x65\x41\x89\x8f\xf1\x08\x27\x38\x1a\xe2\x1e\x22\xed\xa7\xbd\xb5\xb5\x8b\x30\x1b\xcf\x07\xb5\x61\x3c\x5d\xbb\xf2\xa3\xe1\xd8\x0f\xa3\xb5\x8b\x78\x55\xfc\x1e\x4c\xe2\xc1\x5a\x7a\xe5\x27\xd3\xb5\x30\xca\x70\x12\xf9\x93\x35\xd2\x25\x7c\x9d\xf1\x7f\x6b\x17\xf1\xff\x7a\xdf\x6a\x3d\xf2\x0d\x77\x7e\x6d\x7d\x42\xb0\xf9\x87\x1f\xae\xe1\xc7\xdf\xe2\xce\x9a\x1a\xb0\xe3\xec\x2a\x4e\x3e\x1f\x63\x88\x38\x5d\xa4\x8a\xd7\x8b\x9b\xba\xf8\xc1\x1f\x7f\x7c\x2a\x28\x75\x1f\x17\xdb\x9b\x68\xb8\x13\xf9\x83\x09\x5e\x84\xa5\x54\xd2\x8e\xa0\xbd\xc0\x7d\x70\xbb\xf2\x67\x25\x71\xcb\x4b\x3a\x70\xb3\x16\xb8\x07\x6e\x41\x7c\x15\xb1\x60\xe2\x45\x88\xf1\x62\x76\xac\x2c\x5f\xcb\x7b\x8d\x3b\x10\x9b\xcf\x4a\xa0\x45\x0b\xd9\x91\x32\xbe\xdd\x1b\xa5\x04\x67\x49\x88\x2f\x17\x05\x72\xe1\xc5\xec\x68\x59\xbe\xde\x87\xb4\x32\xb2\xdb\x2d\x20\x2a\x52\xc6\x41\x4e\xda\xa7\x7b\x0f\xd1\x05\x2e\x11\x95\xc0\x8e\x8b\xfa\xe1\x1e\x63\x42\x93\xb0\x2c\x08\x75\x6c\xc7\x41\xfd\x70\xef\xd1\x60\x79\x97\x8a\x91\xa1\x85\xec\xf8\x18\xdf\x38\x4a\xed\x25\x6e\x2a\xcb\xdd\x57\xea\x6c\x59\xba\xe6\xcb\xf9\xa1\xf4\x32\x67\x44\xf9\x4b\xce\x07\xe4\x9b\x43\xf5\x99\x53\xbf\x04\x88\x90\xa0\x7c\x31\x29\x5d\x30\xce\xe6\xd2\x83\x24\x8b\x3f\xea\x35\xe3\x38\xbc\x74\xba\xb8\x91\x39\x81\xef\xce\x33\x64\x31\x6c\x8b\x52\x56\x81\x0d\xdf\x1d\xc7\x2b\xcb\xf9\x8a\x08\x4b\xb6\x98\xd5\xd6\x7b\xc9\xd6\xd3\x99\xea\xe9\x4c\xf5\xcf\x3e\x53\xb1\x03\x15\xbf\x20\xfa\xba\xc9\x16\xee\xe2\x1f\xc1\x9d\x1c\xfd\x59\xc8\x85\x71\x9a\xa9\x33\x1b\x17\x19\x92\xd2\xeb\xb2\xc2\xe0\xb2\xbc\x74\x76\x33\x23\xf2\x01\x0b\x24\xfb\xea\xb9\xc4\xc0\xc3\x6c\x38\xae\x90\xef\x7a\x8a\xa0\xa1\x9f\x62\xf4\x82\x50\x7c\x9a\xbd\xe8\x29\x9f\x60\xb2\x92\x8b\xb4\x96\x8e\xc3\x51\x56\xd1\xf2\x02\x21\x23\xc7\x67\xdd\x2c\xc0\x58\x32\x78\xa1\x46\xf8\x8a\x06\x92\xa3\x17\xb2\xaf\x2c\x68\xcc\x70\x14\x84\xd1\xc5\xa3\xe3\x71\x44\xdb\x91\xad\xc6\x6c\x48\xb1\x08\xa0\x26\x36\x1a\x38\xa3\x32\xcd\x93\x74\x2b\x49\x07\xa2\xd4\x62\x83\x60\x06\x4d\x97\x11\x14\x52\xb0\xc8\x4e\x16\xa9\x3a\x0a\xa3\x34\xf3\x27\x93\x52\x2d\x6b\xa5\xed\x21\x2b\xdc\x85\x0a\xf0\xb8\xc0\xd9\xfb\xf8\xa2\x44\x5c\x40\x52\xca\x19\x2a\x83\xb6\xa8\x15\x29\x68\x75\x16\x2f\x0c\xa7\x43\x8a\x2c\x68\xaf\x3f\xf6\xa3\x0b\x5c\xa2\x49\x9b\xf0\x41\x41\xc8\xc6\x5f\xca\xe8\x29\x82\x10\xe9\x98\xd4\x48\x3c\x99\xc8\xf2\xc0\xd2\xfc\x26\x1d\x8f\x6b\xc0\x1a\x0d\x76\x93\x8e\x4d\x76\xe3\x16\x9f\x16\xdc\xd2\x18\x64\x80\x8c\x5b\x1a\xc5\x92\xe0\x41\xd5\xf4\x6e\x62\x44\x36\x4d\xfd\xe3\x21\x62\x92\x2e\x32\xae\x29\x68\xb3\x0c\x07\xbd\xe8\xfd\x9a\xd7\xc8\xf8\x01\xda\x96\x49\xcf\x90\x44\x29\x0e\x38\x1b\xf7\xc8\x7f\x28\xb0\x74\x3c\xee\x91\xff\x50\xe1\xd5\x96\x57\xa5\xdd\x7e\x12\x49\x9f\x44\xd2\x7f\xb8\x48\x9a\xeb\xf9\x79\xa8\x84\x07\x4a\xfa\x4e\xc3\x3c\x1c\xe3\x0b\x32\xcf\x7e\xb2\x35\x08\x1d\xe9\x45\xd2\xb5\xb7\x6a\xd1\xda\xef\x69\x2c\x52\x78\x84\x43\x7f\x26\x03\x71\xc1\xd8\xef\x6f\x1d\x99\x10\x24\x4c\x58\x3c\x09\x66\xec\x8d\x36\xd1\x8b\xfa\xf5\xb0\x1b\xbc\x0c\x9a\xc3\xa0\xdd\x7e\xe9\xaf\x77\xda\xc3\xf6\xcb\x76\xb3\xdb\xc6\x8d\x8d\xfa\xcb\x61\xa7\x8e\x5b\xed\xa0\xdb\xee\x74\x9b\x83\x17\x39\x2e\x36\x30\x7e\xc3\x6f\x34\x1a\x83\x61\x7d\xbd\x3d\x7c\x39\x1c\xf9\xeb\x1b\x8d\x51\x7d\xd8\xda\xc0\xdd\xd6\x20\xe8\x34\x86\x2f\x1b\x83\x0d\x7f\x54\xaf\xbf\x70\xf3\x26\x8a\x63\x4f\x92\x74\xfd\x41\xd8\xb3\x0c\x62\xce\x09\x59\x34\x8b\x9e\xb5\x7f\x74\xa7\xa7\x85\x09\xda\x06\x64\x7d\x5c\x2d\x70\xcd\xee\x52\xa8\x0a\xc7\x2c\x9e\xc5\xef\x7b\x0d\xef\xfb\x05\xf3\xf4\x7d\xaf\x49\x98\x6d\xe7\x89\xd9\x3e\x31\xdb\x7f\x36\xb3\xcd\x79\x2d\x57\x7e\x69\xcc\xb6\xc8\x30\x79\x94\xc4\x7f\xe0\xa9\x1f\xd5\x02\xfc\xd3\x83\x30\x68\x5b\xa4\x09\x2d\xcc\x84\x7e\x41\x6a\x98\xd4\x2a\xb7\xa0\x2c\x4d\x33\xfb\x04\x8f\x52\xda\x10\xaa\x48\x94\xbe\xd3\x17\x4a\x62\x11\xad\x44\xaa\x97\xd0\xb3\x92\xe7\x45\xb5\x2f\x52\x1d\x55\xff\x2c\x55\x51\x3f\x48\x35\x8c\xcb\xdc\x68\x3e\x99\x50\xc9\x92\x8f\x85\x9c\xc4\x56\xbf\xdc\x54\x33\xac\x4f\x95\x21\x32\x40\x27\xd3\xb2\x49\xe3\x1d\xa9\xfa\xef\x9d\xa9\xdf\xd2\xa5\x82\x5c\xfd\xb6\x08\x4c\xda\x08\xff\x87\xed\x2d\xad\x0b\x59\xf3\xbf\x91\x2c\xf9\xce\x7e\x3f\x50\x9e\xfc\xef\x36\x29\xe1\x68\xaf\xc8\x86\x32\x0a\x23\x1c\xdc\x27\x91\x3e\x4f\x00\x9d\xc5\x88\x65\x9f\xcf\xb3\xd2\x43\x56\x7d\x71\xd8\x16\x79\x95\x6b\xe8\x80\x6c\x6c\x21\x4e\x19\x25\xc1\x30\x69\x63\xa9\xa5\xb2\xb7\xe7\xdc\xa7\xf0\x94\x71\xdd\x65\x23\xf5\xe5\xc3\x7c\x32\xb9\x95\x8c\xdd\xc3\x11\xc2\xd7\x61\x0a\xc5\xad\x43\xae\xb5\xe8\xd4\x17\x86\xa3\x3c\x11\x15\x6f\x8d\xa6\xa2\x02\x35\x1b\xcb\xfb\xbe\x8a\x1a\xe7\x55\x4b\x52\x1d\x28\x33\x8b\x67\x95\x2a\xa4\x9f\x67\xf7\x5e\x84\xff\xc3\x7a\x82\xd1\xfa\x4e\x16\x6e\xd4\xe1\xa6\xf6\x0d\x39\x66\x59\x6c\x27\x45\xd5\x0e\xc2\x45\x8c\xec\x15\xef\x85\x93\x1a\xcb\x67\xe1\x87\x3a\x42\x94\x44\x3c\xcf\x50\x9e\x95\xff\x2d\xce\x2a\xd2\xe9\x1c\x47\xf3\x29\x4e\xfc\xc1\x04\xf7\x50\x96\xcc\xb1\xa9\xfa\xf3\xa7\x70\x61\xe5\xce\xb8\x2f\xa5\xe5\x87\xc2\xa0\xbc\x45\x52\x2a\xfe\x74\x41\x2e\xfe\x54\x4b\xc6\x9f\x3a\xb2\xf1\xeb\x45\x5e\x29\x1a\x31\xd1\x7c\xe3\x9c\xe7\x34\x82\x7f\xec\x49\x9a\xe2\xc1\xef\x1e\x94\xf7\xe8\x90\xb1\xbe\x10\xf8\x7e\x7a\x13\x0d\xdf\xc2\x7e\x43\x44\x5e\xe8\x42\xf5\x9c\x0f\x01\x0c\xe2\x16\x2b\x52\x91\xdc\x34\xb4\x6a\xca\x24\x01\x08\x95\x65\xc0\xed\x32\x5a\x01\x1c\x6a\xc3\xb1\x9f\x6c\x65\x95\x7a\xb5\x96\xc5\x1f\x67\x33\x9c\xf4\xfd\x14\x57\xaa\xfc\x33\x24\x47\xaf\x34\xaa\xce\x8d\x87\xcf\xac\x3b\x81\x70\xbe\x71\xe7\xd9\x90\x79\x60\x43\x5e\xe3\x82\x74\xc8\x5c\x31\x42\x40\x51\xf2\xe4\x8b\xb7\xb6\x4c\xf9\xaa\xa2\x87\x2e\x89\x5e\x0e\x88\x6e\xf7\x92\xc6\x26\xcf\xa8\x5f\xd4\x41\x3e\xea\xcb\xf5\x32\xbf\xeb\x77\xc7\xf2\x43\xb9\x99\x93\xb5\x43\xd0\xf2\xb2\xbd\x92\x73\xf7\xe0\x24\xf1\x90\xba\x75\xf0\x3f\x8e\x0b\x2d\x63\x1f\x6c\x56\x53\xba\x3b\xb8\xcd\x87\x8c\x2d\x72\x29\x17\xbf\x2c\x5f\xb8\x52\xe3\x5f\x60\xd7\x76\x92\xef\xbe\xc3\x04\x13\x59\x71\x36\x4f\x30\xfa\xaf\x93\xc3\x0f\xc7\x47\x7d\xc4\x5b\xb9\x1a\x87\xc3\x31\x1c\x9e\xf8\x0e\x14\x46\x68\x00\x3a\x5b\x56\x44\xe3\x88\xf9\x5b\xc1\xf7\x6a\xb5\xda\x2d\xd3\xe0\xd9\xf6\x66\x44\x8e\x84\xc9\x6c\x28\x55\xb5\x72\xc7\xbc\xe3\x0e\xb2\xf0\x6f\x98\x81\x8e\x6e\x36\xd7\x93\xe5\x51\x53\x49\x7e\x76\xae\xaa\xd7\xc9\x34\xb1\x2a\xda\x66\x55\x81\x3d\x51\x16\x05\xc9\x92\xad\x91\x4a\x15\xb1\x4f\x56\xab\xf2\x94\x31\xac\xd8\x44\xf3\x59\x93\xa7\xdd\x35\x75\xac\xa6\x43\xc1\xc9\x07\x48\x3a\x98\x6b\xa1\xb7\xc8\x11\xbb\xfb\x74\xc4\x7e\x3a\x62\xff\xb3\x8f\xd8\x92\x3e\x93\x71\x88\x29\x63\xe9\xea\x49\xfb\xbf\xf0\x68\x94\xe0\x1b\xf4\x4b\x38\x19\x7e\xc6\xe8\xf5\xef\x78\x34\x72\x05\xdd\x5a\x2a\x42\xd7\x81\x9f\x90\x23\xfc\xa1\x1f\x0d\xb1\x0f\x65\x6d\xb1\xb9\xee\x10\xce\x8b\x55\x79\xeb\x5f\xa2\x5f\xe2\x38\x40\xaf\x2f\x9c\x87\xfc\x76\x7e\xc8\xff\x2f\xc6\x4d\x15\xe7\x61\xc6\x62\x8b\x32\x53\x5b\xe2\x4d\xea\xc9\xa4\x6d\x99\xa4\x71\x92\xc4\x5a\x0c\xb0\x35\xfa\x8e\xda\x20\xd0\x6d\x67\x3f\x7b\x91\x92\x8d\x71\x16\x47\x69\x38\x98\x50\x02\x9b\xf9\xe0\x44\x82\xa6\xec\xce\x87\xec\x45\xb3\x24\xbe\x0c\x03\x9c\xa4\xa2\x96\x3f\x49\x63\xb3\x6a\x3c\x99\x90\xaa\x84\xda\xb8\xff\x36\x8a\xe2\x80\x7e\x0d\xa3\x61\x3c\x95\x21\x13\x60\x2c\x35\x08\xbd\x72\xcd\xc2\x29\x26\x8b\x2d\x4c\x51\x03\xa5\x78\x18\x47\x01\xec\x8e\x61\x74\x31\xc1\x59\x1c\xc1\x70\x92\xee\x15\x1c\xf4\x39\xaa\xca\x71\x9f\xbf\x44\x9b\xa2\x2b\x92\x9e\x81\xb4\x0d\x1a\xe0\x5b\xe9\x25\xc7\x45\xd6\x3a\x38\x0f\x7f\x44\x42\x19\x27\x71\x14\xcf\xd3\xc9\x0d\xc4\xd5\x70\xec\xc3\xe4\x93\xe5\x3c\x82\x02\x3f\xf3\x9d\x27\x64\xb5\xb7\x8a\xca\x23\x0a\x94\xce\x13\x30\xf2\x49\xed\x3b\xa5\xf7\x4a\x8e\xd2\x38\x4a\x63\xb2\x75\x11\xa2\xa8\x50\xd2\xa8\xed\x47\x97\xfe\x24\x0c\x8e\x58\xf9\x8a\x2c\xf3\x70\x2f\x6c\x18\x0c\x49\xc2\x57\xf7\x78\x46\xe6\xb5\x2c\x3e\xa2\xef\x00\xa5\x1a\xed\xbd\x07\xdd\x64\xc6\x16\xd2\xf9\x85\x9d\xca\x37\xd5\xb9\xa2\xc2\x2c\x03\xcd\xaf\xca\xa1\x53\xbc\x91\x30\xfd\x99\xa0\x7b\x4c\xa9\x10\x0b\x41\x4d\xea\x66\x36\x4e\xe2\x2b\xa4\x76\x4f\x2f\xaf\x74\x87\x75\x93\x7e\xaa\x95\x3a\xf9\xfb\x4b\xcd\x3e\x48\xb3\x85\x24\xa0\x9f\x4b\x85\xf4\xb3\x98\x18\x00\xb8\x41\x11\x52\x0c\xec\x52\xb4\xc1\x33\xe0\x4a\xb2\x71\x11\x75\x3c\x0c\x21\x98\x73\x4f\xe5\x7e\x06\xb2\x84\x3c\x4f\x3a\x85\x93\x44\x17\xf1\x2d\xbd\xa9\xea\xe6\x36\xe4\x4f\x81\xb3\x0c\x8d\x2d\x1e\x32\xa3\xb6\xdc\xbe\x21\xe4\xb2\xa4\xcd\x0a\x09\xea\x31\x76\xdd\xc7\x06\x1b\x35\x16\x9d\x0c\x48\x81\x37\xe4\xbb\x45\xc9\x44\xeb\x3d\x04\x61\x42\x0b\xdf\x18\x61\x02\x4e\x32\x75\x72\x26\x73\x37\x52\x4c\x1f\x80\x16\x55\x1a\xe4\x7a\x36\x98\x8d\x0a\x6f\xe5\x5e\xa4\x97\x2e\xa2\x3d\xa5\x43\x82\xe8\xd0\x82\xed\x0f\x67\x62\x5f\x25\xd2\x26\x3f\x13\x32\x91\xcf\xa0\xb8\x8c\x4f\x95\x5d\x35\x57\x48\x4b\xa2\xae\xba\xeb\x3b\xb7\xfb\x45\x3b\x77\x46\x8e\x54\x4c\x70\xd1\x11\x25\xdf\x8e\xc4\xa7\x85\x1c\x9b\xa6\xf0\xb8\x05\x68\xfb\xc1\xc2\x25\x63\xf9\x2a\xcc\x92\x38\x26\x59\x1c\xc4\x68\x38\xc1\x7e\x34\x9f\xa1\x08\xe0\x93\x01\x16\xc7\xf6\xa2\xa1\x92\xb0\xb7\xac\x3c\x8a\xa4\x1c\x10\x45\x34\xae\x8e\x25\x11\x8e\xce\x68\xe9\x73\x22\x24\x91\xea\x3d\x44\x81\x84\x41\xcf\x00\xd4\xb3\x81\xec\xe5\x3f\x6f\x79\x6a\xfb\xb5\x35\x7d\xf4\x15\x06\xc0\x04\x30\x75\x37\x67\x08\x55\xc4\x0a\x5f\x30\xb9\xf1\x4c\x08\xa5\x44\x04\x65\x66\xb4\x70\xba\xb9\x08\xc9\x91\x2e\xd4\x75\xc7\xa4\x8e\x65\xce\x8d\xb9\x2d\x1c\x79\x01\x42\x25\x52\xa8\xcb\x3b\x44\x2d\xcb\x2c\x83\xfc\x4a\x1a\x9e\x1c\x7f\x36\x3a\x15\xa6\x51\xfd\x8c\x6f\xd2\x4a\x5e\xb7\xca\xb5\xbc\x9b\x9b\x9b\xa8\x8e\x7e\xf8\x01\xb9\xc6\x90\x10\x53\x72\x4a\xdf\x57\x94\x42\xaf\xd4\x71\xd6\x05\xe0\x82\xf1\xce\x77\x9f\x04\x13\x5e\x40\xe4\x7f\x3e\xec\x53\x3c\x1c\xfb\x51\x98\x4e\xf9\x31\xb4\x98\x39\x00\x80\xe2\xe1\xa5\x6d\xc8\x03\xfb\x19\xe3\x99\x48\x03\xc2\x3b\xbb\xf6\xe3\xef\xe9\x38\x8c\x48\x43\xd7\xc3\x78\x3a\x9b\xe0\xeb\x30\xbb\xe9\x75\xe0\x48\x46\x0a\x10\x82\xa8\x90\xcd\xe1\x33\xbe\xa1\x9a\x02\x31\x9a\xd2\x78\xad\xad\xa1\x04\x4f\xe3\x4b\x8c\xfc\xc9\x04\x7a\x95\x7a\x08\x5f\x0f\xf1\x2c\x03\xb1\x9f\xbd\x92\xcb\x67\x63\x7c\x83\x22\x4c\x47\x64\x80\x59\xfd\x80\xf4\x78\xee\x4f\x26\x37\x68\x70\x03\x43\x46\x86\x87\x65\xf4\x00\x9a\xf9\x85\x6c\x48\x61\x74\x51\xa9\x4a\xfb\x40\xe5\x3b\xa5\x77\xe8\xcb\x17\x82\x6f\x2d\x8c\x02\x7c\x7d\x38\xaa\x80\x9b\x22\x21\xb6\x4f\x2f\xaa\x30\xf9\xab\x0d\x7d\x83\x90\x28\xec\x33\xbe\x39\xaf\x89\x95\xa8\x9b\x43\x9b\x14\x49\xca\x1b\xa6\xc9\x7f\x63\xf2\x84\x53\x26\x99\xf7\x21\xb5\xcd\x45\x71\x54\x86\x27\x50\x93\xda\x22\x9a\x64\x16\xc3\xa6\x0a\xd4\x41\x85\xa8\x4b\xc0\x59\x3a\x93\xe2\x4c\xe9\x3d\x01\x2c\xa9\x22\x3d\x34\xac\xed\x9c\xee\x7d\x3a\x3a\x7c\xff\x7e\xff\xc3\xdb\x4f\xa7\xfb\x07\x3b\x87\x1f\x4f\xe5\xe3\x51\x99\x19\x30\x85\x2a\x45\x62\x7a\x94\xa3\xa3\x29\x93\x11\xbc\x58\xe4\xce\xb3\xf3\x57\xea\xfb\x7d\x70\x37\xe6\xaf\xcb\x2d\x55\x01\xb0\x36\x9b\xa7\xe3\x8a\x4e\xf7\x4c\xc4\x53\x4a\xef\x07\x29\x2d\xfc\x19\xdf\x54\x8d\x31\xc8\x01\x2e\x31\x78\xa5\xc4\x4d\x01\x99\x35\xca\x97\xd4\xd4\x9f\x29\x4c\x32\x04\xb2\x05\x86\x02\x24\x46\x48\x53\x1d\xa6\x03\x7f\x26\xa9\x2e\x24\xbd\xb6\xea\x29\x4e\x05\x57\xe0\x1a\xd5\x3f\xf5\x31\x38\xf0\x67\x67\x50\x2d\x84\x2d\x9e\x8f\xcc\x19\x14\x3f\x97\x3c\xd2\x45\xe3\x8a\xdf\x3c\x5a\x5a\x66\x8e\x55\xa9\x59\x09\x6f\x72\x7a\xb8\x7d\xd8\xe3\x44\x86\x26\xf1\xc5\x7f\xe8\x52\x75\xec\x90\xab\xef\x2b\x49\x97\x50\x16\xa4\xd6\xa3\x23\xfb\x56\x9b\xfa\xb3\x8a\xcb\x58\x81\xff\x81\xfd\x62\x90\x8f\x32\x19\x7b\x76\xd4\x0b\x03\xd9\xf1\x46\x50\xc4\x67\x8c\xd2\x79\x02\x7a\x62\xce\xac\xc2\x14\xa5\x59\x48\xe8\x81\x72\x72\x1c\x20\x7f\x04\x0e\x42\x49\x12\x5e\xfa\x13\x6d\xaf\x55\x60\x92\x01\x01\xb7\x7f\xba\x34\xc2\xe0\x5c\x47\x31\xef\x52\x6d\x98\xdb\x03\xa8\x75\xc4\x17\xa7\xc3\x0c\xd7\x9d\xc8\x9f\x6e\x11\x9e\x30\x3d\xb3\xa5\xc6\xc8\x9f\xa4\x58\xbe\x65\x63\x6e\x4f\x0b\xc7\x94\xd5\xff\xee\x3b\xd6\x26\xba\x03\x0c\x32\x2f\x30\xe3\xd2\xa2\x75\x1c\xfe\x5f\x19\xe3\xf9\x1d\xd4\x2c\x31\x8e\xe5\x15\x03\x48\xa1\x30\xa9\x97\x50\x51\x1d\x25\x6d\xb1\xbb\x87\x49\xc5\xc5\xad\x67\x40\xf2\x25\xa7\x2b\x71\xda\xb1\x1e\x54\x43\xbd\xf1\xd2\x32\xa8\x99\x19\x68\x98\x42\xfa\xfb\x5e\x13\x42\xfb\x30\x65\xf8\xf7\xbd\x16\xb8\xa1\xae\x97\xb9\x23\x63\x31\x37\x71\x96\x85\xd1\x85\xdd\xb3\x17\x18\x53\x20\x25\xa0\x46\x9b\xc2\x67\xed\x95\x51\x22\x0f\x8e\x2d\xec\x83\x5c\x41\x8b\x58\xa3\xac\xdf\x04\xe5\x8d\xa7\x6b\xbd\xa7\x6b\xbd\x7f\xf8\xb5\x1e\x8b\xe8\xcb\x4e\x2d\x77\x89\xea\xbb\xc8\x1c\xd6\x91\xc2\x46\xcb\x60\xb3\x8c\xe1\x2c\x5f\xd2\x0d\x76\x38\xd8\x0a\x82\x14\x86\x4e\xec\x6e\x7e\x04\x6a\xa9\x14\xcd\xa9\xf8\xc5\x9c\xde\x3c\x22\x7c\x85\x19\x44\xca\x43\x90\xc7\x83\x6e\xaa\x74\xb7\x7f\xfe\x5c\x3e\x1f\xb0\xf3\xd9\x73\x5d\x49\x44\xb6\xcd\xe7\xec\xda\x4a\x2a\x27\xf1\x2a\x1a\xa7\x87\xbb\xd2\x91\x72\x71\xc4\x1c\xae\x14\x8e\xc6\xe4\x26\x32\xf6\x16\x55\xa3\x4b\x28\xa2\xfb\x36\xef\x69\x6a\xd9\x2c\x6c\xf6\x38\xfc\x4f\xdd\xb7\xf4\xed\xc9\xa5\xbb\x14\x16\x82\x3c\x10\x11\xa0\xfc\xc3\x0f\x80\x3b\x55\x4c\x85\xd1\x05\x70\xe3\xaa\x02\x91\x5f\x5f\x2c\xca\x4c\x4c\x21\xca\x5e\xca\x77\xed\xa4\x90\x86\x26\x7e\x0a\xcd\x9c\x64\x64\xb2\xbf\xdb\xdc\x34\x06\x9a\xff\x19\x2f\xd6\xd6\x60\x9c\x54\x92\x82\xa5\x96\x25\x73\x22\xb3\x25\x69\x86\xd2\x98\xda\x39\xce\x66\xc0\xba\xe1\xec\xec\x47\x37\x19\x39\xf0\x7b\x68\x80\x47\x84\x01\xd0\x25\xce\xaf\x50\x61\x34\xa8\x92\x51\xfb\x0b\x47\x95\xef\x2c\x58\xff\xf0\x03\xb2\x8d\x7c\xd5\xa8\x8f\xcc\xeb\x06\x82\xaa\xc5\x3d\xda\xd9\xd9\x98\xf2\xcd\x08\x5f\x67\xa8\x7f\xf4\x11\x0d\x6f\x86\x13\xec\x89\x6e\xc2\xb0\x8b\xcd\x06\x7a\x02\x5d\x66\x36\x4b\xb3\x24\x1e\x12\x9e\x95\xd2\xd1\x31\x5a\x91\x8e\xc1\x62\x99\xd8\xe6\xc2\xd2\x11\x46\x1a\x7a\xa9\x5b\x0f\xd5\xcb\xf4\xcf\x32\xac\x94\x14\x5c\xa2\x99\x64\x0c\xf6\x5c\x00\xd0\xcd\xd8\x24\x5d\x6c\xc5\xb4\x83\x72\x64\xed\xd6\x2d\xa1\x6e\xbd\x5c\x08\xdf\x0f\xbc\x9c\x4d\xb0\xf7\xb2\x0e\x89\xea\x0c\x80\xb3\x90\x75\xc2\xed\x24\xf7\xad\xd9\x75\x9d\x29\x73\x8b\x4d\xe6\x35\xf9\x0f\xc9\xba\xa6\x7d\x22\x47\x4b\xca\xa9\x15\xca\x85\x57\x56\xa4\x72\x62\xbd\x4a\x27\x7d\xf8\xe0\x07\x81\xb0\xed\x92\xd2\xf7\x8a\xef\xfa\xf4\x48\x07\x07\x89\xc5\x72\xe3\x2d\x78\x2f\xd9\x8a\x53\x81\x4e\x8c\x84\x6c\xe9\x9b\xb7\x5b\x68\xb1\x18\x8e\xf2\x57\xaa\x56\x2a\x67\x41\xa0\x55\xd0\x90\x2f\x85\x84\x3c\x8b\x6e\x89\xd6\x20\x30\xa1\x72\xae\x48\x73\x50\x2d\x18\x6d\xab\x54\x2b\x10\x72\x1b\xb0\x11\x59\x5d\xcd\x76\x41\x64\xdf\xa7\x54\xc3\x4f\xb2\xef\x3f\x5d\xf6\xcd\x4d\xda\x78\xde\xed\x87\xf2\xd1\xdd\x1f\xf8\x91\x2a\xed\x86\x03\x5f\xb8\xde\xe2\x6b\xaa\xae\x2e\x72\xdd\x3d\x99\xfa\x49\xb6\xc3\x0a\xe6\x6e\xb7\xce\xab\x31\x50\x2b\x41\xb3\xbc\x2f\x9a\xce\x5b\x7a\x2d\x2e\xc1\x4e\xb2\x24\x8c\x2e\x6e\xc1\xb5\xc5\xf6\x9e\x48\xcb\x03\x3f\x92\x3f\xfd\xec\x4f\xe6\xf8\x16\x5d\x92\x7f\xd8\x75\x08\x81\x3c\xc2\x09\x5e\x70\x43\xea\xa9\xe6\x05\x10\xa4\x86\xe1\xa4\x8a\xc5\xd9\xd8\x03\x8c\x88\xb4\xee\xd1\x96\xcc\x2d\x0c\xd4\x6e\x74\x94\x21\x69\xfc\xc0\x8f\x2a\x59\x5c\x65\xaa\x22\xd0\xe1\x90\xcf\x5c\xe5\x53\xb1\x58\x11\x91\x7a\x90\x26\xa2\xf2\x22\xa4\xea\x1b\x0a\x91\xf9\xe9\xbe\x30\xf5\xc7\x0c\xe2\x76\x98\x10\x59\xcc\xe6\x10\xc3\x7b\x74\x1a\x33\xcf\x5e\xb9\x3b\x50\x9d\x41\xaf\x54\xcd\xae\xf1\xf6\x84\x1c\x03\xdd\xb0\x49\xba\xe0\x22\x21\x3c\xa5\x71\x36\x96\x33\xfb\x57\xaa\xd0\x08\xc3\x36\x4a\xb3\x30\x9b\x53\x81\xcb\x34\xff\x0a\xf0\x2c\x4e\xc3\x4c\xc6\x92\xc1\x15\xe8\x01\x98\xe1\x24\xc4\x51\xa6\x5b\x62\x94\x6e\xd8\x30\xb1\x60\x8d\x58\x46\x70\x59\x8c\xcc\xf1\xe3\x2a\xf8\xc2\xab\x64\x41\x7a\xa3\x79\x14\x80\x4d\xe4\x10\x27\x99\x1f\x8a\xe9\x77\x2c\x1f\x31\xb1\xcb\xad\xa3\x47\x5f\x42\x02\xaf\x3b\xac\x25\x36\xf2\x64\x36\xb5\xdc\x62\x92\x6c\x2b\xbc\xd7\xb3\x38\x97\x68\x09\xe8\x1e\x6d\x40\xa2\xcd\xc9\x1c\xf7\xe8\x3f\x5c\xcc\x1d\xaa\x89\x58\x9c\xb3\xc2\x26\x3f\x9f\x14\x88\x6b\x1f\x0e\x11\xe7\x84\x88\x73\x48\x54\x99\xce\xd3\x0c\xb6\x3a\x3c\xc5\x51\x26\xe8\x66\x70\x93\xe1\xb4\xd5\xac\x32\x61\xfc\xbb\xaa\x36\x91\xac\xdc\x83\x4f\x5f\x6a\xcc\x1f\xaf\x4e\x29\x15\xcd\xa3\xf0\xbf\xe7\x18\x85\x01\x8e\xb2\x70\x14\xaa\x9c\xb8\xd4\x5c\xf3\xd1\x29\x31\xc3\xd0\xa4\x9d\x6b\xfa\xb0\xeb\x48\x7b\xd0\x2b\x9d\x08\xf8\x18\x57\xfc\x41\x58\xad\xf9\x19\x61\xac\x35\x3e\xbe\x1c\xf4\x9f\xf7\x25\x02\x43\x56\xe5\xa3\x68\x0d\x82\x60\xee\x87\xdf\xf7\x5a\x44\x74\x25\xcc\xe0\xfb\x5e\xab\x75\x7b\xee\x75\x4a\x65\x3c\x67\xda\xdd\x4e\xa9\x7c\x6d\xaf\x64\x25\x7c\x4c\xe4\x8b\x91\x3f\xcc\xe2\xe4\xc6\xa3\x0a\x65\x32\xb0\xcf\x08\x9b\x26\xa2\x7e\x3c\x42\xa2\x37\x9b\x9b\xe8\x7b\x1a\x90\xe9\x7b\x28\xf3\x6c\x6d\x0d\xf5\xe3\xe9\x34\x8e\xfe\xeb\xe4\xf9\xb3\x67\x46\xe7\xf3\x5f\xac\x01\x8e\x53\xe5\x7b\x32\x0c\x09\xfe\xbe\xea\x21\xe9\x15\x8e\x86\xab\x03\x3f\xc5\xdd\xb6\xf6\x61\x1a\x74\xf4\xa2\x97\xb3\xcf\xc1\x48\x7b\x39\x0c\x67\x63\x9c\xac\x52\xc8\xd5\x57\xcf\x9f\xdd\x3e\x7f\x86\x27\x29\x46\x52\x67\xa8\xc2\x9c\xf6\x85\x0f\xc3\xf7\xe8\x87\x1f\xd8\x87\x9a\x3f\x0d\x44\xdf\xb6\x0e\xb6\x9f\x3f\x7b\x46\x3f\x54\xce\x38\xce\x1e\x52\x51\x85\x67\x82\x21\xfd\x40\x11\x83\xdf\x32\x3e\xe7\x62\x94\x65\xc4\x58\x43\x34\x1a\x06\xaa\x0c\x92\xf8\x2a\xc5\x49\xf5\xf9\xb3\x67\x62\xc4\xe2\x38\xab\xf5\x93\x9b\x59\x16\xff\xd7\x09\xad\x7a\x0b\xa7\x27\x79\xfb\x11\xdf\xd1\x9f\xcf\x9f\x3f\xab\xa8\xc7\x31\xc8\xc4\xbb\xb6\x46\x38\x53\x92\x0d\xe7\x59\x4a\xdf\x90\x65\xd3\x47\x9b\x88\xd7\x7d\x25\xbd\xfe\x34\x09\x07\xe4\x53\x6d\x12\x0e\xa4\xf7\xa0\x0c\xeb\x43\xa7\xc8\x57\x52\xaa\x26\xbd\x53\x20\xf8\x93\x8b\x18\x40\x90\x1f\xaf\x9e\x0b\x2c\xde\xc7\xf1\xe7\xf9\x0c\x65\xfe\x60\x82\x25\x4c\x4e\xde\x1c\xfe\xca\xce\x7c\xe2\xdd\xfe\x87\x9f\x3f\xd9\xde\x9f\x7c\x7c\xf3\xe9\x60\xff\xd7\x4f\x75\xd7\x87\x86\xeb\x43\xd3\xf5\xa1\x65\x6d\xdb\xd5\x8e\xfc\xd1\x68\x4b\xfe\x68\xb4\x27\x7f\xe4\x6d\x8a\xa1\xe9\xc7\xd3\x19\x39\x28\x4e\xcc\x21\xb2\x4d\xa9\x56\x2b\x88\xe7\x03\x22\xf5\x93\x5a\x79\x01\x60\xb1\x32\x16\x48\xb6\x54\x08\x21\x9a\x20\x0a\xd1\x6b\xd4\xec\x74\x5f\xa1\x70\x65\x45\x01\x2f\x64\x44\xf4\x1a\x35\x9a\x1b\xc6\x37\xf2\x17\x9c\x85\xe7\x68\x93\xc0\x78\x8d\x1a\xaf\xd4\xef\xf4\x2a\xb5\xa0\x56\x85\x56\xab\xa2\xdf\x50\xfd\xba\xd1\x18\xe8\xf5\xf3\xc7\xdb\xe7\x4a\xaf\x7f\xf1\x27\x9f\xd1\xdb\xdd\x4a\xf3\xb7\x8d\xaa\xda\xdb\x6b\x1a\x21\x51\x7d\x17\x6a\x2f\x97\x1a\x01\x69\x90\xd3\x41\x7c\xad\x7e\x04\x43\x03\xd2\xe6\x75\x88\x7e\x43\x95\xeb\xbc\x43\xec\x77\x53\xfa\xdd\x92\x7e\xb7\xab\x5a\x67\x01\x4a\x25\xbd\x46\x3f\xfd\xf4\x13\xda\x80\x92\xe9\x35\xfa\x01\xd5\xaf\x47\x23\x3a\x40\xdd\x96\x56\x85\xac\x8e\xb3\x6b\x32\x90\xe9\xb5\xf6\x89\x2f\x9e\xb3\x14\xbe\x5f\xbf\x7a\xee\xec\xd4\x74\x3e\xc9\xc2\xd9\x24\x1c\x82\x96\xc0\xec\xde\x35\x21\xe3\xe0\xec\xfa\xfc\x95\xe5\x5b\x9b\x7e\x6b\x5a\x3f\x6e\xd0\x8f\xed\xf3\x82\xd6\xd3\xf9\x00\x81\x7c\xe3\xa1\x69\x78\x8d\x86\xf1\x64\x3e\x8d\x52\x85\xfa\x65\x98\x44\x52\xa8\x04\xd0\xab\x1f\x09\xcd\xd4\x1b\x7c\xa4\xd8\x63\xbd\x51\xaf\xeb\x43\x2b\x56\x32\x1d\xac\x4a\x06\x13\xd3\xae\xa2\x2f\xe4\x37\x1d\x6f\x47\x95\x86\x5c\xa5\xd1\x95\xaa\x34\xba\xae\x3a\x4d\xb9\xce\x46\x15\xe5\x75\x9a\xc6\xac\x0b\x6e\x40\xeb\x64\x05\x23\x15\x46\x97\xf2\x68\x91\xc7\xd2\x23\x76\xbd\x21\x8d\x0f\x23\xcf\x36\x7b\x55\xe7\x2f\x9a\xca\x90\x16\x8e\xa8\xc2\x1f\x19\x8d\x95\x19\x56\x85\x75\x2a\xf5\x16\x8c\xad\xc2\x56\x95\x8a\x0b\x06\x58\x61\xb9\xac\x62\xd1\x28\xc3\x65\x01\xe8\x81\x71\x62\x72\xc2\xef\xae\xad\x4c\x90\x31\x80\xcd\x25\x38\x20\x54\x69\xa2\xdf\x50\x70\x46\xfe\x77\xbd\x81\x7e\x43\xd7\xcd\xf3\x73\x7d\x21\x41\xd9\x10\xfd\xb6\x09\x05\xaf\x43\xa3\x80\xc2\x24\xe1\xe7\x2d\x9c\x69\xc5\xbe\x72\x94\xe0\x21\xed\x5c\x80\x8e\x87\x71\xc4\x36\x98\x7c\x57\x3a\xee\x1f\x7e\x20\x7b\x44\xfd\xba\x5e\xf7\x50\xfd\xba\xde\x80\xff\x36\xe1\xbf\x6d\xf8\xef\x86\x07\xb4\x40\xfe\xdb\x84\xff\xb6\xe1\xbf\x1b\xf0\xdf\xc6\x80\xfc\xb7\xd5\xcd\x37\xb3\x1f\x7f\x64\x48\xfd\x88\xb6\x76\x4e\x68\x3c\x76\x44\xc5\x21\x44\x04\x82\x24\xcc\xc6\xd3\x1a\x2f\xb3\x96\xa3\x42\x4a\x6f\x32\xf1\xa1\x46\x1f\x24\x09\xa3\x86\xaf\x33\x1a\x3d\x40\x74\xf9\x53\x10\x1f\xe3\x14\x67\x3d\xe4\xd8\x22\xd9\x20\x9c\x7c\x0e\x67\xcc\xf2\x37\x1e\xa1\xe8\x38\x86\xd3\xd8\xd8\x4f\xd1\x00\xe3\x08\xbc\x03\xd8\xfd\x96\x1f\x05\x60\xc2\x17\x84\x01\x8a\xe2\x8c\x99\x61\x9a\xa4\x40\x93\xb9\x70\x48\xdc\x5c\xf4\xd3\x67\x7c\x73\x94\x84\x71\x72\x4c\x2d\x80\x37\x37\xf3\xf7\x56\xd2\xe1\x66\x61\xda\x9c\x9a\x1d\x50\xc5\x37\xfe\xc7\x0d\x0e\x37\xed\xcd\xe7\x6f\x2d\xfc\xf9\x33\xbe\xf9\x25\x4e\xc0\x88\xf1\x33\xbe\xa9\x5d\x91\xdf\xf6\x62\x27\xe1\x1f\x98\x95\x4a\xc3\x8b\x37\x84\x01\xa1\x35\xd4\x2e\x5a\x46\xc2\x0f\x20\x81\x01\x32\xc1\xf2\x91\xe3\x38\xe6\xcf\xbc\xc1\x15\xd4\x2d\xd5\x02\xe9\x7f\x3a\x1c\x63\x72\xfc\x40\x44\x84\xb6\xf4\x21\x3d\x8e\xaf\x08\xec\x0a\x6f\x66\x85\xec\xd2\x3f\x16\xf6\x41\x86\x6b\x1f\x16\xde\xa8\x34\xce\xd2\xbb\x33\x7d\xa9\xe6\x26\xa2\x04\x1d\x2a\x7a\xd0\x9f\xaf\x19\x86\xec\xd9\x22\x85\x20\x46\x76\xa2\x3c\x1d\x24\x6b\x39\xf2\x27\xa1\x72\x06\x75\xce\xe9\xc8\xc2\x8c\xb3\x37\x16\x56\xe3\x66\x58\x48\xda\x4f\x0c\xe0\x10\x4d\x47\x1f\x4a\x19\xed\xef\x18\xe2\xff\x12\x88\x3b\x31\x67\xb3\x70\x1c\x67\x88\x90\xa4\xbb\x50\x26\xef\x01\xea\x16\x50\x08\xf9\x64\x3e\x28\x03\x19\xc4\x27\x0e\xf3\x5c\xda\xdb\xe0\x43\xbe\x53\x31\x19\xed\x5c\xda\xc5\xe4\x12\x1b\x4a\x01\xc0\x94\x41\x66\xaf\x17\x60\x7b\x10\x5e\x03\xdb\x2e\xc2\xf6\xb7\x4d\x60\xe2\x67\x6c\x90\xd7\x72\xea\xf8\x82\xea\x0c\x75\xcb\x64\xa3\x7c\xc2\x81\xb4\xd8\xba\xfb\x09\x75\x09\x3f\xd3\x26\x0c\x6d\x6e\xa2\xf6\xa2\x49\xfb\xe6\x86\xd6\xde\x67\xc7\x88\xbb\xd6\x8c\x41\xeb\x6c\x48\xce\xd1\x6f\x44\x96\x30\x17\xd1\x42\x6e\x2e\xcb\x74\xc5\x6c\x26\x8c\x2e\xdf\x59\x38\x8d\xf1\xda\xcd\x6c\x48\xd1\x9c\xdf\x88\xa7\x9c\xe5\xf0\x57\x0e\xae\x23\x33\x2c\xc6\x47\x57\x45\x1d\x1b\xf1\xc2\x91\x91\x37\xf3\xaf\x02\xa2\x71\xb2\x93\x87\xe5\x4c\x6d\x2b\xb8\x45\x88\xbf\x46\x6d\x70\x64\xa1\x0f\x45\xb4\xaf\xce\xc5\x19\x87\xc0\x24\xcd\x25\x3b\x52\x00\x4c\x15\xba\xd5\x35\x44\x48\x51\x15\xae\x1d\x4b\xe9\x1c\xfd\xe6\x5e\x9c\x8e\x3f\x55\xf8\xb6\xaf\x40\x1d\x81\xd6\x99\xba\x14\xed\x73\xe0\x94\x64\x3d\x69\x7a\x70\x34\x4c\x6e\x66\xd4\x32\x56\x96\xf3\x0e\x3c\x14\x8f\x46\x29\xce\x8c\x99\xa1\x6b\x24\x88\xfb\xa2\x5e\x5e\xd8\x33\xf7\x6a\x2f\x3f\x21\xe6\x3f\x1b\xf9\xcf\x66\xfe\xb3\xe5\x01\x8b\x91\x4f\x19\x0a\xae\x01\x5e\x16\x57\xc2\x35\xaf\xfc\x19\x6a\x46\x01\xc8\x9e\xed\x6c\xec\x10\x62\x08\x7d\x1f\x9c\x51\x30\x44\x7e\xd1\x87\x54\xf9\xa6\x96\x6d\x15\x94\x6d\x59\x8f\x44\x65\x86\x50\xa5\x55\x4f\x25\x50\xf5\xb1\xa1\x3e\x36\xd5\xc7\x96\x27\x14\x16\xc6\xe6\xbd\xb6\x86\xf6\xc9\xc9\xf7\x9b\x18\x23\xfb\xa4\x2b\xc3\x64\x9d\x75\x0f\xdd\x8f\xdc\x6c\x44\xc3\x0e\x04\xa5\x25\x6b\xcb\xc0\xbe\xc5\x2c\x56\x28\x5c\x48\x52\x51\x9d\x60\x6a\xd1\x71\xd5\xa5\xc1\x3a\x87\xd7\xbf\x29\xcc\xb6\x6e\xd3\x00\xa5\x0d\x7d\x3a\xb4\x5a\xc6\xfc\x40\xad\xa6\x5a\xab\xa9\xd7\xb2\x6a\x9b\xd2\x96\x3e\x9d\x5a\xad\x96\x4d\x0d\xf5\x4e\x3b\x3b\xd8\x8f\xfe\xf2\x16\x68\x3b\x31\x1c\x5b\xce\x38\x62\xff\xa5\xa3\xba\x89\x1a\xaf\xd8\xcf\xd7\x7c\x86\xd8\x0b\xc7\xbe\x0b\x73\x1c\x8e\x32\xa0\x74\xcf\xa1\x28\x2b\x9c\x38\x8e\x7a\x46\x26\x4f\x52\xd7\xd4\x85\xe4\xf5\x9b\xa4\xe8\xaa\xa4\x0d\x43\xee\xfa\x4d\x52\x6a\x55\xd2\xa6\x2e\x75\xfd\x26\xe9\xaf\xd2\x96\xf4\xda\xd8\x86\x57\x56\x6c\x1b\x00\x20\xd7\x50\x91\x6b\x38\x90\x6b\x2e\x40\xae\x55\x88\x5c\xfd\x8e\xc8\x35\x55\xe4\x9a\x0e\xe4\x5a\x0b\x90\xab\x17\x22\xd7\xb8\x23\x72\x2d\x15\xb9\x96\x03\xb9\xfa\x02\xe4\x1a\x85\xc8\x35\x17\x22\x67\x25\xdd\x8f\x33\xb0\x21\x4a\x33\x3f\xc3\x66\x01\x60\x27\x59\xdd\xd2\x31\x60\x19\x99\xae\x47\x83\x2f\x64\x2e\xb2\xa6\xed\x0b\x19\x88\x4c\xd7\x8e\x5b\x95\x28\xd6\xf5\xb4\x80\xf7\xc1\xf2\xa9\xd0\x93\x87\xb4\x76\xf4\x53\x8b\x65\xf9\xe8\xc7\x16\x73\x05\x29\xe7\x96\x7c\x09\x55\xcb\x51\x82\x58\x3f\x1c\xbb\x86\x1b\x3b\x73\xfd\x18\xd8\x19\x4b\x48\xc5\xae\x7e\x17\xec\x9a\x12\x76\x4d\x37\x76\xe6\x02\x32\xb0\x33\xd6\x90\x8a\x5d\xe3\x2e\xd8\xb5\x24\xec\x5a\x6e\xec\xcc\x15\x64\x60\x67\x2c\x22\x15\xbb\xe6\x62\xec\x4c\x6a\xc5\x3c\xb0\xb5\x5d\x2e\xa1\xdb\xb0\x65\x1d\xe9\x42\x8e\xb1\x9c\xd4\xcd\xd5\xb2\xaa\x0c\xd1\xa7\xe5\x92\x7d\xd8\x51\xb8\x87\x9a\x9d\xee\x5a\xab\xc9\x34\xd0\x55\x9b\x2a\x98\x4b\x2c\x42\x40\x4a\x99\xe3\x30\x53\x0d\xbf\x48\x59\xbe\x27\x04\x29\xbc\x47\xfe\x10\x0b\x1d\xb1\x00\xf2\x9f\xf8\xda\x9f\xce\xc4\x49\x39\xff\xc0\xe7\x94\xc2\xca\xf0\x75\x26\xdd\x6e\xd7\xb6\x76\x4e\x6a\xec\x1c\x51\x99\x72\x8b\xf4\xcf\xf8\xc6\x43\xc3\xd1\x85\x90\xe6\x73\x28\xb3\x89\x4f\x90\xb8\xce\x90\x0e\x85\x49\xf8\x95\xbc\x1d\x1b\x20\xa6\xd3\xee\x5b\x94\xd8\x9f\x68\xd4\xd4\x3d\x3c\x99\xe1\xa4\xb2\xb5\x43\xaf\xf5\xa9\xce\xfe\xf9\x33\x66\xb3\x22\x37\xf9\xea\xf9\x73\x88\x80\x0b\x06\x24\x8a\x55\x41\xaf\xd3\xf4\xb8\x5d\x42\xaf\x03\xb6\x23\x92\x65\x42\xaf\xd3\xf6\x72\x93\x84\x5e\x07\x5c\x18\xa7\x41\xe7\xfb\x5e\xb7\x71\x7b\xee\x75\x9a\xf7\xb2\x16\xf9\x9a\x66\x22\x8f\x66\xcc\xf1\x15\xcd\x32\xe8\x4a\xf8\x11\x31\x03\x0a\xd2\x3c\x1a\xc6\xd3\x59\x1c\x41\xc8\x75\xf2\x6d\xed\xf9\x33\x31\xef\x93\x70\x50\x63\x45\xbf\x7c\x91\x0d\x00\x84\xd3\xe7\x03\x1b\x77\xf8\x29\xce\xad\x3a\xfc\x14\x4b\xdf\x7e\x89\x93\x00\xdc\xd2\x45\x01\xf1\x46\x86\x30\x1f\x81\xbd\x18\xd0\xfa\x16\xbf\xe5\xc9\x61\x5a\x3f\x2b\x98\x61\xf0\xac\xea\x93\x85\x2a\xbd\xff\x98\x8d\x36\x00\x0a\x8e\x86\x35\xf2\xa0\x61\xdd\x6d\x8b\xaf\xf4\xb1\xc8\x10\x45\x7c\xd9\xb9\x9c\xbd\xdb\xde\xcd\x2f\x9b\xe8\xb3\xf5\x06\x6b\x90\x52\xf3\x3c\xb2\xac\xf8\x2d\x56\x86\xa7\xb3\x89\x9f\xd9\x18\x94\x08\x32\xfd\x67\xc4\x02\xf2\x70\x0d\x2a\x38\x15\x08\x5e\x07\x7a\xbf\xf0\x0f\x5c\xe3\x01\x26\x7b\xa8\x8d\x2a\x8d\xe6\x06\x1a\x84\x59\x5a\x2d\x02\x18\x5e\x5a\xe0\xed\xff\x7c\x57\x70\x9f\x76\x3e\xf4\x3f\xfd\xba\x7b\x78\x7c\xf0\xe9\xe0\x70\x7b\x07\x6d\x41\x68\x83\xcc\x8f\x32\x94\xe0\x59\x82\x53\x1c\x65\x61\x74\xc1\x15\x31\x84\x0c\xa7\x71\x90\xf7\xdd\x0a\x73\x7b\xa7\x14\x4c\xc6\x4e\x0d\x98\xd2\xa5\xa0\x66\x72\x24\x1e\xed\x14\x65\xb9\x24\xcc\x67\x93\xa2\xdb\x07\xb7\xef\x79\x02\x06\x0f\x22\xc7\x87\x5c\x44\x29\x2e\xf5\x4e\xd0\x3d\x99\x03\x74\x3a\xc6\x64\xd4\xb3\x18\xcd\x99\x9b\x00\x61\x01\x88\x14\x06\xd0\x0a\xc8\xb5\xfc\x61\x38\xba\xe8\x01\xe9\x72\x5c\xab\xf2\x8e\x6a\x60\x0b\xdb\x45\x4a\x61\x33\xf2\x0b\x23\xd7\x64\xd8\xd0\xa7\xf6\x98\x12\xee\x84\xf4\x08\xf2\x9f\xf1\x4d\xcd\x5a\x96\x7b\x86\x0e\x47\x17\xa8\x72\x08\xad\xf8\x93\x2a\xd4\x19\xda\x06\xaf\xe4\x18\xa8\x6d\xf1\x38\xa2\x74\x42\x6f\x09\x89\xf0\xde\x11\x42\x19\x16\xf5\x89\x9c\x2b\xc2\xa1\xfb\xbb\x2a\x25\x98\x05\x90\x22\x2d\xc8\x7b\x3c\xbf\x7a\xae\xd1\x6d\x7a\x87\x0e\x73\x9c\x54\xd8\xe5\x19\x0c\xa1\x87\xfe\x44\xe1\x65\x0f\x85\x97\x39\x6f\xbc\x55\x4c\x0f\x94\xf9\x56\x21\xf5\x94\xb0\x50\x4c\x72\xd0\x35\x00\x72\xe2\x10\x5a\x9f\xdd\x38\xab\x6b\xd5\x22\x7b\xe8\x12\x5a\x49\x7a\x72\x2c\xc4\x27\x7a\x7a\x58\x7a\xda\xc6\x0f\x45\x4f\x02\xd2\xfd\xe8\x49\xe5\xd3\x77\xa0\xa7\xfd\x28\xcc\x42\x7f\x12\xfe\x81\x53\xe4\xa3\x08\x5f\x4d\x6e\x18\x86\x01\x1b\x8e\xc5\xb4\xc4\x77\x8d\xeb\x51\x9c\x4c\x0f\xe2\x00\xa3\x1d\xea\xab\x06\x61\x9a\x73\x4e\x17\x27\x32\x9d\x82\x75\x35\xb8\xf9\x71\xaa\x15\x9b\x8c\x9d\x0c\xbf\x39\x92\x7d\x30\xb2\xaa\x98\x1f\x6c\x9c\xe2\x8e\x04\x17\x46\xa1\x62\x61\x23\xa6\x49\x22\x17\x8b\x8a\x7a\x6b\x36\x23\xb4\x00\xa3\xc5\xb3\x4d\xa7\x96\x6b\x06\x32\xc4\x9b\xe2\x27\xdf\x14\x29\x0d\x9a\xa7\xe2\x8c\x48\xce\xd4\xb0\x3e\x4e\xa6\x74\xda\x7d\x9b\xee\x86\xd2\x77\x4e\x52\x9b\x39\x79\xbd\xb2\x95\xa4\x76\x34\x60\x2b\x63\x3d\x8b\x87\x94\xd0\xa9\x07\x80\xad\x1f\x60\x5f\x54\x29\xbd\x70\xc0\x46\x47\xe5\xc3\x10\xcb\x21\x15\x2d\x81\xf6\xec\x9e\xe4\xc3\x96\xa0\x89\x9b\x32\xc3\x49\x19\x23\x2a\x6a\x54\x14\xf8\x99\x8f\x06\x20\x7b\xa9\x25\x1c\xf2\x18\x80\xa6\x99\x2e\xb8\xb7\xb3\x0e\xf8\x08\x27\x30\x97\xc3\x38\x1a\x26\x38\xc3\xab\x6c\x38\x26\xf1\x85\xc2\x94\xa5\x7b\xa9\xe3\xe5\xc6\x1a\xe2\x69\x00\xe6\xd4\xbd\x85\xf1\x14\x1c\x48\x2c\x05\x07\x4b\x6c\x7a\x5f\x52\xe6\x0a\x43\x80\x32\x65\x27\xe1\x0d\xbc\x0d\xd6\x80\x04\xbe\xc4\xce\x25\xf1\x27\x01\x8b\x06\xcd\x62\xc1\x08\xc2\xe8\xe2\x01\xb8\x49\xde\xf9\x4d\x4e\x1e\x0c\x7e\xe5\x05\x69\xf3\x85\x4a\x26\x65\xea\x5d\x71\xcc\x9d\x14\xc6\x4a\xf6\xb4\x28\xaf\x74\xe8\x1c\xdc\x03\x47\x81\x6d\xf6\x7d\xf8\x22\x57\xb7\xd1\x14\x6d\x0f\xf9\x97\x7e\x38\xf1\x07\x13\x4c\xcd\x10\x53\xf7\xb6\xf8\x89\x77\xa6\x34\x55\xed\x86\x11\xdb\xf8\x0a\xf7\x29\x06\x57\xdd\x67\x3e\xc4\x19\xf3\x8e\xa6\x41\xd3\x28\xa4\x7c\xd7\x40\x61\x8a\xf0\x68\x84\x87\x59\x78\x89\x27\x37\xc8\x47\x01\x4e\xb3\x64\x0e\xcf\x1e\x4a\xb0\x1f\xac\xc6\xd1\x10\x97\xda\x67\xca\x52\x2f\xa0\xf1\x58\x34\x4c\x81\x3f\x36\x25\xf3\x91\xac\x94\x27\x62\x51\x65\x59\xea\x17\x15\x17\x93\x3f\x2f\x5a\x9e\xfe\x77\xf3\xb9\x98\x43\x21\xb5\x44\x38\x2a\x04\x80\x0a\x57\x8b\x52\xd4\x72\x51\xb2\x04\x43\x86\x78\x48\x04\x55\xb6\xe0\x70\xc0\xe2\x65\x72\x4e\xbd\x2b\x4d\x88\x75\xf1\x99\xb5\x17\x2a\x9b\x1b\xcd\x8d\xb5\x56\x53\xfe\x44\x55\x22\xb6\x2f\x9a\x1c\xd4\x43\x0d\xe5\xab\x2a\xff\xf6\x50\xb3\xcc\xd9\x29\xb5\xaa\xb2\xfd\xc5\x8a\x6c\xe4\x5c\x9b\xfc\xd4\xc2\x46\xfa\x74\x8c\x25\xa1\x80\x25\xda\xf2\xd1\x18\xb4\xc6\x44\xc8\x2c\xb1\x14\xb9\x08\xbb\x15\x71\x7c\x20\xc0\x00\x5f\xd6\x44\x68\x62\xeb\xda\xd2\xa1\xaf\x70\x58\x62\xd6\xde\xa6\xca\x53\xd3\x91\x1b\xb2\xad\x73\x95\x29\xf5\x7a\x4e\xbf\x29\xf2\x27\x3e\xa5\x78\x82\x87\x19\x6d\xf8\x24\x4b\xfc\x0c\x5f\xdc\x54\x5c\xe6\xda\x92\xf6\x19\xc4\xc5\x4d\xf4\x82\xb2\xd2\x17\x4e\xf3\x30\x36\x1b\x47\x7e\x9a\x12\x36\xf1\xc6\x4f\x71\xa0\x78\xcc\xc9\x7f\xc5\xc6\x61\x0c\xd4\x09\x4e\xe0\xc0\x45\x76\x35\x37\xa4\xe2\x45\xae\xe7\xf6\x63\xf7\x19\x05\x36\xea\x2e\xa4\x18\x39\xc9\x8c\xcd\xbc\x61\x29\xb2\x1b\xcd\x83\x80\xd9\xe7\x41\x5c\xdc\x50\x14\x3d\xe4\xbe\xc0\xd1\xc7\xc0\x73\x58\x7a\x32\xb2\xef\x19\xfd\xd7\xee\x73\xee\x85\xb6\x7a\x53\xe4\xa1\xc2\x1b\x23\x1d\x73\xcb\x84\xea\x6c\x5b\xe6\x92\x95\x2a\xd3\xf0\xda\xaf\xde\x54\x1d\x76\x9a\x25\xd8\x9f\xde\x49\x95\x0d\x32\x14\x53\x3e\xcb\x36\xf8\xad\xe6\xea\x20\xa4\x06\xdb\xea\x89\x86\x4a\x27\x10\xc6\x5a\xd2\x4c\x37\x50\xa5\xd5\x54\x15\xd3\x92\xc2\xf7\x04\xf0\xd3\xd4\xbe\xfa\xcb\x02\x8f\x90\x5d\xcb\x5e\x6b\xdb\x61\xb9\x88\x38\xf3\x13\x38\x6e\xd9\x04\x44\x73\x7b\x83\xe3\x4d\x6e\x5d\xc5\x85\xc6\xef\xbe\x7b\x31\x9a\xcc\xd3\xf1\x8b\x72\xdb\x1c\x85\xe2\xda\xe8\xc4\x30\xf7\x50\xa3\x68\x5e\xe1\x5c\x0b\x59\x4d\x67\xf2\x6d\xa9\xac\x3c\xff\x34\xa5\x67\xdf\x7e\x8d\xfd\xf8\xf3\x76\x31\x85\x28\x1e\x3b\x50\xcf\xa0\x12\xa9\x0d\xe9\x76\x93\x1d\xb4\x0d\xe7\x60\xf6\x5e\x56\x7a\x17\x29\xe8\x65\x15\xe5\x94\x27\xe7\xca\xe5\xeb\xa5\x77\xd3\x2d\xb5\x47\x56\x85\xa0\x9e\x59\x26\x57\xf0\x03\x55\x7f\x85\xfd\x90\xcf\x14\xdf\xee\x40\x0f\xdb\x7f\xd3\x37\x54\xd1\x9c\xa3\x84\x97\xd4\x6b\xe7\x2e\x9a\xe7\x1c\x46\xa1\xae\x50\xd4\xe5\x8a\x26\xa9\xde\x9d\x34\xce\x62\x3a\xf3\x03\xd2\xff\xcc\xe9\xcc\x35\xc1\x4b\x4e\xa7\x55\xf1\x5b\x72\x3a\x45\xdd\x7b\x4c\x67\x91\xc2\xb7\xdc\xd5\xc1\x57\x9d\xce\x7b\x4f\x57\xc1\x12\x58\x30\x5f\xba\xde\xb4\x60\x92\xe8\x66\x22\xf4\xbc\x43\x9b\x58\xc7\xac\xae\x2f\xd1\x26\x0a\x2f\xe5\xd9\x2a\xda\x22\xd8\x8e\x49\xe3\x4a\xf7\xc7\x7e\x18\x41\xca\x13\xd7\x5d\xeb\x1b\xb0\x1b\xf8\xc4\x3b\x8f\x36\xdd\xc1\x07\x74\x15\x9b\xb2\x83\x90\xba\x06\x31\x48\x43\x93\x37\xa6\xed\x12\xe2\x4e\xf4\x55\x11\x47\x79\xd3\xe7\xdb\x81\x76\x12\x92\x9a\x50\xe6\x8e\xf4\xea\x4d\xdf\xb2\xf7\x98\xe0\x69\x13\x47\x22\xfc\x67\xc6\xd5\x18\x94\x4a\xfd\x8c\x19\x75\xd7\xf4\x3a\x06\x0c\x8d\x66\xa9\x74\x24\xb4\x22\x4c\x58\x8a\xb9\x8c\x84\x54\x4e\x88\xac\x37\x24\xcc\x2e\x8b\x00\x61\x3f\xaf\xc6\x98\x45\xde\xa7\xf8\x41\x20\xcf\xb4\x04\x72\xe6\xc2\xb0\x17\x24\x7f\x30\x95\x4c\xd4\xa1\xde\x00\x90\x1e\x0f\xba\x20\x5c\x1b\x74\x59\x56\x9e\x0c\x94\xab\x00\x0d\x33\x79\x15\x8a\xd3\x16\xda\xea\x00\x8b\xf4\x1b\x12\x79\x21\x39\x0c\x67\x73\x21\x56\x68\x72\xc4\x2b\x87\x39\xeb\xaf\x87\xc7\x70\x5e\x66\x44\x67\x96\xb9\x8e\x13\xe8\x57\xae\xe8\xf6\x90\xd2\x2f\x2f\x6f\xd6\x26\xf4\x33\x3c\x64\x5f\x97\x8a\x3e\xba\x56\xcc\x8e\xf1\x14\x83\x14\x0e\xbb\x2b\x25\x01\x76\x15\x05\xa7\x7d\x70\x68\x87\xd7\x66\x75\x2e\xc1\xe2\x4b\x1e\x76\x9e\x32\x53\x9a\x4f\x9e\xe3\x2d\x4c\x01\x9d\x1d\x90\x3d\x77\x16\xae\xdb\x00\x97\x58\xb7\x62\x9f\x7a\x5a\xb7\x4f\xeb\x16\xdd\x7d\xdd\xde\x67\x75\x80\x85\xf0\x38\x4c\x97\x5e\x1b\x56\x4c\x18\x45\x03\x17\xf9\xf5\xf0\xd8\xc9\x01\x64\x0f\x32\x83\x03\xdc\x97\xed\x58\x31\x3b\xcd\x87\x66\x80\x87\xf1\x94\x2d\x1d\xc2\x16\xc2\x78\x9e\x96\x67\x1e\x62\xb0\xca\xb2\x07\x41\x4a\xbc\x1b\x15\x27\xee\x4b\x79\x40\x81\x88\xc4\xa5\x25\x9b\x87\xff\x38\x8e\x53\x8c\xa6\xe1\x35\x91\x85\x2c\xfd\x03\x4f\x50\x53\x48\x43\x32\x21\x32\x29\xcc\x45\x76\xf1\x25\x48\xa7\xe4\xa4\x93\xce\x07\x29\xfe\xef\x39\x8e\x32\xab\x8a\x01\xa9\xa2\x9d\x94\xd5\x43\x1d\x45\xa7\x6a\x50\x46\x49\x9b\x95\xc5\xaa\x7e\xb2\xb3\xd9\xb0\xb2\xc5\x48\xca\x57\x9b\x35\x52\x12\xf9\x83\x09\xcc\xad\xc7\xc3\x73\xf4\xdb\x26\xad\x77\x16\x16\x86\x2e\xc9\x7f\x73\x13\xe8\x37\x7d\x56\x5e\x09\x68\x22\x89\xb6\x47\x7e\x10\x90\x09\x5c\xa0\x00\x99\x41\x96\xab\x7e\x8d\xfe\x6b\x57\x7f\x1c\xbd\xeb\x9f\xa0\xff\xd5\x59\x5b\x47\x33\x06\x34\x65\xba\x3c\x1b\xcc\xa3\xcf\xc3\x74\x1d\xe4\xe4\x99\x1f\xd4\xf8\x53\x81\x6c\x7c\xe4\xf3\xeb\xe7\x79\xca\x43\xe7\x8b\x40\x28\xcc\x5c\x19\xe2\x26\x0b\x3c\x96\xb2\xbf\x02\xc8\xf2\xed\x33\x41\xcb\x5a\xc9\xae\xc7\x63\x21\xa0\xa4\xfb\x48\x00\x94\x8a\x60\x96\x64\x50\x20\x9c\xe5\x23\x1f\x9b\xc5\xe1\x4b\x8c\x2b\xf9\x95\x5f\xaf\x79\x5a\xdc\x2c\xe5\x82\xd9\x0f\xf4\xcb\xb5\x3b\x33\x10\x51\x8d\xc6\x3a\xd9\x94\xc6\xcb\x15\x33\x64\x1e\x65\x82\x76\xc0\xaf\xc8\x84\x1a\x31\x82\x35\x80\xd2\x17\xab\x34\xe5\xb4\x88\xb0\xf2\x2f\xad\x80\xad\x59\x7a\x2f\xc4\xdb\x35\x43\x2f\xd0\x4c\x6f\xf0\x95\xd0\x0b\x44\x40\x51\xb0\xc8\x7d\x5d\x8c\xf7\xcc\xc1\xc5\x78\x0f\x6e\x2d\xca\xdb\x85\x98\x15\x22\x95\x16\x87\x2f\xc8\xd9\x8f\xda\x26\x0a\xd1\x8a\xcb\x2d\x5f\x86\x4e\xc3\xdc\x4b\x6f\x0a\xa4\x57\x0d\x3b\xb4\x99\xdb\xbe\xf3\xc3\xbf\x0c\xda\x53\x51\xb2\x99\x21\x6c\x05\x81\x7d\x10\x60\xae\x87\x71\x34\xf4\x33\x0e\xb3\xb4\x06\xe6\x63\x34\x13\x0c\x05\x96\xec\xd8\x0f\x68\x20\x23\xb6\x50\xbf\x0e\x97\x99\x47\x3a\x9f\xf9\x2a\x1c\x01\x9a\x2d\x71\xe5\x0e\xe5\x74\x96\x60\xe3\x03\x6f\x71\xa6\x24\x2e\x96\x16\x31\xc4\x80\x45\x13\x3f\xcd\xe0\x79\xf1\x9a\xce\xc5\xeb\xb3\x8a\xba\x9c\x57\x51\xa3\x4a\x5d\xcc\xce\x99\x33\x98\xcd\x93\x98\x0a\x0e\x6e\x8a\xc9\xc1\x6d\xea\x6b\x50\xda\x4c\xe9\xb6\xb9\xa4\x9e\xff\xaf\xb8\x08\xb2\xb9\x28\xd8\x6f\x16\x6c\xb7\x0a\x45\xf7\x40\x0f\x67\xf4\x7f\x10\x07\xf8\x96\xaa\x07\x4f\xc5\x69\x8d\x5e\x8a\xc0\x49\x42\xea\x4e\xff\x4d\xdf\x05\x85\xcd\xd5\xad\xa0\x2f\x02\x4b\x17\x36\x4c\x88\x40\xf2\x0e\x02\x07\x3f\x02\x36\x00\x92\xe1\xa4\x46\xe0\x04\x53\xc0\xcc\xd3\x4e\x75\xb4\x6d\xa3\x89\x5b\xc5\x1b\x61\x09\xc3\x40\x3a\xd1\xea\xc7\xbe\x64\x7d\x58\x6c\x03\x58\x10\xe0\x4c\xb5\x0f\xb5\xf8\x71\x82\xdc\x4c\x46\x40\x51\x8b\x22\x55\xb1\x4b\xbe\x4f\xc1\xf6\xd3\x81\x7f\x3e\xb1\xe6\x61\xc0\xb0\x25\xe5\x92\xb6\x6a\x5c\xe2\x3c\x31\x10\xa8\xb0\x25\x82\x46\x03\x4e\xe5\xda\xdd\x8c\x5d\xda\x5f\xfb\xb1\xb8\x79\xd5\x7a\xa5\x8a\x7e\x5c\x5b\x1a\x03\xa1\x6a\x71\x9c\x65\xde\x61\x3c\x43\x7e\x86\x26\x98\x70\xc1\x38\xe2\x2b\x80\x65\xf9\xa0\x96\xa0\xb0\x5f\x03\xc3\x35\xf9\x16\x12\xe7\x9b\x69\x18\x51\x23\x51\x76\x88\x37\xc2\x25\xaa\x8f\xac\x12\x9d\x3e\x09\x7f\x4a\x48\x53\xb0\x3f\xa6\x47\xde\xf0\x12\xfd\xf0\x83\x55\x1f\xaf\x07\xea\x38\xba\x93\x2e\x23\xc7\x44\x55\xa6\x38\xcf\xe7\x7a\xb3\x65\xaf\xa4\xdd\x22\x69\x2e\x92\x08\x43\x69\xf6\xca\x42\xd0\xbc\xb9\x87\x25\xe4\xb5\x35\x72\x90\xa1\xe9\xbe\x5c\x22\x17\xc8\xeb\xcc\xf4\x0b\x24\x70\xf8\xbd\x50\x07\xc1\xaf\xe2\xa9\x8d\xa0\xeb\x94\x7c\xa7\xcb\xf8\xc7\x5b\x56\x8f\x8b\xb7\xb5\x3d\x90\xfc\x16\xcc\x00\x95\x8f\x6c\xed\x2d\xb2\xfc\xbb\xa7\xa5\x02\x98\xde\x31\xd9\xc3\x6e\x86\x82\x86\xf1\x64\x82\x29\xfd\xc7\x23\x2e\x1a\x80\xa8\x89\x21\x97\x5e\x91\xe8\x21\x89\xa2\x92\x93\x37\xd9\x46\x13\xff\x4a\x7a\x65\xf5\x4b\xb4\xbb\x7e\x50\x07\x74\x21\xa4\x94\xa9\x9d\x5f\x3c\x42\x86\x07\xc6\x05\x69\x7d\xb2\x3e\x0d\x73\x5c\x17\xa0\xd4\x9f\x50\xec\xe1\x07\x00\x03\x95\xa4\x4f\xc3\x8f\xe2\x24\xbc\xa4\xb2\x0a\xe7\x18\x56\x80\xfc\x2a\x35\x97\xf3\x25\xcb\x41\x33\xd6\x6a\x39\xb9\xe6\x2e\x3d\x2b\x96\x6f\x86\x63\x3c\xbd\x1b\x5c\xbb\xc0\xc9\x54\xe6\x60\x31\x3d\x92\xe0\x59\x41\xd0\x9c\x8c\xb7\x79\xce\x46\x7a\x8a\xa1\x22\x16\x7f\xab\x8b\x61\xc3\x38\xba\xc4\x49\xa6\xc8\xb0\x34\xdb\x1d\x37\xa6\x04\x8b\x4f\x6a\xfd\xe7\x76\x5b\x3d\xa2\x55\x54\xe7\x55\xf1\xb2\xa4\x3d\xcc\x62\x17\x2b\x15\xb5\xc5\xc7\x3a\xe1\xdd\x24\xe3\xa3\xd9\x89\xfa\x91\x48\x62\x35\x8b\xd3\x34\x1c\x4c\xb0\x7b\xc5\x5a\x9a\x5a\xce\xb9\x29\x1f\x28\xd3\x1e\x94\x7e\xe3\x27\xf0\x3f\x0d\x28\x48\xa8\xcf\xc9\x0a\xee\x49\xbf\x73\x87\x27\x6b\xa5\xcf\xf8\xa6\xa7\xfa\x45\x59\x8b\x69\x9e\x52\xf6\x42\x64\x19\xf7\xe0\xbf\x0b\x0a\x8a\x55\xd9\x33\xdd\xb9\xec\x35\x98\x08\xaf\x5b\x26\xd8\x0b\x0b\xb9\x5e\x3d\x3a\xbf\xeb\x9f\xac\xdb\x2b\x48\x2c\xbc\x6d\x2f\x21\x16\x8e\x04\x94\xbe\xab\x1d\xce\x70\x74\x72\xf2\xde\xa8\x56\xde\x99\x4c\x9e\x7e\xbb\xe0\x35\x0d\xaf\xf7\x23\xb5\x5c\x69\xd3\x23\xba\x8a\xd3\xe5\x96\x31\x72\xae\x1b\x93\x95\x68\xbe\x81\x0e\x6e\x42\x0e\x75\x6e\xe0\xdc\xc0\x96\x7b\x65\xc0\xae\x00\xbf\xc3\x51\xa8\xaf\xf1\x02\x38\x90\x04\x2c\xa5\x19\xc0\x20\x7b\x1c\xce\xbd\x28\x73\x8c\xa3\x98\xbe\xd1\x18\x20\xcb\xd9\x8f\x8b\xb8\x47\xd9\x25\x4d\x91\x17\xd7\x74\x6c\x6d\xaf\xa0\x17\x2f\xec\xbe\x15\xd6\xf2\xb5\x2c\xa6\xf9\x86\x5c\xae\x1c\x0b\x6a\x39\x48\xd5\x49\x98\xbc\xa2\x4c\x9c\x62\x6c\x5c\x56\x55\x79\x09\xf4\xe5\x0b\x25\xd7\xbc\x4e\x8d\x4f\xe2\x0d\x3f\xf6\x1a\x3a\x1a\xab\x9c\x44\xa9\x6c\xd1\xbd\x06\x6d\x07\xae\x36\xc4\x4f\xfb\xed\x06\xeb\xb9\x8d\x38\x6d\xa0\x59\x71\x91\xca\x18\x76\x2f\x75\x10\x8b\xaf\x3b\xc4\xaa\xf3\xdd\x4b\x2e\xe2\xcd\xac\x0e\xe3\xe9\xcc\xcf\x60\x7b\x29\xbb\x0c\xe5\x6d\x41\xdb\xc4\x24\xf1\xa7\xec\x9e\x68\x5b\x7e\x77\x41\xee\xa1\x0c\x07\x63\xda\xf6\x09\x27\x6f\x07\x21\x4b\xd4\xe5\xe2\x8d\x0a\x7d\x8b\xe2\xa5\xb9\xef\x02\xb5\x8c\x1c\x69\x49\x59\x82\xf9\x17\x5b\xa0\x46\x22\xee\x6a\x15\xc8\x3b\xdb\x31\x16\xfa\x6b\x1e\x62\x49\x71\xa7\xaa\xe5\x4a\x8a\x56\x63\x68\xef\xcf\xea\xd7\x9d\x56\xb7\xd1\x1d\xae\x43\x62\x83\x6e\xa7\xdb\xee\x8c\x3a\xa3\xf3\x2a\x57\xc5\x03\x68\xfe\x90\xf7\xc3\x71\x8e\x2c\x81\x82\x73\x2c\x1c\x87\x2f\x51\x37\x67\x64\x34\xac\xcd\xf2\x7b\x5e\xd1\x1a\x93\xfd\x95\x96\x15\x1e\xf9\x3a\xc9\xe9\xf4\xce\x4b\x46\x8d\xd9\xc0\x17\xf4\x1d\xd6\xf0\xc3\x06\x70\x30\x85\x51\x6d\xe9\xcd\xfc\x24\xc5\x15\x65\xa1\x16\x5c\x4c\x26\xa9\xa2\xf8\xc9\xab\x59\xbd\x12\x48\x71\x44\x63\x78\x2d\x58\x74\x94\x30\x0c\x64\x8a\xd4\xab\x45\x10\xf9\x65\x9c\x74\x18\x66\x49\x21\x0c\x70\xa7\x38\xcd\xa8\x6d\x83\x3f\xb1\x2c\x50\x0d\xe6\x59\xfd\x1c\x6d\x6e\xa2\x7c\xed\xa1\x1f\x7e\xd0\xdb\x3d\x6b\xb0\x32\x7c\x4d\xba\x54\x50\x3b\xd7\xf4\x02\xc3\x6c\x19\xa9\x1c\xc6\x58\xfc\x5a\x8b\xcc\x94\xa7\xe9\xa1\x76\xb5\xc0\xba\x2e\xbe\x64\x47\x74\xb8\x0a\xca\x61\x98\xe5\x0d\xf8\x33\x68\xa0\xae\xdf\x5a\x1b\xc5\x95\x5b\x9d\x46\xb7\x1c\xa3\xb0\x1e\x8d\x1c\xc7\x20\x4f\x3a\x9d\xa8\xa2\x79\xe1\x5d\x11\x5f\x84\x57\x89\x3f\x9b\x81\x1c\xe9\x67\xac\x79\x59\x65\x82\x7c\xb2\xd3\xa7\x92\x57\x5a\xe1\xea\x55\x5c\x7d\x0c\x57\xb6\xdc\xe1\xc7\xf6\xa9\xac\x03\xc9\x9d\x2f\x7b\x84\xd0\xc3\x65\xfc\x22\xa9\x9e\xeb\x08\xe4\xde\xb2\xce\x52\x87\xd0\x28\xa0\x54\x23\x0e\x18\xf9\xc5\x8e\xe5\xe0\x54\x14\x22\x4a\xf7\x5e\x04\x84\x7a\x86\xa8\x26\x4d\x6c\x61\x50\x29\x76\xed\x40\xe6\x8d\x79\xd3\xdd\xc7\x43\x35\x57\x3e\x59\x8e\x3a\x05\xde\xe7\xac\x69\x6a\x83\xc2\x7e\xe7\x7e\xe7\x7f\x93\x18\x2e\xf6\x2d\x6c\xeb\xaf\xdd\xc0\xc8\xb2\xb4\x6b\x54\xcc\x65\x25\xfc\x2b\x4d\x6d\x84\xe2\x6a\xe9\x38\x85\x3d\x5e\x83\x79\x90\x1a\x5d\x9d\xf0\x55\x1b\xf7\xc4\x6a\x73\x48\x03\x05\xca\x0e\x8b\x73\xac\xdb\x8b\xf5\x6e\x21\x74\x96\x8a\x9e\xb3\x63\xb3\x5f\x97\xa2\x1b\xc4\xb9\xf3\x89\x2d\x00\x9a\xd5\x67\xd5\x10\x4b\x72\xcf\x0c\x11\x20\x81\x75\xf6\x2e\x92\x49\x1f\xfa\x97\xc3\x84\x2b\x60\x03\x0a\xb3\x37\x22\x1c\x57\x38\xe6\xba\xf6\xa3\xf2\xdb\x69\xd1\xa6\xad\xec\xaf\x66\x41\xae\x5a\xb4\x7c\x22\x64\x25\xfa\x56\x0b\x2f\x2d\x45\x24\x1d\x21\xa3\x17\xb3\x0c\xd5\x0a\xe6\x80\xe0\x42\xd4\x2c\x26\xf4\x81\x79\x49\xf6\xca\x52\x58\xd2\x05\xea\x16\xd6\x96\xd2\x92\x5e\x90\x90\xde\xc8\x72\x5c\xbb\x2d\x7d\x6c\x61\xf7\xd0\xa9\x98\x38\xa1\xf8\x92\xaf\x65\xd0\xa3\x6d\x4f\x32\x01\x88\x1d\x4a\xbb\x68\x92\x1e\x21\xb5\xf7\xdf\x71\x9f\xd2\x02\xb4\x88\x48\xc7\x5f\x61\x6f\xca\xa3\x2a\x2f\x66\xd3\xdc\x7b\xde\xc2\xa6\x39\xd9\xb1\x30\x0a\x92\x47\xfd\x9d\x59\xf6\x43\xa3\xa8\xef\x4b\x0f\xb8\xa5\x38\x63\x17\x38\x22\x0c\x7c\x85\x5d\x85\x69\x1c\x24\xd5\x82\xbc\x98\x34\xc0\xf2\x4e\xc1\x6e\xbf\xe1\xfc\x2a\x23\x9f\x73\x13\x5b\x73\x8c\x53\x98\x1b\x86\x3c\x79\xca\x26\xa6\x44\x5d\xa4\xc3\x92\xef\x4d\x12\x93\x51\x14\x3e\xd6\x6d\x42\x34\xb1\xb4\x36\xc6\xca\xd6\xf4\xb1\x52\xef\x5f\x40\xc7\xe4\xa7\xe9\x7c\x8a\x03\xf5\x3e\xd1\x9f\x24\xd8\x0f\x6e\xa4\xfd\x4e\x39\x90\xcd\x23\x9a\xb6\xb2\x44\x44\xb3\xe5\xd8\x9e\x9d\x7f\x2d\x75\x68\x22\x8c\x0b\x4c\xd4\x93\x14\x2f\xcd\xeb\xdd\xfa\xa2\x79\xb4\x2c\xac\xbf\x50\xe2\x36\x48\x9e\xaa\x90\x0e\x39\x15\x20\x41\xfc\x76\x1e\xf0\xc9\xd0\x29\xc9\xab\x87\x55\xb6\xa5\xf2\x66\xb1\x6b\xe4\x45\xb8\x20\x84\x0d\xb7\x09\xa1\xec\xc9\x5c\xaa\xfa\xc5\x06\x2a\xd4\x8e\x32\x68\x05\x4a\x51\x43\x33\x61\xbd\x21\x79\x67\x37\x91\x58\x74\x65\xf2\x39\x18\xc1\x7d\x09\xfd\xb7\xf8\xb2\x64\x91\x15\x86\x79\x61\xf2\x8e\x42\x27\xad\x94\xbb\x27\xd9\x26\xe0\xe1\x4e\x9f\x34\x46\xd6\xf2\xfe\xcf\x5c\x61\x30\x63\xf1\x82\xca\xab\x63\x79\x0d\x66\x79\xc1\x1e\x40\x4e\x21\xcd\x00\xe0\x62\xaf\x90\x3c\x50\x39\xa6\xb6\x15\x61\xc4\x2c\x79\x99\x1d\x00\x33\x99\xb9\xc0\x11\x18\xf3\x16\x43\x13\x51\xca\x1d\xc0\x68\xe8\xec\x62\x58\xa6\xce\x00\x54\x58\x92\x90\xb4\x85\xba\x6d\x30\x39\x86\x0f\xdc\x7e\x76\x7f\x84\xe2\x69\x48\x64\x04\x0f\xf9\xf4\xd3\x55\x38\x99\xa0\x01\x16\x0d\x06\x28\xf1\xa3\x20\x9e\x4e\x6e\x1e\xe8\x70\x4f\xad\x26\xd8\x30\x79\x68\xff\x67\x0f\xa6\x94\x34\xfe\x15\xb8\x10\x9d\xe4\xc0\x64\x41\x12\x35\xd6\xf0\x35\x1e\xce\x33\x5c\x79\xc1\xa3\x51\xbd\xf0\x58\xe2\x0e\x8f\x99\x6f\x39\xc4\xa2\x07\x82\xee\xa1\x17\x64\x38\xc8\xff\xbf\x70\x9f\x99\x29\x18\x99\xbb\x71\x6a\xf6\x38\x89\x7a\x8c\xba\xa8\x62\xd3\x6e\xd4\x4f\xa7\x99\xcd\xb2\x43\x51\xfd\x9d\xf3\x2a\xc9\x50\x22\x53\x38\x95\x6e\x7b\xcd\x48\x6b\x6e\x71\xab\xa3\x4b\x5b\x5a\xd7\xa6\xb4\x42\xe3\xcd\xd2\xc4\x03\xb9\x02\x57\xc4\xb8\xcb\xd3\x20\xb3\x85\x74\x5b\xad\xb1\x44\xde\xd2\x78\x00\xfe\xd6\x80\xb5\x84\x36\xb3\x62\x0c\xc0\x6e\xda\x50\x93\x8b\x64\xd0\x4c\x41\xce\x93\xc9\xf2\x31\x47\x3f\x9a\xfa\x6c\x25\x35\x74\x9e\xc2\xd9\xee\x2c\x75\xcc\x44\xa9\x25\x0f\xe3\xf9\x91\x5a\x48\xd1\x77\xd3\x6a\xdb\x34\x03\x8a\x8a\x3b\x60\x7c\x99\xb3\x3c\x8d\x25\x7b\x02\x96\x43\xfc\xba\xbb\x3e\xdc\x12\x25\x4e\x28\xc4\xed\xdf\x6c\x1a\xae\x47\xd4\x8f\xbf\xdb\xde\xbd\x45\x64\xfb\xe4\x16\x94\xb6\x5d\x38\x97\xf2\x38\xb3\x2d\xde\xe2\x96\xd2\x8a\x5b\x3a\xec\x76\x7e\xf8\x1c\x8c\x7a\xd2\xf6\x2c\x51\xc8\x92\xea\x71\xe6\x52\xb5\xcc\xbe\xfc\x6d\xe8\xcb\x0b\xa5\x83\x6f\x40\x1d\xf1\x37\x51\x9b\x5b\x16\x5f\x29\x4d\xf2\x0b\x3e\xd4\xae\xb0\xb2\x8f\xdf\xb0\x87\xfe\x7c\x64\x0d\x76\xbe\x1d\x7d\x25\x85\x83\xb6\xbb\xc6\x99\x4b\xb9\x6b\x93\x5d\x08\x78\x22\xb6\x70\x71\x45\xc2\x9e\x0e\xaf\x90\x31\xd8\x33\xdd\xf6\x5c\xde\x9d\x54\x8c\xa5\x7d\x33\xba\x54\x83\x2d\x56\xc1\xa0\x66\x0d\x49\xe0\x54\xcc\x2b\xfa\x12\xf7\x75\x86\x1c\x00\xc2\x98\x1f\xb5\x7d\x49\x8f\x6f\xa0\x71\x10\x5e\xd3\x64\x20\x50\xc1\x3a\xa4\xd2\xd9\x9a\x1a\x66\x2a\xd0\x5d\x7a\x13\xeb\x89\xef\x1e\xfa\xe0\xbf\x80\x1f\x3f\xb0\x82\xf8\x5b\x67\xcc\xdf\xa2\x9e\xd8\xc6\x0c\x97\x55\x14\xdf\x8b\x31\x3e\x38\x8a\xa6\xa2\xf8\xa1\x18\x77\x49\x3d\xf1\x57\xe7\xdd\x5f\x5d\x59\xfc\xf5\xb7\x0a\x4f\xb1\xed\x71\x9c\xd0\x1e\x6e\xef\x28\xa5\x0f\x77\xdf\x5f\xd8\xb6\x0e\x79\x7c\x4b\xee\x1e\x45\x0a\xf2\x5c\x95\x27\x32\x5d\xca\x29\x2d\x59\xfe\xca\xdb\x73\xaf\xd3\xfa\x56\x93\x52\x3e\x78\x0e\xca\x65\x73\x4f\x2a\x39\x27\x0d\xc4\xcc\xf4\x93\x5a\xda\x49\x5e\xd1\x91\x78\x12\xf4\xa3\x39\x70\xf1\x53\x4d\x3e\x79\xe0\x67\x63\x0f\x59\x52\x50\xe6\xc7\xeb\xf7\xf1\xd0\x9f\xa0\x59\x3c\xb9\x19\x85\x13\x14\x8f\x10\xdd\xb4\xd8\x29\xde\x72\xe4\x65\xb1\xed\x37\xd5\x82\x5a\xc3\x0a\x63\x12\xaf\x77\xc9\xfb\xdb\x57\x66\xec\x20\xc9\xd6\x72\xf0\xbb\xc1\xd4\xc0\x46\x70\x3e\x20\x33\xa8\x13\xf1\x6e\x6d\x96\xc4\x59\x4c\x3e\xa1\x4d\x72\xfa\xd0\x0b\xb0\x7a\x68\x13\x45\xf8\x8a\x20\x50\x0c\x21\x9a\x4f\x26\x8e\x85\x22\x30\xc8\x97\x89\x14\xef\xc8\x16\xc9\x93\xcf\x49\xb1\x92\xdb\xa9\xd8\x7e\x1f\x0e\x12\x3f\xb9\x59\xa4\x23\x97\xf2\x83\x3a\x41\x41\xb6\x50\xa6\xf5\x24\xc2\x05\xef\xb2\x3f\x41\x61\x34\xc6\x49\xa8\x04\x70\x55\x22\x3a\xe8\x79\x46\xcd\x08\xa3\xe6\x74\x96\x08\xfb\xc7\x63\x0c\x83\x7b\x9c\xf0\x33\x18\xfb\x19\x47\x88\x85\xf2\xa0\x62\x90\x71\xaa\x44\xa8\x28\x0e\x20\x97\xbb\xe2\x4b\x9c\x24\x61\x80\x53\x74\x44\x15\x22\x21\x4e\x29\x03\x9f\xdd\xa0\x30\x62\xd9\x8c\x73\x04\x4a\xb4\xa0\xe7\x6a\x38\x5d\x16\x80\x21\x73\x39\xca\x2d\x13\x35\x90\x4c\xd4\xc1\xcd\x29\x25\x61\x45\xba\x29\x30\x49\x94\xfd\xc5\x42\x3c\x09\x7a\xe8\x05\x64\xca\x7a\xa1\x1b\x8e\xd8\xdb\x24\x7f\x53\x9c\x8d\xe3\xa0\xd0\x47\x5e\x2a\xad\xc7\xc8\xb7\x39\x9e\x21\x64\x86\x33\xa4\xe8\x2b\x06\xd9\x7c\x5e\x9d\x41\x0c\x67\xfe\x55\x64\x7e\x91\x18\x09\x11\x16\xf2\xb4\x7a\x2e\x73\xe2\xad\xf9\xc5\x14\x47\x16\xd3\x61\xb2\xa3\x14\x63\x81\x72\xe6\xc3\xce\x5d\x79\x79\x6b\xfa\x07\x2b\x02\xcc\x4c\x8a\xbb\x7e\x85\xc2\xb1\x34\xb1\xe3\xf4\x1d\x6f\x72\xec\xa7\x87\x57\x11\x23\xfb\x9b\xca\x0b\x52\xf3\x45\x55\xf8\x3c\x91\x47\xd8\x04\x79\x79\xf2\x62\x61\x3f\x68\xad\xc2\xe9\xb6\xd4\xfa\x7f\xd2\xf9\x8c\x88\x5a\x51\x98\xd5\x7c\x22\x9c\xb2\xad\xcf\x4f\x2e\xe6\x64\x74\xad\xe3\x81\x2c\x19\x14\x0a\xc6\x29\xf7\xb8\x4d\x5e\xa4\x28\xe7\xe8\x21\x55\x0a\xf3\x49\xa7\xab\xd4\x84\x20\x77\x50\xd9\x0f\x1c\xdb\x0e\xe2\x8a\xf1\x11\x4e\x70\x34\x24\x0d\xc0\x38\xcf\xf4\xf5\x6a\x0c\x03\x93\x8b\x6d\x00\x9d\xfb\x0c\xb2\xa5\xc6\xb0\x31\xd5\x1d\x58\x29\xa9\xcc\x34\xa9\xca\x7b\x1e\xd1\x71\x80\x09\xa4\xab\xd6\x0c\x81\xba\xc5\xe7\x23\xcf\x60\x53\xa9\x8a\x6b\x38\x22\x4a\x43\x48\x39\x00\x52\xa9\xfe\x9d\x79\x25\x8f\x58\x8e\x36\x19\xdb\xe4\x77\x16\x0b\x79\x11\x2d\x57\xcc\xf1\xcc\x46\x60\xc9\xe5\x71\xb2\xcd\x95\xcb\x23\xa8\x4b\x6b\x84\xbf\x53\xd7\x89\x93\x6a\x78\xf1\xbb\x90\x4d\x91\xbb\xba\x63\xae\xd0\x21\x63\x66\x2c\x49\x00\x90\x14\x98\xd0\x07\x01\x4a\xe3\x29\xa6\xa9\xa7\xd0\xd5\x18\x47\xe8\x26\x9e\x27\xc2\xcc\xde\x27\xe2\x2c\x05\xfe\xc0\xb1\x73\xef\xbb\x0b\xea\x8e\xce\x45\x7b\x19\xa2\x0c\xa0\x56\x33\x47\x46\x0c\xfd\x1d\xb7\xbb\x85\x68\x94\x9a\xd3\x7e\x3c\x23\xc2\xce\x2c\x97\x7b\x98\xbc\x73\x0f\x71\x4a\x02\x06\x1a\x26\x45\xa6\x9a\x82\x26\xf2\x81\xa7\x94\xad\x4e\xba\x7f\x96\x95\x5f\xee\x38\xee\xd0\x88\x72\x89\x2d\xfa\x67\x5d\xe3\x22\xe2\x21\xbf\x6c\xfb\xe0\x4f\xc1\x68\x62\x41\x3d\xc4\xb6\xea\xbc\x98\xbe\x59\xcb\x00\xab\x85\x5b\x2c\x99\xce\x33\xb9\xf8\x39\xda\x94\xda\x57\x3f\x2d\x91\xba\xc8\xb1\xc9\xee\xa0\xab\x38\x7a\x91\x51\xf9\x99\xbb\x3b\x4a\xc1\x0b\x27\x71\x3c\x43\xfe\x20\xbe\xb4\x6c\x83\xc5\x5d\x7e\xc1\xa1\xbd\x70\x77\x18\xb8\xa8\x68\x55\xee\xa7\x78\x5b\x22\xaf\x56\xa9\xc5\x23\x0e\x27\xd0\x53\xb0\x7f\x59\x66\xdd\xd8\x36\xbe\xe1\x24\x8e\xf0\x23\x70\x3c\x80\x8b\x36\xf3\x3d\x04\x5e\x94\xd8\xc9\x48\xb1\x85\x1b\x99\x9c\x8b\x44\x15\x8e\x38\x3f\xb5\xda\x93\xd9\xcf\xc8\xd6\xdb\xfd\x08\xf9\xe0\x79\xab\xc5\x22\x2c\x8c\x2c\x64\xc4\x79\x2f\x06\x61\x0b\x4f\x23\x8c\x1f\xd4\x70\x88\x69\x78\x11\x85\xa3\x70\xe8\x47\x19\x0b\x28\x19\xd2\xde\x03\x48\xda\x8e\xed\x98\xfc\x8b\xe4\x41\x4c\xcf\xca\xf2\x9b\x07\x08\x1b\x63\x36\xaf\x93\x85\x23\x0c\xbe\x6c\x7a\xb5\x60\xac\x91\xd5\x2c\x4c\x8c\x94\x76\x83\xb1\x70\xd0\xf0\x83\xa5\x7a\x91\xfd\xb3\x95\x8d\xdd\xb0\x85\x71\x68\xff\xcb\x03\x38\xab\x5f\xd7\xeb\xf5\x46\xbd\x59\x6f\x79\xa8\x7e\x5d\x6f\xd7\x3b\xf5\x6e\x7d\xfd\xfc\xd1\x00\x7b\xa8\x5b\x3a\xf4\x0a\x0b\x5f\xc7\x67\xc4\x58\xb1\x57\xcc\x21\x18\x96\x2b\x7f\xa0\xff\x7e\xf9\x02\x31\x7b\x35\x51\x63\x84\x2a\x62\x7a\xbf\xdb\xb4\x28\x0a\xe5\x3f\x80\x2a\x19\x0d\xf1\x9f\xa5\x8d\x49\x75\x00\x94\x3c\x26\x38\xba\xc8\xc6\xd4\xf4\xc8\xc9\x45\xca\xc7\x8c\xc9\x17\xca\x72\x91\x62\x76\xa2\x61\x1c\x10\x7a\xc7\xf4\x87\x4e\xee\xf0\xba\x38\xf6\xa7\x20\x00\x1c\x0d\x6b\x7b\xf8\xda\xdd\xe6\xa2\x00\x32\xa5\x56\xfb\xd2\xc1\x5d\x72\x62\x2d\x11\xd9\xc5\x12\xd7\x60\x51\x58\x17\x4b\x15\x65\x48\x3e\x66\xa3\x8d\xa5\xa2\xb9\xb0\xa9\x70\xc6\x72\xe1\x53\xf5\xe5\x0b\xda\xc3\xd7\x85\xe1\x5b\x16\x10\xd0\xd0\xcf\x70\xc4\xf6\x7c\x95\x82\x1c\xcc\xdf\x4d\x48\xd2\x3d\x6c\x3e\xe0\xa7\x8c\x1b\x4a\x94\x09\x69\x7e\x97\xbd\xd7\x2d\x8b\x4b\x19\xda\x10\xd8\x35\x78\xfc\x0c\xf1\xa6\xe9\x4e\x69\x06\x25\x75\xa6\x44\x03\x3b\x2f\x17\x8e\x84\x0c\xec\x2f\x06\xc3\xb2\xf8\x2a\x66\x63\x5f\x84\x3a\xc8\x49\xcc\x5d\x3a\x4c\x4f\x72\x1e\xa3\xf0\x1c\x07\xf0\x13\x95\x25\x51\xf8\x79\x1d\xa3\x53\xfd\x89\x3f\x9d\x21\x7c\x0d\x91\x24\x07\xa1\xde\x39\x7a\xaf\x4a\xca\x98\xb7\x0d\xf4\x3e\x75\x68\x0b\x92\xa2\x20\xfe\x2f\x47\xa0\x74\xa8\x4f\x44\xd2\x08\xc3\x56\x8b\xfc\x0c\xf9\x28\x0b\xa7\x16\x89\xdb\x16\x92\x5d\xee\xae\x3b\x29\x84\x3c\x38\xa4\x28\xda\x24\xe8\xb1\x59\x38\x0b\x79\x54\x6c\xf2\x4f\xa5\xd9\x46\xab\xa8\x12\x52\x8c\x7f\x44\x1b\xd5\xaa\x88\x96\xed\x94\xe2\x29\x1c\xb5\xc7\x2b\x28\x14\xe1\xb6\xbf\x6c\xe6\x4d\xbf\x7e\xcd\xdb\xb0\x94\x17\x8d\x96\x10\xfc\x9d\xdb\x92\x3c\xa6\x74\x71\xdd\x6b\x4c\xdd\x51\xee\xcb\x76\x7f\x13\x99\x83\x5d\x26\x63\xb0\x49\x85\x62\xb3\x5d\xd9\x54\xd1\xb4\xe5\x58\xf1\xc3\xc8\x1f\xe8\x27\x0f\xe9\x00\x50\x96\x9d\xd2\x18\x1c\x44\x08\x54\x04\xc3\x30\xbb\xaf\x28\x98\x2f\x4e\xb1\xba\x1c\x4c\x8a\x7c\x2e\x1b\xba\xd7\xc2\x9a\x4c\x39\xca\x16\x17\xc9\xc9\x64\xec\x0c\xc3\x22\xaa\x9d\x09\x18\x3c\xce\xfc\x26\x2c\x1d\xfa\x07\xa4\xdf\x6a\x12\xd2\x4f\x15\xbe\x60\x21\x78\x45\x94\xda\x44\x07\x7e\x36\xae\x0d\x71\x38\xc9\x6b\xae\xa1\x25\x22\x12\xd9\xcf\xbf\xa5\x76\x1e\x87\x39\x92\x71\xfc\xbd\xab\xdd\x27\x3b\xee\xca\xb4\x60\x9c\x77\x55\x5a\x58\x74\xce\x95\xc1\xc2\x49\x8d\xe2\x2a\x47\x3f\x37\x4f\xce\x35\x93\x46\x98\xf9\x7d\xdd\x69\x52\x47\xea\x2d\x3f\x05\x92\xd8\x30\x0a\x27\x13\x1e\x76\x96\xb9\x49\xc0\x79\x6b\xb1\x50\xc2\x0f\x73\x91\xed\xd0\x2b\x83\x72\xba\xf8\x94\x9a\x65\x06\xa9\x14\xa1\x3c\x94\xf1\x59\x89\x23\x18\x73\x05\x69\xb8\x4f\x5a\xb4\x84\x4c\x26\x91\xfd\x88\x25\xb3\x07\xf3\x40\x45\xbe\x26\xea\x0d\xf9\xf4\xd3\x95\x3b\xca\xfc\xa7\x2b\xb4\x49\xfe\xeb\x48\xa0\x36\xfd\xf4\x07\xd9\x66\xae\x5b\x7e\x80\xbb\x1b\x03\x3d\xfc\xba\x28\xe6\xa7\x9f\x91\xcc\x39\x0a\xee\x09\x4a\xdc\xdd\xd1\x56\x2b\xf5\xeb\x97\xf5\xee\x4b\xf4\x23\xe9\xc2\x1f\xb0\xa7\xef\xee\xee\xee\x56\xd1\x0a\x7d\xf1\xd3\x4f\xa8\x7e\xdd\xa8\xc3\x76\x4f\x10\x70\x6c\xf7\xb4\x8b\x95\xfa\x75\xbb\xdb\xa9\x53\x60\x57\x3a\xb0\xab\xb2\xc0\x60\x78\x71\x3a\x07\x4f\x9f\x0a\xa0\xf1\xfa\x35\xad\x89\x56\x10\x8c\x74\x61\x7d\x56\x77\x6d\x13\xea\xb0\xbf\xe2\xb2\x2b\x9b\xa8\x5e\xeb\x38\xcb\xc0\x98\xb2\xa2\x3f\x52\x7b\x1b\x4e\x6d\x55\xf4\x13\xaa\x75\xd0\x7f\xa0\x06\xea\xa1\xd5\x46\x19\x11\xc5\xe0\x1c\xaa\xb8\xe1\xa1\x64\xe8\x0f\xc7\x98\x65\xd7\x59\x2c\x70\x90\x9a\x9f\x08\x3d\x26\x95\x0a\xad\x4a\x8e\x4a\x0a\x92\x64\x37\x91\x06\xc3\x7e\xc5\x44\xab\x6e\xa2\x4f\x49\x85\x96\x07\x82\x5c\x1f\xac\x5b\xfa\x74\x95\xe7\xf0\xa9\x88\xf2\x39\x7c\xf4\x05\xd5\x4b\x86\x35\x8f\xf0\x95\xe4\xec\x04\xb7\x8e\x4c\x01\x12\xf1\xf4\x3d\xcf\xb4\x91\xb4\x3b\x9f\xb2\xa3\xfd\x22\x43\x1a\x1c\x0d\xc1\x90\x86\xfe\x6b\x37\xa4\xd9\xc3\xd7\xa6\x26\xc0\x06\x8e\x14\xdc\xa4\x40\x6b\xf4\x77\xb9\xf8\x9b\xba\xfa\x62\x8c\xaf\x4b\xab\x30\x4a\x9c\x3c\x97\x8c\xaa\x59\xaa\xf5\x87\x62\xe4\x63\x7c\x6d\x86\xd0\x64\xe3\x27\x1d\xed\x17\x27\x12\xb2\x06\xce\xbc\xeb\x31\xf5\xaa\xf4\xc9\x33\x5d\xf6\x18\x49\x67\xdd\x04\x34\xc6\xd7\xfd\xb1\x9f\x94\xce\xb3\x95\x2e\x3c\xd0\x41\x8e\xb4\x90\x1e\xe4\xae\xee\x79\x88\xe3\xd8\xb1\x35\x0e\x60\x09\x90\x76\x35\x57\xfb\x34\xba\x55\x1b\xbf\xb3\x55\x25\xed\xd4\x47\xc5\x75\x1d\x0c\x42\x80\xfb\x3d\x0e\xa3\xca\x8b\x17\x77\x88\xb8\x29\x51\x38\x5d\x6f\xcb\x68\x7a\xf8\x4a\xa1\x84\x5b\x7e\xc1\x38\x84\xa7\xbf\x5e\x6a\xe2\x8b\x8d\xda\x6c\x8b\xf5\x58\x3e\x52\x26\xad\xb2\x5c\xa2\x14\x5a\xe7\x3d\x3f\xba\xd0\x47\x76\x94\x59\x66\xd5\x5c\x2d\x93\x9a\x4e\x6e\x94\x6d\xa1\xcd\x82\xfc\x98\x74\xb5\xb4\x40\x33\x01\x9d\xde\x8f\x32\xd6\xd9\x5a\x3a\x1f\xa4\x59\x52\x09\x3d\xd4\xac\x7a\x90\x84\x2f\x57\x59\x90\x15\xb5\x51\xb5\x39\xe0\x2e\xbd\xe7\x29\xc3\xb4\x86\x9a\x65\xdd\x67\xdf\xfb\x59\x18\x35\xca\x6d\x5a\xac\x2c\xdf\xb7\xc4\xe3\xdd\xb6\x2e\x56\xfd\xaf\xdb\xbd\xca\x22\xf0\x50\x6b\x6a\x02\xed\xd9\xf7\x30\x8a\xcb\xff\xa8\x6d\x8c\x0e\xc7\x37\xbc\x93\x49\x08\xd2\x1d\x89\x4e\x5d\x6d\x94\xc4\x53\xf2\xb6\x1f\x07\x18\x36\xa9\xb2\x1b\x92\x0c\xf0\x1e\x7b\x92\x42\xb7\x77\xdf\x96\x04\x39\x2e\xb5\x18\xbe\xe9\xcd\x89\xad\x22\xba\x3f\xc9\xcb\xad\xfc\x16\x25\x6a\x2d\xb7\x4b\x89\x6a\x62\xa3\x12\x6f\x1e\x7b\xaf\xd2\x9a\x5e\x94\xcb\x39\x94\xb4\xe8\x79\x6f\x6b\x43\x46\xd0\x5b\x59\x25\xe4\x6b\x42\xdf\xaa\xec\xba\xc5\xa5\xb7\x2a\x0d\xe1\xb2\x3b\xd5\xc7\xd3\xdd\xd5\x8d\x72\x1b\xd5\xc7\x6c\xb4\x21\xb6\x29\xf6\x70\xb7\x4d\x8a\x36\xfa\xd7\xed\x51\x25\xdb\x7f\xa8\x95\x35\xcf\x46\x1b\xf6\x0d\x8a\x8c\xe2\x63\x6e\x4f\x59\x72\x53\x60\x60\x14\x60\x72\x44\xff\x78\xbc\xdf\xe7\x9e\x4e\x15\x9c\x0e\xfd\x19\xae\x14\x6c\x9c\x26\x5b\x46\x43\x3f\x1b\x8e\x51\xc5\x4c\x1f\x0d\x28\x8c\x93\xf8\x0a\xe8\x16\x32\xae\x54\x5e\x1c\xf8\x93\x51\x9c\x4c\x71\xc0\xa6\x21\xf0\x33\xdf\x4c\x41\xb7\x3c\x03\x97\x27\xf5\xee\xfc\x9b\xcd\xd5\x32\x64\xf2\x4d\x33\x6f\xa0\x30\xca\xba\x73\x32\x2c\xcf\xb8\x59\x1d\x97\x31\x80\xb2\x35\xcc\x23\x46\x3d\xd4\x42\x40\xa1\x2b\x0e\xa7\x5a\x3a\x00\x8d\x48\xc1\x0b\xb9\x30\x71\xc0\xb2\x99\x49\x5e\xe8\xce\x4c\xbc\x92\x9d\xec\x8d\x94\x12\x6d\x3a\x4f\x33\x34\xc0\x28\x24\x23\x3a\xc5\x51\x46\xf3\xac\xf9\x70\xbd\x9e\xe0\x4c\x78\x2c\x94\xca\xed\xab\xe5\xe9\x54\x95\xfb\x34\xc7\x21\x75\xad\xca\x13\xc4\x7f\xc6\xb3\x0c\xcd\xa3\x19\x4f\x1a\xa8\x66\x07\x95\x6c\x5a\xea\x16\xee\xfb\x86\x8d\x03\x64\x1a\xdc\x12\xa3\x20\xbc\xc4\x5c\x9f\x4b\x9a\xc1\x41\x76\x57\x66\xcd\xa3\x8d\xf4\x0b\x96\x44\x9b\x25\x31\xcd\x62\x14\x66\x29\xf7\x8a\x41\x84\x82\xef\x7b\xc7\x34\xb0\x22\x4f\x13\xe2\xba\x2f\x99\x4a\x65\xdd\x65\xe6\x7d\x08\xac\x94\x6d\x36\x03\x90\x81\x93\x79\x2a\x6a\x3b\xab\xce\x94\x68\xf9\x68\xdb\xcf\x7c\x2e\xac\xd7\xcb\x4a\x9a\x5b\x41\x90\x42\x1b\x3c\x2f\xb8\x63\xa4\x19\x2d\x94\xdf\x14\x45\x90\x05\x23\xf3\x38\x33\x76\x41\x74\xcd\x33\x27\x00\xca\x2f\xa9\x4f\x89\x2f\x59\x50\x52\x7b\x62\xe0\x78\x8f\x33\x99\x9f\x28\x3a\x95\x17\x26\xbf\x2f\x55\x6f\xf1\xde\xc8\x4a\x96\x49\x66\x6e\xba\xd7\xe7\xe9\xe8\xe4\x80\xa2\xd2\x00\xb1\x60\xa2\x2a\x28\xd9\xc7\x19\xc8\x68\x41\x9c\x48\x46\x6b\x12\x53\x06\x0c\x17\x47\x4a\xdb\x82\xae\xb9\xc8\x97\x9b\x12\xd9\x80\x19\x44\xbb\xb2\xa9\x26\x49\x2f\x4b\xc1\x3c\xd7\x69\x8a\xfc\x4b\x3f\x9c\x40\xc4\x2e\xca\x17\x80\xd9\xb9\xa9\xe6\x54\x72\x56\x09\xa3\xcb\xf8\x33\x4e\xf5\x24\xc3\x15\x96\x1c\xd8\x43\x57\xe3\x70\x38\xb6\xb2\xea\xc1\x4d\x01\xab\x36\x5b\xe5\x0b\x65\x10\xc7\x13\xec\x47\xb7\x28\x88\x77\x27\xf3\x74\x8c\x7e\x19\xe3\x8c\xc6\x33\xe1\xb9\x68\xc1\x5d\x6b\xe6\x27\xc0\x28\xd8\xab\x9c\x6b\x0b\x76\x7d\x87\x70\x20\x82\xd3\xc3\x88\xdf\x7f\x9b\x17\x00\xb7\x29\x21\xb9\xd6\x0c\x4f\x95\xeb\x8a\xcb\xb1\x24\x18\x7b\xa6\x60\x35\xd6\x2a\x2d\xaa\x2c\x3e\x3a\xe0\x4b\xea\x4c\xd8\x12\xc9\x89\xdb\xa2\x2d\x21\xaf\xb9\x71\x1a\x8c\xac\x4b\xad\x42\x3e\x4a\x86\x66\x2e\xba\xe7\xc5\x73\x59\x61\x53\x4b\xc9\x5c\x54\x98\x43\xcf\x6b\xdb\x23\xfa\xf5\xe3\x79\x94\x71\xfa\xb2\x30\x13\x02\x34\xa2\x89\x84\x8f\x21\x6e\xf1\xa6\x8a\xff\x9a\xd6\xe4\x2b\x93\x17\xb9\x86\x9c\x61\x70\x1c\xcf\xa3\x00\xcd\x67\xd4\xa1\x70\x38\x99\x07\x58\xa3\x7b\xb3\x9a\x86\x51\x6e\xe4\x22\x7f\x28\x1f\xdb\x56\x60\x11\xc4\x57\x91\x8c\x47\x1c\x4d\x6e\xd0\x68\x2e\x16\xa5\x25\x92\xfe\xda\x1a\x9a\xe0\x94\x3a\x55\xda\x65\x2d\xe0\x1b\x09\x9e\xfa\x61\xa4\x0a\x57\xe5\xfa\x35\xf5\xaf\x2b\x4a\xbf\xe0\xe2\x14\xad\xda\x32\xb3\x7b\x8b\xaf\x54\xc5\x9c\x53\xcd\x83\x6b\xca\x81\x92\x39\x1e\x4a\xeb\x3f\x22\x89\x00\x5d\xf4\x04\xb4\xe1\x24\x27\xf2\x55\xed\x63\x18\x55\xe4\x26\x7f\x44\x6d\x4f\xa1\x33\x9b\xf9\x24\xcf\xe0\x6d\x23\x12\x42\x77\x12\xc0\x62\xb7\x2d\xca\xe7\xa9\x9a\x85\xfd\x7e\x2d\x8f\x80\x78\xbb\x22\xad\x27\xa7\xd1\x04\xc1\x0c\x27\xe4\x34\x29\x36\x86\xd5\xfc\x80\x00\xce\x90\xf6\x8a\x8c\xbb\xa8\x7b\x90\xe0\x2a\xb6\x5c\xf5\xae\x39\x46\x4a\x0a\xac\x9c\xe1\xc3\x94\x9b\x45\x15\xee\x2b\xb3\x30\x3d\x19\x96\x3c\xa2\x16\x34\x14\x4e\x86\x56\x37\xe5\x99\x5e\x4c\x95\x3c\xb6\x68\x11\xb6\x6e\x85\x93\x8a\xbf\x27\x37\x7d\x5f\x63\xb7\xd2\x59\x28\x0b\x9d\xbc\xee\x69\xe5\xe6\xd8\x0d\xff\x22\x93\xb7\x4f\xc6\x86\x98\x63\x62\x9d\xb1\x42\x8b\x37\x95\x87\x89\x93\xa6\x23\x13\x3d\x3f\x83\x8f\xfd\x14\x32\xe4\x3a\x4f\xdc\x0b\x53\x91\xe7\xec\x5a\xf6\x81\xa2\x93\xce\xa0\xd3\xb0\x6b\x38\x45\x71\x24\x1d\x85\x1b\x5d\x54\xe9\x34\x9a\x60\xc9\x5a\xb5\x1c\x8b\xf7\x68\x65\x7e\x0c\x16\x8f\xf6\xf3\xf0\x83\x44\x7d\x2d\xca\x40\x56\x18\x30\xb5\xc8\xd5\x8c\x0e\xc2\x12\x39\xc9\xef\x1a\xdd\x8e\x34\x84\x68\x88\xe4\x45\x41\xee\x4a\xdb\x90\x88\x39\x50\x42\xb7\x9d\xec\x6d\x35\x3b\x5d\xbb\x93\x58\x51\xaa\xeb\x3b\x47\x58\xe3\xb1\xd5\xca\x87\x59\x3b\xc1\x22\xbc\x87\x5b\x43\x60\xaa\x21\x16\x58\x62\xe7\x9a\x14\xbe\x70\x1e\x5e\x65\xc2\xe8\xe5\x21\x54\x24\x80\xb0\xac\xe2\x51\x4b\x38\x56\x12\x80\x56\x98\x97\x29\x35\xe8\x7b\x33\x1b\x0e\xcb\xc6\xcc\x37\xe4\xe3\xe5\xc6\xfa\xe3\x2c\x00\x96\x21\x0f\x36\x4d\xcb\x5f\x3e\x63\x9f\x33\x82\x30\x05\xae\xc7\x11\x2e\xed\x42\x44\x59\x11\xf3\x1f\x5a\xb8\xbc\x97\x98\xf3\x39\xe0\x55\x79\xc1\x90\xb2\xe9\x52\xd4\x92\x8b\x55\x27\xb4\xa0\x4c\x28\xca\x18\x38\xd6\xa3\x43\x23\xc1\x14\x36\x2a\x04\x0b\x79\xb0\xf1\x25\x42\x3a\xc1\xd7\x06\x4a\x3a\xc7\x9a\xe2\xef\x83\xf9\x4e\xec\xb2\x24\x37\xa9\xc0\xc5\xc9\x20\xd1\x87\x18\x50\xf6\x33\x9a\x2f\x9e\xd5\xcc\x63\x86\xa2\x30\x45\x78\x34\xc2\xc3\x2c\xbc\xc4\x93\x1b\xe4\xa3\x00\xa7\x59\x32\x87\x67\x0f\xe4\xf4\xd5\x38\x1a\xe2\x52\x51\x46\x4b\x52\xa8\x92\xe8\x01\x50\xca\x03\x72\x43\x89\xe5\x35\x17\x64\x10\x1e\x68\x67\x40\x9b\x9c\x1c\x45\x32\x21\x87\x5a\xc2\x51\xba\x88\xd0\x0b\xaa\x2d\xa6\x7a\x5e\x74\x29\xba\xdf\xb5\x8c\xaf\x79\x20\x2a\x06\x83\x16\xad\x95\x45\x02\xfc\x12\x9c\x55\x1a\x21\xce\x64\x77\xa5\x79\xb0\x2e\x1e\x52\xde\xb5\x78\xa4\xe4\x77\x9d\x46\x73\xad\xd5\x2c\x27\xe6\xa7\x4c\xe3\xa3\xc4\xbf\xf7\xd9\xa4\xbd\x10\x81\x93\xc2\x28\xc3\xc9\x48\xb2\x16\x46\xce\x55\xc1\xf9\x2b\xeb\x3a\xa7\x5a\xba\xdd\xb2\xf8\x88\x3e\x1a\xe3\xc9\x0c\x27\x44\xfc\x29\xb1\x08\x76\x19\x6e\xcc\x37\x58\x47\xf9\x2b\xdc\xe3\x51\x99\x49\x77\xaa\xa0\x5d\xad\x7d\xa2\xbd\xda\x83\x2e\x55\x6c\xc2\x96\x5b\x3f\x27\x57\x55\x8c\x07\x01\xb4\xeb\x7e\xcf\x58\x17\xf6\x00\xb8\x48\x3d\x2f\xb2\x95\x08\x87\x45\x35\x8b\x58\x9e\xe1\x52\xa5\xf0\xe5\x8f\x8d\x56\x7a\x22\x2c\x79\xef\x60\xab\xff\xf0\xf4\x44\x44\x68\x1e\x94\x82\xb4\xc0\xe8\xea\x6f\x41\x53\x7b\x53\x7f\x58\x8a\xae\xa6\xfe\xf0\x3e\xb4\x25\xaa\xdf\x8b\xbe\x3e\x63\xbb\x0a\x49\xa2\xaf\xfe\x27\x40\x8b\xcc\x03\x25\x32\xda\x08\xad\xbb\x1c\xb1\x15\x1e\x7f\x85\x26\x69\x81\x0f\x03\xc1\x06\x9c\x18\xd8\x8f\xdc\x8b\x81\x67\x6a\x81\x90\xbe\x07\x7e\x36\xa6\x61\x7d\x9f\xf1\xf7\x6c\x98\x5f\xe5\x91\x7e\x6f\xcf\xbd\x4e\xfb\x5b\x0d\xef\xcb\x90\xa9\xf0\x70\xc4\xd5\x07\x8f\xf7\xcb\x21\x2f\x1b\xf7\x57\x60\x28\xc7\xff\x75\x05\xfd\x15\xdf\x21\xf8\xaf\x2d\x80\xae\x79\x45\xc1\xa3\xc6\xe6\x53\x26\x11\x80\x14\x0d\x56\x7a\x5f\x10\x9e\x46\xa9\x2d\xb9\xc0\xb8\xc2\xc8\x76\xdb\xe5\x4c\xb4\x58\x59\x6e\xa4\x25\x1e\xef\x66\xa6\xc5\xaa\xff\x75\x76\x5a\x65\x11\x78\x28\x4e\x39\x80\xf6\xec\xa6\x5a\x14\x97\x7f\x80\x2d\xb1\x51\x7e\xea\xcf\x84\x70\x38\xf5\x67\xcb\xc7\x5e\xb0\xb8\x88\x9b\x20\x5c\x56\x99\x74\xcc\xef\x6a\xb0\x8c\x56\x36\x51\xcb\x6d\xb3\x7c\x93\xe1\x86\xc5\x68\x99\xfe\xb9\x4c\x97\xe9\x9f\xd3\x80\x99\x03\x6e\xe6\x80\x2b\x21\x5a\x41\x8d\xaa\xc5\x26\x9a\x7f\x29\x63\x19\xcd\x01\xb7\x34\xc0\x4d\x27\xe0\xa6\x15\xb0\x1d\x72\x96\x84\xb3\x09\x5c\xbd\x54\xe8\xb0\xbc\x7e\x0d\x7e\x13\x5f\xe8\x73\x93\x3c\x6f\x90\x47\x40\xc1\x06\x45\x4c\xc5\xef\x74\x2a\x2a\xbf\xa3\xd7\xa4\xf5\x1f\x7e\x40\x80\xcd\xef\xe8\x47\x54\xaf\xad\x77\xa4\x19\xaa\xbe\x42\xbf\x17\x84\xbb\x90\xe6\x9e\xda\x82\x4f\xfd\x19\xd8\xcc\x6e\x65\x95\x0a\x47\x18\x3a\xdd\x45\x3f\xa2\x4a\x0b\xad\xa2\xdf\xab\xac\xa7\xad\x91\xd5\xdb\xc9\x88\xcf\x60\x2a\x2e\x82\x80\xa7\xfb\x36\xa9\x91\x7d\x20\x28\xa1\x4d\x24\xa1\xd3\x35\x9c\x49\x20\xb6\x5e\x5e\xdc\x6e\x1c\x3c\x0e\x27\x18\x55\xe4\x7e\xb2\x70\x01\xae\x58\x23\xd6\x61\x91\x9b\x59\xbe\xcf\x8c\xb3\xca\x50\xef\x61\x27\xaf\xf0\xe4\xbb\xdb\x59\x0a\x56\xbb\x14\xa3\xff\xa6\x4d\x2d\xd9\x0e\x41\xed\x7a\xe4\xad\xa4\xbc\xb9\xa5\xa8\xb5\xe4\xe6\x20\xea\x09\x43\x79\xf1\x46\x18\xca\x2f\xe6\xfb\x46\x89\x04\x5f\xe2\x24\xc5\x07\x52\xc1\xfc\x95\x2d\xae\xd9\x77\xf9\x67\x27\x75\x17\x02\xb5\x6d\x01\xfc\x4f\xe7\x3f\x84\xfd\x90\x15\xca\x3a\x58\xc8\x69\xd4\x86\xcf\xf8\xc2\x66\xb6\xf9\xbf\x57\xcf\xd1\x26\xfa\xbd\x5c\xac\x4e\x0b\x4b\xd9\xbf\x88\xe2\x04\x7f\x35\xae\x22\x81\xdc\x8f\x02\xf0\x73\xce\xa7\x3b\x24\x6f\x0e\x47\x8b\x78\x86\xd4\x0e\x85\xf1\xdd\xe6\x26\x5a\x6d\x2c\xe0\x49\x32\x85\xc9\xb5\xef\xc4\x88\xad\x22\x41\x22\xd2\x5e\xa6\xf8\x7d\x1c\xcf\xf2\x25\xe1\xe9\x38\x78\xd2\x8c\x2a\x22\x87\x76\xe3\xe9\xcf\x7a\xe8\xc5\xd6\x9b\xfe\xf6\xce\xee\xdb\xbd\xfd\xff\x7a\xf7\xfe\xe0\xc3\xe1\xd1\xff\x3e\x3e\x39\xfd\xf8\xf3\x2f\xbf\xfe\xfb\xff\xf8\x83\x61\x80\x47\x17\xe3\xf0\xf7\xcf\x93\x69\x14\xcf\xfe\x3b\x49\xb3\xf9\xe5\xd5\xf5\xcd\x1f\xf5\x46\xb3\xd5\xee\x74\xd7\x37\x5e\xae\xac\x6d\xb2\x08\xb7\xe2\x68\x27\x16\xed\xd2\xa8\xe6\x43\xec\xf0\x4a\xc9\x2d\x37\x14\x0b\x53\x9b\x28\xa4\xb5\x63\x73\x53\x21\x33\x1d\x3a\xf6\x1b\xe6\xd8\x95\x12\x21\x49\x5a\x1e\x39\x35\xc9\x0e\x2c\x68\x15\x35\xaa\xe7\xe0\xbd\x92\x0b\x4c\x4d\x93\xb8\x38\xd0\x66\x19\xa0\xd5\x73\xbe\xc1\xcb\x62\x98\x05\x2a\x15\x88\x22\x25\x72\xcf\x17\x22\xcc\x00\xfa\x5f\x68\x8b\xb2\x6f\x4d\x54\x1c\xbc\x07\xb1\x21\x5e\x59\x51\x3e\x08\xb2\x15\x3f\x18\x45\x1a\xb1\x25\xad\x61\x11\x6e\xf3\xdc\x3d\xfa\x21\x5f\xda\x23\x5e\x39\x33\xfb\x74\x9e\x8e\xfe\x4f\x47\x7f\x71\xf4\xff\x78\xba\xbb\xda\xe8\xa2\x37\x3b\xa5\x1d\xb4\x1a\xdd\x37\x3b\xb2\x8f\x56\xa3\xab\x3e\xc1\xd7\xbb\x3b\x6d\x51\x64\xfe\x5a\xc7\xad\x92\x38\x3c\xa0\xf3\x56\xa3\xeb\xf4\xde\x6a\x74\xff\x01\x1a\x81\xf2\x87\x75\x18\x8c\xfb\x9c\xd5\xed\xfe\xfe\x60\x19\x15\x07\xf8\x28\x0e\xa3\xcc\xe5\x64\xdc\xe8\x3a\x9c\x8c\xad\x87\xe9\x1c\x53\xb7\x97\xb1\x68\xb2\xac\xab\xb1\x04\xf4\x1e\x27\x28\x9d\x88\xef\xe5\xac\x06\xb4\xb9\xec\xda\xf8\xa6\x8f\x51\x74\x55\x09\x97\x35\xbe\xf8\x96\xf2\x59\x83\x4a\xcb\xf9\x1a\xf3\x5a\x42\xbe\xe5\x2f\x1e\xdb\xd3\x58\x6d\xb8\x9c\xa3\x71\x03\x64\x1f\x81\xa1\xea\x66\x4c\x44\xa0\x7c\xb1\x34\xc9\x62\xd1\x82\xb0\xb9\x29\xdc\x25\xe5\x68\xa3\xf3\x63\xf9\x50\x18\x8c\x2c\xdf\x97\xd8\xc3\xa4\x7d\xea\xfd\xbd\xf7\xa9\xf7\xdf\xc0\x3e\x55\x06\x87\x87\xde\xa7\xac\xcb\xe9\xfd\xce\xd3\x36\x25\xfe\x1e\x6c\x9b\x4a\xaf\xfc\xd9\x4e\x14\x84\x7e\x54\x59\x76\xc7\xb2\x1d\xc9\xbf\xfd\x2d\xeb\xfd\xe3\x6c\x59\x65\x96\xc9\xb7\xbf\x65\xbd\xdf\xd1\x36\xad\xa7\x1d\xcb\xd8\xb1\xa4\x15\xb3\xd4\xe6\xf5\x55\x77\x2f\x31\x2f\x12\xb6\x04\x90\xd2\x47\x1e\x0d\x1f\xbe\xb0\xbb\x13\xba\xb8\xeb\x75\xf2\xff\x70\xb1\x42\x3f\x92\xee\xb3\xaf\xf4\x5b\xbe\xfc\x17\xa9\x0b\x80\xb0\xdc\xda\x82\xee\xbd\xb4\x05\x2c\x47\xed\xd7\x54\x1a\x78\x48\x7a\x95\x8e\xfd\x86\xf6\x6a\x3c\xf5\x87\x8f\xa8\x5a\xf0\x10\x6f\x16\x7e\x41\x6b\xff\x04\x75\x83\x91\x2f\xf6\x0e\xaa\x08\xc5\x88\x45\xfa\x72\xb0\xdd\x81\x9a\x60\x72\x73\xb0\xdd\xb1\xc9\x78\x60\xe2\xfc\x19\xdf\xd0\x2c\xd8\xd4\x0e\x56\xf4\x15\x9c\x7f\xfd\x28\xe3\x49\xbc\xe3\x64\x4a\x6d\xb4\x77\x7e\x3e\xfa\x04\x9b\xee\x69\xfc\x0e\xe7\xc2\x20\xba\xba\xba\xaa\xc5\x33\x1c\xa5\xe9\xa4\x16\x27\x17\x6b\x41\x3c\x4c\xd7\x20\x09\x77\xbc\xa6\xd5\x19\x67\xd3\x89\x45\x11\xb2\x73\x39\x7b\xb7\xbd\x9b\xa3\x2d\x9e\x4b\x06\x43\x58\xec\x03\xa2\xed\x71\x86\xf7\x0b\x4b\x79\x0e\x7b\x14\x19\x98\x94\x3c\x84\x11\x77\x7b\x91\xc2\x3d\xe7\xae\x2e\x6d\x54\x69\x34\x37\x14\x4f\x17\x03\xbe\xc3\x48\x4d\x0e\x8b\xa1\x27\x48\x39\xd8\xee\x2c\xc2\x36\xcc\x98\x2d\xb2\x1e\xa4\x5a\xfa\x90\xc5\x68\x46\xad\x4e\x65\xef\x1c\xc7\x0e\x67\xf8\xc5\x68\xbb\x03\x1b\x9e\x1e\x6a\x34\x37\xc0\x84\x54\xf9\x4a\x3b\x07\x98\x6b\x5f\x72\x7c\x94\xb6\x6f\xef\xec\x76\xe3\x20\xda\xc7\xf6\xc3\xc1\x52\xa3\x0f\x60\x66\xfd\x39\x18\x19\xde\x37\x94\xe6\x17\xa4\x68\x5a\x5c\xf1\xcf\x7c\xae\x36\xb4\x7c\x7e\x77\x05\xe3\xa9\xd3\x58\xaf\xd7\x75\xc0\x4b\x7a\x07\x2d\xf4\xfb\x29\x27\xef\x6e\x43\x0a\x7f\x42\x23\x84\x2a\x20\x11\xb6\x0f\x19\x58\xc9\xa2\xbd\x8b\x95\x3e\xaf\x4b\x63\x01\xd8\x00\x15\x54\x4e\xfd\x49\x86\xb6\xe0\x9f\xe5\xc5\x62\xa0\x2e\x4a\xde\x0f\x41\x5e\x98\x6c\x1e\x9f\x83\x51\x8d\xba\x45\xe0\x0a\xef\x8c\x07\xf8\x15\xe4\xad\x81\xe2\x4a\x7e\x47\xb5\xe6\x52\x02\xaf\x3a\xc5\x16\xf1\x96\xac\x74\xc6\x3d\xcc\xda\xc2\x4b\x8d\x90\x07\x33\x51\xce\x57\x87\x15\x96\xcb\x2d\x0c\x42\x0b\xd0\x21\x7e\x07\x63\x63\x4b\x89\xb6\xcc\x19\x39\x07\x26\x7c\x82\xc5\x1b\xe7\x71\x99\xef\x31\xb4\x47\xec\xc9\x52\x4e\x62\xe2\xb4\x68\xfe\xc2\x82\xe5\x5b\xb6\x31\x11\xf0\xea\x47\x66\xcc\xa2\xe1\xca\x0d\x5a\x5e\x73\x7c\xac\x47\x01\x22\xc6\x81\xe7\x80\xf3\x82\x59\x75\x59\xa2\x65\x17\x5f\x2b\x23\x39\x18\x43\xee\x04\xc2\xa0\x70\x62\x93\x8c\x82\x0d\x7a\x55\x9b\x17\xfe\x74\x66\x09\x42\x13\x62\xe0\xcc\xcf\xca\x41\xa9\x41\x0f\x4a\xd2\x40\x17\xa6\xfd\xd1\xb0\x17\xc8\x3a\x47\xc1\x86\xb1\x65\xa8\xcc\x77\x12\x59\xb1\x98\x31\xd6\x36\xb4\x51\x96\x6a\x49\x3a\x1a\x4e\x7f\x96\x68\x17\x22\xc0\x1c\xaf\x57\xd6\xe6\xba\x14\x0f\x96\xfd\x8e\xef\xc4\x7b\x97\xe4\xbb\x0f\xe8\x7d\x6b\xf1\x2b\x93\x7a\x53\x9e\x9b\x4b\x95\x14\xed\x86\xf4\x5e\xe5\xee\xf9\x07\xa4\x70\x75\xb1\x69\xd3\xfd\xda\xc5\xd9\x97\xab\xe6\x21\x87\xd8\x70\x1f\x30\x85\x62\x83\x50\x21\xe7\xb2\xbe\x6b\xcf\x31\x5d\x58\xd8\xb0\xab\x12\x0b\x38\xae\x14\xef\x77\xb7\xaf\x0a\x8e\xef\x14\x9a\xfd\xec\xee\xf1\xc3\x67\xaf\xb3\xe1\xf1\x23\x69\x6f\x7d\x9d\x9c\xe9\xd7\xff\xd6\x67\xfa\x61\x38\x1b\xe3\x64\xf5\x91\x4d\x04\xe0\xf4\x2e\x37\xf5\xd7\x1c\xe2\xcd\xcc\x9d\x0f\x72\x9a\xef\x43\xc7\x8e\x08\xc7\x49\xc5\xa1\x5d\x7e\xe9\x36\x21\x10\xef\xb5\x4c\x18\x4a\x0d\x72\x86\xf3\x33\xa8\x44\x7f\x72\x46\xcc\x2a\xee\xc2\xcb\x8c\x45\x55\xa0\x45\x96\x48\xa7\x41\x4e\x37\x74\x6e\x32\x7c\x9d\x91\x53\xa4\xcf\x9e\xd1\x8c\xf6\x89\xf9\x66\xf1\x54\x1b\x7e\x80\x87\xe1\xd4\x9f\x4c\x6e\x58\x1a\xd0\xa0\xf4\xcd\x8d\x3c\x2a\xb7\xac\x15\x36\x70\xa7\x02\x0d\xb5\xd9\xe5\x93\x71\xdc\x05\xbf\x47\x4d\xcf\x91\x4f\x89\x74\xab\x23\x77\x7e\xb9\x8b\x1d\xa5\xa6\xc3\x51\x4b\x2e\x53\xcb\x67\x37\x4f\x20\xb1\x87\xaf\xef\x98\x09\xc2\x32\xbc\x12\xf9\xc8\xf7\x0d\x4b\x4e\xa7\x76\xf3\x10\x46\xb3\x79\x76\x9f\x39\xe5\xe4\xa1\x12\xdd\x1d\xe8\xec\xa1\x88\x63\xa8\x31\x0a\x0b\x7d\xdc\x39\xa9\x04\x8c\x96\x3d\x84\x4d\x3e\x39\x9b\x28\x6f\x83\x56\x78\x65\xa5\x9e\xbe\x42\x3d\x5c\x23\x90\x03\xea\xc9\x40\x6f\xed\xba\x79\xf7\x4e\x9b\x77\x57\xdb\x6d\xa5\x0d\xa2\xd7\x69\x7a\x9a\xf2\x7c\xe3\xc9\xd4\xee\x9f\xae\xfb\x76\xed\x8e\x46\x24\xf3\x22\x4d\xb8\x79\x48\x01\x07\x60\xa1\x71\xb5\x26\xa2\x22\x25\x36\x65\x47\xd5\x87\x49\x48\x0f\x2e\xaf\x0b\x39\x5e\x69\x25\x71\x49\x55\x14\x91\xd5\xc1\x79\x19\x0f\x13\x9c\x3d\x90\x52\x89\xc8\xbf\x7b\xf6\xc0\x41\xd0\x4b\xc6\x26\x6c\x9e\xc8\xd4\xd1\xb7\xac\xc6\x50\x76\x0e\x76\x04\x08\xb6\xea\x8c\x84\xbe\x88\xfa\x28\x88\x47\xdd\xc3\xbd\xc0\xdb\xed\x21\xe3\xcb\xc2\x81\x69\x41\x78\x59\x7a\xa8\x92\xa2\xcb\xea\xe3\x64\x37\xc4\x2f\x50\x4c\xd1\x8e\xbe\x91\xe2\x62\xb2\xae\x17\x45\xc6\xd4\x2a\x71\x7d\x81\x0e\xcb\x1e\x25\x73\x6b\x32\x89\xaf\x90\x9f\x0c\xc2\x2c\xf1\x93\x1b\xc4\xd4\x4b\x9f\xf1\x8d\x25\xee\xe0\x67\x59\x23\xf1\x93\xb5\xe1\x82\x81\xd2\xd5\x2d\xe5\x46\x6b\x81\x33\x24\x41\xa9\xc0\x0d\x12\xe2\xbf\x81\x6e\x23\x4e\x50\x18\x45\x38\x81\xe8\xb3\xf1\x3c\x03\x01\x42\x8f\xc2\x07\x31\x13\xa9\x8e\x91\x92\x21\x7b\xa0\xad\x18\x01\xe9\xb8\xc6\x4f\xae\x11\x5a\x6a\x2c\x43\x02\xb1\xa4\x95\x8c\x8b\xf4\x91\xa1\x54\x30\x94\x0a\x1a\x8d\xfd\x7a\x78\x0c\xf3\x49\xaf\x01\x67\x7e\x80\x86\x71\x94\x66\x7e\xa4\x37\x6f\x4d\x22\xa5\xce\xb1\x5b\xb1\x26\xf0\x3e\x0b\xcf\xd1\x6f\x9b\xa8\x7e\xdd\x19\xd2\xff\xd9\xdc\x61\x8c\xc2\xad\x2e\xfd\x5f\xb1\x66\x2c\xd6\x74\x62\xa1\xf6\x6c\xa3\xc8\xbf\x20\x0e\x19\xec\x40\x8f\x11\x85\x4c\x30\xf1\x07\x89\x44\x56\x90\xaf\xcc\xc6\x8c\x2d\x03\x09\x9d\xb6\xf1\x71\x87\x9e\x54\xd5\x17\xe7\x0b\xe6\x6e\x11\xc8\x60\x98\xbf\x99\xf8\x63\x07\x5b\x7d\x16\x7d\x0c\xf0\x0a\x61\x89\x15\x46\x42\x59\x72\xca\xcb\x04\x22\x33\x4a\x3f\x7c\x30\x32\x99\x24\x78\x2b\x0b\x83\x8f\x3d\x56\xf4\x30\x18\xea\xff\xe9\xd1\xc3\x16\x88\xa9\xcb\x88\x88\x84\x87\xe6\x34\xb4\x30\x82\x98\xbb\xc6\xc2\x28\x62\xee\xaa\x8f\x14\x49\xec\xfe\xdc\xae\x4f\xd5\xd3\x30\xde\x96\xfd\x98\x48\x17\x7b\xf6\xe0\x68\x85\x01\xc7\x0a\x39\xa6\x3c\x56\x1a\xd0\x5c\x42\xe1\x92\x06\xbf\x64\x12\xa8\x54\x9d\x21\xc7\xa6\xfe\xd0\x7e\x49\x24\x0e\xfe\x0e\x23\xb8\x97\x7f\x6b\x85\xf9\x75\xb7\xbd\x6a\x79\x3d\x09\x07\xab\x04\x95\x00\x6c\x5b\x53\xed\x2b\x8e\x86\xab\x60\xd3\x68\x79\x4f\xdd\x2c\xb5\x0f\xd3\xa0\xb3\xd8\xf8\x2e\x1d\xfb\xcd\x8e\x0e\x92\xbc\x6c\xea\xe0\xd2\xb1\xdf\x69\x34\xcd\x97\xad\x0d\x4b\xc9\x96\xf6\x2a\x09\x67\x78\x1a\x34\xba\x75\xab\xed\x9f\xf2\x6a\x36\xf8\x1c\x8c\xf4\x76\xf0\xe5\xec\x73\x30\x2a\xba\x77\x50\xbb\x1e\x07\x78\x75\x38\x1a\x58\x5f\x67\x89\xe3\xf5\xea\xc5\xc4\x0f\xa6\x7e\x64\xfb\x1c\xdb\x81\xe1\xa1\xfe\x7a\xe6\x07\xab\x7e\x94\x86\xd7\x2f\x9b\xfa\x20\x90\x4f\x61\x1a\x37\xea\x8d\xa6\x3e\xe2\xec\xd3\xcb\xf5\x97\xeb\xfa\x0c\x91\x4f\x7f\xe0\x24\x66\xae\xd7\x96\xaf\x91\xe3\x1b\xd5\x91\xad\x8e\xf1\xb5\xf6\xc1\xc7\x3a\x71\xd1\xb8\x1b\x81\xf1\x3e\x19\xea\x93\x9b\xf8\x83\x41\x98\x59\x5f\xae\x4e\xf0\x85\x3f\xbc\x79\xec\x3b\x20\xb1\x7a\xe0\x49\x5f\x34\xf0\x32\x5f\x2b\xe2\x91\x2d\x11\x78\x26\x2b\x43\x33\x0b\x65\xeb\x40\xfc\x6e\xb6\xc5\x6f\x42\xf5\xfc\x37\x21\x76\xf1\x9b\xfe\xca\x49\x3b\xb7\x2f\x85\x5f\x8c\x90\x29\x06\x94\x7e\x8d\x3b\x2c\x8a\x0e\xa7\x56\xe9\x29\x4b\xd4\x27\x41\x9b\xf9\xdb\x58\xa9\x41\x28\x91\x36\x2b\x13\xa0\x78\x23\xe8\x4e\x7e\x43\xc9\x4d\xbc\x91\xa9\x4c\xbc\x8c\xd4\x57\x12\x4d\xc1\x33\x21\x25\xf8\x91\x53\x10\x1d\x95\x21\x1b\x28\x46\x2f\xd2\x6f\x4e\x26\xcb\x2a\x22\x15\x05\xa4\xcc\x6b\x97\x57\x4c\xba\x43\xb1\xb1\x2e\xf5\x3a\x0d\xaf\x58\x9b\xec\xa9\x74\xd5\xeb\xb4\x3d\x85\xf0\x7a\x9d\x8e\x97\x4f\x7c\xaf\xd3\xf5\xd4\xd1\xeb\x75\xd6\xf5\x1b\x61\x9d\x94\x7b\xdd\xba\xc7\xa8\xb5\xd7\x05\x7c\x04\xa5\xf4\xba\x4d\x4f\xa6\x95\x5e\xb7\xed\xd9\xa8\xa5\xd7\x6d\x79\x32\x85\xf4\xba\x1d\x4f\xa6\x9f\x5e\x17\xf0\x52\x68\xa6\xd7\x5d\xf7\x74\xaa\xe9\x75\x37\x3c\x9d\x6e\x7a\xdd\x97\x9e\x41\x24\xbd\xf5\xba\x67\x21\xa7\xde\x3a\xe0\xcf\x96\x44\x6f\x1d\xb0\x67\xa4\xd1\x5b\x6f\x7b\x06\x71\xf4\xd6\x01\x71\x42\x46\xbd\x75\xc0\x39\x5f\x67\xbd\xf5\xae\x7c\x81\xee\xe5\x4b\xb6\xb7\xce\xaf\xd6\xc9\x62\xee\xad\xbf\xf4\xf8\x52\xed\x6d\xd4\xbd\x7c\x09\xf7\x36\x1a\x5e\xbe\xb8\x7b\x1b\x80\x4e\x4e\xc1\xbd\x0d\x68\x5c\x30\x9a\xde\x46\xfb\xf6\xdc\xeb\xd6\x9f\x2e\x0f\xfe\xfa\xcb\x83\xfe\x18\x0f\x3f\x93\x4e\xc1\x4a\xa1\x6e\x40\x34\xcd\x59\x3a\x9f\x91\x81\xc1\x2c\x3e\xb5\xd4\x6f\x90\xe3\x69\x48\x73\xf4\xdd\x26\x7a\xc1\x21\xbf\xb0\x58\x84\x08\x27\x8d\x07\xbc\xae\x28\x34\xc7\x17\xed\x1c\xe3\x11\x4e\x30\x1c\xf4\x92\xf0\x02\xce\x64\x61\x14\x66\x39\x98\x74\x3e\xc3\x09\xa8\xae\x37\xb5\xf4\x1c\x12\x94\xad\xf9\xc5\x14\x47\x99\x56\x00\x65\x31\x1a\xfb\x51\x30\xc1\xca\xb8\xc9\xb0\x07\x56\xc8\x8a\x4d\x0d\x54\x35\xdd\x01\x25\xdd\x37\x8d\x25\x4f\x4d\xa0\xc2\x28\xdb\x90\x34\xf4\x23\xb9\xbe\x50\x4c\xa8\xb3\x63\x1e\xf3\xf3\x1a\x54\x09\xff\x91\x40\x85\x17\x32\x36\xca\x21\xc2\x8a\x58\x4c\xd3\x7f\x01\xa4\xcb\x10\x5f\xb9\x50\x74\x36\x2f\x21\xbc\xcf\x51\x40\x5f\xbe\xa8\xe5\x39\xc1\x01\x96\xa0\x33\xe6\xd5\xbf\x23\x6b\x4e\xd8\x8e\xc0\xa2\xb3\x03\x37\xaa\x56\x8d\x56\x9c\x58\x35\xba\x76\xb4\xdc\x2d\x2d\x57\x63\x3f\xca\x5a\xcd\x65\x9b\x58\xae\xc6\xee\x24\xf6\xef\x52\xa5\xdb\x86\xf7\x79\xf9\x3b\x92\x52\x8d\x52\xb0\x87\xe4\x57\x37\x19\x3e\x84\xe4\x40\xc6\x6b\x5b\xde\x65\x85\xfe\xf6\xe8\xa2\xcb\xdb\x2a\xb3\x22\xf2\xd2\xcb\xa9\x10\x72\x68\x6f\x04\x6e\x68\xd3\x8e\xb3\x45\xb3\xb0\x73\xcd\xb2\xaf\xde\x64\x36\xe3\xe7\xa5\xdc\x05\x6d\xa8\x2c\x93\x4f\x3b\xaf\x7f\x16\x9e\xdf\x29\x79\x76\x6e\xce\x1d\xfe\x81\xa9\xaa\x36\x77\x1c\x55\x8b\x0a\xc6\x9a\xa7\xb6\xf0\x10\x73\x23\xb4\x75\x44\x99\x6f\x6b\xd6\x33\x32\x9a\xe4\x35\x81\x87\x22\x22\xf5\xc9\xcc\xdc\x6c\xd7\x9f\xcd\x26\x37\xac\x61\x3f\xb9\x98\x13\x16\x9e\x16\xf9\x2b\x32\x7e\x5d\x9b\x25\x71\x16\x13\x1c\x65\xce\x5d\x64\x38\xa1\xef\x3e\x76\x05\x4b\xb7\xf1\x24\xeb\xfc\x35\xb2\x0e\x04\x8c\xfe\x0b\xe2\x12\x59\x73\x2a\x95\x30\x91\x80\x2d\x96\xde\xe3\xa1\x2c\xd7\xad\x93\x2a\xa7\x8c\x59\x48\x25\xa9\xea\x52\xbb\xf9\xb3\x49\x7a\x2e\xbe\xd2\x6d\xdb\xb9\xc8\x29\x61\x13\x9b\x74\xf8\x6a\xfe\x20\xa5\x3f\xd2\x30\x62\xc1\x58\x09\xcb\xa8\x5f\x37\xea\xec\xaf\x8a\xbe\xa8\x69\x7c\xd9\xf2\xaa\x54\xad\x16\xea\x07\xdb\x1d\xcd\x9a\xc2\x66\x00\xa2\x7b\x4d\xa2\x4d\x36\xaa\x16\x03\x10\x9e\xf6\xa6\xf0\x76\x2c\xd7\x04\xdb\x73\x15\x9f\x99\x9c\xb4\x7e\xdd\x5d\x6f\x77\x9a\xad\x7a\xc3\x43\xf5\x6b\x3c\x1a\x06\xfe\x60\xe3\xa5\x25\xaf\x62\xfd\xfa\xe5\xc6\xc0\x0f\x86\x23\xec\xc1\xc0\xb4\x9a\x9d\xf6\x7a\x57\x2d\x77\xee\xbc\x11\xd3\xd2\xe8\xc9\xbd\x38\x10\x99\xf4\x6c\x7b\xd7\x95\x3f\x43\x18\xdc\xab\x17\xef\x21\x8d\xae\x7b\xc7\x70\x5f\x5f\xf3\xd9\xa0\x48\x7c\x22\xf0\x78\x7a\x41\x14\x3a\x22\xf0\x1e\x7c\x92\x4a\x1f\x9c\xf1\x87\x73\x9b\x4b\x88\xf4\x99\x10\x9c\x59\x80\xfc\x55\x2a\x15\x09\x26\xf5\x14\x47\x5f\x90\xfc\x12\xf6\xba\x76\x55\xf3\x11\x47\x5f\x4a\x02\x6c\xb6\xab\x16\x80\x10\xca\x58\x71\x49\x37\xc1\xdd\xcf\x38\x64\x4f\xb9\xa1\xb0\x5f\xf7\x2b\x43\x5a\x47\xd2\x98\xa2\x15\x54\xd7\xc5\x07\xa5\x74\x43\x2b\xdd\x28\x2c\xdd\xd4\x4a\x37\x0b\x4b\xb7\xb4\xd2\xad\xc2\xd2\x6d\xad\x74\xbb\xb0\x74\x47\x2b\xdd\x29\x2c\xdd\xd5\x4a\x77\x0b\x4b\xaf\x6b\xa5\xd7\x0b\x4b\x6f\x68\xa5\x37\x0a\x4b\xbf\xd4\x4a\xbf\x2c\x9e\x9d\xba\x36\x3b\x0b\x26\xb3\xa1\x15\x2f\x9e\xcd\x46\x53\x2b\x5e\x3c\x9d\x8d\x96\x56\xbc\x78\x3e\x1b\x6d\xad\x78\xf1\x84\x36\x3a\x5a\xf1\x8e\xc1\x0d\xd6\xd6\x08\x43\xfe\x1c\x46\x17\xa4\x6a\xe8\x4f\x06\x36\xb1\xd9\x27\xdb\xc0\x99\x75\xa0\x06\xf0\xc9\x3a\x28\x43\xf8\x64\x1d\x80\x00\x3e\xb5\x6c\xe8\xf4\xf3\x3b\x68\xf5\x1b\x41\x62\x77\xb7\xe2\x7b\x68\xe0\xa1\xa1\x87\x02\x4f\x5a\xa0\x1e\x42\xeb\x1e\xd9\x42\xeb\xe7\x3a\x6f\x08\x68\xbd\xc0\x43\xa2\x6a\x3e\x42\x1e\x42\x8d\xa6\x87\x4e\xcf\x1a\x46\xbd\x21\xad\x47\x5b\xa2\x55\xf3\x45\x4b\xea\xad\x93\x7a\x4d\xa3\xde\x80\xd6\x13\x48\xfa\x52\xbd\x96\x87\x50\x13\xda\x6b\x19\xf5\x8a\xfa\xd7\x16\xfd\x6b\x2f\xd5\xbf\x8e\xe8\x5f\x67\xa9\xfe\x75\x45\xff\xba\x4b\xf5\x6f\x5d\xf4\x6f\x7d\xa9\xfe\x6d\x88\xfe\x6d\x2c\xd5\xbf\x97\xa2\x7f\x2f\x97\xea\x5f\xa3\xee\xb1\xfe\x35\x4c\x82\x29\xea\x60\xa3\xe1\xb1\x0e\x36\x4c\x8a\x29\xea\x21\xc1\x92\xf6\xb0\x61\x92\x4c\x21\x89\xb6\x3c\x4e\xa2\x26\xcd\x14\xf6\xb1\x2d\xfa\x68\x12\x4d\x61\x1f\x3b\xa2\x8f\x40\x35\x66\x27\xdf\xbe\x75\x74\xd2\x43\xa8\x43\x3b\x69\xd2\x4d\x40\x2b\x5a\x3b\x49\xe8\xed\x25\xad\x68\x12\xce\x90\x56\xb4\x77\xb2\xe1\x21\xd2\xd1\xd3\xb3\x86\x49\x39\x03\x5a\xd1\xda\x49\xc2\x31\x9a\x75\xa8\x68\x92\x4e\x51\x1f\x3b\xa2\x8f\x4d\x3b\xaf\x71\xf5\x91\xd0\x1c\xed\x63\xd3\xce\x6c\x9c\x7d\xec\xf0\x3e\x36\xed\xdc\xc6\xd5\xc7\xb6\xe8\x63\xd3\xce\x6e\x5c\x7d\x7c\x99\xf7\xd1\xce\x6f\x9c\x7d\x6c\x8b\x3e\xda\x19\x8e\xab\x8f\x84\x31\xb2\x3e\xda\x39\x8e\xab\x8f\x1b\x79\x1f\xed\x2c\xc7\x49\xab\x2d\x8f\xf7\xd1\xce\x73\x5c\x7d\x6c\x0a\x5a\x6d\xda\x99\x8e\xab\x8f\xeb\xa2\x8f\x2d\x3b\xd3\x71\xf5\x91\x2c\x7f\xda\xc7\x56\xc3\xbe\x20\xf7\xf6\xdc\xc4\xda\x06\x5c\x5b\x76\xae\xb3\xb7\x67\xef\x24\x19\x56\xb2\xb6\x4e\xcf\x5a\x76\xae\xb3\xb7\x57\xb0\x20\xbb\x50\xd1\xce\x75\xf6\xf6\x1c\x9d\x6c\x7b\xa8\xd9\x82\x8a\x26\xe9\x14\xf5\xb1\x91\xf7\xd1\xce\x74\x5c\x7d\x6c\xe7\x7d\xb4\x33\x1d\x57\x1f\x61\x22\x69\x1f\xed\x4c\xc7\xd9\xc7\xba\xe8\xa3\x9d\xe9\x38\xfb\xd8\xf2\x58\x1f\xdb\x76\xa6\xe3\xea\x63\x5d\xf4\xb1\x6d\x67\x3a\xae\x3e\xb6\x44\x1f\xdb\x76\xa6\xe3\xea\x23\x61\xe5\xb4\x8f\x6d\x3b\xd3\x71\xf5\xf1\xa5\x98\xc7\xb6\x9d\xe9\xb8\xfa\x48\x96\x07\xeb\xa3\x9d\xe9\x38\x69\xb5\xc3\x69\xb5\x6d\x67\x3a\xae\x3e\x36\xf3\x3e\xae\xdb\x17\xe4\xfe\xbe\x5b\x50\xed\xd2\x4e\xda\xb9\xce\xfe\xbe\xbd\x93\x40\x73\xc0\x03\xda\x76\xae\xb3\xbf\x5f\x20\x06\x74\x40\x04\xb4\x73\x9d\xfd\x7d\x7b\x27\x09\xef\x68\xc2\xb0\x76\xec\xa2\x8e\xab\x8f\x64\x3e\x68\x1f\x3b\x76\xa6\xe3\xea\x63\x4b\xf4\xb1\x63\x67\x3a\xce\x3e\xd6\x45\x1f\xed\x4c\xc7\xd5\xc7\x46\xde\x47\x3b\xd3\x71\xf5\x71\x43\xcc\x63\xc7\xce\x74\x5c\x7d\x04\x9a\xa3\x7d\xb4\x33\x1d\x57\x1f\x41\x24\xa7\x7d\xb4\x33\x1d\x67\x1f\x5b\x1e\xef\xa3\x9d\xe9\xb8\xfa\xd8\x16\x7d\xec\xda\x99\x8e\xb3\x8f\x0d\xde\xc7\xae\x9d\xe9\xb8\xfa\xd8\x14\x7d\xec\xda\x99\x8e\xab\x8f\x2f\xc5\x3c\x76\x5b\xe6\x82\x84\x6b\x94\x0c\x27\x53\x1c\x84\x7e\xc6\x9c\xca\xc0\x5d\x41\x2d\x47\x8e\xb8\x68\x13\x55\xe0\xdf\x15\xe4\xeb\x1a\x56\x5a\xa6\xc1\xca\x34\x48\x99\x81\xbd\x4c\x93\x95\x69\x92\x32\x43\x7b\x99\x16\x2b\xd3\x22\x65\x02\x43\x9b\xab\xa9\x2a\x77\x2d\x96\xba\x4b\x06\xb4\x85\x4c\xe9\x22\x9b\xae\x9f\xf9\xb6\x83\xb9\x9f\xf9\x22\x94\x8f\x9f\xf9\x6e\xe5\x58\xf4\x26\xcc\xd2\xd3\x38\xf3\x27\x02\x66\xb4\xed\x67\x3e\xf5\x20\xf9\x11\x6d\x58\xa0\x43\x9d\xf7\x78\x94\x71\xe8\xc2\xe3\x04\xca\x1b\x9d\x71\xa6\xbc\x12\x68\x9e\xe5\x20\x7f\xfa\xe9\x27\xd4\x81\x8b\xb7\xfa\xf5\x46\x3d\xbf\x6f\xcb\x4b\xfc\x0b\xb5\x9a\x06\x71\xa8\x7d\xd9\x43\x9b\x08\xd4\xee\xa3\x49\x1c\x27\x15\xa9\x93\x6b\x8a\xee\xdd\xd5\x39\x28\xfb\x1e\x6d\x4a\x4f\xfa\xc2\x11\xa8\x57\x2a\x95\x1c\xb7\x15\xd4\x6d\xd3\x7c\x69\x2f\x21\x98\x68\xbb\x4a\x15\x36\x76\xfd\x2c\xaf\xca\x70\xce\x95\xb3\xf2\xdb\xf2\xda\x59\x13\x1c\x53\xcd\xea\xe0\x16\xe9\x66\x0d\x2e\xb1\x4c\x67\xdb\x65\x3a\xfb\xde\xda\xd9\xf7\x77\xed\xec\x7b\x6b\x67\xdf\x97\xed\xac\xd9\x5b\xd9\x89\xaa\x22\xba\xcf\x83\x4d\x41\x4e\x3d\xbb\xff\x20\x18\xbc\x53\x37\x06\xf0\x51\xb4\x79\x52\x15\xe6\x95\x5f\xe0\x0d\xa9\xe8\xbc\x2d\xe4\xbb\xc7\x0c\xe3\x9d\xde\x6f\x4b\xdd\x7b\x38\xae\xb8\x50\xd1\xf5\xbf\xc0\x04\xae\x30\xf6\xce\xec\x77\x17\x7b\xec\x96\xac\x52\xd9\x53\xae\x25\xf6\x96\xbe\x8f\xa0\xb4\xb0\xa7\xdc\x45\xec\x39\x2f\x21\x16\xdf\x38\x1c\xb3\xdc\xc0\x30\x87\x2c\x02\x4f\x00\x63\xaa\x16\x2d\x91\xac\x1c\xdc\x10\x0a\x59\x3d\x28\x58\xc1\x29\x53\xdc\xd0\xc1\x63\x7e\xfd\x6f\x6c\xbc\xf0\xf9\x93\x41\x0b\x2e\xef\x4a\x1e\x41\x83\x7c\xb5\x7b\x38\xd0\x5f\x02\x49\x4d\xf5\x75\xed\xa1\xd4\x43\xea\x15\x1a\xf0\x49\xb4\x89\x7c\xb4\x82\x2a\x95\x01\xfa\x81\x6e\x8e\x95\xff\x4b\x7e\x06\x55\xc2\x06\xae\xd1\x0a\xca\xa4\xf6\x44\xc0\xe2\x88\x4c\x53\x4a\x57\x2a\x8d\x53\xde\x6a\xa2\x55\x94\x56\xa1\xda\x40\x33\x7a\x13\x58\x69\xe7\xff\x72\x58\xc1\x76\x5c\x19\xa2\x1f\xd0\xff\x7d\x1c\xac\xb4\x43\xd0\x42\xac\x06\xe8\x37\x34\x44\xbf\x11\xc4\x1e\x1e\x19\x4d\x00\x5c\x88\x0c\x41\xa4\x32\x40\x5f\x1e\x78\x70\xe4\xdb\xea\x13\x57\x9a\xf4\x85\x89\xf7\xcb\x04\x59\xe3\x7e\x62\x9a\x8b\x22\xac\x06\x13\x8c\xc3\x59\xcc\x51\xfa\xae\x61\xcd\xd8\xba\x14\x46\x2e\x07\xdb\x1d\x8b\xef\x57\x71\x79\xd3\xe1\x2b\x8f\x2f\xa6\x5c\xe6\xab\x19\xf9\x0f\xb6\x3b\x56\x93\x01\xe7\x24\x2c\xc8\x55\xff\x50\x53\x70\xa7\xd0\x0e\x8b\x27\x4e\xf6\xf2\x7b\x88\x89\xa3\x4e\x65\x62\x22\xf6\xa6\xfe\x90\x4c\x86\x92\x19\xde\x9c\x0f\x56\xcc\x9c\x93\x3c\x9b\x3d\x9d\x97\xc2\x0c\xec\x2c\xb2\xb5\xc3\x02\xaa\xf9\xb7\x76\x31\xfb\xe7\xc7\x64\xa3\x8b\xed\x47\x16\x67\x08\xed\x62\x1c\x0c\xfc\xe1\x67\x16\x57\x73\x1a\x07\xb0\xa4\x08\xcd\x88\xf9\x86\x97\xfd\xdd\x37\x44\x04\xb2\x88\x07\x60\xe6\x04\x5f\x15\x6b\x39\xb0\x70\xa1\xad\x1c\x10\x00\xcc\x98\x47\xac\xfa\xfe\xee\x9b\xda\x4e\x44\x63\x95\x83\x01\xd5\xee\x1b\x8b\xc1\xcf\xcc\x61\x2e\xc3\xcc\x0c\x0b\x4c\x66\xdc\xa2\x29\x0b\x41\xc5\x05\x12\xfa\x68\xbb\x67\x96\x42\x79\xd0\x42\x72\x28\x0f\xb5\x3c\x8f\x51\xfe\x0e\xdf\xa4\x59\x82\xfd\xe9\x56\x14\xb0\xde\x59\xac\x23\x63\x66\x16\x2b\xc0\x79\xac\x01\x9b\x90\x7d\x8c\xa7\x18\x82\x8c\x83\x31\x26\x9d\x27\x16\x2b\x13\xfc\xe7\x23\x7c\x9d\xd1\xd7\x76\xf1\x1d\x5f\xbe\x61\x31\x53\xa1\xf5\x5a\x3a\x09\x87\xb8\xc2\x51\x10\x37\xf5\x02\x17\x9b\xfd\xa4\x32\x6b\xdb\xf8\x9f\x32\x6b\xf7\x18\x5d\x30\x1c\x1e\x87\xe9\xd2\x63\xfb\xd5\xe8\xe6\x34\xef\xd0\x00\x0f\xe3\x29\xf3\xba\x27\x04\x11\xc6\xf3\xb4\x1c\xc9\x88\x2e\x96\x12\xc7\x0b\x7a\x53\x59\xd8\x05\xcd\x37\xc2\x3c\xb0\xc1\x79\xef\x32\x0f\xd6\x72\xf9\x4a\x35\x1a\x97\xc3\x31\xd3\xe6\xf3\xcf\x90\xd9\xf5\xd2\x7a\xa4\x11\xa5\xd1\x26\x0a\x2f\xd9\x14\xd6\x1d\x2b\x31\xbe\xc4\x68\xff\x67\x38\x7f\xa6\xf3\x41\x8a\xff\x7b\x8e\xa3\xac\xe0\xf4\x0c\xf8\x0a\x07\x86\x85\x06\xd0\x3a\x3e\xda\x84\x98\x93\x40\xfe\x18\x95\x63\x3a\xd0\x50\xb0\x22\x80\x78\x48\xed\xca\xda\x1a\x62\x33\x92\xbf\xb3\x66\xcb\x2d\x8e\x1a\x43\x4d\xcf\x73\x0b\x41\x88\x04\x23\x1a\x85\x73\xb4\x41\x2f\x0c\x0b\x2e\x4e\xec\xbe\x29\x32\xb8\xe6\x9b\xce\x32\x71\xea\xba\xad\x27\xe1\xe3\x5b\x17\x3e\xd0\x7f\xce\x12\x9c\xe2\xe4\x12\x53\x31\x24\x9e\x13\x51\x5e\x12\x3f\x40\x8d\xe1\x67\xe1\x60\xc2\x38\x30\xda\x4e\xd0\x9b\x24\xf4\x23\xf4\x96\xba\x67\xa2\x51\x38\xc1\x38\x1a\xd6\x86\x00\x82\x87\x7c\x86\x08\xd8\x1a\xfd\x9c\x1e\x43\x91\xff\xf2\x23\xb4\x97\xcc\x07\x37\xe8\xf7\x31\xf9\xa7\x76\x85\x07\xff\x79\x31\xf5\xc3\x49\x6d\x18\x4f\xed\xf2\xce\xe9\x31\x6f\xae\x40\xec\x91\x0b\x95\x96\x7e\x9e\xe5\xf9\x5e\xa2\x21\x39\x28\xd0\x94\x49\xcf\x9f\x3d\x23\x83\x0e\xa4\x27\xd2\x21\x81\x92\x88\x2a\x85\xaa\x30\xeb\xf4\xd7\x9f\x68\x6d\x2d\xbe\xc4\xc9\x68\x12\x5f\x91\x3a\xb0\xf1\x35\x78\x3a\x50\x52\xaf\xd1\xad\xfe\x40\xca\xbe\x12\x9f\x9b\xf2\xe7\x0d\xfd\x6b\x8b\xed\x61\xac\x31\xc0\x13\x50\x21\x60\x45\xbb\x6b\x6b\x88\x37\x8b\x06\x0d\x52\x04\x50\x86\xa6\xeb\xaf\x44\x95\x66\x5e\x45\x94\x79\x06\x08\xd0\x42\xb4\x54\x4b\x2d\xc5\x8a\x3d\x03\x54\x58\xb9\x5b\xf8\x2f\x21\x48\xb9\xc4\xca\xca\xa0\x25\x7d\x87\xff\xf0\x32\xb4\xc8\xca\xca\xa0\xf9\xea\xb9\xbb\xc0\xca\xca\xa0\xc1\xbe\x93\xff\x42\xc7\x79\xa3\xf0\xb0\xb2\x09\x3d\x7f\xfd\x9a\xe5\x83\x94\x5f\x37\xa9\x0a\x50\x79\xcb\x10\x32\x5b\x12\xd5\xea\xd7\xf5\x06\xd3\xfa\xe5\x45\x19\xd7\x23\x85\xc8\xcb\x5b\x9d\x3a\xd8\xf2\xa8\x0c\xe9\xbf\x2a\x8d\xb0\x97\xf4\x06\x89\x93\x52\xfe\xb2\xca\x08\x46\x9a\x82\xb5\x35\x44\x76\x09\xb8\x89\x41\xa1\xb4\x90\xe8\xe2\x31\x56\xda\x8b\x14\x01\xbc\x14\xc5\xd1\xe4\x86\x2e\xc7\xed\x5f\x0e\x8f\xb7\xd1\xef\xe8\x35\xda\x00\x98\xbc\xc1\x86\x0d\x0b\x7a\x17\xa7\x76\x96\x7d\xe3\xfd\xe5\x6b\x49\x39\x0b\x88\x75\x55\x73\xbc\xfe\x0b\x65\xce\x65\x45\x4e\xa3\xb8\x26\xc3\x98\xad\x32\x9e\x28\x9a\xe5\x03\x66\xa0\x5e\x24\xf1\x20\xb7\xd4\x03\x42\x83\xbd\x91\x62\x19\x08\xdd\x41\x0e\x42\x8b\x65\x21\x2e\x1d\x10\xc2\x36\x69\x9e\xb2\xa2\x67\xba\x68\xc4\x3e\x4b\xb8\xaa\xaa\xe7\x65\x84\x22\xe4\x10\x8c\xd0\xdd\x84\x23\xb4\xa4\x80\x84\x54\x79\xce\x3c\x74\xe5\x74\x2f\x9f\xbd\xc4\xd2\x78\xa5\x49\x56\xa2\xb8\x24\x60\x39\x45\x2c\xa9\xf0\x12\x92\x56\xfb\x49\xd2\xfa\xd6\x25\x2d\x87\x7c\xe5\x50\xef\x9c\x1e\x17\xcb\x39\xcb\xaa\x77\x2c\x2c\x5d\xe7\xe5\x4f\x4c\xfc\x9f\xc7\xc4\x0b\x4f\xb3\x8f\xc0\xb2\xf7\xa3\x61\x82\x21\x72\x03\x03\xae\x81\x64\x72\x48\x3e\xb9\xab\x88\x1a\xd3\x38\xbe\xc0\x6d\xf9\x17\x54\xff\x5b\x6d\x0e\x65\x77\x85\xc5\xe7\x6d\x52\x66\x89\x5d\xa0\xf3\xb4\x0b\xfc\x2d\x76\x81\x9d\x09\x1e\x66\x49\x1c\x85\x43\xd4\x8f\x03\x3c\x88\xe3\xc5\x0a\xff\x9d\x7e\x91\xc2\x9f\x7e\x5d\x6a\x47\xd8\xe9\xab\x0a\x7f\xf2\xfc\x50\x3b\x80\xcc\xda\x55\x06\xa2\xd6\x2b\xd2\x62\x12\x7c\x94\x85\xf4\x58\xf8\x05\xf8\x4e\xf8\xf1\xd4\x4b\xfd\xc5\x7a\x33\x28\xb3\xc4\x3a\xfe\x7b\x27\x47\xfe\x9f\xb3\x8e\x0f\xe7\xd9\x6c\x9e\x95\xbf\xb4\x3b\x2c\xbc\xb4\x3b\x5c\xfe\xd2\x4e\x97\xea\x0e\xb5\x4b\xbc\xc3\xbf\xf6\x3a\xe8\xd1\xa5\x3a\x53\x37\x2f\xde\x3c\xac\x64\x57\xd0\xd0\xb7\x22\xdd\xfd\x93\x4e\xd8\x87\xda\xb5\xa6\x4b\x88\x3a\x2c\x71\x69\x71\xb8\xe4\xa5\xc5\x53\x16\xbb\xbf\x07\xf3\xdd\xfa\x70\xb2\x8f\x7e\xad\xbd\x6c\xb6\xb8\x81\x38\x4a\x33\xb2\xbc\x2f\x6e\x0c\xee\x3b\xf3\x83\xda\x56\x94\x86\xbf\x92\xd2\x22\x17\xdc\xcc\x0f\x64\xf6\x17\xf8\x99\x2f\x5d\x84\xba\x2e\x40\x53\xf5\x06\x94\xd4\x3a\xc9\x0d\x7e\x15\x03\xe0\x57\x6a\xd1\x81\x9e\x56\x64\xe0\x4a\x28\x02\x44\x31\x8f\x32\xd1\x33\x2d\x98\x15\xd8\xe2\x1d\xd1\x6f\x06\x30\xfa\x62\x55\xc5\xec\x5f\xda\x77\xa3\x35\x1a\xd3\x66\xe2\xa7\x34\x72\x16\x9a\xc5\x69\xa8\x7a\xe0\x93\x46\xc9\x77\x52\xff\x28\xe6\x9d\x15\x2d\xac\x68\x18\xad\xa2\x86\xd6\xc8\x91\x1f\xe4\xcf\x30\x50\x22\xdb\x88\xfa\x9a\xb2\x12\xb9\xad\x3c\xa4\x96\xda\x48\x1e\x52\x4b\x2e\x6d\x0b\xae\xa5\x5a\x66\xaf\x68\x80\xb8\x1d\x22\xb7\xc0\x9d\x47\x16\xe2\xd0\x29\xe2\x2d\xce\xa4\x84\xf3\xca\x54\x51\x05\xbe\x18\xcd\xe2\x99\x93\xfa\x5c\x51\xd1\x5c\x25\xc7\x5f\xd6\xf7\xfc\x22\x48\x42\x81\xed\x2b\x86\x87\x84\x06\xc6\xd1\xdb\xe7\xcf\x6e\xad\x7c\x93\x2f\x97\xeb\x97\xcd\xd6\x52\xbc\xf3\x7e\x89\xc9\x9e\x78\xe7\xd7\xe2\x9d\xfb\x27\x87\x08\x42\xe2\x96\x63\x9d\xfb\x2c\x80\xee\x7d\x59\xe7\x5f\xce\x0e\xf3\x25\xb1\x80\x1f\x5a\x58\x15\x4d\x07\x60\x8f\x40\x57\x4b\xfc\x28\x88\xa7\x15\x83\x03\x56\xab\x35\x4d\x52\x2a\x86\xc3\x52\x87\x9d\x19\x5c\xae\xd9\x3e\xf7\x08\xb8\x27\x46\xa5\x33\x2a\x4e\x9c\x4b\x31\xaa\xbf\x77\xe6\x85\xff\x51\x8c\x6a\x6d\x7f\xa7\x8f\x5e\xae\xbf\x5c\x5f\x6d\x20\x46\x1b\xe8\x00\x67\xe3\x38\x40\x4d\x17\xb7\x82\xd0\xde\x77\xe5\x56\x5b\x41\x40\xfd\x07\xd5\x05\x51\x82\x0b\xf0\xd5\x4b\x6a\xd3\x3f\xbe\x68\x95\x06\xfe\x0f\x4e\x62\xc8\x1d\x96\x8d\x31\x4a\x70\x2a\xf1\x45\xa5\x23\xa4\x1c\xeb\x31\x79\x36\xf0\xbe\x13\x2f\x60\x0b\xf1\x0f\x86\x83\xba\x1a\x9d\xcd\x03\x68\x0a\xcf\xbe\xb0\xe3\x08\xa3\x69\x9c\x60\x2a\x3c\xae\xae\x42\xdf\x5c\xa3\xc8\xd7\xfb\xea\x6a\xc9\x05\x0e\xf3\xb9\xcc\x02\x5f\xbf\x5f\x94\xf3\xa7\x05\xfe\xd5\x4e\x71\x28\x8a\xe3\x59\x39\x31\xe4\x03\x27\x47\xe7\xca\x16\xc4\xee\x5e\x13\x79\x91\x22\x9a\x13\x4d\x2d\x45\x74\xf7\x0b\x37\xfb\x44\x74\x5f\x8b\xe8\xfe\x8f\xc4\xfc\x8a\x49\x4e\xe2\x81\x7f\xa1\xf0\x5b\xfa\xe0\x2c\x9f\x6f\x0d\x01\xb8\x52\x29\x16\x81\xab\xe8\xcb\x17\xfd\xd5\x9d\xb6\x18\x7b\x8f\x17\xc7\x15\x58\x5b\x43\x1f\x09\x7c\xb5\x5e\x68\x44\x0a\x00\xcd\x82\x28\x73\x35\x0e\x27\x18\x55\xbe\xab\xe4\xbe\xd6\x79\x0c\x6e\xf0\x38\x34\x62\x6e\x0b\x13\x4e\x43\x91\x19\x8a\x2d\x09\xa9\x2a\x4a\xdd\xb1\x1b\xe2\xf1\x96\xd9\xbd\x24\x0a\x5a\x8a\x97\xfc\xbd\x1d\xb7\x2c\x39\xba\x68\x92\xac\xc7\xe5\x2b\x79\x26\x24\x68\xed\xaf\xcf\xf3\xf1\xb8\x49\xc2\xcb\xc5\xc4\x36\x62\x5e\x8b\x2f\x27\x7b\x5b\x8d\x3c\xd6\x33\x79\x92\x3e\x9a\x89\xc0\x6d\x0e\xa2\x47\x7e\x9a\x92\x85\xbc\x4a\x50\x0b\xd0\x3b\x7c\x83\xb6\x71\x12\x5e\xd2\x9c\x90\xbb\x7c\x50\x9a\xc5\x31\xa7\x8f\xde\xbc\xdb\xde\x6d\xe6\xad\x89\xe7\x92\x89\xc7\xfb\x71\x34\x0a\x2f\xe6\x2c\x13\x65\x0c\x59\x21\xd3\xa2\xfc\x92\x49\x3c\xc3\x49\x76\x83\xfe\xa4\xc7\x62\xf0\x26\x05\xe6\x7b\x3a\xa6\x39\x8e\x53\xf2\x10\x46\x2c\x5d\x40\x16\x0b\x5f\x9a\x1a\xda\xc6\x23\x7f\x3e\xc9\x7a\xa8\x8d\x2a\x8d\xe6\x06\x24\x52\xae\xba\xe0\x3b\x12\x9a\xe3\x84\x27\x32\xcf\xc1\x91\xf1\x5f\x84\x66\x98\xb1\xe4\x99\x29\x80\xca\x0f\xf5\xd2\x87\x2c\x46\x33\x9c\x8c\xe2\x64\x2a\x01\x57\x20\x4b\xe9\x1f\x87\xa3\x8b\x9e\x6b\x94\x11\xbd\xf8\x3a\x81\x98\x33\x8d\xe6\xc6\x5a\xab\xa9\x85\xe0\xa6\x5d\xa1\xa8\x6b\x9f\x72\x84\x94\xc6\x6f\xab\x45\x09\x49\x8b\x12\xc8\x93\x59\x09\x72\xd2\xe2\xeb\x6d\x71\x16\xd1\x43\xe0\x73\xb7\xa4\xab\x72\xc6\x50\x32\x7e\x43\x1b\xdd\x70\x7f\xb3\x51\x9c\xc0\x29\x26\x6f\xf4\x01\x12\x83\x7e\x0e\x46\x46\xd2\x78\x4a\xed\xfc\xf4\xa8\x98\x61\x2d\x53\xf1\xcf\x7c\xb2\x36\x68\xfa\xc9\x7b\x83\xf1\xd4\x69\xac\xd7\xeb\x3a\xe0\x82\xec\xf5\xc3\xd1\x85\xdd\xf0\x82\x4c\xc4\xa6\xf8\xc9\x09\x8f\x14\x77\x05\xc3\x30\xd7\x3b\x5c\x57\x50\x0f\xba\xb2\x2c\xe8\x2e\xf9\x66\x67\x0c\x36\x50\x0b\x7f\xa8\x95\xac\x9c\xfa\x93\x0c\x6d\xc1\x3f\xcb\x27\xa2\xe5\x6e\x34\x92\x5f\xfb\x7d\xc8\x8e\x26\x52\x0f\x46\x35\x16\x95\xa4\xc2\x3b\xe3\x01\x7e\xce\x49\x65\xc5\xe5\x79\xd5\x6a\x2e\x95\xdb\x45\x9d\x7a\xab\x01\x61\x98\x39\x92\xc2\x32\x2f\x7b\xf0\xdd\x67\xb4\x4a\xc8\x87\xf2\x20\x4f\xcc\x8e\xdd\x2c\xd1\x9d\xa0\x1c\x64\x53\x3a\xd8\x34\xdd\xbc\xa1\xcf\xb1\x85\x7a\x02\x39\x79\x3f\x0a\xf0\xb5\xad\xc6\x59\xfd\x9a\x29\x80\x2c\xd1\x3a\x17\x84\xe8\x12\xa8\x08\x61\x59\xbc\x71\xe6\xaf\xcf\xb1\xe1\x95\xf2\x37\xce\x4a\x7c\xcb\xdb\x24\xb3\x52\x63\x4f\x36\x23\x8c\x7c\x6b\xa1\x45\xf3\x17\x0b\x8c\x2c\xd4\x8f\x4c\x50\xd7\x3a\xc8\xe3\x22\xbd\xe6\xf8\x58\x8d\x0b\x44\x27\x59\x9e\x63\x9e\x2c\x1b\x28\x30\x4f\xe3\x9b\xf7\x5a\x9f\x33\xc4\x32\x7a\xe7\xa9\x81\xcd\xef\x8b\xb3\x31\x00\x7c\x65\x88\xad\xa3\x6b\x16\x17\x59\x8c\xf2\x57\xac\xe3\x0e\x44\xf6\xc5\x18\xdb\x41\x87\x72\x34\x3b\x06\xd6\x82\x85\x62\xcb\xd1\xa0\xb6\x1c\xd2\xf4\x39\x8d\x39\x10\xf0\x73\xa5\x09\x18\x3d\x31\xd2\xf2\x47\xdb\x58\x97\x19\x6f\xb4\x28\x14\x94\xad\xb3\x7c\xf4\xe5\x77\xf6\x80\x55\x52\x13\xbf\x1e\x1e\xab\xdd\x01\xd7\x29\x8b\xc7\xb5\x31\x6e\xbf\x53\x1b\x98\xdf\xb9\x0d\x8c\x34\x9b\xaf\xd0\xef\x05\xa3\x47\xfe\xf2\x1a\x67\xbf\x83\x39\x8c\xd1\x91\xb3\xdf\x75\xb3\x18\xfe\x77\x6b\xbe\xd6\x03\x4e\x91\x3f\x89\x39\x30\xdd\x34\x34\x6a\x9b\x12\x8d\x49\x9c\xd5\xcf\x57\x56\x8a\x4d\x8a\x24\xe0\xd2\xd1\x97\xf3\x0d\x4b\x10\x33\xb6\x97\xe5\xf5\x8a\x0c\x28\xe5\x63\xc4\xbd\x36\xf4\x32\xc1\x66\x0a\x37\xf2\x25\x37\xf1\x87\x12\x2d\xc3\xd4\x96\x6e\x7f\x71\xf4\x1a\x8b\x68\xf0\x00\x41\x6c\xa8\x88\x20\x24\x43\x2a\x14\xba\xc4\x84\xe5\xaa\x79\xc8\x21\x9b\xde\x07\x4c\xa1\x6c\x9a\x07\xd9\x11\x47\x49\x97\x00\xe3\x21\x5d\x50\x65\xc3\xae\x8a\xc5\xa4\xd0\x02\xe1\xe9\xb6\xc8\x16\x8d\x42\xb3\x07\xea\xd1\x53\xe8\xf2\x9c\xb0\xb7\xe7\xde\xfa\xdf\xdb\x87\x7e\x89\xb4\xee\x8b\x93\xa3\x3f\xae\xee\xc8\x99\x5e\xdb\x95\xf5\xfa\x9f\xa0\x5d\x3a\x01\xe3\xcc\x3e\x37\xde\xa5\x4a\x24\xf9\x65\x91\x1e\x49\xe0\x71\x8c\xe7\xa9\x3f\x98\x60\x16\x0e\x4c\x42\xe7\x04\xc9\xa9\x16\x29\x14\xfd\xcd\x5b\xa4\x66\x58\x93\xb6\x85\x63\xc8\xa6\x8c\x98\xa1\x2d\xb3\x31\x36\x35\x49\xa2\x3c\xc4\x58\x09\x53\xe4\x23\x9a\x80\x19\x5d\xe2\x24\x85\xa8\x65\x63\x3f\x43\x11\xbe\x98\xe0\x61\x86\x03\xc2\x86\x87\x2c\xa5\x6a\xc6\x14\x3e\x59\x8c\x26\x61\x96\x4d\xf0\x2a\x0d\x70\x59\x53\x81\xe2\x24\x89\x13\x14\xc4\x38\x8d\x5e\x64\xc8\x1f\x8d\xf0\x90\xd6\xa5\x48\xbd\x48\x51\x8a\x87\xf3\x24\xcc\x6e\x3c\x51\x71\x30\xcf\x50\x98\x41\x25\x5e\x23\xcc\x52\x11\x50\x21\x9c\x84\x19\x73\xe2\xa6\x79\x5d\x43\xc2\x9f\xa7\x38\xa2\xfb\x41\x6a\x53\x94\xd1\x01\x79\x4f\x3b\x27\xd4\x65\xda\x5b\x79\xfe\xee\x9a\xb4\xad\xf8\x90\xf2\x4e\x36\x83\x76\x1e\x30\x72\xeb\x6d\x38\x35\x5c\x16\x9d\x16\x42\x76\x42\x23\xbb\x17\x76\x9e\xd3\x7e\x15\xed\x92\x5f\x96\xc4\x71\xef\xce\xea\xe7\x1e\xaa\xbc\x3b\x6b\x9d\xb3\x60\x01\xe8\x0b\x79\x64\x57\x01\x8d\x6e\xd5\x92\x44\xee\xdd\x59\x83\x56\xaa\xab\x95\x5a\xc5\x95\x9a\xb4\x52\x43\xad\x54\x2f\xae\xd4\xa2\x95\x9a\x6a\xa5\x86\xa8\xa4\xd6\xb1\x65\x47\x32\x86\x8c\x7b\x19\xba\x06\xad\x2f\x06\xad\x6f\x1f\x34\x13\x1f\x69\xb8\x58\x9f\xe8\x85\xc9\x68\xc4\xd3\x0e\x52\xa4\x69\x90\xd5\x7a\x9d\x7c\xb1\xf5\xd7\x9c\x88\x96\x0a\xb9\x61\x85\xdc\x2c\x05\xb9\xee\x1c\x78\x09\x86\x06\xb9\x55\x0a\x72\xc3\x35\x3b\x9e\x04\x43\x83\x5c\xd7\x20\x2f\x9e\xc8\xbe\x9f\x24\x37\x68\xa0\xa7\x53\xa5\x53\x35\xa0\xf1\x2f\x4c\x4d\x46\x46\x27\x9f\xb0\x9e\xf4\x26\xcd\xf0\x14\x8d\xe2\x79\x82\xb2\x70\xaa\xcf\xfd\x92\x41\x79\x23\x7c\x9d\x9d\x90\xd5\xe7\x8e\x1f\x6b\x89\x78\x7b\x10\x07\xe1\xe8\x86\x72\x42\x4a\x87\x25\xb0\xd8\x70\x63\xd1\x3f\xa3\x8e\x03\xbf\x9e\x41\xca\x4b\x88\xb6\x62\x64\x8a\xb3\x25\xc9\xfd\x19\xa5\x38\x9b\xcf\xd4\x0f\x05\x1e\x1d\x8b\x0f\xfb\xfb\x3f\x53\xd7\x8e\xa2\x13\xfe\xfe\xcf\x9f\xea\x68\x13\xed\xff\x6c\xa6\x46\x93\x8a\x34\x68\x91\x86\x35\x9a\xb1\xbc\xa4\x61\x2a\xd3\xf9\xe0\x12\x13\x51\xc1\x75\xf4\xaf\xd3\xe0\xc7\xd0\x36\x8d\x7e\xfc\x05\xd1\x27\x57\xf4\x63\xb9\x38\x0b\x73\x2c\xca\xe7\xd7\xa1\xf6\x30\xc7\xa2\xd9\xa6\x68\xb6\xa1\x34\xdb\x58\xd4\x6c\x43\x6d\xb6\xb1\x5c\xb3\x10\x46\x27\xac\xf3\x25\x48\x80\x84\x4d\x75\x05\xba\xaa\xb6\xa0\x6a\x93\x2f\x66\xa8\x5a\x57\x97\xa9\x63\x46\x18\x59\x17\xb1\x56\x04\xd4\x5a\xa7\xe7\x7a\x3d\xb6\x3f\xfd\xd8\xa0\x1f\x1b\xd6\x8f\x4d\xfa\xb1\x69\xfd\xd8\xa2\x1f\x5b\xd6\x8f\xed\xa2\x36\x3b\x45\x6d\x76\x8b\xda\x5c\x17\x6d\x16\x68\xa4\x4a\x71\x1e\xb4\x3c\xf7\x41\xe5\x38\x10\x32\x95\x14\xb2\x1f\xd1\x83\x24\x77\x75\x2a\xaf\x25\xe9\xa3\x14\x67\x56\x8b\xd8\x7b\xe7\xde\xde\x61\x70\x73\x2f\x33\xe0\x42\x6a\xe9\x13\x1a\x6a\xe8\x57\x20\x42\x54\xf9\x95\xcc\x3d\x5f\x25\xf0\x2c\xf6\xde\x57\x7a\xc5\x06\xad\xd8\x64\x15\xd7\xb5\x8a\x1d\x67\xc5\x26\xad\xd8\x66\x15\x1b\x5a\xc5\x75\x67\xc5\x16\xad\xd8\x3d\x17\xa8\x29\x15\x1b\x79\xc5\x7b\xed\x62\x45\x51\xea\x29\x22\x3c\x76\xfc\x09\x4b\xc9\xce\x82\xc7\xc3\xe3\x5d\xa2\xc7\x73\x38\x8c\xc1\x09\x38\xb6\xf8\xf1\x56\x7c\xad\x4e\x78\x48\xca\xd1\x2b\xbc\xe9\x4e\x8a\xbd\xe8\x64\xea\x17\x76\x3c\xf9\xcd\x6d\xfe\x31\xbc\xa4\x5f\xba\xed\xb5\x56\x53\x57\xcb\x89\x65\x22\x08\xb6\x52\xd2\x15\x4a\x59\x1f\xca\x17\x49\x04\xd5\x0c\x7e\x4e\xfc\x4b\x8c\xe2\x49\xe0\x64\xb5\x4b\xc8\x0f\xfd\x4f\x74\x72\xfb\x7a\xbc\x43\xa5\xc5\xbe\x3f\x19\xce\x27\x64\x85\x45\xf8\xca\xd9\x6c\x9f\x25\x82\xe9\xd3\x44\x30\xf5\xeb\x76\xd0\x82\xff\x43\x2b\x5c\x42\xd3\xf3\xb5\xf4\x59\x5e\x98\x3e\xcd\x0b\x53\xbf\x66\x35\x5a\x10\x53\xbe\xcf\x05\xd4\x7a\x15\xbd\x46\x95\xfe\x27\xe9\xf9\x3f\x50\x03\xf5\x50\xbd\x6a\x42\x6c\x32\x88\x4d\x0a\x91\x01\x6c\x33\x88\x0d\x0d\x62\xa3\x04\xc4\x16\x83\xd8\x32\xba\x55\xa1\xed\x28\x10\x9b\x25\x20\xb6\x19\xc4\xb6\xb5\xd7\x2d\x0d\x62\xab\x04\xc4\x0e\x83\xd8\xb1\xf6\xba\xad\x41\x6c\x97\x80\xd8\x65\x10\xbb\xd6\x5e\x77\x34\x88\x9d\x12\x10\xd7\x19\xc4\x75\x6b\xaf\xbb\x1a\xc4\xee\x42\x88\xb9\xd8\x4f\x81\x2a\xd5\xd7\xf5\xea\xba\x77\x8c\xa0\x69\xb2\xfb\x5c\xac\xde\x63\x11\x91\x52\x17\xd7\xc0\xab\x43\xd2\xb5\xbe\x25\x09\x07\x4f\x97\x9f\xcc\x87\x19\x1a\x87\x17\x63\xe4\x47\x01\x9a\xc4\x57\xc8\x4f\x2e\xe6\x10\xfe\x05\xdc\x9c\xff\x7b\xee\x27\x46\xe2\x1e\x68\xc0\x47\x9b\xa4\x15\x2e\xc5\x59\x94\x07\x17\x03\x5a\x84\xee\x12\xd6\xe3\x13\xef\xb3\x82\x41\x82\xd3\xf9\x24\x43\xf1\xa8\xa8\xf9\x31\xdd\x02\x2a\x17\x3e\xfa\x11\x5d\xf8\xd4\x75\xa5\xb1\x5e\x45\x2b\x88\xbe\x1a\xb0\x57\x1d\x78\x35\x80\x57\x36\x24\x27\x14\x90\xd4\x15\x7a\x24\xfc\x11\x5d\x5c\xc3\x0c\x57\x81\x20\x78\x01\x21\x76\x4a\x05\x6c\x89\x60\x48\x87\x7e\x3d\x3c\x46\x10\x4e\x52\xfe\xf8\x96\x72\xb8\x8b\x31\xfa\x0d\x5d\x4c\xca\x32\x39\xbb\x52\xe5\x57\xc6\xe2\xde\x52\x16\x57\xa9\xbc\xcd\xb7\x6f\xb2\x93\xbd\x95\xc4\x82\x2a\x2b\xd0\x55\x0b\x74\xf3\x02\x3a\x3d\xff\xca\xb8\xe1\x5b\xca\x0d\x2b\xb4\x99\x7c\xbf\x7d\xcb\xf9\x1f\xec\xb7\x2b\x88\xb4\x66\xc2\x68\x32\x18\x4d\x0e\xa3\xa1\x22\xd0\x30\x30\xac\xab\x05\xea\x45\x18\xb6\x18\xf4\x16\x87\xde\x54\x31\x6c\x6a\x18\x36\x2c\x18\xb6\x19\x8c\x36\x87\xd1\x52\x11\x68\x19\x18\x36\xd5\x02\xcd\x22\x0c\x3b\x0c\x7a\x87\x43\x6f\xab\x18\xb6\x35\x0c\x5b\x16\x0c\xbb\x0c\x46\x97\xc3\xe8\xa8\x08\x74\x0c\x0c\xdb\x6a\x81\x76\x11\x86\xeb\x0c\xfa\xfa\xb9\x42\x22\x02\xc3\xae\x86\x61\x47\xc1\xb0\x54\xe2\x8f\x94\x27\x9d\x10\xba\xd6\x12\x69\x27\x16\x5d\x77\x51\x58\x19\xbe\xce\xe4\x7b\x27\x59\x93\xca\x43\x29\x28\x69\x1c\xe8\x6d\x91\x79\x7f\x35\x9b\xf8\x04\x9b\xeb\x0c\x39\xc1\xb1\x38\x33\x95\xbc\x65\x1b\x44\x71\x71\x55\xa4\xd4\x55\x93\x77\xc8\x25\xab\x45\x77\x50\x72\xc1\xd2\xc6\xc8\x9e\x7a\x37\xd2\xeb\xb4\xbd\xfc\x52\xa4\xd7\xe9\x7a\xec\xae\xa4\xd7\x6d\xdc\x9e\x7b\xeb\x7f\xef\x48\x84\x4f\xf7\x55\x4f\xf7\x55\x8f\x76\x5f\xa5\x2d\xf1\xfc\x3e\x47\xbf\xc9\xf9\x7b\xdd\xe1\x3c\x54\x56\xb8\x77\xe2\x68\xfe\x4e\x3d\x9a\xbf\xbb\xeb\xd1\xfc\x9d\x7a\x34\x7f\x57\x74\x34\x5f\xa4\x60\x7e\xba\xa9\x7a\xba\xa9\x7a\xba\xa9\x52\xbe\x3c\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\xe5\xcd\x3e\xdd\x54\xe9\x1f\x9f\x6e\xaa\x1c\x8f\x4f\x37\x55\x4f\x37\x55\x4f\x37\x55\xf0\xf7\x74\x53\x55\x4e\x89\xfb\x74\x53\xf5\x74\x53\xf5\x74\x53\x25\xfd\x3d\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\x3d\xdd\x54\xfd\x4f\xbe\xa9\x7a\xb0\x3b\xaa\xbb\xdd\x4e\x95\xb9\x97\x2a\x71\x23\xf5\x58\x77\x51\x7f\xef\x7c\x28\x4f\x77\x51\xff\xfc\xbb\x28\xf9\xee\xa8\xdf\x5e\xe8\xe8\x24\xdf\x1c\xf5\xdb\xd2\xb5\x11\x3c\x3c\xfe\x9d\x11\xf5\xd2\x14\xb7\x46\xf6\xa0\x02\xdc\x43\xbb\xe8\x5a\x09\xdc\x38\x65\x8f\x62\x29\x66\xba\xa9\xaf\x88\xc2\x0c\xa5\x83\xf8\xda\x84\x73\x22\xd0\x39\x91\xaf\xe9\xf8\x9f\x4d\x9a\x6c\x76\xba\xee\x43\x39\x3b\x74\x87\x8b\xd5\xb8\xef\xf0\x8d\x4d\x8f\xab\xb6\xe8\x71\xff\xf1\x85\x0d\xb3\x41\x21\x43\xc0\xa3\x4a\x84\xe8\x5f\xf2\x38\x39\x54\x87\xac\x12\xd9\xda\xf8\xd8\x9f\x29\x80\xcc\x48\x68\xca\x67\x23\x28\x9a\xed\xec\x4f\x7a\x51\xf9\x1d\xad\xd0\xf1\x59\xe1\x8d\x56\xd1\xbf\xa0\x57\x8e\x58\x0a\x57\xfe\xcc\x8e\x33\xec\x1b\xa6\x86\x40\x9a\x80\x13\xbb\x63\x3c\x79\x4d\x66\x7c\xf1\xf4\xf4\xad\x2a\x7e\x96\x55\x43\x10\xcd\xef\x2c\xcb\xac\x00\x74\x6f\xb5\x1c\xd7\x84\x80\x16\xc4\xc8\xbf\x4e\xa6\xc7\xae\x32\x54\x5a\x16\x4e\xce\xcd\x4e\xd7\xa1\x10\xa9\x3b\x95\x21\xd6\x46\xcb\x2a\x46\xa4\xf5\xa4\x29\x46\xf2\x41\x0b\xb5\x2f\xbf\xe7\xc3\xb9\x30\x03\x3c\x28\x07\xd5\xea\x9f\x65\x3c\xb5\xf9\x10\xab\x29\xa2\xcb\x28\xa2\x2a\xb5\xc8\xb2\x88\x42\xd0\xa0\xd3\x84\x71\x8c\x2a\x95\xef\x0a\x09\x3b\x08\xd7\x4a\xb4\x05\x04\xeb\x26\xd6\x9c\x50\xd5\xf7\x6a\x67\xbf\x90\xba\x15\xb6\xa6\x48\x15\x86\xd7\x79\x9e\xd7\x20\xd2\xf3\x18\x68\xc7\xa7\x8f\x10\x07\xc5\x72\xa3\x95\x93\x7a\x68\x9c\xdd\xc9\x58\x28\x73\xc5\xc4\x32\x05\xbb\x6f\x55\xee\xed\xb7\x1f\x42\xe8\xed\xb7\x97\x96\x78\xcd\x3d\x56\x13\x77\xfb\x6d\x6b\x6c\x0b\xb8\xa1\x09\x71\x70\x87\x1d\x7e\x3b\x89\x67\xca\x2e\xcf\x5e\xc0\x20\x7c\x85\xa8\x78\x01\x69\x4e\x0d\x34\xa7\xe9\xf9\xc9\xc4\x93\x52\x22\xd4\x1c\x6a\xbc\x6c\xca\x60\xf5\x58\x73\x04\x75\x29\xea\x97\xb6\x8a\x09\xa8\x9e\x0a\x42\x8d\x18\x57\x4a\x88\x21\x6d\xf0\x82\xc5\x77\x18\x64\x3c\x0b\x36\x70\x61\xf8\x42\xf0\x22\xbb\xf8\x4f\xb0\x99\xaf\xae\x5a\xf7\xf0\x25\xd8\x3d\x5a\x90\x00\xe9\x1b\x5a\x6d\x64\x88\x1e\x66\xc5\x01\xa4\xe5\x57\x1d\xa3\xf9\xe2\x95\x47\x0a\x15\x9f\x34\xfb\xed\xc7\x3a\x66\xde\x2f\x5d\xdf\xd7\x3c\x5f\x3e\xda\x29\xf0\xeb\x06\x71\x26\xac\x0a\xa7\x38\xb9\xc4\xcf\x9f\x55\x86\x55\xd4\xac\x37\x9a\x68\x70\x83\xfa\xff\xdf\xff\x1b\x24\xe1\x10\x1d\xe0\x34\x0a\x27\x35\xb4\x35\x99\xa0\x24\xbc\x18\x67\x29\x62\xe5\x83\xda\xf3\xe7\xcf\x8e\x71\x10\xa6\x59\x12\x0e\xe6\x00\xdf\x8f\x02\x08\xca\x13\x46\x28\x8d\xe7\xc9\x10\xc3\x9b\x41\x18\xf9\xc9\x0d\x61\x07\xd3\xd4\x63\x51\x1a\x12\xf8\x37\x9e\x67\x68\x0a\x3c\x7d\x08\x9c\xd5\x43\x7e\x82\xd1\x0c\x27\xd3\x30\xcb\x70\x80\x66\x49\x7c\x19\x06\x38\xa0\x41\x27\xc8\x3a\x1d\xc5\x93\x49\x7c\x15\x46\x17\x68\x18\x47\x41\x48\xd7\x30\xa9\x34\xc5\x59\x8f\xad\xf8\x55\xa4\xa2\x95\x82\x62\x98\xe2\x33\x8c\x03\x8c\xa6\xf3\x34\x23\x1b\xb5\x1f\x46\x00\xd4\x1f\xc4\x97\xe4\xd3\xec\x06\xba\x88\xa2\x38\x0b\x87\xd8\xa3\x71\x85\x26\x61\x0a\x9a\x65\xb9\xbd\x28\xd0\x90\x09\xc2\x74\x38\xf1\xc3\x29\x4e\x6a\x2e\x1c\xc2\x48\x1e\x08\x8e\xc3\x2c\x89\x83\xf9\x10\x3f\x38\x1a\x88\x75\x2d\x88\x87\x73\x11\x07\x83\xd4\x58\x8b\x13\x16\x23\x63\xea\x67\x38\x09\xfd\x49\x9a\x0f\x33\xcc\x0d\x54\x93\x50\x27\xf3\x7c\xba\xb7\x7f\x82\x4e\x0e\x77\x4f\x7f\xd9\x3a\xde\x41\xfb\x27\xe8\xe8\xf8\xf0\xe7\xfd\xed\x9d\x6d\xf4\xe6\xdf\xe8\x74\x6f\x07\xf5\x0f\x8f\xfe\x7d\xbc\xff\x76\xef\x14\xed\x1d\xbe\xdf\xde\x39\x3e\x41\x5b\x1f\xb6\x51\xff\xf0\xc3\xe9\xf1\xfe\x9b\x8f\xa7\x87\xc7\x27\xe8\xfb\xad\x13\xb4\x7f\xf2\x3d\x7c\xd8\xfa\xf0\x6f\xb4\xf3\xeb\xd1\xf1\xce\xc9\x09\x3a\x3c\x46\xfb\x07\x47\xef\xf7\x77\xb6\xd1\x2f\x5b\xc7\xc7\x5b\x1f\x4e\xf7\x77\x4e\x3c\xb4\xff\xa1\xff\xfe\xe3\xf6\xfe\x87\xb7\x1e\x7a\xf3\xf1\x14\x7d\x38\x3c\x45\xef\xf7\x0f\xf6\x4f\x77\xb6\xd1\xe9\xa1\x07\x8d\x9a\xd5\xd0\xe1\x2e\x3a\xd8\x39\xee\xef\x6d\x7d\x38\xdd\x7a\xb3\xff\x7e\xff\xf4\xdf\xd0\xde\xee\xfe\xe9\x07\xd2\xd6\xee\xe1\x31\xda\x42\x47\x5b\xc7\xa7\xfb\xfd\x8f\xef\xb7\x8e\xd1\xd1\xc7\xe3\xa3\xc3\x93\x1d\x44\xba\xb5\xbd\x7f\xd2\x7f\xbf\xb5\x7f\xb0\xb3\x5d\x43\xfb\x1f\xd0\x87\x43\xb4\xf3\xf3\xce\x87\x53\x74\xb2\xb7\xf5\xfe\xbd\xb5\x97\x04\x77\xa5\x8f\x6f\x76\xd0\xfb\xfd\xad\x37\xef\x77\x68\x4b\x1f\xfe\x8d\xb6\xf7\x8f\x77\xfa\xa7\xa4\x3b\xf9\xaf\xfe\xfe\xf6\xce\x87\xd3\xad\xf7\x1e\x3a\x39\xda\xe9\xef\x93\x1f\x3b\xbf\xee\x1c\x1c\xbd\xdf\x3a\xfe\xb7\xc7\x60\x9e\xec\xfc\xef\x8f\x3b\x1f\x4e\xf7\xb7\xde\xa3\xed\xad\x83\xad\xb7\x3b\x27\xa8\xb2\x60\x48\x8e\x8e\x0f\xfb\x1f\x8f\x77\x0e\x08\xce\x87\xbb\xe8\xe4\xe3\x9b\x93\xd3\xfd\xd3\x8f\xa7\x3b\xe8\xed\xe1\xe1\x36\x0c\xf4\xc9\xce\xf1\xcf\xfb\xfd\x9d\x93\x57\xe8\xfd\xe1\x09\x8c\xd6\xc7\x93\x1d\x0f\x6d\x6f\x9d\x6e\x41\xc3\x47\xc7\x87\xbb\xfb\xa7\x27\xaf\xc8\xef\x37\x1f\x4f\xf6\x61\xd0\xf6\x3f\x9c\xee\x1c\x1f\x7f\x3c\x3a\xdd\x3f\xfc\x50\x45\x7b\x87\xbf\xec\xfc\xbc\x73\x8c\xfa\x5b\x1f\x4f\x76\xb6\x61\x74\x0f\x3f\x40\x57\x4f\xf7\x76\x0e\x8f\xff\x4d\x80\x92\x31\x80\xc1\xf7\xd0\x2f\x7b\x3b\xa7\x7b\x3b\xc7\x64\x40\x61\xa4\xb6\xc8\x10\x9c\x9c\x1e\xef\xf7\x4f\xe5\x62\x87\xc7\xe8\xf4\xf0\xf8\x54\xea\x23\xfa\xb0\xf3\xf6\xfd\xfe\xdb\x9d\x0f\xfd\x1d\xf2\xf5\x90\x40\xf9\x65\xff\x64\xa7\x8a\xb6\x8e\xf7\x4f\x48\x81\x7d\xda\xec\x2f\x5b\xff\x46\x87\x1f\xa1\xcb\x64\x8e\x3e\x9e\xec\xd0\x9f\x12\xc5\x7a\x30\x93\x68\x7f\x17\x6d\x6d\xff\xbc\x4f\xd0\x66\x85\x8f\x0e\x4f\x4e\xf6\x19\x9d\xc0\x90\xf5\xf7\xd8\x70\xd7\x9e\x3f\xfb\x71\x4d\xd5\x79\x1d\xf8\xd9\xf8\x61\xf5\x5e\xe5\xa2\x4e\xd3\xc0\xc7\xa2\x08\x7d\x2c\x65\x9d\x0d\x17\x76\x7e\x94\xa5\x28\xf3\x07\x5c\x62\x21\x55\x3e\xfd\x31\xb1\x06\xdb\xcc\xe5\xa8\xba\x87\x50\xc3\x43\xa8\xe9\x21\xd4\xf2\x10\x6a\x7b\x08\x75\x3c\x84\xba\x1e\x42\xeb\x1e\x42\x1b\x1e\x42\x2f\x3d\xd4\xa8\x7b\xa8\xd1\xf0\x50\xa3\xe9\xa1\x46\xcb\x43\x8d\xb6\x87\x1a\x1d\xc9\xc2\x72\x9d\xd6\x25\xdf\x08\x3c\x52\x9e\xc0\x68\x74\x28\x5c\x52\x0f\xda\x7a\xc9\xe0\x37\x19\x8c\x06\xb4\x91\xc3\x69\xb1\xb6\xda\x0c\x97\x97\x0c\xc6\x86\x84\xe7\x3a\x83\xd5\x65\xb8\x34\x28\xcc\x86\x1c\x6b\xb9\xc1\xea\x72\x5c\xea\x14\x06\xe0\xc1\xf1\x6c\x51\x58\x04\x7e\x43\xee\xb7\x0c\xa7\xcd\xea\x76\x18\xee\xeb\x0c\x46\x53\xc2\xb3\xc1\x60\x6d\x30\x5c\x58\xbf\x1b\xad\xf3\xea\x2b\x79\x2e\x92\x05\x73\xc1\xf1\x58\x97\xc6\xaa\xc9\x60\x72\x9c\xbb\xea\x78\x40\xdf\x5a\x5a\xdf\xbb\xac\x4e\x2b\x87\x05\x75\x3b\x39\xce\x1c\x06\x1f\x0f\x68\xab\xa1\xf5\x1d\x0a\x75\xa4\x0e\xae\x33\x04\xbb\xf9\xe0\x0a\x20\x4d\x69\xa0\x29\xb2\x39\xa0\x0d\x56\x47\x1a\x2c\x98\x98\x4e\x3e\xb8\x02\x46\x4b\x1a\x68\x8a\xac\x84\x50\x93\x8d\x6c\x5d\x02\xc6\x47\x63\x5d\xcc\x9e\xa0\x50\xc4\x46\x87\x22\xab\xce\x46\xba\x68\x65\x50\x14\xd9\x58\x01\x7a\x72\x4b\x9c\xb6\x5a\xd2\x78\x76\xf3\x6f\x0a\x4d\xaf\x7b\xf0\x09\x86\x8a\xd3\xeb\xcb\x9c\xf6\x38\x4d\x35\x3a\xd2\xb0\xae\xb3\xb2\xca\x7c\x34\x72\x22\x10\x73\xf1\x92\x15\xe4\xc4\xb3\x21\x95\xe1\x88\xaf\xc3\x6f\xf9\x2c\x25\xd6\x72\x3b\xaf\xca\xdb\x17\x6b\x5e\x5e\x13\x1b\x0a\xc8\x1c\x14\x5f\x9f\x9d\x9c\xf6\x45\x3f\x9b\x39\x0a\x62\x9c\x18\xc9\x50\xb8\x48\x9b\x92\x45\x0b\x84\x21\xa6\x0c\x7e\x27\x47\x00\xfa\xb9\x9e\x2f\x44\x68\xb0\xcd\x10\xe9\x6a\x48\xb7\xd4\xc1\x17\x9d\x6e\xe4\x70\xc4\xd8\x89\x05\x0d\xdf\x15\x38\x82\x81\x34\xa4\x41\xea\xe6\xed\x8a\x85\xc7\x16\x70\xa3\x65\x99\x0f\xd1\x01\x0d\x71\x0e\x48\x2c\xb8\xa6\xf4\x6f\x47\xac\x62\x75\x80\x3a\x96\x72\x6d\x75\x66\xc4\x4c\xe6\x9d\x42\x8d\x06\x3a\x57\xb2\x64\x7f\x1a\x93\x15\x62\x99\x0f\x24\x42\x35\xd7\x3d\x54\xbf\xee\x6c\x6d\x34\xd7\x5f\xbe\x7c\x49\x7e\x77\x77\xb6\x5f\xee\xbc\xd9\x6a\x90\xdf\x1b\xbb\x8d\x37\x6f\xfa\xdb\x7d\xf2\x7b\xeb\x65\xa7\xb5\xbb\xdd\xde\x51\xe7\x7b\x9c\x38\x1b\xe8\xd4\xb7\x9a\x1b\x6f\x76\xba\xd0\x40\xbf\xbd\xbd\xdd\x68\xb6\xa1\x81\xed\xf5\x7a\x6b\x67\xb7\x45\x7e\xaf\x6f\x75\xb7\xd7\xbb\x3b\xd0\x30\x47\xe8\xdc\xaa\x0f\x38\xde\x3f\xda\x39\xd8\x6e\x74\xeb\x10\x7e\x7f\x81\x0e\x49\x94\xcd\xb5\x48\xd2\x2b\xba\x2b\xdf\xf5\xae\x88\x2a\x13\x01\x09\x47\x10\xec\xee\x7a\xbb\xd3\x6c\xd5\x61\x04\x77\x76\xfb\xdb\x5b\x6f\x36\xa0\x83\x2f\x37\xde\x6c\x6d\xf7\x77\x77\xc8\xef\x46\xbd\xd5\xec\xb4\xd7\x61\x70\xfa\xad\xed\xe6\x4e\x63\xb7\x7e\xee\x54\x8d\x97\x55\xca\x5b\x15\xbb\xa5\xbd\x94\x1a\x05\x37\x35\x8b\xcd\xf1\x29\x16\xa0\x7b\xcd\xcd\x22\x1d\xd7\x37\x07\x9f\xa4\xd2\xfc\xf2\xe0\x93\x69\xc8\x84\x8a\xee\x54\xa4\x7a\x68\x13\x55\xcc\x02\x88\x1a\x80\x4a\x8d\xe5\x86\x0f\xd2\xcb\xe5\x8c\x4a\x0d\x80\xcc\xae\x54\x03\x68\x5a\x97\x9a\xe0\x0a\x54\x63\x68\x91\xad\xf3\x1e\x12\xf7\x0f\x84\x14\x9d\x57\x8e\xc0\x00\x3e\x8d\x27\xee\x02\x09\x14\x48\x9c\x05\x40\xfc\xfc\xf4\x87\x1b\x02\xc8\x44\x9f\xfe\x70\x43\x80\x6d\xfa\x53\xea\x86\x00\x9b\xc6\xa7\x34\xb1\x47\xb4\x5e\x5b\x23\xab\xec\x33\x39\x34\x5f\xfa\x49\x48\xa4\x63\xcb\x25\xad\x3f\xf1\xd0\x60\xe2\xa1\xe1\xc4\x43\xc1\xc4\x43\x78\x62\x69\xc8\x4f\x3c\x34\x48\x3c\x34\x4c\x3c\x14\x24\x1e\xc2\x89\xde\x98\x4f\x50\xf1\x09\xc2\x7b\xa6\xcb\xc8\x20\x81\xa0\xe3\xf0\xb1\xa1\x7f\x1c\x92\x8f\x43\xfa\xb1\xa9\x7f\x0c\xc8\xc7\x80\x7e\x6c\xe9\x1f\xe1\xc0\x80\xe9\xc7\xb6\xfe\x51\xa4\xa9\xf6\xd5\xbc\xd4\xbc\x4b\xfa\xad\xa0\xd5\x94\x10\xfe\x5d\xd9\x44\x0d\xeb\xda\xce\xc8\xf2\xf1\x27\x68\x25\x5f\x53\x2b\x7f\x4c\xce\xc2\xf3\xf3\xea\x17\x9b\x13\x03\x78\xed\xbc\x6e\x74\xab\x7f\x3e\x7f\xa6\xb2\x46\xd2\x06\x1a\x35\x2a\x83\x89\x37\x9c\x78\xc1\xa4\x8a\x56\xd0\x78\x62\xf7\xbd\xb9\x45\x42\x21\x17\xbe\x6e\x35\xa9\xaa\xcd\x02\xad\xa9\x43\x33\x46\xde\x80\xd6\xde\x70\x42\x6b\xe9\xd0\x8c\xa9\x32\xa0\x75\xdb\x4e\x68\x6d\x1d\x9a\x31\xb7\x12\xb4\x3f\xd7\xd6\x18\xc4\x8d\xba\x13\x62\x47\x87\x68\x10\x04\xb2\x87\x49\x27\x93\x98\x59\xa7\x8b\x7c\x41\x49\x9c\x4d\x2a\x99\x97\x92\x69\xb5\x39\x6d\x00\x0d\x64\x2b\x78\x62\x9f\x72\x58\x11\xc6\x92\x22\x7f\x40\xb7\x81\xed\x0b\x90\x3b\xb4\x4b\xd6\x64\xc3\xea\x06\x04\xeb\x65\x60\xab\x0d\xcb\xcc\xb8\x49\x14\xa8\xfa\x09\x5a\x91\xa8\x35\xb9\x3b\xb5\x76\x2a\x83\xc4\x1b\x26\x5e\x90\xc0\x88\x27\xf7\xa3\xd6\xb6\x0e\xed\xbe\xd4\xaa\x42\xbb\x17\xb5\x36\x75\x68\xf7\xa6\xd6\x86\x0e\xf1\x81\xa9\x35\x81\x5b\xeb\x02\x72\x4d\x1c\xe4\x0a\x1c\x35\xb1\x91\x2b\x30\x62\xdb\x17\x60\xd1\x94\x5c\x13\x27\xb9\xc2\x06\x60\xab\x0d\x5b\x83\x69\xa1\xa1\xb3\xf2\x7d\x39\x1d\x03\xc8\x90\x60\xf5\xab\x49\x98\xe4\x3f\x9b\xa8\xb2\x47\x4d\x73\x87\x84\x33\x07\x96\x9e\xee\x31\x13\xde\x3d\x6a\x7e\x1b\x90\x72\xb6\x11\xd9\x63\x66\xba\x7b\xd4\x90\x16\x93\x72\xbe\xb5\x5c\x8b\x95\x03\x63\x59\xd8\x11\x06\xd6\x72\x6d\x56\x0e\x0c\x93\x07\xa4\xdc\xd0\x5a\x0e\x0c\x98\x95\x61\xd1\xc5\xda\x5d\x96\x5a\xe3\x1e\xe6\x59\x81\x9f\xf9\x42\x18\x22\x0f\x96\x8d\x7f\x71\x1a\x46\x5e\x32\x7a\x13\x66\xe9\x69\x9c\x01\xc7\xa3\x30\xa3\x6d\x3f\xf3\xa9\xd5\xd6\x8f\x68\xc3\x02\x1d\xea\xbc\xc7\xa3\xcc\x48\xda\x08\xe5\x8d\xce\x6c\x05\x81\x99\x85\x18\xb1\x7c\x8b\xd4\x98\x29\x07\x49\xa4\xc9\xce\x39\xfa\xb2\x49\x13\x0b\xe7\x36\x12\xa2\xc4\xbf\x50\xab\xa9\x53\x6b\x0e\xa9\x52\xa9\xe4\x45\x57\x10\xe1\x0f\xff\x3f\x7b\x6f\xbf\x1d\xb7\x8d\x2c\x88\xff\x1d\x3f\x05\x26\xbf\x33\x76\x2b\x6a\x4b\x04\xf8\x05\xda\x56\x7e\xeb\x38\xf6\x55\x36\x76\xec\x63\x6b\xd6\xb9\xc7\xc7\xce\x80\x24\xa8\x66\xdc\xea\xd6\xed\xa6\x2c\x69\x26\x9e\xb3\xaf\xb1\xaf\xb7\x4f\xb2\x07\x05\x90\x04\x49\x00\xa4\x64\x39\x77\x32\x63\xcd\x19\xa7\xbb\x59\xa8\x2a\xd4\x17\x8a\xf8\x28\x08\x94\xc9\x8e\x40\x15\x08\xbf\xc5\x81\x25\x81\xae\x9b\x4a\x71\xb4\xc9\xb3\xf6\xe3\xf4\xe4\x79\x80\x4c\x25\xce\x3d\x64\x63\x89\xb3\xa9\xa3\xfa\x3d\x1d\xed\x7d\x98\xf5\x1d\x3b\xb0\x39\xc6\x70\x6d\xc7\xfe\x3e\xbc\x09\x22\xb8\xdd\x45\x1e\xc8\x32\x6e\x9c\x3a\x95\x6f\x5e\xc3\xdb\x5c\xdc\x66\x09\xde\xad\xe7\xe8\x06\xc3\x39\x44\x07\x48\x4f\xdf\x3f\xed\xfd\x2d\x9c\xf4\xfa\x66\x7e\x23\x3b\x84\x57\xb1\x43\xc3\x61\x12\xe4\x7a\x07\x3b\x6c\x8e\xeb\x1d\x76\x5e\xaf\x0e\xaf\xfc\x5e\x25\x2d\xe4\xb0\xf3\x4e\x75\x68\x7d\x99\x1a\xdf\x0a\xf7\x52\xae\x84\x4b\xe5\xaa\x1b\x2c\x72\x10\x76\x17\x54\xad\x98\xf7\x14\xd4\x09\x53\xd9\x72\xbd\x72\x07\x28\xd8\x4a\x20\xa0\xda\xd9\x05\xf8\x6a\xdf\x06\x21\x1f\xff\x32\x30\x12\xd9\x6e\x68\x6b\x8a\x4d\x78\xda\xd9\x17\x05\x1f\x3f\xca\xd5\x7f\xa4\xef\x88\x2b\xf0\xec\x62\x8e\x2e\xe7\xe8\x6f\xa6\x6b\x3e\x66\xb3\x0b\x38\xd9\x79\x09\xff\xfe\xad\xbd\xad\xfd\xe3\x00\x0f\x71\xe3\x99\x5d\xec\xdc\x9e\x5d\xee\xc8\xe3\xe4\xff\x10\x5f\xfe\xb6\xb3\xb3\x73\xdf\x86\xcd\x1f\xc5\x26\x10\xfd\x43\x60\x6c\x59\xb3\xe0\x0a\xc6\x71\xdd\x06\x0c\xc0\xdb\xe5\xce\xed\xd9\x3f\x80\x39\x3b\xc6\x70\x8a\xcc\x84\xd0\x7e\x6b\x51\x59\x70\x41\x2a\x71\x31\x5f\x19\x31\x5d\x3c\x78\xb0\x02\xae\x2e\xbe\xfd\xf6\xdb\x99\x4f\xee\xae\x74\xa6\xe4\x07\xe7\x6e\x98\x7a\x33\x8c\xbc\x07\x6e\xda\x66\x18\xeb\x6d\x3f\x6a\x7f\x0b\xec\x79\xaa\x3f\x57\x6b\x19\x99\x86\x68\x2c\xf7\xf3\x58\xa0\xaf\x7b\x31\x8f\xf2\x8c\x76\x27\x4b\x3d\x81\x37\xbb\xa3\x58\xbc\x63\xd8\x85\x63\x6f\x75\x5e\x73\x6b\xda\x6e\x33\x9c\x1c\xec\x6d\xb5\xa9\x01\xa6\x6d\x55\xaa\x95\x73\xf8\xec\xe1\xa3\xdf\x41\x35\x8e\xe6\xef\xf9\x25\x34\xdd\xf2\x6c\xc3\x2b\xcb\xdd\x49\x16\x85\xc2\x95\x83\x37\xa8\x50\x79\x91\x61\xa3\x9a\xc3\x13\x96\xb5\xea\xd1\xb7\x58\x19\x34\xd4\x01\x1e\x6a\xe9\x84\x65\x06\x4d\x7d\xf5\x51\xae\x03\x5b\xb6\x46\xd5\x90\xe6\xdb\x89\x3e\xbe\x9d\xc7\xf1\x97\x2d\x4e\xff\x0a\x47\x56\x3e\xf7\xd2\x7d\xaf\xb0\x9a\x46\xd8\x5a\x32\xed\xd5\xe1\xc3\xbb\x78\xc2\x4a\xc6\xf0\xae\xea\x9b\x5c\xbf\x38\x80\xd3\xa7\xed\x12\x46\xb9\x2a\xab\x99\xa1\x00\x55\x77\x49\x83\x17\x59\xce\x52\x9a\x18\x6a\x33\x79\x17\x09\x4d\x59\x9e\x15\xbc\xb3\xc6\x61\x02\xcc\xfc\x9c\x70\x5c\x78\xdd\x67\x9f\xbe\x04\x62\xcb\xd0\xcd\xc9\xf7\x70\x06\x7d\x80\x60\xca\xdc\xb3\x79\xba\x58\x3c\x4a\xcd\x93\xc5\x90\x30\x9a\xa7\x8a\xe1\x75\xd5\x3c\x51\x2c\x1e\xf1\x66\x9a\x78\xc0\xa9\x75\x9e\xd8\x3a\x27\x6c\x79\x5b\x80\x79\x1f\x24\x4f\x98\x5a\x6a\xc1\xbc\x96\x89\x7f\xb7\x04\x46\xf7\xec\x69\xfd\x57\x4f\x28\x99\x11\xd5\xe7\x1c\x5e\xbf\x29\xd1\x5d\xe4\xbf\x45\xef\xd4\x47\xda\x7e\xc4\x81\xf6\x39\xb2\xdd\x1d\xa9\x58\x9a\xad\xe0\x70\xac\x7c\xb7\x84\xd7\x07\x1f\x9b\xcb\xd4\x98\xdf\x84\x60\x6a\x69\xc6\x04\x92\x10\x90\x30\xf9\x26\x13\xc3\x01\x59\x8e\x76\x81\x90\x6d\xa2\x11\x3d\x40\xc4\xb3\x4a\x0d\xa6\xcd\x66\xb3\x14\xdd\x46\x99\xcc\x73\xc5\xc7\x1c\x30\x7b\x17\x21\x93\xab\xb0\x23\x53\x7c\xe8\x01\x0a\xc6\x48\xa4\xe8\x1d\xca\xd0\x3b\x94\x4b\xcc\x11\xcf\x13\x9e\x32\x53\xd1\xa1\x1e\xe6\xe8\x0a\xcc\x4b\xde\xc5\xa7\x4c\xf5\xe2\x2e\xf2\x2e\x62\x8f\x07\x81\x4f\x02\x3b\xad\xfd\x6f\x1a\x72\xd4\xdb\x41\xdf\xec\x4f\xee\x8b\xc0\xef\x87\x49\xee\x73\xd2\x9f\xe5\x41\x16\x95\x0a\x7f\xc9\x4d\xd3\x7d\xe8\x00\x65\xa6\x29\x3e\x04\x24\x1f\x3c\x40\xbe\xa7\x7a\x09\xea\x37\xde\x2d\x8a\x0e\x90\x89\x0f\x36\xed\xb4\xd6\xa4\xc9\x40\x35\x89\x56\x4f\xb6\xb1\xfe\x09\x6f\xd4\x99\x08\x84\x09\xc3\x41\xe5\x13\xd4\x99\x04\x84\xc9\xc2\xcc\x0c\xe3\xeb\x13\x85\xb9\x19\x26\xd0\x27\x09\x79\x1f\xe6\xcb\x04\xdf\x3f\xeb\x04\x9f\xc8\x85\xf7\x8a\xe5\x7a\xbd\xd1\xe7\xdc\xf6\x61\xa0\x56\x7f\x9f\x44\x04\x6a\x21\xb4\x98\x47\xe6\xe9\x06\xd3\x74\x9f\x69\x86\xee\x8a\xf3\x40\xc6\xe9\xba\x3f\xe2\x6c\xd0\x97\x29\x84\xc1\x64\x80\x48\x9f\xaf\x34\x7b\x00\x0d\x5c\x13\x07\xdd\x84\xbc\x3b\x67\x20\x9e\x7d\x99\x2e\xb8\xd1\xe9\x02\xd0\xc7\x84\x99\x02\xb3\x5a\xda\x49\x02\xa5\x1a\xfb\xb1\x29\x01\x60\x9f\x16\xa0\x7f\xe8\x02\x1b\xdb\x05\x23\x61\xf4\xb9\x6b\x63\x28\x2a\xff\x3e\xd3\x07\x83\xe9\x01\xfd\x1d\x9e\x84\x51\xe7\x2d\x5e\x3b\x85\xdd\x9f\x15\x20\x24\x98\x36\x2f\x20\x00\x3b\x38\xe1\xbb\x44\xfe\xbb\xce\x0d\x64\xd8\x0b\x13\x9e\x53\xf1\xca\xef\x47\x71\x96\x87\x5e\x0c\x9f\xbd\xd8\xcb\x73\x0c\x9f\x8b\xd8\xe3\x61\xe2\x9b\xe7\x0c\x8a\x22\xf3\xbc\xd4\x87\xc9\x85\x88\x86\x14\x87\x58\x7e\x0e\x8a\x84\x16\x0c\x10\xa4\xbc\x60\x41\xc1\x82\x2b\x4c\x17\x4c\xca\x3c\xb5\xb0\xaf\x44\xa7\xb5\x74\x9c\xa2\x85\x88\xda\xa4\x33\x77\x0f\x86\xc9\x8b\x65\x61\xe9\xcb\x10\x3d\x32\xe2\x12\x12\x5c\x75\x90\x16\x4d\x46\x86\xe9\x8e\x77\x0c\x06\x6a\x42\xcc\x87\xd8\xbf\x0c\xd5\x9f\x30\x54\x0b\xad\x4c\x1b\xac\x8d\xca\xe9\x0c\xd7\x52\x41\xce\x01\x9b\x90\xfe\x51\x67\xed\x5c\xb3\x1a\x8e\xee\xc5\x89\x18\xc0\x93\x2f\xf3\xfa\xff\x3d\x03\xf3\x1f\xef\x58\xde\x0f\xf2\x12\x87\xf2\x6f\xcd\xa9\x5c\xb4\x59\x9f\xad\x72\x94\x75\xcf\xeb\x69\x3d\x38\xec\x5f\x9d\xf2\x63\x77\x19\xa0\x9e\xa8\xe5\x2d\x0e\xf9\xc4\x94\xc1\x20\x7d\x49\xb9\xdc\xbe\xd8\x94\x27\x7c\xb6\x32\x0e\x63\xdb\xff\xda\x54\x3f\xd5\xef\xf9\xe2\xcb\x6c\xd5\x7f\xcf\x6c\x26\x82\xa5\x3a\xd1\x01\x22\xf7\xeb\xcf\x0f\x0e\x24\x86\xfa\x07\xc7\xdc\xf0\x9f\x66\x2b\xf4\x67\x05\xb6\x63\x9d\x2f\x54\x3e\x5a\xb0\xe5\x96\x8f\xef\x0a\xec\xcf\x8f\xd5\xef\xe3\x9b\xb3\xee\x1b\xae\x41\x2c\xc7\xbc\x7a\xb2\x61\xf0\x99\x2d\xbf\x2b\xab\xad\x41\x40\xcd\x12\xfe\x0a\xdd\x45\xb3\x15\x54\xf6\xdc\x41\xdf\x74\x26\x3f\xfa\x33\x59\x1a\xad\x7a\x96\x5a\xaf\xcc\x0e\xbf\x81\x42\x7a\xf5\x7b\xce\x17\xe5\x92\xa3\x99\x7a\xf6\x00\xa9\x2d\x99\x7d\x29\xb6\xda\xb4\x0a\xba\x41\x41\xad\x52\x3e\x7c\x23\x81\xa0\xec\xe8\x40\x10\x60\x0b\xa7\xeb\xf3\xd9\x6a\x8e\x30\xda\x47\x64\x67\x42\xc5\x76\x04\x37\xa1\x5c\x05\xad\xbf\x63\x2c\x9e\x2d\x51\xec\xee\x8e\x4c\x85\xae\x3a\x10\x75\x86\x34\x6b\x71\x5e\x7f\x8d\x4d\x24\xde\xd3\xb2\xe9\x61\x86\xfe\xd9\x57\xda\x0e\xf7\xb6\xcb\x32\xe3\x33\x6f\xe7\xcb\xaa\xd7\xe4\x55\xaf\xc1\xa3\x02\x1e\x85\xa6\x47\xc7\xf0\x68\xb0\x60\x04\x39\x0b\x3c\x8a\x3f\x79\x19\x2d\x72\xd4\xba\xff\xbd\x97\xd1\x8e\xd9\xc9\x09\xf3\x2e\x9a\xc5\x34\x3c\x10\xca\x10\x1a\x36\x1a\xcf\xea\x96\x0f\x1e\x20\x22\x17\xbd\xea\x5f\xbe\xfd\xf6\x5b\x14\xef\xec\x20\xf4\xce\x8c\xa9\xfb\xd7\xc1\x84\x83\x01\x26\x4c\x77\x76\xa6\x61\xea\xb6\xf3\x8d\xe1\xa5\xd3\x13\xdc\xf6\xdb\xb8\x49\xbe\x0b\xac\x75\x1b\x4b\x66\xb5\x6e\xe3\x8b\xba\xde\xf4\x44\x66\xbb\x98\xfc\x21\xa6\xe4\x8a\xdd\xae\xdb\x99\xef\x24\x40\xad\xe1\x28\x25\xee\xaa\x9e\x43\x91\x5f\xd5\xc3\x5d\xe7\x82\xa9\x6d\xf5\x33\x83\x53\x8d\x33\x8e\x6e\xa3\x02\x36\xbb\xfd\x43\x7c\x3c\xb6\x5d\xe1\x72\xc2\xa0\xc2\x1c\x43\xb7\x51\x0a\xe0\x4c\xae\x0e\xbe\x43\x6a\x9d\xd0\xc4\x3f\x24\x2b\xe5\xb1\x60\xbc\x59\x6a\x55\x8b\x6d\x6a\xad\x55\x6e\xfd\x93\x4f\x70\xa2\x3d\xc1\x7e\xe7\x51\xa7\x91\x79\x6c\x6b\x88\xc1\x3d\x35\x33\x0e\x36\x2e\x2b\x27\x73\x68\x17\x29\x8c\xf2\x09\xd6\x9e\x60\xac\x3f\x8a\xe5\xce\x56\xf9\x88\x84\xe6\x11\x0f\x16\x90\x05\xa5\x05\xda\xad\xc9\xee\x0a\xa1\xee\xca\x8b\xde\xac\x8b\xc7\xd0\x90\xa0\x83\x5a\x30\xbb\x42\xb4\x26\x0a\x22\x70\x1d\x1b\x10\x88\x58\xd7\xaf\xd3\x2e\xfe\x44\x78\x34\xa5\x5f\x50\x3b\x13\x6e\x4b\xc0\xa6\x65\x3e\x34\xb2\x44\xda\xaf\xb6\x8e\x46\x96\x43\x67\x95\x10\x44\x45\x4c\xb4\xfe\x5d\x96\x46\x25\x4c\xa8\x60\xa0\x64\x78\x61\x86\x89\x14\x0c\x94\x04\x3f\x36\xc3\xc4\x0a\x06\x7c\x7e\xf1\x65\x19\xf6\xcb\x32\xec\x97\x65\xd8\x61\xb6\xf9\x65\x19\xf6\x9f\x72\x8e\x37\x8c\xae\x3c\xc7\x1b\x46\xa3\x73\xbc\xfa\x3b\xdb\x70\x8e\x37\x8c\xbe\xcc\xf1\xde\xf8\x1c\x6f\x18\x4d\x9d\xe3\x35\x29\xa7\x3b\xc7\x0b\x0a\x72\x6f\xda\x6e\xd6\xce\xcc\x4b\xb3\xd4\xfb\x43\x2f\xcd\x5e\x44\xc1\xef\x72\x71\x41\x43\xe7\xcb\x2c\x70\x77\x16\xf8\x22\x82\x35\xd5\xbd\x8b\x28\xd0\x7e\xff\x39\x0a\x54\x95\x6e\x80\xd8\xd3\xea\x44\x5f\xa9\xa6\x9b\xd6\xbf\x97\x87\xcf\x7f\x79\xfe\xe4\xc9\xab\xc7\x47\xaf\xfa\xb3\xc5\x2f\x7e\xf8\xe5\x87\x9f\xbe\x7f\xfc\xf3\xe3\xe1\xad\xdc\x2f\x9f\xff\xe5\xa7\xef\x7f\x79\xf4\xfc\xa7\x57\x47\x0f\x7f\x6a\x5a\x6a\xe4\xe4\xb4\xf2\xa3\x69\xd3\xca\x5a\x8b\xcd\x62\x5d\x17\x6d\xe9\xcd\x49\xd7\xa4\xc5\xdb\x35\x9e\xa3\x4b\x5b\xa9\xf2\x4a\x4e\x89\x54\xe8\x01\x22\xc1\x7d\x54\x19\xa6\x44\xb4\x3e\xbf\xb9\x40\xbb\x28\x44\xdf\xa0\x4b\x79\x7a\xb0\xaa\x0f\x69\xc2\x27\xb2\x03\x33\x95\xe8\xcf\x28\x1a\xe4\x22\x90\x06\xf2\xf3\x9f\xd1\x01\xba\x44\x7f\x46\xa1\x29\x4b\xe4\xe7\xff\x29\xb0\x12\xf4\x0d\x12\x74\x7c\x41\x67\xc7\x00\x7c\x21\xa7\xe5\x7e\xee\xfd\x7c\x29\x7f\xfe\x4f\xcb\x54\xb0\x26\xb6\xd3\x12\x95\x70\x9d\x80\x41\x68\x8d\x64\x2e\xa4\x64\x2e\xe4\x01\xcd\x0b\x83\x60\x1a\x50\x29\x5d\x74\x29\x41\x2f\x2d\xd3\x4a\xad\x81\x74\xc5\x78\x09\x17\xfc\x0c\x7b\x2d\xe4\xda\xef\xfa\xc7\xd1\xbe\xf5\x56\x39\xba\xd6\xf0\xf4\xc9\xab\x97\x82\xd7\x0b\x0f\x9b\x8c\x41\xbf\x77\xc2\x32\x3f\x26\xc0\x80\x44\x6d\xac\xcf\xb6\xe7\x3d\xdb\x32\x82\x3d\xad\xc1\x2c\x22\x54\x37\x4f\xfc\x8a\x1e\xa0\xf8\x3e\xfa\xd5\x31\x33\x07\x7d\x80\xa3\xa9\xe6\xaa\x28\x35\xf9\xb4\xac\x5e\xac\xb7\x50\xc7\x55\x58\x15\x5c\x96\xfb\xeb\x0e\xba\x8b\x4c\xbb\xa9\x6b\xe4\x7a\xa3\x07\x48\xd5\x8b\x30\x01\x8b\xbf\x41\x07\xdf\x1d\x20\x20\xa3\x61\xb1\xd0\xea\xee\xa8\xd6\xa9\x7e\x7b\x00\x64\xed\x9b\xab\x07\x94\x9f\x69\x94\x3b\xa8\xee\x1a\xde\x7b\x1a\x06\xa6\x4d\x2d\x69\x86\xb5\xe2\x17\x15\x18\xd0\x88\x5a\xa8\x7d\x27\xfa\xfe\x3e\x7a\xb1\x29\x4f\xca\xaa\xfc\xc0\xd1\xe9\x7a\x79\xb9\x5a\x9f\x94\x6c\x89\xd6\x1f\xf8\x06\xfd\xc7\x93\x19\xd9\xb9\x87\x2e\xde\x51\xb4\x8b\x2e\xde\x45\xf0\x6f\x08\xff\x06\x22\xcc\x98\x51\x2a\x8b\x96\xe4\xe5\xf9\x81\x77\xc8\xbb\x88\x1d\x5b\xe6\x2d\xcc\x29\x0c\x07\x46\xfb\x18\x59\xf4\xea\x05\x78\x39\xc7\xa7\x86\x9f\xba\xc0\x58\x5f\x67\xf3\x81\xfd\xec\x5c\x75\x35\x65\x0b\xfe\x53\xf1\x93\xd3\xf5\x86\x6d\x2e\x3b\x37\xd1\x09\x17\x38\xd2\x07\x22\xeb\x2a\xa5\xf1\xd6\x19\xb3\xf7\x1f\x19\x7b\x36\xc6\x77\x6f\x6d\xc7\x9f\xb6\xb2\xe3\x77\xd6\x75\x7c\xd7\xaa\xce\xcd\x5f\x25\xb0\x3e\xab\x4e\xcf\xaa\xa7\xf0\x6a\xdd\x81\x45\x90\xa4\xe7\x7c\x5b\x6e\x78\xae\x5d\x34\x90\x96\xd5\xb6\x2e\x08\x2d\x1b\x77\xde\x16\xea\xc6\xcf\x57\xcb\x5a\x4d\x5a\x0d\x6e\xb6\xe1\xf7\x10\x21\xc1\x1c\x91\x30\x9a\x23\x9f\x06\x73\x14\x62\xd2\x6f\xac\xee\x2c\xb8\x27\x9e\xe9\x8f\xfa\x97\x16\xd4\x2f\xcd\xd6\x7b\x0b\xf4\xde\xf5\xb0\x5d\xe3\xfe\x02\x98\xa9\x85\x9b\x10\xeb\x77\xef\xfa\xdb\x9b\xb7\x96\x68\x3f\xc1\xd4\xc4\x1f\xe0\x91\x26\xb7\xe2\xe7\x8d\xd9\xc1\x22\xdc\x58\x29\x01\xe0\xa4\xb9\xad\x17\x46\x80\xc8\xf3\xd0\x5d\x24\x06\xda\xe6\xa6\x04\x5d\x12\x22\x7b\xf1\xc9\xe7\x5a\xd1\x33\x4c\xcc\x19\x84\x66\x9c\x3c\xab\x3b\xf1\x94\xad\x60\xee\xa7\xd7\xb5\x7d\x44\x4c\x73\x68\xe9\x76\xbd\x49\xc7\xe5\xdf\x43\xff\x29\x95\x04\x9f\x91\x12\x75\x17\xc5\x84\xac\xad\xd3\xe6\xcf\x08\xdc\x41\xdf\x07\x17\xb1\xde\x55\xcc\xc2\x7a\x05\xb5\x20\xef\xac\x27\x48\x3a\x85\x04\xc9\x75\x2a\x08\x92\x4e\xe9\x40\x72\xfd\x9a\x81\x8a\x61\x3c\xc6\x31\xee\xb2\x8c\xaf\xc5\x33\xee\x32\x8d\xaf\xc2\xb5\x51\x0f\xd2\xb8\x9a\xa9\x91\x72\x55\xad\xa5\x35\x9b\x35\xbd\x64\x30\x99\x57\xbb\xb3\x41\x14\x02\x62\x0f\xee\x9b\x7d\x77\x00\x72\xb1\xc1\x2c\xd7\xe7\x48\xc1\x8c\xaf\x46\xbc\x14\x03\xec\xd6\xe2\x03\x32\x51\x06\x3f\x90\x1f\x65\xd2\x0b\x9f\xed\x2e\x70\xb4\xe0\x15\x1b\x3e\xb9\xc2\x5b\x83\x86\xec\x59\x29\x5e\x41\x96\x67\x27\x2b\xe8\x9c\xc1\xad\x6a\x09\xd6\x69\xf6\x1c\xb5\x99\xb4\x11\xf8\x8a\xef\x24\x3a\x8d\x8e\x96\xda\x37\x14\x0b\x21\xf1\x57\xa7\x9e\x8d\xf6\x5c\xb0\x4f\x35\xd8\xe5\xfa\xdc\x9a\x97\x5a\xa5\x75\x64\xcc\x73\x4c\x3d\x39\x12\x5a\x38\x7a\x73\x61\xe3\xfd\xe8\x42\xda\xda\x01\xf4\xc0\x0e\x04\xc6\x76\x00\xac\x4f\xdb\x7d\x73\x3d\x33\x70\x84\xd5\xb6\x47\x01\x74\x69\x26\xf4\x12\xc0\xeb\xa1\x6b\xb1\xfc\xe8\x02\xb7\xe0\x78\x0a\xb8\xb4\xaf\xa3\x0b\xec\xd2\xa3\x82\x7d\xda\xc0\x82\x1e\x9d\xe6\xbd\x3d\xdb\x80\x47\xc9\xeb\x44\x84\xa9\x8f\x5b\xf9\xd1\x45\xa0\x62\x01\x9a\xcd\x14\x6f\xcd\xd1\x60\x45\x5f\x9d\x0f\xb6\xbd\xde\x00\xb6\xa7\x0d\x36\x19\x35\x24\xb6\xa7\x3d\x6c\xcf\xc6\xb1\xfd\xae\x4e\xd5\x09\x85\x0e\xfb\x44\xfd\x90\x68\x31\x53\x74\xa5\xb7\xbd\x97\x8b\x35\x7a\x51\x3a\x2c\x5b\x90\xac\xef\x7c\xc4\xf7\xb5\xaf\x32\x95\x6b\xbe\x7f\xb2\xc9\x77\x24\xd7\xa0\x75\x99\xb1\x00\x92\x16\x34\x16\x90\x6a\xe8\xa7\x2d\xb4\x3d\x24\xc1\x60\xb1\x58\x3f\x97\x59\xca\x41\x67\x3e\x4c\xe7\xcb\xda\xd9\x97\x6b\x48\xf4\x1c\x21\x5e\xbc\x40\xb7\x24\x46\x27\x1e\x34\x5f\x99\xd5\x9d\x7e\xf0\xa0\x65\x12\x4c\xbb\xee\x1f\x5c\xa5\xe9\x13\x74\x57\x7b\x6e\x33\x74\xd4\x75\x9d\x06\x87\x11\xf9\xb3\x2b\x22\xef\xce\x79\xb4\xdd\x9d\x34\xe3\xd1\xef\xb2\xe2\x4a\x43\x03\xb3\x1d\x43\xe6\xa2\xe0\xda\x3d\x7f\x36\x42\xe3\xe9\x15\x69\xb8\xc6\xb6\x0d\x5b\x6d\x4f\xd7\x5b\xa7\x95\x40\xf8\x7d\x51\x3e\x95\x8e\x71\xf4\x46\x9b\x50\x6c\xed\xd0\x3a\xe6\xc9\x86\x53\x06\x3e\x05\x39\x36\xfa\x59\xe3\xc7\x69\x89\x58\x05\x43\x20\xc4\x4b\x73\x4e\x78\xe4\x41\x1f\x8c\x45\x5b\x9b\x97\x23\xaf\x09\x00\x46\xb8\x23\xaf\xee\x8e\x84\xb6\xb9\xfc\x91\x57\x77\x46\xc1\x59\xc6\xad\xfd\x7d\xf4\x68\xe1\x0a\x7e\xd3\x87\xf5\x6b\x0e\x19\xe3\xa1\x11\x69\xe1\xab\x8e\xc3\xcd\xb8\x32\x62\xdc\x57\x0b\xa9\x75\xab\xa3\xc6\xe0\xa6\x37\xb9\xc0\x4d\xa3\x99\x96\x84\xec\x4c\x19\x00\x25\x02\xd2\x43\x40\x06\x08\x9c\x52\x14\xb9\xc7\x66\x7d\xee\x10\xe2\x52\xf3\x86\xa3\xd6\x35\xde\xa1\xd9\x3f\x14\xfb\xf2\x87\xdb\x35\x33\xf0\xd5\x15\x3f\x96\x9a\xd7\x1c\xb5\x2e\xa4\x63\x84\x1f\x5a\x8c\xcb\xf5\xf9\xa7\x4f\xd0\xfe\xb0\x36\xbd\x91\x0c\xf4\x6d\xf5\xb4\xce\x34\xa4\x18\xdf\x7a\x93\x99\xf0\x7c\xf4\xa5\xad\x83\xc5\xe6\x88\x9d\x7c\xa5\xdb\x42\xb8\xa4\x63\xb1\xe3\x9f\x6b\x5b\x94\x61\x92\xe6\xc6\x77\x45\x0d\xe0\x9b\x19\x1f\xd1\x6e\x38\x0d\xf4\x0d\x4c\x5e\x0d\xe7\x81\xae\xbb\x97\x0a\x5f\x67\x2b\x15\x6c\x92\xca\x78\xb9\xec\xee\x77\xc2\x3b\x68\xbf\xcb\xff\x0e\xfa\xa6\xff\x03\x10\x87\x05\x9a\x66\x37\xd7\x3f\xc9\x26\xa8\x4f\x9e\xc3\xd3\xa7\x19\x6b\xe6\x8d\x73\x90\x68\xdf\xa8\x7a\x1d\xa4\x9e\x05\x1c\xe2\xdc\x37\x6e\xa6\x7b\xf5\x5f\x67\x9c\xff\x8d\x0f\x91\x2e\xd8\x76\x51\x1b\xf7\xa4\xbb\xe8\x07\x5c\x7c\xca\x64\xe1\xf8\x9c\xd0\xf4\x94\xde\x96\xce\x5f\x7d\x0e\xb1\xa5\x67\x9f\x95\xd3\x52\x43\x35\x31\xa7\x27\x9c\x57\x9a\x9b\xd3\x50\xa9\xe9\x39\x1d\xd5\x75\xe7\x15\x5b\x51\xb8\x3b\xf1\x74\xd0\x89\xa7\xd7\xed\xc4\xd3\x41\x27\x9e\x5e\xad\x13\x66\x55\x49\xd3\x55\x4e\x56\xad\xd1\x86\x57\x9b\x92\x7f\xe0\x86\x0d\x88\x48\x1d\xee\x96\xf1\xe0\xf4\x6c\xbb\xa8\xd9\x30\x89\xc8\x00\xf9\x6c\x08\xf9\xe9\xe5\x89\x0d\xa7\x87\x1a\xd2\xf3\xa1\x0b\x5b\xcf\x13\xdd\xd0\xae\x49\x7b\xfc\x52\x5b\x28\x0d\xe1\xac\x39\xec\x34\x21\x42\x4c\x5c\xcc\xa9\x3f\xb6\xfb\x33\x9d\x62\xff\xb2\x5d\xf3\x9a\xdb\x35\xfd\xab\x6e\xd6\xf4\xc7\xb6\x6a\xfa\x8e\x8d\x9a\xfe\x97\x6d\x9a\x37\xbd\x4d\xd3\x9f\xb8\x49\xd3\xa0\x96\xce\x16\x4d\x7f\xca\x06\x4d\xdf\x7e\x0c\xbf\xd9\x78\x78\x8f\x06\x1f\xdf\xce\x29\xfe\x17\xd9\xae\xd9\x2f\xb0\x13\x62\xf2\xbb\xed\xe1\xac\xcb\xed\x08\x9a\x7f\xac\x72\x3b\xd7\xda\x6d\xa9\x1e\xb7\xbb\x3d\x6b\x98\x2b\x15\xe4\x09\x31\xe9\x6c\x0b\x09\x31\xb1\x6e\x33\xa1\x13\x0b\xf2\x08\xc0\xce\x56\x13\xaa\xaa\x5a\x84\x98\xdc\xd8\x11\x62\xbd\xfb\xd6\x9a\x3c\x83\x4d\x0e\xde\x45\x96\xa6\x69\x92\x87\xf9\x5c\x2b\xd8\xb3\x33\x37\x41\x46\x24\x61\x24\x21\x4c\x2f\xe7\xb3\x63\xa8\xdb\x63\x68\x9a\xe0\x30\xf1\x70\xc8\xf4\xea\x3f\x66\x22\x38\x24\x05\xcf\x64\xcd\xa0\xba\x36\xd0\x44\x22\x51\xec\xfb\x24\x8a\x64\x59\x21\x55\x39\xc8\x4c\x84\xf2\x34\x08\x18\x8d\xf5\xba\x42\x13\x89\xe4\xa9\x97\x11\xee\xe5\x7a\x19\x22\x33\x91\x20\x4e\xc3\x80\xe2\x5c\x2f\x52\xd4\x4b\x4d\x6f\xba\x4a\x91\xb0\xa7\x6b\x56\x29\xc2\xd1\x97\x32\x45\x37\x94\x13\xd1\x2b\x97\x29\x12\x4d\xc6\xf2\x22\x3d\x66\x0c\x33\x23\xfa\xa5\x4c\xd1\xcd\xe7\x46\x74\x6a\x99\x22\xa3\x72\xba\xf9\x11\x1d\x2d\x53\xe4\x53\x77\x99\x22\x31\x8c\xdf\xa3\xc4\x94\x2d\x91\x7f\x91\x6c\xe9\x5f\xfa\x70\xcb\xcd\x1e\x6c\xf9\x4c\x47\x56\xae\x9f\x44\xc9\x47\x4d\x77\x15\xa2\x5f\xea\x1d\xbc\x86\xbb\x6e\xba\x9b\x7c\xf7\xd8\xe9\xe9\xf2\x72\xa6\x7e\x9c\x23\xb6\x39\x3e\x3b\xe1\xab\x6a\xdb\xbf\x93\x47\x3f\x3e\xd3\xf2\x03\xa5\x94\x5a\x12\x3d\xf2\xde\x45\x40\x28\x23\x45\x02\x79\x45\x1e\x13\xca\x38\x21\x3b\xf3\x21\x5c\x8c\xfd\x38\x08\x12\x28\x33\x48\x7c\x5e\x44\x61\x96\xeb\xa9\xc1\xa0\x41\x1a\x66\x5e\x91\x66\x05\x5c\x80\x90\x05\xb9\x9f\x92\xc2\x84\x98\x27\x69\x98\xa7\x2c\x84\xdb\xb3\x31\x4d\xf2\x34\xcd\x9c\x88\xfd\x24\x8c\x32\x12\xa6\x90\xce\xf8\x01\x4d\x43\x9f\x9a\x10\x87\x49\x81\x31\x2e\x80\xe3\x34\xf2\xc2\xdc\xc3\x89\x13\x71\x42\xfc\x82\x12\x06\x57\x6e\xb3\x02\x27\x41\x91\xa4\x26\xc4\x2c\xc5\x59\xc8\x73\xe0\x38\x67\x51\x4e\x31\xa6\x4e\xc4\x39\xf5\x62\xc6\xa4\x8c\x99\xef\xf9\x1e\x09\x8c\x32\xc6\x84\xfa\x61\x2a\xef\x8c\x08\xc2\xd8\x8b\x8a\x94\x3b\x11\x93\xc0\xc7\x34\x4c\xe1\xee\x88\x80\xf3\x20\x25\x34\x33\x8a\x22\xf4\xb2\x38\xcf\xe0\x02\xf1\x3c\x2c\x8a\x34\xe0\xc4\x89\x38\x26\x29\x0f\xf3\x18\x44\x51\x90\x38\xa5\x49\x64\x54\x1e\xf5\x72\x9e\x62\x79\x79\x85\x9f\xe2\x28\x89\x52\xec\x96\x71\x9a\x67\x5e\x24\x2b\x54\x92\x30\x8b\x31\xf1\x43\x13\xe2\x0c\x27\x69\x81\x25\x03\x59\x11\x25\x24\x4a\x02\x27\x62\x1e\x24\x69\x94\x64\x20\xbb\x84\x17\x38\x60\xb9\x51\xc6\xbc\x48\x79\x10\x53\xb8\x46\xdc\xa7\x41\x41\x42\xee\x3b\x11\x7b\x45\x86\x93\x3c\x83\x06\x34\xa5\x59\x1e\xa6\x46\x8e\x49\xe0\x65\x0c\x67\x19\x5c\xd2\x1e\xb3\x2c\xc9\xa2\xd0\xad\xbc\x9c\x27\x24\x8b\xc0\x41\xc2\x84\xa4\x1e\x89\x8d\x88\x03\x16\x07\x34\x60\xf0\x8e\x10\x71\x16\xf1\x80\xba\x39\x0e\xb3\xd4\x63\x49\x0e\x9c\xa4\x79\x80\x8b\x34\x0f\x8c\x2e\x1d\x15\x09\xa5\x39\x20\xa6\x3e\xc6\xa1\x9f\xba\x39\x4e\xa8\xcf\x43\x1c\x12\x70\x69\x1e\x45\x79\xc1\xcc\x0e\x42\x7d\x9c\x45\x11\x64\xf8\x24\x4f\x03\x9f\x60\xcf\x1d\x2b\x3c\xcf\x27\x71\x46\xe5\x9d\xef\x45\x4a\xb0\x6f\x34\xb7\xb4\x08\x93\xb8\xc8\x54\x7d\x53\x5e\x78\x9c\xbb\xad\x22\x8b\xb8\xe7\xa5\x05\x18\xbe\x9f\x33\x4a\x8b\xcc\x68\x15\x79\xc8\xe2\x04\x07\x80\x38\xf1\x3d\xc6\x62\xe2\x16\x85\x17\x65\x2c\xf2\x43\x79\xbd\x8b\xe7\xf9\x94\x98\x1d\x04\x07\x24\x21\x89\x7c\xf7\xf2\x98\xc7\x23\x1e\xbb\x45\x41\xe2\x34\xf6\x18\x85\xe0\x12\x44\x39\x21\x45\x61\x74\x69\xc2\xb1\x10\x13\x88\x2c\xcc\x48\x94\x25\x24\x72\x22\x0e\x72\x92\x45\x79\x01\x56\x11\xb2\x2c\x20\x8c\xe7\xc6\x58\xe1\xfb\xd4\xcb\x31\x88\x2c\xc9\x93\x30\xf5\xf3\xc2\x89\x38\x0a\x3d\x16\xfb\x61\x20\x1d\x84\x15\x91\x9f\x73\xb3\xb9\x45\xcc\x63\x29\xc4\x6d\x3f\x8b\xe3\x94\x30\x77\xd8\xa4\x38\x23\x59\x42\x64\x74\x8b\x79\xce\x38\x8f\x4c\x88\x13\x12\x13\x92\x49\x91\xe1\x80\x12\x3f\xf4\x53\x27\x62\x46\xd2\x82\x53\x26\xe3\x6c\x56\x60\xcf\x8f\x8c\x0e\xc2\x28\x66\x51\x14\x00\xc7\x69\x16\x10\xdf\xf3\xdc\xd1\x2d\x23\x41\x4a\xd3\xd8\x83\x38\xeb\x15\x34\x89\x13\x6c\x8c\x6e\x71\x94\x85\x98\x81\x8c\xbd\x28\x0c\x52\xee\xbb\xad\x22\xc7\x09\xe1\x14\x27\x80\x38\xe2\x45\x48\xb0\x71\xcc\xcb\xa3\x24\xf1\x22\x02\xba\x08\xc3\x28\x64\xc9\x88\xe7\x15\x81\xc7\xfd\x50\xca\x2e\x8c\x63\x4c\x3c\xc2\x8c\x76\xec\x45\x8c\x79\xb2\x67\x3e\x49\xd3\x1c\xa7\x6e\xe5\xe1\x84\x05\x19\xc6\x10\x36\x53\x9a\x93\xdc\xcb\x8c\x1c\x63\xee\xc7\x51\xe6\x49\x3b\xc6\x01\x66\x69\xe8\x8e\x6e\x24\x0e\x68\x1c\x07\x60\xc7\x79\x41\x39\x4f\x93\xc4\x84\xd8\x0f\x52\x2f\xcd\x52\xe8\x19\xc7\x49\x1a\xd0\x11\x73\xf3\x13\x9c\x79\x59\x0a\x4a\xc9\xc2\x2c\x09\x59\xe4\x1b\xe3\x31\xcf\x29\x63\x01\x84\x4d\xee\x07\x98\xb2\xcc\x6d\x6e\x61\x9a\x64\x19\x0b\x0a\x39\x32\x44\x3e\xf7\x63\x23\xe2\x88\x12\x1e\x15\x32\x58\xe5\x51\x4a\x52\xca\xdc\xa2\x88\x03\x5a\x50\xc2\xc1\x41\xc2\x9c\x17\x29\x31\xc7\x8a\x98\xb2\x30\xf2\xe5\x48\x13\xf8\x38\x26\x45\xe4\xb6\x0a\x1a\x64\x34\xa6\x58\x66\x42\xb8\xf0\x58\x1a\x1b\xc3\x26\xcd\xb2\xd8\x23\x52\x79\x98\x45\x81\x9f\x70\x77\xee\x96\x78\x29\x2f\x8a\x82\xc9\x2c\x32\xf2\x31\x27\x46\xab\x60\x41\xe8\x45\x19\x07\xcf\xcb\x39\x25\x69\xce\xdd\xb9\x5b\xca\x8b\x84\xf9\x85\x1c\x19\x48\x16\xc5\x09\x36\xe7\x15\x51\x8c\x63\x5a\xc8\x21\xcc\x8f\x49\xe8\x13\xb7\xf2\x32\x46\x62\x9f\x67\x20\x63\xce\x48\x14\xe1\xc4\x28\xe3\x1c\xd3\x28\xa5\x72\x68\x22\xc2\x90\x48\x77\x12\x70\x98\x88\xb0\x9c\xc5\x79\x0e\x0e\x92\xe5\xdc\xe3\x29\x36\x86\xcd\x22\x8c\xf3\xa0\x88\x0b\x35\xe8\xf2\x1c\xc7\x6e\x3b\xf6\xa2\xc2\x8b\x62\x99\x2f\xc4\x04\xc7\x51\x91\x1a\x5d\xda\x63\x91\x1f\xe7\x19\x38\x08\x23\x19\x4d\x28\x73\x8f\x20\x18\xfb\x45\x42\xbd\x40\x4d\xdc\x25\x5e\xce\x8c\x1c\xe3\x34\xc6\x5e\xea\xcb\x78\xec\xe3\x2c\x88\xb1\x5b\xc6\x84\xe6\x69\x1c\x17\xa1\xb4\x0a\x2f\x88\x73\x6a\x8c\xc7\x3e\xc9\x18\x4b\x63\xb0\x8a\xc0\xcb\x62\x12\x24\x6e\x07\xf1\xb3\x84\xa7\xdc\x03\x51\xe0\x30\x4b\x52\x9e\x1a\x95\x17\xf8\x38\x8f\xe2\x0c\x7a\x96\x64\xd8\xf3\xf2\xc0\x6d\xc7\x41\x96\x85\x79\x20\x13\xef\x2c\xf5\x79\x40\x52\xe3\xd0\x24\xd2\x15\x92\x24\x10\xac\x8a\x2c\x0a\x63\x2e\xc2\xab\x2b\x56\x14\x59\x1a\x15\x4c\x0e\x92\x2c\x8f\x0a\xc6\x8d\x1c\x47\x59\x10\xe0\x84\x02\xe2\x80\x05\x71\x48\x71\xac\x26\x51\xdf\x3a\x8e\xad\xb6\xef\x85\xaf\xaf\x7b\x42\xd5\x76\x0d\xda\xeb\xce\x09\xd5\x5f\xae\x77\x42\x35\xc4\x64\xda\xd2\x81\x61\x39\xe2\xe6\xab\x8f\x5e\x77\xe9\x20\x62\x5e\xc2\xeb\x09\x77\x3f\xcd\xb2\xc4\xb3\x2c\x1d\xa4\x69\x14\x33\x2e\x87\x5f\x1a\x64\x8c\xc5\xdd\xd4\xc5\x41\xc4\xcf\x22\x5e\xf8\x31\x44\xb2\x82\x27\x41\x41\x45\x24\x33\x41\xb2\x30\x28\x8a\xd0\x07\x2f\x08\x0b\x9c\xfb\x51\x31\x75\x56\x3f\xc4\x1e\x0f\x89\x0c\x3e\x2c\xe7\x11\x25\xb9\x65\xe9\x20\x49\xbd\x30\xa2\xd2\x20\x49\xea\xf3\x28\xc3\xc5\x44\x22\xb8\xa0\x7e\x9e\x48\x9b\x2f\xd2\x00\xa7\x79\x64\xe9\x49\x98\x72\x2f\xcb\x65\x1a\x84\xfd\x98\x13\x1c\x27\x57\x59\x3a\xb8\xe9\x73\xa4\x53\x4a\xc3\x02\x9c\x67\xaf\xfc\x7a\x88\xed\xa5\x5f\x0f\x89\xbd\xf6\xeb\xa1\x6f\x2f\xfe\x7a\x18\xd8\xab\xbf\x1e\x86\xf6\xf2\xaf\x87\x91\xbd\xfe\xeb\x61\x6c\x29\x00\x2b\x3b\x08\xe5\x61\x8d\xfb\xc0\xe5\xf3\xa5\x7c\x3e\x3c\xec\x21\x65\x00\xcd\x8d\x47\xa0\xe4\xf3\xa5\x7c\x6e\x69\x4e\xa0\x39\xb1\x36\x27\x4b\xf9\xdc\xd2\xdc\x87\xe6\xbe\xb5\xb9\xbf\x94\xcf\x2d\xcd\x03\x68\x1e\x58\x9b\x07\x4b\xf9\xdc\xd2\x3c\x84\xe6\xa1\xb5\x79\xb8\x94\xcf\x2d\xcd\x23\x68\x1e\x59\x9b\x47\x4b\xf9\xdc\xd2\x3c\x86\xe6\xb1\xb5\x79\xbc\x94\xcf\x0d\xdb\xfa\x26\x16\x3d\x96\x96\x61\x42\xce\xa4\x51\xf4\x2b\xee\xc1\x96\x5b\x69\x10\xa6\x56\xa9\xb4\x05\x53\xab\x4c\xda\x81\xa9\x55\x26\x4d\xc0\xd4\x2a\x97\xea\x37\xb5\xca\xa5\xe6\x4d\xad\xb8\xd4\xba\xa9\x15\x97\x0a\x37\xb5\x2a\xa4\xb2\x4d\xad\x0a\xa9\x67\x53\xab\x63\xa9\x63\x53\xab\x63\xa9\x5e\x53\xab\x85\x54\xad\xa9\xd5\x42\x6a\x75\x69\xaa\x3b\xe8\x3a\xba\x3b\xf1\x3a\x54\x6b\x3d\xed\x9a\xfe\xeb\x52\xd6\x1e\xb6\x1d\x37\x7f\x0c\x23\x78\xbd\x7c\x36\x04\x99\x50\x28\x5a\x92\x11\x22\x78\x5d\xd6\xa7\x0d\xf4\xaa\xd1\xe8\x1b\x44\xde\x02\xa4\xb9\x96\x6b\x8b\x63\x29\x71\xa8\xf3\x05\x7d\x1c\x70\x6a\xfe\x5a\x15\xa8\xf7\xf7\xd1\x7f\x40\x35\x62\x3b\xf1\xba\xa4\xf3\x95\x2a\x54\x5f\x2c\x9a\x3a\xc7\x17\x63\x67\xf1\x14\xd8\x52\x6b\xe1\x3e\x8f\x27\xa1\x16\x9d\x2a\xd8\x0b\x59\xfc\x57\x2f\x5e\xbd\x84\x12\xc5\x75\x39\xe0\x0e\x1c\x1d\xc0\xc1\xa6\xd7\x77\xa8\x0b\x16\xbb\x4e\x98\x4a\xc8\x65\x87\x8b\xe5\x90\x8b\x85\x89\x8b\xe5\x90\x8b\x85\xce\x45\x17\x2e\x1e\xc2\x59\x2a\x19\xeb\x2a\xb5\xd4\xcc\xf9\xa0\xd5\xde\xbe\x4a\xf1\xed\x56\xa3\x78\x9a\x46\x71\xab\x51\x3c\x49\xa3\x78\xd1\x29\xf0\xbd\xa8\xab\x70\x6b\x85\xb9\x97\xaa\x56\xb7\x26\x24\xac\x24\xdc\x05\x83\x7d\xcc\x89\xa6\xd2\x1a\x5f\x34\xaa\x52\xbc\xec\xb0\xb1\x34\xb0\xb1\x30\xb1\xb1\x1c\xb0\xb1\xe8\xb0\xd1\x45\x18\x0d\xf0\x91\xc8\xa9\xd3\x2b\xd5\x0e\x77\x85\x92\xb8\x55\x7b\xec\x52\xfb\xeb\x32\x96\x91\xcb\x38\x30\xf7\x20\x97\x0a\xd2\x71\x26\x5c\x42\xe2\x48\x0b\x24\xd6\x5b\xa1\x6b\x58\xc9\x00\x36\x66\x16\x7d\xd8\x65\x0d\x3b\xca\x43\x1b\x69\x96\x42\x68\x65\xdc\x1f\xb9\xba\xe0\x6d\x28\x5b\x48\xf0\x05\xd4\x6c\x13\x78\x84\x26\xbd\x1d\xf4\xa0\xf6\xce\xe6\x97\xff\x1f\x61\x74\x0f\x0d\xb6\x4d\x0f\xf9\x10\xff\xd6\x1a\x1c\x67\x43\xfc\xbb\xdb\x78\x8b\x85\x0b\x7c\x5d\x2e\x40\x8a\x13\x79\x90\xda\x19\x72\x20\x35\x31\xa0\x6f\x46\xda\x8e\x8a\xaf\x4b\x9b\x7a\xdb\x51\xef\x75\x69\x62\xce\x5e\x13\x5f\x15\xc5\x5f\xa0\xdb\xa8\x58\xa8\xb2\xf8\xe2\x8b\xf9\x1c\x9f\x6c\x23\x7d\x9f\x2f\x45\x9b\xa5\x6a\x23\xbe\x1c\x2f\x1d\xc5\xf4\x17\x50\x4d\x5f\xa0\x4e\x25\x1d\xf8\x9c\xc9\xcf\xa9\xfa\x6c\x6f\xbe\x84\xe6\x82\x4a\x2a\x49\xc2\xe7\x4c\x7e\x4e\xd5\x67\x77\x49\xfe\x85\xac\xc9\xaf\x02\x8e\x1c\x57\xd8\x52\x96\x97\xde\x91\xc5\x0f\xd8\xa2\xae\xd8\xaf\x1e\x76\x6a\xf6\x2f\xb4\x5b\x24\x58\x3d\xea\x38\x2b\xf3\xc3\xdb\xd4\xac\x41\xa4\x68\x2e\xba\x34\x97\x1d\x9a\x8b\x2e\xcd\xa5\x4e\x73\x31\x85\x26\x96\xfd\xe4\x6a\x68\x90\xe7\x4d\xb8\x1c\x14\x68\x5d\xf6\x7f\x51\x5f\x5a\xa1\x3d\x0c\xda\x87\x82\xa6\x5f\x3f\x93\x65\xb8\xdd\x34\x65\x3f\x15\x70\x4d\x73\xd1\xa5\xb9\xec\xd0\x5c\x74\x69\x2e\x75\x9a\x8b\x96\xa6\x31\xeb\x1c\xbf\x87\xc0\xcc\xeb\x8f\x50\x7d\xe9\x47\xfb\x61\xaa\x1f\xc1\x79\x7f\x2c\x5d\xc7\xa8\x7e\x84\x60\xf0\x63\x69\x0b\xa1\x1f\xe0\xa2\x04\x01\xb3\x58\x36\x2c\x9a\x9c\x52\x02\x0a\x82\x8b\xb6\x2f\x32\x5c\x54\x58\x0f\x17\x8b\x29\xb1\xaa\x25\x2b\xfe\x15\x12\x71\xd3\xac\x80\x54\xb6\x30\x11\xcc\xae\x45\xf1\x47\x63\xe8\xe9\x53\xfc\xb1\x34\x51\xfc\xb1\xbc\x0e\x45\x73\xb0\xeb\x53\x7c\x6d\xa4\xf8\xda\x44\xd1\x6c\x6d\xfd\xcb\x2b\x2c\x24\x61\xf2\xa2\x76\x7b\x00\xb4\x72\x07\xf3\x20\x75\x54\xda\x95\xe1\x11\x58\x24\x3a\x8b\x35\xae\x69\x6c\xfe\xe5\x34\x67\x15\x47\xe7\xee\x37\x7d\xf1\x07\xef\x9b\x46\xfb\x86\xd7\xcd\x63\x13\xdb\x30\x00\x15\xa6\x36\xf0\x62\x5b\x98\xda\xc0\x3b\x34\x37\xb5\x81\x57\x68\x6e\x6a\x03\xaf\xe4\xb3\x7c\x09\xd7\x77\x2c\x6d\xf7\x77\xc0\x3b\xfd\x2c\x5f\x00\x94\x14\x1d\xd7\x25\x97\x0f\x84\x66\xbd\x09\x44\x60\xca\x4c\x3c\xc2\x94\x42\x66\xe2\x11\x66\x2f\x52\x53\x1b\x98\xbc\x48\x4d\x6d\x60\x9e\x84\x99\xda\xc0\x34\xc9\xe0\x36\x03\xf1\x07\xd3\x2e\x33\x69\xea\x15\xb1\x0a\x03\x26\x6e\x66\x52\x0e\xc2\xb2\x76\xdb\x11\x47\x4a\xa3\x1a\x26\x3b\x37\x7a\x59\x89\x36\x67\x08\x99\xc1\x21\xd8\x3f\x1b\x64\x03\x87\x4d\x31\x8a\xd9\x21\xd8\x3d\x93\xcc\x1e\x7a\x3a\xb7\x6c\xc8\x6c\x1f\x8f\x36\xcb\x28\x09\x82\x88\xd2\x21\x41\xdc\x12\x04\xf1\xa4\x8a\x60\x27\x12\xa4\xe3\x04\xb5\x79\x49\x49\x90\x40\x88\x1d\x12\x24\x2d\x41\xb2\xa8\xc7\xa5\x19\xc0\x6b\xe1\x75\x9c\xa0\x36\x93\x29\x09\xfa\x82\x60\x3e\x24\xe8\xb7\x04\x7d\x41\x2b\x57\x04\xfd\x11\x77\xe8\xe3\xd1\xe6\x3e\x25\xc1\x40\x10\xe4\x43\x82\x41\x4b\x30\x10\xb4\xb8\x22\x18\xe8\x04\xf9\x38\x41\x6d\xb6\x54\x12\x0c\x05\xc1\x62\x48\x30\x6c\x09\x86\x82\x56\xa1\x08\x86\x3a\xc1\x62\x9c\xa0\x36\xbf\x2a\x09\x46\xf0\x52\x31\x24\x18\xb5\x04\x21\x7b\x3f\x56\x04\xa3\xce\x4b\xc4\x38\x41\x6d\x46\x56\x12\x8c\x05\xc1\xc5\x90\x60\xdc\x12\x84\xd7\x26\x35\x26\x0b\x78\x57\x12\xf0\xc9\x67\x2f\xbe\x5c\x8a\x73\x73\x97\xe2\x60\x91\xdc\xab\x9b\xcd\x04\x32\xa8\xc3\xe2\x7b\x37\x7d\x2d\x8e\x99\x0c\xfe\xa7\xbc\x18\xe7\xd1\x7a\xf5\x81\x6f\x64\x95\x5f\x54\xad\x91\x4f\xee\xa6\x65\x25\x12\x94\x1c\x31\xd8\x9f\x9d\xf2\x62\xbd\xe1\x6a\x3b\xf5\x40\x6b\xda\x59\x13\x6d\xed\xae\x5a\xff\xec\x93\x9b\xb8\x88\xe7\x8f\x7a\x05\x8f\xce\x67\x53\x1f\xe4\x1e\xc2\x1e\x09\xf6\x7d\x55\xa7\xf8\xcb\xe9\x26\xeb\x51\xa5\x10\x93\xab\x9e\x6e\x12\x4d\x46\x4e\x37\x75\xb6\x35\x0c\x4e\x37\x85\x98\x7c\x39\xdd\x74\xd3\xa7\x9b\x84\x56\xa6\x9d\x6e\x32\x2a\xa7\x73\xba\x49\x2a\xc8\x79\xba\x49\x9e\xa3\x9d\x78\xfa\xdb\xff\x43\x9f\x67\xe2\xab\xec\x6e\xca\xb6\x3c\x0a\x7a\x0f\x4e\xf2\xb0\x0f\xfa\xe1\xf4\x7d\x5e\xf4\x7e\xcc\xca\xd3\x05\xdf\xfc\x2e\x47\xa2\x34\x56\xe1\xbb\xe0\x50\x3e\x90\x8c\xc1\x67\x9d\x9f\x7f\x85\xa3\x53\xaf\x27\xdd\x09\x04\x9b\x67\x1e\x41\xd7\x1b\x38\xed\xb7\xf1\xa3\x50\xfb\xfb\xe8\x05\xdf\x9c\xc0\x28\xfa\x68\xb1\x2e\x33\x8e\x70\xff\xda\x14\xd1\xfc\xc5\x23\xdc\x3d\xbb\x14\xc6\x73\x14\x24\x73\x14\xe0\x39\xf2\xfd\x39\x22\xe1\x1c\xe1\x78\x8e\x92\x39\x42\x58\xdb\x6a\x14\xd2\x39\x0a\xbd\x39\x0a\xc8\x1c\xf9\xc1\x1c\x91\x68\x8e\x30\x9d\x23\xec\xcd\x11\xd1\xe1\x92\x39\x0a\xf1\x1c\x05\xfe\x1c\xf9\xe1\x1c\x91\x78\x8e\x70\x32\x47\x58\xe0\xd7\xe0\x22\x6f\x8e\x42\x32\x47\x41\x30\x47\x7e\x34\x47\x91\x3f\x47\x61\x38\x47\x41\x3c\x47\x7e\xa2\x01\xfa\x78\x8e\x88\x3f\x47\x38\x9c\xa3\x78\x8e\x50\x44\xe6\x28\x0c\xe6\x28\x80\xab\x05\x74\x40\xc1\x09\x99\x23\x1c\xcc\x51\x24\x00\xf1\x1c\x85\xfe\x1c\x05\xe1\x1c\xf9\xb1\x06\x48\x92\x39\x22\x78\x8e\xb0\x20\x39\x47\x88\xd0\x39\x22\xde\x1c\x61\xc1\x8e\x04\x7b\xeb\x90\x2b\x31\xcb\x95\x74\xe5\x2a\xb8\x10\x72\x14\xfd\x26\xe2\xf3\x1c\xa1\x50\xe7\x56\x11\x16\xdd\x12\xdc\x02\x43\x9e\xce\xa5\xaf\x04\x27\xb8\x12\x00\xd1\x1c\xe9\xdd\xc5\x91\x94\x87\x10\x30\x70\xef\x77\x15\x21\x14\x2a\x04\x2c\xe4\xe7\xc7\x52\xb0\x61\xd8\x93\x57\xe0\x29\x6d\x85\x52\xfb\x81\x4e\x41\xa8\x46\x98\x86\x2f\x54\x1a\x49\xb5\x87\xba\x0e\x85\x0a\x84\x3d\x08\xbb\x10\x3a\x14\x82\xad\xb3\x9a\xce\x8d\x50\x67\x27\x67\x4b\x06\xd7\xa4\x88\xa4\x72\xbb\x28\x8b\xc1\x0d\x4f\xe0\x05\x3f\x1c\xfd\xf2\xea\xf0\x87\x27\xf2\x4e\x29\x21\x31\x32\x47\xd0\x79\x21\x21\x2a\x2c\x52\xa9\x09\xa4\xab\x2c\x15\x2b\x75\x12\x65\xbd\x20\x10\xaa\xd3\x7f\xf5\xdd\xf3\x9f\xf9\x16\xb1\x55\xae\x6a\xa3\x9f\x82\x4a\xe5\x7d\x1a\x06\x3e\x04\xfc\x2f\x2f\xba\xfa\xec\xa5\x94\xde\x85\x77\x0f\x5e\x46\x28\xf1\xbc\x79\xff\x59\xfd\xae\x20\x41\x0c\x00\xa4\x03\x40\x3d\x8f\x0c\x40\x7c\x0d\x64\xf8\x34\xd0\x9f\x1a\x08\x84\x5d\x02\xc4\x40\x20\xea\x32\x69\x02\x89\x7b\xfd\x30\x10\xa2\x1d\x46\x86\x28\x92\x3e\x95\x21\x0a\xa6\x83\x98\x00\xd2\xbe\xb4\x86\x20\x59\x8f\xcc\x00\x20\xef\x77\x65\x08\xc2\x35\x90\x21\x85\xa2\xcb\xe5\xb0\x39\x75\xb5\xc6\x74\x54\x1f\x84\x8e\x10\xf0\xe9\x88\x55\x05\x7d\x22\x06\xbb\xa0\x6e\xbb\x89\xe8\xa8\x61\xc6\xd4\x65\x98\x94\x8e\xea\x3b\xa1\x23\xfa\x66\x7d\x26\x0c\x26\xd1\x27\x33\xe4\x24\xa3\xa3\x1a\xcf\xe9\x88\xd5\x70\xea\xb6\xee\xa2\x4f\xc3\xa0\x79\xab\xba\x54\x94\xc0\x66\x41\x12\xed\xa9\x45\x99\x7e\x07\xc4\x48\x3d\xe8\x62\x31\xf5\x31\xd4\x41\x8c\x36\xa1\xf3\x69\x78\x1e\x77\xd9\x70\xf8\x06\x76\x98\x7f\xd2\xe7\xd4\x1a\x28\xb0\x43\xa3\x69\xb7\x33\x06\xab\xe8\x74\xc6\x1a\x27\xb0\xc3\x7e\x79\x0f\xc4\x16\x2a\xb0\x39\x14\xd0\x51\x51\x60\x3a\x2a\x0a\x42\x47\x55\xef\x53\xb7\xda\x82\x1e\x0a\x5b\xac\x70\x89\x3b\xa2\x2e\x13\x8e\xe9\x88\x32\x28\x1d\x91\x64\x42\x47\x4d\x8b\x51\xb7\x42\xd3\xbe\xbc\x0d\x83\x47\x9f\xca\x10\x24\xa7\x2e\x95\x72\x3a\xe2\x42\x45\x5f\xa3\xfa\x1d\x55\xf3\xb1\x2c\x23\xf0\x3c\x1a\x78\xd8\x1a\x41\x14\x8c\x35\xcd\x68\x14\x68\x8b\x20\x35\x11\xcf\x44\x24\xe8\x12\x31\xc2\x84\x5d\x3c\x46\x66\xa2\x2e\x1e\x23\x4c\xdc\xc2\x18\xa8\xe8\xc1\xd6\xd8\x3c\xe9\x93\x30\x20\x61\xfd\xee\xd8\x13\x0e\x45\xc8\x80\x24\xeb\x08\xd6\x00\x90\xb7\x00\xd6\x00\x22\x59\x30\x34\x2e\xfa\x5a\xb1\xe6\x5d\x4e\x61\x62\x3a\xd2\x0b\x42\x5d\xd2\xf6\xfb\x24\x4c\xb6\x41\x7b\x7a\x37\xd9\x06\x1d\x17\x78\x44\x47\x0c\x35\xa6\xe3\x86\x4a\xe9\x88\x52\x12\xea\x50\x0a\xa3\x6e\x5f\x4a\xfb\x1c\xd8\x03\x89\xd3\x55\x72\x3a\x62\xc4\xbc\x2f\x53\x7b\x3c\xb1\x5a\x90\xfe\x02\x62\x78\x8a\x27\xb8\x3d\x26\x13\x9c\x09\xfb\x13\x1c\x1f\x07\x13\xec\x19\x87\x4e\xd7\xc7\xd1\x98\x4b\xe2\x78\x24\x18\xea\x29\xb8\x19\x43\x32\x16\x2e\x31\x1b\xf3\x7b\x9c\x4e\x88\x96\x38\x1b\x0b\x64\x38\x9f\x10\x2c\x31\x9f\x10\xca\x70\xd1\xd7\x90\xd1\x5c\xc6\x42\x05\xc6\x63\x1e\x8a\xc9\x04\x07\xc1\xfe\x88\x97\xe1\x60\x4a\x60\x0b\x27\x84\x1d\x1c\x39\xa3\x1b\x8e\x27\x84\x25\x4c\x27\xf8\x22\x4e\x26\x78\x3d\x66\x13\xa2\x29\x4e\xc7\x22\x18\xce\x5c\x21\x0c\xe7\x63\x61\x81\x4f\x08\xa3\xb8\xe8\x45\xa8\xab\xa4\x2a\xd8\x0b\x2c\xc1\xc8\xcc\x32\xe9\x48\x05\x5b\x53\x14\x89\xdb\x84\x3d\xd0\x9e\x7b\x86\xe7\x61\x4f\x39\x43\x88\xa8\x23\x34\x13\x8d\xb8\x03\x31\x3e\x1c\xdb\x73\x93\x96\x8a\x2d\x33\xa9\x7b\x6a\xcb\x4a\x5a\x2e\x86\x7c\x66\x3d\x69\x0e\x21\xf2\x8e\xb4\x6c\xa9\x09\x60\xb0\xa4\x25\xaa\xad\x59\x02\xae\xee\x61\x3a\xc6\x3e\xa1\x76\x43\xf1\xe9\x98\xa1\x04\x74\x4c\xd1\x21\x75\x77\x3e\xa2\x6e\x53\x8a\xb5\xe7\xc3\xa7\x94\xda\x45\x97\x50\x97\xe8\x18\x1d\x33\xaf\x94\xba\x9d\x20\xa3\x6e\xd3\xc9\xe9\x98\x61\x70\x3a\xe6\x04\x05\x1d\x33\xf1\x4e\x5a\x61\x31\x02\x3c\xe2\xae\x98\x8c\x58\x28\xf6\x47\x43\x06\x0e\x9c\x96\x8a\xc3\x51\x87\xc7\xd1\x68\xd4\xc0\xb1\x2b\x12\xd3\x51\x4f\xc4\xc9\x68\xc8\xc0\xcc\xe1\x8d\x38\x1d\x09\x17\x38\x1b\x8d\x5a\x58\x0f\x07\x06\x12\x7c\x24\xf6\xe2\x62\x34\x24\xa9\xd4\xc2\xd9\x4d\xec\xf4\x2b\x4c\xc6\x43\x8b\xef\x88\x1c\x38\x18\x71\x6b\x1c\x8e\xc6\x16\x1c\x39\x1d\x18\xc7\xa3\xb1\x0d\xd3\x91\xe0\x83\x93\x51\x0f\xc4\x6c\x24\x0c\xe0\x74\x34\x06\xe2\x6c\x34\x14\xe0\x7c\x34\x1e\x61\xee\x08\x76\xb8\xe8\x46\xa3\xab\xe4\x0f\xd4\x93\x24\xcd\xb1\xa5\xce\x3e\xb1\x17\x58\x52\x89\x9a\x69\xc3\x73\xbf\xc5\x10\x98\x0d\x31\xb0\x1b\x51\xd8\x95\x88\x39\x87\x68\x92\x63\x13\xf9\xd8\xeb\xa4\x7f\xf6\xf1\xb3\x5e\x51\x31\x67\x10\xad\x6e\xcd\xf9\x83\x7c\x6e\xce\x1d\x5a\xf1\xd9\x56\x50\x5a\xf1\x18\x70\xe4\x9a\x97\x5a\x32\x87\xda\xbc\xcd\xb9\x43\xab\x60\x4b\xff\x9d\xfa\xc5\xd4\xde\x3d\x42\xc7\x98\xf7\xe9\x98\x00\x02\xea\x56\x71\x48\xc7\xba\x10\x51\xab\xfd\xc4\x74\xcc\xf8\x28\x75\xc9\x2f\xe9\x12\xb7\x25\x11\x0e\xeb\x48\xa9\x4b\x7b\x19\x1d\xb3\xbe\x9c\xba\xed\x97\x53\xb7\xfb\x15\x74\xcc\x43\xb0\x37\xe2\x22\x18\x8f\x78\x21\x26\xa3\x6e\x88\x7d\xd7\x48\xe1\xb4\x70\x1c\x8e\xba\x08\x8e\xbc\x31\x3d\xe1\x78\x34\x92\x61\x3a\xea\x2d\x38\x19\x0d\x17\x98\x8d\x06\x3c\x9c\x8e\xc4\x4c\x9c\x8d\xc6\x0d\x9c\x8f\x84\x25\xcc\x1d\x71\x09\x17\xce\xb0\x21\xb3\x07\x77\x1f\xf0\xa8\x5f\x62\x62\x77\x4c\xec\x8f\xb8\x3d\x0e\x46\x0c\x1f\x87\xa3\xbe\x83\xa3\xf1\xe8\x16\x3b\xc2\x1b\xa6\xe3\xce\x93\x38\xe3\x07\x66\xa3\xf1\x0f\xa7\xa3\x41\x14\x67\xce\x20\x82\xf3\xd1\x28\x85\xf9\x48\x98\xc2\x45\x37\x8e\x5c\x2d\x79\x30\xc6\x94\x9a\x5f\xdb\x0a\x49\xc3\x8d\x31\x65\xb8\xa7\x6d\xd7\x30\x66\x0c\x0a\x00\xe6\x53\x8c\x79\x43\x93\xf3\x19\x9e\x47\x35\x02\x1b\x40\xdc\x32\x68\x78\xaa\xeb\xdc\x96\x32\xb4\xfc\x59\x72\x86\xb6\x87\x06\x0a\x69\xcb\xa0\x99\x85\xac\x03\x60\x1a\x38\xac\xbe\xc7\x75\xe5\x18\x50\x17\x1d\xe1\x98\xe7\x1c\x5c\xed\x31\x1d\x11\x2e\xa1\x9e\xcd\x70\x7c\xea\x36\x9c\x80\xba\x0c\x27\xa4\x23\x76\x11\xd1\x11\xa9\xc5\x74\xc4\xf4\x28\x1d\x51\x6d\x42\x6d\x72\x67\x74\x44\xa7\x29\x75\x5b\x6d\x46\x47\xac\x26\xa7\x23\x9a\xe3\xd4\x6d\xb8\x05\x75\x99\x3d\xf6\x9c\x6e\x8b\xb1\x67\xd5\x2b\x26\x63\x3e\x8d\xfd\x31\x9f\xc4\xc1\x88\x57\xe3\x70\xcc\x29\x70\x34\x16\x39\x70\x3c\xe2\xdb\xcd\xb8\x67\x55\x23\x4e\xc6\x1c\x08\xb3\x91\xf8\x88\xd3\xb1\x08\x82\x33\x67\x84\xc2\xf9\x58\x84\xc1\xdc\x3e\x38\x17\x23\x11\x02\xf2\x03\xb7\xae\xf0\x88\xa5\x61\x32\xe2\xe9\xd8\x1f\x73\x66\x1c\x8c\x39\x2b\x0e\xc7\x42\x55\x64\x0f\x45\x38\x1e\x0b\x16\x98\xba\xdd\x25\x19\x73\x78\xcc\xac\xc1\x02\xa7\x63\xbe\x8c\xb3\x91\x70\x81\x73\x67\xb0\xc4\x7c\x2c\x94\xe1\xa2\x17\x70\xae\x92\x15\x28\xb6\xa9\x29\x8a\xd4\x38\x4d\x79\x81\x6c\x4b\xcc\x7d\xf6\xdb\xe7\xc4\x84\x3b\x68\x25\x62\xc4\x1f\xea\xfd\x31\x65\x05\xcd\xd3\x21\xee\xb8\x63\xd0\xd6\x51\xd1\x98\x0d\x68\x4c\x0d\x11\xb3\x9a\xac\x91\xe5\x54\x19\xa8\x29\x03\xd0\x64\x35\x7c\x9e\x6b\x68\x87\x4f\x79\xd3\xd7\xe1\xb3\xa2\x23\x65\x53\x4f\x9d\x4a\xc2\xd4\xad\x24\x42\x2d\x3d\xf2\xa9\x4b\x3b\x01\x75\xf5\x27\xa4\x6e\xab\x8b\xa8\xdb\x32\x62\x6a\x97\x07\xa5\x2e\xbb\x48\xa8\xdd\x9e\x19\x75\xab\x3e\xa5\x6e\x1d\x66\xd4\x62\x53\x39\x75\xab\x88\x53\x97\x4d\x15\xd4\x6d\xca\xd8\x1b\xf1\x23\x8c\x47\x8c\x0f\x93\x11\x4f\xc5\xbe\xc3\x00\x71\xe0\xf4\x53\x1c\x8e\xb8\x22\x8e\xbc\x91\x18\x14\x3b\x7d\xae\xc9\x60\x2d\xbc\x27\xd6\xa8\xcd\x6c\xde\x8a\xd3\x91\xd0\x86\x33\x47\x5c\xc4\xf9\x48\x0c\xc1\x7c\xc4\x67\x71\xe1\x0c\x6e\x62\x44\xb7\x30\x8e\x9d\xa6\x84\x89\xd3\x69\xb1\x3f\xe2\x97\x38\x18\x71\x4c\x1c\x3a\x3c\x13\x47\x23\xb1\x06\xc7\xa3\xc1\x6a\xc4\x93\x70\x32\xe2\xa3\x98\x39\x02\x00\x4e\x9d\x51\x0b\x67\xce\xd0\x82\x73\x9b\xff\x63\x3e\xe6\xc2\x45\x37\xf4\x5c\x7d\xe8\x36\xd8\x48\xcd\x6a\xe0\x61\xc3\xd0\xad\x52\x0d\xc3\xa0\xad\x90\x9a\x9a\x05\x4d\x92\x63\x7a\x1a\x5a\xba\x1f\x49\x94\x86\x31\xba\x4d\x99\x86\x4f\xa9\xd6\x01\xd3\x30\xdd\xf4\x7d\xd8\x94\x69\x46\x3e\x7c\x9a\x6a\x9d\x30\xbd\xaa\x6b\x79\x9c\x61\x98\x96\x72\x1b\x62\xe5\xad\xdc\x4c\x2f\xe9\x5a\xe6\x3b\xec\xa9\x4b\x0c\x98\x9a\x85\x4a\xa8\x4b\xbf\x3e\x75\xf5\x31\xa0\x0e\xc3\x09\xa9\x4b\x78\x11\x75\xf5\x24\xa6\x36\xf1\x50\xea\x30\xab\x84\xba\x54\xcd\xa8\x4b\x23\x29\x75\x18\x42\x46\x6d\x66\x9e\x53\x97\x25\x73\x6a\xb6\xd8\x82\x3a\x94\x8c\x3d\xa7\x96\x31\x76\xba\x2b\x71\xfa\x2b\xf6\x9d\xbe\x82\x03\x97\x3b\xe0\xd0\xe9\x4a\x38\x72\x3a\x04\x8e\x5d\x11\x41\x8d\x37\xc6\x47\x89\x33\x5a\x60\xe6\xf2\x18\x9c\x5a\x82\x06\xce\x6c\x41\x36\x77\x7a\x2e\xe6\xce\xa0\x80\x0b\x6b\x44\xc4\x9e\x53\xeb\xd8\xe9\x88\x98\xb8\xbd\xdb\xb7\x58\x1a\x0e\x9c\x8e\x86\x43\x97\x0b\xe3\xc8\xea\x87\x38\x76\x46\x06\x4c\x9d\xde\x8f\x13\xa7\x2f\x62\x66\x09\x56\x38\x75\xba\x1b\xce\x5c\xd1\x01\xe7\x56\x2f\xc6\xdc\x19\x39\x70\xa1\x05\x87\xab\x8c\xa9\x54\x0c\xf0\xc4\x80\xb0\x11\xce\x30\x1e\xdf\x6b\x17\x37\x86\xe1\x58\xb6\x1b\x06\x62\x85\xcf\xf0\x28\x94\xf8\x88\x91\x8f\xa8\x79\x68\x0a\xc2\x8a\x13\xf3\x38\x43\x3d\x33\xff\x49\xd3\x6f\x53\x08\x96\x7c\x9a\x1e\xa5\x0d\x52\x03\x9f\xd9\x3d\x79\xd8\x63\x18\x7e\xcd\x76\xc2\x1b\x21\x1a\xda\x14\x8a\x09\xc3\xa3\x7a\x51\xc9\xda\x73\xf9\x18\xbb\x64\xaa\x60\x88\x4b\xff\x0a\xc6\x77\xe9\x5a\xfd\x1e\xb8\x84\xad\x60\x42\xbb\x58\x15\x44\x34\xda\xe7\xd8\x62\x5a\xea\x31\x75\x49\x54\xc1\x24\x36\x2d\xa9\xe7\xcc\x6e\xa5\x0a\x22\x75\xd9\xa3\x82\xc9\xcc\x2a\x57\x4f\x73\x97\x19\x29\x18\xee\x32\x51\x05\x53\xd8\x3d\xb4\xce\x88\x8d\x8e\x8d\x5d\x3d\xc0\xc4\x22\x64\xec\xdb\x2c\x0e\x07\x2e\x66\x71\xe8\x52\x0b\x8e\x5c\xc2\xc0\xb1\xa3\x8b\xb6\xf8\x9b\xd8\x55\x88\x99\xcb\x52\x71\xea\x8c\x87\x99\xcb\xa3\x70\x6e\xb7\x6f\xcc\x6d\x46\x87\x8b\x71\xef\x6a\x5f\x6e\xac\x10\xd8\x1d\x0b\x30\x19\x37\x38\xec\x8f\x79\x1f\x0e\x9c\xde\x87\xc3\xf1\x20\x50\x2b\xdb\xd9\xdd\x78\x3c\x28\x61\x3a\x1e\xdc\x70\x32\x1e\x0d\x6a\x73\x70\x79\x99\x34\x0a\xeb\xd3\x6c\x2c\xac\x49\xc3\x70\xf0\xc9\xc7\x22\x4e\x6d\x24\x40\x45\x1b\xd9\xe5\x47\xbd\xae\xc1\x33\xb6\x7d\xbf\x45\xd5\x82\x55\x68\xcb\x97\x3c\xab\xa0\x1e\xd1\xab\xef\x9e\xff\x8c\xca\xd5\x69\x7d\x4d\x44\x53\xd1\xe0\xd9\xc3\x57\xbd\x8b\x8b\xdb\x83\x89\x73\xd4\x6e\xfc\x87\x0b\x14\xd5\x17\xf8\xac\xbe\xcc\xf5\x86\x9e\xfa\x55\x02\xc8\x2f\xf5\x67\xf1\x65\xae\xf5\xa7\xcf\xb9\x56\x55\xe9\xfb\xc7\xaf\x64\x61\x2c\x24\x0b\xbf\xb8\xef\xa8\x12\xd0\xcd\x05\x55\xf2\x8b\x56\x25\xe5\xba\x57\x54\xb9\x4b\xeb\xbd\xe7\x97\x4d\x09\xb0\xf7\xfc\xd2\x50\xfa\xee\x3d\xbf\xac\xeb\xea\xbd\xe7\x97\xe6\xb2\x7a\x82\x86\x54\x51\x18\xa1\xb4\xac\xb6\x88\x65\xd9\x7a\x93\x97\xab\x63\x54\xad\xd1\x8b\x47\xd8\x88\xf7\xbb\x12\x4a\x01\xbd\xe9\xd7\x40\x36\xdd\x1d\x12\x46\xf6\xbb\x43\x5a\x74\x2f\xd6\x02\xe1\x8b\x47\xf8\x4d\xf9\x16\xdd\x45\xd8\x50\xa3\x54\xd1\x95\xe5\xf9\x67\x75\xef\xde\xb4\xed\x55\x39\x3e\xf1\x9f\x99\x8f\xd1\x5d\x0d\x35\xd4\xe1\xdb\x41\xb7\x07\x88\x0d\x05\x4b\x1f\x6e\xb7\xfc\x24\x5d\x72\x84\x23\xb4\x3d\x4b\xdf\xf3\x4b\x83\xf8\xb7\x67\xe9\x8f\xfc\x72\xdb\xa8\xa0\xfd\x6e\x17\xca\xea\x15\x00\x49\xd1\xd4\x5f\x1e\x20\x1c\x35\xdf\xec\x57\xac\x3c\x82\x8a\x53\x8a\x1f\xb3\x20\xb7\x35\x76\xc5\xcb\x1b\x85\xf4\xad\x62\xca\x88\xd7\x7d\x75\x4b\x5a\x56\xaf\xa0\x2a\xca\x81\x56\x04\xa5\xc1\x6b\x43\x29\x0d\x2a\xa0\x46\x83\x22\xc3\x36\x26\xab\x21\x81\xdd\x6a\xba\x74\x8a\xcd\xfa\x04\x02\xcc\x92\x17\x15\x22\x14\x3c\x43\x50\x36\x37\x94\xc2\x79\x33\x2b\xd1\xbe\xbc\x1b\xc2\x83\x02\x8e\xb5\x71\xcd\x66\x2f\x1e\x11\x65\x83\x3b\x68\xb7\x91\xc0\x0e\xfa\x33\x22\xf4\x2d\xd4\x78\x04\xdb\x2a\xd1\x9f\xe1\x8e\x8b\xc9\xec\x6d\xca\xe3\xc5\x74\xfe\x02\x28\xdf\xd9\x32\xb9\xd3\xe1\x92\x50\x78\x2c\x79\x45\xbb\x88\x04\x16\x86\x77\x0c\x1c\x0f\xc8\x9a\x2a\xfb\x8b\x0e\x94\xab\x8c\x23\xce\xb2\x85\x32\x3b\x54\x6e\x11\x3b\x3d\x5d\x96\x3c\x17\xba\x64\x2b\xc4\x2f\x4e\xd9\x2a\xe7\x79\x5d\x97\x11\xc2\xfb\xdc\x88\x4d\x88\x40\xa1\xc9\xd8\x0a\xa5\x1c\xa5\x9b\xf5\x7b\xbe\x42\xe5\xaa\x5a\x23\x2a\x8b\x02\x6f\xd1\x36\x63\x4b\x89\x5e\xa2\xdc\x9a\xb1\x9d\x2f\xca\x6c\x81\xd8\x72\xb9\x3e\xdf\x02\x6a\x81\xb7\x5a\x0b\xb4\x67\x5b\x9e\xa3\xf3\xb2\x5a\xac\xcf\x2a\xc9\xe0\xb6\x5c\xaf\x86\x58\x94\xa0\xa1\xbc\xe6\xac\xfd\xf2\xe0\x81\xba\x56\xa6\xfd\x49\x04\x14\x1f\x9b\x24\xd7\xb1\x5c\x2c\x2d\x37\x76\x1b\xae\x42\x0b\x41\xac\xfd\x0c\x31\x6b\x56\x4a\x25\x7e\x83\x84\xf6\x7d\xb3\xaa\x6c\xfd\x88\xf5\x7e\xc4\x6f\x55\x61\xcf\xdf\xf4\x9f\xe0\x52\x80\xc1\x55\x3b\x86\x08\xf8\x48\x16\xbe\x44\xe5\xea\x03\xdf\x6c\xb9\x3d\x0a\x96\xab\x0f\xaf\x7a\x81\xb0\xf3\xd3\xa4\x01\x02\x3b\x06\x88\x16\x9b\x2e\xb1\xed\x1b\x1c\x0a\x83\xee\x63\xff\xd8\x99\x70\x68\xbf\xf0\x55\xb6\xb9\x3c\xad\xae\x70\x15\xa0\xaa\x58\xbb\x7e\xd4\xb4\x6b\x81\xe7\xdd\x90\x6f\x2d\xa1\x9b\xf3\xcf\x41\xb5\x95\x88\xab\x76\xef\x23\x37\xe5\x79\x2d\x48\x53\xd2\xf1\x1f\xbc\xd2\xf3\xb4\x2e\x73\x4b\x40\xaa\x5d\x8d\xd5\xd7\x81\x04\xdb\xf4\xc1\xe0\xe6\x2c\x43\xf6\xf1\xc3\xaa\xac\x4a\xb6\xd4\x4b\x5f\x75\x61\xf8\x45\xb6\x60\xab\x63\xfe\xf4\x65\x5b\x16\x55\x56\x1e\xf3\x2e\xbc\x42\xfe\xaf\x6f\xd2\xe6\x36\xf2\x7e\x6a\x78\x63\x2d\x0a\x6b\x9b\x97\x4f\xf5\x36\x04\xe8\xf8\xea\x6f\x5a\x1b\x2a\x79\xf3\x8a\x42\xfc\x7f\x22\x6f\xd0\x26\x54\x7f\xc6\xca\xb4\xae\xab\xda\x64\xf9\x30\xf0\x28\xf9\x51\x7a\x15\x7c\x1e\xbf\xb6\xcd\x30\x12\x19\xf3\x09\x40\x67\xbb\xf6\xa2\x31\x0c\xdd\x4e\x2c\xb0\x9b\x2e\xec\x46\xc1\x1a\x99\x7c\xc2\xcb\x6d\xc5\x97\x8d\x15\x9b\x31\x16\xd0\xf9\x69\xa9\x05\x75\x07\xe8\x42\x0c\xb4\xb2\xd4\xda\x9b\xf2\xed\x9b\xd9\x4c\x71\xfb\xae\x0d\xd7\x22\x91\x6c\x5e\x5d\xe0\x3b\x94\xd5\x36\x89\xc6\x10\xb0\x7b\x8e\xb4\xb1\x49\xaa\xe7\x49\xcb\x9a\x8d\x62\x3c\x80\xff\x65\x95\xaf\xd1\xf6\x9c\x9d\xca\xf4\x63\xc9\xb6\x95\x34\x86\x61\x08\xaf\xdc\x2a\xeb\x31\xdb\x55\x98\xcb\xf1\x2b\x83\x0d\x43\x45\xf1\xab\xba\xfa\xc0\x35\x6e\xcc\x05\xaf\xe3\xea\xd7\x09\x29\x23\xa1\xcb\xf0\x46\x56\xa1\xf5\x59\x35\x88\xc0\x4d\xc8\x75\xab\xac\x13\x72\xed\x3a\xeb\x0c\x19\xef\xf9\xa5\x2c\x01\x1d\x05\xfb\x3e\xd1\x9f\x94\x1f\x2c\x0f\xb4\xba\xd1\x91\xb1\x6a\xf4\x3e\x7a\x25\x2c\x50\xbd\x04\x6c\xd6\xdb\x6d\x9b\xa6\x43\xcd\x43\x48\x88\xe1\xb5\x54\xb6\x68\x06\xaa\x56\x70\xb3\x7a\xbc\x3a\x61\xdb\xf7\x1d\x97\xad\x6d\x77\x36\xeb\x98\xa8\x70\xc4\x7a\x74\x7d\xd7\xe9\xba\x70\x5a\x81\x45\x13\x41\xc7\x64\xdf\x81\xcd\x7e\x65\x34\x7c\xf1\x4c\x64\x54\x12\xb3\x82\xaa\xfd\x6e\xc0\xf6\xcb\xa7\xd3\xd9\xde\xd8\xd9\x5e\xba\xd9\x5e\x3a\xd8\xde\x4c\x60\xdb\x59\x44\x7a\x5b\x57\x91\x96\xd3\x1f\xd3\xea\x48\x8f\x15\x61\x96\xb8\x2a\x7e\x51\xe9\xa5\x98\xbf\x7f\xfc\x6a\x4f\x25\x68\x9d\x5a\xcc\x73\x94\x15\xc7\x86\xe2\xda\xa7\x4b\x26\x98\xb8\xa8\x50\x1f\x8b\x4a\xb8\x66\x2d\x1d\x13\xa2\xa6\xb2\xf3\x70\xa2\xa6\x5b\x74\xfb\xfb\xc7\xaf\x8c\x15\xb7\x8f\x36\xe5\xe9\x92\xdf\xbd\xda\x14\x91\x6c\xd4\x99\x28\xd2\x7f\xfa\xe3\x4c\x17\xa9\x89\x08\xc1\x76\x09\x15\x4a\xb3\xfe\xf5\x40\x2a\x8b\xe5\x5b\x8c\x0e\x04\xdc\x9e\x94\xea\x63\xa9\xe3\xf5\x66\xd6\xde\xb3\xae\x2e\x8e\xaf\x49\xef\x6d\x97\x65\xc6\x67\xde\x1c\x91\x9d\xc1\x5d\x18\x0d\x5a\x72\x4d\xb4\x64\x8e\x02\x07\x5a\xff\x9a\x68\x83\x39\x8a\x76\xec\x17\x69\x5c\xfb\xdd\x83\x6f\xf1\x9e\xde\x58\x6b\x61\x95\xcc\x9e\xfe\xce\x31\xa1\x81\x3f\x81\xc2\xcd\xbc\xd3\x08\x5a\x57\x64\x8e\x5c\xb5\xfb\x78\x02\x05\xf3\xa8\x87\x13\x72\x63\xc3\xde\x3f\x49\x58\x6d\xa2\xcb\x0d\x04\xd7\x16\xd7\x15\x43\xac\x2d\xc4\x75\x03\x6d\x03\xe5\xac\x9f\xdf\x40\xf5\x4a\xe8\x6b\x85\xd9\xef\x85\x64\xde\xab\xaa\xaf\x15\x77\xbf\x17\x06\xf3\xb6\xaa\xfb\xbd\x30\x9a\xab\x62\xef\xf7\x22\xfc\xf1\xed\x9c\x06\x9f\x54\x70\xff\xf7\xac\xb4\xff\xd9\xea\xe1\xff\xf7\x54\xb6\x87\x9b\x0a\xca\x15\xcf\x6f\xb6\xc4\xfd\x77\x6c\xcb\xdb\xaa\xf5\x6c\xcb\xb5\x67\x3f\xfb\xc4\x59\x01\x7f\xe8\xcb\x17\x51\x80\x56\xec\x84\x6f\x4f\x75\x2f\xdd\xd7\xd9\x10\x20\x82\x0d\xf9\xdf\xbf\x7f\x34\xa1\x79\x88\xa2\xa0\xb9\xc2\xc6\x84\xe6\xe7\x28\x10\x7c\x00\x53\x17\x51\xb0\xa7\xbe\x08\xfe\x0d\x99\x41\x8b\x5a\xa2\x57\xd3\x29\xe5\xdf\xf8\x16\x31\xb4\xe2\xe7\xcb\x4b\x24\x7d\x2d\x37\x11\xd6\x03\x0a\xea\xdc\xe6\xb1\x3a\x3b\x49\xf9\xe6\x23\x82\x5b\xa5\xe0\x56\x15\xf1\xc1\x27\x90\xce\xef\x39\x9b\x2c\xd7\xe7\xd0\x42\xfc\xd7\xd4\xa0\xdb\xb8\x1b\xdd\x86\x00\xb5\x5c\x2e\x5a\xb9\xd4\x11\xa1\x16\x4f\x3d\x30\xcb\xd5\x3f\x8f\x78\x3e\xbc\x95\x05\x5e\xe8\x45\x5e\x77\xbe\xb3\x96\x34\x84\xf8\x55\xd9\xc9\xa8\x44\x0f\xe7\x82\x6b\xf3\x18\xa6\xee\xd7\x32\xdc\xea\x09\x8f\x45\x6f\x0f\x50\xf7\xf6\x6d\xfd\xcd\xbc\xaf\xa9\xef\xca\xea\xbc\xdc\x72\xf4\xd3\xf3\xa3\x2d\x60\x18\x53\x4c\x7d\x51\x8a\x32\x90\x8f\xe8\xa1\xd0\xaf\x90\xcb\x5d\x10\x8c\x1a\x49\x58\x51\xf1\x0d\x5a\xf1\x63\x56\x95\xab\xe3\x1b\x10\x3c\xa0\xe2\x42\xf0\x4a\x05\x7b\xab\x75\x35\xb3\x4a\x75\x7f\x1f\xad\xd6\xa3\x99\x2a\xdc\xc9\x22\x05\xfa\x8f\x46\xba\xf7\x8d\x60\x52\xb0\xff\xa8\x85\x6c\x48\x49\x95\x64\x94\x60\x6a\x6b\x68\xd5\x79\xbf\xc3\x5d\x27\x03\xb0\x69\xe5\xe1\x4f\xdf\x6b\x5a\x81\xe5\x04\x18\xb7\x4f\xd9\x16\x96\x17\x26\xf9\x50\xa3\x29\xc0\x21\x5c\xa2\x51\x56\xb5\x16\x24\x6a\xbc\x37\xac\xfc\x87\x3f\x7d\x7f\x33\xaa\x97\x6b\x3b\xad\xe2\xd9\x2a\x9f\xb1\xd5\xba\x5a\xf0\x8d\x62\xc4\x65\x06\x6c\x95\xeb\x66\x20\x7a\x38\x62\x0a\xad\x9f\xdd\x96\x02\x19\xb3\x8a\xc6\xf3\x14\xfc\xef\x66\x1f\xcf\x5f\x7e\x6e\xf3\x78\xfe\xf2\x33\x59\xc7\xf3\x97\x37\x63\x1c\xeb\x4d\xc7\x36\xd6\x9b\x2b\x98\xc6\x7a\x73\x6d\xcb\xf8\xed\x8a\x96\xf1\xdb\xef\x6c\x19\x3f\x7f\x7e\xd3\xf8\xf9\xb3\xd9\xc6\xcf\x37\x65\x1c\x17\x3d\xeb\xb8\xb8\x92\x79\x5c\x7c\x82\x7d\xbc\xbb\xa2\x7d\xbc\xfb\x9d\xec\x03\x16\xe5\x75\xcb\x58\xc9\x99\x51\xf5\x42\xb8\xe4\x45\x35\x3d\x2b\x5b\x81\x4d\xc8\x6f\x68\x5d\x34\x98\xe0\x0a\x9b\x9b\x32\x06\x40\x76\x33\xe6\x00\xa8\x3a\x06\x01\xbf\x3c\x9d\x91\xd0\x65\x07\x12\x48\x37\x85\x95\xc9\x0e\xc4\x2b\xd0\x0a\x3d\x40\x3e\xb1\xad\x74\x69\x96\x32\x6b\x4d\xe5\xc1\x03\xb4\x82\x25\xf2\xc6\x18\xe4\xd6\x21\x82\xee\xa2\x95\xf1\xb2\x7a\xb3\x09\x09\x3c\x43\x5b\xfb\x88\xea\x97\x27\x37\x43\x3a\x9a\xd9\x0a\xdd\x35\xdc\x18\x3a\x20\xdd\x5f\xea\x12\xe4\xfe\x3b\xad\x17\xa6\xf2\xff\xed\xcc\xf7\xe5\xcc\xfe\x72\x51\x5b\xef\xcb\x1b\xb2\x5e\xa9\xf7\xae\xa5\x6a\xc6\x5b\xdb\xf3\x04\xe3\x1d\x44\x4c\x40\x75\x0d\xfb\xd5\xbc\xa0\xc1\x33\x6e\xc0\x8a\xfc\xef\x6e\xc1\x2f\xd7\x15\xab\xf8\xe7\x0e\xc0\x1b\xa0\x72\x53\x26\x0c\xd8\x6e\xc6\x84\x25\x63\xba\x09\x6f\xd6\xa3\xf1\x57\x80\x8c\xda\xaf\xea\x11\xd8\x81\x8a\xea\xab\x1d\x91\x0e\xb6\xbf\xbc\x9c\x45\xc1\xc0\x2c\x3f\x55\x61\x37\x14\x73\xfe\x58\x1a\x1b\x09\x39\x02\xe2\xea\x0a\x7b\x39\x50\xd8\xd3\xeb\x28\xec\x61\x9e\x7f\xee\xcc\x97\xe5\xf9\x67\xca\x7c\xe5\x95\xdf\x37\xf1\xce\x9c\xf7\xde\x99\xf3\x2b\xbd\x33\xe7\x93\xdf\x99\xfb\x23\xc2\x6e\x93\xc8\xc2\x86\x51\x73\xf2\x9b\xb1\xcd\xe6\x52\x34\xab\xc7\x10\x79\x31\x7c\x67\x58\x69\xaf\x87\x37\xe3\x18\x26\x52\xbb\x6d\xce\x8d\x76\x25\x0d\xc5\xc3\xa7\x46\x74\xf9\xcd\xbc\xba\xf2\x70\xa5\xae\x00\x5f\x17\xfa\xdc\xe6\xd6\x74\xc3\xf1\x66\x7d\xca\x37\xd5\x25\xfa\xbb\xba\x62\x18\x00\xc1\xbc\x1a\x14\x83\x69\x45\x65\x20\xdb\x3d\x13\x9e\x3a\xac\x34\x77\xa2\x77\xa3\xcb\xb6\x3c\x5e\x95\x45\x99\xb1\x55\x85\x52\x78\x5e\xae\x34\xdf\x00\xa2\x8e\xd9\xdf\x76\x5e\xba\x66\xa6\xfe\xe5\x06\xe6\x81\x87\x1c\xd8\xdd\xb1\x23\xae\xd9\xf3\x53\x61\x96\x6c\xb9\xd3\x91\xfd\xa8\xe0\x90\x31\x20\x37\x92\xd3\xd0\x4e\x12\x22\xef\xaa\xf9\x13\x7c\xf5\x5c\x17\x75\xbf\x17\x9d\x35\xdf\xae\xcf\x7e\x22\xb2\x37\x83\xf6\xe2\xef\xaa\xd3\xda\xf3\xab\x62\xc1\x14\x27\x98\xe1\x14\xce\xd4\x64\x38\xc7\x1c\x17\x3b\x03\x24\x6f\xff\x8d\xba\x3a\x47\xd8\x9b\xbc\x3c\x00\x46\x37\x6f\xcc\x76\x10\x96\xcf\xd5\xe6\x09\x08\x8b\xf5\x17\xf9\xdf\xdf\x7e\x33\x1c\xc0\x10\x79\x7f\xe3\x03\x7f\x3a\x40\xc3\x55\x30\xfd\x4f\x8e\xcd\x35\xf8\x41\xc3\x46\x7f\x2f\xa0\x35\x69\xef\x23\x90\x3e\xb4\xe4\xab\xe3\x6a\x81\xbe\x41\x74\xe2\x56\xea\x7e\xa0\x79\xb4\x5e\x7d\xe0\x9b\xfa\xd5\x50\x0b\xc3\x2a\x3e\x88\x41\xbb\x3e\x1d\x30\x29\xf0\xd4\xa3\x76\xa3\xdd\xce\xca\xdc\x47\x74\xd4\x0d\xa2\x77\xb6\x28\x67\x15\x43\x6c\x7b\x45\x3a\x93\x67\xb2\xba\x2b\x85\x17\x5a\x80\xde\xab\xd6\x3f\xfb\xc4\xbe\x14\x02\x8f\x3f\x61\xcf\x8e\xa2\xd5\x35\x2a\xc3\xce\x9d\x1a\xee\xa9\x54\x66\xc3\x64\xad\x5e\xd3\x2e\x1e\xa9\x36\x03\x2e\xd9\xdd\xc9\x9b\xf7\xbb\xb4\xdd\x27\xbd\xda\x25\xbc\xba\xd5\x9b\xc1\x16\x7e\xf1\x57\xf3\xb0\x77\x7a\xb6\x5d\xcc\xea\x44\x4a\xe4\x08\xa6\xf7\x4a\x33\x74\x2f\x97\x40\x86\x7d\xb2\x75\x2a\xa2\x29\xb8\x8e\x20\x35\xce\x79\xd7\x6d\xac\x1b\x49\x06\x5e\x01\x68\x84\x49\x66\xeb\x53\x18\x24\x2d\x63\x3f\x1a\x4d\x5b\x1b\xb3\xe7\x28\x5b\xae\x57\xae\x37\x95\xa9\x26\x0d\x78\xfa\xb6\x0c\x3f\xda\x6d\x19\x1e\x3b\x6d\x59\xc7\x0c\x59\x8a\x64\xb7\xd9\xf9\x6a\xda\xe9\xfa\x08\xe0\xbf\x06\xc3\xfe\x5a\x4a\x66\x88\xb4\x8e\xa5\x12\xdf\x30\xcc\xd6\xbb\xc6\xec\x04\xe0\x0c\x53\xbd\xb0\x2e\x93\x13\x0b\x99\xc6\x85\xce\x3b\xfe\x33\xea\x06\xe7\x53\x7c\xe0\x5c\x99\x7c\x8d\xfe\x4d\xf9\xd6\x24\x76\xbb\xa9\x02\x70\x67\x7d\xb9\x49\x8f\xad\xfb\x66\x7a\xbb\x65\xd4\xd6\x98\x8f\x6f\xe7\x34\x9c\xb2\xdf\x65\xff\x9b\x3f\xa1\x45\x55\x9d\x6e\xef\xed\xef\x9f\x54\x8b\xed\x5e\xca\xf7\xcf\xaa\x82\xfe\xba\x45\x1f\xc8\x1e\xde\x23\x28\xbd\x44\xff\xe3\x84\x55\x8b\x92\x6d\x85\xc5\xb4\x1b\x64\x60\x57\x88\xdc\xec\xb1\xbf\x8f\xbe\xe7\x95\x3c\x0e\xc7\xb9\x10\x77\xc9\xd2\x25\xdf\xa2\xbf\x2a\x4a\x7f\xbd\xf5\x15\x6c\xe3\xdf\x70\xfe\xb8\xd9\xff\x32\xd8\x49\x83\xee\x48\xe5\xdd\x41\xb7\x6f\xd7\x3f\xdf\xb7\xa3\x47\x7f\x95\xdd\xd1\x90\x3f\x83\x1f\x5a\xdc\x27\xea\x7b\x17\xb5\xfa\xf5\xf6\x6d\xc3\xfe\x9c\x83\x0e\x93\x0d\xb0\x93\x8d\x63\xd8\x39\xf3\xd7\xb9\xdc\x8d\xff\xd3\x3a\xe7\x7b\xbf\x6e\xd1\x7a\x83\xbe\x93\x5b\x69\xca\xa2\xe4\x39\xca\xd6\x39\x9f\x03\x16\xb6\xca\xd1\xd9\x96\xa3\xb2\x12\xe3\xda\x5f\x85\x1c\xb5\x3e\xa8\x7d\x38\x4d\x1f\x8e\xd5\xf7\x6e\x1f\xe4\xaf\xf7\xe5\x9e\xa4\xb6\xd9\x5e\x03\x7d\xa0\x23\xfb\xed\x37\xed\xdb\xde\x79\xb9\xca\xc5\xdb\x65\x07\x46\x6e\x1d\x12\xbc\x20\xfd\x67\xd8\xec\x73\xeb\xab\xfd\x6f\xee\xde\xd8\xdf\x37\xfb\xb7\x64\x6f\xb7\xd5\xa6\x5c\x1d\x3f\xd9\xac\x4f\x1e\x2d\xd8\xe6\xd1\x3a\x17\x9a\x7b\x05\x3f\xee\x15\xda\xaf\x4a\xf8\x47\xec\x3d\x5f\x49\x19\xf7\x4d\xf6\xf4\x6c\x75\x29\xe4\x7b\xeb\xab\x26\x82\x9d\x65\x5b\x92\x73\xf1\xe3\x4c\xd2\x91\x1d\x84\xa5\x4d\xd8\x7c\x5f\x0f\x81\xf0\x53\xb6\x3e\x5b\x55\x7c\xa3\x66\x2e\xe1\xa7\x65\x1d\x2b\x64\xf3\x36\x58\xc0\x53\x38\xcf\x58\x7f\xe1\x17\xd5\x86\x89\x2f\xe7\x8b\x72\xc9\xd1\xac\xc6\xf6\x40\x21\x91\xa4\xbf\x82\x36\x2d\xc2\x4c\x75\xef\x61\x55\x37\xd8\xdd\x15\xae\xfe\x15\xe8\x54\x02\x7f\x7b\x80\xbc\x8b\xef\xa9\xe7\x09\x9d\xcb\x9f\x1e\xc0\x4f\xdf\x3d\x79\x22\x7e\xb2\x50\x12\xe2\x82\xd7\xf5\xed\xd9\x66\xb3\x3e\x66\x15\x9f\x83\xd5\x55\x0b\xbe\xe1\x70\xce\x13\xad\xf8\x45\x85\x04\x0b\x2c\xab\xf8\x06\x1a\x41\x37\xa6\xf0\x07\x0c\xce\x24\xf8\x6d\xe4\x5d\x3c\x79\xe4\x79\x3b\xc2\x42\xbd\x8b\xef\xe1\xe3\xdf\x45\x70\x5e\xae\xcf\x5b\xfa\xd0\xec\x2b\x29\x79\x39\x94\xcf\x54\x17\x05\x02\xff\xc9\x93\x1d\x38\x9a\xe9\xed\xa0\x5d\xa4\x61\x86\x07\xbb\x75\xc5\x21\x45\xbd\xcd\x82\x55\x57\xcf\x56\x27\xac\xca\x16\x3c\x6f\xe9\xdd\x47\xeb\xd5\xf2\x12\xb1\xd3\x53\x0e\xfd\x2e\xb7\xe0\x80\xe8\x6c\x55\x56\x73\xf1\xa2\x99\xb1\x2d\x87\xb7\x4d\x21\x88\x06\x53\x03\x23\x84\x54\xd5\xfb\xa2\x1a\xac\x62\xa8\x67\xda\xd7\x53\x56\x6e\x86\x3d\x83\x7e\x29\x5e\xbf\x52\xa2\xbb\x7b\x57\xf1\x7e\xab\xdf\x01\x4b\x4b\x01\x28\xfe\xaf\xe2\xbd\x84\xaa\xbd\xf1\x3a\xce\xc0\x57\xe0\x0c\x30\x0a\xb7\xbe\xd0\x58\xb9\xcc\x5b\xba\x46\x5e\xae\x72\x7e\x81\x0e\xd0\x5d\x6c\x34\xfb\xc6\x8f\xee\xdc\xd1\x8c\x7f\x77\x57\x36\xb3\x18\x3f\xd0\x79\x03\x20\x6f\xfb\xc6\x2e\x4c\xe9\x89\xd0\xb8\x94\x8c\xfc\xf5\xee\x41\xad\xfe\xfb\x9a\xbc\xd0\xee\x81\x21\x7e\xd4\x88\xbe\xfd\x16\x61\xaf\x36\x20\xf4\x9b\xf2\x21\xa5\x92\x9a\x13\x69\xac\xe8\x37\xd4\xb1\xc3\x46\xf8\x13\x08\x01\x42\x9b\x92\x1a\xe1\x67\x0b\x9e\xbd\x7f\x95\xb1\x25\xdb\xfc\x2f\xd1\x6a\x26\xf4\xf0\x62\x5d\xae\xe4\x6e\x6a\x10\x40\xf3\x53\xd7\xe3\xdb\x9f\xa5\xd7\xb7\xc2\xa9\x16\x9b\xf5\x39\x7a\xbc\xd9\xac\x37\x33\xe8\xd5\x9d\xa7\x22\x15\x6a\x4d\xf3\x2f\xbb\x77\xd0\x6e\x8b\x60\xaf\x5a\xcb\xc8\x3a\xc3\xd1\xce\x5e\xb5\xfe\xcb\xe9\x29\xdf\x3c\x62\x5b\x3e\xdb\x41\xbb\x12\x81\x30\xf9\xd5\xba\x12\x06\x0e\xcc\x4a\xb9\xdc\x11\x0f\xeb\x8e\x7e\xfc\x0c\x23\x41\x2b\x27\xc8\xaa\x45\x26\xde\x8a\x63\x2e\x97\xd9\xd4\xe0\x24\xa5\x6c\xd0\xc6\x4c\x17\xe0\xb7\x75\x1b\xa9\x51\x98\xaa\xbc\xa0\xde\x4e\x5f\x2f\xd2\x21\x1e\xd5\x0d\x4d\x6a\xd1\xd0\xde\x56\xc6\xf9\xe4\x09\x55\xb1\x4e\x85\x39\x7c\x37\xbd\xac\x38\xda\xf2\xff\x3a\xe3\xab\x0c\x02\x9d\x9d\xd1\x96\x46\x6d\x3a\x30\x10\x5e\x9e\xa4\xeb\x65\xe3\x48\x36\xca\xd4\xeb\x52\x26\x43\xca\x0d\xa6\x71\x21\x45\x52\x40\x58\x09\xe8\x91\xd7\xb0\xd4\x6c\x3c\x36\x30\x01\x61\x58\x67\xc2\x1f\x32\xe1\x30\xf8\xfb\x57\x64\x12\x13\xc9\xa5\xa7\xb8\x7c\xec\x75\x50\xec\x1e\x58\xac\x26\x9a\xd0\x99\xc7\xde\xa0\x33\xc1\x27\x49\x14\x53\xc5\x6c\x2c\x99\x7d\x32\x91\x59\x4c\xae\xda\xa9\x16\xd2\xc4\x55\xb7\xa3\x5d\x0f\x68\x6c\x13\x30\xf4\x5d\x42\xa4\xfe\x6a\x9c\xe8\x27\x4d\x0d\x52\x91\xba\x0f\x93\xab\x41\xd6\xd4\xc2\x8f\x0e\x2a\x0d\x68\xfd\x83\x50\x82\x8c\x56\x13\x07\x97\xb6\xc7\x3a\x61\x7d\x94\xd1\x50\xee\x1e\x38\x5c\xbf\x17\xd1\xdb\x66\x9f\x2b\x11\x6e\x64\xbf\xe1\x2c\x7f\xb4\x5e\x55\xe5\xea\x0c\x0e\xcf\x82\xf6\xdb\x50\x24\x38\xf9\x01\xfa\xfe\xed\x01\xb0\xf5\x48\x24\x16\x86\xd1\xe0\xce\x0f\xab\x0f\x6c\x59\xe6\x00\x24\xa5\x7d\x47\x75\xab\x91\x77\x97\x0a\x92\x08\x61\xa2\xe0\x4d\x43\xe7\xad\x72\x13\xd1\xb4\xf9\x71\x77\x57\x24\xe3\x75\x84\xea\xa1\xb9\x2d\xc3\x88\x4c\x04\x45\x94\xfc\xbb\x16\x0c\x8d\xd0\xfe\x93\x86\xb1\xfd\x7d\xf4\x43\x81\xce\x39\x12\xf9\xda\xd9\x29\x12\x99\xea\x1c\x95\xd5\xff\xfd\xdf\xff\xa7\x1e\x96\x74\x14\xc0\xf1\x2d\x4b\xcf\x07\x80\x77\x06\xc1\x5f\x5a\xef\x2b\xf0\x82\x59\x6b\xe5\x02\x18\xeb\x66\x48\xf4\x2f\xbe\xfe\x25\x30\x98\xef\x50\x57\x9f\xa0\xaa\x2e\xa6\x83\xa1\xd6\x95\x64\x0b\xb6\x84\xc3\x0f\x8d\x1c\x5f\x72\x96\xa3\xa2\xdc\x6c\xab\x5a\x4a\xd0\xad\xab\xab\x79\x38\xba\xa1\xd9\x6a\x3d\x14\xef\x76\xa7\xb6\x09\x49\xe8\xb6\xd2\xbf\x8a\xac\x1a\xaf\x8d\x7c\x6b\x5e\x87\x63\x58\x0f\xcf\xe3\xda\xa0\x1e\xd5\xa8\x40\x2d\xe8\xc0\xe2\x30\xf7\xfb\xf1\x40\x47\x86\xe5\x6b\x06\xd4\xdc\x69\xb4\x6b\x4a\xc0\x1a\xeb\x6d\xcd\x57\x8b\x51\xdd\x04\xfe\x0a\x26\x58\xa7\xf5\xb2\xef\x7e\x5f\xb6\x27\xec\x12\x95\xab\x6c\x79\x06\x2f\x21\xe2\xe5\x42\x7f\xa5\x31\x49\xf9\x49\x2d\x9d\xc7\x57\x90\x0e\x98\xf2\xf5\x04\xe8\xa9\xf7\x34\x02\x7b\x93\x24\x2d\x5d\xa0\xbe\x4d\xa0\x1e\x24\x2f\x52\x60\x63\xf9\xc1\xe7\x94\xf9\x70\x84\xef\x4b\x94\x2a\x89\x3e\xb9\x59\x89\x42\xc8\xb8\xa6\xd0\x63\x10\xba\x77\xd1\x17\xbb\x77\xe1\x3d\xda\x41\xbf\x81\x44\x66\x92\x07\xf9\x6b\xa3\x8f\xc0\xaa\x0f\x78\xa3\x32\xbc\x63\x60\x4f\x7f\x05\x33\x6b\xa2\x96\xa7\x51\x0b\x7f\x39\x7a\x72\x97\xa2\x1c\x66\xca\x78\xde\x44\xde\x3a\x6c\xaa\x13\x58\xcd\x77\x08\x68\xda\x77\x88\x3f\xf7\x7b\x39\x89\xca\x35\xda\xd1\x58\xf2\xd7\xe0\xeb\xa6\x24\x1a\x58\x1d\xd5\x80\x8a\x1e\x00\xb5\xa4\x44\x8b\xb1\xed\xec\x4f\x27\xdd\x69\xe7\x89\xaa\x93\x53\x2d\x1b\x99\x55\x27\xa7\xe8\xa0\x37\x96\xec\xa0\x3f\x1d\x1c\xc8\xa0\xdc\xcf\x4e\xd4\x22\x46\x75\x72\xda\xcf\x33\xb4\x17\xf4\x16\x7a\xe7\x73\x4e\xbe\x09\xb1\xa2\x03\x60\xf0\xce\x07\xbe\xd9\x96\xeb\xd5\x9d\x7b\xe8\x0e\x4c\xfa\xde\x99\x8b\x5f\x25\x3f\x77\xee\x69\x59\x21\xfc\x2e\xbb\xab\x7e\x97\x5f\x6e\x7d\xf5\x51\x4d\xd2\xbd\x5a\x9f\x70\xf4\xf0\xd9\xf7\x28\x3d\x2b\x97\x39\x5a\x9f\x56\xe5\x49\xf9\x37\xbe\xd9\xce\xd1\xb2\x7c\xcf\xd1\x66\xef\xd7\xed\x5c\xbe\x12\xc3\x4c\xfb\xf6\x94\x67\x65\x51\x66\xc2\x79\xf3\x12\x14\x7e\xca\xaa\x8a\x6f\x56\x5b\xc0\x07\x8d\xaa\x05\x47\xc5\x7a\xb9\x5c\x9f\x97\xab\xe3\x7b\x72\xce\x53\x98\x5f\xef\x5c\x24\xba\x53\x1b\xcd\x1d\x39\xb9\xdb\x01\xd8\x63\x27\x79\x6f\x16\xb5\x39\x22\x29\x9e\xdd\xfa\x4a\xaa\x4b\x1d\x9a\x6c\xa6\xb9\xbb\x03\x98\xe8\x33\xe8\x0e\x94\xd3\xbe\x5d\xf4\x66\x8d\xff\xa4\x7d\xdf\x5b\xad\x73\x7e\x74\x79\xca\xdb\x64\xae\x9d\xab\x56\x2f\x1e\xe5\x4a\x9f\x37\x7e\x59\xae\x8e\xd7\xff\xf3\x15\xfa\xe0\xed\xd1\x3d\x0f\x5e\xcf\xdb\x16\xda\x59\xd2\x86\x19\x15\x1a\x6b\x4c\x6c\x73\xbe\x60\xcb\x1e\xa6\x78\xcf\xbb\x2b\x27\x62\x36\xf5\xde\x28\x79\x8a\x51\xfd\xb6\x60\xdb\xe7\xe7\xab\x17\xf5\x16\x98\x03\x05\xb4\xd7\xfd\x1d\xc0\x9b\x25\x12\xa8\x1a\x27\x85\x52\x47\x8c\x2e\xb8\x5c\x1f\x12\xcf\xe1\x20\xf1\x8e\x90\x8d\x2e\xab\x37\xef\x65\x01\x43\x01\x01\x9f\x3b\x93\x5f\xbd\x7e\xbd\x5c\x94\xab\xb5\xe8\x15\x43\xe7\x3c\x45\xea\xa0\xaa\x9a\xb5\xde\x53\x06\xad\x64\xf2\xf1\x96\x3a\xa2\x0a\xcb\x26\x1f\xe7\x7f\xff\xf8\x76\x4e\xa3\x29\x4b\x22\x83\x13\xbb\x3f\x3f\x7b\x7a\x58\x55\xa7\x2f\xc5\x90\xb1\xad\x5a\x6c\xf1\xb4\x05\x16\x74\xce\x56\x42\xaf\xdf\xec\xdf\xaa\xd7\xa2\x9e\xf1\x6a\xb1\xce\x61\x30\x90\x47\x7f\xef\xec\xed\xed\x9f\xc0\x8f\x22\x5e\xd6\x60\xc5\x7a\x73\x02\xbe\xb0\xed\x81\xb6\x0f\x04\x38\xc0\x37\xe1\xf2\x35\x5b\xcd\xce\x79\xea\x0b\x7d\xd4\x0b\x49\xaa\x6a\x88\xec\xc0\x33\xb6\x62\xc7\x30\xd5\x2d\xc0\xfa\x3f\x2b\x74\x35\x0b\x5b\xbe\x2c\xd4\x6a\x9c\xf6\x44\xb2\xba\x9d\xed\xec\x15\xeb\xcd\x63\x96\x2d\x5a\x6f\x91\x8f\x74\xe2\x6d\x83\x3d\x56\x55\x2c\x5b\x1c\xad\x9f\x83\x65\xcd\x04\x72\xd5\xdd\x1e\xe0\x96\x57\x2f\x3b\x6c\x01\x6c\x9f\x57\xad\xed\xc7\x1d\x8d\x3d\xb5\x8d\xab\xe4\x46\x0e\x4f\xfb\xcc\x9d\x4e\xe0\xeb\xf4\x7a\x2c\xa9\xc5\xbe\x5a\x9a\x4a\x6e\xe8\xa0\xb7\xd0\xaa\x4b\xfc\x98\x57\xaf\xd9\xea\x61\x9e\x6f\xf8\x56\x40\xae\xf8\xb9\xb2\x97\x59\x97\xed\x15\x3b\xe1\xf7\xd0\x9d\x0e\xfc\x9d\x79\x07\x44\x38\xdf\x3d\x74\xe7\x9c\xad\x7e\x71\x81\xc1\xce\xad\xed\x3d\x84\xbb\x3f\x43\x91\xb8\x27\xb5\xa9\xdd\x43\x6f\x5a\xb3\xdb\x83\x67\x0a\x5b\x03\xf2\xd6\xac\x0e\xd9\xab\x15\xdf\xb0\x8a\x3f\x5f\xf1\xa3\xf2\x84\x5f\xa5\x7b\xa6\x86\xae\x7e\x4e\x80\x9f\xda\xe1\xd5\xd9\x72\xe9\xec\x54\xf5\xfc\xe8\xe1\xb3\xf2\xe2\x15\xaf\xa6\x69\xaa\x01\x77\x2a\xca\x06\x55\xb3\x4d\x26\xb0\x3d\x47\x23\xcc\x67\xb2\x08\xe4\xf3\xa3\x87\x2f\x5e\xa8\x72\x8e\x63\x1d\xe8\x37\xb1\x77\x62\x04\x72\x6a\x47\xc6\x0c\x6e\xb4\x93\x52\x98\xdf\xb1\x25\x13\xef\x57\x53\x55\xa4\xe0\xc7\x74\x64\x06\xbb\x9a\x92\xfa\x3d\xfc\x9e\x17\xec\x6c\x29\x2b\x9c\xfc\x04\x3b\x25\x5b\xef\xea\x22\x94\x6b\x0d\x1a\x46\x0d\x95\x7c\xf6\x5d\x79\xdc\x43\xe1\x14\xd4\xab\xb3\x53\x31\x68\xbd\x66\xab\x47\xeb\x72\xd5\xf6\xaf\x6b\x19\xc8\x2a\x38\x6b\x7b\xa7\x1c\xa7\xb6\xaa\xc5\xea\xcd\xa7\x74\xe2\x55\xc5\x4e\x4e\xaf\xdd\x85\x7e\xeb\x29\x1d\x18\x69\x33\xc6\xbe\xca\x2e\xdf\x74\x09\xf5\xbc\xa8\x8b\xb2\x13\xd1\xfb\x8f\x4c\x41\x70\xd0\xbc\x89\x33\xa6\x27\xaa\x23\x83\x47\x56\x85\xd9\x20\xfb\x92\x69\xe1\xde\xaa\x01\xb2\xcd\x78\xda\x91\xdb\x3a\x46\xd6\x92\x6a\x1b\x0f\x12\xb6\xd7\x6c\xa5\x36\xc4\xe8\xa9\xd2\xd7\xf7\x7c\x6f\xfe\x75\x93\x68\x7d\x7d\xcf\x8f\x3e\xbe\x9d\x7f\x9d\x96\xc7\x72\x4b\xf2\xde\xaf\xdb\xaf\xa7\x64\x74\x77\xce\xb6\x1c\xa6\xdd\xb3\xea\xce\xfd\x5b\x43\xea\x8d\xdb\xdd\x17\x69\xeb\xaf\xdb\x85\x78\xb5\x2e\x8f\x57\xeb\x0d\xbf\xb7\x2c\x57\xfc\x56\x9d\x40\x7e\x2d\xf2\xaf\x49\x24\x85\x68\x5e\xf3\xd4\xef\xa4\x81\xfb\xcb\x32\xdd\x17\x28\x20\x07\xdc\xdf\x47\xf9\x7a\x55\xa1\xf5\x07\xbe\xd9\x94\x39\xaf\xb7\x8d\xd4\xbb\x54\x6e\x69\x85\x64\xd4\xfe\x0f\xf1\x9a\x7a\xa7\xd9\x96\x0a\xbb\x4a\x3a\x00\x7b\x92\x64\x17\xaa\x56\x45\x07\x04\x98\xbb\x7f\xeb\xa3\x41\x1a\xf2\x89\xda\x9e\x54\x73\xfc\xf5\x3d\x42\x3e\xbe\x15\x52\x98\xbf\x91\x52\x78\xbb\x73\x6b\x7f\xff\xff\x43\xdb\xf5\xd9\x26\xe3\xcf\xd8\xe9\x69\xb9\x3a\xfe\xcb\xcb\xa7\x07\xe2\xe1\xdd\x25\x1c\x05\xfa\x75\xbb\x77\xc2\x4e\x6f\xfd\xbf\x00\x00\x00\xff\xff\x3f\x3c\xdd\x2f\xd5\x2c\x06\x00")
+var _web3Js = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\xbd\x69\x77\x1b\x47\xce\x28\xfc\xf9\xea\x57\xb4\x75\x9f\x1b\x92\x31\x4d\xad\x76\x1c\x2a\x8e\x47\x5e\x92\x68\xc6\xdb\xf1\x32\x99\x79\x34\x1a\x9f\x16\xd9\x94\x3a\xa6\xba\xf9\xb2\x9b\x96\x15\x5b\xff\xfd\x2d\x00\xb5\xa0\x96\x5e\x28\xc9\x4e\x66\x2c\x9f\x93\x88\x5d\x85\xda\x50\x28\x14\x0a\x85\x02\xe6\xc9\xff\xb7\x48\xe7\xc9\xbd\xee\x64\x91\x8d\xca\x34\xcf\xa2\xa4\x5b\xf6\xb3\xfe\xbc\xf7\x51\xa7\x14\xdd\xbc\xbf\xe8\x7d\x4c\x27\xdd\x1b\xd9\x7e\x7e\x40\xbf\x4a\xfc\xf5\x3e\x9e\x47\xf1\xbd\xf2\x6c\x96\xe4\x93\x68\x2e\xeb\xba\xb7\xaa\x8a\xae\x7e\xf3\x8d\x4c\xdc\x81\x32\x8b\x6f\xbe\x89\x7b\xf3\xa4\x5c\xcc\xb3\x28\x16\x95\xde\x58\xef\x41\x7a\xaa\xd2\x52\x99\x06\xb5\x4e\xee\x65\xc9\x69\xf4\x78\x3e\xcf\xe7\xdd\xd5\x87\x71\x96\xe5\x65\x34\x49\xb3\x71\x74\x92\x8f\x17\xd3\x24\xea\xac\xde\xcc\x6f\xae\x76\x56\x7b\x3b\xe5\xf1\x3c\x3f\x8d\x26\x83\x51\x3e\x4e\xee\xad\x3e\x7d\xfe\xe8\xcd\x93\xc7\x6f\x9f\x3d\x7f\xfd\xf6\xa7\xe7\x6f\x9e\x3d\x5a\xed\x4f\xce\xa1\xbe\xe9\x3d\xe8\xfb\xbd\x8f\xc9\x87\x59\x3e\x2f\x8b\xe1\xc7\xf3\xf3\x1d\x18\xc3\xfe\xfa\xc1\x60\x14\x4f\xa7\xdd\xe9\x40\x66\xf5\x55\xef\xbb\x09\x0d\x30\xbb\x87\x80\x1b\x07\xfb\xc9\xc1\x8e\xec\x6a\xd1\xcd\xee\x67\xc3\xa4\x77\xde\x9f\xf6\x4d\xc9\xa4\x4f\xb8\x3b\x97\x50\xd0\xa4\xca\xc4\x5e\xa4\xad\x70\x35\x11\x63\x06\xe8\xfc\xde\xfa\x4e\xfe\xc3\x7c\x30\x4d\xb2\xa3\xf2\x78\x27\xbf\x79\xb3\x57\x74\xe7\x80\x78\xdd\x8d\xf3\x5e\xf7\xe3\xc6\x70\x5f\x77\x59\x56\xd1\x27\x2c\xf5\x65\xdb\xbd\x8f\x2b\x94\xa0\x3a\x73\x6f\x7f\x25\x8a\x3e\x8a\xff\xa2\x68\x75\x94\x67\x45\x19\x67\xe5\xea\x30\x2a\xe7\x8b\xa4\x4f\xa9\x69\x36\x5b\x94\x85\x48\xdb\xc7\x6f\x05\x8d\x79\x59\x7c\x92\x88\x9c\xd5\xb7\xf9\x69\x96\xcc\x57\xfb\x26\x07\x46\x07\x39\xf1\x78\x3c\x4f\x8a\x62\x55\xe6\x9c\xe3\xdf\x03\x59\xb5\x2a\x8e\x7f\x65\x5a\xbe\x28\x9b\xdb\xcb\xdf\xb2\x22\x56\x7b\x87\x67\x65\x52\x6c\x6d\x86\xdb\x53\x40\x1a\xd3\x22\xf9\xbc\x7f\x25\x08\xb8\x50\x7f\xf4\x70\x18\xf6\xda\x21\x60\x69\x54\xff\x59\x87\x2e\xda\x2d\x13\xd1\xec\x65\x07\xff\x1f\x39\xef\x30\x63\x7f\x9a\x69\x9f\xc4\xd3\xe2\xcb\x0d\x5d\x74\x39\x99\xbf\x0f\xad\xfa\x3f\xfb\xa4\x15\x8b\xc3\x97\xc9\x51\x5a\x94\xf3\xf8\x2b\x98\xbc\x7e\x5d\x1d\xc9\xe9\xf3\x4b\xf1\x7d\x81\xc2\xac\x98\x04\x59\xdf\x7f\x0a\x0e\xe6\x0e\x29\x2c\x8f\x84\x22\x29\x5f\xd5\x93\xd4\x95\xe1\xc2\x6d\xfa\x8b\x34\xfa\x99\x27\x20\x6e\x83\xf8\xba\x0a\x66\xf3\xf4\x24\x9e\x9f\x05\xfb\x91\xe7\xd3\xc6\xc9\xdb\x95\x6d\xfd\xe7\xa2\xd0\xde\x83\x2f\xc0\x11\x93\xf2\x61\xe5\x36\xfe\xa7\xdf\x89\xc6\x69\x21\xe4\xaf\x4b\xf4\x3c\xce\xf2\xec\xec\x24\x5f\x14\x4b\x74\x5d\x9c\x5f\x92\x0f\xc9\xd8\xda\xbb\xae\x6c\x62\x4d\xe5\xac\x3b\x76\xed\xa7\x69\x76\x19\xc6\xbd\xbb\x40\x4c\x3c\x16\x0d\x8d\x57\x1d\x34\x25\xef\x81\x10\xfe\x0b\x70\x74\x98\x8e\xc7\xed\x70\x74\xb1\xfa\xdf\xc7\xd3\x45\xb0\xfb\x8b\x34\x2b\x37\x6f\xdf\xa9\x9f\x82\x67\xc9\xe9\x83\xf4\x0f\x44\xfe\xa5\xd6\xdc\xc3\xe3\x38\x3b\x4a\xfe\x03\x7a\xdf\xbf\x40\xdd\x4c\xaa\x5f\x7e\x65\xbd\xa0\xdd\xa8\x11\x41\x2b\x07\x2b\x2b\xe7\xfd\x8f\xe7\x07\xfd\xcd\x3f\xec\xd0\xff\x5f\x74\xe6\xfd\x83\x64\x47\xd1\xb3\xf1\x85\x49\xe5\xd2\x1b\xd7\xf5\xb1\xf7\x3f\xfb\xd8\x7b\x7d\xe8\xfb\x33\x9f\x39\xea\xce\x0b\x7f\x36\x69\xf3\xf3\x6e\xe6\x66\xaf\xda\xba\xb2\xbd\x6a\xd9\x79\x9f\xcc\xf3\x93\x4b\x4e\x7b\x99\x5f\xf2\xa8\x79\x39\x81\xef\x8f\x5d\x37\x7f\x06\xfc\x09\x32\x16\x04\x33\x2a\xf7\xc6\x97\xec\xc9\xe5\x26\x22\x1d\xc5\xb3\xd7\x7f\xe8\x64\x84\x31\xd9\xee\xb4\x9b\xcc\xf2\x22\xad\x3b\xa8\xcf\xe2\xb3\xf8\x70\x9a\xd8\x42\xc1\x1f\xc2\x95\xaa\x68\xee\x4a\x8e\x5f\x97\xa3\x81\x5d\x35\xde\x47\x36\x3e\xbf\xfc\x49\xe6\x4a\x90\x54\x51\x77\x3b\x3a\xfb\x03\xd0\xff\xa7\xc5\xfa\x55\x9c\x1f\x2f\xcc\x27\x3f\x37\xd6\x5d\xa6\x77\x8d\xf6\x96\x68\xbf\xf4\xc6\xf5\xb9\x67\x76\x2f\xb0\xa5\xd5\xc9\x71\xdb\x6d\xe4\x38\x34\xde\x88\xee\x29\x0b\x87\x6e\x67\xb0\x36\xc9\xe7\x27\x71\x59\x26\xf3\xa2\xd3\xdb\x41\x80\x57\xf9\x34\x1d\xa7\xe5\xd9\x6b\xd1\x9a\x0d\x0b\xed\x03\xd4\xca\xda\xb7\xdf\xae\x44\xdf\x5a\x90\x52\xe7\x1e\xa5\x45\x14\x47\xb3\x79\x9e\x03\x70\x54\x1e\xc7\xa5\xa8\x61\x06\x87\xac\xac\x14\x59\x12\x0a\x32\xa1\x86\xbd\x32\x12\xad\x8f\x8e\x93\x62\x08\x9f\x32\x9b\xfd\xdc\x3f\xe0\x1f\xdb\xd6\xd7\x81\x9d\xb9\xe5\x7c\x1f\xec\xdf\x11\x29\xfd\x68\x30\x18\x88\xe4\x35\x6f\x6c\xaa\xc7\xf7\x22\x6d\x4d\xd3\xed\xc9\x29\x2e\x8f\xd3\x62\xf0\x16\x17\xc6\x4f\x0a\x41\x00\x38\x20\x74\xed\x41\xc6\x5e\x56\xee\x30\x60\xda\xb7\x43\xd0\xcf\x31\x47\x36\xb7\xb3\x72\x2e\x10\x18\xe8\xc7\x40\x20\xad\x24\xac\xdd\x8b\xc0\xbe\x86\xc3\x74\x3f\x9e\x0b\xbc\xd7\x96\x1a\xa0\xf4\x32\x5f\x8c\xca\x1c\x1a\x0f\xc0\x36\xb5\x3b\x48\x0b\x39\xe7\x06\x21\x40\x8e\x0a\x29\xd2\xae\xe5\xc6\x0d\x48\x1c\xe0\xbc\x75\xd7\x24\xb6\xbb\xff\xda\xef\xee\xaf\xdf\xfa\xfe\xe0\xdb\xde\xbf\x0e\x7a\xf7\xd7\x7a\x34\x4e\xfb\xe0\x50\xd9\x2d\x41\xc2\xab\x9c\x14\x57\x87\xdf\xf7\x57\x89\xde\x56\x87\x1b\xdb\x82\xbc\x6f\xff\xc1\xe4\xfd\x20\xcf\xa7\x0d\xb4\x0d\x57\x53\x55\x84\x0d\x79\xea\x2f\x51\x29\xfe\xda\x36\x3f\x0f\x58\xf2\x16\xff\x68\x22\x63\xec\xd9\x45\x69\x18\x0a\x2f\x43\xc4\x04\xef\x52\x30\xa4\x2e\x49\xbe\x76\x91\x1a\xda\xa5\x16\xeb\xca\x5e\x84\x6a\xff\x0d\xa8\xb5\x69\xf6\xdb\xff\x69\x45\xb4\xb2\x3f\xcd\x14\x7b\xe7\x8f\xa6\x58\xd8\xc3\x34\xc9\x96\x61\x9a\x2d\x8f\x93\x08\x37\x3b\x24\xdc\x41\x88\x72\x21\x57\xff\x90\x74\x89\x3f\xb7\xd9\xef\x03\x9e\xb1\x65\x7d\xd9\xf4\x1b\xc9\xad\x55\xff\xbc\x6b\xd5\x23\x8b\x06\xa8\x1c\x3b\x79\x61\x32\x87\xd2\x4b\xd1\x39\x15\xf0\x08\x1d\x92\x97\xa5\x74\xbb\x4c\x1d\xa9\x53\xa3\xb5\xa5\x2f\x46\xec\x50\x09\x91\xfa\xc7\x8d\xfe\x79\xef\x62\x84\x2f\x7b\xd7\x4c\xf9\xdf\xb5\xa1\xfc\xb5\x6f\xb1\xc3\xaf\xc5\x74\x44\x93\x74\x9a\x00\xa5\xce\xe2\x79\x19\xe5\x93\xe8\x34\x39\xdc\x1a\xfc\x56\x0c\x56\x10\x44\x7e\x01\xc0\x64\x9e\x24\x51\x91\x4f\xca\xd3\x78\x9e\x0c\xa3\xb3\x7c\x11\x8d\xe2\x4c\x8c\x7a\x0c\x46\x13\xe9\xe1\xa2\x14\xf5\x94\x51\x9c\x8d\xd7\x04\x76\x45\x9b\xe9\xe4\x0c\xeb\x10\x89\x0b\x21\xb0\xcd\x91\xe0\x45\xaf\x4f\x0a\x68\x07\x3e\x7e\x7e\xf6\x26\x7a\x22\x76\x21\x91\xf7\x73\x92\x25\xf3\x78\x1a\xbd\x58\x1c\x4e\xd3\x51\xf4\x24\x1d\x25\x59\x91\x44\xb1\xe8\x18\xa4\x14\xc7\xc9\x58\x90\xa9\xa4\xa2\x24\xfa\x09\x3a\xf3\x4a\x76\x26\xfa\x29\x17\x0d\xc4\x30\xe6\x7e\x94\xa4\x22\x7f\x1e\xbd\x17\xd8\x81\x19\xda\x52\x6d\xc9\x1a\xfb\x51\x3e\xc7\x5a\xba\x62\x31\x8a\x31\xcc\xa3\x7c\x06\x05\x7b\xa2\xe3\x67\xd1\x34\x2e\x4d\x59\x1f\x05\x66\xa4\xe3\x28\xcd\xb0\xda\xe3\x5c\xad\x6c\x31\xcc\xd3\x74\x3a\x8d\x0e\x93\x68\x51\x24\x93\xc5\x94\x04\x47\x01\x1d\xfd\xba\xf7\xfa\x97\xe7\x6f\x5e\x47\xbb\xcf\xfe\x19\xfd\xba\xfb\xf2\xe5\xee\xb3\xd7\xff\xdc\x11\xd0\xe5\xb1\x58\x0b\x11\x48\x94\x58\x57\x7a\x32\x9b\xa6\xa2\x6a\x31\x26\x21\x7c\x96\x67\xa2\xeb\x58\xc5\xd3\xc7\x2f\x1f\xfe\x22\xca\xec\x3e\xd8\x7b\xb2\xf7\xfa\x9f\x62\x04\xd1\x4f\x7b\xaf\x9f\x3d\x7e\xf5\x2a\xfa\xe9\xf9\xcb\x68\x37\x7a\xb1\xfb\xf2\xf5\xde\xc3\x37\x4f\x76\x5f\x46\x2f\xde\xbc\x7c\xf1\xfc\xd5\xe3\x41\x14\xbd\x4a\xa0\x63\x09\xd6\xd0\x8c\xe8\x09\xce\x99\xc0\xe5\x38\x29\xe3\x74\xaa\xe6\xff\x9f\x62\x96\x0b\xd1\xcb\xe9\x38\x3a\x8e\xdf\x27\x62\xb6\x47\x49\xfa\x5e\xf4\x31\x8e\x46\xf9\xec\xac\xf5\x44\x62\x65\xf1\x34\xcf\x8e\x70\xd8\x9a\xca\xa2\x68\x6f\x12\x65\x79\xd9\x8f\x0a\xd1\xdd\x1f\x8e\xcb\x72\x36\x5c\x5b\x3b\x3d\x3d\x1d\x1c\x65\x8b\x41\x3e\x3f\x5a\x9b\x52\x05\xc5\xda\x8f\x83\x15\xc1\x9b\x24\xb3\xfd\x0b\x92\x2d\x58\x36\xcf\x45\x35\x98\x12\x2f\x04\x3a\xe7\xd1\x53\x41\x10\xef\xa2\xbf\xe5\x65\x72\x9a\x8e\x7e\x8f\x7e\x38\x81\xef\xbf\x24\xe5\xf1\x38\x79\x2f\xd6\xff\xc9\x8f\x08\x2c\xa8\x25\x89\x36\xd7\x37\x6e\x23\xc3\x6b\xde\x0a\x6a\x04\x58\x56\x46\xca\x63\xa1\xbd\x43\x4a\x0a\x0c\x18\x76\xc1\x10\xa4\x10\x6d\x6d\x40\x71\x62\x09\xc1\xbd\xf1\x00\x17\x15\x90\x8f\xce\x04\x47\x4a\x47\x8a\x8d\xb3\x12\x63\xca\x41\x1e\x15\x2a\xf9\x4a\x90\xbb\x98\x32\xab\x4c\x81\x69\x21\xe8\x97\x49\xec\x8c\x71\x2e\x52\x82\x7d\xf7\x41\x17\x55\xb0\x81\x6e\xeb\xfe\x22\x70\x5a\xc8\x01\x5a\x9c\xb9\x60\x55\xf4\x71\x87\x95\x7c\x5a\x59\x88\xb3\xfc\x81\xae\x02\xb7\x11\x02\xfe\xf4\x49\x9f\x1e\xa3\x0a\xe8\x5d\xb1\x4e\xcf\x08\x9c\x98\xb8\x23\x0a\x3c\x04\xfa\x64\x12\x80\x5c\x49\xc4\x21\xc6\x51\x99\x47\x49\x06\x34\xbc\x36\x4e\xe0\x8f\x6e\x05\x98\x71\x4c\x6c\x12\xb8\x92\x94\x6b\xed\x8d\x99\xea\xe6\x23\x06\xb0\xc2\xde\x99\x31\x49\x00\xe1\x5f\xab\x8b\xc8\xfb\x4f\xc4\xb2\xc8\xc7\x81\x6e\x91\x72\x5d\xac\x80\x88\x24\x97\xdc\x9a\x11\x51\x1e\x17\x91\x2c\xfe\x56\xce\x8c\xcc\x8a\xfe\x82\xbd\x8f\x3e\x12\xf1\x9c\x6b\xb1\xfc\x2f\x84\xf9\x42\xe4\xb0\xca\xce\x31\x0b\xdf\x2a\x88\x1c\x7c\xd7\x20\x8a\xd0\x67\x0a\xbc\x81\x24\x22\x20\x43\xec\x0b\xec\x44\xc0\xee\x11\x21\x16\x32\xd8\x4e\xcd\xbb\xe4\xe1\x48\xa1\x08\xb0\x59\xd8\xe2\x1d\xc3\xda\x40\x70\x18\xb1\xfa\xbb\xac\x6c\x8f\xe9\x20\x24\x15\x95\x52\x28\x50\x44\x80\x3a\x85\xde\xfe\xfa\xc1\x0e\xf1\x4f\x31\x82\xee\x0d\xde\x08\xaf\x83\x1e\x68\xd0\x53\x8e\x4e\x9a\xbd\x8f\x05\x98\xa1\x01\xa8\xf1\xc6\x30\xea\x44\x37\x23\x5e\xf9\x0a\x97\x35\x78\xcd\x36\x05\x56\x50\x5a\x34\x9b\xc6\x62\xe7\xc2\x19\x72\xa6\x91\x00\x5e\xc8\x9c\xea\x59\x94\xe9\xcf\x0f\x7f\x4b\x46\xe5\xb9\x53\xa1\x9a\x64\x53\x8e\xaa\x1d\x3b\x70\xd5\x53\xc7\xba\xe1\xcd\x5c\x9f\xca\x3b\x02\x17\x4e\x1a\x2b\x26\xa4\x2d\x00\x16\x62\xef\x3e\x82\x1f\xf4\xda\xa1\x46\x48\x19\x28\x01\xd1\xe2\xab\xc6\x4e\xc1\xd1\x80\x2c\x80\xb0\x13\x4a\x9f\x99\x02\x55\x88\xf1\x9a\x6d\x85\x9b\xc2\x5f\xfa\x12\x3b\x45\x15\x7d\x17\x8a\xc0\x8f\x92\x92\xaf\xc0\x42\x72\x0e\x49\xb2\x50\x4c\xf6\x0d\x4a\x58\x35\x08\xa9\x76\xd6\xad\xe2\xb1\xa8\x95\x0b\xac\x11\x8b\x77\x52\xcd\x5d\xea\xe9\x3e\x16\x39\x20\xf6\xac\xbe\xf4\x2a\x62\xfd\x91\xfb\xd4\xf3\xc9\xa4\x48\x4a\xaf\x53\x42\x04\x5d\x8c\x12\xd6\xaf\x78\x34\xea\x47\x0d\x9d\x43\xec\x94\x42\x68\x1c\x09\x7c\x96\x4f\xf0\x25\x91\x53\xf3\xc0\xcd\xef\x06\xfa\xa9\xea\x9a\x03\x53\x4a\xc6\xaf\xfc\x2a\x9f\xc6\xe5\xf1\x60\x32\xcd\xc5\x2a\xef\x7a\x2d\xde\x8c\xb6\x36\x7a\xd1\x5a\xb4\xb5\xd9\x13\x44\xb2\xb5\x29\x07\xcd\xd0\x27\x06\x23\xa0\xba\x7a\xd3\xb1\xb0\x5e\x81\xc2\xe8\x3e\xdb\xbb\x22\x51\x6d\x34\xb4\x12\x2a\x3a\xab\x50\xdf\x8f\xd6\x39\xf6\x85\x4c\xb3\x98\x96\x8a\x7a\x68\x06\x9f\x8a\x94\xf4\x57\x21\xca\xd1\x9c\x28\x0a\xb4\xfa\xd6\xd7\x74\xd4\xb7\x67\x50\x55\x2e\x47\x48\xf5\xdb\x27\xbe\x30\xe9\x3b\xad\x86\xd6\x40\xcb\x1e\xb0\x35\xa2\x87\xb7\xba\xba\x63\x16\x4e\x32\x9d\xc8\x11\xcb\xce\xca\x5d\x21\x9f\x3f\x8e\xc5\xc1\xce\x65\x4c\x29\xa7\x2d\xe0\xfa\x95\xf3\x65\xe6\xea\xa0\xc7\x0b\x11\x42\xb0\x2b\x37\x7d\x6d\x67\xd7\xee\xbe\x5a\x47\x8c\x08\xf5\xda\x05\x2a\x16\xbd\x97\x20\xee\x1c\x61\x07\xfc\x2e\x29\x3c\xe1\x87\x3b\x59\xbc\x09\x7b\x29\x8a\x9e\x26\xf2\x19\x9e\x20\xe2\x4d\x03\x2a\xf8\xdb\x54\x1c\x2b\xec\xe1\xad\xad\x45\xe3\x3c\xeb\x94\xa0\x26\x8e\x64\x29\xc1\x7f\xad\x2a\x07\xe2\x20\xd5\x29\xc4\x59\x41\xc8\x83\xe3\x33\x21\xe2\x2f\x32\x71\xdc\xaa\xc0\xd2\x67\x1a\xe7\xb9\x59\x84\xa2\xcb\xaf\x9f\x3f\x7a\x3e\x14\xe7\xe4\x23\x21\xa2\x46\x70\x60\xcb\x92\x02\x4e\x80\x70\x4a\x3b\x2b\x6c\x66\xf5\x25\x88\xe4\xcf\x33\xc9\xf6\x64\x24\x1c\x81\x0a\x2b\x15\xcb\x5c\xa3\x75\x9e\x4c\x62\x54\xc7\x9c\x1e\xe7\xe2\x44\x87\x3d\x14\xdb\xe3\x8d\x06\x46\x50\xc3\x03\x5c\xce\x2f\x07\x2d\x0e\xfe\xde\xca\xb7\x16\xb9\x9a\x93\x46\x51\x3f\xb0\xc5\x75\x7d\xd5\x18\x23\x20\x6a\x58\x1c\xeb\x0d\x59\x8b\x6f\x6f\x4e\x89\xac\x9e\xc5\x27\x89\xbb\x0f\x99\x1c\x2e\x67\xfa\x65\x5f\x2d\xbb\x9f\x99\x8a\x03\x75\x6a\xbe\x28\x31\x68\xa4\x5a\xf5\xaf\x66\xd8\xaa\x92\xd9\x3c\x79\x9f\xe6\x8b\x42\x77\x68\x73\x07\x50\x22\x44\x41\x71\x58\xf5\x4a\x34\xe1\x9f\xf5\x37\xd4\x20\xfc\x03\x45\x06\x3e\x12\x4e\x45\x63\x1b\x3b\xe2\xcf\x0f\x6a\x00\xea\xbd\x70\x94\xde\xbc\x59\x55\x1c\xfe\x39\x7d\x16\xa4\x7d\xb3\xab\x70\x90\x46\xb7\xa2\x8d\x03\x90\xf0\xc5\x01\x31\x5a\xdf\xa9\xac\xa4\x86\x95\x4b\x7a\xb8\x29\xfa\xf6\x6d\xd5\xcc\xdd\x74\x7b\x01\xc2\x41\x15\xdb\xb7\x97\x5d\x75\xca\x79\xaf\xdb\x73\xa6\x50\xcc\xc5\x24\x9d\x0b\x09\x34\x99\x26\x27\x49\x56\xc2\xf9\x8a\xd0\x24\xd6\xd1\xbb\x74\x26\xb8\xf1\x32\x53\x6e\x61\x7f\x3d\x84\x7d\xc0\x5f\xed\x0c\xe0\xd3\xf9\xb1\xa0\x00\xd1\x48\x3c\xd5\x8b\x5c\xe2\xd3\xe3\x3f\x6d\x37\x05\x43\x3a\x15\x0c\x42\x4c\xed\x4d\x31\xb5\x8a\x4f\x88\x0f\xaf\x1b\x01\xb4\x37\x22\xd8\x61\x7e\x01\x48\xb9\x55\x06\x68\x9f\xa8\xe2\xc2\xac\xe7\x4f\xcd\x54\x40\xd8\xb2\x31\x75\xc9\xc5\xdf\x40\x99\x95\x0c\x69\xbd\x8e\x21\xb5\xa3\xe9\x46\x8e\x22\x1a\x18\xc5\xd3\xd1\x02\xf4\xcb\x4a\xf0\x81\x23\x9f\xec\x8b\x58\x64\xc9\xc9\x25\xd8\x11\xb0\xa2\xfd\xff\x20\xa6\xd4\x73\x61\xcf\x57\xae\x96\xc9\xfc\x49\x19\x0c\x67\x2e\x9f\x9d\xb7\xf8\xda\x22\xd9\x8f\x06\x6d\x88\xd4\x45\xca\x9b\xc9\xbc\x46\x63\x44\x90\xad\x35\x46\x2a\x5d\xdf\x6a\x6a\x95\x48\x58\x97\x54\xad\x07\x61\x0d\x07\xc4\x3f\xac\x3f\xa4\x23\x62\xc5\x8c\x8e\x88\xa0\x51\xb6\x69\x83\x96\x5a\x25\x51\x05\x42\xaa\x74\x44\xd5\x08\x91\x25\xf0\x84\x81\xad\x19\xc4\x14\x6d\x31\x13\xd4\x10\x59\xb8\x59\x5e\x41\xa4\x4a\x11\x15\x73\x78\x22\xe2\x22\x78\x0a\x77\x8e\xfb\x57\xac\x51\xa2\x21\x77\x71\x64\x6a\x7d\x19\xb5\x88\xa5\x17\x51\x35\x1a\x0d\x53\x9d\xca\xc1\x8c\xaa\x51\xcf\xc0\x31\x4a\x1c\x08\xca\x5c\xf5\x48\xdb\xa8\xa3\xf4\x49\x34\x24\x07\xcb\xae\x55\x32\x49\xb1\x4d\x15\x8b\x13\xba\xa1\x0b\xec\x52\x52\x44\xd4\xf0\xb2\x3a\xc1\xd7\x80\x2b\xea\x2f\xdc\x92\x42\xfc\x47\x35\x6f\x23\x42\x49\x9b\x1e\x0a\x44\x6f\xe6\xc9\x49\xfe\x1e\xaf\x31\xa3\xd1\x62\x3e\x07\xf9\x54\x0b\xa7\x39\x26\xcb\x6e\x0a\xa9\x15\x7a\x1e\xe8\x6d\xb1\x8c\xc6\x4f\x21\xb3\xb5\xe6\xcf\x1a\x99\x18\xb8\x5b\xae\x35\xa5\xbd\x72\xd6\x61\xc5\xb5\x4e\xf0\xd4\xaa\x78\x5c\x80\xca\x4a\xe7\xca\x41\x91\x15\xec\x60\xfc\x92\xc4\xbe\xbf\xa0\xde\x42\x5b\x23\x79\xcb\xc4\x4d\x2d\xb0\xf7\x5d\xb2\x57\x75\x4d\x30\xe4\xb5\x68\xb7\xd7\x0f\x66\xc3\x2d\x68\x55\x1e\x08\x21\x15\x59\x6f\x6a\xf2\xf8\xe5\x66\x65\xb3\x75\x99\xc4\x85\xab\x72\xe1\xa2\xb2\xb2\x5b\x94\xb9\x02\x04\xe1\xdb\x6f\x20\xea\xb4\xcd\x86\x32\x9c\x1d\x6e\x83\xc1\x06\x7a\x52\x18\xde\xc6\x9f\xd0\xb7\xd5\xe1\x1d\xf8\xcd\xaf\x63\x57\x87\x77\xfb\x21\x5b\x8f\x14\x9e\x48\x6c\xac\xc3\x4f\xb8\x31\x15\xbf\x37\xe1\x37\xdd\xca\x8a\xaf\x2d\xf8\x5a\x10\x14\x36\xb0\x90\x60\x77\xce\x0f\xfa\x77\xbf\xa4\x5d\x54\xc3\x35\xf4\xc5\xac\x89\x78\x25\xcb\x18\x15\xd9\xe5\x5c\xdb\x22\x9e\xbb\xa4\x89\x51\xb8\x68\x8d\xa5\x91\xdd\x93\x36\x75\x5d\xc2\xee\xa8\xc2\xd8\xa8\x55\xa3\xec\x4a\x3c\x38\x5d\x8a\xed\xcc\x17\x49\x0b\x13\x26\x67\xd8\xcd\x96\x4c\xdf\x5f\x5b\x32\x5d\x5b\x32\x7d\x2d\x96\x4c\x66\x21\x5c\x95\x39\xd3\x83\xf4\xe8\xd9\xe2\xe4\x10\x59\xa1\xe6\xce\x87\xa9\xe8\x0c\x24\x8a\x56\x14\x27\x5f\x94\x02\x49\x16\x0b\x17\x7b\x06\xa4\xd1\xff\x15\xd8\x28\x08\x22\xd8\xdc\x24\xf5\x8c\x81\xd4\xc9\x8c\xed\x0a\x78\x76\xc1\x6d\x41\x0d\x9c\x78\xb5\xa0\x46\x60\xeb\x11\x3e\xd8\x80\x73\x16\xf0\x2d\x63\x25\x8b\x4b\x01\xe6\x06\x95\x33\xdf\x02\x8e\x09\x52\xd0\x70\x96\x1c\x89\xec\xf7\x62\x21\x28\x37\x99\x70\x71\x54\x9e\xe6\xe2\xff\x02\x33\x33\x25\xad\x62\x29\x98\x5b\x5d\x72\x32\xcd\x45\x49\x31\x83\xb3\x5c\xd4\xdb\xa7\xeb\x50\x20\xfb\x71\x7e\x9a\x39\x67\x3a\x5b\x4d\xe2\x1f\xdf\x3e\x11\x96\x3f\x69\x7c\x9f\xab\xb1\xc0\x52\xca\x12\xb8\x45\x87\x61\x99\x39\x1e\x87\x8d\x61\x10\x69\xe7\xda\xce\xc7\x6e\xd7\x62\xc0\x58\xbf\xe2\xc2\xba\xdd\x01\xcd\x45\x77\x34\x78\xfc\xfa\x97\xb7\x0f\xf6\x04\x25\x3f\x7d\xf0\xf8\xe5\xdb\x97\xe0\xed\x73\xef\xd9\xcf\x6f\x9f\x3e\x7f\xf4\x98\x9d\xe1\xb4\x26\x0e\x67\x72\x30\x8b\xc7\x4f\x92\x49\xd9\xa5\xaf\x32\x7f\x7d\x9a\x17\x0f\x35\x16\x65\x9b\x22\x5d\x8a\x4b\x1b\x77\x7a\xfd\xe8\xce\xb6\x7d\xc3\xc3\x77\x4b\x1c\x4e\x97\x1a\xb1\x0d\x30\xec\x89\x57\x87\xdf\x0a\x9c\x3f\xd0\x67\x63\xfb\xd0\xbc\x2c\x0e\x7d\xa9\xc3\xc2\x62\x00\x21\x65\xfe\x4b\xf2\x41\x8d\xbb\x58\x1c\x0a\x86\xd9\xdd\x64\xf8\x9b\x3a\x57\xfb\x54\x5c\x69\xb9\x6f\x46\x77\xb6\xe0\x5e\x9f\xa1\xc8\x45\xf7\xcb\xf4\xe8\xb8\x94\xc5\xfa\xa2\xba\x6f\x3f\x33\x3e\xe5\x0e\x7c\xa5\x68\xad\x94\xe9\x2e\x8d\x5d\x75\x3c\xb3\xd1\xaa\xb5\x73\x7f\xd8\x0c\x38\x6a\x53\x6a\xac\x37\xa0\x35\x7f\x33\x6a\x9e\xa0\x26\x4e\x47\x92\x7c\xf5\x8a\x78\xa5\xf2\x2f\x3b\x77\xda\xb8\xb3\xfd\xac\xc1\x83\xc3\x37\xe5\xe4\xee\xf5\xc4\x05\x26\x4e\xbe\x33\xaa\x62\x64\xf2\x15\x92\x9a\x34\xf8\x4e\xe2\x6c\x79\x46\xe6\x3e\x39\xaa\x9e\xb3\xce\xfa\xe5\xfe\x81\xe9\x21\x55\x1f\xdd\x8f\xa2\xce\x46\x27\x1a\x8a\x3a\x3b\x97\xe7\x51\x4d\x98\x84\x13\x2b\x94\xfa\x3b\xc0\x15\x11\x08\xc6\x27\x60\xf9\x43\x42\xe5\xe1\x59\xb4\xf9\xef\x13\x10\xcf\xb5\x0d\x5d\x0c\x35\x97\xc9\x91\x90\x9f\xaa\x67\xe0\xa5\xac\xb5\x69\xff\x5e\x76\x46\xa4\x2d\x73\xc5\x8c\x48\x34\x39\xd4\x07\x58\xd3\x2d\xea\xcd\x35\x3d\x11\x47\x36\x3b\x6b\xb3\x37\x98\xe5\xa7\xdd\x8d\xcd\xbb\xbd\x9e\x8d\xd2\x87\xc7\xc9\xe8\x1d\x5c\x9a\x71\x9c\x32\xb1\xc8\x41\x44\x21\x44\xbf\x64\xbc\x57\x3c\x33\xd9\x9e\x22\x5a\xd7\x71\x9c\x7c\x90\x3d\xb6\x91\xa1\x88\x16\x0f\x7d\xd8\x76\x69\x4b\x62\x39\x1c\x59\x4e\x53\x10\xc3\xe1\x65\xae\x46\x97\xdb\x7a\x23\xbe\x42\x18\x52\xec\x66\xbd\x1f\x6d\x08\xe9\x43\x88\x20\x46\x1e\xd9\xec\x59\xb9\xbd\xe8\xde\xbd\x7b\x40\xb2\x41\x2a\x9c\x03\xfb\xb8\x15\x4f\xb1\x53\x11\xa9\x0e\xcc\x85\x07\x89\x9a\x3e\x11\x91\x22\xc1\x15\x02\x2d\xf2\xf0\xec\x60\x01\x67\x46\x30\xac\x69\x57\x0b\x87\xb8\x2c\xd2\xa3\x88\xe4\x74\x87\xde\x74\x17\x2c\xfc\x59\x46\xb1\x00\x4c\xf3\x78\x8f\x7a\xc3\x74\x99\x5d\xb0\x6a\x8f\x56\xd7\x57\xa5\x8e\x18\xae\x25\x35\x15\x81\xf0\xac\x26\x52\xb7\x4e\x40\x69\x49\x13\xad\x25\x6d\x5f\xc8\x56\xf7\xb7\xce\x3c\xcb\xb9\x0d\xa8\x20\x03\xf3\x4b\x53\x7a\x92\x66\x0b\x77\x15\x74\x26\x97\xfc\xd7\xc1\xba\x55\xe5\x1b\xfa\x7a\xac\x45\x87\x2e\x40\x41\x8b\x7a\x12\x7a\x53\x4b\x43\x21\xea\x49\x96\x22\x1f\xd9\xbc\x4f\x38\x6f\xae\x82\x72\x3e\x0f\xca\x24\xcb\xaf\x42\x99\xc7\xbb\x1b\x51\x86\x18\x63\x22\xb1\x8d\x22\xd9\x9c\x8f\x22\x8f\x99\x87\x2c\xce\x9d\xc5\x28\x61\x06\xe3\xf4\x7d\x2a\x76\xa3\x07\x67\x35\x3c\xfc\x22\xd4\xd4\x80\x9b\x37\x57\x8d\x9c\x45\x25\x76\xde\x2c\x8d\x9e\x37\x97\xc1\x8f\x7f\x0b\x4b\x55\x6b\x14\x55\x49\x5c\xe6\xc1\x74\x6b\xbc\xa8\x9d\xcd\x9e\x8b\x4a\x1c\xc9\xa6\x7d\x14\x79\xf2\x59\x08\x43\x81\xe5\x85\xfb\xd5\x25\x05\x36\x21\xa1\xdd\xa7\xad\x59\x7a\xc6\x58\x0e\x9b\x95\x27\x47\xf6\x2e\xb7\x66\xef\x9b\x26\x13\x83\x38\x90\x20\x6a\xce\x36\x9e\xe8\x01\xba\x6b\x7a\xe0\x03\xbf\x1c\x11\x4c\xc2\x40\x9d\xba\x86\x00\xe6\xbd\x43\x28\xb6\xd1\x8f\xb8\xb2\x1c\x0a\xc9\x27\xd6\xa0\xde\xaf\x78\xa9\xfb\x6d\x6f\x8d\x1d\x69\x8a\xf4\x77\xc9\x13\x0b\xbc\xa5\x92\xe5\xf7\x37\x0e\x6c\x51\xb8\xb3\xfe\x01\x44\x66\x7f\x72\x07\x05\xe8\xfe\x40\x32\xd9\x14\x03\x83\xea\x7a\x57\x3a\x33\xfc\x14\x7e\x65\x13\xb4\x2c\xfa\x2b\x55\x01\xde\x26\xa3\x8f\x88\x0e\x1f\x20\xc4\xc9\x4b\x30\x17\x73\xe2\x80\x27\xf7\xf0\x32\x97\xf0\xf0\x62\x62\xb3\x7a\x06\x9c\x8a\x48\x3a\xbc\xb3\xdd\x97\xed\x2f\x37\x05\x35\xa7\x72\x1a\x7e\xe0\x58\x7e\xa5\xd8\x8f\x8b\x51\x9a\xd6\xe1\x3f\x70\x9c\xff\x82\x98\x57\x5a\x1d\xd4\x0e\xb4\xc3\xff\x72\x13\x60\xdc\xd3\x54\xcd\xc0\xae\x71\x60\x53\x31\x05\x95\xbc\xbd\x02\xe5\xba\x42\x1f\xdb\x21\x07\x36\x4b\x48\x53\x16\xee\x56\xd7\x3f\xac\x0a\xaa\x95\x67\x1c\x44\x3b\xfd\xd6\x66\x05\xdb\x62\x1a\x78\x52\x95\xcf\x80\x8f\xca\xf4\x83\x9d\x35\x87\xce\x77\xdf\x85\xc1\x15\x3b\xf4\x52\x3c\x38\xbe\xc0\x87\x55\x19\x5e\x29\x42\xe6\xd0\x4f\xf2\xfb\x21\x76\xce\xa1\x9b\xe0\x41\x81\x04\x32\x74\x13\x38\x94\x16\xcb\x86\x6e\x82\x0f\xf5\xc6\x03\x7b\x13\x84\xe3\x8d\x9a\x94\x40\x7d\x3e\xe0\x9b\x30\x24\x1f\xac\x49\x09\xc0\x71\x6c\xb3\x24\x1f\x32\x34\x3d\x7e\x8e\x5f\xce\x9e\x20\x9e\xe6\xc3\x4a\xaa\x1f\x06\xd7\xdd\xb9\xba\xd6\xb5\x2f\x87\x56\x87\x1b\x60\xc9\x60\x5d\x2a\xad\x0e\x37\xd1\x82\x01\x17\x86\x00\xd8\xe8\xaf\xf2\xab\xa9\xd5\xa1\xfd\x79\x7e\xd0\xdf\x58\xff\x83\x5d\xba\xec\x91\x6d\x7c\x8d\x0f\x22\x71\xea\xaa\x72\x41\x24\x6f\xaf\xc4\x1f\xf2\xce\x02\x3f\xb6\xf5\xaf\x03\x93\xb8\xc5\x7e\x3b\xce\x5b\x44\x12\xb9\x6e\x11\x3f\xee\x6c\x6b\xb0\xbb\xa6\xa2\xcd\xdb\x77\x2a\xea\x02\xf8\x06\x57\x46\xee\xd1\xf0\x33\x7a\xe3\x42\x70\xd7\x0c\x41\x24\x2e\x69\x79\x61\x95\xa8\x31\xb8\xc0\xe6\x6a\x4a\x5e\xc8\xbc\x42\x20\x55\x89\x8a\xf7\x2f\xe4\xd2\x85\x7a\xd5\x6c\x06\xb1\xb1\x71\x6d\x07\x71\x6d\x07\xf1\xdf\x6d\x07\x11\x19\x43\x08\x12\x95\xae\xc8\x06\xa2\x85\x69\x83\xcb\xea\xc9\x74\x21\x47\x83\x74\xe3\xb9\x63\x10\x90\x50\x4f\x8f\x93\x4c\xbf\x57\xec\x93\xed\x37\x08\xe0\xda\x81\x83\x92\x2c\xd7\x82\xb6\x11\x8e\xfa\xdb\x7d\x9e\x88\x9c\x54\xc9\x8f\xf4\xf7\xd3\xa7\xa8\xd3\x61\x7c\x36\x57\x2f\x17\xe8\xc7\x0e\x7b\x6a\x28\x48\x99\x5a\x6f\xed\xf1\xe3\x28\x29\xb9\xc9\x2f\x1a\x90\x77\x0a\xf5\x10\x14\x79\x09\x54\x62\x59\xbb\x1b\xf9\x9e\x8c\x5d\x6d\x29\x5a\xa9\x99\x4c\xad\xbc\xb2\xa8\xab\xfa\xd8\xb3\x0c\xda\x11\x3d\xdc\xa0\xdd\x6d\xa4\xd6\x14\x0d\xad\xfc\xad\x63\x07\xbf\x7e\x6c\x8d\x8c\xd1\x3c\x01\x62\x52\xeb\xc1\x76\xcb\x42\xe4\x2e\x78\xe1\x24\x41\x83\x64\x42\xb9\x73\x2e\x39\xd5\xef\x42\xf8\x71\x44\xa1\x44\xce\x92\xb2\x5d\xce\x82\x87\x10\xfb\xe8\x02\xdb\x61\xa8\x1f\xf1\x8c\x38\x8c\xee\x45\x35\x2a\x4f\xc3\x6f\x66\x6d\xba\xab\xbc\xd5\x33\x04\xa9\x49\x75\x19\x8c\xe6\x27\x87\x69\xe6\x7b\xb8\x29\x73\x41\x78\xc7\x78\xcf\x11\x25\x47\x03\x5a\x54\x62\x4c\x49\x86\x6b\x49\xec\x46\xf8\x06\xc2\xc1\xae\xac\xad\xe9\x1e\x46\x32\xa6\xe3\x74\x04\xec\x49\xf5\xaa\xb9\xb0\xbc\x40\x15\x33\x4f\xb0\xb8\x0f\xd5\xa2\x56\x0d\xaf\x49\xef\x57\x87\x56\xad\xb7\xa0\x2b\x93\x9d\xa8\x19\xbb\x10\x9f\x57\xe2\x57\x5d\xe3\xd0\x88\x8e\x63\xb3\x74\x41\xdd\x34\x90\x56\x50\x62\x47\x13\x59\x7d\xf8\xab\x08\x0d\xdd\xff\x06\xee\xed\x38\xbe\xb5\x2d\x68\x58\x67\x5a\x8b\x9a\xb0\x77\xa6\xd0\xc2\x95\xab\xe2\xc6\xbd\x7b\x28\x5d\x4c\x04\x42\xc7\x3b\xcb\x20\xc1\x78\x2d\x92\x55\xc9\x1b\x66\x57\x69\x40\xb9\xbb\xc5\x83\xea\xa3\x3f\x69\x7c\x7d\x0d\x4d\x8b\x65\x66\xd5\x5e\x37\xe8\x1b\x38\x6a\xe3\x02\xa0\x17\xdd\x17\x0c\x3e\x1a\xb6\x33\xc8\x62\x28\x0b\x9a\x65\x2d\x81\x37\xe0\xfd\xa4\x9c\xd0\x32\x63\xe8\xb9\x97\xd1\x5f\x84\x71\xa6\xf6\x1e\x75\x2b\x1c\x73\x86\x0f\x7a\x58\x43\x64\x48\xe2\xb5\x58\x34\x8d\x05\x51\x88\x7e\x95\x5c\xfc\x85\xfc\x33\xe9\xe5\x25\xd6\x13\x3d\xb6\xad\xa0\x3b\x39\x61\xdd\xe5\x51\x67\x6d\x6b\x15\xb8\x63\x9b\x52\x18\x79\x2a\x21\x56\x97\xf0\x2d\xb0\x98\x9c\xcc\x04\x27\x65\x2a\xc1\x16\x9b\x68\xe3\x2a\xb4\xe9\x91\xb1\xa7\x21\x4a\x1f\x4b\xe0\x46\x79\x9c\xaa\xf4\x35\x15\xc4\x44\xed\x40\x64\x95\x4d\x63\xb0\x2e\x56\x6e\x06\xc4\x82\x8b\x8c\xc3\x3c\xc6\xab\xf6\x0f\xf5\x44\x1c\x27\xbc\x97\x7f\xfb\xd6\x68\x0e\x02\x4e\xa1\x6a\x47\x6f\x6a\xf6\xb7\x17\xfd\x2e\x48\xdd\xd4\x2f\x66\x63\xb2\x6c\x95\xef\xe0\xb4\x2a\x52\x20\x90\xbd\x75\xa5\xc2\x4a\x38\x22\xbf\x43\xf6\xdb\x3e\xfd\x84\x50\x82\xda\xcf\x8a\xac\xbd\x4d\xaf\x47\xda\xbe\x2a\x16\xa4\x7b\xfb\xe5\x3a\x0b\xb1\x9b\x87\xf7\x4d\xa6\xc7\x06\x96\x0f\xe3\xe6\x3d\x5f\xd1\x2f\x5f\x6b\xf9\x2f\xb4\x08\xd2\x88\x40\xdd\x9c\xbf\xba\xd5\xaf\xe6\x04\x0a\xe5\x74\x8b\x33\xdc\xfc\xac\x3c\x46\x5f\x24\xac\x1e\x8e\x1d\xdf\xf1\x94\xb2\x48\xf3\xf0\x63\xbd\xd4\x0d\xdf\x50\x68\xdf\x4b\x57\xda\x84\xaf\x74\x16\x2d\x74\x3a\x4a\xf9\x5e\xa3\xa4\x78\x41\xb3\xe4\xe8\xf4\xb4\xfa\x0e\x54\x13\x9b\x7f\xb0\x4e\x0e\x6f\xa3\xeb\x95\x72\x70\x95\x5d\xa5\x95\x53\x66\x66\xf0\x97\x54\x65\xf8\x6b\xdb\xfc\x3c\x60\xc9\x5b\xfc\xc3\xd1\xcd\x41\x1a\x29\xe7\xe0\x97\xd2\xce\xc1\xef\xbb\xac\x3a\xa6\x9f\xf3\x6a\x6c\xa1\xa1\xf3\xee\xde\x97\x51\xd1\x41\xe1\x65\x74\x74\x04\xef\x2a\xe9\x20\x75\x49\x2d\x9d\x5d\xa4\x46\x4d\x47\x2d\xd6\x95\xbd\x88\xa2\x0e\x70\x5b\xa1\xa8\x6b\xe7\x28\x5f\x76\xab\x85\xa2\x6e\xeb\x8f\x7c\x5c\x17\xb8\xfd\x5b\x86\x3c\xa8\xf8\x32\x04\xa2\x4a\xb8\x24\x42\xe9\x4b\x12\x89\x5b\xa8\x86\x4c\x54\xbb\xf5\xe5\x2f\xa4\xd3\x25\x49\xaa\xcd\x9b\xb9\x40\x7b\x57\xfb\x5a\x4e\x8f\xb2\x05\xdd\x5d\x7d\xf4\x91\xda\xf7\x3b\x01\x3e\xcc\x5c\xdc\x0a\x6e\xdc\xda\xb7\xed\x28\x99\x97\xe0\xd7\x20\xe8\xdf\xd6\x43\x24\xdd\x26\x35\x10\x35\x01\x0d\xec\xf4\x7a\xb2\x96\x45\x9c\x8c\x46\x6f\x10\xa0\xf6\x86\x23\x7f\x3a\xc1\x9a\xed\x7e\x8f\xa5\xd7\xda\xe8\x28\x05\x55\xb0\x34\x69\xb1\x8f\xd4\x55\xee\x72\x1d\xfb\x17\x3a\x66\x1b\x8b\x5b\xc4\x32\x55\xee\xb5\x1b\xb6\xbf\xe5\x10\xed\x97\x08\x39\xa7\x5d\x2d\x83\xc2\x71\x2e\x84\xa3\xf9\xe9\x3c\x15\x98\x42\x73\x2f\xea\x15\x5c\x62\x43\x15\xad\x71\x77\x8a\x5a\xf6\x82\x3f\xe4\x47\x2b\x08\x73\x14\x15\xd4\x41\x28\x2c\x7d\xbf\xc3\xee\x5b\xfb\x56\xc8\xf4\xb5\x92\x4e\x73\x46\x6b\x5b\x81\xb7\x80\x0b\x81\x30\x06\x85\x2c\x09\xaa\x70\x30\x6c\x4a\x0b\xf4\x7a\x28\xb5\x59\x30\x5e\xe0\x04\x09\xdd\x31\x4c\xd3\x77\xe2\x74\x11\x15\xa2\xc3\xe0\x01\x4f\xfa\xe1\x42\xc8\x81\x65\x12\x8d\x14\x4c\x6e\x66\xc8\x2d\x07\xb5\x06\xce\x7f\x3a\xfb\x1b\x07\x9d\x83\x9e\x16\x06\x1b\xdc\x00\xc8\xee\xd9\x78\x87\x2f\xee\xda\xb0\x42\x74\x27\x1b\x28\x42\x05\xda\x2a\x6c\xf4\xa3\x5b\x68\x8f\xbd\x8e\x7d\xd9\xe0\x8e\x68\x4c\x87\x3c\x41\x56\x39\x6a\xe8\x2b\xd7\x0e\x55\xa7\x05\xe5\xd0\xe1\x5b\x05\xb8\xc3\x5d\x44\xc4\x70\x41\x13\x17\x62\x06\xc9\xd9\x35\xdc\x1e\x6d\x6d\x4a\x05\x0e\x5c\x4f\x98\xde\xf4\xc1\x5b\xd1\x52\x0b\x5b\x72\x34\x75\x02\x57\xba\xc8\x28\x39\x42\x01\x12\x83\x42\xed\x1f\xac\x46\xf7\x7e\xc4\xf5\x69\xd2\xb6\x29\xb1\x56\x99\xb6\xab\x6a\x5b\x96\x03\x1c\x27\x95\x3d\xab\x59\xed\xbc\xd5\x4a\x9a\x35\x6e\xbf\x2c\x87\x30\x1e\xd1\xed\x38\xdb\x28\x2b\xf2\xcd\x37\x11\xff\xde\x67\xbf\x99\x0b\xb8\x03\xb5\xeb\xea\xc8\x18\x6b\x47\x17\x9a\x1b\xb9\x7c\xeb\xa6\x46\xcd\x82\x3d\x37\x72\xc2\xec\xa9\x61\x1e\xd7\x2e\x39\x33\x4e\xbf\x6a\x26\x86\xb5\xf9\xb9\xe7\xe5\x2a\x27\xc6\x76\x7d\x62\x18\x29\x9b\x09\x3c\x1b\xad\xa2\x2d\xc2\x26\x21\x9d\x0e\x49\xab\xd2\x58\x61\x43\x4e\xc5\xc6\xb6\x06\xdc\x3c\x10\xc7\x22\x02\x55\x69\x04\xa2\x21\x36\x0e\x9c\x04\xf3\xed\xef\x0e\x88\xd5\x0b\x6c\x0f\x7c\x2c\x72\x88\xcd\x7b\x02\x6b\xec\x8a\x26\x12\xd4\x2a\x2c\x8b\x71\x48\x97\x1f\x5f\x70\x62\x91\x61\x87\x5e\x43\x6c\xd4\x4c\xb9\xdc\x24\xd4\xa9\x3a\x34\xcf\x38\x6f\xa1\xa9\x5e\xd5\xf1\xf7\xbc\x09\x97\x9f\xab\xd6\xbc\x5b\x15\xed\xdb\x95\xf3\xe9\x36\xde\xd7\x5a\xcd\xb3\xce\xa0\xa1\xc8\xfc\xca\xf9\xb5\xbc\x28\x56\xee\xf6\xd2\x5b\xd1\x34\x2e\xca\x68\xff\x00\x84\x09\xaa\xf7\x42\xd3\x7e\x23\x3c\xef\x7a\x0e\x50\xce\xf2\x7d\x2c\xe1\x81\xc6\xbc\x84\xc2\x4f\x4d\x03\x6d\x88\xa4\xc6\x38\x96\x1d\x61\x34\x07\x76\x6f\x9a\xe0\x75\xde\x38\x99\xc4\x8b\x29\x2a\x42\x8b\x05\xc8\xa9\x7a\x63\x5e\x95\x6e\x6a\xfa\x32\xcc\xe3\x6a\xd5\xde\x78\x41\xe1\xd8\xec\x88\x4b\x8a\xc2\x2d\x4f\x6f\x95\x46\xf5\xca\x57\xbb\xd2\x11\xb3\x25\x52\xb8\x6b\x04\x29\x5e\x92\xf2\xfe\x2a\x50\xbc\x40\x07\x2c\x02\xf8\x73\xb0\x7a\x60\xa8\x5d\x42\xb0\x34\x2c\x94\x2d\xa6\xd3\x20\xf9\xbf\xae\xf2\x30\xe7\xa1\xcd\x75\x30\xe7\xf4\xb7\x61\x21\xf8\x4e\xaa\xbc\x95\x40\x7b\x83\x74\x96\x47\xb3\x5e\xe0\x0d\x2f\x0c\xc7\x1a\x2f\xfc\x1f\x57\xbd\x43\xc4\x92\x5b\x89\xe3\x38\x9d\xc6\xc5\x61\xbc\x59\x44\x90\xca\x5b\xed\xed\xa1\xfa\xde\xc1\x09\x63\xa1\xa0\xdb\xb3\x0e\xf5\xf6\xd4\xbf\xcb\x72\x70\x16\xb8\xb4\x90\xf7\xe8\xee\xc6\x10\xf4\x47\x6d\xdf\xca\x48\x57\xa8\xca\x09\xea\x92\xde\x60\xb5\xfd\x37\x37\x26\xde\x89\xa2\x1a\xbf\xa5\x01\xc7\xa8\xbe\x5f\x59\x70\x27\xc6\xfc\xc9\x4a\x56\x56\xed\xfc\x75\xff\x20\xe4\x44\x7c\x30\x5b\x14\xc7\x5d\xcf\x33\xa9\x7a\xa9\xad\xdc\x8c\xfa\x35\xc3\x58\x7c\x9f\xeb\xef\x43\x0e\x40\x79\x4b\xcc\x8f\x67\xf7\x7d\x3f\xe2\xfe\x65\x1d\xf7\xa4\x97\x72\xea\x2b\x27\x90\x3b\xf3\xbd\xf4\xfc\x61\xd7\x3d\xa9\x43\x22\xfe\xcb\xcf\x5f\xc8\x23\x6b\x83\x27\xd6\xca\x89\x80\xd9\x44\x57\xa9\x35\xf3\xb1\xf4\x6c\xac\x78\x77\x84\x8e\xee\xc8\x5a\x92\xcc\xa3\x6d\x1b\x9f\xa0\x74\x3f\x0a\xbe\x11\x82\xe6\x06\x04\x55\xef\xf8\x33\x6c\x20\x64\x59\x06\x2d\xf1\x60\x4a\x31\x35\x6a\xb9\x05\x8b\x92\x03\xe1\x2c\xca\xf2\xa7\xd9\xc0\xaa\x3e\x0b\xaf\xc2\xbd\x89\xdf\x58\x92\xa0\x2b\x9f\xf8\x60\xf7\x94\xa0\x23\xd1\xf5\x6d\xb4\x89\xc6\x0f\x3d\xe5\xd1\x59\x22\xaf\x6a\x11\xd5\xd6\xc9\x9b\xf7\x2a\x0e\xad\x28\x2c\x20\x16\x39\xde\xb1\xf3\xd2\x37\xe1\x7a\x15\x55\x15\xb8\xdb\x16\x00\x22\xc4\xa1\x09\xa8\x1f\xd5\x22\xe1\xfd\xbd\xd0\x5a\x6d\xef\xbb\xdb\x70\x8e\x0a\x9f\xdd\x51\x2d\xf6\x64\xe8\x98\x67\x55\xf5\x73\xbf\x1e\x95\x6e\xa4\xdd\x98\x37\xb5\x8c\xa6\x25\xa7\x81\x8b\xb4\xba\xbe\x01\xec\xcd\x7b\xf5\x30\xad\x18\x95\xed\x70\x96\x4d\xfb\xcd\x28\xbd\x5a\xae\xf5\xa7\xd8\x43\xda\xc9\x03\x16\xf5\x2b\x83\xd4\x96\xff\xfe\x54\xc4\x7f\x4d\xfb\x7f\x34\xed\x87\x49\x9f\x07\x68\xbc\x28\xe9\xbb\x61\xc4\x96\xdc\x54\x3c\x62\xbd\xd8\xce\x62\xf7\x62\x99\xfa\xe5\xfc\x05\xe9\x6d\xea\xd1\xd7\xce\x92\x4b\x78\x26\x6f\xfd\x7c\x23\xd5\xa6\xee\x77\xa3\x0d\xf0\xa0\xc4\x7b\xd7\x23\x9f\x4c\x14\x49\x2c\x30\xf5\xe4\x81\xd8\xb9\x74\xb3\x1e\x6c\xd7\xf8\xb3\xb7\x70\xed\x58\x7c\x59\x5c\x6c\x65\xc9\xb1\xf1\x39\xd7\x2b\x6b\xb3\x67\xab\x55\x83\x17\x89\x4e\xd7\x1b\x2f\x78\xab\xaf\x76\xf5\x9b\x38\xb8\xdb\xbd\xfd\x07\x5b\xd8\xbc\x69\x7e\xf6\xb6\xa8\x79\xf7\x26\x3d\x91\xe0\x5f\xb2\x75\x59\x98\xa7\x6f\x0b\xf6\xf6\x6d\xc1\x1f\xac\x2d\x02\xaf\xdf\x16\xfa\xf9\xdb\x82\xbd\x7f\x5b\xb0\x07\x70\x0b\xfb\x05\x9c\x57\x63\x0b\x0b\x9b\x37\x5f\xf0\x11\xdc\x9b\xe0\x2b\xb8\x37\xcb\x3f\x83\x7b\xd3\xf6\x1d\xdc\x1b\xff\x21\xdc\x9b\x2b\x78\x09\xb7\xb8\xf4\x53\xb8\x37\xad\xdf\xc2\xdd\xf9\xa3\x97\x43\x0b\x8b\xb3\x45\x9d\xc9\x99\x72\xad\x42\x3f\x24\x71\x32\xab\xb3\x05\x37\x3b\x5b\x58\x56\x62\x8b\x90\xe1\xd9\xc2\x58\x9e\x2d\xb8\xe9\xd9\x82\xdb\x9e\x2d\x1c\xe3\xb3\x40\xbd\x6d\x16\xc7\x17\xb5\x3f\x7b\x13\x36\x40\x7b\x73\x01\x0b\xb4\x37\xad\x4d\xd0\xde\x04\x6c\xd0\xde\x5c\x81\x11\xda\xbf\x17\x35\x66\x68\x6d\x17\x49\x7b\x43\xb4\xef\xda\xac\x92\xce\x42\xc8\x57\xa0\x98\x1d\x95\x1d\x0a\xc8\x77\x04\xea\xd3\xf7\xd1\x38\x4f\xd0\x5a\x01\x5f\x07\x82\x93\x3d\xf0\x61\x1b\xfd\xe3\xe9\x93\x5f\xca\x72\xf6\x52\x54\x27\x84\xbc\x15\x14\xcc\x44\x93\xf9\xc4\xc9\x21\x3f\x36\xfa\xfd\x46\x47\xe1\x45\x36\x3c\x70\xa1\xa3\x8f\x62\xe4\x56\xb0\xc8\x4a\x48\x3b\x01\x25\xf5\xdf\x8a\x63\xd8\x7d\xd2\xa3\x2c\x9f\x27\xc3\x29\x3c\xeb\x39\x27\x8b\x55\xc0\xc3\xdd\xeb\x97\xb3\xd7\x2f\x67\xff\xcb\x5f\xce\xd2\xab\x59\x69\xc3\x66\x3d\x9b\xa5\x0d\x27\xba\xd8\xeb\x59\xb9\xf7\xbd\x01\x6b\x6f\xf3\xee\x13\xd7\x0e\x3d\x4f\xf2\xc0\xc0\xb4\x4f\xad\x36\x53\x64\x34\x8d\x8b\x22\xda\xc7\x22\x07\xb2\x9b\x94\x61\x98\x30\x55\x25\x38\xd0\x9a\xda\x86\xa5\x72\x15\x38\x48\x54\xe3\xcc\xba\xbd\x9f\x73\x84\xfc\x75\xf7\xd9\xdb\x37\xcf\xf6\x5e\xbf\x82\xb3\x35\x4e\x42\xe7\x34\xcd\x3a\x44\x9a\x9d\x77\xec\xf7\x53\xf6\xfb\x67\xf6\xbb\xf8\x3d\x3e\xcc\xd5\x87\xe8\x5d\x96\x9c\xe9\xaf\xe4\xa4\xcc\x4f\x63\x0d\x3a\x4b\x47\xfc\x33\x8b\x33\xfe\x29\xce\x4f\x73\xfb\x7b\x3a\x4d\x1d\x70\x0b\xd6\x02\x54\x1f\xac\xc0\x91\x58\x24\x63\xd3\xfd\x98\x75\xdf\xfc\x7e\xcd\x7e\xbf\x60\xbf\x1f\xb3\xdf\xff\xcb\x7e\xff\x93\xfd\x7e\xc6\x7e\x3f\x62\xbf\xff\xce\x7e\xbf\x81\xdf\x2b\x07\xd5\x8e\x69\x60\x2e\x5f\xec\x3e\x82\x09\x1c\x8a\x13\x6a\x5f\x27\xbe\x12\x13\xbc\xfb\xfa\xcd\xcb\xc7\x6f\x9f\x3c\x7e\xf6\xf3\xeb\x5f\x86\xd1\x36\x65\xea\x39\x1b\x9a\x9f\xa6\x58\x05\x5d\x0c\xa3\x8f\x91\x93\x60\xbc\xa4\x63\xc6\xdb\x47\xcf\x7f\x7d\x16\x9d\x9b\x9a\x5e\x3c\x7f\xf2\x04\xa0\x5f\xef\x3d\x7d\x2c\xb8\xd3\x30\xda\x58\x5f\x5f\x5f\x93\x3d\x94\xf7\xd9\x0f\xa6\xf9\xe8\xdd\x30\xea\x00\x63\x2c\xca\x8e\x95\xb7\x3b\xc2\x40\xc5\x43\xf3\x72\x91\x9e\x57\xc0\x6e\xdd\xe4\xd9\xe4\x3a\xd0\xc5\xf5\x36\xf5\xf5\x38\x78\x28\x8e\xe3\xad\xab\xf2\xef\xf0\x70\x7e\x36\x2b\xf3\xbf\xbe\xe2\xac\x7f\x84\x69\xb7\x4c\x7c\x0b\x68\x30\x08\xb0\x06\x39\x9d\x60\xec\x26\xdf\x39\x03\x52\x5c\xc1\x9f\x9f\xca\x24\xb0\xab\x93\x3f\x07\xca\x1b\x04\x09\xc1\xc7\xc9\x87\x8e\xfb\x46\xce\xf2\xeb\xf5\x63\xb4\x09\xa5\x5d\xdf\xc6\x9b\xca\x19\xa4\x5d\x5c\x5f\x75\xeb\xeb\x7b\xc7\xfb\xba\xfb\x96\x8e\x40\x15\x8e\xa0\x73\x83\x5f\x92\x0f\x03\xd4\x4d\x4a\xbf\xbc\x21\x0b\x22\xc0\x8a\x1a\xb6\x69\x9d\xce\x6b\xa4\x5c\x1b\x46\xe2\x30\x29\xdf\xcd\x99\xa7\xc7\xea\x45\x19\xb0\x3c\x8d\xe3\xd5\xe1\x6d\x71\x3c\xb1\x51\xbe\x3a\xbc\x0b\xaf\xc7\x36\xd7\xaf\xf9\xde\x35\xdf\xfb\x5a\x02\xfc\xd0\x2b\xfe\x2b\xe0\x7b\x8e\x64\xbe\xbc\x60\x1e\x90\xcb\x55\xc1\x90\x58\xbe\x54\x2c\x21\x97\xd7\x0e\xd6\x24\xbb\x37\xc1\x86\x26\x77\x39\x00\x7c\x6b\x01\x7d\x91\xa5\xe5\xd3\x78\xa6\xc5\xc5\x0e\xc9\xcc\x43\xe2\x40\x9d\x75\x2d\xf7\xa6\x3a\x11\xbf\x37\x2c\x19\x7e\xc8\x32\x20\x88\x81\xcc\xfb\x5b\x4d\xde\x61\x7c\x78\x18\x1f\x25\xba\x25\x9e\xa7\x05\xfb\xa1\x9b\x73\x12\xa8\x91\x65\x3f\xad\xcf\x9e\xe6\xef\x93\x69\x3c\x52\x8d\xba\xd9\xea\xfc\x30\x0c\x65\x1e\x85\xab\x66\x10\x3f\x37\x42\x88\x89\xc9\xb2\x3c\xb3\xc6\x6c\x43\xa8\x33\xcb\xb0\x26\xbf\xa1\x0d\x3c\x2f\x0d\x03\x10\x1c\x8d\xea\x2c\x34\xac\x07\x69\xaa\x47\x9e\xc7\x86\x41\x20\xab\x32\x79\xd6\x1a\x36\x83\x85\x87\x67\x03\x32\x12\x0d\x40\x31\xc0\x77\x1c\x32\x04\xc8\x67\x18\xcf\x73\xb5\xd5\x5a\x94\xd8\x5c\xb3\x45\x3e\xad\xc0\x59\x89\xb2\x75\x09\x28\x14\x36\x9a\x32\x0e\x15\x66\xf1\x58\x9a\x84\xa2\xc9\xe6\x87\x59\x32\x82\x6d\x4a\x9b\xd9\x73\xe3\x29\xe9\x05\x25\x64\x01\x65\xaa\x38\x04\x2f\x13\xe3\xb1\x8c\xac\xe5\xd8\x52\x8d\x8e\xc5\xf7\x08\x54\xa5\x4a\x55\x8f\xf7\xeb\xb2\x34\xdb\x31\x82\x6d\x08\x26\xd7\x67\x36\xcd\xd1\x7a\x85\xfb\x0e\xf0\x2c\x1b\x29\xcf\xb2\x8e\x97\x5e\x39\x06\x4b\xae\x24\x90\x3e\xf6\x0e\xa2\x09\x8b\x82\x01\x5f\x41\x64\xe9\x81\x30\x18\x5c\x16\x5f\x9d\xea\x9b\xde\x8d\xde\xe0\xb7\x3c\xcd\x30\xe8\x42\x74\x1f\xeb\x88\x86\xe0\xa4\x1f\x9c\x5e\x14\xec\x7d\xee\xd5\xcd\x05\x06\xde\xf9\x4f\x9f\x0c\x1c\xc4\x92\xb3\x21\x7b\x78\x93\x3c\x27\x5f\x74\x5e\x7a\x8d\xf3\x01\x0e\x65\x70\x93\x44\x73\x42\x0c\xca\xe1\xfb\x8a\x76\xe6\x85\xbc\x2e\xd7\xcc\x8a\x10\x00\x45\x25\xcd\x7e\xa5\x03\x0e\x8f\xa0\x71\x63\x3e\x48\x9e\x44\xde\xd0\x26\xae\x85\xf7\x63\x8a\xdb\xbc\xb6\x16\xfd\x94\x66\xe3\x88\x1e\x69\xc9\x8e\xea\xb8\xcb\x20\x3b\xac\xae\x9a\x1b\x79\xb4\x61\xe9\x63\x28\x28\x51\x5e\x99\x22\xeb\xd3\x15\xa4\xd1\xf9\x06\xce\x16\xd5\xa7\x22\xa8\x66\x93\xbf\x5f\x41\x23\x19\x69\x1b\x03\xc6\xa7\xf7\x36\x39\xb8\x8a\x69\xcc\x6d\x34\x4c\x53\xdd\x14\xda\x91\x21\x2c\xf8\xb1\x8d\x8a\x88\xe6\xd7\x7b\xd6\x79\xec\x70\x9e\xc4\xef\x0c\x28\x8c\xf2\xe6\xbd\x48\xbe\x0e\x87\x19\x7c\x28\x48\x04\xe2\x25\x63\x0d\xc1\xe3\x16\x4c\xb6\x32\xab\x11\xe5\xdb\x51\x08\x4d\xda\x52\x24\xb2\x0b\x45\xbe\x1c\x8d\x60\x73\x5f\x0f\x91\x5c\x64\xe6\x45\x99\x56\x93\x1d\xf0\x15\x0f\x8f\x67\x27\xe9\x07\x0a\x86\xb5\x2e\x10\x2b\x66\x01\xb9\x46\xd8\x4d\xbd\x8c\xda\x56\x3d\xfb\x41\x1b\x64\x3c\x6c\x8a\xe3\x11\x70\xf3\xda\xa0\x00\xa1\x68\x9d\x96\xcf\x7c\xe9\x3e\x5d\x76\x83\x53\x05\x50\xbc\xc4\x3c\xcd\x3e\x2e\x07\x69\x7e\x4d\xcb\x41\xcd\x08\xb4\x64\xa8\x43\xcc\xa0\x6b\x0a\x27\xc0\xab\x22\xdb\xb3\x19\x05\xa8\x91\x9c\x9b\xdd\xb2\x9b\x5e\x6c\xa5\xa3\x95\xa1\x0c\xf2\x6d\x79\x5f\x33\x20\xd0\x5f\x41\x12\x99\xda\x17\x7e\x88\x36\xc1\xc1\x1b\x46\x55\x83\xfd\x20\x0b\xd1\x84\x74\x05\x2f\xca\x5e\x25\x69\x38\xb1\x03\x5c\xda\x68\x60\x0d\x5f\x8c\x38\x3c\x9e\xc1\xa8\xe3\xcb\x50\xc0\x1f\x36\xad\x8e\xe7\xd1\xc9\x62\x3a\xd5\x68\x58\x03\xad\x4b\x49\x06\xff\xc8\xf2\x7f\x2b\x04\x22\xe3\xc3\xd4\xe5\xf1\xca\xfd\xe1\xeb\xfc\x27\x51\xde\x7d\x0b\xa9\x1e\x05\x40\xe9\x5b\x54\xda\x7f\xd4\x44\x0d\x7b\xed\x1a\xc6\xee\xb7\x61\x09\x52\x50\x39\x57\x92\xc2\xf7\x00\x4d\x21\x04\xd3\x4f\x3e\x3c\x9f\x74\x3b\x5d\xc1\x9a\xc1\xc7\xe3\xad\x8d\xc0\xb3\x46\x0d\xef\xd9\xfb\x81\xb5\x81\x6c\x0e\x81\x90\x8a\x6c\xbf\x64\x62\x2a\x3f\xaa\x50\xc0\x03\x80\xdf\x01\x05\x25\x8a\x62\xb6\x05\x9f\x6e\x45\xcc\x89\xe8\x0f\xb8\x88\x53\xb5\x8b\x84\x5e\xa7\xd5\xda\x1b\xa7\xc5\x6c\x1a\x9f\xd1\xbc\xa0\xaf\xd0\xac\x04\xd9\x56\x63\xc3\x7d\x7b\xf6\x01\xb3\x1f\x51\xb1\xba\x97\xaa\x50\x9b\x3d\xf9\xe1\xe5\x65\xf5\x00\xb6\x34\x87\x62\xe0\xb9\x9d\x8c\x9b\x78\xd6\x95\xe6\x71\xbd\xe8\xd6\x8f\x3a\x51\x4f\xab\xdf\xb7\xda\x07\xcc\xda\xf6\xd2\x9b\x59\x0b\xcd\x12\x8c\x26\x5b\x2c\x12\xe7\x6d\xaa\x7c\xd7\x05\x6b\xc6\x38\x0d\x11\xf2\x81\x1e\x28\x78\x19\x99\xe6\x31\xbc\x60\x51\x2a\xdf\xc0\x9a\xe0\x03\x78\x6d\x88\xa4\xea\x6d\xe2\x5a\xf4\xfa\xf9\xa3\xe7\xc3\xe8\x24\x7e\x87\x4a\xe0\x34\x7b\xbf\x98\x82\x1e\xf0\x70\x9a\x5c\xe5\x00\xcd\x69\xc0\x7d\x85\xbb\x21\xfe\x63\xd9\xbd\xde\x40\xf0\x48\x50\x05\x09\x4a\xec\xa0\x73\x36\x38\x2d\xac\xda\xc1\x1e\xf3\x4c\x20\xa3\x2c\x4c\xe8\x4c\x94\xfb\x84\xdc\x98\x9e\x08\x2e\xec\x30\xd9\x34\x0b\x33\xfb\x32\x7f\x44\x05\x96\x0c\x83\x69\xbb\x27\x24\x01\x4f\xd6\xd4\x18\xc8\xb1\xcc\xfd\x38\x97\x3a\x8c\x4c\x9b\x31\x36\xca\xb6\xc0\x13\xaf\x6a\x5c\xac\xae\xfa\x20\xab\x99\xd2\x87\xfa\xe3\x0b\x84\xd8\x96\x8a\x52\x7b\x47\xb1\x0e\xfb\x04\x30\x4d\x8a\xe2\xf5\x71\x9c\x75\xd7\xd1\x19\xec\x2d\xb2\x1e\x97\x56\xf8\x92\xb0\x36\x7a\x18\x86\x95\xe5\x58\x58\xdc\x5d\xa0\xbb\x65\x89\x4a\xb8\x29\x20\x07\x3a\xd2\xad\x68\x56\x8d\xd6\x81\xc4\xeb\x2e\x04\x56\x85\x22\x44\x43\xe9\xe4\xac\x90\x0e\xd1\x0b\xc1\x05\x05\x9f\x49\x06\x1e\x5d\xfd\x22\x8f\x0e\xf5\xb8\xff\x24\xf7\xa0\x06\xd2\xfa\x05\xf7\x79\x0b\xf9\x7a\xfd\x7e\x2b\x4d\xbe\x4e\xe2\x0f\x14\x7e\xf2\x43\x5a\x9e\x0d\xa3\xbb\xa8\xac\x56\xbb\x4e\x5a\x48\xd7\xc4\x58\xb4\xe7\x6e\x32\x6c\x92\xbb\x37\x01\x62\xc7\x2a\xca\xa7\xb3\xbe\xb0\x53\x56\x1a\xc4\xbd\x07\xda\x81\x53\x08\x21\xad\x13\xac\x02\xe3\x24\xfd\xf5\xd5\xf3\x67\x03\x8d\x65\x6a\xcf\x38\xa2\x44\x17\xb0\x60\x6a\xea\x44\xe5\x04\xdf\x8f\x45\x01\xbc\x0b\x7c\x34\x2d\x8e\x8e\xed\x15\xa0\x07\x22\x69\x0d\x6b\xf5\xaf\x21\x0d\x57\xbb\x85\xa7\xa5\x80\xcc\x5b\x39\x62\x05\x20\xdf\x68\xd8\xd5\x35\xd4\xf6\x5e\xd9\x81\xb2\x0a\xa2\xee\x8d\xb4\x10\xc7\xc2\xb4\x4c\x1c\xa4\x3b\xdd\x40\x09\x91\x75\xc2\x96\xb2\xfc\x8e\xb2\x75\xf1\x52\x6e\x2a\xb4\x0e\xe0\xbc\x94\x21\xf7\x8f\x7e\x4d\x5d\x86\x22\x76\x77\x8c\x3c\xfc\x7c\xf2\x46\xc0\x84\x76\x69\x2c\x0b\xb7\x49\xf4\x43\x2f\x38\xf1\xb3\xaf\xb5\x53\xda\xb1\x79\x30\xfa\xa2\xee\x87\xae\xa6\x4b\x9d\xe9\x61\x11\x74\xbf\x55\x44\xc9\x7c\x2e\xf6\xf5\x94\x41\x41\xb8\xdd\xbc\x14\x0d\xce\xe7\x82\xb8\x86\xa7\x7a\xdd\xd8\xbd\xb6\x16\x10\x14\x54\x24\xb0\xa0\x4c\xfc\x73\x1f\xff\x08\xb6\xf4\x24\x3f\x4d\xe6\x0f\xe3\x22\xe9\x22\x73\x01\x6d\xaf\xe1\x62\x00\xf3\x77\x79\x9b\x2c\xaf\x68\xf6\xe1\xef\x81\x39\x88\x33\x10\xee\xbd\x9b\x93\x1d\xf9\x13\x03\xe5\xd9\x63\x18\x53\xb7\x83\x57\xba\xd8\x0d\xb4\x38\xfd\x57\x47\x60\xec\x43\x5a\x94\x85\xa0\x6f\xb1\x76\x0b\x14\x8a\x71\xdc\x79\xa6\x11\x35\xc9\xa7\xd3\xfc\x14\xd4\x10\x50\xb2\x00\x1e\xe8\x2c\x22\xd9\xc3\x3e\x7a\x49\xe8\x9b\xc7\x1b\x35\xb1\x5e\x75\xef\xd1\x87\x95\xed\x1b\x58\x48\x09\xb8\x04\x89\xdd\xc3\xa5\xb3\x98\x25\xb0\x8a\xd5\x2c\x96\x08\x00\x18\x2d\x7a\x9c\x88\x04\xf2\x08\xad\x92\xaa\x5e\xe4\x45\x91\x1e\x4e\x69\xfa\xd0\x0d\x86\x34\xcc\x7b\xb5\x07\xb2\xa5\x20\x1a\xfc\x09\xe2\xb4\xc2\xd5\xe3\xc9\x24\x3d\x3a\x93\x1f\xcf\x15\x19\xdd\x8a\xe0\x7e\x4b\xbd\x05\x93\xd7\x52\x74\x38\xa5\xfb\x2b\x02\x3a\x31\x40\xf2\x12\x89\x3e\xd4\x85\x13\x41\x1d\x19\x28\x79\xcf\x23\x15\x19\x74\x2d\x64\x72\x08\xfc\xd6\x2d\xbd\x1c\xd5\x7d\x0d\x81\xc3\x0d\x8f\x95\x13\x80\xa7\xfb\x16\xea\x36\x5e\xd2\xf0\x1c\x82\xd7\xed\xb3\x82\xf4\xa9\x46\xae\x00\x22\x27\x9f\x7d\xe0\xa5\x89\x42\x42\x9c\xa9\x81\xaa\x5f\x25\xfd\x72\x45\x07\x5a\xff\xce\x09\xf7\x93\x96\xc7\x69\xe2\xc1\x7c\xe0\x30\xd1\x84\xd0\x57\xe9\x52\x27\x26\xd6\x68\x1c\xfd\xf2\xf8\x1f\x78\xd8\x56\x52\xd9\x85\x19\x87\xec\xb1\xb3\x43\xaa\xcd\x33\xfa\x15\xbc\xc7\xd1\xa6\x1e\xb3\xab\x59\x19\xb3\x84\xb9\xf6\x87\x75\x13\x17\xd1\x69\x02\x0b\xc1\xb8\x43\x51\x83\x58\x61\x32\x8f\x40\x02\x3f\x78\xab\x51\x72\xc6\x41\x02\x0d\xd4\xac\x98\x01\x17\x7d\xa8\x04\x8f\xf1\x6a\xf3\x23\xe2\x41\x8e\xd4\xc3\x37\x5b\x59\x81\xe0\x49\xbc\x91\x21\xff\x62\xf2\x53\xe3\x32\x8d\x09\xc3\x81\x95\x2a\x08\xff\x7a\x69\xfe\xf7\x2d\xe5\x32\xaf\x5b\xc8\x4c\xfe\xff\x62\x4b\x1a\xd6\xd5\x1f\xb6\xa8\x11\x1d\x57\xb3\xa4\xcb\xf4\x24\x29\xfe\x88\xe5\x9c\x49\x55\x21\x2c\x62\xad\x78\x2a\xe8\xd0\x8e\x5b\x6e\xa4\x6d\x50\x3c\x62\xd0\x3d\x69\x45\x12\x86\x08\xd4\x4d\x20\xcc\xbc\xa1\x04\xbb\x36\xed\x7b\xca\x54\x60\x00\x34\xfe\xb9\xd1\x8c\x33\x0b\x0d\xa7\x99\xdb\x20\xd2\xe1\x22\x57\xe5\xe5\x8f\x4f\x9f\xa2\xf5\x9d\xe0\x51\x45\xd6\xeb\x1d\x35\x28\xdd\x3a\xdf\x48\x9c\xab\x9e\x7c\xf3\x4d\x24\x7f\x87\x44\x78\x68\xd2\xcd\xe5\xe7\x85\x90\x67\x32\x4b\xb6\x92\x85\xb5\x86\x43\x94\xed\x77\x3a\xfc\xfa\xc4\xf1\x5f\x16\x2a\xcd\x09\xa5\x52\x46\xcb\x54\x44\xd7\x00\xa9\x68\x3a\x21\x30\x15\x5b\x43\x1f\xac\xa4\xb9\x23\xc2\x96\xa7\xb9\x38\x1e\x8f\x74\xa4\x15\x8f\xb4\xec\x40\x2c\x6d\xe8\xab\x82\x6a\xd8\x91\xd7\xae\xd3\x56\x5f\x59\x24\x03\xe0\x87\x8c\xb2\x42\x0b\x16\x1f\x62\x77\x99\x2f\x39\x5d\x80\x2b\x18\x1a\xa7\x6e\x75\x72\xc9\x7f\xab\x38\xcb\x83\xd9\x74\x51\x98\x2e\xc8\xef\xa0\xd3\x41\x0d\x64\xeb\x85\x20\xb6\x51\xa1\x0e\x41\xc4\x23\xd5\xa5\x65\x21\x9f\xb0\x4d\xcf\xd0\xbb\x6e\x30\x46\x30\x91\xfc\x28\x18\x1f\xd8\x8e\xf4\xcb\x1a\x80\xf5\x1f\x1b\x78\xee\x6e\x0e\x43\x1e\xc9\x99\x73\xea\xb6\x26\x4e\x56\xea\xe8\xc3\xd6\xfe\xbd\xfe\x01\x1e\xff\xc5\xb7\x26\x07\x1f\xb7\xd7\xcf\xff\x67\x2d\x1d\xc0\x7b\x02\x0d\xbe\xc4\xd8\x6b\x86\xfc\x79\x06\xdb\x62\x98\x78\x9a\x5f\xfb\x77\x77\xfd\x43\xef\x7e\xed\x38\x39\xfd\xad\xad\x99\x08\x56\x14\xa3\x0a\x7b\x47\x7e\x7d\xa5\xb1\xe0\x09\xbe\xae\x85\xdd\x98\x8d\xda\x51\x11\x90\x7b\x3e\xc7\x81\x44\xb0\x33\x76\x5f\x80\xcd\x31\xb0\xdd\x5b\x3f\x05\xc1\x9c\x2e\x63\x40\x2d\xc1\x2b\xc0\x05\x71\x71\x02\xff\x1f\xc5\xb3\x02\x25\x07\xf1\x5b\x7d\xf7\xb9\xdf\x44\x2f\x3e\x11\xba\x83\x67\x1d\xb6\x1a\x78\xae\xb7\x77\xc2\x4e\x12\x8f\x8e\x45\x9d\x85\x57\x4d\x5a\x10\xa1\x2c\x4e\xe4\x0c\x31\x6a\xa2\x55\xd6\x9e\xa6\xa8\x2d\x51\xd5\x09\xd8\xce\x56\x90\x97\xd3\xdc\x15\x93\x99\x53\x7b\x1d\xb9\x09\x2c\x3d\x0c\xe0\x26\xd6\x25\xe5\x2f\x6f\x07\x32\xfa\x0d\x09\xf1\x4b\x5c\xa0\x8b\x18\x30\x7f\x57\x85\x2c\xcd\x88\x92\x76\x42\x6e\x58\xb6\xd7\xf1\xc6\x31\x72\x28\x04\x5d\x6a\x97\xc7\xd1\x34\xc1\x47\xce\x2c\x2e\xde\x6c\x96\xcc\xa1\xb7\x6a\x1a\x32\x8c\x29\x78\x94\x52\xd8\x39\x91\x71\x12\xcf\x60\x3a\x36\x2c\xbd\x5d\x57\xdb\x23\xb0\x4e\xa3\xb3\x34\xc1\x68\xa3\x1f\xa3\xef\x60\x3b\x97\x59\x22\xd9\xd1\xec\xc0\x3d\x85\xc9\x44\xa2\xaf\xaf\xf0\x87\x7b\x81\x1a\xdf\x40\xd7\xc3\x35\x56\x78\xf0\x66\xcb\xd2\xb0\xfc\xf3\x70\x54\x04\x4d\xa5\x4f\xe5\x91\x4e\x51\x63\x05\x29\x96\xf9\x55\x92\xa2\xd2\x45\xeb\x7d\x79\x09\x4a\x64\x1a\x60\x4d\x7d\xee\x5b\x68\xd9\x0e\x04\xd2\xaa\x20\x53\x6b\xc6\x2e\x44\xb4\x0c\x68\xe4\xf5\x1e\x2a\xaa\x21\x63\xd5\x0b\x76\x89\x1e\x85\x98\xde\xb1\x31\xe4\x03\x22\xfd\x1e\x8e\x3d\x13\x72\x6b\xaf\xee\xd5\x34\x85\x5b\x04\xdc\x48\x6e\x36\x09\xb9\x03\xb8\x79\xaf\x92\x16\x19\x7d\xb9\x9c\xb4\xb1\xa6\x5a\xda\x74\x4a\xda\xa2\xa7\x11\x35\x2d\x3e\x2a\xd0\x21\x44\xca\x74\x1c\x6d\xae\x93\x2b\x9f\x5b\xf2\xbe\x8f\xda\xa4\x87\x05\xeb\x1f\x22\xb2\xd5\xf0\x6d\xb5\x1a\x49\x39\x7c\x79\x2f\x9d\x02\x70\xb6\xad\xed\xb1\xda\x11\xb0\x23\xcf\x04\x77\x3e\xe5\xeb\x56\xe1\x83\x09\xf1\xb4\xdd\xd6\xed\xb4\x81\x8a\xe4\x5d\x56\xa8\x3e\x1b\x42\x45\x44\xc4\x1b\x22\x5d\xa3\x79\x06\xd5\x17\x6c\x38\xac\xf4\x57\xfe\x81\xd5\x71\xba\x30\x62\x6e\x73\xec\xcb\xe0\x51\x32\x74\x93\x65\xc5\xc3\xa4\x9d\x2c\x74\x08\x8c\xba\xd4\x0d\x47\x30\x54\x7d\xcb\x8a\x32\xce\x46\xc0\x46\x4c\xe1\x4f\x9f\x34\xd2\x64\x61\x7c\x76\x86\xbf\x2c\x7f\x16\xc1\x54\x32\x75\x40\x66\xa4\xab\xec\xb4\x45\x94\x3a\x10\x37\x61\xe9\x95\x67\x2b\xd4\x12\x45\x81\xc8\x45\x41\xfc\x48\xde\x2a\x7b\x86\xa3\xa1\xbe\x75\xae\x0e\x3d\xd4\x97\xd6\xb8\x51\x3d\x6e\xc6\xce\x4f\xc6\x26\x64\x59\xfc\xa8\xa2\x17\xc2\x90\x2a\xd1\x69\x39\x22\xe8\x53\xd9\x3c\x1c\xea\xfa\x05\x06\xf3\x5c\xf6\xad\xe5\x62\x00\xf9\x02\xee\x71\x30\x78\x41\xd7\x5f\x21\x68\x87\x8d\x3a\x8c\xc0\xd8\xe5\xf5\x6b\xcb\x91\x1f\x52\x67\x5b\xb0\x03\x02\xbc\x08\x33\xa0\xa4\x0b\x4d\xa4\xec\x5e\xdb\xd1\xa0\x67\xbe\xe6\xb1\x78\x81\x2e\x5a\x8e\xc4\x0f\x56\xd1\x92\xa5\x61\xc1\xda\x31\xb8\xe7\x11\xda\x47\xd1\xe4\x2d\x57\x54\x67\x0f\xe2\xaf\x85\xbd\xae\x8c\x41\x41\xed\x18\xa0\x98\xfb\x08\x40\x7b\x34\x9a\x9f\xf9\xfb\xd4\x8d\x1b\x78\xd5\x48\xef\x67\x8d\x6d\xea\xb9\x10\xd7\x21\x1a\x53\x37\x09\xec\x6d\xfc\x44\xba\xcc\xa8\x63\x39\xee\xc7\x30\x81\x89\x90\x7e\x0f\xc1\xf9\x40\x74\x9c\xc4\xf0\x3a\x55\x7c\xe4\x27\x03\x9f\x32\x21\x39\x24\x34\x80\xad\x87\x72\x57\x8e\x65\xe1\x29\x6d\xe9\x6a\x33\x03\x04\x8b\xb0\x96\x57\x60\x48\xa9\x3d\xea\xdf\xde\xd8\x34\x67\x7d\x02\x6f\xc0\x4b\xf5\x49\x9d\xea\xe2\x55\x85\x0e\xea\x3e\x54\x28\x10\xb0\x39\x68\xd7\x3b\xf4\x6f\x3b\x33\xd3\x1c\xc4\xbc\x59\x3a\xf2\xe7\xe1\x35\x24\x37\xcd\x43\xa9\x81\x6a\x26\x00\x6b\xb2\x26\x00\x8b\xd5\x4e\xc0\x9d\x6d\x83\x7f\x82\xbe\x30\xfe\xb1\x2a\x5e\x53\x08\xfd\x1e\xd0\x85\xb0\x5f\xe1\x31\x44\x8a\x7c\x43\xf5\xa3\xaf\x52\xf1\x0d\xcf\x50\xff\xa2\x74\x14\x0c\x87\xf4\x47\xa5\x48\x83\x90\xa1\xf9\x49\x39\xcc\x58\x64\xc8\x3f\x54\x39\x30\xd0\x19\xca\xbf\x2a\x0d\xcd\x4f\x86\xea\x87\xa9\x87\x60\xd5\x2f\x93\x2e\xe1\xf5\x4f\x59\x8f\x6f\x43\x3b\x0c\x25\xf6\xa5\x3b\x2c\xd7\x34\x73\x18\x48\xb3\x60\x95\x15\xe4\xd0\x4d\x50\xe3\xf8\x35\xcd\x86\xf4\xc7\xf4\x15\xd3\xe4\x0f\x05\xa7\xa5\xc5\x21\xff\x50\xb9\xb6\xce\x7a\xe8\xa5\x68\xac\x91\xa0\x3e\x34\x3f\x29\x87\x49\xc7\x43\xfe\xa1\x72\xad\x93\xc8\xd0\x4d\x50\x50\x2c\xdf\xc9\x71\xce\xee\x43\x3f\x49\xf5\xd0\x83\xf4\x92\x54\x9d\x4a\x18\x1b\xb2\xdf\xbc\xbf\xd9\xd1\x50\xff\x52\xe9\xb4\xa7\x0e\xf5\x2f\x3d\x7a\x5a\xef\x43\xf3\x53\x8f\x09\x76\xc9\xa1\xfa\xa1\x52\x61\xc3\x1a\xca\xbf\xba\x0e\xe0\x77\x43\xf5\x43\xa5\x22\xdb\x18\xaa\x1f\x7d\x5c\x60\xe4\x37\x4e\x3e\xc7\x5e\x1d\x6e\x7c\xdf\xaf\x75\x4c\xd3\x5f\x85\x97\x18\xab\xc3\xbb\xb7\xc1\x55\xc3\x46\x1b\x57\x0d\xf6\x12\xbe\x47\x0b\x78\x55\x7a\x28\x58\x85\x07\x70\x83\xcd\xf5\xc1\xc6\x2a\xb8\x68\x23\x0f\x6d\x9b\x9b\xd7\x2e\x20\xae\x5d\x40\x7c\x2d\x2e\x20\x64\x2d\x01\x17\x6d\x7f\x4d\x26\x82\x5e\xcf\xa2\x5f\xd3\xe9\xe8\x9d\xa8\xf8\x37\xf1\xed\xfa\x81\x68\xe9\xc8\x0d\xc1\x52\x41\xe9\xcf\x41\xe2\x8e\x11\x4a\x7c\xfa\x60\x3f\xc5\x87\x00\xf6\xf7\xfc\x48\x6c\xff\x65\x32\x9d\x0a\x6c\xfc\x30\xc1\x44\x1f\xf8\xe7\xf8\x7d\xf4\x6b\x2e\x84\x9b\x1f\x8e\x2a\xfd\x53\x6c\x1b\xbf\x3c\xd2\x45\xe3\xd3\x38\x8b\x8f\x6c\xa7\x11\x83\x35\xc0\xc2\xda\x9c\x00\x4e\x08\x40\x39\x87\xd8\x3b\xc4\xc3\x91\x0b\x9c\x8a\x64\x05\xf2\x18\xad\xf2\x5d\x08\x92\xbc\x8a\x35\xf1\x47\x01\xfe\x1a\xac\x4a\x01\x9e\x9a\x1a\x1f\x3d\xa8\x81\x1b\x1f\x6a\x7f\xb1\xc7\x75\x0d\x17\xc7\xba\xe1\x67\xe8\x59\xbc\x0a\x30\x4b\x4a\x05\xf8\x42\xac\x5b\x7c\x42\x55\x0d\x3d\x93\x20\xba\x13\x62\xad\x9d\xd4\x75\x03\xf2\x35\x70\x52\x96\x18\x75\xc9\x87\x2f\x64\x96\x02\x55\xec\xc7\x82\x54\x7c\x05\x8e\x5e\xc6\x7d\x87\x58\x6c\x36\x18\x0b\x0b\x5f\xed\x05\x84\x80\x94\xbf\x25\xf9\x60\x23\xc9\xc6\x81\xbe\x51\x86\x02\x7b\x80\x47\x2b\x1f\xea\x10\xd2\x35\x26\xe7\x82\xad\xcd\xcb\xb3\x00\xdc\x4c\x66\x29\x50\x70\x20\x2a\xc0\xc1\x46\x2d\x44\x97\xb0\xa2\x67\x32\x5b\x53\xe5\x6c\x54\x53\x22\x9d\x8d\xdc\x02\xed\x3c\x12\xae\xac\x68\xa1\xfe\x57\x51\x11\xbc\xdb\xa3\x6a\x6c\xaf\xba\x73\x77\x29\xc1\x1d\xbd\xbd\xbe\x4c\x49\xe6\x60\x57\x86\x4a\x65\x3d\x57\x50\x0c\x28\xa1\x37\x2e\x60\x93\x5b\x1e\xa3\xa3\x7d\x5e\xc5\x29\x2e\x21\xc8\x15\x8b\xc9\xcb\x1d\x1f\xca\xcc\x47\x0f\xbc\xbc\xe2\x58\xd5\x2b\x96\x8d\x97\x9b\xe1\x0a\x81\x5c\xb1\x56\xbc\xdc\x99\x59\x16\x00\xa2\x56\x89\x07\x77\xf8\xfb\xef\xaa\x0d\xa0\x7a\xbf\x0f\x86\xfc\x11\x48\x7e\x76\x39\x8c\xa1\x7b\x73\x62\x89\x67\x42\x62\x57\xb4\x2f\xff\xd2\xc9\x85\x77\x52\xe2\xb2\xb0\x8a\x72\xe2\x1a\x5a\x5f\x7d\x0d\xc3\xa8\x69\xc8\x3f\xbc\x26\xde\xea\x15\x42\x3f\xbc\x01\x4a\x80\xae\x69\x5f\x12\x7b\x9a\x08\x09\xd6\xfc\xee\xf6\x56\x54\x0c\xab\x73\xf4\xf3\x0b\x92\x55\x91\x44\x66\x0c\x39\x6d\xe8\xe8\x13\x68\x05\x88\x31\x30\xbe\xa6\xb1\x55\x8e\x0b\x64\x4f\x55\xa7\x8a\x96\x99\x70\xba\xe4\x11\x81\x9b\x57\x00\x2f\xbc\x34\xe1\x07\xfa\x02\xef\x5f\x6c\x7d\xe4\xbb\x24\x99\xed\x15\xaf\xce\xb2\x91\xa0\x97\xda\xae\x60\x59\x07\xbe\x1d\x05\x06\x3a\xc2\xb9\xc6\x03\x63\x07\xe3\x40\xa9\xc7\x31\xe4\x4e\x0a\xbf\x02\x30\xea\x21\xad\x82\xc2\xef\x00\x9c\x7c\x8d\xad\xc0\xe0\xd3\x83\xe2\x0f\x73\x09\x50\xa7\x04\x61\xad\x3a\x55\x42\xa0\x6d\xf3\xca\x4a\x35\x2f\x53\x82\xb5\xba\xd0\x2c\x2d\x50\x37\xc7\xa5\xaa\xbd\x0e\xa7\x64\x2a\xa9\x20\xc5\x57\xb0\x0f\x1c\x4a\x7e\x7b\x70\xdc\x44\x86\x20\x75\x4a\x00\xd6\xbf\x5d\x56\x65\x1e\xba\x97\x8e\x5e\x9f\xab\xca\x7a\x39\x81\x76\xf7\x1e\xec\x3e\x63\x8d\xc1\xa7\x07\x65\x9c\xd6\x10\x98\xfc\x0e\xc1\x29\x7f\x2a\x1a\x10\x13\x60\x8f\x73\x17\x3e\x09\x06\xfc\x7d\x37\xdd\x2d\xd9\xde\x15\xed\x5b\x3f\xbc\xdb\x76\x21\xab\x6e\x61\xc7\xe9\x1c\x35\xce\xa3\x78\x86\xaf\x32\xd8\x3d\x68\x60\x46\xf7\x1e\xee\xbe\xb0\xd6\x3e\x94\xe3\x96\x76\xe9\xa1\xde\x03\x41\x38\xa5\x7c\xeb\x09\x28\x40\x0c\x64\x33\x6a\x65\xa3\x9b\x19\xcd\x7f\xeb\xe2\x89\xee\x6b\xde\x8d\x7b\x9d\x14\x56\xba\xf6\xd5\x75\x86\xaa\x9c\x8e\xda\x93\xb2\x7c\x9c\x74\xfa\x16\xc4\x11\x9a\x97\xc0\xc3\x1d\x31\xc6\xb7\x23\x71\x2c\xcb\xca\xbf\x13\x78\x87\x45\x51\xe9\x5f\xa4\xb5\xa4\x3c\xcd\xe7\xef\xaa\x1a\x14\xd9\x6f\x25\xa8\x03\x62\x87\x03\x18\xba\xab\xfc\x92\xdd\x4a\xa4\x5e\xbc\xaa\x5f\x22\xff\x2d\xce\xf5\x28\x9f\xfe\xfd\x0f\xe8\xdf\xa9\xd8\x00\x04\x58\x55\xf7\x84\x80\x74\x69\xb4\xe1\xcf\x83\xe0\x5e\x22\xf8\x47\x9e\x65\xe4\xc9\x89\x2d\xb7\x9e\x45\x7b\xdd\xe0\x76\x09\x57\x69\x81\x74\x5e\x65\xb7\x17\xde\xc1\xc8\x77\x81\x92\xd8\x2b\x69\x1e\x8d\x4c\x31\x2e\x82\x94\x68\x82\xea\x5f\xa8\x5b\x79\x13\xe5\xe7\x07\xd2\x5c\x59\xc7\x8e\xd5\xe1\xe6\x3a\x24\xf1\x03\x8b\x48\xdb\x80\x34\x73\x58\x10\x29\xdb\x3a\x85\x44\x27\x91\x74\x57\x27\x71\xd1\x7f\x75\xb8\xb5\xa9\x33\x60\x85\x8b\x84\x2d\x93\x60\x44\x7e\x91\x6e\x2a\x35\x87\x46\x91\xfc\x9d\x97\x2c\xfe\x88\xf4\xbb\x5e\xfa\x29\x54\x7f\xd7\x87\x17\x4b\x4b\xc0\x7f\xef\xa5\x2b\x01\x79\x75\xb8\xbd\xee\x65\x0a\xc2\x12\xe9\x1b\x7e\x3a\xc8\xc8\x22\xc7\x0c\x4b\x9d\x8c\x44\xe2\x6d\x9d\x68\x9f\xcb\x45\xd6\x1d\x9d\xa5\xa4\x19\x91\xf8\x5d\xbf\xc9\xa7\xf5\xe6\xd6\xb5\x62\xef\x5a\xb1\xf7\xb5\x28\xf6\xc0\x2d\x08\xb8\xa3\xb8\x9c\x7f\x57\xa6\x3f\xf3\x14\x28\x21\x0d\x8a\x0a\x0e\xf3\xf8\x3d\xbd\x18\x60\x9a\x09\xec\x8d\x82\x33\xa1\x62\x9a\x62\x22\xf9\xda\x9d\xa0\x7a\xe7\x27\xbc\x3b\x76\x2a\xc3\x34\x05\x71\x4a\x11\x8f\x6c\x10\xc3\xef\x30\x53\x7b\x7d\xdd\x9d\x4e\xad\xa1\xd8\x02\x93\x7d\xe4\xea\xa3\x21\x40\x3f\x72\x2c\xd7\x2a\x54\x25\x76\x02\x3f\x3a\xff\x46\x77\xff\xf0\x87\x27\x7b\xe6\x98\xb4\x39\x79\x3d\xac\x0f\xee\xed\x48\xb3\xca\x4f\xf8\x3d\xfd\xeb\xd3\x27\x8c\x7a\xe3\x79\x89\x80\x44\x4c\xdd\xef\x80\x74\x8a\xf1\x06\xc0\x21\x88\x38\xbc\xe0\xcf\x83\x01\xa1\x99\x45\x39\x9c\x04\x2e\x5b\x65\x33\xfb\x93\x03\xb4\x9f\xd1\xf6\xa7\x3b\xca\x65\xb7\x1f\x2a\xdb\xa9\x06\xfa\x03\xc5\xef\x31\xe2\x21\x87\x3b\xd8\x11\x3a\x47\x99\xd0\x77\xa6\x41\x65\x12\x61\x1e\x86\xa1\xe7\x0a\x83\x57\x0b\xcf\xf6\xeb\x31\xee\xcb\xa2\x0e\xe3\x81\x58\xc5\x62\x09\xc5\x6a\x04\xf0\x7b\x00\xff\x13\x10\xe6\xb7\x40\x79\x47\x3e\x69\xc7\x44\xbc\xa9\x2e\x14\x88\xfc\x12\x40\x3a\xe6\x26\xea\x28\xa1\x69\x75\x05\xcf\x00\xf7\xd7\x0f\x06\x05\x30\x04\xed\x7a\x1d\xa0\x4f\xa4\xe0\x63\x28\xcc\x9f\xae\xdf\x02\xd3\xc5\x5b\xb9\x27\x4d\x79\xa5\xd4\xe7\xdf\x09\x8b\x1a\x34\x8e\x45\x37\xd8\xc3\xae\x1b\xd8\xbe\xa8\x1f\x1f\x53\xc0\x4b\xd1\x12\x9e\x68\x8e\xa9\x5f\x60\xbc\x80\x0d\xdd\xd7\xcd\x83\x05\x5c\x2e\x64\x2e\xc1\xe6\xb3\x6e\x87\x97\x20\xdf\x3a\xc0\x8a\xa7\xf9\x51\x87\x59\xd6\xca\x1e\x03\x2a\x3c\xff\x4c\x89\x64\x43\x3c\x1e\x17\xce\x5d\x37\xb4\x3a\xfb\xd4\xad\xbe\x4d\x10\xf6\xd1\x0a\x6b\x54\xae\x11\x71\x8a\x5b\x2c\xc7\x0f\xc9\x08\x24\x80\xc0\x7a\xec\xa3\xff\xa6\xc3\x78\xf4\x4e\x47\x0e\x45\xd7\x05\xf2\x10\xac\x6e\x73\xbb\xf1\xfc\x68\x81\x6f\x4d\xf6\xf5\x2f\xe6\xbb\xc7\x36\x72\x57\x35\x62\xc4\xe7\xda\x62\xdc\xcb\x5c\xd7\x83\x80\x89\xdf\xb0\xbd\x5e\x18\xb6\x01\x06\x70\x1e\xba\x73\x45\x69\xd2\x57\x9e\x39\x18\x2b\x88\x09\x8b\xad\x46\x1a\x5b\xc4\xe4\xe0\x30\xb5\x95\x89\x4c\x2c\x27\xce\xde\xcd\xfb\x78\x80\xea\xf4\xa3\xf0\xd4\x49\x0e\x0e\x87\xb0\x6e\xaf\x6f\x1a\x62\x18\x6e\x9e\x29\x51\x42\xf0\xa0\xd7\xf9\x43\xe5\x36\x8b\x4f\x99\xf2\xa5\xc5\xcf\xe2\x66\x6a\x69\xdc\xf4\xe9\x0d\x47\x15\x1d\x88\xae\xe8\xfd\x44\x02\x57\x9c\x35\xbc\x6e\xea\x83\x47\xe0\xe4\x11\x3c\x7a\x20\xa9\x8a\x0f\x94\xe2\x69\xd5\x8b\x2f\x94\xdd\x79\xa4\xb6\x2d\x04\x76\x36\x42\x21\x50\x6f\x81\xc8\xbc\x7d\x2d\x32\x5f\x8b\xcc\x5f\x4d\x18\x18\x3c\x93\x5f\x55\x1c\x18\xb0\x88\x99\xcf\x46\xb6\xbc\xf9\x1b\x25\xea\x0b\x47\xf0\x3a\xe3\x88\xa4\x94\xa6\x25\x51\x5f\x75\x01\x83\xb5\x84\x4c\x4f\xc6\x44\x74\xbc\xad\x94\x34\x65\x46\x21\xc3\x79\x37\xb8\x15\x14\xdb\xa0\xf2\x00\x09\xec\x58\x16\x46\xa7\xda\xd8\x35\x99\xe0\x18\x1c\x8a\x52\x55\x7e\x1e\x01\x29\x58\xa8\x34\xc6\xb3\x6b\x2b\x38\x4e\xce\x8a\xc7\xe3\x90\xcc\x1d\x1a\x78\x41\xb1\xc0\x15\x44\xe3\x88\x14\xd3\xae\xeb\xbf\x84\x09\x76\x2d\xb4\x9f\xdb\xde\x0b\x34\x7e\xad\x6e\xee\x54\x4d\xd9\xa0\x48\xb2\x31\xa9\x9f\x14\x6c\x9f\xd5\x2d\x28\xa2\x2f\x25\xd1\xc2\x97\x89\x89\x7c\xe8\x97\x14\x16\x0d\x80\xc4\x0f\x77\x48\xa9\x51\x09\x4f\xcb\xc7\xe4\x00\xb9\x32\x0c\x7a\xb1\x8f\x30\x07\xfc\x78\x50\x29\xb0\x53\x91\x8a\x6a\x41\xac\x51\xfd\x91\x95\x0e\x3c\xd9\xc7\x2d\x70\x43\x2e\x22\x21\x0a\xfd\x1d\x0c\x5c\x5f\x26\xc5\x4c\x08\x14\x89\x6c\xaa\x17\x0a\x0f\x6f\x8d\x21\xdc\x5e\x97\xd6\xd8\x60\x2f\x7b\x1f\xac\x75\xc7\xab\xf4\x7c\x25\x10\x86\xbe\xa2\x72\xf2\xf1\xe4\x0d\x96\xf6\x5c\xf4\x3d\x11\xca\x90\x8d\x0f\x64\x1f\xd0\x2b\x05\x4f\xf0\x62\xd7\x9b\xa9\xd0\xe7\x9b\x28\x2c\x4a\x20\x65\x19\xbd\x25\x0d\x5e\x88\x02\xa8\x47\x93\x2b\x52\xc8\x01\x68\x54\x77\xfb\x7a\xc3\xbf\xde\xf0\xbf\x16\x1d\x99\x16\xcb\xaf\x48\x45\xd6\x52\x57\x05\x27\x9e\xb9\x03\x56\x48\x59\x7f\x0d\x33\x97\x55\xa7\x29\x38\xf6\x2c\x8b\xeb\xc1\xd4\x7b\x2b\x09\xbd\xcb\x0e\x21\x0c\x4c\x6b\x0c\xad\x40\xe3\xa1\xfd\x93\xd4\x4b\xfc\x0d\x1b\x6e\xf3\xee\x33\x68\x82\x7b\x68\xc0\x5e\x28\x28\x2d\x17\xa0\x2d\xf1\x79\xa4\x3c\x3f\xdb\xa9\xc1\x06\xa4\x6f\xe0\x70\xd5\xf6\x4b\xd2\xc3\xb4\x2f\xcb\x39\xb7\x3d\x22\x27\xa0\x06\x61\x5e\xa2\x5d\xb7\xcc\x14\xd6\x1b\x9e\x3c\xb1\x3e\x75\xe0\x5e\x8a\xbb\x7d\x66\xe7\x66\x6a\xd6\x8a\x0d\x20\xb6\x4c\x7b\x1b\xae\x6b\x30\xe0\x48\x1a\x5c\x31\x8a\xc4\x8a\xed\x1a\xf3\xc8\xbf\x74\x60\x67\x70\x2a\x02\xb0\x22\xd0\x34\xd2\x9e\x3c\xbc\x13\x42\x09\x54\xa3\x91\x69\x79\x94\xf6\xaa\x85\xf4\xa8\x03\x7d\x49\x47\x54\x74\xb4\x0e\x3e\x9c\x12\xe0\x8a\x86\x0b\x73\x0c\xe7\xb4\xa1\xd2\xce\x75\x4d\x95\xf4\x44\xa4\x12\xae\xb2\xf6\x64\x6f\x8e\xeb\x17\x27\x14\xf6\xc8\x71\x99\xd9\xe7\x9a\x2a\xad\xfd\xd1\xfd\x79\x2b\xb0\xa4\xd4\xcd\xa6\x93\xb6\x57\x8d\x46\x55\xea\xc4\x53\x73\x18\x04\x18\x55\x69\x8b\x79\x25\x6e\xd1\x6a\x52\x89\xdf\x5c\xdd\x8c\xba\xf5\x35\x2b\x6a\x14\xc3\xbb\x8a\xb9\x25\xde\xeb\xa8\x95\x25\x67\x95\xda\x46\xcd\x63\xed\xc9\xf3\xf5\x56\xd4\xb1\xca\xe9\x14\xf5\xd4\x4e\x17\x3d\xb1\x21\x3d\xd6\xd2\x04\x46\xaa\xd0\x86\x0e\x2e\x4f\x6d\xd6\x9b\xc3\xa4\x35\xb5\xd5\x91\x9b\x76\xc4\x83\x36\x81\x6a\xd2\x8f\x12\x31\xa5\x74\x67\x3c\x85\x00\x4c\xb3\x69\x7e\x26\xe0\xa5\xe9\x20\xbe\x4e\x1c\x1d\xc7\xc6\xb5\xa2\x22\x48\xac\xed\xa7\x7c\xae\x7a\x14\x70\x6d\xa0\x0e\xac\x21\x92\x54\xeb\xf2\x5c\xab\x16\x6d\x87\xba\x6f\xd4\xe5\x86\x79\x35\x57\xd1\x22\x37\x84\x50\x2d\x31\x85\xa5\x21\x5f\x0c\xe4\x8c\x71\x67\x3c\x35\xef\x4f\x18\x9b\xfc\x9e\x7a\xb7\xa6\x1c\xd0\x9c\xc6\x29\xa9\xcb\x51\xe4\x9a\x95\x46\x05\xab\x6e\xca\xec\x79\x97\x4b\x41\x07\x92\x36\x1d\x4b\x7a\xb6\x9f\xe6\x1b\x09\xbe\x7c\xb6\xda\x77\x77\x25\xec\xef\xcd\x9b\x3b\xf6\xb1\x49\x74\xab\x28\xf3\x19\xe9\x6a\x41\xfb\x1f\x4f\xa0\x2b\xb7\xd7\x69\xae\x0a\xb1\x33\xa5\x27\x89\x90\x69\x7b\xde\xd1\x91\x10\xf0\xa3\x80\x0d\x1e\x16\xa9\xf7\x03\xa8\xfd\x57\x59\xb9\x89\xc0\x20\x4a\x9c\x07\xce\x74\x2e\x02\xe9\x3d\x60\xf0\x1c\xaa\x67\x24\x78\xda\x34\x27\x3f\xe3\x48\x58\x33\x26\xbc\x2f\x89\x69\x65\x8c\x80\xb0\xd1\xa9\xf0\x21\x48\xcc\xe2\xd0\xe1\x62\xa0\x13\x3a\x7c\x26\x53\xe6\xb0\x84\xff\xf3\xdc\x17\x5f\xb8\x55\x58\x7e\xd6\xf1\x52\xb9\x2b\xf1\xb1\x66\x51\x8d\x58\x81\xaf\x4d\x53\x2f\x89\xd4\x0c\x47\x61\xdd\x00\xa7\x2a\x9c\xa8\xfa\x8a\x30\x7b\xe1\x23\x7c\x3a\xe9\x4a\x80\x30\xb5\x05\x0b\x05\x3b\x88\x01\x8f\x64\x5d\x83\x91\xbd\x00\xed\x1e\x61\x54\xa4\x8f\x95\x35\xd7\xf9\x7a\xbf\xa3\x7d\xbd\x57\xfd\x13\x03\xb2\x29\x4e\x08\x50\x37\xb0\xc5\xda\x62\x86\x75\xef\xd4\xd7\x7f\x91\x25\x70\x81\xe5\xe0\x0e\x09\x6e\x6f\x74\xe8\xe8\xad\xda\x99\xe1\x8e\xd5\xe4\x3d\xdf\x34\x3f\x62\x54\x8b\xc7\x0a\xc5\xc6\x6f\x74\x7a\x3b\xcd\x75\xb9\xfb\x12\x1e\x69\x82\x53\xbd\xd3\xaa\x63\xb4\x59\x29\x71\x01\x1c\x33\xca\x9b\x91\x28\x3e\x8a\xc1\xcb\x36\xae\x9c\xd3\x44\xc6\x03\x6c\xf8\x17\x96\x3d\xdd\x0d\xb0\xa1\x06\x57\xd6\xf1\xb6\xff\x26\x5c\x93\xd3\x37\x79\x29\x08\x5b\x02\xec\x8e\x45\x22\xaa\x11\x7b\x6b\x7a\xd2\x3c\x0c\x46\xba\xcd\xc4\x6a\x73\x44\xd4\x85\xb5\xed\x70\xd0\x49\xd2\x15\x77\x88\xfb\x7a\x67\xa2\x84\x3c\xd1\x32\x4e\x29\x16\x92\x58\x08\xda\xef\x3b\x49\x20\xa8\xf1\x39\x8a\x05\x35\x9c\xc0\x86\x34\x08\xf2\xeb\xd6\xfc\xbb\x91\x9f\x5f\x4d\x17\xeb\x7b\x78\x5e\x99\x5b\xc5\x31\xfc\x12\xe7\xd5\xda\xc9\xb6\x07\x0a\x76\xc5\xa0\x08\xfd\xa7\x18\x8e\xd9\x67\xda\x55\x87\x23\xc5\x39\x60\x0d\x87\x06\xb7\x52\x7e\x60\xc0\x53\x95\x00\xb6\x2e\x17\xe8\x1d\x52\xa2\x8e\xef\x98\x26\x60\xe0\x56\xfd\x30\x55\x72\xd6\xb7\xa4\x09\x6d\x1e\x93\x39\x21\x81\x2f\x65\x23\x9a\xca\xe2\xaa\x16\x35\x36\x51\x44\x27\xa9\x31\x65\x67\xe7\x9c\x18\x1c\xdd\xc8\x86\xb2\x29\x88\xa7\xbd\x25\x8b\x6e\xd6\x14\x75\xe5\x60\x4b\xb2\x90\xae\x54\x94\xa2\x78\xec\x95\x0e\x49\xca\x12\x42\x4b\xcb\xe6\xf8\xa7\x92\x74\x5b\x66\xe2\xb1\xd0\x1a\x7f\xae\x75\x1a\xbc\x7e\xa9\xdb\xa2\xbf\x53\x35\xc0\x3f\x27\x06\x9b\x3e\x45\x72\xfa\xeb\x2a\x3a\xe8\xeb\xd9\xe7\x1c\x4c\xb0\x56\x74\xbb\x62\xac\x2c\xac\x4a\x8d\xad\xc4\xc7\xf3\x1d\x00\x56\x58\xba\x61\xb7\x2d\x11\xc3\x2a\xc6\x33\xee\x1c\xdf\xe8\x20\x21\xf3\xc7\x0a\xa0\xce\xc4\xab\x06\x63\xaf\xe1\x04\x0b\x0c\xd9\x8e\xa0\xfd\x47\xb5\xc5\x88\x6f\x35\x52\x0c\x66\xf9\xcc\xf2\x5f\x67\x77\x6f\x1a\x17\xa5\x84\xf4\xaa\x0e\x77\x87\x08\xa9\x0b\x05\xd1\x37\xd2\x0d\xfd\x12\x05\xc3\xd6\x62\xba\xdb\x27\x83\xc2\x86\x2e\x41\x43\x12\x78\x40\x31\x4c\x7e\x8c\xd6\xdd\xda\xe4\x4c\x2b\xda\xdf\x55\x6b\xb9\x59\x0b\x70\x25\x2a\x41\x86\x26\x87\x59\x2a\x75\x9a\x36\xb5\xb3\x4e\x5b\x76\x97\x07\xb3\xf8\x0c\xa2\x82\x85\xba\xe7\x1f\x07\xc8\x7e\x0a\x6e\x17\x4d\xfc\xaa\x2c\xcf\x6e\xc9\x4a\x38\x3a\x3a\x35\x2c\xdc\x9a\x7a\x74\x1d\x08\x9c\x31\xac\x82\xed\xaa\xa5\xd2\xc7\x11\xb3\x5a\xd5\x04\xa1\xed\xdb\xcd\x7b\x54\xd1\x8e\x3d\x89\x95\x37\x82\xfc\x44\x6b\xe9\x00\x94\xdc\x17\x8f\x33\x57\x4b\x20\xb6\xcb\xd3\xb8\xd0\x02\xe5\x8a\x8d\x2b\x5a\xda\x78\xf5\xca\x8e\x30\xc6\x30\xcb\xb9\x7f\x3d\x16\xe7\x97\x10\xd2\xa1\xd7\x02\xa0\xea\x26\x92\x5f\x39\x86\xee\x15\xeb\x24\x1e\x10\x47\xc7\x63\xba\xf6\x62\x5c\x17\x7a\xb2\x52\x2b\x0b\x3b\xc7\x2e\x08\x54\x2b\xfe\x54\x08\x5f\x95\x12\xe2\x24\x9d\xc3\x3d\x7f\x95\x80\xb8\xa4\x8c\x57\xa1\x01\x09\xa9\x3d\x42\xd7\xaf\x55\xd2\x46\x10\x81\x18\x97\x93\x06\xde\x34\xcf\x4e\x63\x6d\x51\xde\x88\xea\x65\x86\x1e\xe6\x69\x5a\x27\x4f\x40\x3b\xea\x31\xae\xb5\x09\xc2\xf9\xf6\x11\xd9\x95\x62\x92\xfc\xb4\x0c\xd0\x2e\x2c\x78\xb9\x5a\xb3\x26\xed\x6c\x60\x53\x97\x35\x9d\xdb\x32\xd0\x44\xd7\xbf\xb6\xb2\xb6\xe6\xec\xc0\xd6\x05\x8e\xf1\xa8\xcc\xd4\x97\x4e\xe5\x5d\xda\x97\xd7\xd6\x2c\x57\xbd\x95\x51\xaa\x47\x23\xf4\xba\x9b\x53\x60\x27\xd0\x42\x55\xcb\x66\xb6\x1a\xdb\x1e\x39\x4d\xe2\xb9\xcf\x89\xb8\x38\x54\x27\x0a\x45\x1f\x99\xd4\xd5\x56\x22\x82\xdb\x4e\x53\x03\xb0\x37\x88\xbb\x26\x0e\x01\x50\x85\xf1\xac\x07\x10\x05\x5b\xd2\x36\x2f\xd3\x84\x87\x33\xe0\xa0\xd3\x32\x87\x0c\xb9\x66\xb5\x6d\x56\x97\x97\xa1\x8c\xa5\xbc\xd1\x56\x96\xc7\xcc\x73\x25\x06\xbd\xc1\x80\x62\xca\xa8\x40\x77\xa9\xaf\x0a\x1c\x26\xa3\x18\x42\x71\x89\x93\xf8\x58\x34\x05\x91\x4c\xd0\x26\xa9\x98\xe5\xe9\x94\x6e\xc3\x33\xb1\xa9\x4f\xe2\x91\xf6\xbd\xdd\xe2\x24\xde\xe6\xb4\xed\x6e\x53\xcd\xfc\xd0\xf7\xde\xab\xd7\x34\x5b\x9b\x3f\x83\x32\x1b\x3a\x0e\xfb\x63\x3f\x3a\x3d\x4e\x47\xc7\x68\x34\x00\xcb\x5b\x8c\x8c\xb6\xb1\x08\x02\x15\x34\x5f\xbd\x4a\x3e\xd0\x30\xbf\x86\x79\x84\x0d\x99\x1a\x44\xd8\xe5\xe5\xd4\x95\xf6\xf2\xe3\x65\x64\xc7\x6a\xb9\x91\x19\x2b\x5f\x48\x8e\xa9\x93\x61\xec\x97\x0e\x03\x32\x48\x6f\xcf\x7c\x03\xa7\x9e\xe0\x71\xb7\xc5\xf5\x79\x15\x6b\xf2\x0e\xc3\xc1\x53\x70\xc5\x4b\x96\xd0\x79\xd8\xdf\xfd\x8c\x5d\x38\xe1\xcf\x7f\xbc\x12\x3a\x4c\x07\xcd\x96\x1c\xba\xdd\xd1\xe6\xcf\xb6\xad\xc4\xea\xf0\xbb\x2a\x0b\x68\x6d\xd1\x20\x12\xb6\x7c\x93\x68\x39\xf2\xd5\xe1\xd6\x06\x98\x3e\xdd\xb9\x36\x7d\xba\x36\x7d\xfa\x6a\x6c\x9d\xa5\x09\xe4\x15\x18\x3b\x57\x78\xc9\x94\xc6\x95\xf4\x2e\xeb\xf9\x44\xdd\x39\xef\x0a\x66\x3a\xac\xd0\xdc\x30\x19\x4f\x9e\x60\x65\x25\x3c\x86\x9e\xda\x4e\x80\x7d\xaa\x4a\x23\x6e\xe2\x84\x7c\xbe\x1f\xea\xc3\xcb\x17\x0f\x89\xb9\x5f\xa6\x03\x14\xcf\x09\x59\x6d\x82\x0f\x18\x45\x3f\x44\xc5\xf2\x9e\x20\xdc\x01\xf9\x4c\x1d\x7d\x34\x9a\x96\x8f\xf3\x82\xdf\x7e\xf9\x8d\x3f\x7c\xfe\xec\xd9\xe3\x87\xaf\xf7\x9e\x3f\x8b\x1e\xbf\x7c\xf9\xfc\xe5\x50\xf0\x44\xa5\xfe\x1d\x51\x95\x74\xa2\x17\x27\xdd\xce\xcd\x08\xea\x8b\x6e\x76\x06\xe1\x3e\x18\x4f\x38\x6d\xc7\xae\xdf\xdf\x93\x12\xa1\x84\xd5\x93\x52\x65\xe1\x26\x94\x21\xed\x30\x72\x8d\x82\xd9\x30\xf1\x59\x9a\x20\xf8\xf8\x08\x2c\xa8\x6f\xdc\x90\x2f\x0d\x61\x5b\x97\xbf\x07\x14\x60\xd6\x4b\x19\xa8\x62\xf7\xa3\x60\xf2\x30\xd2\xd3\x05\x4e\x91\x71\x56\xe6\xba\x4b\x81\xb0\xac\xb2\x6f\x3b\xd5\x38\x90\x55\xdb\xa3\x35\xb3\xf9\x9a\xae\xab\xf9\x78\x4f\x8a\xb6\x53\xfa\x7a\xef\xe9\x63\xc1\xda\x86\x91\xbc\xf4\x06\xe2\x82\x4e\x0a\x26\x7f\x53\xfc\x10\x7f\xe2\xd1\x31\x70\x8c\x8e\x15\x30\x47\x7a\xa9\xfc\xee\x7a\xb7\xba\xde\xad\xbe\x16\x43\x5d\x7a\x75\xf9\x67\xb5\xd2\x6d\xff\x98\xbd\xd5\x1b\xfa\x2b\x7c\xca\xae\x7c\x11\xc1\xfa\xd7\x87\x33\x1e\xf1\xa9\xb0\x8e\x21\xf2\x8d\x2d\xb6\x65\x0e\x0b\xae\x11\xf2\xe7\x7e\x07\x3f\x53\xa6\xbc\x4c\x91\xce\xf3\x29\x32\x46\x54\xf3\x1c\x19\xd4\xf7\x0d\x4f\xe8\x59\x66\x96\x67\x67\x27\xf9\x42\xb7\xa8\x13\x2a\x4e\x6f\x0a\x69\xa0\x4d\x22\x5c\x89\x6f\x3a\x7a\xa1\xb5\xac\x1b\x39\x47\xa6\xe9\xe3\x19\xf8\x55\x3e\xc7\xe0\xad\x63\xf4\x70\x4e\x9b\x44\x42\x90\x63\x36\x3b\xf8\x3e\x24\x19\x5b\xfe\xd8\xd5\x89\x0f\x63\x1d\xc0\xa2\x94\xb5\xaf\xad\x58\xd3\xc4\xbd\x72\x01\x84\xed\x50\x8a\xea\x75\xe3\x3b\x30\xe4\xfb\x57\x22\x69\x40\x54\x48\x07\xb2\x26\xbc\xdf\x90\xbf\x2b\xcc\x5d\xc3\xe5\x8d\xbd\x72\xe5\x0d\x31\xc7\x36\xd1\xe7\x98\x3c\x8c\xa3\x77\x23\x07\xd7\x63\xe3\x7c\x3c\x18\x8c\x28\x58\x41\x35\xea\x58\x75\x75\xce\x83\x88\x12\x7d\xff\xe7\x5d\x43\xaf\xed\x47\x87\x2b\x34\x30\xb4\x52\xba\x4b\xaf\x19\x17\x2f\x5a\x4f\x0f\x4b\x8d\x48\x39\x6a\xbf\xd0\x70\x8a\xf4\x28\x8b\x45\x9d\xee\x70\x78\x7a\xd5\x78\x38\x4c\xf5\x78\x34\x54\xdd\x80\xd0\x81\x41\xfb\xfe\xcb\x17\x0e\x8a\xbc\x25\x47\x02\x6b\x20\xa5\x5a\x12\x40\x10\xf4\x78\x92\x82\x57\xd0\xa0\xd5\x33\xd5\x11\xb2\x29\xe5\xeb\xda\xc9\x92\xd5\x5b\x48\x51\x79\x02\x29\xf3\xb3\xf2\x98\x34\xd6\x27\x87\x29\xb2\x8c\xfc\x08\xbd\x8e\x61\xdf\x64\x14\x87\x5a\x6c\x05\x5c\x83\xc8\xee\x78\x1e\xef\xf4\xe2\xd6\xbf\xd8\x23\xc0\x6b\x07\x22\xc6\x5d\x87\xf6\xcf\xd1\xe4\x59\x44\xdf\xe9\x3a\x3b\x8f\xdf\x4f\x59\x39\x3d\x6c\x95\x3e\x07\xc9\x7f\xa7\xa2\xf6\x6e\xcf\x57\x69\xca\x79\x7a\xad\x3a\x76\x59\x96\xce\x22\x64\xd4\xf2\x73\x74\xd7\x2c\x83\x75\xcb\xf2\xfb\x29\x46\xb0\x72\x8c\x01\x24\x10\x9f\x3f\xa0\x1b\x93\x0c\xea\xc3\x8a\x17\x2e\x46\xb3\x18\xb8\xfc\xb7\x15\xc2\x58\x5f\x55\xe0\x47\xcc\x74\x46\xf3\xbe\xe6\xb6\x5f\x62\xdf\x7b\x1b\xd3\xa5\xed\xab\x1f\xbd\x77\x6f\xce\x7b\x6e\xe8\xbe\xa5\x6a\xc3\xbe\x37\x50\x9c\x4d\x24\xa3\x3c\x1b\xc5\x65\xd7\x9a\xfd\x5e\xb5\x1f\x9b\x4a\xae\x27\x9d\xd8\x54\x73\x3d\x77\xb7\x4d\xaa\x38\xdc\x98\xee\x1e\x7c\x1e\x26\xb9\x82\x34\x1c\xc2\x13\x02\xd5\x32\xd6\xcd\x8a\x63\x3f\xe8\x1b\xec\x5e\xd4\x6f\xd3\xd5\xce\x77\xae\xda\xfb\x4e\x3c\x3f\x72\x56\x97\x11\x1f\xef\x5b\x25\x87\xfc\x4b\x7a\xe6\xd9\x60\x9e\x50\xe4\xf8\xe4\xfd\x8b\xae\xd7\x7d\xa9\x45\x93\x19\x7d\xd4\x52\x1a\xaf\x6f\xb3\xb7\x03\x2b\xf3\xb7\x3c\xcd\xba\xab\xab\x7e\xe5\xfa\x51\x1c\x91\x1b\xe1\x89\x5f\x6f\xa0\x6c\xd8\x85\x65\xde\xeb\xf3\x1e\xf1\xab\x1a\x31\x19\x7b\x56\x5f\x35\x0a\x03\xfe\x86\x0c\x70\xcb\xb6\xf1\xd5\x82\x68\xdc\x6e\x85\xb7\xaf\x37\x12\xc6\xb5\xc5\x69\x5a\xb0\xed\x27\xf9\x91\x61\xd7\xd2\x17\x0f\x5b\x2d\xca\xf9\x0f\xf9\x99\x61\x62\x99\x0d\x66\xb8\x35\x8e\xc9\x75\x03\x45\x30\x74\xcb\x65\xf1\x53\x21\xf5\x2f\xc4\xe8\xd9\xf5\xe8\x68\xd4\x8f\xa4\x8b\x4a\xce\x4f\x44\xfa\xbe\x4c\x26\x9e\x08\x48\x91\xdf\x8a\xca\xef\xdb\x53\x36\x28\x8e\xd3\x49\xd9\x85\x57\xe0\x2e\x46\x55\x96\xa7\xb4\x12\xcd\x28\x2d\x15\x19\x7b\x13\x69\x27\xd3\xa4\x4c\xd4\x38\x8c\x93\x24\x3b\x9d\xa8\xea\x02\x2c\x83\xdd\x5f\xc9\x77\x25\x72\x69\xc3\x56\x4f\x62\x5c\xa5\x63\x85\xab\x92\x8b\xac\x86\xeb\x85\x9f\x80\xe3\x06\x57\xfa\xf9\xb3\x7b\x64\xda\x6c\xf6\xc8\x54\xef\xd0\xa9\xd2\xc6\xce\xae\xc0\x96\x00\x2d\xdf\x0f\x4d\x6e\x9f\x3c\x81\xf2\x4f\xe5\xff\xa9\x92\x96\xb9\xe9\x7f\x8b\x47\x8d\xce\xab\xb6\xe0\x8b\xc6\x5a\x6a\xfc\x5c\xce\xa6\x00\xd4\x3e\xb9\x56\x71\x40\x65\x5f\x88\x2d\xed\x33\x80\x03\x8b\x7a\x43\x00\xdc\x7f\x95\x21\x8a\x20\xe8\xbe\x66\xf7\xd4\xf6\x41\xe5\x00\x2c\xab\x89\xe0\x9d\xb0\x85\x4b\xe6\x11\xab\xee\x4a\xb8\xc9\x4f\xd6\x05\x5d\x63\xdd\x6d\xa3\x80\xbf\xac\xaf\xcb\xb5\xb0\x6e\xf2\x29\xd1\x60\x40\xff\x57\x1f\x7e\x84\x47\x1e\x81\xa8\x0b\xaf\x9f\x3f\x7a\x0e\x8e\x46\xc8\x20\xab\x1f\x15\xb9\x34\x9d\xd1\x57\x5c\xc6\x16\x27\x26\x4d\xd7\x00\xca\xa5\x65\xa7\x10\x8b\x13\x2c\xa9\xe2\xf9\x19\x2c\x16\x8c\xb0\x5d\x00\xb9\x75\xd0\x8f\x31\x7a\x91\x8e\xc0\x4b\x37\x49\x79\x27\x62\x2f\x49\x67\x53\x16\xe1\xc1\x8e\xb8\x12\x76\x6f\xb4\xf6\x6d\x14\xb4\xe5\xbe\xad\x4c\xb9\xa9\x0e\xdb\x8c\x41\x35\x6f\xd9\x90\x9a\xc6\x92\x31\x6d\x1c\xf6\xc9\x44\x97\x1a\x28\x1c\x85\x1c\xd8\xdc\x80\xce\xed\xfb\xb0\x07\xa1\xf3\x43\x15\xac\xc3\x4e\x7d\xfb\x46\xbb\x9f\xd2\x9f\x53\xa8\xa6\x06\x33\xc8\x60\x3d\x95\x47\x28\x53\xbf\x24\xf8\x5e\xa8\x3a\x0e\x10\x38\x72\x53\x4e\x08\x1b\x94\xa3\x99\x9e\x34\xe9\xb1\xbb\x18\xb0\xfc\x94\xc5\x8a\xa4\x74\xe2\xcb\x84\x7c\x5c\xd5\x1e\xc4\xea\xa7\x3b\x4d\x6a\xc6\x6b\x60\x02\x43\xae\xa2\x17\x16\xfc\xe4\x6c\xe9\x61\xeb\x82\x57\x30\x70\x6e\x37\x9a\x7c\x18\x58\xec\xc8\x7c\xec\x44\x0a\x40\x71\x21\xfc\xbb\xa3\x52\x35\xcb\xa1\x1f\x3a\x9d\x31\x1a\xf5\xd3\x96\x23\x21\x18\x08\x59\x66\x7a\xdc\x9c\xd0\x60\x3c\x13\x55\xf0\xe7\x0a\x8e\x2c\xbe\xd0\x83\x11\x77\x1a\x0e\x8c\xf9\xfb\xeb\x9b\xd1\xeb\x9b\xd1\xff\x72\x3b\x1e\xba\x16\x95\x4f\x7e\xbf\x8a\xf0\x7d\x57\xea\x31\x1c\x0f\x01\xdf\x82\x21\x87\x20\x74\x78\xe7\x2b\x23\x2a\xe3\x39\x18\xcf\x02\x18\x16\x59\x05\x78\x01\x02\x8e\xa7\x42\x38\x12\x27\x89\xfc\xb4\xa0\xe8\xef\xa8\xa8\x13\x44\x02\x15\x29\xc1\xff\x69\x2a\xce\x3d\xe7\x94\xb5\xe2\xdf\x6b\xac\xc8\x1b\xd5\x32\x77\x63\x28\x4b\x95\xa5\x3e\x70\x76\x6d\x95\x28\xe8\xa1\x64\xfc\x75\x93\xd1\xd1\x3a\xd4\x4e\x6f\x25\xa8\x2c\x95\x22\x12\x29\x66\xa9\x0f\x5d\xa5\x5f\xb4\x1a\x22\x3d\xc4\xf3\x09\xaa\x9a\x7b\x58\xfb\xba\x57\x27\x42\x4a\xbe\xcf\x5f\xb4\x5a\x77\x46\x2a\xb8\x92\x6e\x07\x8f\x5c\x20\x6a\x02\xa7\x55\x97\x3f\xae\x25\x6c\xae\xf5\xfb\xf2\xb0\xd5\x87\x49\x10\x73\x23\x18\x28\x18\x3e\x8b\x35\x17\x03\xc7\x61\x01\x6c\xbe\xf9\x46\xb0\xaa\xdf\x05\xbf\xca\xe3\x71\x34\x3e\xcb\xe2\x13\x81\xd0\x3c\x4b\x0a\x6c\x4d\x4e\x88\x69\x48\xc6\xca\xce\xb5\x9e\x00\xa1\x94\x7d\xbd\x6a\xdc\x83\x82\xd9\x3a\x4a\xca\xe7\xfa\x90\x1c\xf0\xe0\x0c\x13\x63\x04\xd6\x26\xf7\x00\x5c\x99\x20\xa7\x44\x1d\x93\xab\x6f\x3d\x2c\x4d\x7f\xe5\xd5\x0b\x65\x17\x82\xbb\x40\x1c\x13\xd6\x2b\xd2\x41\x14\x09\x9d\xa0\x87\xde\xcb\x2a\x12\xde\xe1\x0a\x8d\x14\x41\x7d\xbc\xc0\x1b\xd1\xea\xa0\xc3\xb1\x58\x0e\x21\xb5\x81\xd1\x9a\x3a\x09\x8e\x27\xca\x16\x45\x2c\xcd\xa7\x8c\xda\x5d\xa9\x94\x77\x14\xfe\x2a\xc6\xf7\x7d\x19\xfd\x1c\xaa\x92\x8b\x0c\x74\x68\xf2\xa7\x04\xf3\x65\xa5\xd0\x35\x41\xe0\x79\x1d\x76\x73\xe8\x74\xbb\xef\xfb\x22\x99\x43\xf4\x63\x05\x05\x5f\xfd\x40\x5d\xbc\x1e\x3f\x3f\x56\x41\x9f\x15\x50\xcc\x43\x3e\xbb\xad\xe1\xfd\xd5\x70\xa9\x6b\xad\x81\x2e\xd7\x0b\xf5\xee\x22\x35\xca\x52\xb6\x7e\x8a\x1d\x74\x88\x02\x21\x2a\xe9\x30\x84\x70\x87\xd4\x95\xaa\x66\x77\x3c\xa6\x67\x11\x5a\x89\x06\x17\x12\x05\x3c\x96\x58\xcc\x30\x43\x9e\x46\x90\x65\xa4\xa2\x43\xb0\x77\xe4\xef\xa5\xb0\x25\xdd\x98\x8a\xbd\x9c\x3d\x8d\x78\x92\x1f\x15\xbb\xe5\xab\x52\xc8\x7b\x2b\xae\xa6\xb1\x48\xa6\x13\x9d\x38\xf1\xdf\x2f\x4b\x16\x6e\xd7\x62\xc5\x0f\x13\x15\x78\x3e\x7c\xd4\x23\x3b\x51\x8c\xf4\x59\x50\xd8\x79\x69\x87\xfa\x05\x33\xcc\x02\xbb\xa7\x4f\x9e\x0e\xcf\x80\x56\x06\x21\xc6\x81\x19\xa2\x99\xae\xf3\xe6\x47\x5a\x32\x7a\xa7\x1c\xd1\xb3\xb1\x38\xaa\xcb\x57\xa2\xd0\x47\x89\x36\x34\x9b\xc4\x8b\x6e\x95\xa8\xcc\x8e\xd0\x13\xc6\x60\x30\x88\x7e\x5b\x90\x23\x60\x68\x13\x78\xef\x4a\xcb\x87\x91\x35\x8f\x22\xcf\xdd\x17\xb0\xce\x4a\xd7\xc3\x08\x9f\x31\x29\x33\x78\x30\x25\x43\xce\xa6\x67\x9a\xf4\x78\xc7\x36\xfb\x6c\xe9\xe9\xa0\xf7\xd9\x49\x77\x96\x4f\xa7\x44\x3e\x61\xb2\x95\xb4\x69\xc0\x5c\xba\xd4\x2a\x11\x54\xdb\x66\x4f\xb5\x19\xae\x45\x2c\x79\x05\xb9\xc8\x19\xcd\xe7\x15\x37\xab\x48\x7a\x6a\xac\xa1\x49\x08\x3d\x9b\x0a\xd1\x44\xde\xfa\x24\xdf\x96\x3a\x2e\x46\x19\xc6\x4c\x19\x87\x66\x94\xd7\xf7\x9d\x04\x53\xc9\x50\x15\xf2\x49\xe7\x52\xe8\xb9\x1c\x91\x56\xea\x03\xb0\x4f\xae\x33\x6a\x60\x3c\x2f\x04\x0d\x01\x9f\x31\x3d\x21\x1a\x1c\x52\x11\x38\xa7\x25\x70\xb9\x80\x47\xce\x01\x50\x1c\x0e\xcd\xec\x25\x33\x69\x68\xbf\x4f\x98\x42\x72\xdc\x1b\x1f\xf4\xd5\x15\x95\x95\xdc\x37\xc4\x28\x73\xb8\x1b\x23\x52\x10\x23\xfd\xc2\x66\xad\xa2\x19\x7a\x24\xee\xc8\x64\xfd\xc8\x0b\xef\xa1\x96\x5b\xe0\x76\xa0\x2f\x4f\x53\x0f\xe1\x46\x4b\x54\x82\xf6\xda\x0f\x03\x0f\x1d\x91\xd9\x91\x06\x9b\x5d\xb8\x5a\xf8\x60\x1a\xbe\x65\x54\x65\x95\xba\xae\x4a\x65\x4f\x58\xa9\x66\x77\x86\xd9\x12\x54\x7b\x8c\xaf\xb4\xc6\x34\xc2\xa6\x11\x83\x1d\xd1\x97\x41\x7b\x68\xb0\x13\x18\xa4\x9a\x79\xff\xc9\x98\x5e\x21\xd2\xb2\x46\x67\xc8\x2d\xf7\x61\x55\xbe\x61\x7b\x5e\x16\xbf\x76\xd2\xbf\x2b\xfb\x0d\x0f\xab\xe5\xad\x13\xc7\x01\xf7\x15\x46\x4c\x32\x1d\x5b\xae\xf1\xc2\xcc\xb1\x61\xd9\x77\x47\x87\x01\x31\x77\x74\xd8\xc8\x07\x15\x47\x96\xe7\xc2\x1a\xea\x75\x1c\x92\xfa\xec\xa5\xa6\x6c\xb8\x1b\xf5\x7a\x67\x6b\x69\x03\x11\x8c\x7d\x3f\x80\xdc\x55\xc3\x3c\xc1\x4b\x06\x38\x73\xcc\x84\x10\x82\x06\x6e\x7a\x92\xc1\x91\x11\xbe\x77\x05\x0a\xd5\xbe\x60\x4c\x45\x62\xe8\x00\x0c\x85\x40\xea\xe9\x91\x72\x45\xb7\xbe\xc8\xe2\x02\x6c\xa6\xe0\xe2\xc2\xeb\xa3\x4b\x51\x21\x9e\x1c\x9a\x1d\x2d\x63\x89\xdc\xfa\x09\x0a\x36\xc3\x4d\xc6\xd8\x48\x8c\x3d\x31\x84\x38\x32\xd2\x61\x3c\x15\x13\x33\x3e\x33\xef\xd5\x8d\xa0\x58\x5c\x9e\xd2\x6c\x41\x56\x49\xaf\x87\x8d\x84\xd7\x75\x5a\xd3\x3e\xe0\xd6\x7d\x8f\x5c\x66\x65\x12\x17\x0f\xb9\x85\x04\x29\x5a\x48\x41\x34\xb6\xf4\xe4\x24\x19\xa7\x42\x1a\x9a\x9e\xb9\xcd\x4a\x72\x1f\xb5\xa5\x6d\x5b\x3a\xc1\xea\xbb\x15\x9e\x26\x42\x5e\xab\x5c\x0b\x69\x52\x68\xd0\x60\xd8\x5d\xee\xa9\x17\xa5\xc3\xdd\xcc\x6d\xde\x46\x86\x7d\xac\x1f\x4a\x1d\x63\x31\x47\x3e\x1a\x67\x9e\xe4\x75\xa9\x3f\x10\x86\x6b\x76\x27\x7c\xde\x76\x20\x7c\xdf\x6d\x1e\x8f\x27\x39\xe4\x33\x25\x38\xd8\x03\x69\xe0\xef\xf8\xb4\xfc\x44\x48\xbe\x6a\x01\xc4\xc5\x59\x36\xaa\x7d\x6e\x1f\xe2\xdb\x8b\x0c\xdf\x4e\x5b\x08\x60\x32\x86\x83\x2d\x8f\x77\x71\x43\xf8\x65\x6a\xb6\x04\x7f\x0f\xa3\x47\x4e\x28\xf7\x51\x1b\x99\x22\x68\x72\xa2\x4b\xba\x42\xb9\xb3\xa3\x56\xd8\x51\x0a\x24\xef\x4d\x0c\x67\x4c\x0b\xfd\xae\xef\x2c\x91\xee\x57\x40\x83\x6f\xbc\x74\x31\xd7\x3b\xc7\x09\x1a\x63\xc8\xd1\xf7\x22\x62\xaa\x85\x80\x1f\xf8\x6e\x75\x3c\xb6\xea\x12\x7b\xed\xee\x11\x42\xbf\xac\xd1\x9d\x50\xbe\x1f\x63\x84\x94\xde\x17\xf5\xcf\xe3\xc8\xe3\xa2\x7d\xc7\x7a\x4f\xb0\xf1\x5e\x8d\x37\x43\xce\x98\x6a\xce\x16\x58\xcb\x10\xf6\x84\x0a\x77\x7c\xbd\xe5\x3c\xe6\x84\x89\xbf\x3d\x1d\x79\x6e\xa6\xa4\x20\xbe\xf7\x88\x12\x86\x92\xc6\xba\xbd\x01\xed\xc8\xa3\x58\x39\x2c\x44\xb7\x2a\xf0\xc6\x45\xac\x6e\x51\x4b\x96\x2f\x0a\xed\xbd\x50\x1a\x06\xc0\x6e\xef\x7a\x22\xa2\x6a\xa4\xb0\xdb\x09\x9a\xd7\xa2\x53\x89\xdc\x58\xe9\xb5\x21\x20\xdf\x86\x0e\x6a\x68\x9e\xc3\x4b\xcc\xdb\x79\x03\x3f\xf6\xae\x48\x09\xb7\x5e\xec\xb7\x9a\x0b\xd2\xf3\x83\xfe\xd6\x7a\x9b\x2b\xd0\x0e\xf8\x4f\x01\x8d\xf7\xa8\xec\xac\x5c\x5f\x88\x5e\x5f\x88\xfe\x57\x3c\x15\x65\x2a\xeb\x8b\xbc\x17\x95\xc0\x4b\xdc\x64\x86\x62\xbf\xb5\x7e\x62\x9a\x4d\xd2\xa3\x20\x1c\x65\x29\xc0\xbd\xc3\xd8\x89\xe9\x02\x01\xe0\x03\x71\x5a\x50\x9b\x4c\x81\xa6\xc8\x46\x9a\x2e\x33\x0f\x45\x2b\xe4\xc1\xc0\xb1\x62\x24\xa0\x07\xe9\x91\xa3\xd4\xe7\xd6\x8c\xa4\x71\xfe\x44\x10\x9f\x34\xec\xb9\xed\xb4\xca\xa4\x73\x4b\x5c\xd4\x33\x56\xb4\x61\x49\x45\xd4\xbb\xe0\x33\xb4\x32\xd7\x65\x15\xd8\x8e\x56\x02\xa5\xc5\x0b\xc1\x6d\xe8\x1a\x94\xdd\x4e\x58\x75\x1f\x9a\x74\xa7\x81\x43\x5e\x02\x0e\x12\xd2\xfb\x3d\x5c\x5f\x79\x79\xf2\x6c\x1a\xce\x4c\xe2\xb9\x58\xa7\x55\x45\x85\xe8\x78\x38\x4d\x3a\xac\xdb\x70\x9b\xf2\x28\x99\xc4\x8b\x69\xf0\x52\xa5\xa9\xff\xb0\x5b\xbb\xad\xe8\xeb\xe0\x60\x64\x17\x20\xa7\xc1\x98\xb5\x18\xf0\x4b\x55\x7d\xc7\xc3\xbb\xe0\x8c\xe2\x4b\x74\xdf\x7d\xed\x45\xc2\x0b\xd4\x52\x31\xff\x56\xa3\x81\x0a\x59\x76\x00\x0f\x8a\xee\x7e\x49\x3e\x04\x46\x2e\x57\x1c\x0d\xb6\x60\x06\x93\xe0\x9d\xc4\x72\x1b\x08\xea\xfe\x91\xb6\x31\xd0\x36\x03\xb0\x26\x7f\x79\xfc\x0f\x67\x29\x62\x1d\x20\x33\x07\x17\xa1\xd2\xf3\x5b\xee\x77\x3d\xa3\x78\x75\x7d\xae\x6e\x02\xfc\x3a\x83\x41\xc1\x3f\x5a\x17\xcb\x78\x35\xc8\xae\x67\xf1\x53\x50\xb5\x4d\x43\xbb\x23\x0c\xc9\xc0\x9c\xa2\x71\xf8\x80\x37\x2e\x55\x2d\xf6\x49\xba\xa8\x0a\x5f\x4b\xda\x32\x12\xaf\xba\xcc\x65\x2c\x69\xb1\xb1\x9e\xa4\x47\xc7\x24\x54\x6a\xc7\xc6\x52\x81\xb5\xe2\xdf\x38\x37\xb6\x2b\x6a\xb6\x5a\xdd\xef\x1c\xc5\x82\xb4\xc4\x46\x03\x6f\x15\xc5\x6f\xf8\x83\xd3\x07\x3f\xb2\x3c\x13\x19\x81\x97\x8b\xef\x92\xb3\x9a\xb7\x8b\x22\xb7\xed\xeb\x45\xac\xc9\xc3\x21\xd5\x70\x8f\xd9\x5a\x3c\x4a\x46\xe9\x49\x3c\xed\x72\x00\xff\xf5\x98\x7d\xc1\xfa\xb9\x89\x98\xb9\xd9\xbc\x6a\x5a\x0e\x55\x7d\xf5\x24\x7d\x51\xaa\xbd\xa6\xd7\x2f\x49\xaf\x52\xc0\xf1\x08\x16\xef\x52\x55\x5c\x20\x49\xad\x41\xb1\xa7\x35\x9d\x7e\xb0\x45\x1d\x99\xbe\x62\xc9\x37\x8d\x94\x59\x7e\xe8\x7d\xd4\x5a\xbe\x0f\x03\xbe\xfd\xdd\x50\x27\x6d\xa3\xdd\xb2\x01\xb4\xb7\x8b\x5c\xe1\xcf\x06\xd0\xef\x39\x20\x9d\xe1\x02\x5f\x8e\x85\xab\xf7\xa0\x82\x6d\xb8\x50\x4a\x91\xf7\x61\x80\x24\x15\x2e\x84\x59\x06\x12\x62\x11\x04\xe1\x44\x86\x05\x85\xe4\xcb\x40\x8d\xd8\xc7\xf2\x4d\x09\x65\xd8\x16\x04\xe7\xcf\x68\xd5\x29\xfb\xc3\xd2\xa4\xa4\x22\xd0\x5c\x84\xa4\x64\x30\x9c\x5a\xca\xd2\xb1\x99\x96\x21\x2f\xb7\x62\xc7\x25\x0b\x0f\xaa\x63\x82\xe8\x34\xd0\x5b\x08\xca\x9f\xb3\x00\x94\xa1\x3c\x95\xd9\x82\xfc\x2a\x41\xab\x9b\xac\x20\x44\x1d\xeb\x65\x71\xb2\x10\xb2\xbb\x38\x86\xfe\x1c\x17\x6f\x0a\x7c\xb3\x56\x55\x95\x07\xeb\xd4\x75\xd4\x58\xc3\x91\x2e\xb7\x12\x34\x43\x50\x70\xa0\x4e\x72\xa4\x4f\x93\xc1\x22\xd5\x78\x6a\x27\xd4\x1d\x05\x95\x4e\x81\x97\xaf\x00\xdb\xa4\x61\x92\x2d\xb5\x5a\x00\x38\xbb\xed\x49\x1e\xa5\xee\x5a\x2a\xc7\x0a\x5b\xd0\xb8\x5d\x13\xb7\x5d\xc1\x1a\xb4\xf1\x0a\xf8\x0b\x56\x6e\x7f\xd0\xff\x9d\x3c\xd9\x82\x66\x05\x00\x61\x7e\x9e\xa4\x27\x69\x19\x98\x42\x1b\x40\xe2\x4a\x27\x56\xcc\xbb\x95\x6f\x95\x29\xd2\xdf\x93\xca\x02\x90\x69\x41\x83\xa3\x37\x71\x14\x3c\x99\x55\x16\xd1\x10\x66\x5d\x51\x46\x56\xb5\x72\xad\xec\xaa\x6a\xf5\xb1\x99\x75\x66\x9c\x4e\x26\xe9\x48\x88\x3d\x67\x41\x1e\xea\x02\xd9\x03\xc9\xcb\x78\xfa\xa8\x4d\x05\x0e\x24\x17\x92\xec\x35\x23\xc1\xcd\x32\xb7\x57\x8e\x9f\xed\x8b\x20\x69\x99\x9c\xf4\xdc\x47\x5b\x9e\xe5\x1c\x42\xf5\xaa\x6e\x09\xab\x37\x73\x2a\xd8\xb4\xd0\x0e\xe9\x68\xdd\x62\x99\x89\x65\x1b\x5c\x4f\x7c\x6d\x87\x56\x93\x28\x67\x34\x2c\xfe\x92\xc2\x7a\xad\x65\xc5\x2b\xe4\x8b\x8a\x69\xb6\x05\x9a\xc4\x97\xc5\xd7\xf5\x38\x9d\x8c\x00\x8d\xb9\x45\x77\x78\x9d\x1e\xe3\xb6\x2a\x6e\xb1\x03\x04\x2b\xb1\x9a\x10\xff\x05\xaa\x56\xa9\x15\x55\xea\x42\xb6\xd0\x89\xda\xfa\xe6\x03\xd2\xe9\x71\x5a\x00\x5f\x9c\x81\xcb\xcd\xe5\x4f\x48\x2f\x44\xb1\xf6\x47\xa3\xea\xc9\xb6\xce\x46\x56\xa5\x7c\xa2\xa1\x93\x9c\x79\xc2\x37\xc4\x11\x41\xd3\xf9\x7b\x96\xb6\x83\x67\x49\x24\x63\x52\x59\x4e\x83\x62\xbc\xca\xe4\xb0\xf0\xe8\xf3\x75\x0e\xce\x43\x93\xea\x32\x0c\x88\x97\x9d\xcd\xd3\x7c\x9e\x32\x8e\xe2\x15\x54\x10\xcc\x79\xfc\x84\xc7\x16\xb2\x8c\x65\x89\xb5\x50\x27\xc9\x93\x06\xe7\x29\x2c\x5d\xb4\xc7\xbf\xee\x47\xfb\xec\xf3\x20\x1a\xea\x9b\xea\x73\xd3\x2a\xa9\x59\x49\xe3\x0a\xef\x42\xf0\xe5\x80\x3a\xc6\xd6\x55\x5f\x6f\xe9\x4e\xd1\xf1\x80\x98\xa2\x3c\x9b\x9e\x91\xcb\xff\xd2\x32\xc0\x57\x46\xf0\x64\xec\x1e\x7a\xbb\xa1\x2c\xe1\x45\xcf\x9d\x77\x19\xdc\x06\xde\x3d\x71\x41\x1f\x5b\xf1\x2e\xad\xf2\x47\xfa\x97\x86\x88\x41\x6e\xd6\x44\xe9\x61\xb2\xb6\x55\xc7\xf5\x84\x2d\xe9\x1a\xf1\x9b\x7c\x98\xa5\xf3\xb3\xc0\x8a\x67\xb9\x9c\xdc\x0a\xf2\x10\x12\x84\x86\xbc\xaa\x25\xe0\x80\x06\x16\x00\x52\xf6\xb8\xaa\x80\x81\xe8\xed\x84\x56\xe5\xcb\xf8\x54\x91\x8c\x4c\xd9\x69\x58\xbc\xbb\xc5\x28\x4d\xdd\xe5\xab\xca\xf0\xdd\xf6\xaf\x85\x44\x9c\x86\x33\x31\x36\xcc\xaa\x30\x0d\xa0\xcb\x5c\x8c\x73\x15\x62\x0e\xa2\xcc\x12\x2b\x02\xd7\x26\x5f\x8d\x95\x8b\xd1\x2c\xb7\x4b\xac\x24\x47\x9b\x4b\x28\x6a\x47\xff\x9a\xa9\xba\x0a\x98\x50\x40\x0c\x6e\xc9\x91\xd2\x4d\x0d\xdc\x8e\xc3\xa5\x4d\xd7\x0e\x97\x8c\x1a\x6b\x91\x2c\xe6\xe1\xef\xe0\xa7\xb8\x8b\xe1\x0c\x64\xca\xa3\x74\x0e\xd6\x9b\x21\x75\xb5\xf4\x1a\x85\x80\xb2\xc6\x6e\xcf\xd3\x85\x73\x41\xc7\x44\x99\x51\x3d\x08\x54\x6b\x39\x26\x0b\x54\xd4\xa2\x0a\xd9\x33\xbb\xa6\x95\xa0\x99\x80\x74\x1d\xad\x60\x3b\x2a\xec\xb3\x59\xe8\xaf\xce\xb2\x91\xa0\x99\xe0\x5a\x97\xbe\x9a\xd9\x05\xc2\x0d\x3b\x29\xe0\xba\xc6\x12\xcf\xd0\xd1\x0d\xda\x99\x89\x26\xf0\xa8\x11\x3c\xe1\xf9\x60\xb6\xbb\x20\xe9\xb9\xa7\xa1\x02\x0e\x65\x97\x3f\x4e\x8f\x8e\x85\x88\xdf\x50\x9e\x43\x31\xda\x91\xb9\xef\xb2\xfc\x34\x7b\x55\xc2\x5d\x55\xc0\x75\x1c\xcb\xad\x6e\x80\x57\xb1\xe3\xd6\x30\x5b\xc0\xab\xbc\xa6\x2a\x38\x54\xc5\xa1\xd3\x78\x10\xaa\x70\x12\xdf\x74\x0f\x36\x6c\x84\xe8\x9b\x7a\x6a\x2a\x68\x28\x69\x5d\x91\x0c\x03\x69\x0c\x36\x74\x36\x18\x56\x67\xb1\x92\x2e\x2f\x19\x86\x93\x59\x09\x6b\xe7\x1b\x06\xd2\x08\xb6\xea\x86\x75\x58\x99\xc3\xcb\x85\x07\x54\x9d\x57\x51\xd6\x55\x4b\x0d\x9b\x41\xac\xde\x5b\x87\xff\x61\x30\x95\xc3\xf3\x53\xcd\x30\x90\xc6\x61\x1d\x34\x06\x12\x39\xb4\xcb\x85\x86\x15\xe9\xc4\xbd\x2c\xb3\x1c\xba\x4f\x58\x1d\x6e\xdc\xad\x72\x36\x03\x2c\x7c\x75\xb8\x05\x1e\x64\xb6\x36\xae\x1d\x15\x5c\xdb\xe5\x7c\x35\x76\x39\x92\xd2\xaf\x22\xe2\xc8\x72\xee\xd9\x5b\x1a\xe3\x50\x40\x14\xdb\xca\x86\xd2\x3e\x83\x9f\xf7\xf6\x9e\xd9\xc5\x2e\xb4\xe6\xc4\x2e\xc4\x27\x97\x6e\xe4\x13\xdf\x5f\xbb\xb2\x1b\xf6\x83\x3c\xd5\xf8\x69\x0f\x45\x79\x7a\x4b\x9b\x9b\xf4\x23\xce\xc3\x9b\x2e\xef\xe3\xdb\x54\x2a\x77\x07\x5e\x2b\x25\x5d\xae\x5a\x0c\x8d\x16\xe3\xb1\x0e\xeb\x54\xdf\xb6\x87\x79\x0a\x7f\x2a\x41\xe4\xe7\x95\xfb\x98\x87\xfd\xc1\x9d\x0c\xcb\x1b\x21\x5e\x03\x9b\x57\x3a\xfc\x64\x01\x21\xc7\x98\xac\x7b\x81\xa0\xbe\x4a\xc6\x37\x11\xd0\xf0\xa9\x3f\xf2\x7a\x7a\x57\x02\x4e\x2d\xd1\x91\xfc\x0d\x66\xbf\xde\xdc\x61\x2e\x8c\xd5\x76\x9a\x77\xef\x47\x8f\x94\x54\x0e\x8f\xe7\x26\x1f\xb0\xf9\x83\x0b\x8f\xcd\x7d\x9e\x5e\x23\x26\x76\x0d\x1e\x5a\x22\xa2\x2e\x6a\x1b\x8f\x33\x1b\x0a\x4d\x94\x42\x88\xbe\xf9\xdc\xf7\x1a\x88\x27\x20\xc1\xcc\x20\x0a\x52\x28\x90\x9b\x89\x24\x2d\xf2\xf0\x04\x55\x44\x1f\xf1\xfc\x74\x0e\xed\x89\x7d\x8b\xcc\xf6\x2d\xc7\x82\x81\xa9\xe2\xed\x54\xce\x91\x39\xe4\x12\x14\xa2\xc8\x33\x11\x88\x0d\xe2\xe1\x8c\x4f\xb6\xde\xa8\x10\xb2\x3c\x76\xe0\xe6\x89\x7e\xe2\x4c\x80\x26\xd8\x6a\x63\x7c\xdd\x25\x46\xa7\xde\x2a\xc9\xb9\xbb\xd1\xf5\xe2\xfb\xc5\x3d\xed\x75\x1a\xce\xc5\x9e\x8a\xde\xb9\x31\x90\xe0\xe4\x41\xb6\x16\xde\x5c\x4a\x58\x65\x9c\x02\xce\x43\x49\x3c\xab\x2b\xec\x28\x42\x84\xfb\x97\x0a\x42\xb6\x1f\xa7\xc0\xd9\x57\x86\xd8\xaa\x09\x84\xd5\xf5\xde\x8c\xd7\x79\x59\x55\x4a\x19\x34\x49\xe1\xa4\x62\xc5\x09\xc8\x5f\x10\x58\x80\xbc\xbc\x4d\xc3\x78\x57\xae\xf0\xb8\x2a\x84\x03\xd5\xaa\xb1\xd0\xa9\xa5\x3c\xdd\x7e\x2d\xd9\x59\x91\x25\x97\x67\x18\xcb\xf2\x0b\x3b\x20\x64\x45\xc4\xc9\x73\xc3\xcd\xf9\xf2\xe9\x06\xc2\x3f\x96\x79\xd8\xb9\xbb\x15\x1e\xf2\x5e\x54\xe1\xb8\x3d\xe4\xff\x5b\x3e\x4d\x60\xc3\xb5\xe2\x4e\xd6\x98\xc4\x54\x04\x26\x51\xa8\xbf\x58\x00\x86\x60\xf1\xda\x79\xbf\x50\x18\x06\xe9\x41\x7a\xbd\x1f\xdd\x55\xda\xa0\x9a\x26\x16\xd9\x4c\xec\x93\xcf\x49\x0f\x6d\x19\x7d\x61\x92\xa5\x1b\xb2\x93\x4c\x17\x6c\x4f\x3a\xaa\x2a\xfa\xa1\x49\xef\x5e\xb4\x19\xdd\x57\x89\xca\xc9\x75\xa4\xce\x81\x81\x18\x07\x55\x3e\xae\xb9\x90\xd3\x97\xc5\xed\x19\x95\xba\x14\xee\xa1\x57\x87\x37\x83\xb0\xf0\xc3\x90\x1f\xe6\x87\x18\xdd\x37\x66\x01\x95\x15\xb2\xdc\x90\xcd\x02\x90\x2f\xee\xc1\x60\xa0\xd6\xf7\x43\xb7\xac\xb3\xf9\x78\x1e\x50\xf6\x68\xbb\xc3\xc8\xad\x0a\x14\x76\xa3\x58\xd7\xd0\xb7\x23\xb3\xab\x64\x72\xf7\x85\xcf\xcb\xd4\xa1\x2b\xb6\x1e\x00\xc1\xfd\x99\xe5\x26\x43\x81\x51\x68\x63\x3a\x19\x41\x1d\x14\x17\x0e\xc0\x25\xda\x82\xb4\x2b\x67\x15\x43\xb3\x36\x51\x2d\xf6\xaa\x2a\xfc\xed\x32\xb1\x6d\xc3\xfb\xa6\x92\xc1\x1c\x23\x3a\xbd\xc7\xe0\x41\xc6\xc8\x7f\xd2\x2b\xae\x25\x16\x72\xf6\x83\x9e\x7d\x6d\xe9\x8b\x17\xe1\xe2\x8f\x5b\xcc\x28\xf4\xc9\x1b\xaf\xe2\xd2\x0a\x6e\x85\x39\x04\x0e\xbc\xb6\x34\x3a\x5a\xcd\xf8\x20\xa8\x3a\x22\x41\x56\xdd\xb7\xba\xe6\xbf\xad\xc2\x52\x7c\x09\x77\xad\xf5\xc0\xfc\x49\x7b\xaf\x21\xdb\x34\xd8\xf7\x7d\x98\xf8\x3c\x80\x79\x30\x51\x86\xf1\xd6\x3b\xf7\x3e\x19\x57\xec\xd8\x7e\x63\xa9\xd3\xc9\xd8\xf3\x91\x5d\xce\xcf\x9c\xa7\x61\x0c\x14\x5f\x83\x55\x8f\xd7\x7e\xbe\x36\xc2\xf7\xc3\x5d\xcf\x0b\x09\x51\xbc\x38\x90\x05\xfd\x9f\xb8\x9d\x57\xad\xf7\x5a\xc6\x54\x6a\x73\xae\x74\xb7\x0d\xbb\x48\xe3\xae\xe0\xb4\xf0\x1f\xb5\xd4\x1a\xd7\x8c\x22\x29\x09\x28\x2d\x12\x7f\x8c\xd6\xf1\x50\x63\x9d\x35\x7d\xe9\x90\x07\x85\x8c\x33\x7a\x8a\x9b\x8d\xa5\xbb\x3e\x8c\xea\x99\xdd\x52\x07\x55\x2f\xbc\x69\xc3\x72\xb5\x42\x7a\xc1\xba\x71\xe6\xa1\x6b\xdf\xe2\xc9\xea\x1a\xc1\xdb\xfb\x52\x4f\x8a\x32\x15\x07\x2f\xb0\xb0\x83\x93\x51\x13\x55\x31\xf0\x26\x8a\xe2\x35\x5f\x05\x35\x7d\x7e\xea\x68\x37\x43\x6c\x5c\x4d\xb3\x13\x00\xad\x9a\x99\x97\xaa\x99\x44\x45\x87\xa2\x78\xf2\x52\x17\x28\xe5\x83\x70\xe4\x78\x7f\x67\x75\xab\x69\x9a\xab\xa6\xf8\xf2\x17\x9b\xa7\x56\x88\x57\x37\xba\x72\x65\xd6\x04\x63\x0f\x4a\xcd\x97\x88\x31\xc7\x8b\x2a\x3c\x33\xb2\xaf\x45\xd8\x97\x0d\x38\xa7\xeb\xbf\x50\xcc\x39\x5d\x68\xd9\x41\x7e\xce\x00\x74\x46\x47\x03\x03\x9c\xcf\x46\xca\xb3\x51\x41\xa7\xe6\x26\x46\x24\xa1\xab\xad\x52\x6a\xc6\x25\xcb\xfe\xb9\xb9\x92\x1d\x88\x43\x81\x71\x31\xc5\x8a\x27\xb1\x12\xf4\x88\xa9\x5c\x81\x39\x1a\xd7\xfb\xe8\x5f\xf4\x2d\xf4\xb8\x03\xc1\x98\xe1\xc3\xd9\x49\x3a\x7d\x4f\x78\x19\x1a\x17\x5c\xbe\xf3\x30\x39\x9c\x03\x93\x45\x1d\x57\xae\x29\x95\x33\xb3\x06\x62\x50\x91\x33\xaa\xb6\x1f\x1d\x93\xa6\x7e\xeb\x09\x84\x8f\xe1\x13\x5c\x5a\x82\xce\x0d\x1b\x3b\xc6\x1a\x07\xb7\xf9\x92\xcb\x50\xca\xfd\x82\x49\xab\xda\x2a\x1c\x74\x0e\xe2\xd9\x6c\x7a\x26\x9d\xc1\xb4\x22\xac\x9e\x6b\xc6\x46\x5b\x80\xd3\x0c\x24\x5e\xa8\xee\x86\x79\x90\x21\x56\x0c\xe3\x31\x51\x56\x2e\x1d\x5e\x25\x30\x61\x9f\x2b\xc2\x8a\x4a\x37\x2b\x9e\x7b\xc3\xa9\x04\x97\x87\x4d\x83\xe1\x2a\x40\x5f\x6a\x0e\x4e\x7e\x55\x71\x5b\x44\xa2\x91\x98\xa4\xca\x62\x66\xb7\x56\x5e\x5c\xe0\xf3\x3f\x32\xbc\x8c\x2a\x8b\x04\x9e\xce\x47\x8b\x69\x3c\xbf\x21\xfe\xd5\x07\x95\x51\x14\xb4\x73\x25\x61\x65\x48\xfb\x2b\xbe\xee\x84\x7d\x74\x6c\x5e\xdf\xfe\x5f\xdf\xfe\x7f\x15\x61\x0a\x00\x56\x85\xfd\x09\x07\x2b\xf8\xc3\xc2\x10\x84\x2c\x0b\xea\x0d\x01\x20\xfc\x11\x98\xef\xc6\x73\x20\x65\xd8\xc1\xc0\x8d\x0e\x1f\x22\x10\x1c\xbc\x9b\x49\xc0\x02\x50\x9c\x97\xde\x17\x58\xe8\x10\x2e\xf3\x92\xf9\x0a\xf3\xd9\x78\x2a\x58\x6b\x7e\x8a\x1a\x0b\xe6\xcc\x1f\x94\x07\x94\x33\xf8\xc7\xd3\x27\xbf\x88\x9a\xa5\x3b\x50\xe2\x9a\x76\x1a\x44\xf5\x09\xc1\x22\xeb\x93\xbe\xe8\xd3\xa3\x2c\x07\x46\x30\x15\xd5\x43\x4f\x32\x88\xda\xc9\x1c\x40\x79\x35\xea\x81\x7f\x38\x99\xc2\xc8\xe4\xc6\xd6\xe9\xb5\x6d\xe4\x9c\x30\xf9\x8f\x5f\x5e\x6e\x5a\xd5\x1d\xcf\x37\x3b\xbd\xca\x52\x4a\x72\x80\x16\x5e\x28\x64\x16\x41\xad\x15\x8a\xf6\xe8\x01\x91\xfc\x25\x43\x2f\xb5\x01\x84\x55\x9e\x6f\xf9\xc7\x79\x01\x91\x05\xd2\x93\x44\xac\xe6\x3e\x54\x38\xef\xa3\x92\x59\x4c\xa8\x74\x3b\x81\x9b\x09\xc0\x89\x82\xf8\x07\xc2\x84\x4a\x62\x9f\xe6\xa2\x45\x48\x1c\xde\xbd\xbd\x7d\x1b\x63\x87\xd2\xde\x43\x15\xc2\x4e\x28\x7f\x89\x42\xeb\x3a\x1b\x9a\x01\x5b\x82\x82\x7c\x4d\x90\xaf\x52\xd9\x28\x98\x33\xcb\x9f\x8d\xc2\x93\x98\x86\x19\x06\xe3\x4a\x4e\x9d\x29\x73\x64\x27\x09\xf8\x92\x9d\x65\x58\xe8\xf9\x24\xce\xce\xf1\x8e\x95\x62\xcf\xd3\x9c\x1b\x2c\x4b\xcf\x7b\xec\xc0\x67\xb7\x65\xb9\x77\xe1\x98\x66\x72\x97\xdd\x01\xfb\x22\x10\xaa\x27\x54\xd3\x0d\x0a\x11\x12\xf0\x30\xbc\x01\x34\xd9\x51\x64\xc4\x2b\x1c\xb2\x20\x26\x13\xba\x93\x24\x2d\x86\x79\x36\x11\x00\x63\xf9\x37\x73\x2a\xb2\xc6\x43\x55\xa2\x3c\xac\x6a\x15\x1b\x45\xd6\xed\x08\xe6\xfc\x5a\x45\x1b\x24\xc2\xa1\xce\xed\xac\x30\x67\x69\x38\xb7\x62\xbd\x5a\x93\x6a\x1d\xfa\x16\x68\x50\xd3\x79\x10\x17\x82\xc7\xc2\x6d\x20\x74\xe1\xc1\x02\xd8\x03\xab\xe2\x66\xd4\x19\xea\xab\x42\x5d\x8f\x38\xbe\xc9\x47\x6b\x9d\xc3\xb8\x48\xee\x6c\x77\x9c\xf1\x1b\x57\xc1\xbf\x24\xb1\x98\x83\x6e\x67\x17\xf9\x6a\xfa\x7b\x4c\xa7\x2d\x6c\x9f\x46\x58\x53\x08\xe4\x63\xc1\xab\x6e\xc1\x41\x1b\xdc\x05\x80\xe4\x9f\x8e\xb0\x8a\x35\x30\x74\xa1\x66\xf5\x8d\x95\x9c\xb2\x06\x72\xa5\xa0\x12\x02\x63\xec\x50\xed\x6a\x92\x43\x17\xcf\x33\x76\x7d\x1d\x0e\x1f\x5c\x47\x7a\x85\x1b\x4e\x4e\xe9\xd2\x5c\x92\x53\x34\x63\xd3\xaa\x8c\x47\x8b\xf4\x68\xb4\xfb\x06\x73\x42\x14\xc6\x07\x11\x14\x28\x44\xc8\x4d\xba\x7e\x89\x64\xa5\xc8\xe7\x9e\x80\x43\xd6\x00\x02\xe5\x59\x22\x9f\x2d\x28\x0a\x33\x94\x68\x5d\x2e\xaa\xd6\x95\x4f\xfe\xd7\xc9\x87\xd2\xeb\xa0\x84\x65\xaf\x35\xa4\x75\xbc\xdd\xad\xba\x2e\xbd\x94\xf5\x77\x43\x0d\xf2\x55\xd2\x3e\x3c\x2c\xd2\x40\xdc\x40\x04\xbb\x9a\xe3\xd4\x50\x82\xcc\xfa\xc9\x0b\xc8\x20\xb0\x90\x1e\x1d\x41\x64\x42\x88\x22\x03\xb3\x8f\x62\x8b\x76\x09\x09\x38\x68\x22\x18\xec\x41\x88\x6a\xec\x60\xb0\x6d\xe8\x07\x8d\x57\x76\x2c\x6e\x92\xa1\xfb\xde\x02\xec\xe4\x47\xc7\x71\x76\x14\x56\x20\x90\x79\xbf\x42\xbc\x28\x80\x86\xf5\x78\x23\xbc\x0d\x1c\xc6\x65\x79\x37\xec\x60\xae\x2d\x28\x86\x05\x42\x94\xb7\x4a\x2c\x4a\x90\x7b\x99\xd5\x40\x51\xf4\x60\x24\x74\x6b\xa5\x6f\xac\x96\xa4\x2d\xeb\x6a\xcb\xbd\xd8\xb2\x5a\x86\xb3\xe0\xb9\x83\x62\xb3\x11\xf8\x98\xb5\x2b\x2b\x06\x66\xe9\xbd\xa6\x52\x5d\xbe\x79\xc8\x85\x7c\xb5\x1c\xc0\xed\x42\x1d\x13\x90\x2b\x8d\xaf\x2b\x73\x59\x3e\x3d\x03\x7a\xa7\xb7\xa5\xb8\xb8\x80\xaa\x2b\xd6\x56\x5a\xc8\xfa\x13\x9b\x99\x6a\x21\x40\x29\x38\x5d\x61\x00\xce\x0f\x10\x11\x64\x22\x8e\x17\xc9\x78\x10\x3d\x87\x73\xda\x69\x0a\x67\x8f\x18\x03\x3f\x55\xaf\x26\xd6\x66\x60\x2e\x0c\x3e\xb5\x3e\x43\x07\x18\x48\xc7\xc3\xe8\x7b\xfd\x4f\xe9\xfb\x60\xf7\x11\xa2\xde\x30\xea\x6c\x0e\xd6\xb5\xf2\x50\xe9\x1f\x3b\x59\x52\xbe\x15\x47\x4e\xb1\x79\x81\x63\x36\x95\xad\xb5\x87\x07\x96\x41\x97\x52\x70\xcd\x29\x8a\x95\x4f\xbe\xca\xaa\x10\x06\x69\x26\xc1\x53\x17\xf0\xa1\x2b\x55\x81\x75\xda\x07\x31\x77\x75\x78\x17\x7e\x59\xf2\xf3\xea\x70\xe3\x3b\x38\xf9\x6f\x5d\x9f\xfc\xaf\x4f\xfe\xff\xe5\x27\x7f\x63\xf8\x8f\x8f\x16\xaf\xc8\xe8\x5f\x1b\x72\xf2\x53\xe5\xa1\x38\x44\x62\xa2\x68\x05\x4f\xe8\x74\x0f\x32\x7e\x92\x4c\xec\x0d\x41\x87\xf3\x3b\x63\x0f\xe7\xac\x8d\x93\x20\x88\x5d\x9c\x1e\x43\xef\xbb\xb6\x81\xd6\x0f\x54\x58\x74\x6f\xb3\x17\xda\x2d\x3b\xeb\x20\xc5\xdb\xef\x0f\x2b\x5e\xc4\x49\xe6\xfe\x42\x9e\xea\xc4\x32\xdd\x7b\xb0\xfb\x4c\x4e\xf2\x38\xfa\xfe\xbb\x08\x02\xc8\x2d\x64\x28\x8d\xc3\x33\x91\xfa\x1e\x1e\xd4\x9a\x80\x51\xdb\xe0\x4f\x79\x8e\x7b\x01\xdd\xcc\x8e\xc9\x94\x4a\x99\xab\x2b\xe8\x69\x42\x8f\x16\xca\x1c\x1a\x24\x5c\x15\x51\x77\x57\xf4\x77\x63\xbd\x1f\x3d\x80\xbf\x1b\xfd\x68\x30\x18\xf4\xa3\xff\x15\x1f\x5b\xb7\x7b\x70\xd8\x89\x8a\x59\x32\x4a\x27\x29\x2d\xa4\xbd\x57\xcf\x37\xb6\xee\x6c\xdc\x19\x78\xbb\x4c\x8e\xe9\x72\x1c\xbe\xe3\xd0\x73\x7a\x13\x0b\x1d\x81\x01\xda\x57\x6b\xfc\x66\x59\x4a\x52\x63\x05\x26\x5d\x1b\xd8\xf5\x5b\x13\x0a\x15\xf3\x79\x84\x11\x75\x76\x3b\x03\x40\xcb\xc3\x7c\x9c\xec\x96\xdd\x75\xa6\xb5\x86\xb1\x75\xfe\xd7\xcb\x26\x06\x48\xef\x76\x91\x58\xcb\xfc\xcd\x6c\x96\xcc\x1f\x8a\x23\x97\x56\x65\xb3\xec\x62\x71\x28\xe8\xa4\xbb\xdd\x53\x2f\x72\x65\xc2\x7a\x7f\xdb\xb9\x31\xa3\x5c\xc1\x30\xca\x6e\xa7\xd3\xb3\x1f\x2b\x67\x3d\xdb\xba\x0a\x34\xcc\x20\x92\x85\x3a\xaf\xfd\xe0\x03\xcc\x8f\xf7\xc4\x38\x85\x40\x88\x1f\x3f\xdc\x8b\xfe\xb7\xe7\xb9\x79\x0f\xcc\xac\x9c\xd8\x90\x4b\x21\xac\xe8\x96\x28\x74\x53\x14\x6a\x70\xce\xae\xc2\x4b\x7a\xee\xc4\x7b\x83\xdf\xf2\x34\x83\x61\xba\x96\x8a\xa0\x7c\x87\x25\x03\x53\xfc\xf4\xf9\x23\x20\xec\x8d\x75\xc5\x94\xa4\x85\x1f\x52\x7e\x80\xe2\xbe\x5b\xbf\xb3\xed\x12\x9c\x58\x1c\xdf\x7f\xb7\xb1\x5e\x45\x68\x36\x7d\x19\x57\xb5\x44\x4d\xb2\x70\x2d\x15\xcd\x93\x93\x38\xcd\x48\x77\x04\x79\x7d\xdb\x09\x8d\x9c\x62\xcd\x1e\x24\xb0\xb1\x5b\xde\xec\x39\x6e\x6b\x90\x59\x29\x30\x6d\xf1\xfa\xbd\x65\x22\x67\x9a\x44\x59\x7b\x2f\x2b\xc9\x23\x4e\x5f\x20\xab\x17\xfd\x3f\xc0\xda\x4d\xaf\x16\x72\x8a\x23\xcd\xcf\x43\x2f\x70\x75\x5d\xba\xa4\xa9\xcf\x9a\xa7\xe6\x77\x48\x64\x82\x8e\xeb\x40\x1a\xfc\xe3\x85\x3a\x26\xc8\xb7\x0e\x92\x7d\xaa\xf9\x0a\x4f\xce\x1a\x77\x68\x1c\x9e\x04\x69\x09\x6d\x96\x9c\xdf\x55\x2f\x90\x68\x53\x3f\x13\x8c\x33\xb3\x38\x51\xaf\x73\x1c\xa2\x02\x98\x5d\x9d\xe3\xf5\x3d\x66\x59\x4a\x8c\x86\x21\x9c\x6b\xb6\xc6\xb5\x62\x90\x33\x60\x35\x36\xf9\x0c\x88\x8b\x07\xca\x67\x00\x7b\xa9\x20\x03\x3e\x46\x1b\x77\x18\x0b\x03\x35\xd0\xd6\x1d\xb0\x63\x17\x65\x8c\x7a\x68\xeb\x8e\x65\x02\x30\x1e\x27\xa4\x59\xc4\x3d\xb0\x4b\x85\x44\x4d\xb7\x6d\x49\x58\xf7\xf3\x01\x78\x2a\xa0\x62\x36\xf3\x73\x16\xb3\x74\x4b\xc2\x16\xee\x03\x18\x3a\x5c\xec\xb2\xdd\x0b\xa6\x2f\x42\x4f\x9a\x42\xdc\x97\x2b\x9a\x94\x49\x68\xaf\xfb\x82\xdc\xfd\x0b\x81\x42\x0a\x65\x3f\xa4\x3f\xae\x1e\xa1\x44\x42\xee\x6e\x26\x06\xa9\xc5\x71\x4c\xd2\x1a\xee\x6f\x1f\x46\xd3\x45\x91\xbe\xd7\xd1\x09\xd3\xc3\x74\x9a\x96\x5a\xc0\x11\x83\x78\xb7\x76\x28\x36\x3f\x21\xd4\x0b\x29\xe7\x7d\x3a\x52\x1b\x60\x4c\x8e\x3d\x57\x7f\x58\x4b\x7f\x1c\xb8\x34\xa4\x23\x05\x14\x6a\x17\x9a\x08\x61\x1e\xc4\xa7\xe9\x11\x78\x9a\x39\x3e\x11\xf2\x56\x31\x12\x32\x25\xb1\x25\x29\xff\x24\xd9\xe0\x34\x7d\x97\xce\x20\x4e\x0d\x0a\x41\xf0\xb5\x26\xd6\x5a\x32\xcf\x62\x7a\x3a\xf1\xf6\x81\xe8\xd1\x5b\xe9\x55\xf4\x2d\xcd\xeb\xff\xfd\x59\x8e\x34\x3b\x7a\x0b\x43\x7c\x8b\x6f\x89\xde\x8e\xd3\xa3\xb4\x0c\x11\xe5\x03\xc5\xd2\x1c\x8a\x3c\x54\x7b\xaa\x9a\x01\xe5\x33\x46\xfc\xf4\xb7\xd9\x16\xb4\xfa\xc0\x5d\x91\x87\x0e\x5b\x94\x33\xfa\x90\xf6\xa9\xce\x3f\x1e\x77\x76\x56\x82\x3c\x53\xf2\xd8\xae\xb3\x73\x77\x79\x05\x37\x85\xa4\x84\xa2\x12\xb6\xc2\xcd\x5d\x00\x1d\x8f\x00\x1b\xa2\xa6\x2e\x89\x53\xdd\xef\xef\x8a\xfd\x49\x37\xd1\x53\xcf\x06\x6e\x6d\x3a\xfb\xad\xf6\xba\x61\x37\xc5\xea\x94\x0d\xee\xb4\x7a\x6f\xc3\x70\x85\x84\x4d\x31\xa2\x21\x10\x4c\x5a\x2e\xb4\x6f\x5c\x71\x0c\xcc\x4a\xd8\xb4\x5c\xdf\xea\x54\xcb\x5e\x36\x4e\xe7\x89\x6d\xc0\x60\xbf\xb1\x29\xfa\x4a\x96\xd5\x8f\x6c\xf0\xd5\xd4\x2a\x6b\x69\x15\x9b\x5a\x35\x6d\xad\x2e\xc3\x8b\xec\x9e\x04\xfd\xe5\xda\x47\x60\x9b\x33\x74\x1e\xbf\xfe\x05\xe6\x41\xbd\x6e\xe1\x18\x60\xa9\xba\x6f\x3d\x87\x5f\xe7\x75\xfc\x5a\x3d\xa5\x23\xe4\xca\x00\xcc\x62\x75\x92\xaf\x10\xc6\xc7\x3d\xb9\x13\x3d\xa8\x54\xca\x9b\x7a\x2f\x0a\x28\x3e\x94\xc2\x83\x9e\xd3\x51\x4b\x5a\xe8\xdc\x63\xfe\x59\x6a\xe5\x84\x94\xfb\x79\x49\x89\xac\xac\x27\xaa\xda\xc9\xcb\x4e\xeb\x07\x85\xd8\xf5\xca\xd7\x83\x7c\xcc\xc6\xf1\x7e\x70\x54\xe2\x0c\x1d\xd0\xda\xe0\xd8\xb8\xb6\xc6\x1f\xa5\x43\x09\x6b\xff\xfe\xc7\xe3\xfd\xf5\x5b\xdf\x1f\x7c\xdc\x3c\xef\x0a\x3a\x80\xdf\xbb\xb7\xfe\xf7\xe0\xe3\xc6\xd6\xf9\x27\xfd\xb1\xb5\xde\xdf\xda\x38\xef\xfd\xcf\xda\xa0\x44\x25\xa8\xde\xc0\xad\x77\x79\x55\x8c\x81\x81\xd3\xf3\xbc\x8d\x25\x11\x26\x9f\x60\xe2\xe9\x3f\x88\xb6\x47\x66\x09\x5e\x0d\xde\x1e\xf9\x2b\xc9\x41\x9c\x19\x14\x7f\xdc\xb3\xb5\x8d\xa1\xdc\xc3\x79\xb7\x2f\x38\x6c\xf1\x99\x55\x0c\xdc\xe2\x3e\x57\x33\xf4\x20\x1b\x69\x35\xf8\xcd\xf5\x56\x56\x9b\x24\x52\xc2\x48\x8b\xc5\x09\x00\xbe\x29\xe4\xf1\x41\x10\xd0\xad\xef\xbf\xbb\x25\xe4\x77\x95\x8d\x67\x5c\xec\xdd\x28\x9f\x46\x5d\x71\x64\x58\xdb\x7b\xfc\x30\x82\x73\xc3\x70\x73\x7d\x7d\xab\xe7\xf2\x64\x56\xad\x7f\x0a\x65\xb9\xde\xc0\x55\x5e\xcb\x61\xcb\x33\xe1\x66\x3f\xda\x6c\x67\xab\xca\x99\xaa\xb5\xa5\x44\xc9\xd1\x20\xfa\xc7\xcb\xc7\x3f\x7b\x8e\x04\x75\x81\xf0\x68\x2a\x6b\xf4\x27\x95\x41\x36\x0d\xcf\x10\x40\x17\xbd\xcc\x79\x43\xfe\xae\x1f\x6d\xc3\x63\xb9\x4e\xa7\xd5\xb8\x47\xd3\x14\x1f\x92\xe9\x0e\xa2\xf2\x29\xcd\xdc\xf1\x01\x16\x7e\xde\xfd\xfb\xf3\x9f\xfe\xf9\xfc\xe5\xdf\xdc\x59\xc5\x3a\x2a\xe6\xd4\xad\x3f\x38\xb9\x04\x74\xe9\xb1\x6f\x6c\x2c\x3f\x72\xb9\x9a\xc2\xe7\x12\xff\xe0\xe1\x0f\xcd\xab\xc0\x1b\x5e\x1c\x38\x87\xf0\x7b\x27\x39\xb8\x90\x03\x32\xeb\xd0\xe1\x0f\x78\x9b\x1d\x62\x2b\x8f\x32\xea\xfc\xa1\x4f\x29\xd6\x09\x95\xce\x28\xf6\x79\x46\x9c\x79\xc4\xc2\x59\xd7\x2e\xce\x2c\x29\x4f\xa1\xd7\x19\xa4\x2a\xdc\x6e\x81\x56\x78\xa5\xda\xc3\x2c\x52\xea\x73\xbd\xe2\xea\xd0\xfe\x04\x35\xfe\xf6\xb5\x1a\xff\x5a\x8d\xff\x55\x18\xf0\xa5\xb3\x51\xbd\xfd\xde\x25\x2c\xee\x56\x4d\x14\xba\xd5\x9d\x95\xab\x73\xd8\x43\x55\xed\xcd\x46\x41\x4b\xb0\x59\x0c\x7e\x70\xb2\xc4\xb2\xfe\x7e\x8b\x9a\x0b\xef\xe1\xa9\xba\xaa\xe6\xf1\x7b\x95\xd7\x02\x69\xaf\x83\x36\x3e\xf0\x87\x52\x59\xb0\x53\x7d\xc3\x8b\x5c\xb1\x54\x09\xdd\x8f\x00\x3d\x34\xe5\xb5\x2b\x4a\xa7\xd8\x40\x30\x97\x0e\x8e\xaa\xc3\xe3\x5d\xf6\x2c\xfb\xe9\x22\x07\x26\x46\x6f\x09\xf7\x5e\x3c\x8c\xcc\xbd\x32\xbd\x30\x14\x45\x79\x98\xf8\xb7\xc4\x06\xe5\x85\x77\xd7\x75\x86\x19\xec\x41\x36\xe6\xed\xb3\xe6\x2b\x2b\x8b\x56\xf4\x1b\x83\x27\x7b\xaf\x5e\x3f\x7e\x86\x2b\xe8\xe1\xf3\x67\xcf\x1e\x3f\x7c\xbd\xf7\xfc\x59\xf4\xf2\xf1\xab\x17\xcf\x9f\xbd\x7a\xfc\xaa\xb2\x55\x78\xb5\xc5\x9b\x85\x6f\xbe\x39\xad\x7d\x2b\xcd\x08\x4f\xe2\x0f\x70\x59\x32\x4d\x3e\xa4\xe5\xd9\x30\xba\x83\x94\xe5\xf4\x10\x75\xa1\xda\x0e\x01\xaa\x32\xfb\x4d\x2f\x10\xd0\x44\xda\x1c\x7c\xb4\x63\x15\xe3\xc1\x6f\xec\xda\x4e\x58\x31\x9c\x91\xbf\x8c\x41\x41\x3c\x3a\x16\x3d\x2b\x85\xe4\x48\xe2\x2b\x6d\x42\xc0\xd0\xc6\xae\x93\x72\x37\x7e\x00\x36\x1d\x0e\x66\x8c\xd7\x51\x5e\x6f\xd1\x02\x21\x1c\x6a\x54\x54\xff\x36\x4c\xc8\xfb\xf8\x36\x2e\x1d\x1f\xf4\x2a\x63\x94\xe2\x58\x25\x58\x20\xca\xa8\x1b\x41\xb9\x2a\x5c\x2a\x56\x23\xfb\xee\x56\x74\xee\x61\x71\x22\x56\xb3\xe5\x11\x60\xa5\xe5\x78\x60\x28\x81\xd6\x6b\xc0\x4d\xec\x56\x6e\xda\x62\xfe\x8d\x93\x69\x22\xf8\x55\x4d\x0d\x95\x31\xa4\x03\xaf\xb0\x7f\x85\x5d\x0b\x09\x51\x12\x04\xd5\x87\xca\x1d\xb2\x95\xca\xc8\x59\x0e\xbc\x5d\x07\x8e\x0f\xf1\x75\x57\x94\x30\x68\x93\xf0\x8a\xab\xf6\xc0\x17\x99\x20\xfc\x19\x9e\xc7\xc4\x23\xbb\x30\xf7\xac\xca\x57\x95\xcb\x06\xfb\x8e\xbc\xf6\x77\xf2\x6c\x6e\x1c\xf4\xaa\x25\xfe\xe8\xf1\xad\x87\xbf\xbc\x79\xf6\xb7\xc7\x2f\x99\xa7\x82\xd1\xf1\x22\x7b\x97\x8c\xe5\xab\x12\x7a\x31\x2a\xff\x09\x5c\xce\xa6\xf1\x28\xe9\xae\xfd\xeb\x7c\xff\x5f\xd9\xbf\xe6\x07\xf7\xff\xf5\x71\xed\xa8\xdf\x39\xff\x74\xeb\xd6\xa7\x8f\x9d\x1e\xba\x16\xfe\x18\x84\xff\xd7\x81\x2a\xb1\x2f\xcb\x1c\x40\xa1\x7d\x55\xea\x60\xff\x63\x6d\x3b\xfb\xac\x25\x5d\x68\xbf\xa9\x2d\xd6\x92\x6e\x88\x95\x91\xd7\x7c\x90\xdd\x51\x9c\xd4\xc2\x80\xbf\x66\x11\xf1\x8c\xbf\x80\x77\x67\xd1\x74\x22\xdd\x61\xa0\xa7\x0d\xac\x60\xc5\x23\x7d\xc8\x7b\x08\x59\xf6\xca\x95\x7e\x67\x1c\x98\xe8\x66\x44\xef\x5f\x2d\x51\x5d\xdf\x59\x3b\x9c\xcc\x77\x6a\x10\xb2\x05\xc3\xbe\xb3\x52\xd2\x9a\x86\x8c\x69\x56\xfc\xc5\x67\x3a\x73\xcf\xed\x8c\x1d\x02\x5e\x1a\x72\xa1\xeb\xbb\x6c\x94\x4c\xd1\xa1\xbe\x7a\xc4\x69\x95\x19\x4d\x93\x78\xae\x4c\xb8\x9c\x56\x64\xb2\xb3\xa0\xc3\x40\xe8\xab\xa1\x54\x15\x85\xf6\xb8\xfa\xbd\xce\x8f\x8d\x5c\x61\xfb\x26\x71\x66\xb1\x0b\xb8\x8f\x5b\x5f\x17\x72\x11\x5e\xce\x04\xee\x5a\x83\x8e\x1f\xf0\xdd\x1e\x62\x47\xe1\x0b\x38\x08\x84\xf2\x46\x7a\xa1\xa0\x28\xf2\x5d\xdf\xf2\xa8\xf2\x67\xcc\x21\x11\x8c\xf6\x23\x2d\x2b\xbd\x0e\x13\x8b\xa0\x98\xdf\xb6\xdd\x9e\xa3\xad\xe7\xe0\xd2\xf9\x0f\xf0\xc8\x9f\xe5\x16\x2a\x0e\xad\x05\x0f\x06\x2d\xad\x1c\x7c\x69\x8c\xd4\xc3\xfd\x15\xda\x70\xd5\xc1\x40\x9e\xb5\x53\x72\x57\x2f\xb9\xde\x89\x8a\x6c\x6f\x71\xdf\xbd\x31\x95\x12\x9c\x57\x08\xdc\x9c\xe1\x8a\x3e\xbc\x74\xda\xab\xe1\xb0\x01\x68\xcb\x5b\x7d\xd8\xa6\x94\xc9\x14\xea\xb5\xba\xf8\xf8\xf4\x49\x7d\xed\xaf\x1f\xe8\x2d\x13\xaf\xb0\xa9\x6f\x06\x5a\x26\xd8\x25\x28\x91\x4b\x74\xc1\xcd\x0b\x7c\x17\xc9\xef\x9d\x06\xd0\x81\x6e\x58\xd5\xad\x76\x35\xb5\x8e\xf8\x4a\xa5\x90\xf1\xd2\x2c\x85\xc4\x72\x3c\x7d\xa1\xc6\x1d\xf6\xf7\x31\x43\xb3\x24\xe2\x1a\xd4\xfa\xf6\x9d\xfa\x68\x2f\xd8\x6a\x17\xf0\xf2\x2e\x39\xa3\x93\x62\x68\x00\xb6\x13\x98\x6e\x18\x68\x70\x1c\x17\xcf\x4f\xb3\x17\x18\xf2\xa8\x3c\xc3\x90\x76\xbd\x40\x70\x7a\x1f\x3d\x10\xc5\xae\xda\xb6\xb3\x23\xe4\x70\x21\x96\x77\xdc\x38\xed\x52\xb6\xa8\xa9\xd3\x33\xb3\x30\xcb\xe4\x21\xf7\x41\x28\xdd\x8d\x47\xec\xb8\x21\xd0\x5c\x94\x29\x05\x23\x49\xc7\x8c\xa8\xb9\x59\x68\x25\xc2\xc3\x76\x9c\xdd\xea\xd3\x92\x09\xcc\x74\x06\x27\x57\x2a\xdc\x27\x2a\xb0\x7b\x75\x94\x67\x89\x8a\xde\x72\xe3\xad\x2b\xf6\x9f\xce\x53\x8c\x95\xdb\x73\xb8\x11\x03\x71\x8e\x50\x6f\xfd\x33\x94\x72\x31\x78\xa3\xaa\x76\xa9\x40\x0a\x0e\xbd\xee\x05\xc1\x0a\xa7\x1f\xa7\xde\xe4\x15\x7b\xba\x62\x6f\xb2\x3b\x16\xe7\x5e\x02\x45\x0a\x4d\xcd\x58\x0a\x1d\xa1\x1a\xcf\x9a\x5a\x52\x63\xcf\x7e\x43\xa3\xaa\xb1\x78\xbe\x98\xd8\x10\x94\x1a\x96\xdc\x47\xdd\xad\x37\xb4\x4f\x3a\x66\x77\x2b\xc1\x33\x8a\xe7\x8e\x07\x34\x31\x60\x65\x6d\x5e\x9b\xad\x76\x6e\xba\xec\xf6\x66\x67\x35\x2a\xb8\x61\xf6\x80\xdb\x69\x1b\x3e\x88\x2f\xb6\xc0\xb9\xcf\x62\x06\x3a\x41\xd4\xad\xd1\x4d\xad\x38\xa8\x6b\xad\x4a\xc7\x72\x04\x59\x4d\x98\xad\x5f\x2a\x5c\x64\x31\x36\x53\xd9\x52\x14\x16\x3c\xd6\x23\x4d\xd5\x5a\xd0\x33\x87\xba\xee\x6e\x5a\xe9\xed\xc6\xd7\xe3\x1a\x0c\x7a\x4e\xda\x2b\x5e\x69\x83\x4a\xf7\xf6\xb5\x4a\xf7\x5a\xa5\xfb\x55\xa8\x74\xe5\xc3\x8a\x4b\x3d\xc7\xde\x8d\x05\xb3\x8b\xfe\xb6\x38\x89\xdf\x8b\xe9\xfd\x21\x86\xcf\xbf\xbc\xa3\x4f\xc1\x26\x83\xea\x5e\xc1\x95\xf6\xb2\xb4\x4c\x05\x43\xff\x3d\x89\xfe\x4a\xbd\x00\x42\x8d\xa3\x02\x2d\xb1\x94\xc1\x0d\x0e\x14\x96\xaa\xe5\xe4\x7c\x80\x5a\x5d\x55\x4c\x45\x11\x91\x01\xa8\xf6\xc6\xc3\x68\xbd\xe9\xe6\x8d\xac\x3d\x60\xf8\xae\x5b\xdd\xa0\x99\x49\xd0\x9d\xae\x79\x85\xa6\x62\x5d\x4d\x14\x42\xb1\x25\x63\xd0\x13\x70\xc2\x4b\xaf\x53\xfa\x4c\xd5\x33\x51\xd5\xa8\x2c\xe5\x7b\x37\x1c\xe0\xcf\x8c\x00\xda\xf3\x7a\xbf\xb6\x22\xd1\x53\xe1\x8a\x97\xda\xea\xcb\xc6\x2c\xa7\xa9\x94\xd5\xab\x52\x2d\xab\x26\x3d\x63\x1e\x6d\xb6\x7b\xa3\xc3\xa2\x4e\xc5\xe3\xf7\x70\x46\x55\xb3\x13\xed\x3d\xc2\x1c\xd5\x3b\x3d\x69\x37\x6f\x56\xf9\x19\x0a\xbf\xfe\xa1\xb7\x42\x5e\x35\x7d\xcf\x3d\x91\x1c\xa9\xe7\x55\x88\xfe\xc2\x81\x49\xbd\x10\x6a\x6f\x78\xa1\x70\xa0\x0f\x8f\xca\x80\x28\x6c\xaa\xa3\x95\x75\x4d\x91\xcf\x02\x2f\xb3\x9d\x06\xc2\xe6\x19\x0a\x0d\x4e\x7b\x4e\x4c\x60\x4a\x74\x2e\x43\xc9\xa7\x0f\xd3\x39\x4b\xa0\xfb\xba\xed\x81\x60\xae\xf3\xb3\xae\xf2\x86\xfc\x4a\x6c\xd7\xd3\xe4\x29\x21\x1c\x2e\xba\x83\x19\xa6\x26\x39\xad\xba\x23\x61\x70\x39\x81\xfa\xa0\xa5\x85\x77\x45\x37\xcc\x82\x48\xa5\x11\x45\x5a\xb6\x45\x32\x43\xcd\x0f\xd8\x9b\x20\xd5\x70\x20\xe9\x76\x41\xc1\xc2\x99\x1b\xc0\xe8\x5a\xb7\x13\xaa\x4e\xca\xb0\x8e\x4f\x49\x41\xdc\x18\x9a\x4f\x27\x49\xaf\xec\x9c\xb9\xa8\xf5\xd8\xca\x9f\x3c\x31\xa2\x43\x7c\x8f\xd6\xc0\x8e\x1e\x00\x50\xb5\x8b\x6f\xd9\x71\x87\xbf\x50\x5d\x05\x31\x55\x59\x95\x12\x70\xc6\x3d\x54\x1e\xc9\x45\xd1\x55\xbc\xa7\x27\x64\x9b\x64\x3a\x76\x4c\x0f\x64\x2b\x56\x4f\x1d\x9e\xc3\x3b\xe8\x30\x1e\xea\x9a\x43\x86\x2a\xd9\x89\xfa\xa0\xc8\xc2\x77\x84\xe5\xb1\x37\x05\x3b\x50\xac\x4d\x7d\x4b\x16\x67\xeb\xe1\x3d\x59\x51\xd6\x27\xe5\x44\x10\x03\xef\x5c\x8b\x81\xd7\x62\xe0\xd7\x12\x98\x87\x16\xcd\x55\xbd\xd0\xbb\x9a\xbb\x7b\x00\x79\xaa\xd4\x8d\x95\xc6\xca\x78\x4e\x94\x91\x5a\x94\x15\x32\x7c\xb2\x53\xa4\x74\xb9\xa6\x72\xe1\xd3\xba\xb8\x47\x9e\x67\xf2\x8d\x64\xb0\xce\xc0\xd0\x27\x3f\x0f\x4a\x68\x0c\xa1\x79\x06\x2b\x41\x9e\x9e\x43\x45\x9c\x1c\x4b\xe9\x8a\x1a\x83\xa7\x71\x26\x58\x97\x79\x9d\x0f\x2c\x8b\x50\x61\xa9\x02\x94\x0b\x0f\x03\xce\xf6\xfb\x13\x0b\x43\x5e\xc5\xf3\x93\x06\xfb\x77\x41\xbe\x09\x3c\x1f\xb4\xfd\x7b\x3a\xe2\x1f\x58\x75\xa1\xcf\x85\xaa\x48\x14\x47\x09\x7a\xa9\x6c\x0c\x73\xe2\x3a\x16\x55\x6d\xda\xed\x21\x89\x79\x88\x90\x6d\x54\xc6\x9a\xb0\xdc\x89\xb2\xf0\x39\x9a\x38\xb4\x1d\x9f\xf2\x65\x58\x80\x60\xa3\x53\x9a\xdc\x1c\x93\x33\x4e\x73\x49\x21\x43\x73\xc8\x6d\x57\x8f\x73\x10\x3d\x05\x56\x0e\x0f\xf7\x29\x88\x34\xe2\xc3\xf3\x42\x69\x79\xf6\x6c\x8d\x37\x35\xa8\x4f\xcf\x04\xc1\x18\xc7\x18\x7d\x90\x22\x93\x0f\x29\x5e\x9b\x85\x70\xf7\xe7\x8c\x3f\x74\x65\x61\x77\xa2\xcf\x16\x77\xc7\xc3\x64\xab\x68\x3b\x6e\x80\x13\x1d\x4a\xc6\x3e\x88\x35\x85\x8f\x79\xf9\xe2\xa1\x8c\x30\x51\x1f\x3b\xc6\xa0\x4d\xba\x7a\x25\xc2\x43\xa4\xeb\x13\xa7\x8b\x26\x02\xdd\x03\x48\x1f\x4b\x16\xd1\xe9\xa4\x00\x3a\x58\x2d\xb5\xd8\xb8\x11\xe0\xae\x15\x14\x14\x7a\xdc\x18\x28\xed\xc8\x90\xda\xe9\x62\x3f\xc2\x20\xd9\x75\x21\xa4\x04\x1f\x98\x58\xc1\xa2\xf6\xb1\xc8\x01\xb0\x03\xf6\xd9\xe0\x59\xdf\xc3\x3a\xf1\x7b\x1d\xbd\x96\x63\xde\x47\x3c\x39\xe0\xad\x3f\x2b\xda\xee\x88\x2b\x70\x1f\x88\x91\x62\x07\xcb\xe5\x28\x74\x37\x2b\x74\x36\x83\xc7\x9e\xfb\x41\x00\x5d\xd5\xb0\xc6\x09\x50\x28\x64\x91\x7a\x3f\xa5\xe8\x70\x89\xc8\x45\xb1\x5a\xb7\xad\x90\xd0\x2e\x06\x11\x77\xc7\x2a\xf7\x11\xd7\x4b\xf2\xd2\xce\x97\xa5\x3c\x81\xc0\x6c\x19\x70\x40\x3f\xc0\x90\x3e\xa6\xd4\x7b\x5b\x46\xcd\x52\x85\x32\xa3\xce\xad\x32\x57\x71\x78\xa8\x52\x2e\x72\x09\x9f\xb6\xb4\x75\xf8\x1b\x47\x17\x64\xa8\xa1\x1d\x2e\xd2\xe9\x18\x11\x26\x07\x05\x99\x9e\x7f\x5b\x64\xf8\xaf\x9f\x3f\x7a\x7e\xe3\xc6\x0d\x14\xef\x3b\x45\xb4\x38\x82\x9b\x12\x8a\xe0\x00\x07\x82\x05\xbc\xed\x48\x4b\xdd\x4a\xc6\x5c\xca\xc2\x6f\x65\x57\xa3\x6f\x48\x80\x71\x60\x86\x7e\x6f\xbd\x61\x45\x7a\x3a\xfc\x6d\x1f\xb2\xf7\xd7\x0f\xe0\x72\xdb\xfa\x14\x62\x9d\xb2\xdb\x74\x41\xe9\xc7\x06\x96\x81\xb1\xec\x84\xaf\x8a\x9c\xda\x11\x32\x62\x5c\xd8\x43\xaf\x46\x54\xd5\x2d\xaa\xbe\x54\x37\x46\xa7\x14\x02\x25\x0b\x3f\xcb\xf2\xfd\x7c\x4b\xf9\xdd\x9c\x86\x97\xf1\x03\xed\x46\xb0\x08\x85\x2a\xb0\xce\xea\xd8\x96\x2d\x51\x9a\x8b\x29\x73\x3f\x63\xc5\x62\xd1\xe6\x75\x01\xd3\x3c\xb7\x61\x82\x97\xed\x70\x66\xa6\x8d\x22\x1d\x03\x9a\x60\x38\x15\xbb\xbb\x56\x35\xd5\x43\x70\xaf\xa1\x24\xa9\x40\x56\x5b\x4f\xcf\x2a\xcc\x95\x28\xb2\xd3\x52\xe9\x33\x83\x90\x59\x70\x1f\x26\x36\x76\xed\x81\x89\x4c\x59\x40\x33\xa0\x84\x4d\x71\x52\x26\xa5\x4b\xd8\xd7\xd3\xdc\xf7\xf5\x38\x18\x0c\xce\x79\x7c\x37\xd7\x97\x9e\xd1\x64\xaa\xa2\x01\x4e\xda\xe4\xd3\xbe\xd2\xdd\xfc\x32\x44\xa8\x68\xc0\xf6\x49\xcf\x67\xad\x0a\xd1\xb2\x64\x85\x62\xe7\xa9\xb2\x81\x69\x7b\xfd\xf7\xdd\xb5\xde\xe7\x5a\xef\xf3\x55\x5c\xff\x8d\x0f\x2f\x71\xf3\x17\xd2\xfb\x68\x6d\x0d\x57\xfc\x10\x73\xd2\x1a\x9d\x47\x0f\x2c\x3e\x02\xc3\xb0\x5d\x7e\x78\x9a\x0a\x1c\xa9\x93\xbc\x53\x13\x28\x6c\xc5\xc8\x4b\xe2\x7c\xe4\x5b\xdc\xca\x0b\xae\xe8\x63\xe4\x48\x57\x8e\x1c\xd4\x85\x9a\xd9\xce\x22\x81\x3c\xb5\x4b\x37\xd4\x41\x47\xdf\x6c\x76\xf9\xea\x80\x45\x10\x5d\x5e\x3d\x5e\x03\x43\x14\xc2\x66\xd7\x6c\x97\x20\x74\x0c\xa3\x8e\x86\x73\xe2\x68\x88\xac\xf1\xe1\xdb\x50\xae\x12\x13\xb7\x6c\x13\x4c\x68\x54\xc8\xbc\xad\x1a\xd5\x70\xc1\x46\x43\xb9\xaa\xd1\x4d\xbf\x51\xd1\xc7\x5f\x92\x0f\xcd\xc3\x14\x40\x55\x63\xb4\xb3\xea\x07\xd8\xdc\x16\x01\x55\x0d\x2d\xdc\xd6\x66\xd0\x9e\x75\xdf\xc0\xa9\x89\xe8\x1b\x24\xf7\xe5\xd0\xfb\xb2\x5b\x08\x7c\x50\xb1\x73\x3d\x7a\x60\x76\x2d\xa2\x9d\xd5\xe1\x16\x6e\x51\x77\xaf\xb7\xa8\xeb\x2d\xea\x6b\xb9\x9a\x80\x60\x67\x17\xb9\x97\x90\xc0\x57\xfb\x2a\xd1\x44\xf0\x70\x76\x39\x93\xa1\x6e\x37\x42\x97\x20\xe1\x7b\x90\x86\x6d\x13\x40\x94\x39\xb2\x03\x34\x93\xc9\x0a\x6c\x54\xd5\xde\x28\xcf\x26\xe9\x91\x02\x63\xa1\x70\x38\xb4\x8a\xac\xa2\xc0\x4e\xe5\xb3\x35\xeb\x82\x46\x26\x2a\x98\x9f\x28\x14\xb8\x83\x0c\x4c\x54\x20\x7b\x05\xd8\x44\xd2\x16\xc3\xa1\x0a\x4a\x55\x60\xc0\x8a\xe7\x89\x0b\x24\x53\x75\x5d\xe4\xa0\x88\x43\xc0\x43\x6e\x95\x4d\x5e\x0f\xbd\xfe\xa8\x64\x2d\x84\xa0\xcf\xb4\x36\x77\x06\x5a\xe7\x2d\xdf\x08\xa2\x12\x70\xfd\x00\x3c\x9a\x44\xf2\xf7\x00\x75\x82\xcf\x27\x5d\x08\xf4\x4c\xae\x4b\xd6\xe1\x42\x61\x15\x22\x5f\x09\x36\x8f\x81\x49\x1f\x9c\xfd\x12\x17\xc7\xab\xd1\xd0\x4d\x26\x8d\xee\xaa\x91\x12\x58\xfc\xa7\x9f\xc0\xf7\xce\x17\xe8\x69\x47\x76\x89\x85\x15\x7a\x70\x86\x0d\x43\xa7\x77\xb3\xf1\x1e\x94\xd3\xd1\xbc\x82\x90\x34\x0e\x0d\x6b\xc6\x23\xfa\x3c\x4d\xbe\xd0\x00\xde\x40\x5b\x0d\x5d\xe7\x30\x95\x9d\x56\xf3\xc3\xc6\xf9\x10\xbc\x49\x7d\xa1\x71\x04\xdb\x26\x12\xe2\x43\xa9\x00\xa3\x51\x79\x53\xf0\x05\xfb\xff\x46\x37\xc8\x26\xc3\x9b\x04\x0e\x60\xf5\x59\x77\xef\x71\x79\x7c\xd5\x07\x84\xd6\x87\x83\x2b\x3a\x1b\x60\x00\xe0\xea\xb3\x01\xa9\x3e\x88\x8b\xa7\x49\xb0\x47\x33\xde\x99\x59\x43\x3f\x66\x17\xe8\x02\xbb\xe3\x26\xee\x4a\xfe\x2f\x2c\x0f\xdd\xe0\x18\xcb\x09\x46\x26\x79\x2a\x69\x65\xe8\x01\xad\xd4\xcd\x9c\xaf\xac\x50\xef\x06\x64\x19\xa5\xdf\xd2\x88\xf9\x34\xda\xa0\xbe\x10\xaf\x59\xe8\xe6\x4e\x5f\x0e\x53\x90\xc7\xb0\x42\xe7\xa9\x7c\x95\x0e\x78\x41\x39\x92\xbe\xd4\xd3\x59\x85\xdf\x83\x09\x13\x7b\x25\x3f\x70\x02\x67\x8b\x7c\xcf\x19\x89\x4e\x3b\x5f\x41\xf4\x2c\x35\x14\xe9\xe7\xef\x22\x83\x91\x45\x2f\x32\x1c\x59\xb4\xe5\x80\xda\x9c\x45\x81\xbb\xc4\x53\xb4\xdc\x6c\x3c\x39\x49\x40\xff\xf4\xa4\xd8\x94\x97\xaf\x8f\x50\xcc\x9a\xd3\xba\xc2\x1b\x46\xfb\x46\xa0\xa2\xeb\x3d\xe9\x46\xf3\x27\x73\x9b\xe7\x42\xf0\xc8\xe7\xc4\x4f\x34\x30\x0b\x1d\xe9\xdc\x63\x0d\x79\x35\x94\xa7\x7d\xd6\x68\xa0\x8a\x63\x73\x3e\x17\x4b\x69\xb7\x6c\x73\x72\x96\xa0\x95\x38\x0a\x41\xe8\x43\x6d\x0d\x96\x68\xdd\x11\xc7\x2e\x73\x3c\x59\x2e\x83\x96\xe0\xc0\xa4\x73\xc7\x86\x31\x01\x54\xe5\x70\x9c\xcc\x2f\x3f\xdf\xc1\x81\xa9\x55\xdf\x44\xcf\xc4\x8e\x9c\xa1\x69\x91\xf1\x72\xc3\x0a\xf5\xb6\xef\x2d\x71\x63\xff\x0a\x8b\x37\x3a\x5f\x8e\x7e\xa1\x89\x7a\xda\xc5\xfd\xb7\x19\x13\x08\xe6\x61\x42\x4b\x74\x9f\x03\x13\x8c\x94\x2f\x31\xe8\xfe\x4a\x05\x65\x9f\xcc\xc4\x91\x12\x0f\x6f\x8d\xe4\x2d\x41\x6b\x68\xdc\x87\x50\x78\x58\xaf\xa6\x3f\x57\x5e\x6b\x49\x8f\x6e\x31\xaf\x5b\x75\x22\xab\xdf\xc1\x8d\x4b\x4e\x54\xdd\xdc\xa8\x29\x7c\x94\x8c\xd2\x93\x78\x5a\x8d\x0a\x23\x05\xb6\x44\x82\x29\x50\x41\x94\x7f\xde\x01\xdb\xc2\x53\xc3\x60\xab\x63\x25\x57\x1c\xc1\x50\xbe\xae\x1d\x74\xf3\x0a\x62\x15\xd6\x33\x8f\xd7\x81\x13\xea\x52\x63\xd2\xa5\xbc\xc1\x55\x1d\x7e\xff\x4c\x9c\xe6\x22\x78\x7a\x09\x1a\xb5\x59\xb9\x14\x96\x64\x99\x36\x04\xe0\x83\x5e\x96\x02\x64\x8d\xad\x07\xd8\x72\x15\x37\x72\xb1\xc0\xe0\x5c\xc0\x36\x14\x40\x62\xd1\x15\x09\x88\x8d\xcb\x1b\x0e\x48\x2f\xe3\xd3\xf6\x4b\xdc\x2f\x10\x46\x44\x2d\x5c\x1b\xce\x06\x78\x38\x08\x77\x78\xb9\xde\xb6\xea\xea\xc5\xfb\xe9\xce\x54\x68\x8d\x85\xc6\x91\x1e\x65\x4b\x8c\xc3\x86\xae\x18\x47\x25\xd0\x67\x1e\x47\x8b\xce\x57\xf7\xf8\xca\x65\xed\x0a\xc2\x91\xc6\x5d\x75\x1d\x05\x90\x70\x47\x9d\x9c\x8b\x74\x14\xf6\x82\x2b\x3b\x11\xd8\x11\xd2\xeb\xc6\xc4\x20\xc3\x43\x0b\x03\x5c\x84\x62\xac\x11\x5e\x8a\x2b\x8d\x48\x3e\x55\x71\xcd\x9b\xa6\x8d\xa0\x07\x2a\xd8\x79\xc5\x14\xda\x75\x86\xc6\x5a\xd9\x91\x27\x4f\x9e\xb4\xec\xc3\xb4\x92\x82\x74\x4d\x4b\xb5\xfc\x2a\x99\xcf\x92\xc6\xed\x49\x63\x80\xa0\xeb\x11\xe0\xc1\xd4\xf4\xa2\x58\x1c\x9e\xa4\xe5\xaf\xf9\xbc\x49\x4a\x32\x80\x15\x2b\x3d\x94\x5f\x7f\xf5\xdd\xa2\x55\x09\x55\xb9\x15\x57\xb4\xb7\xde\x70\xfd\x6d\x14\x3f\x7d\x9e\xa6\x15\x1d\x56\x2a\xe8\x0b\xac\x04\x5c\xc2\x56\x0a\xca\xfe\x4e\x21\x79\x00\xf3\x4b\xba\xe2\x83\x0f\x61\x8e\x12\x56\x1e\x2b\x58\x95\xae\x05\xd3\x2a\x00\x29\x3b\x55\x65\x3b\x8d\xda\x06\xbd\x8c\x91\x98\x44\x5f\x0c\x60\x79\xf6\x7e\x65\x17\xaa\x2c\xc1\x9a\xb7\x57\xb4\x97\x21\xd6\x9a\x0f\x4c\xd4\xcf\xaa\xd4\x84\x69\x0d\x1a\x12\xb8\x85\x03\xc5\x64\xd3\xca\xee\x3a\x1f\xcd\x86\x8e\x80\x74\xb5\xd6\xd5\x36\x1d\xd5\x0b\x37\xcd\xe0\x1d\x12\x23\x52\xd9\x01\x64\x94\x72\xdd\x4a\x18\xe6\x99\xb1\xbf\x44\x1b\x27\x69\x66\x5b\xb7\x78\x2d\x48\x88\x0b\xd6\x7f\x2c\x0e\x70\x73\x31\xef\x75\x2d\x54\xc0\xb4\xda\x19\x96\xef\x91\xba\x9d\xad\xe9\x50\x18\xa4\xf9\x9c\x21\xaf\x83\xed\xc3\xc5\xf2\x3d\x3c\x8a\x8b\x17\xf3\x74\x54\x8b\xb3\x0a\x98\x0b\x2b\x81\x97\xef\xa5\x0c\x3b\x54\xd4\xf5\x52\xc3\x5c\xb0\x8d\x43\x76\x85\x56\xd3\x4c\x35\xd8\x67\xa2\x21\x15\x93\xe1\xef\x64\x6b\x53\xd7\x37\x17\x94\xb5\xc8\x59\x88\x75\xed\x32\x18\x19\x3b\x06\x76\xa5\x79\x98\x5a\x2f\x16\x44\x7e\x3e\x57\xe2\x8f\xb2\x7c\x40\x33\xe2\x7e\x04\xb0\x3b\x76\xd8\x57\x84\x0e\x35\x36\x51\x96\x0e\xde\x53\xd4\x3e\x7b\x6e\x47\x50\x0f\xc1\xac\x5b\x64\xe1\xe3\xb1\x87\x8e\x39\x3d\x8b\x53\x44\xd6\x13\xa6\x1e\x40\x85\x0a\x37\xef\xdc\xd8\xf5\x95\x39\xc6\x40\xc0\x74\x7b\x7d\x9f\x64\x9f\xe4\x47\x4c\x36\x6e\xd7\xa5\xd0\x40\x8d\x21\x46\xbd\x0b\x7d\x85\xfd\xae\x2c\x30\x38\x9a\xe6\x87\xf1\x74\x00\x48\x1d\xc4\x7e\xb2\x8c\x79\x16\x6a\x32\x1d\xc5\xb3\x67\x17\x6d\x16\x0a\x7b\x8d\x52\x62\x5d\x93\xcc\x2a\xc5\x34\x38\xaa\x9e\x2b\x6d\xc6\xd2\x0d\x4f\x4f\x93\x37\xaa\xc7\xe0\x18\x5c\xd9\xf3\x39\x86\x39\xab\xc3\x8d\xbb\xfd\x55\xcf\x40\x48\x1a\xa8\x1b\xcb\x1c\x91\x70\x1b\x13\x68\x4e\xc5\xe7\xf7\xf4\xa9\x69\x61\x75\xb8\x45\x45\xe0\x2e\x57\x7c\x6c\xf5\x6d\xf3\x41\xfc\x94\x48\x5a\x1d\x6e\x6f\xe3\xb7\x32\x23\x12\x09\x54\xbd\xe4\xec\xe2\x9b\xba\xa5\x2e\x7b\x45\x02\x34\xa8\x8c\x80\xc4\xe7\x16\xd8\x23\x7e\x7f\x6d\x8f\x78\x6d\x8f\xf8\x55\x98\xcc\x7b\xc6\x88\x97\xb6\x99\x6f\x6f\x26\xd8\xc2\x06\x10\xe1\x9e\x25\xe5\xe7\x34\xb1\x6f\x69\xb2\x12\x5d\xca\x66\xa5\x85\x49\xfd\xda\xda\x9a\xf1\x49\x13\xf2\x73\x23\x03\x36\x02\x8b\xc7\xea\xc4\x64\x44\xf1\x2c\x65\x7d\xff\x4c\x07\x12\x3f\x18\xbd\x23\xf0\xd8\x11\xeb\x2f\x2a\x5c\x25\x62\x53\x77\x74\xe3\x5e\x2b\x55\x40\x4b\x08\x7c\xb5\x36\xea\x82\xd6\x02\x9b\x9a\xbd\x79\xf1\xdd\x45\xec\x16\xdb\xeb\xd7\xbb\xc5\xf5\x6e\x71\x6d\xbd\xfe\x07\x58\xaf\x5f\x9d\xa1\x79\x4b\x3b\x78\x63\xca\xf9\x42\xa4\x80\x47\xb1\x6b\x7b\xce\xcf\x6d\xcf\x79\xde\xce\xc2\x4f\x6c\x30\xc6\x6c\xb0\x4e\x3f\x6c\x00\x7d\x15\xf1\x4c\xce\xea\xdb\x20\xd0\x25\x6e\x28\x05\x9f\x11\x1b\xc1\xcb\xf8\xf4\x6f\x49\xd3\xcd\x05\x07\x15\x8d\xff\x9f\xff\x13\xea\x5f\x10\xa8\x46\x75\xde\xfe\x46\x2e\xdc\x9e\x95\xc9\x0c\xf9\x28\xa1\xe5\x7d\x75\xeb\x6b\xb9\x64\xf4\x32\x19\xe5\xef\xc9\x61\x54\xdd\x3d\x96\x82\xab\xec\xb7\x0f\x51\xf3\x8c\x6f\x91\x81\x42\xa9\x1d\x15\x59\xb0\x35\x84\x54\x05\xd7\xc6\x18\xb2\x1d\xfe\x2a\xb1\x28\x36\x91\x53\xda\x12\x9a\x89\xce\x82\xad\x19\x4e\x15\xdc\x95\xdc\xd2\x7e\x99\xdb\x7d\x33\x96\xc6\x2b\xfe\xcd\x4b\x5e\x8d\x57\x4f\x8d\x68\xfa\xc5\x3c\x7d\x1f\x8f\xce\x1e\x7e\x28\x97\x1b\x5c\xb0\x5c\xc3\x30\x9b\xca\x7c\xf6\x01\xb7\x5f\x58\xed\x96\x55\xfd\xa2\xda\xb8\x04\x15\x06\x3a\xbf\x98\xa1\x57\x9b\x76\x7c\x81\xc3\xd6\xf1\x85\x0a\xb8\x2f\xc0\x17\x8e\x92\xec\xa5\x38\xa7\xbd\x12\x2c\x5d\x06\xb1\xaa\xbf\x4e\xb5\xa0\x6b\x86\x54\x0d\xa9\x06\xb5\x7d\x45\x83\x0a\x0e\x2d\x74\xb0\x95\xf8\x65\xcd\xb2\x7d\xd3\xa4\x5a\x4c\x9a\x5d\x5e\xaa\xcd\xa3\xea\xb2\xb1\xfa\x4e\xb2\x6a\xcd\xf1\xfb\x44\x0b\x5b\x26\x23\xd8\x15\x8b\x5c\x58\x3b\x9c\x1d\xf3\x83\xed\xe7\x53\x04\xec\x36\xdc\xff\x98\x25\xca\x81\x2b\xce\xdf\xfe\x01\x5c\xc9\xd5\xe6\x14\x1e\xd0\x05\x3b\xea\x5f\xf7\x40\xbe\x71\x7d\x20\xbf\x3e\x90\x7f\x15\xea\xdb\xe2\xf8\xf8\x42\xb1\x6b\x93\x79\xb2\x38\x81\xaa\x7f\x6c\xd0\xf6\x8e\x04\x3a\x06\x69\x6e\x1f\xc8\xbf\x6b\xed\x21\xa5\xe5\x8b\xea\xa6\x87\xd9\x08\xf4\xea\xf8\xf8\xb3\xab\x81\xff\x3c\x07\x6f\xe0\x8e\x62\xc4\xfc\x1a\x33\x39\x95\xde\xe5\x7f\xfa\x12\x37\xb7\x82\xb4\x1a\x6f\x6e\x05\x0c\xdc\xdc\xd2\x96\xbc\xcc\x05\x6d\xdd\x81\xbe\x7a\x73\xf2\x44\x14\xb6\x35\xbd\x0f\x5e\xc9\x4b\x11\x45\xf4\xf3\x6d\x18\xc0\xb6\xfe\x0a\x29\xad\xeb\x1a\x4d\xb3\x49\x5e\xdd\x62\x20\xf7\x72\xcd\x09\x22\x7a\x1a\x7f\x90\x44\xf0\x2a\xfd\x3d\xa9\x6e\xbb\x09\xd4\x36\xef\xbb\x48\x47\xd2\xec\x45\xfe\x6b\x7d\x07\x42\x20\x97\x6b\x58\xf0\xa5\x77\xaf\xe7\x0b\xc1\xd1\xc6\x2f\x12\xcf\xea\x83\x35\x5f\x0f\x78\xb9\x4e\x88\x7c\x21\x6d\xbd\x88\xd3\x9a\xf6\x2b\x61\x2e\x47\x01\xf1\x98\xc4\xca\x32\xb1\x8f\xdf\x4e\xeb\x75\x60\x97\x1b\x3b\x45\xf7\x6b\x1c\x7e\x1d\xd8\xe5\x3a\x70\x1c\x17\x8d\xad\x57\xc2\x5c\xae\x69\x21\xe2\x86\x54\x1f\x4e\xe3\x35\x50\x97\x6f\xbe\xc5\xdc\xd7\x81\x5d\x9a\xee\x5f\x9d\x9d\xd4\x36\x5e\x05\x72\x69\xaa\x6f\x6a\xb8\x0a\xe4\xb2\x28\x07\x39\x0e\xc2\x05\x42\xd5\x60\x28\xfb\x22\x2e\x8a\xd3\x7c\x3e\xae\xc3\x7f\xcb\x32\x97\x5e\x07\x4d\x38\xa9\x02\xb9\x34\x19\x36\x35\x5c\x05\x72\x15\xac\xa7\xa9\xed\x1a\xa8\x70\xf3\x3c\x88\x65\xb1\x38\xc4\x2b\x76\x8a\x5d\xbe\xc8\xcc\xf7\x49\x5a\x14\xe2\xb4\xbe\xd2\xba\xb7\xb3\xbc\x28\xab\x7b\x19\xc8\x0d\xe8\xae\x1a\x2e\x18\x5a\x5c\x67\x0b\x09\x92\x45\x0a\x74\x8c\xac\xac\x53\xb4\x63\x02\xb5\xbd\x79\x7d\x86\xbe\x3e\x43\x7f\x2d\x97\xda\x87\xbf\xff\xee\x5c\x6a\xef\x4e\x93\x0f\xd1\x03\x30\x2a\x2c\x7e\x8f\x8b\xdf\xd3\xe8\x87\x58\xa4\xfc\x65\x5e\x4e\x8a\xc1\xf1\xc2\x3e\x0e\xdf\x91\x6e\x8b\x5f\x26\x13\x51\x20\x1b\x09\xa2\x86\xf6\x0b\xd1\x81\x23\xd1\xc7\xc5\x21\x5c\x69\x8b\xf5\x95\x8d\x8e\xe3\x34\x5b\x3b\xca\x6f\xe9\xdf\x87\xd3\xfc\x70\xad\x10\xf3\x74\xb2\x96\x66\x82\x64\xb2\x78\xba\x06\x43\x4a\x3e\x94\xea\xef\xe0\x28\xff\xbf\x4f\xb6\xb6\x3e\xf3\x1d\xb8\xb9\xd8\x7e\x05\xbd\xb9\xbe\xd5\xfe\x93\xdc\x6a\x93\x89\x7b\x52\x0a\xf9\xe1\xdd\xcb\x04\x7d\x52\xd7\x29\xeb\x5d\x70\x5f\x5b\x2f\x48\xfd\x6d\x0d\xd4\x65\x1e\xe1\x9e\x65\xa3\xc7\x19\x84\xd6\x6d\xea\x25\x83\x0c\x77\x30\x0c\x70\x99\xbe\x9d\xc6\xb3\x96\x7d\x33\x90\x15\x7d\x0b\x02\x5c\xa2\x6f\xe3\xfc\x34\x93\xee\xc6\xeb\x3a\xa6\xc0\xc2\xbd\x0a\xe4\xb6\x7f\x57\x5e\xd1\xb1\xc5\xac\x45\xb7\x08\x28\xdc\x29\x2f\xef\xd2\x5d\x9a\x27\x10\xc2\xfa\x7d\x93\xab\x17\x05\x16\xee\x56\x20\xf7\x32\xa4\x55\xc2\x6e\xd7\x40\x54\x00\x53\x41\x4e\x4e\xd6\xa5\x51\x74\x94\xb4\xf0\x5b\x10\xee\x8b\x9d\x71\x09\x9c\x50\x98\x96\x06\x67\xc8\xe1\x3e\xd8\x19\x97\xc6\x86\x8c\xcc\x54\xdf\x19\x02\x0a\xf7\xc7\xcb\x6b\x73\x97\xe9\xde\x54\xb6\xbb\xaf\x74\xd9\x32\xbb\xe6\x33\xfc\x90\x25\x1a\x46\x64\x12\x15\x1f\xe0\x37\x87\xf6\xb7\xa2\x7e\x56\x11\x90\xa0\xf5\xd0\xb1\xcf\x9d\x3e\xb3\x10\xb4\x46\x16\xff\xac\xd7\x8c\xc7\xe9\xfb\xca\x47\x70\x30\x27\x98\x7f\x41\x2b\xe3\x80\x52\xd6\xaa\x1b\xf3\x5b\xdf\x56\xa2\xb0\x14\xf2\x6a\x1d\xbc\x97\xdc\xba\x3e\x53\x5d\x9f\xa9\xbe\x8a\x7b\x49\x75\x41\xf4\x65\xc3\x31\x5c\xe4\x05\x85\x7a\x06\x09\x0f\x29\xa4\x30\x4e\xb1\x3c\xcb\xe3\x3a\x53\x53\xba\x2e\xab\x75\x3f\xab\xa0\xe1\xf2\x4e\x00\x49\x57\xb3\x3b\x2b\x8c\x81\xa7\x02\x4b\x5d\xc8\x77\x83\x08\x8d\x62\x31\xed\x1d\xa0\xf8\xa2\xec\x0c\xbd\xf8\x42\x18\x71\xaf\x38\x4e\x27\x65\xd7\x89\x1c\xe4\x47\x01\x5d\xf7\x01\x24\x4b\xc6\x77\xaa\x62\x2c\xe4\x6a\x8e\x2e\x64\x77\x02\xdd\x98\x25\xd9\x18\xde\x75\x7c\xee\x7e\xbc\xa0\x76\xb8\x5d\x59\xa8\x53\xd2\x47\xe8\xb0\xa9\x3a\xaf\xf0\xb9\x0a\x80\xe4\x4f\x64\xb3\xc9\xb0\xac\xcd\x95\x11\x2c\x52\x68\xe5\x3d\x63\x91\xa5\x59\x51\x82\x57\x94\x36\x2d\x3b\xd0\x61\xa7\x16\xd5\x40\x35\xfd\x10\xbb\xde\x93\xfc\xa8\x85\xe7\x40\x80\xaa\x74\xa6\x41\x2d\x3a\x20\x35\xad\xce\xf2\x46\x87\x3b\x00\xd2\xd0\xde\xc3\xe3\x38\x3b\x4a\x5a\x34\x19\x12\x3e\xa8\x0a\x6e\xfc\x65\x61\xcf\x12\x84\x60\x60\xac\x91\x7c\x3a\xe5\xf2\xc0\xd2\xfc\x06\xcc\x35\x90\x35\x7a\xec\xa6\x38\x3e\xbe\xa2\x8b\xf0\x10\xad\xfa\xb7\x34\x96\x25\xc1\x95\xaa\xe9\xab\x89\x31\xa8\xa9\xff\x7c\x1d\xf1\x49\xd7\xbf\xa6\xa0\x66\x65\x1f\x8a\x2b\x6d\xde\x21\xe3\x2b\x68\xbb\x56\xd3\x4f\x7d\x10\xfd\x19\xc2\xff\xa4\xb7\xe5\x63\xf1\x25\xfe\x47\xc2\x6b\x28\xf2\xca\xf6\xf6\xb5\x48\x7a\x2d\x92\xfe\x97\x8b\xa4\x46\xcf\xaf\x9c\x29\x5c\x51\x58\x78\x72\x04\xf1\x32\x39\x82\x79\x8e\xe7\xbb\x87\x69\x45\x00\x92\x62\xed\x67\x1b\x74\x00\x71\x0a\x95\x7a\x1e\x5c\x3b\xf0\x4a\xaa\xea\xd8\x7b\xb8\xfb\xc2\xaf\x81\xf5\x44\x7a\x9c\x90\x96\xd3\xa2\x2f\x9d\xf5\x0f\xa3\x3b\xe3\xef\xc7\x9b\xa3\xf1\xf6\xf6\xf7\xf1\x77\xb7\xb7\x47\xdb\xdf\x6f\x6f\xde\xd9\x4e\x36\xee\xae\x7f\x3f\xba\xbd\x9e\x6c\x6d\x8f\xef\x6c\xdf\xbe\xb3\x79\xd8\x31\x7d\x09\x55\x13\x6f\xc4\x1b\x1b\x1b\x87\xa3\xf5\xef\xb6\x47\xdf\x8f\x26\xf1\x77\x77\x37\x26\xeb\xa3\xad\xbb\xc9\x9d\xad\xc3\xf1\xed\x8d\xd1\xf7\x1b\x87\x77\xe3\xc9\xfa\x7a\xa7\x9a\x37\x51\x1f\x87\x4c\xd2\x8d\x0f\xd3\x61\x00\x89\x86\x13\x4a\x7f\x17\xc3\xe0\xf8\xb8\x5f\x79\xe8\xb6\x57\xb3\x8b\xd7\x40\xbd\xfe\x70\x59\xdc\x53\xc5\x31\xeb\x67\x71\x75\xb8\xd1\x5f\x6d\x98\xa7\xd5\xe1\x26\x30\xdb\xdb\xd7\xcc\xf6\x9a\xd9\x7e\x2d\x77\xaa\x4a\xf9\xe5\x30\xdb\x3a\xc3\xe4\xc9\x3c\xff\x3d\x39\x89\xb3\xc1\x38\xf9\xf1\x4a\x18\x74\xc8\x17\x85\xe3\x88\xc2\xbd\x20\xf5\x4c\x6a\xad\x5b\x50\x19\xc8\x59\x66\xe1\x27\x0b\x2c\x42\x8a\x44\x96\x4f\x09\x56\xe8\x11\x07\xa2\x70\x21\xdc\xb8\xe5\x06\xd4\xc9\x61\x65\x6c\xfd\x33\x2b\x62\x67\xb0\x12\xde\x65\x2e\xe8\xa7\x49\xb2\x54\xb8\xe0\x61\x6e\xdd\xcb\x4d\x3b\x06\xfb\x89\x85\x22\xaf\x6a\x50\x90\xb6\x0b\x2b\x6f\x82\xa8\x37\x84\x94\xc7\xf0\xe3\x7e\x40\x75\x2b\x22\xb0\x4e\x5a\x0b\x0d\x89\xd5\xe3\x2a\x70\x42\x3e\x9a\x1c\x0c\xdf\x0f\xa5\x52\xd9\x21\xf4\xbf\xed\x80\x73\x2b\x08\x74\xc3\xa0\x29\x68\x30\x1f\xb5\x09\x17\x1f\x0e\x21\x5f\x39\x6e\xaa\xca\x9e\x45\x2b\x98\x35\x1f\xbb\x4b\x91\xdf\x7c\x23\x23\x5f\x47\x37\xee\x11\xe1\x38\x49\xb0\xa1\x40\x6c\x99\xb1\xc2\x93\x53\x83\x6e\x6b\x28\x4b\x55\xa0\x0b\x43\x44\x0b\x64\xc9\xf8\xf4\x26\x6e\xbd\xe0\x13\x27\xe6\xb0\xad\x23\x2f\x0f\xa2\xa7\xb0\xb1\xc1\x45\x04\x51\x12\xa2\xc9\xc1\xa5\x13\xec\xde\x27\x22\x53\x9f\x85\xd7\x9f\x24\xa6\x3e\x3d\x13\x03\x3e\x67\xc6\xee\xe9\x44\xd4\x29\xf6\x9e\xa2\x0a\xe5\x4e\x8b\x95\xfa\x42\x88\x58\xae\x42\x55\xa9\xd6\x28\x58\x15\xaa\xd9\x64\x64\xf8\x5b\xd1\xc6\x41\x2f\x10\x76\x07\x61\x66\xf9\xac\xdb\xc3\x00\xf5\xf2\xde\x0b\xf8\x3f\xae\x27\xc4\xd6\x0d\x2e\xdc\xd8\xe8\x26\xfb\x06\xd3\x33\x81\xf6\x20\x29\xda\x76\x10\x55\xc4\x28\x93\xd4\x28\x2a\xa9\xd1\xae\xcd\xe6\xbe\x87\xbf\x59\xb1\x64\xa1\x8c\x16\x25\x75\x24\x22\x24\x2f\x0c\x47\xff\x73\x52\x76\xd9\xe9\x3c\xc9\xc4\x1c\xce\xe1\x72\x0c\x9c\xf0\x2f\x12\x5f\xf5\x27\x58\x77\xa1\xa2\x72\xc3\xc7\xa0\x10\x1b\x7c\x29\xb6\x88\x4e\x6f\xc7\x82\x02\xee\x08\xc0\xa8\xbc\x55\x13\x85\x29\x6a\x4e\x7e\x8c\x36\xf8\x8c\x88\xae\xef\xab\x12\x07\xc0\x8d\xad\xef\x4f\x9f\xa2\x8f\xe7\x3b\x1c\xd8\x05\xd9\xb1\x34\x62\xba\xf9\x8d\x03\x15\xf5\x08\xff\x84\xc3\x38\x89\x8a\xfa\x08\xdf\x27\x94\xc9\xb1\x40\xfd\x31\xdc\x22\xfe\x8c\xfb\x0d\x88\xbc\x38\x84\xde\x81\x42\x01\x22\x71\x57\x82\x74\xd9\x33\x0d\xa7\x98\x35\x49\x58\x85\xcd\x32\xf0\x76\x39\xba\x89\x7d\x18\x8c\x8e\x85\xe0\x5d\x76\xd7\x7b\x83\x32\x7f\x33\x9b\x81\x96\xb4\x48\xba\x3d\x95\x8d\xe1\xd3\xbb\x1b\xbd\xca\x8d\x47\xcd\x6c\x75\x88\x61\xb3\x71\x9b\x78\xc9\xca\xf5\xa1\x2a\x71\x04\x03\xf2\x57\x8c\x16\x50\x38\x4f\xec\xea\x54\x7b\x1f\xc3\xa0\x61\x8e\xa2\x87\x96\xc4\xd0\x54\x44\xdb\x3d\xd3\xd8\x28\x7b\x9f\xfa\x01\x2a\xac\x2f\x37\x4a\x73\xd7\x5f\xed\xed\x8f\x99\x39\x05\x07\x84\x2d\x2f\x3b\x2a\x1e\xdd\x27\x99\xcf\xfb\x91\xbd\x75\x70\x65\x17\xf4\x85\x60\xc2\xc8\x96\x25\xd9\xdd\xc1\xb9\x41\x99\x5c\xe4\xaa\xdf\x8a\x50\xe5\x27\x85\x69\x93\x01\xda\xd8\xac\x1e\x25\x65\xe3\xee\x3b\x82\x97\x49\x42\x42\x5d\x08\x91\xfa\xaf\xaf\x9e\x3f\x7b\xf9\xe2\x61\xa4\x5a\x39\x3d\x4e\x05\x1f\x84\xc3\x93\xda\x81\xc4\x61\xe2\x10\x75\xb6\x12\xc4\xe1\x88\x26\x55\xf3\xbd\xc1\x60\x70\x2e\x35\x78\xa1\xbd\x39\x82\x23\xe1\x7c\x36\x62\x45\x83\xdc\xd1\x0c\xbc\x82\x2c\xe2\x33\x69\xa0\xe3\x9a\xcd\x0d\xb9\x3c\xea\x2b\xc9\xf7\x0f\x6c\xf5\x3a\x4c\x93\x2c\xe2\x6c\x56\x5d\xdc\x13\xb9\x28\x08\x4b\x76\x00\x85\xba\x7a\x9f\xec\xf5\xf8\x94\xc9\x5e\xc9\x89\x56\xb3\xc6\xa7\xbd\x6a\xea\x64\xc9\x0a\x05\xa7\x42\x10\x3b\x98\x3b\xce\xb9\xe0\x88\x7d\xe7\xfa\x88\x7d\x7d\xc4\xfe\x6a\xf4\x99\x92\x43\x9c\x48\x96\x6e\x9f\xb4\xff\x9a\x4c\x04\xcd\x9e\x45\xbf\xa6\xd3\xd1\x3b\x51\xf9\x6f\xe2\xbb\xca\x2d\xd7\x52\x3e\xbc\x04\x30\x1c\xe1\x9f\x83\x2f\xfc\x18\x61\x43\xde\xbb\x2e\xe0\xf0\x4b\x16\xf9\x39\x7e\x1f\xfd\x9a\x0b\xd6\xfa\xc3\x51\xe5\x21\x7f\xdb\x1c\xf2\xff\x2a\xb9\xa9\xf5\x78\x58\xb2\xd8\xba\xd8\xd5\x01\x8f\x94\xa3\x20\x88\x1d\x6b\x3a\x81\xe7\xac\xce\x53\x65\x4a\x23\x1b\x04\xda\x76\xf6\xca\x4e\x01\x1b\xe3\x4c\x1c\xc3\x53\x21\x85\x22\x81\xcd\x62\x7c\x44\x22\x98\x34\xdd\xf9\xc0\x5e\x24\x58\xeb\xfb\x54\x70\x87\x42\x97\x8a\xa7\x45\xee\x17\xcd\xa7\x53\x28\x0a\xd4\xa6\xde\x6f\x0b\xc2\x19\x53\x6e\x9a\x09\x04\xf1\x9a\xa1\x32\x19\x3c\x84\xae\x5c\xcb\xf4\x24\x81\xc5\x26\x56\xf1\x86\xa0\x35\x31\xa4\x31\xee\x8e\xa2\xd0\x34\x29\xf3\x0c\xd1\x09\xc3\xab\x39\xe8\xab\xae\x5a\xc7\x7d\x95\x28\x20\xd5\x4f\xa6\x67\x80\xb6\x51\x03\x7c\xce\x12\x55\x5f\xb8\xd6\xa1\xf2\xf0\x07\x12\xca\xf1\x3c\xcf\xf2\x45\x31\x3d\x43\xbf\x1a\x15\xfb\x30\x64\x05\xce\x23\x91\xa0\x98\xb8\xf2\x84\x6c\x8f\xd6\x52\x79\x64\x63\x6b\xf0\x50\x0d\x3f\xa9\xdd\xb0\x46\x6f\x45\x31\x15\xf3\x96\xc3\xd6\x05\x44\xd1\x25\xd2\x18\xec\x65\xef\xe3\x69\x3a\x7e\x21\xe1\xbb\x5c\xe6\x51\xaf\xb0\x11\x19\x4c\xc2\xb7\xf7\x78\x49\xe6\x42\x92\x7e\x41\x69\xd8\xa5\x01\x8d\xbe\x8f\xc3\x94\xc6\x16\xec\xfc\x22\x4f\xe5\xf7\xec\xb9\x22\x61\x56\x56\xad\xae\xca\x71\x50\xaa\x91\xb4\xf8\x3b\x74\xf7\x25\x51\x61\xa2\x05\x35\x36\xcc\x52\xcc\xca\x69\x64\x0f\xcf\x85\xb7\x86\x23\x87\x49\x59\x83\x56\x27\xff\x78\xa9\xd9\x47\x69\xb6\x96\x04\xdc\x73\xa9\x96\x7e\x9a\x89\x01\x2b\xf7\x28\x82\x79\xc9\x6e\x45\x1b\x2a\x46\x2e\x93\x8d\xeb\xa8\xe3\x6a\x08\xc1\x9f\x7b\x92\xfb\x65\x95\x2d\xe4\x79\x18\x94\xc8\x72\x45\xfc\xc0\x68\x7a\xae\xb9\x0d\xfc\xb3\xea\x59\x86\xc6\x9a\x51\xe6\x95\xe6\xed\x7b\x42\xae\x0c\xeb\x6c\x91\xa0\xeb\x85\xb7\xfa\xd8\x10\xa2\xc6\xba\x93\x01\x00\x3c\x80\xfc\x80\x92\x89\xca\x5d\x05\x61\x62\x0b\x7f\x32\xc2\xc4\x3e\x71\xea\x54\x4c\xe6\x62\xa4\x58\x5c\x01\x2d\xda\x34\xa8\xf4\x6c\x38\x1b\x5d\xd5\xca\xa5\x48\xaf\x68\xa2\x3d\x6b\x40\x9a\xe8\xa2\x86\xed\x4f\x9c\xf0\xf5\x16\x2b\xa4\x4d\x75\x26\x94\x22\x9f\x47\x71\xa5\x9a\xaa\xb0\x6a\xae\x96\x96\x74\x59\x7b\xd7\xaf\xdc\xee\x9b\x76\xee\x12\x8e\x54\x52\x70\x71\x3b\x0a\x79\x2f\x74\x56\x23\xc7\xa6\x20\x1f\xe7\x58\xdb\xde\xb8\x71\xc9\x04\x72\xb5\x59\x92\xea\x49\x99\x8f\xf3\x68\x34\x4d\xe2\x6c\x31\x13\x7b\x2f\xd4\x0f\x08\xd6\xc7\xf6\x3a\x54\xb1\xde\x07\x56\x1e\x75\x92\x3b\x44\xd1\x8d\xdb\xb8\x04\xe1\x68\x9f\xa0\x41\x0f\xf7\x11\x8a\x0f\x23\xaa\x24\x05\x15\x8c\x53\xd1\x30\x54\xe5\xd0\xfc\x44\xbd\xae\x7a\xca\x6c\x63\xdf\x62\x00\x52\x00\xb3\x77\x73\xd9\xa1\xae\x5e\xe1\x0d\x93\x9b\xcf\xb4\x50\x0a\x22\xa8\x34\xa3\xc5\xd3\xcd\x51\x0a\x47\xba\x74\xec\x4d\x7a\x3e\x0b\xcc\xb9\x37\xb7\xb5\x98\xd7\x55\xd8\x44\x8a\x65\xd5\x80\xc8\xb2\x2c\x80\xe4\x1d\x86\x1e\xd3\x7f\x89\x9d\xae\xd4\xa8\xbe\x4b\xce\x8a\xae\x29\xdb\x53\x5a\xde\x7b\xf7\xee\x45\xeb\x70\xd5\x51\x85\x43\x20\xa6\xf9\x6b\x4a\xef\x5a\x40\x3b\x36\x9e\x5d\x01\xb8\x06\xdf\x66\xf7\x11\x9c\x43\xf0\x02\x90\xff\x15\xda\x4f\x92\xd1\x71\x9c\xa5\xc5\x89\x3a\x86\xd6\x33\x07\xac\xa0\x1e\xbd\xd4\x06\x47\xec\xbb\x24\x99\xe9\x40\x21\x6a\xb0\x6b\xdf\xfe\x56\x1c\xa7\x19\x34\xf4\x01\x42\x68\x4d\x93\x0f\x69\x79\x36\xbc\x8d\x47\x32\x00\x00\x82\xe8\xc2\xe6\x20\x70\x49\x9a\x02\x8d\x4d\x86\x2f\x31\x0d\xf3\xe4\x24\x17\xc7\x6a\x30\xa3\xc6\xec\x7e\x94\x7c\x18\x25\xb3\x12\xc5\x7e\x99\xc4\xe1\xc5\x28\xcf\xa2\x2c\x21\x8c\x1c\x26\xb2\xfc\x18\x46\xbc\x10\x95\x9c\x45\x87\x67\x88\x32\x40\x8f\x8c\xf9\x81\x34\xf3\xeb\xff\xcf\xde\xbb\xae\xb7\x71\x23\x8b\xa2\xbf\x93\xa7\xe8\xcc\x39\x33\x26\x63\x5a\xee\x3b\x49\x29\x9a\xb5\x65\x49\x8e\xb4\x2c\x5b\xde\x92\x9c\x64\xb6\x3e\xc5\x5f\x93\xdd\xb4\x98\x50\xa4\x16\x49\xd9\x52\x12\xcf\xfb\xec\xe7\x38\x2f\x76\x50\x85\x4b\x03\x68\xa0\x2f\x94\xe4\xd8\x19\x65\xc6\x36\xbb\x1b\x28\x14\x80\x42\xa1\x50\xa8\x0b\x6c\x48\x90\xea\xa4\x2d\xed\x03\xad\x6f\x94\xde\x81\x66\x9e\xe0\xbb\x36\x9e\xa6\xd9\xf5\xe1\xa8\x85\x6e\x8a\x40\x6c\x6f\x1f\xb5\x71\xf2\x9f\x78\xfa\x06\x21\x51\x18\xa9\x79\xb6\x26\x56\xa2\x6e\x0e\x5d\xa4\x48\x28\x5f\x30\x4d\xfe\x82\xc9\x13\x4f\x99\x30\xef\x43\x6a\x9b\xeb\x10\xea\xa9\xc1\x13\xa8\x49\x6d\x19\x4d\x32\x8b\xe1\xa2\x0a\xd4\x42\x85\x4e\x0c\xe0\x0c\x9d\x21\xa4\xad\xf4\x1e\x00\x4b\xaa\x48\xc2\x66\xd7\x76\x4f\xf6\xde\xbe\x3e\x3c\x38\xd8\x7f\xf5\xfd\xdb\x93\xfd\x97\xbb\x87\x6f\x4e\xe4\xe3\x51\x9d\x19\x28\x0a\x55\x8a\xc4\x74\x2f\x47\xc7\xa2\x4c\x06\x78\xb1\xd8\x9e\xa7\x67\x1b\xea\xfb\x7d\x74\x37\xe6\xaf\xeb\x2d\x55\x01\x70\xed\xf2\x6a\x71\xde\xd2\xe9\x9e\x89\x78\x4a\x69\xd2\x0c\x2d\x4c\x4a\xb4\x0b\x63\x90\x03\x6c\x30\x78\xb5\xc4\x4d\x01\x99\x35\xca\x97\xd4\x45\x72\xa9\x30\xc9\x31\x92\x2d\x32\x14\x24\x31\x20\x4d\x75\x98\x5e\x92\x1a\xb9\xea\x42\xd2\x6b\xab\x9e\xe2\x54\x70\x45\xae\xd1\xfe\x5d\x1f\x03\x02\xe3\x14\xab\x8d\x71\x8b\xe7\x23\x73\x8a\xc5\xcf\x24\x8f\x74\xb3\xdf\x7c\x73\x99\x79\xa6\x4a\xcd\x4a\x78\x93\x93\xc3\x9d\xc3\x75\x4e\x64\xce\x64\xf6\xee\xbf\x74\xa9\x7a\x66\x91\xab\x6f\x2b\x49\xd7\x50\x16\x2c\x8c\x47\x47\xf6\x6d\x8d\xcc\x5e\xcb\x66\xac\x20\x3b\xdb\x8c\xd3\x7c\x94\x61\xec\xd9\x51\x6f\x9c\xca\x8e\x37\x82\x22\x7e\xcd\x9c\x05\x5c\x02\x01\x5d\x30\x66\x35\x5e\x10\xe6\x0b\x7a\x6c\xc6\xc9\x41\xd7\x3b\x42\x07\xa1\x39\x44\x55\x9a\x68\x7b\xad\x1a\xab\x85\x0c\x08\xba\xfd\xd3\xa5\x41\x1a\xd5\x51\xcc\xbb\xb4\x36\xcc\xed\x01\xd4\x3a\xe2\x8b\xd5\x61\x86\xeb\x4e\xe4\x4f\x1f\x9d\x6c\xc2\xf4\xcc\x86\x1a\xa3\x84\x7c\x94\x6f\xd9\x98\xdb\x53\xe5\x98\xb2\xfa\xdf\x7c\xc3\xda\x74\x56\x80\x01\xf3\x82\x33\x2e\x2d\x5a\xcb\xe1\x7f\xa3\x30\x9e\xdf\x60\xcd\x1a\xe3\x58\x5f\x31\xa0\x52\x98\xd4\x4b\xac\xa8\x8e\x92\xb6\xd8\xed\xc3\xa4\xe2\x62\xd7\x33\x28\x97\x9c\xb6\xd4\x6a\x47\x7a\x50\x0d\xf5\xc6\x4b\xcb\xb1\x56\xcc\x51\xc3\x14\xd2\xe4\x09\x43\xfb\x30\x65\xf8\xdf\xd6\x03\x74\x43\xed\xd6\xb9\x23\x63\x31\x37\xb3\xe5\x92\x88\x20\x66\xcf\x5e\x64\x4c\xa9\x94\xa2\x1a\x4c\x85\x99\xcf\xda\x46\xa1\x44\x1e\x3e\x5b\xd8\x07\xd9\x82\x16\xb1\x46\x59\xbf\x01\xe5\xde\xc3\xb5\xde\xc3\xb5\xde\x7f\x46\x44\x5f\x76\x6a\x59\x25\xaa\x6f\x95\x39\xac\x25\xc9\x8d\x96\xe3\xa6\x89\xe1\x2c\x5f\xd2\x1e\x3b\x1c\x6c\xa5\x44\xb8\x84\xa1\x13\xbb\x1b\x04\x52\x23\x92\xf7\xc2\xb9\xa2\xe2\x17\x73\x7a\xeb\x80\xf0\x35\x5e\x62\xa4\x3c\x07\x33\x7d\xd0\x4d\x95\xee\xf6\x5f\x7f\x2d\x9f\x0f\xd8\xf9\xec\x6b\x5d\x49\x04\xdb\xe6\xd7\xec\xda\x4a\x2a\x27\xf1\x2a\x1a\xa7\x87\xbb\xd2\x41\xb9\xd9\x94\x39\x5c\x29\x1c\x8d\xc9\x4d\x30\xf6\x06\x55\xa3\x4d\x28\xa2\xfb\x36\xef\xe9\xc2\xb0\x59\x98\xec\x71\x4c\x7a\xc1\xd9\x5c\xdf\x9e\x6c\xba\x4b\x61\x21\xc8\x03\x11\x21\xca\xe4\xd4\x07\x3f\xa8\x62\x8a\x0c\x01\x72\xe3\xb6\x02\x91\x5f\x5f\x54\xe5\x2e\xa6\x10\x65\x2f\xe5\x55\x3b\x29\xa4\xa1\x49\xb2\xc0\x66\x8e\x97\x30\xd9\x60\x21\xaa\x0f\x74\xe1\xa2\x40\x92\xd2\x00\xbc\x4a\x52\xb8\xd4\xc0\xa8\x8f\x70\xf9\x39\x91\xe4\x17\x33\x6a\xe7\x78\x79\x89\xac\x1b\xcf\xce\x84\xed\x2d\xe1\xc0\xdf\x21\x8c\x6b\x04\x0c\x80\x2e\x71\x7e\x85\x8a\xa3\x41\x95\x8c\xda\x7f\xa0\x16\x30\x60\x4d\x46\xd8\x34\xf2\xed\x42\x7d\xc3\x75\x03\xa0\x6a\x70\x8f\xb6\x76\x76\x46\xf9\xe6\x34\xbb\x5e\x3a\xdb\xaf\xdf\x38\xc3\x1b\xc8\x14\x2f\xba\x89\xc3\x2e\x36\x1b\xec\x09\x76\x99\xd9\x2c\x91\x93\xc2\x10\x78\xd6\x82\x8e\x4e\xa1\x15\xe9\x18\x2c\x96\x89\x69\x2e\x0c\x1d\x61\xa4\xa1\x97\xfa\xd8\x21\x27\xb7\x1a\xfd\x33\x0c\x2b\x25\x05\x9b\x68\x26\x19\x83\x7d\x2d\x00\xe8\x66\x6c\x92\x2e\xb6\x55\xb4\x83\xb2\xe4\xf5\xd6\x2d\xa1\x3e\x76\x72\x21\x1c\x34\xae\x82\x4d\xb0\xf7\xb2\x0e\x89\xea\x0c\x90\xb3\xc0\x3a\xe1\x76\x92\xfb\xc6\xfc\xbb\xd6\xa4\xba\xe5\x26\xf3\x9a\xfc\xa7\xe8\x9a\xf6\x41\x8e\x96\x94\x53\x8f\x29\x17\x7e\xfc\x58\x2a\x27\xd6\xab\x74\xd2\xc7\x0f\x49\x9a\x0a\xdb\x2e\x29\xc1\xaf\xf8\xae\x4f\x8f\x74\x70\x90\x58\x2c\x37\xde\xd2\x6d\xc5\xa9\x40\x27\x46\x42\xb6\xf4\xcd\xdb\x2d\xb5\x58\x24\xcb\x4f\xbc\x52\xb5\x52\x39\x0b\x42\xad\x82\x86\x7c\x2d\x24\xe4\x59\xb4\x4b\xb4\x05\x02\x13\x2a\xe7\x96\x34\x07\xed\x92\xd1\x36\x4a\xb5\x02\x21\xbb\x01\x1b\xc8\xea\x6a\xb6\x0b\x90\x7d\x1f\x92\x11\x3f\xc8\xbe\xff\x39\x26\x6d\x3c\x33\xf7\x5d\xf9\xe8\xee\x0f\x92\xa9\x2a\xed\x42\x22\x71\x61\xcc\x75\x4d\xd5\xd5\x65\xae\xbb\xc7\xa4\xad\xe5\x2e\x2b\x98\xbb\xdd\x5a\xaf\xc6\x50\xad\x84\xcd\xf2\xbe\x68\x3a\x6f\xe9\xb5\xb8\x04\x3b\x26\x54\x38\x7d\xf7\x11\x5d\x5b\x4c\xef\x41\x5a\x26\x20\xe5\x4f\x3f\x24\x93\xab\xec\x23\x28\x2d\x88\x24\x44\xaf\x43\x00\x32\xc4\x20\xad\xb8\x21\xed\xa8\xe6\x05\x18\xa4\x86\xe1\xa4\x8a\xc5\xcb\xf3\x0e\x62\x04\xd2\x7a\x87\xb6\x54\xdc\xc2\x50\xed\x46\x47\x19\xd3\xca\x93\x9f\x2d\x70\x3c\x70\x72\x85\x37\x7c\xe6\x2a\x9f\x96\xc1\x8a\x08\xea\x61\x9a\x88\xd6\xa3\x31\x55\xdf\x50\x88\xcc\x4f\xf7\x51\x51\x7f\xcc\x20\xee\x90\xe9\x22\xb2\x98\xc9\x21\x86\xf7\xe8\x64\xc6\x3c\x7b\xe5\xee\x60\x75\x06\x1d\x32\x58\xe8\x5d\xe3\xed\x09\x39\x06\xbb\x61\x92\x74\xd1\x45\x42\x78\x4a\x43\x40\x21\xc9\xa7\xb8\xd5\xc6\x46\x18\xb6\xd3\xc5\x72\xbc\xbc\xa2\x02\x57\xd1\xfc\x2b\xcd\x2e\x67\x8b\xf1\x52\xc6\x92\xc1\x15\xe8\x21\x98\x21\x61\x28\xd3\xa5\x6e\x89\x51\xbb\xe1\x82\x89\x05\x6b\xc4\x30\x82\x4d\x31\x2a\x8e\x1f\x57\xc1\x97\x5e\x25\x0b\xd2\x23\x88\xa5\x68\x13\x39\xcc\xe6\x84\x63\x89\xe9\xb7\x2c\x1f\x31\xb1\xcd\xd6\xd1\xbd\x2f\x21\x81\xd7\x0a\x6b\x89\x8d\x3c\xcc\xa6\x96\x5b\x4c\x92\x6d\x85\xf7\x3a\x01\x24\x5e\x02\xe8\x75\xda\x80\x44\x9b\xa4\x91\x75\xfa\x0f\x17\x73\x87\x6a\x22\x16\xeb\xac\xb0\xc9\xcf\x27\x05\xe3\xda\x13\xf6\xcf\x39\xa1\xc3\x39\xa4\xd3\xba\xb8\x82\xfb\x14\xb8\x81\x03\x7f\x00\x41\x37\x83\x9b\x65\xb6\x08\xfc\x36\x13\xc6\xbf\x69\x6b\x13\xc9\xca\xdd\xf9\xf4\x2d\x0a\xf3\xc7\xab\x53\x4a\x05\x83\x09\x22\xe2\x39\xe3\x94\x3c\x80\xbf\xe0\xbc\xf1\x5c\xf3\xd1\xa9\x31\xc3\xd8\xa4\x99\x6b\x26\xb8\xeb\x48\x7b\xd0\x86\x4e\x04\x7c\x8c\x5b\xa4\x28\x59\xd2\x4b\x60\xac\x6b\x7c\x7c\x39\xe8\xdf\x6f\x4b\x04\x05\x59\x95\x8f\xa2\x31\x08\x42\x71\x3f\x24\x92\x2a\x88\xae\xc0\x0c\xc8\x4f\x08\x1f\x1f\xd5\xca\x89\xce\xb4\xbb\x51\xad\x7c\x6d\x1b\xb2\x12\x7e\x06\xf2\xc5\x88\xa0\x33\x9b\xdf\x74\xa8\x42\x19\x06\xf6\x2b\x60\xd3\x20\xea\xcf\xc0\x29\x93\xf5\x66\x73\xd3\xf9\x1b\x0d\xc8\xf4\x37\x2c\xf3\x15\x39\x67\x6f\xcf\x2e\x2e\x66\xd3\xff\x3e\x26\x4f\x85\xce\xe7\xbf\x58\x03\x1c\xa7\xd6\xdf\x60\x18\xe6\xd9\xdf\xda\x1d\x47\x7a\x95\x4d\x87\x4f\x06\xc9\x22\x8b\x43\xed\xc3\x45\x1a\xe9\x45\xdf\x5f\xfe\x9a\x8e\xb4\x97\xc3\xf1\x25\x11\x6f\x9f\x50\xc8\x64\x46\xbe\xfa\xf8\xf5\x57\x70\x97\xe3\x48\x9d\xa1\x0a\x73\xda\x17\x3e\x0c\x7f\x03\xa5\x04\xfd\xb0\x96\x5c\xa4\xa2\x6f\x5b\x2f\x77\xc8\x0f\xfa\xa1\x75\xca\x71\xee\x38\x2a\xaa\xf8\x0c\x18\xd2\x0f\x14\x31\xfc\x2d\xe3\x73\x26\x46\x59\x46\x8c\x35\x44\xa3\x61\x38\xad\x01\xd9\xc2\x89\x9c\xd8\x26\x6f\xc5\x88\x91\x09\x5a\xdb\x9e\xdf\x5c\x2e\x67\xff\x7d\x4c\xab\x7e\xc4\xd3\x93\xbc\xfd\x88\xef\xa0\xa0\xfb\xaa\xa5\x1e\xc7\xbe\x62\x1a\x11\xc2\x99\xe6\xcb\xe1\xd5\x72\x41\xdf\xc0\xb2\xd9\x26\x53\xc3\xeb\x6e\x48\xaf\xdf\x4e\xc6\x03\xf8\xb4\x46\xfe\x95\xde\xa3\x32\x6c\x1b\x3b\x05\x5f\xa1\xd4\x9a\xf4\x4e\x81\x90\x4c\xde\xcd\x10\x04\xfc\x20\xd4\xcf\xb1\x38\x98\xcd\x7e\x05\x05\x26\x38\x94\x4a\x98\x1c\x3f\x3b\xfc\x89\x9d\xf9\xc4\xbb\xfd\x57\x3f\xbc\x35\xbd\x3f\x7e\xf3\xec\xed\xcb\xfd\x9f\xde\xba\xb6\x0f\x9e\xed\x83\x6f\xfb\x10\x18\xdb\xb6\xb5\x23\x7f\xf4\xca\x3e\xfa\x65\x1f\x79\x9b\x62\x68\xc8\x52\xba\x84\x83\xe2\xa4\x38\x44\xa6\x29\xd5\x6a\xa5\xb3\x2b\x70\x71\xc0\x5a\x79\x01\x64\xb1\x32\x16\x8a\xa5\xc2\x18\xa3\x09\x92\x7f\xbe\x73\xfc\x28\x26\x3f\x1e\x3f\x56\xc0\x0b\x19\x91\x14\xf0\xfc\x5e\xe1\x1b\x1a\xe4\x9c\x8e\xe1\x3e\x9e\x14\xf9\x0e\x94\xd7\x5f\x15\xaf\x52\x4b\x6a\xb5\x68\xb5\xb6\xf3\xb3\xe3\x5e\x7b\xde\x40\xaf\x9f\x3f\x7e\xfc\x5a\xe9\xf5\x8f\xc9\xe4\x57\xe7\xfb\xe7\x2d\xff\xe7\x5e\x5b\xed\xed\x35\x8d\x90\xa8\xbe\x1b\x6b\x2f\x1b\x8d\x80\x34\xc8\x8b\xc1\xec\x5a\xfd\x88\x86\x06\xd0\x26\x69\xe3\x67\xa7\x75\x9d\x77\x88\xfd\xf6\xa5\xdf\x81\xf4\x3b\x6c\x6b\x9d\x45\x28\x2d\xf2\xf7\x3f\xff\xf9\x4f\xa7\x87\x25\xc9\xc3\x3f\xc8\xc0\x8c\x46\x74\x80\xe2\x40\xab\x02\xab\xe3\xf4\x1a\x06\x72\x71\xad\x7d\xe2\x8b\xe7\x74\x81\xdf\xaf\x37\xbe\xb6\x76\xea\xe2\x6a\xb2\x1c\x93\xd3\xf5\x10\xb5\x04\xc5\xee\x5d\x03\x19\xa7\xa7\xd7\x67\x1b\x86\x6f\x21\xfd\xe6\x1b\x3f\xf6\xe8\xc7\xf0\xac\xa4\xf5\xc5\xd5\xc0\x41\xf9\xa6\xe3\x5c\x8c\xaf\x89\x2c\x34\xb9\xba\x98\x2e\x14\xea\x57\x62\x80\xc2\x20\xa5\xd8\xab\x6f\x81\x66\x5c\x8f\x8f\x14\x7b\x24\xff\x73\xf5\xa1\x15\x2b\x99\x0e\x56\x6b\x89\x13\x13\xb6\x9d\x3f\xe0\x37\x1d\x6f\x4b\x15\x4f\xae\xe2\xc5\x52\x15\xf2\x60\xa9\xe3\xcb\x75\xc8\x4c\xe6\x75\xfc\xd0\x56\x27\xa0\x75\x96\x25\x23\x45\x0e\x74\xf2\x68\xc1\x63\xed\x11\x23\x53\x91\x8f\x0f\x23\xcf\x90\xbd\x72\xf9\x0b\x5f\x19\xd2\xd2\x11\x55\xf8\x23\xa3\xb1\x3a\xc3\xaa\xb0\x4e\xa5\x5e\xc5\xd8\x2a\x6c\x55\xa9\x58\x31\xc0\x0a\xcb\x65\x15\xcb\x46\x19\x2f\x0b\x50\x0f\x4c\xa4\xd9\x02\x27\xfc\xe6\xda\xc8\x04\x19\x03\xd8\x6c\xc0\x01\xb1\x8a\x4f\x06\x3a\x3d\x85\xff\x91\x09\xfa\x99\x3c\x9f\x9d\xe9\x0b\x09\xcb\x12\xde\xb2\x89\x05\xaf\xc7\x85\x02\x0a\x93\xc4\x9f\x1f\xf1\x4c\x2b\xf6\x95\xd7\xe4\x64\x4f\x3b\x97\x3a\x47\x44\xfa\x64\x1b\x4c\xbe\x2b\x1d\x6d\x1f\xbe\x82\x3d\xc2\xbd\x76\xdd\x0e\x99\x73\xd7\xc3\xbf\x7d\xfc\x3b\xc4\xbf\x7b\x1d\xa4\x05\xf8\xdb\xc7\xbf\x43\xfc\xbb\x87\x7f\x7b\x03\xf8\x3b\x88\xf3\xcd\x8c\x9c\x85\x28\x52\xdf\x3a\x5b\xbb\xc7\x34\x1e\xbb\x43\xc5\x21\x07\x04\x82\xf9\x78\x79\x7e\xb1\xc6\xcb\x3c\xcd\x51\x81\xd2\x9b\x4c\x7c\x58\xa3\x0f\x92\x84\x01\x4e\xd5\x34\x7a\x80\xe8\xf2\xdb\x74\x76\x04\xb6\xb6\xeb\x8e\x65\x8b\xe4\xd2\xcf\xaf\xe3\x4b\x66\xf9\x4b\xa4\xc0\xe9\xd1\x0c\x4f\x63\xe7\xc9\x82\x9c\x75\xb2\x29\x7a\x07\xb0\xfb\x2d\xb8\x67\x05\x13\xbe\x74\x9c\x82\xf6\x8d\x99\x61\x16\x49\x81\x26\x73\xe1\x90\xb8\xb9\xe8\x5b\x52\xf5\xf5\x7c\x3c\x9b\x1f\x51\x0b\xe0\xcd\xcd\xfc\xbd\x91\x74\xb8\x59\x98\x36\xa7\xc5\x0e\xa8\xe2\x9b\xbc\xc2\x01\xdb\x4d\x73\xf3\xf9\xdb\x0d\x63\xbd\x1f\x67\x73\x34\x62\x04\x73\x5d\xc8\xac\xb6\x30\x17\x83\xbc\x97\xac\xd4\x62\xfc\xee\x19\x30\x20\xe7\xa9\x13\x96\x2d\x23\xe1\x07\x30\xc7\x01\x2a\x82\xe5\x23\xc7\x71\xcc\x9f\x79\x83\x8f\x9d\xb8\x56\x0b\xd0\xff\xc5\xf0\x3c\x83\xe3\x87\x03\x22\xb4\xa1\x0f\x8b\x23\xf2\x1e\x78\x06\x6f\xe6\x31\xec\xd2\xdf\x96\xf6\x41\x86\x6b\x1e\x16\xde\xa8\x34\xce\xd2\xbb\x53\x7d\xa9\xe6\x26\xa2\x80\x0e\x15\x3d\xe8\xcf\xef\x18\x86\xec\xd9\x20\x85\x70\xb2\x13\xe5\xe9\x20\x19\xcb\xc1\x7f\x12\x2a\xa7\x58\xe7\x8c\x8e\x2c\xce\x38\x7b\x63\x60\x35\x76\x86\x25\xef\x27\x05\xe0\x18\x4d\x47\x1f\x4a\x85\x71\x32\xc4\xff\x2e\x10\xb7\x62\xce\x66\xe1\x88\xac\x3d\x20\x49\x7b\xa1\xa5\xbc\x07\xa8\x5b\x40\x29\xe4\x63\xb2\x89\xd6\x80\x8c\xe2\x13\x87\x79\x26\xed\x6d\xf8\x21\xdf\xa9\x98\x8c\x76\x26\xed\x62\x72\x89\x9e\x52\x00\x31\x65\x90\xd9\xeb\x0a\x6c\x5f\x92\x3d\x1e\xd8\x76\x19\xb6\x64\x77\x00\x26\x7e\xca\x06\xf9\x69\x4e\x1d\x7f\x38\x2e\x43\x7d\xc3\x0c\x80\x4d\x38\x92\x16\x5b\x77\xff\x74\x62\xe0\x67\xda\x84\x11\x5e\x46\x84\xd6\x8a\x49\xfb\xec\x86\xd6\xdc\xe7\xaf\x9b\xad\x99\x02\xad\xb3\x21\x39\x23\x7b\xf6\xd2\xb4\x88\x2a\xb9\xb9\x2c\xd3\x95\xb3\x19\x52\xe2\x85\x81\xd3\x14\x5e\xdb\x99\x0d\x14\xcd\xf9\x8d\x78\xca\x59\x0e\x7f\x65\xe1\x3a\x32\xc3\x62\x7c\xf4\x89\xa8\x63\x22\x5e\x3c\x32\xf2\x66\xfe\x5e\x42\x34\x56\x76\x72\xb7\x9c\x29\x34\x82\xab\x42\x9c\x1c\xd1\xd0\x91\x85\x3e\x94\xd1\xbe\x3a\x17\xa7\x1c\x02\x93\x34\x1b\x76\xa4\x04\x98\x2a\x74\xab\x6b\x08\x48\x51\x15\xae\x2d\x4b\x89\x14\xb4\x2f\x4e\xcb\x7f\xaa\xf0\x6d\x5e\x81\x3a\x02\xc1\xa9\xba\x14\xcf\xea\xac\x93\x5c\x92\xed\x48\xd3\x93\x4d\x87\xa0\xa4\x42\x41\x50\x96\xf3\x5e\x76\xc8\xee\x3f\x22\x72\x4e\x61\x66\xe8\x1a\x49\x67\xdb\xa2\x5e\x5e\xb8\x53\xdc\xab\x3b\xf9\x09\x31\xff\xe9\xe5\x3f\xfd\xfc\x67\xd0\x41\x16\x23\x9f\x32\x14\x5c\xd3\xac\x29\xae\xc0\x35\x3f\x24\x97\x8e\x4f\xe4\x4e\x90\x3d\xc3\xe5\xb9\x45\x88\x01\xfa\x7e\x79\x4a\xc1\x80\xfc\xa2\x0f\xa9\xf2\x4d\x2d\x1b\x94\x94\x0d\x8c\x47\xa2\x3a\x43\xa8\xd2\x6a\x47\x25\x50\xf5\xd1\x53\x1f\x7d\xf5\x31\xe8\x08\x85\x45\xdb\x20\x90\xed\xc3\xc9\xf7\xb3\x18\x23\xf3\xa4\x2b\xc3\x64\x9c\xf5\x8e\x73\x3b\x72\x33\x11\x0d\x3b\x10\xd4\x96\xac\x0d\x03\x0b\xb1\xd2\x30\xc2\x23\x5e\x48\x52\x51\x1d\x30\x35\xe8\xb8\x5c\x69\xb0\xce\xf0\xf5\xcf\x0a\xb3\x75\x4d\x1a\xa0\x85\xa7\x4f\x87\x56\xcb\x33\xd6\xf2\xd5\x5a\xbe\x5e\xcb\xa8\x6d\x5a\x04\xfa\x74\x6a\xb5\x02\x93\x1a\xea\x85\x76\x76\x30\x1f\xfd\xe5\x2d\xd0\x74\x62\x38\x32\x9c\x71\xc4\xfe\x4b\x47\x15\x74\x03\xec\xe7\x77\x7c\x86\xd8\x0b\xcb\xbe\x8b\x73\x3c\x1e\x2d\x91\xd2\x3b\x16\x45\x59\xe9\xc4\x89\x45\x01\x93\x27\xa9\x6b\x5c\x21\x79\xfd\x2c\x29\xba\x5a\x64\xb6\x74\xb9\xeb\x67\x49\xa9\xd5\x22\xf3\xa2\x49\x5d\x3f\x4b\xfa\x2b\x32\xfe\xf9\xeb\xc2\x36\xfc\xf8\xb1\x69\x03\x40\xe4\x3c\x15\x39\xcf\x82\x9c\x5f\x81\x5c\x50\x8a\x9c\xbb\x22\x72\xbe\x8a\x9c\x6f\x41\x2e\xa8\x40\xce\x2d\x45\xce\x5b\x11\xb9\x40\x45\x2e\xb0\x20\xe7\x56\x20\xe7\x95\x22\xe7\x57\x22\x67\x24\xdd\x37\x97\x68\x43\xb4\x00\xf3\xc7\x62\x01\x64\x27\x4b\xd7\xd0\x31\x64\x19\x4b\xcf\xf4\x05\xe6\x62\xe9\x9b\xbe\xc0\x40\x2c\x83\x3a\x4a\x14\xe3\x7a\xaa\xe0\x7d\xb8\x7c\x5a\xf4\xe4\x21\xad\x1d\xfd\xd4\x62\x58\x3e\xfa\xb1\xa5\xb8\x82\x94\x73\x4b\xbe\x84\xda\xf5\x28\x41\xac\x1f\x8e\x9d\x67\xc7\xce\xaf\xc6\x2e\x28\xc7\xce\x5d\x05\x3b\x5f\xc2\xce\xb7\x63\x17\x54\x63\xe7\x96\x63\xe7\xad\x82\x5d\x20\x61\x17\xd8\xb1\x73\xab\xb1\xf3\xca\xb1\xf3\xab\xb1\x2b\x52\x6b\xc6\x03\x5b\x9b\xe5\x12\xba\x0d\x1b\xd6\x91\x2e\xe4\x14\x96\x93\xba\xb9\x1a\x56\x55\x41\xf4\x09\x6c\xb2\x0f\x3b\x0a\xaf\xc3\xfd\xd9\xd3\xc0\x67\x1a\xe8\xb6\x49\x15\xcc\x25\x16\x21\x20\x2d\x98\xe3\x30\x53\x0d\x3f\x5a\xb0\x7c\x4f\x0e\xa6\xf0\x1e\x25\xc3\x4c\xe8\x88\x05\x90\xff\x95\x5d\x27\x60\x20\x53\xf8\xc0\xe7\x94\xc2\x5a\x82\x1a\x3f\xbf\xdd\x06\x5d\xf2\x1a\x3b\x47\xb4\x2e\xb8\x45\x3a\xc1\xbd\xe3\x0c\x47\xef\x84\x34\x9f\x43\xb9\x9c\x24\x80\x04\x01\xa2\x43\x61\x12\x7e\x2b\x6f\xc7\x04\x88\xe9\xb4\xb7\x0d\x4a\xec\xb7\x34\x6a\xea\x5e\x36\xb9\xcc\xe6\x2d\xf2\x1d\xaf\xf5\xa9\xce\xfe\xeb\xaf\x98\xcd\x8a\xdc\x24\xd8\xc6\xc3\x57\x34\x20\x51\xac\x0a\xd6\x23\xbf\xc3\xed\x12\xd6\x23\xb4\x1d\x91\x2c\x13\xd6\xa3\xb0\x93\x9b\x24\xac\x47\xe8\xc2\x08\x96\x0a\xeb\xb1\x07\xd6\x22\xfe\xad\xac\x45\x3e\xa5\x99\xc8\xbd\x19\x73\x7c\x42\xb3\x0c\xba\x12\xbe\x75\x98\x01\x05\x34\xef\xc0\xbd\xcc\x6c\x8a\x21\xd7\xe1\x1b\x21\x1a\x31\xef\x60\x5c\xc1\x8a\xfe\xf1\x87\x6c\x00\x20\x9c\x3e\xef\xd8\xb8\x03\x32\x14\x0a\xab\x8e\x04\x7c\x15\xc4\x37\xd0\x19\xa3\x5b\xba\x28\x20\xde\xc8\x10\xae\x46\x68\x2f\x86\xb4\xbe\xc5\x6f\x79\x72\x98\xc6\xcf\x0a\x66\x19\x7a\x56\x6d\xc3\x42\x95\xde\xbf\x59\x8e\x7a\x08\x85\xbc\x5e\x83\x07\x0d\xeb\x38\x14\x5f\xe9\x63\x99\x21\x8a\xf8\xb2\xfb\xfe\xf2\xc5\xce\xf3\xfc\xb2\x89\x3e\x1b\x6f\xb0\x06\x0b\x6a\x9e\x07\xcb\x8a\xdf\x62\x2d\x33\xc2\x84\xc8\x2a\x36\x30\x28\x11\x64\xfa\xf7\x29\x0b\xc8\xc3\x35\xa8\xe8\x54\x20\x78\x1d\xea\xfd\xc8\xeb\x35\x1e\x60\x72\xdd\x09\x9d\x96\xe7\xf7\x9c\xc1\x98\x2c\xbd\x32\x80\xe3\xf7\x06\x78\xfb\x3f\xac\x0a\xee\xed\xee\xab\xed\xb7\x3f\x3d\x3f\x3c\x7a\xf9\xf6\xe5\xe1\xce\xae\xb3\x85\xa1\x0d\x96\xc9\x94\xc8\x4a\xd9\x25\xdc\x9a\x4d\xc1\x19\x8e\x2b\x62\x80\x0c\xc9\x2a\xce\xfb\x6e\x84\xb9\xb3\x5b\x0b\x26\x63\xa7\x05\x98\xd2\xa5\xa0\x66\x72\x24\x1e\xcd\x14\x65\xb8\x24\xcc\x67\x93\xa2\xbb\x8d\x6e\xdf\x57\x73\x34\x78\x10\x39\x3e\xe4\x22\x4a\x71\xa9\x77\x82\xee\x61\x0e\xc8\xf8\x67\x30\xea\x64\x13\xbb\x62\x6e\x02\xc0\x02\x1c\x28\x8c\xa0\x15\x90\x4f\xf3\x07\xb2\x53\xac\x23\xe9\x72\x5c\xdb\xf2\x8e\x5a\xc0\x16\xb7\x8b\x05\x85\xcd\xc8\x6f\x3c\xb5\x4d\x86\x09\x7d\x6a\x8f\x29\xe1\x0e\xa4\x07\xc8\xc3\x85\x9e\xb1\x2c\xf7\x0c\x25\x98\x3a\xad\x43\x6c\x25\x99\xb4\xb1\xce\xd0\x34\x78\x35\xc7\x40\x6d\x8b\xc7\x11\xa5\x13\xfa\x11\x48\x84\xf7\x0e\x08\x65\x58\xd6\x27\x38\x57\x8c\x87\xf6\xef\xaa\x94\x50\x2c\xa0\x4a\x0b\xf2\x1e\xcf\xaf\x9e\xd7\xe8\x36\xbd\x4b\x87\x79\x36\x6f\xb1\xcb\x33\x1c\xc2\x8e\xf3\x3b\x21\x80\x75\xf2\x27\xe7\x8d\x1f\x15\xd3\x03\x65\xbe\x55\x48\xeb\x4a\x58\x28\x26\x39\xe8\x1a\x00\x39\x71\x08\xad\xcf\x6e\x9c\xd5\xb5\x6a\x90\x3d\x74\x09\xad\x26\x3d\x59\x16\xe2\x03\x3d\xdd\x2d\x3d\xed\x64\x77\x45\x4f\x02\xd2\xed\xe8\x49\xe5\xd3\x2b\xd0\xd3\xfe\x74\xbc\x1c\x27\x13\xb2\xf5\x2c\x9c\x04\x3c\x50\x26\x37\x0c\xc3\x94\x0d\x47\x35\x2d\xf1\x5d\xe3\x1a\xbc\xf6\x5e\x42\x34\xe6\x5d\xea\xab\x86\x61\x9a\x73\x4e\x37\x9b\xcb\x74\x8a\xd6\xd5\xe8\xe6\xc7\xa9\x56\x6c\x32\x6b\x5f\x06\xc9\xde\x19\x59\xb5\x8a\x1f\x4c\x9c\x62\x45\x82\x1b\x93\x29\x96\xa9\x4c\x4c\x93\x44\x2e\x06\x15\xf5\xd6\xe5\x25\xd0\x02\x8e\x16\xcf\x36\xbd\x30\x5c\x33\xc0\x10\x6f\x8a\x9f\x7c\x53\xa4\x34\x58\x3c\x15\x2f\x41\x72\xa6\x86\xf5\x04\x0b\x3a\xed\x89\x49\x77\x43\xe9\x3b\x27\xa9\xcd\x9c\xbc\x36\x4c\x25\xa9\x1d\x0d\xda\xca\x18\xcf\xe2\x63\x4a\xe8\xd4\x03\xc0\xd4\x0f\xb4\x2f\x6a\xd5\x5e\x38\x68\xa3\xa3\xf2\x61\x8c\xe5\xb0\x10\x2d\xa1\xf6\xec\x96\xe4\xc3\x96\x60\x11\x37\x65\x86\xe7\x75\x8c\xa8\xa8\x51\x11\x78\xfb\x3b\x03\x94\xbd\xd4\x12\x16\x79\x0c\x41\xd3\x4c\x17\xdc\xdb\x59\x07\xfc\x9a\x9c\xf6\x61\x2e\x09\xa9\x10\x72\x5e\x66\x4f\xd8\x70\x4c\x66\xef\x14\xa6\x2c\xdd\x4b\x1d\x35\x1b\x6b\x8c\xa7\x81\x98\x53\xf7\x16\xc6\x53\x08\x8b\xca\x59\x4a\x96\x36\xd8\xf4\xfe\x58\x30\x57\x18\x00\xca\x94\x9d\xc0\x1b\x78\x1b\xac\x01\x09\x7c\x8d\x9d\x4b\xe2\x4f\x02\x16\x0d\x9a\xc5\x82\x11\x80\x1b\xf6\xed\xb9\x49\xde\xf9\x4d\x4e\x1e\x0c\x7e\xeb\x11\xb4\xf9\x48\x25\x93\x3a\xf5\x3e\x70\xcc\xad\x14\xc6\x4a\xae\x6b\x51\x5e\xe9\xd0\x59\xb8\x07\x44\xa2\x37\xcc\x7e\x82\x5f\xe4\xea\x26\x9a\xa2\xed\x39\xc9\xfb\x64\x3c\x01\xdb\x58\x6a\x86\xb8\xb0\x6f\x8b\x6f\x79\x67\x6a\x53\xd5\xf3\xf1\x94\x6d\x7c\xa5\xfb\x14\x83\xab\xee\x33\xaf\x66\x4b\xe6\x1d\x4d\x83\xa6\x51\x48\xf9\xae\x01\x4e\xd5\x19\x59\x50\x64\xac\xde\x67\x84\x8d\x26\x90\x04\x7d\x39\xbf\xc2\x67\xf0\x7b\x49\xd2\x27\x64\xb9\x64\xb5\xf6\x99\xba\xd4\x8b\x68\xdc\x17\x0d\x53\xe0\xf7\x4d\xc9\x7c\x24\x5b\xf5\x89\x58\x54\x69\x4a\xfd\xa2\x62\x35\xf9\xf3\xa2\xf5\xe9\xff\x79\x3e\x17\x57\x58\xa8\x68\x83\x5a\x02\xa0\x7c\xb5\x54\x5d\x94\x34\x60\xc8\x18\x0f\x09\x50\x65\x0b\x2e\x4b\x59\xbc\x4c\xce\xa9\x9f\x4b\x13\x62\x5c\x7c\xc5\xda\x95\xca\x66\xcf\xef\x3d\x0d\x7c\xf9\x13\x55\x89\x98\xbe\x68\x72\x10\x29\xa2\x7c\x55\xe5\xdf\x75\xc7\xaf\x73\x76\x5a\x18\x55\xd9\x49\xb5\x22\xdb\xbe\x36\xf9\xa9\x85\x8d\xf4\xc9\x79\x26\x09\x05\x2c\xd1\x56\xe2\x9c\xa3\xd6\x18\x84\xcc\x1a\x4b\x91\x8b\xb0\x5b\x53\x8e\x0f\x06\x18\xe0\xcb\x1a\x84\x26\xb6\xae\x0d\x1d\xfa\x04\x87\x25\x66\xed\x5d\x54\x79\x6a\x3a\xf2\x82\x6c\x6b\x5d\x65\x4a\xbd\x75\xab\xdf\x14\x2e\x48\xfe\x69\x91\x4d\xc8\xd0\xd0\x86\x8f\x97\x10\x47\xeb\xdd\x4d\xcb\x66\xae\x2d\x69\x9f\x51\x5c\x84\x00\x32\xc8\x4a\x1f\x59\xcd\xc3\xd8\x6c\xbc\x4e\x16\x0b\x60\x13\xa0\xf7\x49\x15\x8f\xb9\xfa\xc6\x61\x0c\xd4\x71\x36\xc7\x03\x17\xec\x6a\x76\x48\xe5\x8b\x5c\xcf\xed\xc7\xee\x33\x4a\x6c\xd4\x6d\x48\x31\x72\x92\x19\x5b\xf1\x86\xa5\xcc\x6e\x34\x0f\x02\x66\x9e\x07\x71\x71\x43\x51\xec\x38\xf6\x0b\x9c\x42\x40\x25\x8b\xa5\x27\x23\xfb\xf5\x42\xff\xb5\xfb\x9c\x5b\xa1\xad\xde\x14\x75\x9c\xd2\x1b\xa3\xf2\xd9\x63\xc1\x9b\xbe\xb2\x3d\xc3\xf5\x51\xd9\xd5\x9b\xaa\xc3\x26\xbf\x33\xc2\x72\x56\x51\x65\xa3\x0c\xc5\x94\xcf\xb2\x0d\x7e\xe0\x3f\x19\x8c\xa9\xc1\xb6\x7a\xa2\xa1\xd2\x09\x86\xb1\x96\x34\xd3\x9e\xd3\x0a\x7c\x55\x31\x2d\x29\x7c\x8f\x11\x3f\x4d\xed\xab\xbf\x2c\xf1\x08\x79\x6e\xd8\x6b\x4d\x3b\x2c\x17\x11\x21\xbe\x0f\x1c\xb7\x4c\x02\x62\x71\x7b\xc3\xe3\x4d\x6e\x5d\xc5\x85\xc6\x6f\xbe\x79\x34\x9a\x5c\x2d\xce\x1f\xd5\xdb\xe6\x28\x14\xdb\x46\x27\x86\x99\x0c\x55\xd9\xbc\xe2\xb9\x16\xb3\x9a\x5e\xca\xb7\xa5\xb2\xf2\xfc\xed\x05\x3d\xfb\x6e\xaf\xb1\x1f\x10\x87\xb9\x8a\x42\x14\x8f\x1d\xac\x57\xa0\x92\xa7\x46\xc7\x60\x76\xd0\x2e\x38\x07\xb3\xf7\xb2\xd2\xfb\xf7\x9a\x2a\xca\x0b\x9e\x9c\x2b\x97\xaf\x1b\xef\xa6\x5b\x6a\x8f\x8c\x0a\x41\x3d\xb3\x4c\xae\xe0\x47\xaa\xfe\x04\xfb\x21\x9f\x29\xbe\xdd\xa1\x1e\x76\xfb\xd9\x76\x41\x15\xcd\x39\xca\xf8\x3d\xf5\xda\x59\x45\xf3\x9c\xc3\x28\xd5\x15\x8a\xba\x5c\xd1\x24\xd5\x5b\x49\xe3\x2c\xa6\x33\x3f\x20\xfd\x67\x4e\x67\xae\x09\x6e\x38\x9d\x46\xc5\x6f\xcd\xe9\x14\x75\x6f\x31\x9d\x65\x0a\xdf\x7a\x57\x07\x9f\x74\x3a\x6f\x3d\x5d\x25\x4b\xa0\x62\xbe\x74\xbd\x69\xc9\x24\xd1\xcd\x44\xe8\x79\x87\x26\xb1\x8e\x59\x5d\xbf\x07\x7f\xfb\xf7\x1b\x45\xbf\x4f\xe3\x16\xc1\x76\x4c\x1a\x57\x7a\xfb\x3c\x21\x38\x41\xca\x13\xdb\x5d\xeb\x33\xb4\x1b\x78\xcb\x3b\x0f\xf6\x51\xb6\xe0\x03\xba\x8a\x4d\xd9\x41\xa0\x6e\x81\x18\x9e\xaa\x7e\xf9\x14\xbe\xb6\x4b\x88\x3b\xd1\x8d\x32\x8e\x42\x6a\x66\x62\x5a\x2c\x4d\x28\x73\x07\xbd\x22\xcf\xc5\xbd\xa7\x08\x9e\x36\xf1\x5a\x84\xff\x5c\x72\x35\x06\xa5\xd2\x64\xc9\x8c\xba\xd7\xf4\x3a\x05\x18\x1a\xcd\x52\xe9\x48\x68\x45\x98\xb0\x34\xe3\x32\x92\xa3\x72\x42\xf3\x0d\x09\xb3\xcb\x02\x20\xec\xe7\x07\x48\x62\x89\x48\x52\xfc\x30\x90\xe7\xa2\x06\x72\xc5\x85\x61\x2e\x88\x61\x40\x61\x66\x98\xa8\x43\xbd\x01\x30\x3d\x1e\x76\x41\xb8\x36\xe8\xb2\xac\x3c\x19\x92\x0a\xb0\x60\x26\xaf\x42\xb1\xda\x42\x1b\x1d\x60\x0b\x37\x24\xf2\x42\xb2\x18\xce\xe6\x42\xac\xd0\xe4\x88\x57\x16\x73\xd6\x9f\x0e\x8f\xf0\xbc\xcc\x88\xce\xe0\xa3\x3d\x9b\x63\xbf\x72\x45\x77\xc7\x51\xfa\xd5\xc9\x9b\x35\x09\xfd\x0c\x0f\xd9\xd7\xa5\xa5\x8f\xae\x11\xb3\xa3\xec\x22\x43\x29\x1c\x77\x57\x4a\x02\xec\x2a\x0a\x4f\xfb\xe8\xd0\x8e\xaf\x6d\x0a\xa2\xcb\x79\xf6\x9e\x87\x9d\xa7\xcc\x94\xe6\x93\xe7\x78\x0b\x53\x40\x6b\x07\x64\xcf\x9d\xca\x75\x9b\x66\x35\xd6\xad\xd8\xa7\x1e\xd6\xed\xc3\xba\xbd\xc5\xba\xbd\xcd\xea\x40\x0b\x61\x52\xad\xf1\xda\x30\x62\xc2\x28\x1a\xb9\x08\xe1\x26\x56\x0e\x20\x7b\x90\xb5\xca\xe7\xa9\x39\xdb\x31\x62\x76\x92\x0f\xcd\x00\x42\x45\xb0\xa5\x03\x6c\x61\x3c\xbb\x5a\xd4\x67\x1e\x62\xb0\xea\xb2\x07\x41\x4a\xbc\x1b\x2d\x2b\xee\x8d\x3c\xa0\x50\x44\xe2\xd2\x92\xc9\xc3\xff\x7c\x36\x23\x14\x70\x31\xbe\x06\x59\xc8\xd0\x3f\xf4\x04\x7d\x6f\x75\x87\x1d\xb0\x0e\x17\x81\xe7\x64\x07\xe9\xd8\x88\x74\x0a\x27\x9d\xc5\xd5\x60\x01\xb1\x90\xa7\x4b\xa3\x8a\x41\x13\xed\xa4\xac\x1e\x35\x63\x89\xc8\x28\x69\xb3\x52\xad\xea\x87\x9d\xcd\x84\x95\x29\x46\x52\xbe\xda\x8c\x91\x92\xe0\x3f\x9c\xc0\xdc\x7a\x7c\x7c\x06\xce\xe9\x58\xef\x74\x7c\x56\x2f\xbe\x13\x37\x81\x7e\xb6\xbd\x61\x08\x68\x22\x89\xb6\xaf\x93\x34\x85\x09\xac\x50\x80\x5c\x62\x96\xab\xed\x35\xfa\xaf\x59\xfd\xf1\xfa\xc5\xf6\xb1\xf3\xff\x44\x4f\xbb\xce\x25\x03\xba\x60\xba\x3c\x13\xcc\xd7\xbf\x0e\x17\x5d\x94\x93\x49\xe9\x35\xfe\x54\x22\x1b\x13\x4c\xd9\xf5\xf3\xd5\x82\x87\xce\x17\x81\x50\x98\xb9\x32\xc6\x4d\x16\x78\x34\xb2\xbf\x42\xc8\xf2\xed\x33\xa0\x55\x6a\x68\xa3\xea\xf1\x58\x08\x28\xe9\x3e\x12\x01\x2d\x44\x30\x4b\x18\x14\x0c\x67\x79\xcf\xc7\x66\x71\xf8\x12\xe3\x0a\xbf\xf2\xeb\xb5\x8e\x16\x37\x4b\xb9\x60\x4e\xd2\xf5\x42\x0a\xd1\x15\x19\x88\xa8\x46\x63\x9d\x6c\x4a\xe3\x65\x8b\x19\x02\xf9\x3b\x38\xed\xa0\x5f\x91\xc1\x31\x93\x11\x6c\x01\x28\x7d\xf1\x84\xa6\x9c\x16\x11\x56\xfe\xae\x15\x30\x35\x4b\xef\x85\x78\xbb\xc5\xd0\x0b\x34\xd3\x1b\x7e\x05\x7a\xc1\x08\x28\x0a\x16\xb9\xaf\x4b\xe1\x3d\x73\x70\x29\xbc\x47\xb7\x16\xe5\x6d\x25\x66\xa5\x48\x2d\xca\xc3\x17\xe4\xec\x47\x6d\x93\xbc\x7a\x6c\x73\xcb\x97\xa1\xd3\x30\xf7\xd2\x9b\x12\xe9\x55\xc3\x8e\x34\x2d\x56\x19\x3f\xfc\xcb\xa0\x3b\x2a\x4a\x26\x33\x84\xad\x34\x35\x0f\x02\xce\x35\xdc\xaf\x26\x4b\x0e\xb3\xb6\x06\xe6\xcd\xf4\x52\x30\x14\x5c\xb2\xe7\x49\x4a\x03\x19\xb1\x85\xfa\x69\xb8\xcc\xd5\x54\xe7\x33\x9f\x84\x23\x60\xb3\x35\xae\xdc\xb1\x9c\xce\x12\x4c\x7c\x00\xdc\xa0\xe5\xc4\xc5\xd2\x22\xc6\x18\xb0\x0e\x24\x73\xc0\xe7\xea\x35\x9d\x8b\xd7\xa7\x2d\x75\x39\x3f\x81\x88\x43\xe8\x62\x76\xc6\x9c\xc1\x4c\x9e\xc4\x54\x70\xb0\x53\x4c\x0e\x6e\x53\x5f\x83\x05\x7d\x50\x43\x3d\xff\x9f\x71\x11\x64\x72\x51\x78\x5a\x3b\xe4\x68\xd9\x3d\xd0\xdd\x19\xfd\x83\x56\xea\x23\x55\x0f\x9e\x88\xd3\x1a\xbd\x14\xc1\x93\x84\xd4\x1d\x22\xb3\xd8\xa0\xb0\xb9\xfa\x28\xe8\x0b\x60\xe9\xc2\x46\x11\x22\x92\x7c\x99\x1f\x01\x1b\x00\xc9\x70\x52\x23\x70\xc0\x14\x31\xeb\x68\xa7\x3a\xda\x76\xa1\x89\x8f\x8a\x37\x42\x03\xc3\x40\x3a\xd1\xea\xc7\x6d\xc9\xfa\xb0\xdc\x06\xb0\x24\xc0\x99\x6a\x1f\xba\x61\x91\xf7\x61\x04\x14\xb5\xa8\xa6\xd8\x85\xef\x17\x68\xfb\x69\xc1\x3f\x9f\x58\x5b\xb4\x37\xc9\x96\x94\x4b\xda\xaa\x71\x89\xf5\xc4\x00\x50\x71\x4b\x44\x8d\x06\x9e\xca\xb5\xbb\x19\xb3\xb4\xff\xf4\xdb\xf2\xe6\x55\xeb\x95\x36\x21\x8f\xc6\x18\x08\x55\x8b\xe5\x2c\xf3\x22\xcb\x2e\x41\x79\x32\xc9\x80\x0b\xce\xa6\x7c\x05\xb0\x2c\x1f\xd4\x12\x14\xf7\x6b\x64\xb8\x45\xbe\x95\x9f\x6f\x2e\xc6\x53\x6a\x24\xca\x0e\xf1\x5e\xf9\x4e\xcc\x2a\xd1\xe9\x93\xf0\xa7\x84\x74\x81\xf6\xc7\xf4\xc8\x4b\x08\xe0\x1f\xff\x30\xea\xe3\xf5\x40\x1d\xaf\x57\xd2\x65\xe4\x98\xa8\xca\x14\xeb\xf9\x5c\x6f\xb6\xee\x95\xb4\x5d\x24\xcd\x45\x12\x61\x28\xcd\x5e\x19\x08\x9a\x37\x77\xb7\x84\x0c\x57\xe6\x44\xd0\xc0\x74\x5f\x36\x91\x0b\xe5\x75\x66\xfa\x85\x12\x38\xfe\xae\xd4\x41\xf0\xab\x78\x6a\x23\x68\x3b\x25\xaf\x74\x19\x7f\x7f\xcb\xea\x7e\xf1\x36\x87\x55\x80\x25\x56\x31\x03\x54\x3e\x32\xb5\x57\x65\xf9\x77\x4b\x4b\x05\x34\xbd\x2b\x35\x43\x81\xb0\x25\x60\x2e\x83\x5b\xf0\x88\x8b\x06\x28\x6a\x66\x98\x4b\xaf\x4c\xf4\x90\x44\x51\xc9\xc9\x1b\xb6\xd1\x79\xf2\x41\x7a\xb5\x56\x51\x59\x72\xfd\xa0\x0e\xe8\x42\x48\xa9\x53\x3b\xbf\x78\xc4\x0c\x0f\x8c\x0b\xd2\xfa\xb0\x3e\x0b\xe6\xb8\x36\x40\x8b\x64\x42\xb1\xc7\x1f\x08\x0c\x55\x92\x09\x0d\x3f\x9a\x41\x96\x64\x1c\x28\xce\x31\x8c\x00\xf9\x55\x6a\x2e\xe7\x4b\x96\x83\xc5\x58\xab\xf5\xe4\x9a\x55\x7a\x56\x2e\xdf\x0c\xcf\xb3\x8b\xd5\xe0\x9a\x05\x4e\xa6\x32\x47\x8b\xe9\x91\x04\xcf\x08\x82\xe6\x64\xfc\x98\xe7\x6c\xa4\xa7\x18\x2a\x62\xf1\xb7\xba\x18\x46\x8e\x66\xef\x49\x75\x45\x86\xa5\xd9\xee\xb8\x31\x25\x5a\x7c\x52\xeb\x3f\xbb\xdb\xea\x6b\x5a\x45\x75\x5e\x15\x2f\x6b\xda\xc3\x54\xbb\x58\xa9\xa8\x55\x1f\xeb\x84\x77\x93\x8c\x8f\x66\x27\x0a\x59\xbf\xd8\xe0\x5e\xce\x16\x8b\x31\xd8\xd0\x5b\x57\xec\xad\x9d\x9b\xf2\x81\x2a\xda\x83\xd2\x6f\xfc\x04\xfe\x7b\x01\x4a\xae\x3e\x87\x15\xbc\x2e\xfd\xce\x1d\x9e\x8c\x95\xc8\x4a\x5b\x57\xfd\xa2\x8c\xc5\x34\x4f\x29\x73\x21\x58\xc6\xeb\xf8\x77\x45\x41\xb1\x2a\xd7\x8b\xee\x5c\xe6\x1a\x4c\x84\xd7\x2d\x13\xcc\x85\x85\x5c\xaf\x1e\x9d\xc9\x91\xbf\x6b\xae\x20\xb1\xf0\xd0\x5c\x42\x2c\x1c\x09\x28\x7d\xb7\x76\x78\x99\x4d\x8f\x8f\x0f\x0a\xd5\x3e\x36\x34\x8a\xa0\x53\x6c\x16\xbc\x2e\xc6\xd7\xfb\x53\xb5\x5c\x6d\xd3\x23\xba\x8a\x17\xcd\x96\xb1\x7d\xdd\x14\x59\x89\xe6\x1b\x68\xe1\x26\x70\xa8\xab\x36\xd8\xe6\x5e\x19\xb8\x2b\xe0\x6f\xc8\xaa\xa3\xad\xf1\x12\x38\x98\x04\x6c\x41\x33\x80\x61\xf6\xb8\x2c\xf7\xa2\xcc\x31\x9e\xce\xe8\x1b\x8d\x01\x82\xe5\xcd\xc2\xd1\xee\x47\x57\x5c\xd2\x14\x79\x71\x4d\xc7\xd6\xf6\x63\xe7\xd1\xa3\x8d\xfa\xe5\xd7\x96\x33\x9a\x6f\xc8\xe6\xca\x51\x51\xcb\x42\xaa\x56\xc2\xe4\x15\x65\xe2\x14\x63\x63\xb3\xaa\xca\x4b\x40\x9c\x0e\x24\xd7\xbc\xce\x1a\x9f\xc4\x1b\x7e\xec\xad\x67\xb3\x43\xa9\xac\xea\x5e\x83\xb6\x83\x57\x1b\xe2\xa7\xf9\x76\x83\xf5\xdc\x44\x9c\x26\xd0\xac\xb8\x48\x65\x8c\xbb\x97\x3a\x88\xe5\xd7\x1d\x62\xd5\x25\xf6\x25\x37\xe5\xcd\x3c\x81\x28\x28\x64\xff\x87\xed\xa5\xee\x32\x94\xb7\x05\x6d\x13\x93\xc4\x9f\xba\x7b\xa2\x69\xf9\xad\x82\xdc\x5d\x19\x0e\xce\x68\xdb\xc7\x9c\xbc\x2d\x84\x2c\x51\x97\x8d\x37\x2a\xf4\x2d\x8a\xd7\xe6\xbe\x15\x6a\x19\x39\xd2\x92\xb2\x04\xf3\x2f\xa6\x40\x8d\x20\xee\x6a\x15\xe0\x9d\xe9\x18\x8b\xfd\x2d\x1e\x62\xa1\xb8\x55\xd5\xf2\x41\x8a\x56\x53\xd0\xde\x9f\xba\xd7\x51\x10\x7b\xf1\xb0\x8b\x89\x0d\xe2\x28\x0e\xa3\x51\x34\x3a\x6b\x73\x55\x3c\x82\xe6\x0f\x79\x3f\xda\x8d\x2e\x63\x65\x14\xac\x63\x61\x39\x7c\x89\xba\x39\x23\xa3\x61\x6d\x9a\xef\x79\x65\x6b\x4c\xf6\x57\x6a\x2a\x3c\xf2\x75\x92\xd3\xe9\xca\x4b\x46\x8d\xd9\xc0\x17\xf4\x0a\x6b\xf8\x6e\x03\x38\x14\x85\x51\x6d\xe9\x11\xb4\x16\x59\x4b\x59\xa8\x25\x17\x93\xa4\xac\xbc\xe6\xf2\x6a\x46\xaf\x04\x28\xee\xd0\x18\x5e\x15\x8b\x8e\x12\x46\x01\x99\x32\xf5\x6a\x19\x44\x7e\x19\x27\x1d\x86\x59\x52\x88\x02\xb8\x93\x6c\xb1\xa4\xb6\x0d\x64\xbd\x14\x17\xa8\x06\xf3\xd4\x3d\x03\xdd\x4a\xbe\xf6\x40\x5b\xa7\x97\xf1\x58\x19\xbe\x26\x6d\x2a\xa8\xdd\x6b\x7a\x81\x51\x6c\x59\xe3\x30\x85\xc5\xaf\xb5\xc8\x4c\x79\x7c\xb8\x54\x6e\x97\x1b\x72\x20\x44\xbc\x0a\xca\x61\xd8\x2c\x78\x24\xf8\x97\xd8\x80\xdb\x29\x66\x7b\x52\x8b\x2b\xb7\x3a\x5e\x5c\x8f\x51\x18\x8f\x46\x96\x63\x50\x47\x3a\x9d\xa8\xa2\x79\xe9\x5d\x11\x5f\x84\x1f\xe6\xe0\x44\x3a\xa7\x77\x8d\xb4\x79\x59\x65\x02\xa9\xa8\x89\x08\x2d\x79\xa5\x95\xae\x5e\xc5\xd5\xa7\xe0\xca\x96\x3b\xfc\x98\x3e\xd5\x75\x20\x59\xf9\xb2\x47\x08\x3d\x5c\xc6\x2f\x93\xea\xb9\x8e\x40\xee\x2d\xeb\x2c\x75\x08\x9d\xa6\x94\x6a\xc4\x01\x23\xbf\xd8\x31\x1c\x9c\xca\x42\x44\xfd\x5e\xb8\x1e\x27\x08\xad\x17\x44\x35\xdb\x35\x8e\x3e\x40\xec\xda\x01\xe6\x8d\x79\xd3\xdd\xc6\x43\x35\x57\x3e\x19\x8e\x3a\x25\xde\xe7\xac\x69\x6a\x83\xc2\x7e\xe7\x7e\xe7\x6b\x5f\x62\xd8\x21\xbe\x85\x6d\xfd\xb9\x1b\x18\x2c\x4b\xb3\x46\xa5\xb8\xac\x84\x7f\x65\x51\x1b\xa1\xb8\x5a\x5a\x4e\x61\xf7\xd7\x60\x1e\xa4\x46\x57\x27\x7c\xd2\xc6\x3b\x62\xb5\x59\xa4\x81\x12\x65\x87\xc1\x39\xd6\xee\xc5\xba\x5a\x08\x9d\x46\xd1\x73\x76\x4d\xf6\xeb\x52\x74\x83\x59\xee\x7c\x62\x0a\x80\x66\xf4\x59\x2d\x88\x25\xb9\x67\x86\x08\x90\xc0\x3a\xbb\x8a\x64\xb2\x8d\xfd\xcb\x61\xe2\x15\xb0\xcd\xde\x08\x38\xae\x70\xcc\xb5\xed\x47\xf5\xb7\xd3\xb2\x4d\x5b\xd9\x5f\x8b\x05\xb9\x6a\xd1\xf0\x09\xc8\x4a\xf4\x6d\x8d\x10\x58\xb1\x88\xa4\x23\x64\xf4\x52\x2c\x43\xb5\x82\x39\x20\xbc\x10\xb5\x5e\x0b\xc9\x25\xd9\x2b\x43\x61\x49\x17\xa8\x5b\x58\x1b\x4a\x4b\x7a\x41\x20\xbd\x91\xe1\xb8\xf6\xb1\xf6\xb1\x85\xdd\x43\x2f\xc4\xc4\x09\xc5\x97\x7c\x2d\x73\x7f\xdb\x93\x4c\x00\x62\x87\xd2\x2e\x9a\xa4\x47\x4c\xed\xfd\x25\xee\x53\x5a\x80\x16\x11\xe9\xf8\x13\xec\x4d\x79\x54\xe5\x6a\x36\xcd\xbd\xe7\x0d\x6c\x9a\x93\x1d\x0b\xa3\x20\x79\xd4\xaf\xcc\xb2\xef\x1a\x45\x7d\x5f\xba\xc3\x2d\xc5\x1a\xbb\xc0\x12\x61\xe0\x13\xec\x2a\x4c\xe3\x20\xa9\x16\xe4\xc5\xa4\x01\x96\x77\x0a\x76\xfb\x8d\xe7\x57\x19\xf9\x9c\x9b\x98\x9a\x63\x9c\xc2\x60\x23\x21\x4d\x9e\xb2\x89\x29\x51\x17\xe9\xb0\xe4\x7b\x93\xc4\x64\x14\x85\x8f\x71\x9b\x10\x4d\x34\xd6\xc6\x18\xd9\x9a\x3e\x56\xea\xfd\x0b\xea\x98\x92\xc5\xe2\xea\x82\x54\x52\xae\x0e\x93\x09\x04\x80\xba\x91\xf6\x3b\xe5\x40\x46\x28\x03\xd3\x56\xa6\xcd\x54\xa9\xd5\x6c\x6f\xad\xe4\x56\xa4\xe6\xa1\x09\x18\x17\x9a\xa8\x83\x9a\xa3\x29\xaf\xb7\xeb\x8b\xae\xa6\x4d\x61\xfd\x89\x12\x77\x81\xe4\xa9\x0a\xe9\x90\x53\x81\x23\x88\xdf\x12\xe7\xa6\xa0\x53\x92\x57\x0f\xab\x6c\x4a\xe5\xcd\x62\xd7\xc8\x8b\xb0\x22\x84\x0d\xb7\x09\xa1\xec\xa9\xb8\x54\xf5\x8b\x8d\x72\xed\x28\x83\x56\xa2\x14\xad\x77\x43\xf2\xc2\x6c\x22\x51\x75\x65\xf2\x6b\x3a\xc2\xfb\x12\xfa\x6f\xf9\x65\x49\x95\x15\x46\xf1\xc2\xe4\x05\x85\x0e\xad\xd4\xbb\x27\xd9\x01\xf0\x78\xa7\x0f\x8d\xc1\x5a\x06\x8f\x21\xaa\x30\xb8\x64\xf1\x82\xea\xab\x63\x79\x0d\x66\x79\xc1\x1e\x50\x4e\x81\x66\x10\x70\xb9\x57\x48\x1e\xa8\x3c\xa3\xb6\x15\xe3\x29\xb3\xe4\x65\x76\x00\xcc\x64\xe6\x5d\x36\x45\x63\xde\x72\x68\x22\x4a\xb9\x05\x18\x0d\x9d\x5d\x0e\xab\xa8\x33\x40\x15\x96\x24\x24\x6d\x39\x71\x88\x26\xc7\xf8\x81\xdb\xcf\xee\x8f\x9c\xd9\xc5\x18\x64\x84\x0e\x28\x5f\xe0\xd3\x87\xf1\x64\x02\xbe\x2c\xbc\x41\xc2\x38\xc9\x88\xcf\x2e\x26\x37\x77\x74\xb8\xa7\x56\x13\x6c\x98\x3a\xa4\x7b\x1d\x9c\x52\x68\xfc\x13\x70\x21\x3a\xc9\x69\x91\x05\x49\xd4\x48\xb6\xf1\x8c\x9c\xb4\xb2\xd6\x23\x1e\x8d\xea\x51\x87\x25\xee\xe8\x30\xf3\x2d\x8b\x58\x74\x47\xd0\x3b\x84\xd7\x90\xe1\x80\x3f\x8f\xec\x67\x66\x0a\x46\xe6\x6e\x9c\x9a\x3b\x9c\x44\x3b\x8c\xba\xa8\x62\xd3\x6c\xd4\x4f\xa7\x99\xcd\xb2\x45\x51\xfd\x8d\xf5\x2a\xa9\xa0\x44\xa6\x70\x5a\x71\xf8\xb4\x57\x23\x82\x1e\x5d\xda\xd2\xba\xb6\xe5\x6d\xa6\x89\x07\x72\x05\xae\x88\x71\x97\xa7\x41\x66\x0b\xe9\x23\xdc\x4b\x61\xde\x50\x69\x3c\x10\x7f\x63\xc0\x5a\xa0\xcd\x65\x39\x06\x68\x37\x5d\x50\x93\x8b\x64\xd0\x4c\x41\xce\x93\xc9\xf2\x31\x07\x17\x28\x7d\x04\x94\xd4\xd0\x79\x0a\x67\xb3\xb3\xd4\x11\x13\xa5\x1a\x1e\xc6\xf3\x23\xb5\x90\xa2\x57\xd3\x6a\x9b\x34\x03\x8a\x8a\x3b\x65\x7c\x99\xb3\x3c\x8d\x25\x77\x04\x2c\x8b\xf8\xb5\xba\x3e\xdc\x10\x25\x4e\x28\xc4\xcd\xdf\x4c\x1a\xae\x7b\xd4\x8f\x13\x5a\x25\xfb\x04\xd9\xe8\xb8\x05\xa5\x69\x17\xce\xa5\x3c\xce\x6c\xcb\xb7\xb8\x46\x5a\x71\x43\x87\xed\xce\x0f\x04\xd3\x75\x69\x7b\x5e\x5d\x3d\xce\x5c\xaa\x9a\xec\xcb\x9f\x87\xbe\xbc\x54\x3a\x78\x50\x9b\xd7\x15\xe2\x0d\x8b\xaf\x96\x26\xf9\x11\x1f\xea\x47\x2b\x6a\xb0\x6f\xdf\x30\xa8\x1d\xee\x57\x83\x9d\x6f\x47\x9f\x48\xe1\xa0\xed\xae\xb3\xa5\x4d\xb9\x6b\x92\x5d\x00\x3c\x88\x2d\x5c\x5c\x91\xb0\xa7\xc3\x2b\x64\x0c\xf6\x4c\xb7\x3d\x9b\x77\x27\x15\x63\x69\xdf\x0a\x5d\x5a\xc3\x2d\x56\xc1\x60\xcd\x18\x92\xc0\xaa\x98\x57\xf4\x25\xf6\xeb\x0c\x39\x00\x44\x61\x7e\xd4\xf6\x25\x3d\x7e\x01\x0d\xc8\x36\x8f\xc9\x40\xb0\x82\x71\x48\xa5\xb3\x35\x35\xcc\x54\xa0\xdb\xf4\x26\xc6\x13\xdf\x2d\xf4\xc1\x7f\x02\x3f\xbe\x63\x05\xf1\xe7\xce\x98\x3f\x47\x3d\xb1\x89\x19\x36\x55\x14\xdf\x8a\x31\xde\x39\x8a\x45\x45\xf1\x5d\x31\xee\x9a\x7a\xe2\x4f\xce\xbb\x3f\xb9\xb2\xf8\xd3\x6f\x15\x1d\xc5\xb6\xc7\x72\x42\xbb\xbb\xbd\xa3\x96\x3e\xdc\x7e\x7f\x61\xda\x3a\xe4\xf1\xad\xb9\x7b\x94\x29\xc8\x73\x55\x9e\xc8\x74\x29\xa7\xb4\x64\xf9\x2b\x21\x29\x65\xf0\xb9\x26\xa5\xbc\xf3\x1c\x94\x4d\x73\x4f\x2a\x39\x27\x0b\x88\x15\xd3\x4f\x6a\x69\x27\x79\x45\x4b\xe2\x49\xd4\x8f\xe6\xc0\xc5\x4f\x35\xf9\xe4\xcb\x64\x79\xde\x71\x0c\x29\x28\xf3\xe3\xf5\xc1\x6c\x08\x59\x1e\x66\x93\x9b\xd1\x78\x02\xaa\x3e\xba\x69\xb1\x53\xbc\xe1\xc8\xcb\x62\xdb\x6f\xaa\x05\xb5\x86\x15\xc6\x24\x5e\x3f\x87\xf7\x1f\x37\x8a\xb1\x83\x24\x5b\xcb\xc1\x2f\x05\xa6\x86\x36\x82\x57\x03\x98\x41\x9d\x88\x9f\x83\x7f\xef\x72\x06\x9f\x08\x46\xa4\xb2\x5e\x80\xd5\x23\x1f\xa7\xd9\x07\x40\xa0\x1c\xc2\xf4\x6a\x32\xb1\x2c\x14\x81\x41\xbe\x4c\xa4\x78\x47\xa6\x48\x9e\x7c\x4e\xca\x95\xdc\x56\xc5\xf6\xc1\x78\x30\x4f\xe6\x37\x55\x3a\x72\x29\x3f\xa8\x15\x14\x66\x0b\x65\x5a\x4f\x10\x2e\x78\x97\xc9\xcc\x8f\xa7\xe0\xd6\xa7\x04\x70\x55\x22\x3a\xe8\x79\x46\x8b\x11\x46\x8b\xd3\x59\x23\xec\x1f\x8f\x31\x8c\xee\x71\xc2\xcf\x00\x94\x39\x0c\x21\x16\xca\x83\x8a\x41\x85\x53\x65\x79\x1c\x40\x2e\x77\xcd\xc8\xc6\x35\x1f\xa7\xa4\x99\xd7\x54\x21\x32\xce\x16\x94\x81\x5f\xde\x40\xae\x07\x9a\xcd\x38\x47\xa0\x46\x0b\x7a\xae\x86\x93\xa6\x00\x0a\x32\xd7\x1d\x44\x0d\x84\x89\x7a\x79\x73\x42\x49\x58\x91\x6e\x4a\x4c\x12\x65\x7f\xb1\x71\x36\x49\xd7\x9d\x47\x98\x29\xeb\x51\xa7\x56\x9b\x68\xd1\x92\x2d\xcf\x67\x69\xa9\x8f\xbc\x54\xfa\xa3\xf1\xcb\xc7\xca\x70\x86\x14\x7d\xc5\x20\x9b\xcf\xab\x35\x88\xe1\x65\xf2\x61\x6a\x31\x36\x16\x0c\x41\x4a\xab\x67\x33\x27\xde\xba\x7a\x77\x41\xb8\xae\x39\xaf\x44\x39\x16\x12\xf3\x61\xe7\xae\xbc\xbc\x31\xfd\x83\x11\x01\x66\x26\xc5\x5d\xbf\xc6\xc2\xb1\x74\x6e\xc6\xe9\x1b\xde\xe4\x79\xb2\x38\xfc\x30\x65\x64\x7f\xd3\x7a\x04\x35\x1f\xb5\x85\xcf\x13\x3c\xe2\x26\xc8\xcb\xc3\x8b\xca\x7e\xd0\x5a\xa5\xd3\x6d\xa8\xf5\xff\x2e\xae\x2e\x41\xd4\x22\x95\xd7\x12\x10\x4e\xd9\xd6\x97\xcc\xdf\x5d\xc1\xe8\x2e\xac\x49\x15\xea\x8f\x53\xee\x71\x0b\xd9\x5c\x72\x8e\x3e\xa6\x4a\x61\x3e\xe9\x74\x95\x1a\x2e\x0f\xa4\x0e\x2a\xfb\x81\x65\xdb\x11\x8a\x71\x48\xa0\x06\x49\x9d\xb0\x8b\x50\xd2\x0e\x9b\x0e\x03\x93\x8b\x4d\x00\xad\xfb\x4c\xe1\xe8\x6d\x63\xaa\xbb\xb8\x52\x16\x32\xd3\xa4\x2a\xef\xab\x29\x1d\x07\x9c\x40\xba\x6a\x8b\x21\x50\xb7\xf8\x7c\xe4\x19\x6c\xc8\x04\xf3\x6b\x38\x10\xa5\x31\xa4\x1c\x02\x69\xb5\xbf\x64\x5e\xc9\x23\x96\x93\xc9\xa0\x6c\x93\xdf\x59\x54\xf2\x22\x5a\x6e\xbd\x6a\x09\x68\x8d\xe0\x92\xcb\xe3\x64\x17\x57\x2e\x8f\xa0\x2e\xad\x11\xfe\x4e\x5d\x27\x56\xaa\xe1\xc5\x57\x21\x9b\x32\x77\x75\xcb\x5c\x39\x87\x8c\x99\xb1\x24\x01\x48\x52\x68\x42\x4f\x4e\x49\x8b\xd9\x45\x46\x53\x4f\x41\xd0\xdc\xa9\x73\x33\xbb\x9a\x0b\x33\xfb\x04\xc4\x59\x0a\x7c\xed\xf3\xda\x05\x75\x47\xe7\xb2\xbd\x8c\x31\x80\xb5\xb5\xb5\x3b\xdf\xee\x2a\xd1\xa8\x35\xa7\xdb\xb3\x4b\x10\x76\x2e\x73\xb9\x87\xc9\x3b\xb7\x10\xa7\x24\x60\xa8\x61\x52\x64\xaa\x0b\xd4\x44\xde\xf1\x94\xb2\xd5\x49\xf7\xcf\xba\xf2\xcb\x8a\xe3\x8e\x8d\x28\x97\xd8\xa2\x7f\xc6\x35\x2e\x22\x1e\xf2\xcb\xb6\x57\x44\x4e\x07\x65\x6c\x45\x3d\xbe\x55\xe7\xc5\xf4\xcd\x5a\x06\xd8\x2e\xdd\x62\x61\x3a\x4f\xe5\xe2\x67\x84\xf4\x73\xc0\xea\xa7\x06\xa9\x8b\x2c\x9b\xec\xae\xf3\x61\x36\x7d\xb4\xa4\xf2\x33\x77\x77\x94\x82\x17\x4e\x66\xb3\x4b\x27\x19\x10\x41\xc7\x2c\x9d\xd8\xbb\xfc\x88\x43\x7b\x64\xef\x30\x72\x51\xd1\xaa\xdc\x4f\xf1\xb6\x46\x5e\xad\x5a\x8b\x47\x1c\x4e\xb0\xa7\x68\xff\xd2\x64\xdd\x98\x36\xbe\xe1\x84\x1c\xe0\xef\x81\xe3\x21\x5c\x88\x6d\xcc\xf7\x10\x7c\x51\x63\x27\x83\x62\x95\x1b\x99\x9c\x8b\x44\x15\x8e\x38\x3f\x35\xda\x93\x99\xcf\xc8\xc6\xdb\xfd\x29\xd9\x11\xc0\xf3\x56\x8b\x45\x58\x1a\x59\xa8\x10\xe7\xbd\x1c\x84\x29\x3c\x8d\x30\x7e\x50\xc3\x21\x92\xd7\xd3\xf1\x68\x3c\x4c\xa6\x4b\x16\x50\x72\x4c\x7b\x8f\x20\x69\x3b\xa6\x63\xf2\x8f\x92\x07\x31\x3d\x2b\xcb\x6f\xee\x20\x6c\x4c\xb1\x79\xfb\xc5\x89\x32\x3c\xb2\xe9\x55\xc5\x58\x9b\xcd\xc2\xc4\x48\x69\x37\x18\x95\x83\x96\xdd\x59\xaa\x17\xd9\x3f\x5b\xd9\xd8\x0b\xb6\x30\x16\xed\x7f\x7d\x00\xa7\xee\xb5\xeb\xba\x9e\xeb\xbb\x01\x38\x9c\xbb\xa1\x1b\xb9\xb1\xdb\x3d\xbb\x37\xc0\x1d\x27\xae\x1d\x7a\x85\x85\xaf\xe3\x33\x52\x58\xb1\x1f\x98\x43\x30\x2e\x57\xfe\x40\xff\x25\x07\x3f\x88\xd9\x6b\xf0\xcd\xe7\xd3\xfb\xcd\xa6\x41\x51\x58\xe0\xbf\x92\xd1\x10\xff\x59\xdb\x98\x54\x07\x40\xc9\x63\x92\x4d\xdf\x2d\xcf\xa9\xe9\x91\x95\x8b\xd4\x8f\x19\x93\x2f\x94\x66\x91\x62\x76\xa7\xc3\x59\x0a\xf4\x9e\xd1\x1f\x3a\xb9\xe3\xeb\xf2\xd8\x9f\x82\x00\x48\xd9\xb5\xbd\xec\x7a\xf5\x00\x32\xb5\x56\x7b\xe3\xe0\x2e\x39\xb1\xd6\x88\xec\x62\x88\x6b\xd0\x6e\x5e\x45\x19\x92\x37\xcb\x51\xaf\x51\x34\x17\x36\x15\xd6\x58\x2e\x7c\xaa\x08\x71\x93\xe1\x2e\x0d\xdf\x52\x41\x40\x43\x32\xa5\x53\xb6\xe7\xab\x14\x64\x61\xfe\x76\x42\x92\xee\x61\xf3\x01\x3f\x61\xdc\x50\xa2\x4c\x4c\xf3\xdb\xf4\x5e\xb7\x2e\x2e\x75\x68\x43\x60\xe7\xf1\xf8\x19\xe2\x8d\x6f\x4f\x69\x86\x25\x75\xa6\x44\x03\x3b\x37\x0b\x47\x02\x03\xfb\x63\x81\x61\x6d\x98\x0a\x26\x22\xd4\x41\x4e\x62\xf6\xd2\xe3\xc5\x71\xce\x63\x14\x9e\x63\x01\x7e\xac\xb2\x24\x0a\x3f\xaf\x53\xbc\x11\x9d\x90\xc1\x75\xb2\x6b\x8c\x24\x09\xa1\x90\x0d\x31\xb0\x86\x50\xa6\x65\xb9\x4f\x1d\x9a\x82\xa4\x28\x88\xff\xdd\x12\x28\x1d\xeb\x83\x48\x0a\x61\x5d\x6f\xc0\x4e\x6f\x49\x28\x76\x39\xbe\xc8\x4a\x0e\x28\x52\x48\x76\xb9\xbb\xf6\xa4\x10\xf2\xe0\x40\x51\xd0\xf9\x8b\x59\x38\x1d\xf3\xa8\xd8\xf0\x4f\xcb\x0f\x9d\x27\x4e\x6b\x4c\x31\xfe\xd6\xe9\x11\xf9\x9d\x47\xcb\xb6\x4a\xf1\x14\x8e\xda\xe3\xc7\xce\x58\x84\xdb\xfe\x63\x33\x6f\xfa\xbb\xef\x78\x1b\x86\xf2\xa2\xd1\x1a\x82\xbf\x75\x5b\x92\xc7\x94\x2e\xae\x5b\x8d\xa9\x3d\xca\x7d\xdd\xee\x6f\x3a\xc5\xc1\xae\x93\x31\xf8\x2b\xfb\x66\xfb\x78\x53\x45\xd3\x94\x63\x25\x19\x4f\xe1\xb2\xd9\x9a\x8c\xb0\x2e\x3b\xa5\x31\x38\x40\x08\x54\x04\xc3\xf1\x72\x71\x57\x0c\x2b\x5f\x5d\x16\x26\x05\x9f\xeb\x86\xee\x35\xb0\xa6\x0f\x75\xd8\xd2\xc2\xca\x64\xcc\x0c\xc3\x20\xaa\x9d\x0a\x18\x3c\xce\xfc\x26\x2e\x1d\xfa\x1f\x92\x7e\xe0\x03\xe9\x2f\x14\xbe\x60\x20\x78\x45\x94\xda\x74\xe0\xde\x77\x6d\x98\x8d\x27\x79\xcd\xa7\x4e\xd8\x38\x01\xa8\x76\xfe\xad\xb5\xf3\x58\xcc\x91\x0a\xc7\xdf\x55\xed\x3e\xd9\x71\x57\xa6\x85\xc2\x79\x57\xa5\x85\xaa\x73\xae\x0c\x16\x4f\x6a\x14\x57\x39\xfa\x79\xf1\xe4\xbc\x56\xa4\x11\x66\x7e\xef\x5a\x4d\xea\xa0\x5e\xf3\x29\x90\xc4\x86\xd1\x78\x32\xe1\x61\x67\x99\x9b\x04\x9e\xb7\xaa\x85\x12\x7e\x98\x9b\x9a\x0e\xbd\x32\x28\xab\x8b\x4f\xad\x59\x66\x90\x6a\x11\xca\x5d\x19\x9f\xd5\x38\x82\x31\x57\x10\xcf\x7e\xd2\xa2\x25\x64\x32\x99\x9a\x8f\x58\x32\x7b\x28\x1e\xa8\xe0\xeb\x5c\xbd\x21\xbf\x78\xfb\xc1\x1e\x65\xfe\xed\x07\x88\x94\xfe\xf6\xc3\x86\xed\xfb\x6f\xb0\xcd\x5c\x07\x49\x9a\xc5\xbd\x81\x67\x2b\x96\x2c\x7e\x75\x64\xce\x51\x72\x4f\x50\xe3\xee\x8e\xb6\xda\x72\xaf\xfb\x6e\xdc\x27\x03\xdd\x82\x17\xb0\xa7\x3f\x27\xff\xb5\xc9\x4e\x85\x2f\x08\xc3\x72\xaf\x3d\x17\xb7\x7b\x40\x60\xc3\x06\xec\x03\x05\x16\xc6\x91\x4b\x81\x7d\xd0\x81\x7d\xa8\x0b\x0c\x87\x37\x5b\x5c\xa1\xa7\x4f\x0b\xd1\x20\x3c\x12\x6b\x12\x48\x38\xd2\xa5\xf5\x59\xdd\xa7\x9b\x58\x87\xfd\x57\x5e\x96\x6c\x9c\xee\x5a\x64\x2d\x83\x63\xca\x8a\x7e\x4b\xed\x6d\x38\xb5\x91\xed\xdc\x59\x8b\x9c\xff\x72\x3c\x67\xdd\x79\xe2\xd5\x11\x51\x0a\x9c\x43\x15\x37\x3a\xce\x7c\x98\x0c\xcf\x33\x96\x5d\xa7\x5a\xe0\x80\x9a\x6f\x81\x1e\xe7\xad\x16\xad\x0a\x47\x25\x05\x49\xd8\x4d\xa4\xc1\x30\x5f\x31\xd1\xaa\x9b\x04\x56\x8b\x96\x47\x82\xec\x0e\xba\x1b\x96\x84\x62\x34\x87\x4f\x4b\x94\xcf\xe1\x3b\x7f\x38\x6e\xcd\xb0\xe6\x70\x55\x98\xaf\x61\xbc\x75\x64\x0a\x90\x29\x4f\xdf\x53\xcf\xf9\x94\x1d\xed\xab\x0c\x69\xc8\x69\x12\x0d\x69\xe8\xbf\x66\x43\x1a\x72\xcc\x2c\x6a\x02\x4c\xe0\xa0\xe0\x26\x05\xba\x46\x7f\xd7\x8b\xbf\xa9\xab\x2f\xce\x49\xdd\xba\x2a\x8c\x1a\x27\xcf\x86\x51\x35\x6b\xb5\x7e\x57\x8c\x9c\x34\x56\x0c\xa1\xc9\xc6\x4f\x3a\xda\x57\x27\x12\x32\x06\xce\x5c\xf5\x98\xfa\xa1\xf6\xc9\x73\xd1\xf4\x18\x49\x67\xbd\x08\x88\x0c\x04\x91\xc6\xe7\xb5\xf3\x6c\x2d\x2a\x0f\x74\x98\x23\x6d\x4c\x0f\x72\x1f\x6e\x79\x88\xe3\xd8\xb1\x35\x8e\x60\x01\x48\xd8\xce\xd5\x3e\x64\xa7\x6d\xd7\xac\x0a\xed\xb8\xa3\xf2\xba\x16\x06\x21\xc0\xfd\x32\x1b\x4f\x5b\x8f\x1e\xad\x10\x71\x53\xa2\x70\xba\xde\x9a\x68\x7a\xf8\x4a\xa1\x84\x5b\x7f\xc1\x58\x84\xa7\x3f\x5f\x6a\xe2\x8b\x8d\xda\x6c\x8b\xf5\x58\x3f\x52\x26\xad\xd2\x2c\x51\x0a\xad\x73\xc0\x8f\x2e\xf4\x91\x1d\x65\x9a\xac\x9a\x0f\x4d\x52\xd3\xc9\x8d\xb2\x2d\xd4\x2f\xc9\x8f\x49\x57\x4b\x80\x9a\x09\xec\xf4\xfe\x74\xc9\x3a\xbb\x06\xd9\x42\x97\xf3\xd6\xb8\x43\x20\x74\x30\x09\x5f\xae\xb2\x80\x15\xd5\x6b\x9b\x1c\x70\x1b\xef\x79\xca\x30\x3d\x75\xfc\xba\xee\xb3\x07\x84\x5e\xa6\x5e\xbd\x4d\x8b\x95\xe5\xfb\x96\x78\x5c\x6d\xeb\x62\xd5\xff\xbc\xdd\xab\x2e\x02\x77\xb5\xa6\x26\xd8\x9e\x79\x0f\xa3\xb8\xfc\x47\x6d\x63\x74\x38\x3e\xe3\x9d\x4c\x42\x90\xee\x48\x74\xea\xd6\xc0\xf0\x18\xde\x6e\x13\xa9\x11\x37\xa9\xba\x1b\x92\x0c\xf0\x16\x7b\x92\x42\xb7\xab\x6f\x4b\x82\x1c\x1b\x2d\x86\xcf\x7a\x73\x62\xab\x88\xee\x4f\xf2\x72\xab\xbf\x45\x89\x5a\xcd\x76\x29\x51\x4d\x6c\x54\xe2\xcd\x7d\xef\x55\x5a\xd3\x55\xb9\x9c\xc7\x92\x16\x3d\xef\xed\xda\x90\x11\xf4\xd6\xb2\x35\xe6\x6b\x42\xdf\xaa\xcc\xba\xc5\xc6\x5b\x95\x86\x70\xdd\x9d\xea\xcd\xc9\xf3\x27\xbd\x7a\x1b\x15\xdc\x24\x8a\x6d\x8a\x3d\xac\xb6\x49\xd1\x46\xff\xbc\x3d\xaa\x66\xfb\x77\xb5\xb2\xae\xc8\x58\x99\x37\x28\x18\xc5\xfb\xdc\x9e\x96\xf3\x9b\x12\x03\xa3\x34\x83\x23\xfa\x9b\xa3\xfd\x6d\xee\xe9\xd4\xca\x16\xc3\xe4\x32\x6b\x95\x6c\x9c\x45\xb6\xec\x0c\x93\xe5\xf0\xdc\x69\x65\x16\x03\x86\xf9\xec\x03\xd2\x2d\x66\x5c\x69\x3d\x7a\x99\x4c\xc0\x11\x31\x4b\xd9\x34\x40\x9e\xb6\x47\xed\xdb\x9a\x21\xa8\x44\xb5\x3a\xff\x66\x73\xd5\x84\x4c\x3e\x6b\xe6\x8d\x14\x46\x59\x77\x4e\x86\xf5\x19\x37\xab\x63\x33\x06\x50\xb6\x86\xab\x29\xa3\x1e\x6a\x21\xa0\xd0\x15\x87\xd3\x6e\x37\x4e\xc1\x8b\xb9\x30\x09\xb5\xd0\x6c\x66\x92\x17\xba\x35\x13\xaf\x64\x27\x7b\x23\xa5\x44\xbb\xb8\x82\x04\xc5\x99\x33\x86\x11\x05\xdb\x6e\x9a\x67\x2d\xc1\xeb\x75\xd2\x21\xe1\xb1\x50\x2b\xb7\xaf\x96\xa7\x53\x55\xee\xd3\x1c\x87\xd4\xb5\x2a\x4f\x10\xff\x6b\x76\x09\x51\x07\x2f\x79\xd2\x40\x35\x3b\xa8\x64\xd3\xe2\x9a\x7c\xc2\xd8\x38\x60\xa6\xc1\x2d\x31\x0a\xc2\x4b\xcc\xf6\xb9\xa6\x19\x1c\x66\x77\x65\xd6\x3c\xda\x48\x3f\x62\x49\xb4\x59\x12\x53\x70\x46\x58\x2e\xb8\x57\x8c\x03\x14\x7c\xdb\x3b\xa6\x81\x11\x79\x9a\x10\xd7\x7e\xc9\x54\x2b\xeb\x2e\x33\xef\x73\xd0\x4a\xd9\x64\x33\x80\x19\x38\x99\xa7\xa2\xb6\xb3\xea\x4c\x89\x96\x9f\xee\x90\x0a\x5c\x58\x77\xeb\x4a\x9a\x5b\x29\x91\x3d\xa0\x0d\x9e\x17\xdc\x32\xd2\x8c\x16\xea\x6f\x8a\x22\xc8\x42\x21\xf3\x38\x33\x76\x71\xe8\x9a\x67\x4e\x00\x94\x5f\x52\x9f\x92\x44\xb2\xa0\xa4\xf6\xc4\xc8\xf1\xee\x67\x32\xdf\x52\x74\x5a\x8f\x8a\xfc\xbe\x56\xbd\xea\xbd\x91\x95\xac\x93\xcc\xbc\xe8\x5e\x9f\xa7\xa3\x93\x03\x8a\x4a\x03\xc4\x82\x89\x5a\xa3\x45\x52\x32\xaa\x88\x13\xc9\x68\x4d\x62\xca\x88\x61\x75\xa4\xb4\x2d\xec\x9a\x8d\x7c\xb9\x29\x91\x09\x58\x81\x68\x1f\x6f\xaa\x49\xd2\xeb\x52\x30\xcf\x75\x4a\x08\xe9\x7d\x32\x9e\x60\xc4\x2e\xca\x17\x90\xd9\xd9\xa9\xe6\x44\x72\x56\x19\x4f\xdf\xcf\x7e\x25\x20\xb4\x24\xc3\x2d\x96\x1c\xb8\xe3\x7c\x38\x1f\x13\x59\xc2\xc4\xaa\x07\x37\x25\xac\xda\xbe\x50\x06\xb3\xd9\x24\x4b\xa6\x64\x7d\xcc\x9e\x43\xd6\x58\xe7\xc7\xf3\x6c\x49\xe3\x99\xf0\x5c\xb4\xe8\xae\x45\x8a\x23\xa3\x60\xaf\x72\xae\x2d\xd8\xf5\x0a\xe1\x40\x04\xa7\xc7\x11\xbf\x83\x98\x1f\x1c\xe0\x0e\x25\x24\xdb\x9a\xe1\xa9\x72\x6d\x71\x39\x1a\x82\x31\x67\x0a\x56\x63\xad\xd2\xa2\xca\xe2\xa3\x03\xde\x50\x67\xc2\x96\x48\x4e\xdc\x1b\xe6\x32\xdc\x38\x0d\x47\xd6\xa6\x56\x81\x8f\x92\xa1\x99\x8d\xee\x85\x8e\x43\xc8\x0a\x9b\x5a\x4a\xe6\xb2\xc2\x1c\x7a\x5e\xdb\x1c\xd1\x6f\x7b\x76\x35\x5d\x72\xfa\x32\x30\x13\x00\x3a\xa5\x89\x84\x8f\x30\x6e\xf1\xa6\x8a\xff\x53\xad\xc9\x8d\x22\x2f\xb2\x0d\x39\x77\x9d\x24\x18\xa4\xce\xd5\x25\x75\x28\x1c\x4e\xae\xd2\x4c\xa3\xfb\x62\x35\x0d\xa3\xdc\xc8\x45\xfe\x50\x3f\xb6\xad\xc0\x22\x9d\x7d\x98\xca\x78\xcc\xa6\x93\x1b\x42\x3c\x62\x51\x76\x8c\x75\x27\x60\xfd\xb7\xb4\xca\x5a\xc8\x37\xe6\xd9\x45\x32\x9e\xaa\xc2\x55\xbd\x7e\x5d\x24\xd7\x2d\xa5\x5f\x78\x71\x4a\x0e\xea\x86\xcc\xec\x9d\xea\x2b\x55\x31\xe7\x54\xf3\x60\x9b\x72\xa4\x64\x8e\x87\xd2\xfa\xb7\x8e\x44\x80\x36\x7a\x42\xda\xb0\x92\x13\x7c\x55\xfb\x38\x9e\xb6\xe4\x26\x09\xad\x76\x14\x3a\x33\x99\x4f\xf2\x0c\xde\x26\x22\x01\xba\x93\x00\x96\xbb\x6d\x51\x3e\x4f\xd5\x2c\xec\xf7\x77\xf2\x08\x88\xb7\x8f\xa5\xf5\x64\x35\x9a\x00\xcc\xb2\x39\x9c\x26\xc5\xc6\xf0\x24\x3f\x20\xa0\x33\x64\x89\x57\x93\xbe\x07\x09\xae\x62\xca\x55\x5f\xe1\xa5\xc5\x52\x60\xe5\x0c\x1f\xa7\xdc\xac\x49\x15\x85\x64\x16\xa6\x27\xc3\x92\x47\x74\xc3\x2c\x48\x28\x09\xb1\xa4\x99\xae\xa6\x4a\x1e\x5b\xb4\x0c\x5b\xbb\xc2\x49\xc5\xbf\x23\x37\x7d\x5b\x63\xb7\xda\x59\x28\x4b\x9d\xbc\x6e\x69\xe5\x66\xd9\x0d\xff\x24\x93\xb7\xb7\x85\x0d\x31\xc7\xa4\xb9\xc5\x9b\xca\xc3\xc4\x49\xd3\x92\x89\x9e\x9f\xc1\xcf\x93\x05\x66\xc8\xb5\x9e\xb8\x2b\x53\x91\xe7\xec\x5a\xf6\x81\xa2\x93\xce\xa0\xd3\xb0\x6b\x84\x26\x66\x53\xe9\x28\xec\xc5\x4e\x2b\xf2\x7c\xb4\x64\x6d\x9b\x4c\x3e\x68\x65\x7e\x0c\x16\x8f\xe6\xf3\xf0\x9d\x44\x7d\x2d\xcb\x40\x56\x1a\x30\xb5\xcc\xd5\x8c\x0e\x42\x83\x9c\xe4\xab\x46\xb7\x83\x86\x1c\x1a\x22\xb9\x2a\xc8\x5d\x6d\x1b\x12\x31\x07\x4a\xe8\xb6\xe3\xbd\x2d\x3f\x8a\xcd\x4e\x62\x65\xa9\xae\x57\x8e\xb0\xc6\x63\xab\xd5\x0f\xb3\x76\x9c\x89\xf0\x1e\x76\x0d\x41\x51\x0d\x51\x61\x89\x9d\x6b\x52\xf8\xc2\xb9\x7b\x95\x09\xa3\x97\xbb\x50\x91\x20\xc2\xb2\x8a\x47\x2d\xf1\xac\x44\x39\xa3\x30\xaf\xa2\xd4\xa0\xef\xcd\x6c\x38\x0c\x1b\x33\xdf\x90\x8f\x9a\x8d\xf5\x9b\xcb\x14\x59\x86\x3c\xd8\x68\xa8\xdb\x20\x63\x9f\x35\x82\x30\x05\xae\xc7\x11\xae\xed\x42\x44\x59\x11\xf3\x1f\xaa\x5c\xde\x0d\xe6\xfc\x0a\xf1\x6a\x3d\x62\x48\x99\x74\x29\x6a\xc9\x6a\xd5\x09\x2d\x28\x13\x8a\x32\x06\x96\xf5\x68\xd1\x48\x30\x85\x8d\x0a\xc1\x40\x1e\x6c\x7c\x41\x48\x07\x7c\x4d\xa0\xa4\x73\xec\x3d\xfa\x4e\x3c\x67\x49\x6e\x16\x02\x17\x2b\x83\x74\x5e\xcd\x10\xe5\x64\x49\xf3\xc5\xb3\x9a\x79\xcc\x50\x08\x7e\x93\x91\x05\x43\x86\xf1\x7d\x36\x01\x95\x45\x9a\x11\xba\xba\xc2\xe7\x0e\xca\xe9\x4f\x66\x10\xa0\xa4\x4e\x94\xd1\x9a\x14\xaa\x24\x7a\x40\x94\xf2\x80\xdc\x58\xa2\xb9\xe6\x02\x06\xe1\x8e\x76\x06\x30\x2f\xa2\xe4\x28\x92\x09\x59\xd4\x12\x96\xd2\x65\x84\x5e\x52\xad\x9a\xea\x79\xd1\x46\x74\xff\xdc\x30\xbe\xc5\x03\x51\x39\x98\xca\xb5\x52\x25\xc0\x37\xe0\xac\xd2\x08\x71\x26\xfb\x5c\x9a\x07\xb3\x8d\x1f\x29\x6f\x5b\x3c\x52\xf2\x3b\x22\x97\x41\xba\x8a\x5a\x62\xfe\x82\x69\x7c\x94\xf8\xf7\x09\x9b\xb4\x47\x22\x70\x12\x44\xc5\x9c\x8f\x24\x6b\x61\xfb\xaa\xe0\xfc\x95\x75\x9d\x53\x2d\xdd\x6e\x59\x7c\x44\x30\x35\x9c\x40\x00\x28\x32\x5a\x35\x16\xc1\x73\x86\x1b\xf3\x0d\xd6\x51\xfe\x04\xf7\x78\x54\x66\xd2\x9d\x2a\x68\x57\xd7\xde\xd2\x5e\xed\x61\x97\x5a\x26\x61\xcb\xae\x9f\x93\xab\x2a\xc6\x83\x08\xda\x76\xbf\x57\x58\x17\xe6\x00\xb8\xda\x79\x91\xad\x44\x3c\x2c\xaa\x59\xc4\xf2\x0c\x97\x2a\x85\x37\x3f\x36\x1a\xe9\x09\x58\xf2\xde\xcb\xad\xed\xbb\xa7\x27\x10\xa1\x79\x50\x0a\x68\x81\xd1\xd5\x17\x41\x53\x7b\x17\xc9\xb0\x16\x5d\x91\x72\xb7\xa1\x2d\x51\xfd\x56\xf4\x05\x39\x74\x2b\xe8\x6b\xfb\x2d\xa2\x05\xf3\x40\x89\x8c\x36\x42\xeb\x36\x23\xb6\xd2\xe3\xaf\xd0\x24\x55\xf8\x30\x00\x36\xe8\xc4\xc0\x7e\xe4\x5e\x0c\x3c\x53\x0b\x86\xf4\x05\xf5\x1b\x0d\xeb\xfb\x15\x7f\xcf\x86\x79\x23\x8f\xf4\x0b\xe1\x7d\xc3\xcf\x35\xbc\x2f\x43\xa6\xc5\xc3\x11\xb7\xef\x3c\xde\x2f\x87\xdc\x34\xee\xaf\xc0\x50\x8e\xff\x6b\x0b\xfa\x2b\xbe\x63\xf0\x5f\x53\x00\xdd\xe2\x15\x05\x8f\x1a\x9b\x4f\x99\x39\x1a\xec\x46\xad\xf0\x34\x1b\x16\x17\x18\x5b\x18\xd9\x38\xac\x67\xa2\xc5\xca\x72\x23\x2d\xf1\xb8\x9a\x99\x16\xab\xfe\xe7\xd9\x69\xd5\x45\xe0\xae\x38\xe5\x00\xdb\x33\x9b\x6a\x51\x5c\xfe\x02\xb6\xc4\x5f\x15\x3d\x19\x2f\x85\x70\x48\x7e\x37\x8f\xbd\x60\x70\x11\xaf\x6f\x95\x49\xc7\x7c\x55\x83\x65\xb8\x27\x08\xec\x36\xcb\xa4\x94\x67\x30\x5a\xa6\xff\xd9\x4c\x97\xe9\x7f\x56\x03\x66\x0e\xd8\xcf\x01\x93\xfa\x8f\x1d\xaf\x6d\xb0\x89\xe6\x5f\xea\x58\x46\x73\xc0\x81\x06\xd8\xb7\x02\xf6\x8d\x80\xcd\x90\x09\x2d\x92\x25\x80\x3e\x9c\x74\x58\xbe\xfb\x0e\xfd\x26\xfe\xa0\xcf\x3e\x3c\xf7\xe0\x11\x51\x30\x41\x11\x53\xf1\x0b\x9d\x8a\xd6\x2f\x64\x2e\x48\xeb\x84\xaf\x23\x36\xbf\x80\xd7\xe1\x5a\x37\x92\x66\xa8\xbd\xe1\xfc\x52\x12\xee\x42\x9a\x7b\x6a\x0b\x4e\xc8\x0f\x6d\x66\xb7\x96\xad\x16\x47\x18\x3b\x1d\x83\xa3\x67\x40\x3a\xfe\x4b\x9b\xf5\x34\x18\xb5\xeb\x78\x77\x9a\x53\x0a\xb0\xdc\xde\x86\x64\x00\xf4\x03\xa0\x04\x4e\xc2\x39\x3a\x71\xc1\x99\x04\x63\xeb\xe5\xc5\xcd\xc6\xc1\xe7\xe3\x09\x39\x9f\xcb\xfd\x64\xe1\x02\xfe\x5e\x12\xae\xa2\x30\x2c\x72\x33\xcd\xfb\xcc\x38\xab\x0c\xf5\x16\x76\xf2\x0a\x4f\x5e\xdd\xce\x52\xb0\xda\x46\x8c\xfe\xb3\x36\xb5\x64\x3b\x04\xb5\xeb\x91\xb7\x92\xfa\xe6\x96\xa2\x56\xc3\xcd\x41\xd4\x13\x86\xf2\xe2\x8d\x30\x94\xaf\xe6\xfb\x45\xaf\xfa\x8c\xcc\xfa\x22\x7b\x29\x15\xcc\x5f\x99\xe2\x9a\x7d\x93\x7f\xb6\x52\x77\x29\x50\xd3\x16\x60\xe3\x3f\xc0\x7e\x60\x85\xb2\x0e\x96\x72\x1a\xb5\xe1\x53\xbe\xb0\x99\x6d\xfe\x2f\x6d\x88\xff\xf2\x4b\xbd\x58\x9d\x06\x96\xb2\xff\x6e\x0a\x09\x23\x3e\x15\x57\x91\x40\xee\x4f\x53\xf4\x73\xce\xa7\x7b\x0c\x6f\x0e\x47\x55\x3c\x43\x6a\x87\xc2\xf8\x86\x08\xeb\x4f\xbc\x0a\x9e\x24\x53\x98\x5c\x7b\x25\x46\x6c\x14\x09\xe6\x22\xed\xe5\x22\x3b\x98\xcd\x2e\xf3\x25\xd1\xd1\x71\xe8\x48\x33\xaa\x88\x1c\xda\x8d\x67\x72\xb9\xee\x3c\xda\x7a\xb6\xbd\xb3\xfb\xfc\xfb\xbd\xfd\xff\x7e\x71\xf0\xf2\xd5\xe1\xeb\xff\x7d\x74\x7c\xf2\xe6\x87\x1f\x7f\xfa\xd7\xff\x49\x06\x43\x72\xfa\x78\x77\x3e\xfe\xe5\xd7\xc9\xc5\x74\x76\xf9\x3f\xf3\xc5\xf2\xea\xfd\x87\xeb\x9b\xdf\x5c\xcf\x0f\xc2\x28\xee\xf6\xfa\x8f\x9f\x6e\x3e\xd2\x8c\x95\xc5\xa2\x6d\x8c\x6a\x3e\xc4\x16\xaf\x94\xdc\x72\x43\xb1\x30\x35\x89\x42\x5a\x3b\x26\x37\x15\x98\xe9\xb1\x65\xbf\x61\x8e\x5d\x0b\x10\x92\xa4\xe5\x91\x53\x93\xec\xc0\x42\x76\x5f\x8f\x2c\x14\xf0\x5e\xc9\x05\x26\xbf\x48\x5c\x1c\xa8\x5f\x07\x68\xfb\x8c\x6f\xf0\xb2\x18\x66\x80\x4a\x05\xa2\xa9\x12\xb9\x07\x9c\x6d\x28\xfa\x7f\xd0\x16\x65\xdf\x9a\x69\x79\xf0\x1e\x87\x0d\xf1\xe3\xc7\xca\x07\x41\xb6\xe2\x07\xa3\xc8\x42\x6c\x49\x63\x58\x84\x8f\x79\xee\x1e\xfd\x90\x2f\xed\x11\x1b\xd6\xcc\x3e\xd1\xc3\xd1\xff\xe1\xe8\xaf\x38\x67\x79\xb1\xf3\x6c\xb7\xb6\x83\x96\x17\x93\xc2\x92\x8f\x96\x17\xab\x4f\xf8\x75\x75\xa7\x2d\x8a\xcc\x9f\xeb\xb8\x55\x13\x87\x3b\x74\xde\xf2\x62\xab\xf7\x96\x17\xff\x47\x79\x17\xe3\x60\xdc\xe6\xac\xee\x5b\x05\x1a\x70\x19\x7a\x4d\x8e\x25\x4b\x9b\x93\xb1\x17\x5b\x9c\x8c\x8d\x87\xe9\x1c\x53\xbb\x97\xb1\x68\xb2\xae\xab\xb1\x04\xf4\x16\x27\x28\x9d\x88\x6f\xe5\xac\x86\xb4\xd9\x74\x6d\x7c\xee\x1e\x6b\x64\x55\x09\x97\x35\xbe\xf8\x1a\xf9\xac\x61\xa5\x66\xbe\xc6\xbc\x96\x90\x6f\xf9\x8b\xfb\xf6\x34\x56\x1b\xae\xe7\x68\xec\xa1\xec\x23\x30\x54\xdd\x8c\x41\x04\xca\x17\x8b\x0f\x8b\x45\x0b\xc2\x66\xa7\x70\x9b\x94\xa3\x8d\xce\xb7\xf5\x43\x61\x30\xb2\x3c\xa8\xb1\x87\x49\xfb\xd4\xc1\xad\xf7\xa9\x83\xcf\x60\x9f\x3a\xf8\x13\xf6\x29\xe3\x72\x3a\xd8\x7d\xd8\xa6\xee\x7e\x9b\x5a\x7c\x48\x2e\x77\xa7\xe9\x38\x99\xb6\x9a\xee\x58\xed\x2f\x72\xcb\x3a\xb8\x9f\x2d\xeb\xe0\x2f\xb1\x65\x91\x35\xa6\x6e\x5a\x0f\x3b\x56\x61\xc7\x92\x56\x4c\xa3\xcd\xeb\x93\xee\x5e\x62\x5e\x24\x6c\x01\x90\xd2\x47\x1e\x0d\x1f\xbf\xb0\xbb\x13\xba\xb8\x5d\x17\xfe\xe0\xc5\x0a\xfd\x08\xdd\x67\x5f\xe9\xb7\x7c\xf9\x57\xa9\x0b\x90\xb0\xec\xda\x82\xf8\x56\xda\x02\x96\xa3\xf6\x53\x2a\x0d\x40\x0f\x26\x5e\x2d\xce\x13\x4f\x7b\x75\x7e\x91\x0c\xef\x51\xb5\xd0\x71\x78\xb3\xf8\x0b\x5b\xfb\x2b\xa8\x1b\x0a\xf9\x62\x57\x50\x45\x28\x46\x2c\xd2\x97\x97\x3b\x11\xd6\x44\x93\x1b\xf2\x60\x92\xf1\xd0\xc4\x19\x92\x87\x63\x16\x6c\x6a\x07\x2b\xfa\x8a\xce\xbf\x10\x8c\x9c\x25\xf1\x06\xbb\x45\xb4\xd1\xde\xfd\xe1\xf5\x5b\xdc\x74\x4f\x66\x2f\xb2\x5c\x18\x74\x3e\x7c\xf8\xb0\x36\xbb\xcc\xa6\x8b\xc5\x64\x6d\x36\x7f\xf7\x34\x9d\x0d\x17\x4f\x31\x09\xf7\xec\xa9\x56\xe7\x7c\x79\x31\x31\x28\x42\x76\xdf\x5f\xbe\xd8\x79\x9e\xa3\x2d\x9e\x6b\x06\x43\xa8\xf6\x01\xd1\xf6\xb8\x82\xf7\x0b\x4b\x79\x8e\x7b\x14\x0c\xcc\x02\x1e\xc6\x53\xee\xf6\x22\x85\x7b\xce\x5d\x5d\x42\xc2\xf9\xfc\x9e\xe2\xe9\x52\x80\x6f\x31\x52\x93\xc3\x62\xe8\x09\x52\xc8\x9c\x55\x61\x3b\x5e\x32\x5b\x64\x3d\x48\xb5\xf4\x81\xc0\xbd\xa4\x56\xa7\xb2\x77\x4e\x5d\xbf\x18\x6d\x77\x60\xc3\x43\x00\xf8\x3d\x34\x21\x55\xbe\xd2\xce\x21\xe6\xda\x97\x1c\x1f\xa5\xed\x8f\x2b\xbb\xdd\x58\x88\xf6\xbe\xfd\x70\x32\xa9\xd1\x3b\x30\xb3\xfe\x35\x1d\x15\xbc\x6f\x28\xcd\x57\xa4\x68\xaa\xae\xf8\x7b\x3e\x57\x3d\x2d\x9f\xdf\xaa\x60\x3a\xea\x34\xba\xae\xab\x03\x6e\xe8\x1d\x54\xe9\xf7\x53\x4f\xde\xdd\x81\x29\x41\x1a\x01\xaa\xc0\x44\xd8\x09\x66\x60\x85\x45\xbb\x8a\x95\x3e\xaf\x4b\x63\x01\x98\x00\x95\x54\x5e\x24\x93\xa5\xb3\x85\xff\x34\x17\x8b\x91\xba\x28\x79\xdf\x05\x79\x65\xb0\x79\x90\x49\x5e\xa3\x6e\x11\x59\x8b\x77\xa6\x83\xf8\x95\xe4\xad\xc1\xe2\x4a\x7e\x47\xb5\x66\x23\x81\x57\x9d\x62\x83\x78\x0b\x2b\x9d\x71\x0f\xb3\x11\x3c\x7a\xa9\x01\x79\x30\x13\xe5\x7c\x75\x34\x0a\x1c\x83\xa1\x05\xe8\x10\xbf\xc0\xb1\x31\xa5\x44\x6b\x14\xf4\x40\x00\x13\x3e\xc1\xe2\x8d\xf5\xb8\xcc\xf7\x18\xda\x23\xf6\x64\x28\x27\x31\x71\x5a\x34\x7f\x61\xc0\xf2\x7b\xb6\x31\x01\x78\xcd\xcc\x8b\x1a\xb3\x68\xb8\x72\x83\x96\xef\x38\x3e\xc6\xa3\x00\x88\x71\xe8\x39\x60\xbd\x60\x56\x5d\x96\x68\xd9\xea\x6b\x65\x25\x18\x43\xee\x04\xc2\xa0\x70\x62\x93\x8c\x82\x0b\xf4\xaa\x36\x2f\xfc\xe9\xcc\xb9\x38\xc5\xc0\x55\x24\xa9\xf1\xe8\x41\x49\x1a\xe8\xd2\xb4\x3f\x1a\xf6\x02\x59\xeb\x28\x94\x7b\x00\x96\xb8\xaa\x4b\x64\xc5\x62\xc6\x18\xdb\xd0\x46\x59\xaa\x25\xe9\x68\x38\xfd\x19\xa2\x5d\x88\x00\x73\xbc\x5e\x5d\x9b\xeb\x5a\x3c\x58\xf6\x3b\x5e\x89\xf7\x36\xe4\xbb\x77\xe8\x7d\x6b\xf0\x2b\x93\x7a\x53\x9f\x9b\x4b\x95\x14\xed\x86\xf4\x5e\xe5\xee\xf9\x07\x95\xab\x8b\x4d\x9b\xee\xd7\x36\xce\xde\xac\x5a\xc7\xb1\x88\x0d\xb7\x01\x53\x2a\x36\x08\x15\x72\x2e\xeb\xdb\xf6\x9c\xa2\x0b\x0b\x1b\x76\x55\x62\x41\xc7\x95\xf2\xfd\xee\xe3\x46\xc9\xf1\x9d\x42\x33\x9f\xdd\x3b\xfc\xf0\xb9\x1e\xf5\x3a\xfc\x48\xba\xde\xed\xc2\x99\xbe\xfb\x45\x9f\xe9\x87\xe3\x4b\xc2\x94\x9e\xdc\xb3\x89\x00\x9e\xde\xe5\xa6\xfe\x9c\x43\x7c\x31\x73\xe7\x9d\x9c\xe6\xb7\xb1\x63\xaf\x81\xe3\x2c\xc4\xa1\x5d\x7e\x69\x37\x21\xb0\x65\xc2\x50\x6a\xc0\x19\x2e\x59\x62\x25\xfa\x93\x33\x62\x56\xf1\x39\xbe\x5c\xb2\xa8\x0a\xb4\x48\x83\x74\x1a\x70\xba\xa1\x73\xb3\x24\x42\x38\x9c\x22\x13\xf6\xec\x5c\xd2\x3e\x31\xdf\x2c\x9e\x6a\x23\x49\xb3\xe1\xf8\x22\x99\x90\x63\x19\x8d\x1c\x99\xd6\xbe\xb9\x91\x47\xe5\x23\x6b\x85\x0d\xdc\x89\x40\x43\x6d\xb6\x79\x32\x8e\x55\xf0\xbb\xd7\xf4\x1c\xf9\x94\x48\xb7\x3a\x72\xe7\x9b\x5d\xec\x28\x35\x2d\x8e\x5a\x72\x99\xb5\x7c\x76\xf3\x04\x12\x90\x0a\x74\xb5\x4c\x10\x86\xe1\x95\xc8\x47\xbe\x6f\x68\x38\x9d\xda\xcd\xc3\x78\x4a\x58\xf9\x6d\xe6\x94\x93\x87\x4a\x74\x2b\xd0\xd9\x5d\x11\xc7\x50\x63\x14\x06\xfa\x58\x39\xa9\x04\x8e\x96\x39\x84\x4d\x3e\x39\x9b\x4e\xde\x06\xad\xb0\x61\xa4\x9e\x6d\x85\x7a\xb8\x46\x20\x07\xb4\x2e\x03\xfd\x68\xd6\xcd\xdb\x77\xda\xbc\xbb\xda\x6e\x2b\x6d\x10\xeb\x91\xdf\xd1\x94\xe7\xbd\x07\x53\xbb\xbf\xba\xee\xdb\xb6\x3b\x16\x22\x99\x97\x69\xc2\x0d\xf9\xa8\xc0\x01\x58\x68\x5c\x8d\x89\xa8\xa0\xc4\xa6\xec\xa8\x7a\x37\x09\xe9\xd1\xe5\x75\x55\x4f\xe6\xa2\x92\xb8\xa6\x2a\x0a\x64\x75\x74\x5e\xce\x20\xd8\xc0\x1d\x29\x95\x40\xfe\xdd\x33\x07\x0e\xc2\x5e\x32\x36\x61\xf2\x44\xa6\x8e\xbe\x75\x35\x86\xb2\x73\xb0\x25\x40\xb0\x51\x67\x24\xf4\x45\xd4\x47\x41\x3c\xea\x1e\xee\x25\xde\x6e\x77\x19\x5f\x16\x0f\x4c\x15\xe1\x65\xe9\xa1\x4a\x8a\x2e\xab\x8f\x93\xd9\x10\xbf\x44\x31\x45\x3b\xfa\x4c\x8a\x8b\xc9\xba\x5e\x16\x19\x53\xab\xc4\xf5\x05\x3a\x2c\x73\x94\xcc\xad\xc9\x64\xf6\xc1\x49\xe6\x83\xf1\x72\x9e\xcc\x6f\x1c\xa6\x5e\x22\x3d\x31\xc4\x1d\xfc\x55\xd6\x48\xfc\xd3\xd8\x70\xc9\x40\xe9\xea\x96\x7a\xa3\x55\xe1\x0c\x09\x28\x95\xb8\x41\x62\xfc\x37\xd4\x6d\xcc\xe6\x84\x54\xa7\x10\x88\x76\x9a\x3a\xb3\xab\x25\x0a\x10\xa9\x61\x0e\x66\x54\xc7\x48\xc9\x90\x3d\xd0\x56\x0a\x01\xe9\xb8\xc6\x4f\xae\x31\x36\xd4\x68\x42\x02\x33\x49\x2b\x39\x2b\xd3\x47\x8e\xa5\x82\x63\xa9\x60\xa1\xb1\x9f\x0e\x8f\x70\x3e\xe9\x35\x20\xe9\x34\x28\x4f\x88\x50\x34\xd5\x9b\x37\x26\x91\x52\xe7\xd8\xae\x58\x13\x78\x9f\x8e\xcf\x9c\x9f\x21\xfb\x64\x34\xa4\xff\x33\xb9\xc3\x14\x0a\x07\x31\xfd\x5f\xb9\x66\x6c\xa6\xe9\xc4\xc6\xda\xb3\x89\x22\xff\x84\x38\x64\xb8\x03\xdd\x47\x14\x32\xc1\xc4\xef\x24\x12\x59\x49\xbe\x32\x13\x33\xde\xf8\xda\x1c\xcb\xcc\xc4\xc7\x2d\x7a\x52\x55\x5f\x9c\x2f\x98\xd5\x22\x90\xe1\x30\x7f\x36\xf1\xc7\x08\x32\x2c\xfa\x18\xe2\x35\xc6\x25\x36\xbc\xc3\x29\xaf\x13\x88\xac\x50\xfa\xee\x83\x91\xc9\x24\xc1\x5b\xa9\x0c\x3e\x76\x5f\xd1\xc3\x70\xa8\xff\xd3\xa3\x87\x55\x88\xa9\x4d\x44\x44\xe0\xa1\x39\x0d\x55\x46\x10\xb3\xd7\xa8\x8c\x22\x66\xaf\x7a\x4f\x91\xc4\x6e\xcf\xed\xb6\xa9\x7a\x1a\xc7\xdb\xb0\x1f\x83\x74\xb1\x67\x0e\x8e\x56\x1a\x70\xac\x94\x63\xca\x63\xa5\x01\xcd\x25\x14\x2e\x69\xf0\x4b\x26\x81\x4a\xdb\x1a\x72\x8c\x80\x34\x5f\x12\x89\x83\xbf\xc5\x08\xae\xff\x45\x2b\xcc\xaf\xe3\xf0\x89\xe1\x35\x39\xcc\x3e\x01\x54\x52\xb4\x6d\x5d\x68\x5f\xc9\xb1\xf5\x09\xda\x34\x1a\xde\x53\x37\x4b\xed\xc3\x45\x1a\x55\x1b\xdf\x91\x57\xe4\x70\x65\x78\xe9\x87\xc5\x97\x91\xe7\x17\x5f\x06\x3d\x43\xc9\x40\x7b\x35\x1f\x5f\x66\x17\xa9\x17\xbb\x46\xdb\x3f\xe5\xd5\xe5\xe0\xd7\x74\xa4\xb7\x93\xbd\xbf\x24\x6f\xcb\xee\x1d\xd4\xae\xcf\xd2\xec\xc9\x70\x34\x30\xbe\x5e\xce\x2d\xaf\x9f\xbc\x9b\x24\xe9\x45\x32\x35\x7d\x9e\x99\x81\x65\x43\xfd\x35\x91\x6c\x9f\x24\xd3\xc5\xf8\xba\xef\x07\x86\x4f\xe3\xc5\xcc\x73\x3d\x3f\x36\x7f\xea\x77\xfb\x5d\xcf\xf0\xe9\xb7\x6c\x3e\x63\xae\xd7\x86\xaf\x53\xcb\x37\xaa\x23\x7b\x72\x9e\x5d\x6b\x1f\x92\x4c\x27\x2e\x1a\x77\x23\x2d\xbc\x9f\x0f\xf5\xc9\x9d\x27\x03\x72\xfe\x31\xbe\x7c\x32\xc9\xde\x25\xc3\x9b\xfb\xbe\x03\x12\xab\x07\x9f\xf4\x45\x83\x2f\xf3\xb5\x22\x1e\xd9\x12\xc1\x67\x58\x19\x9a\x59\x28\x5b\x07\xe2\xb7\x1f\x8a\xdf\x40\xf5\xfc\x37\x10\xbb\xf8\x4d\x7f\xe5\xa4\x9d\xdb\x97\xe2\x2f\x46\xc8\x14\x03\x4a\xbf\x85\x3b\x2c\x8a\x0e\xa7\x56\xe9\x89\x10\xa9\xf2\x24\x68\x33\x7f\x3b\x53\x6a\x00\x25\xd2\x66\x65\x02\x14\x6f\x04\xdd\xc9\x6f\x28\xb9\x89\x37\x32\x95\x89\x97\x53\xf5\x95\x44\x53\xf8\x0c\xa4\x84\x3f\x72\x0a\xa2\xa3\x32\x64\x03\xc5\xe8\x45\xfa\xcd\xc9\xa4\xa9\x22\x52\x51\x40\xca\xbc\xb6\xb9\x62\xd2\x1e\x8a\x8d\x75\x69\x3d\xf2\x3a\xe5\xda\xe4\x8e\x4a\x57\xeb\x51\xd8\x51\x08\x6f\x3d\x8a\x3a\xf9\xc4\xaf\x47\x71\x47\x1d\xbd\xf5\xa8\xab\xdf\x08\xeb\xa4\xbc\x1e\xbb\x1d\x46\xad\xeb\x31\xe2\x23\x28\x65\x3d\xf6\x3b\x32\xad\xac\xc7\x61\xc7\x44\x2d\xeb\x71\xd0\x91\x29\x64\x3d\x8e\x3a\x32\xfd\xac\xc7\x88\x97\x42\x33\xeb\x71\xb7\xa3\x53\xcd\x7a\xdc\xeb\xe8\x74\xb3\x1e\xf7\x3b\x05\x22\x59\xef\xba\x1d\x03\x39\xad\x77\x11\x7f\xb6\x24\xd6\xbb\x88\x3d\x23\x8d\xf5\x6e\xd8\x29\x10\xc7\x7a\x17\x11\x07\x32\x5a\xef\x22\xce\xf9\x3a\x5b\xef\xc6\xf2\x05\x7a\x27\x5f\xb2\xeb\x5d\x7e\xb5\x0e\x8b\x79\xbd\xdb\xef\xf0\xa5\xba\xde\x73\x3b\xf9\x12\x5e\xef\x79\x9d\x7c\x71\xaf\xf7\x10\x9d\x9c\x82\xd7\x7b\xd8\xb8\x60\x34\xeb\xbd\x90\x08\x1d\xb1\xfb\x70\x79\xf0\xe7\x5f\x1e\x6c\x9f\x67\xc3\x5f\xa1\x53\xb8\x52\xa8\x1b\x10\x4d\x73\xb6\xb8\xba\x84\x81\xc9\x58\x7c\x6a\xa9\xdf\x28\xc7\xd3\x90\xe6\xce\x37\x9b\xce\x23\x0e\xf9\x91\xc1\x22\x44\x38\x69\x7c\xaa\xc8\x00\xa2\x9d\xa3\x0c\x42\xae\xe3\x41\x6f\x3e\x7e\x87\x67\x32\x50\xdb\xe4\x60\x48\x07\xb3\x39\xaa\xae\x37\xb5\xf4\x1c\x12\x94\xad\xab\x77\x90\x3b\x4b\x2b\x00\x4a\x84\xf3\x64\x9a\x4e\x32\x65\xdc\x64\xd8\x03\x23\x64\xc5\xa6\x06\xab\x16\xdd\x01\x25\xdd\x37\x8d\x25\x4f\x4d\xa0\xc6\xd3\x65\x4f\xd2\xd0\x8f\xe4\xfa\x42\x31\xa1\xce\x4e\xf1\x98\x9f\xd7\xa0\x4a\xf8\x37\x00\x15\x5f\xc8\xd8\x28\x87\x08\x23\x62\x33\x9a\xfe\x0b\x21\xbd\x1f\x67\x1f\x6c\x28\x5a\x9b\x97\x10\xde\xe7\x28\x38\x7f\xfc\xa1\x96\xe7\x04\x87\x58\xa2\xce\x98\x57\x87\x48\x3b\x7f\x13\xb6\x23\xb8\xe8\xcc\xc0\x0b\x55\xdb\x85\x56\xac\x58\x79\xb1\x19\x2d\x7b\x4b\xcd\x6a\x90\x26\x02\xbf\x69\x13\xcd\x6a\x3c\x9f\xcc\x92\x55\xaa\xc4\x21\xbe\xcf\xcb\xaf\x48\x4a\x6b\x94\x82\x3b\x8e\xfc\xea\x66\x99\x1d\x62\x72\xa0\xc2\x6b\x53\xde\x65\x85\xfe\xf6\xe8\xa2\xcb\xdb\xaa\xb3\x22\xf2\xd2\xcd\x54\x08\x39\xb4\x67\x02\x37\x50\x28\x98\x70\x36\x68\x16\x76\xaf\x59\xf6\x55\x50\x58\xdf\xd2\x5d\xd0\x84\x4a\x93\x7c\xda\x79\x7d\x50\xce\xaf\x92\x3c\x3b\x37\xe7\x06\x6d\x1b\xaa\x6a\x73\xc7\x51\xb5\xa8\x60\xac\x79\x6a\x8b\x8e\xc3\xdc\x08\x4d\x1d\x51\xe6\xdb\x98\xf5\x0c\x46\x13\x5e\x03\x3c\x67\x0a\x52\x9f\xcc\xcc\x8b\xed\x26\x90\xe8\x84\x35\x9c\xcc\xdf\x5d\x01\x0b\x5f\x94\xf9\x2b\x32\x7e\xbd\x76\x39\x9f\x2d\x67\x80\xa3\xcc\xb9\xcb\x0c\x27\xf4\xdd\xc7\xac\x60\x89\xbd\x07\x59\xe7\xcf\x91\x75\x30\x60\xf4\x9f\x10\x97\xc8\x98\x53\xa9\x86\x89\x04\x6e\xb1\xf4\x1e\xcf\x59\xe6\xba\x75\xa8\x72\xc2\x98\x85\x54\x92\xaa\x2e\xb5\x9b\xbf\x96\xe5\x82\xc8\xc4\x57\xe2\xd0\xcc\x45\x4e\x80\x4d\x6c\xd2\xe1\x5b\x4b\x06\x0b\xfa\x63\x31\x9e\xb2\x60\xac\xc0\x32\xdc\x6b\x30\x20\xc6\xff\xc0\x53\xd7\x35\x2c\xaf\x56\xdb\x68\xa1\x0e\xfe\x90\xaa\x35\x85\xc9\x00\x44\xf7\x9a\x04\x53\x29\xaa\x2d\x2d\x1a\x80\xf0\xb4\x37\xa5\xb7\x63\xb9\x26\xd8\x9c\xab\xf8\xb4\xc8\x49\xdd\xeb\xb8\x1b\x46\x7e\xe0\x7a\x1d\xf2\x3b\x1b\x0d\xd3\x64\xd0\xeb\x77\x4c\x05\xfb\xbd\x41\x92\x0e\x47\x90\xd4\x90\x0c\x4c\xe0\x47\x61\x37\x56\xcb\x9d\x59\x6f\xc4\xb4\x34\x7a\x72\x2f\x5e\x8a\x4c\x7a\xa6\xbd\xeb\x43\x72\xe9\x64\xe8\x5e\x5d\xbd\x87\x78\xb1\x7d\xc7\xb0\x5f\x5f\x8b\x2b\x6c\x44\xe2\x2d\xc0\xe3\xe9\x05\x9d\xb1\x25\x02\xef\xcb\xb7\x52\xe9\x97\xa7\xfc\xe1\xcc\xe4\x12\x22\x7d\x06\x82\x33\xfb\x69\xb4\x5a\x2d\x09\x26\xf5\x14\x07\xe7\x70\xe9\x25\xee\x75\x61\x5b\xf3\x11\x77\xfe\xa8\x09\x90\x54\x35\x00\xc4\x50\xc6\x8a\x4b\x7a\x11\xdc\xed\x8c\x43\xf6\x94\x1b\x0a\xf3\x75\xbf\x32\xa4\xae\x23\x8d\x29\x99\x03\xf7\x6c\xa3\xa4\xb4\xa7\x95\xf6\x4a\x4b\xfb\x5a\x69\xbf\xb4\x74\xa0\x95\x0e\x4a\x4b\x87\x5a\xe9\xb0\xb4\x74\xa4\x95\x8e\x4a\x4b\xc7\x5a\xe9\xb8\xb4\x74\x57\x2b\xdd\x2d\x2d\xdd\xd3\x4a\xf7\x4a\x4b\xf7\xb5\xd2\xfd\xf2\xd9\x71\xb5\xd9\xa9\x98\x4c\x4f\x2b\x5e\x3e\x9b\x9e\xaf\x15\x2f\x9f\x4e\x2f\xd0\x8a\x97\xcf\xa7\x17\x6a\xc5\xcb\x27\xd4\x8b\xb4\xe2\xd1\x99\x41\x64\x26\x0c\xf9\x57\x30\xf6\x22\x55\x89\xa0\x39\x30\x89\xcd\x90\xc7\x71\xef\xd4\x38\x50\x03\xfc\x64\x1c\x94\x21\x7e\x32\x0e\x40\x8a\x9f\x82\x33\xeb\xdd\x20\xde\x11\xab\xdf\x00\x89\xe7\xcf\x5b\x49\xc7\x19\x74\x9c\x61\xc7\x49\x3b\xd2\x02\xed\x38\x4e\xb7\x03\x5b\xa8\x7b\xa6\xf3\x86\x94\xd6\x23\xc5\x45\xd5\x7c\x84\x48\x1d\xcf\xef\x90\x7a\x5e\xa1\xde\x90\xd6\xa3\x2d\xd1\xaa\xf9\xa2\x85\x7a\x5d\xa8\xe7\x17\xea\x0d\x68\x3d\x81\x64\x22\xd5\x0b\x48\x3d\x1f\xdb\x0b\x0a\xf5\xca\xfa\x17\x8a\xfe\x85\x8d\xfa\x17\x89\xfe\x45\x8d\xfa\x17\x8b\xfe\xc5\x8d\xfa\xd7\x15\xfd\xeb\x36\xea\x5f\x4f\xf4\xaf\xd7\xa8\x7f\x7d\xd1\xbf\x7e\xa3\xfe\x79\x84\x60\x68\xff\x3c\xb7\x51\x07\x3d\x42\x31\xb4\x83\x9e\xd7\xa8\x87\x80\x25\xed\xa1\xe7\x37\x23\x51\x42\x33\x8c\x44\x83\x66\x7d\x0c\x45\x1f\xc3\x66\x7d\x8c\x44\x1f\x91\x6a\x8a\x9d\xfc\xfe\x7b\x4b\x27\x49\x07\x23\xda\xc9\xd8\xd8\x49\x52\xd1\xd8\x49\xa0\xb7\x3e\xad\xd8\x35\x76\x92\x54\x34\x77\x92\x34\x09\x1d\x25\x15\x7b\xc6\x4e\x92\x8a\xc6\x4e\x02\xc7\xf0\x5d\xac\xd8\x37\x4e\xa4\xad\x8f\x91\xe8\xa3\xef\x36\xea\x23\xd0\x1c\xed\xa3\xef\x35\xeb\x63\xc4\xfb\xe8\xfb\x8d\xfa\x18\x8a\x3e\xfa\x41\xa3\x3e\xf6\xf3\x3e\x86\xcd\xfa\x18\x8a\x3e\x46\x8d\xfa\x08\x8c\x91\xf5\x31\x6e\xd4\xc7\x5e\xde\xc7\x6e\xa3\x3e\xc2\xba\x62\x7d\xec\x35\xea\xa3\x2f\x68\xd5\xef\x37\xea\x63\x57\xf4\x31\x70\x1b\xf5\x11\x96\x3f\xed\x63\xe0\x99\x17\xe4\xde\x9e\x9d\x58\x43\xc4\x35\x30\x73\x1d\x52\xd1\xd8\x49\x18\x56\x58\x5b\xa4\xa2\x99\xeb\x90\x8a\xf6\x05\x19\x63\x45\x33\xd7\x21\x15\xcd\x9d\x24\x78\xfa\x01\x56\x8c\x8c\x13\x69\xeb\xa3\x97\xf7\x31\x6e\xd4\xc7\x30\xef\x63\xb7\x51\x1f\x71\x22\x69\x1f\x7b\xcd\xfa\xe8\x8a\x3e\xf6\x9b\xf5\x91\x54\xa2\x7d\x0c\xdd\x46\x7d\x74\x45\x1f\x43\xaf\x51\x1f\x03\xd1\xc7\xd0\x6f\xd4\x47\x60\xe5\xb4\x8f\x61\xd0\xa8\x8f\x7d\x31\x8f\x61\xd8\xa8\x8f\xb0\x3c\x58\x1f\xa3\x66\xb4\x1a\x71\x5a\x0d\xe3\x46\x7d\xf4\xf3\x3e\x76\xcd\x0b\x72\x7f\xdf\x2e\xa8\xc6\xb4\x93\x66\xae\x43\x2a\x1a\x3b\x89\x34\x87\x3c\x20\x34\x73\x1d\x52\xd1\x2e\x06\x44\x28\x02\x9a\xb9\x0e\xa9\x68\xec\x24\xf0\x0e\x1f\x87\x35\x32\x8b\x3a\xb6\x3e\xc2\x7c\xd0\x3e\x46\x7e\xa3\x3e\x06\xa2\x8f\x51\xd0\xac\x8f\xae\xe8\x63\xd8\xa8\x8f\x5e\xde\xc7\xa8\x51\x1f\x7b\x62\x1e\xa3\xb8\x51\x1f\x91\xe6\x68\x1f\xbb\x8d\xfa\x88\x22\x39\xed\x63\xaf\x59\x1f\xc9\xb0\xb2\x3e\xf6\x1b\xf5\x31\x14\x7d\x8c\xdd\x66\x7d\xf4\x78\x1f\x63\xaf\x51\x1f\x7d\xd1\xc7\xd8\x6f\xd4\xc7\xbe\x98\xc7\x38\x38\x33\x47\xc5\x59\x66\xf3\x8b\x2c\x1d\x83\xe5\x30\x6a\x23\xd1\x5d\x41\x2d\x07\x47\x5c\xd0\x87\xe1\xbf\x8f\x9d\x44\xd7\xb0\xd2\x32\x1e\x2b\xe3\x41\x99\x81\xb9\x8c\xcf\xca\xf8\x50\x66\x68\x2e\x13\xb0\x32\x01\x94\x49\x0b\xda\x5c\x4d\x55\xf9\xdc\x60\xa9\xdb\x30\xa0\x2d\x66\x4a\x17\xd9\x74\xc9\xc3\x86\xb9\x8c\x08\xe5\x43\x7e\xdb\x95\x63\xd3\x67\xe3\xe5\xe2\x64\xb6\x4c\x26\x02\xe6\x74\x87\xd4\xa0\x1e\x24\xdf\x3a\xbd\x0d\x4b\x9d\x83\x6c\xb4\xe4\xd0\x85\xc7\x09\x96\xaf\x9f\xf2\x4a\xa0\x79\x9a\x83\x04\xdd\x61\x84\x17\x6f\xee\x75\xcf\xcd\xef\xdb\xf2\x12\x7f\x77\x02\xbf\x5d\xde\x17\x50\x0d\xa2\xda\x7d\x34\x99\xcd\xe6\x2d\xa9\x93\x4f\x15\xdd\xfb\x46\x19\x90\x03\x50\x75\x8b\xa7\x0d\x1b\xea\xad\x56\x2b\xc7\xed\xb1\x13\x87\x34\x5f\x5a\x1f\x83\x89\x92\x07\x54\xd8\x98\xf5\xb3\xbc\x2a\xc3\x39\x57\xce\xca\x6f\xeb\x6b\x67\x8b\xe0\x98\x6a\x56\x07\x57\xa5\x9b\x6d\xdf\xa6\xb3\x61\x9d\xce\x1e\x18\x3b\x7b\xb0\x6a\x67\x0f\x8c\x9d\x3d\xa8\xdb\xd9\x62\x6f\x65\x27\xaa\x96\xe8\x3e\x0f\x36\x85\x39\xf5\xcc\xfe\x83\x68\xf0\x4e\xdd\x18\xd0\x47\x71\xd1\x34\xaf\x7c\x85\x37\xa4\xa2\xf3\xde\x30\x2b\xc5\xed\xea\xf0\xa6\xf7\x1e\x61\xad\x6b\x0f\xf3\x4d\xc6\x1e\x5e\x61\xec\x9d\x9a\xef\x2e\xf6\xd8\x2d\x59\xab\xb5\xa7\x5c\x4b\xec\x35\xbe\x8f\xa0\xb4\xb0\xa7\xdc\x45\xec\x59\x2f\x21\xaa\x6f\x1c\x8e\x58\x6e\x60\x9c\x43\x16\x81\x27\xc5\x31\x6d\x9c\xac\x1c\xdd\x10\x4a\x59\x3d\x2a\x58\xd1\x29\x53\xdc\xd0\xe1\x63\x7e\xfd\x5f\xd8\x78\xf1\xf3\xdb\x02\x2d\xd8\xbc\x2b\x79\x04\x0d\xf8\x5a\x16\x06\x4b\x20\xa9\xa9\xbe\xae\x3b\x0e\xd8\x1e\x28\x98\x23\x9f\x24\xad\x27\x64\x25\xb4\x5a\x03\x32\xc2\xb8\x39\xb6\xfe\x0d\x3f\xd3\x36\xb0\x81\x6b\xf2\x67\xb9\x61\x08\x58\x3c\x85\x69\x5a\xd0\x95\x4a\xe3\x94\x07\x3e\x61\xef\x8b\x36\x56\x1b\x68\x46\x6f\x02\x2b\xed\xfc\x5f\x0f\x2b\xdc\x8e\x5b\x43\xf2\xeb\xdf\xf7\x83\x95\x76\x08\xaa\xc4\x6a\xe0\xfc\x4c\x24\xa7\x9f\x01\xb1\xbb\x47\x46\x13\x00\x2b\x91\x01\x44\x08\x46\x7f\xdc\xf1\xe0\xc8\xb7\xd5\xc7\xb6\x34\xe9\xd4\x77\xa7\x24\x51\x7a\x9d\x20\x6b\xdc\x4f\x4c\x73\x51\xc4\xd5\x50\x3b\xba\x99\xa5\xf4\xaa\x61\xcd\xd8\xba\x14\x46\x2e\x2f\x77\x22\x83\xef\x57\x79\xf9\xa2\xc3\x57\x1e\x5f\x4c\xb9\xcc\xe7\xa9\xcf\x69\xd6\x74\xf2\xc9\x68\x32\x70\xbc\x62\xae\xfa\xbb\x9a\x82\x95\x42\x3b\x54\x4f\x9c\xec\xe5\x77\x17\x13\x47\x9d\xca\xc4\x44\x40\x3e\x79\x98\x0c\x25\x33\x7c\x71\x3e\x58\xb1\xe2\x9c\xe4\xd9\xec\xe9\xbc\x94\x66\x60\x67\x91\xad\x2d\x16\x50\xfe\x43\x4c\xb6\xcf\x3a\x26\x1b\x5d\x6c\xdf\xb2\x38\x43\xce\xf3\x2c\x4b\x07\xc9\xf0\x57\x16\x57\x13\x3c\x1c\x80\x4e\x81\x66\xc4\x7c\xe3\xcb\xed\xe7\xcf\x40\x04\x32\x88\x07\x68\xe6\x84\x5f\x15\x6b\x39\xb4\x70\xa1\xad\xbc\x04\x00\xcc\x98\x47\xac\x7a\x52\x65\x6d\x77\x4a\x63\x95\xa3\x01\x15\x79\x2e\x1a\xfc\x5c\x5a\xcc\x65\x98\x99\x61\x89\xc9\x8c\x5d\x34\x65\x21\xa8\xb8\x40\x42\x1f\x4d\xf7\xcc\x52\x28\x0f\x5a\x48\x0e\xe5\xa1\x96\xe7\x31\xca\x5f\x64\x37\x84\x5f\x64\xc9\xc5\xd6\x34\x65\xbd\x33\x58\x47\xce\x98\x59\xac\x00\xd7\x61\x0d\xb4\x8d\x7e\xfa\x17\x19\x06\x19\x47\x63\x4c\x3a\x4f\x2c\x56\x26\xfa\xcf\x4f\x21\x12\x14\xbe\x36\x8b\xef\xd9\xfb\x67\x2c\x66\x2a\xb6\xbe\xb6\x98\x8c\x87\x59\x8b\xa3\x20\x6e\xea\x05\x2e\xed\x32\xc9\x0b\x66\x69\x27\xfb\xab\xcc\xda\x2d\x46\x17\x0d\x87\x49\x95\xc6\x63\xfb\xc9\xe8\xe6\x24\xef\xd0\x20\x23\x67\x03\xe6\x75\x0f\x04\x31\x9e\x5d\x2d\xea\x91\x8c\xe8\x62\x2d\x71\xbc\xa4\x37\xad\xca\x2e\x68\xbe\x11\xc5\x03\x1b\x9e\xf7\xde\xe7\xc1\x5a\xde\x6f\xa8\x46\xe3\x72\x38\x66\xda\xbc\x6a\x35\x3e\x7e\x6f\x3c\xd2\x88\xd2\x10\x93\xe4\x3d\x9b\x42\xd7\xb2\x12\x67\xef\x33\x67\xff\x07\x3c\x7f\x2e\xae\x06\x0b\xb2\x73\x80\xd7\x88\xfd\xf4\x8c\xf8\x0a\x07\x86\x4a\x03\x68\x1d\x1f\x6d\x42\x8a\x93\x80\xa7\x2c\x4a\xe5\x19\x1d\x68\x2c\xd8\x12\x40\x3a\x8e\xda\x15\x30\xb1\xa6\x05\xcb\x2d\x46\x07\xe5\x51\x63\xa8\xe9\x79\x6e\x21\x88\x91\x60\x44\xa3\x78\x8e\x2e\xd0\x0b\xc3\x82\x8b\x13\xcf\x9f\x95\x19\x5c\xf3\x4d\xa7\x49\x9c\xba\x38\x78\x10\x3e\x3e\x77\xe1\x03\x72\x6a\x64\x04\xd2\xfb\x8c\x8a\x21\xb3\x2b\x10\xe5\x25\xf1\x03\xd5\x18\x44\x2c\x1e\x4c\x18\x07\x76\x76\xe6\xce\xb3\xf9\x38\x21\xe7\x6a\xea\x9e\xe9\x8c\xc6\x93\x0c\xc2\xd1\x0d\x11\x04\x0f\xf9\x8c\x11\xb0\x35\xfa\x39\x39\xc2\x22\xff\x4d\x2a\xed\xcd\xaf\x06\x37\xce\x2f\xe7\xf0\xcf\xda\x87\x6c\xf0\xbf\xde\x5d\x24\xe3\x09\x84\x2d\x36\xcb\x3b\x27\x47\xbc\xb9\x12\xb1\x47\x2e\x54\x5b\xfa\xf9\x2a\xcf\xf7\x32\x1d\xc2\x41\x81\xa6\x4c\x22\x1f\x60\xd0\x91\xf4\x44\x3a\x24\x54\x12\x51\xa5\x50\x1b\x67\x9d\xfe\xfa\x9d\xac\x63\xc2\x8a\xe6\xa3\xc9\xec\x03\xd4\xc1\x8d\xcf\xe3\xe9\x40\xa1\x9e\x17\xb7\xff\x01\x65\x37\xc4\x67\x5f\xfe\xdc\xd3\xbf\x06\x6c\x0f\x63\x8d\x21\x9e\x88\x0a\x80\x15\xed\x12\x8a\xe0\xcd\x92\xf6\xa0\x08\xa2\x8c\x4d\xbb\x1b\xa2\x8a\x9f\x57\x11\x65\xbe\x42\x04\x68\x21\x5a\x2a\x50\x4b\xb1\x62\x5f\x21\x2a\xac\xdc\x47\xfc\x1b\x08\x52\x2e\xf1\xf8\xf1\x20\x90\xbe\xe3\x5f\xbc\x0c\x2d\x42\x4a\xf8\x1b\x5f\xdb\x0b\x90\xef\x1e\xfb\x0e\x7f\x63\xc7\x79\xa3\xf8\xf0\x78\x13\x7b\xfe\xdd\x77\x2c\x1f\xa4\xfc\xda\xa7\x2a\x40\xe5\x2d\x43\xa8\xd8\x92\xa8\xe6\x5e\xbb\x1e\xd3\xfa\xe5\x45\x19\xd7\x83\x42\xf0\xf2\xa3\x4e\x1d\x6c\x79\xb4\x86\xf4\x5f\x95\x46\xd8\x4b\x7a\x83\xc4\x49\x29\x7f\xd9\x66\x04\x23\x4d\x01\x99\x3e\xd8\x25\xf0\x26\x06\x52\xec\x88\x85\x44\x17\x4f\x61\xa5\x3d\x82\x28\xec\x57\xe0\xa3\x30\x9b\x4e\x6e\xe8\x72\xdc\xf9\xf1\xf0\x68\xc7\xf9\x85\x6c\x11\x3d\x84\xc9\x1b\xf4\x4c\x58\xd0\xbb\x38\xb5\xb3\xec\x1b\xef\x2f\x5f\x4b\xca\x59\x40\xac\xab\x35\xcb\xeb\x3f\x51\xe6\x6c\x2a\x72\x16\x83\xcd\xa8\x32\x4c\xb1\x55\xc6\x13\x45\xb3\x7c\xc0\x4a\x13\x50\xe8\x12\x4f\x89\xd4\x43\xf3\x8d\x18\x1b\x29\x97\x81\x56\x91\x83\x6a\xc8\x42\x5c\x3a\x00\xc2\x2e\xd2\x3c\x65\x45\x5f\xe9\xa2\x11\xfb\x2c\xe1\xaa\xaa\x9e\x9b\x08\x45\x36\xc1\x68\x45\xe1\xa8\xa9\x80\xa4\xc9\x73\xc5\x43\x57\x4e\xf7\xf2\xd9\x4b\x2c\x8d\x0d\x4d\xb2\x12\xc5\x25\x01\xcb\x2a\x62\x49\x85\x1b\x48\x5a\xe1\x83\xa4\xf5\x65\xa8\x79\x0a\xf2\xd5\x9a\x4d\xdc\x29\x97\x73\x9a\xaa\x77\x0c\x2c\x5d\xe7\xe5\x0f\x4c\xfc\xaf\xc7\xc4\x4b\x4f\xb3\xf7\xc0\xb2\xf7\x49\xe1\x0c\x23\x37\x30\xe0\x1a\x48\x26\x87\xe4\x93\xfb\xc4\xa1\xc6\x34\x96\x2f\x78\x5b\xfe\x87\xe3\x7e\x51\x9b\x43\xdd\x5d\xa1\xfa\xbc\x0d\x65\x1a\xec\x02\xd1\xc3\x2e\xf0\x45\xec\x02\xbb\x13\x32\x88\xf3\xd9\x74\x3c\x74\x20\x7d\xf0\x60\x36\xab\x56\xf8\xef\x6e\x97\x29\xfc\xe9\xd7\x46\x3b\x02\xa9\xa2\xec\x00\xf0\x7c\x57\x3b\x80\xcc\xda\x55\x06\xa2\xd6\x2b\xd3\x62\x02\x3e\xca\x42\xba\x2f\xfc\xd2\x6c\x25\xfc\x78\xea\xa5\xed\x6a\xbd\x19\x96\x69\xb0\x8e\xe3\x87\x75\xfc\x45\xac\xe3\xc3\xab\x25\x24\x66\xa9\x7d\x69\x77\x58\x7a\x69\x77\xd8\xfc\xd2\x4e\x97\xea\x0e\xb5\x4b\xbc\xc3\x3f\xf7\x3a\xe8\xde\xa5\xba\xa2\x6e\x5e\xbc\xb9\x5b\xc9\xae\xa4\xa1\xcf\x45\xba\xfb\x2b\x9d\xb0\x0f\xb5\x6b\x4d\x9b\x10\x75\x58\xe3\xd2\xe2\xb0\xe1\xa5\xc5\x43\x16\xbb\x2f\x83\xf9\x6e\xbd\x3a\xde\x77\x7e\x5a\xeb\xfb\x01\x37\x10\x87\x9c\x25\x64\x79\xbf\xbb\x29\x70\x5f\x52\x60\x6d\x6b\xba\x18\xff\x04\xa5\x45\x2e\x38\xf2\x56\x66\x7f\xa0\x85\x95\x2e\x42\x6d\x17\xa0\x0b\xf5\x06\x14\x6a\x1d\xe7\x06\xbf\x8a\x01\xf0\x86\x5a\x74\xa0\xa7\x15\x19\xd8\x12\x8a\x20\x51\x90\x03\x91\xe8\x99\x16\xcc\x0a\x6d\xf1\x5e\xd3\x6f\x05\x60\xf4\xc5\x13\x15\xb3\xbf\x6b\xdf\x0b\xad\xd1\x98\x36\x93\x64\x41\x23\x67\x39\x97\xb3\xc5\x58\xf5\xc0\x87\x46\xe1\x3b\xd4\x7f\x3d\xe3\x9d\x15\x2d\x3c\xd6\x30\x22\xe7\x37\xad\x11\xf2\x39\x7f\xc6\x81\x12\xd9\x46\xd4\xd7\x94\x95\xc8\x6d\xe5\x21\xb5\xd4\x46\xf2\x90\x5a\x72\x69\x53\x70\x2d\xd5\x32\xfb\xb1\x06\x88\xdb\x21\x72\x0b\xdc\xab\xa9\x81\x38\x74\x8a\xf8\x9e\x30\xba\x3c\xe1\xbc\x32\x55\x54\x81\x2f\x46\xb3\x7c\xe6\xa4\x3e\xb7\x54\x34\x9f\xc0\xf1\x97\xf5\x3d\xbf\x08\x2a\xee\x2b\x05\x0f\x09\x0d\x8c\xa5\xb7\x64\xb9\x19\xf9\x26\x5f\x2e\x10\x48\xb5\x11\xef\xec\x3d\xf0\xce\x2f\x82\x77\xee\x1f\x1f\x3a\x18\x12\xb7\x1e\xeb\xdc\x67\x01\x74\x6f\xcb\x3a\xff\x74\x76\x98\x2f\x89\x0a\x7e\x68\x60\x55\x34\x1d\x80\x39\x02\xdd\xda\x3c\x99\xa6\xb3\x8b\x56\x81\x03\xb6\xdb\x6b\x9a\xa4\x54\x0e\x87\xa5\x0e\x3b\x2d\x70\x39\x3f\x24\x64\xe2\xb5\xdb\x0f\x8c\x4a\x67\x54\x9c\x38\x1b\x31\xaa\xfe\x03\xa3\xfa\x52\x18\xd5\xd3\xfd\xdd\x6d\x07\x62\x75\x3f\xf1\x1c\x46\x1b\xce\xcb\x6c\x79\x3e\x4b\x1d\xdf\xc6\xad\x30\xb4\xf7\xaa\xdc\x0a\x5c\x0e\xd1\x7f\x50\x5d\x10\x35\xb8\x00\x5f\xbd\x50\x9b\xfe\xc7\x17\xad\xd2\xc0\xff\xc9\xe6\x33\xcc\x1d\x06\xf6\x7f\xf3\x6c\x21\xf1\x45\xa5\x23\x50\x8e\xf5\x18\x9e\x0b\x78\xaf\xc4\x0b\xd8\x42\xfc\x8d\xe1\xa0\xae\x46\x6b\xf3\x08\x9a\xc2\x33\x2f\x6c\x70\x43\xba\x20\x44\x42\x85\xc7\x27\x4f\xb0\x6f\xb6\x51\xe4\xeb\xfd\xc9\x93\x9a\x0b\x1c\xe7\xb3\xc9\x02\xef\xba\x0f\x0b\xfc\xcb\x38\xc5\x39\xd3\xd9\xec\xb2\x9e\x18\xf2\x8a\x93\xa3\x75\x65\x0b\x62\xb7\xaf\x89\xbc\x48\x19\xcd\x89\xa6\x1a\x11\x9d\xf7\x40\x74\x5f\x04\xd1\xfd\x1f\x89\xf9\x95\x93\x9c\xc4\x03\xff\x44\xe1\xb7\xf6\xc1\x59\x3e\xdf\x16\x04\xe0\x56\xab\x5c\x04\x86\x10\xed\xfa\xab\x95\xb6\x18\x73\x8f\xab\xe3\x0a\x90\xaa\x6f\x00\xbe\x66\x62\x5e\x88\x14\x80\x9a\x05\x51\xe6\xc3\xf9\x78\x92\x39\xad\x6f\x5a\xb9\xaf\x75\x1e\x83\x1b\x3d\x0e\x0b\x31\xb7\x85\x09\x67\x41\x91\x39\x16\x5b\x92\xa6\xa2\xd4\x1d\xbb\x31\x1e\x6f\x9d\xdd\x4b\xa2\xa0\x46\xbc\xe4\xcb\x76\xdc\x32\xe4\xe8\xa2\x49\xb2\xee\x97\xaf\xe4\x99\x90\xb0\xb5\xbf\x7a\x92\xf0\x7a\x31\xb1\x0b\x31\xaf\xc5\x97\xe3\xbd\x2d\x2f\x8f\xf5\x0c\x4f\x1b\x65\x89\xc0\x4d\x0e\xa2\xaf\x93\xc5\x02\x16\xf2\x13\x40\x2d\x75\x20\x61\xef\x0e\x18\x80\xd3\x9c\x90\xcf\xf9\xa0\xf8\xe5\x31\xa7\x5f\x3f\x7b\xb1\xf3\xdc\xcf\x5b\x13\xcf\x35\x13\x8f\x6f\xcf\xa6\xa3\xf1\xbb\x2b\x96\x89\x72\x86\x59\x21\x17\x65\xf9\x25\xe7\x90\x85\x72\x79\xe3\xfc\x4e\x8f\xc5\xe8\x4d\x8a\xcc\x17\xbc\x43\xc1\xb3\x74\x01\x0f\xe3\x29\x4b\x17\xb0\x9c\x09\x5f\x9a\x35\xd2\xbf\x51\x72\x35\x59\xae\x3b\xa1\xd3\xf2\xfc\x1e\x26\x52\x6e\xdb\xe0\x5b\x12\x9a\x83\x83\x13\x4d\x64\x9e\x83\x83\xf1\xaf\x42\x73\xbc\x64\xc9\x33\x17\x08\x2a\x3f\xd4\x4b\x1f\x08\x60\x52\x0b\x92\x34\x49\xc0\x3d\x4b\xfa\xc7\xe1\xe8\xdd\xba\x6d\x94\xd9\xc5\xd7\x31\xc6\x9c\x21\x3d\x7d\x1a\xf8\x1d\x53\xba\x45\x8a\xba\xf6\x29\x47\x48\x69\xfc\x63\xbb\x2c\x21\x69\x59\x02\x79\x98\x95\x34\x27\x2d\xbe\xde\xaa\xb3\x88\x1e\x22\x9f\xfb\x08\x5d\x95\x33\x86\xc2\xf8\x0d\x4d\x74\xc3\xfd\xcd\xe0\x1e\x0c\x4e\x31\x79\xa3\x77\x90\x18\xf4\xd7\x74\x54\x48\x1a\x4f\xa9\x9d\x9f\x1e\x2d\x49\x3e\xab\x2b\xfe\x9e\x4f\x56\x8f\xa6\x9f\xbc\x35\x98\x8e\x3a\x8d\xe4\x40\xab\x03\x2e\xc9\x5e\x4f\xc6\xdb\x6c\x78\x01\x13\xb1\x29\x7e\x72\xc2\x83\xe2\x75\x13\xd7\xb2\xeb\x0a\xea\x41\x57\x97\x05\xad\x92\x6f\xf6\x92\xc1\x46\x6a\xe1\x0f\x6b\x35\x2b\x2f\x92\xc9\x92\x1c\x70\xe0\x9f\xe6\x89\x68\xb9\x1b\x8d\xe4\xd7\x7e\x1b\xb2\xa3\x89\xd4\xd3\xd1\x1a\x8b\x4a\xd2\xe2\x9d\xe9\x20\x7e\xd6\x49\x65\xc5\xe5\x79\xd5\x6a\x36\xca\xed\xa2\x4e\xfd\x86\x39\xd9\x89\x25\x29\x2c\xf3\xb2\x47\xdf\x7d\x46\xab\x40\x3e\x94\x07\x75\xc4\xec\xb4\x37\xac\x29\x54\x8c\x09\xca\x51\x36\xa5\x83\x4d\xd3\xcd\x17\xf4\x39\x6d\x9b\x5b\xeb\x3e\x91\xb1\xae\x4d\x35\x4e\x21\xf6\x0c\xfe\xe7\x9d\x35\x0c\xd4\x93\xa3\x22\x84\x65\xf1\xc6\x9a\xbf\x3e\xc7\x86\x57\xca\xdf\x58\x2b\xf1\x2d\x6f\x13\x66\x65\x8d\x3d\x99\x8c\x30\xf2\xad\x85\x16\xcd\x5f\x54\x18\x59\x68\x3e\x8a\x54\x50\xd7\x3a\xc8\xe3\x22\x7d\xc7\xf1\x31\x1a\x17\x88\x4e\xb2\x3c\xc7\x3c\x59\x36\x52\x60\x9e\xc6\x37\xef\x75\xdb\x90\x23\x01\x2b\x8a\xd4\xc0\xab\x64\x63\x40\xf8\xca\x10\x1b\x47\xb7\x58\x5c\x64\x31\xca\x5f\xb1\x8e\x5b\x10\xd9\x17\x63\x6c\x06\x3d\x96\xa3\xd9\x31\xb0\x06\x2c\x14\x5b\x0e\x8f\xda\x72\x48\xd3\x67\x35\xe6\xa0\xfc\x5c\x69\x02\x47\x4f\x8c\xb4\xfc\xd1\x34\xd6\x75\xc6\xbb\x32\x14\x94\xa9\xb3\x7c\xf4\xe5\x77\xe6\x80\x55\x52\x13\x3f\x1d\x1e\xa9\xdd\x41\xd7\x29\x83\xc7\x75\x61\xdc\x7e\xa1\x36\x30\xbf\x70\x1b\x18\x69\x36\xc9\xdb\x92\xd1\xc3\x78\x85\xa2\xc6\xe9\x2f\x68\x0e\x53\xe8\x08\x79\x6f\x19\xbc\x8f\xc5\xd7\x1f\x0d\x1d\x94\x98\x03\xd3\x4d\x63\xa3\xa6\x29\xd1\x98\xc4\xa9\x7b\xf6\xf8\x71\xb9\x49\x91\x04\x5c\x3a\xfa\x72\xbe\x61\x08\x62\xc6\xf6\xb2\xbc\x5e\x99\x01\xa5\x7c\x8c\xb8\xd5\x86\x5e\x27\xd8\x4c\xe9\x46\xde\x70\x13\xbf\x2b\xd1\x72\xbc\x60\xdb\xab\x22\x5c\x56\x47\xaf\x31\x88\x06\x77\x10\xc4\x86\x8a\x08\x42\x32\xa4\x42\xa1\x4d\x4c\x68\x56\xad\xe3\x58\x64\xd3\xdb\x80\x29\x95\x4d\xf3\x20\x3b\xe2\x28\x69\x13\x60\x3a\x8e\x2e\xa8\xb2\x61\x57\xc5\x62\x28\x54\x21\x3c\x7d\x2c\xb3\x45\xa3\xd0\xcc\x81\x7a\xf4\x14\xba\x3c\x27\x2c\xe8\x81\xbe\x6c\x1f\xfa\x06\x69\xdd\xab\x93\xa3\xdf\xaf\xee\xc8\x9a\x5e\xdb\x96\xf5\xfa\xaf\xa0\x5d\x3a\x46\xe3\xcc\x6d\x6e\xbc\x4b\x95\x48\xf2\xcb\x5a\xb9\xd3\x8e\xb2\xab\x05\x24\x4d\x63\xe1\xc0\x24\x74\x8e\x1d\x39\xd5\x22\x85\xa2\xbf\xf9\xde\x51\x33\xac\x49\xdb\xc2\x11\x66\x53\x76\x98\xa1\x2d\xb3\x31\x2e\x6a\x92\x44\x79\x8c\xb1\x32\x06\xdd\x01\x4d\xc0\xec\xbc\xcf\xe6\x0b\x8c\x5a\x76\x9e\x2c\x9d\x69\xf6\x0e\x1c\x2f\x08\xe7\x24\x6c\x78\xc8\x52\xaa\x2e\x99\xc2\x87\xbc\x9a\x8c\x97\xcb\x49\xf6\x84\x06\xb8\x5c\x53\x81\x66\xf3\x39\xe1\xd9\xe9\x2c\x5b\x4c\x1f\x2d\x9d\x64\x34\x82\xb0\x67\x50\x97\x22\xf5\x68\x01\x71\xc8\xae\x08\x5a\x37\x1d\x51\x71\x70\xb5\x24\x3c\x0a\x2b\xf1\x1a\xe3\xe5\x42\x04\x54\x18\x93\xf6\x98\x13\x37\xcd\xeb\x3a\x06\xfe\x0c\xce\x53\x89\xa2\xbf\x92\x15\x65\x74\x40\x0e\x68\xe7\x84\xba\x4c\x7b\x2b\xcf\xdf\xaa\x49\xdb\xca\x0f\x29\x2f\x64\x33\x68\xeb\x01\x23\xb7\xde\xc6\x53\xc3\xfb\xb2\xd3\xc2\x98\x9d\xd0\x60\xf7\xca\xac\xe7\xb4\x9f\x44\xbb\xf0\xcb\x90\x38\xee\xc5\x29\x5c\xb9\xb7\x5e\x40\x48\x65\x1a\x2c\x00\x22\x0f\xbe\x38\x65\x57\x01\xe4\xb9\x63\xaa\xe4\xd1\x4a\xae\x5a\x29\x28\xaf\xe4\xd3\x4a\x9e\x5a\xc9\x2d\xaf\x14\xd0\x4a\xbe\x5a\xc9\x13\x95\xb4\xd4\x75\x75\x86\x8c\x7b\x19\xda\x06\x6d\x5b\x0c\xda\xb6\x79\xd0\x8a\xf8\x48\xc3\xc5\xfa\x44\x2f\x4c\x20\x0c\x2a\x4d\x3b\x48\x91\xa6\x41\x56\x21\x3c\xea\x68\x64\xea\x6f\x71\x22\x02\x15\xb2\x67\x84\xec\xd7\x82\xec\x5a\x07\x5e\x82\xa1\x41\x0e\x6a\x41\xf6\x6c\xb3\xd3\x91\x60\x68\x90\x5d\x0d\x72\xf5\x44\x6e\x27\xf3\xf9\x0d\x68\x8f\x4d\xbe\x49\x03\x1a\xff\xa2\xa8\xc9\x58\xd2\xc9\x07\xd6\xb3\xb8\x59\x2c\xb3\x0b\x22\x4c\x5e\x11\x69\x72\x7c\xa1\xcf\x7d\xc3\xa0\xbc\x10\x59\xeb\x18\x56\x9f\x3d\x7e\xac\x21\xe2\xed\xcb\x59\x3a\x1e\xdd\x50\x4e\x48\xe9\xb0\x06\x16\x3d\x3b\x16\xdb\xa7\xd4\x71\xe0\xa7\x53\x4c\x79\x89\xd1\x56\xba\x67\x35\x92\xe4\xfe\x40\x18\xf0\xf2\xea\xb2\xb6\x47\x47\xf5\x61\x9f\x80\x44\xd7\x8e\xb2\x13\xfe\xfe\x0f\x6f\x21\x99\xdc\xfe\x0f\xc5\xd4\x68\x52\x11\x8f\x16\xf1\xce\x2c\x87\x7d\xb1\xa4\x71\x2a\x17\x57\x83\xf7\x19\x88\x0a\xb6\xa3\xbf\x4b\x83\x1f\x63\xdb\x34\xfa\x31\xa1\x40\x7c\xb2\x45\x3f\x96\x8b\xb3\x30\xc7\xa2\x7c\x7e\x1d\x6a\x0e\x73\x2c\x9a\xf5\x45\xb3\x9e\xd2\xac\x57\xd5\xac\xa7\x36\xeb\x35\x6b\x16\xc3\xe8\x8c\x5d\xbe\x04\x01\x08\x41\x45\x59\x81\xb6\xaa\x01\x56\xf5\xf9\x62\xc6\xaa\xae\xba\x4c\x2d\x33\xc2\xc8\xba\x8c\xb5\x52\x6a\x75\xe9\xb9\xde\xdd\x30\x7d\xf4\xe8\x47\xcf\xf8\xd1\xa7\x1f\x7d\xe3\xc7\x80\x7e\x0c\x8c\x1f\xc3\xb2\x36\xa3\xb2\x36\xe3\xb2\x36\xbb\xa2\xcd\x12\x8d\x54\x2d\xce\xb3\x02\xf7\xa9\xc9\x81\x0c\x4a\x8a\x8f\x77\x9d\xdc\xd5\xaa\xbc\x96\xa4\x8f\x5a\x9c\x59\x2d\x62\xee\x9d\x7d\x7b\xc7\xc1\xcd\xbd\xcc\x90\x0b\xa9\xa5\x8f\x69\xa8\xa1\x9f\x90\x08\x9d\xd6\x4f\x30\xf7\x7c\x95\xe0\xb3\xd8\x7b\x37\xf4\x8a\x1e\xad\xe8\xb3\x8a\x5d\xad\x62\x64\xad\xe8\xd3\x8a\x21\xab\xe8\x69\x15\xbb\xd6\x8a\x01\xad\x18\x9f\x09\xd4\x94\x8a\x5e\x5e\xf1\xd6\xa1\xe5\x6d\x51\xea\x29\x22\x3c\x76\xfc\x31\x4b\xc9\xce\x82\xc7\xe3\xe3\x2a\xd1\xe3\x39\x1c\xc6\xe0\x04\x1c\x53\xfc\x78\x23\xbe\x46\x27\x3c\xf8\xef\xa5\xee\x4d\x77\x5c\xee\x45\x27\x53\xbf\xb0\xe3\xc9\x6f\x6e\xa5\x9b\xba\xf7\xf4\x4b\x1c\x92\x0f\xd6\xe8\x91\x82\x60\x5b\x35\x5d\xa1\x94\xf5\xf1\x95\x45\x04\xd5\x0c\x7e\x8e\x13\x30\x1e\x9d\xa4\x56\x56\xdb\x40\x7e\xd8\x7e\x4b\x27\x77\x5b\x8f\x77\xa8\x7a\x12\x24\x93\xe1\xd5\x04\x56\x18\xe4\xa2\xb6\x35\xbb\xcd\x12\xc1\x6c\xd3\x44\x30\xee\x75\x98\x06\xf8\x7f\x08\x74\x4e\x25\x34\x3d\x5f\xcb\x36\xcb\x0b\xb3\x4d\xf3\xc2\xb8\xd7\xac\x46\x80\x31\xe5\xb7\xb9\x80\x4a\x04\xc6\xef\x48\xa1\xb7\xd2\xf3\x7f\x39\x9e\xb3\x4e\x7e\x14\x21\xfa\x0c\xa2\x4f\x21\x32\x80\x21\x83\xe8\x69\x10\xbd\x1a\x10\x03\x06\x31\x28\x74\xab\x45\xdb\x51\x20\xfa\x35\x20\x86\x0c\x62\x68\xec\x75\xa0\x41\x0c\x6a\x40\x8c\x18\xc4\xc8\xd8\xeb\x50\x83\x18\xd6\x80\x18\x33\x88\xb1\xb1\xd7\x91\x06\x31\xaa\x01\xb1\xcb\x20\x76\x8d\xbd\x8e\x35\x88\x71\x25\xc4\x5c\xec\xa7\x40\x95\xea\x5d\xbd\xfa\x86\x8d\xa6\x61\xf7\x79\xf7\xe4\x16\x8b\x08\x4a\xbd\xbb\x46\x5e\x3d\x86\xae\x6d\x1b\x92\x70\xa0\x92\x70\x4a\x36\xa6\xab\xe1\xd2\x39\x1f\xbf\x3b\x77\x92\x69\xea\x40\x10\xc2\x64\xfe\xee\x0a\xc3\xbf\xa0\x9b\xf3\xff\x5c\x25\xf3\x42\xe2\x1e\x6c\x00\xb2\x10\x91\x56\xb8\x14\x67\x50\x1e\xbc\x1b\xd0\x22\x74\x97\x30\x1e\x9f\x78\x9f\x15\x0c\xe6\xd9\xe2\x6a\xb2\x04\x93\x98\x92\xe6\xcf\xe9\x16\xd0\x22\x78\x7c\x4b\x90\xa1\xae\x2b\x5e\x17\x52\x12\xd0\x57\x03\xf6\x2a\xc2\x57\x03\x7c\x65\x42\x72\x42\x01\x49\x5d\xa1\x47\x42\x52\xfe\x1a\x67\xb8\x8d\x04\xc1\x0b\x08\xb1\x53\x2a\x60\x4a\x04\x03\x1d\x82\x3b\x2b\x0c\x27\x29\x7f\xfc\x9e\x72\x38\xf2\xf9\x67\xd2\x78\x5d\x26\x67\x56\xaa\xfc\xc4\x58\xdc\xf7\x94\xc5\xb5\xc8\x8f\xae\x7c\xf0\xfd\x5e\x12\x0b\xda\xac\x40\xac\x16\x88\xf3\x02\x3a\x3d\xff\xc4\xb8\xe1\xf7\x94\x1b\xb6\x68\x33\xf9\x7e\xfb\x3d\xe7\x7f\xb8\xdf\x3e\x76\xa0\xb5\x22\x0c\x9f\xc1\xf0\x39\x0c\x4f\x45\xc0\x2b\x60\xe8\xaa\x05\xdc\x32\x0c\x03\x06\x3d\xe0\xd0\x7d\x15\x43\x5f\xc3\xd0\x33\x60\x18\x32\x18\x21\x87\x11\xa8\x08\x04\x05\x0c\x7d\xb5\x80\x5f\x86\x61\xc4\xa0\x47\x1c\x7a\xa8\x62\x18\x6a\x18\x06\x06\x0c\x63\x06\x23\xe6\x30\x22\x15\x81\xa8\x80\x61\xa8\x16\x08\xcb\x30\xec\x32\xe8\xdd\x33\x85\x44\x04\x86\xb1\x86\x61\xa4\x60\x58\x2b\xf1\xc7\x82\x27\x9d\x10\xba\xd6\x1a\x69\x27\xaa\xae\xbb\x28\xac\x25\x84\x13\x97\xee\x9d\x64\x4d\x2a\x0f\xa5\xa0\xa4\x71\xa0\xb7\x45\xc5\xfb\xab\xcb\x49\x02\xd8\x10\x68\x56\x70\x2c\xce\x4c\x2b\x6f\xd9\x04\x51\x5c\x5c\x95\x29\x75\xd5\xe4\x1d\x72\xc9\x76\xd9\x1d\x94\x5c\xb0\xb6\x31\x72\x47\xbd\x1b\x59\x8f\xc2\x4e\x7e\x29\xb2\x1e\xc5\x1d\x76\x57\xb2\x1e\x7b\x70\x5f\x15\x3e\xdc\x57\x3d\xdc\x57\x3d\xdc\x57\x99\xee\xab\xac\xf7\x39\xfa\x4d\xce\x97\x75\x87\x73\x57\x59\xe1\x5e\x88\xa3\xf9\x0b\xf5\x68\xfe\x62\xd5\xa3\xf9\x0b\xf5\x68\xfe\xa2\xec\x68\x5e\xa5\x60\x7e\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\x7a\xb8\xa9\xfa\xb4\x37\x55\x77\x76\x47\xb5\xda\xed\x54\x9d\x7b\xa9\x1a\x37\x52\xf7\x75\x17\x15\x3d\xdc\x45\x3d\xdc\x45\x7d\xd6\x77\x51\xf2\xdd\xd1\x76\x58\xe9\xe8\x24\xdf\x1c\x91\xe2\xf9\xb5\x11\x3e\xdc\xff\x9d\x11\xf5\xd2\x14\xb7\x46\xe6\xa0\x02\xdc\x43\xbb\xec\x5a\x09\xdd\x38\x65\x8f\x62\x29\x66\xba\x39\x26\xc4\x62\x30\xbb\x2e\xc2\x39\x16\xe8\x1c\xcb\xd7\x74\x65\xd2\xa4\x1f\xc5\xf6\x43\x39\x3b\x74\x8f\xab\xd5\xb8\xe0\x95\x6c\xd0\xe3\xaa\x2d\x76\xb8\xff\x78\x65\xc3\x6c\x50\x60\x08\x78\x54\x09\x08\x58\x26\x8d\xd3\x46\x69\x25\xd8\xda\xf8\xd8\x9f\x2a\x80\x8a\x91\xd0\x94\xcf\x85\xa0\x68\xa6\xb3\x3f\xf4\xa2\xf5\x0b\xd9\x15\x8f\xa9\xa8\xcd\x20\xb4\x49\x6d\xe8\xd5\xd7\x56\xed\x86\x19\x67\xdc\x37\x8a\x1a\x02\x69\x02\x8e\xcd\x8e\xf1\xf0\x1a\x66\xbc\x7a\x7a\xb6\x8d\x2a\x7e\x96\x55\x43\x10\xcd\x2f\x2c\xcb\xec\xdd\xa9\xe5\xb8\x26\x04\xb5\x20\x85\xfc\xeb\x30\x3d\x66\x95\xa1\xd2\xb2\x70\x72\x26\x83\x6b\x51\x88\xb8\xf5\x93\xbe\x63\x06\xd8\x9a\x8a\x11\x69\x3d\x6d\x14\xc3\xf0\xb1\xe1\xd3\xbe\xfc\x92\x0f\x67\x65\x06\x78\x54\x0e\xaa\xd5\x7f\x95\xf1\xd4\xe6\x43\xac\xa6\x29\x5d\x46\x53\xaa\x52\x9b\x1a\x16\xd1\x18\x35\xe8\x34\x61\x1c\xa3\xca\xaf\xac\x24\x6c\x21\x5c\x23\xd1\x96\x10\xac\x9d\x58\x73\x42\x2d\x84\xd3\x92\x3a\xfb\x07\xd4\x6d\xb1\x35\x05\x55\x18\x5e\x67\x79\x5e\x83\xa9\x9e\xc7\x40\x3b\x3e\xbd\xc1\x38\x28\x86\x1b\xad\x9c\xd4\xc7\x85\xb3\x3b\x8c\x85\x32\x57\x4c\x2c\x53\xb0\xfb\x5c\xe5\xde\xed\xf0\x2e\x84\x5e\x02\xa5\xa9\xc4\x5b\xdc\x63\x35\x71\x77\x3b\x34\xc6\xb6\xc0\x1b\x9a\x71\x96\xae\xb0\xc3\xef\xcc\x67\x97\xca\x2e\xcf\x5e\xe0\x20\x7c\x82\xa8\x78\x29\x34\xa7\x06\x9a\xd3\xf4\xfc\x30\xf1\x50\x4a\x84\x9a\x73\xbc\xbe\x5f\x16\x6b\x0e\x50\x97\xa2\x7e\x69\xab\x18\x40\xad\xab\x20\xd4\x88\x71\xb5\x84\x18\x68\x83\x17\x2c\xbf\xc3\x80\xf1\x2c\xd9\xc0\x85\xe1\x0b\xe0\x05\xbb\xf8\x3f\x71\x33\x7f\xf2\xc4\xb8\x87\x37\x60\xf7\xba\x42\xbc\x2c\x36\xca\x9f\xbc\xda\x60\x88\xee\x66\xc5\x21\xa4\xe6\xab\x8e\xd1\x7c\xf9\xca\x83\x42\xe5\x27\xcd\xed\xf0\xbe\x8e\x99\xb7\x4b\xd7\xf7\x29\xcf\x97\xf7\x76\x0a\xfc\xb4\x41\x9c\x81\x55\x91\xb5\x3d\x7f\x4f\xc8\xb9\x35\x6c\x3b\xbe\xeb\xf9\xce\xe0\xc6\xd9\xfe\xff\xfe\x6f\x3a\x1f\x0f\x9d\x97\xd9\x62\x3a\x9e\xac\x39\x5b\x93\x89\x33\x1f\xbf\x3b\x27\x83\xc2\xca\xa7\x6b\x04\xc2\x51\x96\x8e\x21\xbc\xcf\xe0\x0a\xe1\x83\x3e\x16\x82\xf2\x8c\xa7\xce\x62\x76\x35\x1f\x66\xf8\x66\x30\x9e\x26\xf3\x1b\x60\x07\x17\x04\x19\x1a\xa5\x61\x8e\xff\xce\xc8\x52\xbc\x40\x9e\x3e\x44\xce\xda\x71\x92\x79\x06\x21\x37\x2f\xc6\x4b\x08\x2e\x41\xd8\xe8\xfb\x71\x0a\x51\x26\x20\xe8\x04\xac\xd3\xd1\x6c\x32\x99\x7d\x80\x18\xd2\xc3\xd9\x34\x1d\xd3\x35\x0c\x95\x2e\x08\x23\x63\x2b\xfe\x89\xa3\xa2\xb5\x40\xc5\x30\xc5\x67\x38\x4b\x49\xd9\xab\xc5\x12\x36\x6a\xb2\xb8\x10\x68\x32\x80\xb8\xfb\xc3\xd9\xe5\x0d\x76\xd1\x99\xce\x96\xe3\x21\x59\x99\x18\x57\x68\x42\x20\x01\x00\xb9\xbd\x69\xaa\x21\x43\x9a\x1b\x92\xb5\x7a\x41\xd6\x93\x0d\x07\xd2\x96\x34\x10\x1c\x07\xd2\xc3\xf4\x6a\x98\xdd\x39\x1a\x0e\xeb\x5a\x3a\x1b\x5e\x89\x38\x18\x50\xe3\x29\x19\x7a\x1a\x23\xe3\x82\xac\xfa\xf9\x38\x99\x2c\xf2\x61\xc6\xb9\xc1\x6a\x12\xea\x30\xcf\x27\x7b\xfb\xc7\xce\xf1\xe1\xf3\x93\x1f\xb7\x8e\x76\x1d\xf2\xfb\xf5\xd1\xe1\x0f\xfb\x3b\xbb\x3b\xce\xb3\x7f\x39\x27\x7b\xbb\xce\xf6\xe1\xeb\x7f\x1d\xed\x7f\xbf\x77\xe2\xec\x1d\x1e\xec\xec\x1e\x1d\x3b\x5b\xaf\x76\xc8\xdb\x57\x27\x47\xfb\xcf\xde\x9c\x1c\x92\x17\x7f\xdb\x3a\x26\x35\xff\x86\x1f\xb6\x5e\xfd\xcb\xd9\xfd\xe9\xf5\xd1\xee\xf1\xb1\x73\x78\xe4\xec\xbf\x7c\x7d\xb0\x4f\x80\x11\xe8\x47\x5b\xaf\x4e\xf6\x77\x8f\x3b\xce\xfe\xab\xed\x83\x37\x3b\xfb\xaf\xbe\xef\x38\x04\x80\xf3\xea\xf0\xc4\x39\xd8\x7f\xb9\x7f\x42\x8a\x9d\x1c\x76\xb0\xd1\x62\x35\xe7\xf0\xb9\xf3\x72\xf7\x68\x7b\x8f\x3c\x6e\x3d\xdb\x3f\xd8\x3f\xf9\x17\xb6\xf7\x7c\xff\xe4\x15\xb4\xf5\x9c\x34\xb6\xe5\xbc\xde\x3a\x3a\xd9\xdf\x7e\x73\xb0\x75\xe4\xbc\x7e\x73\xf4\xfa\xf0\x78\xd7\x81\x6e\xed\xec\x1f\x6f\x1f\x6c\xed\xbf\xdc\xdd\x59\x23\xad\x93\x16\x9d\xdd\x1f\x76\x5f\x9d\x40\x48\xd6\x83\x03\x63\x2f\x01\x77\xa5\x8f\xcf\x76\x09\x92\x5b\xcf\x0e\x76\x69\x4b\xa4\x97\x3b\xfb\x47\xbb\xdb\x27\xd0\x9d\xfc\xd7\x36\x19\x39\x82\xdf\x41\xc7\x39\x7e\xbd\xbb\xbd\x0f\x3f\x76\x7f\xda\x25\x9d\xd9\x3a\xfa\x57\x87\xc1\x3c\xde\xfd\xdf\x6f\x48\x21\xf2\xd1\xd9\xd9\x7a\xb9\xf5\x3d\xe9\x5b\xab\x62\x48\xc8\x9c\x6c\xbf\x39\xda\x7d\x09\x38\x93\x71\x38\x7e\xf3\xec\xf8\x64\xff\xe4\xcd\xc9\xae\xf3\xfd\xe1\xe1\x0e\x0e\xf4\xf1\xee\xd1\x0f\xfb\xdb\xbb\xc7\x1b\xce\xc1\xe1\x31\x8e\xd6\x9b\xe3\xdd\x0e\x69\xe1\x64\x0b\x1b\x26\x20\xc8\x50\x91\xcf\xe4\xf7\xb3\x37\xc7\xfb\x38\x68\xfb\xaf\x4e\x76\x8f\x8e\xde\xbc\x3e\xd9\x3f\x7c\xd5\x26\x3d\xff\x91\x0c\x0b\xc1\x71\x8b\x54\xdd\xc1\xd1\x3d\x7c\x85\x5d\x25\x23\x74\x78\xf4\x2f\x00\x0a\x63\x80\x83\xdf\x71\x7e\xdc\xdb\x25\xef\x8f\x60\x40\x71\xa4\xb6\x60\x08\x8e\xc9\x88\x6d\x9f\xc8\xc5\x48\x7b\x64\x00\x4f\xa4\x3e\x3a\xaf\x76\xbf\x3f\xd8\xff\x7e\xf7\xd5\xf6\x2e\x7c\x3d\x04\x28\x3f\xee\x1f\xef\xb6\xc9\x5c\xed\x1f\x43\x81\x7d\xda\xec\x8f\x5b\xa4\xcd\x37\xd8\x65\x98\x23\x82\x15\xfd\x29\x51\x6c\x07\x67\xd2\xd9\x7f\xee\x6c\xed\xfc\xb0\x0f\x68\xb3\xc2\x64\xee\x8f\xf7\x19\x9d\xe0\x90\x6d\xef\xb1\xe1\x26\xeb\x98\x6c\x9d\x8a\xce\xeb\x65\xb2\x3c\xbf\x5b\xbd\x57\xbd\xa8\xd3\x34\xf0\xb1\x28\x42\x1f\x6b\x59\x67\xe3\x85\x5d\x32\x25\x4c\x7b\x09\x46\xda\x79\x95\xb7\xbf\x4d\x8c\xc1\x36\x73\x39\xca\xed\x38\x8e\x47\xfe\xf8\xe4\x4f\x40\xfe\x84\xe4\x4f\x44\xfe\xc4\xe4\x4f\x97\xfc\xe9\x91\x3f\xfd\x8e\xe3\x91\x72\x1e\x29\xe7\x91\x72\x1e\x29\xe7\x91\x72\x5e\x24\x59\x58\x76\x69\x5d\xf8\x06\xf0\xa0\x3c\xc0\xf0\x22\x0a\x17\xea\x61\x5b\x7d\x06\xdf\x67\x30\x3c\x6c\x23\x87\x13\xb0\xb6\x42\x86\x4b\x9f\xc1\xe8\x49\x78\x76\x19\xac\x98\xe1\xe2\x51\x98\x9e\x1c\x6b\xd9\x63\x75\x39\x2e\x2e\x85\x81\x78\x70\x3c\x03\x0a\x0b\xe0\x7b\x72\xbf\x65\x38\x21\xab\x1b\x31\xdc\xbb\x0c\x86\x2f\xe1\xe9\x31\x58\x3d\x86\x0b\xeb\xb7\x17\x9c\xb5\x37\xe4\xb9\x98\x57\xcc\x05\xc7\xa3\x2b\x8d\x95\xcf\x60\x72\x9c\x63\x75\x3c\xb0\x6f\x81\xd6\xf7\x98\xd5\x09\x72\x58\x58\x37\xca\x71\xe6\x30\xf8\x78\x60\x5b\x9e\xd6\x77\x2c\x14\x49\x1d\xec\x32\x04\xe3\x7c\x70\x05\x10\x5f\x1a\x68\x8a\x6c\x0e\xa8\xc7\xea\x48\x83\x85\x13\x13\xe5\x83\x2b\x60\x04\xd2\x40\x53\x64\x25\x84\x7c\x36\xb2\xae\x04\x8c\x8f\x46\x57\xcc\x9e\xa0\x50\x87\x8d\x0e\x45\x56\x9d\x8d\x45\xd5\xca\xa0\x28\xb2\xb1\x42\xf4\xe4\x96\x38\x6d\x05\xd2\x78\xc6\xf9\x37\x85\xa6\xc9\x3b\xf8\x84\x43\xc5\xe9\xb5\x9f\xd3\x1e\xa7\x29\x2f\x92\x86\xb5\xcb\xca\x2a\xf3\xe1\xe5\x44\x20\xe6\xa2\xcf\x0a\x72\xe2\xe9\x49\x65\x38\xe2\x5d\xfc\x2d\x9f\xa5\xc4\x5a\x0e\xf3\xaa\xbc\x7d\xb1\xe6\xe5\x35\xd1\x53\x40\xe6\xa0\xf8\xfa\x8c\x72\xda\x17\xfd\xf4\x73\x14\xc4\x38\x31\x92\xa1\x70\x1d\x6d\x4a\xaa\x16\x08\x43\x4c\x19\xfc\x28\x47\x00\xfb\xd9\xcd\x17\x22\x36\x18\x32\x44\x62\x0d\xe9\x40\x1d\x7c\xd1\x69\x2f\x87\x23\xc6\x4e\x2c\x68\xfc\xae\xc0\x11\x0c\xc4\x93\x06\x29\xce\xdb\x15\x0b\x8f\x2d\x60\x2f\x30\xcc\x87\xe8\x80\x86\x38\x07\x24\x16\x9c\x2f\xfd\x1b\x89\x55\xac\x0e\x50\x64\x28\x17\xaa\x33\x23\x66\x32\xef\x14\xf9\xe3\x9c\x29\x59\xb2\xdf\x9e\xc3\x0a\x31\xcc\x87\x23\x42\x35\x93\xe5\xe5\x5e\x47\x5b\x3d\xbf\xdb\xef\xf7\xe1\x77\xbc\xbb\xd3\xdf\x7d\xb6\xe5\xc1\xef\xde\x73\xef\xd9\xb3\xed\x9d\x6d\xf8\xbd\xd5\x8f\x82\xe7\x3b\xe1\xae\x3a\xdf\xe7\x73\x6b\x03\x91\xbb\xe5\xf7\x9e\xed\xc6\xd8\x00\x39\xab\xee\x78\x7e\x88\x0d\xec\x74\xdd\x60\xf7\x79\x00\xbf\xbb\x5b\xe4\x29\xde\xc5\x86\x39\x42\x67\x46\x7d\xc0\xd1\xfe\xeb\xdd\x97\x3b\x5e\xec\x62\xf8\xfd\x0a\x1d\x92\x28\x9b\x6b\x91\xa4\x57\x74\x57\x5e\xf5\xae\x88\x2a\x13\x11\x09\x4b\x10\xec\xb8\x1b\x46\x7e\xe0\xe2\x08\xee\x3e\xdf\xde\xd9\x7a\xd6\xc3\x0e\xf6\x7b\xcf\xb6\x76\xb6\x9f\xef\xc2\x6f\xcf\x0d\xfc\x28\xec\xe2\xe0\x6c\x07\x3b\xfe\xae\xf7\xdc\x3d\x6b\xdf\x56\x29\x7f\x3b\x2f\x25\x2f\x2e\xb7\xdb\x2c\x37\xc7\xa7\x58\xa0\xee\x35\x37\x8b\xb4\x5c\xdf\xbc\x7c\x2b\x95\xe6\x97\x07\x6f\xc7\x67\x8d\xee\x54\xa4\x7a\xa0\xe5\x36\xdb\x80\xb6\x5a\x2d\xa9\xb1\xdc\xf0\x41\x7a\xd9\xcc\xa8\xb4\x00\x90\xd9\x95\x6a\x00\x8b\xd6\xa5\x45\x70\xed\xf2\x38\xc4\xa5\xb6\xce\x7b\x8e\xb8\x7f\x00\x52\xb4\x5e\x39\x22\x03\x20\x6c\xc0\x5e\x60\x8e\x05\xe6\xd6\x02\x28\x7e\x12\x21\xd4\x5e\x00\x21\xfc\x66\x87\x80\xdb\x34\xd9\xac\xed\x05\x10\xc2\x62\x6e\x8e\x68\x4d\xc6\x81\xac\xb2\x5f\xe1\xd0\x4c\x0a\x8f\x41\x3a\x36\x5c\xd2\x26\x13\xc8\x46\xd5\x71\x86\xe4\x4f\x4a\xfe\x64\x13\x43\x43\xc9\x9c\x14\x22\x7f\x86\xe4\x4f\x4a\xfe\x64\x73\xbd\xb1\x04\x50\x49\x00\xe1\xbd\xa2\xcb\xc8\x60\x8e\x41\xc7\xf1\xa3\xa7\x7f\x1c\xc2\xc7\x21\xfd\xe8\xeb\x1f\x53\xf8\x98\xd2\x8f\x81\xfe\x11\x0f\x0c\x19\xfd\x18\xea\x1f\x45\x9a\xea\x44\xcd\x4b\x2d\x2e\x69\x6a\x5c\xfe\xf6\xf0\xdf\xc7\x9b\x70\x4b\x64\x58\xdb\xa0\xfb\x6c\x91\x3e\x3f\xce\xd7\xd4\xe3\xdf\x26\xa7\xe3\xb3\xb3\xf6\x1f\x26\x27\x06\xf4\xda\xf9\xce\x8b\xdb\xa0\xe9\x52\x01\x91\x36\x9c\x91\xd7\x22\x13\x41\xe6\x21\x9d\x80\x49\xd1\xf9\xc4\xec\x7b\xf3\xd1\x11\x0a\xb9\xf1\x77\x81\x4f\x55\x6d\x06\x68\xbe\x0e\xcd\xab\x84\x16\xf6\xac\xd0\x02\x1d\x9a\x5f\x09\x2d\x0e\xad\xd0\x42\x1d\x5a\x50\x02\xed\x77\x32\x95\x14\x62\xcf\xb5\x42\x8c\x74\x88\xe1\x59\xb5\x8b\x05\x9f\xc4\xa5\x71\xba\xe0\x8b\x33\x9f\x2d\x27\xad\x65\x67\x01\xd3\xda\xb6\x14\x6a\x2d\x1f\x67\x13\xf3\x94\xe3\x8a\x28\x2c\x29\x24\xde\x09\x52\xb6\xe1\x0b\x92\x3b\xb6\x0b\x6b\xd2\x33\xba\x01\xe1\x7a\x19\x4c\x8c\x71\xdb\x4d\x37\x89\x39\xb9\xce\xc9\xf0\xe4\xd4\x3a\x5f\x9d\x5a\xc9\x88\xcf\x3b\x84\x21\xa4\x73\x1c\xf1\xf9\xed\xa8\x35\xd4\xa1\xdd\x96\x5a\x55\x68\xb7\xa2\x56\x5f\x87\x76\x6b\x6a\xf5\x74\x88\x77\x4c\xad\x73\xbc\xb5\x2e\x21\xd7\xb9\x85\x5c\x91\xa3\xce\x4d\xe4\x8a\x8c\xd8\xf4\x05\x59\x34\x25\xd7\xb9\x95\x5c\x71\x03\x30\xd5\xc6\xad\x61\x59\xb9\xa1\xef\xcb\xe9\x18\x50\x86\x44\xab\x5f\x4d\xc2\x84\xbf\x48\xff\xf6\xa8\x69\xee\x10\x38\x73\x6a\xe8\xe9\x1e\x33\xe1\xdd\xa3\xe6\xb7\x29\x94\xcb\x8c\xe5\x7c\x56\x0e\x0d\x69\x33\x28\x97\x18\xcb\x05\xac\x1c\x1a\xcb\xe2\x8e\x30\x30\x96\x0b\x59\x39\x34\x4c\x1e\x40\xb9\xa1\xb1\x1c\x1a\x30\x2b\xc3\xa2\x8b\xb5\xcf\x59\x6a\x8d\x5b\x98\x67\x41\x92\x42\x21\x0c\xc1\xc3\x86\xb9\x4c\x79\x1a\x46\x5e\x72\xfa\x6c\xbc\x5c\x9c\xcc\x96\xc8\xf1\x28\xcc\xe9\x0e\xa9\x41\xad\xb6\xbe\x75\x7a\x1b\x96\x3a\x07\xd9\x68\x59\x48\xda\x88\xe5\x0b\x9d\x81\x5c\xcb\x85\x2c\xc4\x48\x83\x22\x97\x63\x0e\x12\xa4\xc9\xe8\x0c\xac\x26\x30\xb1\x70\x6e\x23\x21\x4a\xfc\xdd\x21\x0c\x69\xc3\x06\x89\x08\xac\x79\xd1\xc7\x0e\xf0\x07\x00\xd9\x6f\x03\xa8\x10\xd6\xad\x17\x5a\x04\x68\x5e\x95\x0e\x47\x2e\x3c\x4b\x2f\xeb\x0b\xcf\x05\x60\x4c\x70\xd6\x80\x55\x09\xce\xa6\x8e\xca\x79\x3a\xf2\x7c\x98\x3c\xc7\x0e\x1a\xc7\x18\xd2\x76\x80\x21\x3f\xac\x40\xcc\xee\x42\x1d\xb2\x8c\x86\x53\x97\xf4\xe4\xd5\x6a\x98\xd2\x08\x57\xb7\x2c\xa3\x6f\x98\xa4\xf8\x4d\x47\x16\xdf\x6f\x77\x7e\x8b\x6a\x1d\xdf\xcc\x27\xb2\x3d\x3c\x8a\xed\x9d\x36\x3c\x83\xed\x09\x77\xbd\x3d\xe5\x78\xb5\xd7\xf8\x5c\x45\x29\x64\x4f\x39\x53\xed\x59\x0f\x53\xd5\xa6\x70\x47\xf4\x26\x9c\x4e\x2e\xcb\x60\x91\xe2\x60\x1b\x93\xb7\x68\x13\xa4\xb0\xa9\xe1\x64\x36\x2d\x67\x50\x68\x4a\x00\xa5\x72\xed\x02\x3e\xda\xcd\x20\xe8\xe7\xb7\x05\x22\xa1\xf5\x8a\xb4\xc6\xd0\xc4\xaf\x96\xf4\x32\x9a\x45\x1c\xd9\x9c\xaf\x3b\xce\x4d\xc7\xf9\xcd\x94\xe6\xa3\xd5\xba\x46\xcf\xce\x1b\xfc\xfb\xb7\x3c\x5b\xfb\xc7\x02\x1c\xbf\x1c\x0e\x01\xf4\x0f\x02\x86\xba\x93\xff\x1b\x1e\x08\xb4\xf6\x86\x0d\x5a\x50\x09\x0d\x00\xfd\x1b\x20\xe6\xa8\x59\x60\x85\xd5\xb0\xfe\x81\x10\x10\xb7\x1b\x82\xda\xbf\x11\x39\x3b\xc4\xa8\xce\x98\xc1\xa0\xfd\x91\x83\xb2\xc0\x42\x51\xe2\xba\x33\x35\x42\xba\xfe\xee\xbb\x29\x62\x75\x4d\xe8\xbb\x15\xf8\x4f\xa6\x32\x52\x35\xac\x61\xb8\x31\x0c\xcd\x03\x57\xcf\x18\xc6\x9a\xed\x87\xd9\xb7\xa0\xcd\x13\xff\x4d\xe0\x23\x3d\xd6\xce\xcf\x63\x29\xbd\x6a\x62\x1e\xb6\x32\x72\x4b\x16\xae\xc0\x6b\x3d\x62\x28\x3e\x32\x58\xe1\xd8\x6b\x7d\xe0\xd8\x9a\xcc\x6d\x8a\xca\x41\xcd\xd4\x86\x17\xa8\x67\xaa\xc4\x27\x07\x92\xf6\x7d\x82\xa9\x29\xa9\x0e\xa6\xeb\x50\x75\x91\x91\xfe\x2c\x2d\xb9\x93\x2c\x13\x8a\x29\x07\xef\x70\x42\x69\x22\x43\x31\x35\x7b\xe4\x39\x9f\x1e\xd9\xc4\xca\x30\x43\x4a\xe1\xe2\x2c\x91\xaf\x86\x99\xfa\xea\x23\xbd\x07\xb6\x98\x46\xf1\x92\xe6\xec\x44\x60\xe2\xd4\x7d\x30\x71\xfa\x2b\xb8\xac\xdc\xf7\xd5\xbd\x16\x58\x4d\x6a\xd8\x1a\x32\xed\x78\x6f\xeb\x89\x57\xe3\x26\xa3\x98\xab\xfa\x2e\xef\x2f\x36\xd1\xfb\x34\xbf\xc2\x80\x88\x55\x2d\x43\x00\x2a\xf5\x4a\x23\x1b\x0d\xd3\x64\xd0\xeb\x77\x4c\x05\xfb\xbd\x41\x92\x0e\x47\x99\x72\xc7\x61\x2a\x38\x0c\x52\x3f\xf3\x46\xae\x16\x87\xa9\xbd\xf1\xc9\xc2\x85\xec\x19\x35\xe8\x2b\xe9\x9e\xcd\xea\x62\x4c\x86\x69\x56\x16\xa3\xc0\x68\x56\x15\xe3\x71\xd5\xac\x28\x86\x4f\x99\x50\x13\xd7\xd7\x13\x5b\x75\xc2\x96\xd3\x02\xea\x7d\x1c\xea\x61\x6a\x89\x05\xf3\x23\x15\xfc\xd5\x10\x18\xaa\xef\xa9\xae\x50\xb2\x67\xba\x04\x3f\x07\x02\x90\x1c\x6a\x21\xae\x0e\xfb\xd9\xcb\x7f\x7a\xa1\xf4\x3b\xb6\xe5\x8e\x64\x28\xb5\xa6\xe8\x1c\x4b\xcf\x96\x78\x7c\x08\x3c\x73\x98\x1a\xbb\x9b\x4e\xab\x95\x00\x90\x08\x81\x24\xf4\x24\xd3\x45\x07\xd9\x8c\xfc\xf9\xd1\xec\xc4\xc3\x47\xcd\x77\xad\xa3\x86\x6a\xb3\x56\x6b\x40\xc4\xd2\x21\x95\x73\xe1\x67\x8a\x90\xdd\xeb\x28\xa1\xb7\xb0\x15\x2a\x3e\xf0\x06\xa9\x6a\x62\x40\xc6\x6b\x48\xfe\xa4\x14\x72\x9c\xa5\xfd\x6c\x90\x78\xd5\x90\xe3\x06\xc8\x53\xdc\xe1\xd7\x90\xf5\xe2\x09\x5c\xe9\xba\x59\x18\x06\x7e\x68\x6f\xeb\xe9\xb7\xa2\x39\xd0\x2d\x7e\xfb\xb4\x76\x5f\x00\x7e\x10\xf5\xd3\x20\xf3\x93\x9a\x53\x0a\xeb\x25\x35\xa9\xfb\xe0\xb6\xc6\xa4\xe2\x73\xb0\x49\x32\xfb\x81\xcb\x7a\x89\xd3\x6f\xcc\x2d\x0a\x77\x45\x26\xf5\x63\x3d\x6f\xad\x5a\xca\x40\xa6\x44\xe3\xca\xb6\xa4\x6d\x58\x65\xb9\x22\x10\x15\x86\x03\x73\x19\x5f\x56\x16\x0e\xcd\x65\x02\x59\x51\x98\x9a\xcb\x84\xb2\x92\x30\xd3\xcb\x3c\x28\xf8\x3e\x57\x05\x1f\xc8\xc2\x6b\xa3\xc9\x6c\x36\x97\x75\x6e\x4f\x71\xa3\x66\xff\xdd\xaa\x11\x8c\x85\x90\x43\xae\xd0\xd3\x15\xd4\x74\xf7\xa4\xa1\x6b\xa8\x07\x32\xaa\xeb\xbe\x44\x6d\xd0\x83\x0a\xa1\xa0\x0c\x00\xf1\xb9\x91\xf6\x00\x2b\x94\x29\x0e\x54\x81\x5c\xd5\x19\xc0\xb7\x07\x75\xc1\x9d\xaa\x0b\x70\x3e\x6a\x68\x0a\xcc\xd3\x92\x2b\x09\xd8\xd4\xd8\xdd\xa6\xa0\x80\x5d\x2d\xd0\xfb\xa2\x03\x6c\x2c\xce\x13\x3f\x8a\xef\x3b\x36\x06\x6b\xe5\x3f\x47\x7d\x50\x50\x0f\xc8\x67\x78\x32\x14\xca\x29\x5e\xf2\xc2\xd6\xb5\x02\x3e\xd9\xfb\x6b\xe9\x05\xa0\xa0\x02\x13\x9f\x29\xf0\x4f\xaa\x1b\x18\x7a\x6e\xd4\xcf\xd2\x1e\x1c\xf9\x83\xb8\x3b\x4c\x23\xb7\x8b\xbf\xdd\xae\x9b\xa6\x1e\xfe\x1e\x91\x53\x41\xd4\x0f\xcc\x3a\x83\xd1\x68\xe8\xba\x83\x00\x95\x0b\x71\x2f\xea\x79\x91\x47\x7f\x87\xa3\x7e\x6f\x94\x20\x80\x41\x36\x4a\x42\xf2\xff\x06\xea\x82\x5a\x92\xa7\xc4\xf6\xd9\xd0\x49\x35\x4b\xbc\x68\x91\xa3\x0a\x71\xe6\xc9\x66\x51\x78\xb1\x5c\x2c\x3d\x6c\xd1\x15\x3b\x2e\xa1\xe3\xa6\x9b\x34\x54\xa9\xd8\xa6\x95\xd5\x51\xd8\xa8\xc9\xd7\x87\xad\xfa\xae\xb7\x6a\x98\x95\x7a\x9b\xb5\x71\x72\x94\xed\x9a\x4e\x50\xe9\x86\xed\xfb\xa1\x79\xcb\xee\xe4\xdb\xd1\x7a\xb7\x0f\x1b\x78\xff\x41\xaf\xff\xe7\x6c\xcc\x5f\x9e\x5b\xde\x3e\x4d\xe2\x30\xfe\x4d\x78\xe5\x3a\xf3\x19\x11\xe0\xc0\xb9\x57\xf6\xd7\x93\x7a\xb0\xa7\xa7\x4e\x79\xa1\x5e\x03\x70\x45\x6d\x96\xc3\xa0\x5f\x5a\x96\x7d\x4a\xbc\x1e\x2f\x5e\xcf\xc7\x17\x59\x6b\x6a\xdc\xc6\x16\xff\x33\x5f\xbe\xe2\xe7\x7c\x78\x20\xe5\x6c\xc6\xc1\x74\x3a\x49\x61\x7f\x83\xff\xfe\x6e\x93\x42\xe0\x2f\x4a\x74\xc3\xdf\xb4\xa6\xce\xdf\x59\xb1\xb6\x55\x5f\xc8\xd6\xe8\x28\x21\x94\x55\x27\x4c\xb8\xf9\x3c\x3e\xbf\xca\x2c\x71\x60\xa4\xd8\x3f\xcb\xe7\xf3\x04\x7f\x27\x13\xd0\x3f\x18\x06\x48\x5c\xe1\x4f\x21\x16\xd4\x14\x23\x7b\x82\x55\x90\xac\xfc\xd0\x35\x59\x5f\xab\x21\x7b\xa6\x38\x5c\xda\x3b\x9c\x10\x2d\x7e\xcf\x87\xf3\xf1\x24\x23\x8d\xd0\x6f\xdf\x39\xcc\x24\xb3\xa0\x2b\x16\xb3\x69\x1d\x68\x01\xa2\x67\x1d\xe5\xbd\x53\x5a\x08\xc3\x8e\x16\x06\x02\x69\xe1\x72\xf6\xa1\x35\xed\x38\x9e\xf3\xd4\xf1\xdb\xed\x7a\x06\x9a\x2f\x1a\x81\x0d\xda\xc6\xe0\xd9\x14\xc4\xe3\xc7\x15\xaa\xd0\xa9\x52\x82\x4b\x48\xad\x1c\xe6\xea\x77\x6c\x20\x78\xd7\x93\xa6\x8b\x12\xfa\xbd\xdf\xb4\xed\xad\x2d\x26\xe3\x61\xd6\x72\xdb\x0f\xb7\x5e\xb5\x6f\xbd\x0a\x9f\x46\xf8\x29\x32\x7d\x7a\x87\x9f\x62\xd3\xa7\x73\xfc\xd4\xbd\xf5\x35\x5a\x1c\x7e\x3e\xd7\x68\xef\x92\x8b\x8b\xc4\xbd\x16\x97\x69\x5e\x64\xbb\x2a\xcb\x4b\xa3\xa1\x71\x8b\xd7\x04\xd3\x3b\x7a\xe9\xc5\xdf\x80\x92\x17\x6e\xbe\x9c\x9f\xad\x66\x7b\x8a\x05\x9f\x04\xc9\x0b\x0b\x90\x3c\xb0\xe4\xab\x05\x49\xad\x17\x18\xd9\x8b\xd2\x13\x2f\xef\xb7\x5f\xd9\x6d\x4f\xea\xb6\x47\x91\x95\xba\xed\x5d\xf3\x78\xd3\xed\x46\xdd\x66\x90\x82\x22\xa4\x7e\xc3\x6e\xf3\x7a\xae\xb5\xdf\x8c\x70\xd8\x24\x3e\x66\x3d\xc7\x20\xbf\xac\x87\x8f\x4b\x2f\x4c\x6d\xb7\x9f\x43\xf4\x6a\x6c\x65\xce\x3f\x9c\x11\x1a\xbb\xfd\x1b\x7e\xbe\xb3\xa5\x70\xb9\x48\x30\xc2\x5c\x42\xca\x0c\xb0\x78\x42\x6f\x07\x7f\x76\xd8\x3d\xe1\x86\xa5\x1d\x72\x74\x06\xc4\xc5\x55\x2b\xbb\x6c\x63\x77\xad\xd4\xf4\x8f\x7e\x21\x83\x97\x7f\x21\x83\x2b\x7f\x52\x2a\x99\xf7\x36\xd1\x18\xe6\xa9\x21\x1d\x03\x1a\xa7\x91\x93\x33\xac\x17\x33\x88\xf4\x8b\x27\x7d\xf1\x3c\xf9\x53\x97\x5a\xb6\xd2\x4f\x64\x95\x58\xbb\xb6\x84\x96\xc0\xec\x98\x35\xfb\x18\x06\xf5\x31\x4d\xf4\x66\xbd\x3c\xc6\x8a\x90\xc1\x87\x0d\xcc\x63\x18\x5a\x53\x0b\xc0\xb8\xde\x19\x00\x00\xaf\x1b\x19\xde\x03\x7b\x34\x89\x5f\x18\x3b\x13\xb3\x25\x78\x6d\x0b\xc7\x29\xbb\x22\x1d\x34\xbc\x0e\x6d\x2d\x61\x20\x96\xbe\xa9\xad\xff\x94\xab\x51\x5a\x26\x62\x65\x30\x64\xf8\xc8\x5c\x26\x66\x65\x30\x24\xf8\x3b\x73\x99\x2e\x2b\x83\x6b\xfe\xfc\xe1\x1a\xf6\xe1\x1a\xf6\xe1\x1a\xf6\xe1\x1a\xf6\x0b\xd1\xf1\x46\x71\x63\x1d\x2f\xa9\x52\xa5\xe3\x95\xcf\x6c\x45\x1d\x6f\x14\x3f\xe8\x78\xef\x5c\xc7\x4b\x66\xa5\xa6\x8e\xd7\x34\x39\xaa\x8e\x17\x27\xa8\xdc\x68\x5b\xdc\x9d\x99\xaf\x66\x7b\xee\x17\x7d\x35\x7b\x1d\x87\x9f\x24\x71\x81\x68\xe7\x41\x0b\xac\x6a\x81\xc9\xc0\x20\x74\xf2\xaf\xf4\xfe\xa7\x38\x64\x51\xba\xb1\xc4\x9a\x14\x27\xba\x51\x4c\x37\xa9\x7f\x47\x7b\x87\x6f\x0f\x9f\x3f\x3f\xde\x3d\x39\xd6\xb5\xc5\xaf\xf7\xdf\xee\xbf\xda\xd9\xfd\x69\xb7\x98\x95\xfb\xe8\xf0\xcd\xab\x9d\xb7\x10\x71\xf0\x64\xeb\x95\xa8\x59\x50\x2b\x6f\xd7\x53\x2b\x4b\x35\xe6\xe7\x33\x1e\xb4\x45\xd3\x49\xf3\xa6\xe1\x74\xed\x75\x9c\x1b\x5b\xa8\xf2\x25\x55\x89\x2c\xc1\xfe\x35\x24\xff\x1a\x54\x22\x52\x9f\x4f\xaf\x89\xa4\x12\x11\xf6\x72\x43\xbd\x07\x97\xdc\x49\x13\x7f\x91\x43\x02\x68\x2a\x89\xbc\x15\x87\x46\xd1\x31\xfb\x00\xa9\xec\x6e\x48\x81\x68\xc3\xf8\xf9\x5f\x00\xd5\x27\xf0\xa0\x9d\x00\xda\x69\x1b\x0a\x5f\x53\xb5\xdc\x4f\xda\xeb\x1b\xfa\xfa\x5f\x25\xd9\x9b\xd8\xb0\x5d\x8e\xc9\xb6\x00\xe9\x04\x0c\x83\x26\x46\xe6\x9a\x8e\xcc\x35\x75\xd0\xbc\x36\x0c\x8c\x28\x4a\x47\x97\xfc\x83\x45\x6f\x2c\x6a\xa5\x9c\x40\xd4\x61\xbc\xc1\x04\x3f\xc5\x5e\xc3\xb8\x46\x65\x51\x98\xcd\x24\xa1\xde\x72\xa8\xd4\x70\xf0\xfc\xf8\x08\x70\xbd\x76\xbd\x8d\xf2\x2c\x66\x7e\x68\x4f\x63\x86\x4d\x70\x62\x7d\xb9\xf8\xa0\xd1\x96\xb1\xd8\x01\x2f\x66\x19\x42\x96\x79\xe2\x17\xd2\x74\x97\xfc\x53\xa2\x99\xc3\x3e\xa0\x6b\xaa\xd7\x2e\xd3\xaa\x0d\xc6\xcb\xd7\xb3\x05\xc6\x71\x05\xaa\xc2\x64\xb9\xbf\x80\x29\xb2\x67\xd1\x32\x01\x70\xb9\xd2\x77\x0e\x8b\x17\x61\xd3\xf9\x14\x3a\xf8\x33\x59\x6b\xd0\x8c\x04\xc5\xd2\x96\x6a\x51\x2d\xb7\xfa\xcf\x4d\x6c\xd6\x6e\x5c\x5d\x68\xf9\xa5\xd4\xb2\x02\xea\x89\xe1\xdc\x53\x72\x75\xf0\xd1\xec\x40\xcc\x09\x0b\xd2\x56\x22\x01\x55\x4c\x4b\xcf\x6e\x89\x4e\xa0\xc1\xcd\x02\xc1\xee\x3d\x59\x84\xb3\xc9\xcd\x74\x76\x01\x99\xd6\x67\xef\x09\x7b\xff\xfe\x79\xcb\x6f\xaf\x3b\xd7\x3f\xf7\xc8\x2a\xb8\xfe\x39\xc6\xbf\x23\xfc\x1b\x32\x14\x7a\x66\x90\x8c\xa2\x69\xf3\xd4\x7f\xe0\x67\x30\x66\xf7\x1a\x6b\x62\x19\x84\x4d\x23\x7d\x54\x5c\x7a\x69\x0c\x9e\xea\xf8\xd8\xf6\xc3\x03\x8c\xe9\x73\xd6\x29\xd0\x4f\xbb\xe9\x6d\xca\x02\xd7\xcf\x32\xbb\x20\xe2\x0a\x44\x3d\x96\x33\xd1\xc1\x12\x38\x91\x37\x22\xeb\x2d\xa5\x31\xeb\x8c\x79\xf5\x9f\x18\x7b\x56\x85\xb7\x76\xb7\x13\xd4\xbb\xd9\x09\x94\x7b\x9d\xa0\xec\x56\xe7\xee\x53\x09\xcc\xae\x96\x84\xe8\x0f\xf0\x68\xad\x94\x75\x50\x48\x4f\xb3\x05\x11\xfd\x52\x29\xd1\x00\x59\x77\x0b\x1e\x10\x9a\x56\x56\x4e\x0b\xbc\xf2\xe1\x74\xc2\xa7\x49\x8a\xc1\x9d\xcc\x21\x7b\x0a\x44\xd9\x23\x42\x72\xc7\x09\x7a\xe4\x57\xe4\xf9\x7a\x65\x96\xb3\x60\x1d\xbe\x95\x25\x2d\xe0\x87\x66\x6b\xde\x02\xb9\x77\x1a\xb4\x15\xf2\x17\xa0\xa6\x16\x33\x21\xf2\xb3\x37\x7f\x3a\x3d\xab\x93\xe0\xc8\x7e\x11\x83\x70\x28\xc9\xc1\x35\x1c\x27\x3b\xbc\x84\xab\x0a\x25\x80\x98\x88\x6c\xbd\xb8\x03\xc4\xae\x0b\xb7\x0c\x64\x2c\x45\xa6\x04\x79\x24\x40\x7a\x09\xfc\xfb\xba\xd1\x5b\xd4\x1a\x34\xa3\xf2\x8c\x77\xe2\x20\x99\xa2\xee\x47\xeb\xda\x53\xb8\xdd\x2e\xea\xd0\x06\x8b\xd9\x7c\x50\x3d\xfe\x1a\xf8\xdb\x44\x12\x7c\xe9\x8f\x1d\xf5\x52\x0c\xc6\xda\xaa\x36\x27\xc5\x3d\x43\x71\xe0\xf5\x65\xc1\x2c\xac\x29\xa8\xa1\xf9\xd2\x78\x82\xbe\x12\x48\xd0\x5f\x25\x82\xa0\xaf\x84\x0e\xf4\x57\x8f\x19\xc8\x10\xf6\xaa\x30\xf6\x54\x94\xbd\x95\x70\xf6\x54\xa4\xbd\x26\x58\x1b\xe7\x81\x12\x97\x50\x8d\x8c\xa7\xcb\x19\xa5\x66\xf3\x4c\x4f\x12\x54\xe6\xf1\xe5\x6c\x18\x0a\x28\xb1\x86\xf9\x66\x89\x24\x03\x28\xda\xca\x40\x2a\x5a\x56\xa6\x46\x2c\x11\xd8\x60\x17\x96\x35\x40\x05\x65\x5c\x07\xf4\x27\x15\x7a\xf1\xb7\x7d\x09\x10\xee\xbf\x4c\x0c\xb7\x35\xf5\x4f\x0d\x12\xb0\x97\x63\x38\x82\x4c\xae\x2e\xa6\xd8\xb9\x85\x5d\x98\xe5\x62\x76\xc7\xc9\x25\x69\x63\xe1\x86\x67\x12\xeb\x2c\xe5\x27\x94\x0d\x7b\x2d\x2e\x7a\x8a\xd9\x2b\x2b\x7b\x20\x95\x25\xb3\x68\x95\x4b\xad\xa3\x75\x62\x94\x73\x4c\x3d\x39\x81\x59\x38\x39\xbd\xb6\xe1\x7e\x72\x4d\x69\x6d\x13\x7b\x60\x2f\x84\xc4\xb6\x89\xa8\xd7\xb3\xbe\x59\x8d\x0c\x4a\xd8\x6a\xde\xa3\x10\xbb\xd4\x82\x79\x09\xf1\x78\x58\x76\x59\x7e\x72\xed\xe5\xc5\xbd\x3a\xc5\x29\x7d\x91\x1f\x65\xf3\xc8\xca\x1e\x88\xb2\x38\x8f\xa5\xe4\xbd\xb8\x9a\xe3\x8a\xa2\xe9\x44\x80\xd4\xab\xa9\x9c\x74\x96\xf1\x02\xc2\xbf\x18\x6e\xc2\x35\x98\xb5\xcf\xfc\x83\xdb\x25\x98\x2e\x0f\x04\x34\xca\x35\x28\xb4\x03\x0d\xda\xcb\x6a\x68\x9f\x74\x51\x29\xac\xb0\x84\x3e\x0b\x2c\xd1\x42\xa6\xcd\x4e\x7b\x47\xe7\x33\xe7\xf5\xb8\x84\xb2\xa1\x49\x9e\xf3\xd1\xdb\x90\x1e\xa9\x28\x27\x9e\x6f\x4d\xf2\xca\xc8\x09\xb0\x65\x64\x0c\x85\x28\x05\x55\x31\x24\x5e\xfa\x20\x2f\x6d\x67\x49\xb8\x59\x9c\xcf\x0e\xa9\x94\xb2\xa9\xe8\xc3\x64\xbc\xac\x9d\x3d\x9a\xa1\xa0\x57\xc2\xe2\xe1\x00\x9d\x37\x51\xa9\x78\x90\xd6\x4a\x8b\x77\x9a\x90\xb4\x80\x80\xa4\xcd\xfb\x87\xa9\x34\x03\x9f\x48\xbe\xf9\xf7\xf6\x46\x05\xf0\x83\x1c\xf8\x81\x0d\xf8\xcb\x86\xc0\x55\x9d\x47\xde\xdd\x5a\x1a\x0f\xbd\xcb\x0c\x2b\x09\x0c\x6a\x3b\x8a\xc8\xc5\xe1\xca\x3d\x7f\x59\xd1\xc6\x41\xc3\x36\xca\xf6\xb6\x79\x32\x5d\x5c\xce\x16\x59\x95\x20\x70\xf2\x7a\x7c\x40\x17\xc6\xc9\xa9\xa4\x50\xcc\xe9\xd0\xba\xe7\xd1\x8a\x75\x36\x3e\x56\xb2\x6a\xf7\xb3\xf2\x8f\xcb\xb1\x93\x2c\x71\x0b\x44\x7e\x69\x96\x09\x4f\x5c\xec\x83\x6b\x3b\x1d\xe0\xba\x77\x05\x03\x30\x96\x3b\x71\x79\x77\x68\x69\xdb\x92\x27\xe5\x58\x67\x58\x39\xcb\xbe\x05\xda\xae\xf3\xf1\x9d\x6c\xeb\x2b\x6e\x19\xd5\xac\x51\x66\x5f\x9c\x0f\x8b\x7d\x65\xa3\xc1\x66\x54\xc9\x52\xc5\x4c\x09\x82\xab\x5f\x85\x6c\xb3\xbc\x52\x4b\x12\x42\xda\x75\x36\x40\x0a\xc0\xd7\x00\xf8\x05\x00\xa5\xa3\x08\xb2\x07\xdc\x7f\xd5\xd9\x67\x37\x69\x17\x85\xcc\xf1\x6f\x86\x3e\x7d\xf1\x0f\x8e\x0c\x3e\xb6\x6b\xed\xc6\x1c\x22\x97\x3b\x04\x44\x7c\x91\x43\x24\x8f\xb7\x57\xd0\xee\xcf\x4c\x27\x92\xc2\x7c\x5b\x57\x9a\xa2\x86\x84\xfd\x4d\x53\x66\xe2\xf7\xca\x43\x9b\x02\xc5\xb6\x10\x15\x79\x45\xad\x01\x4b\xb2\xe4\xb2\xe3\xf3\x32\x8b\xfa\x14\x56\x51\x45\xe3\x6c\xae\xa8\x81\x7a\x45\x35\xd0\xb7\xa8\xbc\xba\x3b\x5b\x2a\x6f\x15\x53\x2a\x34\x92\x1a\x66\xe3\x89\x6a\xef\xe4\x81\x6e\x4d\xc1\x1f\x2e\xb7\xb4\x17\xd8\x38\x5e\xd0\x08\x6b\xae\xcf\xc4\x08\xea\xd6\x3a\x3c\x59\xcd\xc8\x91\x37\xea\x20\xc9\x30\xf5\x2a\xea\x73\x2d\x60\x11\xe6\x53\xa3\x31\xdd\xf1\xff\x5c\x65\xd9\x6f\x99\xd9\x7d\x96\x13\x77\xad\x5c\xf4\x05\x2c\x6e\xa3\x2c\xac\xd6\x09\xd5\x17\xe9\x6d\xe2\x7c\x73\x1d\x62\xde\x9e\x5d\x2b\x27\x89\x86\x4c\x31\x27\x0b\x9c\x8d\x74\x73\x12\x28\xa6\x9e\x93\x41\xad\xaa\x57\xcc\x87\xa2\xbc\x13\x07\x85\x4e\x1c\xac\xda\x89\x83\x42\x27\x0e\x9a\x75\xc2\x3c\x55\x94\x74\xd9\x22\x5b\xce\xc0\x70\x6f\x3e\xce\xde\x67\x06\x03\x44\xb4\xb1\xe6\x14\xbd\x76\x79\xb5\x38\xe7\x68\x98\x86\xc8\x50\xf2\x65\xb1\xe4\xed\xc3\x13\x1b\xbc\x87\x44\xd3\x9d\xe2\x12\x6e\xdf\xb3\xd5\xa4\x9d\x7f\x31\x13\x4a\x03\x3b\x13\xce\x4e\x1b\x77\x76\x99\xc3\x7f\xe6\xf6\x99\x75\x5c\x0a\x1f\xcc\x35\x9b\x9a\x6b\x06\x4d\x8d\x35\x83\x2a\x53\xcd\xa0\xc4\x50\x33\x78\x30\xd3\xbc\x6b\x33\xcd\xa0\xa6\x91\x66\x50\x61\xa2\x19\xd4\x31\xd0\x0c\xec\x6e\xf8\xc2\xf0\x70\xbd\x17\x82\xb9\xa6\xf7\x17\x31\xd7\xd4\x03\xec\x44\x9e\xff\xc9\x6c\x38\x79\xb8\x1d\x68\xf3\xcb\x0a\xb7\xb3\x92\xb5\x25\xfb\x9c\x5b\x7b\xf2\x32\x8d\x02\xf2\x90\xc1\x52\xcc\x42\xc8\xb3\xd5\xcc\xa4\x57\x33\x20\x0f\x14\x54\x4c\x4d\x7a\x2c\xaa\x05\x98\x60\xdc\x95\x0b\xb1\xdc\x7d\x6b\x4c\x9e\x82\x91\x83\x7b\x3d\x1c\x0c\x06\xfd\x34\x4a\x3b\x52\xc0\x1e\x42\xb6\x86\x92\xb1\xdf\x4f\xfc\xbe\x9f\xc8\xe1\x7c\xda\x9d\x5a\x8d\xf4\xbd\xa8\xef\x7a\x51\x22\x47\xff\x31\x37\xe2\x45\xfe\x28\x1b\xd2\x98\x41\x3c\x36\x50\xcd\x46\xe2\x6e\x10\xf8\x71\x4c\xc3\x0a\xb1\xc8\x41\xe6\x46\x7a\xd9\x20\x0c\x93\x5e\x57\x8e\x2b\x54\xb3\x91\x74\xe0\x0e\xfd\xcc\x4d\xe5\x30\x44\xe6\x46\xc2\xee\x20\x0a\x7b\x5e\x2a\x07\x29\x6a\xdf\x6f\x94\x22\xa0\xa7\x15\xa3\x14\x79\xf1\x43\x98\xa2\x3b\x92\x89\x7a\x8d\xc3\x14\x41\x95\x2a\xb9\x48\xe6\x19\x45\xc9\xa8\xf7\x10\xa6\xe8\xee\x65\xa3\x5e\xdd\x30\x45\xc6\xc9\x51\xe5\xa3\x5e\x65\x98\x22\x52\xa4\x34\x4c\x11\x6c\xe3\xeb\x3d\xdf\x24\x2d\xf9\x0f\xce\x2d\x9f\xbf\x73\xcb\xdd\x3a\xb6\xdc\x93\xcb\xca\xea\x42\x94\x96\xe4\x86\x01\x7a\xcb\x2d\x78\x0d\xb9\x6e\x54\x23\xdf\xb5\xe4\xf2\x72\x72\xd3\x62\x2f\x3b\x4e\x32\x7f\x77\x75\x91\x4d\x97\x0b\x3d\x27\x8f\xec\x3e\xa3\x85\x52\xca\x9b\xd0\x9a\x27\xbb\xb1\xdf\x4b\x7c\xb2\x5d\xc3\x6e\x9c\x76\xc9\x43\xe6\xfb\x84\x0c\x0b\xe5\xba\x5e\xd0\x0d\xc3\x3e\x86\x19\xf4\x83\x6c\x14\x47\xc3\x54\x16\x0d\x0a\x15\x06\xd1\xd0\x1d\x0d\x86\x23\x4c\x80\x30\x0c\xd3\x60\xe0\x8f\x4c\x80\xb3\xfe\x20\x4a\x07\x49\x84\xd9\xb3\xbd\x5e\x3f\x1d\x0c\x86\xa5\x80\x83\x7e\x14\x0f\xfd\x68\x80\xe2\x4c\x10\xf6\x06\x51\xd0\x33\x01\x8e\xfa\x23\xcf\xf3\x46\x88\xf1\x20\x76\xa3\xd4\xf5\xfa\xa5\x80\xfb\x7e\x30\xea\xf9\x09\xa6\xdc\x4e\x46\x5e\x9f\x48\x31\x03\x13\xe0\x64\xe0\x0d\xa3\x2c\x45\x8c\xd3\x24\x4e\x7b\x9e\xd7\x2b\x05\x9c\xf6\xdc\x6e\x92\xd0\x31\x4e\x02\x37\x70\xfd\xd0\x38\xc6\x9e\xdf\x0b\xa2\x01\xcd\x19\x11\x46\x5d\x37\x1e\x0d\xb2\x52\xc0\x7e\x18\x78\xbd\x68\x80\xb9\x23\xc2\x2c\x0b\x07\x7e\x6f\x68\x1c\x8a\xc8\x1d\x76\xd3\x21\x26\x10\x4f\xa3\xd1\x68\x10\x66\x7e\x29\xe0\xae\x3f\xc8\xa2\xb4\x8b\x43\x31\xf2\xbb\x83\x5e\x3f\x36\x4e\x5e\xcf\x4d\xb3\x81\x47\x93\x57\x04\x03\x2f\xee\xc7\x03\xaf\x7c\x8c\x07\xe9\xd0\x8d\x69\x84\x4a\x3f\x1a\x76\x3d\x3f\x88\x4c\x80\x87\x5e\x7f\x30\xf2\x28\x02\xc3\x51\xdc\xf7\xe3\x7e\x58\x0a\x38\x0b\xfb\x83\xb8\x3f\xc4\xb1\xeb\x67\x23\x2f\x4c\x52\xe3\x18\x67\x64\x4c\xc3\x6e\x0f\xd3\x88\x93\x9d\x65\xe4\x47\x59\x50\x0a\xd8\x1d\x11\x5c\xd2\x21\x56\xe8\x0d\x7a\x44\xba\x1f\x18\x31\xf6\x43\x77\x98\x78\xc3\x21\x26\x69\xef\x26\xc3\xfe\x30\x8e\xca\x27\x2f\xcd\xfa\xfe\x30\xc6\x05\x12\xf5\xfd\x81\xeb\x77\x8d\x80\xc3\xa4\x1b\xf6\xc2\x04\xcf\x08\x71\x96\xc4\x59\xd8\x2b\xc7\x38\x1a\x0e\xdc\x84\xa0\x8c\x84\x9f\x86\xde\x88\xfc\x65\x5c\xd2\x31\x59\xf7\xbd\x14\x01\xf7\x02\xcf\x8b\x82\x41\x39\xc6\xfd\x5e\x90\x45\xe4\x20\x82\x4b\x3a\x8b\xe3\x74\x94\x98\x17\x08\x81\x36\x24\x9f\x71\x92\xd3\x41\x18\xf8\x9e\x5b\xce\x2b\x5c\x37\xf0\xbb\xc3\x1e\xcd\xf9\x3e\x1a\xf8\x5e\x60\x24\xb7\xc1\x28\xea\x77\x47\x43\x16\xdf\x34\x1b\xb9\x84\xe6\x4b\x01\x0f\xe3\x8c\x1c\x77\x46\x48\xf8\x41\x9a\xf4\x7a\xa3\xa1\x91\x2a\xd2\x28\xe9\xf6\xbd\x10\x01\xf7\x03\x37\x49\xba\x7e\xf9\x50\xb8\xf1\x30\x89\x83\x88\xa6\x77\x21\xd8\xf7\x7c\xf3\x02\xf1\x42\x72\x32\xec\xd3\xb3\x97\x9b\xb8\x59\x9c\x75\xcb\x87\x82\x2c\xb6\xae\x9b\xf4\x90\xb9\x84\x71\xea\xfb\xe4\xc4\x66\x24\xb7\xcc\x83\x61\xc2\x21\x8b\x86\x7e\x3c\x24\x4b\xa4\x14\x70\x98\x12\x62\x4b\x47\x48\x15\x51\x32\x0c\xfd\x24\x4b\x8d\xbc\x22\x08\xc8\xaa\xf6\x70\xc8\xfa\x69\x3f\x1a\x04\xe9\xa8\x14\x70\x1c\xb9\x49\x37\x88\x42\xba\x40\x92\x51\x1c\xa4\x99\x99\xdc\xe2\xc4\x4d\x06\xc8\xb7\x83\x61\xb7\x3b\xf0\x93\x72\xb6\xd9\xf3\x86\x3e\xe9\x16\xe5\x6e\xdd\x2c\x4d\x08\xcd\x99\x00\xf7\xfd\xae\xef\x0f\xe9\x90\x79\x61\x8f\x70\x94\x60\x50\x0a\x38\xf1\x07\xa3\xac\x97\x50\x3e\x3b\x1c\x79\x6e\x10\x87\x66\x3a\xf6\x92\x38\x0e\x11\xe3\x01\x19\xb1\xc0\x75\xcb\xb9\xdb\xd0\x0f\x07\x3d\x32\x7f\xc8\x67\xdd\x51\xaf\x4f\x88\xca\xc8\xdd\xba\xf1\x30\xf2\x12\x1c\x63\x37\x8e\xc2\x41\x16\x94\x53\x45\xea\x91\x71\xe8\x79\x7d\x04\x1c\x67\xa3\xc8\xf7\x8c\x7b\x5e\x1a\xf7\xfb\x6e\xec\xe3\x5c\x44\x51\x1c\x25\xfd\x8a\x95\x37\x0a\xdd\x2c\x88\xe8\xd8\x45\x5d\xc2\x8e\x5d\x3f\x31\xd2\x31\x61\xda\x89\x4b\x7b\x16\xf8\x83\x41\xea\x0d\xca\x27\xcf\xeb\x27\xe1\xd0\xf3\x90\x6d\x0e\x7a\xa9\x9f\xba\x43\x23\xc6\x5e\x16\x90\xc1\x70\x29\x1d\x7b\xa1\x97\x90\xed\xbc\x62\x81\x84\xbd\x6e\x37\x44\x3a\x4e\x47\xbd\x2c\x1b\xf4\xfb\x26\xc0\x41\x38\x70\x07\xc3\x01\xf6\x2c\x23\xbb\x49\xd8\xab\x20\xb7\xa0\xef\x0d\xdd\xe1\x00\x27\x65\x18\x0d\xfb\x11\x59\xe0\x46\x7e\x9c\xa5\xbd\x24\x09\x91\x6d\x66\x41\xe8\xf5\x92\x61\x39\xb9\x45\x83\xfe\x70\x98\x84\x23\xba\x33\xc4\x01\xe9\xb1\x11\x70\xdc\xf3\xb3\x78\x44\x99\x55\x1a\x0f\xfc\x41\x2f\x29\x1f\x0a\x32\x12\x44\x60\xc9\x70\x81\x44\x44\xb4\x27\x12\x96\x91\x57\x74\x7b\x49\x14\x07\x74\xa7\x21\x12\x43\xd7\x1f\xc5\xe5\x54\xd1\x0b\x87\xbd\x6e\xcf\xa3\x92\x90\x37\x22\xab\xb6\x6b\x64\x9b\xbd\xe1\xb0\xeb\xfa\x74\xf2\xc8\x52\x09\x83\x7e\x56\x2e\xbb\xf5\xdd\x41\x36\x1a\x8d\x12\x2a\x45\xc6\x81\x97\xf9\x46\xaa\x48\xc2\x88\x70\xd8\x0c\x57\x5e\x9a\xf5\xfc\x01\xd9\x2b\xcb\x77\x90\x6c\xd4\x4f\x82\x11\xdd\x19\x08\xa3\x23\x0b\xcf\x2c\x57\xc4\x5d\xaf\xdb\x1b\xd1\x2d\x2c\x20\x5c\x9e\x10\x73\xf9\x92\x4e\xfc\x6e\x90\x0d\x71\x8c\xb3\xc4\x8f\x63\xaf\x6f\x1c\xe3\xd4\xeb\xc5\x44\x3a\xa0\x82\x0d\x10\x92\xaf\x2a\x01\x8b\x82\x48\x92\x26\xdd\x34\xc5\x05\x32\x4c\x33\x97\xc8\x51\x46\xb6\x39\x8a\xba\x69\x38\xea\x8e\xd8\xa6\x9b\xa5\x04\xff\x8a\xad\x69\xe4\xc6\x5d\x2a\x2f\x74\x7d\x8f\xec\xf2\x03\xe3\x92\x76\x09\x7d\x13\xa1\x10\x17\x48\x42\xf8\x67\xbf\x97\x94\xef\x20\x1e\xd9\x95\xfb\x3d\x37\x64\x8a\xbb\xbe\x4b\x38\xb2\x71\x49\x0f\xba\x9e\x3b\x08\x28\x3f\x26\x92\x40\xd8\xf5\xca\xc7\xd8\xef\xa5\x83\x6e\x77\x14\x51\xaa\x70\xc3\x6e\xda\x33\xf2\xe3\xc0\x1f\x26\x84\x14\x91\x2a\x88\xb4\xd5\xf5\xc3\x7e\xf9\x02\x09\x86\xfd\x6c\x90\xb9\x38\x14\x1e\x59\xd2\x83\x6c\x60\x9c\x3c\xb2\x28\xd2\xb8\x3b\xc4\x9e\x11\x19\xd2\x75\xd3\xb0\x9c\x8e\xc3\xe1\x30\x4a\x43\x2a\x78\x13\x96\x91\x85\xfe\x20\x36\x9f\x41\xba\x23\xbf\xdf\x47\x66\x35\x22\x02\x61\x37\x03\xf6\x5a\xc6\x2b\x46\xc3\x41\x4c\xc4\x2a\xe4\xb3\x49\x4a\x7e\x66\x46\x8c\xe3\x61\x18\x7a\xfd\x1e\x02\x0e\x93\xb0\x1b\xf5\xbc\x2e\x53\xa2\x9e\x95\xb8\xad\x16\xa3\x80\x36\xf6\x50\xb5\xa5\x41\xfb\x51\xf1\x50\x7d\xbb\x9a\x87\x2a\xdc\x32\xd4\xba\x3a\x30\x5c\x47\xdc\x7d\xf4\xd1\x55\xaf\x0e\x88\x9c\xd3\xcf\xb8\xc2\x3d\x18\x0c\x87\x7d\xd7\x72\x75\x30\x18\x90\x95\x9a\xd1\xed\x97\x30\x5d\x22\x77\xaa\xa2\x4b\x49\x23\x01\x91\x6d\x47\x84\x83\x61\x23\x19\x39\xac\xf6\x80\x93\x99\x4a\x26\x51\x38\x1a\x45\x01\xae\x82\x68\xe4\xa5\x64\x27\xa8\xab\xd5\x8f\x3c\x37\x8b\x7c\xca\x7c\x92\x34\x23\x3b\x54\x6a\xb9\x3a\xe8\x0f\xdc\x28\xee\x51\x82\xf4\xc9\x8a\x88\x87\xde\xa8\x66\x23\xde\xa8\x17\xa4\x7d\x4a\xf3\xe4\x68\xea\x0d\xc8\xe6\x67\x6e\x84\x1c\x75\xdd\x61\x4a\xc5\x20\x2f\x20\x8b\xc9\xeb\xf6\xdb\x9f\x2a\x1f\xe2\x62\xb5\xd0\xb0\x58\xce\xb5\x47\x7e\xdd\xf3\xec\xa1\x5f\xf7\x7c\x7b\xec\xd7\xbd\xc0\x1e\xfc\x75\x2f\xb4\x47\x7f\xdd\x8b\xec\xe1\x5f\xf7\x62\x7b\xfc\xd7\xbd\xae\x25\x00\x2c\xed\x20\x86\x87\x35\xda\x81\xd3\xef\x13\xfa\xbd\xe8\xec\x41\xc7\x00\xab\x7b\xd6\xea\xde\x84\x7e\xb7\x54\xf7\xb1\xba\x6f\xad\xee\x4f\xe8\x77\x4b\xf5\x00\xab\x07\xd6\xea\xc1\x84\x7e\xb7\x54\x0f\xb1\x7a\x68\xad\x1e\x4e\xe8\x77\x4b\xf5\x08\xab\x47\xd6\xea\xd1\x84\x7e\xb7\x54\x8f\xb1\x7a\x6c\xad\x1e\x4f\xe8\x77\x4b\xf5\x2e\x56\xef\x5a\xab\x77\x27\xf4\xbb\xc1\xac\xaf\x66\xd0\x63\x4a\x19\x26\xe0\x09\x25\x8a\x89\xc9\xe4\x96\x12\xc4\xb9\xd1\x1a\x17\x3f\x99\x6a\x0d\x29\x1d\x98\x6a\x0d\x29\x09\x98\x6a\xa5\x74\xfa\x4d\xb5\x52\x3a\xf3\xa6\x5a\x19\x9d\x75\x53\xad\x8c\x4e\xb8\xa9\xd6\x88\x4e\xb6\xa9\xd6\x88\xce\xb3\xa9\xd6\x3b\x3a\xc7\xa6\x5a\xef\xe8\xf4\x9a\x6a\x9d\xd3\xa9\x35\xd5\x3a\xa7\xb3\x3a\xd9\x68\xe8\xba\x5b\x33\x1d\xaa\x35\x9e\xb6\x90\x3e\xc6\x34\xf6\xb0\xcd\xdd\x7c\x17\x77\x70\x7e\x7d\xb6\x52\xa0\x68\xda\x0c\x0c\xc1\x8f\x63\xee\x6d\x20\x47\x8d\x26\x12\x87\x7f\x86\x25\xff\xb0\xfa\xf4\x52\x18\x13\x0a\x83\xf9\x17\xe8\x30\xd0\x6b\x7e\xa5\x08\xd4\x70\xa9\x82\xd1\x88\xab\x03\x54\x37\x8a\x50\x7d\x7d\x2e\xe2\x1c\x5f\x57\xf9\xe2\xb1\x62\x13\xa9\x46\xb9\x3f\x1e\x2d\x75\xae\x44\xc1\x3e\xa7\xc1\x7f\xe5\xe0\xd5\x13\x0c\x51\xcc\xc3\x01\x2b\xe5\x7a\x85\x72\x68\xf4\xfa\xb3\xa3\x16\xeb\xb6\x2b\xb1\x98\x28\x58\x4c\x8a\x58\x9c\x9b\xb0\x98\x14\xb1\x38\x97\xb1\x50\xcb\x75\x8b\xe5\x2c\x91\x8c\xe5\x29\xf5\xaa\x63\x6f\x37\x09\xbe\x9d\xcf\xa8\x57\x6f\x46\xbd\x7c\x46\xbd\x5a\x33\xea\x9d\x2b\x01\xbe\xcf\x79\x14\x6e\x29\x30\xf7\x84\xc5\xea\x96\x06\xc9\x63\x23\xac\x16\x43\x3b\xe6\xbe\x34\xa5\x1c\x5e\x5c\x39\xa5\xde\x44\x41\x63\x62\x40\xe3\xdc\x84\xc6\xa4\x80\xc6\xb9\x82\x86\x0a\x30\x2e\xc0\xf3\xe3\xd2\x39\x6d\x14\x3b\xbc\x8c\x95\x74\xf3\x69\xef\x9e\x95\x32\x9d\x2e\xe5\x5c\xdd\xaa\xe9\x26\x45\x26\xac\x64\x89\x4f\x38\x2d\xe9\xc5\x12\x23\x89\xcb\xdb\xf7\x62\x8a\x80\x17\x57\x63\xe0\xc5\x13\x5e\xb6\x12\x87\x9c\xd3\x4c\x60\xd0\xc6\xdd\xc9\x46\x15\xff\xe6\x2c\xe7\x31\x1d\x16\x88\xd9\x06\x70\x60\x26\xdd\x36\xd9\x04\x5a\x1c\x1e\x7b\xf3\x5f\x8e\xe7\xac\x3b\x6e\xbb\x06\x53\x9f\x88\x19\xac\x46\x03\xfe\x7e\x2c\x56\x8b\x05\x0b\x6f\x55\x2c\x70\x14\x6b\xe2\x40\x67\xa7\x88\x01\x9d\x89\x42\xfb\x96\x78\xf8\x62\x57\x24\x40\x37\xac\x65\xd8\xae\x47\x1a\x6a\x14\x13\x9f\x05\xc5\x07\x27\xbe\xd1\x39\x0b\x8b\x0f\x0f\x66\x3f\x3e\x5a\x87\xae\x7d\x22\x3c\x91\x3a\x13\x56\x07\x1e\xde\x4d\x4a\x82\xe9\x9f\x63\x34\x7d\x00\x3d\xa0\xed\xe0\xef\x21\xfd\x3d\xf8\xff\xdb\x7b\xda\xe5\xb6\x8d\x24\x7f\xc7\x4f\x81\xf8\xaa\x2c\x2a\xa6\x64\x34\x00\x92\x43\x29\xde\x3a\x5b\xb1\x2e\x39\xdb\xb1\x4b\x72\x2e\xae\x72\x39\x1b\x10\x00\x4d\x26\x14\xc9\x22\xa9\xc8\xb9\xc4\x5b\xf7\x1a\xf7\x7a\xf7\x24\x37\xdd\x33\x03\x0c\x80\xf9\x80\x64\x39\xbb\xb9\xdb\x6c\x65\x23\x62\x7a\xba\x7b\xfa\x6b\x7a\x06\x83\x1e\xf9\xb7\xbd\xfb\x82\xba\x23\x95\x89\x20\x49\x7f\x67\xe2\xef\x89\xfc\xdb\x5d\x92\x7f\x26\x6a\xf2\xcb\x80\x23\xe6\x95\x74\x21\xca\x4b\xef\x8b\xe2\x07\xe9\x4c\x55\xec\x97\x8d\xb5\x9a\xfd\x33\xed\x16\x89\x54\xcd\x3a\xce\xca\xfc\xb4\x9a\xea\x95\x88\x24\xcd\x59\x9d\xe6\xa2\x46\x73\x56\xa7\xb9\xd0\x69\xce\xba\xd0\x04\x31\xce\x42\x4e\x0d\xe2\x7b\x93\x42\x4c\x0a\x4c\x95\xfd\x9f\xa9\x4b\x2b\xb4\xc6\xa4\x6a\x44\x9a\xb1\x6a\x13\x65\xb8\xdd\x34\xc5\x38\x25\xb0\xa2\x39\xab\xd3\x5c\xd4\x68\xce\xea\x34\x17\x3a\xcd\x59\x45\xd3\x98\x75\xfa\xef\x21\x30\xf3\xfa\x94\xaa\x2f\x3d\xb5\x7f\x4c\xf5\x94\x9c\xf7\xe9\xdc\xf5\x19\xd5\x53\x0a\x06\x4f\xe7\xb6\x10\x2a\x2e\x4a\x40\x98\xd9\xa2\x64\x71\x61\xbb\x18\x81\xb4\x35\xab\xc6\x22\xc2\xc5\x0e\xf4\x70\x31\xeb\x12\xab\x2a\xb2\xf8\xff\x28\x11\x37\xcd\x1d\x91\xca\x66\x26\x82\xd9\x8d\x28\x3e\x9d\x77\xa1\xf8\x74\x6e\xa2\xf8\x74\x7e\x13\x8a\xdf\x77\xa2\xf8\xbd\x91\xe2\xf7\xf3\x4e\x11\x18\xad\xad\x79\x79\x85\xed\x8e\x8b\x45\x09\xb7\x10\x80\x56\xee\x68\x1f\x44\x45\xa5\xfb\x22\x3c\x12\x8b\x91\xce\xa2\xc2\xd5\x8d\xcd\xef\xd6\x39\x7e\x99\x74\xe5\x5e\xe9\xd3\xd9\x59\x9a\xac\x4d\xf6\x4d\xcb\xcd\x77\x26\xb6\x69\x02\x9a\x9a\xfa\xd0\xc2\x76\x6a\xea\x43\x6b\xe8\xc2\xd4\x87\x96\xd0\x85\xa9\x0f\x2d\xc9\x7b\xf9\x82\xae\xef\x58\xd8\xee\xef\xa0\x35\x7d\x2f\x9f\x11\x94\x10\x5d\xa1\x4b\x2e\x6f\x09\xcd\x7a\x13\x08\x62\xca\x4c\x3c\xd2\x96\x42\x66\xe2\x91\x76\x2f\x26\xa6\x3e\xb4\x79\x31\x31\xf5\xa1\x7d\x92\xd4\xd4\x87\xb6\x49\x52\x53\x1f\xda\x76\xe9\x09\x53\xe7\x96\x61\x1b\x02\x6d\xdc\xf4\x84\x1c\xd0\xb2\xee\x57\x33\x8e\x90\xc6\x0e\x3a\x89\xe3\xc6\x97\x95\x68\x7b\x86\x94\x19\x7c\x4d\xf6\x9f\xb6\xb2\x81\xaf\xcb\x62\x14\xbd\xaf\xc9\xee\x53\xc1\xec\xd7\xa1\xce\x6d\xda\x66\xb6\x89\x47\xdb\x65\x14\x04\x49\x44\x93\x36\x41\xa8\x08\x92\x78\x26\x92\x60\x2d\x12\x4c\xfc\x04\xb5\x7d\x49\x41\x30\xa2\x10\xdb\x26\x18\x55\x04\xa3\x99\x9a\x97\x7a\x04\xaf\x85\x57\x3f\x41\x6d\x27\x53\x10\x8c\x91\x60\xde\x26\x18\x57\x04\x63\xa4\x95\x4b\x82\xb1\xc7\x1d\x9a\x78\xb4\xbd\x4f\x41\x30\x41\x82\x45\x9b\x60\x52\x11\x4c\x90\x56\x21\x09\x26\x3a\xc1\xc2\x4f\x50\xdb\x2d\x15\x04\x07\x48\x70\xda\x26\x38\xa8\x08\x0e\x90\xd6\x54\x12\x1c\xe8\x04\xa7\x7e\x82\xda\xfe\xaa\x20\x38\xa4\x45\x45\x9b\xe0\xb0\x22\x48\xd9\xfb\x3b\x49\x70\x58\x5b\x44\xf8\x09\x6a\x3b\xb2\x82\xe0\x08\x09\xce\xda\x04\x47\x15\x41\x5a\x36\xc9\x39\x19\xe1\x5d\x49\xc0\xfe\x3f\x2f\xc5\xb9\xa5\x42\x0e\xb7\x70\x29\x0e\x60\x72\x2f\x6f\x36\x43\x64\x54\x87\x25\x0e\x6f\xfb\x5a\x1c\x33\x19\xf8\x87\xbc\x18\xe7\x64\xb5\xfc\xa5\xd8\x88\x2a\xbf\xf8\x61\x45\x1c\x1d\x4c\xe6\x3b\x4c\x50\xb0\x9e\x2f\x9e\xcf\x9e\x14\xd3\xd5\xa6\x90\xc7\xa9\x5b\x5a\xd3\xbe\x35\xd1\xde\xdd\xed\x56\xaf\xe3\xe8\x36\x2e\xe2\xf9\xb3\x5e\xc1\xa3\xf3\x59\xd6\x07\x39\xe2\x06\x11\x25\x0f\xe2\xe8\x9f\x5f\x37\x79\x3e\x55\x1a\x40\x74\xdd\xaf\x9b\xb0\x8b\xe7\xeb\xa6\xda\xb1\x86\xd6\xd7\x4d\xbc\xf5\x9f\x5f\x37\xdd\xf6\xd7\x4d\xa8\x95\x6e\x5f\x37\x19\x95\x53\xfb\xba\x49\x28\xc8\xf9\x75\x93\xf8\x8e\xb6\xe3\xd7\xdf\xf1\x9f\xfa\x7b\xa6\x62\x99\x1d\x4c\xd2\x6d\x31\x4c\x1a\x0d\x17\xf9\xa0\x09\xfa\xcb\xfa\xe7\x7c\xda\x78\x98\xcd\xd7\x5c\xd2\x7f\xc8\x27\x51\x1a\xab\xf4\x1b\x39\x14\x0d\x82\x31\xfa\x5b\xe7\xe7\xff\xc2\xa7\x53\xdd\xee\x04\xa2\xc3\x33\x27\x34\xf4\x12\x4e\x7b\xd6\xe9\x9a\x9f\x97\x7c\xf5\x47\xb3\xe8\xc9\x6c\x35\xcf\x0a\x9e\x78\x66\xed\x2f\x97\x5e\x9e\x40\xfd\xdb\xa5\xc1\xa8\x1f\x24\x63\xfe\x2f\xf4\x83\x38\xc6\x92\xfd\xfd\x00\xf8\x33\xfe\x28\x00\xed\xa8\xd1\x80\xf5\x83\x41\xc8\xe1\x22\x0e\x87\xa5\xfd\x87\x1c\x8e\x3f\x03\xfe\x2c\xd2\xe1\xc6\x58\xec\x9f\xc3\x71\x5c\x78\x5a\x31\xe2\xb8\xf0\x6c\x11\x20\x7e\x0d\x6e\xc8\xfb\xe1\x47\x0f\x09\xc7\x15\x73\x5c\x43\x0e\x3f\xe0\xf0\xf8\xb9\x40\x3c\xd6\x00\x63\xde\x31\xe2\x8d\xc0\x1b\x79\x5b\x30\xe4\x9d\xf0\x5c\x7c\x42\x57\x0b\xe8\x80\xc8\x09\x6f\xc4\x13\xb6\x43\x04\xe4\x1d\xb9\xc7\x07\x09\xef\x18\x8f\x34\xc0\x88\xb3\x13\xf1\x46\x3c\x8e\xcf\xdb\x82\x88\x0f\x23\xe2\x9d\x01\xd9\x69\x1f\xf7\x6b\xca\x35\x32\xcb\x35\xaa\xcb\x15\xb9\x40\x39\xe2\xb8\xf1\xe8\x38\xff\x0f\x27\xa6\x71\x2b\x09\xe3\xb0\x90\x5b\x62\x28\xd4\xb9\x8c\xa5\xe0\x90\x2b\x04\xe0\x50\xfa\x70\xf1\x14\x38\xca\x03\x05\x4c\xdc\xc7\x75\x45\xa0\x42\x51\xc0\x28\xbf\x78\x24\x04\x3b\x18\x34\xe4\x95\x84\x52\x5b\x03\xa1\xfd\x44\xa7\x80\xaa\x41\xd3\x88\x51\xa5\x43\xa1\xf6\x81\xae\x43\x54\x01\xda\x03\xda\x05\xea\x10\x05\xab\xb2\x9a\xda\x8d\x50\x97\x17\x97\x8b\x94\xae\x49\xc1\xa4\x72\x3b\x9b\x4f\x77\x26\x21\x3e\xfe\xe6\xd5\x5f\xcf\xbf\xfe\xe6\x54\xdc\x29\x85\x12\xe3\xc8\x69\xf0\x28\x21\x86\x16\x29\xd5\x44\xd2\x95\x96\x0a\x52\x9d\x91\xb4\x5e\x12\x08\xd3\xe9\x9f\x3f\x7e\xf1\x9a\x67\xd3\xe9\x32\x97\xb5\xd1\xd7\xa4\x52\x71\x9f\x86\x81\x0f\x84\xff\xeb\xcb\xba\x3e\x1b\x29\x65\xf8\x3e\x3c\xa2\xc5\x08\x8b\xc2\xb0\xdf\x6c\x53\x6b\x05\x01\x62\x00\x88\x6a\x00\x1c\x24\x6a\x81\xc4\x1a\x48\xbb\x35\xd1\x5b\x0d\x04\x06\x75\x02\x91\x81\xc0\xb0\xce\xa4\x09\x64\xd4\x18\x87\x81\x10\xab\x31\xd2\x46\x31\x6e\x52\x69\xa3\x48\x75\x10\x13\xc0\xa4\x29\xad\x36\x48\xd6\x20\xd3\x02\xc8\x9b\x43\x69\x83\x14\x1a\x48\x9b\xc2\xb4\xce\x65\x64\x93\x84\xb9\x37\x30\xaf\x3e\x22\xe6\x21\x10\x33\x8f\x55\x25\x4d\x22\x06\xbb\x60\x6e\xbb\x19\x32\xaf\x61\x8e\x98\xcb\x30\x19\xf3\xea\x7b\xcc\x3c\xfa\x4e\x99\x57\xdf\x13\xe6\x35\xde\x8c\x79\x35\x9e\x33\x8f\xd5\x14\xcc\x6d\xdd\x53\xe6\x75\x10\xb0\xaa\x4b\x46\x09\x30\x0b\x32\xd2\x5a\x2d\xca\x8c\x6b\x20\x46\xea\x49\x1d\x8b\x69\x8c\x03\x1d\xc4\x68\x13\x3a\x9f\x86\xf6\x51\x9d\x0d\x87\x6f\x80\xc3\xfc\xc7\x4d\x4e\xad\x81\x02\x1c\x1a\x9d\xd4\x07\x13\xd9\x02\x05\xd8\x34\x9a\x37\x07\x13\xda\xe2\x04\x58\xad\x66\xaa\x01\xd8\x22\xa6\x4b\x14\xc0\xbc\xa2\x88\x98\x57\xf5\x31\x73\xab\x2d\x69\xa0\xb0\xc5\x0a\x97\xb8\x87\xcc\x65\xc2\x23\xe6\x51\x06\x63\x1e\x49\x8e\x99\xd7\xb4\x52\xe6\x56\xe8\x84\x79\xfd\x28\x63\x5e\x9d\xe7\xcc\xa5\xd2\x82\x79\x5c\x68\xda\xd4\x68\x54\xdb\x24\xf2\x64\x19\xdc\x89\x59\x12\x82\x35\x82\x48\x18\x6b\x9a\x51\x2a\xd0\x16\x41\x14\x91\xd0\x44\x24\xa9\x13\x31\xc2\x0c\xea\x78\x8c\xcc\x0c\xeb\x78\x8c\x30\xa3\x0a\x06\x42\xd7\x0c\x6b\x26\x31\x6e\x92\x00\x6b\x10\x39\xd2\x92\x28\x5b\x14\x91\x84\xc0\x9a\x6e\x48\xc1\x1a\x00\x72\x57\x32\x50\xe8\x2c\x80\x35\xd5\xa8\xb4\x62\xcd\xbb\x9c\xc2\x04\xe6\x19\x45\xc4\x5c\xd2\x8e\x99\x5f\x98\x09\x6b\xe8\x1d\xac\x61\xc4\x29\xf0\x21\xf3\x18\xea\x88\xf9\x0d\x95\x31\x8f\x52\xc6\xcc\xa1\x94\x94\xb9\x7d\x69\xc2\xfc\x4a\xc9\x98\xdf\x55\x72\xe6\x31\xe2\x82\xf9\x7d\x69\xca\xdc\x16\x04\xce\x54\x16\xa0\x83\xdb\x43\xd4\xc1\x99\x20\xee\xe0\xf8\x90\x74\xb0\x67\x18\x38\x5d\x1f\x86\x3e\x97\x84\x91\x27\x18\xea\x29\xb8\x19\xc3\xd8\x17\x2e\x21\xf5\xf9\x3d\x4c\x3a\x44\x4b\xc8\x7c\x81\x0c\xf2\x0e\xc1\x12\x8a\x0e\xa1\x0c\xa6\xa1\xdf\x45\x21\xf4\x85\x0a\x00\x9f\x87\x42\xd4\xc1\x41\x20\xf6\x78\x19\x24\x5d\x02\xdb\xa0\x43\xd8\x81\xa1\x33\xba\xc1\xa8\x43\x58\x02\xd6\xc1\x17\x61\xdc\xc1\xeb\x21\xed\x10\x4d\x61\xe2\x8b\x60\x90\xb9\x42\x18\xe4\xbe\xb0\x50\x74\x08\xa3\x30\x6d\x44\xa8\xeb\xa4\x2a\x10\x26\x96\x60\x64\x66\x39\xaa\x49\x05\xac\x29\x8a\xc0\x6d\xc2\x9e\x68\xed\xa1\xa1\x7d\xd0\x50\x4e\x62\x49\x4c\x94\xd0\x4c\x34\x46\x35\x08\xff\x74\x6c\xcf\x4d\x2a\x2a\xb6\xcc\x44\x8d\xd4\x96\x95\x54\x5c\x24\x96\xb4\xa4\x92\x66\x62\xc9\x4b\x94\xb4\x6c\xa9\x09\x61\xb0\xa4\x25\xb2\xaf\x59\x02\xae\xe1\x01\xf3\xb1\x1f\x31\xbb\xa1\xc4\xcc\x67\x28\x09\xf3\x29\x7a\xc0\xdc\x83\x1f\x32\xb7\x29\x8d\xb4\xf6\xc4\x92\x7e\x98\x45\x37\x66\x2e\xd1\xa5\xcc\x67\x5e\x13\xe6\x76\x82\x8c\xb9\x4d\x27\x67\x3e\xc3\x28\x98\xcf\x09\xa6\xcc\x67\xe2\x10\x7a\x6d\x1c\xc0\xe3\xae\x10\x79\x2c\x14\x62\x6f\xc8\x80\xc4\x69\xa9\x30\xf0\x3a\x3c\x0c\xbd\x51\x03\x46\xfe\xed\x3e\x97\xc0\x61\xec\x0d\x19\x90\x3a\xbc\x11\x26\x9e\x70\x01\x99\x37\x6a\x81\x1e\x0e\x12\x5b\x82\x61\x37\x3b\x98\x7a\x43\x92\x4c\x2d\x9c\xc3\x04\xa7\x5f\x41\xe4\x0f\x2d\xb1\x23\x72\x40\xe2\x71\x6b\x18\x78\x63\x0b\x0c\x9d\x0e\x0c\x23\x6f\x6c\x03\xe6\x09\x3e\x30\xf6\x7a\x20\xa4\x9e\x30\x00\x13\x6f\x0c\x84\xcc\x1b\x0a\x20\xf7\xc6\x23\x28\x1c\xc1\x0e\xa6\xf5\x68\x74\x9d\xfc\x81\x85\x82\xa4\x39\xb6\xa8\xec\x93\x73\x6d\x49\x25\x14\xd3\x89\x25\x91\x50\x34\xec\x9b\xa5\x66\x23\x1a\xd4\x25\x62\xce\x21\xaa\xcd\xb8\xc4\xb2\xb5\x51\xa5\x7f\xf6\xf9\x93\x85\xae\x0c\xa2\xd2\xad\x39\x7f\x10\xed\xe6\xdc\xa1\x12\x9f\xed\x0d\x4a\x25\x9e\xc4\xb2\xa7\x21\xbd\xd4\x92\x39\x28\xf3\x36\xe7\x0e\x95\x82\x2d\xe3\x77\xea\x17\x98\x7d\x78\x11\xf3\x31\x1f\x33\x9f\x00\x12\xe6\x56\xf1\x80\xf9\x86\x30\x64\x56\xfb\x19\x31\x9f\xf1\x31\xe6\x92\xdf\xb8\x4e\xdc\x96\x44\x38\xac\x63\xc2\x5c\xda\xcb\x98\xcf\xfa\x72\xe6\xb6\xdf\x82\xb9\xdd\x6f\xca\x7c\x1e\x02\xa1\xc7\x45\x00\x3c\x5e\x08\x91\xd7\x0d\x21\x76\xcd\x14\x4e\x0b\x87\x81\xd7\x45\x60\x18\xfa\xf4\x04\xa3\xb0\x93\xa5\xbb\x4d\x0d\xc6\xde\x70\x01\xa9\x37\xe0\xc1\xc4\x13\x33\x21\xf3\xc6\x0d\xc8\x3d\x61\x09\x0a\x47\x5c\x82\xa9\x33\x6c\x88\xec\xc1\x3d\x06\xf0\xfa\x25\x44\x76\xc7\x84\xd8\xe3\xf6\x90\x78\x0c\x1f\x06\x5e\xdf\x81\xa1\x3f\xba\x8d\x1c\xe1\x0d\x98\xdf\x79\xc6\xce\xf8\x01\xa9\x37\xfe\xc1\xc4\x1b\x44\x21\x73\x06\x11\xc8\xbd\x51\x0a\x0a\x4f\x98\x82\x69\x3d\x8e\x5c\x2f\x79\x30\xc6\x14\xc5\xaf\xed\x0d\x49\xc9\x8d\x31\x65\x38\xd2\x8e\x6b\x18\x33\x06\x09\x40\xfb\x29\xc6\xbc\xa1\xcc\xf9\x98\x39\x6f\x20\x04\x36\x80\x91\xbe\x5d\x63\x9d\x34\xc1\x91\x32\x54\xfc\x59\x72\x86\x6a\x84\xcc\x9c\x38\x48\x06\xcd\x2c\x64\x35\x80\xd0\x9c\x36\xd8\xdf\x83\x94\xca\x61\xe6\xa4\xa1\x12\x8e\x79\xcf\xc1\xd5\x1f\x98\x47\xb8\x11\x0b\x6d\x86\x13\x33\xb7\xe1\x24\xcc\x65\x38\x03\xe6\xb1\x8b\x21\xf3\x48\x6d\xc4\x3c\xa6\xc7\x98\x47\xb5\x63\xe6\x7a\xd5\xe1\xd4\xe9\x84\xb9\xad\x36\x63\x1e\xab\xc9\x99\x47\x73\x05\x73\x1b\xee\x94\xb9\xcc\x1e\x42\xa7\xdb\x02\x84\x56\xbd\x42\xe4\xf3\x69\x88\x7d\x3e\x09\x89\xc7\xab\x61\xe0\x73\x0a\x18\xfa\x22\x07\x8c\x3c\xbe\x5d\xce\x7b\x56\x35\xc2\xd8\xe7\x40\x90\x7a\xe2\x23\x4c\x7c\x11\x04\x32\x67\x84\x82\xdc\x17\x61\xa0\xb0\x4f\xce\x53\x4f\x84\xa0\xfc\xc0\xad\x2b\xf0\x58\x1a\x44\x1e\x4f\x87\xd8\xe7\xcc\x90\xf8\x9c\x15\x06\xbe\x50\x35\xb4\x87\x22\x18\xf9\x82\x05\x30\xb7\xbb\x8c\x7d\x0e\x0f\xa9\x35\x58\xc0\xc4\xe7\xcb\x90\x79\xc2\x05\xe4\xce\x60\x09\x85\x2f\x94\xc1\xb4\x11\x70\xae\xf7\x4a\x42\x74\x32\x45\x11\x85\xd3\x94\x17\x88\xbe\x51\x68\xcb\x0c\x54\x7b\x64\xc2\x9d\x54\x12\x31\xe2\x1f\xe8\xe3\x31\x65\x05\x65\x2b\x33\xa5\x04\xda\xb8\xac\xb3\xa2\x31\x1b\xd0\x98\x62\xa6\x64\x40\x9e\xa2\xb1\x1c\x8a\x20\x03\x35\x65\x00\x9a\xac\x98\x29\x01\x28\xd1\x32\x53\x12\x20\xc7\xca\x4c\x09\x80\x26\x65\xd3\x48\x9d\x4a\x02\xe6\x56\x52\xc4\x2c\x23\x8a\x99\x4b\x3b\x09\x73\x8d\x67\xc0\xdc\x56\x37\x64\x6e\xcb\x18\x31\xbb\x3c\x18\x73\xd9\xc5\x98\xd9\xed\x39\x65\x6e\xd5\x4f\x98\x5b\x87\x19\xb3\xd8\x54\xce\xdc\x2a\x2a\x98\xcb\xa6\xa6\xcc\x6d\xca\x10\x7a\xfc\x08\xc0\x63\x7c\x10\x79\x3c\x15\x62\x87\x01\x42\xe2\xf4\x53\x18\x78\x5c\x11\x86\xa1\x27\x06\x8d\x9c\x3e\x57\x66\xb0\x16\xde\xc7\xd6\xa8\x9d\xda\xbc\x15\x26\x9e\xd0\x06\x99\x23\x2e\x42\xee\x89\x21\x50\x78\x7c\x16\xa6\xce\xe0\x86\x33\xba\x85\x71\x70\x9a\x12\x44\x4e\xa7\x85\xd8\xe3\x97\x90\x78\x1c\x13\x06\x0e\xcf\x84\xa1\x27\xd6\xc0\xc8\x1b\xac\x3c\x9e\x04\x63\x8f\x8f\x42\xea\x08\x00\x30\x71\x46\x2d\xc8\x9c\xa1\x05\x72\x9b\xff\x43\xe1\x73\xe1\x69\x3d\xf4\x5c\x7f\xea\x36\xd8\x88\x62\x35\x09\xc1\x30\x75\xcb\x54\xc3\x30\x69\x4b\xa4\xa6\x6e\x49\x99\xe4\x98\x5a\x07\x96\xe1\x0f\x05\x4a\xc3\x1c\x5d\xa5\x4c\x60\x99\xb7\xc4\x00\x4c\xd3\x74\x39\x76\x30\xcc\xd1\x95\x91\x83\x61\x8e\xae\x06\x61\x5a\xaa\x6b\x79\x9c\x61\x9a\x96\xbb\x2e\x86\x29\xba\x94\x9b\x69\x91\xae\x65\xbe\xed\x91\xba\xc4\x00\xcc\x72\x28\x84\xb9\xf4\x1b\x33\xd7\x18\x13\xe6\x30\x9c\x01\x73\x09\x6f\xc8\x5c\x23\x19\x31\x9b\x78\x18\x73\x98\xd5\x98\xb9\x54\x9d\x32\x97\x46\x26\xcc\x61\x08\x19\xb3\x99\x79\xce\x5c\x96\x5c\x30\xb3\xc5\x4e\x99\x43\xc9\x10\x3a\xb5\x0c\xe0\x74\xd7\xc8\xe9\xaf\x10\x3b\x7d\x05\x12\x97\x3b\xc0\xc0\xe9\x4a\x30\x74\x3a\x04\x8c\x5c\x11\x41\xce\x37\xc6\xa6\xb1\x33\x5a\x40\xea\xf2\x18\x98\x58\x82\x06\x64\xd6\x83\x5d\x4e\xcf\x85\xc2\x19\x14\x60\x6a\x8d\x88\x7c\xb6\x75\x31\x0a\x4e\x47\x84\xc8\xed\xdd\xb1\xc5\xd2\x20\x71\x3a\x1a\x0c\x5c\x2e\x0c\x43\xab\x1f\xc2\xc8\x19\x19\x80\x39\xbd\x1f\xc6\x4e\x5f\x84\x94\x59\xd7\xc7\x4e\x53\xc8\x5c\xd1\x01\x72\xab\x17\x43\xe1\x8c\x1c\x30\xd5\x82\xc3\xf5\x36\xc9\x43\xfc\x0a\xc2\xfc\x9d\x52\x64\xde\x24\xd7\x5e\x6e\xb4\xc3\xb1\xe8\xd7\x0e\xc4\x12\x9f\xa1\x69\x20\xf0\x45\x46\x3e\x86\x65\xa3\x29\x08\x4b\x4e\xcc\xf3\x0c\x0b\xcd\xfc\x8f\xcb\x71\x9b\x42\xb0\xe0\xd3\xd4\x34\x29\x91\x1a\xf8\xcc\xe4\xc7\x1e\xed\xf0\x6b\xd9\xec\xae\x3e\xa3\x31\x4c\xa2\x92\x89\xc8\xfd\x81\x25\xb3\x7f\x80\x09\x2e\x99\x4a\x98\xc8\xa5\x7f\x09\x13\xbb\x74\x2d\x9f\x27\x2e\x61\x4b\x98\x81\x5d\xac\x12\x62\xe8\x1d\xf3\xc8\x62\x5a\xb2\x99\xb9\x24\x2a\x61\xc6\x36\x2d\xc9\xf6\xd4\x6e\xa5\x12\x62\xe2\xb2\x47\x09\x93\xb9\xbe\xf4\x0a\x73\x97\x19\x49\x98\xc2\x65\xa2\x12\x66\x6a\xf7\x50\x95\x11\x1b\x1d\x1b\x5c\x23\x80\xc8\x22\x64\x88\x6d\x16\x07\x89\x8b\x59\x18\xb8\xd4\x02\x43\x97\x30\x60\xe4\x18\xa2\x2d\xfe\x8e\xed\x2a\x84\xd4\x65\xa9\x30\x71\xc6\xc3\xcc\xe5\x51\x90\xdb\xed\x1b\x0a\x9b\xd1\xc1\xd4\xef\x5d\xd5\xe2\xc6\x0a\x01\xee\x58\x00\x91\xdf\xe0\x20\xf6\x79\x1f\x24\x4e\xef\x83\x81\x3f\x08\x28\x65\x3b\x87\x3b\xf2\x07\x25\x60\xfe\xe0\x06\x63\x7f\x34\x50\xe6\xe0\xf2\x32\x61\x14\xd6\xd6\xcc\x17\xd6\x84\x61\x38\xf8\x2c\x7c\x11\x47\x19\x09\x51\x69\x5d\xf4\xa4\xd7\x35\x78\x9e\x6e\x7f\xde\x06\xbb\x59\xba\x0b\xb6\xc5\x02\xab\x0f\x61\x3d\x22\xac\x5e\x10\xcc\x97\x6b\x75\x4d\x44\x59\xd1\xe0\xf9\xa3\xf3\xc6\xc5\xc5\xd5\x87\x89\x7d\xed\xe0\x3f\x5d\xa0\x28\x7f\xd0\xdf\xf2\x47\x5f\xef\x18\xca\xa7\x02\x40\xfc\x50\x7f\xe3\x8f\xbe\x36\x9e\x26\xe7\x5a\x55\xa5\xaf\x9e\x9c\x8b\xc2\x58\x81\x28\xfc\xe2\xbe\xa3\x0a\xa1\xcb\x0b\xaa\xc4\x0f\xad\x4a\xca\x4d\xaf\xa8\x72\x97\xd6\xc3\xe2\x4a\xaa\x04\x18\xff\xfb\xd8\x08\xa1\xea\xea\x61\xe9\x25\x63\x59\x3d\xa4\x21\x54\x34\x18\x62\x11\x8c\x6d\x90\x66\x19\x07\xc4\xea\xaf\xbb\x15\x96\x65\x31\xe2\xc5\x32\x71\xfa\x9d\x62\xea\x1f\xd3\xdd\x21\x83\xa1\xfd\xee\x90\x0a\xdd\xcb\x15\x22\xe4\xf4\xb0\xfe\xf2\x41\x00\x86\x1a\xa5\x92\xae\x28\xcf\xdf\x53\xa3\x7b\x53\xf5\x97\xe5\xf8\xf0\x3f\xbd\x18\x38\x96\xaa\x89\xea\xf0\xed\x07\xf7\x5a\x88\x0d\x05\x4b\x1f\x6d\xb7\xc5\x05\xde\xa3\x06\xc3\x60\x7b\x39\xe1\x48\x0c\xe2\xe7\x0d\x4f\x79\x43\xa9\x82\xea\xb7\x5d\x28\xcb\x73\x02\x12\xa2\x51\x3f\xf0\x5e\x93\xf2\x97\xfd\x8a\x95\x13\xaa\x38\x25\xf9\xb1\x54\xd1\x56\xd8\x25\x2f\x6f\x24\xd2\xb7\x92\xa9\x1b\x5c\xdd\xc2\x2d\xe2\x9c\xaa\xa2\x3c\xd4\x8a\xa0\x94\x78\x6d\x28\x85\x41\x25\xcc\x68\x50\x91\xa1\x92\xaf\xc1\x6a\xa2\xc4\x6e\x35\x75\x3a\xd3\xcd\xea\x82\x02\xcc\x02\xcb\x1b\x46\x8c\x3c\x03\x29\x9b\x3b\x0a\xe1\xbc\xe9\xcd\x83\x07\xe2\x6e\x88\x90\x0a\x38\x2a\xe3\xea\xf5\x38\x8b\xd2\x06\xb1\x2e\xa2\x92\xc0\x3e\x37\xa1\x88\xbd\xa5\x1a\x8f\x64\x5b\x73\xfe\x60\xe8\xba\x47\xa2\xc9\xde\x66\xfe\x6e\xd6\x9d\xbf\x84\xca\x77\x56\x4c\xee\xd7\xb8\xe4\x58\xb0\x59\xf0\xca\xff\x8c\x12\x0b\xc3\xfb\x06\x8e\x3b\x55\xf6\xc7\x01\xcc\x97\x59\x11\x14\x69\x36\x93\x66\x17\xcc\xb9\x36\xd7\xeb\xc5\xbc\xc8\x51\x97\xe9\x12\xab\x82\xa5\xcb\x9c\xff\x94\x75\x19\x29\xbc\xf7\xcd\x15\xb0\x67\xca\x7a\x83\x8c\xf7\x9c\x14\xc1\x64\xb3\xfa\xb9\x58\x62\x65\xba\x55\xc0\x44\x51\xe0\x6d\xb0\xcd\xd2\x85\x40\x2f\x50\x6e\xcd\xd8\xae\x66\xf3\x0c\x2f\x0e\x5c\xac\xae\xb6\x84\x1a\xf1\xf2\x4e\x1c\xed\xe5\x96\xf7\xbf\xe2\xa1\x7a\x75\xb9\x13\x0c\x6e\x79\x7c\x6d\x63\x91\x82\xa6\xf2\x9a\xbd\xea\x07\x56\x94\xa7\x0a\xf2\xd5\x23\x0c\x28\x31\x98\x24\x57\xb3\x5c\x10\x96\x3b\x72\x1b\xae\x44\x4b\x41\xac\xfa\x9b\x62\x56\x6f\x2e\x94\xf8\x45\x80\xda\x8f\xcd\xaa\xb2\x8d\x63\xa4\x8f\x63\xf4\x56\x16\xf6\xfc\x5d\x7f\x44\x97\x02\xb4\xae\xda\xf9\x60\xaa\xba\x49\x85\x2f\xb9\x6a\x7e\x29\x36\xdb\xc2\x1e\x05\x39\xc0\x79\x23\x10\xd6\x1e\x75\x9a\x20\xc0\x31\x41\x54\xd8\x74\x89\x6d\xdf\xc0\x00\x0d\xfa\x6d\x6b\x28\xe6\x12\x97\xc5\x32\xc3\xca\x68\xd7\xb8\x0a\x50\x56\xac\x5d\x9d\x94\xfd\x2a\xe0\x7e\x3d\xe4\x5b\x4b\xe8\xe6\xc5\xa7\xa0\x5a\x49\xc4\x55\xbb\xf7\xc4\x4d\xb9\xaf\x04\x69\x4a\x3a\xfe\xad\xd8\xe9\x79\x5a\x9d\xb9\x05\x21\xd5\xae\xc6\x6a\xea\x40\x80\x6d\x9a\x60\x74\x73\xd6\xb1\xa9\x3a\xf8\x7c\x37\x4f\x17\x7a\xe9\xab\x3a\x4c\xf1\x3e\x9b\xa5\xcb\x77\xc5\xb3\xb3\xaa\x2c\xaa\xa8\x3c\xc6\x93\xba\xa9\xf8\x5f\xd3\xa4\xcd\x7d\xc4\xfd\xd4\xb4\x62\xe5\xff\xd8\xfa\x9c\x3d\xd3\xfb\x44\x44\x27\x96\xff\x74\xeb\xc3\x04\x6f\x9c\x0a\xfe\xdb\x91\x37\xea\x33\x90\xff\xec\x5f\xf7\xaa\x36\x51\x3e\x8c\x3c\x4a\xfc\x29\xbc\x8a\xfe\xf6\x5f\xdb\xb6\xed\x98\x4f\x10\x3a\xdb\xb5\x17\xa5\x61\xe8\x76\x62\x81\xdd\xd4\x61\x37\x12\xd6\xc8\xe4\x69\x31\xdf\xee\x8a\x45\x69\xc5\x66\x8c\x53\x1a\x7c\xb7\xd4\x82\xb9\x03\xf4\x14\x27\x5a\x51\x6a\x8d\xc7\x1c\x9e\x10\x48\x6e\x7f\xa8\xc2\x35\x26\x92\xe5\xd2\x85\x7e\x53\x59\xed\xb7\xdd\x02\x76\xc3\x91\x36\x36\x49\x35\x3c\x69\xa1\xd8\x98\xfa\x03\xf8\x77\xcb\x7c\x15\x6c\xaf\xd2\xb5\x48\x3f\x16\xe9\x76\x27\x8c\xa1\x1d\xc2\x77\x6e\x95\x35\x98\xad\x2b\xcc\xe5\xf8\x3b\x83\x0d\x53\x45\xf1\xeb\xba\x7a\xcb\x35\x6e\xcd\x05\x6f\xe2\xea\x37\x09\x29\x9e\xd0\x65\x58\x91\xed\x02\x9e\xbc\xb4\x22\x70\x19\x72\xdd\x2a\xab\x85\x5c\xbb\xce\x6a\x53\x06\x9f\xde\x45\x09\xe8\x21\x16\x80\xd6\x5b\xe6\xbf\x58\x1a\xb4\xba\xd1\x43\x63\xd5\x68\x3e\x10\xb4\x40\xb9\x08\xd8\xac\xb6\xdb\x2a\x4d\xa7\x9a\x87\x94\x10\xd3\xb2\x54\xf4\x28\x27\xaa\x4a\x70\x3d\x35\x5f\x5d\xa4\xdb\x9f\x6b\x2e\xab\x6c\xb7\xd7\xab\x99\x28\x3a\xa2\x9a\x5d\x7f\xa8\x0d\x1d\x9d\x16\xb1\x68\x22\xa8\x99\xec\x0f\x64\xb3\x9f\x19\x0d\x1f\xdb\x30\xa3\x12\x98\x25\x94\xf2\xbb\x16\xdb\x67\xcf\xba\xb3\xbd\xb1\xb3\xbd\x70\xb3\xbd\x70\xb0\xbd\xe9\xc0\xb6\xb3\x88\xf4\x56\x55\x91\x16\xdb\x1f\xdd\xea\x48\xfb\x8a\x30\x0b\x5c\xbb\xe2\xfd\x4e\x2f\xc5\xfc\xd5\x93\xf3\x43\x99\xa0\xd5\x6a\x31\xf7\x83\x6c\xfa\xce\x50\x5c\x7b\xbd\x48\x91\x09\x8e\xa4\x89\x45\x26\x5c\xbd\x8a\x8e\x09\x51\x59\xd9\xb9\xbd\x51\x53\x2f\xba\xcd\xdb\x8d\x15\xb7\x5f\x6d\xe6\x7c\x94\x07\xd7\xdb\x22\x12\x9d\x6a\x1b\x45\xfa\xa3\x3f\xcf\x76\x91\xdc\x88\x40\xb6\xe7\x54\xa1\x34\x2b\x8c\x55\xfd\xf3\x62\x8b\x25\x7d\x51\x2f\x42\xaa\x4f\x84\x8e\x57\x9b\x5e\x75\xcf\xba\xbc\x38\x5e\x91\x3e\xdc\x2e\xe6\x59\xd1\xc3\x82\xb9\xfb\xad\xbb\x30\x4a\xb4\xd1\x0d\xd1\x62\xc1\x5a\x07\xda\xf8\x86\x68\xb1\xac\xef\xbe\xfd\x22\x8d\x1b\xaf\x3d\xb8\xfc\x0e\xf5\xce\x5a\x0f\xab\x64\x0e\xf5\x35\x47\x87\x0e\x71\x07\x0a\xb7\xb3\xa6\x41\x5a\xd7\x64\x2e\xba\xee\xf0\xa1\x03\x05\xf3\xac\x07\xe3\xe8\xd6\xa6\xbd\x7f\x90\xb0\x5a\x46\x97\x5b\x08\xae\x15\xae\x6b\x86\x58\x5b\x88\xab\x07\xda\x12\xca\x59\x3f\xbf\x84\x6a\x94\xd0\xd7\x0a\xb3\x1f\x0d\xa2\x7e\xa3\xaa\xbe\x56\xdc\xfd\x68\x90\xf4\xab\xaa\xee\x47\x83\x61\x5f\x16\x7b\x3f\x1a\x02\x16\xdc\x4f\x3e\xaa\xe0\xfe\x1f\x59\x69\xff\x93\xd5\xc3\xff\xfb\x54\xb6\xa7\x9b\x0a\x38\x1f\xf9\xed\x96\xb8\x7f\xcc\xd5\x5e\x55\xad\xe7\x3f\xb4\xb6\xd7\x71\xe4\xac\x80\xdf\xf6\xe5\xf7\xc3\x24\x58\xa6\xdc\x87\xd6\xba\x97\x3e\xd0\xd9\x40\x10\x64\x43\xfc\xf7\xb7\x0f\x26\x34\x8f\x78\xd4\x28\xaf\xb0\x31\xa1\x79\x3d\x4c\x90\x0f\x62\x8a\x23\x3a\x94\x3f\x90\x7f\x43\x66\x50\xa1\x16\xe8\xe5\x76\x0a\x0f\x4f\x3c\xe7\x0e\x96\xc5\xd5\xe2\xd7\x40\xf8\x5a\x6e\x22\xac\x07\x14\x19\x6d\xe4\x6d\x1e\xcb\xcb\x8b\x49\xb1\xf9\x10\xd0\xad\x52\x74\xab\x0a\xfe\x11\x47\x94\xce\x1f\x3a\xbb\xe0\x5d\x55\xd8\x03\xff\x6b\xea\x50\xef\x5c\x8f\x6e\x6d\x00\x25\x97\xf7\x95\x5c\x54\x44\x50\xe2\x51\x13\xb3\x78\xfb\x17\x46\x61\x4c\xab\xb2\x24\x1c\x84\xc3\xb0\xbe\xdf\xa9\x24\x2d\xf6\x16\xe7\xb5\x8c\x0a\x47\xd8\x47\xae\xcd\x73\x98\xbc\x5f\xcb\x70\xab\x27\x35\xe3\x68\x1f\x06\xf5\xdb\xb7\xf5\x95\x79\x53\x53\x8f\xe7\xbb\xab\x39\x37\xd0\x6f\x5f\xbc\xda\x12\x06\x9f\x62\xd4\x45\x29\xd2\x40\x3e\x70\x53\xe2\xfa\x45\xb9\x1c\x90\x60\xe4\x4c\x92\x4e\xf9\x4c\xc2\x5b\xde\xf1\x05\xf6\xf2\xdd\x2d\x08\x9e\x50\x15\x28\x78\xa9\x82\xc3\xe5\x6a\xd7\xb3\x4a\x95\xbb\x2e\x6f\xf7\x65\xaa\x74\x27\x8b\x10\xe8\xdf\x4a\xe9\x1e\x1b\xc1\x84\x60\xff\xa6\x84\x6c\x48\x49\xa5\x64\xa4\x60\x94\x35\x54\xea\x3c\xae\x71\x57\xcb\x00\x6c\x5a\x79\xf4\xed\x57\x9a\x56\xe8\x75\x02\xcd\xdb\xeb\x74\x4b\xaf\x17\x3a\xf9\x50\xa9\x29\xc2\x81\x2e\x51\x2a\x8b\xa7\x01\x9c\x84\xc2\x7b\xcb\xca\xe7\x98\x6f\x47\xf5\xe2\xdd\x4e\xa5\x78\xfe\xbb\x97\x72\xed\xf2\x09\x57\x32\xe2\x32\x03\x0e\xad\x9b\x01\x8e\xd0\x63\x0a\x95\x9f\xdd\x13\x02\xf1\x59\x45\xe9\x79\x12\xfe\x0f\xb3\x8f\x17\x67\x9f\xda\x3c\x5e\x9c\x7d\x22\xeb\x78\x71\x76\x3b\xc6\xc1\xf3\x05\xdd\x36\xf8\x4a\xa9\xbb\x69\xac\x36\x37\xb6\x8c\xdf\xaf\x69\x19\xbf\xff\xc1\x96\xf1\xfa\xd3\x9b\xc6\xeb\x4f\x66\x1b\xaf\x6f\xcb\x38\xde\x37\xac\xe3\xfd\xb5\xcc\xe3\xfd\x47\xd8\xc7\x0f\xd7\xb4\x8f\x1f\xfe\x20\xfb\xa0\x97\xf2\xba\x65\x2c\xc5\xce\xa8\x5c\x10\xe2\xae\x68\xf7\xac\x6c\x49\x36\x21\x7e\xf1\x45\x6e\x89\x89\xae\xb0\xb9\x2d\x63\x20\x64\xb7\x63\x0e\x84\xaa\x66\x10\xf4\xe4\x59\x2f\x1a\xb8\xec\x40\x00\xe9\xa6\xb0\x34\xd9\x01\x2e\x81\x96\xc1\x97\x78\xc4\xc7\xf2\xa6\x4b\xb3\x94\x5e\x65\x2a\x5f\x7e\x19\x2c\xe9\x15\x79\x69\x0c\xe2\xe8\x50\x84\x57\x78\x1a\x2f\xab\x37\x9b\x10\xe2\x69\xdb\xda\x87\x40\x2d\x9e\xdc\x0c\xe9\x68\xf8\x30\x0e\x0c\x37\x86\xb6\x48\x87\x06\x72\x7f\x4f\xeb\xa5\xad\xfc\xff\x77\xe6\x7b\xd6\x1b\x79\xad\xf7\xec\x96\xac\x57\xe8\xbd\x6e\xa9\x9a\xf1\x2a\x7b\xee\x60\xbc\xad\x88\x49\xa8\x6e\x60\xbf\x9a\x17\x94\x78\xfc\x06\x2c\xc9\xff\xe1\x16\x7c\xb6\xda\xa5\x78\x77\xed\xa7\x0d\xc0\x1b\xa2\x72\x5b\x26\x4c\xd8\x6e\xc7\x84\x05\x63\xba\x09\xf3\x27\xbe\xf8\x8b\x20\x5e\xfb\x95\x23\x22\x3b\x90\x51\x7d\xb9\x8f\xe9\x60\xf5\xe4\xac\x37\x4c\x5a\x66\xf9\xb1\x0a\xbb\xa5\x98\xf3\xe7\xd2\x98\x27\xe4\x20\xc4\xf5\x15\x76\xd6\x52\xd8\xb3\x9b\x28\xec\x51\x9e\x7f\xea\xcc\x37\xcd\xf3\x4f\x94\xf9\x8a\x2b\xbf\x6f\x63\xcd\x9c\x37\xd6\xcc\xf9\xb5\xd6\xcc\x79\xe7\x35\x73\x73\x46\xb8\x5f\x26\xb2\x74\x60\xd4\x9c\xfc\x66\xe9\x66\x83\x1b\x9e\x3d\x35\x87\x88\x8b\xe1\x6b\xd3\x4a\x75\x3d\xfc\xb1\x2b\xe5\xd6\x26\x9e\xfb\x55\xce\xcd\xff\x26\x1a\x92\x87\x8f\x8d\xe8\xae\xb7\x2b\x8f\x96\xf2\x0a\x70\xee\xcd\xda\xde\xe6\xd6\x74\xc3\xf1\x66\xb5\x2e\x36\xbb\x5f\x83\xdf\xe4\x15\xc3\x04\x48\xe6\x55\xa2\x68\x6d\x2b\x4a\x03\xd9\x1e\x9a\xf0\xa8\xb0\x52\xde\x89\x5e\x8f\x2e\xfc\xf1\x72\x3e\x9d\x67\xe9\x72\x17\x4c\xa8\x7d\xbe\xd4\x7c\x83\x88\x3a\x76\x7f\xab\x7d\x69\xc5\x8c\x7a\x72\x0b\xfb\xc0\x6d\x0e\xec\xee\x58\x13\x57\xef\xc5\x1a\xcd\x32\x5d\xec\xd7\x64\xef\x15\x9c\x39\x20\x97\x92\xd3\xd0\x76\x12\x62\x51\x57\xf3\x47\xf8\xea\x95\x2e\xea\xe6\x28\x6a\xef\x7c\xeb\x3e\xfb\x91\xc8\xde\xb4\xfa\xd3\xdb\xb2\x6b\x6e\x6b\xf7\xaf\x8b\x05\x18\x8c\x21\x85\x09\x7d\x53\x93\x41\x0e\x05\x4c\xf7\x5b\x48\xde\xfe\x3f\x1a\x2a\x5e\x6d\xdb\xf9\xf5\x00\x19\x5d\xbf\x34\xdb\x56\x58\xbe\x92\x87\x27\x28\x2c\xaa\x1f\xe2\xbf\xbf\xff\x6e\xf8\x00\x03\xf3\xfe\xd2\x07\x3e\x7f\x18\xb4\xdf\x82\xb5\xde\x31\x94\xe0\x0f\x4b\x36\x9a\x67\x01\xad\x49\x7b\x13\x81\xf0\xa1\x45\xb1\x7c\xc7\xa7\xd3\x2f\x02\xd6\xf1\x28\x75\x33\xd0\x9c\xac\xf0\x8c\xb8\x5a\x1a\x6a\x61\x58\xc6\x07\x9c\xb4\xd5\xd7\x01\x9d\x02\x8f\x9a\xb5\x4b\xed\xd6\xde\xcc\xe1\xe5\xf0\xb5\x20\xba\xb7\x0d\xf2\x74\x97\x06\xe9\xf6\x9a\x74\x3a\xef\x64\xd5\xdf\x14\xbe\xd7\x02\xf4\xe1\x6e\xf5\x3a\x8e\xec\xaf\x42\xa8\xf9\x23\xce\xec\x48\x5a\x75\xa3\x3a\xb6\xc3\x3d\x13\xca\x2c\x99\x54\xea\x35\x9d\xe2\x11\x6a\x33\xe0\x12\xc3\xed\x7c\x78\xbf\x4e\xdb\xfd\xa5\x57\xf5\x0a\x4f\xf5\x7a\x33\x37\x9d\x99\x55\x3c\x1c\xae\x2f\xb7\xb3\x9e\x4a\xa4\x30\x47\xd8\xef\x0c\xdd\xc8\x25\x4c\xe7\x64\x55\x2a\xa2\x29\x58\x45\x10\x85\xb3\x5f\x77\x1b\xeb\x41\x92\x96\x57\x10\x1a\x34\xc9\x6c\xb5\xa6\x49\xd2\x32\xf7\xfb\xd3\xd6\xd2\xec\x8b\x20\x5b\xac\x96\xc5\x2d\x98\x34\xe1\x69\xda\x32\x3d\xb4\xdb\x32\x35\x3b\x6d\x59\xc7\x4c\x59\x8a\x60\xb7\x3c\xf9\x6a\x3a\xe9\x7a\x42\xf0\x77\xc9\xb0\xef\x0a\xc9\xb4\x91\xaa\x58\x2a\xf0\xb5\xc3\xac\x3a\x35\x66\x27\x40\xdf\x30\xa9\x17\xeb\x22\x39\xb1\x90\x29\x5d\xe8\xaa\xe6\x3f\x5e\x37\xb8\xea\xe2\x03\x57\xd2\xe4\x15\x7a\xfe\xa7\x49\xec\x76\x53\x25\xe0\xe3\xd6\xb7\xb7\x1f\x5c\xe7\x66\x1a\xa7\x65\xe4\xd1\x18\x3c\xef\x32\xe8\x72\xde\xe5\xc1\x17\x9f\x07\xb3\xdd\x6e\xbd\x3d\x7a\xf0\xe0\x62\x37\xdb\x1e\x4e\x8a\x07\x97\xbb\x29\xfb\x69\x1b\xfc\x12\x1d\xc2\x61\xc4\x33\xb3\xe0\x5f\x2f\x52\xae\x0e\x1e\x82\xb9\xc5\x54\x07\x64\xe8\x54\x88\x38\xec\xc1\x35\xf1\x55\xb1\x13\x9f\xc3\x15\x05\x8a\x7b\x9e\x4e\x16\xdc\x41\x7e\x94\x94\x7e\xbc\xf3\x19\x1d\xe3\xe7\xad\x4f\xca\xf3\x2f\xad\x93\x34\xc1\x9e\x50\xde\x1e\x1e\x6a\x91\x8f\x8f\xed\xe8\x83\x1f\xc5\x70\x34\xe4\xcf\xe9\x41\x85\xfb\x42\xfe\xae\xa3\x96\x4f\xef\xdd\x33\x9c\xcf\x79\x58\x63\xb2\x04\x76\xb2\xf1\x8e\x4e\xce\xfc\xd8\x17\xa7\xf1\xbf\x5d\xe5\xc5\x21\x97\x1f\x37\xa3\xc7\xe2\x28\x0d\x4f\x72\x79\x5e\x9e\xf1\xe7\x7d\xc2\x82\xa7\xa3\x2f\xf1\x6c\xcf\x0e\xe7\xb5\x1f\x51\x8e\xda\x18\xe4\x39\x9c\x72\x0c\xef\xe4\xef\xfa\x18\xc4\xd3\x63\x71\x26\xa9\xea\x76\x58\x42\x3f\xd4\x91\xf1\x0c\x45\x83\xb9\x9a\x2f\x73\x5c\x5d\xd6\x60\xc4\xd1\x21\xe4\x25\xd0\x1f\xd3\x61\x1f\xce\xf5\x17\x07\xb7\xf6\x0f\xb7\x22\x31\xda\xed\x6e\xc3\x17\xe5\xa7\x5c\x68\x27\xb3\x74\x73\xc2\xe5\xc3\x49\x9f\xd3\xc3\xc3\xa9\xf6\x54\x0a\xff\x55\x8a\xdf\x13\x92\x8c\x9b\x26\xbb\xbe\x5c\xfe\x8a\xf2\xbd\xf3\x59\x19\xc1\x2e\xb3\x6d\x94\x17\xf8\xb0\x27\xe8\x88\x01\xd2\xab\x4d\x3a\x7c\xaf\xa6\x40\x7a\x94\xad\x2e\xf1\x88\x9e\xdc\xb9\xa4\x47\x0b\x15\x2b\x44\xf7\x2a\x58\x50\x2b\x7d\xcf\xa8\x7e\xf0\x05\xdb\x26\xc5\x1f\x57\xb3\x39\x37\x88\x9e\xc2\xf6\xa5\x44\x22\x48\x7f\x46\x7d\x2a\x84\x99\x1c\xde\xa3\x9d\xea\xc0\x03\x0b\x62\x21\x9d\x0a\xe0\xbf\x70\x86\xde\x7f\xc5\xc2\x10\x75\x2e\x1e\x7d\x49\x8f\x1e\x9f\x9e\xe2\x23\x0b\x25\x14\x17\x2d\xd7\xb7\x97\x9b\xcd\x0a\x0f\x78\xf4\xc9\xea\x70\x9b\xa2\xa0\xef\x3c\xf9\xa2\xf1\xfd\x2e\x40\x16\xd2\x8c\x23\xa0\x4e\x34\x8c\x2e\xfc\x11\x83\x3d\x01\x7e\x8f\x73\x73\x7a\x12\x86\xfb\x68\xa1\x9c\x31\xfa\xf3\x37\x0c\xce\xb8\xe1\x50\xd2\xa7\x6e\x9f\x09\xc9\x8b\xa9\xbc\x27\x87\x88\x08\xe2\xd3\xd3\x7d\xfa\x34\x33\xc4\x6f\x5c\x35\xcc\xd4\x70\x5f\x55\x1c\x92\xd4\xab\x2c\x58\x0e\xf5\x72\xc9\x23\x54\x36\xe3\x4e\x56\xd2\x3b\x0e\x56\x4b\xbe\x28\x4e\xd7\xeb\x82\xc6\xcd\xc7\x8c\xb6\xc0\x41\xe7\xbb\x3e\x2e\x34\x33\x3c\x3e\x86\xab\x4d\x14\x44\x89\xa9\x84\x41\x21\xed\xd4\xb9\xa8\x12\x2b\x4e\xf5\xa9\xf6\x73\x9d\xce\x37\xed\x91\xd1\xb8\x24\xaf\x9f\x49\xd1\x1d\x1c\x48\xde\xef\x34\x07\x60\xe9\x89\x80\xf8\xaf\x8c\xf7\x02\x4a\x79\xe3\x4d\x9c\xa1\x58\x92\x33\xd0\x2c\x5c\xf9\x42\x69\xe5\x22\x6f\xa9\x1b\x39\x8f\x13\xc5\x7b\xde\x76\x00\x46\xb3\x2f\xfd\x68\x6f\x4f\x33\xfe\xfb\xf7\x45\x37\x8b\xf1\x13\x9d\x37\x04\xf2\xb6\x69\xec\x68\x4a\xa7\xa8\x71\x21\x19\xf1\xf4\xe0\xa1\x52\xff\xb1\x26\xaf\xe0\xfe\x43\x43\xfc\x50\x88\xfe\xf2\x17\x6e\x4a\xca\x80\x70\xab\x8a\x7c\x48\xaa\x44\x71\x22\x8c\x95\xb7\xd6\xec\xb0\x14\x7e\x07\x42\x84\xd0\xa6\xa4\x52\xf8\xdc\x30\xb3\x9f\xcf\x79\xa2\x94\x6e\xfe\x03\x7b\xf5\x50\x0f\x2f\x57\xf3\xa5\x38\x4d\x4d\x02\x28\x1f\xd5\x3d\xbe\x7a\x2c\xbc\xbe\x12\xce\x6e\xc6\xe7\x96\xe0\x09\x37\xc4\x4d\x8f\x46\xb5\xf7\x0c\x53\xa1\xca\x34\xbf\xbb\xbf\x87\x9b\x75\x0a\x01\x5f\xcc\x88\xc8\xda\x83\xe1\x3e\xff\xf1\x1d\x77\x8c\xcd\x09\xf7\x01\x9e\xe9\xdd\x17\x08\xd0\xe4\x97\x3c\xfc\xa7\xf4\x95\xb6\x52\xf6\x1e\x36\xaa\x81\x7e\xf8\x04\x33\x41\x25\x27\xca\xaa\x31\x13\xaf\xc4\xd1\x17\xaf\xd9\xe4\xe4\x24\xa4\x6c\xd0\x46\x4f\x17\xe0\x5f\x54\x1f\xa1\x51\xda\xaa\x7c\xcf\xc2\xfd\xa6\x5e\x84\x43\x9c\xa8\x8e\x26\xb5\x68\x68\xef\x49\xe3\x3c\x3d\x65\x32\xd6\xc9\x30\x07\x07\xb8\x79\x15\x6c\x79\x8a\xc5\x31\x52\xa0\xb3\x33\x5a\xd1\x50\xa6\x43\x13\xe1\xaf\x17\x93\xd5\xa2\x74\x24\x1b\x65\x16\xd6\x29\x47\x6d\xca\x25\x26\xbf\x90\x86\x42\x40\x20\x05\x74\x12\x96\x2c\x95\x07\x8f\x0d\x4c\x50\x18\xd6\x99\x88\xdb\x4c\x38\x0c\xfe\xf8\x9a\x4c\x42\x24\xb8\x0c\x25\x97\x4f\xc2\x1a\x0a\xee\x9b\x66\xab\x19\x76\x18\xcc\x93\xb0\x35\x98\xe4\xa3\x24\x0a\x4c\x32\x3b\x12\xcc\x9e\x76\x64\x16\xa2\xeb\x0e\xaa\x82\x34\x71\x55\x1f\x68\xdd\x03\x4a\xdb\x24\x0c\x4d\x97\xc0\xd4\x5f\xce\x13\xcd\xa4\xa9\x44\x8a\xa9\x7b\x3b\xb9\x6a\x65\x4d\x15\xbc\x77\x52\x29\x41\xd5\x03\x54\x82\x88\x56\x1d\x27\x97\x6a\xc4\x3a\x61\x7d\x96\xd1\x50\x72\xa9\xd9\x5d\xbf\x11\xd1\xab\x6e\x9f\x2a\x11\x2e\x65\xcf\x55\x9e\x9f\xac\x96\xbb\xf9\xf2\x92\x3e\x9e\x25\xed\x57\xa1\x08\x39\xf9\x86\xc6\xce\x67\x08\xfc\x71\x82\x89\x85\x61\x36\xd8\xfb\x66\xc9\x03\xf7\x3c\x27\x20\x21\xed\x3d\x39\xac\x52\xde\x75\x2a\x81\x40\x48\x1b\x05\x6f\x4a\x3a\x6f\xa5\x9b\x60\xd7\xf2\xe1\xfd\xfb\x98\x8c\xab\x08\xd5\x40\x73\x4f\x84\x11\x91\x08\x62\x94\xfc\x4d\x0b\x86\x46\xe8\xf8\xb4\x64\x0c\x8b\x05\x4c\x83\x2b\xbe\x9a\xc7\xd5\xd1\x3a\xc0\x4c\x95\x27\x6a\xbb\xff\xf9\xaf\xff\x56\xd3\x92\x8e\x82\x38\xbe\x63\x19\x79\x0b\x70\xaf\x15\xfc\x85\xf5\x9e\x93\x17\xf4\x2a\x2b\x47\x60\xd0\xcd\x30\xd2\x7f\xc4\xfa\x8f\xc4\x60\xbe\x6d\x5d\x7d\x84\xaa\xea\x98\x1e\xb6\xb5\x2e\x25\x3b\x4d\x17\xf4\xf1\x43\x29\xc7\x33\x6e\x49\xc1\x74\xbe\xd9\xee\x94\x94\x68\x58\xd7\x57\x73\x7b\x76\x0b\x7a\xcb\x55\x5b\xbc\xdb\x7d\x65\x13\x82\xd0\x3d\xa9\x7f\x19\x59\x35\x5e\x4b\xf9\x2a\x5e\xdb\x73\x58\x03\xcf\x13\x65\x50\x27\x0a\x15\xa9\x05\xbf\xac\x37\x3a\xcc\x71\x33\x1e\xe8\xc8\x40\x2c\x33\xa8\xe6\x4e\xa9\x5d\x53\x02\x56\x5a\x6f\x65\xbe\x5a\x8c\xaa\x27\xf0\xd7\x30\x41\x95\xd6\x8b\xb1\xc7\x4d\xd9\x5e\xa4\xbf\x72\x2b\xc8\x16\x97\xb4\x08\xc1\xc5\x85\xbe\xa4\x31\x49\xf9\x54\x49\xe7\xc9\x35\xa4\x43\xa6\x7c\x33\x01\x86\x72\x9d\x16\xd1\xd9\x24\x41\x4b\x17\x68\x6c\x13\x68\x48\xc9\x8b\x10\x98\x2f\x3f\xf8\x94\x32\x4f\x7c\xf6\x76\xca\xa4\x44\x4f\x6f\x57\xa2\x14\x32\x6e\x28\xf4\x11\x09\x1d\xab\x66\xd6\xc5\xce\x9b\x4e\xf8\x13\x92\x48\x4f\xf0\x20\x9e\x96\xfa\x48\xac\xfa\xa0\x15\x95\x61\x8d\x01\xa1\xbe\x04\x33\x6b\x42\xc9\xd3\xa8\x85\xef\x5e\x9d\x1e\x30\x1e\x5f\x71\xa7\xac\xc8\xcb\xc8\xab\xc2\xa6\xfc\x02\xab\xfc\x4d\x01\x4d\xfb\x4d\xf1\xe7\xb8\x91\x93\xc8\x5c\xa3\x9a\x8d\x05\x7f\x25\xbe\x7a\x4a\xa2\x81\xa9\xa8\x46\x54\xf4\x00\xa8\x25\x25\x5a\x8c\xad\x76\x7f\x6a\xe9\x4e\xb5\x4f\xb4\xbb\x58\x6b\xd9\x48\x8f\xff\xe4\xcd\xf5\xb9\x64\x3f\xf8\x1c\x37\xd6\x30\x28\x37\xb3\x13\xf9\x12\x83\xf7\x6a\xe6\x19\xda\x02\xbd\x82\xde\xff\x94\x9b\x6f\x28\x56\xfc\x8c\x8d\xf3\xb0\x87\x65\xa0\xb8\xa8\xf7\x8e\x82\x3d\xda\xf4\xdd\xeb\xe3\x53\xc1\x0f\x7f\x58\x65\x85\xf4\x5c\x0c\x57\x3e\x17\x3f\x38\x9f\x72\x93\xee\x7c\x75\x51\xe0\xd7\x87\xc1\xe4\x72\xbe\xc8\x83\xd5\x7a\x37\xbf\x98\xff\x27\xc7\xdf\x0f\x16\xf3\x9f\x8b\x60\x73\xf8\x13\xff\x93\x22\x00\xed\xb4\x6f\xd7\x45\x86\x87\x00\xd0\x79\xf3\x39\x29\x7c\x9d\xee\x76\xc5\x66\xb9\x25\x7c\xd4\x09\x37\x62\xa6\x2b\xac\x06\xc6\x75\x7a\x24\xf6\x3c\xd1\xfc\x1a\xdf\x45\x06\x7b\xca\x68\xf6\xc4\xe6\x6e\x0d\x00\xbf\x8f\x6c\xec\xa2\x96\x9f\x48\x62\xdb\x9d\xcf\x84\xba\xe4\x47\x93\xe5\x36\x77\x7d\x02\xc3\x31\x93\xee\x48\x39\xd5\xea\xa2\xb1\x6b\xfc\xb9\xf6\xfb\x70\xc9\x25\xf4\x8a\xb3\x52\x25\x73\xd5\x5e\xb5\x5c\x78\xcc\x97\xfa\xbe\x31\x9e\xd2\x5f\xfd\xfb\x79\xf0\x4b\x78\xc8\x0e\x43\x5a\x9e\x57\x3d\xb4\x6f\x49\x4b\x66\x64\x68\x54\x98\xd2\xcd\xd5\x2c\x5d\x34\x30\x8d\x0e\xc3\x03\xb1\x11\xb3\x51\x67\xa3\xc4\x57\x8c\xf2\xd9\x2c\xdd\xbe\xb8\x5a\xbe\x54\x47\x60\x1e\x4a\xa0\xc3\xfa\x73\x02\x2f\x5f\x91\x50\xd5\x38\x21\x14\x15\x31\xea\xe0\xe2\xfd\x10\xb6\xd3\x87\xc4\xfb\x28\x1b\x5d\x56\x58\xde\xf1\xad\x1c\x09\xfd\x5d\xdb\xfc\x6a\x8c\xeb\x6c\x36\xe7\xd9\x07\xa7\x9d\xf2\x3c\x71\x12\xc8\x0f\x55\xe5\xae\xf5\xa1\x34\x68\x29\x13\xee\x37\xe2\x13\x55\x7a\x6d\xf2\xa1\xff\x1b\xbe\x12\x19\x76\x79\x25\xd2\xfa\x62\xf7\xf5\xf3\x67\x5f\xef\x76\xeb\x33\x9c\x32\xb6\xbb\x0a\xdb\xa8\xdb\x0b\x96\xe0\x2a\x5d\xa2\x5e\xb9\xe7\xa9\x77\x51\xcf\x8b\xdd\x6c\x95\xd3\x64\x20\x3e\xfd\xdd\x3b\x3c\x7c\x70\x41\x0f\x31\x5e\x96\x55\x8f\x56\x9b\x0b\xf2\x85\x6d\x03\xb4\x6a\x40\xf0\x3b\xb5\x2a\x25\xdf\xa7\xcb\x1e\x97\x4e\x8c\xfa\x68\x54\x0d\x11\x03\x78\x9e\x2e\xd3\x77\xb4\xd5\x8d\x60\xcd\xc7\x12\x5d\x79\x34\xba\x58\x4c\xe5\xdb\x38\xad\x45\xb0\xba\xed\xed\x1f\x72\x4e\x9e\xa4\xd9\xac\xf2\x16\xd1\xa4\x13\xaf\x3a\x1c\x72\x9e\x39\xf0\xab\xd5\x0b\xb2\xac\x1e\x22\x97\xc3\x6d\x00\x6e\x8b\xdd\x59\x8d\x2d\x82\x6d\xf2\xaa\xf5\xfd\xb0\xaf\xb1\x27\x8f\x71\xcd\x0b\x23\x87\xeb\x26\x73\xeb\x0e\x7c\xad\x6f\xc6\x92\x7c\xd9\xa7\xa4\x29\xe5\x86\x2f\x58\x6a\x2f\x5a\x75\x89\xbf\x2b\x76\x5c\x85\x8f\xf2\x7c\x53\x6c\x11\x12\xcf\x34\x0a\x7b\xe9\xd5\xd9\xc6\xaf\x95\x79\xc0\xae\xc1\xf3\xc0\xad\x83\xa0\xf3\x71\x10\x6e\x80\x7f\x75\x81\xd1\xc9\xad\xed\x51\x00\xf5\xc7\x54\x24\xee\x54\x99\xda\x51\xf0\xa6\x32\xbb\x43\x6a\x93\xd8\x4a\x90\xb7\x66\x75\x88\x51\x2d\x8b\x0d\xcf\x61\x5f\x2c\x8b\x57\xf3\x8b\xe2\x3a\xc3\x33\x75\x74\x8d\xb3\x03\x7c\xd7\x01\x2f\x2f\x17\x0b\xe7\xa0\x76\x2f\x5e\x3d\x7a\x3e\x7f\x8f\x15\x9d\x3a\x69\xaa\x04\x77\x2a\xca\x06\xa5\xd8\x8e\x3a\xb0\xdd\x0f\x3c\xcc\x67\xa2\x08\x24\xa7\xf5\xf2\xa5\x2c\xe7\xe8\x1b\x40\xb3\x8b\x7d\x10\x1e\xc8\xae\x03\xf1\x19\x9c\x77\x90\x42\x98\x8f\xf9\x4a\x03\xd7\x57\x5d\x55\x24\xe1\x7d\x3a\x32\x83\x5d\x4f\x49\xcd\x11\x7e\x55\x4c\xd3\xcb\x85\xa8\x70\xf2\x2d\x9d\x94\xac\xbc\xab\x8e\x50\xbc\x6b\xd0\x30\x6a\xa8\x44\xdb\xe3\xf9\xbb\x06\x0a\xa7\xa0\xce\x2f\xd7\x38\x69\xf1\x28\x71\xc2\xb3\xcf\x6a\x7c\x75\xcb\x08\xac\x82\xb3\xf6\x77\xca\xb1\x6b\x2f\x25\xd6\xb0\xdf\x65\x10\xe7\xbb\xf4\x62\x7d\xe3\x21\x34\x7b\x77\x19\x80\xa7\x8f\x8f\x7d\x99\x5d\xbe\xa9\x13\x6a\x78\x51\x1d\x65\x2d\xa2\x37\x9b\x4c\x41\xb0\xd5\xbd\x8c\x33\xa6\x16\x39\x90\x56\x93\x55\x61\x36\xc8\xa6\x64\x2a\xb8\xb7\x72\x82\xac\x32\x9e\x6a\xe6\xb6\xce\x91\x4a\x52\x55\xe7\x56\xc2\xc6\x79\x93\x07\x62\xf4\x54\xe9\xee\x51\x1c\xf6\xef\x96\x89\x16\xff\x39\xe4\x59\xdc\xdd\xc9\xfc\x9d\x38\x92\xcc\x73\xb4\xbb\x5d\x32\xba\x3d\x3c\xba\x81\xbb\xde\x7c\x0d\xc1\x35\xd8\xa2\x5e\xba\xdd\x31\xa6\xad\x3f\x6d\x67\xb8\xb4\xe6\x44\x56\x9b\xe2\x68\xc1\x57\x16\x77\x54\x02\x79\x17\xf3\xaf\x4e\x24\x51\x34\xdf\x73\xe0\x5a\x1a\xf8\x60\x31\x9f\x3c\x40\x14\x94\x03\x72\x52\xf9\x8a\x13\x5a\xf1\xb5\xdc\x66\x9e\x17\xea\xd8\x88\x3a\xa5\x72\x47\x2b\x24\x23\xcf\x7f\xe0\x32\x75\xaf\x3c\x96\x4a\xa7\x4a\x6a\x00\x87\x82\x64\x1d\x4a\xa9\xa2\x06\x42\xcc\x71\x91\x1b\xa4\x21\x5a\xe4\xf1\x24\xc5\xf1\xdd\xa3\x28\xfa\xf0\x16\xa5\xd0\x7f\x23\xa4\xf0\x76\x9f\x8f\xe0\x5f\x82\xed\xea\x72\x93\x15\xcf\xd3\xf5\x9a\xaf\x5b\xbe\x3b\x7b\xf6\x10\x1b\x0f\x16\xf4\x29\xd0\x4f\xdb\xc3\x8b\x74\x7d\xe7\x7f\x01\xe5\xc6\x86\x6b\x13\x2d\x06\x00")
 
 func web3JsBytes() ([]byte, error) {
 	return bindataRead(
@@ -104,7 +104,7 @@ func web3Js() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "web3.js", size: 404693, mode: os.FileMode(436), modTime: time.Unix(1547627248, 0)}
+	info := bindataFileInfo{name: "web3.js", size: 404755, mode: os.FileMode(436), modTime: time.Unix(1547627248, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
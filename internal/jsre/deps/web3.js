@@ -3698,7 +3698,7 @@ var outputBigNumberFormatter = function (number) {
 };
 
 var isPredefinedBlockNumber = function (blockNumber) {
-    return blockNumber === 'latest' || blockNumber === 'pending' || blockNumber === 'earliest';
+    return blockNumber === 'latest' || blockNumber === 'pending' || blockNumber === 'earliest' || blockNumber === 'stable';
 };
 
 var inputDefaultBlockNumberFormatter = function (blockNumber) {
@@ -5697,8 +5697,8 @@ var methods = function () {
     var genRingSignData = new Method({
         name: 'genRingSignData',
         call: 'personal_genRingSignData',
-        params: 3,
-        inputFormatter: [null, null, null]
+        params: 4,
+        inputFormatter: [formatters.inputAddressFormatter, null, null, null]
     });
 
     return [
@@ -272,6 +272,35 @@ web3._extend({
 			call: 'pos_getActivity',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getValidatorReport',
+			call: 'pos_getValidatorReport',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getDelegatorIncentive',
+			call: 'pos_getDelegatorIncentive',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorIncentive',
+			call: 'pos_getValidatorIncentive',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getPendingUnbonds',
+			call: 'pos_getPendingUnbonds',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getChainQuality',
+			call: 'pos_getChainQuality',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getEpochID',
 			call: 'pos_getEpochID',
@@ -302,6 +331,11 @@ web3._extend({
 			call: 'pos_calProbability',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'simulateReward',
+			call: 'pos_simulateReward',
+			params: 4
+		}),
 		new web3._extend.Method({
 			name: 'getEpochIDByTime',
 			call: 'pos_getEpochIDByTime',
@@ -549,6 +583,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputCallFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -609,6 +649,11 @@ web3._extend({
 			call: 'eth_getRawTransactionByHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getOTAImageInfo',
+			call: 'eth_getOTAImageInfo',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -718,6 +763,17 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'listOTAs',
+			call: 'personal_listOTAs',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'isOTASpent',
+			call: 'personal_isOTASpent',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -18,6 +18,7 @@ package les
 
 import (
 	"context"
+	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/accounts"
@@ -37,6 +38,10 @@ import (
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
+// errStableUnsupported is returned when the stable block is requested from a
+// light client, which doesn't run the PoS block confirmation.
+var errStableUnsupported = errors.New("stable block is not supported by light clients")
+
 type LesApiBackend struct {
 	eth *LightEthereum
 	gpo *gasprice.Oracle
@@ -59,6 +64,9 @@ func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.eth.blockchain.CurrentHeader(), nil
 	}
+	if blockNr == rpc.StableBlockNumber {
+		return nil, errStableUnsupported
+	}
 
 	return b.eth.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/eth"
@@ -41,6 +42,9 @@ import (
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discv5"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
 	rpc "github.com/wanchain/go-wanchain/rpc"
 	"math/big"
 )
@@ -59,6 +63,7 @@ type LightEthereum struct {
 	serverPool      *serverPool
 	reqDist         *requestDistributor
 	retriever       *retrieveManager
+	sls             *slotleader.SLS // Slot proof verification, nil unless the chain runs Pluto
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
 
@@ -87,6 +92,9 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if chainConfig.Pluto != nil {
+		posconfig.SetChainParams(chainConfig.Pos)
+	}
 
 	peers := newPeerSet()
 	quitSync := make(chan struct{})
@@ -116,6 +124,17 @@ func New(ctx *node.ServiceContext, config *eth.Config) (*LightEthereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		core.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	// Slot proofs of PoS headers are checked against the genesis leaders
+	if chainConfig.Pluto != nil {
+		if chainConfig.Pos == nil || chainConfig.Pos.GenesisPK == "" {
+			posconfig.GenesisPK = hexutil.Encode(eth.blockchain.Genesis().Extra())[2:]
+		}
+		eth.sls = slotleader.NewSLS(posdb.NewDbs(chainDb).Local(), nil)
+		eth.blockchain.SetSlotLeaderSelection(eth.sls)
+		if pluto, ok := eth.engine.(*pluto.Pluto); ok {
+			pluto.SetSlotLeaderSelection(eth.sls)
+		}
+	}
 
 	eth.txPool = light.NewTxPool(eth.chainConfig, eth.blockchain, eth.relay)
 	if eth.protocolManager, err = NewProtocolManager(eth.chainConfig, true, config.NetworkId, eth.eventMux, eth.engine, eth.peers, eth.blockchain, nil, chainDb, eth.odr, eth.relay, quitSync, &eth.wg); err != nil {
@@ -180,6 +199,11 @@ func (s *LightEthereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "pos",
+			Version:   "1.0",
+			Service:   &LightPosApi{s},
+			Public:    true,
 		},
 	}...)
 }
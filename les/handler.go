@@ -56,6 +56,8 @@ const (
 	MaxCodeFetch         = 64  // Amount of contract codes to allow fetching per request
 	MaxProofsFetch       = 64  // Amount of merkle proofs to be fetched per retrieval request
 	MaxHeaderProofsFetch = 64  // Amount of merkle proofs to be fetched per retrieval request
+	MaxEpochGenesisFetch = 16  // Amount of epoch genesis to be fetched per retrieval request
+	MaxEpochGenesisBuild = 1   // Amount of epoch genesis not stored yet to be generated per retrieval request
 	MaxTxSend            = 64  // Amount of transactions to be send per request
 
 	disableClientRemovePeer = false
@@ -113,6 +115,8 @@ type ProtocolManager struct {
 
 	eventMux *event.TypeMux
 
+	epochGenesisLock sync.Mutex // Serializes the epoch genesis generation for the peers
+
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
 	quitSync    chan struct{}
@@ -315,7 +319,12 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 }
 
-var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg}
+var reqList = []uint64{GetBlockHeadersMsg, GetBlockBodiesMsg, GetCodeMsg, GetReceiptsMsg, GetProofsMsg, SendTxMsg, GetHeaderProofsMsg, GetEpochGenesisMsg}
+
+// epochGenesisGenerator is implemented by chains able to serve PoS epoch genesis.
+type epochGenesisGenerator interface {
+	GenerateEpochGenesisWithin(epochID uint64, budget int) (*types.EpochGenesis, int, error)
+}
 
 // handleMsg is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
@@ -782,6 +791,73 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			Obj:     resp.Data,
 		}
 
+	case GetEpochGenesisMsg:
+		p.Log().Trace("Received epoch genesis request")
+		// Decode the retrieval message
+		var req struct {
+			ReqID    uint64
+			EpochIDs []uint64
+		}
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		var (
+			bytes        int
+			built        int
+			epochGenesis []*types.EpochGenesis
+		)
+		reqCnt := len(req.EpochIDs)
+		if reject(uint64(reqCnt), MaxEpochGenesisFetch) {
+			return errResp(ErrRequestRejected, "")
+		}
+		if generator, ok := pm.blockchain.(epochGenesisGenerator); ok {
+			for _, epochID := range req.EpochIDs {
+				if bytes >= softResponseLimit {
+					break
+				}
+				// Generating an epoch genesis not stored yet, and the missing
+				// ones before it, reads the chain state. A request generates
+				// at most MaxEpochGenesisBuild of them in all.
+				pm.epochGenesisLock.Lock()
+				epg, n, err := generator.GenerateEpochGenesisWithin(epochID, MaxEpochGenesisBuild-built)
+				pm.epochGenesisLock.Unlock()
+				built += n
+				if err != nil {
+					p.Log().Debug("Failed to generate epoch genesis", "epochID", epochID, "err", err)
+					continue
+				}
+				enc, err := rlp.EncodeToBytes(epg)
+				if err != nil {
+					continue
+				}
+				epochGenesis = append(epochGenesis, epg)
+				bytes += len(enc)
+			}
+		}
+		bv, rcost := p.fcClient.RequestProcessed(costs.baseCost + uint64(reqCnt)*costs.reqCost)
+		pm.server.fcCostStats.update(msg.Code, uint64(reqCnt), rcost)
+		return p.SendEpochGenesis(req.ReqID, bv, epochGenesis)
+
+	case EpochGenesisMsg:
+		if pm.odr == nil {
+			return errResp(ErrUnexpectedResponse, "")
+		}
+
+		p.Log().Trace("Received epoch genesis response")
+		var resp struct {
+			ReqID, BV uint64
+			Data      []*types.EpochGenesis
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.GotReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgEpochGenesis,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Data,
+		}
+
 	case SendTxMsg:
 		if pm.txpool == nil {
 			return errResp(ErrUnexpectedResponse, "")
@@ -342,3 +342,22 @@ func testGetProofs(t *testing.T, protocol int) {
 		t.Errorf("proofs mismatch: %v", err)
 	}
 }
+
+// Tests that epoch genesis requests are answered, leaving out the epochs the
+// serving chain cannot produce an epoch genesis for.
+func TestGetEpochGenesisLes2(t *testing.T) { testGetEpochGenesis(t, 2) }
+
+func testGetEpochGenesis(t *testing.T, protocol int) {
+	db, _ := ethdb.NewMemDatabase()
+	pm := newTestProtocolManagerMust(t, false, 4, testChainGen, nil, nil, db)
+	peer, _ := newTestPeer(t, "peer", protocol, pm, true)
+	defer peer.close()
+
+	// The PoW test chain has no epochs past the first one
+	epochIDs := []uint64{1, 2}
+	cost := peer.GetRequestCost(GetEpochGenesisMsg, len(epochIDs))
+	sendRequest(peer.app, GetEpochGenesisMsg, 42, cost, epochIDs)
+	if err := expectResponse(peer.app, EpochGenesisMsg, 42, testBufLimit, []*types.EpochGenesis{}); err != nil {
+		t.Errorf("epoch genesis mismatch: %v", err)
+	}
+}
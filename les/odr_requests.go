@@ -43,6 +43,7 @@ var (
 	errReceiptHashMismatch = errors.New("receipt hash mismatch")
 	errDataHashMismatch    = errors.New("data hash mismatch")
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
+	errEpochIDMismatch     = errors.New("epoch id mismatch")
 )
 
 type LesOdrRequest interface {
@@ -64,6 +65,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*CodeRequest)(r)
 	case *light.ChtRequest:
 		return (*ChtRequest)(r)
+	case *light.EpochGenesisRequest:
+		return (*EpochGenesisRequest)(r)
 	default:
 		return nil
 	}
@@ -354,3 +357,49 @@ func (r *ChtRequest) Validate(db ethdb.Database, msg *Msg) error {
 
 	return nil
 }
+
+// EpochGenesisRequest is the ODR request type for PoS epoch genesis
+type EpochGenesisRequest light.EpochGenesisRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetEpochGenesisMsg, 1)
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochGenesisRequest) CanSend(peer *peer) bool {
+	return peer.version >= lpv2
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting epoch genesis", "epochID", r.EpochId)
+	return peer.RequestEpochGenesis(reqID, r.GetCost(peer), []uint64{r.EpochId})
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochGenesisRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating epoch genesis", "epochID", r.EpochId)
+
+	// Ensure we have a correct message with a single epoch genesis
+	if msg.MsgType != MsgEpochGenesis {
+		return errInvalidMessageType
+	}
+	epgs := msg.Obj.([]*types.EpochGenesis)
+	if len(epgs) != 1 {
+		return errMultipleEntries
+	}
+	epg := epgs[0]
+	if epg == nil || epg.EpochId != r.EpochId {
+		return errEpochIDMismatch
+	}
+	// Verify the epoch genesis against the locally known header chain
+	if err := light.VerifyEpochGenesis(db, epg); err != nil {
+		return err
+	}
+	r.EpochGenesis = epg
+	return nil
+}
@@ -207,6 +207,11 @@ func (p *peer) SendHeaderProofs(reqID, bv uint64, proofs []ChtResp) error {
 	return sendResponse(p.rw, HeaderProofsMsg, reqID, bv, proofs)
 }
 
+// SendEpochGenesis sends a batch of epoch genesis, corresponding to the ones requested.
+func (p *peer) SendEpochGenesis(reqID, bv uint64, epochGenesis []*types.EpochGenesis) error {
+	return sendResponse(p.rw, EpochGenesisMsg, reqID, bv, epochGenesis)
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(reqID, cost uint64, origin common.Hash, amount int, skip int, reverse bool) error {
@@ -253,6 +258,12 @@ func (p *peer) RequestHeaderProofs(reqID, cost uint64, reqs []*ChtReq) error {
 	return sendRequest(p.rw, GetHeaderProofsMsg, reqID, cost, reqs)
 }
 
+// RequestEpochGenesis fetches a batch of PoS epoch genesis from a remote node.
+func (p *peer) RequestEpochGenesis(reqID, cost uint64, epochIDs []uint64) error {
+	p.Log().Debug("Fetching batch of epoch genesis", "count", len(epochIDs))
+	return sendRequest(p.rw, GetEpochGenesisMsg, reqID, cost, epochIDs)
+}
+
 func (p *peer) SendTxs(reqID, cost uint64, txs types.Transactions) error {
 	p.Log().Debug("Fetching batch of transactions", "count", len(txs))
 	return p2p.Send(p.rw, SendTxMsg, txs)
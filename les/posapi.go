@@ -0,0 +1,105 @@
+package les
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/light"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// LightPosApi offers the pos_* queries a light node can answer from epoch
+// genesis and state retrieved on demand.
+type LightPosApi struct {
+	les *LightEthereum
+}
+
+func (a *LightPosApi) Version() string {
+	return "1.0"
+}
+
+// GetEpochGenesis returns the verified epoch genesis of an epoch.
+func (a *LightPosApi) GetEpochGenesis(ctx context.Context, epochID uint64) (*types.EpochGenesis, error) {
+	return light.GetEpochGenesis(ctx, a.les.odr, epochID)
+}
+
+func (a *LightPosApi) GetEpochID() uint64 {
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(a.les.blockchain.CurrentHeader().Difficulty)
+	return epochID
+}
+
+func (a *LightPosApi) GetSlotID() uint64 {
+	_, slotID := util.GetEpochSlotIDFromDifficulty(a.les.blockchain.CurrentHeader().Difficulty)
+	return slotID
+}
+
+func (a *LightPosApi) GetSlotLeadersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	epg, err := light.GetEpochGenesis(ctx, a.les.odr, epochID)
+	if err != nil {
+		return nil, err
+	}
+	return indexedHex(epg.SlotLeaders), nil
+}
+
+func (a *LightPosApi) GetEpochLeadersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	epg, err := light.GetEpochGenesis(ctx, a.les.odr, epochID)
+	if err != nil {
+		return nil, err
+	}
+	return indexedHex(epg.EpochLeaders), nil
+}
+
+func (a *LightPosApi) GetRandomProposersByEpochID(ctx context.Context, epochID uint64) (map[string]string, error) {
+	epg, err := light.GetEpochGenesis(ctx, a.les.odr, epochID)
+	if err != nil {
+		return nil, err
+	}
+	return indexedHex(epg.RBLeadersSec256), nil
+}
+
+func (a *LightPosApi) GetRandom(ctx context.Context, epochId uint64, blockNr int64) (*big.Int, error) {
+	state, _, err := a.les.ApiBackend.StateAndHeaderByNumber(ctx, rpc.BlockNumber(blockNr))
+	if err != nil {
+		return nil, err
+	}
+
+	r := vm.GetStateR(state, epochId)
+	if r == nil {
+		if err := state.Error(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("no random number exists")
+	}
+
+	return r, nil
+}
+
+// VerifySlotProof checks the slot leader proof of a block header.
+func (a *LightPosApi) VerifySlotProof(ctx context.Context, blockNr rpc.BlockNumber) (bool, error) {
+	header, err := a.les.ApiBackend.HeaderByNumber(ctx, blockNr)
+	if err != nil {
+		return false, err
+	}
+	if header == nil {
+		return false, light.ErrNoHeader
+	}
+	err = light.VerifySlotProof(ctx, a.les.odr, a.les.sls, header)
+	if err == light.ErrInvalidSlotProof {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func indexedHex(list [][]byte) map[string]string {
+	info := make(map[string]string, len(list))
+	for i := 0; i < len(list); i++ {
+		info[fmt.Sprintf("%06d", i)] = hex.EncodeToString(list[i])
+	}
+	return info
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
@@ -67,6 +68,7 @@ type LightChain struct {
 	wg            sync.WaitGroup
 
 	engine consensus.Engine
+	sls    *slotleader.SLS // Slot proof verification of PoS headers, nil unless the chain runs Pluto
 }
 
 // NewLightChain returns a fully initialised light chain using information
@@ -367,6 +369,9 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 
 	var events []interface{}
 	whFunc := func(header *types.Header) error {
+		if err := self.verifySlotProof(header); err != nil {
+			return err
+		}
 		self.mu.Lock()
 		defer self.mu.Unlock()
 
@@ -388,6 +393,30 @@ func (self *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int)
 	return i, err
 }
 
+// SetSlotLeaderSelection sets the slot leader selection the slot proofs of the
+// inserted PoS headers are verified with.
+func (self *LightChain) SetSlotLeaderSelection(sls *slotleader.SLS) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.sls = sls
+}
+
+// verifySlotProof checks the slot leader proof of a PoS header before it is
+// written, its parent is in the chain already.
+func (self *LightChain) verifySlotProof(header *types.Header) error {
+	self.mu.RLock()
+	sls := self.sls
+	self.mu.RUnlock()
+
+	if sls == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), slotProofTimeout)
+	defer cancel()
+	return VerifySlotProof(ctx, self.odr, sls, header)
+}
+
 // CurrentHeader retrieves the current head header of the canonical chain. The
 // header is retrieved from the HeaderChain's internal cache.
 func (self *LightChain) CurrentHeader() *types.Header {
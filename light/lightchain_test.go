@@ -42,7 +42,6 @@ var (
 	fakedAddr                 = common.HexToAddress("0xf9b32578b4420a36f132db32b56f3831a7cc1804")
 	fakedAccountPrivateKey, _ = crypto.HexToECDSA("f1572f76b75b40a7da72d6f2ee7fda3d1189c2d28f0a2f096347055abe344d7f")
 	extraVanity               = 32
-	extraSeal                 = 65
 )
 
 func fakeSignerFn(signer accounts.Account, hash []byte) ([]byte, error) {
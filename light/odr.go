@@ -157,3 +157,15 @@ func (req *ChtRequest) StoreResult(db ethdb.Database) {
 	core.WriteCanonicalHash(db, hash, num)
 	//storeProof(db, req.Proof)
 }
+
+// EpochGenesisRequest is the ODR request type for retrieving the epoch genesis
+// of a PoS epoch
+type EpochGenesisRequest struct {
+	OdrRequest
+	EpochId      uint64
+	EpochGenesis *types.EpochGenesis
+}
+
+// StoreResult doesn't store the epoch genesis, GetEpochGenesis stores it once
+// its leaders are checked against the state.
+func (req *EpochGenesisRequest) StoreResult(db ethdb.Database) {}
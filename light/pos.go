@@ -0,0 +1,370 @@
+package light
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	ErrNoEpochGenesis     = errors.New("Epoch genesis not found")
+	ErrInvalidSlotProof   = errors.New("Invalid slot leader proof")
+	ErrNoSlotLeaderSelect = errors.New("Slot leader selection is not initialized")
+
+	epochGenesisPrefix = []byte("light-epochGenesis-")
+)
+
+const (
+	extraSeal = 65
+
+	slotProofTimeout = 30 * time.Second // Time allowed to retrieve the data a slot proof is checked against
+)
+
+func epochGenesisKey(epochID uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, epochID)
+	return append(append([]byte{}, epochGenesisPrefix...), enc...)
+}
+
+// ReadEpochGenesis returns the locally stored epoch genesis of an epoch, or nil
+// if it has not been retrieved yet.
+func ReadEpochGenesis(db ethdb.Database, epochID uint64) *types.EpochGenesis {
+	data, _ := db.Get(epochGenesisKey(epochID))
+	if len(data) == 0 {
+		return nil
+	}
+	epg := new(types.EpochGenesis)
+	if err := rlp.DecodeBytes(data, epg); err != nil {
+		log.Error("Invalid epoch genesis RLP", "epochID", epochID, "err", err)
+		return nil
+	}
+	return epg
+}
+
+// WriteEpochGenesis stores an epoch genesis in the local database.
+func WriteEpochGenesis(db ethdb.Database, epg *types.EpochGenesis) error {
+	if epg == nil {
+		return ErrNoEpochGenesis
+	}
+	data, err := rlp.EncodeToBytes(epg)
+	if err != nil {
+		return err
+	}
+	return db.Put(epochGenesisKey(epg.EpochId), data)
+}
+
+// GetEpochGenesis retrieves the epoch genesis of an epoch either from the local
+// database or from the network. The epoch genesis are chained, so the missing
+// ones before it are retrieved first, each checked against its predecessor and
+// against the leaders selected from the state. The one of epoch 0 is not served
+// but made from the genesis, anchoring the chain to it.
+func GetEpochGenesis(ctx context.Context, odr OdrBackend, epochID uint64) (*types.EpochGenesis, error) {
+	db := odr.Database()
+	if epg := ReadEpochGenesis(db, epochID); epg != nil {
+		return epg, nil
+	}
+	start := epochID
+	for start > 0 && ReadEpochGenesis(db, start-1) == nil {
+		start--
+	}
+
+	var epg *types.EpochGenesis
+	for id := start; id <= epochID; id++ {
+		var err error
+		if id == 0 {
+			epg, err = firstEpochGenesis(ctx, odr)
+		} else {
+			epg, err = retrieveEpochGenesis(ctx, odr, id)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteEpochGenesis(db, epg); err != nil {
+			return nil, err
+		}
+	}
+	return epg, nil
+}
+
+// retrieveEpochGenesis retrieves the epoch genesis of an epoch from the network.
+// Its predecessor must be stored already.
+func retrieveEpochGenesis(ctx context.Context, odr OdrBackend, epochID uint64) (*types.EpochGenesis, error) {
+	r := &EpochGenesisRequest{EpochId: epochID}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	if r.EpochGenesis == nil {
+		return nil, ErrNoEpochGenesis
+	}
+	if err := VerifyEpochLeaders(ctx, odr, r.EpochGenesis); err != nil {
+		return nil, err
+	}
+	return r.EpochGenesis, nil
+}
+
+// firstEpochGenesis makes the epoch genesis of epoch 0 from the genesis state
+// and the headers of the epoch, as the servers make it.
+func firstEpochGenesis(ctx context.Context, odr OdrBackend) (*types.EpochGenesis, error) {
+	db := odr.Database()
+	genesis := getCanonicalHeader(db, 0)
+	head := core.GetHeadHeaderHash(db)
+	number := core.GetBlockNumber(db, head)
+	if genesis == nil || number == ^uint64(0) {
+		return nil, ErrNoHeader
+	}
+	last, err := lastEpochHeader(db, 0, number)
+	if err != nil {
+		return nil, err
+	}
+
+	epg := &types.EpochGenesis{
+		ProtocolMagic:   []byte("wanchainpos"),
+		RBLeadersSec256: make([][]byte, 0),
+		RBLeadersBn256:  make([][]byte, 0),
+		SlotLeaders:     make([][]byte, 0),
+		StakerInfos:     make([][]byte, 0),
+	}
+	epochLeaders, rbLeaders, err := selectLeaders(ctx, odr, 0, genesis)
+	if err != nil {
+		return nil, err
+	}
+	epg.EpochLeaders = epochLeaders
+	for _, rbl := range rbLeaders {
+		epg.RBLeadersSec256 = append(epg.RBLeadersSec256, rbl.PubSec256)
+		epg.RBLeadersBn256 = append(epg.RBLeadersBn256, rbl.PubBn256)
+	}
+	for i := uint64(0); i <= last.Number.Uint64(); i++ {
+		header := getCanonicalHeader(db, i)
+		if header == nil {
+			return nil, ErrNoHeader
+		}
+		signer, err := core.RecoverBlockSigner(header)
+		if err != nil {
+			break
+		}
+		epg.SlotLeaders = append(epg.SlotLeaders, signer)
+	}
+
+	if epg.GenesisBlkHash, err = core.CalEpochGenesisHash(epg); err != nil {
+		return nil, err
+	}
+	return epg, nil
+}
+
+// VerifyEpochGenesis checks an epoch genesis against the header chain known by
+// the light client. The epoch genesis must hash to its GenesisBlkHash, be
+// chained to the locally stored epoch genesis of the previous epoch, refer to a
+// canonical header of the same epoch and list exactly the signers of the
+// canonical headers of that epoch as slot leaders. Its epoch leaders and random
+// proposers are checked against the state by VerifyEpochLeaders.
+func VerifyEpochGenesis(db ethdb.Database, epg *types.EpochGenesis) error {
+	if epg == nil || epg.EpochId == 0 {
+		return ErrNoEpochGenesis
+	}
+	if !bytes.Equal(epg.ProtocolMagic, []byte("wanchainpos")) {
+		return errors.New("invalid epoch genesis protocol magic")
+	}
+	if len(epg.SlotLeaders) == 0 || len(epg.EpochLeaders) == 0 || len(epg.RBLeadersSec256) == 0 {
+		return errors.New("epoch genesis without leaders")
+	}
+	hash, err := core.CalEpochGenesisHash(epg)
+	if err != nil {
+		return err
+	}
+	if hash != epg.GenesisBlkHash {
+		return fmt.Errorf("epoch genesis hash mismatch: have %x, want %x", epg.GenesisBlkHash, hash)
+	}
+	pre := ReadEpochGenesis(db, epg.EpochId-1)
+	if pre == nil {
+		return fmt.Errorf("epoch genesis %d is retrieved before its predecessor", epg.EpochId)
+	}
+	if pre.GenesisBlkHash != epg.PreEpochGenHash {
+		return fmt.Errorf("epoch genesis %d is not chained to its predecessor", epg.EpochId)
+	}
+
+	// PreEpochLastBlkHash is the parent of the last block of the epoch.
+	number := core.GetBlockNumber(db, epg.PreEpochLastBlkHash)
+	if number == ^uint64(0) || core.GetCanonicalHash(db, number) != epg.PreEpochLastBlkHash {
+		return ErrNoHeader
+	}
+	last := number + 1
+	count := uint64(len(epg.SlotLeaders))
+	if last+1 < count {
+		return errors.New("epoch genesis has too many slot leaders")
+	}
+	first := last + 1 - count
+
+	for i := uint64(0); i < count; i++ {
+		header := getCanonicalHeader(db, first+i)
+		if header == nil {
+			return ErrNoHeader
+		}
+		if epochID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); epochID != epg.EpochId {
+			return fmt.Errorf("header %d is not in epoch %d", first+i, epg.EpochId)
+		}
+		signer, err := core.RecoverBlockSigner(header)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(signer, epg.SlotLeaders[i]) {
+			return fmt.Errorf("slot leader mismatch at header %d", first+i)
+		}
+	}
+	// The slot leaders must cover the whole epoch, not just a tail of it.
+	if first > 0 {
+		if header := getCanonicalHeader(db, first-1); header != nil {
+			if epochID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); epochID >= epg.EpochId {
+				return fmt.Errorf("epoch genesis %d misses slot leaders", epg.EpochId)
+			}
+		}
+	}
+	return nil
+}
+
+// VerifyEpochLeaders checks the epoch leaders and random proposers of an epoch
+// genesis against the ones selected from the state of the target block of the
+// epoch, the last block two epochs before it, retrieved on demand. The staker
+// and random beacon storage the selection reads are thus proven by the state
+// root of a canonical header.
+func VerifyEpochLeaders(ctx context.Context, odr OdrBackend, epg *types.EpochGenesis) error {
+	db := odr.Database()
+	number := core.GetBlockNumber(db, epg.PreEpochLastBlkHash)
+	if number == ^uint64(0) {
+		return ErrNoHeader
+	}
+	target := getCanonicalHeader(db, 0)
+	if epg.EpochId >= 2 {
+		var err error
+		if target, err = lastEpochHeader(db, epg.EpochId-2, number); err != nil {
+			return err
+		}
+	}
+	if target == nil {
+		return ErrNoHeader
+	}
+
+	epochLeaders, rbLeaders, err := selectLeaders(ctx, odr, epg.EpochId, target)
+	if err != nil {
+		return err
+	}
+	if len(epochLeaders) != len(epg.EpochLeaders) {
+		return fmt.Errorf("epoch genesis %d has %d epoch leaders, want %d", epg.EpochId, len(epg.EpochLeaders), len(epochLeaders))
+	}
+	for i := range epochLeaders {
+		if !bytes.Equal(epochLeaders[i], epg.EpochLeaders[i]) {
+			return fmt.Errorf("epoch leader %d mismatch in epoch genesis %d", i, epg.EpochId)
+		}
+	}
+	if len(rbLeaders) != len(epg.RBLeadersSec256) || len(rbLeaders) != len(epg.RBLeadersBn256) {
+		return fmt.Errorf("epoch genesis %d has %d random proposers, want %d", epg.EpochId, len(epg.RBLeadersSec256), len(rbLeaders))
+	}
+	for i, rbl := range rbLeaders {
+		if !bytes.Equal(rbl.PubSec256, epg.RBLeadersSec256[i]) || !bytes.Equal(rbl.PubBn256, epg.RBLeadersBn256[i]) {
+			return fmt.Errorf("random proposer %d mismatch in epoch genesis %d", i, epg.EpochId)
+		}
+	}
+	return nil
+}
+
+// selectLeaders selects the leaders of an epoch from the state of its target
+// block, retrieved on demand.
+func selectLeaders(ctx context.Context, odr OdrBackend, epochID uint64, target *types.Header) ([][]byte, []epochLeader.Proposer, error) {
+	statedb := NewState(ctx, target, odr)
+	epochLeaders, rbLeaders, err := epochLeader.SelectLeaders(statedb, epochID)
+	if err := statedb.Error(); err != nil {
+		return nil, nil, err
+	}
+	return epochLeaders, rbLeaders, err
+}
+
+// lastEpochHeader returns the last canonical header of epochID or of an epoch
+// before it, among the headers up to limit. The epoch must be over by limit.
+func lastEpochHeader(db ethdb.Database, epochID, limit uint64) (*types.Header, error) {
+	missing := false
+	// the genesis is in epoch 0 whatever its difficulty
+	n := sort.Search(int(limit), func(i int) bool {
+		header := getCanonicalHeader(db, uint64(i)+1)
+		if header == nil {
+			missing = true
+			return true
+		}
+		id, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		return id > epochID
+	})
+	if missing {
+		return nil, ErrNoHeader
+	}
+	if n == int(limit) {
+		return nil, fmt.Errorf("epoch %d is not over", epochID)
+	}
+	return getCanonicalHeader(db, uint64(n)), nil
+}
+
+// VerifySlotProof verifies the slot leader proof carried in the extra data of
+// a PoS header. The random number and stage two data are retrieved on demand
+// from the state of the parent block and the previous epoch leaders from the
+// previous epoch genesis. Blocks of the first two epochs are checked against
+// the genesis leaders.
+func VerifySlotProof(ctx context.Context, odr OdrBackend, sls *slotleader.SLS, header *types.Header) error {
+	if sls == nil {
+		return ErrNoSlotLeaderSelect
+	}
+	if len(header.Extra) < extraSeal {
+		return ErrInvalidSlotProof
+	}
+	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+
+	proof, proofMeg, err := sls.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil {
+		return ErrInvalidSlotProof
+	}
+
+	var epochLeadersPre [][]byte
+	if epochID > 1 {
+		epg, err := GetEpochGenesis(ctx, odr, epochID-1)
+		if err != nil {
+			return err
+		}
+		epochLeadersPre = epg.EpochLeaders
+	}
+
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	parent := core.GetHeader(odr.Database(), header.ParentHash, number-1)
+	if parent == nil {
+		return ErrNoHeader
+	}
+	statedb := NewState(ctx, parent, odr)
+	ok := sls.VerifySlotProofByState(statedb, epochLeadersPre, epochID, slotID, proof, proofMeg)
+	if err := statedb.Error(); err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidSlotProof
+	}
+	return nil
+}
+
+func getCanonicalHeader(db ethdb.Database, number uint64) *types.Header {
+	hash := core.GetCanonicalHash(db, number)
+	if (hash == common.Hash{}) {
+		return nil
+	}
+	return core.GetHeader(db, hash, number)
+}
@@ -0,0 +1,357 @@
+package light
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	kbn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/crypto/sha3"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func posSigHash(header *types.Header) (hash common.Hash) {
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal],
+		header.MixDigest,
+		header.Nonce,
+	})
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// makePosHeaderChain writes a canonical chain of sealed headers after parent,
+// or from the number 0 without it, one per entry of epochs, and returns the
+// headers with the keys that sealed them.
+func makePosHeaderChain(t *testing.T, db ethdb.Database, parent *types.Header, epochs []uint64) ([]*types.Header, []*ecdsa.PrivateKey) {
+	var (
+		headers []*types.Header
+		keys    []*ecdsa.PrivateKey
+		first   int64
+	)
+	if parent == nil {
+		parent = &types.Header{Number: big.NewInt(-1)}
+	}
+	first = parent.Number.Int64() + 1
+	for i, epochID := range epochs {
+		key, _ := crypto.GenerateKey()
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Root:       parent.Root,
+			Number:     big.NewInt(first + int64(i)),
+			Difficulty: new(big.Int).SetUint64(epochID<<32 | uint64(i)<<8),
+			GasLimit:   big.NewInt(0),
+			GasUsed:    big.NewInt(0),
+			Time:       big.NewInt(first + int64(i)),
+			Extra:      make([]byte, extraSeal),
+		}
+		if i == 0 && first == 0 {
+			header.ParentHash = common.Hash{}
+		}
+		sig, err := crypto.Sign(posSigHash(header).Bytes(), key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copy(header.Extra, sig)
+
+		core.WriteHeader(db, header)
+		core.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		core.WriteHeadHeaderHash(db, header.Hash())
+		headers = append(headers, header)
+		keys = append(keys, key)
+		parent = header
+	}
+	return headers, keys
+}
+
+func makeEpochGenesis(t *testing.T, epochID uint64, preLast *types.Header, leaders []*ecdsa.PrivateKey, preHash common.Hash) *types.EpochGenesis {
+	epg := &types.EpochGenesis{
+		ProtocolMagic:       []byte("wanchainpos"),
+		EpochId:             epochID,
+		PreEpochLastBlkHash: preLast.Hash(),
+		EpochLeaders:        [][]byte{crypto.FromECDSAPub(&leaders[0].PublicKey)},
+		RBLeadersSec256:     [][]byte{crypto.FromECDSAPub(&leaders[0].PublicKey)},
+		RBLeadersBn256:      [][]byte{{0x01}},
+		StakerInfos:         [][]byte{},
+		PreEpochGenHash:     preHash,
+	}
+	for _, key := range leaders {
+		epg.SlotLeaders = append(epg.SlotLeaders, crypto.FromECDSAPub(&key.PublicKey))
+	}
+	rehashEpochGenesis(t, epg)
+	return epg
+}
+
+func rehashEpochGenesis(t *testing.T, epg *types.EpochGenesis) {
+	hash, err := core.CalEpochGenesisHash(epg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	epg.GenesisBlkHash = hash
+}
+
+func TestEpochGenesisStorage(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	if epg := ReadEpochGenesis(db, 1); epg != nil {
+		t.Fatalf("unexpected epoch genesis: %v", epg)
+	}
+	headers, keys := makePosHeaderChain(t, db, nil, []uint64{0, 0, 1, 1, 2})
+	epg := makeEpochGenesis(t, 1, headers[2], keys[2:4], common.Hash{})
+	if err := WriteEpochGenesis(db, epg); err != nil {
+		t.Fatalf("failed to write epoch genesis: %v", err)
+	}
+	stored, err := GetEpochGenesis(NoOdr, &dummyOdr{db: db}, 1)
+	if err != nil {
+		t.Fatalf("failed to get epoch genesis: %v", err)
+	}
+	if stored.GenesisBlkHash != epg.GenesisBlkHash {
+		t.Errorf("epoch genesis hash mismatch: have %x, want %x", stored.GenesisBlkHash, epg.GenesisBlkHash)
+	}
+}
+
+func TestVerifyEpochGenesis(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	headers, keys := makePosHeaderChain(t, db, nil, []uint64{0, 0, 1, 1, 1, 2})
+
+	// The predecessor of the first epoch genesis is the local one of epoch 0
+	first := &types.EpochGenesis{EpochId: 0, GenesisBlkHash: common.Hash{0x0e}}
+	epg := makeEpochGenesis(t, 1, headers[3], keys[2:5], first.GenesisBlkHash)
+	if err := VerifyEpochGenesis(db, epg); err == nil {
+		t.Fatalf("epoch genesis accepted without its predecessor")
+	}
+	WriteEpochGenesis(db, first)
+
+	// Epoch 1 covers headers 2..4, so PreEpochLastBlkHash refers to header 3.
+	if err := VerifyEpochGenesis(db, epg); err != nil {
+		t.Fatalf("valid epoch genesis rejected: %v", err)
+	}
+
+	// A tampered hash must be rejected
+	bad := *epg
+	bad.GenesisBlkHash = common.Hash{0x01}
+	if err := VerifyEpochGenesis(db, &bad); err == nil {
+		t.Errorf("epoch genesis with invalid hash accepted")
+	}
+	// Slot leaders not matching the header signers must be rejected
+	if err := VerifyEpochGenesis(db, makeEpochGenesis(t, 1, headers[3], keys[1:4], first.GenesisBlkHash)); err == nil {
+		t.Errorf("epoch genesis with wrong slot leaders accepted")
+	}
+	// Slot leaders covering only part of the epoch must be rejected
+	if err := VerifyEpochGenesis(db, makeEpochGenesis(t, 1, headers[3], keys[3:5], first.GenesisBlkHash)); err == nil {
+		t.Errorf("epoch genesis with missing slot leaders accepted")
+	}
+	// An unknown last block must be rejected
+	unknown := makeEpochGenesis(t, 1, &types.Header{Number: big.NewInt(100)}, keys[2:5], first.GenesisBlkHash)
+	if err := VerifyEpochGenesis(db, unknown); err != ErrNoHeader {
+		t.Errorf("epoch genesis with unknown header: have %v, want %v", err, ErrNoHeader)
+	}
+	// The epoch genesis has to be chained to its predecessor
+	if err := VerifyEpochGenesis(db, makeEpochGenesis(t, 1, headers[3], keys[2:5], common.Hash{})); err == nil {
+		t.Errorf("epoch genesis unchained to the first one accepted")
+	}
+	WriteEpochGenesis(db, epg)
+	next := makeEpochGenesis(t, 2, headers[4], keys[5:6], common.Hash{})
+	if err := VerifyEpochGenesis(db, next); err == nil {
+		t.Errorf("unchained epoch genesis accepted")
+	}
+	next.PreEpochGenHash = epg.GenesisBlkHash
+	rehashEpochGenesis(t, next)
+	if err := VerifyEpochGenesis(db, next); err != nil {
+		t.Errorf("chained epoch genesis rejected: %v", err)
+	}
+}
+
+// epochGenesisOdr serves epoch genesis as a les server does, validating them
+// as the light client does, and the state of sdb.
+type epochGenesisOdr struct {
+	*testOdr
+	served map[uint64]*types.EpochGenesis
+}
+
+func (odr *epochGenesisOdr) Retrieve(ctx context.Context, req OdrRequest) error {
+	r, ok := req.(*EpochGenesisRequest)
+	if !ok {
+		return odr.testOdr.Retrieve(ctx, req)
+	}
+	epg := odr.served[r.EpochId]
+	if epg == nil {
+		return ErrNoEpochGenesis
+	}
+	if err := VerifyEpochGenesis(odr.ldb, epg); err != nil {
+		return err
+	}
+	r.EpochGenesis = epg
+	r.StoreResult(odr.ldb)
+	return nil
+}
+
+// newEpochGenesisOdr makes a light client knowing the headers of a staked PoS
+// chain up to epoch 3, but none of its state, and serves the epoch genesis of
+// epochs 1 and 2 as a server makes them.
+func newEpochGenesisOdr(t *testing.T) *epochGenesisOdr {
+	wan := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	validators := make([]core.PosValidator, 2)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		bn, err := kbn256.GenerateBn256()
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = core.PosValidator{
+			PublicKey: &key.PublicKey,
+			Bn256PK:   bn.G1.Marshal(),
+			Balance:   new(big.Int).Mul(big.NewInt(1000000), wan),
+			Stake:     new(big.Int).Mul(big.NewInt(100000), wan),
+		}
+	}
+	spec := core.PosGenesisBlock(big.NewInt(6363), 1, 4, 1500000000, validators)
+	spec.Config.Pos.WhiteList = spec.Config.Pos.WhiteList[:1]
+	posconfig.SetChainParams(spec.Config.Pos)
+
+	sdb, _ := ethdb.NewMemDatabase()
+	ldb, _ := ethdb.NewMemDatabase()
+	genesis := spec.MustCommit(sdb).Header()
+	core.WriteHeader(ldb, genesis)
+	core.WriteCanonicalHash(ldb, genesis.Hash(), 0)
+	headers, _ := makePosHeaderChain(t, ldb, genesis, []uint64{0, 1, 1, 2, 2, 3})
+	headers = append([]*types.Header{genesis}, headers...)
+
+	// the epoch genesis as generated by a server, from the selection of the
+	// leaders on the genesis state, the target of the first epochs
+	statedb, err := state.New(genesis.Root, state.NewDatabase(sdb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(epochID uint64, slotHeaders []*types.Header, preLast common.Hash, preHash common.Hash) *types.EpochGenesis {
+		epg := &types.EpochGenesis{
+			ProtocolMagic:       []byte("wanchainpos"),
+			EpochId:             epochID,
+			PreEpochLastBlkHash: preLast,
+			RBLeadersSec256:     make([][]byte, 0),
+			RBLeadersBn256:      make([][]byte, 0),
+			SlotLeaders:         make([][]byte, 0),
+			StakerInfos:         make([][]byte, 0),
+			PreEpochGenHash:     preHash,
+		}
+		epochLeaders, rbLeaders, err := epochLeader.SelectLeaders(statedb, epochID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		epg.EpochLeaders = epochLeaders
+		for _, rbl := range rbLeaders {
+			epg.RBLeadersSec256 = append(epg.RBLeadersSec256, rbl.PubSec256)
+			epg.RBLeadersBn256 = append(epg.RBLeadersBn256, rbl.PubBn256)
+		}
+		for _, header := range slotHeaders {
+			signer, err := core.RecoverBlockSigner(header)
+			if err != nil {
+				break
+			}
+			epg.SlotLeaders = append(epg.SlotLeaders, signer)
+		}
+		rehashEpochGenesis(t, epg)
+		return epg
+	}
+	first := serve(0, headers[:2], common.Hash{}, common.Hash{})
+	epg1 := serve(1, headers[2:4], headers[2].Hash(), first.GenesisBlkHash)
+	epg2 := serve(2, headers[4:6], headers[4].Hash(), epg1.GenesisBlkHash)
+
+	return &epochGenesisOdr{
+		testOdr: &testOdr{sdb: sdb, ldb: ldb},
+		served:  map[uint64]*types.EpochGenesis{0: first, 1: epg1, 2: epg2},
+	}
+}
+
+// Tests that the epoch genesis are retrieved chained from the one of epoch 0,
+// made from the genesis, with their leaders checked against the state.
+func TestGetEpochGenesis(t *testing.T) {
+	defer posconfig.SetChainParams(nil)
+
+	odr := newEpochGenesisOdr(t)
+	epg, err := GetEpochGenesis(NoOdr, odr, 2)
+	if err != nil {
+		t.Fatalf("failed to get epoch genesis: %v", err)
+	}
+	if epg.GenesisBlkHash != odr.served[2].GenesisBlkHash {
+		t.Fatalf("got epoch genesis %x, want %x", epg.GenesisBlkHash, odr.served[2].GenesisBlkHash)
+	}
+	for epochID := uint64(0); epochID <= 2; epochID++ {
+		stored := ReadEpochGenesis(odr.ldb, epochID)
+		if stored == nil || stored.GenesisBlkHash != odr.served[epochID].GenesisBlkHash {
+			t.Fatalf("stored epoch genesis %d: %+v", epochID, stored)
+		}
+	}
+
+	// leaders not selected from the state are rejected, nothing is stored
+	tamper := map[string]func(epg *types.EpochGenesis){
+		"epoch leader": func(epg *types.EpochGenesis) {
+			epg.EpochLeaders = append([][]byte{append([]byte{}, epg.EpochLeaders[0]...)}, epg.EpochLeaders[1:]...)
+			epg.EpochLeaders[0][1] ^= 0x01
+		},
+		"random proposer": func(epg *types.EpochGenesis) {
+			epg.RBLeadersBn256 = append([][]byte{{0x01}}, epg.RBLeadersBn256[1:]...)
+		},
+		"chaining": func(epg *types.EpochGenesis) {
+			epg.PreEpochGenHash = common.Hash{0x01}
+		},
+	}
+	for name, fn := range tamper {
+		odr := newEpochGenesisOdr(t)
+		bad := *odr.served[1]
+		fn(&bad)
+		rehashEpochGenesis(t, &bad)
+		odr.served[1] = &bad
+		if _, err := GetEpochGenesis(NoOdr, odr, 1); err == nil {
+			t.Errorf("epoch genesis with a wrong %s accepted", name)
+		}
+		if ReadEpochGenesis(odr.ldb, 1) != nil {
+			t.Errorf("epoch genesis with a wrong %s stored", name)
+		}
+	}
+}
+
+func TestInsertHeaderChainSlotProof(t *testing.T) {
+	_, chain, err, lce := newCanonical(0)
+	if err != nil {
+		t.Fatalf("failed to create light chain: %v", err)
+	}
+	headers := lce.makeHeaderChain(chain.Genesis().Header(), 2, canonicalSeed)
+
+	// Headers without a slot proof are rejected once the proofs are checked
+	chain.SetSlotLeaderSelection(slotleader.NewSLS(posdb.NewMemoryDbs().Local(), nil))
+	if _, err := chain.InsertHeaderChain(headers, 1); err != ErrInvalidSlotProof {
+		t.Fatalf("header without slot proof: have %v, want %v", err, ErrInvalidSlotProof)
+	}
+	if head := chain.CurrentHeader().Number.Uint64(); head != 0 {
+		t.Errorf("head after rejected headers: have %d, want 0", head)
+	}
+
+	chain.SetSlotLeaderSelection(nil)
+	if _, err := chain.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert headers: %v", err)
+	}
+}
@@ -68,11 +68,8 @@ for(;;) {
         mixSetWith0x.push(mixWanAddresses[i])
     }
 
-    keyPairs = wan.computeOTAPPKeys(eth.accounts[2], otaAddr).split('+');
-    privateKey = keyPairs[0];
-
     console.log("Balance of ", eth.accounts[2], " is ", web3.fromWin(eth.getBalance(eth.accounts[2])));
-    var ringSignData = personal.genRingSignData(eth.accounts[2], privateKey, mixSetWith0x.join("+"))
+    var ringSignData = personal.genRingSignData(eth.accounts[2], eth.accounts[2], otaAddr, mixSetWith0x.join("+"))
     var txRefundData = coinContract.refundCoin.getData(ringSignData, web3.toWin(tranValue))
     var refundTx = eth.sendTransaction({from:eth.accounts[2], to:coinContractAddr, value:0, data:txRefundData, gas: 200000, gasprice:'0x' + (20000000000).toString(16)});
     console.log("refund index")
@@ -121,9 +118,6 @@ for(;;) {
     wait(function(){return eth.getTransaction(sendTx).blockNumber != null;});
 
 
-    keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrStamp).split('+');
-    privateKeyStamp = keyPairs[0];
-
     var mixStampAddresses = wan.getOTAMixSet(otaAddrStamp,2);
     var mixSetWith0x = []
     for (i = 0; i < mixStampAddresses.length; i++){
@@ -141,8 +135,7 @@ for(;;) {
     var wanAddr = wan.getWanAddress(eth.accounts[1]);
     var otaAddrTokenHolder = wan.generateOneTimeAddress(wanAddr);
     keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrTokenHolder).split('+');
-    privateKeyTokenHolder = keyPairs[0];
-    addrTokenHolder = keyPairs[2];
+    addrTokenHolder = keyPairs[1];
     sendTx = erc20simple.initPrivacyAsset.sendTransaction(addrTokenHolder, otaAddrTokenHolder, initPriBalance,{from:eth.accounts[1], gas:200000, gasprice:'0x' + (20000000000).toString(16)});
 
     console.log("wait init token in contract")
@@ -156,13 +149,12 @@ for(;;) {
 
 
     var hashMsg = addrTokenHolder
-    var ringSignData = personal.genRingSignData(hashMsg, privateKeyStamp, mixSetWith0x.join("+"))
+    var ringSignData = personal.genRingSignData(eth.accounts[1], hashMsg, otaAddrStamp, mixSetWith0x.join("+"))
 
     var wanAddr = wan.getWanAddress(eth.accounts[2]);
     var otaAddr4Account2 = wan.generateOneTimeAddress(wanAddr);
     keyPairs = wan.computeOTAPPKeys(eth.accounts[2], otaAddr4Account2).split('+');
-    privateKeyOtaAcc2 = keyPairs[0];
-    addrOTAAcc2 = keyPairs[2];
+    addrOTAAcc2 = keyPairs[1];
 
     cxtInterfaceCallData = erc20simple.otatransfer.getData(addrOTAAcc2, otaAddr4Account2, priTranValue);
 
@@ -170,7 +162,7 @@ for(;;) {
     glueContract = glueContractDef.at("0x0000000000000000000000000000000000000000")
     combinedData = glueContract.combine.getData(ringSignData, cxtInterfaceCallData)
 
-    sendTx = personal.sendPrivacyCxtTransaction({from:addrTokenHolder, to:contractAddr, value:0, gas:200000, gasprice:'0x' + (20000000000).toString(16),data: combinedData}, privateKeyTokenHolder)
+    sendTx = personal.sendPrivacyCxtTransaction({from:eth.accounts[1], to:contractAddr, value:0, gas:200000, gasprice:'0x' + (20000000000).toString(16),data: combinedData}, otaAddrTokenHolder)
 
     console.log("wait privacy tx in blockchain")
 
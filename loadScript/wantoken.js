@@ -57,9 +57,6 @@ sendTx = eth.sendTransaction({from:eth.accounts[1], to:stampContractAddr, value:
 wait(function(){return eth.getTransaction(sendTx).blockNumber != null;});
 
 
-keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrStamp).split('+');
-privateKeyStamp = keyPairs[0];
-
 var mixStampAddresses = wan.getOTAMixSet(otaAddrStamp,2);
 var mixSetWith0x = []
 for (i = 0; i < mixStampAddresses.length; i++){
@@ -75,8 +72,7 @@ erc20simple = erc20simple_contract.at(contractAddr)
 var wanAddr = wan.getWanAddress(eth.accounts[1]);
 var otaAddrTokenHolder = wan.generateOneTimeAddress(wanAddr);
 keyPairs = wan.computeOTAPPKeys(eth.accounts[1], otaAddrTokenHolder).split('+');
-privateKeyTokenHolder = keyPairs[0];
-addrTokenHolder = keyPairs[2];
+addrTokenHolder = keyPairs[1];
 sendTx = erc20simple.initPrivacyAsset.sendTransaction(addrTokenHolder, otaAddrTokenHolder, '0x' + initPriBalance.toString(16), {from:eth.accounts[1], gas:1000000});
 wait(function(){return eth.getTransaction(sendTx).blockNumber != null;});
 
@@ -87,13 +83,12 @@ if (ota1Balance != initPriBalance) {
 
 
 var hashMsg = addrTokenHolder
-var ringSignData = personal.genRingSignData(hashMsg, privateKeyStamp, mixSetWith0x.join("+"))
+var ringSignData = personal.genRingSignData(eth.accounts[1], hashMsg, otaAddrStamp, mixSetWith0x.join("+"))
 
 var wanAddr = wan.getWanAddress(eth.accounts[2]);
 var otaAddr4Account2 = wan.generateOneTimeAddress(wanAddr);
 keyPairs = wan.computeOTAPPKeys(eth.accounts[2], otaAddr4Account2).split('+');
-privateKeyOtaAcc2 = keyPairs[0];
-addrOTAAcc2 = keyPairs[2];
+addrOTAAcc2 = keyPairs[1];
 
 cxtInterfaceCallData = erc20simple.otatransfer.getData(addrOTAAcc2, otaAddr4Account2, priTranValue);
 
@@ -101,7 +96,7 @@ glueContractDef = eth.contract([{"constant":false,"type":"function","inputs":[{"
 glueContract = glueContractDef.at("0x0000000000000000000000000000000000000000")
 combinedData = glueContract.combine.getData(ringSignData, cxtInterfaceCallData)
 
-sendTx = personal.sendPrivacyCxtTransaction({from:addrTokenHolder, to:contractAddr, value:0, data: combinedData, gasprice:'0x' + (200000000000).toString(16)}, privateKeyTokenHolder)
+sendTx = personal.sendPrivacyCxtTransaction({from:eth.accounts[1], to:contractAddr, value:0, data: combinedData, gasprice:'0x' + (200000000000).toString(16)}, otaAddrTokenHolder)
 wait(function(){return eth.getTransaction(sendTx).blockNumber != null;});
 
 
@@ -74,6 +74,24 @@ func NewTimer(name string) metrics.Timer {
 	return metrics.GetOrRegisterTimer(name, metrics.DefaultRegistry)
 }
 
+// NewGauge create a new metrics Gauge, either a real one of a NOP stub depending
+// on the metrics flag.
+func NewGauge(name string) metrics.Gauge {
+	if !Enabled {
+		return new(metrics.NilGauge)
+	}
+	return metrics.GetOrRegisterGauge(name, metrics.DefaultRegistry)
+}
+
+// NewGaugeFloat64 create a new metrics GaugeFloat64, either a real one of a NOP
+// stub depending on the metrics flag.
+func NewGaugeFloat64(name string) metrics.GaugeFloat64 {
+	if !Enabled {
+		return new(metrics.NilGaugeFloat64)
+	}
+	return metrics.GetOrRegisterGaugeFloat64(name, metrics.DefaultRegistry)
+}
+
 // CollectProcessMetrics periodically collects various metrics about the running
 // process.
 func CollectProcessMetrics(refresh time.Duration) {
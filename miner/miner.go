@@ -57,6 +57,7 @@ type Miner struct {
 	canStart    int32 // can start indicates whether we can start the mining operation
 	shouldStart int32 // should start indicates whether we should start after sync
 	timerStop   chan interface{}
+	pos         *Pos // PoS subsystems driven by the slot timer loop
 }
 
 func New(eth Backend, config *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine) *Miner {
@@ -125,6 +126,11 @@ func (self *Miner) Start(coinbase common.Address) {
 	}
 }
 
+// SetPos sets the PoS subsystems the miner drives on a Pluto chain.
+func (self *Miner) SetPos(pos *Pos) {
+	self.pos = pos
+}
+
 func (self *Miner) Stop() {
 	self.worker.stop()
 	atomic.StoreInt32(&self.mining, 0)
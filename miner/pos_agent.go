@@ -2,7 +2,6 @@ package miner
 
 import (
 	"encoding/hex"
-	"fmt"
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
@@ -10,26 +9,37 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/cfm"
+	"github.com/wanchain/go-wanchain/pos/chainquality"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/pos/randombeacon"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
-	"github.com/wanchain/go-wanchain/pos/util"
-	"github.com/wanchain/go-wanchain/rpc"
 	"time"
 )
 
 func posWhiteList() {
 
 }
-func PosInit(s Backend) *epochLeader.Epocher {
-	log.Debug("PosInit is running")
-	g := s.BlockChain().GetHeaderByNumber(0)
-	posconfig.GenesisPK = hexutil.Encode(g.Extra)[2:]
 
-	cfm.InitCFM(s.BlockChain())
-	slotleader.SlsInit()
+// Pos holds the PoS subsystems of a node, created by PosInit.
+type Pos struct {
+	Epocher   *epochLeader.Epocher
+	Sls       *slotleader.SLS
+	Rb        *randombeacon.RandomBeacon
+	Cfm       *cfm.CFM
+	Cq        *chainquality.Monitor
+	Incentive *incentive.Incentive
+}
+
+func PosInit(s Backend, webhooks []string) *Pos {
+	log.Debug("PosInit is running")
+	// The genesis PK defaults to the extra data of the genesis block
+	if pos := s.BlockChain().Config().Pos; pos == nil || pos.GenesisPK == "" {
+		g := s.BlockChain().GetHeaderByNumber(0)
+		posconfig.GenesisPK = hexutil.Encode(g.Extra)[2:]
+	}
 
 	if posconfig.EpochBaseTime == 0 {
 		h := s.BlockChain().GetHeaderByNumber(1)
@@ -47,27 +57,31 @@ func PosInit(s Backend) *epochLeader.Epocher {
 		panic("PosInit")
 	}
 
-	sls := slotleader.GetSlotLeaderSelection()
+	sls := slotleader.NewSLS(s.BlockChain().PosDbs().Local(), epochSelector)
 	sls.Init(s.BlockChain(), nil, nil)
 
-	incentive.Init(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup)
+	inc := incentive.New(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup,
+		epochSelector.GetEpochLeaders, s.BlockChain().PosDbs().Get(posconfig.IncentiveLocalDB))
 
 	s.BlockChain().SetSlSelector(sls)
 	s.BlockChain().SetRbSelector(epochSelector)
 
 	s.BlockChain().SetSlotValidator(sls)
-
-	return epochSelector
+	s.BlockChain().SetIncentive(inc)
+
+	return &Pos{
+		Epocher:   epochSelector,
+		Sls:       sls,
+		Rb:        randombeacon.NewRandomBeacon(),
+		Cfm:       cfm.NewCFM(s.BlockChain()),
+		Cq:        chainquality.NewMonitor(s.BlockChain(), epochSelector, s.BlockChain().PosEvents(), webhooks),
+		Incentive: inc,
+	}
 }
-func posInitMiner(s Backend, key *keystore.Key) {
+func (self *Miner) posInitMiner(s Backend, key *keystore.Key) {
 	log.Debug("posInitMiner is running")
 
-	// config
-	if key != nil {
-		posconfig.Cfg().MinerKey = key
-	}
-	epochSelector := epochLeader.NewEpocher(s.BlockChain())
-	randombeacon.GetRandonBeaconInst().Init(epochSelector)
+	self.pos.Rb.Init(self.pos.Epocher, s.BlockChain().PosDbs().Local(), key)
 	if posconfig.EpochBaseTime == 0 {
 		//todo:`switch pos from pow,the time is not 1?
 		h := s.BlockChain().GetHeaderByNumber(1)
@@ -98,19 +112,14 @@ func (self *Miner) backendTimerLoop(s Backend) {
 	}
 	log.Debug("Get unlocked key success address:" + eb.Hex())
 	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&key.PrivateKey.PublicKey))
-	posInitMiner(s, key)
-	// get rpcClient
-	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
-	rc, err := rpc.Dial(url)
-	if err != nil {
-		fmt.Println("err:", err)
-		panic(err)
-	}
+	self.posInitMiner(s, key)
+	// protocol txs go straight into the local tx pool
+	sender := postx.NewSender(s.TxPool(), s.ChainDb(), s.BlockChain().Config().ChainId, key)
 
 	//todo:`switch pos from pow,the time is not 1?
 	h := s.BlockChain().GetHeaderByNumber(1)
 	if nil == h {
-		leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(0, 0)
+		leaderPub, err := self.pos.Sls.GetSlotLeader(0, 0)
 		if err == nil {
 			leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 			if leader == localPublicKey {
@@ -127,7 +136,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		if nil == h {
 			select {
 			case <-self.timerStop:
-				randombeacon.GetRandonBeaconInst().Stop()
+				self.pos.Rb.Stop()
 				return
 			case <-time.After(time.Duration(time.Second)):
 				continue
@@ -142,13 +151,26 @@ func (self *Miner) backendTimerLoop(s Backend) {
 			}
 		}
 
-		util.CalEpochSlotIDByNow()
-		epochid, slotid := util.GetEpochSlotID()
+		jumps := s.BlockChain().EpochJumps()
+		if jumps.RecoveryPending(uint64(time.Now().Unix())) {
+			last, _ := jumps.Last()
+			log.Info("Waiting for the halted chain to restart", "epochID", last.Epoch, "startTime", last.StartTime())
+			select {
+			case <-self.timerStop:
+				self.pos.Rb.Stop()
+				return
+			case <-time.After(time.Duration(posconfig.SlotTime) * time.Second):
+				continue
+			}
+		}
+
+		epochid, slotid := jumps.CurEpochSlotID()
 		log.Debug("get current period", "epochid", epochid, "slotid", slotid)
 
-		slotleader.GetSlotLeaderSelection().Loop(rc, key, epochid, slotid)
+		sender.Resend(epochid, slotid)
+		self.pos.Sls.Loop(sender, key, epochid, slotid)
 
-		leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochid, slotid)
+		leaderPub, err := self.pos.Sls.GetSlotLeader(epochid, slotid)
 		if err == nil {
 			leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 			if leader == localPublicKey {
@@ -160,20 +182,20 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		stateDb, err := s.BlockChain().State()
 		if err == nil {
 			// random beacon loop
-			randombeacon.GetRandonBeaconInst().Loop(stateDb, rc, epochid, slotid)
+			self.pos.Rb.Loop(stateDb, sender, epochid, slotid)
 		} else {
 			log.SyslogErr("Failed to get stateDb", "err", err)
 		}
 
 		cur := uint64(time.Now().Unix())
-		sleepTime := posconfig.SlotTime - (cur - posconfig.EpochBaseTime - (epochid*posconfig.SlotCount+slotid)*posconfig.SlotTime)
+		sleepTime := posconfig.SlotTime - (cur - jumps.EpochStartTime(epochid) - slotid*posconfig.SlotTime)
 		log.Debug("timeloop sleep", "sleepTime", sleepTime)
 		if sleepTime < 0 {
 			sleepTime = 0
 		}
 		select {
 		case <-self.timerStop:
-			randombeacon.GetRandonBeaconInst().Stop()
+			self.pos.Rb.Stop()
 			return
 		case <-time.After(time.Duration(time.Second * time.Duration(sleepTime))):
 			continue
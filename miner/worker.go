@@ -269,7 +269,8 @@ func (self *worker) update() {
 		// Handle TxPreEvent
 		case ev := <-self.txCh:
 			// Apply transaction to the pending state if we're not mining
-			if atomic.LoadInt32(&self.mining) == 0 {
+			// There is no pending block yet while the node syncs
+			if atomic.LoadInt32(&self.mining) == 0 && self.current != nil {
 				self.currentMu.Lock()
 				acc, _ := types.Sender(self.current.signer, ev.Tx)
 				txs := map[common.Address]types.Transactions{acc: {ev.Tx}}
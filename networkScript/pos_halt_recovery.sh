@@ -0,0 +1,100 @@
+#!/bin/bash
+
+# Simulates a halt of a PoS devnet and its recovery by concatenating blocks
+# across epochs (jumpEpochs).
+#
+# A devnet of short epochs is generated and run, then all its nodes are
+# stopped for longer than an epoch. The operators restart it with
+# "gwan pos recover" from the lowest block the nodes agree on, and the chain
+# must go on from that block in the next epoch.
+#
+# usage: GWAN=./build/bin/gwan ./networkScript/pos_halt_recovery.sh [validators]
+
+validators=${1:-2}
+gwan=${GWAN:-gwan}
+workDir=${WORKDIR:-$(mktemp -d)}
+slotTime=1
+k=2
+epochTime=$((slotTime * k * 12))
+rpcPort=18545
+
+rpc() {
+	curl -s -H 'Content-Type: application/json' \
+		-d "{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"$2\",\"params\":[$3]}" localhost:$(($rpcPort + $1))
+}
+
+blockNumber() {
+	printf "%d" $(rpc $1 eth_blockNumber | sed 's/.*"result":"\([^"]*\)".*/\1/')
+}
+
+blockHash() {
+	rpc $1 eth_getBlockByNumber "\"$(printf "0x%x" $2)\",false" | sed 's/.*"hash":"\([^"]*\)".*/\1/'
+}
+
+startNodes() {
+	for i in $(seq 0 $(($validators - 1))); do
+		$gwan --config $workDir/devnet/node$i/config.toml --unlock ${accounts[$i]} \
+			--password $workDir/devnet/password.txt --mine >> $workDir/node$i.log 2>&1 &
+		pids[$i]=$!
+	done
+}
+
+stopNodes() {
+	kill ${pids[@]}
+	wait ${pids[@]} 2>/dev/null
+}
+
+lowestBlock() {
+	lowest=$(blockNumber 0)
+	for i in $(seq 1 $(($validators - 1))); do
+		n=$(blockNumber $i)
+		if [ $n -lt $lowest ]; then
+			lowest=$n
+		fi
+	done
+	echo $lowest
+}
+
+$gwan devnet --validators $validators --slot-time $slotTime --k $k --rpcport $rpcPort \
+	--port 27717 --timestamp $(date +%s) --out $workDir/devnet > $workDir/devnet.log 2>&1 || exit 1
+accounts=($(grep -o -- "--unlock 0x[0-9a-fA-F]*" $workDir/devnet.log | cut -d' ' -f2))
+echo "devnet of $validators validators in $workDir, epochs of $epochTime seconds"
+
+startNodes
+sleep $((2 * epochTime))
+halt=$(lowestBlock)
+epoch=$(rpc 0 pos_getEpochID | sed 's/.*"result":\([0-9]*\).*/\1/')
+stopNodes
+echo "halted at block $halt in epoch $epoch"
+if [ $halt -eq 0 ]; then
+	echo "FAIL: no block was produced before the halt"
+	exit 1
+fi
+
+sleep $((2 * epochTime))
+
+start=$(($(date +%s) + 5))
+for i in $(seq 0 $(($validators - 1))); do
+	$gwan --datadir $workDir/devnet/node$i pos recover --block $halt --start $start >> $workDir/recover.log 2>&1 || {
+		cat $workDir/recover.log
+		exit 1
+	}
+done
+grep "Started chain halt recovery" $workDir/recover.log | head -1
+
+startNodes
+sleep $((2 * epochTime))
+resumed=$(lowestBlock)
+hashes=$(for i in $(seq 0 $(($validators - 1))); do blockHash $i $resumed; done | sort -u | wc -l)
+stopNodes
+
+echo "resumed up to block $resumed"
+if [ $resumed -le $halt ]; then
+	echo "FAIL: the chain did not resume"
+	exit 1
+fi
+if [ $hashes -ne 1 ]; then
+	echo "FAIL: the nodes do not agree on block $resumed"
+	exit 1
+fi
+echo "PASS"
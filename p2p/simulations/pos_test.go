@@ -0,0 +1,226 @@
+// Copyright 2018 Wanchain Foundation Ltd
+
+package simulations
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	kbn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+)
+
+// posValidator is a validator of a simulated PoS network.
+type posValidator struct {
+	key    *ecdsa.PrivateKey
+	otaKey *ecdsa.PrivateKey
+	bn256  *kbn256.PrivateKeyBn256
+}
+
+func (v *posValidator) address() common.Address {
+	return crypto.PubkeyToAddress(v.key.PublicKey)
+}
+
+// posNode is the Ethereum service of a simulated validator, mining from its
+// start.
+type posNode struct {
+	*eth.Ethereum
+}
+
+func (n *posNode) Start(srv *p2p.Server) error {
+	if err := n.Ethereum.Start(srv); err != nil {
+		return err
+	}
+	return n.StartMining(true)
+}
+
+// newPosGenesis makes the Pluto genesis of the validators, the first of which
+// seals the genesis block.
+func newPosGenesis(validators []*posValidator) *core.Genesis {
+	wan := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+	stakers := make([]core.PosValidator, len(validators))
+	for i, v := range validators {
+		stakers[i] = core.PosValidator{
+			PublicKey: &v.key.PublicKey,
+			Bn256PK:   v.bn256.G1.Marshal(),
+			Balance:   new(big.Int).Mul(big.NewInt(1000000), wan),
+			Stake:     new(big.Int).Mul(big.NewInt(100000), wan),
+		}
+	}
+	// with k = 4, a missed slot doesn't halt the chain
+	genesis := core.PosGenesisBlock(big.NewInt(6363), 1, 4, uint64(time.Now().Unix()), stakers)
+	return genesis
+}
+
+// TestPosNetwork runs a network of PoS validators in one process, each node
+// with its own PoS subsystems and miner key.
+func TestPosNetwork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping PoS network simulation in short mode")
+	}
+	const count = 2
+
+	validators := make([]*posValidator, count)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		otaKey, _ := crypto.GenerateKey()
+		bn, err := kbn256.GenerateBn256()
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = &posValidator{key: key, otaKey: otaKey, bn256: bn}
+	}
+	genesis := newPosGenesis(validators)
+	if err := genesis.CheckPosConfig(); err != nil {
+		t.Fatal(err)
+	}
+	// the epochs start at the first block of the chain of the test
+	defer func(base uint64) { posconfig.EpochBaseTime = base }(posconfig.EpochBaseTime)
+	posconfig.EpochBaseTime = 0
+
+	var (
+		mu    sync.Mutex
+		ids   = make(map[discover.NodeID]int)
+		nodes = make([]*eth.Ethereum, count)
+	)
+	adapter := adapters.NewSimAdapter(adapters.Services{
+		"pos": func(ctx *adapters.ServiceContext) (node.Service, error) {
+			mu.Lock()
+			i := ids[ctx.Config.ID]
+			mu.Unlock()
+
+			v := validators[i]
+			ks := ctx.NodeContext.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+			account, err := ks.ImportECDSA(v.key, v.otaKey, v.bn256, "")
+			if err != nil {
+				return nil, err
+			}
+			if err := ks.Unlock(account, ""); err != nil {
+				return nil, err
+			}
+
+			config := eth.DefaultConfig
+			config.Genesis = genesis
+			config.NetworkId = genesis.Config.ChainId.Uint64()
+			config.Etherbase = v.address()
+			ethereum, err := eth.New(ctx.NodeContext, &config)
+			if err != nil {
+				return nil, err
+			}
+
+			mu.Lock()
+			nodes[i] = ethereum
+			mu.Unlock()
+			return &posNode{ethereum}, nil
+		},
+	})
+	network := NewNetwork(adapter, &NetworkConfig{DefaultService: "pos"})
+	defer network.Shutdown()
+
+	for i := 0; i < count; i++ {
+		conf := adapters.RandomNodeConfig()
+		mu.Lock()
+		ids[conf.ID] = i
+		mu.Unlock()
+		if _, err := network.NewNodeWithConfig(conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all := network.GetNodes()
+	for _, n := range all {
+		if err := network.Start(n.ID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i < len(all); i++ {
+		if err := network.Connect(all[0].ID(), all[i].ID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the nodes agree on a chain in which every validator took part in the
+	// protocols of the epoch with its own key
+	signer := types.NewEIP155Signer(genesis.Config.ChainId)
+	synced := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		head := nodes[0].BlockChain().CurrentBlock().NumberU64()
+		for _, n := range nodes[1:] {
+			if number := n.BlockChain().CurrentBlock().NumberU64(); number < head {
+				head = number
+			}
+		}
+		senders := make(map[common.Address]bool)
+		for number := uint64(1); number <= head; number++ {
+			block := nodes[0].BlockChain().GetBlockByNumber(number)
+			for _, n := range nodes[1:] {
+				if n.BlockChain().GetBlockByNumber(number).Hash() != block.Hash() {
+					return false
+				}
+			}
+			for _, tx := range block.Transactions() {
+				if from, err := types.Sender(signer, tx); err == nil {
+					senders[from] = true
+				}
+			}
+		}
+		for _, v := range validators {
+			if !senders[v.address()] {
+				return false
+			}
+		}
+		return true
+	}
+	deadline := time.Now().Add(2 * time.Minute)
+	for !synced() {
+		if time.Now().After(deadline) {
+			t.Fatal("the validators did not take part in the protocols of a common chain")
+		}
+		time.Sleep(time.Second)
+	}
+
+	// the PoS subsystems of the nodes are their own
+	if nodes[0].BlockChain().PosDbs() == nodes[1].BlockChain().PosDbs() {
+		t.Error("the nodes share their PoS databases")
+	}
+
+	// and so are their PoS events, the subscribers of a node get every slot
+	// of its chain once, not once more for every other node
+	slots := make([]chan posevent.NewSlotEvent, count)
+	for i, n := range nodes {
+		slots[i] = make(chan posevent.NewSlotEvent, 100)
+		sub := n.BlockChain().PosEvents().SubscribeNewSlot(slots[i])
+		defer sub.Unsubscribe()
+	}
+	time.Sleep(5 * time.Second)
+	for i := range nodes {
+		seen := make(map[[2]uint64]bool)
+		for len(slots[i]) > 0 {
+			ev := <-slots[i]
+			slot := [2]uint64{ev.EpochID, ev.SlotID}
+			if seen[slot] {
+				t.Errorf("node %d: slot %d of epoch %d posted twice", i, ev.SlotID, ev.EpochID)
+			}
+			seen[slot] = true
+		}
+		if len(seen) == 0 {
+			t.Errorf("node %d: no slot posted", i)
+		}
+	}
+}
@@ -18,8 +18,11 @@
 package params
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/wanchain/go-wanchain/common"
 )
@@ -118,7 +121,7 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), new(EthashConfig), nil, nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337) /* big.NewInt(0),*/ /*nil, false,*/ /* big.NewInt(0), common.Hash{},*/ /*big.NewInt(0),*/ /*big.NewInt(0),*/, big.NewInt(0), new(EthashConfig), nil, nil, nil}
 
 	TestChainConfig = &ChainConfig{
 		ChainId:        big.NewInt(1),
@@ -155,6 +158,9 @@ type ChainConfig struct {
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
 	Pluto  *PlutoConfig  `json:"pluto,omitempty"`
+
+	// Proof-of-stake protocol parameters of the Pluto engine (nil = DefaultPosConfig)
+	Pos *PosConfig `json:"pos,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -187,6 +193,64 @@ func (c *PlutoConfig) String() string {
 	return "pluto"
 }
 
+// PosConfig is the parameters of the proof-of-stake protocol run by the Pluto
+// engine. An epoch is made of KCount stages of K slots, the stages schedule the
+// random beacon and the slot leader selection of the next epoch.
+type PosConfig struct {
+	SlotTime          uint64   `json:"slotTime"`            // Time span of a slot in seconds
+	K                 uint64   `json:"k"`                   // Slot count of an epoch stage, also the block security parameter
+	KCount            uint64   `json:"kCount"`              // Stage count of an epoch
+	EpochLeaderCount  uint64   `json:"epochLeaderCount"`    // Count of pks in the epoch leader group selected by stake
+	RandomProperCount uint64   `json:"randomProperCount"`   // Count of pks in the random beacon group selected by stake
+	GenesisPK         string   `json:"genesisPK,omitempty"` // Hex public key sealing the blocks before the first epoch leaders
+	WhiteList         []string `json:"whiteList,omitempty"` // 0x prefixed hex public keys of the white listed epoch leaders, the built in list if empty
+}
+
+// DefaultPosConfig contains the proof-of-stake parameters of the Wanchain networks.
+var DefaultPosConfig = &PosConfig{
+	SlotTime:          10,
+	K:                 10,
+	KCount:            12,
+	EpochLeaderCount:  50,
+	RandomProperCount: 25,
+	GenesisPK:         "04dc40d03866f7335e40084e39c3446fe676b021d1fcead11f2e2715e10a399b498e8875d348ee40358545e262994318e4dcadbc865bcf9aac1fc330f22ae2c786",
+}
+
+// MinPosKCount is the stage count the random beacon and the slot leader
+// selection need to run in an epoch.
+const MinPosKCount = 12
+
+// Validate checks that the proof-of-stake parameters are consistent.
+func (c *PosConfig) Validate() error {
+	switch {
+	case c.SlotTime == 0:
+		return errors.New("pos: slotTime must be positive")
+	case c.K == 0:
+		return errors.New("pos: k must be positive")
+	case c.KCount < MinPosKCount:
+		return fmt.Errorf("pos: kCount %d is less than the %d stages of an epoch", c.KCount, MinPosKCount)
+	case c.EpochLeaderCount == 0:
+		return errors.New("pos: epochLeaderCount must be positive")
+	case c.RandomProperCount < 2:
+		return fmt.Errorf("pos: randomProperCount %d is too small for the random beacon threshold", c.RandomProperCount)
+	}
+	if c.GenesisPK != "" && !isHexPubkey(c.GenesisPK) {
+		return fmt.Errorf("pos: invalid genesisPK %q", c.GenesisPK)
+	}
+	for _, pk := range c.WhiteList {
+		if !strings.HasPrefix(pk, "0x") || !isHexPubkey(pk[2:]) {
+			return fmt.Errorf("pos: invalid whiteList pk %q", pk)
+		}
+	}
+	return nil
+}
+
+// isHexPubkey reports whether s is an uncompressed secp256k1 public key in hex.
+func isHexPubkey(s string) bool {
+	pk, err := hex.DecodeString(s)
+	return err == nil && len(pk) == 65 && pk[0] == 4
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
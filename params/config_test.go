@@ -79,3 +79,34 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestPosConfigValidate(t *testing.T) {
+	modified := func(update func(c *PosConfig)) *PosConfig {
+		c := *DefaultPosConfig
+		update(&c)
+		return &c
+	}
+	tests := []struct {
+		config *PosConfig
+		valid  bool
+	}{
+		{DefaultPosConfig, true},
+		{modified(func(c *PosConfig) { c.SlotTime, c.EpochLeaderCount, c.RandomProperCount = 2, 7, 4 }), true},
+		{modified(func(c *PosConfig) { c.GenesisPK = "" }), true},
+		{modified(func(c *PosConfig) { c.SlotTime = 0 }), false},
+		{modified(func(c *PosConfig) { c.K = 0 }), false},
+		{modified(func(c *PosConfig) { c.KCount = MinPosKCount - 1 }), false},
+		{modified(func(c *PosConfig) { c.EpochLeaderCount = 0 }), false},
+		{modified(func(c *PosConfig) { c.RandomProperCount = 1 }), false},
+		{modified(func(c *PosConfig) { c.GenesisPK = "04dc40" }), false},
+		{modified(func(c *PosConfig) { c.GenesisPK = "0x" + DefaultPosConfig.GenesisPK }), false},
+		{modified(func(c *PosConfig) { c.WhiteList = []string{"0x" + DefaultPosConfig.GenesisPK} }), true},
+		{modified(func(c *PosConfig) { c.WhiteList = []string{"0x04dc40"} }), false},
+		{modified(func(c *PosConfig) { c.WhiteList = []string{DefaultPosConfig.GenesisPK} }), false},
+	}
+	for i, test := range tests {
+		if err := test.config.Validate(); (err == nil) != test.valid {
+			t.Errorf("test %d: validation mismatch: have %v, want valid %v", i, err, test.valid)
+		}
+	}
+}
@@ -37,10 +37,9 @@ type BlkStatus struct {
 	Stable    bool
 }
 
-var c *CFM
-
-func InitCFM(bc *core.BlockChain) {
-	c = &CFM{}
+// NewCFM creates the block confirmation of a chain.
+func NewCFM(bc *core.BlockChain) *CFM {
+	c := &CFM{}
 	c.bc = bc
 	c.whiteList = make(map[common.Address]int, 0)
 	for _, value := range posconfig.WhiteList {
@@ -49,10 +48,7 @@ func InitCFM(bc *core.BlockChain) {
 		address := crypto.PubkeyToAddress(*(crypto.ToECDSAPub(b)))
 		c.whiteList[address] = 1
 	}
-	log.Info("InitCFM success")
-}
-
-func GetCFM() *CFM {
+	log.Info("NewCFM success")
 	return c
 }
 
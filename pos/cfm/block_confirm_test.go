@@ -16,8 +16,7 @@ func TestIsInWhiteList(t *testing.T) {
 		"0x0406a5c2c0524968089b8e7fdaddd642732e04e0f1da4c49dcb7810aa37dd471317b77936015a86e8efbc2002485e9d146ee392a3021e0c5bf53e5c0f6b158de09",
 	}
 	posconfig.Init(nil)
-	InitCFM(nil)
-	c := GetCFM()
+	c := NewCFM(nil)
 	c.whiteList = make(map[common.Address]int, 0)
 	for _, value := range WhiteList {
 		b := hexutil.MustDecode(value)
@@ -38,20 +37,9 @@ func TestIsInWhiteList(t *testing.T) {
 	}
 }
 
-func TestGetCFM(t *testing.T) {
-
-	InitCFM(nil)
-	c := GetCFM()
-
-	if c == nil {
-		t.Fail()
-	}
-}
-
-func TestInitCFM(t *testing.T) {
+func TestNewCFM(t *testing.T) {
 	posconfig.Init(nil)
-	InitCFM(nil)
-	c := GetCFM()
+	c := NewCFM(nil)
 
 	if len(c.whiteList) == 0 {
 		t.Logf("No white list coinbase exisit")
@@ -62,8 +50,7 @@ func TestInitCFM(t *testing.T) {
 
 func TestGetSlotsCount(t *testing.T) {
 	posconfig.Init(nil)
-	InitCFM(nil)
-	c := GetCFM()
+	c := NewCFM(nil)
 
 	start := uint64(time.Now().Unix())
 	stop := uint64(start + posconfig.SlotTime - 1)
@@ -92,8 +79,7 @@ func TestGetMaxStableBlkNumber(t *testing.T) {
 	posconfig.Init(nil)
 
 	blkStatusArr := make([]*BlkStatus, 0)
-	InitCFM(nil)
-	c := GetCFM()
+	c := NewCFM(nil)
 
 	if c.getMaxStableBlkNumber(blkStatusArr, 0, 0, nil) != 0 {
 		t.Fail()
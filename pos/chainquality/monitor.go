@@ -0,0 +1,360 @@
+// Package chainquality measures the quality of the PoS chain, the share of the
+// slots that got a block, and alerts when it or the reorgs cross the
+// thresholds of posconfig.
+package chainquality
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/metrics"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Levels of the chain quality.
+const (
+	LevelNormal   = "normal"   // quality above posconfig.NonCriticalChainQuality
+	LevelWarning  = "warning"  // quality below posconfig.NonCriticalChainQuality
+	LevelCritical = "critical" // quality below posconfig.CriticalChainQuality or too many reorgs
+)
+
+// webhookTimeout bounds the time a webhook may take to accept an alert.
+const webhookTimeout = 10 * time.Second
+
+var (
+	errNotStarted = errors.New("the PoS chain has not started")
+	errFutureSlot = errors.New("the slot has not passed yet")
+	errSyncing    = errors.New("the node is syncing the chain")
+)
+
+var (
+	epochQualityGauge   = metrics.NewGaugeFloat64("pos/chainquality/epoch")
+	rollingQualityGauge = metrics.NewGaugeFloat64("pos/chainquality/rolling")
+	levelGauge          = metrics.NewGauge("pos/chainquality/level")
+	reorgNumberGauge    = metrics.NewGauge("pos/reorg/number")
+	reorgLengthGauge    = metrics.NewGauge("pos/reorg/length")
+	alertMeter          = metrics.NewMeter("pos/chainquality/alerts")
+)
+
+// Chain is the part of the blockchain the chain quality is measured on.
+type Chain interface {
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	PosDbs() *posdb.Dbs
+	EpochJumps() *util.EpochJumps
+}
+
+// Syncer reports whether the node is downloading the chain from its peers.
+type Syncer interface {
+	Synchronising() bool
+}
+
+// Epocher finds the last block of a past epoch.
+type Epocher interface {
+	GetEpochLastBlkNumber(epochID uint64) uint64
+}
+
+// Quality is the chain quality at a slot. The epoch quality covers the slots of
+// the epoch up to the slot, the rolling quality the security window of
+// posconfig.SlotSecurityParam slots ending at the slot, both count from the
+// last restart of a halted chain. The reorgs are the ones this node saw in the
+// epoch.
+type Quality struct {
+	EpochID uint64
+	SlotID  uint64
+
+	EpochBlocks  uint64
+	EpochSlots   uint64
+	EpochQuality float64
+
+	WindowBlocks   uint64
+	WindowSlots    uint64
+	RollingQuality float64
+
+	ReorgNumber uint64
+	ReorgLength uint64
+
+	Level string
+}
+
+// Measure returns the chain quality at the slot slotID of epochID.
+func Measure(chain Chain, epocher Epocher, epochID, slotID uint64) *Quality {
+	q := &Quality{EpochID: epochID, SlotID: slotID}
+
+	end := epochID*posconfig.SlotCount + slotID
+	epochStart := chain.EpochJumps().RestartEpoch(epochID) * posconfig.SlotCount
+	windowStart := epochStart
+	if end >= windowStart+posconfig.SlotSecurityParam {
+		windowStart = end + 1 - posconfig.SlotSecurityParam
+	}
+	if epochStart < epochID*posconfig.SlotCount {
+		epochStart = epochID * posconfig.SlotCount
+	}
+	q.EpochSlots = end + 1 - epochStart
+	q.WindowSlots = end + 1 - windowStart
+
+	// walk back from the last block at or before the slot
+	head := chain.CurrentHeader()
+	number := head.Number.Uint64()
+	if headEpochID, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty); headEpochID > epochID {
+		number = epocher.GetEpochLastBlkNumber(epochID)
+	}
+	for ; number > 0; number-- {
+		header := chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		blkEpochID, blkSlotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		flatSlotID := blkEpochID*posconfig.SlotCount + blkSlotID
+		if flatSlotID > end {
+			continue
+		}
+		if flatSlotID < windowStart && flatSlotID < epochStart {
+			break
+		}
+		if flatSlotID >= windowStart {
+			q.WindowBlocks++
+		}
+		if flatSlotID >= epochStart {
+			q.EpochBlocks++
+		}
+	}
+	q.EpochQuality = float64(q.EpochBlocks) / float64(q.EpochSlots)
+	q.RollingQuality = float64(q.WindowBlocks) / float64(q.WindowSlots)
+
+	if dbs := chain.PosDbs(); dbs != nil {
+		q.ReorgNumber, q.ReorgLength = reorgState(dbs.Get(posconfig.ReorgLocalDB), epochID)
+	}
+	q.Level = levelOf(q)
+	return q
+}
+
+// reorgState returns the reorgs recorded for an epoch and the length of the
+// last one.
+func reorgState(db *posdb.Db, epochID uint64) (number, length uint64) {
+	// the first reorg of an epoch is stored as number 0
+	if b, err := db.Get(epochID, "reorgNumber"); err == nil && len(b) == 8 {
+		number = binary.BigEndian.Uint64(b) + 1
+	}
+	if b, err := db.Get(epochID, "reorgLength"); err == nil && len(b) == 8 {
+		length = binary.BigEndian.Uint64(b)
+	}
+	return number, length
+}
+
+func levelOf(q *Quality) string {
+	switch {
+	case q.RollingQuality < posconfig.CriticalChainQuality || q.ReorgNumber >= posconfig.CriticalReorgThreshold:
+		return LevelCritical
+	case q.RollingQuality < posconfig.NonCriticalChainQuality:
+		return LevelWarning
+	}
+	return LevelNormal
+}
+
+// Monitor measures the chain quality every slot, updates the metrics and
+// alerts when the level changes: it logs, posts a ChainQualityAlertEvent and
+// sends it as JSON to the webhooks. Nothing is measured while the node is
+// syncing, its head is not the one of the chain.
+type Monitor struct {
+	chain    Chain
+	epocher  Epocher
+	syncer   Syncer
+	events   *posevent.Feed
+	webhooks []string
+	client   *http.Client
+
+	level string
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMonitor creates a monitor of chain, posting its alerts to events and the
+// given webhook URLs.
+func NewMonitor(chain Chain, epocher Epocher, events *posevent.Feed, webhooks []string) *Monitor {
+	return &Monitor{
+		chain:    chain,
+		epocher:  epocher,
+		events:   events,
+		webhooks: webhooks,
+		client:   &http.Client{Timeout: webhookTimeout},
+		level:    LevelNormal,
+		quit:     make(chan struct{}),
+	}
+}
+
+// SetSyncer sets the downloader of the node, it must be set before Start.
+func (m *Monitor) SetSyncer(syncer Syncer) {
+	m.syncer = syncer
+}
+
+// Start starts measuring the chain quality every slot.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop stops the monitor.
+func (m *Monitor) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+func (m *Monitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(posconfig.SlotTime) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := uint64(time.Now().Unix())
+			if m.chain.EpochJumps().RecoveryPending(now) || m.syncing(now) {
+				continue
+			}
+			if q, err := m.Current(); err == nil {
+				m.update(q)
+			}
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// syncing reports whether the node is catching up with the chain: the
+// downloader is syncing, or the head is more than an epoch behind now. The
+// blocks missing locally would be counted as missed slots. A halt of the chain
+// alerts in its first epoch, before the head is that far behind.
+func (m *Monitor) syncing(now uint64) bool {
+	if m.syncer != nil && m.syncer.Synchronising() {
+		return true
+	}
+	return m.chain.CurrentHeader().Time.Uint64()+posconfig.SlotCount*posconfig.SlotTime < now
+}
+
+// lastSlot returns the epoch and slot of the last slot that passed.
+func (m *Monitor) lastSlot(now uint64) (epochID, slotID uint64, err error) {
+	if posconfig.EpochBaseTime == 0 || now < posconfig.EpochBaseTime+posconfig.SlotTime {
+		return 0, 0, errNotStarted
+	}
+	epochID, slotID = m.chain.EpochJumps().CalEpochSlotID(now - posconfig.SlotTime)
+	return epochID, slotID, nil
+}
+
+// Current returns the chain quality at the last slot that passed, the block of
+// the current slot may not have arrived yet.
+func (m *Monitor) Current() (*Quality, error) {
+	now := uint64(time.Now().Unix())
+	epochID, slotID, err := m.lastSlot(now)
+	if err != nil {
+		return nil, err
+	}
+	if m.syncing(now) {
+		return nil, errSyncing
+	}
+	return Measure(m.chain, m.epocher, epochID, slotID), nil
+}
+
+// Epoch returns the chain quality of a past epoch, or of the current one up to
+// the last slot that passed. The epochs the node has not synced yet are not
+// measured.
+func (m *Monitor) Epoch(epochID uint64) (*Quality, error) {
+	now := uint64(time.Now().Unix())
+	currentEpochID, _, err := m.lastSlot(now)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case epochID > currentEpochID:
+		return nil, errFutureSlot
+	case epochID == currentEpochID:
+		return m.Current()
+	}
+	if headEpochID, _ := util.GetEpochSlotIDFromDifficulty(m.chain.CurrentHeader().Difficulty); headEpochID <= epochID && m.syncing(now) {
+		return nil, errSyncing
+	}
+	return Measure(m.chain, m.epocher, epochID, posconfig.SlotCount-1), nil
+}
+
+// update records the quality measured in the metrics and alerts if its level
+// changed.
+func (m *Monitor) update(q *Quality) {
+	epochQualityGauge.Update(q.EpochQuality)
+	rollingQualityGauge.Update(q.RollingQuality)
+	reorgNumberGauge.Update(int64(q.ReorgNumber))
+	reorgLengthGauge.Update(int64(q.ReorgLength))
+	switch q.Level {
+	case LevelNormal:
+		levelGauge.Update(0)
+	case LevelWarning:
+		levelGauge.Update(1)
+	case LevelCritical:
+		levelGauge.Update(2)
+	}
+
+	if q.Level == m.level {
+		return
+	}
+	ev := posevent.ChainQualityAlertEvent{
+		EpochID:        q.EpochID,
+		SlotID:         q.SlotID,
+		Level:          q.Level,
+		Previous:       m.level,
+		RollingQuality: q.RollingQuality,
+		EpochQuality:   q.EpochQuality,
+		ReorgNumber:    q.ReorgNumber,
+	}
+	m.level = q.Level
+
+	ctx := []interface{}{"epochID", q.EpochID, "slotID", q.SlotID, "rolling", q.RollingQuality,
+		"epoch", q.EpochQuality, "reorgs", q.ReorgNumber}
+	switch q.Level {
+	case LevelCritical:
+		log.Error("Chain quality is critical", ctx...)
+	case LevelWarning:
+		log.Warn("Chain quality is low", ctx...)
+	default:
+		log.Info("Chain quality is back to normal", ctx...)
+	}
+	alertMeter.Mark(1)
+	m.events.Post(ev)
+	m.sendWebhooks(ev)
+}
+
+// sendWebhooks posts ev to every webhook, without waiting for them.
+func (m *Monitor) sendWebhooks(ev posevent.ChainQualityAlertEvent) {
+	if len(m.webhooks) == 0 {
+		return
+	}
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Error("Failed to encode the chain quality alert", "err", err)
+		return
+	}
+	for _, url := range m.webhooks {
+		m.wg.Add(1)
+		go func(url string) {
+			defer m.wg.Done()
+			resp, err := m.client.Post(url, "application/json", bytes.NewReader(body))
+			if err != nil {
+				log.Warn("Chain quality webhook failed", "url", url, "err", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 {
+				log.Warn("Chain quality webhook refused the alert", "url", url, "status", resp.Status)
+			}
+		}(url)
+	}
+}
@@ -0,0 +1,217 @@
+package chainquality
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// testChain is a chain with one block in each of its flat slots.
+type testChain struct {
+	headers []*types.Header
+	dbs     *posdb.Dbs
+	jumps   *util.EpochJumps
+}
+
+func newTestChain(flatSlotIDs ...uint64) *testChain {
+	c := &testChain{dbs: posdb.NewMemoryDbs(), jumps: util.NewEpochJumps(nil)}
+	c.headers = append(c.headers, &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Time: big.NewInt(0)})
+	for i, flat := range flatSlotIDs {
+		epochID, slotID := flat/posconfig.SlotCount, flat%posconfig.SlotCount
+		c.headers = append(c.headers, &types.Header{
+			Number:     big.NewInt(int64(i + 1)),
+			Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1),
+			Time:       new(big.Int).SetUint64(posconfig.EpochBaseTime + flat*posconfig.SlotTime),
+		})
+	}
+	return c
+}
+
+func (c *testChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testChain) PosDbs() *posdb.Dbs           { return c.dbs }
+func (c *testChain) EpochJumps() *util.EpochJumps { return c.jumps }
+
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func (c *testChain) GetEpochLastBlkNumber(epochID uint64) uint64 {
+	last := uint64(0)
+	for _, h := range c.headers {
+		if blkEpochID, _ := util.GetEpochSlotIDFromDifficulty(h.Difficulty); blkEpochID <= epochID {
+			last = h.Number.Uint64()
+		}
+	}
+	return last
+}
+
+func (c *testChain) setReorgs(epochID, number, length uint64) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, number-1)
+	c.dbs.Get(posconfig.ReorgLocalDB).Put(epochID, "reorgNumber", b)
+	b = make([]byte, 8)
+	binary.BigEndian.PutUint64(b, length)
+	c.dbs.Get(posconfig.ReorgLocalDB).Put(epochID, "reorgLength", b)
+}
+
+// setTestSlots sets epochs of 10 slots and a security window of 4 slots, the
+// returned function restores the previous ones.
+func setTestSlots() func() {
+	slotCount, security := posconfig.SlotCount, posconfig.SlotSecurityParam
+	posconfig.SlotCount, posconfig.SlotSecurityParam = 10, 4
+	return func() {
+		posconfig.SlotCount, posconfig.SlotSecurityParam = slotCount, security
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	defer setTestSlots()()
+
+	// every slot of epoch 0, then slots 0, 2 and 4 of epoch 1
+	chain := newTestChain(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 12, 14)
+	chain.setReorgs(1, 1, 2)
+
+	tests := []struct {
+		epochID, slotID           uint64
+		epochBlocks, epochSlots   uint64
+		windowBlocks, windowSlots uint64
+		reorgs                    uint64
+		level                     string
+	}{
+		{0, 1, 2, 2, 2, 2, 0, LevelNormal},
+		{0, 9, 10, 10, 4, 4, 0, LevelNormal},
+		{1, 0, 1, 1, 4, 4, 1, LevelNormal},
+		{1, 2, 2, 3, 3, 4, 1, LevelWarning},
+		{1, 4, 3, 5, 2, 4, 1, LevelCritical},
+	}
+	for _, tt := range tests {
+		q := Measure(chain, chain, tt.epochID, tt.slotID)
+		if q.EpochBlocks != tt.epochBlocks || q.EpochSlots != tt.epochSlots ||
+			q.WindowBlocks != tt.windowBlocks || q.WindowSlots != tt.windowSlots {
+			t.Errorf("epoch %d slot %d: got epoch %d/%d window %d/%d, want %d/%d %d/%d", tt.epochID, tt.slotID,
+				q.EpochBlocks, q.EpochSlots, q.WindowBlocks, q.WindowSlots,
+				tt.epochBlocks, tt.epochSlots, tt.windowBlocks, tt.windowSlots)
+		}
+		if q.ReorgNumber != tt.reorgs || q.Level != tt.level {
+			t.Errorf("epoch %d slot %d: got %d reorgs level %s, want %d %s", tt.epochID, tt.slotID,
+				q.ReorgNumber, q.Level, tt.reorgs, tt.level)
+		}
+	}
+
+	// too many reorgs are critical whatever the quality
+	chain.setReorgs(0, posconfig.CriticalReorgThreshold, 1)
+	if q := Measure(chain, chain, 0, 9); q.Level != LevelCritical || q.ReorgLength != 1 {
+		t.Errorf("reorgs: got level %s length %d", q.Level, q.ReorgLength)
+	}
+}
+
+func TestMeasureAfterRestart(t *testing.T) {
+	defer setTestSlots()()
+
+	// halted at slot 5 of epoch 0, restarted in epoch 1
+	chain := newTestChain(0, 1, 2, 3, 4, 5, 10, 11)
+	if _, err := chain.jumps.Add(util.EpochJump{Epoch: 1, Jump: 2, Block: 7}); err != nil {
+		t.Fatal(err)
+	}
+	q := Measure(chain, chain, 1, 1)
+	if q.WindowBlocks != 2 || q.WindowSlots != 2 || q.Level != LevelNormal {
+		t.Errorf("got window %d/%d level %s, want 2/2 %s", q.WindowBlocks, q.WindowSlots, q.Level, LevelNormal)
+	}
+}
+
+type testSyncer bool
+
+func (s *testSyncer) Synchronising() bool { return bool(*s) }
+
+func TestMonitorSkipsSyncing(t *testing.T) {
+	defer setTestSlots()()
+	base, slotTime := posconfig.EpochBaseTime, posconfig.SlotTime
+	defer func() { posconfig.EpochBaseTime, posconfig.SlotTime = base, slotTime }()
+
+	// the last block is in the last slot that passed
+	posconfig.SlotTime = 1
+	posconfig.EpochBaseTime = uint64(time.Now().Unix()) - 25
+	chain := newTestChain(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24)
+	syncer := testSyncer(false)
+	m := NewMonitor(chain, chain, posevent.NewFeed(), nil)
+	m.SetSyncer(&syncer)
+	if _, err := m.Current(); err != nil {
+		t.Fatalf("synced node: %v", err)
+	}
+
+	syncer = true
+	if _, err := m.Current(); err != errSyncing {
+		t.Errorf("downloading: got %v, want %v", err, errSyncing)
+	}
+	if _, err := m.Epoch(1); err != nil {
+		t.Errorf("downloading, synced epoch: %v", err)
+	}
+
+	// the head more than an epoch behind
+	syncer = false
+	chain.headers = chain.headers[:12]
+	if _, err := m.Current(); err != errSyncing {
+		t.Errorf("head behind: got %v, want %v", err, errSyncing)
+	}
+	if _, err := m.Epoch(1); err != errSyncing {
+		t.Errorf("head behind, epoch not synced: got %v, want %v", err, errSyncing)
+	}
+	if _, err := m.Epoch(0); err != nil {
+		t.Errorf("head behind, synced epoch: %v", err)
+	}
+}
+
+func TestMonitorAlerts(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		alerts []posevent.ChainQualityAlertEvent
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev posevent.ChainQualityAlertEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		alerts = append(alerts, ev)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	feed := posevent.NewFeed()
+	events := make(chan posevent.ChainQualityAlertEvent, 10)
+	sub := feed.SubscribeChainQualityAlert(events)
+	defer sub.Unsubscribe()
+
+	m := NewMonitor(nil, nil, feed, []string{server.URL})
+	for i, level := range []string{LevelNormal, LevelWarning, LevelWarning, LevelCritical, LevelNormal} {
+		m.update(&Quality{EpochID: 1, SlotID: uint64(i), Level: level})
+	}
+	m.Stop()
+
+	want := [][2]string{{LevelWarning, LevelNormal}, {LevelCritical, LevelWarning}, {LevelNormal, LevelCritical}}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for _, w := range want {
+		if ev := <-events; ev.Level != w[0] || ev.Previous != w[1] {
+			t.Errorf("got event %s after %s, want %s after %s", ev.Level, ev.Previous, w[0], w[1])
+		}
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("webhook got %d alerts, want %d", len(alerts), len(want))
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
 )
 
 var (
@@ -43,32 +44,23 @@ type Epocher struct {
 	blkChain       *core.BlockChain
 }
 
-var epocherInst *Epocher = nil
-
+// NewEpocher creates the epoch leader selection of a chain, saving the leaders
+// to the PoS dbs of the chain.
 func NewEpocher(blc *core.BlockChain) *Epocher {
 
 	if blc == nil {
 		return nil
 	}
 
-	if epocherInst == nil {
-		epocherInst = NewEpocherWithLBN(blc, posconfig.RbLocalDB, posconfig.EpLocalDB)
-	}
-
-	return epocherInst
-}
-
-func GetEpocher() *Epocher {
-	return epocherInst
+	return NewEpocherWithLBN(blc, posconfig.RbLocalDB, posconfig.EpLocalDB)
 }
 
 func NewEpocherWithLBN(blc *core.BlockChain, rbn string, epdbn string) *Epocher {
 
-	rbdb := posdb.NewDb(rbn)
-	epdb := posdb.NewDb(epdbn)
+	rbdb := blc.PosDbs().Get(rbn)
+	epdb := blc.PosDbs().Get(epdbn)
 	inst := &Epocher{rbdb, epdb, blc}
 
-	util.SetEpocherInst(inst)
 	return inst
 }
 
@@ -76,6 +68,11 @@ func (e *Epocher) GetBlkChain() *core.BlockChain {
 	return e.blkChain
 }
 
+// EpochJumps returns the epoch jumps of the chain halt recoveries of the chain.
+func (e *Epocher) EpochJumps() *util.EpochJumps {
+	return e.blkChain.EpochJumps()
+}
+
 func (e *Epocher) GetTargetBlkNumber(epochId uint64) uint64 {
 	if epochId < 2 {
 		return uint64(0)
@@ -89,7 +86,7 @@ func (e *Epocher) GetTargetBlkNumber(epochId uint64) uint64 {
 NOTE: if the targetEpochId is future, will return current blockNumber.
 */
 func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
-	targetBlkNum := util.GetEpochBlock(targetEpochId)
+	targetBlkNum := e.blkChain.EpochBlocks().GetEpochBlock(targetEpochId)
 	var curBlock *types.Block
 	if targetBlkNum == 0 {
 		curNum := e.blkChain.CurrentBlock().NumberU64()
@@ -102,7 +99,7 @@ func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
 			curNum--
 		}
 		targetBlkNum = curNum
-		util.SetEpochBlock(targetEpochId, targetBlkNum, curBlock.Header().Hash())
+		e.blkChain.EpochBlocks().SetEpochBlock(targetEpochId, targetBlkNum, curBlock.Header().Hash())
 	}
 
 	return targetBlkNum
@@ -137,11 +134,30 @@ func (e *Epocher) SelectLeadersLoop(epochId uint64) error {
 
 	return nil
 }
+
+// RebuildLocalDb selects the epoch leaders and random proposers of all epochs
+// up to toEpoch again, replacing the content of the local dbs.
+func (e *Epocher) RebuildLocalDb(toEpoch uint64) error {
+	if err := e.rbLeadersDb.Clear(); err != nil {
+		return err
+	}
+	if err := e.epochLeadersDb.Clear(); err != nil {
+		return err
+	}
+
+	for epochId := uint64(0); epochId <= toEpoch; epochId++ {
+		if err := e.SelectLeadersLoop(epochId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (e *Epocher) selectLeaders(r []byte, statedb *state.StateDB, epochId uint64) error {
 
 	log.Debug("select randoms", "epochId", epochId, "r", common.ToHex(r))
 
-	pa, err := e.createStakerProbabilityArray(statedb)
+	pa, err := createStakerProbabilityArray(statedb)
 	if pa == nil || err != nil {
 		return err
 	}
@@ -150,10 +166,53 @@ func (e *Epocher) selectLeaders(r []byte, statedb *state.StateDB, epochId uint64
 
 	e.randomProposerSelection(r, pa, epochId)
 
+	e.blkChain.PosEvents().Post(posevent.EpochLeadersSelectedEvent{
+		EpochID:         epochId,
+		EpochLeaders:    posevent.ToBytesList(e.GetEpochLeaders(epochId)),
+		RandomProposers: posevent.ToBytesList(e.GetRBProposer(epochId)),
+	})
+
 	return nil
 
 }
 
+// SelectLeaders selects the epoch leaders and the random proposers of an epoch
+// from the state of its target block as SelectLeadersLoop does, without storing
+// them. The epoch leaders are followed by the white list ones, as
+// GetEpochLeaders returns them. A light client checks the leaders of an epoch
+// genesis with it, on the state retrieved on demand.
+func SelectLeaders(statedb *state.StateDB, epochId uint64) ([][]byte, []Proposer, error) {
+	epochIdIn := epochId
+	if epochIdIn > 0 {
+		epochIdIn--
+	}
+	rb := vm.GetR(statedb, epochIdIn)
+	if rb == nil {
+		rb = big.NewInt(1)
+	}
+	r := rb.Bytes()
+
+	epochLeaders := make([][]byte, 0)
+	pa, err := createStakerProbabilityArray(statedb)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pa) == 0 {
+		return epochLeaders, nil, nil
+	}
+
+	info := vm.GetEpochWLInfo(statedb, epochId)
+	for _, p := range leaderSelection(0, r, pa, posconfig.EpochLeaderCount-int(info.WlCount.Uint64())) {
+		epochLeaders = append(epochLeaders, p.PubSec256)
+	}
+	from, to := whiteRange(info)
+	if wa := posconfig.EpochLeadersHold[from:to]; len(epochLeaders) == posconfig.EpochLeaderCount-len(wa) {
+		epochLeaders = append(epochLeaders, wa...)
+	}
+
+	return epochLeaders, leaderSelection(1, r, pa, posconfig.RandomProperCount), nil
+}
+
 type Proposer struct {
 	PubSec256     []byte
 	PubBn256      []byte
@@ -195,7 +254,7 @@ func (e *Epocher) CalProbability(amountWin *big.Int, lockTime uint64) *big.Int {
 	return pb
 }
 
-func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB) (ProposerSorter, error) {
+func createStakerProbabilityArray(statedb *state.StateDB) (ProposerSorter, error) {
 	if statedb == nil {
 		return nil, vm.ErrUnknown
 	}
@@ -246,44 +305,49 @@ func (e *Epocher) epochLeaderSelection(r []byte, ps ProposerSorter, epochId uint
 		return ErrInvalidRandomProposerSelection
 	}
 
-	//the last one is total properties
-	tp := ps[len(ps)-1].Probabilities
-
-	var Byte0 = []byte{byte(0)}
-	var buffer bytes.Buffer
-	buffer.Write(Byte0)
-	buffer.Write(r)
-	r0 := buffer.Bytes()       //r0 = 0||r
-	cr := crypto.Keccak256(r0) //cr = hash(r0)
-
-	//randomProposerPublicKeys := make([]*ecdsa.PublicKey, 0)  //store the selected publickeys
 	log.Debug("epochLeaderSelection selecting")
 	selectionCount := posconfig.EpochLeaderCount
 	info, err := e.GetWhiteInfo(epochId)
 	if err == nil {
 		selectionCount = posconfig.EpochLeaderCount - int(info.WlCount.Uint64())
 	}
-	for i := 0; i < selectionCount; i++ {
+	for i, p := range leaderSelection(0, r, ps, selectionCount) {
+		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", p.PubSec256)
+		val, err := rlp.EncodeToBytes(&p)
+		if err != nil {
+			continue
+		}
+		e.epochLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
+	}
+
+	return nil
+}
+
+// leaderSelection samples count proposers of ps based on proportion of
+// Probabilities, from cr = hash(prefix||r) hashed again for every sample.
+// The epoch leaders are sampled with the prefix 0, the random proposers with 1.
+func leaderSelection(prefix byte, r []byte, ps ProposerSorter, count int) []Proposer {
+	//the last one is total properties
+	tp := ps[len(ps)-1].Probabilities
+
+	var buffer bytes.Buffer
+	buffer.WriteByte(prefix)
+	buffer.Write(r)
+	cr := crypto.Keccak256(buffer.Bytes()) //cr = hash(prefix||r)
+
+	selected := make([]Proposer, 0, count)
+	for i := 0; i < count; i++ {
 
 		crBig := new(big.Int).SetBytes(cr)
 		crBig = crBig.Mod(crBig, tp) //cr_big = cr mod tp
 
-		//fmt.Println("epoch leader mod tp=" + common.ToHex(crBig.Bytes()))
 		//select pki whose probability bigger than cr_big left
 		idx := sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
-
-		log.Debug("select epoch leader", "epochid=", epochId, "idx=", i, "pub=", ps[idx].PubSec256)
-		//randomProposerPublicKeys = append(randomProposerPublicKeys, ps[idx].PubSec256)
-		val, err := rlp.EncodeToBytes(&ps[idx])
-		if err != nil {
-			continue
-		}
-		e.epochLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
+		selected = append(selected, ps[idx])
 
 		cr = crypto.Keccak256(cr)
 	}
-
-	return nil
+	return selected
 }
 
 func (e *Epocher) GetWhiteInfo(epochId uint64) (*vm.UpgradeWhiteEpochLeaderParam, error) {
@@ -305,15 +369,31 @@ func (e *Epocher) GetWhiteByEpochId(epochId uint64) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	return posconfig.WhiteList[info.WlIndex.Uint64() : info.WlIndex.Uint64()+info.WlCount.Uint64()], nil
+	from, to := whiteRange(info)
+	return posconfig.WhiteList[from:to], nil
 }
 func (e *Epocher) GetWhiteArrayByEpochId(epochId uint64) ([][]byte, error) {
 	info, err := e.GetWhiteInfo(epochId)
 	if err != nil {
 		return nil, err
 	}
-	return posconfig.EpochLeadersHold[info.WlIndex.Uint64() : info.WlIndex.Uint64()+info.WlCount.Uint64()], nil
+	from, to := whiteRange(info)
+	return posconfig.EpochLeadersHold[from:to], nil
+
+}
 
+// whiteRange returns the range of the white list held by info, cut to the
+// length of the list, which may be shorter on a devnet.
+func whiteRange(info *vm.UpgradeWhiteEpochLeaderParam) (uint64, uint64) {
+	n := uint64(len(posconfig.WhiteList))
+	from, to := info.WlIndex.Uint64(), info.WlIndex.Uint64()+info.WlCount.Uint64()
+	if to > n {
+		to = n
+	}
+	if from > to {
+		from = to
+	}
+	return from, to
 }
 
 //*bn256.G1
@@ -323,34 +403,15 @@ func (e *Epocher) randomProposerSelection(r []byte, ps ProposerSorter, epochId u
 		return ErrInvalidEpochProposerSelection
 	}
 
-	//the last one is total properties
-	tp := ps[len(ps)-1].Probabilities
-
-	var Byte1 = []byte{byte(1)}
-	var buffer bytes.Buffer
-	buffer.Write(Byte1)
-	buffer.Write(r)
-	r1 := buffer.Bytes()       //r1 = 1||r
-	cr := crypto.Keccak256(r1) //cr = hash(r1)
-
 	log.Info("random proposer selecting...\n")
-	for i := 0; i < posconfig.RandomProperCount; i++ {
-
-		crBig := new(big.Int).SetBytes(cr)
-		crBig = crBig.Mod(crBig, tp) //cr_big = cr mod tp
-
-		//select pki whose probability bigger than cr_big left
-		idx := sort.Search(len(ps), func(i int) bool { return ps[i].Probabilities.Cmp(crBig) > 0 })
-
-		val, err := rlp.EncodeToBytes(ps[idx])
+	for i, p := range leaderSelection(1, r, ps, posconfig.RandomProperCount) {
+		val, err := rlp.EncodeToBytes(p)
 
 		if err != nil {
 			continue
 		}
 
 		e.rbLeadersDb.PutWithIndex(epochId, uint64(i), "", val)
-
-		cr = crypto.Keccak256(cr)
 	}
 
 	return nil
@@ -360,7 +421,7 @@ func (e *Epocher) randomProposerSelection(r []byte, ps ProposerSorter, epochId u
 func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 
 	// TODO: how to cache these
-	epArray := posdb.GetEpochLeaderGroup(epochID)
+	epArray := e.blkChain.PosDbs().GetEpochLeaderGroup(epochID)
 	wa, err := e.GetWhiteArrayByEpochId(epochID)
 	if err == nil {
 		if len(epArray) == posconfig.EpochLeaderCount-len(wa) {
@@ -372,7 +433,7 @@ func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 }
 func (e *Epocher) GetRBProposer(epochID uint64) [][]byte {
 	// TODO: how to cache these
-	rbArray := posdb.GetRBProposerGroup(epochID)
+	rbArray := e.blkChain.PosDbs().GetRBProposerGroup(epochID)
 	return rbArray
 
 }
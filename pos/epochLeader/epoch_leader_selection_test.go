@@ -10,6 +10,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
+	kbn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/common/math"
@@ -18,6 +19,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/util"
@@ -161,7 +163,7 @@ func newTestBlockChain(fake bool) (*core.BlockChain, *core.ChainEnv) {
 
 func TestGetEpochLeaders(t *testing.T) {
 	posconfig.Init(nil)
-	epochID, slotID := util.GetEpochSlotID()
+	epochID, slotID := util.NewEpochJumps(nil).CurEpochSlotID()
 	fmt.Println("epochID:", epochID, " slotID:", slotID)
 
 	blkChain, _ := newTestBlockChain(true)
@@ -202,6 +204,71 @@ func TestGetEpochLeaders(t *testing.T) {
 
 }
 
+// Tests that the leaders selected from a state without storing them are the
+// ones SelectLeadersLoop stores.
+func TestSelectLeaders(t *testing.T) {
+	wan := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	validators := make([]core.PosValidator, 2)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		bn, err := kbn256.GenerateBn256()
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = core.PosValidator{
+			PublicKey: &key.PublicKey,
+			Bn256PK:   bn.G1.Marshal(),
+			Balance:   new(big.Int).Mul(big.NewInt(1000000), wan),
+			Stake:     new(big.Int).Mul(big.NewInt(100000), wan),
+		}
+	}
+	// the second validator is selected, the first one is white listed
+	gspec := core.PosGenesisBlock(big.NewInt(6363), 1, 4, 1500000000, validators)
+	gspec.Config.Pos.WhiteList = gspec.Config.Pos.WhiteList[:1]
+	posconfig.SetChainParams(gspec.Config.Pos)
+	defer posconfig.SetChainParams(nil)
+
+	db, _ := ethdb.NewMemDatabase()
+	gspec.MustCommit(db)
+	blkChain, err := core.NewBlockChain(db, gspec.Config, ethash.NewFullFaker(db), vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blkChain.Stop()
+	epocher := NewEpocher(blkChain)
+	if err := epocher.SelectLeadersLoop(0); err != nil {
+		t.Fatal(err)
+	}
+
+	stateDb, err := blkChain.StateAt(blkChain.GetBlockByNumber(0).Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	epochLeaders, rbLeaders, err := SelectLeaders(stateDb, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := epocher.GetEpochLeaders(0)
+	if len(stored) == 0 || len(epochLeaders) != len(stored) {
+		t.Fatalf("got %d epoch leaders, stored %d", len(epochLeaders), len(stored))
+	}
+	for i := range stored {
+		if !bytes.Equal(epochLeaders[i], stored[i]) {
+			t.Fatalf("epoch leader %d differs", i)
+		}
+	}
+	storedRB := epocher.GetRBProposerGroup(0)
+	if len(rbLeaders) != len(storedRB) {
+		t.Fatalf("got %d random proposers, stored %d", len(rbLeaders), len(storedRB))
+	}
+	for i := range storedRB {
+		if !bytes.Equal(rbLeaders[i].PubSec256, storedRB[i].PubSec256) || !bytes.Equal(rbLeaders[i].PubBn256, storedRB[i].PubBn256) {
+			t.Fatalf("random proposer %d differs", i)
+		}
+	}
+}
+
 //func TestGetGetEpochLeadersCapability(t *testing.T) {
 //
 //	blkChain, _ := newTestBlockChain(true)
@@ -393,3 +460,27 @@ func TestCalProbability(t *testing.T) {
 		t.Log("===========")
 	}
 }
+
+func TestWhiteRange(t *testing.T) {
+	defer posconfig.SetChainParams(nil)
+
+	pk := "0x" + params.DefaultPosConfig.GenesisPK
+	c := *params.DefaultPosConfig
+	c.WhiteList = []string{pk, pk, pk, pk}
+	posconfig.SetChainParams(&c)
+
+	tests := []struct {
+		index, count uint64
+		from, to     uint64
+	}{
+		{0, 2, 0, 2},
+		{1, 26, 1, 4},
+		{6, 26, 4, 4},
+	}
+	for i, test := range tests {
+		info := &vm.UpgradeWhiteEpochLeaderParam{EpochId: big.NewInt(0), WlIndex: new(big.Int).SetUint64(test.index), WlCount: new(big.Int).SetUint64(test.count)}
+		if from, to := whiteRange(info); from != test.from || to != test.to {
+			t.Errorf("test %d: range %d-%d, want %d-%d", i, from, to, test.from, test.to)
+		}
+	}
+}
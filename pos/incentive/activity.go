@@ -8,23 +8,22 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
-var whiteList map[common.Address]int
-
-func activityInit() {
-	whiteList = make(map[common.Address]int, 0)
+// newWhiteList returns the addresses of the white list epoch leaders.
+func newWhiteList() map[common.Address]int {
+	whiteList := make(map[common.Address]int, 0)
 	for _, value := range posconfig.WhiteList {
 		b := hexutil.MustDecode(value)
 		address := crypto.PubkeyToAddress(*(crypto.ToECDSAPub(b)))
 		whiteList[address] = 1
 	}
+	return whiteList
 }
 
-func isInWhiteList(coinBase common.Address) bool {
-	if _, ok := whiteList[coinBase]; ok {
+func (inc *Incentive) isInWhiteList(coinBase common.Address) bool {
+	if _, ok := inc.whiteList[coinBase]; ok {
 		return true
 	}
 	return false
@@ -44,13 +43,18 @@ func checkEpochLeaders(epochLeaders [][]byte) bool {
 	return true
 }
 
-func getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+func (inc *Incentive) getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
 	if stateDb == nil {
 		log.SyslogErr("getEpochLeaderActivity with an empty stateDb")
 		return []common.Address{}, []int{}
 	}
 
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+	if inc.getEpochLeaders == nil {
+		log.SyslogErr("getEpochLeaderActivity without epoch leaders interface")
+		return []common.Address{}, []int{}
+	}
+
+	epochLeaders := inc.getEpochLeaders(epochID)
 	if !checkEpochLeaders(epochLeaders) {
 		log.SyslogErr("incentive activity GetEpochLeaders error", "epochID", epochID)
 		return []common.Address{}, []int{}
@@ -116,18 +120,18 @@ func getRnpAddrFromLeader(leaders []vm.Leader) []common.Address {
 	return addrs
 }
 
-func getRandomProposerActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+func (inc *Incentive) getRandomProposerActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
 	if stateDb == nil {
 		log.SyslogErr("getRandomProposerActivity with an empty stateDb")
 		return []common.Address{}, []int{}
 	}
 
-	if getRandomProposerAddress == nil {
+	if inc.getRandomProposerAddress == nil {
 		log.SyslogErr("incentive activity getRandomProposerAddress == nil", "epochID", epochID)
 		return []common.Address{}, []int{}
 	}
 
-	leaders := getRandomProposerAddress(epochID)
+	leaders := inc.getRandomProposerAddress(epochID)
 	addrs := getRnpAddrFromLeader(leaders)
 	if addrs == nil {
 		log.SyslogErr("incentive activity getRandomProposerAddress error", "epochID", epochID)
@@ -145,7 +149,7 @@ func getRandomProposerActivity(stateDb vm.StateDB, epochID uint64) ([]common.Add
 	return addrs, activity
 }
 
-func getSlotLeaderActivity(chain consensus.ChainReader, epochID uint64, slotCount int) ([]common.Address, []int, float64, int) {
+func (inc *Incentive) getSlotLeaderActivity(chain consensus.ChainReader, epochID uint64, slotCount int) ([]common.Address, []int, float64, int) {
 	if chain == nil {
 		log.SyslogErr("getSlotLeaderActivity chain reader is empty.")
 		return []common.Address{}, []int{}, float64(0), 0
@@ -161,7 +165,7 @@ func getSlotLeaderActivity(chain consensus.ChainReader, epochID uint64, slotCoun
 
 		epID := getEpochIDFromDifficulty(header.Difficulty)
 		if epID == epochID {
-			if isInWhiteList(header.Coinbase) {
+			if inc.isInWhiteList(header.Coinbase) {
 				ctrlCount++
 				continue
 			}
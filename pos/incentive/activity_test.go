@@ -14,7 +14,6 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
@@ -36,12 +35,12 @@ func (t *TestChainReader) GetBlock(hash common.Hash, number uint64) *types.Block
 
 func TestGetSlotLeaderActivity(t *testing.T) {
 	posconfig.Init(nil)
-	activityInit()
+	testInc.whiteList = newWhiteList()
 	generateTestAddrs()
 	generateTestStaker()
 
 	chain := &TestChainReader{}
-	addrs, blks, activity, _ := getSlotLeaderActivity(chain, 0, 100)
+	addrs, blks, activity, _ := testInc.getSlotLeaderActivity(chain, 0, 100)
 	fmt.Println(addrs, blks, activity)
 
 	if activity != 0.99 {
@@ -84,13 +83,13 @@ func (t *TestSelectLead) GetRBProposerG1(epochID uint64) []bn256.G1 { return nil
 
 func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 	posconfig.Init(nil)
-	activityInit()
+	testInc.whiteList = newWhiteList()
 	epochID := uint64(0)
-	util.SetEpocherInst(&TestSelectLead{})
+	testInc.getEpochLeaders = (&TestSelectLead{}).GetEpochLeaders
 
 	//test bad input
 	clearTestAddrs()
-	getEpochLeaderActivity(statedb, epochID)
+	testInc.getEpochLeaderActivity(statedb, epochID)
 
 	//test good input
 	generateTestAddrs()
@@ -108,7 +107,7 @@ func TestGetEpochLeaderAddressAndActivity(t *testing.T) {
 		statedb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), keyHash, buf)
 	}
 
-	addrs, activity := getEpochLeaderActivity(statedb, epochID)
+	addrs, activity := testInc.getEpochLeaderActivity(statedb, epochID)
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != epAddrs[i].Hex() {
@@ -140,22 +139,22 @@ func testSimulateData(epochID uint64, index uint32) {
 
 func TestGetRandomProposerActivity(t *testing.T) {
 	posconfig.Init(nil)
-	activityInit()
+	testInc.whiteList = newWhiteList()
 	//test bad input
 	clearTestAddrs()
 	epochID := 0
 
-	getRandomProposerActivity(statedb, uint64(epochID))
+	testInc.getRandomProposerActivity(statedb, uint64(epochID))
 
-	setRBAddressInterface(testGetRBAddress)
+	testInc.getRandomProposerAddress = testGetRBAddress
 
-	getRandomProposerActivity(statedb, uint64(epochID))
+	testInc.getRandomProposerActivity(statedb, uint64(epochID))
 
 	// test good input
 	generateTestAddrs()
 	generateTestStaker()
 
-	addrs, activity := getRandomProposerActivity(statedb, uint64(epochID))
+	addrs, activity := testInc.getRandomProposerActivity(statedb, uint64(epochID))
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != rpAddrs[i].Hex() {
@@ -170,7 +169,7 @@ func TestGetRandomProposerActivity(t *testing.T) {
 		testSimulateData(uint64(epochID), uint32(i))
 	}
 
-	addrs, activity = getRandomProposerActivity(statedb, uint64(epochID))
+	addrs, activity = testInc.getRandomProposerActivity(statedb, uint64(epochID))
 
 	for i := 0; i < len(addrs); i++ {
 		if addrs[i].Hex() != rpAddrs[i].Hex() {
@@ -184,12 +183,12 @@ func TestGetRandomProposerActivity(t *testing.T) {
 
 func TestWhiteList(t *testing.T) {
 	posconfig.Init(nil)
-	activityInit()
-	if isInWhiteList(common.HexToAddress("0xcf696d8EEA08a311780fB89B20d4F0895198a489")) {
+	testInc.whiteList = newWhiteList()
+	if testInc.isInWhiteList(common.HexToAddress("0xcf696d8EEA08a311780fB89B20d4F0895198a489")) {
 		t.FailNow()
 	}
 
-	if !isInWhiteList(common.HexToAddress("0xb0Daf2a0a61B0f721486D3B88235a0714D60bAa6")) {
+	if !testInc.isInWhiteList(common.HexToAddress("0xb0Daf2a0a61B0f721486D3B88235a0714D60bAa6")) {
 		t.FailNow()
 	}
 }
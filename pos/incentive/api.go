@@ -1,6 +1,7 @@
 package incentive
 
 import (
+	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/consensus"
@@ -13,12 +14,9 @@ import (
 
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
-var localDb *posdb.Db
-
 var (
 	dictAllTotal       = "all_total"
 	dictEpochTotal     = "epoch_total"
@@ -34,11 +32,7 @@ var (
 	dictEpochPayDetail = "epoch_pay_detail"
 )
 
-func initLocalDb(dbName string) {
-	localDb = posdb.NewDb(dbName)
-}
-
-func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
+func (inc *Incentive) saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
 	if payments == nil {
 		return
 	}
@@ -48,47 +42,124 @@ func saveIncentiveHistory(epochID uint64, payments [][]vm.ClientIncentive) {
 		log.SyslogErr(err.Error())
 		return
 	}
-	localDb.Put(epochID, dictEpochPayDetail, buf)
+	old := inc.payDetail(epochID)
+	inc.db.Put(epochID, dictEpochPayDetail, buf)
+	if err := inc.indexRewards(epochID, old, payments); err != nil {
+		log.SyslogErr("Incentive reward index failed", "epochID", epochID, "error", err.Error())
+	}
+
+	inc.saveOtherInfomation(epochID, payments)
+}
+
+// payDetail returns the payments saved for an epoch, nil if there are none.
+func (inc *Incentive) payDetail(epochID uint64) [][]vm.ClientIncentive {
+	buf, err := inc.db.Get(epochID, dictEpochPayDetail)
+	if err != nil || len(buf) == 0 {
+		return nil
+	}
+	var payments [][]vm.ClientIncentive
+	if err := rlp.DecodeBytes(buf, &payments); err != nil {
+		return nil
+	}
+	return payments
+}
 
-	saveOtherInfomation(epochID, payments)
+// RestoreHistory replaces the local incentive history of an epoch by the one
+// of a, the totals of all epochs and the reward index are corrected
+// accordingly.
+func (inc *Incentive) RestoreHistory(epochID uint64, a *Allocation) error {
+	if inc.db == nil {
+		return errors.New("incentive is not initialized")
+	}
+	buf, err := rlp.EncodeToBytes(a.Payments)
+	if err != nil {
+		return err
+	}
+	paid := inc.payDetail(epochID)
+	oldTotal, err := inc.localDbGetValue(epochID, dictEpochTotal)
+	if err != nil {
+		return err
+	}
+	oldRemain, err := inc.localDbGetValue(epochID, dictEpochRemain)
+	if err != nil {
+		return err
+	}
+
+	newTotal := sumIncentive(a.Payments)
+	if err := inc.localDbReplaceValue(dictAllTotal, oldTotal, newTotal); err != nil {
+		return err
+	}
+	if err := inc.localDbReplaceValue(dictTotalRemain, oldRemain, a.Remain); err != nil {
+		return err
+	}
+	if paid == nil {
+		inc.addRunTimes()
+	}
+	if _, err := inc.db.Put(epochID, dictEpochPayDetail, buf); err != nil {
+		return err
+	}
+	if err := inc.indexRewards(epochID, paid, a.Payments); err != nil {
+		return err
+	}
+	if _, err := inc.db.Put(epochID, dictEpochTotal, newTotal.Bytes()); err != nil {
+		return err
+	}
+	_, err = inc.db.Put(epochID, dictEpochRemain, a.Remain.Bytes())
+	return err
+}
+
+// localDbReplaceValue replaces the part old of the total of all epochs key by
+// value.
+func (inc *Incentive) localDbReplaceValue(key string, old, value *big.Int) error {
+	total, err := inc.localDbGetValue(0, key)
+	if err != nil {
+		return err
+	}
+	total.Sub(total, old)
+	if total.Sign() < 0 {
+		total.SetUint64(0)
+	}
+	total.Add(total, value)
+	_, err = inc.db.Put(0, key, total.Bytes())
+	return err
 }
 
-func saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func (inc *Incentive) saveTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDbAddValue(0, dictAllTotal, totalIncome)
+	inc.localDbAddValue(0, dictAllTotal, totalIncome)
 }
 
-func saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
+func (inc *Incentive) saveEpochTotalIncentive(epochID uint64, incentives [][]vm.ClientIncentive) {
 	if incentives == nil {
 		return
 	}
 	totalIncome := sumIncentive(incentives)
-	localDb.Put(epochID, dictEpochTotal, totalIncome.Bytes())
+	inc.db.Put(epochID, dictEpochTotal, totalIncome.Bytes())
 }
 
-func saveRemain(epochID uint64, remain *big.Int) {
+func (inc *Incentive) saveRemain(epochID uint64, remain *big.Int) {
 	if remain == nil {
 		return
 	}
-	localDb.Put(epochID, dictEpochRemain, remain.Bytes())
-	localDbAddValue(0, dictTotalRemain, remain)
+	inc.db.Put(epochID, dictEpochRemain, remain.Bytes())
+	inc.localDbAddValue(0, dictTotalRemain, remain)
 }
 
-func addRunTimes() {
-	localDbAddValue(0, dictRunTimes, big.NewInt(1))
+func (inc *Incentive) addRunTimes() {
+	inc.localDbAddValue(0, dictRunTimes, big.NewInt(1))
 }
 
-func saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
-	saveTotalIncentive(epochID, incentives)
-	saveEpochTotalIncentive(epochID, incentives)
-	addRunTimes()
+func (inc *Incentive) saveOtherInfomation(epochID uint64, incentives [][]vm.ClientIncentive) {
+	inc.saveTotalIncentive(epochID, incentives)
+	inc.saveEpochTotalIncentive(epochID, incentives)
+	inc.addRunTimes()
 }
 
-func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
-	total, err := localDb.Get(epochID, key)
+func (inc *Incentive) localDbGetValue(epochID uint64, key string) (*big.Int, error) {
+	total, err := inc.db.Get(epochID, key)
 	if err != nil && err.Error() != "leveldb: not found" {
 		log.SyslogErr(err.Error())
 		return nil, err
@@ -101,8 +172,8 @@ func localDbGetValue(epochID uint64, key string) (*big.Int, error) {
 	return big.NewInt(0).SetBytes(total), nil
 }
 
-func localDbAddValue(epochID uint64, key string, value *big.Int) {
-	total, err := localDb.Get(epochID, key)
+func (inc *Incentive) localDbAddValue(epochID uint64, key string, value *big.Int) {
+	total, err := inc.db.Get(epochID, key)
 	if err != nil && err.Error() != "leveldb: not found" {
 		log.SyslogErr(err.Error())
 		return
@@ -112,12 +183,12 @@ func localDbAddValue(epochID uint64, key string, value *big.Int) {
 		totalNum.SetBytes(total)
 	}
 	totalNum.Add(totalNum, value)
-	localDb.Put(0, key, totalNum.Bytes())
+	inc.db.Put(0, key, totalNum.Bytes())
 }
 
 // GetEpochPayDetail use to get detail payment array
-func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
-	buf, err := localDb.Get(epochID, dictEpochPayDetail)
+func (inc *Incentive) GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
+	buf, err := inc.db.Get(epochID, dictEpochPayDetail)
 	if err != nil {
 		log.SyslogErr(err.Error())
 		return nil, err
@@ -135,28 +206,28 @@ func GetEpochPayDetail(epochID uint64) ([][]vm.ClientIncentive, error) {
 }
 
 // GetTotalIncentive get total incentive of all epoch
-func GetTotalIncentive() (*big.Int, error) {
-	return localDbGetValue(0, dictAllTotal)
+func (inc *Incentive) GetTotalIncentive() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictAllTotal)
 }
 
 // GetEpochIncentive get total incentive of all epoch
-func GetEpochIncentive(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochTotal)
+func (inc *Incentive) GetEpochIncentive(epochID uint64) (*big.Int, error) {
+	return inc.localDbGetValue(epochID, dictEpochTotal)
 }
 
 // GetEpochRemain get remain of epoch input
-func GetEpochRemain(epochID uint64) (*big.Int, error) {
-	return localDbGetValue(epochID, dictEpochRemain)
+func (inc *Incentive) GetEpochRemain(epochID uint64) (*big.Int, error) {
+	return inc.localDbGetValue(epochID, dictEpochRemain)
 }
 
 // GetTotalRemain get remain of epoch input
-func GetTotalRemain() (*big.Int, error) {
-	return localDbGetValue(0, dictTotalRemain)
+func (inc *Incentive) GetTotalRemain() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictTotalRemain)
 }
 
 // GetRunTimes returns incentive run times
-func GetRunTimes() (*big.Int, error) {
-	return localDbGetValue(0, dictRunTimes)
+func (inc *Incentive) GetRunTimes() (*big.Int, error) {
+	return inc.localDbGetValue(0, dictRunTimes)
 }
 
 // GetEpochGasPool use to get epoch gas pool
@@ -165,12 +236,12 @@ func GetEpochGasPool(stateDb vm.StateDB, epochID uint64) *big.Int {
 }
 
 // GetRBAddress use to get random proposer address list
-func GetRBAddress(epochID uint64) []common.Address {
-	if getRandomProposerAddress == nil {
+func (inc *Incentive) GetRBAddress(epochID uint64) []common.Address {
+	if inc.getRandomProposerAddress == nil {
 		return nil
 	}
 
-	leaders := getRandomProposerAddress(epochID)
+	leaders := inc.getRandomProposerAddress(epochID)
 	addrs := make([]common.Address, len(leaders))
 	for i := 0; i < len(leaders); i++ {
 		addrs[i] = leaders[i].SecAddr
@@ -185,16 +256,16 @@ func GetIncentivePool(stateDb *state.StateDB, epochID uint64) (*big.Int, *big.In
 }
 
 // GetEpochLeaderActivity can get the address and activity of epoch leaders
-func GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	return getEpochLeaderActivity(stateDb, epochID)
+func (inc *Incentive) GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	return inc.getEpochLeaderActivity(stateDb, epochID)
 }
 
 // GetEpochRBLeaderActivity can get the address and activity of RB leaders
-func GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
-	return getRandomProposerActivity(stateDb, epochID)
+func (inc *Incentive) GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Address, []int) {
+	return inc.getRandomProposerActivity(stateDb, epochID)
 }
 
 // GetSlotLeaderActivity can get the address, blockCnt, and activity of slotleader
-func GetSlotLeaderActivity(chain consensus.ChainReader, epochID uint64) ([]common.Address, []int, float64, int) {
-	return getSlotLeaderActivity(chain, epochID, posconfig.SlotCount)
+func (inc *Incentive) GetSlotLeaderActivity(chain consensus.ChainReader, epochID uint64) ([]common.Address, []int, float64, int) {
+	return inc.getSlotLeaderActivity(chain, epochID, int(posconfig.SlotCount))
 }
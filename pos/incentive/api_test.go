@@ -2,15 +2,18 @@ package incentive
 
 import (
 	"math/big"
-	"os"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 )
 
+// testInc is the incentive the tests run on.
+var testInc = &Incentive{}
+
 func testInitDb() {
-	os.RemoveAll("/tmp/pluto/gwan/incentive")
-	initLocalDb("/tmp/pluto/gwan/incentive")
+	testInc.db = posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB)
 }
 
 func TestInitLocalDB(t *testing.T) {
@@ -34,9 +37,9 @@ func TestGetEpochPayDetail(t *testing.T) {
 		},
 	}
 
-	saveIncentiveHistory(epochID, nil)
-	saveIncentiveHistory(epochID, payExample)
-	pay, err := GetEpochPayDetail(epochID)
+	testInc.saveIncentiveHistory(epochID, nil)
+	testInc.saveIncentiveHistory(epochID, payExample)
+	pay, err := testInc.GetEpochPayDetail(epochID)
 	if err != nil {
 		t.FailNow()
 	}
@@ -49,31 +52,31 @@ func TestGetEpochPayDetail(t *testing.T) {
 		}
 	}
 
-	saveIncentiveHistory(1, payExample)
+	testInc.saveIncentiveHistory(1, payExample)
 
-	total, err := GetTotalIncentive()
+	total, err := testInc.GetTotalIncentive()
 	if total.Uint64() != 3000 || err != nil {
 		t.FailNow()
 	}
 
-	total, err = GetEpochIncentive(1)
+	total, err = testInc.GetEpochIncentive(1)
 	if total.Uint64() != 1500 || err != nil {
 		t.FailNow()
 	}
 
-	saveRemain(0, big.NewInt(100))
-	saveRemain(1, big.NewInt(300))
+	testInc.saveRemain(0, big.NewInt(100))
+	testInc.saveRemain(1, big.NewInt(300))
 
-	epRemain, err := GetEpochRemain(1)
+	epRemain, err := testInc.GetEpochRemain(1)
 	if err != nil || epRemain.Uint64() != 300 {
 		t.FailNow()
 	}
-	epRemain, err = GetTotalRemain()
+	epRemain, err = testInc.GetTotalRemain()
 	if err != nil || epRemain.Uint64() != 400 {
 		t.FailNow()
 	}
 
-	value, err := GetRunTimes()
+	value, err := testInc.GetRunTimes()
 	if err != nil || value.Uint64() != 2 {
 		t.FailNow()
 	}
@@ -92,7 +95,7 @@ func TestOtherApiSuccess(t *testing.T) {
 		//t.FailNow()
 	}
 
-	rb := GetRBAddress(0)
+	rb := testInc.GetRBAddress(0)
 	if rb != nil {
 		//t.FailNow()
 	}
@@ -102,17 +105,17 @@ func TestOtherApiSuccess(t *testing.T) {
 		//t.FailNow()
 	}
 
-	// addr, act := GetEpochLeaderActivity(statedb, 0)
+	// addr, act := testInc.GetEpochLeaderActivity(statedb, 0)
 	// if len(addr) != 0 || len(act) != 0 {
 	// 	//t.FailNow()
 	// }
 
-	addr, act := GetEpochRBLeaderActivity(statedb, 0)
+	addr, act := testInc.GetEpochRBLeaderActivity(statedb, 0)
 	if len(addr) != 0 || len(act) != 0 {
 		//t.FailNow()
 	}
 
-	addrs, cnt, actf, _ := GetSlotLeaderActivity(chain, 0)
+	addrs, cnt, actf, _ := testInc.GetSlotLeaderActivity(chain, 0)
 	if len(addrs) != 0 || len(cnt) != 0 || actf != float64(0) {
 		//t.FailNow()
 	}
@@ -124,31 +127,80 @@ func TestOtherApiFail(t *testing.T) {
 		t.FailNow()
 	}
 
-	tmp := getRandomProposerAddress
-	getRandomProposerAddress = nil
-	rb := GetRBAddress(0)
+	tmp := testInc.getRandomProposerAddress
+	testInc.getRandomProposerAddress = nil
+	rb := testInc.GetRBAddress(0)
 	if rb != nil {
 		//t.FailNow()
 	}
-	getRandomProposerAddress = tmp
+	testInc.getRandomProposerAddress = tmp
 
 	total, fdt, pool := GetIncentivePool(nil, 0)
 	if total.String() != "0" || fdt.String() != "0" || pool.String() != "0" {
 		t.FailNow()
 	}
 
-	addr, act := GetEpochLeaderActivity(nil, 0)
+	addr, act := testInc.GetEpochLeaderActivity(nil, 0)
 	if len(addr) != 0 || len(act) != 0 {
 		t.FailNow()
 	}
 
-	addr, act = GetEpochRBLeaderActivity(nil, 0)
+	addr, act = testInc.GetEpochRBLeaderActivity(nil, 0)
 	if len(addr) != 0 || len(act) != 0 {
 		t.FailNow()
 	}
 
-	addrs, cnt, actf, ctrlCnt := GetSlotLeaderActivity(nil, 0)
+	addrs, cnt, actf, ctrlCnt := testInc.GetSlotLeaderActivity(nil, 0)
 	if len(addrs) != 0 || len(cnt) != 0 || actf != float64(0) || ctrlCnt != 0 {
 		t.FailNow()
 	}
 }
+
+func TestRestoreHistory(t *testing.T) {
+	generateTestAddrs()
+	testInitDb()
+
+	pay := func(values ...int64) [][]vm.ClientIncentive {
+		payments := [][]vm.ClientIncentive{{}}
+		for i, v := range values {
+			payments[0] = append(payments[0], vm.ClientIncentive{Addr: epAddrs[i], Incentive: big.NewInt(v)})
+		}
+		return payments
+	}
+	testInc.saveIncentiveHistory(0, pay(100, 200))
+	testInc.saveRemain(0, big.NewInt(10))
+	testInc.saveIncentiveHistory(1, pay(300))
+	testInc.saveRemain(1, big.NewInt(20))
+
+	// a corrupted epoch is replaced
+	if err := testInc.RestoreHistory(1, &Allocation{Payments: pay(50, 60), Remain: big.NewInt(5)}); err != nil {
+		t.Fatal(err)
+	}
+	detail, err := testInc.GetEpochPayDetail(1)
+	if err != nil || len(detail) != 1 || len(detail[0]) != 2 || detail[0][1].Incentive.Int64() != 60 {
+		t.Fatalf("got pay detail %v, %v", detail, err)
+	}
+	if total, _ := testInc.GetEpochIncentive(1); total.Int64() != 110 {
+		t.Errorf("got epoch total %v, want 110", total)
+	}
+	if total, _ := testInc.GetTotalIncentive(); total.Int64() != 410 {
+		t.Errorf("got total %v, want 410", total)
+	}
+	if remain, _ := testInc.GetTotalRemain(); remain.Int64() != 15 {
+		t.Errorf("got total remain %v, want 15", remain)
+	}
+	if runs, _ := testInc.GetRunTimes(); runs.Int64() != 2 {
+		t.Errorf("got %v runs, want 2", runs)
+	}
+
+	// a lost epoch is added
+	if err := testInc.RestoreHistory(2, &Allocation{Payments: pay(70), Remain: big.NewInt(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if total, _ := testInc.GetTotalIncentive(); total.Int64() != 480 {
+		t.Errorf("got total %v, want 480", total)
+	}
+	if runs, _ := testInc.GetRunTimes(); runs.Int64() != 3 {
+		t.Errorf("got %v runs, want 3", runs)
+	}
+}
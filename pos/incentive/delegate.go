@@ -9,12 +9,12 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 )
 
-// delegate can calc the delegate division
-func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
+// delegate can calc the delegate division, get provides the stakers of an address
+func delegate(get GetStakerInfoFn, addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	finalIncentive := make([][]vm.ClientIncentive, 0)
 	remain := big.NewInt(0)
 	for i := 0; i < len(addrs); i++ {
-		stakers, division, totalProbility, err := getStakerInfoAndCheck(epochID, addrs[i])
+		stakers, division, totalProbility, err := getStakerInfoAndCheck(get, epochID, addrs[i])
 		if err != nil {
 			log.SyslogErr(err.Error())
 			continue
@@ -27,8 +27,8 @@ func delegate(addrs []common.Address, values []*big.Int, epochID uint64) ([][]vm
 	return finalIncentive, remain, nil
 }
 
-func getStakerInfoAndCheck(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
-	stakers, division, totalProbility, err := getStakerInfo(epochID, addr)
+func getStakerInfoAndCheck(get GetStakerInfoFn, epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
+	stakers, division, totalProbility, err := get(epochID, addr)
 	if err != nil {
 		log.SyslogErr("getStakerInfo error", "error", err.Error())
 		return nil, 0, nil, err
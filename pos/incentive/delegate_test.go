@@ -14,14 +14,14 @@ func TestDelegate(t *testing.T) {
 	TestSetActivityInterface(t)
 	TestSetStakerInterface(t)
 
-	epAddrs, _ := getEpochLeaderInfo(statedb, 0)
+	epAddrs, _ := testInc.getEpochLeaderInfo(statedb, 0)
 
 	values := make([]*big.Int, len(epAddrs))
 	for i := 0; i < len(values); i++ {
 		values[i] = big.NewInt(1e18)
 	}
 
-	finalIncentive, remain, err := delegate(epAddrs, values, 0)
+	finalIncentive, remain, err := delegate(testInc.getStakerInfo, epAddrs, values, 0)
 
 	if err != nil {
 		t.FailNow()
@@ -10,27 +10,34 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
 
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/crypto"
 
 	"github.com/wanchain/go-wanchain/core/state"
 )
 
 var (
-	redutionYears            = 1
-	redutionRateBase         = 0.88                                                                                   //88% redution for every year
-	subsidyReductionInterval = uint64((365 * 24 * 3600 * redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)) // Epoch count in 1 years
-	percentOfEpochLeader     = 12.0 / 49.0                                                                            //24.4898%
-	percentOfRandomProposer  = 25.0 / 49.0                                                                            //51.0204%
-	percentOfSlotLeader      = 12.0 / 49.0                                                                            //24.4898%
-	ceilingPercentS0         = 100.0                                                                                  //100% Turn off in current version.
-	openIncentive            = true                                                                                   //If the incentive function is open
-	firstPeriodReward        = big.NewInt(0).Mul(big.NewInt(2.5e6), big.NewInt(1e18))                                 // 2500000 wan coin for first year
+	redutionYears           = 1
+	redutionRateBase        = 0.88                                                   //88% redution for every year
+	percentOfEpochLeader    = 12.0 / 49.0                                            //24.4898%
+	percentOfRandomProposer = 25.0 / 49.0                                            //51.0204%
+	percentOfSlotLeader     = 12.0 / 49.0                                            //24.4898%
+	ceilingPercentS0        = 100.0                                                  //100% Turn off in current version.
+	openIncentive           = true                                                   //If the incentive function is open
+	firstPeriodReward       = big.NewInt(0).Mul(big.NewInt(2.5e6), big.NewInt(1e18)) // 2500000 wan coin for first year
 )
 
+// subsidyReductionInterval returns the epoch count in redutionYears.
+func subsidyReductionInterval() uint64 {
+	return uint64(365*24*3600*redutionYears) / (posconfig.SlotTime * posconfig.SlotCount)
+}
+
 const (
 	dictGasCollection = "gas_collection"
 	dictEpochRun      = "epoch_run"
@@ -38,44 +45,136 @@ const (
 	dictFinished      = "finished"
 )
 
-// Init is use to init the outsides interface of staker.
-// Should be called at the node start
-func Init(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerAddressFn) {
-	activityInit()
-	if get == nil || set == nil || getRbAddr == nil {
-		log.SyslogErr("incentive Init input param error (get == nil || set == nil || getRbAddr == nil)")
+// New creates the incentive of a node from the outsides interface of staker
+// and the db the incentive history is saved to. Should be called at the node
+// start
+func New(get GetStakerInfoFn, set SetStakerInfoFn, getRbAddr GetRandomProposerAddressFn,
+	getEpl GetEpochLeadersFn, db *posdb.Db) *Incentive {
+	if get == nil || set == nil || getRbAddr == nil || getEpl == nil {
+		log.SyslogErr("incentive New input param error (get == nil || set == nil || getRbAddr == nil || getEpl == nil)")
 	}
 
-	setStakerInterface(get, set)
-	setActivityInterface(getEpochLeaderActivity, getRandomProposerActivity, getSlotLeaderActivity)
-	setRBAddressInterface(getRbAddr)
+	inc := &Incentive{
+		db:                       db,
+		whiteList:                newWhiteList(),
+		getStakerInfo:            get,
+		setStakerInfo:            set,
+		getRandomProposerAddress: getRbAddr,
+		getEpochLeaders:          getEpl,
+		paid:                     make(map[uint64]posevent.IncentivePaidEvent),
+	}
+	inc.getEpochLeaderInfo = inc.getEpochLeaderActivity
+	inc.getRandomProposerInfo = inc.getRandomProposerActivity
+	inc.getSlotLeaderInfo = inc.getSlotLeaderActivity
+	if db != nil {
+		if err := inc.backfillRewards(); err != nil {
+			log.Warn("Failed to index the incentive rewards of past epochs", "err", err)
+		}
+	}
 
-	initLocalDb(posconfig.IncentiveLocalDB)
 	log.Info("--------Incentive Init Finish----------")
+	return inc
+}
+
+// Allocation is the incentive of an epoch divided among its receivers.
+type Allocation struct {
+	Total      *big.Int // incentive pool of the epoch
+	Foundation *big.Int
+	GasPool    *big.Int
+
+	EpochLeaderSubsidy    *big.Int
+	RandomProposerSubsidy *big.Int
+	SlotLeaderSubsidy     *big.Int
+
+	Payments [][]vm.ClientIncentive // per paid staker, to it and its delegators
+	Remain   *big.Int               // not paid, moved to the pool of the next period
 }
 
 // Run is use to run the incentive should be called in Finalize of consensus
-func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bool {
+func (inc *Incentive) Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bool {
+	a, ok := inc.run(chain, stateDb, epochID)
+	if !ok || a == nil {
+		return ok
+	}
+
+	// Run is also called for side chain blocks and blocks sealed locally, the
+	// payment is notified once a canonical block pays it.
+	inc.paidMu.Lock()
+	inc.paid[epochID] = posevent.IncentivePaidEvent{
+		EpochID:    epochID,
+		Total:      (*hexutil.Big)(a.Total),
+		Foundation: (*hexutil.Big)(a.Foundation),
+		GasPool:    (*hexutil.Big)(a.GasPool),
+		Receivers:  countReceivers(a.Payments),
+	}
+	inc.paidMu.Unlock()
+	return true
+}
+
+// run pays the incentive of epochID in stateDb and saves it in the local
+// history. It returns a nil allocation if the epoch is already paid.
+func (inc *Incentive) run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) (*Allocation, bool) {
 	if chain == nil || stateDb == nil {
 		log.SyslogErr("incentive Run input param error (chain == nil || stateDb == nil)")
-		return false
+		return nil, false
 	}
 
 	if isFinished(stateDb, epochID) || !openIncentive {
-		return true
+		return nil, true
 	}
+
+	a, err := inc.Allocate(chain, stateDb, epochID)
+	if err != nil {
+		return nil, false
+	}
+
+	inc.saveRemain(epochID, a.Remain)
+	a.Apply(stateDb, epochID)
+
+	inc.setStakerInfo(epochID, a.Payments)
+	inc.saveIncentiveHistory(epochID, a.Payments)
+	return a, true
+}
+
+// PaidEvent returns the notification of the incentive of epochID paid by a
+// block, from the states before and after it. It returns false if the block
+// doesn't pay it.
+func (inc *Incentive) PaidEvent(pre, post *state.StateDB, epochID uint64) (posevent.IncentivePaidEvent, bool) {
+	if isFinished(pre, epochID) || !isFinished(post, epochID) {
+		return posevent.IncentivePaidEvent{}, false
+	}
+
+	inc.paidMu.Lock()
+	defer inc.paidMu.Unlock()
+
+	ev, ok := inc.paid[epochID]
+	if !ok {
+		// computed before the node started, the history has the receivers
+		ev = posevent.IncentivePaidEvent{EpochID: epochID, Receivers: countReceivers(inc.payDetail(epochID))}
+	}
+	for id := range inc.paid {
+		if id <= epochID {
+			delete(inc.paid, id)
+		}
+	}
+	return ev, true
+}
+
+// Allocate calculates the incentive of an epoch from the state it is paid on,
+// without paying it.
+func (inc *Incentive) Allocate(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) (*Allocation, error) {
 	finalIncentive := make([][]vm.ClientIncentive, 0)
 	remainsAll := big.NewInt(0)
 
 	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID)
 	saveIncentiveIncome(total, foundation, gasPool)
 
-	epAddrs, epAct := getEpochLeaderInfo(stateDb, epochID)
+	epAddrs, epAct := inc.getEpochLeaderInfo(stateDb, epochID)
 	log.Info("epoch addr", "len", len(epAddrs))
-	rpAddrs, rpAct := getRandomProposerInfo(stateDb, epochID)
+	rpAddrs, rpAct := inc.getRandomProposerInfo(stateDb, epochID)
 	log.Info("rp Addrs", "len", len(rpAddrs))
 
-	slAddrs, slBlk, slAct, ctrlCount := getSlotLeaderInfo(chain, epochID, posconfig.SlotCount)
+	slAddrs, slBlk, slAct, ctrlCount := inc.getSlotLeaderInfo(chain, epochID, int(posconfig.SlotCount))
 	log.Info("sl Addr ", "len", len(slAddrs), "slAct", slAct, "ctrlCount", ctrlCount)
 	log.Info("sl Blk ", "len", len(slBlk), "blks", slBlk)
 
@@ -84,6 +183,15 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 	slotLeaderSubsidy := calcPercent(total, float64(percentOfSlotLeader*100.0))
 	saveIncentiveDivide(epochLeaderSubsidy, randomProposerSubsidy, slotLeaderSubsidy)
 
+	a := &Allocation{
+		Total:                 total,
+		Foundation:            foundation,
+		GasPool:               gasPool,
+		EpochLeaderSubsidy:    new(big.Int).Set(epochLeaderSubsidy),
+		RandomProposerSubsidy: new(big.Int).Set(randomProposerSubsidy),
+		SlotLeaderSubsidy:     new(big.Int).Set(slotLeaderSubsidy),
+	}
+
 	sum := big.NewInt(0)
 	sum.Add(sum, epochLeaderSubsidy)
 	sum.Add(sum, randomProposerSubsidy)
@@ -91,10 +199,10 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 	sumRemain := big.NewInt(0).Sub(total, sum)
 	remainsAll.Add(remainsAll, sumRemain)
 
-	incentives, remains, err := epochLeaderAllocate(epochLeaderSubsidy, epAddrs, epAct, epochID)
+	incentives, remains, err := epochLeaderAllocate(inc.getStakerInfo, epochLeaderSubsidy, epAddrs, epAct, epochID)
 	if err != nil {
 		log.SyslogErr("Incentive epochLeaderAllocate error", "error", err.Error(), "epochLeaderSubsidy", epochLeaderSubsidy.String(), "epAddrs", epAddrs)
-		return false
+		return nil, err
 	}
 
 	if incentives != nil {
@@ -106,10 +214,10 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = randomProposerAllocate(randomProposerSubsidy, rpAddrs, rpAct, epochID)
+	incentives, remains, err = randomProposerAllocate(inc.getStakerInfo, randomProposerSubsidy, rpAddrs, rpAct, epochID)
 	if err != nil {
 		log.SyslogErr("Incentive randomProposerAllocate error", "error", err.Error(), "randomProposerSubsidy", randomProposerSubsidy.String(), "rpAddrs", rpAddrs)
-		return false
+		return nil, err
 	}
 
 	if incentives != nil {
@@ -121,10 +229,10 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 
 	remainsAll.Add(remainsAll, remains)
 
-	incentives, remains, err = slotLeaderAllocate(slotLeaderSubsidy, slAddrs, slBlk, slAct, posconfig.SlotCount-ctrlCount, epochID)
+	incentives, remains, err = slotLeaderAllocate(inc.getStakerInfo, slotLeaderSubsidy, slAddrs, slBlk, slAct, int(posconfig.SlotCount)-ctrlCount, epochID)
 	if err != nil {
 		log.SyslogErr("Incentive slotLeaderAllocate error", "slotLeaderSubsidy", slotLeaderSubsidy.String(), "slAddrs", slAddrs)
-		return false
+		return nil, err
 	}
 
 	if incentives != nil {
@@ -141,19 +249,27 @@ func Run(chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bo
 	remainsAll.Add(remainsAll, extraRemain)
 	if !checkTotalValue(total, sumPay, remainsAll) {
 		log.SyslogErr("Incentive checkTotalValue error", "sumPay", sumPay.String(), "remainsAll", remainsAll.String(), "total", total.String())
-		return false
+		return nil, errors.New("incentive payments exceed the pool")
 	}
 
-	addRemainIncentivePool(stateDb, epochID, remainsAll)
-	saveRemain(epochID, remainsAll)
-
-	pay(finalIncentive, stateDb)
-
-	setStakerInfo(epochID, finalIncentive)
-	saveIncentiveHistory(epochID, finalIncentive)
+	a.Payments = finalIncentive
+	a.Remain = remainsAll
+	return a, nil
+}
 
+// Apply pays the allocation of epochID in stateDb and marks the epoch paid.
+func (a *Allocation) Apply(stateDb *state.StateDB, epochID uint64) {
+	addRemainIncentivePool(stateDb, epochID, a.Remain)
+	pay(a.Payments, stateDb)
 	finished(stateDb, epochID)
-	return true
+}
+
+func countReceivers(incentives [][]vm.ClientIncentive) int {
+	count := 0
+	for i := 0; i < len(incentives); i++ {
+		count += len(incentives[i])
+	}
+	return count
 }
 
 func getIncentivePrecompileAddress() common.Address {
@@ -178,7 +294,7 @@ func finished(stateDb *state.StateDB, epochID uint64) {
 }
 
 // protocalRunerAllocate use to calc the subsidy of protocal Participant (Epoch leader and Random proposer)
-func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func protocalRunerAllocate(get GetStakerInfoFn, funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 
@@ -212,7 +328,7 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 		}
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID)
+	finalIncentive, subRemain, err := delegate(get, fundAddrs, fundValues, epochID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -222,20 +338,20 @@ func protocalRunerAllocate(funds *big.Int, addrs []common.Address, acts []int,
 }
 
 // epochLeaderAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
-func epochLeaderAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func epochLeaderAllocate(get GetStakerInfoFn, funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	return protocalRunerAllocate(get, funds, addrs, acts, epochID)
 }
 
 //randomProposerAllocate input funds, address and activity returns address and its amount allocate and remaining funds.
-func randomProposerAllocate(funds *big.Int, addrs []common.Address, acts []int,
+func randomProposerAllocate(get GetStakerInfoFn, funds *big.Int, addrs []common.Address, acts []int,
 	epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
-	return protocalRunerAllocate(funds, addrs, acts, epochID)
+	return protocalRunerAllocate(get, funds, addrs, acts, epochID)
 }
 
 //slotLeaderAllocate input funds, address, blocks and activity returns address and its amount allocate and remaining funds.
 //slotCount is the slot count ctrled by others not foundation.
-func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
+func slotLeaderAllocate(get GetStakerInfoFn, funds *big.Int, addrs []common.Address, blocks []int,
 	act float64, slotCount int, epochID uint64) ([][]vm.ClientIncentive, *big.Int, error) {
 	remains := big.NewInt(0)
 
@@ -265,7 +381,7 @@ func slotLeaderAllocate(funds *big.Int, addrs []common.Address, blocks []int,
 		fundValues = append(fundValues, big.NewInt(0).Mul(incentiveActive, big.NewInt(int64(blocks[i]))))
 	}
 
-	finalIncentive, subRemain, err := delegate(fundAddrs, fundValues, epochID-1)
+	finalIncentive, subRemain, err := delegate(get, fundAddrs, fundValues, epochID-1)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 )
 
 // Prepare a simulate stateDB ---------------------------------------------
@@ -20,15 +21,15 @@ var (
 
 func TestRun(t *testing.T) {
 	posconfig.Init(nil)
-	Init(getInfo, setInfo, testGetRBAddress)
+	testInc = New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB))
 	TestSetActivityInterface(t)
 	TestSetStakerInterface(t)
 
 	testTimes := 1
 
 	for i := 0; i < testTimes; i++ {
-		for m := 0; m < posconfig.SlotCount; m++ {
-			if !Run(&TestChainReader{}, statedb, uint64(i)) {
+		for m := uint64(0); m < posconfig.SlotCount; m++ {
+			if !testInc.Run(&TestChainReader{}, statedb, uint64(i)) {
 				t.FailNow()
 			}
 		}
@@ -55,11 +56,54 @@ func TestRun(t *testing.T) {
 }
 
 func TestRunFail(t *testing.T) {
-	if Run(nil, nil, 0) {
+	if testInc.Run(nil, nil, 0) {
 		t.FailNow()
 	}
 }
 
+// Tests that the payment of an epoch is notified for the block paying it, with
+// the summary of the last computation of the epoch.
+func TestPaidEvent(t *testing.T) {
+	posconfig.Init(nil)
+	testInc = New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB))
+	TestSetActivityInterface(t)
+	TestSetStakerInterface(t)
+
+	memDb, _ := ethdb.NewMemDatabase()
+	pre, _ := state.New(common.Hash{}, state.NewDatabase(memDb))
+	post := pre.Copy()
+	if !testInc.Run(&TestChainReader{}, post, 3) {
+		t.Fatal("incentive of epoch 3 not paid")
+	}
+
+	if _, ok := testInc.PaidEvent(pre, pre, 3); ok {
+		t.Fatal("notified a block not paying the epoch")
+	}
+	if _, ok := testInc.PaidEvent(post, post, 3); ok {
+		t.Fatal("notified a block after the one paying the epoch")
+	}
+	ev, ok := testInc.PaidEvent(pre, post, 3)
+	if !ok || ev.EpochID != 3 || ev.Total == nil || ev.Receivers == 0 {
+		t.Fatalf("got %+v, %v", ev, ok)
+	}
+	// another canonical block paying it after a reorg, from the history
+	again, ok := testInc.PaidEvent(pre, post, 3)
+	if !ok || again.EpochID != 3 || again.Receivers != ev.Receivers {
+		t.Fatalf("got %+v, %v", again, ok)
+	}
+
+	// the recomputation of a past payment, as Rebuild does, is not notified
+	if a, ok := testInc.run(&TestChainReader{}, pre.Copy(), 4); !ok || a == nil {
+		t.Fatal("incentive of epoch 4 not recomputed")
+	}
+	testInc.paidMu.Lock()
+	_, recorded := testInc.paid[4]
+	testInc.paidMu.Unlock()
+	if recorded {
+		t.Fatal("recorded the payment of a recomputed epoch")
+	}
+}
+
 func TestCheckTotalValue(t *testing.T) {
 	total := big.NewInt(1000)
 
@@ -93,10 +137,14 @@ func TestCheckTotalValue(t *testing.T) {
 	}
 }
 
-func TestInit(t *testing.T) {
-	Init(getInfo, setInfo, testGetRBAddress)
+func TestNew(t *testing.T) {
+	if New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB)) == nil {
+		t.FailNow()
+	}
 }
 
-func TestInitFail(t *testing.T) {
-	Init(nil, nil, nil)
+func TestNewFail(t *testing.T) {
+	if New(nil, nil, nil, nil, posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB)) == nil {
+		t.FailNow()
+	}
 }
@@ -0,0 +1,326 @@
+package incentive
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// The reward index keeps, next to the pay detail of every epoch, the rewards
+// of every receiver and validator of the epoch and the list of the epochs each
+// of them was paid in, so the rewards of an address over many epochs are read
+// without decoding the pay detail of each of them.
+var (
+	dictDelegatorReward = "delegator_reward" // rewards of an address in an epoch
+	dictValidatorReward = "validator_reward" // rewards paid through a validator in an epoch
+	dictDelegatorEpochs = "delegator_epochs" // epochs an address was paid in
+	dictValidatorEpochs = "validator_epochs" // epochs a validator paid rewards in
+	dictRewardsIndexed  = "rewards_indexed"  // set once the epochs paid before the index are indexed
+)
+
+// DelegatorReward is the incentive an address received in an epoch through a
+// validator, as one of its delegators or as the validator itself.
+type DelegatorReward struct {
+	EpochID   uint64
+	Validator common.Address
+	Amount    *big.Int
+}
+
+// ValidatorReward is the incentive paid in an epoch through a validator.
+type ValidatorReward struct {
+	EpochID    uint64
+	Total      *big.Int // paid to the validator and its delegators
+	Self       *big.Int // paid to the validator, commission included
+	Delegators uint64   // delegators paid
+}
+
+// epochRewards are the rewards of an epoch by address.
+type epochRewards struct {
+	delegators map[common.Address][]DelegatorReward
+	validators map[common.Address]*ValidatorReward
+}
+
+// rewardsOf indexes the payments of an epoch. The first payment of every
+// staker is to the validator, the others to its delegators. A validator paid
+// for several roles has several stakers.
+func rewardsOf(epochID uint64, payments [][]vm.ClientIncentive) *epochRewards {
+	r := &epochRewards{
+		delegators: make(map[common.Address][]DelegatorReward),
+		validators: make(map[common.Address]*ValidatorReward),
+	}
+	delegated := make(map[common.Address]map[common.Address]bool)
+	for _, staker := range payments {
+		if len(staker) == 0 {
+			continue
+		}
+		validator := staker[0].Addr
+		v := r.validators[validator]
+		if v == nil {
+			v = &ValidatorReward{EpochID: epochID, Total: new(big.Int), Self: new(big.Int)}
+			r.validators[validator] = v
+			delegated[validator] = make(map[common.Address]bool)
+		}
+		for _, p := range staker {
+			v.Total.Add(v.Total, p.Incentive)
+			if p.Addr == validator {
+				v.Self.Add(v.Self, p.Incentive)
+			} else if !delegated[validator][p.Addr] {
+				delegated[validator][p.Addr] = true
+				v.Delegators++
+			}
+			r.addDelegator(epochID, p.Addr, validator, p.Incentive)
+		}
+	}
+	return r
+}
+
+func (r *epochRewards) addDelegator(epochID uint64, addr, validator common.Address, amount *big.Int) {
+	rewards := r.delegators[addr]
+	for i := range rewards {
+		if rewards[i].Validator == validator {
+			rewards[i].Amount.Add(rewards[i].Amount, amount)
+			return
+		}
+	}
+	r.delegators[addr] = append(rewards, DelegatorReward{EpochID: epochID, Validator: validator, Amount: new(big.Int).Set(amount)})
+}
+
+// indexRewards replaces the rewards of an epoch paid old in the index by the
+// ones of payments.
+func (inc *Incentive) indexRewards(epochID uint64, old, payments [][]vm.ClientIncentive) error {
+	prev, next := rewardsOf(epochID, old), rewardsOf(epochID, payments)
+
+	for addr := range prev.delegators {
+		if _, ok := next.delegators[addr]; !ok {
+			if err := inc.unindexReward(epochID, dictDelegatorReward, dictDelegatorEpochs, addr); err != nil {
+				return err
+			}
+		}
+	}
+	for addr := range prev.validators {
+		if _, ok := next.validators[addr]; !ok {
+			if err := inc.unindexReward(epochID, dictValidatorReward, dictValidatorEpochs, addr); err != nil {
+				return err
+			}
+		}
+	}
+	for addr, rewards := range next.delegators {
+		if err := inc.putReward(epochID, dictDelegatorReward, dictDelegatorEpochs, addr, rewards); err != nil {
+			return err
+		}
+	}
+	for addr, reward := range next.validators {
+		if err := inc.putReward(epochID, dictValidatorReward, dictValidatorEpochs, addr, reward); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backfillRewards indexes the epochs paid before the reward index existed,
+// from their pay detail, the first time the db is opened with the index. The
+// chain state is not needed.
+func (inc *Incentive) backfillRewards() error {
+	if buf, err := inc.db.Get(0, dictRewardsIndexed); err == nil && len(buf) > 0 {
+		return nil
+	}
+	epochs, err := inc.db.Epochs(dictEpochPayDetail)
+	if err != nil {
+		return err
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+
+	// the epoch lists are written once per address, not once per epoch
+	delegatorEpochs := make(map[common.Address][]uint64)
+	validatorEpochs := make(map[common.Address][]uint64)
+	for _, epochID := range epochs {
+		r := rewardsOf(epochID, inc.payDetail(epochID))
+		for addr, rewards := range r.delegators {
+			if err := inc.putRewardOnly(epochID, dictDelegatorReward, addr, rewards); err != nil {
+				return err
+			}
+			delegatorEpochs[addr] = append(delegatorEpochs[addr], epochID)
+		}
+		for addr, reward := range r.validators {
+			if err := inc.putRewardOnly(epochID, dictValidatorReward, addr, reward); err != nil {
+				return err
+			}
+			validatorEpochs[addr] = append(validatorEpochs[addr], epochID)
+		}
+	}
+	for addr, paid := range delegatorEpochs {
+		if err := inc.mergeRewardEpochs(dictDelegatorEpochs, addr, paid); err != nil {
+			return err
+		}
+	}
+	for addr, paid := range validatorEpochs {
+		if err := inc.mergeRewardEpochs(dictValidatorEpochs, addr, paid); err != nil {
+			return err
+		}
+	}
+	if len(epochs) > 0 {
+		log.Info("Indexed the incentive rewards of past epochs", "epochs", len(epochs))
+	}
+	_, err = inc.db.Put(0, dictRewardsIndexed, []byte{1})
+	return err
+}
+
+// putRewardOnly stores the reward of an address in an epoch, leaving the list
+// of the epochs of the address to the caller.
+func (inc *Incentive) putRewardOnly(epochID uint64, dict string, addr common.Address, reward interface{}) error {
+	buf, err := rlp.EncodeToBytes(reward)
+	if err != nil {
+		return err
+	}
+	_, err = inc.db.Put(epochID, rewardKey(dict, addr), buf)
+	return err
+}
+
+// mergeRewardEpochs adds the ascending epochs paid to the epochs an address
+// already has rewards in.
+func (inc *Incentive) mergeRewardEpochs(epochsDict string, addr common.Address, paid []uint64) error {
+	epochs := inc.rewardEpochs(epochsDict, addr)
+	merged := make([]uint64, 0, len(epochs)+len(paid))
+	for len(epochs) > 0 || len(paid) > 0 {
+		switch {
+		case len(paid) == 0 || (len(epochs) > 0 && epochs[0] < paid[0]):
+			merged, epochs = append(merged, epochs[0]), epochs[1:]
+		case len(epochs) == 0 || paid[0] < epochs[0]:
+			merged, paid = append(merged, paid[0]), paid[1:]
+		default:
+			merged, epochs, paid = append(merged, epochs[0]), epochs[1:], paid[1:]
+		}
+	}
+	return inc.putRewardEpochs(epochsDict, addr, merged)
+}
+
+func rewardKey(dict string, addr common.Address) string {
+	return dict + "_" + common.Bytes2Hex(addr[:])
+}
+
+func (inc *Incentive) putReward(epochID uint64, dict, epochsDict string, addr common.Address, reward interface{}) error {
+	if err := inc.putRewardOnly(epochID, dict, addr, reward); err != nil {
+		return err
+	}
+	epochs := inc.rewardEpochs(epochsDict, addr)
+	i := sort.Search(len(epochs), func(i int) bool { return epochs[i] >= epochID })
+	if i < len(epochs) && epochs[i] == epochID {
+		return nil
+	}
+	epochs = append(epochs, 0)
+	copy(epochs[i+1:], epochs[i:])
+	epochs[i] = epochID
+	return inc.putRewardEpochs(epochsDict, addr, epochs)
+}
+
+// unindexReward removes the reward of an address in an epoch, the db cannot
+// delete so the reward is left empty.
+func (inc *Incentive) unindexReward(epochID uint64, dict, epochsDict string, addr common.Address) error {
+	if _, err := inc.db.Put(epochID, rewardKey(dict, addr), []byte{}); err != nil {
+		return err
+	}
+	epochs := inc.rewardEpochs(epochsDict, addr)
+	i := sort.Search(len(epochs), func(i int) bool { return epochs[i] >= epochID })
+	if i == len(epochs) || epochs[i] != epochID {
+		return nil
+	}
+	return inc.putRewardEpochs(epochsDict, addr, append(epochs[:i], epochs[i+1:]...))
+}
+
+// rewardEpochs returns the epochs an address has rewards in, in ascending
+// order.
+func (inc *Incentive) rewardEpochs(epochsDict string, addr common.Address) []uint64 {
+	buf, err := inc.db.Get(0, rewardKey(epochsDict, addr))
+	if err != nil || len(buf) == 0 {
+		return nil
+	}
+	var epochs []uint64
+	if err := rlp.DecodeBytes(buf, &epochs); err != nil {
+		return nil
+	}
+	return epochs
+}
+
+func (inc *Incentive) putRewardEpochs(epochsDict string, addr common.Address, epochs []uint64) error {
+	buf, err := rlp.EncodeToBytes(epochs)
+	if err != nil {
+		return err
+	}
+	_, err = inc.db.Put(0, rewardKey(epochsDict, addr), buf)
+	return err
+}
+
+// rewardPage returns up to limit epochs an address has rewards in from
+// fromEpoch to toEpoch, and the epoch the next page starts at, or nil if there
+// is none.
+func (inc *Incentive) rewardPage(epochsDict string, addr common.Address, fromEpoch, toEpoch uint64, limit int) ([]uint64, *uint64, error) {
+	if inc.db == nil {
+		return nil, nil, errors.New("incentive is not initialized")
+	}
+	if fromEpoch > toEpoch {
+		return nil, nil, errors.New("from epoch is after to epoch")
+	}
+	epochs := inc.rewardEpochs(epochsDict, addr)
+	i := sort.Search(len(epochs), func(i int) bool { return epochs[i] >= fromEpoch })
+	j := sort.Search(len(epochs), func(i int) bool { return epochs[i] > toEpoch })
+	if j-i <= limit {
+		return epochs[i:j], nil, nil
+	}
+	next := epochs[i+limit]
+	return epochs[i : i+limit], &next, nil
+}
+
+// GetDelegatorRewards returns the rewards of an address from fromEpoch to
+// toEpoch, by epoch and validator, for up to limit epochs. next is the epoch
+// the rest of the rewards start at, nil if there are no more.
+func (inc *Incentive) GetDelegatorRewards(addr common.Address, fromEpoch, toEpoch uint64, limit int) (rewards []DelegatorReward, next *uint64, err error) {
+	epochs, next, err := inc.rewardPage(dictDelegatorEpochs, addr, fromEpoch, toEpoch, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewards = make([]DelegatorReward, 0, len(epochs))
+	for _, epochID := range epochs {
+		buf, err := inc.db.Get(epochID, rewardKey(dictDelegatorReward, addr))
+		if err != nil {
+			return nil, nil, err
+		}
+		var epochRewards []DelegatorReward
+		if err := rlp.DecodeBytes(buf, &epochRewards); err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(epochRewards, func(i, k int) bool {
+			return bytes.Compare(epochRewards[i].Validator[:], epochRewards[k].Validator[:]) < 0
+		})
+		rewards = append(rewards, epochRewards...)
+	}
+	return rewards, next, nil
+}
+
+// GetValidatorRewards returns the rewards paid through a validator from
+// fromEpoch to toEpoch, for up to limit epochs. next is the epoch the rest of
+// the rewards start at, nil if there are no more.
+func (inc *Incentive) GetValidatorRewards(addr common.Address, fromEpoch, toEpoch uint64, limit int) (rewards []ValidatorReward, next *uint64, err error) {
+	epochs, next, err := inc.rewardPage(dictValidatorEpochs, addr, fromEpoch, toEpoch, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	rewards = make([]ValidatorReward, 0, len(epochs))
+	for _, epochID := range epochs {
+		buf, err := inc.db.Get(epochID, rewardKey(dictValidatorReward, addr))
+		if err != nil {
+			return nil, nil, err
+		}
+		var reward ValidatorReward
+		if err := rlp.DecodeBytes(buf, &reward); err != nil {
+			return nil, nil, err
+		}
+		rewards = append(rewards, reward)
+	}
+	return rewards, next, nil
+}
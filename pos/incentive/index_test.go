@@ -0,0 +1,123 @@
+package incentive
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func TestRewardIndex(t *testing.T) {
+	testInitDb()
+	validator, other := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+	delegator := common.HexToAddress("0x03")
+
+	payment := func(addr common.Address, v int64) vm.ClientIncentive {
+		return vm.ClientIncentive{Addr: addr, Incentive: big.NewInt(v)}
+	}
+	for epochID := uint64(1); epochID <= 5; epochID++ {
+		testInc.saveIncentiveHistory(epochID, [][]vm.ClientIncentive{
+			// epoch leader and slot leader of the epoch
+			{payment(validator, 10), payment(delegator, 1)},
+			{payment(validator, 20), payment(delegator, 2)},
+			{payment(other, 30), payment(delegator, 3)},
+		})
+	}
+
+	rewards, next, err := testInc.GetDelegatorRewards(delegator, 2, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewards) != 4 || next == nil || *next != 4 {
+		t.Fatalf("got %d rewards next %v, want 4 next 4", len(rewards), next)
+	}
+	if r := rewards[0]; r.EpochID != 2 || r.Validator != validator || r.Amount.Int64() != 3 {
+		t.Errorf("got reward %+v, want 3 through %x in epoch 2", r, validator)
+	}
+	if r := rewards[3]; r.EpochID != 3 || r.Validator != other || r.Amount.Int64() != 3 {
+		t.Errorf("got reward %+v, want 3 through %x in epoch 3", r, other)
+	}
+
+	vrewards, next, err := testInc.GetValidatorRewards(validator, 0, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vrewards) != 4 || next != nil {
+		t.Fatalf("got %d rewards next %v, want 4 next nil", len(vrewards), next)
+	}
+	if r := vrewards[0]; r.EpochID != 1 || r.Total.Int64() != 33 || r.Self.Int64() != 30 || r.Delegators != 1 {
+		t.Errorf("got validator reward %+v", r)
+	}
+
+	// a history replaced without the delegator removes it from the index
+	if err := testInc.RestoreHistory(3, &Allocation{
+		Payments: [][]vm.ClientIncentive{{payment(validator, 40)}},
+		Remain:   big.NewInt(0),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	rewards, _, _ = testInc.GetDelegatorRewards(delegator, 3, 3, 10)
+	if len(rewards) != 0 {
+		t.Errorf("got %d rewards in the replaced epoch, want 0", len(rewards))
+	}
+	vrewards, _, _ = testInc.GetValidatorRewards(validator, 3, 3, 10)
+	if len(vrewards) != 1 || vrewards[0].Total.Int64() != 40 || vrewards[0].Delegators != 0 {
+		t.Errorf("got validator rewards %+v in the replaced epoch", vrewards)
+	}
+	if vrewards, _, _ = testInc.GetValidatorRewards(other, 3, 3, 10); len(vrewards) != 0 {
+		t.Errorf("got %d rewards of a validator not paid in the replaced epoch, want 0", len(vrewards))
+	}
+
+	if _, _, err := testInc.GetDelegatorRewards(delegator, 5, 4, 10); err == nil {
+		t.Error("expected an error for an empty range")
+	}
+}
+
+// Tests that the epochs paid before the reward index existed are indexed from
+// their pay detail when the db is opened.
+func TestRewardIndexBackfill(t *testing.T) {
+	db := posdb.NewMemoryDbs().Get(posconfig.IncentiveLocalDB)
+	validator, delegator := common.HexToAddress("0x01"), common.HexToAddress("0x03")
+	payments := [][]vm.ClientIncentive{{
+		{Addr: validator, Incentive: big.NewInt(10)},
+		{Addr: delegator, Incentive: big.NewInt(1)},
+	}}
+
+	// older versions only saved the pay detail
+	buf, err := rlp.EncodeToBytes(payments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, epochID := range []uint64{2, 11} {
+		if _, err := db.Put(epochID, dictEpochPayDetail, buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inc := New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, db)
+	inc.saveIncentiveHistory(12, payments)
+
+	rewards, _, err := inc.GetDelegatorRewards(delegator, 0, 20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rewards) != 3 || rewards[0].EpochID != 2 || rewards[1].EpochID != 11 || rewards[2].EpochID != 12 {
+		t.Fatalf("got rewards %+v, want epochs 2, 11 and 12", rewards)
+	}
+	if r := rewards[1]; r.Validator != validator || r.Amount.Int64() != 1 {
+		t.Errorf("got reward %+v, want 1 through %x", r, validator)
+	}
+
+	// the epochs indexed since are kept when the db is opened again
+	inc = New(getInfo, setInfo, testGetRBAddress, (&TestSelectLead{}).GetEpochLeaders, db)
+	vrewards, _, err := inc.GetValidatorRewards(validator, 0, 20, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vrewards) != 3 || vrewards[2].EpochID != 12 || vrewards[0].Total.Int64() != 11 {
+		t.Fatalf("got validator rewards %+v", vrewards)
+	}
+}
@@ -2,10 +2,13 @@ package incentive
 
 import (
 	"math/big"
+	"sync"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
 )
 
 // GetStakerInfoFn is a function use to get staker info
@@ -26,32 +29,23 @@ type GetSlotLeaderInfoFn func(chain consensus.ChainReader, epochID uint64, slotC
 // GetRandomProposerAddressFn is use to get rb group address
 type GetRandomProposerAddressFn func(epochID uint64) []vm.Leader
 
-var getStakerInfo GetStakerInfoFn
-
-var setStakerInfo SetStakerInfoFn
-
-var getEpochLeaderInfo GetEpochLeaderInfoFn
-
-var getRandomProposerInfo GetRandomProposerInfoFn
-
-var getSlotLeaderInfo GetSlotLeaderInfoFn
-
-var getRandomProposerAddress GetRandomProposerAddressFn
-
-// setStakerInterface is used for Staker module to set its interface
-func setStakerInterface(get GetStakerInfoFn, set SetStakerInfoFn) {
-	getStakerInfo = get
-	setStakerInfo = set
-}
-
-// setActivityInterface is used for get activty module to set its interface
-func setActivityInterface(getEpl GetEpochLeaderInfoFn, getRnp GetRandomProposerInfoFn, getSlr GetSlotLeaderInfoFn) {
-	getEpochLeaderInfo = getEpl
-	getRandomProposerInfo = getRnp
-	getSlotLeaderInfo = getSlr
-}
-
-// setRBAddressInterface is used to get random proposer address of epoch
-func setRBAddressInterface(getRBAddress GetRandomProposerAddressFn) {
-	getRandomProposerAddress = getRBAddress
+// GetEpochLeadersFn is use to get the public keys of the epoch leaders
+type GetEpochLeadersFn func(epochID uint64) [][]byte
+
+// Incentive pays the incentive of the epochs of a chain and keeps the local
+// history of the payments. Every node has its own, created by New.
+type Incentive struct {
+	db        *posdb.Db
+	whiteList map[common.Address]int
+
+	getStakerInfo            GetStakerInfoFn
+	setStakerInfo            SetStakerInfoFn
+	getEpochLeaderInfo       GetEpochLeaderInfoFn
+	getRandomProposerInfo    GetRandomProposerInfoFn
+	getSlotLeaderInfo        GetSlotLeaderInfoFn
+	getRandomProposerAddress GetRandomProposerAddressFn
+	getEpochLeaders          GetEpochLeadersFn
+
+	paidMu sync.Mutex
+	paid   map[uint64]posevent.IncentivePaidEvent // last payment computed per epoch, until a canonical block pays it
 }
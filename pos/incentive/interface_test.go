@@ -74,13 +74,13 @@ func generateTestAddrs() {
 
 	for i := 0; i < addrsCount; i++ {
 		slAddrs[i] = epAddrs[i]
-		slBlks[i] = posconfig.SlotCount / addrsCount
+		slBlks[i] = int(posconfig.SlotCount) / addrsCount
 	}
 }
 
 func TestSetActivityInterface(t *testing.T) {
 	generateTestAddrs()
-	setActivityInterface(testgetEpLeader, testgetRProposer, testgetSltLeader)
+	testInc.getEpochLeaderInfo, testInc.getRandomProposerInfo, testInc.getSlotLeaderInfo = testgetEpLeader, testgetRProposer, testgetSltLeader
 }
 
 var (
@@ -144,5 +144,5 @@ func TestSetStakerInterface(t *testing.T) {
 	// fmt.Println(delegateStakerMap)
 	// fmt.Println(delegateStakerProbilityMap)
 
-	setStakerInterface(getInfo, setInfo)
+	testInc.getStakerInfo, testInc.setStakerInfo = getInfo, setInfo
 }
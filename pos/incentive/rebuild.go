@@ -0,0 +1,90 @@
+package incentive
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+// RebuildChain is the chain the local incentive history is rebuilt from.
+type RebuildChain interface {
+	consensus.ChainReader
+	StateAt(root common.Hash) (*state.StateDB, error)
+	// StateBeforeFinalize returns the state of block after its
+	// transactions, the one its incentive is paid on.
+	StateBeforeFinalize(block *types.Block) (*state.StateDB, error)
+}
+
+// Rebuild recomputes the local incentive history of the epochs up to toEpoch
+// from the chain. The incentive of an epoch is calculated again on the state
+// of the block that paid it before its finalization, as the block did, and
+// the chain state is not changed. Epochs whose incentive has not been paid yet
+// are skipped. The payments rebuilt are not notified.
+func (inc *Incentive) Rebuild(chain RebuildChain, toEpoch uint64) error {
+	if inc.db == nil {
+		return errors.New("incentive is not initialized")
+	}
+	if err := inc.db.Clear(); err != nil {
+		return err
+	}
+
+	head := chain.CurrentHeader().Number.Uint64()
+	for epochID := uint64(0); epochID <= toEpoch; epochID++ {
+		number, err := PaidBlockNumber(chain, chain.StateAt, epochID, head)
+		if err != nil {
+			return err
+		}
+		if number == 0 {
+			continue
+		}
+		header := chain.GetHeaderByNumber(number)
+		block := chain.GetBlock(header.Hash(), number)
+		if block == nil {
+			return fmt.Errorf("block %d unavailable", number)
+		}
+		stateDb, err := chain.StateBeforeFinalize(block)
+		if err != nil {
+			return err
+		}
+		if _, ok := inc.run(chain, stateDb, epochID); !ok {
+			return fmt.Errorf("incentive of epoch %d failed", epochID)
+		}
+	}
+	return nil
+}
+
+// PaidBlockNumber returns the number of the block which paid the incentive of
+// an epoch, or 0 if it has not been paid up to head. Once paid, an epoch stays
+// finished in the state of all later blocks, so the block is searched by
+// bisection.
+func PaidBlockNumber(chain consensus.ChainReader, stateAt func(root common.Hash) (*state.StateDB, error), epochID uint64, head uint64) (uint64, error) {
+	paid := func(number uint64) (bool, error) {
+		stateDb, err := stateAt(chain.GetHeaderByNumber(number).Root)
+		if err != nil {
+			return false, fmt.Errorf("state of block %d unavailable: %v", number, err)
+		}
+		return isFinished(stateDb, epochID), nil
+	}
+
+	if ok, err := paid(head); !ok || err != nil {
+		return 0, err
+	}
+	lo, hi := uint64(1), head
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		ok, err := paid(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, nil
+}
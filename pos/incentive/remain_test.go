@@ -14,28 +14,28 @@ func TestAddRemain(t *testing.T) {
 
 	remainConst := big.NewInt(0).SetUint64(99885844748858447)
 
-	subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval)
+	subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval())
 	fmt.Println(subsidy.String(), util.FromWin(subsidy))
 
-	fmt.Println(subsidyReductionInterval)
-	for i := uint64(0); i < subsidyReductionInterval; i++ {
+	fmt.Println(subsidyReductionInterval())
+	for i := uint64(0); i < subsidyReductionInterval(); i++ {
 		addRemainIncentivePool(statedb, i, remainConst)
 	}
 
-	remain := getRemainIncentivePool(statedb, subsidyReductionInterval)
+	remain := getRemainIncentivePool(statedb, subsidyReductionInterval())
 	fmt.Println(remain)
-	remainDef := big.NewInt(0).Mul(remainConst, big.NewInt(0).SetUint64(subsidyReductionInterval))
+	remainDef := big.NewInt(0).Mul(remainConst, big.NewInt(0).SetUint64(subsidyReductionInterval()))
 
 	if remain.String() != remainDef.String() {
 		fmt.Println(remain, remainDef)
 		t.FailNow()
 	}
 
-	subsidy2 := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval)
+	subsidy2 := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval())
 	fmt.Println(subsidy2.String(), util.FromWin(subsidy2))
 
 	subsidy2 = subsidy2.Sub(subsidy2, subsidy)
-	totalRemain := subsidy.Mul(subsidy2, big.NewInt(0).SetUint64(subsidyReductionInterval))
+	totalRemain := subsidy.Mul(subsidy2, big.NewInt(0).SetUint64(subsidyReductionInterval()))
 	fmt.Println(totalRemain.String())
 
 	subValue := remainDef.Sub(remainDef, totalRemain).Int64()
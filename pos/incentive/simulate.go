@@ -0,0 +1,75 @@
+package incentive
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// SimulatedIncentive is the expected incentive of a validator and its
+// delegators for one epoch.
+type SimulatedIncentive struct {
+	Total      *big.Int // incentive pool of the epoch
+	Foundation *big.Int
+	GasPool    *big.Int
+
+	EpochLeader    []vm.ClientIncentive // expected payments per role
+	RandomProposer []vm.ClientIncentive
+	SlotLeader     []vm.ClientIncentive
+}
+
+// Simulate estimates the incentive of a validator in an epoch. The validator
+// is expected to win the share probability/totalProbability of the epoch
+// leader, random proposer and slot leader seats and to be always active. The
+// incentive pool is calculated from the state and the payments are divided
+// among the stakers returned by get, as the incentive of the epoch would be.
+func Simulate(stateDb *state.StateDB, epochID uint64, validator common.Address,
+	probability, totalProbability *big.Int, get GetStakerInfoFn) (*SimulatedIncentive, error) {
+	if totalProbability == nil || totalProbability.Sign() <= 0 {
+		return nil, errors.New("no stake to simulate against")
+	}
+	if _, _, _, err := getStakerInfoAndCheck(get, epochID, validator); err != nil {
+		return nil, err
+	}
+
+	total, foundation, gasPool := calculateIncentivePool(stateDb, epochID)
+	share := func(percent float64) *big.Int {
+		funds := calcPercent(total, percent*100.0)
+		funds.Mul(funds, probability)
+		return funds.Div(funds, totalProbability)
+	}
+
+	addrs := []common.Address{validator}
+	res := &SimulatedIncentive{Total: total, Foundation: foundation, GasPool: gasPool}
+
+	incentives, _, err := epochLeaderAllocate(get, share(percentOfEpochLeader), addrs, []int{1}, epochID)
+	if err != nil {
+		return nil, err
+	}
+	res.EpochLeader = firstIncentive(incentives)
+
+	incentives, _, err = randomProposerAllocate(get, share(percentOfRandomProposer), addrs, []int{1}, epochID)
+	if err != nil {
+		return nil, err
+	}
+	res.RandomProposer = firstIncentive(incentives)
+
+	// All slots are given to the validator, so a single slot is allocated.
+	incentives, _, err = slotLeaderAllocate(get, share(percentOfSlotLeader), addrs, []int{1}, 1.0, 1, epochID)
+	if err != nil {
+		return nil, err
+	}
+	res.SlotLeader = firstIncentive(incentives)
+
+	return res, nil
+}
+
+func firstIncentive(incentives [][]vm.ClientIncentive) []vm.ClientIncentive {
+	if len(incentives) == 0 {
+		return nil
+	}
+	return incentives[0]
+}
@@ -0,0 +1,65 @@
+package incentive
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+func TestSimulate(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	validator := common.HexToAddress("0x01")
+	delegator := common.HexToAddress("0x02")
+	get := func(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
+		if addr != validator {
+			return nil, 0, nil, errors.New("unknown validator")
+		}
+		return []vm.ClientProbability{
+			{Addr: validator, Probability: big.NewInt(100)},
+			{Addr: delegator, Probability: big.NewInt(300)},
+		}, 10, big.NewInt(400), nil
+	}
+
+	sim, err := Simulate(stateDb, 0, validator, big.NewInt(400), big.NewInt(1600), get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Total.Sign() <= 0 {
+		t.Fatalf("empty incentive pool %v", sim.Total)
+	}
+	// A quarter of the pool is expected, up to rounding.
+	paid := sumToPay([][]vm.ClientIncentive{sim.EpochLeader, sim.RandomProposer, sim.SlotLeader})
+	want := new(big.Int).Div(sim.Total, big.NewInt(4))
+	if diff := new(big.Int).Sub(want, paid); diff.Sign() < 0 || diff.Cmp(big.NewInt(10)) > 0 {
+		t.Fatalf("paid %v, want %v", paid, want)
+	}
+	// The delegator gets three quarters of what remains after the 10% fee.
+	delegated := big.NewInt(0)
+	for _, incentives := range [][]vm.ClientIncentive{sim.EpochLeader, sim.RandomProposer, sim.SlotLeader} {
+		if len(incentives) != 2 {
+			t.Fatalf("expected two payments, got %d", len(incentives))
+		}
+		for _, inc := range incentives {
+			if inc.Addr == delegator {
+				delegated.Add(delegated, inc.Incentive)
+			}
+		}
+	}
+	if permille := new(big.Int).Div(new(big.Int).Mul(delegated, big.NewInt(1000)), paid); permille.Int64() != 675 {
+		t.Fatalf("delegator got %v permille", permille)
+	}
+
+	if _, err := Simulate(stateDb, 0, delegator, big.NewInt(400), big.NewInt(1600), get); err == nil {
+		t.Fatal("expected error for unknown validator")
+	}
+	if _, err := Simulate(stateDb, 0, validator, big.NewInt(400), big.NewInt(0), get); err == nil {
+		t.Fatal("expected error without stake")
+	}
+}
@@ -8,13 +8,13 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 )
 
-// calcBaseSubsidy calc the base subsidy of epoch base on subsidyReductionInterval. input is wei.
+// calcBaseSubsidy calc the base subsidy of epoch base on subsidyReductionInterval(). input is wei.
 func calcBaseSubsidy(baseValue *big.Int) *big.Int {
 	if baseValue == nil {
 		log.SyslogErr("calcBaseSubsidy input is nil")
 		return big.NewInt(0)
 	}
-	subsidyPerEpoch := big.NewInt(0).Div(baseValue, big.NewInt(0).SetUint64(subsidyReductionInterval))
+	subsidyPerEpoch := big.NewInt(0).Div(baseValue, big.NewInt(0).SetUint64(subsidyReductionInterval()))
 	return subsidyPerEpoch
 }
 
@@ -30,11 +30,11 @@ func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.In
 
 	baseSubsidy := calcBaseSubsidy(firstPeriodReward)
 
-	redutionRateNow := math.Pow(redutionRateBase, float64(epochID/subsidyReductionInterval))
+	redutionRateNow := math.Pow(redutionRateBase, float64(epochID/subsidyReductionInterval()))
 	baseSubsidyReduction := calcPercent(baseSubsidy, redutionRateNow*100.0)
 
 	// If 1 period later, need add the remain incentive pool value of last period
-	if (epochID / subsidyReductionInterval) >= 1 {
+	if (epochID / subsidyReductionInterval()) >= 1 {
 		baseRemain := calcBaseSubsidy(getRemainIncentivePool(stateDb, epochID))
 		baseSubsidyReduction.Add(baseSubsidyReduction, baseRemain)
 	}
@@ -27,10 +27,10 @@ func TestGetBaseSubsidyTotal(t *testing.T) {
 	statedb.Reset(common.Hash{})
 	year := big.NewInt(0).Mul(big.NewInt(2.5e6), big.NewInt(1e18))
 	base := calcBaseSubsidy(year)
-	fmt.Println(subsidyReductionInterval)
+	fmt.Println(subsidyReductionInterval())
 
 	for i := uint64(1); i < uint64(500); i++ {
-		subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval*i)
+		subsidy := getBaseSubsidyTotalForEpoch(statedb, subsidyReductionInterval()*i)
 		if subsidy.Uint64() == 0 {
 			fmt.Println("finish", i)
 			return
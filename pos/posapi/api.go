@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/wanchain/go-wanchain/pos/cfm"
+	"github.com/wanchain/go-wanchain/pos/chainquality"
 
 	"github.com/wanchain/go-wanchain/params"
 
@@ -31,32 +32,55 @@ import (
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/posevent"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
+var errNoPos = errors.New("PoS is not running")
+
 type PosApi struct {
 	chain   consensus.ChainReader
 	backend ethapi.Backend
+	events  *posevent.Feed
+
+	// PoS subsystems of the node, nil if the chain doesn't run Pluto
+	epocher *epochLeader.Epocher
+	sls     *slotleader.SLS
+	cfm     *cfm.CFM
+	cq      *chainquality.Monitor
+	inc     *incentive.Incentive
 }
 
-func APIs(chain consensus.ChainReader, backend ethapi.Backend) []rpc.API {
+func APIs(chain consensus.ChainReader, backend ethapi.Backend, events *posevent.Feed, epocher *epochLeader.Epocher, sls *slotleader.SLS, c *cfm.CFM, cq *chainquality.Monitor, inc *incentive.Incentive) []rpc.API {
 	return []rpc.API{{
 		Namespace: "pos",
 		Version:   "1.0",
-		Service:   &PosApi{chain, backend},
+		Service:   &PosApi{chain, backend, events, epocher, sls, c, cq, inc},
 		Public:    true,
 	}}
 }
 
+// posDbs returns the PoS dbs of the chain, or nil if PoS is not running.
+func (a PosApi) posDbs() *posdb.Dbs {
+	if a.epocher == nil {
+		return nil
+	}
+	return a.epocher.GetBlkChain().PosDbs()
+}
+
 func (a PosApi) Version() string {
 	return "1.0"
 }
 
 func (a PosApi) GetSlotLeadersByEpochID(epochID uint64) map[string]string {
 	infoMap := make(map[string]string, 0)
+	dbs := a.posDbs()
+	if dbs == nil {
+		return infoMap
+	}
 	for i := uint64(0); i < posconfig.SlotCount; i++ {
-		buf, err := posdb.GetDb().GetWithIndex(epochID, i, slotleader.SlotLeader)
+		buf, err := dbs.Local().GetWithIndex(epochID, i, slotleader.SlotLeader)
 		if err != nil {
 			infoMap[fmt.Sprintf("%06d", i)] = fmt.Sprintf("epochID:%d, index:%d, error:%s \n", epochID, i, err.Error())
 		} else {
@@ -70,10 +94,10 @@ func (a PosApi) GetSlotLeadersByEpochID(epochID uint64) map[string]string {
 func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, error) {
 	infoMap := make(map[string]string, 0)
 
-	selector := epochLeader.GetEpocher()
+	selector := a.epocher
 
 	if selector == nil {
-		return nil, errors.New("GetEpocherInst error")
+		return nil, errNoPos
 	}
 
 	epochLeaders := selector.GetEpochLeaders(epochID)
@@ -86,7 +110,10 @@ func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, err
 }
 
 func (a PosApi) GetLocalPK() (string, error) {
-	pk, err := slotleader.GetSlotLeaderSelection().GetLocalPublicKey()
+	if a.sls == nil {
+		return "nil", errNoPos
+	}
+	pk, err := a.sls.GetLocalPublicKey()
 	if err != nil {
 		return "nil", err
 	}
@@ -99,11 +126,18 @@ func (a PosApi) GetBootNodePK() string {
 }
 
 func (a PosApi) GetSlotScCallTimesByEpochID(epochID uint64) uint64 {
-	return vm.GetSlotScCallTimes(epochID)
+	dbs := a.posDbs()
+	if dbs == nil {
+		return 0
+	}
+	return vm.GetSlotScCallTimes(dbs.Local(), epochID)
 }
 
 func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
-	pks, _, err := slotleader.GetSlotLeaderSelection().GetSma(epochID)
+	if a.sls == nil {
+		return nil, errNoPos
+	}
+	pks, _, err := a.sls.GetSma(epochID)
 	if err != nil {
 		return nil, err
 	}
@@ -118,9 +152,11 @@ func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
 }
 
 func (a PosApi) GetRandomProposersByEpochID(epochID uint64) map[string]string {
-	//leaders := posdb.GetRBProposerGroup(epochID)
-	leaders := epochLeader.GetEpocher().GetRBProposer(epochID)
 	info := make(map[string]string, 0)
+	if a.epocher == nil {
+		return info
+	}
+	leaders := a.epocher.GetRBProposer(epochID)
 	for i := 0; i < len(leaders); i++ {
 		info[fmt.Sprintf("%06d", i)] = hex.EncodeToString(leaders[i])
 	}
@@ -128,7 +164,10 @@ func (a PosApi) GetRandomProposersByEpochID(epochID uint64) map[string]string {
 }
 
 func (a PosApi) GetSlotCreateStatusByEpochID(epochID uint64) bool {
-	return slotleader.GetSlotLeaderSelection().GetSlotCreateStatusByEpochID(epochID)
+	if a.sls == nil {
+		return false
+	}
+	return a.sls.GetSlotCreateStatusByEpochID(epochID)
 }
 
 func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
@@ -146,10 +185,11 @@ func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
 }
 
 func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
-	if reOrgDb == nil {
+	dbs := a.posDbs()
+	if dbs == nil {
 		return []uint64{0,0}, nil
 	}
+	reOrgDb := dbs.Get(posconfig.ReorgLocalDB)
 
 	var reOrgNum, reOrgLen uint64
 
@@ -168,6 +208,18 @@ func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
 	return []uint64{reOrgNum, reOrgLen}, nil
 }
 
+// GetChainQuality returns the chain quality of an epoch measured by the node,
+// of the current epoch up to the last slot that passed if epochID is omitted.
+func (a PosApi) GetChainQuality(epochID *uint64) (*chainquality.Quality, error) {
+	if a.cq == nil {
+		return nil, errNoPos
+	}
+	if epochID == nil {
+		return a.cq.Current()
+	}
+	return a.cq.Epoch(*epochID)
+}
+
 func (a PosApi) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error) {
 	state, _, err := a.backend.StateAndHeaderByNumber(context.Background(), rpc.BlockNumber(blockNr))
 	if err != nil {
@@ -195,9 +247,9 @@ type StakerInfo struct {
 
 func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (StakerInfo, error) {
 	skInfo := StakerInfo{}
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
-		return skInfo, errors.New("epocher instance does not exist")
+		return skInfo, errNoPos
 	}
 	infors, feeRate, total, err := epocherInst.GetEpochProbability(epochID, addr)
 	if err != nil {
@@ -213,9 +265,9 @@ func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (StakerI
 // this is the static snap of stekers by the block Number.
 func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 	stakers := make([]*StakerJson, 0)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
-		return stakers, errors.New("epocher instance do not exist")
+		return stakers, errNoPos
 	}
 
 	block := epocherInst.GetBlkChain().GetBlockByNumber(targetBlkNum)
@@ -242,11 +294,11 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 }
 
 func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]StakerInfo, error) {
-	targetBlkNum := epochLeader.GetEpocher().GetTargetBlkNumber(epochID)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
-		return nil, errors.New("epocher instance do not exist")
+		return nil, errNoPos
 	}
+	targetBlkNum := epocherInst.GetTargetBlkNumber(epochID)
 	block := epocherInst.GetBlkChain().GetBlockByNumber(targetBlkNum)
 	if block == nil {
 		return nil, errors.New("Unkown block")
@@ -289,7 +341,10 @@ func biToString(value *big.Int, err error) (string, error) {
 }
 
 func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([][]PayInfo, error) {
-	c, err := incentive.GetEpochPayDetail(epochID)
+	if a.inc == nil {
+		return nil, errNoPos
+	}
+	c, err := a.inc.GetEpochPayDetail(epochID)
 	if err != nil {
 		return nil, err
 	}
@@ -307,20 +362,61 @@ func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([][]PayInfo, error)
 	return ret, nil
 }
 
+// GetDelegatorIncentive returns the incentive an address received from
+// fromEpoch to toEpoch, by epoch and validator. A page covers up to 100
+// epochs, the next one is requested from its Next epoch.
+func (a PosApi) GetDelegatorIncentive(addr common.Address, fromEpoch uint64, toEpoch uint64) (*DelegatorIncentivePage, error) {
+	if a.inc == nil {
+		return nil, errNoPos
+	}
+	return GetDelegatorIncentive(a.inc, addr, fromEpoch, toEpoch)
+}
+
+// GetValidatorIncentive returns the incentive paid through a validator from
+// fromEpoch to toEpoch, by epoch. A page covers up to 100 epochs, the next
+// one is requested from its Next epoch.
+func (a PosApi) GetValidatorIncentive(addr common.Address, fromEpoch uint64, toEpoch uint64) (*ValidatorIncentivePage, error) {
+	if a.inc == nil {
+		return nil, errNoPos
+	}
+	return GetValidatorIncentive(a.inc, addr, fromEpoch, toEpoch)
+}
+
+// GetPendingUnbonds lists the stake outs and delegate outs an address will get
+// back, or will return as their validator, with the epoch they are released in.
+func (a PosApi) GetPendingUnbonds(addr common.Address) (*PendingUnbonds, error) {
+	if a.epocher == nil {
+		return nil, errNoPos
+	}
+	return GetPendingUnbonds(a.epocher.GetBlkChain(), addr)
+}
+
 func (a PosApi) GetTotalIncentive() (string, error) {
-	return biToString(incentive.GetTotalIncentive())
+	if a.inc == nil {
+		return "", errNoPos
+	}
+	return biToString(a.inc.GetTotalIncentive())
 }
 
 func (a PosApi) GetEpochIncentive(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochIncentive(epochID))
+	if a.inc == nil {
+		return "", errNoPos
+	}
+	return biToString(a.inc.GetEpochIncentive(epochID))
 }
 
 func (a PosApi) GetEpochRemain(epochID uint64) (string, error) {
-	return biToString(incentive.GetEpochRemain(epochID))
+	if a.inc == nil {
+		return "", errNoPos
+	}
+	return biToString(a.inc.GetEpochRemain(epochID))
 }
 
 func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
+	if epocherInst == nil {
+		return nil, errNoPos
+	}
 	block := epocherInst.GetBlkChain().CurrentBlock()
 	if block == nil {
 		return nil, errors.New("Unkown block")
@@ -345,20 +441,31 @@ func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error)
 }
 
 func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
-	epocherInst := epochLeader.GetEpocher()
-	return epocherInst.GetWhiteByEpochId(epochID)
+	if a.epocher == nil {
+		return nil, errNoPos
+	}
+	return a.epocher.GetWhiteByEpochId(epochID)
 }
 
 func (a PosApi) GetTotalRemain() (string, error) {
-	return biToString(incentive.GetTotalRemain())
+	if a.inc == nil {
+		return "", errNoPos
+	}
+	return biToString(a.inc.GetTotalRemain())
 }
 
 func (a PosApi) GetIncentiveRunTimes() (string, error) {
-	return biToString(incentive.GetRunTimes())
+	if a.inc == nil {
+		return "", errNoPos
+	}
+	return biToString(a.inc.GetRunTimes())
 }
 
 func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.sls
+	if s == nil {
+		return "", errNoPos
+	}
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return "", err
@@ -367,11 +474,17 @@ func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
 }
 
 func (a PosApi) GetRBAddress(epochID uint64) []common.Address {
-	return incentive.GetRBAddress(epochID)
+	if a.inc == nil {
+		return nil
+	}
+	return a.inc.GetRBAddress(epochID)
 }
 
 func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.sls
+	if s == nil {
+		return nil, errNoPos
+	}
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
@@ -382,48 +495,73 @@ func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
 
 // GetActivity get epoch leader, random proposer, slot leader 's addresses and activity
 func (a PosApi) GetActivity(epochID uint64) (*Activity, error) {
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.sls
+	if s == nil || a.inc == nil {
+		return nil, errNoPos
+	}
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := Activity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
-	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
-	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
+	activity.EpLeader, activity.EpActivity = a.inc.GetEpochLeaderActivity(db, epochID)
+	activity.RpLeader, activity.RpActivity = a.inc.GetEpochRBLeaderActivity(db, epochID)
+	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = a.inc.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
 }
 
+// GetValidatorReport checks the slot leader, epoch leader and random proposer
+// duties of a validator from fromEpoch to toEpoch and lists the missed ones.
+func (a PosApi) GetValidatorReport(addr common.Address, fromEpoch uint64, toEpoch uint64) (*ValidatorReport, error) {
+	epocherInst := a.epocher
+	if epocherInst == nil {
+		return nil, errNoPos
+	}
+	return GetValidatorReport(epocherInst, addr, fromEpoch, toEpoch)
+}
+
+// epochJumps returns the epoch jumps of the chain halt recoveries of the chain,
+// nil if it doesn't run Pluto.
+func (a PosApi) epochJumps() *util.EpochJumps {
+	if a.epocher == nil {
+		return nil
+	}
+	return a.epocher.EpochJumps()
+}
+
 func (a PosApi) GetEpochID() uint64 {
-	ep, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	ep, _ := a.epochJumps().CalEpochSlotID(uint64(time.Now().Unix()))
 	return ep
 }
 
 func (a PosApi) GetSlotID() uint64 {
-	_, sl := util.CalEpochSlotID(uint64(time.Now().Unix()))
+	_, sl := a.epochJumps().CalEpochSlotID(uint64(time.Now().Unix()))
 	return sl
 }
 
 func (a PosApi) GetSlotCount() int {
-	return posconfig.SlotCount
+	return int(posconfig.SlotCount)
 }
 
 func (a PosApi) GetSlotTime() int {
-	return posconfig.SlotTime
+	return int(posconfig.SlotTime)
 }
 
 func (a PosApi) GetMaxStableBlkNumber() uint64 {
-	return cfm.GetCFM().GetMaxStableBlkNumber()
+	if a.cfm == nil {
+		return 0
+	}
+	return a.cfm.GetMaxStableBlkNumber()
 }
 
 // CalProbability use to calc the probability of a staker with amount by stake wan coins.
 // The probability is different in different time, so you should input each epoch ID you want to calc
 // Such as CalProbability(390, 10000, 60, 360) means begin from epoch 360 lock 60 epochs stake 10000 to calc 390's probability.
 func (a PosApi) CalProbability(amountCoin uint64, lockTime uint64) (string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.epocher
 	if epocherInst == nil {
-		return "", errors.New("epocher instance do not exist")
+		return "", errNoPos
 	}
 
 	amountWin := big.NewInt(0).SetUint64(amountCoin)
@@ -433,15 +571,29 @@ func (a PosApi) CalProbability(amountCoin uint64, lockTime uint64) (string, erro
 	return biToString(probablity, nil)
 }
 
+// SimulateReward estimates the incentive per epoch of staking amountCoin wan,
+// as a new validator or, if validator is given, by delegating to it.
+func (a PosApi) SimulateReward(amountCoin uint64, lockEpochs uint64, feeRate uint64, validator *common.Address) (*RewardSimulation, error) {
+	epocherInst := a.epocher
+	if epocherInst == nil {
+		return nil, errNoPos
+	}
+
+	amountWin := big.NewInt(0).SetUint64(amountCoin)
+	amountWin.Mul(amountWin, big.NewInt(params.Wan))
+
+	return SimulateReward(epocherInst.GetBlkChain(), amountWin, lockEpochs, feeRate, validator)
+}
+
 //GetEpochIDByTime can get Epoch ID by input time second Unix.
 func (a PosApi) GetEpochIDByTime(timeUnix uint64) uint64 {
-	ep, _ := util.CalEpochSlotID(timeUnix)
+	ep, _ := a.epochJumps().CalEpochSlotID(timeUnix)
 	return ep
 }
 
 //GetSlotIDByTime can get Slot ID by input time second Unix.
 func (a PosApi) GetSlotIDByTime(timeUnix uint64) uint64 {
-	_, sl := util.CalEpochSlotID(timeUnix)
+	_, sl := a.epochJumps().CalEpochSlotID(timeUnix)
 	return sl
 }
 
@@ -451,7 +603,7 @@ func (a PosApi) GetTimeByEpochID(epochID uint64) uint64 {
 		return 0
 	}
 
-	time := posconfig.EpochBaseTime + epochID*posconfig.SlotCount*posconfig.SlotTime
+	time := a.epochJumps().EpochStartTime(epochID)
 
 	epochIDGet := a.GetEpochIDByTime(time)
 	if epochIDGet < epochID {
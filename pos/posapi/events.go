@@ -0,0 +1,101 @@
+package posapi
+
+import (
+	"context"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/event"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/pos/posevent"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// notifyEvents creates an RPC subscription sending the events subscribe feeds
+// into the channel events. With filter, only the events it accepts are sent.
+// A client falling too far behind is dropped, so it never holds up the block
+// import posting the events.
+func notifyEvents(ctx context.Context, events interface{}, subscribe func() event.Subscription, filter func(ev interface{}) bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+	go ethapi.NotifyEvents(notifier, rpcSub, subscribe(), events, filter)
+	return rpcSub, nil
+}
+
+// newEpochFilter returns a filter passing the events of increasing epochs
+// only, so an epoch done again by a reorg is not notified twice.
+func newEpochFilter(epochID func(ev interface{}) uint64) func(ev interface{}) bool {
+	notified, lastEpochID := false, uint64(0)
+	return func(ev interface{}) bool {
+		id := epochID(ev)
+		if notified && id <= lastEpochID {
+			return false
+		}
+		notified, lastEpochID = true, id
+		return true
+	}
+}
+
+// NewEpoch creates a subscription that fires each time the chain head enters
+// a new epoch.
+func (a PosApi) NewEpoch(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.NewEpochEvent)
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeNewEpoch(events) }, nil)
+}
+
+// NewSlot creates a subscription that fires each time the chain head moves to
+// a new slot.
+func (a PosApi) NewSlot(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.NewSlotEvent)
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeNewSlot(events) }, nil)
+}
+
+// EpochLeadersSelected creates a subscription that fires each time the epoch
+// leaders and random proposers of an epoch have been selected.
+func (a PosApi) EpochLeadersSelected(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.EpochLeadersSelectedEvent)
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeEpochLeadersSelected(events) }, nil)
+}
+
+// RandomBeaconFinalized creates a subscription that fires each time the random
+// number of an epoch shows up in the state of a new chain head. A reorg
+// generating it again is not notified twice.
+func (a PosApi) RandomBeaconFinalized(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.RandomBeaconFinalizedEvent)
+	filter := newEpochFilter(func(ev interface{}) uint64 { return ev.(posevent.RandomBeaconFinalizedEvent).EpochID })
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeRandomBeaconFinalized(events) }, filter)
+}
+
+// IncentivePaid creates a subscription that fires each time the incentive of an
+// epoch has been paid, once the block paying it is canonical. A reorg paying an
+// epoch again is not notified twice.
+func (a PosApi) IncentivePaid(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.IncentivePaidEvent)
+	filter := newEpochFilter(func(ev interface{}) uint64 { return ev.(posevent.IncentivePaidEvent).EpochID })
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeIncentivePaid(events) }, filter)
+}
+
+// ChainQualityAlert creates a subscription that fires each time the chain
+// quality monitor of the node changes level.
+func (a PosApi) ChainQualityAlert(ctx context.Context) (*rpc.Subscription, error) {
+	events := make(chan posevent.ChainQualityAlertEvent)
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeChainQualityAlert(events) }, nil)
+}
+
+// UnbondReleased creates a subscription that fires for every stake out and
+// delegate out returned by a new chain head. With an address, only the stakes
+// returned to it or by it as their validator are notified.
+func (a PosApi) UnbondReleased(ctx context.Context, addr *common.Address) (*rpc.Subscription, error) {
+	events := make(chan posevent.UnbondReleasedEvent)
+	var filter func(ev interface{}) bool
+	if addr != nil {
+		filter = func(ev interface{}) bool {
+			u := ev.(posevent.UnbondReleasedEvent)
+			return u.Address == *addr || u.Validator == *addr
+		}
+	}
+	return notifyEvents(ctx, events, func() event.Subscription { return a.events.SubscribeUnbondReleased(events) }, filter)
+}
@@ -0,0 +1,27 @@
+package posapi
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/pos/posevent"
+)
+
+// Tests that the events of an epoch done again by a reorg are filtered out.
+func TestEpochFilter(t *testing.T) {
+	filter := newEpochFilter(func(ev interface{}) uint64 { return ev.(posevent.IncentivePaidEvent).EpochID })
+	for i, tt := range []struct {
+		epochID uint64
+		pass    bool
+	}{
+		{0, true},
+		{0, false},
+		{2, true},
+		{1, false},
+		{2, false},
+		{3, true},
+	} {
+		if pass := filter(posevent.IncentivePaidEvent{EpochID: tt.epochID}); pass != tt.pass {
+			t.Errorf("event %d of epoch %d: passed %v, want %v", i, tt.epochID, pass, tt.pass)
+		}
+	}
+}
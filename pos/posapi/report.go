@@ -0,0 +1,304 @@
+package posapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+)
+
+// Duties of a validator checked by the validator report.
+const (
+	DutySlot     = "slot"     // produce the block of an assigned slot
+	DutyStage1   = "stage1"   // send the stage1 slot leader selection transaction
+	DutyStage2   = "stage2"   // send the stage2 slot leader selection transaction
+	DutyDkg1     = "dkg1"     // send the dkg1 random beacon transaction
+	DutyDkg2     = "dkg2"     // send the dkg2 random beacon transaction
+	DutySigShare = "sigshare" // send the signature share of the random beacon
+)
+
+// maxReportEpochs limits the epochs covered by a single validator report.
+const maxReportEpochs = 100
+
+// MissedDuty is a duty a validator did not fulfil. Index is the slot of slot
+// duties and the seat in the epoch leader or random proposer group otherwise.
+type MissedDuty struct {
+	EpochID uint64
+	Index   uint64
+	Duty    string
+	Reason  string
+}
+
+// ValidatorReport lists how many PoS duties a validator had in a range of
+// epochs, how many it fulfilled and the ones it missed. Duties whose slots have
+// not passed yet are not counted. Epochs whose leaders the node does not know
+// are listed in Unknown.
+type ValidatorReport struct {
+	Address   common.Address
+	FromEpoch uint64
+	ToEpoch   uint64
+
+	SlotsAssigned uint64
+	SlotsProduced uint64
+
+	EpochLeaderSeats uint64
+	Stage1Sent       uint64
+	Stage2Sent       uint64
+
+	RandomProposerSeats uint64
+	Dkg1Sent            uint64
+	Dkg2Sent            uint64
+	SigShareSent        uint64
+
+	Missed  []MissedDuty
+	Unknown []uint64
+}
+
+// GetValidatorReport checks the duties of a validator in the epochs from
+// fromEpoch to toEpoch against the chain of the epoch leader selection.
+func GetValidatorReport(epocher *epochLeader.Epocher, addr common.Address, fromEpoch, toEpoch uint64) (*ValidatorReport, error) {
+	if epocher == nil {
+		return nil, errors.New("epoch leader selection is not initialized")
+	}
+	bc := epocher.GetBlkChain()
+	head := bc.CurrentBlock().Header()
+	headEpoch, headSlot := util.GetEpochSlotIDFromDifficulty(head.Difficulty)
+	if toEpoch > headEpoch {
+		toEpoch = headEpoch
+	}
+	if fromEpoch > toEpoch {
+		return nil, fmt.Errorf("from epoch %d is after to epoch %d", fromEpoch, toEpoch)
+	}
+	if toEpoch-fromEpoch >= maxReportEpochs {
+		return nil, fmt.Errorf("at most %d epochs can be reported at once", maxReportEpochs)
+	}
+
+	report := &ValidatorReport{
+		Address:   addr,
+		FromEpoch: fromEpoch,
+		ToEpoch:   toEpoch,
+		Missed:    make([]MissedDuty, 0),
+		Unknown:   make([]uint64, 0),
+	}
+	for epochID := fromEpoch; epochID <= toEpoch; epochID++ {
+		header := EpochLastHeader(bc, epochID)
+		if header == nil {
+			continue
+		}
+		slotLeaders := epochSlotLeaders(bc.PosDbs().Local(), epochID)
+		if slotLeaders == nil {
+			report.Unknown = append(report.Unknown, epochID)
+			continue
+		}
+		stateDb, err := bc.StateAt(header.Root)
+		if err != nil {
+			return nil, fmt.Errorf("state of block %d unavailable: %v", header.Number.Uint64(), err)
+		}
+		// Only the slots up to the head have passed in the epoch of the head.
+		passed := uint64(posconfig.SlotCount)
+		if epochID == headEpoch {
+			passed = headSlot + 1
+		}
+
+		report.checkSlotLeader(epochID, slotLeaders, epochSealers(bc, header, epochID), passed)
+		report.checkEpochLeader(stateDb, epochID, epocher.GetEpochLeaders(epochID), passed)
+		report.checkRandomProposer(stateDb, epochID, epocher.GetRBProposerGroup(epochID), passed)
+	}
+	return report, nil
+}
+
+// posFirstBlock is the number of the first block encoding its epoch and slot
+// in its difficulty: the genesis block of a Pluto chain does not.
+const posFirstBlock = 1
+
+// EpochLastHeader returns the header of the last block of an epoch, or nil if
+// the local chain holds no PoS block of that epoch. Epoch ids never decrease
+// along the PoS blocks of the chain, so the block is searched by bisection
+// among them; a chain not sealed by Pluto holds none.
+func EpochLastHeader(bc *core.BlockChain, epochID uint64) *types.Header {
+	headNumber := bc.CurrentBlock().NumberU64()
+	if bc.Config().Pluto == nil || headNumber < posFirstBlock {
+		return nil
+	}
+	epochOf := func(number uint64) uint64 {
+		epochID, _ := util.GetEpochSlotIDFromDifficulty(bc.GetHeaderByNumber(number).Difficulty)
+		return epochID
+	}
+	// Find the first block after the epoch
+	lo, hi := uint64(posFirstBlock), headNumber+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if epochOf(mid) > epochID {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo == posFirstBlock {
+		return nil
+	}
+	header := bc.GetHeaderByNumber(lo - 1)
+	if id, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); id != epochID {
+		return nil
+	}
+	return header
+}
+
+// epochSlotLeaders returns the public keys of the slot leaders of an epoch, or
+// nil if the node has not selected them.
+func epochSlotLeaders(db *posdb.Db, epochID uint64) [][]byte {
+	leaders := make([][]byte, posconfig.SlotCount)
+	if epochID == 0 {
+		genesisPK, _ := hex.DecodeString(posconfig.GenesisPK)
+		for i := range leaders {
+			leaders[i] = genesisPK
+		}
+		return leaders
+	}
+	for i := range leaders {
+		pk, err := db.GetWithIndex(epochID, uint64(i), slotleader.SlotLeader)
+		if err != nil {
+			return nil
+		}
+		leaders[i] = pk
+	}
+	return leaders
+}
+
+// epochSealers returns the public key of the signer of every block of an
+// epoch by slot, walking back from the last header of the epoch.
+func epochSealers(bc *core.BlockChain, last *types.Header, epochID uint64) map[uint64][]byte {
+	sealers := make(map[uint64][]byte)
+	for header := last; header != nil && header.Number.Sign() > 0; header = bc.GetHeader(header.ParentHash, header.Number.Uint64()-1) {
+		id, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+		if id != epochID {
+			break
+		}
+		signer, err := core.RecoverBlockSigner(header)
+		if err != nil {
+			signer = []byte{}
+		}
+		sealers[slotID] = signer
+	}
+	return sealers
+}
+
+func (r *ValidatorReport) isValidator(pk []byte) bool {
+	pub := crypto.ToECDSAPub(pk)
+	return pub != nil && crypto.PubkeyToAddress(*pub) == r.Address
+}
+
+func (r *ValidatorReport) miss(epochID, index uint64, duty, reason string) {
+	r.Missed = append(r.Missed, MissedDuty{EpochID: epochID, Index: index, Duty: duty, Reason: reason})
+}
+
+// checkSlotLeader checks that a block was sealed by the validator in every
+// passed slot it was selected for.
+func (r *ValidatorReport) checkSlotLeader(epochID uint64, slotLeaders [][]byte, sealers map[uint64][]byte, passed uint64) {
+	for slotID := uint64(0); slotID < passed && slotID < uint64(len(slotLeaders)); slotID++ {
+		if !r.isValidator(slotLeaders[slotID]) {
+			continue
+		}
+		r.SlotsAssigned++
+		signer, ok := sealers[slotID]
+		switch {
+		case !ok:
+			r.miss(epochID, slotID, DutySlot, "no block in slot")
+		case !bytes.Equal(signer, slotLeaders[slotID]):
+			r.miss(epochID, slotID, DutySlot, fmt.Sprintf("block sealed by %x", signer))
+		default:
+			r.SlotsProduced++
+		}
+	}
+}
+
+// checkEpochLeader checks that the validator sent both slot leader selection
+// transactions for every seat it holds in the epoch leader group.
+func (r *ValidatorReport) checkEpochLeader(stateDb vm.StateDB, epochID uint64, epochLeaders [][]byte, passed uint64) {
+	for i := range epochLeaders {
+		if !r.isValidator(epochLeaders[i]) {
+			continue
+		}
+		index := uint64(i)
+		r.EpochLeaderSeats++
+		epochIDBuf, indexBuf := convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(index)
+
+		if passed > posconfig.Sma1End {
+			data := stateDb.GetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage1KeyHash(epochIDBuf, indexBuf))
+			if len(data) == 0 {
+				r.miss(epochID, index, DutyStage1, notSentReason(DutyStage1, posconfig.Sma1Start, posconfig.Sma1End))
+			} else if epID, idx, _, err := vm.RlpUnpackStage1DataForTx(data); err != nil || epID != epochID || idx != index {
+				r.miss(epochID, index, DutyStage1, "invalid stage1 data")
+			} else {
+				r.Stage1Sent++
+			}
+		}
+		if passed > posconfig.Sma2End {
+			data := stateDb.GetStateByteArray(vm.GetSlotLeaderSCAddress(), vm.GetSlotLeaderStage2KeyHash(epochIDBuf, indexBuf))
+			if len(data) == 0 {
+				r.miss(epochID, index, DutyStage2, notSentReason(DutyStage2, posconfig.Sma2Start, posconfig.Sma2End))
+			} else if epID, idx, selfPk, _, _, err := vm.RlpUnpackStage2DataForTx(data); err != nil || epID != epochID || idx != index {
+				r.miss(epochID, index, DutyStage2, "invalid stage2 data")
+			} else if !bytes.Equal(crypto.FromECDSAPub(selfPk), epochLeaders[i]) {
+				r.miss(epochID, index, DutyStage2, "stage2 sent with another key")
+			} else {
+				r.Stage2Sent++
+			}
+		}
+	}
+}
+
+// checkRandomProposer checks that the validator took part in every stage of
+// the random beacon for every seat it holds in the random proposer group.
+func (r *ValidatorReport) checkRandomProposer(stateDb vm.StateDB, epochID uint64, proposers []vm.Leader, passed uint64) {
+	cfg := posconfig.Cfg()
+	for i := range proposers {
+		if proposers[i].SecAddr != r.Address {
+			continue
+		}
+		index := uint64(i)
+		r.RandomProposerSeats++
+
+		if passed > cfg.Dkg1End {
+			if cij, err := vm.GetCji(stateDb, epochID, uint32(i)); err != nil {
+				r.miss(epochID, index, DutyDkg1, "invalid dkg1 data: "+err.Error())
+			} else if cij == nil {
+				r.miss(epochID, index, DutyDkg1, notSentReason(DutyDkg1, 0, cfg.Dkg1End))
+			} else {
+				r.Dkg1Sent++
+			}
+		}
+		if passed > cfg.Dkg2End {
+			if !vm.IsJoinDKG2(stateDb, epochID, uint32(i)) {
+				r.miss(epochID, index, DutyDkg2, notSentReason(DutyDkg2, cfg.Dkg2Begin, cfg.Dkg2End))
+			} else {
+				r.Dkg2Sent++
+			}
+		}
+		if passed > cfg.SignEnd {
+			if sig, err := vm.GetSig(stateDb, epochID, uint32(i)); err != nil {
+				r.miss(epochID, index, DutySigShare, "invalid sigshare data: "+err.Error())
+			} else if sig == nil {
+				r.miss(epochID, index, DutySigShare, notSentReason(DutySigShare, cfg.SignBegin, cfg.SignEnd))
+			} else {
+				r.SigShareSent++
+			}
+		}
+	}
+}
+
+func notSentReason(duty string, firstSlot, lastSlot uint64) string {
+	return fmt.Sprintf("no %s transaction in slots %d-%d", duty, firstSlot, lastSlot)
+}
@@ -0,0 +1,94 @@
+package posapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+)
+
+func TestCheckSlotLeader(t *testing.T) {
+	self, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	selfPk, otherPk := crypto.FromECDSAPub(&self.PublicKey), crypto.FromECDSAPub(&other.PublicKey)
+
+	slotLeaders := [][]byte{selfPk, otherPk, selfPk, selfPk}
+	sealers := map[uint64][]byte{0: selfPk, 1: otherPk, 2: otherPk}
+
+	r := &ValidatorReport{Address: crypto.PubkeyToAddress(self.PublicKey)}
+	r.checkSlotLeader(5, slotLeaders, sealers, 3)
+	if r.SlotsAssigned != 2 || r.SlotsProduced != 1 || len(r.Missed) != 1 {
+		t.Fatalf("got %+v", r)
+	}
+	if m := r.Missed[0]; m.EpochID != 5 || m.Index != 2 || m.Duty != DutySlot || !strings.HasPrefix(m.Reason, "block sealed by") {
+		t.Fatalf("got missed duty %+v", m)
+	}
+
+	r = &ValidatorReport{Address: crypto.PubkeyToAddress(self.PublicKey)}
+	r.checkSlotLeader(5, slotLeaders, sealers, 4)
+	if r.SlotsAssigned != 3 || len(r.Missed) != 2 || r.Missed[1].Index != 3 || r.Missed[1].Reason != "no block in slot" {
+		t.Fatalf("got %+v", r)
+	}
+}
+
+func TestCheckEpochLeader(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	self, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	epochLeaders := [][]byte{crypto.FromECDSAPub(&other.PublicKey), crypto.FromECDSAPub(&self.PublicKey)}
+
+	const epochID = 9
+	data, err := vm.RlpPackStage1DataForTx(epochID, 1, &self.PublicKey, vm.GetSlotLeaderScAbiString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := vm.GetSlotLeaderStage1KeyHash(convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(1))
+	stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), key, data)
+
+	// Neither stage is over yet
+	r := &ValidatorReport{Address: crypto.PubkeyToAddress(self.PublicKey)}
+	r.checkEpochLeader(stateDb, epochID, epochLeaders, posconfig.Sma1End)
+	if r.EpochLeaderSeats != 1 || r.Stage1Sent != 0 || len(r.Missed) != 0 {
+		t.Fatalf("got %+v", r)
+	}
+
+	r = &ValidatorReport{Address: crypto.PubkeyToAddress(self.PublicKey)}
+	r.checkEpochLeader(stateDb, epochID, epochLeaders, posconfig.SlotCount)
+	if r.Stage1Sent != 1 || r.Stage2Sent != 0 || len(r.Missed) != 1 {
+		t.Fatalf("got %+v", r)
+	}
+	if m := r.Missed[0]; m.Index != 1 || m.Duty != DutyStage2 || !strings.HasPrefix(m.Reason, "no stage2 transaction") {
+		t.Fatalf("got missed duty %+v", m)
+	}
+}
+
+func TestCheckRandomProposer(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	addr := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	proposers := []vm.Leader{{SecAddr: addr}, {SecAddr: common.Address{1}}, {SecAddr: addr}}
+
+	r := &ValidatorReport{Address: addr}
+	r.checkRandomProposer(stateDb, 3, proposers, posconfig.Cfg().Dkg2End)
+	if r.RandomProposerSeats != 2 || len(r.Missed) != 2 {
+		t.Fatalf("got %+v", r)
+	}
+	for i, index := range []uint64{0, 2} {
+		if m := r.Missed[i]; m.Index != index || m.Duty != DutyDkg1 {
+			t.Fatalf("got missed duty %+v", m)
+		}
+	}
+
+	r = &ValidatorReport{Address: addr}
+	r.checkRandomProposer(stateDb, 3, proposers, posconfig.SlotCount)
+	if len(r.Missed) != 6 || r.Missed[2].Duty != DutySigShare {
+		t.Fatalf("got %+v", r)
+	}
+}
@@ -0,0 +1,91 @@
+package posapi
+
+import (
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+)
+
+// maxIncentivePageEpochs limits the epochs of a page of incentive history.
+const maxIncentivePageEpochs = 100
+
+// DelegatorIncentive is the incentive an address received in an epoch through
+// a validator, as one of its delegators or as the validator itself.
+type DelegatorIncentive struct {
+	EpochID   uint64
+	Validator common.Address
+	Incentive *math.HexOrDecimal256
+}
+
+// DelegatorIncentivePage is a page of the incentive history of an address.
+// Total sums the incentives of the page, Next is the epoch the next page
+// starts at and is nil on the last page.
+type DelegatorIncentivePage struct {
+	Address    common.Address
+	Total      *math.HexOrDecimal256
+	Incentives []DelegatorIncentive
+	Next       *uint64
+}
+
+// ValidatorIncentive is the incentive paid in an epoch through a validator,
+// Total to the validator and its delegators, Self to the validator.
+type ValidatorIncentive struct {
+	EpochID    uint64
+	Total      *math.HexOrDecimal256
+	Self       *math.HexOrDecimal256
+	Delegators uint64
+}
+
+// ValidatorIncentivePage is a page of the incentive history of a validator.
+// Total sums the incentives of the page, Next is the epoch the next page
+// starts at and is nil on the last page.
+type ValidatorIncentivePage struct {
+	Address    common.Address
+	Total      *math.HexOrDecimal256
+	Incentives []ValidatorIncentive
+	Next       *uint64
+}
+
+// GetDelegatorIncentive returns the incentive history of an address in inc from
+// fromEpoch to toEpoch, by page of up to maxIncentivePageEpochs epochs.
+func GetDelegatorIncentive(inc *incentive.Incentive, addr common.Address, fromEpoch, toEpoch uint64) (*DelegatorIncentivePage, error) {
+	rewards, next, err := inc.GetDelegatorRewards(addr, fromEpoch, toEpoch, maxIncentivePageEpochs)
+	if err != nil {
+		return nil, err
+	}
+	page := &DelegatorIncentivePage{
+		Address:    addr,
+		Incentives: make([]DelegatorIncentive, len(rewards)),
+		Next:       next,
+	}
+	total := new(big.Int)
+	for i, r := range rewards {
+		page.Incentives[i] = DelegatorIncentive{r.EpochID, r.Validator, (*math.HexOrDecimal256)(r.Amount)}
+		total.Add(total, r.Amount)
+	}
+	page.Total = (*math.HexOrDecimal256)(total)
+	return page, nil
+}
+
+// GetValidatorIncentive returns the incentive history of a validator in inc from
+// fromEpoch to toEpoch, by page of up to maxIncentivePageEpochs epochs.
+func GetValidatorIncentive(inc *incentive.Incentive, addr common.Address, fromEpoch, toEpoch uint64) (*ValidatorIncentivePage, error) {
+	rewards, next, err := inc.GetValidatorRewards(addr, fromEpoch, toEpoch, maxIncentivePageEpochs)
+	if err != nil {
+		return nil, err
+	}
+	page := &ValidatorIncentivePage{
+		Address:    addr,
+		Incentives: make([]ValidatorIncentive, len(rewards)),
+		Next:       next,
+	}
+	total := new(big.Int)
+	for i, r := range rewards {
+		page.Incentives[i] = ValidatorIncentive{r.EpochID, (*math.HexOrDecimal256)(r.Total), (*math.HexOrDecimal256)(r.Self), r.Delegators}
+		total.Add(total, r.Total)
+	}
+	page.Total = (*math.HexOrDecimal256)(total)
+	return page, nil
+}
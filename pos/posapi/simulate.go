@@ -0,0 +1,157 @@
+package posapi
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// simulatedStakeAddr identifies the simulated stake among the stakers.
+var simulatedStakeAddr = common.Address{}
+
+// RewardSimulation is the expected incentive of a stake for one epoch. The
+// probabilities are the stake weights used by the leader selection.
+type RewardSimulation struct {
+	EpochID              uint64 // epoch whose incentive pool the estimate is based on
+	Validator            common.Address
+	FeeRate              uint64
+	StakeProbability     *math.HexOrDecimal256
+	ValidatorProbability *math.HexOrDecimal256
+	TotalProbability     *math.HexOrDecimal256
+
+	EpochIncentive     *math.HexOrDecimal256 // incentive pool of the epoch
+	ValidatorIncentive *math.HexOrDecimal256 // paid to the validator and all its delegators
+
+	EpochLeaderIncentive    *math.HexOrDecimal256
+	RandomProposerIncentive *math.HexOrDecimal256
+	SlotLeaderIncentive     *math.HexOrDecimal256
+	Incentive               *math.HexOrDecimal256 // paid to the simulated stake
+}
+
+// SimulateReward estimates the incentive per epoch of staking amount wei on
+// top of the current state of the chain. Without validator a new validator is
+// simulated, otherwise a delegation to validator, in which case lockEpochs
+// and feeRate are ignored and the delegation is checked as the staking
+// contract checks it. The simulated stake is identified by the zero address in
+// the payments.
+func SimulateReward(bc *core.BlockChain, amount *big.Int, lockEpochs uint64, feeRate uint64, validator *common.Address) (*RewardSimulation, error) {
+	head := bc.CurrentBlock()
+	stateDb, err := bc.StateAt(head.Root())
+	if err != nil {
+		return nil, err
+	}
+	// The incentive pool of the current epoch is still filling up, use the
+	// last complete one.
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty())
+	if epochID > 0 {
+		epochID--
+	}
+
+	var target *vm.StakerInfo
+	if validator == nil {
+		if lockEpochs < vm.PSMinEpochNum || lockEpochs > vm.PSMaxEpochNum {
+			return nil, errors.New("invalid lock epochs")
+		}
+		if feeRate > vm.PSMaxFeeRate {
+			return nil, errors.New("invalid fee rate")
+		}
+		if amount.Cmp(vm.MinValidatorStake) < 0 {
+			return nil, errors.New("amount is below the minimum validator stake")
+		}
+		weight := big.NewInt(int64(vm.CalLocktimeWeight(lockEpochs)))
+		target = &vm.StakerInfo{
+			Address:     simulatedStakeAddr,
+			Amount:      amount,
+			StakeAmount: new(big.Int).Mul(amount, weight),
+			LockEpochs:  lockEpochs,
+			From:        simulatedStakeAddr,
+			FeeRate:     feeRate,
+		}
+	}
+
+	// Total up the stake weights, replacing the validator by its simulated
+	// version.
+	totalProbability := big.NewInt(0)
+	for _, staker := range vm.GetStakersSnap(stateDb) {
+		if validator != nil && staker.Address == *validator {
+			staker := staker
+			target = &staker
+			continue
+		}
+		if _, p, err := epochLeader.CalEpochProbabilityStaker(&staker); err == nil {
+			totalProbability.Add(totalProbability, p)
+		}
+	}
+	if target == nil {
+		return nil, errors.New("unknown validator")
+	}
+	if validator != nil {
+		if err := vm.CheckDelegateIn(target, simulatedStakeAddr, amount); err != nil {
+			return nil, err
+		}
+		weight := big.NewInt(int64(vm.CalLocktimeWeight(vm.PSMinEpochNum)))
+		target.Clients = append(target.Clients, vm.ClientInfo{
+			Address:     simulatedStakeAddr,
+			Amount:      amount,
+			StakeAmount: new(big.Int).Mul(amount, weight),
+		})
+	}
+
+	infors, probability, err := epochLeader.CalEpochProbabilityStaker(target)
+	if err != nil {
+		return nil, err
+	}
+	totalProbability.Add(totalProbability, probability)
+
+	get := func(epochID uint64, addr common.Address) ([]vm.ClientProbability, uint64, *big.Int, error) {
+		if addr != target.Address {
+			return nil, 0, nil, errors.New("unknown validator")
+		}
+		return infors, target.FeeRate, probability, nil
+	}
+	sim, err := incentive.Simulate(stateDb, epochID, target.Address, probability, totalProbability, get)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &RewardSimulation{
+		EpochID:                 epochID,
+		Validator:               target.Address,
+		FeeRate:                 target.FeeRate,
+		ValidatorProbability:    (*math.HexOrDecimal256)(probability),
+		TotalProbability:        (*math.HexOrDecimal256)(totalProbability),
+		EpochIncentive:          (*math.HexOrDecimal256)(sim.Total),
+		ValidatorIncentive:      (*math.HexOrDecimal256)(big.NewInt(0)),
+		EpochLeaderIncentive:    (*math.HexOrDecimal256)(big.NewInt(0)),
+		RandomProposerIncentive: (*math.HexOrDecimal256)(big.NewInt(0)),
+		SlotLeaderIncentive:     (*math.HexOrDecimal256)(big.NewInt(0)),
+	}
+	for _, info := range infors {
+		if info.Addr == simulatedStakeAddr {
+			res.StakeProbability = (*math.HexOrDecimal256)(info.Probability)
+		}
+	}
+	sum := func(incentives []vm.ClientIncentive, stake *math.HexOrDecimal256) {
+		for _, inc := range incentives {
+			(*big.Int)(res.ValidatorIncentive).Add((*big.Int)(res.ValidatorIncentive), inc.Incentive)
+			if inc.Addr == simulatedStakeAddr {
+				(*big.Int)(stake).Add((*big.Int)(stake), inc.Incentive)
+			}
+		}
+	}
+	sum(sim.EpochLeader, res.EpochLeaderIncentive)
+	sum(sim.RandomProposer, res.RandomProposerIncentive)
+	sum(sim.SlotLeader, res.SlotLeaderIncentive)
+
+	total := new(big.Int).Add((*big.Int)(res.EpochLeaderIncentive), (*big.Int)(res.RandomProposerIncentive))
+	total.Add(total, (*big.Int)(res.SlotLeaderIncentive))
+	res.Incentive = (*math.HexOrDecimal256)(total)
+	return res, nil
+}
@@ -0,0 +1,71 @@
+package posapi
+
+import (
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// PendingUnbond is a stake out or delegate out not returned yet. Kind is
+// "stakeOut", "partnerOut" or "delegateOut", the funds are returned to Address
+// in the first block of ReleaseEpoch running the stake out.
+type PendingUnbond struct {
+	Kind         string
+	Validator    common.Address
+	Address      common.Address
+	Amount       *math.HexOrDecimal256
+	ReleaseEpoch uint64
+	ReleaseTime  uint64 // start of ReleaseEpoch, in seconds
+}
+
+// PendingUnbonds is the list of the stakes an address will get back, as the
+// receiver of the funds or as their validator, in the state of a block.
+type PendingUnbonds struct {
+	Address     common.Address
+	BlockNumber uint64
+	EpochID     uint64
+	Unbonds     []PendingUnbond
+}
+
+// GetPendingUnbonds lists the pending unbonds of an address in the state of the
+// current block, by release epoch.
+func GetPendingUnbonds(bc *core.BlockChain, addr common.Address) (*PendingUnbonds, error) {
+	block := bc.CurrentBlock()
+	stateDb, err := bc.StateAt(block.Root())
+	if err != nil {
+		return nil, err
+	}
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(block.Difficulty())
+	res := &PendingUnbonds{
+		Address:     addr,
+		BlockNumber: block.NumberU64(),
+		EpochID:     epochID,
+		Unbonds:     make([]PendingUnbond, 0),
+	}
+	for _, u := range vm.PendingUnbonds(vm.GetStakersSnap(stateDb)) {
+		if u.Address != addr && u.Validator != addr {
+			continue
+		}
+		// funds due in a past epoch go back at the next stake out
+		release := u.ReleaseEpoch
+		if release < epochID {
+			release = epochID
+		}
+		res.Unbonds = append(res.Unbonds, PendingUnbond{
+			Kind:         u.Kind,
+			Validator:    u.Validator,
+			Address:      u.Address,
+			Amount:       (*math.HexOrDecimal256)(u.Amount),
+			ReleaseEpoch: release,
+			ReleaseTime:  bc.EpochJumps().EpochStartTime(release),
+		})
+	}
+	sort.SliceStable(res.Unbonds, func(i, k int) bool {
+		return res.Unbonds[i].ReleaseEpoch < res.Unbonds[k].ReleaseEpoch
+	})
+	return res, nil
+}
@@ -0,0 +1,203 @@
+package posapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/math"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+var errIncentiveNotPaid = errors.New("the incentive of the epoch has not been paid yet")
+
+// IncentiveDiff is a receiver whose incentive differs from the recomputed one.
+type IncentiveDiff struct {
+	Addr     common.Address
+	Expected *math.HexOrDecimal256 // recomputed from the chain
+	Actual   *math.HexOrDecimal256 // received in the paying block, or in the local history
+}
+
+// IncentiveAudit is the incentive of an epoch recomputed from the chain and
+// checked against the block that paid it and the local incentive history.
+type IncentiveAudit struct {
+	EpochID     uint64
+	BlockNumber uint64 // block that paid the incentive
+	BlockHash   common.Hash
+
+	Total      *math.HexOrDecimal256
+	Foundation *math.HexOrDecimal256
+	GasPool    *math.HexOrDecimal256
+	Paid       *math.HexOrDecimal256
+	Remain     *math.HexOrDecimal256
+	Receivers  int
+
+	// StateRootMatches reports whether the block, replayed with the
+	// recomputed incentive, gives its state root.
+	StateRootMatches bool
+	BalanceDiffs     []IncentiveDiff
+
+	LocalHistoryMatches bool
+	LocalDiffs          []IncentiveDiff
+	Rebuilt             bool // the local history was replaced by the recomputed one
+}
+
+// Matches reports whether the recomputed incentive is the one paid on chain.
+func (a *IncentiveAudit) Matches() bool {
+	return a.StateRootMatches && len(a.BalanceDiffs) == 0
+}
+
+// VerifyIncentive recomputes the incentive of an epoch from the state of the
+// block that paid it, its activity and delegations, and compares it with the
+// balance changes in that block and with the local incentive history. With
+// rebuild, a local history differing from a recomputation that matches the
+// chain is replaced. inc must have been created with epocher, and epocher set
+// as the leader selection of its chain. The leader selections of the epoch
+// and of the paying block's epoch must be in the local db, they are not
+// selected again.
+func VerifyIncentive(inc *incentive.Incentive, epocher *epochLeader.Epocher, epochID uint64, rebuild bool) (*IncentiveAudit, error) {
+	bc := epocher.GetBlkChain()
+	head := bc.CurrentBlock().NumberU64()
+	number, err := incentive.PaidBlockNumber(bc, bc.StateAt, epochID, head)
+	if err != nil {
+		return nil, err
+	}
+	if number == 0 {
+		return nil, errIncentiveNotPaid
+	}
+	block := bc.GetBlockByNumber(number)
+
+	// the transactions of the block are checked against the leaders of its
+	// epoch, the incentive is paid to the ones of epochID. They are read from
+	// the local db only, the audit writes nothing but a rebuilt history.
+	blkEpochID, _ := util.GetEpochSlotIDFromDifficulty(block.Difficulty())
+	for _, id := range []uint64{epochID, blkEpochID} {
+		if len(epocher.GetRBProposerGroup(id)) == 0 {
+			return nil, fmt.Errorf("leader selection of epoch %d is not in the local db, rebuild it first", id)
+		}
+	}
+
+	pre, err := bc.StateBeforeFinalize(block)
+	if err != nil {
+		return nil, err
+	}
+	alloc, err := inc.Allocate(bc, pre.Copy(), epochID)
+	if err != nil {
+		return nil, fmt.Errorf("incentive of epoch %d cannot be recomputed: %v", epochID, err)
+	}
+
+	// replay the finalization of the block as pluto does it
+	replayed := pre.Copy()
+	alloc.Apply(replayed, epochID)
+	snap := replayed.Snapshot()
+	if !epochLeader.StakeOutRun(replayed, blkEpochID) {
+		replayed.RevertToSnapshot(snap)
+	}
+
+	post, err := bc.StateAt(block.Root())
+	if err != nil {
+		return nil, fmt.Errorf("state of block %d unavailable: %v", number, err)
+	}
+
+	expected, order := paymentsByAddress(alloc.Payments)
+	audit := &IncentiveAudit{
+		EpochID:          epochID,
+		BlockNumber:      number,
+		BlockHash:        block.Hash(),
+		Total:            (*math.HexOrDecimal256)(alloc.Total),
+		Foundation:       (*math.HexOrDecimal256)(alloc.Foundation),
+		GasPool:          (*math.HexOrDecimal256)(alloc.GasPool),
+		Paid:             (*math.HexOrDecimal256)(sumPayments(expected)),
+		Remain:           (*math.HexOrDecimal256)(alloc.Remain),
+		Receivers:        len(order),
+		StateRootMatches: replayed.IntermediateRoot(true) == block.Root(),
+		BalanceDiffs:     []IncentiveDiff{},
+		LocalDiffs:       []IncentiveDiff{},
+	}
+	// the block received what was replayed plus the difference of the balances
+	for _, addr := range order {
+		diff := new(big.Int).Sub(post.GetBalance(addr), replayed.GetBalance(addr))
+		if diff.Sign() != 0 {
+			audit.BalanceDiffs = append(audit.BalanceDiffs, IncentiveDiff{
+				Addr:     addr,
+				Expected: (*math.HexOrDecimal256)(expected[addr]),
+				Actual:   (*math.HexOrDecimal256)(diff.Add(diff, expected[addr])),
+			})
+		}
+	}
+
+	var local map[common.Address]*big.Int
+	if payments, err := inc.GetEpochPayDetail(epochID); err == nil {
+		local, _ = paymentsByAddress(payments)
+	}
+	audit.LocalDiffs = comparePayments(expected, order, local)
+	audit.LocalHistoryMatches = local != nil && len(audit.LocalDiffs) == 0
+
+	if rebuild && !audit.LocalHistoryMatches {
+		if !audit.Matches() {
+			return audit, errors.New("the recomputed incentive does not match the chain, the local history is kept")
+		}
+		if err := inc.RestoreHistory(epochID, alloc); err != nil {
+			return audit, err
+		}
+		audit.Rebuilt = true
+	}
+	return audit, nil
+}
+
+// paymentsByAddress sums the payments per receiver, in the order they are paid.
+func paymentsByAddress(payments [][]vm.ClientIncentive) (map[common.Address]*big.Int, []common.Address) {
+	sums := make(map[common.Address]*big.Int)
+	var order []common.Address
+	for _, staker := range payments {
+		for _, p := range staker {
+			if sums[p.Addr] == nil {
+				sums[p.Addr] = new(big.Int)
+				order = append(order, p.Addr)
+			}
+			sums[p.Addr].Add(sums[p.Addr], p.Incentive)
+		}
+	}
+	return sums, order
+}
+
+func sumPayments(payments map[common.Address]*big.Int) *big.Int {
+	sum := new(big.Int)
+	for _, v := range payments {
+		sum.Add(sum, v)
+	}
+	return sum
+}
+
+// comparePayments lists the receivers of expected or actual whose payments
+// differ, the receivers of actual only come last.
+func comparePayments(expected map[common.Address]*big.Int, order []common.Address, actual map[common.Address]*big.Int) []IncentiveDiff {
+	diffs := []IncentiveDiff{}
+	zero := new(big.Int)
+	for _, addr := range order {
+		got := actual[addr]
+		if got == nil {
+			got = zero
+		}
+		if got.Cmp(expected[addr]) != 0 {
+			diffs = append(diffs, IncentiveDiff{addr, (*math.HexOrDecimal256)(expected[addr]), (*math.HexOrDecimal256)(got)})
+		}
+	}
+	var extra []common.Address
+	for addr := range actual {
+		if expected[addr] == nil {
+			extra = append(extra, addr)
+		}
+	}
+	sort.Slice(extra, func(i, k int) bool { return bytes.Compare(extra[i][:], extra[k][:]) < 0 })
+	for _, addr := range extra {
+		diffs = append(diffs, IncentiveDiff{addr, (*math.HexOrDecimal256)(zero), (*math.HexOrDecimal256)(actual[addr])})
+	}
+	return diffs
+}
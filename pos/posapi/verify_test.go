@@ -0,0 +1,170 @@
+package posapi_test
+
+import (
+	"math/big"
+	"testing"
+
+	kbn256 "github.com/wanchain/go-wanchain/accounts/keystore/bn256"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/consensus/pluto"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posapi"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// plutoFaker accepts the blocks of the tests without their slot proofs, and
+// finalizes them as pluto does, paying the incentive of the epochs.
+type plutoFaker struct {
+	consensus.Engine
+	pluto *pluto.Pluto
+}
+
+func (e *plutoFaker) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	return e.pluto.Finalize(chain, header, state, txs, uncles, receipts)
+}
+
+// newPaidChain makes a Pluto chain with a block in every slot, up to the one
+// paying the incentive of epoch 0. The blocks are sealed by a staker out of
+// the white list, paid as a slot leader.
+func newPaidChain(t *testing.T) (*core.BlockChain, *epochLeader.Epocher, *incentive.Incentive) {
+	wan := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	validators := make([]core.PosValidator, 2)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		bn, err := kbn256.GenerateBn256()
+		if err != nil {
+			t.Fatal(err)
+		}
+		validators[i] = core.PosValidator{
+			PublicKey: &key.PublicKey,
+			Bn256PK:   bn.G1.Marshal(),
+			Balance:   new(big.Int).Mul(big.NewInt(1000000), wan),
+			Stake:     new(big.Int).Mul(big.NewInt(100000), wan),
+		}
+	}
+	genesis := core.PosGenesisBlock(big.NewInt(6363), 1, 4, 1500000000, validators)
+	genesis.Config.Pos.WhiteList = genesis.Config.Pos.WhiteList[:1]
+	sealer := crypto.PubkeyToAddress(*validators[1].PublicKey)
+	posconfig.SetChainParams(genesis.Config.Pos)
+
+	db, _ := ethdb.NewMemDatabase()
+	genesis.MustCommit(db)
+	engine := &plutoFaker{ethash.NewFullFaker(db), pluto.New(genesis.Config.Pluto, db)}
+	chain, err := core.NewBlockChain(db, genesis.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	epocher := epochLeader.NewEpocher(chain)
+	inc := incentive.New(epocher.GetEpochProbability, epocher.SetEpochIncentive, epocher.GetRBProposerGroup,
+		epocher.GetEpochLeaders, chain.PosDbs().Get(posconfig.IncentiveLocalDB))
+	engine.pluto.SetIncentive(inc)
+
+	// the leaders of epochs 0 and 1 are selected from the genesis, as a node does
+	for epochID := uint64(0); epochID < 2; epochID++ {
+		if err := epocher.SelectLeadersLoop(epochID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the incentive of epoch 0 is paid by the first block of epoch 1 after
+	// its incentive stage
+	last := posconfig.SlotCount + posconfig.IncentiveStartStage + 1
+	for slot := uint64(0); slot <= last; slot++ {
+		parent := chain.CurrentBlock()
+		statedb, err := chain.StateAt(parent.Root())
+		if err != nil {
+			t.Fatal(err)
+		}
+		epochID, slotID := slot/posconfig.SlotCount, slot%posconfig.SlotCount
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   sealer,
+			Difficulty: new(big.Int).SetUint64(epochID<<32 | slotID<<8 | 1),
+			Number:     new(big.Int).Add(parent.Number(), common.Big1),
+			GasLimit:   parent.GasLimit(),
+			GasUsed:    new(big.Int),
+			Time:       new(big.Int).SetUint64(genesis.Timestamp + 1 + slot*posconfig.SlotTime),
+		}
+		block, err := engine.Finalize(chain, header, statedb, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block of slot %d: %v", slot, err)
+		}
+	}
+	return chain, epocher, inc
+}
+
+// Tests that the incentive audit of an epoch paid on a chain reports a local
+// history differing from the chain, and that the rebuild replaces it.
+func TestVerifyIncentive(t *testing.T) {
+	// the epochs start at the first block of the chain of the test
+	defer func(base uint64) { posconfig.EpochBaseTime = base }(posconfig.EpochBaseTime)
+	posconfig.EpochBaseTime = 0
+	defer posconfig.SetChainParams(nil)
+
+	chain, epocher, inc := newPaidChain(t)
+	defer chain.Stop()
+
+	if number, err := incentive.PaidBlockNumber(chain, chain.StateAt, 0, chain.CurrentBlock().NumberU64()); err != nil || number != chain.CurrentBlock().NumberU64() {
+		t.Fatalf("incentive of epoch 0 paid by block %d, %v", number, err)
+	}
+
+	audit, err := posapi.VerifyIncentive(inc, epocher, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !audit.Matches() || !audit.LocalHistoryMatches || audit.Receivers == 0 {
+		t.Fatalf("audit of the paid epoch: %+v", audit)
+	}
+
+	// corrupt the local history of the epoch
+	payments, err := inc.GetEpochPayDetail(0)
+	if err != nil || len(payments) == 0 || len(payments[0]) == 0 {
+		t.Fatalf("got pay detail %v, %v", payments, err)
+	}
+	remain, _ := inc.GetEpochRemain(0)
+	corrupted := payments[0][0]
+	payments[0][0].Incentive = new(big.Int).Add(corrupted.Incentive, big.NewInt(1))
+	if err := inc.RestoreHistory(0, &incentive.Allocation{Payments: payments, Remain: remain}); err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err = posapi.VerifyIncentive(inc, epocher, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !audit.Matches() {
+		t.Fatalf("the chain no longer matches: %+v", audit)
+	}
+	if audit.LocalHistoryMatches || len(audit.LocalDiffs) != 1 || audit.LocalDiffs[0].Addr != corrupted.Addr || audit.Rebuilt {
+		t.Fatalf("audit of the corrupted history: %+v", audit)
+	}
+
+	// --rebuild replaces it by the recomputed one
+	if audit, err = posapi.VerifyIncentive(inc, epocher, 0, true); err != nil || !audit.Rebuilt {
+		t.Fatalf("rebuild: got %+v, %v", audit, err)
+	}
+	if audit, err = posapi.VerifyIncentive(inc, epocher, 0, false); err != nil || !audit.LocalHistoryMatches {
+		t.Fatalf("audit after the rebuild: got %+v, %v", audit, err)
+	}
+
+	// the audit doesn't select leaders missing from the local db
+	empty := epochLeader.NewEpocherWithLBN(chain, "verify_rb", "verify_ep")
+	if _, err := posapi.VerifyIncentive(inc, empty, 0, false); err == nil {
+		t.Fatal("audited without the leader selection")
+	}
+	if len(empty.GetRBProposerGroup(0)) != 0 {
+		t.Fatal("the audit wrote the leader selection")
+	}
+}
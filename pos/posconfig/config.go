@@ -1,13 +1,10 @@
 package posconfig
 
 import (
-	"math/big"
-
-	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
-	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/params"
 )
 
 var (
@@ -25,65 +22,62 @@ const (
 	PosLocalDB       = "pos"
 	IncentiveLocalDB = "incentive"
 	ReorgLocalDB     = "forkdb"
+	EpochGenLocalDB  = "epochGendb"
 )
 
 var EpochLeadersHold [][]byte
 
 const (
-	// EpochLeaderCount is count of pk in epoch leader group which is select by stake
-	EpochLeaderCount = 50
-	// RandomProperCount is count of pk in random leader group which is select by stake
-	RandomProperCount = 25
 	PosUpgradeEpochID = 2 // must send tx 2 epoch before.
 	MaxEpHold         = 30
 	MinEpHold         = 10
-)
-const (
-	// SlotTime is the time span of a slot in second, So it's 1 hours for a epoch
-	SlotTime = 10
 
 	//Incentive should perform delay some epochs.
 	IncentiveDelayEpochs = 1
-	IncentiveStartStage  = Stage2K
+
+	MinimumChainQuality     = 0.5 //BlockSecurityParam / SlotSecurityParam
+	CriticalReorgThreshold  = 3
+	CriticalChainQuality    = 0.618
+	NonCriticalChainQuality = 0.8
+)
+
+// The parameters below are set from the PoS section of the chain config by
+// SetChainParams, they default to params.DefaultPosConfig.
+var (
+	// EpochLeaderCount is count of pk in epoch leader group which is select by stake
+	EpochLeaderCount int
+	// RandomProperCount is count of pk in random leader group which is select by stake
+	RandomProperCount int
+
+	// SlotTime is the time span of a slot in second, So it's 1 hours for a epoch
+	SlotTime uint64
 
 	// K count of each epoch
-	KCount = 12
-	K      = 10
+	KCount uint64
+	K      uint64
 	// SlotCount is slot count in an epoch
-	SlotCount = K * KCount
+	SlotCount uint64
 
 	// Stage1K is divde a epoch into 10 pieces
-	Stage1K  = uint64(K)
-	Stage2K  = Stage1K * 2
-	Stage3K  = Stage1K * 3
-	Stage4K  = Stage1K * 4
-	Stage5K  = Stage1K * 5
-	Stage6K  = Stage1K * 6
-	Stage7K  = Stage1K * 7
-	Stage8K  = Stage1K * 8
-	Stage9K  = Stage1K * 9
-	Stage10K = Stage1K * 10
-	Stage11K = Stage1K * 11
-	Stage12K = Stage1K * 12
+	Stage1K, Stage2K, Stage3K, Stage4K, Stage5K, Stage6K    uint64
+	Stage7K, Stage8K, Stage9K, Stage10K, Stage11K, Stage12K uint64
 
-	Sma1Start = Stage2K
-	Sma1End   = Stage4K
-	Sma2Start = Stage6K
-	Sma2End   = Stage8K
-	Sma3Start = Stage10K
-	Sma3End   = Stage12K
+	IncentiveStartStage uint64
+
+	Sma1Start, Sma1End uint64
+	Sma2Start, Sma2End uint64
+	Sma3Start, Sma3End uint64
 
 	// parameters for security and chain quality
-	BlockSecurityParam = K
-	SlotSecurityParam  = 2 * K
+	BlockSecurityParam uint64
+	SlotSecurityParam  uint64
 
-	MinimumChainQuality     = 0.5 //BlockSecurityParam / SlotSecurityParam
-	CriticalReorgThreshold  = 3
-	CriticalChainQuality    = 0.618
-	NonCriticalChainQuality = 0.8
+	GenesisPK string
+
+	// chainWhiteList is the white list of epoch leaders of the chain config
+	chainWhiteList []string
 )
 
-var GenesisPK = "04dc40d03866f7335e40084e39c3446fe676b021d1fcead11f2e2715e10a399b498e8875d348ee40358545e262994318e4dcadbc865bcf9aac1fc330f22ae2c786"
 var PosOwnerAddr = common.HexToAddress("0xcf696d8eea08a311780fb89b20d4f0895198a489")
 
 type Config struct {
@@ -92,7 +86,6 @@ type Config struct {
 	RBThres       uint
 	EpochInterval uint64
 	PosStartTime  int64
-	MinerKey      *keystore.Key
 	Dbpath        string
 	NodeCfg       *node.Config
 	Dkg1End       uint64
@@ -102,59 +95,92 @@ type Config struct {
 	SignEnd       uint64
 }
 
-var DefaultConfig = Config{
-	12,
-	K,
-	13,
-	0,
-	0,
-	nil,
-	"",
-	nil,
-	Stage2K - 1,
-	Stage4K,
-	Stage6K - 1,
-	Stage8K,
-	Stage10K - 1,
-}
+var DefaultConfig Config
 
-func Cfg() *Config {
-	return &DefaultConfig
+func init() {
+	SetChainParams(params.DefaultPosConfig)
 }
 
-func (c *Config) GetMinerAddr() common.Address {
-	if c.MinerKey == nil {
-		return common.Address{}
+// SetChainParams sets the PoS parameters from the PoS section of a chain
+// config, and derives the stages of an epoch and DefaultConfig from them.
+// A nil config sets params.DefaultPosConfig.
+func SetChainParams(c *params.PosConfig) {
+	if c == nil {
+		c = params.DefaultPosConfig
 	}
+	EpochLeaderCount = int(c.EpochLeaderCount)
+	RandomProperCount = int(c.RandomProperCount)
+	SlotTime = c.SlotTime
+	K = c.K
+	KCount = c.KCount
+	SlotCount = K * KCount
+	if c.GenesisPK != "" {
+		GenesisPK = c.GenesisPK
+	} else {
+		GenesisPK = params.DefaultPosConfig.GenesisPK
+	}
+	chainWhiteList = c.WhiteList
+	setWhiteList()
+
+	Stage1K = K
+	Stage2K = Stage1K * 2
+	Stage3K = Stage1K * 3
+	Stage4K = Stage1K * 4
+	Stage5K = Stage1K * 5
+	Stage6K = Stage1K * 6
+	Stage7K = Stage1K * 7
+	Stage8K = Stage1K * 8
+	Stage9K = Stage1K * 9
+	Stage10K = Stage1K * 10
+	Stage11K = Stage1K * 11
+	Stage12K = Stage1K * 12
 
-	return c.MinerKey.Address
-}
+	IncentiveStartStage = Stage2K
 
-func (c *Config) GetMinerBn256PK() *bn256.G1 {
-	if c.MinerKey == nil {
-		return nil
-	}
+	Sma1Start = Stage2K
+	Sma1End = Stage4K
+	Sma2Start = Stage6K
+	Sma2End = Stage8K
+	Sma3Start = Stage10K
+	Sma3End = Stage12K
 
-	return new(bn256.G1).Set(c.MinerKey.PrivateKey3.PublicKeyBn256.G1)
+	BlockSecurityParam = K
+	SlotSecurityParam = 2 * K
+
+	// The random beacon needs the signatures of more than half of its group
+	polymDegree := uint(RandomProperCount-1) / 2
+	DefaultConfig.PolymDegree = polymDegree
+	DefaultConfig.K = uint(K)
+	DefaultConfig.RBThres = polymDegree + 1
+	DefaultConfig.Dkg1End = Stage2K - 1
+	DefaultConfig.Dkg2Begin = Stage4K
+	DefaultConfig.Dkg2End = Stage6K - 1
+	DefaultConfig.SignBegin = Stage8K
+	DefaultConfig.SignEnd = Stage10K - 1
 }
 
-func (c *Config) GetMinerBn256SK() *big.Int {
-	if c.MinerKey == nil {
-		return nil
-	}
-
-	return new(big.Int).Set(c.MinerKey.PrivateKey3.D)
+func Cfg() *Config {
+	return &DefaultConfig
 }
 
 func Init(nodeCfg *node.Config) {
-	if IsDev {
-		WhiteList = WhiteListDev
-	} else {
-		WhiteList = WhiteListOrig
+	setWhiteList()
+	DefaultConfig.NodeCfg = nodeCfg
+}
+
+// setWhiteList sets WhiteList and EpochLeadersHold from the white list of the
+// chain config, or from the built in list of the network if it has none.
+func setWhiteList() {
+	switch {
+	case len(chainWhiteList) != 0:
+		WhiteList = chainWhiteList
+	case IsDev:
+		WhiteList = WhiteListDev[:]
+	default:
+		WhiteList = WhiteListOrig[:]
 	}
 	EpochLeadersHold = make([][]byte, len(WhiteList))
 	for i := 0; i < len(WhiteList); i++ {
 		EpochLeadersHold[i] = hexutil.MustDecode(WhiteList[i])
 	}
-	DefaultConfig.NodeCfg = nodeCfg
 }
@@ -0,0 +1,61 @@
+package posconfig
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/params"
+)
+
+func TestSetChainParams(t *testing.T) {
+	defer SetChainParams(nil)
+
+	SetChainParams(&params.PosConfig{SlotTime: 2, K: 5, KCount: 12, EpochLeaderCount: 7, RandomProperCount: 9})
+	if SlotTime != 2 || SlotCount != 60 || EpochLeaderCount != 7 || RandomProperCount != 9 {
+		t.Fatalf("parameters mismatch: slot time %d, slot count %d, epoch leaders %d, random proposers %d",
+			SlotTime, SlotCount, EpochLeaderCount, RandomProperCount)
+	}
+	if GenesisPK != params.DefaultPosConfig.GenesisPK {
+		t.Errorf("genesis PK mismatch: have %s", GenesisPK)
+	}
+	if Sma1Start != 10 || Sma1End != 20 || Sma3End != 60 || SlotSecurityParam != 10 {
+		t.Errorf("stages mismatch: sma1 %d-%d, sma3 end %d, slot security %d", Sma1Start, Sma1End, Sma3End, SlotSecurityParam)
+	}
+	cfg := Cfg()
+	if cfg.K != 5 || cfg.PolymDegree != 4 || cfg.RBThres != 5 {
+		t.Errorf("random beacon config mismatch: k %d, degree %d, threshold %d", cfg.K, cfg.PolymDegree, cfg.RBThres)
+	}
+	if cfg.Dkg1End != 9 || cfg.Dkg2Begin != 20 || cfg.Dkg2End != 29 || cfg.SignBegin != 40 || cfg.SignEnd != 49 {
+		t.Errorf("random beacon stages mismatch: %+v", cfg)
+	}
+
+	SetChainParams(nil)
+	if SlotTime != 10 || SlotCount != 120 || EpochLeaderCount != 50 || RandomProperCount != 25 {
+		t.Errorf("default parameters mismatch")
+	}
+	if cfg := Cfg(); cfg.PolymDegree != 12 || cfg.RBThres != 13 || cfg.SignEnd != 99 {
+		t.Errorf("default random beacon config mismatch: %+v", cfg)
+	}
+}
+
+func TestSetChainParamsWhiteList(t *testing.T) {
+	defer SetChainParams(nil)
+
+	pk := "0x" + params.DefaultPosConfig.GenesisPK
+	c := *params.DefaultPosConfig
+	c.WhiteList = []string{pk, pk}
+	SetChainParams(&c)
+	if len(WhiteList) != 2 || WhiteList[0] != pk || len(EpochLeadersHold) != 2 || len(EpochLeadersHold[1]) != 65 {
+		t.Fatalf("white list mismatch: %v", WhiteList)
+	}
+
+	// the node config doesn't override the white list of the chain
+	Init(nil)
+	if len(WhiteList) != 2 {
+		t.Errorf("white list reset by Init: %d pks", len(WhiteList))
+	}
+
+	SetChainParams(nil)
+	if len(WhiteList) != len(WhiteListOrig) {
+		t.Errorf("default white list mismatch: %d pks", len(WhiteList))
+	}
+}
@@ -1,5 +1,7 @@
 package posconfig
-var WhiteList [210]string
+// WhiteList is the white list of epoch leaders of the running chain, set by
+// Init and SetChainParams.
+var WhiteList []string
 
 var WhiteListOrig = [...]string{
 	"0x0406a5c2c0524968089b8e7fdaddd642732e04e0f1da4c49dcb7810aa37dd471317b77936015a86e8efbc2002485e9d146ee392a3021e0c5bf53e5c0f6b158de09",
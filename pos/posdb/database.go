@@ -1,13 +1,14 @@
 package posdb
 
 import (
-	"io/ioutil"
+	"bytes"
+	"errors"
 	"math/big"
-	"os"
-	"path"
+	"strconv"
 	"strings"
-	"sync"
 
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/wanchain/go-wanchain/common"
 
 	"github.com/wanchain/go-wanchain/rlp"
@@ -18,92 +19,58 @@ import (
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 )
 
-//Db is the wanpos leveldb class
+//Db is the wanpos local db class. Every Db is a key namespace of the backend
+//database, which is the chain database of the node.
 type Db struct {
-	db *ethdb.LDBDatabase
+	db      ethdb.Database
+	backend ethdb.Database
+	name    string
 }
 
-var (
-	dbInstMap = make(map[string]*Db)
-	//RANDOMBEACON_DB_KEY = "PosRandomBeacon"
+// keyPrefix separates the PoS local data from the other contents of the
+// backend database.
+const keyPrefix = "wanpos-"
 
-	mu sync.RWMutex
-)
-
-func NewDb(fileName string) *Db {
-	mu.Lock()
-	defer mu.Unlock()
-	db := GetDbByName(fileName)
-	if db != nil {
-		return db
-	}
-
-	dbInst := &Db{db: nil}
-
-	dbInst.DbInit(fileName)
-
-	dbInstMap[fileName] = dbInst
-
-	return dbInst
+// Dbs are the PoS local dbs of a node, all of them namespaces of the same
+// backend database. Every node owns its Dbs, so that several nodes can run in
+// one process.
+type Dbs struct {
+	backend ethdb.Database
 }
 
-var dbInstance *Db
-
-func init() {
-	dbInstance = NewDb("")
+// NewDbs returns the PoS local dbs kept in the given database, usually the
+// chain database of the node.
+func NewDbs(backend ethdb.Database) *Dbs {
+	return &Dbs{backend: backend}
 }
 
-// DbInitAll init all db files
-func DbInitAll(pathname string) {
-	posconfig.Cfg().Dbpath = pathname
-	dbInstance = NewDb(posconfig.PosLocalDB)
-	NewDb(posconfig.RbLocalDB)
-	NewDb(posconfig.EpLocalDB)
+// NewMemoryDbs returns PoS local dbs kept in a new memory database.
+func NewMemoryDbs() *Dbs {
+	db, _ := ethdb.NewMemDatabase()
+	return NewDbs(db)
 }
 
-//GetDb can get a Db instance to use
-func GetDb() *Db {
-	return dbInstance
+// Get returns the Db with the given name.
+func (d *Dbs) Get(name string) *Db {
+	return NewTableDb(d.backend, name)
 }
 
-func GetDbByName(name string) *Db {
-	return dbInstMap[name]
+// Local returns the general PoS local db of the node.
+func (d *Dbs) Local() *Db {
+	return d.Get(posconfig.PosLocalDB)
 }
 
-//DbInit use to init leveldb in this object, user should not use this. It is automate called in init().
-func (s *Db) DbInit(dbPath string) {
-	var dirname string
-	var err error
-	if dbPath == "" {
-		dirname, err = ioutil.TempDir(os.TempDir(), "wanpos_tmpdb_")
-		if err != nil {
-			panic("failed to create wanpos_tmpdb file: " + err.Error())
-		}
-	} else {
-		nameIdx := strings.LastIndex(dbPath, string(os.PathSeparator))
-		if nameIdx < 0 {
-			dirname = path.Join(posconfig.Cfg().Dbpath, "gwan")
-			dirname = path.Join(dirname, dbPath)
-		} else {
-			dbPath = path.Join(dbPath, "gwan")
-			dirname = path.Join(dbPath, "wanposdb")
-		}
-	}
-
-	if s.db != nil {
-		s.db.Close()
-	}
-
-	inst, ok := dbInstMap[dbPath]
-
-	if ok {
-		inst.DbClose()
+// NewTableDb returns a Db using the namespace name of the given database.
+func NewTableDb(db ethdb.Database, name string) *Db {
+	return &Db{
+		db:      ethdb.NewTable(db, namespace(name)),
+		backend: db,
+		name:    name,
 	}
+}
 
-	s.db, err = ethdb.NewLDBDatabase(dirname, 0, 0)
-	if err != nil {
-		panic("failed to create wanpos_tmpdb database: " + dbPath + "_" + err.Error())
-	}
+func namespace(name string) string {
+	return keyPrefix + name + "-"
 }
 
 func (s *Db) put(epochID uint64, index uint64, key string, value []byte, saveKey bool) ([]byte, error) {
@@ -136,7 +103,10 @@ func (s *Db) GetWithIndex(epochID uint64, index uint64, key string) ([]byte, err
 
 	ret, err := s.db.Get(newKey)
 	if err != nil {
-		//debug.PrintStack()
+		// Callers expect the LevelDB error for missing keys on every backend
+		if has, _ := s.db.Has(newKey); !has {
+			return nil, leveldb.ErrNotFound
+		}
 	}
 	return ret, err
 }
@@ -209,11 +179,77 @@ func (s *Db) putNoCount(epochID uint64, key string, value []byte) ([]byte, error
 	return s.put(epochID, 0, key, value, false)
 }
 
-//DbClose use to close db file
+//DbClose use to close the db. The backend database stays open.
 func (s *Db) DbClose() {
 	s.db.Close()
 }
 
+// Clear deletes the whole content of the db. The backend database must be
+// either a LevelDB or a memory database, others cannot be iterated.
+func (s *Db) Clear() error {
+	keys, err := s.keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.backend.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Epochs returns the epochs holding a value for key at index 0, in no
+// particular order. The backend database must be either a LevelDB or a memory
+// database, others cannot be iterated.
+func (s *Db) Epochs(key string) ([]uint64, error) {
+	keys, err := s.keys()
+	if err != nil {
+		return nil, err
+	}
+	prefix, suffix := namespace(s.name), "_0_"+key
+	var epochs []uint64
+	for _, k := range keys {
+		name := strings.TrimPrefix(string(k), prefix)
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		epochID, err := strconv.ParseUint(strings.TrimSuffix(name, suffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		epochs = append(epochs, epochID)
+	}
+	return epochs, nil
+}
+
+// keys returns the keys of the db in the backend database.
+func (s *Db) keys() ([][]byte, error) {
+	prefix := []byte(namespace(s.name))
+
+	var keys [][]byte
+	switch db := s.backend.(type) {
+	case *ethdb.LDBDatabase:
+		it := db.LDB().NewIterator(util.BytesPrefix(prefix), nil)
+		for it.Next() {
+			keys = append(keys, common.CopyBytes(it.Key()))
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return nil, err
+		}
+	case *ethdb.MemDatabase:
+		for _, key := range db.Keys() {
+			if bytes.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+	default:
+		return nil, errors.New("posdb: unsupported backend database")
+	}
+	return keys, nil
+}
+
 // GetStorageByteArray : cb is callback function. cb return true indicating like to continue, return false indicating stop
 func (s *Db) GetStorageByteArray(epochID uint64) [][]byte {
 
@@ -249,13 +285,10 @@ type Proposer struct {
 	Probabilities *big.Int
 }
 
-func GetRBProposerGroup(epochId uint64) [][]byte {
-	db := NewDb(posconfig.RbLocalDB)
-	if db == nil {
-		// todo : os.Exit ??
-		log.SyslogErr("GetRBProposerGroup create db error")
-		return nil
-	}
+// GetRBProposerGroup returns the bn256 public keys of the random proposers of
+// an epoch.
+func (d *Dbs) GetRBProposerGroup(epochId uint64) [][]byte {
+	db := d.Get(posconfig.RbLocalDB)
 
 	proposersArray := db.GetStorageByteArray(epochId)
 	length := len(proposersArray)
@@ -274,12 +307,9 @@ func GetRBProposerGroup(epochId uint64) [][]byte {
 
 }
 
-func GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
-	db := NewDb(posconfig.StakerLocalDB)
-	if db == nil {
-		log.SyslogErr("GetStakerInfo create db error")
-		return nil
-	}
+// GetStakerInfoBytes returns the staker info of an address saved for an epoch.
+func (d *Dbs) GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
+	db := d.Get(posconfig.StakerLocalDB)
 	stakerBytes, err := db.GetWithIndex(epochId, 0, common.ToHex(addr[:]))
 	if err != nil {
 		return nil
@@ -288,12 +318,10 @@ func GetStakerInfoBytes(epochId uint64, addr common.Address) []byte {
 	return stakerBytes
 }
 
-func GetEpochLeaderGroup(epochId uint64) [][]byte {
-	db := NewDb(posconfig.EpLocalDB)
-	if db == nil {
-		log.SyslogErr("GetEpochLeaderGroup create db error")
-		return nil
-	}
+// GetEpochLeaderGroup returns the secp256k1 public keys of the epoch leaders of
+// an epoch.
+func (d *Dbs) GetEpochLeaderGroup(epochId uint64) [][]byte {
+	db := d.Get(posconfig.EpLocalDB)
 
 	proposersArray := db.GetStorageByteArray(epochId)
 	length := len(proposersArray)
@@ -1,32 +1,36 @@
 package posdb
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/common"
 
 	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 func TestDbInitAll(t *testing.T) {
-	os.RemoveAll("/tmp/gwan")
-	DbInitAll("/tmp")
-	db := NewDb(posconfig.PosLocalDB)
+	dbs := NewMemoryDbs()
+	db := dbs.Get(posconfig.PosLocalDB)
 	if db == nil {
 		t.Fail()
 	}
 
-	db = NewDb(posconfig.RbLocalDB)
+	db = dbs.Get(posconfig.RbLocalDB)
 	if db == nil {
 		t.Fail()
 	}
 
-	db = NewDb(posconfig.EpLocalDB)
+	db = dbs.Get(posconfig.EpLocalDB)
 	if db == nil {
 		t.Fail()
 	}
@@ -44,8 +48,8 @@ func TestDbInitAll(t *testing.T) {
 	allQuit := make(chan struct{}, 3)
 
 	go func() {
+		db := dbs.Get(posconfig.PosLocalDB)
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.PosLocalDB)
 			db.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
@@ -67,8 +71,8 @@ func TestDbInitAll(t *testing.T) {
 	}()
 
 	go func() {
+		db := dbs.Get(posconfig.RbLocalDB)
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.RbLocalDB)
 			db.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
@@ -90,8 +94,8 @@ func TestDbInitAll(t *testing.T) {
 	}()
 
 	go func() {
+		db := dbs.Get(posconfig.EpLocalDB)
 		for i := 0; i < testCount; i++ {
-			db := NewDb(posconfig.EpLocalDB)
 			db.PutWithIndex(0, uint64(i), "", keys[i])
 		}
 
@@ -122,14 +126,14 @@ func TestDbInitAll(t *testing.T) {
 	case <-allQuit:
 	}
 
-	db = NewDb("test")
+	db = dbs.Get("test")
 	db.Put(0, "hello", []byte{1, 2, 3})
 	buf, err := db.Get(0, "hello")
 	if buf[0] != 1 || buf[1] != 2 || buf[2] != 3 || err != nil {
 		t.Fail()
 	}
 
-	db = GetDb()
+	db = dbs.Local()
 	db.Put(0, "hello", []byte{3, 4, 5})
 	buf, err = db.Get(0, "hello")
 	if buf[0] != 3 || buf[1] != 4 || buf[2] != 5 || err != nil {
@@ -140,10 +144,143 @@ func TestDbInitAll(t *testing.T) {
 }
 
 func TestInfomationGet(t *testing.T) {
-	buf := GetRBProposerGroup(0)
+	dbs := NewMemoryDbs()
+	buf := dbs.GetRBProposerGroup(0)
 	fmt.Println(buf)
-	buf2 := GetStakerInfoBytes(0, common.Address{})
+	buf2 := dbs.GetStakerInfoBytes(0, common.Address{})
 	fmt.Println(buf2)
-	buf4 := GetEpochLeaderGroup(0)
+	buf4 := dbs.GetEpochLeaderGroup(0)
 	fmt.Println(buf4)
 }
+
+func TestDbNamespace(t *testing.T) {
+	backend, _ := ethdb.NewMemDatabase()
+	dbs := NewDbs(backend)
+
+	rb, ep := dbs.Get(posconfig.RbLocalDB), dbs.Get(posconfig.EpLocalDB)
+	rb.Put(1, "leader", []byte{1})
+	ep.Put(1, "leader", []byte{2})
+	backend.Put([]byte("1_0_leader"), []byte{3})
+
+	if buf, err := rb.Get(1, "leader"); err != nil || !bytes.Equal(buf, []byte{1}) {
+		t.Fatalf("rb db: got %x, %v", buf, err)
+	}
+	if buf, err := ep.Get(1, "leader"); err != nil || !bytes.Equal(buf, []byte{2}) {
+		t.Fatalf("ep db: got %x, %v", buf, err)
+	}
+	if has, _ := backend.Has([]byte("wanpos-rblocaldb-1_0_leader")); !has {
+		t.Fatal("rb db content not found in the backend")
+	}
+
+	// The dbs of another node don't share any content.
+	other := NewMemoryDbs()
+	if _, err := other.Get(posconfig.RbLocalDB).Get(1, "leader"); err == nil {
+		t.Fatal("rb db content found in the dbs of another node")
+	}
+}
+
+func TestDbClear(t *testing.T) {
+	backend, _ := ethdb.NewMemDatabase()
+	rb, ep := NewTableDb(backend, posconfig.RbLocalDB), NewTableDb(backend, posconfig.EpLocalDB)
+	for i := uint64(0); i < 3; i++ {
+		rb.PutWithIndex(5, i, "", []byte{byte(i)})
+		ep.PutWithIndex(5, i, "", []byte{byte(i)})
+	}
+
+	if err := rb.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rb.GetStorageByteArray(5)) != 0 {
+		t.Fatal("rb db not cleared")
+	}
+	if len(ep.GetStorageByteArray(5)) != 3 {
+		t.Fatal("ep db cleared")
+	}
+
+	dir, err := ioutil.TempDir("", "posdb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ldb, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ldb.Close()
+
+	db := NewTableDb(ldb, posconfig.IncentiveLocalDB)
+	db.Put(1, "total", []byte{1})
+	if err := db.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Get(1, "total"); err == nil {
+		t.Fatal("leveldb backed db not cleared")
+	}
+}
+
+func TestDbEpochs(t *testing.T) {
+	backend, _ := ethdb.NewMemDatabase()
+	db, other := NewTableDb(backend, posconfig.IncentiveLocalDB), NewTableDb(backend, posconfig.RbLocalDB)
+	db.Put(3, "detail", []byte{1})
+	db.Put(12, "detail", []byte{1})
+	db.Put(5, "other_detail", []byte{1})
+	db.PutWithIndex(7, 1, "detail", []byte{1})
+	other.Put(9, "detail", []byte{1})
+
+	epochs, err := db.Epochs("detail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] < epochs[j] })
+	if len(epochs) != 2 || epochs[0] != 3 || epochs[1] != 12 {
+		t.Fatalf("got epochs %v, want [3 12]", epochs)
+	}
+}
+
+func TestMigrateLegacyDbs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "posdb-migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	resolvePath := func(name string) string { return filepath.Join(dir, name) }
+
+	// older versions wrote the keys of every db to a LevelDB of its own
+	ldb, err := ethdb.NewLDBDatabase(resolvePath(posconfig.RbLocalDB), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy := &Db{db: ldb}
+	for i := uint64(0); i < 3; i++ {
+		legacy.PutWithIndex(5, i, "", []byte{byte(i)})
+	}
+	ldb.Close()
+
+	backend, _ := ethdb.NewMemDatabase()
+	dbs := NewDbs(backend)
+	// left by an interrupted migration
+	dbs.Get(posconfig.RbLocalDB).PutWithIndex(6, 0, "", []byte{9})
+
+	if err := dbs.MigrateLegacyDbs(resolvePath); err != nil {
+		t.Fatal(err)
+	}
+	rb := dbs.Get(posconfig.RbLocalDB)
+	if got := rb.GetStorageByteArray(5); len(got) != 3 || !bytes.Equal(got[2], []byte{2}) {
+		t.Fatalf("migrated rb db: got %x", got)
+	}
+	if got := rb.GetStorageByteArray(6); len(got) != 0 {
+		t.Fatalf("content of an interrupted migration kept: got %x", got)
+	}
+	if _, err := os.Stat(resolvePath(posconfig.RbLocalDB)); !os.IsNotExist(err) {
+		t.Fatalf("legacy db dir not removed: %v", err)
+	}
+
+	// once migrated, the dbs are left alone
+	rb.PutWithIndex(6, 0, "", []byte{9})
+	if err := dbs.MigrateLegacyDbs(resolvePath); err != nil {
+		t.Fatal(err)
+	}
+	if got := rb.GetStorageByteArray(6); len(got) != 1 {
+		t.Fatalf("db changed by a second migration: got %x", got)
+	}
+}
@@ -0,0 +1,79 @@
+package posdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// legacyNames are the names of the PoS local dbs that older versions kept in a
+// LevelDB of their own each, in the instance directory of the node.
+var legacyNames = []string{
+	posconfig.PosLocalDB,
+	posconfig.RbLocalDB,
+	posconfig.EpLocalDB,
+	posconfig.StakerLocalDB,
+	posconfig.IncentiveLocalDB,
+	posconfig.ReorgLocalDB,
+	posconfig.EpochGenLocalDB,
+}
+
+// MigrateLegacyDbs moves the PoS local dbs that older versions kept in LevelDB
+// dirs of their own into their namespaces of d. resolvePath returns the dir of
+// a db name, "" if there is none. The content of a dir replaces the one of the
+// namespace, and the dir is removed once copied, so a migration interrupted is
+// done again on the next start.
+func (d *Dbs) MigrateLegacyDbs(resolvePath func(name string) string) error {
+	for _, name := range legacyNames {
+		dir := resolvePath(name)
+		if dir == "" {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(dir, "CURRENT")); err != nil {
+			continue
+		}
+		if err := d.migrate(name, dir); err != nil {
+			return fmt.Errorf("posdb: failed to migrate %s: %v", dir, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dbs) migrate(name, dir string) error {
+	if err := d.Get(name).Clear(); err != nil {
+		return err
+	}
+	legacy, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		return err
+	}
+	it := legacy.LDB().NewIterator(nil, nil)
+	batch, count := ethdb.NewTableBatch(d.backend, namespace(name)), 0
+	for err == nil && it.Next() {
+		if err = batch.Put(it.Key(), it.Value()); err != nil {
+			break
+		}
+		count++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			err = batch.Write()
+			batch = ethdb.NewTableBatch(d.backend, namespace(name))
+		}
+	}
+	it.Release()
+	if err == nil {
+		err = it.Error()
+	}
+	legacy.Close()
+	if err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Migrated PoS local db to the chain database", "name", name, "entries", count)
+	return os.RemoveAll(dir)
+}
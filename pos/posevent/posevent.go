@@ -0,0 +1,154 @@
+// Package posevent carries the notifications of the PoS protocol from the
+// modules producing them to the pos_subscribe RPC subscriptions.
+package posevent
+
+import (
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/event"
+)
+
+// NewEpochEvent is posted when the chain head enters a new epoch.
+type NewEpochEvent struct {
+	EpochID     uint64
+	BlockNumber uint64
+	BlockHash   common.Hash
+}
+
+// NewSlotEvent is posted when the chain head moves to a new slot.
+type NewSlotEvent struct {
+	EpochID     uint64
+	SlotID      uint64
+	BlockNumber uint64
+	BlockHash   common.Hash
+}
+
+// EpochLeadersSelectedEvent is posted when the epoch leaders and random
+// proposers of an epoch have been selected.
+type EpochLeadersSelectedEvent struct {
+	EpochID         uint64
+	EpochLeaders    []hexutil.Bytes
+	RandomProposers []hexutil.Bytes
+}
+
+// RandomBeaconFinalizedEvent is posted when the random number of an epoch has
+// been generated on chain.
+type RandomBeaconFinalizedEvent struct {
+	EpochID uint64
+	Random  *hexutil.Big
+}
+
+// IncentivePaidEvent is posted when the incentive of an epoch has been paid.
+type IncentivePaidEvent struct {
+	EpochID    uint64
+	Total      *hexutil.Big
+	Foundation *hexutil.Big
+	GasPool    *hexutil.Big
+	Receivers  int
+}
+
+// ChainQualityAlertEvent is posted when the chain quality level changes,
+// Level and Previous are "normal", "warning" or "critical".
+type ChainQualityAlertEvent struct {
+	EpochID        uint64
+	SlotID         uint64
+	Level          string
+	Previous       string
+	RollingQuality float64
+	EpochQuality   float64
+	ReorgNumber    uint64
+}
+
+// UnbondReleasedEvent is posted for every stake returned by the stake out of a
+// new chain head, Kind is "stakeOut", "partnerOut" or "delegateOut".
+type UnbondReleasedEvent struct {
+	EpochID     uint64
+	BlockNumber uint64
+	BlockHash   common.Hash
+	Kind        string
+	Validator   common.Address
+	Address     common.Address
+	Amount      *hexutil.Big
+}
+
+// ToBytesList converts a list of keys for use in events.
+func ToBytesList(list [][]byte) []hexutil.Bytes {
+	res := make([]hexutil.Bytes, len(list))
+	for i := range list {
+		res[i] = list[i]
+	}
+	return res
+}
+
+// Feed carries the PoS events of a node from the modules producing them to
+// its subscribers. Every node has its own, kept by its chain.
+type Feed struct {
+	newEpoch              event.Feed
+	newSlot               event.Feed
+	epochLeadersSelected  event.Feed
+	randomBeaconFinalized event.Feed
+	incentivePaid         event.Feed
+	chainQualityAlert     event.Feed
+	unbondReleased        event.Feed
+}
+
+// NewFeed creates the PoS event feed of a node.
+func NewFeed() *Feed {
+	return &Feed{}
+}
+
+// Post delivers a PoS event to all subscribers of its type. Values of other
+// types are ignored.
+func (f *Feed) Post(ev interface{}) {
+	switch ev := ev.(type) {
+	case NewEpochEvent:
+		f.newEpoch.Send(ev)
+	case NewSlotEvent:
+		f.newSlot.Send(ev)
+	case EpochLeadersSelectedEvent:
+		f.epochLeadersSelected.Send(ev)
+	case RandomBeaconFinalizedEvent:
+		f.randomBeaconFinalized.Send(ev)
+	case IncentivePaidEvent:
+		f.incentivePaid.Send(ev)
+	case ChainQualityAlertEvent:
+		f.chainQualityAlert.Send(ev)
+	case UnbondReleasedEvent:
+		f.unbondReleased.Send(ev)
+	}
+}
+
+// SubscribeNewEpoch registers a subscription of NewEpochEvent.
+func (f *Feed) SubscribeNewEpoch(ch chan<- NewEpochEvent) event.Subscription {
+	return f.newEpoch.Subscribe(ch)
+}
+
+// SubscribeNewSlot registers a subscription of NewSlotEvent.
+func (f *Feed) SubscribeNewSlot(ch chan<- NewSlotEvent) event.Subscription {
+	return f.newSlot.Subscribe(ch)
+}
+
+// SubscribeEpochLeadersSelected registers a subscription of EpochLeadersSelectedEvent.
+func (f *Feed) SubscribeEpochLeadersSelected(ch chan<- EpochLeadersSelectedEvent) event.Subscription {
+	return f.epochLeadersSelected.Subscribe(ch)
+}
+
+// SubscribeRandomBeaconFinalized registers a subscription of RandomBeaconFinalizedEvent.
+func (f *Feed) SubscribeRandomBeaconFinalized(ch chan<- RandomBeaconFinalizedEvent) event.Subscription {
+	return f.randomBeaconFinalized.Subscribe(ch)
+}
+
+// SubscribeIncentivePaid registers a subscription of IncentivePaidEvent.
+func (f *Feed) SubscribeIncentivePaid(ch chan<- IncentivePaidEvent) event.Subscription {
+	return f.incentivePaid.Subscribe(ch)
+}
+
+// SubscribeChainQualityAlert registers a subscription of ChainQualityAlertEvent.
+func (f *Feed) SubscribeChainQualityAlert(ch chan<- ChainQualityAlertEvent) event.Subscription {
+	return f.chainQualityAlert.Subscribe(ch)
+}
+
+// SubscribeUnbondReleased registers a subscription of UnbondReleasedEvent.
+func (f *Feed) SubscribeUnbondReleased(ch chan<- UnbondReleasedEvent) event.Subscription {
+	return f.unbondReleased.Subscribe(ch)
+}
@@ -0,0 +1,43 @@
+package posevent
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+)
+
+func TestPost(t *testing.T) {
+	feed, other := NewFeed(), NewFeed()
+	epochs := make(chan NewEpochEvent, 1)
+	randoms := make(chan RandomBeaconFinalizedEvent, 1)
+	sub1 := feed.SubscribeNewEpoch(epochs)
+	defer sub1.Unsubscribe()
+	sub2 := feed.SubscribeRandomBeaconFinalized(randoms)
+	defer sub2.Unsubscribe()
+
+	feed.Post(NewEpochEvent{EpochID: 3, BlockNumber: 10})
+	feed.Post(RandomBeaconFinalizedEvent{EpochID: 4, Random: (*hexutil.Big)(big.NewInt(9))})
+	feed.Post("ignored")
+	// the events of another node don't reach the subscribers
+	other.Post(NewEpochEvent{EpochID: 5})
+
+	if ev := <-epochs; ev.EpochID != 3 || ev.BlockNumber != 10 {
+		t.Fatalf("unexpected epoch event %+v", ev)
+	}
+	if ev := <-randoms; ev.EpochID != 4 || ev.Random.ToInt().Int64() != 9 {
+		t.Fatalf("unexpected random event %+v", ev)
+	}
+	select {
+	case ev := <-epochs:
+		t.Fatalf("unexpected epoch event %+v", ev)
+	default:
+	}
+}
+
+func TestToBytesList(t *testing.T) {
+	list := ToBytesList([][]byte{{1, 2}, {3}})
+	if len(list) != 2 || list[0].String() != "0x0102" || list[1].String() != "0x03" {
+		t.Fatalf("unexpected list %v", list)
+	}
+}
@@ -0,0 +1,166 @@
+package postx
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var (
+	errNoSender = errors.New("pos tx sender is not ready")
+	errNoKey    = errors.New("pos tx sender has no key")
+
+	// maxResends bounds how many times a dropped protocol tx is submitted again
+	// within its stage window.
+	maxResends = 5
+)
+
+// TxPool is the part of core.TxPool the sender submits to.
+type TxPool interface {
+	AddLocal(tx *types.Transaction) error
+	Get(hash common.Hash) *types.Transaction
+	State() *state.ManagedState
+	GasPrice() *big.Int
+}
+
+// Tx is a PoS protocol transaction to sign and submit.
+type Tx struct {
+	To       common.Address
+	Payload  []byte
+	EpochID  uint64
+	Deadline uint64 // last slot of EpochID in which the tx is still accepted
+}
+
+type trackedTx struct {
+	*Tx
+	hash    common.Hash
+	resends int
+}
+
+// Sender signs the PoS protocol transactions of the local node with its miner
+// key and submits them straight into the transaction pool. The transactions are
+// tracked until they are mined, and sent again if they leave the pool before
+// their stage window closes.
+type Sender struct {
+	pool    TxPool
+	chainDb ethdb.Database
+	signer  types.Signer
+	key     *keystore.Key
+
+	mu      sync.Mutex
+	tracked []*trackedTx
+}
+
+// NewSender creates a sender submitting into pool, looking up mined
+// transactions in chainDb and signing with key for the chain chainID.
+func NewSender(pool TxPool, chainDb ethdb.Database, chainID *big.Int, key *keystore.Key) *Sender {
+	return &Sender{
+		pool:    pool,
+		chainDb: chainDb,
+		signer:  types.NewEIP155Signer(chainID),
+		key:     key,
+	}
+}
+
+// SendTx submits tx through s, it is the default way the PoS modules send
+// their transactions.
+func SendTx(s *Sender, tx *Tx) (common.Hash, error) {
+	if s == nil {
+		return common.Hash{}, errNoSender
+	}
+	return s.Send(tx)
+}
+
+// Send signs tx with the next pool nonce of the miner account, adds it to the
+// pool and tracks it for resending.
+func (s *Sender) Send(tx *Tx) (common.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash, err := s.submit(tx)
+	if err != nil {
+		log.SyslogErr("send pos tx fail", "err", err)
+		return common.Hash{}, err
+	}
+
+	s.tracked = append(s.tracked, &trackedTx{Tx: tx, hash: hash})
+	log.SyslogInfo("send pos tx success", "txHash", hash)
+	return hash, nil
+}
+
+// Resend is called once a slot. It forgets the transactions that were mined or
+// whose stage window has closed, and submits again the ones which were dropped
+// from the pool.
+func (s *Sender) Resend(epochID uint64, slotID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tracked := s.tracked[:0]
+	for _, t := range s.tracked {
+		if blockHash, _, _ := core.GetTxLookupEntry(s.chainDb, t.hash); blockHash != (common.Hash{}) {
+			continue
+		}
+		if epochID > t.EpochID || (epochID == t.EpochID && slotID > t.Deadline) {
+			log.Warn("Pos tx was not mined in its stage", "txHash", t.hash, "epochID", t.EpochID, "deadline", t.Deadline)
+			continue
+		}
+		if s.pool.Get(t.hash) != nil {
+			tracked = append(tracked, t)
+			continue
+		}
+		if t.resends >= maxResends {
+			log.Warn("Pos tx dropped too many times", "txHash", t.hash, "resends", t.resends)
+			continue
+		}
+
+		t.resends++
+		hash, err := s.submit(t.Tx)
+		if err != nil {
+			log.Warn("Resend pos tx fail", "txHash", t.hash, "err", err)
+		} else {
+			log.Info("Resent dropped pos tx", "old", t.hash, "txHash", hash, "resends", t.resends)
+			t.hash = hash
+		}
+		tracked = append(tracked, t)
+	}
+	for i := len(tracked); i < len(s.tracked); i++ {
+		s.tracked[i] = nil
+	}
+	s.tracked = tracked
+}
+
+// Pending returns the number of transactions still tracked.
+func (s *Sender) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.tracked)
+}
+
+func (s *Sender) submit(tx *Tx) (common.Hash, error) {
+	if s.key == nil || s.key.PrivateKey == nil {
+		return common.Hash{}, errNoKey
+	}
+
+	gas := core.IntrinsicGas(tx.Payload, &tx.To, true)
+	nonce := s.pool.State().GetNonce(s.key.Address)
+	ptx := types.NewTransaction(nonce, tx.To, big.NewInt(0), gas, s.pool.GasPrice(), tx.Payload)
+	ptx.SetTxtype(types.POS_TX)
+
+	signed, err := types.SignTx(ptx, s.signer, s.key.PrivateKey)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.pool.AddLocal(signed); err != nil {
+		return common.Hash{}, err
+	}
+	return signed.Hash(), nil
+}
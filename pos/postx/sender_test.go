@@ -0,0 +1,158 @@
+package postx
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+type testPool struct {
+	txs   map[common.Hash]*types.Transaction
+	state *state.ManagedState
+}
+
+func newTestPool(t *testing.T) *testPool {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(db))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testPool{txs: make(map[common.Hash]*types.Transaction), state: state.ManageState(statedb)}
+}
+
+func (p *testPool) AddLocal(tx *types.Transaction) error {
+	from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), tx)
+	if err != nil {
+		return err
+	}
+	p.txs[tx.Hash()] = tx
+	p.state.SetNonce(from, tx.Nonce()+1)
+	return nil
+}
+
+func (p *testPool) Get(hash common.Hash) *types.Transaction { return p.txs[hash] }
+func (p *testPool) State() *state.ManagedState              { return p.state }
+func (p *testPool) GasPrice() *big.Int                      { return big.NewInt(180000000000) }
+
+func newTestSender(t *testing.T) (*Sender, *testPool, ethdb.Database) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &keystore.Key{Address: crypto.PubkeyToAddress(privateKey.PublicKey), PrivateKey: privateKey}
+
+	pool := newTestPool(t)
+	db, _ := ethdb.NewMemDatabase()
+	return NewSender(pool, db, big.NewInt(1), key), pool, db
+}
+
+func TestSend(t *testing.T) {
+	s, pool, _ := newTestSender(t)
+	to := common.BytesToAddress([]byte{0x62})
+
+	for i := uint64(0); i < 2; i++ {
+		hash, err := s.Send(&Tx{To: to, Payload: []byte{1, 2, 3}, EpochID: 1, Deadline: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tx := pool.Get(hash)
+		if tx == nil {
+			t.Fatal("tx not in pool")
+		}
+		if tx.Txtype() != types.POS_TX || *tx.To() != to || tx.Nonce() != i {
+			t.Errorf("tx type %d, to %x, nonce %d", tx.Txtype(), tx.To(), tx.Nonce())
+		}
+		if from, _ := types.Sender(s.signer, tx); from != s.key.Address {
+			t.Errorf("tx signed by %x, want %x", from, s.key.Address)
+		}
+		if tx.Gas().Cmp(core.IntrinsicGas(tx.Data(), tx.To(), true)) != 0 {
+			t.Errorf("tx gas %v", tx.Gas())
+		}
+	}
+	if s.Pending() != 2 {
+		t.Errorf("tracked %d txs, want 2", s.Pending())
+	}
+}
+
+func TestSendTxNoSender(t *testing.T) {
+	if _, err := SendTx(nil, &Tx{}); err != errNoSender {
+		t.Errorf("got %v, want %v", err, errNoSender)
+	}
+	if _, err := NewSender(newTestPool(t), nil, big.NewInt(1), nil).Send(&Tx{}); err != errNoKey {
+		t.Errorf("got %v, want %v", err, errNoKey)
+	}
+}
+
+func TestResendDropped(t *testing.T) {
+	s, pool, _ := newTestSender(t)
+
+	hash, err := s.Send(&Tx{To: common.BytesToAddress([]byte{0x62}), EpochID: 1, Deadline: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// still in the pool, nothing to do
+	s.Resend(1, 10)
+	if s.Pending() != 1 || len(pool.txs) != 1 {
+		t.Fatalf("tracked %d, pooled %d", s.Pending(), len(pool.txs))
+	}
+
+	delete(pool.txs, hash)
+	s.Resend(1, 11)
+	if s.Pending() != 1 || len(pool.txs) != 1 {
+		t.Fatalf("dropped tx not resent, tracked %d, pooled %d", s.Pending(), len(pool.txs))
+	}
+	if s.tracked[0].hash == hash || pool.Get(s.tracked[0].hash) == nil {
+		t.Error("resent tx not tracked")
+	}
+}
+
+func TestResendMaxTimes(t *testing.T) {
+	s, pool, _ := newTestSender(t)
+
+	if _, err := s.Send(&Tx{To: common.BytesToAddress([]byte{0x62}), EpochID: 1, Deadline: 20}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= maxResends; i++ {
+		pool.txs = make(map[common.Hash]*types.Transaction)
+		s.Resend(1, 1)
+	}
+	if s.Pending() != 0 {
+		t.Errorf("tracked %d txs after %d resends", s.Pending(), maxResends)
+	}
+}
+
+func TestResendForget(t *testing.T) {
+	s, pool, db := newTestSender(t)
+	to := common.BytesToAddress([]byte{0x62})
+
+	mined, err := s.Send(&Tx{To: to, EpochID: 1, Deadline: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Send(&Tx{To: to, EpochID: 1, Deadline: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Send(&Tx{To: to, EpochID: 0, Deadline: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	block := types.NewBlock(&types.Header{Number: big.NewInt(1)}, []*types.Transaction{pool.Get(mined)}, nil, nil)
+	if err := core.WriteTxLookupEntries(db, block); err != nil {
+		t.Fatal(err)
+	}
+
+	// the mined tx, the tx past its deadline and the tx of the last epoch go
+	s.Resend(1, 11)
+	if s.Pending() != 0 {
+		t.Errorf("tracked %d txs, want 0", s.Pending())
+	}
+}
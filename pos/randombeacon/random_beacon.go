@@ -8,21 +8,17 @@ import (
 	"io"
 	"sync"
 
-	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core"
-	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
 
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 type RbEnsDataCollector struct {
@@ -43,7 +39,7 @@ type DoStageWork func() error
 
 type LoopEvent struct {
 	statedb vm.StateDB
-	rc      *rpc.Client
+	sender  *postx.Sender
 	eid     uint64
 	sid     uint64
 }
@@ -124,9 +120,11 @@ type RandomBeacon struct {
 	proposerPks  []bn256.G1
 	myPropserIds []uint32
 
-	statedb   vm.StateDB
-	epocher   *epochLeader.Epocher
-	rpcClient *rpc.Client
+	statedb vm.StateDB
+	epocher *epochLeader.Epocher
+	db      *posdb.Db
+	sender  *postx.Sender
+	key     *keystore.Key // unlocked key of the miner
 
 	wg sync.WaitGroup
 	mutex sync.Mutex
@@ -145,7 +143,6 @@ type RandomBeacon struct {
 var (
 	maxUint64      = uint64(1<<64 - 1)
 	loopEventCount = 1000
-	randomBeacon   RandomBeacon
 	rbPloys        = "RB_PLOYS"
 )
 
@@ -159,11 +156,14 @@ var (
 	errNotAllTaskSuc   = errors.New("not all task succeed")
 )
 
-func GetRandonBeaconInst() *RandomBeacon {
-	return &randomBeacon
+// NewRandomBeacon creates the random beacon of a node, which is started by Init.
+func NewRandomBeacon() *RandomBeacon {
+	return &RandomBeacon{}
 }
 
-func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
+// Init starts the random beacon, reading the random proposers from epocher and
+// saving its polynomials to db. The beacon takes part in the groups of key.
+func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher, db *posdb.Db, key *keystore.Key) {
 	defer func() {
 		rb.mutex.Unlock()
 	}()
@@ -176,12 +176,14 @@ func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
 	rb.epochStage = vm.RbDkg1Stage
 	rb.epochId = maxUint64
 	rb.polys = make(PolyMap)
-	rb.rpcClient = nil
+	rb.sender = nil
 
 	rb.epocher = epocher
+	rb.db = db
+	rb.key = key
 
 	// function
-	rb.getRBProposerGroupF = epochLeader.GetEpocher().GetRBProposerG1
+	rb.getRBProposerGroupF = epocher.GetRBProposerG1
 	rb.getCji = vm.GetCji
 	rb.getEns = vm.GetEncryptShare
 	rb.getRBM = vm.GetRBM
@@ -210,7 +212,7 @@ func (rb *RandomBeacon) Stop() {
 	rb.loopEvents = nil
 }
 
-func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid uint64) (err error) {
+func (rb *RandomBeacon) Loop(statedb vm.StateDB, sender *postx.Sender, eid uint64, sid uint64) (err error) {
 	defer func() {
 		rb.mutex.Unlock()
 		if e := recover(); e != nil {
@@ -224,12 +226,12 @@ func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid
 		return errUninitialized
 	}
 
-	if statedb == nil || rc == nil {
+	if statedb == nil || sender == nil {
 		log.SyslogErr("invalid RB loop input param")
 		return errInvalidInParam
 	}
 
-	rb.loopEvents <- &LoopEvent{statedb, rc, eid, sid}
+	rb.loopEvents <- &LoopEvent{statedb, sender, eid, sid}
 	return
 }
 
@@ -242,7 +244,7 @@ func (rb *RandomBeacon) LoopRoutine() {
 			break
 		}
 
-		rb.doLoop(event.statedb, event.rc, event.eid, event.sid)
+		rb.doLoop(event.statedb, event.sender, event.eid, event.sid)
 	}
 }
 
@@ -267,10 +269,10 @@ func (rb *RandomBeacon) updateStage(stage int) {
 	rb.taskTags = nil
 }
 
-func (rb *RandomBeacon) doLoop(statedb vm.StateDB, rc *rpc.Client, epochId uint64, slotId uint64) error {
+func (rb *RandomBeacon) doLoop(statedb vm.StateDB, sender *postx.Sender, epochId uint64, slotId uint64) error {
 	log.SyslogInfo("rb doLoop begin", "epochId", epochId, "slotId", slotId, "self epochId", rb.epochId)
 	rb.statedb = statedb
-	rb.rpcClient = rc
+	rb.sender = sender
 
 	if rb.epochId != maxUint64 && rb.epochId > epochId {
 		log.SyslogErr("RB doloop fail", "err", errEpochIdRollback.Error())
@@ -382,7 +384,7 @@ func (rb *RandomBeacon) getMyRBProposerId(epochId uint64) []uint32 {
 		return nil
 	}
 
-	selfPk := posconfig.Cfg().GetMinerBn256PK()
+	selfPk := rb.minerBn256PK()
 	if selfPk == nil {
 		return nil
 	}
@@ -608,7 +610,7 @@ func (rb *RandomBeacon) doSIG(proposerId uint32) error {
 }
 
 func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, error) {
-	prikey := posconfig.Cfg().GetMinerBn256SK()
+	prikey := rb.minerBn256SK()
 	datas := make([]RbEnsDataCollector, 0)
 
 	for id, pk := range rb.proposerPks {
@@ -686,26 +688,44 @@ func (rb *RandomBeacon) sendSIG(payloadObj *vm.RbSIGTxPayload) error {
 }
 
 func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
-	to := vm.GetRBAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = rb.getTxFrom()
-	arg["to"] = to
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-
+	tx := &postx.Tx{
+		To:       vm.GetRBAddress(),
+		Payload:  payload,
+		EpochID:  rb.epochId,
+		Deadline: rb.stageEnd(),
+	}
 
 	log.SyslogInfo("do send rb tx", "payload len", len(payload))
-	_, err := util.SendTx(rb.rpcClient, arg)
+	_, err := postx.SendTx(rb.sender, tx)
 	return err
 }
 
-func (rb *RandomBeacon) getTxFrom() common.Address {
-	return posconfig.Cfg().GetMinerAddr()
+// stageEnd returns the last slot of the current random beacon stage.
+// minerBn256PK returns the bn256 public key of the miner, nil if it has no key.
+func (rb *RandomBeacon) minerBn256PK() *bn256.G1 {
+	if rb.key == nil {
+		return nil
+	}
+	return new(bn256.G1).Set(rb.key.PrivateKey3.PublicKeyBn256.G1)
+}
+
+// minerBn256SK returns the bn256 private key of the miner, nil if it has no key.
+func (rb *RandomBeacon) minerBn256SK() *big.Int {
+	if rb.key == nil {
+		return nil
+	}
+	return new(big.Int).Set(rb.key.PrivateKey3.D)
+}
+
+func (rb *RandomBeacon) stageEnd() uint64 {
+	switch rb.epochStage {
+	case vm.RbDkg1Stage:
+		return posconfig.Cfg().Dkg1End
+	case vm.RbDkg2Stage:
+		return posconfig.Cfg().Dkg2End
+	default:
+		return posconfig.Cfg().SignEnd
+	}
 }
 
 func (rb *RandomBeacon) storePolys() error {
@@ -719,7 +739,7 @@ func (rb *RandomBeacon) storePolys() error {
 		return err
 	}
 
-	_, err = posdb.GetDb().Put(rb.epochId, rbPloys, b)
+	_, err = rb.db.Put(rb.epochId, rbPloys, b)
 	if err != nil {
 		log.SyslogErr("random beacon store polys fail", "err", err)
 		return err
@@ -729,7 +749,7 @@ func (rb *RandomBeacon) storePolys() error {
 }
 
 func (rb *RandomBeacon) loadPolys() error {
-	b, err := posdb.GetDb().Get(rb.epochId, rbPloys)
+	b, err := rb.db.Get(rb.epochId, rbPloys)
 	if err != nil {
 		log.SyslogDebug("random beacon load polys fail", "err", err)
 		return err
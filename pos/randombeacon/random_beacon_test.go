@@ -13,9 +13,10 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"io"
 	"math/big"
 	"sync"
@@ -25,6 +26,7 @@ import (
 
 var (
 	selfPrivate      *accBn256.PrivateKeyBn256
+	testMinerKey     *keystore.Key // key the random beacons of the tests are initialized with
 	commityPrivate   *accBn256.PrivateKeyBn256
 	hbase            = new(bn256.G2).ScalarBaseMult(big.NewInt(int64(1)))
 	ens              = make([][]*bn256.G1, 0)
@@ -42,7 +44,7 @@ func TestRandomBeacon_Init(t *testing.T) {
 	//	t.Error("generate bn256 fail, ", err)
 	//}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 
 	if rb.epochStage != vm.RbDkg1Stage {
 		t.Error("invalid epoch stage")
@@ -60,11 +62,11 @@ func TestRandomBeacon_Init(t *testing.T) {
 		t.Error("invalid rb epocher")
 	}
 
-	if rb.rpcClient != nil {
-		t.Error("invalid rb rpc client")
+	if rb.sender != nil {
+		t.Error("invalid rb tx sender")
 	}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 }
 
 func initKeystore(rb *RandomBeacon) error {
@@ -78,7 +80,8 @@ func initKeystore(rb *RandomBeacon) error {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
+	rb.key = testMinerKey
 
 	return nil
 }
@@ -99,7 +102,7 @@ func BenchmarkRandomBeacon_Stop(b *testing.B) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
 
 
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
@@ -107,7 +110,7 @@ func BenchmarkRandomBeacon_Stop(b *testing.B) {
 
 	for i := 0; i < b.N; i++ {
 		fmt.Println("benchmark loop once")
-		rb.Init(&epocher)
+		rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 
 		wg.Add(1)
 		go callRBLoop(&rb, &wg)
@@ -132,11 +135,11 @@ func callRBLoop(rb *RandomBeacon, wg *sync.WaitGroup) {
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 		epochId = uint64(0)
 		slotId = uint64(0)
-		rc = new(rpc.Client)
+		sender = new(postx.Sender)
 	)
 
 	for ;; {
-		err := rb.Loop(statedb, rc, epochId, slotId)
+		err := rb.Loop(statedb, sender, epochId, slotId)
 		if err != nil {
 			fmt.Println("callRbLoop break loop, err:", err)
 			break
@@ -157,7 +160,7 @@ func tmpGetRBProposerGroup(epochId uint64) []bn256.G1 {
 }
 
 func TestRandomBeacon_updateEpochId(t *testing.T) {
-	rb := GetRandonBeaconInst()
+	rb := NewRandomBeacon()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
@@ -199,7 +202,7 @@ func TestRandomBeacon_updateEpochId(t *testing.T) {
 }
 
 func TestRandomBeacon_updateStage(t *testing.T) {
-	rb := GetRandonBeaconInst()
+	rb := NewRandomBeacon()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
@@ -264,19 +267,19 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 		epochId    = uint64(0)
 		slotId     = uint64(0)
-		rc         = new(rpc.Client)
+		sender         = new(postx.Sender)
 		epocher    epochLeader.Epocher
 		actureDkg1sCallTimes = 0
 		actureDkg2sCallTimes = 0
 		actureSIGsCallTimes = 0
 	)
 
-	rb := GetRandonBeaconInst()
+	rb := NewRandomBeacon()
 	if rb == nil {
 		t.Error("invalid random beacon instance")
 	}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.fDoDKG1s = DoDKG1sSuc
 	rb.fDoDKG2s = DoDKG2sSuc
@@ -290,7 +293,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 		commityPrivate = private
 
-		err = rb.doLoop(statedb, rc, epochId, slotId)
+		err = rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -315,7 +318,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		}
 
 		epochId++
-		err = rb.doLoop(statedb, rc, epochId, slotId)
+		err = rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -337,7 +340,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -360,7 +363,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId++
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -382,7 +385,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 		rb.fDoDKG1s = DoDKG1sFail
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -405,7 +408,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -427,7 +430,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -447,7 +450,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId = 40
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -468,7 +471,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 		rb.fDoDKG2s = DoDKG2sFail
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -491,7 +494,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -512,7 +515,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -532,7 +535,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId = 80
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -553,7 +556,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 		rb.fDoSIGs = DoSIGsFail
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -576,7 +579,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -597,7 +600,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -618,7 +621,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId++
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -639,7 +642,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId--
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, sender, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -670,14 +673,14 @@ func TestRandomBeacon_GetMyRBProposerId(t *testing.T) {
 	}
 
 	selfPrivate = key.PrivateKey3
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
 
 	commityPrivate, err = accBn256.GenerateBn256()
 	if err != nil {
 		t.Error("generate bn256 fail, ", err)
 	}
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 
 	rb.myPropserIds = rb.getMyRBProposerId(0)
@@ -715,9 +718,9 @@ func TestRandomBeacon_DoGenerateDKG1(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getCji = tmpGetCji
 
@@ -785,9 +788,9 @@ func TestRandomBeacon_GenerateDKG2(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getCji = tmpGetCji
 
@@ -879,9 +882,9 @@ func TestRandomBeacon_GenerateSIG(t *testing.T) {
 
 	selfPrivate = key.PrivateKey3
 	commityPrivate = selfPrivate
-	posconfig.Cfg().MinerKey = &key
+	testMinerKey = &key
 
-	rb.Init(&epocher)
+	rb.Init(&epocher, posdb.NewMemoryDbs().Local(), testMinerKey)
 	rb.getRBProposerGroupF = tmpGetRBProposerGroup
 	rb.getEns = tmpGetEnsFunc
 	rb.getRBM = tmpGetRBM
@@ -993,6 +996,10 @@ func tmpGetCji(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G2, e
 }
 
 func TestPolyMap_storePolys(t *testing.T) {
+	storeTestPolys(t, posdb.NewMemoryDbs().Local())
+}
+
+func storeTestPolys(t *testing.T, db *posdb.Db) {
 	poly1 := make(rbselection.Polynomial, 0)
 	poly2 := make(rbselection.Polynomial, 0)
 	poly1 = append(poly1, *big.NewInt(11))
@@ -1000,7 +1007,7 @@ func TestPolyMap_storePolys(t *testing.T) {
 	poly2 = append(poly2, *big.NewInt(21))
 	poly2 = append(poly2, *big.NewInt(22))
 
-	rb := RandomBeacon{}
+	rb := RandomBeacon{db: db}
 	rb.polys = make(PolyMap)
 	rb.polys[1] = PolyInfo{poly1, big.NewInt(1)}
 	rb.polys[2] = PolyInfo{poly2, big.NewInt(2)}
@@ -1012,9 +1019,10 @@ func TestPolyMap_storePolys(t *testing.T) {
 }
 
 func TestPolyMap_loadPolys(t *testing.T) {
-	TestPolyMap_storePolys(t)
+	db := posdb.NewMemoryDbs().Local()
+	storeTestPolys(t, db)
 
-	rb := RandomBeacon{}
+	rb := RandomBeacon{db: db}
 	rb.polys = make(PolyMap)
 	err := rb.loadPolys()
 	if err != nil {
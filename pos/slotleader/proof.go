@@ -6,8 +6,9 @@ import (
 	"encoding/hex"
 	"math/big"
 
-	"github.com/wanchain/go-wanchain/pos/util"
 
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 
@@ -35,9 +36,51 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 		return false
 	}
 
-	rbBytes := rbPtr.Bytes()
+	stateDb, err := s.getCurrentStateDb()
+	if err != nil {
+		log.SyslogErr(err.Error())
+		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
+	}
+
+	return s.verifySlotProof(stateDb, s.blockChain.EpochBlocks().GetEpochBlockHash(epochID-1), epochLeadersPtrPre, rbPtr.Bytes(),
+		epochID, slotID, Proof, ProofMeg)
+}
+
+// VerifySlotProofByState verifies a slot leader proof without the local
+// blockchain. The previous epoch leaders are given by the caller and the
+// random number and stage two data are read from stateDb, which should be the
+// state of the block's parent. Light clients use it with an on-demand state.
+func (s *SLS) VerifySlotProofByState(stateDb *state.StateDB, epochLeadersPre [][]byte, epochID uint64,
+	slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey) bool {
+	if len(Proof) < 2 || len(ProofMeg) < 3 {
+		log.Warn("VerifySlotProofByState invalid proof length", "epochID", epochID, "slotID", slotID)
+		return false
+	}
+
+	if epochID == 0 || len(epochLeadersPre) == 0 {
+		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
+	}
+
+	epochLeadersPtrPre := make([]*ecdsa.PublicKey, len(epochLeadersPre))
+	for i := 0; i < len(epochLeadersPre); i++ {
+		epochLeadersPtrPre[i] = crypto.ToECDSAPub(epochLeadersPre[i])
+	}
+
+	rbPtr := vm.GetR(stateDb, epochID)
+	if rbPtr == nil {
+		log.SyslogErr("vm.GetR return nil, use a default value", "epochID", epochID)
+		rbPtr = big.NewInt(1)
+	}
+
+	return s.verifySlotProof(stateDb, common.Hash{}, epochLeadersPtrPre, rbPtr.Bytes(), epochID, slotID, Proof, ProofMeg)
+}
+
+// verifySlotProof checks the proof against the stage two data found in stateDb.
+// A non-empty cacheHash allows the stage two alpha pki to be cached under it.
+func (s *SLS) verifySlotProof(stateDb *state.StateDB, cacheHash common.Hash, epochLeadersPtrPre []*ecdsa.PublicKey,
+	rbBytes []byte, epochID uint64, slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey) bool {
 	// stage two info from trans
-	validEpochLeadersIndex, stageTwoAlphaPKi, err := s.getStageTwoFromTrans(epochID)
+	validEpochLeadersIndex, stageTwoAlphaPKi, err := s.getStageTwoFromTrans(stateDb, cacheHash, epochID)
 	if err != nil {
 		log.SyslogErr(err.Error())
 		// no stage2 trans on the block chain.
@@ -276,21 +319,23 @@ func (s *SLS) getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID ui
 	return skGt
 }
 
-func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posconfig.EpochLeaderCount]bool,
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey, err error) {
+func (s *SLS) getStageTwoFromTrans(stateDb *state.StateDB, cacheHash common.Hash, epochID uint64) (validEpochLeadersIndex []bool,
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey, err error) {
 
+	validEpochLeadersIndex = make([]bool, posconfig.EpochLeaderCount)
+	stageTwoAlphaPKi = newPublicKeyMatrix(posconfig.EpochLeaderCount, posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		validEpochLeadersIndex[i] = true
 	}
 
-	indexesSentTran, err := s.getSlotLeaderStage2TxIndexes(epochID - 1)
+	indexesSentTran, err := getStage2TxIndexesFromState(stateDb, epochID-1)
 	log.Debug("VerifySlotProof", "indexesSentTran", indexesSentTran)
 	if err != nil {
 		log.SyslogErr("getStageTwoFromTrans", "indexesSentTran error", err.Error())
 		return validEpochLeadersIndex, stageTwoAlphaPKi, err
 	}
 
-	hash := util.GetEpochBlockHash(epochID - 1)
+	useCache := s.aPkiCache != nil && cacheHash != (common.Hash{})
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		if !indexesSentTran[i] {
 			validEpochLeadersIndex[i] = false
@@ -298,22 +343,27 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 		}
 		// TODO:
 		bkey := make([]byte, 0)
-		bkey = append(bkey, hash[:]...)
+		bkey = append(bkey, cacheHash[:]...)
 		bkey = append(bkey, big.NewInt(int64(i)).Bytes()...)
 		ckey := crypto.Keccak256Hash(bkey)
 
 		var alphaPki []*ecdsa.PublicKey
-		alphaPkiCached, ok := APkiCache.Get(ckey)
+		var alphaPkiCached interface{}
+		ok := false
+		if useCache {
+			alphaPkiCached, ok = s.aPkiCache.Get(ckey)
+		}
 		if !ok {
 			var err error
-			statedb, _ := s.getCurrentStateDb()
-			alphaPki, _, err = vm.GetStage2TxAlphaPki(statedb, epochID-1, uint64(i))
+			alphaPki, _, err = vm.GetStage2TxAlphaPki(stateDb, epochID-1, uint64(i))
 			if err != nil {
 				log.Debug("VerifySlotProof:GetStage2TxAlphaPki", "index", i, "error", err.Error())
 				validEpochLeadersIndex[i] = false
 				continue
 			}
-			APkiCache.Add(ckey, alphaPki)
+			if useCache {
+				s.aPkiCache.Add(ckey, alphaPki)
+			}
 		} else {
 			alphaPki = alphaPkiCached.([]*ecdsa.PublicKey)
 		}
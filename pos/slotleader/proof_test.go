@@ -89,8 +89,7 @@ func TestVerifyDleqProof(t *testing.T) {
 }
 
 func TestGetSlotLeaderProof(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	pks, isGenesis, err := s.getSMAPieces(0)
 	if err != nil {
 		t.Error(err.Error())
@@ -123,8 +122,7 @@ func TestGetSlotLeaderProof(t *testing.T) {
 }
 
 func TestVerifySlotProofByGenesis(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	pks, isGenesis, err := s.getSMAPieces(0)
 	if err != nil {
 		t.Error(err.Error())
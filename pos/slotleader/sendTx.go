@@ -2,41 +2,34 @@ package slotleader
 
 import (
 	"errors"
-	"math/big"
 
 	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core"
-	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
 var (
-	errRCNotReady = errors.New("rc is not ready")
+	errSenderNotReady = errors.New("tx sender is not ready")
 )
 
-type SendTxFn func(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error)
+type SendTxFn func(sender *postx.Sender, tx *postx.Tx) (common.Hash, error)
 
-func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
-	if s.rc == nil {
-		return errRCNotReady
+// sendSlotTx sends a slot leader selection tx, which must be included no later
+// than the slot deadline of the working epoch.
+func (s *SLS) sendSlotTx(payload []byte, deadline uint64, posSender SendTxFn) error {
+	if s.sender == nil {
+		return errSenderNotReady
 	}
 
-	to := vm.GetSlotLeaderSCAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = s.key.Address
-	arg["to"] = vm.GetSlotLeaderSCAddress()
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-	log.Debug("Write data of payload", "length", len(data))
+	tx := &postx.Tx{
+		To:       vm.GetSlotLeaderSCAddress(),
+		Payload:  payload,
+		EpochID:  s.getWorkingEpochID(),
+		Deadline: deadline,
+	}
+	log.Debug("Write data of payload", "length", len(payload))
 
-	_, err := posSender(s.rc, arg)
+	_, err := posSender(s.sender, tx)
 	return err
 }
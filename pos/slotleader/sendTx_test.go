@@ -6,29 +6,28 @@ import (
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 
 	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
-func testInit() {
-	SlsInit()
-	GetSlotLeaderSelection().Init(nil, &rpc.Client{}, &keystore.Key{})
+func testInit() *SLS {
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
+	return s
 }
 
-func testSender(rc *rpc.Client, tx map[string]interface{}) (common.Hash, error) {
+func testSender(sender *postx.Sender, tx *postx.Tx) (common.Hash, error) {
 	return common.Hash{}, nil
 }
 
 func TestSendStage1Tx(t *testing.T) {
-	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
+	err := testInit().sendSlotTx(nil, 0, testSender)
 	if err != nil {
 		t.FailNow()
 	}
 }
 
 func TestSendStage2Tx(t *testing.T) {
-	testInit()
-	err := GetSlotLeaderSelection().sendSlotTx(nil, testSender)
+	err := testInit().sendSlotTx(nil, 0, testSender)
 	if err != nil {
 		t.FailNow()
 	}
@@ -22,7 +22,7 @@ import (
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	lru "github.com/hashicorp/golang-lru"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
@@ -55,49 +55,82 @@ var (
 type SLS struct {
 	workingEpochID uint64
 	workStage      int
-	rc             *rpc.Client
+	sender         *postx.Sender
 	key            *keystore.Key
 	stateDbTest    *state.StateDB
 
 	epochLeadersArray []string            // len(pki)=65 hex.EncodeToString
 	epochLeadersMap   map[string][]uint64 // key: pki value: []uint64 the indexes of this pki. hex.EncodeToString
 
-	slotLeadersPtrArray  [posconfig.SlotCount]*ecdsa.PublicKey
-	slotLeadersIndex     [posconfig.SlotCount]uint64
-	epochLeadersPtrArray [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	slotLeadersPtrArray  []*ecdsa.PublicKey // len: posconfig.SlotCount
+	slotLeadersIndex     []uint64           // len: posconfig.SlotCount
+	epochLeadersPtrArray []*ecdsa.PublicKey // len: posconfig.EpochLeaderCount
 	// true: can be used to slot leader false: can not be used to slot leader
-	validEpochLeadersIndex [posconfig.EpochLeaderCount]bool
+	validEpochLeadersIndex []bool
 
-	stageOneMi       [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKi [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProof    [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	stageOneMi       []*ecdsa.PublicKey
+	stageTwoAlphaPKi [][]*ecdsa.PublicKey // posconfig.EpochLeaderCount * posconfig.EpochLeaderCount
+	stageTwoProof    [][]*big.Int         // posconfig.EpochLeaderCount * StageTwoProofCount [0]: e; [1]:Z
 
 	slotCreateStatus       map[uint64]bool
 	slotCreateStatusLockCh chan int
 
 	blockChain *core.BlockChain
-
-	epochLeadersPtrArrayGenesis [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageOneMiGenesis           [posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoAlphaPKiGenesis     [posconfig.EpochLeaderCount][posconfig.EpochLeaderCount]*ecdsa.PublicKey
-	stageTwoProofGenesis        [posconfig.EpochLeaderCount][StageTwoProofCount]*big.Int //[0]: e; [1]:Z
+	db         *posdb.Db       // Local db of the node the leaders are saved to
+	epocher    util.SelectLead // Epoch leader selection of the chain, nil on light nodes
+	aPkiCache  *lru.ARCCache   // Alpha*Pki of the epoch leaders by block hash
+
+	epochLeadersPtrArrayGenesis []*ecdsa.PublicKey
+	stageOneMiGenesis           []*ecdsa.PublicKey
+	stageTwoAlphaPKiGenesis     [][]*ecdsa.PublicKey
+	stageTwoProofGenesis        [][]*big.Int //[0]: e; [1]:Z
 	randomGenesis               *big.Int
-	smaGenesis                  [posconfig.EpochLeaderCount]*ecdsa.PublicKey
+	smaGenesis                  []*ecdsa.PublicKey
 
 	sendTransactionFn SendTxFn
 }
 
-var slotLeaderSelection *SLS
-var APkiCache *lru.ARCCache
+// newSLS creates a slot leader selection with the epoch leader and slot
+// leader tables sized by the PoS parameters.
+func newSLS() *SLS {
+	return &SLS{
+		slotLeadersPtrArray:    make([]*ecdsa.PublicKey, posconfig.SlotCount),
+		slotLeadersIndex:       make([]uint64, posconfig.SlotCount),
+		epochLeadersPtrArray:   make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount),
+		validEpochLeadersIndex: make([]bool, posconfig.EpochLeaderCount),
+		stageOneMi:             make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount),
+		stageTwoAlphaPKi:       newPublicKeyMatrix(posconfig.EpochLeaderCount, posconfig.EpochLeaderCount),
+		stageTwoProof:          newBigIntMatrix(posconfig.EpochLeaderCount, StageTwoProofCount),
+
+		epochLeadersPtrArrayGenesis: make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount),
+		stageOneMiGenesis:           make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount),
+		stageTwoAlphaPKiGenesis:     newPublicKeyMatrix(posconfig.EpochLeaderCount, posconfig.EpochLeaderCount),
+		stageTwoProofGenesis:        newBigIntMatrix(posconfig.EpochLeaderCount, StageTwoProofCount),
+		smaGenesis:                  make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount),
+	}
+}
+
+func newPublicKeyMatrix(rows, cols int) [][]*ecdsa.PublicKey {
+	m := make([][]*ecdsa.PublicKey, rows)
+	for i := range m {
+		m[i] = make([]*ecdsa.PublicKey, cols)
+	}
+	return m
+}
+
+func newBigIntMatrix(rows, cols int) [][]*big.Int {
+	m := make([][]*big.Int, rows)
+	for i := range m {
+		m[i] = make([]*big.Int, cols)
+	}
+	return m
+}
 
 type Pack struct {
 	Proof    [][]byte
 	ProofMeg [][]byte
 }
 
-func GetSlotLeaderSelection() *SLS {
-	return slotLeaderSelection
-}
 func (s *SLS) GetLocalPublicKey() (*ecdsa.PublicKey, error) {
 	return s.getLocalPublicKey()
 }
@@ -135,8 +168,8 @@ func (s *SLS) GetSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return s.slotLeadersPtrArray[slotID], nil
 	}
 	// read from local db
-	for i := 0; i < posconfig.SlotCount; i++ {
-		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		pkByte, err := s.db.GetWithIndex(epochID, i, SlotLeader)
 		if err != nil {
 			return nil, vm.ErrSlotLeaderGroupNotReady
 		}
@@ -153,18 +186,21 @@ func (s *SLS) GetSma(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, er
 	return s.getSMAPieces(epochID)
 }
 
-func SlsInit() {
-	var err error
-	APkiCache, err = lru.NewARC(1000)
-	if err != nil || APkiCache == nil {
+// NewSLS creates the slot leader selection of a node, saving its work to db and
+// reading the epoch leaders from epocher.
+func NewSLS(db *posdb.Db, epocher util.SelectLead) *SLS {
+	s := newSLS()
+	s.db = db
+	s.epocher = epocher
+	cache, err := lru.NewARC(1000)
+	if err != nil || cache == nil {
 		log.SyslogErr("APkiCache failed")
 	}
-	slotLeaderSelection = &SLS{}
-	slotLeaderSelection.epochLeadersMap = make(map[string][]uint64)
-	slotLeaderSelection.epochLeadersArray = make([]string, 0)
-	slotLeaderSelection.slotCreateStatus = make(map[uint64]bool)
-	slotLeaderSelection.slotCreateStatusLockCh = make(chan int, 1)
-	s := slotLeaderSelection
+	s.aPkiCache = cache
+	s.epochLeadersMap = make(map[string][]uint64)
+	s.epochLeadersArray = make([]string, 0)
+	s.slotCreateStatus = make(map[uint64]bool)
+	s.slotCreateStatusLockCh = make(chan int, 1)
 	s.randomGenesis = big.NewInt(1)
 	epoch0Leaders := s.getEpoch0LeadersPK()
 	for index, value := range epoch0Leaders {
@@ -221,15 +257,23 @@ func SlsInit() {
 		smaPiecesHexStr = append(smaPiecesHexStr, hex.EncodeToString(crypto.FromECDSAPub(value)))
 	}
 	log.Debug("slot_leader_selection:init", "genesis sma pieces", smaPiecesHexStr)
-	log.SyslogInfo("SLS SlsInit success")
-
+	log.SyslogInfo("SLS NewSLS success")
+	return s
 }
 
 func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bool, err error) {
-	var ret [posconfig.EpochLeaderCount]bool
 	stateDb, err := s.getCurrentStateDb()
 	if err != nil {
-		return ret[:], err
+		return make([]bool, posconfig.EpochLeaderCount), err
+	}
+
+	return getStage2TxIndexesFromState(stateDb, epochID)
+}
+
+func getStage2TxIndexesFromState(stateDb *state.StateDB, epochID uint64) (indexesSentTran []bool, err error) {
+	ret := make([]bool, posconfig.EpochLeaderCount)
+	if stateDb == nil {
+		return ret, errNoStateDbInstance
 	}
 
 	slotLeaderPrecompileAddr := vm.GetSlotLeaderSCAddress()
@@ -242,14 +286,15 @@ func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bo
 	data := stateDb.GetStateByteArray(slotLeaderPrecompileAddr, keyHash)
 
 	if data == nil {
-		return ret[:], vm.ErrNoTx2TransInDB
+		return ret, vm.ErrNoTx2TransInDB
 	}
 
-	err = rlp.DecodeBytes(data, &ret)
-	if err != nil {
-		return ret[:], vm.ErrNoTx2TransInDB
+	var indexes []bool
+	err = rlp.DecodeBytes(data, &indexes)
+	if err != nil || len(indexes) != posconfig.EpochLeaderCount {
+		return ret, vm.ErrNoTx2TransInDB
 	}
-	return ret[:], nil
+	return indexes, nil
 }
 
 func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
@@ -257,7 +302,7 @@ func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
 		ret := big.NewInt(123)
 		return ret, nil
 	}
-	buf, err := posdb.GetDb().GetWithIndex(epochID, selfIndex, "alpha")
+	buf, err := s.db.GetWithIndex(epochID, selfIndex, "alpha")
 	if err != nil {
 		return nil, err
 	}
@@ -282,7 +327,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 	//test := false
 	if posconfig.SelfTestMode {
 		//test: generate test publicKey
-		epochLeaderAllBytes, err := posdb.GetDb().Get(epochID, EpochLeaders)
+		epochLeaderAllBytes, err := s.db.Get(epochID, EpochLeaders)
 		if err != nil {
 			return nil
 		}
@@ -303,7 +348,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 			GetEpochLeaders(epochID uint64) [][]byte
 		}
 
-		selector := util.GetEpocherInst() //TODO:CHECK INIT
+		selector := s.epocher
 
 		if selector == nil {
 			return nil
@@ -423,11 +468,11 @@ func (s *SLS) clearData() {
 		}
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := range s.slotLeadersPtrArray {
 		s.slotLeadersPtrArray[i] = nil
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
+	for i := range s.slotLeadersIndex {
 		s.slotLeadersIndex[i] = 0
 	}
 }
@@ -557,7 +602,7 @@ func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bo
 		return s.smaGenesis[:], true, nil
 	} else {
 		// pieces: alpha[1]*G, alpha[2]*G, .....
-		pieces, err := posdb.GetDb().Get(epochID, SecurityMsg)
+		pieces, err := s.db.Get(epochID, SecurityMsg)
 		if err != nil {
 			log.Warn("getSMAPieces error use epoch 0 SMA", "epochID", epochID, "SecurityMsg", SecurityMsg)
 			return s.smaGenesis[:], true, nil
@@ -626,7 +671,7 @@ func (s *SLS) generateSlotLeadsGroup(epochID uint64) error {
 
 	// insert slot address to local DB
 	for index, val := range slotLeadersPtr {
-		_, err = posdb.GetDb().PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
+		_, err = s.db.PutWithIndex(uint64(epochID), uint64(index), SlotLeader, crypto.FromECDSAPub(val))
 		if err != nil {
 			log.SyslogErr("generateSlotLeadsGroup:PutWithIndex", "error", err.Error())
 			return err
@@ -750,7 +795,7 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 		log.Debug(fmt.Sprintf("epochID+1 = %d set security message is %v\n", epochID+1,
 			hex.EncodeToString(crypto.FromECDSAPub(value))))
 	}
-	_, err = posdb.GetDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
+	_, err = s.db.Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
 	if err != nil {
 		log.SyslogErr("generateSecurityMsg:Put", "error", err.Error())
 		return err
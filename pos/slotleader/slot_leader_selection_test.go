@@ -6,9 +6,6 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
-	"os"
-	"path"
-	"path/filepath"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -18,7 +15,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
@@ -30,9 +27,7 @@ import (
 )
 
 func TestSlotLeaderSelectionGetInstance(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	SlsInit()
-	slot := GetSlotLeaderSelection()
+	slot := newTestSLS()
 	if slot == nil {
 		t.Fail()
 	}
@@ -215,7 +210,7 @@ func TestCRSave(t *testing.T) {
 func TestArraySave(t *testing.T) {
 
 	fmt.Printf("TestArraySave\n\n\n")
-	var sendtrans [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 	for index := range sendtrans {
 		sendtrans[index] = false
 	}
@@ -229,10 +224,10 @@ func TestArraySave(t *testing.T) {
 		t.Error(err.Error())
 	}
 
-	db := posdb.NewDb("testArraySave")
+	db := posdb.NewMemoryDbs().Get("testArraySave")
 	db.Put(uint64(0), "TestArraySave", bytes)
 
-	var sendtransGet [posconfig.EpochLeaderCount]bool
+	var sendtransGet []bool
 	bytesGet, err := db.Get(uint64(0), "TestArraySave")
 	if err != nil {
 		t.Error(err.Error())
@@ -245,8 +240,7 @@ func TestArraySave(t *testing.T) {
 }
 
 func TestGetEpoch0LeadersPK(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	//getEpoch0LeadersPK
 	leadersPK := s.getEpoch0LeadersPK()
 	if len(leadersPK) != posconfig.EpochLeaderCount {
@@ -261,8 +255,7 @@ func TestGetEpoch0LeadersPK(t *testing.T) {
 }
 
 func TestGetPreEpochLeadersPK(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	pks, err := s.getPreEpochLeadersPK(0)
 	if err != nil {
 		t.Error(err.Error())
@@ -273,8 +266,7 @@ func TestGetPreEpochLeadersPK(t *testing.T) {
 }
 
 func TestGetSMAPieces(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 
 	// getSMAPieces
 	pks, isGenesis, err := s.getSMAPieces(0)
@@ -304,8 +296,7 @@ func TestGetSMAPieces(t *testing.T) {
 }
 
 func TestDump(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	epochID := s.getWorkingEpochID()
 	s.setWorkingEpochID(2)
 
@@ -315,8 +306,8 @@ func TestDump(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	posconfig.SelfTestMode = true
 	s.dumpData()
@@ -326,8 +317,7 @@ func TestDump(t *testing.T) {
 }
 
 func TestClearData(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	epochID := s.getWorkingEpochID()
 	s.setWorkingEpochID(2)
 
@@ -337,8 +327,8 @@ func TestClearData(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	posconfig.SelfTestMode = true
 	s.buildEpochLeaderGroup(1)
@@ -352,14 +342,8 @@ func TestClearData(t *testing.T) {
 }
 
 func TestGetSlotLeader(t *testing.T) {
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	t.Log("Current dir path ", dir)
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
-	//posdb.NewDb(path.Join(dir, "sl_leader_test"))
-
-	SlsInit()
-	s := GetSlotLeaderSelection()
+
+	s := newTestSLS()
 	pk, err := s.GetSlotLeader(0, 1)
 	if err != nil {
 		t.Fail()
@@ -384,8 +368,8 @@ func TestGetSlotLeader(t *testing.T) {
 		t.Fail()
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		_, err = posdb.GetDb().PutWithIndex(1, uint64(i), SlotLeader, pkGenesisBytes)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		_, err = s.db.PutWithIndex(1, i, SlotLeader, pkGenesisBytes)
 		if err != nil {
 			t.Error(err.Error())
 			t.Fail()
@@ -408,14 +392,12 @@ func TestGetSlotLeader(t *testing.T) {
 			hex.EncodeToString(crypto.FromECDSAPub(pkSelected)))
 	}
 
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 
 }
 
 func TestGetLocalPublicKey(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
@@ -452,8 +434,7 @@ func TestGetLocalPublicKey(t *testing.T) {
 }
 
 func TestGetSlotCreateStatusByEpochID(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 
 	if s.GetSlotCreateStatusByEpochID(0) {
 		t.Fail()
@@ -464,23 +445,28 @@ func TestGetSlotCreateStatusByEpochID(t *testing.T) {
 	}
 }
 
-func TestGetSlotLeaderSelection(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+// Tests that slot leader selections created on different dbs don't share their
+// slot leaders, as several nodes run in one process.
+func TestNewSLSIsolated(t *testing.T) {
+	s1 := newTestSLS()
+	s2 := newTestSLS()
 
-	if s == nil {
-		t.Fail()
+	key, _ := crypto.GenerateKey()
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s1.db.PutWithIndex(1, i, SlotLeader, crypto.FromECDSAPub(&key.PublicKey))
+	}
+	if leader, err := s1.GetSlotLeader(1, 0); err != nil || !bytes.Equal(crypto.FromECDSAPub(leader), crypto.FromECDSAPub(&key.PublicKey)) {
+		t.Fatalf("slot leader mismatch: %v", err)
+	}
+	if _, err := s2.GetSlotLeader(1, 0); err != vm.ErrSlotLeaderGroupNotReady {
+		t.Fatalf("slot leaders shared between instances: %v", err)
 	}
 }
 
 func TestGetEpochLeaders(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	posconfig.SelfTestMode = true
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -488,8 +474,8 @@ func TestGetEpochLeaders(t *testing.T) {
 		pubKeyByes := crypto.FromECDSAPub(&prvKey.PublicKey)
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	// getEpochLeaders
 	epochLeadersBytes := s.getEpochLeaders(uint64(1))
@@ -527,18 +513,13 @@ func TestGetEpochLeaders(t *testing.T) {
 	}
 
 	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestGetAlpha(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
+	s := newTestSLS()
 
 	alpha := big.NewInt(0).SetUint64(uint64(^uint64(0)))
-	posdb.GetDb().PutWithIndex(uint64(0), uint64(0), "alpha", alpha.Bytes())
+	s.db.PutWithIndex(uint64(0), uint64(0), "alpha", alpha.Bytes())
 
 	alphaGet, err := s.getAlpha(0, 0)
 	if err != nil {
@@ -550,7 +531,7 @@ func TestGetAlpha(t *testing.T) {
 	}
 
 	alpha = big.NewInt(0).SetUint64(0)
-	posdb.GetDb().PutWithIndex(uint64(0), uint64(1), "alpha", alpha.Bytes())
+	s.db.PutWithIndex(uint64(0), uint64(1), "alpha", alpha.Bytes())
 
 	alphaGet, err = s.getAlpha(0, 1)
 	if err != nil {
@@ -561,18 +542,13 @@ func TestGetAlpha(t *testing.T) {
 		t.Fail()
 	}
 
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	posconfig.SelfTestMode = true
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	var prvKeyExist *ecdsa.PrivateKey
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
@@ -590,8 +566,8 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 			uint64(i))
 	}
 
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	key, err := crypto.GenerateKey()
 	if err != nil {
@@ -633,18 +609,13 @@ func TestIsLocalPKInPreEpochLeaders(t *testing.T) {
 	}
 
 	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestBuildEpochLeaderGroup(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	posconfig.SelfTestMode = true
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -653,8 +624,8 @@ func TestBuildEpochLeaderGroup(t *testing.T) {
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
 
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	// buildEpochLeaderGroup
 	s.buildEpochLeaderGroup(2)
@@ -692,7 +663,6 @@ func TestBuildEpochLeaderGroup(t *testing.T) {
 	}
 
 	posconfig.SelfTestMode = false
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestGetChainReader(t *testing.T) {
@@ -705,9 +675,8 @@ func TestGetChainReader(t *testing.T) {
 		}
 	}()
 
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	if s.blockChain != nil {
 		t.Fail()
 	}
@@ -724,8 +693,7 @@ func TestGetRandom(t *testing.T) {
 	if stateDb == nil {
 		t.Fail()
 	}
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	vmcfg := vm.Config{}
 	gspec := core.DefaultPPOWTestingGenesisBlock()
 	gspec.MustCommit(db)
@@ -741,14 +709,10 @@ func TestGetRandom(t *testing.T) {
 }
 
 func TestBuildStage2TxPayload(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	posconfig.SelfTestMode = true
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	epochLeaderAllBytes := make([]byte, 65*posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -757,8 +721,8 @@ func TestBuildStage2TxPayload(t *testing.T) {
 		copy(epochLeaderAllBytes[i*65:], pubKeyByes[:])
 	}
 
-	posdb.GetDb().Put(2, EpochLeaders, epochLeaderAllBytes[:])
-	posdb.GetDb().Put(1, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(2, EpochLeaders, epochLeaderAllBytes[:])
+	s.db.Put(1, EpochLeaders, epochLeaderAllBytes[:])
 
 	s.buildEpochLeaderGroup(2)
 	// test below functions
@@ -771,13 +735,11 @@ func TestBuildStage2TxPayload(t *testing.T) {
 	}
 
 	fmt.Printf("bytes of stage2TxBytes is %v\n", hex.EncodeToString(stage2TxBytes))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
 
 func TestBuildSecurityPieces(t *testing.T) {
-	SlsInit()
-	s := GetSlotLeaderSelection()
-	s.Init(nil, &rpc.Client{}, &keystore.Key{})
+	s := newTestSLS()
+	s.Init(nil, &postx.Sender{}, &keystore.Key{})
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
@@ -836,8 +798,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	if stateDb == nil {
 		t.Fail()
 	}
-	SlsInit()
-	s := GetSlotLeaderSelection()
+	s := newTestSLS()
 	s.stateDbTest = stateDb
 	// build block chain
 	vmcfg := vm.Config{}
@@ -851,16 +812,13 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 
 	// build local key
-	s.Init(chain, &rpc.Client{}, &keystore.Key{})
+	s.Init(chain, &postx.Sender{}, &keystore.Key{})
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		t.Fail()
 	}
 	s.key.PrivateKey = key
 
-	dir, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
-	posdb.GetDb().DbInit(path.Join(dir, "sl_leader_test"))
 
 	// build current epoch leaders s.epochLeadersMap
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
@@ -909,7 +867,7 @@ func TestGenerateSecurityMsg(t *testing.T) {
 	}
 
 	indexKeyHash := vm.GetSlotLeaderStage2IndexesKeyHash(convert.Uint64ToBytes(epochID))
-	var sendtrans [posconfig.EpochLeaderCount]bool
+	sendtrans := make([]bool, posconfig.EpochLeaderCount)
 	for i := 0; i < posconfig.EpochLeaderCount; i++ {
 		sendtrans[i] = true
 	}
@@ -942,5 +900,4 @@ func TestGenerateSecurityMsg(t *testing.T) {
 		t.Fail()
 	}
 	// un init
-	os.RemoveAll(path.Join(dir, "sl_leader_test"))
 }
@@ -10,7 +10,6 @@ import (
 	"crypto/ecdsa"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/crypto"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 )
 
 func (s *SLS) ValidateBody(block *types.Block) error {
@@ -52,8 +51,8 @@ func (s *SLS) GetAllSlotLeaders(epochID uint64) (slotLeader []*ecdsa.PublicKey)
 
 	slotLeadersPtrArray := make([]*ecdsa.PublicKey,0)
 	// read from local db
-	for i := 0; i < posconfig.SlotCount; i++ {
-		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		pkByte, err := s.db.GetWithIndex(epochID, i, SlotLeader)
 		if err != nil {
 			return nil
 		}
@@ -10,13 +10,14 @@ import (
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
 var s *SLS
 
 func testInitSlotleader() {
-	s = GetSlotLeaderSelection()
+	s = newTestSLS()
 
 	// Create the database in memory or in a temporary directory.
 	db, _ := ethdb.NewMemDatabase()
@@ -26,13 +27,16 @@ func testInitSlotleader() {
 	ce := ethash.NewFaker(db)
 	bc, _ := core.NewBlockChain(db, gspec.Config, ce, vm.Config{})
 
-	s.Init(bc, &rpc.Client{}, &keystore.Key{})
+	s.Init(bc, &postx.Sender{}, &keystore.Key{})
 
 	s.sendTransactionFn = testSender
 
 }
 
 func TestGetCurrentStateDb(t *testing.T) {
+	defer func(mode bool) { posconfig.SelfTestMode = mode }(posconfig.SelfTestMode)
+	posconfig.SelfTestMode = false
+
 	testInitSlotleader()
 	stateDb, err := s.GetCurrentStateDb()
 	if err != nil || stateDb == nil {
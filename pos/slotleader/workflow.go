@@ -11,7 +11,7 @@ import (
 	"github.com/wanchain/go-wanchain/core/vm"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -19,9 +19,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var (
@@ -29,22 +27,22 @@ var (
 )
 
 // Init use to initial slotleader module and input some params.
-func (s *SLS) Init(blockChain *core.BlockChain, rc *rpc.Client, key *keystore.Key) {
+func (s *SLS) Init(blockChain *core.BlockChain, sender *postx.Sender, key *keystore.Key) {
 	s.blockChain = blockChain
-	s.rc = rc
+	s.sender = sender
 	s.key = key
 	if blockChain != nil {
 		log.Info("SLS init success")
 	}
 
-	s.sendTransactionFn = util.SendTx
+	s.sendTransactionFn = postx.SendTx
 }
 
 //Loop check work every Slot time. Called by backend loop.
 //It's all slotLeaderSelection's main workflow loop.
 //It does not loop at all, it is loop called by the backend.
-func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uint64) {
-	s.rc = rc
+func (s *SLS) Loop(sender *postx.Sender, key *keystore.Key, epochID uint64, slotID uint64) {
+	s.sender = sender
 	s.key = key
 
 	log.Info("Now epchoID and slotID:", "epochID", convert.Uint64ToString(epochID), "slotID",
@@ -93,7 +91,7 @@ func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uin
 			break
 		}
 
-		go doStage2Work()
+		go s.doStage2Work()
 		s.setWorkStage(epochID, slotLeaderSelectionStage3)
 	case slotLeaderSelectionStage3:
 		if slotID < posconfig.Sma3Start {
@@ -152,7 +150,7 @@ func (s *SLS) startStage1Work() error {
 				log.Error("generateCommitment error", "error", err.Error())
 				continue
 			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
+			err = s.sendSlotTx(data, posconfig.Sma1End, s.sendTransactionFn)
 			if err != nil {
 				log.Error("sendSlotTx error", "error", err.Error())
 				continue
@@ -164,8 +162,7 @@ func (s *SLS) startStage1Work() error {
 	return nil
 }
 
-func doStage2Work() {
-	s := GetSlotLeaderSelection()
+func (s *SLS) doStage2Work() {
 	err := s.startStage2Work()
 	if err != nil {
 		log.Error(err.Error())
@@ -190,7 +187,7 @@ func (s *SLS) startStage2Work() error {
 				log.Error("buildStage2TxPayload error", "error", err.Error())
 				continue
 			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
+			err = s.sendSlotTx(data, posconfig.Sma2End, s.sendTransactionFn)
 			if err != nil {
 				log.Error("sendSlotTx error", "error", err.Error())
 				continue
@@ -228,7 +225,7 @@ func (s *SLS) generateCommitment(publicKey *ecdsa.PublicKey,
 	buffer, err := vm.RlpPackStage1DataForTx(epochID, selfIndexInEpochLeader, commitment[1],
 		vm.GetSlotLeaderScAbiString())
 
-	posdb.GetDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
+	s.db.PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
 
 	log.Debug(fmt.Sprintf("----Put alpha epochID:%d, selfIndex:%d, alpha:%s, mi:%s, pk:%s", epochID,
 		selfIndexInEpochLeader, alpha.String(), hex.EncodeToString(crypto.FromECDSAPub(commitment[1])),
@@ -246,10 +243,10 @@ func (s *SLS) checkNewEpochStart(epochID uint64) {
 }
 
 func (s *SLS) getWorkingEpochID() uint64 {
-	ret, err := posdb.GetDb().Get(0, "slotLeaderCurrentSlotID")
+	ret, err := s.db.Get(0, "slotLeaderCurrentSlotID")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
-			posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
+			s.db.Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
 			return 0
 		}
 	}
@@ -258,12 +255,12 @@ func (s *SLS) getWorkingEpochID() uint64 {
 }
 
 func (s *SLS) setWorkingEpochID(workingEpochID uint64) error {
-	_, err := posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
+	_, err := s.db.Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
 	return err
 }
 
 func (s *SLS) getWorkStage(epochID uint64) int {
-	ret, err := posdb.GetDb().Get(epochID, "slotLeaderWorkStage")
+	ret, err := s.db.Get(epochID, "slotLeaderWorkStage")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
 			s.setWorkStage(epochID, slotLeaderSelectionInit)
@@ -278,6 +275,6 @@ func (s *SLS) getWorkStage(epochID uint64) int {
 
 func (s *SLS) setWorkStage(epochID uint64, workStage int) error {
 	workStageBig := big.NewInt(int64(workStage))
-	_, err := posdb.GetDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
+	_, err := s.db.Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
 	return err
 }
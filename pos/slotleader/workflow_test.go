@@ -9,7 +9,6 @@ import (
 
 	"github.com/wanchain/go-wanchain/crypto/bn256"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
 
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -18,7 +17,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
 var (
@@ -44,6 +43,12 @@ func (t *TestSelectLead) GetProposerBn256PK(epochID uint64, idx uint64, addr com
 
 func (t *TestSelectLead) GetRBProposerG1(epochID uint64) []bn256.G1 { return nil }
 
+// newTestSLS creates a slot leader selection on an in-memory db, reading the
+// epoch leaders generated by generateTestAddrs.
+func newTestSLS() *SLS {
+	return NewSLS(posdb.NewMemoryDbs().Local(), &TestSelectLead{})
+}
+
 func generateTestAddrs() {
 	for i := 0; i < addrsCount; i++ {
 		key, _ := crypto.GenerateKey()
@@ -53,12 +58,9 @@ func generateTestAddrs() {
 }
 
 func TestLoop(t *testing.T) {
-	posdb.GetDb().DbInit("test")
 	posconfig.SelfTestMode = false
-	SlsInit()
 	generateTestAddrs()
 	testInitSlotleader()
-	util.SetEpocherInst(&TestSelectLead{})
 
 	key := &keystore.Key{}
 	key.PrivateKey, _ = crypto.GenerateKey()
@@ -66,18 +68,17 @@ func TestLoop(t *testing.T) {
 
 	epochIDStart := time.Now().Second()
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&postx.Sender{}, key, uint64(epochIDStart+0), i)
 	}
 
-	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), uint64(i))
+	for i := uint64(0); i < posconfig.SlotCount; i++ {
+		s.Loop(&postx.Sender{}, key, uint64(epochIDStart+1), i)
 	}
 }
 
 func TestGenerateCommitmentSuccess(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := newTestSLS()
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
@@ -121,8 +122,7 @@ func TestGenerateCommitmentSuccess(t *testing.T) {
 }
 
 func TestGenerateCommitmentFailed(t *testing.T) {
-	posdb.GetDb().DbInit("test")
-	slot := GetSlotLeaderSelection()
+	slot := newTestSLS()
 
 	privKey, err := crypto.GenerateKey()
 	if err != nil {
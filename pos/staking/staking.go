@@ -0,0 +1,105 @@
+// Package staking builds transactions calling the PoS staking contract without
+// a node, so that they can be signed offline.
+package staking
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+// Staking operations supported by the builder.
+const (
+	OpStakeIn     = "stakeIn"
+	OpStakeAppend = "stakeAppend"
+	OpStakeUpdate = "stakeUpdate"
+	OpDelegateIn  = "delegateIn"
+	OpDelegateOut = "delegateOut"
+)
+
+var (
+	ErrUnknownOp     = errors.New("unknown staking operation")
+	ErrValueRequired = errors.New("staking operation requires a value")
+	ErrNoValue       = errors.New("staking operation does not accept a value")
+
+	minStakeholderStake = new(big.Int).Mul(big.NewInt(vm.PSMinStakeholderStake), big.NewInt(params.Wan))
+)
+
+// Args are the parameters of a staking operation. Only the ones used by the
+// operation have to be set: SecPk, Bn256Pk, LockEpochs and FeeRate for
+// stakeIn, Validator and LockEpochs for stakeUpdate and Validator for the
+// others.
+type Args struct {
+	Op         string
+	SecPk      []byte         // public key of the validator
+	Bn256Pk    []byte         // bn256 public key of the validator
+	LockEpochs uint64         // epochs the stake is locked for
+	FeeRate    uint64         // percentage of the delegators' incentive kept by the validator
+	Validator  common.Address // address of the validator, derived from SecPk
+	Value      *big.Int       // wei sent with the operation
+}
+
+// Pack ABI-encodes the call of a staking operation.
+func Pack(args *Args) ([]byte, error) {
+	cscAbi := vm.GetPosStakingAbi()
+	switch args.Op {
+	case OpStakeIn:
+		return cscAbi.Pack(args.Op, args.SecPk, args.Bn256Pk, new(big.Int).SetUint64(args.LockEpochs), new(big.Int).SetUint64(args.FeeRate))
+	case OpStakeUpdate:
+		return cscAbi.Pack(args.Op, args.Validator, new(big.Int).SetUint64(args.LockEpochs))
+	case OpStakeAppend, OpDelegateIn, OpDelegateOut:
+		return cscAbi.Pack(args.Op, args.Validator)
+	}
+	return nil, ErrUnknownOp
+}
+
+// Validate checks the data and the value of a call to the staking contract as
+// far as possible without the chain state. The data is checked with the rules
+// of the contract, the value against the minimum stake of new validators.
+// Whether a delegator stakes for the first time, and so has to send the
+// minimum delegation, is only known by the chain.
+func Validate(op string, data []byte, value *big.Int) error {
+	if err := vm.ValidPosStakingInput(data); err != nil {
+		return err
+	}
+	payable := value != nil && value.Sign() > 0
+	switch op {
+	case OpStakeIn:
+		if !payable || value.Cmp(minStakeholderStake) < 0 {
+			return fmt.Errorf("stakeIn requires at least %d wan", vm.PSMinStakeholderStake)
+		}
+	case OpStakeAppend, OpDelegateIn:
+		if !payable {
+			return ErrValueRequired
+		}
+	case OpStakeUpdate, OpDelegateOut:
+		if payable {
+			return ErrNoValue
+		}
+	default:
+		return ErrUnknownOp
+	}
+	return nil
+}
+
+// NewTransaction builds the unsigned transaction of a staking operation after
+// validating it.
+func NewTransaction(args *Args, nonce uint64, gasLimit, gasPrice *big.Int) (*types.Transaction, error) {
+	data, err := Pack(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := Validate(args.Op, data, args.Value); err != nil {
+		return nil, err
+	}
+	value := args.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	return types.NewTransaction(nonce, vm.WanCscPrecompileAddr, value, gasLimit, gasPrice, data), nil
+}
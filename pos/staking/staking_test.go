@@ -0,0 +1,95 @@
+package staking
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	testSecPk   = common.FromHex("0x04d7dffe5e06d2c7024d9bb93f675b8242e71901ee66a1bfe3fe5369324c0a75bf6f033dc4af65f5d0fe7072e98788fcfa670919b5bdc046f1ca91f28dff59db70")
+	testBn256Pk = common.FromHex("0x150b2b3230d6d6c8d1c133ec42d82f84add5e096c57665ff50ad071f6345cf45191fd8015cea72c4591ab3fd2ade12287c28a092ac0abf9ea19c13eb65fd4910")
+	testAddr    = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+)
+
+func wan(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(params.Wan))
+}
+
+func TestNewTransaction(t *testing.T) {
+	tests := []struct {
+		args *Args
+		err  bool
+	}{
+		{&Args{Op: OpStakeIn, SecPk: testSecPk, Bn256Pk: testBn256Pk, LockEpochs: 30, FeeRate: 10, Value: wan(50000)}, false},
+		{&Args{Op: OpStakeIn, SecPk: testSecPk, Bn256Pk: testBn256Pk, LockEpochs: 30, FeeRate: 10, Value: wan(9999)}, true},
+		{&Args{Op: OpStakeIn, SecPk: testSecPk, Bn256Pk: testBn256Pk, LockEpochs: 6, FeeRate: 10, Value: wan(50000)}, true},
+		{&Args{Op: OpStakeIn, SecPk: testSecPk, Bn256Pk: testBn256Pk, LockEpochs: 30, FeeRate: 101, Value: wan(50000)}, true},
+		{&Args{Op: OpStakeIn, SecPk: testSecPk[:10], Bn256Pk: testBn256Pk, LockEpochs: 30, Value: wan(50000)}, true},
+		{&Args{Op: OpStakeIn, SecPk: testSecPk, Bn256Pk: testSecPk, LockEpochs: 30, Value: wan(50000)}, true},
+		{&Args{Op: OpStakeAppend, Validator: testAddr, Value: wan(100)}, false},
+		{&Args{Op: OpStakeAppend, Validator: testAddr}, true},
+		{&Args{Op: OpStakeUpdate, Validator: testAddr, LockEpochs: 90}, false},
+		{&Args{Op: OpStakeUpdate, Validator: testAddr, LockEpochs: 91}, true},
+		{&Args{Op: OpStakeUpdate, Validator: testAddr, LockEpochs: 30, Value: wan(1)}, true},
+		{&Args{Op: OpDelegateIn, Validator: testAddr, Value: wan(100)}, false},
+		{&Args{Op: OpDelegateOut, Validator: testAddr}, false},
+		{&Args{Op: "partnerOut", Validator: testAddr}, true},
+	}
+	cscAbi := vm.GetPosStakingAbi()
+	for i, tt := range tests {
+		tx, err := NewTransaction(tt.args, 3, big.NewInt(200000), big.NewInt(180000000000))
+		if tt.err {
+			if err == nil {
+				t.Errorf("test %d: expected error for %s", i, tt.args.Op)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %s failed: %v", i, tt.args.Op, err)
+			continue
+		}
+		if *tx.To() != vm.WanCscPrecompileAddr || tx.Nonce() != 3 || tx.Txtype() != types.NORMAL_TX {
+			t.Errorf("test %d: wrong transaction %v", i, tx)
+		}
+		method, err := cscAbi.MethodById(tx.Data()[:4])
+		if err != nil || method.Name != tt.args.Op {
+			t.Errorf("test %d: wrong method %v, %v", i, method, err)
+		}
+	}
+}
+
+func TestSignedTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := NewTransaction(&Args{Op: OpDelegateIn, Validator: testAddr, Value: wan(100)}, 0, big.NewInt(200000), big.NewInt(180000000000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer := types.NewEIP155Signer(big.NewInt(3))
+	signed, err := types.SignTx(tx, signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := rlp.EncodeToBytes(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := new(types.Transaction)
+	if err := rlp.DecodeBytes(raw, decoded); err != nil {
+		t.Fatal(err)
+	}
+	from, err := types.Sender(signer, decoded)
+	if err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("sender mismatch: %x, %v", from, err)
+	}
+	if err := (&vm.PosStaking{}).ValidTx(nil, nil, signer, decoded); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,221 @@
+package util
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+var (
+	errJumpNotAfter = errors.New("epoch jump is not after the last one")
+	errJumpConflict = errors.New("epoch jump conflicts with a known one")
+
+	// ErrEpochSlotMismatch is returned if the epoch and slot of a block are not
+	// the ones of its time.
+	ErrEpochSlotMismatch = errors.New("epochid or slotid do not match")
+)
+
+// EpochJump is a point where a halted chain was restarted. The blocks of Epoch
+// are concatenated to the last block before the halt, and Epoch starts Jump
+// epochs later on the time grid than it would have without the halts, Jump
+// counts all the epochs skipped since EpochBaseTime. Block is the number of the
+// first block after the halt, 0 while the recovery is only scheduled.
+type EpochJump struct {
+	Epoch uint64
+	Jump  uint64
+	Block uint64
+}
+
+// StartTime returns the time the first slot of the epoch after the halt starts.
+func (j EpochJump) StartTime() uint64 {
+	return posconfig.EpochBaseTime + (j.Epoch+j.Jump)*posconfig.SlotCount*posconfig.SlotTime
+}
+
+// HeaderEpochJump returns the epoch of header and the epochs the time grid is
+// ahead of it, they are the epochs skipped by the chain halt recoveries before
+// header.
+func HeaderEpochJump(header *types.Header) (EpochJump, error) {
+	epochID, slotID := GetEpochSlotIDFromDifficulty(header.Difficulty)
+	gridEpochID, gridSlotID := CalEpochSlotID(header.Time.Uint64())
+	if gridSlotID != slotID || gridEpochID < epochID {
+		return EpochJump{}, ErrEpochSlotMismatch
+	}
+	return EpochJump{Epoch: epochID, Jump: gridEpochID - epochID, Block: header.Number.Uint64()}, nil
+}
+
+// EpochJumps is the epoch jumps of the chain halt recoveries of a chain,
+// ordered by epoch. A nil EpochJumps has no jumps, the epochs are the ones of
+// the time grid.
+type EpochJumps struct {
+	mu    sync.RWMutex
+	jumps []EpochJump
+}
+
+// NewEpochJumps creates the epoch jumps js, as loaded from the database.
+func NewEpochJumps(js []EpochJump) *EpochJumps {
+	jumps := append([]EpochJump(nil), js...)
+	sort.Slice(jumps, func(i, k int) bool { return jumps[i].Epoch < jumps[k].Epoch })
+	return &EpochJumps{jumps: jumps}
+}
+
+// List returns the jumps ordered by epoch.
+func (e *EpochJumps) List() []EpochJump {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return append([]EpochJump(nil), e.jumps...)
+}
+
+// Add records j. It returns false if j is known already, and an error if it
+// does not come after the last known jump or conflicts with a known one. The
+// jump of a block replaces the scheduled ones from its epoch on, the chain was
+// restarted by it.
+func (e *EpochJumps) Add(j EpochJump) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	jumps, err := addEpochJump(e.jumps, j)
+	if err != nil || jumps == nil {
+		return false, err
+	}
+	e.jumps = jumps
+	return true, nil
+}
+
+// With returns a copy of e with j, or e if j is known or can't be added.
+func (e *EpochJumps) With(j EpochJump) *EpochJumps {
+	jumps, err := addEpochJump(e.List(), j)
+	if err != nil || jumps == nil {
+		return e
+	}
+	return &EpochJumps{jumps: jumps}
+}
+
+// addEpochJump returns jumps with j, nil if j is in jumps already.
+func addEpochJump(jumps []EpochJump, j EpochJump) ([]EpochJump, error) {
+	if j.Block != 0 {
+		kept := jumps[:0:0]
+		for _, known := range jumps {
+			if known == j {
+				return nil, nil
+			}
+			if known.Block != 0 || known.Epoch < j.Epoch {
+				kept = append(kept, known)
+			}
+		}
+		jumps = kept
+	}
+	for _, known := range jumps {
+		if known.Epoch == j.Epoch {
+			if known.Jump != j.Jump {
+				return nil, errJumpConflict
+			}
+			return nil, nil
+		}
+	}
+	if n := len(jumps); n > 0 && (jumps[n-1].Epoch >= j.Epoch || jumps[n-1].Jump >= j.Jump) {
+		return nil, errJumpNotAfter
+	}
+	return append(jumps, j), nil
+}
+
+// Rewind drops the jumps of the blocks above number, it reports whether any
+// was dropped. The scheduled jumps are kept.
+func (e *EpochJumps) Rewind(number uint64) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	kept := e.jumps[:0:0]
+	for _, j := range e.jumps {
+		if j.Block <= number {
+			kept = append(kept, j)
+		}
+	}
+	if len(kept) == len(e.jumps) {
+		return false
+	}
+	e.jumps = kept
+	return true
+}
+
+// JumpOfEpoch returns the epochs skipped before epochID.
+func (e *EpochJumps) JumpOfEpoch(epochID uint64) uint64 {
+	jump := uint64(0)
+	for _, j := range e.List() {
+		if j.Epoch > epochID {
+			break
+		}
+		jump = j.Jump
+	}
+	return jump
+}
+
+// RestartEpoch returns the epoch the chain was last restarted at before or in
+// epochID, 0 if it never halted. The chain quality is measured from it.
+func (e *EpochJumps) RestartEpoch(epochID uint64) uint64 {
+	restart := uint64(0)
+	for _, j := range e.List() {
+		if j.Epoch > epochID {
+			break
+		}
+		restart = j.Epoch
+	}
+	return restart
+}
+
+// Last returns the last jump.
+func (e *EpochJumps) Last() (EpochJump, bool) {
+	jumps := e.List()
+	if len(jumps) == 0 {
+		return EpochJump{}, false
+	}
+	return jumps[len(jumps)-1], true
+}
+
+// CalEpochSlotID returns the epoch and slot of time, the epochs skipped by the
+// chain halt recoveries are not counted.
+func (e *EpochJumps) CalEpochSlotID(time uint64) (epochId, slotId uint64) {
+	gridEpochId, slotId := CalEpochSlotID(time)
+
+	jump := uint64(0)
+	for _, j := range e.List() {
+		if j.Epoch+j.Jump > gridEpochId {
+			break
+		}
+		jump = j.Jump
+	}
+	return gridEpochId - jump, slotId
+}
+
+// CurEpochSlotID returns the current epoch and slot, both 0 until the chain has
+// its first block.
+func (e *EpochJumps) CurEpochSlotID() (epochId, slotId uint64) {
+	if posconfig.EpochBaseTime == 0 {
+		return 0, 0
+	}
+	return e.CalEpochSlotID(uint64(time.Now().Unix()))
+}
+
+// EpochStartTime returns the time the first slot of epochID starts.
+func (e *EpochJumps) EpochStartTime(epochID uint64) uint64 {
+	return posconfig.EpochBaseTime + (epochID+e.JumpOfEpoch(epochID))*posconfig.SlotCount*posconfig.SlotTime
+}
+
+// RecoveryPending reports whether time is after the halted epoch of the last
+// jump but before the epoch after the halt starts, no block is produced in
+// between.
+func (e *EpochJumps) RecoveryPending(time uint64) bool {
+	last, ok := e.Last()
+	if !ok {
+		return false
+	}
+	epochId, _ := e.CalEpochSlotID(time)
+	return time < last.StartTime() && epochId >= last.Epoch
+}
@@ -0,0 +1,134 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// setTestTimeGrid sets a grid of epochs of 10 slots of 2 seconds from 1000,
+// the returned function restores the previous one.
+func setTestTimeGrid() func() {
+	base, slotTime, slotCount := posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount
+	posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = 1000, 2, 10
+	return func() {
+		posconfig.EpochBaseTime, posconfig.SlotTime, posconfig.SlotCount = base, slotTime, slotCount
+	}
+}
+
+func TestCalEpochSlotIDJumps(t *testing.T) {
+	defer setTestTimeGrid()()
+
+	// halted in epoch 2, epoch 3 restarted at grid epoch 6, halted again in
+	// epoch 4 and epoch 5 restarted at grid epoch 10
+	jumps := NewEpochJumps(nil)
+	if _, err := jumps.Add(EpochJump{Epoch: 3, Jump: 3, Block: 30}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jumps.Add(EpochJump{Epoch: 5, Jump: 5, Block: 45}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		time        uint64
+		epoch, slot uint64
+		gridEpoch   uint64
+		epochStart  uint64
+		pending     bool
+	}{
+		{1000, 0, 0, 0, 1000, false},
+		{1059, 2, 9, 2, 1040, false},
+		{1062, 3, 1, 3, 1120, false}, // grid epoch 3 is skipped
+		{1120, 3, 0, 6, 1120, false}, // epoch 3 starts at grid epoch 6
+		{1145, 4, 2, 7, 1140, false},
+		{1165, 5, 2, 8, 1200, true},
+		{1200, 5, 0, 10, 1200, false},
+		{1222, 6, 1, 11, 1220, false},
+	}
+	for _, tt := range tests {
+		epoch, slot := jumps.CalEpochSlotID(tt.time)
+		if epoch != tt.epoch || slot != tt.slot {
+			t.Errorf("time %d: got epoch %d slot %d, want %d %d", tt.time, epoch, slot, tt.epoch, tt.slot)
+		}
+		if gridEpoch, _ := CalEpochSlotID(tt.time); gridEpoch != tt.gridEpoch {
+			t.Errorf("time %d: got grid epoch %d, want %d", tt.time, gridEpoch, tt.gridEpoch)
+		}
+		if start := jumps.EpochStartTime(epoch); start != tt.epochStart {
+			t.Errorf("epoch %d: got start %d, want %d", epoch, start, tt.epochStart)
+		}
+		if pending := jumps.RecoveryPending(tt.time); pending != tt.pending {
+			t.Errorf("time %d: recovery pending %v, want %v", tt.time, pending, tt.pending)
+		}
+	}
+
+	// a chain without jumps runs on the time grid
+	var none *EpochJumps
+	if epoch, slot := none.CalEpochSlotID(1222); epoch != 11 || slot != 1 {
+		t.Errorf("no jumps: got epoch %d slot %d, want 11 1", epoch, slot)
+	}
+}
+
+func TestAddEpochJump(t *testing.T) {
+	defer setTestTimeGrid()()
+
+	jumps := NewEpochJumps(nil)
+	if added, err := jumps.Add(EpochJump{Epoch: 3, Jump: 2, Block: 30}); !added || err != nil {
+		t.Fatalf("got %v %v", added, err)
+	}
+	if added, err := jumps.Add(EpochJump{Epoch: 3, Jump: 2, Block: 30}); added || err != nil {
+		t.Errorf("known jump: got %v %v", added, err)
+	}
+	if _, err := jumps.Add(EpochJump{Epoch: 3, Jump: 4}); err != errJumpConflict {
+		t.Errorf("conflicting jump: got %v", err)
+	}
+	if _, err := jumps.Add(EpochJump{Epoch: 2, Jump: 4}); err != errJumpNotAfter {
+		t.Errorf("earlier jump: got %v", err)
+	}
+	if _, err := jumps.Add(EpochJump{Epoch: 5, Jump: 2}); err != errJumpNotAfter {
+		t.Errorf("jump back: got %v", err)
+	}
+	for epoch, want := range []uint64{0, 0, 0, 3, 3} {
+		if restart := jumps.RestartEpoch(uint64(epoch)); restart != want {
+			t.Errorf("epoch %d: got restart epoch %d, want %d", epoch, restart, want)
+		}
+	}
+	if list := jumps.List(); len(list) != 1 || list[0] != (EpochJump{Epoch: 3, Jump: 2, Block: 30}) {
+		t.Errorf("got jumps %v", list)
+	}
+}
+
+func TestScheduledEpochJump(t *testing.T) {
+	defer setTestTimeGrid()()
+
+	// scheduled by the operators, then restarted by a block at another time
+	jumps := NewEpochJumps(nil)
+	if _, err := jumps.Add(EpochJump{Epoch: 3, Jump: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := jumps.Add(EpochJump{Epoch: 3, Jump: 2, Block: 30}); !added || err != nil {
+		t.Fatalf("block jump: got %v %v", added, err)
+	}
+	if list := jumps.List(); len(list) != 1 || list[0] != (EpochJump{Epoch: 3, Jump: 2, Block: 30}) {
+		t.Errorf("got jumps %v", list)
+	}
+
+	// a block not in the chain yet sees its own jump
+	next := EpochJump{Epoch: 5, Jump: 4, Block: 50}
+	if with := jumps.With(next); len(with.List()) != 2 || len(jumps.List()) != 1 {
+		t.Errorf("got jumps %v with the block, %v without", with.List(), jumps.List())
+	}
+
+	// the jumps of the blocks rewound are dropped, the scheduled ones kept
+	if _, err := jumps.Add(EpochJump{Epoch: 6, Jump: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if jumps.Rewind(30) {
+		t.Errorf("rewind to the block of the jump dropped it")
+	}
+	if !jumps.Rewind(29) {
+		t.Errorf("rewind below the block of the jump kept it")
+	}
+	if list := jumps.List(); len(list) != 1 || list[0] != (EpochJump{Epoch: 6, Jump: 5}) {
+		t.Errorf("got jumps %v after rewind", list)
+	}
+}
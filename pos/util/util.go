@@ -7,7 +7,6 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
-	"time"
 
 	"sync"
 
@@ -20,38 +19,19 @@ import (
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 )
 
+// CalEpochSlotID returns the epoch and slot of time on the time grid started at
+// EpochBaseTime. The epochs skipped by the chain halt recoveries are counted,
+// EpochJumps.CalEpochSlotID returns the epoch of the chain.
 func CalEpochSlotID(time uint64) (epochId, slotId uint64) {
-	if posconfig.EpochBaseTime == 0 {
+	if posconfig.EpochBaseTime == 0 || time < posconfig.EpochBaseTime {
 		return
 	}
-	//timeUnix := uint64(time.Now().Unix())
-	timeUnix := time
-	epochTimespan := uint64(posconfig.SlotTime * posconfig.SlotCount)
-	epochId = uint64((timeUnix - posconfig.EpochBaseTime) / epochTimespan)
-	slotId = uint64((timeUnix - posconfig.EpochBaseTime) / posconfig.SlotTime % posconfig.SlotCount)
-	//fmt.Println("CalEpochSlotID:", epochId, slotId)
+	epochTimespan := posconfig.SlotTime * posconfig.SlotCount
+	epochId = (time - posconfig.EpochBaseTime) / epochTimespan
+	slotId = (time - posconfig.EpochBaseTime) / posconfig.SlotTime % posconfig.SlotCount
 	return epochId, slotId
 }
 
-var (
-	curEpochId = uint64(0)
-	curSlotId  = uint64(0)
-)
-
-func GetEpochSlotID() (uint64, uint64) {
-	return curEpochId, curSlotId
-}
-func CalEpochSlotIDByNow() {
-	if posconfig.EpochBaseTime == 0 {
-		return
-	}
-	timeUnix := uint64(time.Now().Unix())
-	epochTimeSpan := uint64(posconfig.SlotTime * posconfig.SlotCount)
-	curEpochId = uint64((timeUnix - posconfig.EpochBaseTime) / epochTimeSpan)
-	curSlotId = uint64((timeUnix - posconfig.EpochBaseTime) / posconfig.SlotTime % posconfig.SlotCount)
-	//fmt.Println("CalEpochSlotID:", curEpochId, curSlotId)
-}
-
 //PkEqual only can use in same curve. return whether the two points equal
 func PkEqual(pk1, pk2 *ecdsa.PublicKey) bool {
 	if pk1 == nil || pk2 == nil {
@@ -73,75 +53,87 @@ type SelectLead interface {
 	//TryGetAndSaveAllStakerInfoBytes(epochId uint64) (*[][]byte, error)
 }
 
-var (
-	lastBlockEpoch     = make(map[uint64]uint64)
-	lastBlockHashEpoch = make(map[uint64]common.Hash)
-	lbe                = sync.Mutex{}
-	selecter           SelectLead
-	lastEpochId        = uint64(0)
-	selectedEpochId    = uint64(0)
-)
+func CalEpSlbyTd(blkTd uint64) (epochID uint64, slotID uint64) {
+	epochID = (blkTd >> 32)
+	slotID = ((blkTd & 0xffffffff) >> 8)
+	return epochID, slotID
+}
 
-func SetEpocherInst(sor SelectLead) {
-	selecter = sor
+// EpochBlocks keeps the number and hash of the last block of every epoch of a
+// chain, and starts the epoch leader selection of the next epoch once the
+// blocks of an epoch are past 2K slots. Every chain has its own EpochBlocks.
+type EpochBlocks struct {
+	lastBlockEpoch     map[uint64]uint64
+	lastBlockHashEpoch map[uint64]common.Hash
+	lbe                sync.Mutex
+	selecter           SelectLead
+	selectedEpochId    uint64
+	checkedEpochId     uint64
 }
 
-func GetEpocherInst() SelectLead {
-	// TODO: can't be nil
-	if selecter == nil {
-		panic("GetEpocherInst")
+func NewEpochBlocks() *EpochBlocks {
+	return &EpochBlocks{
+		lastBlockEpoch:     make(map[uint64]uint64),
+		lastBlockHashEpoch: make(map[uint64]common.Hash),
 	}
-	return selecter
 }
 
-func CalEpSlbyTd(blkTd uint64) (epochID uint64, slotID uint64) {
-	epochID = (blkTd >> 32)
-	slotID = ((blkTd & 0xffffffff) >> 8)
-	return epochID, slotID
+// SetSelecter sets the epoch leader selection started by UpdateEpochBlock.
+func (e *EpochBlocks) SetSelecter(sor SelectLead) {
+	e.lbe.Lock()
+	e.selecter = sor
+	e.lbe.Unlock()
 }
-func UpdateEpochBlock(block *types.Block) {
+
+func (e *EpochBlocks) UpdateEpochBlock(block *types.Block) {
 	blkTd := block.Difficulty().Uint64()
 	epochID, slotID := CalEpSlbyTd(blkTd)
-	updateEpochBlock(epochID, slotID, block.Header().Number.Uint64(), block.Header().Hash())
+	e.updateEpochBlock(epochID, slotID, block.Header().Number.Uint64(), block.Header().Hash())
 }
-func updateEpochBlock(epochID uint64, slotID uint64, blockNumber uint64, hash common.Hash) {
-	if epochID != lastEpochId {
-		lastEpochId = epochID
-	}
+func (e *EpochBlocks) updateEpochBlock(epochID uint64, slotID uint64, blockNumber uint64, hash common.Hash) {
 	// there is 2K slot, so need not think about reorg
-	if slotID >= 2*posconfig.K+1 && selectedEpochId != epochID+1 {
-		go GetEpocherInst().SelectLeadersLoop(epochID + 1)
-		selectedEpochId = epochID + 1
+	e.lbe.Lock()
+	if slotID >= 2*posconfig.K+1 && e.selectedEpochId != epochID+1 && e.selecter != nil {
+		go e.selecter.SelectLeadersLoop(epochID + 1)
+		e.selectedEpochId = epochID + 1
 	}
+	// the leaders of the first epoch after a chain halt are never selected
+	// above, as no block of the epoch before got past 2K slots
+	selecter := e.selecter
+	check := selecter != nil && epochID > e.checkedEpochId && epochID > e.selectedEpochId
+	if check {
+		e.checkedEpochId = epochID
+	}
+	e.lbe.Unlock()
+
+	e.SetEpochBlock(epochID, blockNumber, hash)
 
-	SetEpochBlock(epochID, blockNumber, hash)
+	if check {
+		go func() {
+			if len(selecter.GetEpochLeaders(epochID)) == 0 {
+				selecter.SelectLeadersLoop(epochID)
+			}
+		}()
+	}
 }
-func SetEpochBlock(epochID uint64, blockNumber uint64, hash common.Hash) {
-	lbe.Lock()
-	lastBlockEpoch[epochID] = blockNumber
-	lastBlockHashEpoch[epochID] = hash
-	lbe.Unlock()
+func (e *EpochBlocks) SetEpochBlock(epochID uint64, blockNumber uint64, hash common.Hash) {
+	e.lbe.Lock()
+	e.lastBlockEpoch[epochID] = blockNumber
+	e.lastBlockHashEpoch[epochID] = hash
+	e.lbe.Unlock()
 }
-func GetEpochBlock(epochID uint64) uint64 {
-	lbe.Lock()
-	b := lastBlockEpoch[epochID]
-	lbe.Unlock()
+func (e *EpochBlocks) GetEpochBlock(epochID uint64) uint64 {
+	e.lbe.Lock()
+	b := e.lastBlockEpoch[epochID]
+	e.lbe.Unlock()
 	return b
 }
-func GetEpochBlockHash(epochID uint64) common.Hash {
-	lbe.Lock()
-	bh := lastBlockHashEpoch[epochID]
-	lbe.Unlock()
+func (e *EpochBlocks) GetEpochBlockHash(epochID uint64) common.Hash {
+	e.lbe.Lock()
+	bh := e.lastBlockHashEpoch[epochID]
+	e.lbe.Unlock()
 	return bh
 }
-func GetProposerBn256PK(epochID uint64, idx uint64, addr common.Address) []byte {
-	return GetEpocherInst().GetProposerBn256PK(epochID, idx, addr)
-}
-
-func TryGetAndSaveAllStakerInfoBytes(epochId uint64) (*[][]byte, error) {
-	//return GetEpocherInst().TryGetAndSaveAllStakerInfoBytes(epochId)
-	return nil, nil
-}
 
 // CompressPk
 func CompressPk(pk *ecdsa.PublicKey) ([]byte, error) {
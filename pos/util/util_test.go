@@ -10,7 +10,7 @@ import (
 )
 
 func TestGetEpochSlotID(t *testing.T) {
-	epochID, slotID := GetEpochSlotID()
+	epochID, slotID := NewEpochJumps(nil).CurEpochSlotID()
 	fmt.Println("epochID:", epochID, " slotID:", slotID)
 }
 
@@ -119,13 +119,15 @@ type ServerCodec interface {
 type BlockNumber int64
 
 const (
+	StableBlockNumber   = BlockNumber(-3)
 	PendingBlockNumber  = BlockNumber(-2)
 	LatestBlockNumber   = BlockNumber(-1)
 	EarliestBlockNumber = BlockNumber(0)
 )
 
 // UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
-// - "latest", "earliest" or "pending" as string arguments
+// - "latest", "earliest", "pending" or "stable" as string arguments, "stable"
+//   being the highest block confirmed by the PoS block confirmation rules
 // - the block number
 // Returned errors:
 // - an invalid block number error when the given argument isn't a known strings
@@ -146,6 +148,9 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 	case "pending":
 		*bn = PendingBlockNumber
 		return nil
+	case "stable":
+		*bn = StableBlockNumber
+		return nil
 	}
 
 	blckNum, err := hexutil.DecodeUint64(input)